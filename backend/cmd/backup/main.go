@@ -0,0 +1,20 @@
+// Command backup creates, lists and restores KUNO backup archives using the
+// same configuration as the server (config file and environment). It is the
+// same as "kuno backup".
+//
+//	backup create [-offsite] snapshot the database and uploads into a new archive
+//	backup list              list existing archives, newest first
+//	backup restore <name>    replace the database and uploads with an archive
+//
+// Restoring while the server is running is safe but the server keeps any
+// data it has cached in memory; prefer the admin API or restart afterwards.
+package main
+
+import (
+	"blog-backend/internal/cli"
+	"os"
+)
+
+func main() {
+	cli.Run("backup", os.Args[1:])
+}
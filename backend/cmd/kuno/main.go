@@ -0,0 +1,14 @@
+// Command kuno is the admin CLI: reset passwords, run migrations, create
+// and restore backups, reindex embeddings and export content using the
+// same configuration as the server. Run it without arguments for a list of
+// commands.
+package main
+
+import (
+	"blog-backend/internal/cli"
+	"os"
+)
+
+func main() {
+	cli.Main(os.Args[1:])
+}
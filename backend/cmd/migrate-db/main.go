@@ -0,0 +1,170 @@
+// Command migrate-db copies all data from one KUNO database to another, for
+// example from the default SQLite file to PostgreSQL or MySQL.
+//
+//	migrate-db -from-path ./data/blog.db -to-driver postgres -to-dsn "host=db user=kuno dbname=kuno"
+//
+// The destination schema is created with the same versioned migrations the
+// server runs.
+// The destination must be empty; stop the server before copying.
+package main
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+const batchSize = 500
+
+func main() {
+	fromDriver := flag.String("from-driver", database.DriverSQLite, "source driver (sqlite, postgres, mysql)")
+	fromPath := flag.String("from-path", "./data/blog.db", "source SQLite file")
+	fromDSN := flag.String("from-dsn", "", "source DSN for postgres/mysql")
+	toDriver := flag.String("to-driver", "", "destination driver (sqlite, postgres, mysql)")
+	toPath := flag.String("to-path", "", "destination SQLite file")
+	toDSN := flag.String("to-dsn", "", "destination DSN for postgres/mysql (secret references allowed)")
+	flag.Parse()
+
+	if *toDriver == "" {
+		fmt.Fprintln(os.Stderr, "-to-driver is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src, err := open(config.DatabaseConfig{Driver: *fromDriver, Path: *fromPath, DSN: *fromDSN})
+	if err != nil {
+		log.Fatalf("Failed to open source database: %v", err)
+	}
+	dst, err := open(config.DatabaseConfig{Driver: *toDriver, Path: *toPath, DSN: *toDSN})
+	if err != nil {
+		log.Fatalf("Failed to open destination database: %v", err)
+	}
+
+	log.Printf("Creating destination schema (%s)", *toDriver)
+	if err := database.Migrate(dst); err != nil {
+		log.Fatalf("Failed to migrate destination schema: %v", err)
+	}
+
+	for _, model := range orderModels(dst, database.Models()) {
+		count, err := copyTable(src, dst, model)
+		if err != nil {
+			log.Fatalf("Failed to copy %T: %v", model, err)
+		}
+		log.Printf("Copied %d rows of %T", count, model)
+	}
+
+	if *toDriver == database.DriverPostgres {
+		resetSequences(dst, database.Models())
+	}
+
+	log.Printf("Migration complete. Set DB_DRIVER=%s and DB_DSN to use the new database.", *toDriver)
+}
+
+func open(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	dialector, err := database.OpenDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)})
+}
+
+// orderModels sorts models so referenced tables are copied before the
+// tables whose foreign keys point at them. Constraints may be declared on
+// either side of a relationship, so all models are inspected first.
+func orderModels(db *gorm.DB, models []interface{}) []interface{} {
+	tables := make([]string, len(models))
+	byTable := make(map[string]interface{}, len(models))
+	depends := make(map[string][]string)
+
+	for i, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			log.Fatalf("Failed to parse %T: %v", model, err)
+		}
+		tables[i] = stmt.Schema.Table
+		byTable[stmt.Schema.Table] = model
+
+		for _, rel := range stmt.Schema.Relationships.Relations {
+			if c := rel.ParseConstraint(); c != nil && c.Schema != c.ReferenceSchema {
+				depends[c.Schema.Table] = append(depends[c.Schema.Table], c.ReferenceSchema.Table)
+			}
+		}
+	}
+
+	var ordered []interface{}
+	visited := make(map[string]bool)
+	var visit func(table string)
+	visit = func(table string) {
+		if visited[table] {
+			return
+		}
+		visited[table] = true
+		for _, dependency := range depends[table] {
+			visit(dependency)
+		}
+		if model, ok := byTable[table]; ok {
+			ordered = append(ordered, model)
+		}
+	}
+	for _, table := range tables {
+		visit(table)
+	}
+	return ordered
+}
+
+// copyTable copies every row of a model, including soft-deleted ones,
+// preserving primary keys and zero values that would otherwise fall back to
+// column defaults. Associations are copied as their own tables.
+func copyTable(src, dst *gorm.DB, model interface{}) (int, error) {
+	var existing int64
+	if err := dst.Model(model).Unscoped().Count(&existing).Error; err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 0, fmt.Errorf("destination table is not empty (%d rows)", existing)
+	}
+
+	sliceType := reflect.SliceOf(reflect.TypeOf(model).Elem())
+	batch := reflect.New(sliceType)
+
+	total := 0
+	result := src.Model(model).Unscoped().FindInBatches(batch.Interface(), batchSize, func(tx *gorm.DB, _ int) error {
+		rows := batch.Elem()
+		if rows.Len() == 0 {
+			return nil
+		}
+		if err := dst.Select("*").Omit(clause.Associations).Create(batch.Interface()).Error; err != nil {
+			return err
+		}
+		total += rows.Len()
+		return nil
+	})
+	return total, result.Error
+}
+
+// resetSequences moves PostgreSQL id sequences past the copied ids
+func resetSequences(db *gorm.DB, models []interface{}) {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil || stmt.Schema.PrioritizedPrimaryField == nil {
+			continue
+		}
+		table := stmt.Schema.Table
+		column := stmt.Schema.PrioritizedPrimaryField.DBName
+		err := db.Exec(fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+			table, column, column, table,
+		)).Error
+		if err != nil {
+			log.Printf("Failed to reset sequence for %s: %v", table, err)
+		}
+	}
+}
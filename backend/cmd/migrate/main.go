@@ -0,0 +1,17 @@
+// Command migrate manages versioned database schema migrations using the
+// same configuration as the server (config file and environment). It is
+// the same as "kuno migrate".
+//
+//	migrate status          list migrations and whether they are applied
+//	migrate up              apply all pending migrations
+//	migrate down [-steps N] revert the last N applied migrations (default 1)
+package main
+
+import (
+	"blog-backend/internal/cli"
+	"os"
+)
+
+func main() {
+	cli.Run("migrate", os.Args[1:])
+}
@@ -2,48 +2,138 @@ package main
 
 import (
 	"blog-backend/internal/api"
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
 	"blog-backend/internal/database"
-	"log"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
-	"time"
+	"syscall"
 )
 
 func main() {
-	// Enhanced logging setup
-	log.SetOutput(os.Stdout)
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
-
-	log.Printf("Starting KUNO Blog Backend Server")
-	log.Printf("Time: %s", time.Now().Format("2006-01-02 15:04:05"))
-	log.Printf("Go Version: %s", runtime.Version())
-	log.Printf("OS/Arch: %s/%s", runtime.GOOS, runtime.GOARCH)
-	log.Printf("Working Directory: %s", func() string {
-		wd, _ := os.Getwd()
-		return wd
-	}())
+	// Load the config file and environment before anything reads settings
+	cfg := config.Get()
+
+	// Structured logging setup (LOG_LEVEL, LOG_FORMAT)
+	logging.Setup()
+
+	if err := config.LoadError(); err != nil {
+		slog.Error("Invalid configuration", "file", cfg.File, "error", err)
+		os.Exit(1)
+	}
+	if cfg.File != "" {
+		slog.Info("Loaded configuration file", "file", cfg.File)
+	}
+
+	wd, _ := os.Getwd()
+	slog.Info("Starting KUNO Blog Backend Server",
+		"go_version", runtime.Version(),
+		"os", runtime.GOOS,
+		"arch", runtime.GOARCH,
+		"working_dir", wd,
+	)
+
+	// Shared cache (CACHE_DRIVER); Redis must be reachable at startup
+	if err := cache.Init(cfg.Cache); err != nil {
+		slog.Error("Failed to initialize cache", "driver", cfg.Cache.Driver, "error", err)
+		os.Exit(1)
+	}
+	if cfg.Cache.Driver == "redis" && cfg.Auth.JWTSecret == "" {
+		// A shared cache suggests several replicas, which each generate their
+		// own secret and reject each other's tokens
+		slog.Warn("JWT_SECRET is not set; every instance signs tokens with its own random secret")
+	}
 
 	// Initialize database with enhanced error handling
-	log.Println("Initializing database connection...")
+	slog.Info("Initializing database connection")
 	database.InitDatabase()
-	log.Println("Database initialization completed")
+	slog.Info("Database initialization completed")
+
+	// Shared services are built once here, before any request or job can
+	// race to create them
+	if err := services.InitContainer(services.NewContainer()); err != nil {
+		slog.Error("Failed to initialize services", "error", err)
+		os.Exit(1)
+	}
 
-	// Setup routes with enhanced logging
-	log.Println("Setting up API routes...")
+	// gin reads GIN_MODE at package init, before a config file could set it
+	if cfg.Server.GinMode != "" {
+		gin.SetMode(cfg.Server.GinMode)
+	}
+
+	// Setup routes
 	r := api.SetupRoutes()
-	log.Println("API routes configured")
+	slog.Info("API routes configured")
+
+	// Background job workers start once every job type is registered
+	services.GetGlobalJobQueue().Start()
+
+	// Periodic tasks, including scheduled backups (BACKUP_SCHEDULE)
+	services.GetGlobalScheduler().Start()
 
-	// Start server with detailed logging
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8085"
+	// Failing readiness checks are posted to HEALTH_ALERT_WEBHOOK_URL
+	services.GetGlobalHealthService().Start()
+
+	// Critical caches are warmed in the background; /readyz waits for them
+	services.GetGlobalWarmup().Start()
+
+	// Start server
+	port := cfg.Server.Port
+
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server starting", "port", port, "gin_mode", cfg.Server.GinMode, "db_path", cfg.Database.Path)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		slog.Error("Failed to start server", "port", port, "error", err)
+		os.Exit(1)
+	case <-ctx.Done():
 	}
+	stop()
 
-	log.Printf("Server starting on port %s", port)
-	log.Printf("GIN_MODE: %s", os.Getenv("GIN_MODE"))
-	log.Printf("DB_PATH: %s", os.Getenv("DB_PATH"))
+	timeout := cfg.Server.ShutdownTimeout.Std()
+	slog.Info("Shutting down", "timeout", timeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server on port %s: %v", port, err)
+	// Stop accepting requests and let in-flight ones finish
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("HTTP server shutdown did not complete", "error", err)
 	}
+
+	// Drain the behavior queue, wait for embedding jobs and flush caches
+	if err := services.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Background shutdown did not complete", "error", err)
+	}
+
+	if err := cache.Close(); err != nil {
+		slog.Error("Failed to close cache", "error", err)
+	}
+
+	if err := database.Close(); err != nil {
+		slog.Error("Failed to close database", "error", err)
+	}
+
+	slog.Info("Server stopped")
 }
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// WebFinger resolves acct:username@host so fediverse servers can find the
+// blog's actor
+func WebFinger(c *gin.Context) {
+	resource := c.Query("resource")
+	if resource == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "resource is required"})
+		return
+	}
+	document, err := services.GetGlobalActivityPubService().WebFinger(getBaseURL(c), resource)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	writeActivityJSON(c, "application/jrd+json", document)
+}
+
+// GetActivityPubActor returns the blog's actor document
+func GetActivityPubActor(c *gin.Context) {
+	actor, err := services.GetGlobalActivityPubService().Actor(currentSiteID(c), getBaseURL(c))
+	if err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	writeActivityJSON(c, services.ActivityContentType, actor)
+}
+
+// GetActivityPubOutbox returns the outbox, or one page of it with ?page=N
+func GetActivityPubOutbox(c *gin.Context) {
+	var query struct {
+		Page int `form:"page" binding:"omitempty,min=1"`
+	}
+	if !bindQuery(c, &query) {
+		return
+	}
+	outbox, err := services.GetGlobalActivityPubService().Outbox(c.Request.Context(), currentSiteID(c), getBaseURL(c), query.Page)
+	if err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	writeActivityJSON(c, services.ActivityContentType, outbox)
+}
+
+// GetActivityPubFollowers returns the number of followers
+func GetActivityPubFollowers(c *gin.Context) {
+	followers, err := services.GetGlobalActivityPubService().Followers(c.Request.Context(), currentSiteID(c), getBaseURL(c))
+	if err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	writeActivityJSON(c, services.ActivityContentType, followers)
+}
+
+// GetActivityPubObject returns an article as an ActivityPub object
+func GetActivityPubObject(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+	object, err := services.GetGlobalActivityPubService().Object(c.Request.Context(), currentSiteID(c), getBaseURL(c), uint(id))
+	if err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	writeActivityJSON(c, services.ActivityContentType, object)
+}
+
+// PostActivityPubInbox receives signed activities from fediverse servers
+func PostActivityPubInbox(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, services.MaxActivitySize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read activity"})
+		return
+	}
+	if len(body) > services.MaxActivitySize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Activity too large"})
+		return
+	}
+	if err := services.GetGlobalActivityPubService().HandleInbox(c.Request.Context(), currentSiteID(c), getBaseURL(c), c.Request, body); err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// ListArticleFediverseReplies returns the approved fediverse replies to an
+// article
+func ListArticleFediverseReplies(c *gin.Context) {
+	articleID, err := strconv.ParseUint(c.Query("article_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+	replies, err := services.GetGlobalActivityPubService().ListReplies(c.Request.Context(), models.ReplyApproved, uint(articleID))
+	if err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"replies": replies})
+}
+
+// ListFediverseFollowers returns the blog's fediverse followers
+func ListFediverseFollowers(c *gin.Context) {
+	followers, err := services.GetGlobalActivityPubService().ListFollowers(c.Request.Context())
+	if err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"followers": followers})
+}
+
+// RemoveFediverseFollower stops sending articles to a follower
+func RemoveFediverseFollower(c *gin.Context) {
+	id, ok := activityPubID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalActivityPubService().RemoveFollower(c.Request.Context(), id); err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Follower removed"})
+}
+
+// ListFediverseReplies returns fediverse replies for moderation, filtered
+// by ?status= and ?article_id=
+func ListFediverseReplies(c *gin.Context) {
+	var query struct {
+		ArticleID uint `form:"article_id"`
+	}
+	if !bindQuery(c, &query) {
+		return
+	}
+	replies, err := services.GetGlobalActivityPubService().ListReplies(c.Request.Context(), c.Query("status"), query.ArticleID)
+	if err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"replies": replies})
+}
+
+// UpdateFediverseReply approves or rejects a fediverse reply
+func UpdateFediverseReply(c *gin.Context) {
+	id, ok := activityPubID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if !bindJSON(c, &input) {
+		return
+	}
+	reply, err := services.GetGlobalActivityPubService().SetReplyStatus(c.Request.Context(), id, input.Status)
+	if err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, reply)
+}
+
+// DeleteFediverseReply removes a fediverse reply
+func DeleteFediverseReply(c *gin.Context) {
+	id, ok := activityPubID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalActivityPubService().DeleteReply(c.Request.Context(), id); err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Reply deleted"})
+}
+
+func writeActivityJSON(c *gin.Context, contentType string, document interface{}) {
+	body, err := json.Marshal(document)
+	if err != nil {
+		respondActivityPubError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, contentType+"; charset=utf-8", body)
+}
+
+func activityPubID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondActivityPubError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, services.ErrFederatedReplyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case errors.Is(err, services.ErrInvalidSignature):
+		logging.FromGin(c).Info("Rejected ActivityPub request", "error", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidActivity):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("ActivityPub operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "ActivityPub operation failed"})
+	}
+}
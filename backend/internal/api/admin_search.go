@@ -0,0 +1,41 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminSearch searches articles in every language, media, categories,
+// settings, tracked keywords and comments at once, returning the hits
+// grouped by type. ?types= takes a comma-separated list of groups and
+// ?limit= the hits per group.
+func AdminSearch(c *gin.Context) {
+	var types []string
+	if list := c.Query("types"); list != "" {
+		for _, t := range strings.Split(list, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+	}
+	slice, ok := bindLimit(c, 0, 0)
+	if !ok {
+		return
+	}
+	results, err := services.GetGlobalAdminSearchService().Search(c.Request.Context(), c.Query("q"), types, slice.Limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAdminSearch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logging.FromGin(c).Error("Admin search failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
@@ -44,8 +44,7 @@ type TrackUsageRequest struct {
 // TrackUsage records AI service usage
 func (controller *AIUsageController) TrackUsage(c *gin.Context) {
 	var req TrackUsageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -259,8 +258,7 @@ type SetCostLimitsRequest struct {
 // SetCostLimits updates the cost limits
 func (controller *AIUsageController) SetCostLimits(c *gin.Context) {
 	var req SetCostLimitsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -2,9 +2,14 @@ package api
 
 import (
 	"blog-backend/internal/database"
+	"blog-backend/internal/logging"
 	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -20,6 +25,12 @@ type AnalyticsResponse struct {
 	GeographicStats []models.GeographicStats `json:"geographic_stats"`
 	BrowserStats    []models.BrowserStats    `json:"browser_stats"`
 	PlatformStats   []models.PlatformStats   `json:"platform_stats"`
+	// LanguageStats shows the language balance of the articles: how many
+	// can be read in each language and the most read of them
+	LanguageStats []services.LanguageStats `json:"language_stats"`
+	// Discussions is the engagement on the external comment system, when
+	// the site keeps one
+	Discussions *services.DiscussionStats `json:"discussions,omitempty"`
 }
 
 type ArticleViewStats struct {
@@ -35,6 +46,15 @@ type DailyViewStats struct {
 	Views int64  `json:"views"`
 }
 
+// siteArticleViewsSQL restricts raw SQL on article_views to the views of
+// one site's articles, as views carry no site of their own
+const siteArticleViewsSQL = "article_id IN (SELECT id FROM articles WHERE site_id = ?)"
+
+// siteArticleViews returns a query on the views of the site's articles
+func siteArticleViews(db *gorm.DB, siteID uint) *gorm.DB {
+	return db.Model(&models.ArticleView{}).Where(siteArticleViewsSQL, siteID)
+}
+
 type CategoryViewStats struct {
 	Category     string `json:"category"`
 	ViewCount    int64  `json:"view_count"`
@@ -51,29 +71,31 @@ func GetAnalytics(c *gin.Context) {
 	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	weekAgo := today.AddDate(0, 0, -7)
 	monthAgo := today.AddDate(0, -1, 0)
+	db := siteDB(c)
+	siteID := currentSiteID(c)
 
 	// Get total views and articles
 	var totalViews int64
-	database.DB.Model(&models.Article{}).Select("COALESCE(SUM(view_count), 0)").Scan(&totalViews)
+	db.Model(&models.Article{}).Select("COALESCE(SUM(view_count), 0)").Scan(&totalViews)
 
 	var totalArticles int64
-	database.DB.Model(&models.Article{}).Count(&totalArticles)
+	db.Model(&models.Article{}).Count(&totalArticles)
 
 	// Get views for different time periods
 	var viewsToday, viewsThisWeek, viewsThisMonth int64
 
 	// Views today
-	database.DB.Model(&models.ArticleView{}).
+	siteArticleViews(db, siteID).
 		Where("created_at >= ?", today).
 		Count(&viewsToday)
 
 	// Views this week
-	database.DB.Model(&models.ArticleView{}).
+	siteArticleViews(db, siteID).
 		Where("created_at >= ?", weekAgo).
 		Count(&viewsThisWeek)
 
 	// Views this month
-	database.DB.Model(&models.ArticleView{}).
+	siteArticleViews(db, siteID).
 		Where("created_at >= ?", monthAgo).
 		Count(&viewsThisMonth)
 
@@ -81,7 +103,7 @@ func GetAnalytics(c *gin.Context) {
 	var topArticles []ArticleViewStats
 	if lang == "zh" {
 		// Default language - use original data
-		database.DB.Model(&models.Article{}).
+		db.Model(&models.Article{}).
 			Select("articles.id, articles.title, articles.view_count, categories.name as category, articles.created_at").
 			Joins("LEFT JOIN categories ON articles.category_id = categories.id").
 			Where("articles.deleted_at IS NULL").
@@ -90,7 +112,7 @@ func GetAnalytics(c *gin.Context) {
 			Scan(&topArticles)
 	} else {
 		// Non-default language - use translations
-		database.DB.Raw(`
+		db.Raw(`
 			SELECT 
 				a.id,
 				COALESCE(at.title, a.title) as title,
@@ -101,23 +123,24 @@ func GetAnalytics(c *gin.Context) {
 			LEFT JOIN categories c ON a.category_id = c.id
 			LEFT JOIN article_translations at ON a.id = at.article_id AND at.language = ?
 			LEFT JOIN category_translations ct ON c.id = ct.category_id AND ct.language = ?
-			WHERE a.deleted_at IS NULL
+			WHERE a.deleted_at IS NULL AND a.site_id = ?
 			ORDER BY a.view_count DESC
 			LIMIT 10
-		`, lang, lang).Scan(&topArticles)
+		`, lang, lang, siteID).Scan(&topArticles)
 	}
 
 	// Get daily view stats for the last 30 days
 	var recentViews []DailyViewStats
 	thirtyDaysAgo := today.AddDate(0, 0, -30)
 
-	rows, err := database.DB.Raw(`
-		SELECT DATE(created_at) as date, COUNT(*) as views 
+	dateExpr := database.DateExpr("created_at")
+	rows, err := db.Raw(`
+		SELECT `+dateExpr+` as date, COUNT(*) as views 
 		FROM article_views 
-		WHERE created_at >= ? 
-		GROUP BY DATE(created_at) 
+		WHERE created_at >= ? AND `+siteArticleViewsSQL+`
+		GROUP BY `+dateExpr+` 
 		ORDER BY date DESC
-	`, thirtyDaysAgo).Rows()
+	`, thirtyDaysAgo, siteID).Rows()
 
 	if err == nil {
 		defer rows.Close()
@@ -133,20 +156,20 @@ func GetAnalytics(c *gin.Context) {
 	var categoryStats []CategoryViewStats
 	if lang == "zh" {
 		// Default language - use original data
-		database.DB.Raw(`
+		db.Raw(`
 			SELECT 
 				c.name as category,
 				COALESCE(SUM(a.view_count), 0) as view_count,
 				COUNT(a.id) as article_count
 			FROM categories c
 			LEFT JOIN articles a ON c.id = a.category_id AND a.deleted_at IS NULL
-			WHERE c.deleted_at IS NULL
+			WHERE c.deleted_at IS NULL AND c.site_id = ?
 			GROUP BY c.id, c.name
 			ORDER BY view_count DESC
-		`).Scan(&categoryStats)
+		`, siteID).Scan(&categoryStats)
 	} else {
 		// Non-default language - use translations
-		database.DB.Raw(`
+		db.Raw(`
 			SELECT 
 				COALESCE(ct.name, c.name) as category,
 				COALESCE(SUM(a.view_count), 0) as view_count,
@@ -154,15 +177,15 @@ func GetAnalytics(c *gin.Context) {
 			FROM categories c
 			LEFT JOIN articles a ON c.id = a.category_id AND a.deleted_at IS NULL
 			LEFT JOIN category_translations ct ON c.id = ct.category_id AND ct.language = ?
-			WHERE c.deleted_at IS NULL
+			WHERE c.deleted_at IS NULL AND c.site_id = ?
 			GROUP BY c.id, c.name, ct.name
 			ORDER BY view_count DESC
-		`, lang).Scan(&categoryStats)
+		`, lang, siteID).Scan(&categoryStats)
 	}
 
 	// Get geographic statistics
 	var geographicStats []models.GeographicStats
-	database.DB.Raw(`
+	db.Raw(`
 		SELECT 
 			country,
 			region,
@@ -170,30 +193,30 @@ func GetAnalytics(c *gin.Context) {
 			COUNT(DISTINCT fingerprint) as visitor_count,
 			COUNT(*) as view_count
 		FROM article_views 
-		WHERE country != '' AND country != 'Unknown'
+		WHERE country != '' AND country != 'Unknown' AND `+siteArticleViewsSQL+`
 		GROUP BY country, region, city
 		ORDER BY view_count DESC
 		LIMIT 20
-	`).Scan(&geographicStats)
+	`, siteID).Scan(&geographicStats)
 
 	// Get browser statistics
 	var browserStats []models.BrowserStats
-	database.DB.Raw(`
+	db.Raw(`
 		SELECT 
 			browser,
 			browser_version,
 			COUNT(DISTINCT fingerprint) as visitor_count,
 			COUNT(*) as view_count
 		FROM article_views 
-		WHERE browser != '' AND browser != 'Unknown'
+		WHERE browser != '' AND browser != 'Unknown' AND `+siteArticleViewsSQL+`
 		GROUP BY browser, browser_version
 		ORDER BY view_count DESC
 		LIMIT 15
-	`).Scan(&browserStats)
+	`, siteID).Scan(&browserStats)
 
 	// Get platform statistics
 	var platformStats []models.PlatformStats
-	database.DB.Raw(`
+	db.Raw(`
 		SELECT 
 			os,
 			os_version,
@@ -202,11 +225,25 @@ func GetAnalytics(c *gin.Context) {
 			COUNT(DISTINCT fingerprint) as visitor_count,
 			COUNT(*) as view_count
 		FROM article_views 
-		WHERE os != '' AND os != 'Unknown'
+		WHERE os != '' AND os != 'Unknown' AND `+siteArticleViewsSQL+`
 		GROUP BY os, os_version, platform, device_type
 		ORDER BY view_count DESC
 		LIMIT 15
-	`).Scan(&platformStats)
+	`, siteID).Scan(&platformStats)
+
+	// Get articles by language, with the five most read in each
+	languageStats, err := services.GetLanguageStats(db, 5)
+	if err != nil {
+		logging.FromGin(c).Warn("Failed to count articles by language", "error", err)
+		languageStats = []services.LanguageStats{}
+	}
+
+	// Get the comments on the external comment system, with the ten most
+	// discussed articles
+	discussions, err := services.GetGlobalCommentBridgeService().Stats(c.Request.Context(), 10)
+	if err != nil {
+		logging.FromGin(c).Warn("Failed to sum up external comments", "error", err)
+	}
 
 	response := AnalyticsResponse{
 		TotalViews:      totalViews,
@@ -220,6 +257,8 @@ func GetAnalytics(c *gin.Context) {
 		GeographicStats: geographicStats,
 		BrowserStats:    browserStats,
 		PlatformStats:   platformStats,
+		LanguageStats:   languageStats,
+		Discussions:     discussions,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -228,9 +267,10 @@ func GetAnalytics(c *gin.Context) {
 // GetGeographicAnalytics returns detailed geographic statistics
 func GetGeographicAnalytics(c *gin.Context) {
 	var stats []models.GeographicStats
+	db := siteDB(c)
 
 	// Get geographic distribution with more details
-	database.DB.Raw(`
+	db.Raw(`
 		SELECT 
 			country,
 			region,
@@ -238,11 +278,11 @@ func GetGeographicAnalytics(c *gin.Context) {
 			COUNT(DISTINCT fingerprint) as visitor_count,
 			COUNT(*) as view_count
 		FROM article_views 
-		WHERE country != '' AND country != 'Unknown' AND country != 'Local'
+		WHERE country != '' AND country != 'Unknown' AND country != 'Local' AND `+siteArticleViewsSQL+`
 		GROUP BY country, region, city
 		ORDER BY view_count DESC
 		LIMIT 50
-	`).Scan(&stats)
+	`, currentSiteID(c)).Scan(&stats)
 
 	c.JSON(http.StatusOK, gin.H{
 		"geographic_stats": stats,
@@ -253,23 +293,25 @@ func GetGeographicAnalytics(c *gin.Context) {
 func GetBrowserAnalytics(c *gin.Context) {
 	var browserStats []models.BrowserStats
 	var platformStats []models.PlatformStats
+	db := siteDB(c)
+	siteID := currentSiteID(c)
 
 	// Get browser statistics
-	database.DB.Raw(`
+	db.Raw(`
 		SELECT 
 			browser,
 			browser_version,
 			COUNT(DISTINCT fingerprint) as visitor_count,
 			COUNT(*) as view_count
 		FROM article_views 
-		WHERE browser != '' AND browser != 'Unknown'
+		WHERE browser != '' AND browser != 'Unknown' AND `+siteArticleViewsSQL+`
 		GROUP BY browser, browser_version
 		ORDER BY view_count DESC
 		LIMIT 30
-	`).Scan(&browserStats)
+	`, siteID).Scan(&browserStats)
 
 	// Get platform/device statistics
-	database.DB.Raw(`
+	db.Raw(`
 		SELECT 
 			os,
 			os_version,
@@ -278,11 +320,11 @@ func GetBrowserAnalytics(c *gin.Context) {
 			COUNT(DISTINCT fingerprint) as visitor_count,
 			COUNT(*) as view_count
 		FROM article_views 
-		WHERE os != '' AND os != 'Unknown'
+		WHERE os != '' AND os != 'Unknown' AND `+siteArticleViewsSQL+`
 		GROUP BY os, os_version, platform, device_type
 		ORDER BY view_count DESC
 		LIMIT 30
-	`).Scan(&platformStats)
+	`, siteID).Scan(&platformStats)
 
 	c.JSON(http.StatusOK, gin.H{
 		"browser_stats":  browserStats,
@@ -292,7 +334,11 @@ func GetBrowserAnalytics(c *gin.Context) {
 
 // GetTrendAnalytics returns time-based analytics with multiple metrics
 func GetTrendAnalytics(c *gin.Context) {
-	days := c.DefaultQuery("days", "30")
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	since := time.Now().AddDate(0, 0, -days)
 
 	// Get daily trends for the specified period
 	var trends []struct {
@@ -304,19 +350,20 @@ func GetTrendAnalytics(c *gin.Context) {
 		TabletVisitors  int64  `json:"tablet_visitors"`
 	}
 
-	database.DB.Raw(`
+	dateExpr := database.DateExpr("created_at")
+	siteDB(c).Raw(`
 		SELECT 
-			DATE(created_at) as date,
+			`+dateExpr+` as date,
 			COUNT(*) as views,
 			COUNT(DISTINCT fingerprint) as unique_visitors,
 			COUNT(CASE WHEN device_type = 'desktop' THEN 1 END) as desktop_visitors,
 			COUNT(CASE WHEN device_type = 'mobile' THEN 1 END) as mobile_visitors,
 			COUNT(CASE WHEN device_type = 'tablet' THEN 1 END) as tablet_visitors
 		FROM article_views 
-		WHERE created_at >= DATE('now', '-' || ? || ' days')
-		GROUP BY DATE(created_at) 
+		WHERE created_at >= ? AND `+siteArticleViewsSQL+`
+		GROUP BY `+dateExpr+` 
 		ORDER BY date DESC
-	`, days).Scan(&trends)
+	`, since, currentSiteID(c)).Scan(&trends)
 
 	c.JSON(http.StatusOK, gin.H{
 		"trends": trends,
@@ -331,34 +378,36 @@ func GetArticleAnalytics(c *gin.Context) {
 	}
 
 	// Get article basic info with language support
+	db := siteDB(c)
 	var article models.Article
-	if err := database.DB.Preload("Category").Preload("Translations").First(&article, articleID).Error; err != nil {
+	if err := db.Preload("Category").Preload("Translations").First(&article, articleID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
 		return
 	}
 
 	// Apply translation if needed
 	if lang != "zh" && lang != "" {
-		applyTranslation(&article, lang)
+		services.ApplyTranslation(&article, lang)
 	}
 
 	// Get unique visitors count
 	var uniqueVisitors int64
-	database.DB.Model(&models.ArticleView{}).
-		Where("article_id = ?", articleID).
+	db.Model(&models.ArticleView{}).
+		Where("article_id = ?", article.ID).
 		Count(&uniqueVisitors)
 
 	// Get daily views for this article in the last 30 days
 	var dailyViews []DailyViewStats
 	thirtyDaysAgo := time.Now().AddDate(0, 0, -30)
 
-	rows, err := database.DB.Raw(`
-		SELECT DATE(created_at) as date, COUNT(*) as views 
+	dateExpr := database.DateExpr("created_at")
+	rows, err := db.Raw(`
+		SELECT `+dateExpr+` as date, COUNT(*) as views 
 		FROM article_views 
 		WHERE article_id = ? AND created_at >= ?
-		GROUP BY DATE(created_at) 
+		GROUP BY `+dateExpr+` 
 		ORDER BY date DESC
-	`, articleID, thirtyDaysAgo).Rows()
+	`, article.ID, thirtyDaysAgo).Rows()
 
 	if err == nil {
 		defer rows.Close()
@@ -377,20 +426,87 @@ func GetArticleAnalytics(c *gin.Context) {
 		CreatedAt time.Time `json:"created_at"`
 	}
 
-	database.DB.Model(&models.ArticleView{}).
+	db.Model(&models.ArticleView{}).
 		Select("ip_address, user_agent, created_at").
-		Where("article_id = ?", articleID).
+		Where("article_id = ?", article.ID).
 		Order("created_at DESC").
 		Limit(10).
 		Scan(&recentVisitors)
 
+	shares, err := services.GetGlobalShareCountService().Shares(c.Request.Context(), article.ID)
+	if err != nil {
+		logging.FromGin(c).Error("Failed to read article share counts", "error", err)
+		shares = &services.ArticleShares{Platforms: []services.PlatformShares{}}
+	}
+	discussion, err := services.GetGlobalCommentBridgeService().Discussion(c.Request.Context(), article.ID)
+	if err != nil {
+		logging.FromGin(c).Error("Failed to read the article's external discussion", "error", err)
+	}
+
 	response := gin.H{
 		"article":         article,
 		"unique_visitors": uniqueVisitors,
 		"total_views":     article.ViewCount,
 		"daily_views":     dailyViews,
 		"recent_visitors": recentVisitors,
+		"share_counts":    shares,
+		"discussion":      discussion,
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+// GetArticleViews returns an article's counted views and the number of
+// distinct visitors behind them
+func GetArticleViews(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+	counts, err := services.GetGlobalArticleViewService().Get(c.Request.Context(), uint(id), isAdminRequest(c))
+	if errors.Is(err, services.ErrArticleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+	if err != nil {
+		logging.FromGin(c).Error("Failed to count article views", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count article views"})
+		return
+	}
+	c.JSON(http.StatusOK, counts)
+}
+
+// RefreshArticleShareCounts queues a collection of an article's share
+// counts from the configured platforms now
+func RefreshArticleShareCounts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+	job, err := services.GetGlobalShareCountService().Queue(c.Request.Context(), currentSiteID(c), uint(id))
+	switch {
+	case errors.Is(err, services.ErrArticleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+	case errors.Is(err, services.ErrShareCountsNotConfigured):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		logging.FromGin(c).Error("Failed to queue share count collection", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue share count collection"})
+	default:
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// RecountArticleViews rebuilds the view counts of articles from the
+// recorded views
+func RecountArticleViews(c *gin.Context) {
+	updated, err := services.GetGlobalArticleViewService().Recount(c.Request.Context())
+	if err != nil {
+		logging.FromGin(c).Error("Failed to recount article views", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recount article views"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListCurrentAnnouncements returns the announcements to show now, in the
+// language of ?lang= where translated
+func ListCurrentAnnouncements(c *gin.Context) {
+	announcements, err := services.GetGlobalAnnouncementService().Current(c.Request.Context())
+	if err != nil {
+		respondAnnouncementError(c, err)
+		return
+	}
+	lang := c.Query("lang")
+	for i := range announcements {
+		if lang != "" {
+			services.ApplyAnnouncementTranslation(&announcements[i], lang)
+		}
+		announcements[i].Translations = nil
+	}
+	c.JSON(http.StatusOK, gin.H{"announcements": announcements})
+}
+
+// ListAllAnnouncements returns every announcement, including scheduled,
+// expired and inactive ones, with all its translations
+func ListAllAnnouncements(c *gin.Context) {
+	announcements, err := services.GetGlobalAnnouncementService().List(c.Request.Context())
+	if err != nil {
+		respondAnnouncementError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"announcements": announcements})
+}
+
+// CreateAnnouncement adds an announcement
+func CreateAnnouncement(c *gin.Context) {
+	var input services.AnnouncementInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	announcement, err := services.GetGlobalAnnouncementService().Create(c.Request.Context(), input)
+	if err != nil {
+		respondAnnouncementError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, announcement)
+}
+
+// UpdateAnnouncement edits an announcement and, when the body has them,
+// replaces its translations
+func UpdateAnnouncement(c *gin.Context) {
+	id, ok := announcementID(c)
+	if !ok {
+		return
+	}
+	var input services.AnnouncementInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	announcement, err := services.GetGlobalAnnouncementService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondAnnouncementError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, announcement)
+}
+
+// DeleteAnnouncement removes an announcement
+func DeleteAnnouncement(c *gin.Context) {
+	id, ok := announcementID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalAnnouncementService().Delete(c.Request.Context(), id); err != nil {
+		respondAnnouncementError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Announcement deleted"})
+}
+
+func announcementID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondAnnouncementError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAnnouncementNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidAnnouncement):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Announcement operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Announcement operation failed"})
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries the key of a third-party app reading the public API
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyMiddleware counts requests made with an API key against the key's
+// hourly limit, answering 401 for unknown or revoked keys and 429 once the
+// limit is reached. Keys are read-only: they cannot be used to write.
+// Requests without a key pass through as anonymous.
+func APIKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader(APIKeyHeader)
+		if secret == "" {
+			c.Next()
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API keys are read-only"})
+			return
+		}
+		allowance, err := services.GetGlobalAPIKeyService().Authorize(c.Request.Context(), secret)
+		if allowance != nil {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(allowance.Limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(allowance.Remaining))
+			c.Header("X-RateLimit-Reset", strconv.FormatInt(allowance.Reset.Unix(), 10))
+		}
+		switch {
+		case err == nil:
+			c.Set("apiKeyID", allowance.Key.ID)
+			c.Next()
+		case errors.Is(err, services.ErrAPIKeyRateLimited):
+			retry := int(time.Until(allowance.Reset).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retry))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrAPIKeyRejected):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			logging.FromGin(c).Error("API key check failed", "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "API key check failed"})
+		}
+	}
+}
+
+// ListAPIKeys returns the site's API keys with their recent usage
+func ListAPIKeys(c *gin.Context) {
+	keys, err := services.GetGlobalAPIKeyService().List(c.Request.Context())
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// CreateAPIKey issues an API key. The response holds its secret, which is
+// not shown again.
+func CreateAPIKey(c *gin.Context) {
+	var input services.APIKeyInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	key, secret, err := services.GetGlobalAPIKeyService().Create(c.Request.Context(), input)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"api_key": key, "key": secret})
+}
+
+// UpdateAPIKey renames an API key or changes its rate limit
+func UpdateAPIKey(c *gin.Context) {
+	id, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+	var input services.APIKeyInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	key, err := services.GetGlobalAPIKeyService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+// RevokeAPIKey stops an API key from working
+func RevokeAPIKey(c *gin.Context) {
+	id, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+	key, err := services.GetGlobalAPIKeyService().Revoke(c.Request.Context(), id)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+// GetAPIKeyUsage returns the daily requests made with an API key over the
+// last ?days= (30 by default)
+func GetAPIKeyUsage(c *gin.Context) {
+	id, ok := apiKeyID(c)
+	if !ok {
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days"})
+		return
+	}
+	usage, err := services.GetGlobalAPIKeyService().Usage(c.Request.Context(), id, days)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"usage": usage})
+}
+
+func apiKeyID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidAPIKey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("API key operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "API key operation failed"})
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetArchive returns the visible articles grouped by year and month, with
+// titles and slugs in ?lang= where translated. ?year= limits it to one year.
+func GetArchive(c *gin.Context) {
+	year := 0
+	if value := c.Query("year"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1970 || parsed > 9999 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year"})
+			return
+		}
+		year = parsed
+	}
+	archive, err := services.GetGlobalArchiveService().Get(c.Request.Context(), c.Query("lang"), getArticleDefaultLanguage(c), year)
+	if err != nil {
+		logging.FromGin(c).Error("Failed to build archive", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build archive"})
+		return
+	}
+	c.JSON(http.StatusOK, withholdRegionalArchive(c, archive))
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rendered article content, cleared whenever articles change
+var articleHTMLCache = cache.New("article_html", time.Hour)
+
+func init() {
+	cache.Subscribe(cache.TopicArticles, articleHTMLCache.Clear)
+}
+
+// GetArticleHTML returns an article's content rendered as HTML, with code
+// highlighted in inline styles, math as MathML and diagrams as images, for
+// pages and emails built without the site's scripts and stylesheets. ?lang=
+// picks the translation.
+func GetArticleHTML(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+	var article models.Article
+	if err := siteDB(c).Preload("Translations").First(&article, id).Error; err != nil ||
+		(!isAdminRequest(c) && article.CreatedAt.After(time.Now())) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+	if respondRegionWithheld(c, &article) {
+		return
+	}
+	// Without a translation in the language asked for, the original is served
+	language := article.DefaultLang
+	if requested := c.Query("lang"); requested != "" {
+		for _, translation := range article.Translations {
+			if translation.Language == requested && translation.Content != "" {
+				language = requested
+			}
+		}
+	}
+	services.ApplyTranslation(&article, language)
+	lockMembersOnlyArticle(c, &article)
+
+	key := fmt.Sprintf("%d:%d:%s", currentSiteID(c), article.ID, language)
+	if article.Locked {
+		key += ":teaser"
+	}
+	var content string
+	if !articleHTMLCache.Get(key, &content) {
+		content = articleHTML(c.Request.Context(), article)
+		articleHTMLCache.Set(key, content)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":       article.ID,
+		"title":    article.Title,
+		"language": language,
+		"dir":      models.LanguageDirection(language),
+		"html":     content,
+	})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCachedPDFSize is the largest rendered PDF kept in the cache; larger
+// ones, heavy with images, are rendered again when asked for
+const maxCachedPDFSize = 4 << 20
+
+// Rendered article PDFs, cleared whenever articles change
+var articlePDFCache = cache.New("article_pdf", time.Hour)
+
+func init() {
+	cache.Subscribe(cache.TopicArticles, articlePDFCache.Clear)
+}
+
+// ExportArticlePDF serves an article as a paginated PDF document, with its
+// code highlighted and images embedded, for offline reading. ?lang= picks
+// the translation.
+func ExportArticlePDF(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+	var article models.Article
+	if err := siteDB(c).Preload("Category").Preload("Translations").First(&article, id).Error; err != nil ||
+		(!isAdminRequest(c) && article.CreatedAt.After(time.Now())) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+	if respondRegionWithheld(c, &article) {
+		return
+	}
+	// Without a translation in the language asked for, the original is served
+	language := article.DefaultLang
+	if requested := c.Query("lang"); requested != "" {
+		for _, translation := range article.Translations {
+			if translation.Language == requested && translation.Content != "" {
+				language = requested
+			}
+		}
+	}
+	services.ApplyTranslation(&article, language)
+	lockMembersOnlyArticle(c, &article)
+
+	key := fmt.Sprintf("%d:%d:%s", currentSiteID(c), article.ID, language)
+	if article.Locked {
+		key += ":teaser"
+	}
+	var document []byte
+	if !articlePDFCache.Get(key, &document) {
+		var settings models.SiteSettings
+		siteDB(c).Limit(1).Find(&settings)
+		var buf bytes.Buffer
+		err := services.GetGlobalArticlePDFRenderer().Render(c.Request.Context(), &buf, article, language, services.ArticlePDFOptions{
+			BaseURL:   getBaseURL(c),
+			UploadDir: UploadDir,
+			SiteTitle: settings.SiteTitle,
+		})
+		if err != nil {
+			logging.FromGin(c).Error("Failed to render article PDF", "article_id", article.ID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render article"})
+			return
+		}
+		document = buf.Bytes()
+		if len(document) <= maxCachedPDFSize {
+			articlePDFCache.Set(key, document)
+		}
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.pdf\"", services.SafeFilename(article.Title)))
+	c.Data(http.StatusOK, "application/pdf", document)
+}
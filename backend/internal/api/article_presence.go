@@ -0,0 +1,84 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// articlePresenceInput is the heartbeat of a tab with an article open
+type articlePresenceInput struct {
+	SessionID string `json:"session_id" binding:"required"`
+	Mode      string `json:"mode" binding:"required,oneof=viewing editing"`
+}
+
+// GetArticlePresence returns who has an article open in the admin, leaving
+// out the caller's own tab given as ?session_id=
+func GetArticlePresence(c *gin.Context) {
+	articleID, ok := presenceArticleID(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, services.GetGlobalArticlePresenceService().State(currentSiteID(c), articleID, c.Query("session_id")))
+}
+
+// UpdateArticlePresence records that the caller's tab has an article open
+// ({"session_id": "...", "mode": "editing"}) and returns who else does.
+// Tabs send it every heartbeat_seconds while the article stays open.
+func UpdateArticlePresence(c *gin.Context) {
+	articleID, ok := presenceArticleID(c)
+	if !ok {
+		return
+	}
+	var input articlePresenceInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	state, err := services.GetGlobalArticlePresenceService().Heartbeat(c.Request.Context(), currentSiteID(c), articleID, services.ArticlePresence{
+		SessionID: input.SessionID,
+		UserID:    c.GetUint("userID"),
+		Username:  c.GetString("username"),
+		Mode:      input.Mode,
+	})
+	if err != nil {
+		respondArticlePresenceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
+
+// LeaveArticlePresence removes the caller's tab, given as ?session_id=,
+// from an article as it is closed
+func LeaveArticlePresence(c *gin.Context) {
+	articleID, ok := presenceArticleID(c)
+	if !ok {
+		return
+	}
+	services.GetGlobalArticlePresenceService().Leave(currentSiteID(c), articleID, c.GetUint("userID"), c.Query("session_id"))
+	c.JSON(http.StatusOK, gin.H{"message": "Presence removed"})
+}
+
+func presenceArticleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondArticlePresenceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrArticleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPresence):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Article presence operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Article presence operation failed"})
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateArticlePreviewLink signs a link that shows a scheduled article to
+// whoever holds it, so it can be shared for review before it is published.
+// expires_in_hours sets how long the link works, 72 hours unless given and
+// at most 30 days.
+func CreateArticlePreviewLink(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+	var input struct {
+		ExpiresInHours int `json:"expires_in_hours" binding:"omitempty,min=1"`
+	}
+	if c.Request.ContentLength != 0 && !bindJSON(c, &input) {
+		return
+	}
+	ttl := services.DefaultPreviewTTL
+	if input.ExpiresInHours > 0 {
+		ttl = time.Duration(input.ExpiresInHours) * time.Hour
+	}
+	if ttl > services.MaxPreviewTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Preview links can work for at most 30 days"})
+		return
+	}
+
+	var article models.Article
+	if err := siteDB(c).Limit(1).Find(&article, id).Error; err != nil || article.ID == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	token, err := services.GetGlobalArticlePreviewService().Token(currentSiteID(c), article.ID, expires)
+	if err != nil {
+		logging.FromGin(c).Error("Failed to sign a preview link", "article_id", article.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the preview link"})
+		return
+	}
+	link := fmt.Sprintf("%s/%s/article/%d?%s", getBaseURL(c), article.DefaultLang, article.ID, url.Values{"preview": {token}}.Encode())
+	c.JSON(http.StatusCreated, gin.H{
+		"url":        link,
+		"token":      token,
+		"expires_at": expires,
+	})
+}
+
+// scheduledHidden tells whether an article scheduled for later is kept from
+// the request. Admins see it, as does whoever passes a valid preview token
+// in ?preview=; their responses are kept out of shared caches and search
+// engines.
+func scheduledHidden(c *gin.Context, article *models.Article) bool {
+	if !article.CreatedAt.After(time.Now()) || isAdminRequest(c) {
+		return false
+	}
+	token := c.Query("preview")
+	if token == "" {
+		return true
+	}
+	err := services.GetGlobalArticlePreviewService().Verify(currentSiteID(c), article.ID, token)
+	if err != nil {
+		if !errors.Is(err, services.ErrInvalidPreviewToken) && !errors.Is(err, services.ErrPreviewTokenExpired) {
+			logging.FromGin(c).Error("Failed to check a preview token", "article_id", article.ID, "error", err)
+		}
+		return true
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("X-Robots-Tag", "noindex")
+	return false
+}
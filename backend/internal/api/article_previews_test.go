@@ -0,0 +1,79 @@
+package api
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestScheduledArticlesNeedAPreviewToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "blog.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+
+	previews := services.GetGlobalArticlePreviewService()
+	scheduled := models.Article{ID: 7, CreatedAt: time.Now().Add(24 * time.Hour)}
+	valid, err := previews.Token(models.DefaultSiteID, scheduled.ID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := previews.Token(models.DefaultSiteID, scheduled.ID, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherArticle, err := previews.Token(models.DefaultSiteID, scheduled.ID+1, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiry, signature, _ := strings.Cut(valid, ".")
+	_, otherSignature, _ := strings.Cut(otherArticle, ".")
+
+	hidden := func(token string) (bool, http.Header) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/articles/7?"+url.Values{"preview": {token}}.Encode(), nil)
+		return scheduledHidden(c, &scheduled), w.Header()
+	}
+
+	if isHidden, header := hidden(valid); isHidden || header.Get("Cache-Control") != "private, no-store" {
+		t.Errorf("valid token: hidden %v, Cache-Control %q", isHidden, header.Get("Cache-Control"))
+	}
+	for name, token := range map[string]string{
+		"none":               "",
+		"expired":            expired,
+		"another article's":  otherArticle,
+		"tampered signature": expiry + "." + otherSignature,
+		"tampered expiry":    "9999999999." + signature,
+	} {
+		if isHidden, _ := hidden(token); !isHidden {
+			t.Errorf("%s token: the scheduled article was shown", name)
+		}
+	}
+
+	published := models.Article{ID: 8, CreatedAt: time.Now().Add(-time.Hour)}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/articles/8", nil)
+	if scheduledHidden(c, &published) {
+		t.Error("a published article was hidden")
+	}
+}
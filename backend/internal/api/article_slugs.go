@@ -0,0 +1,51 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redirectToArticleSlug sends a request for a slug the article no longer
+// uses to its current slug in the requested language
+func redirectToArticleSlug(c *gin.Context, article *models.Article) {
+	target := path.Join(path.Dir(c.Request.URL.Path), url.PathEscape(services.ArticleSlug(article, c.Query("lang"))))
+	if c.Request.URL.RawQuery != "" {
+		target += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusMovedPermanently, target)
+}
+
+// checkArticleSlug responds with a conflict and returns false when another
+// article answers to slug in language; an empty language is the article's
+// own slug
+func checkArticleSlug(c *gin.Context, articleID uint, language, slug string) bool {
+	err := services.CheckArticleSlug(siteDB(c), articleID, language, slug)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, services.ErrSlugInUse) {
+		response := gin.H{"error": err.Error()}
+		if language != "" {
+			response["language"] = language
+		}
+		c.JSON(http.StatusConflict, response)
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	return false
+}
+
+// recordSlugChange keeps the old slug of an article redirecting to its new
+// one. A failure only loses the redirect, so the save still succeeds.
+func recordSlugChange(c *gin.Context, article *models.Article, language, oldSlug, newSlug string) {
+	if err := services.RecordSlugChange(siteDB(c), article, language, oldSlug, newSlug); err != nil {
+		logging.FromGin(c).Warn("Failed to record the old article slug", "article_id", article.ID, "slug", oldSlug, "error", err)
+	}
+}
@@ -1,23 +1,29 @@
 package api
 
 import (
+	"blog-backend/internal/cache"
 	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/logging"
 	"blog-backend/internal/models"
 	"blog-backend/internal/search"
+	"blog-backend/internal/security"
 	"blog-backend/internal/services"
-	"crypto/sha256"
-	"fmt"
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 )
 
 // Helper function to get the site's default language
-func getArticleDefaultLanguage() string {
+func getArticleDefaultLanguage(c *gin.Context) string {
 	var settings models.SiteSettings
-	if err := database.DB.First(&settings).Error; err != nil {
+	if err := siteDB(c).First(&settings).Error; err != nil {
 		// Fallback to 'zh' if unable to get settings
 		return "zh"
 	}
@@ -30,10 +36,25 @@ func getArticleDefaultLanguage() string {
 func GetArticles(c *gin.Context) {
 	var articles []models.Article
 
-	query := database.DB.Preload("Category").Preload("Translations")
+	query := siteDB(c).Preload("Category").Preload("Translations")
 
 	if categoryID := c.Query("category_id"); categoryID != "" {
-		query = query.Where("category_id = ?", categoryID)
+		// With include_subcategories=true, articles filed under the
+		// categories below it are listed too
+		id, err := strconv.ParseUint(categoryID, 10, 32)
+		if c.Query("include_subcategories") == "true" && err == nil {
+			ids, err := services.GetGlobalCategoryService().Descendants(c.Request.Context(), uint(id))
+			if err != nil && !errors.Is(err, services.ErrCategoryNotFound) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if len(ids) == 0 {
+				ids = []uint{uint(id)}
+			}
+			query = query.Where("category_id IN ?", ids)
+		} else {
+			query = query.Where("category_id = ?", categoryID)
+		}
 	}
 
 	// Filter future articles for non-admin requests
@@ -48,10 +69,29 @@ func GetArticles(c *gin.Context) {
 
 	// Apply language filtering if requested
 	lang := c.Query("lang")
-	defaultLang := getArticleDefaultLanguage()
+	defaultLang := getArticleDefaultLanguage(c)
 	if lang != "" && lang != defaultLang {
 		for i := range articles {
-			applyTranslation(&articles[i], lang)
+			services.ApplyTranslation(&articles[i], lang)
+			applyCategoryTranslation(&articles[i].Category, lang)
+		}
+	}
+
+	if err := services.GetGlobalAuthorService().Attribute(c.Request.Context(), articles); err != nil {
+		logging.FromGin(c).Warn("Failed to credit article authors", "error", err)
+	}
+	if err := services.GetGlobalCommentBridgeService().Attach(c.Request.Context(), articles); err != nil {
+		logging.FromGin(c).Warn("Failed to read article comment counts", "error", err)
+	}
+	if err := services.ApplyLicenses(siteDB(c), articles); err != nil {
+		logging.FromGin(c).Warn("Failed to look up the default license", "error", err)
+	}
+	articles = withholdRegional(c, articles)
+	lockMembersOnly(c, articles)
+
+	if security.SanitizeOnRender() {
+		for i := range articles {
+			sanitizeArticleForRender(&articles[i])
 		}
 	}
 
@@ -62,43 +102,93 @@ func GetArticle(c *gin.Context) {
 	idParam := c.Param("id")
 
 	var article models.Article
+	moved := false
 
 	// Try numeric ID first, fall back to seo_slug lookup
 	if id, err := strconv.Atoi(idParam); err == nil {
-		if err := database.DB.Preload("Category").Preload("Translations").First(&article, id).Error; err != nil {
+		if err := siteDB(c).Preload("Category").Preload("Translations").First(&article, id).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
 			return
 		}
 	} else {
-		if err := database.DB.Preload("Category").Preload("Translations").Where("seo_slug = ?", idParam).First(&article).Error; err != nil {
+		// Slugs may be translated and old ones redirect to the current one
+		articleID, slugMoved, err := services.ResolveArticleSlug(siteDB(c), idParam, c.Query("lang"))
+		if err != nil || articleID == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+			return
+		}
+		if err := siteDB(c).Preload("Category").Preload("Translations").First(&article, articleID).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
 			return
 		}
+		moved = slugMoved
 	}
 
-	// Check if article is scheduled for future publication and request is not from admin
-	if !isAdminRequest(c) && article.CreatedAt.After(time.Now()) {
+	// Scheduled articles are only shown to admins and preview links
+	if scheduledHidden(c, &article) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
 		return
 	}
 
-	// Track unique visitor if not an admin request and IP fingerprint is provided
-	if !isAdminRequest(c) {
-		go trackArticleView(article.ID, c)
+	if respondRegionWithheld(c, &article) {
+		return
+	}
+
+	if moved {
+		redirectToArticleSlug(c, &article)
+		return
+	}
+
+	// Track the visitor; whether the view counts is up to the site settings
+	if !article.CreatedAt.After(time.Now()) {
+		trackArticleView(article.ID, c)
 	}
 
 	// Clean up any invalid translations for default language (data consistency fix)
 	if article.DefaultLang != "" {
-		database.DB.Where("article_id = ? AND language = ?", article.ID, article.DefaultLang).Delete(&models.ArticleTranslation{})
+		siteDB(c).Where("article_id = ? AND language = ?", article.ID, article.DefaultLang).Delete(&models.ArticleTranslation{})
 		// Reload translations after cleanup
-		database.DB.Preload("Translations").First(&article, article.ID)
+		siteDB(c).Preload("Translations").First(&article, article.ID)
 	}
 
 	// Apply language filtering if requested
 	lang := c.Query("lang")
-	defaultLang := getArticleDefaultLanguage()
+	defaultLang := getArticleDefaultLanguage(c)
 	if lang != "" && lang != defaultLang {
-		applyTranslation(&article, lang)
+		services.ApplyTranslation(&article, lang)
+		applyCategoryTranslation(&article.Category, lang)
+	}
+
+	credited := []models.Article{article}
+	if err := services.GetGlobalAuthorService().Attribute(c.Request.Context(), credited); err != nil {
+		logging.FromGin(c).Warn("Failed to credit the article's author", "article_id", article.ID, "error", err)
+	}
+	if err := services.GetGlobalCommentBridgeService().Attach(c.Request.Context(), credited); err != nil {
+		logging.FromGin(c).Warn("Failed to read the article's comment count", "article_id", article.ID, "error", err)
+	}
+	if err := services.ApplyLicenses(siteDB(c), credited); err != nil {
+		logging.FromGin(c).Warn("Failed to look up the default license", "error", err)
+	}
+	lockMembersOnly(c, credited)
+	article = credited[0]
+
+	// Breadcrumbs through the parents of the article's category
+	pathLang := lang
+	if pathLang == defaultLang {
+		pathLang = ""
+	}
+	if path, err := services.GetGlobalCategoryService().Path(c.Request.Context(), article.CategoryID, pathLang); err == nil {
+		article.CategoryPath = path
+	} else if !errors.Is(err, services.ErrCategoryNotFound) {
+		logging.FromGin(c).Warn("Failed to look up the category path", "article_id", article.ID, "error", err)
+	}
+
+	if security.SanitizeOnRender() {
+		sanitizeArticleForRender(&article)
+	}
+
+	if err := hooks.Filter(c.Request.Context(), hooks.ArticleRender, &article); err != nil {
+		logging.FromGin(c).Warn("Render hook failed, serving article unchanged", "article_id", article.ID, "error", err)
 	}
 
 	c.JSON(http.StatusOK, article)
@@ -122,15 +212,40 @@ func CreateArticle(c *gin.Context) {
 		SEODescription string `json:"seo_description"`
 		SEOKeywords   string  `json:"seo_keywords"`
 		SEOSlug       string  `json:"seo_slug"`
+		// ShowDonation overrides the site's donation setting for the article
+		ShowDonation *bool `json:"show_donation"`
+		// MembersOnly serves everyone but members a teaser
+		MembersOnly bool `json:"members_only"`
+		// License overrides the site's default license for the article
+		License       string `json:"license"`
+		LicenseCustom string `json:"license_custom"`
+		// RegionRule (block or allow) and Regions, comma-separated country
+		// codes, limit where the article is served
+		RegionRule string `json:"region_rule"`
+		Regions    string `json:"regions"`
 		Translations []struct {
 			Language string `json:"language"`
 			Title    string `json:"title"`
 			Content  string `json:"content"`
 			Summary  string `json:"summary"`
+			SEOSlug  string `json:"seo_slug"`
+			// MachineTranslated marks a translation from a translation service
+			MachineTranslated *bool `json:"machine_translated"`
 		} `json:"translations"`
+		// AllowMissingTranslations publishes without the required languages
+		AllowMissingTranslations bool `json:"allow_missing_translations"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if !bindJSON(c, &req) {
+		return
+	}
+	license, licenseCustom, err := services.NormalizeLicense(req.License, req.LicenseCustom, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	regionRule, regions, err := services.NormalizeRegionRule(req.RegionRule, req.Regions)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -138,7 +253,7 @@ func CreateArticle(c *gin.Context) {
 	// Create main article
 	article := models.Article{
 		Title:       req.Title,
-		Content:     req.Content,
+		Content:     sanitizeArticleContent(c, req.Content, req.ContentType),
 		ContentType: req.ContentType,
 		Summary:     req.Summary,
 		CategoryID:  req.CategoryID,
@@ -152,6 +267,13 @@ func CreateArticle(c *gin.Context) {
 		SEODescription: req.SEODescription,
 		SEOKeywords:    req.SEOKeywords,
 		SEOSlug:        req.SEOSlug,
+		ShowDonation:   req.ShowDonation,
+		MembersOnly:    req.MembersOnly,
+		License:        license,
+		LicenseCustom:  licenseCustom,
+		RegionRule:     regionRule,
+		Regions:        regions,
+		AuthorID:       currentAuthorID(c),
 	}
 	if article.DefaultLang == "" {
 		article.DefaultLang = "zh"
@@ -164,17 +286,32 @@ func CreateArticle(c *gin.Context) {
 		}
 	}
 
-	// Validate seo_slug uniqueness
-	if article.SEOSlug != "" {
-		var count int64
-		database.DB.Model(&models.Article{}).Where("seo_slug = ?", article.SEOSlug).Count(&count)
-		if count > 0 {
-			c.JSON(http.StatusConflict, gin.H{"error": "SEO slug already in use"})
+	if err := hooks.Filter(c.Request.Context(), hooks.BeforeArticleSave, &article); err != nil {
+		respondHookError(c, err)
+		return
+	}
+
+	// Validate seo_slug uniqueness, per language for translations
+	if !checkArticleSlug(c, 0, "", article.SEOSlug) {
+		return
+	}
+	for _, translation := range req.Translations {
+		if translation.Language != article.DefaultLang && !checkArticleSlug(c, 0, translation.Language, translation.SEOSlug) {
 			return
 		}
 	}
+	translations := make([]models.ArticleTranslation, 0, len(req.Translations))
+	for _, translation := range req.Translations {
+		translations = append(translations, models.ArticleTranslation{Language: translation.Language, Title: translation.Title, Content: translation.Content})
+	}
+	if !checkRequiredTranslations(c, &article, translations, req.AllowMissingTranslations) {
+		return
+	}
+	if !checkPublishChecklist(c, &article) {
+		return
+	}
 
-	if err := database.DB.Create(&article).Error; err != nil {
+	if err := siteDB(c).Create(&article).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -191,14 +328,22 @@ func CreateArticle(c *gin.Context) {
 				ArticleID: article.ID,
 				Language:  translation.Language,
 				Title:     translation.Title,
-				Content:   translation.Content,
+				Content:   sanitizeArticleContent(c, translation.Content, article.ContentType),
 				Summary:   translation.Summary,
+				SEOSlug:   translation.SEOSlug,
+				MachineTranslated: translation.MachineTranslated != nil && *translation.MachineTranslated,
 			}
-			database.DB.Create(&newTranslation)
+			newTranslation.StampSource(&article)
+			siteDB(c).Create(&newTranslation)
+			recordSlugChange(c, &article, translation.Language, "", translation.SEOSlug)
 		}
 	}
+	recordSlugChange(c, &article, "", "", article.SEOSlug)
+
+	cache.Publish(cache.TopicArticles)
 
-	database.DB.Preload("Category").Preload("Translations").First(&article, article.ID)
+	siteDB(c).Preload("Category").Preload("Translations").First(&article, article.ID)
+	notifyArticleSaved(c, &article, false)
 	c.JSON(http.StatusCreated, article)
 }
 
@@ -210,10 +355,11 @@ func UpdateArticle(c *gin.Context) {
 	}
 
 	var article models.Article
-	if err := database.DB.First(&article, id).Error; err != nil {
+	if err := siteDB(c).First(&article, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
 		return
 	}
+	wasPublished := !article.CreatedAt.After(time.Now())
 
 	var req struct {
 		Title       string `json:"title"`
@@ -236,22 +382,67 @@ func UpdateArticle(c *gin.Context) {
 		IsPinned     *bool   `json:"is_pinned"`
 		PinOrder     *int    `json:"pin_order"`
 		PinnedAt     *string `json:"pinned_at"`
+		// UnpinAt ends the pin at an RFC3339 time; "" clears it
+		UnpinAt      *string `json:"unpin_at"`
+		// ShowDonation overrides the site's donation setting for the article
+		ShowDonation *bool `json:"show_donation"`
+		// MembersOnly serves everyone but members a teaser; unchanged when left out
+		MembersOnly *bool `json:"members_only"`
+		// License overrides the site's default license; unchanged when left out
+		License       *string `json:"license"`
+		LicenseCustom string  `json:"license_custom"`
+		// CanonicalURL points search engines at where the article first
+		// appeared; unchanged when left out, "" clears it
+		CanonicalURL *string `json:"canonical_url"`
+		// RegionRule limits where the article is served to or away from
+		// Regions; unchanged when left out, "" serves it everywhere
+		RegionRule *string `json:"region_rule"`
+		Regions    string  `json:"regions"`
 		Translations []struct {
 			Language string `json:"language"`
 			Title    string `json:"title"`
 			Content  string `json:"content"`
 			Summary  string `json:"summary"`
+			SEOSlug  string `json:"seo_slug"`
+			// MachineTranslated marks a translation from a translation service
+			MachineTranslated *bool `json:"machine_translated"`
 		} `json:"translations"`
+		// AllowMissingTranslations publishes without the required languages
+		AllowMissingTranslations bool `json:"allow_missing_translations"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
+	if req.License != nil {
+		license, licenseCustom, err := services.NormalizeLicense(*req.License, req.LicenseCustom, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		article.License, article.LicenseCustom = license, licenseCustom
+	}
+	if req.CanonicalURL != nil {
+		canonical := strings.TrimSpace(*req.CanonicalURL)
+		if target, err := url.Parse(canonical); canonical != "" &&
+			(err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" || len(canonical) > 500) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "canonical_url must be an absolute http(s) URL"})
+			return
+		}
+		article.CanonicalURL = canonical
+	}
+	if req.RegionRule != nil {
+		rule, regions, err := services.NormalizeRegionRule(*req.RegionRule, req.Regions)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		article.RegionRule, article.Regions = rule, regions
+	}
 
 	// Update main article
 	article.Title = req.Title
-	article.Content = req.Content
+	article.Content = sanitizeArticleContent(c, req.Content, req.ContentType)
 	article.ContentType = req.ContentType
 	article.Summary = req.Summary
 	article.CategoryID = req.CategoryID
@@ -267,16 +458,21 @@ func UpdateArticle(c *gin.Context) {
 	article.SEOTitle = req.SEOTitle
 	article.SEODescription = req.SEODescription
 	article.SEOKeywords = req.SEOKeywords
-	// Validate seo_slug uniqueness (exclude current article)
-	if req.SEOSlug != "" && req.SEOSlug != article.SEOSlug {
-		var count int64
-		database.DB.Model(&models.Article{}).Where("seo_slug = ? AND id != ?", req.SEOSlug, article.ID).Count(&count)
-		if count > 0 {
-			c.JSON(http.StatusConflict, gin.H{"error": "SEO slug already in use"})
+	// Validate seo_slug uniqueness (exclude current article), per language for translations
+	if req.SEOSlug != article.SEOSlug && !checkArticleSlug(c, article.ID, "", req.SEOSlug) {
+		return
+	}
+	for _, translation := range req.Translations {
+		if translation.Language != article.DefaultLang && !checkArticleSlug(c, article.ID, translation.Language, translation.SEOSlug) {
 			return
 		}
 	}
+	oldSlug := article.SEOSlug
 	article.SEOSlug = req.SEOSlug
+	article.ShowDonation = req.ShowDonation
+	if req.MembersOnly != nil {
+		article.MembersOnly = *req.MembersOnly
+	}
 
 	// Update created_at if provided
 	if req.CreatedAt != "" {
@@ -290,11 +486,8 @@ func UpdateArticle(c *gin.Context) {
 		// If trying to pin the article
 		if *req.IsPinned && !article.IsPinned {
 			// Check how many articles are already pinned
-			var pinnedCount int64
-			database.DB.Model(&models.Article{}).Where("is_pinned = ?", true).Count(&pinnedCount)
-
-			if pinnedCount >= 2 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Maximum 2 articles can be pinned"})
+			if err := services.GetGlobalPinService().CheckLimit(c.Request.Context(), article.ID); err != nil {
+				respondPinError(c, err)
 				return
 			}
 
@@ -312,6 +505,7 @@ func UpdateArticle(c *gin.Context) {
 			article.IsPinned = false
 			article.PinOrder = 0
 			article.PinnedAt = nil
+			article.UnpinAt = nil
 		}
 	}
 
@@ -320,13 +514,53 @@ func UpdateArticle(c *gin.Context) {
 		article.PinOrder = *req.PinOrder
 	}
 
-	if err := database.DB.Save(&article).Error; err != nil {
+	// Schedule the end of the pin (only if article is pinned)
+	if req.UnpinAt != nil && article.IsPinned {
+		article.UnpinAt = nil
+		if *req.UnpinAt != "" {
+			unpinAt, err := time.Parse(time.RFC3339, *req.UnpinAt)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unpin_at must be an RFC3339 time"})
+				return
+			}
+			if err := services.GetGlobalPinService().ValidateUnpinAt(&unpinAt); err != nil {
+				respondPinError(c, err)
+				return
+			}
+			article.UnpinAt = &unpinAt
+		}
+	}
+
+	if err := hooks.Filter(c.Request.Context(), hooks.BeforeArticleSave, &article); err != nil {
+		respondHookError(c, err)
+		return
+	}
+
+	// Translations the request leaves out are kept, so they count too
+	var translations []models.ArticleTranslation
+	siteDB(c).Where("article_id = ?", article.ID).Find(&translations)
+	for _, translation := range req.Translations {
+		if translation.Title != "" || translation.Content != "" || translation.Summary != "" {
+			translations = append(translations, models.ArticleTranslation{Language: translation.Language, Title: translation.Title, Content: translation.Content})
+		}
+	}
+	if !checkRequiredTranslations(c, &article, translations, req.AllowMissingTranslations) {
+		return
+	}
+	// Editing an article already live is not publishing it
+	if !wasPublished && !checkPublishChecklist(c, &article) {
+		return
+	}
+
+	if err := siteDB(c).Save(&article).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	recordSlugChange(c, &article, "", oldSlug, article.SEOSlug)
+
 	// Clean up any existing translation for default language (shouldn't exist)
-	database.DB.Where("article_id = ? AND language = ?", article.ID, article.DefaultLang).Delete(&models.ArticleTranslation{})
+	siteDB(c).Where("article_id = ? AND language = ?", article.ID, article.DefaultLang).Delete(&models.ArticleTranslation{})
 
 	// Update translations (excluding default language)
 	for _, translation := range req.Translations {
@@ -337,27 +571,45 @@ func UpdateArticle(c *gin.Context) {
 
 		if translation.Title != "" || translation.Content != "" || translation.Summary != "" {
 			var existingTranslation models.ArticleTranslation
-			if err := database.DB.Where("article_id = ? AND language = ?", article.ID, translation.Language).First(&existingTranslation).Error; err != nil {
+			if err := siteDB(c).Where("article_id = ? AND language = ?", article.ID, translation.Language).First(&existingTranslation).Error; err != nil {
 				// Create new translation
 				newTranslation := models.ArticleTranslation{
 					ArticleID: article.ID,
 					Language:  translation.Language,
 					Title:     translation.Title,
-					Content:   translation.Content,
+					Content:   sanitizeArticleContent(c, translation.Content, article.ContentType),
 					Summary:   translation.Summary,
+					SEOSlug:   translation.SEOSlug,
+					MachineTranslated: translation.MachineTranslated != nil && *translation.MachineTranslated,
 				}
-				database.DB.Create(&newTranslation)
+				// Translations written now are stamped with the source they were made from
+				newTranslation.StampSource(&article)
+				siteDB(c).Create(&newTranslation)
+				recordSlugChange(c, &article, translation.Language, "", translation.SEOSlug)
 			} else {
 				// Update existing translation
+				oldTranslationSlug := existingTranslation.SEOSlug
+				content := sanitizeArticleContent(c, translation.Content, article.ContentType)
+				if existingTranslation.Title != translation.Title || existingTranslation.Content != content || existingTranslation.Summary != translation.Summary {
+					existingTranslation.StampSource(&article)
+				}
+				if translation.MachineTranslated != nil {
+					existingTranslation.MachineTranslated = *translation.MachineTranslated
+				}
 				existingTranslation.Title = translation.Title
-				existingTranslation.Content = translation.Content
+				existingTranslation.Content = content
 				existingTranslation.Summary = translation.Summary
-				database.DB.Save(&existingTranslation)
+				existingTranslation.SEOSlug = translation.SEOSlug
+				siteDB(c).Save(&existingTranslation)
+				recordSlugChange(c, &article, translation.Language, oldTranslationSlug, translation.SEOSlug)
 			}
 		}
 	}
 
-	database.DB.Preload("Category").Preload("Translations").First(&article, article.ID)
+	cache.Publish(cache.TopicArticles)
+
+	siteDB(c).Preload("Category").Preload("Translations").First(&article, article.ID)
+	notifyArticleSaved(c, &article, wasPublished)
 	c.JSON(http.StatusOK, article)
 }
 
@@ -368,11 +620,12 @@ func DeleteArticle(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&models.Article{}, id).Error; err != nil {
+	if err := siteDB(c).Delete(&models.Article{}, id).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	cache.Publish(cache.TopicArticles)
 	c.JSON(http.StatusOK, gin.H{"message": "Article deleted successfully"})
 }
 
@@ -383,51 +636,63 @@ func ImportMarkdown(c *gin.Context) {
 		CategoryID uint   `json:"category_id"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	summary := req.Content
+	content := sanitizeArticleContent(c, req.Content, "markdown")
+	summary := content
 	if len(summary) > 200 {
 		summary = summary[:200] + "..."
 	}
 
 	article := models.Article{
 		Title:       req.Title,
-		Content:     req.Content,
+		Content:     content,
 		ContentType: "markdown",
 		Summary:     summary,
 		CategoryID:  req.CategoryID,
+		AuthorID:    currentAuthorID(c),
 	}
 
-	if err := database.DB.Create(&article).Error; err != nil {
+	if err := hooks.Filter(c.Request.Context(), hooks.BeforeArticleSave, &article); err != nil {
+		respondHookError(c, err)
+		return
+	}
+
+	if err := siteDB(c).Create(&article).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	database.DB.Preload("Category").First(&article, article.ID)
+	cache.Publish(cache.TopicArticles)
+
+	siteDB(c).Preload("Category").First(&article, article.ID)
+	notifyArticleSaved(c, &article, false)
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Markdown imported successfully",
 		"article": article,
 	})
 }
 
-// Helper function to apply translation to an article
-func applyTranslation(article *models.Article, lang string) {
-	for _, translation := range article.Translations {
-		if translation.Language == lang {
-			if translation.Title != "" {
-				article.Title = translation.Title
-			}
-			if translation.Content != "" {
-				article.Content = translation.Content
-			}
-			if translation.Summary != "" {
-				article.Summary = translation.Summary
-			}
-			break
-		}
+// sanitizeArticleContent applies the HTML allowlist to content submitted by
+// authors who are not trusted under HTML_SANITIZE_MODE
+func sanitizeArticleContent(c *gin.Context, content, contentType string) string {
+	isAdmin, _ := c.Get("isAdmin")
+	trusted, _ := isAdmin.(bool)
+	if !security.ShouldSanitize(trusted) {
+		return content
+	}
+	return security.GetGlobalHTMLPolicy().SanitizeContent(content, contentType)
+}
+
+// sanitizeArticleForRender re-sanitizes stored content before it is served,
+// covering articles saved before sanitization was enabled
+func sanitizeArticleForRender(article *models.Article) {
+	policy := security.GetGlobalHTMLPolicy()
+	article.Content = policy.SanitizeContent(article.Content, article.ContentType)
+	for i := range article.Translations {
+		article.Translations[i].Content = policy.SanitizeContent(article.Translations[i].Content, article.ContentType)
 	}
 }
 
@@ -456,13 +721,7 @@ func getClientIP(c *gin.Context) string {
 
 // Helper function to generate fingerprint from request
 func generateFingerprint(c *gin.Context) string {
-	ip := getClientIP(c)
-	userAgent := c.GetHeader("User-Agent")
-
-	// Create a simple fingerprint from IP + User-Agent
-	fingerprint := fmt.Sprintf("%s|%s", ip, userAgent)
-	hash := sha256.Sum256([]byte(fingerprint))
-	return fmt.Sprintf("%x", hash)
+	return services.GetGlobalVisitorService().ViewID(getClientIP(c), c.GetHeader("User-Agent"))
 }
 
 // Track article view asynchronously with detailed analytics
@@ -470,11 +729,18 @@ func trackArticleView(articleID uint, c *gin.Context) {
 	ip := getClientIP(c)
 	userAgent := c.GetHeader("User-Agent")
 	fingerprint := generateFingerprint(c)
-
-	// Check if this fingerprint has already viewed this article
-	var existingView models.ArticleView
-	if err := database.DB.Where("article_id = ? AND fingerprint = ?", articleID, fingerprint).First(&existingView).Error; err != nil {
-		// No existing view found, create new one with detailed analytics
+	admin := isAdminRequest(c)
+	// The request is over before the view is stored, but its site stays
+	ctx := context.WithoutCancel(c.Request.Context())
+
+	go func() {
+		views := services.GetGlobalArticleViewService()
+		// Skip looking up views that will not count; the queue checks
+		// again when counting
+		counts, err := views.Counts(ctx, articleID, fingerprint, admin)
+		if err != nil || !counts {
+			return
+		}
 
 		// Parse user agent information
 		uaInfo := services.ParseUserAgent(userAgent)
@@ -510,13 +776,15 @@ func trackArticleView(articleID uint, c *gin.Context) {
 			DeviceType: uaInfo.DeviceType,
 			Platform:   uaInfo.Platform,
 		}
-
-		if err := database.DB.Create(&view).Error; err == nil {
-			// Increment article view count
-			database.DB.Model(&models.Article{}).Where("id = ?", articleID).UpdateColumn("view_count", database.DB.Raw("view_count + 1"))
+		// Strict privacy keeps only what the view statistics show in aggregate
+		if services.GetGlobalVisitorService().StrictPrivacy() {
+			view.IPAddress, view.UserAgent = "", ""
+			view.Region, view.City = "", ""
 		}
-	}
-	// If view already exists, do nothing (unique visitor already counted)
+		if err := views.Track(ctx, view, admin); err != nil {
+			slog.Warn("Failed to record article view", "article_id", articleID, "error", err)
+		}
+	}()
 }
 
 // SearchArticles handles article search functionality with advanced search syntax
@@ -540,22 +808,31 @@ func SearchArticles(c *gin.Context) {
 		return
 	}
 
+	var searchOptions struct {
+		SnippetLength int `form:"snippet_length" binding:"omitempty,min=40,max=500"`
+	}
+	if !bindQuery(c, &searchOptions) {
+		return
+	}
+
 	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	if page < 1 {
-		page = 1
+	paging, ok := bindPage(c, 10, maxPageSize)
+	if !ok {
+		return
 	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	page, limit, offset := paging.Page, paging.Limit, paging.Offset()
+
+	fields, err := parseListFields(c, models.Article{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	offset := (page - 1) * limit
 
 	var articles []models.Article
 	var total int64
 
 	// Build base query with joins
-	searchQuery := database.DB.Preload("Category").Preload("Translations")
+	searchQuery := siteDB(c).Preload("Category").Preload("Translations")
 
 	// Filter future articles for non-admin requests
 	if !isAdminRequest(c) {
@@ -565,7 +842,7 @@ func SearchArticles(c *gin.Context) {
 	// Build advanced search conditions
 	sqlCondition, params := parsedQuery.BuildSQLQuery()
 	if sqlCondition != "" {
-		searchQuery = searchQuery.Where(sqlCondition, params...)
+		searchQuery = searchQuery.Where(database.Like(sqlCondition), params...)
 	}
 
 	// Also search in translations if there are free text terms
@@ -590,10 +867,10 @@ func SearchArticles(c *gin.Context) {
 
 			// Add OR condition for translations
 			searchQuery = searchQuery.Or(
-				database.DB.Where("id IN (?)",
-					database.DB.Table("article_translations").
+				siteDB(c).Where("id IN (?)",
+					siteDB(c).Table("article_translations").
 						Select("article_id").
-						Where(translationSQL, translationParams...),
+						Where(database.Like(translationSQL), translationParams...),
 				),
 			)
 		}
@@ -601,6 +878,9 @@ func SearchArticles(c *gin.Context) {
 
 	// Get total count
 	searchQuery.Model(&models.Article{}).Count(&total)
+	if total == 0 && page == 1 && len(parsedQuery.FreeText) > 0 && !isAdminRequest(c) {
+		recordUnansweredSearch(c, strings.Join(parsedQuery.FreeText, " "))
+	}
 
 	// Apply sorting
 	sortClause := parsedQuery.GetSortClause()
@@ -613,7 +893,7 @@ func SearchArticles(c *gin.Context) {
 
 	// Apply language filtering if requested
 	lang := c.Query("lang")
-	defaultLang := getArticleDefaultLanguage()
+	defaultLang := getArticleDefaultLanguage(c)
 
 	if lang != "" && lang != defaultLang {
 		for i := range articles {
@@ -622,16 +902,43 @@ func SearchArticles(c *gin.Context) {
 					articles[i].Title = translation.Title
 					articles[i].Content = translation.Content
 					articles[i].Summary = translation.Summary
+					articles[i].Dir = translation.Dir
 					// Note: SEO fields are not translated, keep original values
 					break
 				}
 			}
+			applyCategoryTranslation(&articles[i].Category, lang)
+		}
+	}
+	articles = withholdRegional(c, articles)
+	lockMembersOnly(c, articles)
+
+	// Highlight matches server-side so result lists need no full content
+	language := lang
+	if language == "" {
+		language = defaultLang
+	}
+	snippetLength := searchOptions.SnippetLength
+	if snippetLength == 0 {
+		snippetLength = search.DefaultSnippetLength
+	}
+	terms := parsedQuery.Terms()
+	for i := range articles {
+		articles[i].Highlight = &models.SearchHighlight{
+			Title:   search.Highlight(articles[i].Title, terms),
+			Summary: search.Highlight(articles[i].Summary, terms),
+			Snippet: search.Snippet(search.PlainText(articles[i].Content, articles[i].ContentType), terms, language, snippetLength),
 		}
 	}
+	results, err := fields.apply(articles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Return paginated results with parsed query info
 	c.JSON(http.StatusOK, gin.H{
-		"articles": articles,
+		"articles": results,
 		"pagination": gin.H{
 			"page":        page,
 			"limit":       limit,
@@ -644,3 +951,17 @@ func SearchArticles(c *gin.Context) {
 		"sort_order":   parsedQuery.SortOrder,
 	})
 }
+
+// recordUnansweredSearch counts a search without results in the language
+// the reader searched in, for the writing suggestions
+func recordUnansweredSearch(c *gin.Context, text string) {
+	language := c.Query("lang")
+	if language == "" {
+		language = getArticleDefaultLanguage(c)
+	}
+	go func() {
+		if err := services.GetGlobalWritingSuggestionService().RecordUnansweredSearch(text, language); err != nil {
+			slog.Warn("Failed to record unanswered search", "error", err)
+		}
+	}()
+}
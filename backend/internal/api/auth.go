@@ -1,7 +1,6 @@
 package api
 
 import (
-	"blog-backend/internal/auth"
 	"blog-backend/internal/database"
 	"blog-backend/internal/models"
 	"github.com/gin-gonic/gin"
@@ -21,24 +20,39 @@ type LoginResponse struct {
 
 func Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	var user models.User
-	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	if err := siteDB(c).Where("username = ?", req.Username).First(&user).Error; err != nil {
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "Invalid username or password")
 		return
 	}
 
 	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+		respondError(c, http.StatusUnauthorized, ErrCodeInvalidCredentials, "Invalid username or password")
 		return
 	}
 
-	token, err := auth.GenerateToken(user.ID, user.Username, user.IsAdmin)
+	// Users with a passkey requirement finish login via /passkeys/login/finish
+	if user.PasskeyRequired {
+		var passkeyCount int64
+		siteDB(c).Model(&models.WebAuthnCredential{}).Where("user_id = ?", user.ID).Count(&passkeyCount)
+		if passkeyCount > 0 {
+			challenge, err := beginPasskeyAssertion(c, user.ID, passkeyPurposeMFA)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey verification"})
+				return
+			}
+			challenge["passkey_required"] = true
+			c.JSON(http.StatusOK, challenge)
+			return
+		}
+	}
+
+	token, err := startLoginSession(c, user, "password")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -54,7 +68,7 @@ func GetCurrentUser(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
 	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	if err := siteDB(c).First(&user, userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -70,13 +84,12 @@ func ChangePassword(c *gin.Context) {
 		NewPassword string `json:"new_password" binding:"required,min=6"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	var user models.User
-	if err := database.DB.First(&user, userID).Error; err != nil {
+	if err := siteDB(c).First(&user, userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
@@ -97,7 +110,7 @@ func ChangePassword(c *gin.Context) {
 
 	// Update password
 	user.Password = string(hashedPassword)
-	if err := database.DB.Save(&user).Error; err != nil {
+	if err := siteDB(c).Save(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
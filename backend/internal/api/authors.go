@@ -0,0 +1,125 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/security"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuthor returns an author's public profile, with the bio in the
+// language of ?lang= where translated
+func GetAuthor(c *gin.Context) {
+	id, ok := authorID(c)
+	if !ok {
+		return
+	}
+	author, err := services.GetGlobalAuthorService().Get(c.Request.Context(), id, c.Query("lang"))
+	if err != nil {
+		respondAuthorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, author)
+}
+
+// ListAuthorArticles returns a page of an author's published articles,
+// newest first
+func ListAuthorArticles(c *gin.Context) {
+	id, ok := authorID(c)
+	if !ok {
+		return
+	}
+	page, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	authors := services.GetGlobalAuthorService()
+	articles, total, err := authors.Articles(c.Request.Context(), id, page, limit)
+	if err != nil {
+		respondAuthorError(c, err)
+		return
+	}
+	if err := authors.Attribute(c.Request.Context(), articles); err != nil {
+		respondAuthorError(c, err)
+		return
+	}
+	if err := services.GetGlobalCommentBridgeService().Attach(c.Request.Context(), articles); err != nil {
+		logging.FromGin(c).Warn("Failed to read article comment counts", "error", err)
+	}
+	articles = withholdRegional(c, articles)
+	lockMembersOnly(c, articles)
+	lang := c.Query("lang")
+	for i := range articles {
+		if lang != "" {
+			services.ApplyTranslation(&articles[i], lang)
+			applyCategoryTranslation(&articles[i].Category, lang)
+		}
+		if security.SanitizeOnRender() {
+			sanitizeArticleForRender(&articles[i])
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"articles":   articles,
+		"pagination": gin.H{"page": page, "limit": limit, "total": total},
+	})
+}
+
+// GetMyAuthorProfile returns the current user's author profile for
+// editing, with every translation; it is empty until first saved
+func GetMyAuthorProfile(c *gin.Context) {
+	profile, err := services.GetGlobalAuthorService().Profile(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		respondAuthorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateMyAuthorProfile edits the current user's author profile, which
+// credits their articles to them once saved
+func UpdateMyAuthorProfile(c *gin.Context) {
+	var input services.AuthorProfileInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	profile, err := services.GetGlobalAuthorService().UpdateProfile(c.Request.Context(), c.GetUint("userID"), input)
+	if err != nil {
+		respondAuthorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// currentAuthorID returns the signed-in user, whom new articles are
+// credited to
+func currentAuthorID(c *gin.Context) *uint {
+	if id := c.GetUint("userID"); id != 0 {
+		return &id
+	}
+	return nil
+}
+
+func authorID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondAuthorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAuthorNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidAuthor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Author operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Author operation failed"})
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/logging"
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// aiTimeoutRetryAfter is the Retry-After, in seconds, of a request that
+// ran out of time
+const aiTimeoutRetryAfter = 30
+
+// AIEndpoint guards an endpoint that holds its connection while AI
+// providers answer or every article is analyzed. Each endpoint gets its own
+// AI_MAX_CONCURRENT slots; a request waits up to AI_QUEUE_TIMEOUT for one
+// and is turned away with 429 if none frees up. Its context is cancelled
+// after AI_REQUEST_TIMEOUT, which aborts the provider calls made with it,
+// and the request is answered with 503. Both answers carry Retry-After.
+func AIEndpoint(name string) gin.HandlerFunc {
+	cfg := config.Get().AI
+	return backpressure(name, cfg.MaxConcurrent, cfg.QueueTimeout.Std(), cfg.RequestTimeout.Std())
+}
+
+func backpressure(name string, concurrent int, queueTimeout, requestTimeout time.Duration) gin.HandlerFunc {
+	slots := make(chan struct{}, max(concurrent, 1))
+	return func(c *gin.Context) {
+		if !acquireSlot(c.Request.Context(), slots, queueTimeout) {
+			if c.Request.Context().Err() != nil {
+				// The client gave up while waiting
+				c.Abort()
+				return
+			}
+			logging.FromGin(c).Warn("AI endpoint busy, request turned away", "endpoint", name, "slots", cap(slots))
+			c.Header("Retry-After", strconv.Itoa(int(queueTimeout.Seconds())+1))
+			respondError(c, http.StatusTooManyRequests, ErrCodeBusy, "Too many requests in progress, try again shortly")
+			return
+		}
+		defer func() { <-slots }()
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), requestTimeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		writer := &deadlineWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if ctx.Err() == context.DeadlineExceeded && !c.Writer.Written() {
+			logging.FromGin(c).Warn("AI endpoint request timed out", "endpoint", name, "timeout", requestTimeout.String())
+			c.Header("Retry-After", strconv.Itoa(aiTimeoutRetryAfter))
+			respondError(c, http.StatusServiceUnavailable, ErrCodeTimeout, "The request took too long, try again later")
+		}
+	}
+}
+
+// acquireSlot takes a slot, waiting up to wait for one
+func acquireSlot(ctx context.Context, slots chan struct{}, wait time.Duration) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+	if wait <= 0 {
+		return false
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+// deadlineWriter drops a response started after its context's deadline.
+// Handlers answer a cancelled provider call with an error of their own,
+// which AIEndpoint replaces with 503.
+type deadlineWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+}
+
+func (w *deadlineWriter) late() bool {
+	return w.ctx.Err() == context.DeadlineExceeded && !w.ResponseWriter.Written()
+}
+
+func (w *deadlineWriter) WriteHeader(code int) {
+	if !w.late() {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *deadlineWriter) WriteHeaderNow() {
+	if !w.late() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *deadlineWriter) Write(data []byte) (int, error) {
+	if w.late() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *deadlineWriter) WriteString(s string) (int, error) {
+	if w.late() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBackpressure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	started, release := make(chan struct{}), make(chan struct{})
+	r.GET("/slow", backpressure("slow", 1, 50*time.Millisecond, time.Minute), func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/stuck", backpressure("stuck", 1, 0, 20*time.Millisecond), func(c *gin.Context) {
+		// Like a provider call, give up when the request is cancelled
+		<-c.Request.Context().Done()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": c.Request.Context().Err().Error()})
+	})
+
+	var wg sync.WaitGroup
+	first := httptest.NewRecorder()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-started
+
+	// The only slot is taken, and it is not freed within the queue timeout
+	busy := httptest.NewRecorder()
+	r.ServeHTTP(busy, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if busy.Code != http.StatusTooManyRequests || busy.Header().Get("Retry-After") != "1" {
+		t.Errorf("busy endpoint: %d, Retry-After %q", busy.Code, busy.Header().Get("Retry-After"))
+	}
+
+	// A request waiting in line gets the slot once it is freed
+	queued := httptest.NewRecorder()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.ServeHTTP(queued, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	release <- struct{}{}
+	<-started
+	release <- struct{}{}
+	wg.Wait()
+	if first.Code != http.StatusOK || queued.Code != http.StatusOK {
+		t.Errorf("requests within the limit: %d and %d", first.Code, queued.Code)
+	}
+
+	// The handler's own error for the cancelled call is replaced
+	stuck := httptest.NewRecorder()
+	r.ServeHTTP(stuck, httptest.NewRequest(http.MethodGet, "/stuck", nil))
+	if stuck.Code != http.StatusServiceUnavailable || stuck.Header().Get("Retry-After") == "" {
+		t.Errorf("timed out request: %d %s, Retry-After %q", stuck.Code, stuck.Body, stuck.Header().Get("Retry-After"))
+	}
+}
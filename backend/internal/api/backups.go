@@ -0,0 +1,128 @@
+package api
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListBackups returns all backup archives, newest first
+func ListBackups(c *gin.Context) {
+	backups, err := services.GetGlobalBackupService().List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list backups"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"backups": backups})
+}
+
+// CreateBackup snapshots the database and uploads into a new archive
+func CreateBackup(c *gin.Context) {
+	backup, err := services.GetGlobalBackupService().Create()
+	if err != nil {
+		if errors.Is(err, database.ErrSnapshotUnsupported) {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": database.ErrSnapshotUnsupported.Error()})
+			return
+		}
+		logging.FromGin(c).Error("Failed to create backup", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create backup"})
+		return
+	}
+	c.JSON(http.StatusCreated, backup)
+}
+
+// DownloadBackup streams a backup archive
+func DownloadBackup(c *gin.Context) {
+	path, err := services.GetGlobalBackupService().Path(c.Param("name"))
+	if err != nil {
+		respondBackupError(c, err)
+		return
+	}
+	c.FileAttachment(path, c.Param("name"))
+}
+
+// UploadBackup stores an uploaded backup archive so it can be restored
+func UploadBackup(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No backup file provided"})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer src.Close()
+
+	backup, err := services.GetGlobalBackupService().Import(src)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, backup)
+}
+
+// RestoreBackup replaces the database and uploads with a backup. The
+// current state is saved as a new backup first.
+func RestoreBackup(c *gin.Context) {
+	name := c.Param("name")
+	safety, err := services.GetGlobalBackupService().Restore(name)
+	if err != nil {
+		if errors.Is(err, services.ErrBackupNotFound) || errors.Is(err, services.ErrInvalidBackupName) {
+			respondBackupError(c, err)
+			return
+		}
+		if errors.Is(err, database.ErrSnapshotUnsupported) {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": database.ErrSnapshotUnsupported.Error()})
+			return
+		}
+		logging.FromGin(c).Error("Failed to restore backup", "name", name, "safety_backup", safety, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "safety_backup": safety})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Backup restored successfully", "safety_backup": safety})
+}
+
+// DeleteBackup removes a backup archive
+func DeleteBackup(c *gin.Context) {
+	if err := services.GetGlobalBackupService().Delete(c.Param("name")); err != nil {
+		respondBackupError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Backup deleted successfully"})
+}
+
+// GetBackupStatus reports the backup schedule and the last successful
+// scheduled backup for the admin dashboard
+func GetBackupStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, services.GetGlobalBackupService().BackupStatus())
+}
+
+// RunScheduledBackup runs the scheduled backup job immediately, including
+// the off-site copy and retention
+func RunScheduledBackup(c *gin.Context) {
+	result, err := services.GetGlobalBackupService().RunScheduledBackup()
+	if err != nil {
+		logging.FromGin(c).Error("Backup run failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func respondBackupError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidBackupName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid backup name"})
+	case errors.Is(err, services.ErrBackupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to access backup"})
+	}
+}
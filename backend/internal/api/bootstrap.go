@@ -0,0 +1,115 @@
+package api
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bootstrap is what the frontend needs to know about the deployment at
+// runtime, so it can be built once without baking in any URL
+type Bootstrap struct {
+	SiteURL         string   `json:"site_url"`
+	BasePath        string   `json:"base_path"`
+	APIBase         string   `json:"api_base"`
+	UploadBase      string   `json:"upload_base"`
+	Features        []string `json:"features"`
+	DefaultLanguage string   `json:"default_language"`
+	MultiSite       bool     `json:"multi_site"`
+}
+
+// GetBootstrapConfig returns the canonical URLs and enabled features for
+// the site the request is for
+func GetBootstrapConfig(c *gin.Context) {
+	siteURL := getBaseURL(c)
+	bootstrap := Bootstrap{
+		SiteURL:         siteURL,
+		BasePath:        basePath(siteURL),
+		APIBase:         siteURL + "/api",
+		UploadBase:      siteURL + "/api/uploads",
+		Features:        services.GetGlobalFeatureService().EnabledNames(),
+		DefaultLanguage: "zh",
+		MultiSite:       config.Get().Server.MultiSite,
+	}
+	var settings models.SiteSettings
+	if err := siteDB(c).Select("default_language").Limit(1).Find(&settings).Error; err == nil && settings.DefaultLanguage != "" {
+		bootstrap.DefaultLanguage = settings.DefaultLanguage
+	}
+
+	// The answer depends on the host and proxy headers, so it must not be
+	// shared between sites by caches in front of the backend
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Vary", "Host, X-Forwarded-Host, X-Forwarded-Proto, X-Forwarded-Prefix")
+	c.JSON(http.StatusOK, bootstrap)
+}
+
+// getBaseURL returns the canonical address of the site the request is for,
+// without a trailing slash. PUBLIC_URL wins when set outside multi-site
+// mode; otherwise the scheme, host and subpath come from the request and
+// the X-Forwarded-Proto, X-Forwarded-Host and X-Forwarded-Prefix headers a
+// reverse proxy sets. In multi-site mode a request reaching a site through
+// a host it does not list is answered with the site's first host.
+func getBaseURL(c *gin.Context) string {
+	site := currentSite(c)
+	if public := config.Get().Server.PublicURL; public != "" && site == nil {
+		return strings.TrimRight(public, "/")
+	}
+
+	scheme := "http"
+	proto, _, _ := strings.Cut(c.GetHeader("X-Forwarded-Proto"), ",")
+	if c.Request.TLS != nil || strings.TrimSpace(proto) == "https" {
+		scheme = "https"
+	}
+
+	host := c.Request.Host
+	if forwardedHost := c.GetHeader("X-Forwarded-Host"); forwardedHost != "" {
+		host, _, _ = strings.Cut(forwardedHost, ",")
+		host = strings.TrimSpace(host)
+	}
+	if site != nil {
+		if hosts := site.HostList(); len(hosts) > 0 && !containsHost(hosts, models.NormalizeHost(host)) {
+			host = hosts[0]
+		}
+	}
+
+	return fmt.Sprintf("%s://%s%s", scheme, host, forwardedPrefix(c))
+}
+
+// forwardedPrefix returns the subpath from X-Forwarded-Prefix, cleaned and
+// without a trailing slash, or "" when the site is served from the root
+func forwardedPrefix(c *gin.Context) string {
+	prefix := strings.TrimSpace(c.GetHeader("X-Forwarded-Prefix"))
+	if prefix == "" || strings.ContainsAny(prefix, ":?#\\") {
+		return ""
+	}
+	prefix = path.Clean("/" + prefix)
+	if prefix == "/" {
+		return ""
+	}
+	return prefix
+}
+
+// basePath returns the path part of a site URL, "" for the root
+func basePath(siteURL string) string {
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(u.Path, "/")
+}
+
+func containsHost(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"blog-backend/internal/models"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		site    *models.Site
+		want    string
+	}{
+		{"request host", nil, nil, "http://localhost:8085"},
+		{"reverse proxy", map[string]string{
+			"X-Forwarded-Proto": "https, http",
+			"X-Forwarded-Host":  "blog.example.com",
+		}, nil, "https://blog.example.com"},
+		{"subpath", map[string]string{
+			"X-Forwarded-Host":   "example.com",
+			"X-Forwarded-Prefix": "/blog/",
+		}, nil, "http://example.com/blog"},
+		{"unsafe prefix ignored", map[string]string{
+			"X-Forwarded-Prefix": "//evil.example.com",
+		}, nil, "http://localhost:8085/evil.example.com"},
+		{"listed site host", map[string]string{
+			"X-Forwarded-Host": "www.example.org",
+		}, &models.Site{Hosts: "example.org, www.example.org"}, "http://www.example.org"},
+		{"unlisted site host", nil, &models.Site{Hosts: "example.org"}, "http://example.org"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "http://localhost:8085/api/config", nil)
+			for key, value := range tt.headers {
+				c.Request.Header.Set(key, value)
+			}
+			if tt.site != nil {
+				c.Set("site", tt.site)
+			}
+			if got := getBaseURL(c); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := basePath("https://example.com/blog"); got != "/blog" {
+		t.Errorf("unexpected base path %q", got)
+	}
+}
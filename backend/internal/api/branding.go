@@ -0,0 +1,136 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// faviconSetPrefix starts the names of favicon set folders in the branding
+// upload directory
+const faviconSetPrefix = "favicons_"
+
+// GetBranding returns the site's logos and the icons to link from page
+// heads
+func GetBranding(c *gin.Context) {
+	var settings models.SiteSettings
+	if err := siteDB(c).First(&settings).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Settings not found"})
+		return
+	}
+	logoDark := settings.LogoDarkURL
+	if logoDark == "" {
+		logoDark = settings.LogoURL
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"logo_url":      settings.LogoURL,
+		"logo_dark_url": logoDark,
+		"favicon_url":   settings.FaviconURL,
+		"icons":         services.FaviconSet(settings.FaviconSetURL),
+	})
+}
+
+// GetWebManifest serves the site's web app manifest
+func GetWebManifest(c *gin.Context) {
+	var settings models.SiteSettings
+	if err := siteDB(c).First(&settings).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Settings not found"})
+		return
+	}
+	c.Header("Content-Type", "application/manifest+json")
+	c.JSON(http.StatusOK, services.WebManifest(&settings))
+}
+
+// GenerateFavicons makes the full favicon set - favicon.ico, PNG icons,
+// the Apple touch icon and web app manifest icons - from one uploaded
+// image, replacing the site's favicon and earlier set
+func GenerateFavicons(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	if header.Size > 5<<20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File too large"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+	source, err := services.DecodeFaviconSource(io.LimitReader(file, 5<<20))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFavicon) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		}
+		return
+	}
+
+	var settings models.SiteSettings
+	if err := siteDB(c).First(&settings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find settings"})
+		return
+	}
+
+	uploadSubDir := siteUploadDir(c, "branding")
+	setName := fmt.Sprintf("%s%d", faviconSetPrefix, time.Now().Unix())
+	setDir := filepath.Join(UploadDir, uploadSubDir, setName)
+	if err := services.WriteFaviconSet(source, setDir); err != nil {
+		os.RemoveAll(setDir)
+		logging.FromGin(c).Error("Failed to write favicon set", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate favicons"})
+		return
+	}
+
+	oldFavicon, oldSet := settings.FaviconURL, settings.FaviconSetURL
+	settings.FaviconSetURL = fmt.Sprintf("/uploads/%s/%s", uploadSubDir, setName)
+	settings.FaviconURL = settings.FaviconSetURL + "/" + services.FaviconICO
+	if err := siteDB(c).Save(&settings).Error; err != nil {
+		os.RemoveAll(setDir)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+		return
+	}
+
+	// Remove the replaced favicon and set when they were uploaded here
+	if name := brandingFileName(oldSet, uploadSubDir); strings.HasPrefix(name, faviconSetPrefix) && name != setName {
+		os.RemoveAll(filepath.Join(UploadDir, uploadSubDir, name))
+	}
+	if name := brandingFileName(oldFavicon, uploadSubDir); name != "" && !strings.Contains(name, "/") {
+		os.Remove(filepath.Join(UploadDir, uploadSubDir, name))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"favicon_url":     settings.FaviconURL,
+		"favicon_set_url": settings.FaviconSetURL,
+		"icons":           services.FaviconSet(settings.FaviconSetURL),
+		"message":         "Favicons generated successfully",
+	})
+}
+
+// brandingFileName returns the name under uploadSubDir of an uploaded
+// branding file or folder, or "" for addresses outside it
+func brandingFileName(fileURL, uploadSubDir string) string {
+	for _, prefix := range []string{"/api/uploads/", "/uploads/"} {
+		if name, ok := strings.CutPrefix(fileURL, prefix+uploadSubDir+"/"); ok {
+			if strings.Contains(name, "..") {
+				return ""
+			}
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// smartCacheNamespace is the shared tier of the smart cache, which also
+// keeps its results in the database
+const smartCacheNamespace = "smart"
+
+// GetCacheOverview reports the cache driver, every cache namespace with its
+// size and hit rate, and the groups of results in the smart cache. The
+// HTTP response caches are the namespaces starting with http_.
+func GetCacheOverview(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"driver":     cache.Current().Stats(),
+		"namespaces": cache.Namespaces(),
+		"smart":      services.GetGlobalCache().Groups(),
+	})
+}
+
+// PurgeCacheNamespace removes every entry of a namespace. Purging the
+// smart cache's namespace clears its database tier too.
+func PurgeCacheNamespace(c *gin.Context) {
+	name := c.Param("name")
+	if name == smartCacheNamespace {
+		services.GetGlobalCache().InvalidatePattern("*")
+	} else if !cache.Purge(name) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cache namespace not found"})
+		return
+	}
+	logging.FromGin(c).Info("Cache namespace purged", "namespace", name)
+	c.JSON(http.StatusOK, gin.H{"message": "Cache namespace purged", "namespace": name})
+}
+
+// PurgeSmartCacheGroup removes one group of results, such as search or
+// recommendations, from both tiers of the smart cache
+func PurgeSmartCacheGroup(c *gin.Context) {
+	group := c.Param("group")
+	if !services.GetGlobalCache().PurgeGroup(group) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cache group not found"})
+		return
+	}
+	logging.FromGin(c).Info("Smart cache group purged", "group", group)
+	c.JSON(http.StatusOK, gin.H{"message": "Cache group purged", "group": group})
+}
+
+// PurgeAllCaches empties every cache
+func PurgeAllCaches(c *gin.Context) {
+	if err := cache.PurgeAll(); err != nil {
+		logging.FromGin(c).Error("Failed to purge the caches", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge the caches"})
+		return
+	}
+	services.GetGlobalCache().InvalidatePattern("*")
+	logging.FromGin(c).Info("All caches purged")
+	c.JSON(http.StatusOK, gin.H{"message": "All caches purged"})
+}
@@ -1,17 +1,20 @@
 package api
 
 import (
-	"blog-backend/internal/database"
+	"blog-backend/internal/cache"
+	"blog-backend/internal/logging"
 	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
 )
 
 // Helper function to get the site's default language
-func getCategoryDefaultLanguage() string {
+func getCategoryDefaultLanguage(c *gin.Context) string {
 	var settings models.SiteSettings
-	if err := database.DB.First(&settings).Error; err != nil {
+	if err := siteDB(c).First(&settings).Error; err != nil {
 		// Fallback to 'zh' if unable to get settings
 		return "zh"
 	}
@@ -24,7 +27,7 @@ func getCategoryDefaultLanguage() string {
 func GetCategories(c *gin.Context) {
 	var categories []models.Category
 
-	query := database.DB.Preload("Translations")
+	query := siteDB(c).Preload("Translations")
 
 	if err := query.Find(&categories).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -33,7 +36,7 @@ func GetCategories(c *gin.Context) {
 
 	// Apply language filtering if requested
 	lang := c.Query("lang")
-	defaultLang := getCategoryDefaultLanguage()
+	defaultLang := getCategoryDefaultLanguage(c)
 	if lang != "" && lang != defaultLang {
 		for i := range categories {
 			applyCategoryTranslation(&categories[i], lang)
@@ -51,26 +54,61 @@ func GetCategory(c *gin.Context) {
 	}
 
 	var category models.Category
-	if err := database.DB.Preload("Articles").First(&category, id).Error; err != nil {
+	if err := siteDB(c).Preload("Articles").First(&category, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
 		return
 	}
+	category.Articles = withholdRegional(c, category.Articles)
 
 	c.JSON(http.StatusOK, category)
 }
 
+// GetCategoryTree returns the categories nested under their parents, with
+// the number of articles in each and in the categories below it
+func GetCategoryTree(c *gin.Context) {
+	tree, err := services.GetGlobalCategoryService().Tree(c.Request.Context(), c.Query("lang"), isAdminRequest(c))
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
+// GetCategoryBreadcrumb returns the category and the categories above it,
+// top first
+func GetCategoryBreadcrumb(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+	path, err := services.GetGlobalCategoryService().Path(c.Request.Context(), uint(id), c.Query("lang"))
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"breadcrumb": path})
+}
+
 func CreateCategory(c *gin.Context) {
 	var category models.Category
-	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &category) {
+		return
+	}
+
+	if err := services.GetGlobalCategoryService().ValidateParent(c.Request.Context(), 0, category.ParentID); err != nil {
+		respondCategoryError(c, err)
 		return
 	}
 
-	if err := database.DB.Create(&category).Error; err != nil {
+	stampCategoryTranslations(&category)
+
+	if err := siteDB(c).Create(&category).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	cache.Publish(cache.TopicCategories)
 	c.JSON(http.StatusCreated, category)
 }
 
@@ -82,24 +120,41 @@ func UpdateCategory(c *gin.Context) {
 	}
 
 	var category models.Category
-	if err := database.DB.First(&category, id).Error; err != nil {
+	if err := siteDB(c).First(&category, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
 		return
 	}
 
-	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &category) {
+		return
+	}
+
+	if err := services.GetGlobalCategoryService().ValidateParent(c.Request.Context(), category.ID, category.ParentID); err != nil {
+		respondCategoryError(c, err)
 		return
 	}
 
-	if err := database.DB.Save(&category).Error; err != nil {
+	stampCategoryTranslations(&category)
+
+	if err := siteDB(c).Save(&category).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	cache.Publish(cache.TopicCategories)
 	c.JSON(http.StatusOK, category)
 }
 
+// stampCategoryTranslations marks translations added with a category as
+// made from its current text
+func stampCategoryTranslations(category *models.Category) {
+	for i := range category.Translations {
+		if category.Translations[i].ID == 0 {
+			category.Translations[i].SourceHash = category.SourceHash()
+		}
+	}
+}
+
 func DeleteCategory(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -107,25 +162,50 @@ func DeleteCategory(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&models.Category{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Subcategories move up to the deleted category's parent
+	if err := services.GetGlobalCategoryService().Delete(c.Request.Context(), uint(id)); err != nil {
+		respondCategoryError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
 }
 
+// MergeCategory moves everything filed under a category to the target
+// category and deletes it. With ?dry_run=true it only reports what would
+// move.
+func MergeCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+	target, err := strconv.ParseUint(c.Param("target"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target category ID"})
+		return
+	}
+	merge, err := services.GetGlobalCategoryService().Merge(c.Request.Context(), uint(id), uint(target), c.Query("dry_run") == "true")
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, merge)
+}
+
+func respondCategoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidCategory):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Category operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Category operation failed"})
+	}
+}
+
 // Helper function to apply translation to a category
 func applyCategoryTranslation(category *models.Category, lang string) {
-	for _, translation := range category.Translations {
-		if translation.Language == lang {
-			if translation.Name != "" {
-				category.Name = translation.Name
-			}
-			if translation.Description != "" {
-				category.Description = translation.Description
-			}
-			break
-		}
-	}
+	services.ApplyCategoryTranslation(category, lang)
 }
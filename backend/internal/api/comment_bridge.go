@@ -0,0 +1,44 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCommentBridgeStatus returns the discussions and comment counts synced
+// from the external comment system and the latest sync
+func GetCommentBridgeStatus(c *gin.Context) {
+	status, err := services.GetGlobalCommentBridgeService().Status(c.Request.Context())
+	if err != nil {
+		respondCommentBridgeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// SyncCommentBridge queues a sync of the external comment system now;
+// follow the returned job for its progress
+func SyncCommentBridge(c *gin.Context) {
+	job, err := services.GetGlobalCommentBridgeService().Queue()
+	if err != nil {
+		respondCommentBridgeError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Comment sync queued", "job": job})
+}
+
+func respondCommentBridgeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCommentBridgeNotConfigured):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCommentSyncRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Comment bridge request failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Comment bridge request failed"})
+	}
+}
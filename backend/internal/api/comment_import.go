@@ -0,0 +1,67 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCommentImportSize is the largest comment export accepted
+const maxCommentImportSize = 50 << 20
+
+// ImportComments adds the comments of an uploaded Disqus, Artalk or Waline
+// export to the replies of the site's articles. The format is ?format=, or
+// told by the file name for Disqus XML and Artalk .artrans files.
+func ImportComments(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No comment export provided"})
+		return
+	}
+	if header.Size > maxCommentImportSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Comment export is too large"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxCommentImportSize))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".xml":
+			format = models.ReplySourceDisqus
+		case ".artrans":
+			format = models.ReplySourceArtalk
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "set ?format= to disqus, artalk or waline"})
+			return
+		}
+	}
+	result, err := services.GetGlobalCommentImporter().Import(c.Request.Context(), format, data)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCommentImport) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logging.FromGin(c).Error("Comment import failed", "format", format, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Comment import failed"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitContactMessage receives a message from the contact form. Messages
+// discarded as spam get the same answer as delivered ones.
+func SubmitContactMessage(c *gin.Context) {
+	var input services.ContactInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	_, err := services.GetGlobalContactService().Submit(c.Request.Context(), input, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondContactError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Thank you, your message has been sent"})
+}
+
+// ListContactMessages returns a page of the contact inbox with counts by
+// status. Filter with ?status=new|read|archived|spam; spam is only listed
+// when asked for.
+func ListContactMessages(c *gin.Context) {
+	paging, ok := bindPage(c, 50, maxAdminPageSize)
+	if !ok {
+		return
+	}
+	service := services.GetGlobalContactService()
+	messages, total, err := service.List(c.Request.Context(), c.Query("status"), paging.Page, paging.Limit)
+	if err != nil {
+		respondContactError(c, err)
+		return
+	}
+	stats, err := service.Stats(c.Request.Context())
+	if err != nil {
+		respondContactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"messages":   messages,
+		"stats":      stats,
+		"pagination": gin.H{"page": paging.Page, "limit": paging.Limit, "total": total},
+	})
+}
+
+// GetContactMessage returns one message
+func GetContactMessage(c *gin.Context) {
+	id, ok := contactMessageID(c)
+	if !ok {
+		return
+	}
+	message, err := services.GetGlobalContactService().Get(c.Request.Context(), id)
+	if err != nil {
+		respondContactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, message)
+}
+
+// UpdateContactMessage marks a message read, archived or spam
+// ({"status": ...}) and records a reply ({"replied": true})
+func UpdateContactMessage(c *gin.Context) {
+	id, ok := contactMessageID(c)
+	if !ok {
+		return
+	}
+	var update services.ContactUpdate
+	if !bindJSON(c, &update) {
+		return
+	}
+	message, err := services.GetGlobalContactService().Update(c.Request.Context(), id, update)
+	if err != nil {
+		respondContactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, message)
+}
+
+// DeleteContactMessage removes a message
+func DeleteContactMessage(c *gin.Context) {
+	id, ok := contactMessageID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalContactService().Delete(c.Request.Context(), id); err != nil {
+		respondContactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Message deleted"})
+}
+
+func contactMessageID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondContactError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrContactMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidEmail), errors.Is(err, services.ErrInvalidContactMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrContactRateLimited):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Contact message operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Contact message operation failed"})
+	}
+}
@@ -3,7 +3,6 @@ package api
 import (
 	"blog-backend/internal/services"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -68,15 +67,13 @@ func (cac *ContentAssistantController) AnalyzeTopicGaps(c *gin.Context) {
 func (cac *ContentAssistantController) GetWritingInspiration(c *gin.Context) {
 	category := c.DefaultQuery("category", "")
 	language := c.DefaultQuery("language", "en")
-	limitStr := c.DefaultQuery("limit", "5")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 5
-	}
-	if limit > 20 {
-		limit = 20 // Cap at 20 ideas
+	query := struct {
+		Limit int `form:"limit" binding:"min=1,max=20"` // Cap at 20 ideas
+	}{Limit: 5}
+	if !bindQuery(c, &query) {
+		return
 	}
+	limit := query.Limit
 
 	ideas, err := cac.contentAssistant.GetWritingInspiration(category, language, limit)
 	if err != nil {
@@ -97,11 +94,7 @@ func (cac *ContentAssistantController) GetWritingInspiration(c *gin.Context) {
 // GenerateSmartTags generates intelligent tags for content
 func (cac *ContentAssistantController) GenerateSmartTags(c *gin.Context) {
 	var req SmartTagsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -109,7 +102,7 @@ func (cac *ContentAssistantController) GenerateSmartTags(c *gin.Context) {
 		req.Language = "en"
 	}
 
-	tags, err := cac.contentAssistant.GenerateSmartTags(req.Content, req.Language)
+	tags, err := cac.contentAssistant.GenerateSmartTags(c.Request.Context(), req.Content, req.Language)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to generate smart tags",
@@ -128,11 +121,7 @@ func (cac *ContentAssistantController) GenerateSmartTags(c *gin.Context) {
 // RecommendSEOKeywords recommends SEO keywords for content
 func (cac *ContentAssistantController) RecommendSEOKeywords(c *gin.Context) {
 	var req SEOKeywordsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -208,11 +197,7 @@ func (cac *ContentAssistantController) ValidateContentIdea(c *gin.Context) {
 		Category string `json:"category"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -221,7 +206,7 @@ func (cac *ContentAssistantController) ValidateContentIdea(c *gin.Context) {
 	}
 
 	// Generate tags for the title to analyze the idea
-	tags, err := cac.contentAssistant.GenerateSmartTags(req.Title, req.Language)
+	tags, err := cac.contentAssistant.GenerateSmartTags(c.Request.Context(), req.Title, req.Language)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to validate content idea",
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetContentQualityReport lists the content quality of articles, weakest
+// first. language and max_score narrow the list; limit and offset page it.
+func GetContentQualityReport(c *gin.Context) {
+	slice, ok := bindLimit(c, 0, 0)
+	if !ok {
+		return
+	}
+	filter := services.ContentQualityFilter{Language: c.Query("language"), Limit: slice.Limit, Offset: slice.Offset}
+	if raw := c.Query("max_score"); raw != "" {
+		score, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max_score"})
+			return
+		}
+		filter.MaxScore = &score
+	}
+	analyses, total, err := services.GetGlobalContentQualityService().Report(filter)
+	if err != nil {
+		respondContentQualityError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"analyses": analyses, "total": total})
+}
+
+// GetArticleContentQuality returns the content quality of one article
+func GetArticleContentQuality(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+	analysis, err := services.GetGlobalContentQualityService().Get(uint(id))
+	if err != nil {
+		respondContentQualityError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, analysis)
+}
+
+// RunContentQuality queues an analysis of every article now
+func RunContentQuality(c *gin.Context) {
+	job, err := services.GetGlobalScheduler().Trigger(services.ContentQualityScheduleName)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func respondContentQualityError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrContentQualityNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	logging.FromGin(c).Error("Content quality operation failed", "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Content quality operation failed"})
+}
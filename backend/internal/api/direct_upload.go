@@ -0,0 +1,257 @@
+package api
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/events"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/storage"
+	"bytes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DirectUploadTTL is how long a presigned upload URL stays valid
+const DirectUploadTTL = 15 * time.Minute
+
+// directUploadStore returns the store direct uploads go to: the remote
+// storage driver when it can presign uploads, otherwise the S3 bucket from
+// S3_BUCKET. It returns nil when neither is available.
+func directUploadStore() storage.Driver {
+	if driver := storage.GetGlobalDriver(); storage.IsRemote() {
+		if _, ok := driver.(storage.Presigner); ok {
+			return driver
+		}
+	}
+	if storage.IsS3Enabled() {
+		return storage.GetGlobalS3Client()
+	}
+	return nil
+}
+
+// CreateDirectUpload issues a presigned URL so large files are uploaded
+// straight to object storage without passing through the Go process
+func CreateDirectUpload(c *gin.Context) {
+	store := directUploadStore()
+	if store == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Direct uploads require the S3 storage backend"})
+		return
+	}
+
+	var req struct {
+		FileName    string `json:"file_name" binding:"required"`
+		ContentType string `json:"content_type" binding:"required"`
+		Size        int64  `json:"size" binding:"required"`
+		Alt         string `json:"alt"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.Size <= 0 || req.Size > MaxFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file size exceeds 100MB limit"})
+		return
+	}
+
+	mediaType, subDir, statusCode, err := checkMediaType(c, req.ContentType, req.FileName, req.Size)
+	if err != nil {
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	token := uuid.New().String()
+	ext := strings.ToLower(filepath.Ext(req.FileName))
+	objectKey := fmt.Sprintf("uploads/%s/%s%s", siteUploadDir(c, subDir), uuid.New().String(), ext)
+
+	uploadURL, headers, err := store.(storage.Presigner).PresignPutObject(objectKey, req.ContentType, req.Size, DirectUploadTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload URL"})
+		return
+	}
+
+	upload := models.DirectUpload{
+		Token:        token,
+		ObjectKey:    objectKey,
+		OriginalName: req.FileName,
+		MimeType:     req.ContentType,
+		MediaType:    mediaType,
+		Size:         req.Size,
+		Alt:          strings.TrimSpace(req.Alt),
+		Status:       models.DirectUploadPending,
+		ExpiresAt:    time.Now().Add(DirectUploadTTL),
+	}
+	if err := siteDB(c).Create(&upload).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record upload"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":      token,
+		"upload_url": uploadURL,
+		"method":     http.MethodPut,
+		"headers":    headers,
+		"expires_at": upload.ExpiresAt,
+	})
+}
+
+// FinalizeDirectUpload validates the uploaded object with the same checks as
+// regular uploads and creates the media library record. The presigned URL
+// keeps working until it expires, so the validated content is copied to a
+// key of its own and the uploaded object removed; uploading again to the URL
+// cannot change the finalized file.
+func FinalizeDirectUpload(c *gin.Context) {
+	var upload models.DirectUpload
+	if err := siteDB(c).Where("token = ?", c.Param("token")).First(&upload).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	}
+
+	if upload.Status != models.DirectUploadPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload already " + upload.Status})
+		return
+	}
+
+	client := directUploadStore()
+	if client == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Direct uploads require the S3 storage backend"})
+		return
+	}
+
+	// Object uploads may finish just after the URL expires, so allow a grace period
+	if time.Now().After(upload.ExpiresAt.Add(DirectUploadTTL)) {
+		failDirectUpload(client, &upload, models.DirectUploadExpired, "upload token expired")
+		c.JSON(http.StatusGone, gin.H{"error": "Upload token expired"})
+		return
+	}
+
+	info, err := client.Stat(upload.ObjectKey)
+	if err == storage.ErrObjectNotFound {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File has not been uploaded yet"})
+		return
+	} else if err != nil {
+		logging.FromGin(c).Error("Failed to inspect direct upload", "key", upload.ObjectKey, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to inspect uploaded file"})
+		return
+	}
+
+	if info.Size != upload.Size || info.Size > MaxFileSize {
+		failDirectUpload(client, &upload, models.DirectUploadFailed, "uploaded size does not match declared size")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Uploaded size does not match declared size"})
+		return
+	}
+
+	reader, _, err := client.Get(upload.ObjectKey)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	fileContent, err := io.ReadAll(io.LimitReader(reader, MaxFileSize+1))
+	reader.Close()
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	cleanContent, report, statusCode, err := validateMediaContent(fileContent, upload.MimeType, upload.MediaType, upload.OriginalName)
+	if err != nil {
+		failDirectUpload(client, &upload, models.DirectUploadFailed, err.Error())
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Store the validated content, with images re-encoded without metadata,
+	// beside the uploaded object under a name the client was never given
+	finalKey := path.Join(path.Dir(upload.ObjectKey), uuid.New().String()+path.Ext(upload.ObjectKey))
+	if err := client.Put(finalKey, bytes.NewReader(cleanContent), int64(len(cleanContent)), upload.MimeType); err != nil {
+		logging.FromGin(c).Error("Failed to store finalized direct upload", "key", finalKey, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to store processed file"})
+		return
+	}
+
+	media := models.MediaLibrary{
+		FileName:     path.Base(finalKey),
+		OriginalName: upload.OriginalName,
+		FilePath:     client.Name() + "://" + finalKey,
+		FileSize:     int64(len(cleanContent)),
+		MimeType:     upload.MimeType,
+		MediaType:    upload.MediaType,
+		URL:          client.URL(finalKey),
+		Alt:          upload.Alt,
+	}
+	media.MetadataReport = report
+	if err := siteDB(c).Create(&media).Error; err != nil {
+		if err := client.Delete(finalKey); err != nil {
+			logging.FromGin(c).Warn("Failed to delete unrecorded direct upload", "key", finalKey, "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save media record"})
+		return
+	}
+	recordImageMetadata(c, &media)
+	if err := client.Delete(upload.ObjectKey); err != nil {
+		logging.FromGin(c).Warn("Failed to delete the uploaded object of a direct upload", "key", upload.ObjectKey, "error", err)
+	}
+
+	upload.Status = models.DirectUploadCompleted
+	upload.MediaID = &media.ID
+	siteDB(c).Save(&upload)
+
+	events.Dispatch(c.Request.Context(), hooks.MediaUploaded, &media)
+	c.JSON(http.StatusOK, media)
+}
+
+// failDirectUpload marks an upload as failed and removes the stored object
+func failDirectUpload(client storage.Driver, upload *models.DirectUpload, status, reason string) {
+	if err := client.Delete(upload.ObjectKey); err != nil {
+		slog.Warn("Failed to delete rejected upload", "key", upload.ObjectKey, "error", err)
+	}
+	upload.Status = status
+	upload.Error = reason
+	database.DB.Save(upload)
+}
+
+// expireStaleDirectUploads cleans up uploads that were never finalized and
+// returns how many were expired. It runs as a scheduled job.
+func expireStaleDirectUploads() (int, error) {
+	client := directUploadStore()
+	if client == nil {
+		return 0, nil
+	}
+
+	var stale []models.DirectUpload
+	cutoff := time.Now().Add(-DirectUploadTTL)
+	if err := database.DB.Where("status = ? AND expires_at < ?", models.DirectUploadPending, cutoff).Find(&stale).Error; err != nil {
+		return 0, err
+	}
+
+	for i := range stale {
+		failDirectUpload(client, &stale[i], models.DirectUploadExpired, "upload was never finalized")
+	}
+	return len(stale), nil
+}
+
+// removeMediaFile deletes the stored file for a media record from local disk
+// or the object store named by its file path, such as s3://uploads/...
+func removeMediaFile(media models.MediaLibrary) error {
+	scheme, key, ok := strings.Cut(media.FilePath, "://")
+	if !ok {
+		return os.Remove(media.FilePath)
+	}
+	driver := storage.GetGlobalDriver()
+	if scheme == storage.DriverS3 && driver.Name() != storage.DriverS3 {
+		driver = storage.GetGlobalS3Client()
+	}
+	if driver.Name() != scheme {
+		return fmt.Errorf("media is stored in %s storage, which is not configured", scheme)
+	}
+	return driver.Delete(key)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListDonationMethods returns the active donation methods for the sponsor
+// page. With ?article_id=, enabled tells whether they are shown under that
+// article.
+func ListDonationMethods(c *gin.Context) {
+	var articleID uint64
+	if value := c.Query("article_id"); value != "" {
+		var err error
+		if articleID, err = strconv.ParseUint(value, 10, 32); err != nil || articleID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+			return
+		}
+	}
+	methods, enabled, err := services.GetGlobalDonationService().Public(c.Request.Context(), uint(articleID))
+	if err != nil {
+		respondDonationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": enabled, "methods": methods})
+}
+
+// TrackDonationClick counts a reader opening a donation method
+func TrackDonationClick(c *gin.Context) {
+	id, ok := donationMethodID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalDonationService().Click(c.Request.Context(), id, c.ClientIP()); err != nil {
+		respondDonationError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListAllDonationMethods returns every donation method with its click count
+func ListAllDonationMethods(c *gin.Context) {
+	methods, err := services.GetGlobalDonationService().List(c.Request.Context(), false)
+	if err != nil {
+		respondDonationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"methods": methods})
+}
+
+// CreateDonationMethod adds a donation method at the end of the list
+func CreateDonationMethod(c *gin.Context) {
+	var input services.DonationMethodInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	method, err := services.GetGlobalDonationService().Create(c.Request.Context(), input)
+	if err != nil {
+		respondDonationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, method)
+}
+
+// UpdateDonationMethod edits a donation method
+func UpdateDonationMethod(c *gin.Context) {
+	id, ok := donationMethodID(c)
+	if !ok {
+		return
+	}
+	var input services.DonationMethodInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	method, err := services.GetGlobalDonationService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondDonationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, method)
+}
+
+// DeleteDonationMethod removes a donation method
+func DeleteDonationMethod(c *gin.Context) {
+	id, ok := donationMethodID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalDonationService().Delete(c.Request.Context(), id); err != nil {
+		respondDonationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Donation method deleted"})
+}
+
+// UpdateDonationMethodOrder sets the display order of donation methods
+// ([{"id": 1, "order": 2}, ...])
+func UpdateDonationMethodOrder(c *gin.Context) {
+	var orders []services.DonationMethodOrder
+	if !bindJSON(c, &orders) {
+		return
+	}
+	if err := services.GetGlobalDonationService().Reorder(c.Request.Context(), orders); err != nil {
+		respondDonationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Order updated"})
+}
+
+func donationMethodID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondDonationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDonationMethodNotFound), errors.Is(err, services.ErrArticleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidDonationMethod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Donation operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Donation operation failed"})
+	}
+}
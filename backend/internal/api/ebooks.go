@@ -0,0 +1,66 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListEbooks returns the site's exported EPUB books, newest first
+func ListEbooks(c *gin.Context) {
+	books, err := services.GetGlobalEbookService().List(currentSiteID(c))
+	if err != nil {
+		respondEbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ebooks": books})
+}
+
+// CreateEbook queues the export of a category or a list of articles as an
+// EPUB book, downloadable under the returned name once the job succeeds
+func CreateEbook(c *gin.Context) {
+	var req services.EbookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	export, err := services.GetGlobalEbookService().Queue(c.Request.Context(), currentSiteID(c), getBaseURL(c), req)
+	if err != nil {
+		respondEbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, export)
+}
+
+// DownloadEbook streams an exported book
+func DownloadEbook(c *gin.Context) {
+	path, err := services.GetGlobalEbookService().Path(currentSiteID(c), c.Param("name"))
+	if err != nil {
+		respondEbookError(c, err)
+		return
+	}
+	c.FileAttachment(path, c.Param("name"))
+}
+
+// DeleteEbook removes an exported book
+func DeleteEbook(c *gin.Context) {
+	if err := services.GetGlobalEbookService().Delete(currentSiteID(c), c.Param("name")); err != nil {
+		respondEbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Ebook deleted"})
+}
+
+func respondEbookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidEbook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEbookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ebook not found"})
+	default:
+		logging.FromGin(c).Error("Ebook operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ebook operation failed"})
+	}
+}
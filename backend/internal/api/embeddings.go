@@ -6,13 +6,11 @@ import (
 	"blog-backend/internal/services"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Global embedding service instance
-var globalEmbeddingService *services.EmbeddingService
-
 // EmbeddingController handles embedding-related API endpoints
 type EmbeddingController struct {
 	embeddingService *services.EmbeddingService
@@ -20,24 +18,20 @@ type EmbeddingController struct {
 
 // NewEmbeddingController creates a new embedding controller
 func NewEmbeddingController() *EmbeddingController {
-	if globalEmbeddingService == nil {
-		globalEmbeddingService = services.NewEmbeddingService()
-	}
 	return &EmbeddingController{
-		embeddingService: globalEmbeddingService,
+		embeddingService: GetGlobalEmbeddingService(),
 	}
 }
 
-// GetGlobalEmbeddingService returns the global embedding service instance
+// GetGlobalEmbeddingService returns the embedding service shared with
+// recommendations, so reloading its settings reaches both
 func GetGlobalEmbeddingService() *services.EmbeddingService {
-	if globalEmbeddingService == nil {
-		globalEmbeddingService = services.NewEmbeddingService()
-	}
-	return globalEmbeddingService
+	return services.GetGlobalEmbeddingService()
 }
 
-// isRAGAvailable checks if RAG services are available and operational
-func (ec *EmbeddingController) isRAGAvailable() bool {
+// isRAGAvailable checks if RAG services are available and operational for
+// the request's site
+func (ec *EmbeddingController) isRAGAvailable(c *gin.Context) bool {
 	// Check if embedding service is available
 	if ec.embeddingService == nil {
 		return false
@@ -50,7 +44,7 @@ func (ec *EmbeddingController) isRAGAvailable() bool {
 
 	// Check if there are embeddings in the database
 	var embeddingCount int64
-	database.DB.Model(&models.ArticleEmbedding{}).Count(&embeddingCount)
+	services.SiteEmbeddings(siteDB(c)).Count(&embeddingCount)
 
 	// RAG is available if we have embeddings and services are configured
 	return embeddingCount > 0
@@ -80,8 +74,13 @@ func (ec *EmbeddingController) ProcessArticleEmbeddings(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
 		return
 	}
+	var article models.Article
+	if err := siteDB(c).Select("id").First(&article, articleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
 
-	err = ec.embeddingService.ProcessArticleEmbeddings(uint(articleID))
+	err = ec.embeddingService.ProcessArticleEmbeddings(c.Request.Context(), uint(articleID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -93,24 +92,24 @@ func (ec *EmbeddingController) ProcessArticleEmbeddings(c *gin.Context) {
 	})
 }
 
-// BatchProcessEmbeddings processes embeddings for all articles
+// BatchProcessEmbeddings queues embedding processing for all articles
 func (ec *EmbeddingController) BatchProcessEmbeddings(c *gin.Context) {
-	err := ec.embeddingService.BatchProcessAllArticles()
+	job, err := services.GetGlobalJobQueue().Enqueue(JobEmbeddingsBatch, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Batch processing completed successfully",
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Batch processing queued",
+		"job":     job,
 	})
 }
 
 // SemanticSearch performs semantic search using embeddings
 func (ec *EmbeddingController) SemanticSearch(c *gin.Context) {
 	var req SemanticSearchRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -126,7 +125,7 @@ func (ec *EmbeddingController) SemanticSearch(c *gin.Context) {
 	}
 
 	// Perform search
-	results, err := ec.embeddingService.SearchSimilarArticles(req.Query, req.Language, req.Limit, req.Threshold)
+	results, err := ec.embeddingService.SearchSimilarArticles(c.Request.Context(), req.Query, req.Language, req.Limit, req.Threshold)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -148,8 +147,7 @@ func (ec *EmbeddingController) SemanticSearch(c *gin.Context) {
 // HybridSearch combines keyword and semantic search
 func (ec *EmbeddingController) HybridSearch(c *gin.Context) {
 	var req SemanticSearchRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -165,7 +163,7 @@ func (ec *EmbeddingController) HybridSearch(c *gin.Context) {
 	}
 
 	// Perform semantic search
-	semanticResults, err := ec.embeddingService.SearchSimilarArticles(req.Query, req.Language, req.Limit*2, req.Threshold)
+	semanticResults, err := ec.embeddingService.SearchSimilarArticles(c.Request.Context(), req.Query, req.Language, req.Limit*2, req.Threshold)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -186,7 +184,7 @@ func (ec *EmbeddingController) HybridSearch(c *gin.Context) {
 // GetSimilarArticles returns articles similar to a given article
 func (ec *EmbeddingController) GetSimilarArticles(c *gin.Context) {
 	// Check if RAG services are available
-	if !ec.isRAGAvailable() {
+	if !ec.isRAGAvailable(c) {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
 			"error":   "Similar articles service temporarily unavailable",
 			"details": "RAG (Retrieval-Augmented Generation) services are not configured or available",
@@ -205,33 +203,33 @@ func (ec *EmbeddingController) GetSimilarArticles(c *gin.Context) {
 
 	// Get article content
 	var article models.Article
-	if err := database.DB.First(&article, articleID).Error; err != nil {
+	if err := siteDB(c).First(&article, articleID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
 		return
 	}
 
 	// Get language from query param
 	language := c.DefaultQuery("language", article.DefaultLang)
-	limit := 5 // Default to 5 similar articles
-
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
+	query := struct {
+		Limit int `form:"limit" binding:"min=1,max=50"`
+	}{Limit: 5} // Default to 5 similar articles
+	if !bindQuery(c, &query) {
+		return
 	}
+	limit := query.Limit
 
 	// Use article content as search query
 	searchText := article.Title + " " + article.Summary
 	if language != article.DefaultLang {
 		// Try to get translation
 		var translation models.ArticleTranslation
-		if err := database.DB.Where("article_id = ? AND language = ?", articleID, language).First(&translation).Error; err == nil {
+		if err := siteDB(c).Where("article_id = ? AND language = ?", articleID, language).First(&translation).Error; err == nil {
 			searchText = translation.Title + " " + translation.Summary
 		}
 	}
 
 	// Perform search (exclude the current article)
-	results, err := ec.embeddingService.SearchSimilarArticles(searchText, language, limit+5, 0.5)
+	results, err := ec.embeddingService.SearchSimilarArticles(c.Request.Context(), searchText, language, limit+5, 0.5)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -257,9 +255,9 @@ func (ec *EmbeddingController) GetSimilarArticles(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// GetEmbeddingStats returns statistics about embeddings
+// GetEmbeddingStats returns statistics about the site's embeddings
 func (ec *EmbeddingController) GetEmbeddingStats(c *gin.Context) {
-	stats, err := ec.embeddingService.GetEmbeddingStats()
+	stats, err := ec.embeddingService.GetEmbeddingStats(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -280,7 +278,7 @@ func (ec *EmbeddingController) DeleteArticleEmbeddings(c *gin.Context) {
 	}
 
 	// Delete embeddings
-	result := database.DB.Where("article_id = ?", articleID).Delete(&models.ArticleEmbedding{})
+	result := services.SiteEmbeddings(siteDB(c)).Where("article_id = ?", articleID).Delete(&models.ArticleEmbedding{})
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
 		return
@@ -293,23 +291,17 @@ func (ec *EmbeddingController) DeleteArticleEmbeddings(c *gin.Context) {
 	})
 }
 
-// RebuildEmbeddings rebuilds all embeddings
+// RebuildEmbeddings queues a rebuild of all embeddings
 func (ec *EmbeddingController) RebuildEmbeddings(c *gin.Context) {
-	// Delete all existing embeddings
-	if err := database.DB.Exec("DELETE FROM article_embeddings").Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear existing embeddings"})
-		return
-	}
-
-	// Rebuild all embeddings
-	err := ec.embeddingService.BatchProcessAllArticles()
+	job, err := services.GetGlobalJobQueue().Enqueue(JobEmbeddingsRebuild, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Embeddings rebuilt successfully",
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Embeddings rebuild queued",
+		"job":     job,
 	})
 }
 
@@ -330,8 +322,7 @@ func (ec *EmbeddingController) SetDefaultProvider(c *gin.Context) {
 		Provider string `json:"provider" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -347,14 +338,15 @@ func (ec *EmbeddingController) SetDefaultProvider(c *gin.Context) {
 	})
 }
 
-// GetEmbeddingTrends returns embedding generation trends
+// GetEmbeddingTrends returns embedding generation trends of the site
 func (ec *EmbeddingController) GetEmbeddingTrends(c *gin.Context) {
-	days := 30 // Default to 30 days
-	if daysStr := c.Query("days"); daysStr != "" {
-		if parsedDays, err := strconv.Atoi(daysStr); err == nil && parsedDays > 0 {
-			days = parsedDays
-		}
+	options := struct {
+		Days int `form:"days" binding:"min=1,max=365"`
+	}{Days: 30} // Default to 30 days
+	if !bindQuery(c, &options) {
+		return
 	}
+	days := options.Days
 
 	// Query embedding creation trends
 	var trends []struct {
@@ -363,18 +355,15 @@ func (ec *EmbeddingController) GetEmbeddingTrends(c *gin.Context) {
 		Provider string `json:"provider"`
 	}
 
-	query := `
-		SELECT 
-			DATE(created_at) as date, 
-			COUNT(*) as count,
-			provider
-		FROM article_embeddings 
-		WHERE created_at >= datetime('now', '-' || ? || ' days')
-		GROUP BY DATE(created_at), provider
-		ORDER BY date DESC
-	`
-
-	if err := database.DB.Raw(query, days).Scan(&trends).Error; err != nil {
+	since := time.Now().AddDate(0, 0, -days)
+	dateExpr := database.DateExpr("created_at")
+	err := services.SiteEmbeddings(siteDB(c)).
+		Select(dateExpr+" as date, COUNT(*) as count, provider").
+		Where("created_at >= ?", since).
+		Group(dateExpr + ", provider").
+		Order("date DESC").
+		Scan(&trends).Error
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trends"})
 		return
 	}
@@ -389,13 +378,13 @@ func (ec *EmbeddingController) GetEmbeddingTrends(c *gin.Context) {
 func (ec *EmbeddingController) GetEmbeddingVectors(c *gin.Context) {
 	method := c.DefaultQuery("method", "pca") // pca, tsne, umap
 	dimensions := 2                           // Fixed to 2D for now
-	limit := 200                              // Limit for performance
-
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 1000 {
-			limit = parsedLimit
-		}
+	query := struct {
+		Limit int `form:"limit" binding:"min=1,max=1000"`
+	}{Limit: 200} // Limit for performance
+	if !bindQuery(c, &query) {
+		return
 	}
+	limit := query.Limit
 
 	vectors, err := ec.embeddingService.GetReducedVectors(method, dimensions, limit)
 	if err != nil {
@@ -463,14 +452,15 @@ func (ec *EmbeddingController) GetRAGProcessVisualization(c *gin.Context) {
 	}
 
 	language := c.DefaultQuery("language", "en")
-	limit := 10
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 50 {
-			limit = parsedLimit
-		}
+	options := struct {
+		Limit int `form:"limit" binding:"min=1,max=50"`
+	}{Limit: 10}
+	if !bindQuery(c, &options) {
+		return
 	}
+	limit := options.Limit
 
-	processData, err := ec.embeddingService.GetRAGProcessVisualization(query, language, limit)
+	processData, err := ec.embeddingService.GetRAGProcessVisualization(c.Request.Context(), query, language, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -504,7 +494,7 @@ func (ec *EmbeddingController) GetRAGServiceStatus(c *gin.Context) {
 
 	// Check if there are any embeddings in the database
 	var embeddingCount int64
-	database.DB.Model(&models.ArticleEmbedding{}).Count(&embeddingCount)
+	services.SiteEmbeddings(siteDB(c)).Count(&embeddingCount)
 
 	// Check recommendation engine availability
 	isRecommendationAvailable := false
@@ -0,0 +1,331 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode identifies the kind of failure an error response reports, so
+// clients can act on it without parsing the message
+type ErrorCode string
+
+// Error codes of the API. Most follow from the HTTP status; the others
+// name a failure clients may want to tell apart from the rest of its
+// status.
+const (
+	ErrCodeInvalidRequest      ErrorCode = "invalid_request"
+	ErrCodeValidation          ErrorCode = "validation_failed"
+	ErrCodeUnauthorized        ErrorCode = "unauthorized"
+	ErrCodeInvalidCredentials  ErrorCode = "invalid_credentials"
+	ErrCodeForbidden           ErrorCode = "forbidden"
+	ErrCodeNotFound            ErrorCode = "not_found"
+	ErrCodeFeatureDisabled     ErrorCode = "feature_disabled"
+	ErrCodeConflict            ErrorCode = "conflict"
+	ErrCodeGone                ErrorCode = "gone"
+	ErrCodeUnavailableInRegion ErrorCode = "unavailable_in_region"
+	ErrCodePayloadTooLarge     ErrorCode = "payload_too_large"
+	ErrCodeUnprocessable       ErrorCode = "unprocessable"
+	ErrCodeMissingTranslations ErrorCode = "missing_translations"
+	ErrCodeChecklistFailed     ErrorCode = "checklist_failed"
+	ErrCodeRateLimited         ErrorCode = "rate_limited"
+	ErrCodeBusy                ErrorCode = "busy"
+	ErrCodeInternal            ErrorCode = "internal_error"
+	ErrCodeNotImplemented      ErrorCode = "not_implemented"
+	ErrCodeUpstream            ErrorCode = "upstream_error"
+	ErrCodeUnavailable         ErrorCode = "unavailable"
+	ErrCodeMaintenance         ErrorCode = "maintenance"
+	ErrCodeTimeout             ErrorCode = "timeout"
+)
+
+// errorMessageLanguage is the language of error messages for readers
+// whose language has no translation
+const errorMessageLanguage = "en"
+
+// errorCatalogEntry describes an error code: the status it is sent with
+// and what it means, by base language
+type errorCatalogEntry struct {
+	Status   int
+	Messages map[string]string
+}
+
+// errorCatalog holds every error code of the API
+var errorCatalog = map[ErrorCode]errorCatalogEntry{
+	ErrCodeInvalidRequest: {http.StatusBadRequest, map[string]string{
+		"en": "The request is invalid",
+		"zh": "请求无效",
+	}},
+	ErrCodeValidation: {http.StatusBadRequest, map[string]string{
+		"en": "Some fields of the request are invalid",
+		"zh": "请求中的部分字段无效",
+	}},
+	ErrCodeUnauthorized: {http.StatusUnauthorized, map[string]string{
+		"en": "Sign in to continue",
+		"zh": "请先登录",
+	}},
+	ErrCodeInvalidCredentials: {http.StatusUnauthorized, map[string]string{
+		"en": "The username or password is incorrect",
+		"zh": "用户名或密码错误",
+	}},
+	ErrCodeForbidden: {http.StatusForbidden, map[string]string{
+		"en": "You do not have permission to do this",
+		"zh": "没有执行此操作的权限",
+	}},
+	ErrCodeNotFound: {http.StatusNotFound, map[string]string{
+		"en": "The requested item was not found",
+		"zh": "请求的内容不存在",
+	}},
+	ErrCodeFeatureDisabled: {http.StatusNotFound, map[string]string{
+		"en": "This feature is not enabled",
+		"zh": "此功能未启用",
+	}},
+	ErrCodeConflict: {http.StatusConflict, map[string]string{
+		"en": "The request conflicts with the current state",
+		"zh": "请求与当前状态冲突",
+	}},
+	ErrCodeGone: {http.StatusGone, map[string]string{
+		"en": "This is no longer available",
+		"zh": "此内容已不再可用",
+	}},
+	ErrCodeUnavailableInRegion: {http.StatusUnavailableForLegalReasons, map[string]string{
+		"en": "This is not available in your region",
+		"zh": "此内容在您所在的地区不可用",
+	}},
+	ErrCodePayloadTooLarge: {http.StatusRequestEntityTooLarge, map[string]string{
+		"en": "The upload is too large",
+		"zh": "上传内容过大",
+	}},
+	ErrCodeUnprocessable: {http.StatusUnprocessableEntity, map[string]string{
+		"en": "The request could not be processed",
+		"zh": "无法处理该请求",
+	}},
+	ErrCodeMissingTranslations: {http.StatusUnprocessableEntity, map[string]string{
+		"en": "Translations are missing for required languages",
+		"zh": "必需语言的翻译缺失",
+	}},
+	ErrCodeChecklistFailed: {http.StatusUnprocessableEntity, map[string]string{
+		"en": "The article does not meet the publish checklist",
+		"zh": "文章未满足发布检查清单",
+	}},
+	ErrCodeRateLimited: {http.StatusTooManyRequests, map[string]string{
+		"en": "Too many requests, try again later",
+		"zh": "请求过于频繁，请稍后再试",
+	}},
+	ErrCodeBusy: {http.StatusTooManyRequests, map[string]string{
+		"en": "The server is busy, try again shortly",
+		"zh": "服务器繁忙，请稍后再试",
+	}},
+	ErrCodeInternal: {http.StatusInternalServerError, map[string]string{
+		"en": "Something went wrong on the server",
+		"zh": "服务器内部错误",
+	}},
+	ErrCodeNotImplemented: {http.StatusNotImplemented, map[string]string{
+		"en": "This is not supported",
+		"zh": "暂不支持此操作",
+	}},
+	ErrCodeUpstream: {http.StatusBadGateway, map[string]string{
+		"en": "An external service failed to respond",
+		"zh": "外部服务响应失败",
+	}},
+	ErrCodeUnavailable: {http.StatusServiceUnavailable, map[string]string{
+		"en": "The service is temporarily unavailable",
+		"zh": "服务暂时不可用",
+	}},
+	ErrCodeMaintenance: {http.StatusServiceUnavailable, map[string]string{
+		"en": "The site is undergoing maintenance",
+		"zh": "网站正在维护中",
+	}},
+	ErrCodeTimeout: {http.StatusServiceUnavailable, map[string]string{
+		"en": "The request took too long, try again later",
+		"zh": "请求超时，请稍后再试",
+	}},
+}
+
+// statusErrorCodes are the codes of error responses that name none
+var statusErrorCodes = map[int]ErrorCode{
+	http.StatusBadRequest:            ErrCodeInvalidRequest,
+	http.StatusUnauthorized:          ErrCodeUnauthorized,
+	http.StatusForbidden:             ErrCodeForbidden,
+	http.StatusNotFound:              ErrCodeNotFound,
+	http.StatusConflict:              ErrCodeConflict,
+	http.StatusGone:                  ErrCodeGone,
+	http.StatusRequestEntityTooLarge: ErrCodePayloadTooLarge,
+	http.StatusUnprocessableEntity:   ErrCodeUnprocessable,
+	http.StatusTooManyRequests:       ErrCodeRateLimited,
+	http.StatusInternalServerError:   ErrCodeInternal,
+	http.StatusNotImplemented:        ErrCodeNotImplemented,
+	http.StatusBadGateway:            ErrCodeUpstream,
+	http.StatusServiceUnavailable:    ErrCodeUnavailable,
+	http.StatusGatewayTimeout:        ErrCodeTimeout,
+}
+
+// errorCodeForStatus returns the code of an error response that names none
+func errorCodeForStatus(status int) ErrorCode {
+	if code, ok := statusErrorCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return ErrCodeInternal
+	}
+	return ErrCodeInvalidRequest
+}
+
+// respondError aborts the request with an error response naming code
+func respondError(c *gin.Context, status int, code ErrorCode, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": message, "code": code})
+}
+
+// localizedErrorMessage returns what code means in the reader's language
+func localizedErrorMessage(code ErrorCode, acceptLanguage string) string {
+	messages := errorCatalog[code].Messages
+	languages := make([]string, 0, len(messages))
+	for language := range messages {
+		languages = append(languages, language)
+	}
+	language := services.NegotiateLanguage(languages, errorMessageLanguage, "", acceptLanguage, nil).Language
+	return messages[language]
+}
+
+// ErrorEnvelope gives every JSON error response the same shape. Handlers
+// answer {"error": message}, optionally with a "code" from the catalog and
+// "details"; the envelope adds the code that follows from the status when
+// there is none, the message under "message" as well, what the code means
+// in the reader's language under "localized_message" and the request ID.
+// "error" stays a string for existing clients, and other fields are kept.
+func ErrorEnvelope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		original := c.Writer
+		writer := &errorEnvelopeWriter{ResponseWriter: original}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+		if writer.status == 0 {
+			return
+		}
+
+		body := writer.body.Bytes()
+		if strings.HasPrefix(original.Header().Get("Content-Type"), "application/json") {
+			if wrapped, ok := wrapErrorBody(body, writer.status, logging.GetRequestID(c), c.GetHeader("Accept-Language")); ok {
+				body = wrapped
+				original.Header().Del("Content-Length")
+			}
+		}
+		original.WriteHeader(writer.status)
+		original.Write(body)
+	}
+}
+
+// wrapErrorBody puts an {"error": message} body into the error envelope
+func wrapErrorBody(body []byte, status int, requestID, acceptLanguage string) ([]byte, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, false
+	}
+	var message string
+	if err := json.Unmarshal(fields["error"], &message); err != nil {
+		return nil, false
+	}
+	var code ErrorCode
+	if raw, ok := fields["code"]; !ok || json.Unmarshal(raw, &code) != nil || code == "" {
+		code = errorCodeForStatus(status)
+	}
+	set := func(key string, value interface{}) {
+		if encoded, err := json.Marshal(value); err == nil {
+			fields[key] = encoded
+		}
+	}
+	set("code", code)
+	set("message", message)
+	set("localized_message", localizedErrorMessage(code, acceptLanguage))
+	if requestID != "" {
+		set("request_id", requestID)
+	}
+	wrapped, err := json.Marshal(fields)
+	if err != nil {
+		return nil, false
+	}
+	return wrapped, true
+}
+
+// errorEnvelopeWriter holds error responses back so ErrorEnvelope can
+// rewrite them, and passes every other response through as it is written
+type errorEnvelopeWriter struct {
+	gin.ResponseWriter
+	// status is set once the handler answers with an error
+	status int
+	body   bytes.Buffer
+}
+
+func (w *errorEnvelopeWriter) WriteHeader(code int) {
+	if code >= http.StatusBadRequest && !w.ResponseWriter.Written() {
+		w.status = code
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *errorEnvelopeWriter) WriteHeaderNow() {
+	if w.status == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *errorEnvelopeWriter) Write(data []byte) (int, error) {
+	if w.status != 0 {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *errorEnvelopeWriter) WriteString(s string) (int, error) {
+	if w.status != 0 {
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *errorEnvelopeWriter) Flush() {
+	if w.status == 0 {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *errorEnvelopeWriter) Status() int {
+	if w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *errorEnvelopeWriter) Written() bool {
+	return w.status != 0 || w.ResponseWriter.Written()
+}
+
+// ErrorCatalogEntry describes an error code of the API
+type ErrorCatalogEntry struct {
+	Code     ErrorCode         `json:"code"`
+	Status   int               `json:"status"`
+	Messages map[string]string `json:"messages"`
+}
+
+// GetErrorCatalog lists the error codes of the API with the status they
+// are sent with and their messages by language
+func GetErrorCatalog(c *gin.Context) {
+	entries := make([]ErrorCatalogEntry, 0, len(errorCatalog))
+	for code, entry := range errorCatalog {
+		entries = append(entries, ErrorCatalogEntry{Code: code, Status: entry.Status, Messages: entry.Messages})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Status != entries[j].Status {
+			return entries[i].Status < entries[j].Status
+		}
+		return entries[i].Code < entries[j].Code
+	})
+	c.JSON(http.StatusOK, gin.H{"errors": entries})
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(logging.RequestID(), ErrorEnvelope())
+	r.GET("/missing", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+	})
+	r.GET("/disabled", func(c *gin.Context) {
+		respondError(c, http.StatusNotFound, ErrCodeFeatureDisabled, "Feature not enabled")
+	})
+	r.GET("/translations", func(c *gin.Context) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Missing: ja", "code": ErrCodeMissingTranslations, "missing_languages": []string{"ja"}})
+	})
+	r.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"error": "not an error"})
+	})
+	r.GET("/text", func(c *gin.Context) {
+		c.String(http.StatusBadRequest, "bad range")
+	})
+
+	get := func(path, language string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Language", language)
+		req.Header.Set(logging.RequestIDHeader, "req-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w, body
+	}
+
+	w, body := get("/missing", "zh-CN,zh;q=0.9")
+	if w.Code != http.StatusNotFound || body["error"] != "Article not found" || body["message"] != "Article not found" ||
+		body["code"] != string(ErrCodeNotFound) || body["localized_message"] != errorCatalog[ErrCodeNotFound].Messages["zh"] ||
+		body["request_id"] != "req-1" {
+		t.Errorf("not found: %d %v", w.Code, body)
+	}
+
+	// Languages without translations get English
+	_, body = get("/disabled", "fr")
+	if body["code"] != string(ErrCodeFeatureDisabled) || body["localized_message"] != errorCatalog[ErrCodeFeatureDisabled].Messages["en"] {
+		t.Errorf("feature disabled: %v", body)
+	}
+
+	_, body = get("/translations", "")
+	if body["code"] != string(ErrCodeMissingTranslations) || body["missing_languages"] == nil {
+		t.Errorf("missing translations: %v", body)
+	}
+
+	if w, body := get("/ok", ""); w.Code != http.StatusOK || body["code"] != nil {
+		t.Errorf("success response changed: %d %v", w.Code, body)
+	}
+	if w, _ := get("/text", ""); w.Code != http.StatusBadRequest || w.Body.String() != "bad range" {
+		t.Errorf("text error changed: %d %q", w.Code, w.Body.String())
+	}
+
+	for code, entry := range errorCatalog {
+		if entry.Messages["en"] == "" || entry.Messages["zh"] == "" {
+			t.Errorf("%s: missing a message", code)
+		}
+	}
+}
@@ -1,24 +1,33 @@
 package api
 
 import (
-	"archive/zip"
-	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/logging"
 	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// ExportArticle exports a single article as markdown file
+// ExportArticle exports a single article as markdown file, as an HTML
+// page with ?format=html, or with ?format=json as an article document that
+// ImportArticle on another instance can load
 func ExportArticle(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
 		return
 	}
+	if c.Query("format") == "json" {
+		exportArticleDocument(c, uint(id))
+		return
+	}
 
 	lang := c.Query("lang")
 	if lang == "" {
@@ -26,21 +35,40 @@ func ExportArticle(c *gin.Context) {
 	}
 
 	var article models.Article
-	if err := database.DB.Preload("Category").Preload("Translations").First(&article, id).Error; err != nil {
+	if err := siteDB(c).Preload("Category").Preload("Translations").First(&article, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
 		return
 	}
 
 	// Apply translation if needed
+	language := article.DefaultLang
 	if lang != "zh" && lang != "" {
-		applyTranslation(&article, lang)
+		services.ApplyTranslation(&article, lang)
+		for _, translation := range article.Translations {
+			if translation.Language == lang && translation.Content != "" {
+				language = lang
+			}
+		}
+	}
+
+	// A standalone page with code, math and diagrams rendered
+	if c.Query("format") == "html" {
+		page, err := services.ArticleHTMLPage(c.Request.Context(), article, language)
+		if err != nil {
+			logging.FromGin(c).Error("Failed to render article", "article_id", article.ID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render article"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.html\"", services.SafeFilename(article.Title)))
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+		return
 	}
 
 	// Generate markdown content
-	markdown := generateMarkdown(article)
+	markdown := services.ArticleMarkdown(article)
 
 	// Generate filename
-	safeTitle := sanitizeFilename(article.Title)
+	safeTitle := services.SafeFilename(article.Title)
 	filename := fmt.Sprintf("%s.md", safeTitle)
 
 	// Set headers for file download
@@ -51,6 +79,48 @@ func ExportArticle(c *gin.Context) {
 	c.String(http.StatusOK, markdown)
 }
 
+// exportArticleDocument downloads the article, its translations, category
+// and media references as one JSON document
+func exportArticleDocument(c *gin.Context, id uint) {
+	doc, err := services.ExportArticleDocument(siteDB(c), id, getBaseURL(c))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+	if err != nil {
+		logging.FromGin(c).Error("Failed to export article document", "article_id", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export article"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.kuno-article.json\"", services.SafeFilename(doc.Article.Title)))
+	c.JSON(http.StatusOK, doc)
+}
+
+// ImportArticle creates an article from a document exported by another
+// instance with ?format=json. Its category, media and slugs are matched to
+// this site's and the response maps the exported ids to the new ones.
+func ImportArticle(c *gin.Context) {
+	var doc services.ArticleDocument
+	if !bindJSON(c, &doc) {
+		return
+	}
+	article, result, err := services.ImportArticleDocument(c.Request.Context(), siteDB(c), &doc, currentAuthorID(c))
+	switch {
+	case errors.Is(err, services.ErrInvalidArticleDocument), errors.Is(err, services.ErrUnsupportedArticleDocument):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrArticleAlreadyImported):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.As(err, new(*hooks.RejectError)):
+		respondHookError(c, err)
+	case err != nil:
+		logging.FromGin(c).Error("Failed to import article document", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import article"})
+	default:
+		notifyArticleSaved(c, article, false)
+		c.JSON(http.StatusCreated, gin.H{"message": "Article imported", "article": article, "result": result})
+	}
+}
+
 // ExportArticles exports multiple articles as a zip file
 func ExportArticles(c *gin.Context) {
 	lang := c.Query("lang")
@@ -62,7 +132,7 @@ func ExportArticles(c *gin.Context) {
 	articleIDs := c.Query("article_ids") // Comma-separated list of article IDs
 
 	var articles []models.Article
-	query := database.DB.Preload("Category").Preload("Translations")
+	query := siteDB(c).Preload("Category").Preload("Translations")
 
 	if articleIDs != "" {
 		// Export specific articles
@@ -90,35 +160,8 @@ func ExportArticles(c *gin.Context) {
 	c.Header("Content-Type", "application/zip")
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"articles-export-%s.zip\"", time.Now().Format("2006-01-02")))
 
-	zipWriter := zip.NewWriter(c.Writer)
-	defer zipWriter.Close()
-
-	for _, article := range articles {
-		// Apply translation if needed
-		if lang != "zh" && lang != "" {
-			applyTranslation(&article, lang)
-		}
-
-		// Generate markdown content
-		markdown := generateMarkdown(article)
-
-		// Generate filename
-		safeTitle := sanitizeFilename(article.Title)
-		filename := fmt.Sprintf("%s.md", safeTitle)
-
-		// Create file in zip
-		fileWriter, err := zipWriter.Create(filename)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create zip file"})
-			return
-		}
-
-		// Write markdown content to zip file
-		_, err = fileWriter.Write([]byte(markdown))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write to zip file"})
-			return
-		}
+	if err := services.WriteArticlesZip(c.Writer, articles, lang, false); err != nil {
+		logging.FromGin(c).Error("Failed to write export archive", "error", err)
 	}
 }
 
@@ -130,7 +173,7 @@ func ExportAllArticles(c *gin.Context) {
 	}
 
 	var articles []models.Article
-	if err := database.DB.Preload("Category").Preload("Translations").Find(&articles).Error; err != nil {
+	if err := siteDB(c).Preload("Category").Preload("Translations").Find(&articles).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch articles"})
 		return
 	}
@@ -144,100 +187,53 @@ func ExportAllArticles(c *gin.Context) {
 	c.Header("Content-Type", "application/zip")
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"blog-export-%s.zip\"", time.Now().Format("2006-01-02")))
 
-	zipWriter := zip.NewWriter(c.Writer)
-	defer zipWriter.Close()
-
-	for _, article := range articles {
-		// Apply translation if needed
-		if lang != "zh" && lang != "" {
-			applyTranslation(&article, lang)
-		}
-
-		// Generate markdown content
-		markdown := generateMarkdown(article)
-
-		// Generate filename with category folder
-		safeTitle := sanitizeFilename(article.Title)
-		safeCategoryName := sanitizeFilename(article.Category.Name)
-		filename := fmt.Sprintf("%s/%s.md", safeCategoryName, safeTitle)
-
-		// Create file in zip
-		fileWriter, err := zipWriter.Create(filename)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create zip file"})
-			return
-		}
-
-		// Write markdown content to zip file
-		_, err = fileWriter.Write([]byte(markdown))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write to zip file"})
-			return
-		}
+	if err := services.WriteArticlesZip(c.Writer, articles, lang, true); err != nil {
+		logging.FromGin(c).Error("Failed to write export archive", "error", err)
 	}
 }
 
-// generateMarkdown generates markdown content from article
-func generateMarkdown(article models.Article) string {
-	var builder strings.Builder
-
-	// Write front matter (YAML header)
-	builder.WriteString("---\n")
-	builder.WriteString(fmt.Sprintf("title: \"%s\"\n", strings.ReplaceAll(article.Title, "\"", "\\\"")))
-	builder.WriteString(fmt.Sprintf("category: \"%s\"\n", article.Category.Name))
-	builder.WriteString(fmt.Sprintf("date: \"%s\"\n", article.CreatedAt.Format("2006-01-02 15:04:05")))
-	if article.Summary != "" {
-		builder.WriteString(fmt.Sprintf("summary: \"%s\"\n", strings.ReplaceAll(article.Summary, "\"", "\\\"")))
+// ExportSite downloads the site's content, media and settings as a .kuno
+// archive that ImportSite on this or another instance can load
+func ExportSite(c *gin.Context) {
+	name := "site"
+	if site := currentSite(c); site != nil {
+		name = site.Slug
 	}
-	if article.ViewCount > 0 {
-		builder.WriteString(fmt.Sprintf("views: %d\n", article.ViewCount))
-	}
-	builder.WriteString("---\n\n")
-
-	// Write title as H1
-	builder.WriteString(fmt.Sprintf("# %s\n\n", article.Title))
-
-	// Write summary if exists
-	if article.Summary != "" {
-		builder.WriteString(fmt.Sprintf("*%s*\n\n", article.Summary))
-	}
-
-	// Write main content
-	builder.WriteString(article.Content)
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"kuno-%s-%s%s\"",
+		name, time.Now().Format("2006-01-02"), services.SiteArchiveExtension))
 
-	// Add metadata footer
-	builder.WriteString("\n\n---\n")
-	builder.WriteString(fmt.Sprintf("**Published:** %s  \n", article.CreatedAt.Format("2006-01-02 15:04:05")))
-	builder.WriteString(fmt.Sprintf("**Category:** %s  \n", article.Category.Name))
-	if article.ViewCount > 0 {
-		builder.WriteString(fmt.Sprintf("**Views:** %d  \n", article.ViewCount))
+	archive := services.NewSiteArchive(siteDB(c), UploadDir, siteUploadDir(c, ""))
+	if _, err := archive.Export(c.Writer); err != nil {
+		logging.FromGin(c).Error("Failed to write site archive", "error", err)
 	}
-
-	return builder.String()
 }
 
-// sanitizeFilename removes or replaces invalid characters for filenames
-func sanitizeFilename(filename string) string {
-	// Replace invalid characters with underscores
-	invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
-	result := filename
-
-	for _, char := range invalidChars {
-		result = strings.ReplaceAll(result, char, "_")
+// ImportSite loads an uploaded .kuno archive into the site. Existing
+// categories and articles are kept; settings=false keeps the current site
+// settings as well.
+func ImportSite(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No archive file provided"})
+		return
 	}
-
-	// Trim spaces and dots from the end
-	result = strings.TrimRight(result, " .")
-
-	// Limit filename length to 100 characters
-	if len(result) > 100 {
-		result = result[:100]
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
 	}
+	defer file.Close()
 
-	// Ensure filename is not empty
-	if result == "" {
-		result = "untitled"
+	archive := services.NewSiteArchive(siteDB(c), UploadDir, siteUploadDir(c, ""))
+	result, err := archive.Import(file, header.Size, c.DefaultQuery("settings", "true") != "false")
+	switch {
+	case errors.Is(err, services.ErrInvalidSiteArchive), errors.Is(err, services.ErrUnsupportedSiteArchive):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		logging.FromGin(c).Error("Failed to import site archive", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import site archive"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Site archive imported", "result": result})
 	}
-
-	return result
 }
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListFeatures returns every feature flag and its state
+func ListFeatures(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"features": services.GetGlobalFeatureService().List()})
+}
+
+// UpdateFeature turns a feature on or off for every instance
+func UpdateFeature(c *gin.Context) {
+	var req struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	feature, err := services.GetGlobalFeatureService().Set(c.Param("name"), *req.Enabled)
+	respondFeature(c, feature, err)
+}
+
+// ResetFeature drops the admin override so the configured default applies
+func ResetFeature(c *gin.Context) {
+	feature, err := services.GetGlobalFeatureService().Reset(c.Param("name"))
+	respondFeature(c, feature, err)
+}
+
+func respondFeature(c *gin.Context, feature services.Feature, err error) {
+	switch {
+	case errors.Is(err, services.ErrUnknownFeature):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown feature"})
+	case err != nil:
+		logging.FromGin(c).Error("Failed to update feature flag", "feature", c.Param("name"), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update feature flag"})
+	default:
+		c.JSON(http.StatusOK, feature)
+	}
+}
+
+// RequireFeature hides the routes of a disabled feature behind a 404, as if
+// they did not exist
+func RequireFeature(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !services.GetGlobalFeatureService().Enabled(name) {
+			respondError(c, http.StatusNotFound, ErrCodeFeatureDisabled, "Feature not enabled")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListFeedImports returns the feeds posts are imported from, with the
+// number of posts of each waiting for review
+func ListFeedImports(c *gin.Context) {
+	feeds, err := services.GetGlobalFeedImportService().Sources(c.Request.Context())
+	if err != nil {
+		respondFeedImportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"feeds": feeds})
+}
+
+// AddFeedImport adds an RSS or Atom feed and queues the job that stages
+// its posts as drafts. With follow set, new posts are staged on
+// FEED_IMPORT_SCHEDULE.
+func AddFeedImport(c *gin.Context) {
+	var input services.FeedSourceInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	feed, job, err := services.GetGlobalFeedImportService().Add(c.Request.Context(), input)
+	if err != nil && feed == nil {
+		respondFeedImportError(c, err)
+		return
+	}
+	if err != nil {
+		logging.FromGin(c).Error("Failed to queue feed import", "feed_id", feed.ID, "error", err)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"feed": feed, "job": job})
+}
+
+// UpdateFeedImport edits a feed, such as to follow it or to change the
+// category and language its posts are published in
+func UpdateFeedImport(c *gin.Context) {
+	id, ok := feedImportID(c)
+	if !ok {
+		return
+	}
+	var input services.FeedSourceInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	feed, err := services.GetGlobalFeedImportService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondFeedImportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, feed)
+}
+
+// DeleteFeedImport removes a feed and the posts staged from it; articles
+// already published from it stay
+func DeleteFeedImport(c *gin.Context) {
+	id, ok := feedImportID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalFeedImportService().Delete(c.Request.Context(), id); err != nil {
+		respondFeedImportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Feed deleted"})
+}
+
+// FetchFeedImport queues a fetch of a feed for posts not staged yet
+func FetchFeedImport(c *gin.Context) {
+	id, ok := feedImportID(c)
+	if !ok {
+		return
+	}
+	job, err := services.GetGlobalFeedImportService().Queue(c.Request.Context(), id)
+	if err != nil {
+		respondFeedImportError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListFeedImportPosts lists the posts staged from feeds, newest first.
+// status defaults to draft; filter by feed with ?feed_id=.
+func ListFeedImportPosts(c *gin.Context) {
+	var query struct {
+		LimitQuery
+		FeedID uint `form:"feed_id"`
+	}
+	if !bindQuery(c, &query) {
+		return
+	}
+	posts, err := services.GetGlobalFeedImportService().Items(c.Request.Context(), query.FeedID,
+		c.DefaultQuery("status", models.FeedItemDraft), query.Limit)
+	if err != nil {
+		respondFeedImportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"posts": posts})
+}
+
+// PublishFeedImportPosts publishes draft posts as articles
+func PublishFeedImportPosts(c *gin.Context) {
+	decideFeedImportPosts(c, models.FeedItemPublished)
+}
+
+// DismissFeedImportPosts discards draft posts; they are not staged again
+func DismissFeedImportPosts(c *gin.Context) {
+	decideFeedImportPosts(c, models.FeedItemDismissed)
+}
+
+func decideFeedImportPosts(c *gin.Context, status string) {
+	var req struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	posts, err := services.GetGlobalFeedImportService().Decide(c.Request.Context(), req.IDs, status)
+	if err != nil {
+		respondFeedImportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"posts": posts})
+}
+
+func feedImportID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondFeedImportError(c *gin.Context, err error) {
+	var reject *hooks.RejectError
+	switch {
+	case errors.Is(err, services.ErrFeedSourceNotFound), errors.Is(err, services.ErrFeedItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidFeed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, &reject):
+		respondHookError(c, err)
+	default:
+		logging.FromGin(c).Error("Feed import operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Feed import operation failed"})
+	}
+}
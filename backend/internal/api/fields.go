@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listFields is the ?fields= selection of a list endpoint, the JSON keys
+// each item is trimmed to. A nil selection keeps items whole.
+type listFields []string
+
+// parseListFields reads the comma-separated ?fields= parameter and checks
+// it against the JSON keys of the item type of the list
+func parseListFields(c *gin.Context, item interface{}) (listFields, error) {
+	param := strings.TrimSpace(c.Query("fields"))
+	if param == "" {
+		return nil, nil
+	}
+	known := jsonFieldNames(reflect.TypeOf(item))
+	var fields listFields
+	for _, field := range strings.Split(param, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if _, ok := known[field]; !ok {
+			names := make([]string, 0, len(known))
+			for name := range known {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown field %q, use %s", field, strings.Join(names, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// apply trims each item of a list to the selected keys
+func (f listFields) apply(items interface{}) (interface{}, error) {
+	if f == nil {
+		return items, nil
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var whole []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &whole); err != nil {
+		return nil, err
+	}
+	trimmed := make([]map[string]json.RawMessage, len(whole))
+	for i, item := range whole {
+		trimmed[i] = make(map[string]json.RawMessage, len(f))
+		for _, field := range f {
+			if value, ok := item[field]; ok {
+				trimmed[i][field] = value
+			}
+		}
+	}
+	return trimmed, nil
+}
+
+// jsonFieldNames returns the JSON keys of a struct type, including those
+// of its embedded structs
+func jsonFieldNames(t reflect.Type) map[string]struct{} {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	names := make(map[string]struct{})
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			for embedded := range jsonFieldNames(field.Type) {
+				names[embedded] = struct{}{}
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = struct{}{}
+	}
+	return names
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBlogroll returns the active friend links grouped by category for the
+// blogroll page
+func GetBlogroll(c *gin.Context) {
+	categories, err := services.GetGlobalFriendLinkService().Blogroll(c.Request.Context())
+	if err != nil {
+		respondFriendLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+// ListFriendLinks returns every friend link, including inactive ones, with
+// its reciprocal check result and the categories in use. Filter with
+// ?category=.
+func ListFriendLinks(c *gin.Context) {
+	var category *string
+	if value, ok := c.GetQuery("category"); ok {
+		category = &value
+	}
+	service := services.GetGlobalFriendLinkService()
+	links, err := service.List(c.Request.Context(), category)
+	if err != nil {
+		respondFriendLinkError(c, err)
+		return
+	}
+	categories, err := service.Categories(c.Request.Context())
+	if err != nil {
+		respondFriendLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"links": links, "categories": categories})
+}
+
+// GetFriendLink returns one friend link
+func GetFriendLink(c *gin.Context) {
+	id, ok := friendLinkID(c)
+	if !ok {
+		return
+	}
+	link, err := services.GetGlobalFriendLinkService().Get(c.Request.Context(), id)
+	if err != nil {
+		respondFriendLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// CreateFriendLink adds a friend link at the end of the blogroll
+func CreateFriendLink(c *gin.Context) {
+	var input services.FriendLinkInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	link, err := services.GetGlobalFriendLinkService().Create(c.Request.Context(), input)
+	if err != nil {
+		respondFriendLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, link)
+}
+
+// UpdateFriendLink edits a friend link
+func UpdateFriendLink(c *gin.Context) {
+	id, ok := friendLinkID(c)
+	if !ok {
+		return
+	}
+	var input services.FriendLinkInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	link, err := services.GetGlobalFriendLinkService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondFriendLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// DeleteFriendLink removes a friend link
+func DeleteFriendLink(c *gin.Context) {
+	id, ok := friendLinkID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalFriendLinkService().Delete(c.Request.Context(), id); err != nil {
+		respondFriendLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Friend link deleted"})
+}
+
+// UpdateFriendLinkOrder sets the display order of friend links
+// ([{"id": 1, "order": 2}, ...])
+func UpdateFriendLinkOrder(c *gin.Context) {
+	var orders []services.FriendLinkOrder
+	if !bindJSON(c, &orders) {
+		return
+	}
+	if err := services.GetGlobalFriendLinkService().Reorder(c.Request.Context(), orders); err != nil {
+		respondFriendLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Order updated"})
+}
+
+// CheckFriendLink queues a check that the friend's site links back
+func CheckFriendLink(c *gin.Context) {
+	id, ok := friendLinkID(c)
+	if !ok {
+		return
+	}
+	job, err := services.GetGlobalFriendLinkService().QueueCheck(c.Request.Context(), id)
+	if err != nil {
+		respondFriendLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func friendLinkID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondFriendLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrFriendLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidFriendLink):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Friend link operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Friend link operation failed"})
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListGuestbook returns a page of approved guestbook entries, newest first.
+// Filter with ?lang= to show the wall of one language.
+func ListGuestbook(c *gin.Context) {
+	page, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	entries, total, err := services.GetGlobalGuestbookService().Public(c.Request.Context(), c.Query("lang"), page, limit)
+	if err != nil {
+		respondGuestbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"entries":    entries,
+		"pagination": gin.H{"page": page, "limit": limit, "total": total},
+	})
+}
+
+// SignGuestbook receives a guestbook entry. Entries are shown once approved;
+// those discarded as spam get the same answer.
+func SignGuestbook(c *gin.Context) {
+	var input services.GuestbookInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	_, err := services.GetGlobalGuestbookService().Sign(c.Request.Context(), input, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondGuestbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Thank you, your message will appear once approved"})
+}
+
+// ListAllGuestbookEntries returns a page of guestbook entries for
+// moderation. Filter with ?status=pending|approved|rejected|spam and
+// ?lang=; spam is only listed when asked for.
+func ListAllGuestbookEntries(c *gin.Context) {
+	page, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	entries, total, err := services.GetGlobalGuestbookService().List(c.Request.Context(), c.Query("status"), c.Query("lang"), page, limit)
+	if err != nil {
+		respondGuestbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"entries":    entries,
+		"pagination": gin.H{"page": page, "limit": limit, "total": total},
+	})
+}
+
+// UpdateGuestbookEntry approves, rejects or files away a guestbook entry
+func UpdateGuestbookEntry(c *gin.Context) {
+	id, ok := guestbookEntryID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		Status string `json:"status" binding:"required"`
+	}
+	if !bindJSON(c, &input) {
+		return
+	}
+	entry, err := services.GetGlobalGuestbookService().SetStatus(c.Request.Context(), id, input.Status)
+	if err != nil {
+		respondGuestbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// ReplyGuestbookEntry sets the site's public reply to an approved guestbook
+// entry, or removes it with an empty reply. Senders who opted in are emailed
+// about the first reply.
+func ReplyGuestbookEntry(c *gin.Context) {
+	id, ok := guestbookEntryID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		Reply string `json:"reply"`
+	}
+	if !bindJSON(c, &input) {
+		return
+	}
+	entry, err := services.GetGlobalGuestbookService().Reply(c.Request.Context(), id, input.Reply)
+	if err != nil {
+		respondGuestbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// UnsubscribeReplies follows the signed link in reply emails and stops
+// them for the address. Mail clients may POST it for one-click unsubscribe.
+func UnsubscribeReplies(c *gin.Context) {
+	email, signature := c.Query("email"), c.Query("sig")
+	if email == "" || signature == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidUnsubscribeLink.Error()})
+		return
+	}
+	err := services.GetGlobalCommentNotifier().Unsubscribe(c.Request.Context(), currentSiteID(c), email, signature)
+	if errors.Is(err, services.ErrInvalidUnsubscribeLink) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromGin(c).Error("Failed to unsubscribe from reply emails", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "You will no longer be emailed about replies"})
+}
+
+// DeleteGuestbookEntry removes a guestbook entry
+func DeleteGuestbookEntry(c *gin.Context) {
+	id, ok := guestbookEntryID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalGuestbookService().Delete(c.Request.Context(), id); err != nil {
+		respondGuestbookError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Guestbook entry deleted"})
+}
+
+func guestbookEntryID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondGuestbookError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrGuestbookEntryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidGuestbookEntry), errors.Is(err, services.ErrInvalidEmail):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrGuestbookRateLimited):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Guestbook operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Guestbook operation failed"})
+	}
+}
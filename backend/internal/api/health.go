@@ -0,0 +1,30 @@
+package api
+
+import (
+	"blog-backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz answers as long as the process serves requests, for liveness
+// probes
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz runs the readiness checks and answers 503 when one fails or the
+// server is shutting down, so load balancers stop sending it traffic
+func Readyz(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	if services.IsShuttingDown() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting_down"})
+		return
+	}
+	report := services.GetGlobalHealthService().Check(c.Request.Context())
+	status, code := "ok", http.StatusOK
+	if !report.Ready {
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": report.Checks, "checked_at": report.CheckedAt})
+}
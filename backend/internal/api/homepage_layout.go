@@ -0,0 +1,46 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxHomepageLayoutSize is the largest homepage layout body accepted
+const maxHomepageLayoutSize = 256 << 10
+
+// GetHomepageLayout returns the site's homepage layout, the default layout
+// when none has been saved
+func GetHomepageLayout(c *gin.Context) {
+	layout, err := services.GetGlobalHomepageLayoutService().Get(c.Request.Context())
+	if err != nil {
+		respondHomepageLayoutError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, layout)
+}
+
+// UpdateHomepageLayout validates and saves the homepage layout in the body,
+// returning it with the sections missing from its order filled in
+func UpdateHomepageLayout(c *gin.Context) {
+	body := io.LimitReader(c.Request.Body, maxHomepageLayoutSize)
+	layout, err := services.GetGlobalHomepageLayoutService().Update(c.Request.Context(), body)
+	if err != nil {
+		respondHomepageLayoutError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, layout)
+}
+
+func respondHomepageLayoutError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidHomepageLayout) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logging.FromGin(c).Error("Homepage layout operation failed", "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Homepage layout operation failed"})
+}
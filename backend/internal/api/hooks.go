@@ -0,0 +1,40 @@
+package api
+
+import (
+	"blog-backend/internal/events"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondHookError reports a failed filter hook. A rejection is the user's
+// to fix; anything else is a server error.
+func respondHookError(c *gin.Context, err error) {
+	var reject *hooks.RejectError
+	if errors.As(err, &reject) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": reject.Reason, "hook": reject.Hook})
+		return
+	}
+	logging.FromGin(c).Error("Hook failed", "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Hook failed"})
+}
+
+// notifyArticleSaved runs the after-save hooks, then dispatches the
+// publish event when the article has just become visible to readers or the
+// update event when it already was
+func notifyArticleSaved(c *gin.Context, article *models.Article, wasPublished bool) {
+	ctx := c.Request.Context()
+	hooks.Notify(ctx, hooks.AfterArticleSave, article)
+	switch {
+	case article.CreatedAt.After(time.Now()):
+	case wasPublished:
+		events.Dispatch(ctx, hooks.ArticleUpdated, article)
+	default:
+		events.Dispatch(ctx, hooks.ArticlePublished, article)
+	}
+}
@@ -0,0 +1,328 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Job types implemented with the API's embedding service, which is the
+// instance reloaded when AI settings change
+const (
+	JobEmbeddingsArticle    = "embeddings.article"
+	JobEmbeddingsBatch      = "embeddings.batch"
+	JobEmbeddingsRebuild    = "embeddings.rebuild"
+	JobEmbeddingsMissing    = "embeddings.missing"
+	JobEmbeddingsPrecompute = "embeddings.precompute"
+)
+
+// Maintenance job types for data owned by the API package
+const (
+	JobPurgeSessions       = "sessions.purge"
+	JobExpireDirectUploads = "uploads.expire_direct"
+)
+
+// registerJobHandlers adds the API-level job types to the global queue
+func registerJobHandlers(queue *services.JobQueue) {
+	queue.Register(JobEmbeddingsArticle, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		var req struct {
+			ArticleID uint `json:"article_id"`
+		}
+		if err := json.Unmarshal(payload, &req); err != nil || req.ArticleID == 0 {
+			return nil, fmt.Errorf("payload must contain article_id")
+		}
+		return nil, GetGlobalEmbeddingService().ProcessArticleEmbeddings(ctx, req.ArticleID)
+	})
+	queue.Register(JobEmbeddingsBatch, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return nil, GetGlobalEmbeddingService().BatchProcessAllArticles(ctx)
+	})
+	queue.Register(JobEmbeddingsRebuild, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		// Rebuilt beside the live embeddings, so search keeps working
+		return services.GetGlobalSearchIndexService().Rebuild(ctx)
+	})
+	queue.Register(JobEmbeddingsMissing, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		// Small batches keep the hourly AI spend predictable
+		return nil, GetGlobalEmbeddingService().BatchProcessMissingEmbeddings(ctx, 5)
+	})
+	queue.Register(JobEmbeddingsPrecompute, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return nil, GetGlobalEmbeddingService().PrecomputePopularQueries(ctx)
+	})
+	queue.Register(JobPurgeSessions, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		deleted, err := purgeLoginSessions()
+		return map[string]int64{"sessions_deleted": deleted}, err
+	})
+	queue.Register(JobExpireDirectUploads, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		expired, err := expireStaleDirectUploads()
+		return map[string]int{"uploads_expired": expired}, err
+	})
+}
+
+// registerSchedules adds the periodic tasks for the API-level job types
+func registerSchedules(scheduler *services.Scheduler) {
+	schedules := []services.Schedule{
+		{
+			Name:        "embeddings-missing",
+			Description: "Generate embeddings for articles that have none",
+			Cron:        "0 * * * *",
+			JobType:     JobEmbeddingsMissing,
+		},
+		{
+			Name:        "embeddings-precompute",
+			Description: "Precompute search results for popular queries",
+			Cron:        "30 */6 * * *",
+			JobType:     JobEmbeddingsPrecompute,
+		},
+		{
+			Name:        "sessions-purge",
+			Description: "Delete login sessions that expired more than 30 days ago",
+			Cron:        "0 4 * * *",
+			JobType:     JobPurgeSessions,
+		},
+		{
+			Name:        "direct-uploads-expire",
+			Description: "Expire direct uploads that were never finalized",
+			Cron:        "*/15 * * * *",
+			JobType:     JobExpireDirectUploads,
+		},
+	}
+	for _, schedule := range schedules {
+		if err := scheduler.Add(schedule); err != nil {
+			slog.Error("Invalid schedule, task disabled", "schedule", schedule.Name, "error", err)
+		}
+	}
+}
+
+// ListJobs returns jobs filtered by status and type, newest first
+func ListJobs(c *gin.Context) {
+	slice, ok := bindLimit(c, 50, maxAdminPageSize)
+	if !ok {
+		return
+	}
+
+	jobs, total, err := services.GetGlobalJobQueue().List(services.JobFilter{
+		Status: c.Query("status"),
+		Type:   c.Query("type"),
+		Limit:  slice.Limit,
+		Offset: slice.Offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list jobs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs, "total": total})
+}
+
+// GetJobStats returns job counts by status and the registered job types
+func GetJobStats(c *gin.Context) {
+	stats, err := services.GetGlobalJobQueue().Stats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get job statistics"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetJob returns a single job with its result or last error
+func GetJob(c *gin.Context) {
+	id, ok := jobID(c)
+	if !ok {
+		return
+	}
+	job, err := services.GetGlobalJobQueue().Get(id)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+const (
+	// jobProgressPoll is how often a progress stream reads its job
+	jobProgressPoll = time.Second
+	// jobProgressKeepAlive is how long a progress stream stays silent
+	// before sending a comment, so proxies keep the connection open
+	jobProgressKeepAlive = 15 * time.Second
+)
+
+// StreamJobProgress sends the progress of a job as server-sent events: a
+// "progress" event with the job's status, percent, step and failed items
+// whenever they change, then a "done" event once the job succeeded, was
+// dead-lettered or cancelled. Progress is read from the job's row, so any
+// instance can stream a job that runs on another.
+func StreamJobProgress(c *gin.Context) {
+	id, ok := jobID(c)
+	if !ok {
+		return
+	}
+	queue := services.GetGlobalJobQueue()
+	job, err := queue.Get(id)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	var last []byte
+	sentAt := time.Time{}
+	ticker := time.NewTicker(jobProgressPoll)
+	defer ticker.Stop()
+	c.Stream(func(w io.Writer) bool {
+		if job == nil {
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-ticker.C:
+			}
+			if job, err = queue.Get(id); err != nil {
+				c.SSEvent("error", gin.H{"error": err.Error()})
+				return false
+			}
+		}
+		event, _ := json.Marshal(services.NewJobProgressEvent(job))
+		finished := services.JobFinished(job)
+		job = nil
+		switch {
+		case finished:
+			c.SSEvent("done", string(event))
+			return false
+		case !bytes.Equal(event, last):
+			c.SSEvent("progress", string(event))
+			last, sentAt = event, time.Now()
+		case time.Since(sentAt) >= jobProgressKeepAlive:
+			io.WriteString(w, ": keep-alive\n\n")
+			sentAt = time.Now()
+		}
+		return true
+	})
+}
+
+// CreateJob queues a job of a registered type
+func CreateJob(c *gin.Context) {
+	var req struct {
+		Type    string          `json:"type" binding:"required"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var payload interface{}
+	if len(req.Payload) > 0 {
+		payload = req.Payload
+	}
+	job, err := services.GetGlobalJobQueue().Enqueue(req.Type, payload)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// RetryJob puts a dead or cancelled job back in the queue
+func RetryJob(c *gin.Context) {
+	id, ok := jobID(c)
+	if !ok {
+		return
+	}
+	job, err := services.GetGlobalJobQueue().Retry(id)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJob stops a pending job from running
+func CancelJob(c *gin.Context) {
+	id, ok := jobID(c)
+	if !ok {
+		return
+	}
+	job, err := services.GetGlobalJobQueue().Cancel(id)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// DeleteJob removes a job that is not running from the history
+func DeleteJob(c *gin.Context) {
+	id, ok := jobID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalJobQueue().Delete(id); err != nil {
+		respondJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Job deleted"})
+}
+
+// ListSchedules returns the periodic tasks with their next and last runs
+func ListSchedules(c *gin.Context) {
+	schedules, err := services.GetGlobalScheduler().List()
+	if err != nil {
+		logging.FromGin(c).Error("Failed to list schedules", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list schedules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+// GetSchedule returns one periodic task with its last run result
+func GetSchedule(c *gin.Context) {
+	schedule, err := services.GetGlobalScheduler().Get(c.Param("name"))
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// RunSchedule queues a run of a periodic task now
+func RunSchedule(c *gin.Context) {
+	job, err := services.GetGlobalScheduler().Trigger(c.Param("name"))
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func jobID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondJobError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrJobNotFound), errors.Is(err, services.ErrScheduleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUnknownJobType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrJobState), errors.Is(err, services.ErrScheduleBusy):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Job operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Job operation failed"})
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLinkPreview returns the oEmbed and OpenGraph preview of ?url= for
+// rendered articles. Only links that a published article of the site
+// contains are previewed, so the endpoint cannot be used to fetch any page.
+func GetLinkPreview(c *gin.Context) {
+	target, err := services.ParsePreviewURL(c.Query("url"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pattern := "%" + target.String() + "%"
+	var count int64
+	translated := siteDB(c).Model(&models.ArticleTranslation{}).Select("article_id").Where("content LIKE ?", pattern)
+	err = siteDB(c).Model(&models.Article{}).Where("created_at <= ?", time.Now()).
+		Where("content LIKE ? OR id IN (?)", pattern, translated).Count(&count).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No published article links to this URL"})
+		return
+	}
+
+	preview, ok := linkPreview(c, target.String())
+	if !ok {
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, preview)
+}
+
+// PreviewLink returns the preview of any link, for the editor
+func PreviewLink(c *gin.Context) {
+	var req struct {
+		URL string `json:"url" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	if preview, ok := linkPreview(c, req.URL); ok {
+		c.JSON(http.StatusOK, preview)
+	}
+}
+
+// linkPreview fetches a preview, answering the request itself on failure
+func linkPreview(c *gin.Context, raw string) (*services.LinkPreview, bool) {
+	target, err := services.ParsePreviewURL(raw)
+	if err == nil {
+		var preview *services.LinkPreview
+		if preview, err = services.GetGlobalLinkPreviewService().Preview(c.Request.Context(), target); err == nil {
+			return preview, true
+		}
+	}
+	switch {
+	case errors.Is(err, services.ErrInvalidPreviewURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPreviewBlocked):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	}
+	return nil, false
+}
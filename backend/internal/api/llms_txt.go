@@ -5,15 +5,20 @@ import (
 	"log"
 	"net/http"
 	"sort"
-	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"blog-backend/internal/cache"
 	"blog-backend/internal/database"
+	"blog-backend/internal/events"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/metrics"
 	"blog-backend/internal/models"
+	"blog-backend/internal/profiling"
 	"blog-backend/internal/services"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Cache structure for LLMs.txt content
@@ -21,29 +26,81 @@ type LLMsTxtCache struct {
 	Content   string
 	Language  string
 	Timestamp time.Time
-	Hash      string // Hash of data used to generate content
 }
 
-// Global cache with mutex for thread safety
 var (
-	llmsTxtCache    = make(map[string]*LLMsTxtCache)
-	llmsCacheMutex  = sync.RWMutex{}
 	llmsCacheExpiry = 1 * time.Hour // Cache expires after 1 hour
+	llmsTxtCache    = cache.New("llms", llmsCacheExpiry)
 	usageTracker    = services.NewAIUsageTracker()
 )
 
+// llmsTxtQueryBudget is the most database queries one llms.txt request
+// should run. A cache miss takes twelve however many categories, articles
+// and languages the site has, so going over it means a query has crept
+// back into a loop.
+const llmsTxtQueryBudget = 12
+
+// llms.txt requests served by this process and the queries they ran, by
+// cache status
+var (
+	llmsTxtRequestsCounter   = metrics.NewCounterVec("cache")
+	llmsTxtQueriesCounter    = metrics.NewCounterVec("cache")
+	llmsTxtOverBudgetCounter = metrics.NewCounterVec("cache")
+)
+
+func init() {
+	events.Subscribe(llmsTxtCache.Clear, hooks.ArticlePublished, hooks.ArticleUpdated, hooks.SettingsChanged)
+	// Deleted articles, imports, restores and category changes have no
+	// domain event
+	for _, topic := range []string{cache.TopicArticles, cache.TopicCategories, cache.TopicSettings} {
+		cache.Subscribe(topic, llmsTxtCache.Clear)
+	}
+	metrics.Register(collectLLMsTxtMetrics)
+}
+
+func collectLLMsTxtMetrics() ([]metrics.Family, error) {
+	return []metrics.Family{
+		{Name: "kuno_llms_txt_requests_total", Help: "llms.txt requests served by this process", Type: metrics.Counter, Samples: llmsTxtRequestsCounter.Samples()},
+		{Name: "kuno_llms_txt_queries_total", Help: "Database queries run for llms.txt requests", Type: metrics.Counter, Samples: llmsTxtQueriesCounter.Samples()},
+		{Name: "kuno_llms_txt_over_budget_total", Help: "llms.txt requests that ran more queries than the budget", Type: metrics.Counter, Samples: llmsTxtOverBudgetCounter.Samples()},
+		{Name: "kuno_llms_txt_query_budget", Help: "Most database queries an llms.txt request should run", Type: metrics.Gauge, Samples: []metrics.Sample{{Value: llmsTxtQueryBudget}}},
+	}, nil
+}
+
+// recordLLMsTxtQueries counts the queries of an llms.txt request and warns
+// about requests over the budget, naming their most expensive statement
+func recordLLMsTxtQueries(c *gin.Context, cacheStatus string, profile *profiling.Profile) {
+	queries, _, statements := profile.Summary()
+	llmsTxtRequestsCounter.Add(1, cacheStatus)
+	llmsTxtQueriesCounter.Add(float64(queries), cacheStatus)
+	if queries <= llmsTxtQueryBudget {
+		return
+	}
+	llmsTxtOverBudgetCounter.Add(1, cacheStatus)
+	logging.FromGin(c).Warn("llms.txt query budget exceeded",
+		"cache", cacheStatus,
+		"queries", queries,
+		"budget", llmsTxtQueryBudget,
+		"statement", statements[0].SQL)
+}
+
 type LLMsTxtContent struct {
 	SiteName        string
 	SiteDescription string
 	BaseURL         string
 	Language        string
+	License         string // default license of the site's articles
 	ArticleCount    int
 	Categories      []CategoryInfo
 	RecentArticles  []ArticleInfo
 	KeyTopics       []string
 	Features        []string
 	SEOStats        SEOStatistics
-	UpdatedAt       time.Time
+	// Languages is how many articles can be read in each language, and
+	// LanguageTopArticles the most read of them in Language
+	Languages           []services.LanguageStats
+	LanguageTopArticles []services.LanguageArticle
+	UpdatedAt           time.Time
 }
 
 type CategoryInfo struct {
@@ -59,6 +116,7 @@ type ArticleInfo struct {
 	SEOTitle     string
 	SEOKeywords  string
 	CategoryName string
+	License      string
 	ViewCount    uint
 	CreatedAt    time.Time
 }
@@ -70,8 +128,15 @@ func generateLLMsTxt(c *gin.Context) {
 	var errorMessage string
 	var contentLength int
 
-	// Check cache first
-	cacheKey := fmt.Sprintf("llms_%s", lang)
+	// The queries are counted against the budget with a profile of their
+	// own, and keep the request's site so they only see its content
+	profile := profiling.NewProfile(logging.GetRequestID(c))
+	db := database.DB.WithContext(profiling.WithProfile(c.Request.Context(), profile))
+
+	// Check cache first. It is cleared by the events that change the
+	// content, so a hit runs no queries. Each site has its own entries.
+	cacheKey := fmt.Sprintf("llms_%d_%s", currentSiteID(c), lang)
+	cacheStatus := "HIT"
 	if cachedContent := getCachedLLMsTxt(cacheKey); cachedContent != "" {
 		contentLength = len(cachedContent)
 
@@ -81,7 +146,13 @@ func generateLLMsTxt(c *gin.Context) {
 		c.String(http.StatusOK, cachedContent)
 	} else {
 		// Generate new content if cache miss
-		content, err := generateLLMsTxtContentWithError(lang, c.Request.Host)
+		cacheStatus = "MISS"
+		var settings *models.SiteSettings
+		var loaded models.SiteSettings
+		if err := db.First(&loaded).Error; err == nil {
+			settings = &loaded
+		}
+		content, err := generateLLMsTxtContentWithError(db, settings, lang, c.Request.Host)
 		if err != nil {
 			success = false
 			errorMessage = err.Error()
@@ -98,6 +169,7 @@ func generateLLMsTxt(c *gin.Context) {
 			c.String(http.StatusOK, content)
 		}
 	}
+	recordLLMsTxtQueries(c, strings.ToLower(cacheStatus), profile)
 
 	// Record usage metrics
 	responseTime := time.Since(startTime)
@@ -129,69 +201,47 @@ func generateLLMsTxt(c *gin.Context) {
 	}()
 }
 
-func generateLLMsTxtContentWithError(lang, baseURL string) (string, error) {
-	// Get site settings
-	var settings models.SiteSettings
-	if err := database.DB.Preload("Translations").First(&settings).Error; err != nil {
-		log.Printf("Error fetching site settings: %v", err)
-		return "", fmt.Errorf("failed to fetch site settings: %v", err)
+func generateLLMsTxtContentWithError(db *gorm.DB, settings *models.SiteSettings, lang, baseURL string) (string, error) {
+	if settings == nil {
+		return "", fmt.Errorf("failed to fetch site settings: the site has no settings")
 	}
-
-	content := generateLLMsTxtContentInternal(settings, lang, baseURL)
-	return content, nil
+	return generateLLMsTxtContentInternal(db, *settings, lang, baseURL), nil
 }
 
-func generateLLMsTxtContent(lang, baseURL string) string {
-	content, err := generateLLMsTxtContentWithError(lang, baseURL)
-	if err != nil {
-		return "Error: " + err.Error()
-	}
-	return content
-}
-
-func generateLLMsTxtContentInternal(settings models.SiteSettings, lang, baseURL string) string {
+func generateLLMsTxtContentInternal(db *gorm.DB, settings models.SiteSettings, lang, baseURL string) string {
 	// Get localized site title and subtitle
 	siteName := settings.SiteTitle
 	siteDescription := settings.SiteSubtitle
 
 	// Check for translations
-	for _, translation := range settings.Translations {
-		if translation.Language == lang {
-			siteName = translation.SiteTitle
-			siteDescription = translation.SiteSubtitle
-			break
-		}
+	var settingsTranslations []models.SiteSettingsTranslation
+	db.Where("settings_id = ? AND language = ?", settings.ID, lang).Limit(1).Find(&settingsTranslations)
+	for _, translation := range settingsTranslations {
+		siteName = translation.SiteTitle
+		siteDescription = translation.SiteSubtitle
 	}
 
-	// Get articles count
-	var articleCount int64
-	database.DB.Model(&models.Article{}).Count(&articleCount)
-
-	// Get categories with article counts
+	// Get categories with article counts, counted in one grouped query
 	var categories []CategoryInfo
 	var dbCategories []models.Category
-	database.DB.Preload("Translations").Find(&dbCategories)
-
-	for _, cat := range dbCategories {
-		var count int64
-		database.DB.Model(&models.Article{}).Where("category_id = ?", cat.ID).Count(&count)
+	db.Preload("Translations", "language = ?", lang).Find(&dbCategories)
 
-		categoryName := cat.Name
-		categoryDesc := cat.Description
-
-		// Check for translations
-		for _, translation := range cat.Translations {
-			if translation.Language == lang {
-				categoryName = translation.Name
-				categoryDesc = translation.Description
-				break
-			}
-		}
+	var categoryCounts []struct {
+		CategoryID uint
+		Count      int64
+	}
+	db.Model(&models.Article{}).Select("category_id, COUNT(*) AS count").Group("category_id").Scan(&categoryCounts)
+	countByCategory := make(map[uint]int64, len(categoryCounts))
+	for _, row := range categoryCounts {
+		countByCategory[row.CategoryID] = row.Count
+	}
 
+	for _, cat := range dbCategories {
+		applyCategoryTranslation(&cat, lang)
 		categories = append(categories, CategoryInfo{
-			Name:        categoryName,
-			Description: categoryDesc,
-			Count:       int(count),
+			Name:        cat.Name,
+			Description: cat.Description,
+			Count:       int(countByCategory[cat.ID]),
 		})
 	}
 
@@ -200,22 +250,37 @@ func generateLLMsTxtContentInternal(settings models.SiteSettings, lang, baseURL
 		return categories[i].Count > categories[j].Count
 	})
 
-	// Get recent articles (top 10 by views or recent creation)
+	// Get recent articles (top 10 by views or recent creation). Those with
+	// a region rule are left out, as llms.txt is read from everywhere.
 	var articles []models.Article
-	database.DB.Preload("Category").
+	db.Scopes(unrestrictedArticles).Preload("Category").
 		Order("view_count DESC, created_at DESC").
 		Limit(10).
 		Find(&articles)
+	services.GetGlobalCategoryTranslator().ApplyToArticles(articles, lang)
+
+	// Get their translations in one query, the first one of each article
+	translationByArticle := make(map[uint]models.ArticleTranslation, len(articles))
+	if len(articles) > 0 {
+		articleIDs := make([]uint, len(articles))
+		for i, article := range articles {
+			articleIDs[i] = article.ID
+		}
+		var translations []models.ArticleTranslation
+		db.Where("article_id IN ? AND language = ?", articleIDs, lang).Order("id").Find(&translations)
+		for _, translation := range translations {
+			if _, ok := translationByArticle[translation.ArticleID]; !ok {
+				translationByArticle[translation.ArticleID] = translation
+			}
+		}
+	}
 
 	var recentArticles []ArticleInfo
 	for _, article := range articles {
 		// Get localized article data if available
-		var translation models.ArticleTranslation
 		title := article.Title
 		summary := article.Summary
-
-		err := database.DB.Where("article_id = ? AND language = ?", article.ID, lang).First(&translation).Error
-		if err == nil {
+		if translation, ok := translationByArticle[article.ID]; ok {
 			title = translation.Title
 			summary = translation.Summary
 		}
@@ -227,6 +292,7 @@ func generateLLMsTxtContentInternal(settings models.SiteSettings, lang, baseURL
 			SEOTitle:     article.SEOTitle,
 			SEOKeywords:  article.SEOKeywords,
 			CategoryName: article.Category.Name,
+			License:      services.LicenseText(services.ResolveLicense(&article, &settings)),
 			ViewCount:    article.ViewCount,
 			CreatedAt:    article.CreatedAt,
 		}
@@ -242,8 +308,12 @@ func generateLLMsTxtContentInternal(settings models.SiteSettings, lang, baseURL
 		siteDescription = aiDescription
 	}
 
-	// Get aggregated SEO statistics
-	seoStats := getSEOStatistics()
+	// Get aggregated SEO statistics, which count the articles as well
+	seoStats, articleCount := getSEOStatistics(db)
+
+	// Get the language balance of the articles
+	languages, _ := services.GetLanguageStats(db, 0)
+	languageTopArticles, _ := services.TopArticlesInLanguage(db.Scopes(unrestrictedArticles), lang, 5)
 
 	// Get localized system features
 	features := getLocalizedSystemFeatures(lang)
@@ -253,13 +323,17 @@ func generateLLMsTxtContentInternal(settings models.SiteSettings, lang, baseURL
 		SiteDescription: siteDescription,
 		BaseURL:         baseURL,
 		Language:        lang,
+		License:         services.LicenseText(services.ResolveLicense(&models.Article{}, &settings)),
 		ArticleCount:    int(articleCount),
 		Categories:      categories,
 		RecentArticles:  recentArticles,
 		KeyTopics:       keyTopics,
 		Features:        features,
 		SEOStats:        seoStats,
-		UpdatedAt:       time.Now(),
+		Languages:       languages,
+		// Only the articles that can be read in the language are its top
+		LanguageTopArticles: languageTopArticles,
+		UpdatedAt:           time.Now(),
 	}
 
 	return formatLLMsTxt(content)
@@ -340,6 +414,7 @@ func formatLLMsTxt(content LLMsTxtContent) string {
 	builder.WriteString("## Site Information\n\n")
 	builder.WriteString(fmt.Sprintf("- **Base URL**: %s\n", content.BaseURL))
 	builder.WriteString(fmt.Sprintf("- **Language**: %s\n", content.Language))
+	builder.WriteString(fmt.Sprintf("- **Content License**: %s, unless an article states otherwise\n", content.License))
 	builder.WriteString(fmt.Sprintf("- **Total Articles**: %d\n", content.ArticleCount))
 	builder.WriteString(fmt.Sprintf("- **Articles with SEO**: %d\n", content.SEOStats.TotalArticlesWithSEO))
 	builder.WriteString(fmt.Sprintf("- **Total Views**: %d\n", content.SEOStats.TotalViews))
@@ -359,6 +434,23 @@ func formatLLMsTxt(content LLMsTxtContent) string {
 		builder.WriteString("\n")
 	}
 
+	// Articles by language
+	if len(content.Languages) > 0 {
+		builder.WriteString("## Languages\n\n")
+		for _, language := range content.Languages {
+			builder.WriteString(fmt.Sprintf("- **%s**: %d articles (%d written in it, %d translated), %.0f%% of all articles\n",
+				language.Language, language.Original+language.Translated, language.Original, language.Translated, language.Coverage*100))
+		}
+		builder.WriteString("\n")
+		if len(content.LanguageTopArticles) > 0 {
+			builder.WriteString(fmt.Sprintf("Most read in %s:\n\n", content.Language))
+			for _, article := range content.LanguageTopArticles {
+				builder.WriteString(fmt.Sprintf("- %s (%d views)\n", article.Title, article.ViewCount))
+			}
+			builder.WriteString("\n")
+		}
+	}
+
 	// Key topics from content
 	if len(content.KeyTopics) > 0 {
 		builder.WriteString("## Key Topics\n\n")
@@ -390,6 +482,7 @@ func formatLLMsTxt(content LLMsTxtContent) string {
 			builder.WriteString(fmt.Sprintf("- **Category**: %s\n", article.CategoryName))
 			builder.WriteString(fmt.Sprintf("- **Views**: %d\n", article.ViewCount))
 			builder.WriteString(fmt.Sprintf("- **Published**: %s\n", article.CreatedAt.Format("2006-01-02")))
+			builder.WriteString(fmt.Sprintf("- **License**: %s\n", article.License))
 
 			if article.SEOKeywords != "" {
 				builder.WriteString(fmt.Sprintf("- **Topics**: %s\n", article.SEOKeywords))
@@ -430,6 +523,7 @@ func formatLLMsTxt(content LLMsTxtContent) string {
 	builder.WriteString("- Refer to the categories above to understand content organization\n")
 	builder.WriteString("- Use the key topics to understand the site's focus areas\n")
 	builder.WriteString("- Popular articles represent high-quality, frequently accessed content\n")
+	builder.WriteString("- Quote and reuse articles only as their license allows, and credit the source\n")
 	builder.WriteString("- The site supports advanced search with filters (title:, content:, category:, date:, views:)\n")
 	builder.WriteString("- Content is available in 70+ languages with automatic translation\n")
 	builder.WriteString("- All articles include SEO optimization and structured data\n\n")
@@ -455,39 +549,38 @@ type SEOStatistics struct {
 	TotalViews           int64
 }
 
-func getSEOStatistics() SEOStatistics {
-	var stats SEOStatistics
-
-	// Count articles with SEO data
-	var articlesWithSEO int64
-	database.DB.Model(&models.Article{}).Where("seo_title != '' OR seo_description != '' OR seo_keywords != '' OR seo_slug != ''").Count(&articlesWithSEO)
-	stats.TotalArticlesWithSEO = int(articlesWithSEO)
-
-	// Count specific SEO fields
-	var seoTitles, seoDescriptions, seoKeywords, seoSlugs int64
-	database.DB.Model(&models.Article{}).Where("seo_title != ''").Count(&seoTitles)
-	database.DB.Model(&models.Article{}).Where("seo_description != ''").Count(&seoDescriptions)
-	database.DB.Model(&models.Article{}).Where("seo_keywords != ''").Count(&seoKeywords)
-	database.DB.Model(&models.Article{}).Where("seo_slug != ''").Count(&seoSlugs)
-
-	stats.TotalSEOTitles = int(seoTitles)
-	stats.TotalSEODescriptions = int(seoDescriptions)
-	stats.TotalSEOKeywords = int(seoKeywords)
-	stats.TotalSEOSlugs = int(seoSlugs)
-
-	// Calculate average view count
-	var totalArticles int64
-	database.DB.Model(&models.Article{}).Count(&totalArticles)
-	if totalArticles > 0 {
-		var result struct {
-			TotalViews int64 `gorm:"column:total_views"`
-		}
-		database.DB.Model(&models.Article{}).Select("SUM(view_count) as total_views").Scan(&result)
-		stats.TotalViews = result.TotalViews
-		stats.AverageViewCount = float64(result.TotalViews) / float64(totalArticles)
-	}
-
-	return stats
+// getSEOStatistics aggregates the SEO fields of all articles in one query
+// and returns the number of articles with them
+func getSEOStatistics(db *gorm.DB) (SEOStatistics, int64) {
+	var row struct {
+		Articles     int64
+		WithSEO      int64
+		Titles       int64
+		Descriptions int64
+		Keywords     int64
+		Slugs        int64
+		Views        int64
+	}
+	db.Model(&models.Article{}).Select(`COUNT(*) AS articles,
+		COALESCE(SUM(CASE WHEN seo_title != '' OR seo_description != '' OR seo_keywords != '' OR seo_slug != '' THEN 1 ELSE 0 END), 0) AS with_seo,
+		COALESCE(SUM(CASE WHEN seo_title != '' THEN 1 ELSE 0 END), 0) AS titles,
+		COALESCE(SUM(CASE WHEN seo_description != '' THEN 1 ELSE 0 END), 0) AS descriptions,
+		COALESCE(SUM(CASE WHEN seo_keywords != '' THEN 1 ELSE 0 END), 0) AS keywords,
+		COALESCE(SUM(CASE WHEN seo_slug != '' THEN 1 ELSE 0 END), 0) AS slugs,
+		COALESCE(SUM(view_count), 0) AS views`).Scan(&row)
+
+	stats := SEOStatistics{
+		TotalArticlesWithSEO: int(row.WithSEO),
+		TotalSEOTitles:       int(row.Titles),
+		TotalSEODescriptions: int(row.Descriptions),
+		TotalSEOKeywords:     int(row.Keywords),
+		TotalSEOSlugs:        int(row.Slugs),
+		TotalViews:           row.Views,
+	}
+	if row.Articles > 0 {
+		stats.AverageViewCount = float64(row.Views) / float64(row.Articles)
+	}
+	return stats, row.Articles
 }
 
 func generateAIEnhancedDescription(_, originalDescription string, articles []models.Article, lang string) string {
@@ -581,98 +674,44 @@ func generateAIEnhancedDescription(_, originalDescription string, articles []mod
 
 // Cache management functions
 func getCachedLLMsTxt(cacheKey string) string {
-	llmsCacheMutex.RLock()
-	defer llmsCacheMutex.RUnlock()
-
-	cached, exists := llmsTxtCache[cacheKey]
-	if !exists {
-		return ""
-	}
-
-	// Check if cache is expired
-	if time.Since(cached.Timestamp) > llmsCacheExpiry {
-		// Cache expired, remove it
-		delete(llmsTxtCache, cacheKey)
-		return ""
-	}
-
-	// Check if content is still valid (based on data hash)
-	currentHash := generateContentHash()
-	if cached.Hash != currentHash {
-		// Data changed, cache invalid
-		delete(llmsTxtCache, cacheKey)
+	var cached LLMsTxtCache
+	if !llmsTxtCache.Get(cacheKey, &cached) {
 		return ""
 	}
-
 	return cached.Content
 }
 
 func setCachedLLMsTxt(cacheKey, content, lang string) {
-	llmsCacheMutex.Lock()
-	defer llmsCacheMutex.Unlock()
-
-	llmsTxtCache[cacheKey] = &LLMsTxtCache{
+	llmsTxtCache.Set(cacheKey, &LLMsTxtCache{
 		Content:   content,
 		Language:  lang,
 		Timestamp: time.Now(),
-		Hash:      generateContentHash(),
-	}
-}
-
-func generateContentHash() string {
-	// Generate a hash based on key data that affects LLMs.txt content
-	// This is a simple implementation - in production you might want to use actual hashing
-	var hashData []string
-
-	// Count articles
-	var articleCount int64
-	database.DB.Model(&models.Article{}).Count(&articleCount)
-	hashData = append(hashData, fmt.Sprintf("articles:%d", articleCount))
-
-	// Count categories
-	var categoryCount int64
-	database.DB.Model(&models.Category{}).Count(&categoryCount)
-	hashData = append(hashData, fmt.Sprintf("categories:%d", categoryCount))
-
-	// Get latest article update time
-	var latestArticle models.Article
-	if err := database.DB.Order("updated_at DESC").First(&latestArticle).Error; err == nil {
-		hashData = append(hashData, fmt.Sprintf("latest:%d", latestArticle.UpdatedAt.Unix()))
-	}
-
-	// Get settings update time
-	var settings models.SiteSettings
-	if err := database.DB.First(&settings).Error; err == nil {
-		hashData = append(hashData, fmt.Sprintf("settings:%d", settings.UpdatedAt.Unix()))
-	}
-
-	return strings.Join(hashData, "|")
+	})
 }
 
 func ClearLLMsTxtCache() {
-	llmsCacheMutex.Lock()
-	defer llmsCacheMutex.Unlock()
-
-	llmsTxtCache = make(map[string]*LLMsTxtCache)
+	llmsTxtCache.Clear()
 	log.Println("LLMs.txt cache cleared")
 }
 
 func GetCacheStats() map[string]interface{} {
-	llmsCacheMutex.RLock()
-	defer llmsCacheMutex.RUnlock()
-
+	keys := llmsTxtCache.Keys()
 	stats := map[string]interface{}{
-		"cache_entries":      len(llmsTxtCache),
+		"cache_entries":      len(keys),
 		"cache_expiry_hours": llmsCacheExpiry.Hours(),
 	}
 
-	entries := make([]map[string]interface{}, 0, len(llmsTxtCache))
-	for key, cache := range llmsTxtCache {
+	entries := make([]map[string]interface{}, 0, len(keys))
+	for _, key := range keys {
+		var entry LLMsTxtCache
+		if !llmsTxtCache.Get(key, &entry) {
+			continue
+		}
 		entries = append(entries, map[string]interface{}{
 			"key":         key,
-			"language":    cache.Language,
-			"timestamp":   cache.Timestamp,
-			"age_minutes": time.Since(cache.Timestamp).Minutes(),
+			"language":    entry.Language,
+			"timestamp":   entry.Timestamp,
+			"age_minutes": time.Since(entry.Timestamp).Minutes(),
 		})
 	}
 	stats["entries"] = entries
@@ -682,12 +721,13 @@ func GetCacheStats() map[string]interface{} {
 
 // GetLLMsTxtUsageStats returns usage statistics for LLMs.txt API
 func GetLLMsTxtUsageStats(c *gin.Context) {
-	days := 30 // Default to 30 days
-	if daysParam := c.Query("days"); daysParam != "" {
-		if parsedDays, err := strconv.Atoi(daysParam); err == nil && parsedDays > 0 {
-			days = parsedDays
-		}
+	query := struct {
+		Days int `form:"days" binding:"min=1,max=365"`
+	}{Days: 30} // Default to 30 days
+	if !bindQuery(c, &query) {
+		return
 	}
+	days := query.Days
 
 	// Get usage statistics from AI usage tracker
 	stats, err := usageTracker.GetUsageStats("llms_txt", "kuno_blog", days)
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/profiling"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestLLMsTxtQueryBudget generates llms.txt for a site with more
+// categories and articles than the budget, so a query per category or
+// article shows up as going over it
+func TestLLMsTxtQueryBudget(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "blog.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(database.QueryProfiler{}); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := models.SiteSettings{SiteTitle: "Blog", Translations: []models.SiteSettingsTranslation{{Language: "en", SiteTitle: "English Blog"}}}
+	if err := db.Create(&settings).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3*llmsTxtQueryBudget; i++ {
+		category := models.Category{Name: fmt.Sprintf("Category %d", i)}
+		if err := db.Create(&category).Error; err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j <= i%3; j++ {
+			article := models.Article{
+				Title:          fmt.Sprintf("Article %d-%d", i, j),
+				CategoryID:     category.ID,
+				SEODescription: "seo",
+				ViewCount:      uint(i),
+				Translations:   []models.ArticleTranslation{{Language: "en", Title: fmt.Sprintf("Translated %d-%d", i, j)}},
+			}
+			if err := db.Create(&article).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	profile := profiling.NewProfile("llms")
+	scoped := db.WithContext(profiling.WithProfile(context.Background(), profile))
+	var loaded models.SiteSettings
+	if err := scoped.First(&loaded).Error; err != nil {
+		t.Fatal(err)
+	}
+	content, err := generateLLMsTxtContentWithError(scoped, &loaded, "en", "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if queries, _, statements := profile.Summary(); queries > llmsTxtQueryBudget {
+		t.Errorf("generating llms.txt ran %d queries, over the budget of %d: %+v", queries, llmsTxtQueryBudget, statements)
+	}
+	for _, want := range []string{"# English Blog", "**Category 35**: 3 articles", "Translated 35-", "**Total Articles**: 72", "**Articles with SEO**: 72",
+		"- **en**: 72 articles (0 written in it, 72 translated), 100% of all articles", "- **zh**: 72 articles (72 written in it, 0 translated)",
+		"Most read in en:\n\n- Translated 35-"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("llms.txt does not contain %q", want)
+		}
+	}
+}
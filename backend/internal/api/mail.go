@@ -0,0 +1,229 @@
+package api
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"blog-backend/internal/services"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxMailWebhookSize bounds the body of a mail provider webhook; SendGrid
+// batches many events in one
+const maxMailWebhookSize = 1 << 20
+
+// GetMailSettings returns the SMTP settings in use. The password is never
+// returned, only whether one is set.
+func GetMailSettings(c *gin.Context) {
+	settings, err := services.GetGlobalMailer().Settings()
+	if err != nil {
+		respondMailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateMailSettings saves SMTP settings that replace the SMTP_*
+// configuration. Leave the password empty to keep the current one, or the
+// host empty to go back to the configuration.
+func UpdateMailSettings(c *gin.Context) {
+	var input services.MailSettings
+	if !bindJSON(c, &input) {
+		return
+	}
+	settings, err := services.GetGlobalMailer().SaveSettings(input)
+	if err != nil {
+		respondMailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// SendTestMail sends the test template to an address to check the SMTP
+// settings. Errors from the mail server are passed on to help fix them.
+func SendTestMail(c *gin.Context) {
+	var req struct {
+		To       string `json:"to" binding:"required"`
+		Language string `json:"language"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	err := services.GetGlobalMailer().SendTest(req.To, req.Language)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Test email sent to " + req.To})
+	case errors.Is(err, services.ErrInvalidEmail), errors.Is(err, services.ErrMailNotConfigured):
+		respondMailError(c, err)
+	default:
+		logging.FromGin(c).Warn("Test email failed", "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	}
+}
+
+// ListMailTemplates lists the email templates with their variables, built-in
+// languages and overridden languages
+func ListMailTemplates(c *gin.Context) {
+	templates, err := services.GetGlobalMailer().Templates()
+	if err != nil {
+		respondMailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// GetMailTemplate returns the template used for a language
+func GetMailTemplate(c *gin.Context) {
+	template, err := services.GetGlobalMailer().Template(c.Param("name"), c.Param("lang"))
+	if err != nil {
+		respondMailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// UpdateMailTemplate overrides a template for one language
+func UpdateMailTemplate(c *gin.Context) {
+	var req struct {
+		Subject string `json:"subject" binding:"required"`
+		Body    string `json:"body" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	template, err := services.GetGlobalMailer().SaveTemplate(c.Param("name"), c.Param("lang"), req.Subject, req.Body)
+	if err != nil {
+		respondMailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// ResetMailTemplate removes the override for a language
+func ResetMailTemplate(c *gin.Context) {
+	if err := services.GetGlobalMailer().DeleteTemplate(c.Param("name"), c.Param("lang")); err != nil {
+		respondMailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Template reset"})
+}
+
+// PreviewMailTemplate renders a template with sample data
+func PreviewMailTemplate(c *gin.Context) {
+	subject, body, err := services.GetGlobalMailer().PreviewTemplate(c.Param("name"), c.Param("lang"))
+	if err != nil {
+		respondMailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"subject": subject, "body": body})
+}
+
+// ListMailSuppressions returns a page of the addresses email is not sent
+// to, optionally filtered by ?reason=
+func ListMailSuppressions(c *gin.Context) {
+	paging, ok := bindPage(c, 50, maxAdminPageSize)
+	if !ok {
+		return
+	}
+	suppressions, total, err := services.GetGlobalMailer().ListSuppressions(c.Query("reason"), paging.Page, paging.Limit)
+	if err != nil {
+		respondMailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"suppressions": suppressions,
+		"pagination":   gin.H{"page": paging.Page, "limit": paging.Limit, "total": total},
+	})
+}
+
+// AddMailSuppression stops email to an address
+func AddMailSuppression(c *gin.Context) {
+	var req struct {
+		Email  string `json:"email" binding:"required"`
+		Reason string `json:"reason"`
+		Detail string `json:"detail"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = models.SuppressionManual
+	}
+	suppression, err := services.GetGlobalMailer().Suppress(req.Email, req.Reason, "admin", req.Detail)
+	if err != nil {
+		respondMailError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, suppression)
+}
+
+// DeleteMailSuppression lets email be sent to an address again
+func DeleteMailSuppression(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if err := services.GetGlobalMailer().Unsuppress(uint(id)); err != nil {
+		respondMailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Suppression removed"})
+}
+
+// MailWebhook receives bounce and complaint events from the mail provider
+// named in the path. The provider is given the URL with ?token= set to
+// SMTP_WEBHOOK_SECRET; without a secret the endpoint does not exist.
+func MailWebhook(c *gin.Context) {
+	secret, err := security.ResolveSecret(config.Get().SMTP.WebhookSecret)
+	if err != nil {
+		logging.FromGin(c).Error("Failed to resolve the mail webhook secret", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Mail operation failed"})
+		return
+	}
+	if secret == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook token"})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxMailWebhookSize+1))
+	if err != nil || len(body) > maxMailWebhookSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Webhook too large"})
+		return
+	}
+	suppressed, err := services.GetGlobalMailer().HandleWebhook(c.Request.Context(), c.Param("provider"), body)
+	if err != nil {
+		respondMailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"suppressed": suppressed})
+}
+
+func respondMailError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMailTemplateUnknown), errors.Is(err, services.ErrSuppressionNotFound),
+		errors.Is(err, services.ErrUnknownMailProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidMailSettings), errors.Is(err, services.ErrInvalidMailTemplate),
+		errors.Is(err, services.ErrInvalidEmail), errors.Is(err, services.ErrInvalidSuppression),
+		errors.Is(err, services.ErrInvalidMailWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAddressSuppressed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMailNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Mail operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Mail operation failed"})
+	}
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/services"
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxMaintenanceCacheBody is the largest response kept for maintenance mode
+const maxMaintenanceCacheBody = 2 << 20
+
+// Public responses captured while maintenance mode is on, cleared whenever
+// it is toggled
+var maintenanceResponses = cache.New("maintenance", time.Hour)
+
+func init() {
+	cache.Subscribe(cache.TopicMaintenance, maintenanceResponses.Clear)
+}
+
+// maintenanceExempt are the writes still accepted in maintenance mode, so
+// admins can sign in, switch maintenance off, create or restore backups,
+// optimize the database and follow their notifications. The notification
+// stream is also kept out of the response cache, which cannot hold a
+// websocket.
+var maintenanceExempt = []string{
+	"/api/login",
+	"/api/passkeys/login/",
+	"/api/system/maintenance",
+	"/api/system/database/optimize",
+	"/api/backups",
+	"/api/notifications/stream",
+}
+
+type cachedResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// MaintenanceMiddleware puts the API into read-only mode while maintenance
+// is enabled. Writes get 503 with Retry-After; GETs without an admin or
+// member token are answered from responses cached during maintenance, and
+// a GET that fails without a cached copy gets the same 503 instead of an
+// error.
+func MaintenanceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := services.GetGlobalMaintenanceService().State()
+		if !state.Enabled {
+			c.Next()
+			return
+		}
+
+		path := unversionedPath(c.Request.URL.Path)
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if !maintenanceExempted(path) {
+				respondMaintenance(c, state)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if c.Request.Method != http.MethodGet || c.GetHeader("Authorization") != "" || c.GetHeader(MemberTokenHeader) != "" ||
+			strings.HasPrefix(path, "/api/uploads/") || maintenanceExempted(path) {
+			c.Next()
+			return
+		}
+
+		// Keyed by the start of the maintenance window so a window never sees
+		// pages cached during an earlier one, even when another process
+		// toggled maintenance without reaching this instance's cache
+		key := c.Request.Host + c.Request.URL.RequestURI()
+		if state.Since != nil {
+			key = strconv.FormatInt(state.Since.Unix(), 10) + ":" + key
+		}
+		var cached cachedResponse
+		if maintenanceResponses.Get(key, &cached) {
+			c.Header("X-Maintenance", "cached")
+			c.Data(cached.Status, cached.ContentType, cached.Body)
+			c.Abort()
+			return
+		}
+
+		original := c.Writer
+		buffered := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = buffered
+		c.Next()
+		c.Writer = original
+
+		if buffered.status >= http.StatusInternalServerError {
+			respondMaintenance(c, state)
+			return
+		}
+		if buffered.status == http.StatusOK && buffered.body.Len() <= maxMaintenanceCacheBody {
+			maintenanceResponses.Set(key, cachedResponse{
+				Status:      buffered.status,
+				ContentType: original.Header().Get("Content-Type"),
+				Body:        buffered.body.Bytes(),
+			})
+		}
+		original.WriteHeader(buffered.status)
+		original.Write(buffered.body.Bytes())
+	}
+}
+
+func maintenanceExempted(path string) bool {
+	for _, prefix := range maintenanceExempt {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func respondMaintenance(c *gin.Context, state services.MaintenanceState) {
+	message := state.Message
+	if message == "" {
+		message = "The site is undergoing maintenance, please try again shortly"
+	}
+	c.Header("Retry-After", strconv.Itoa(state.RetryAfter))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"error":       message,
+		"code":        ErrCodeMaintenance,
+		"maintenance": true,
+		"retry_after": state.RetryAfter,
+	})
+}
+
+// bufferedWriter holds a response back so it can be cached or replaced
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+// GetMaintenance returns the maintenance mode state
+func GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, services.GetGlobalMaintenanceService().State())
+}
+
+// SetMaintenance switches maintenance mode on or off
+func SetMaintenance(c *gin.Context) {
+	var req struct {
+		Enabled    bool   `json:"enabled"`
+		Message    string `json:"message"`
+		RetryAfter int    `json:"retry_after"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	maintenance := services.GetGlobalMaintenanceService()
+	if !req.Enabled {
+		if err := maintenance.Disable(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable maintenance mode"})
+			return
+		}
+		c.JSON(http.StatusOK, maintenance.State())
+		return
+	}
+	state, err := maintenance.Enable(strings.TrimSpace(req.Message), req.RetryAfter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable maintenance mode"})
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
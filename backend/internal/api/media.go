@@ -1,9 +1,16 @@
 package api
 
 import (
-	"blog-backend/internal/database"
+	"blog-backend/internal/config"
+	"blog-backend/internal/events"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/logging"
 	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"blog-backend/internal/storage"
 	"bytes"
+	"compress/gzip"
+	"encoding/binary"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -15,51 +22,38 @@ import (
 	"image/jpeg"
 	"image/png"
 	"io"
+	"log/slog"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
-	MaxFileSize         = 100 * 1024 * 1024 // 100MB
+	MaxFileSize         = services.MaxUploadSize // 100MB, the highest limit an upload policy can set
 	MaxBatchFiles       = 20
 	MaxBatchTotalSize   = MaxFileSize
 	MaxBatchRequestSize = MaxBatchTotalSize + 1*1024*1024 // Allow multipart overhead and alt text.
 	MaxGIFFrames        = 1000                            // Prevent GIF bombs
+	MaxGIFPixels        = 200_000_000                     // Pixels of all GIF frames together
 	MaxPNGChunks        = 100                             // Prevent PNG bombs
 	MaxImageDimension   = 10000                           // 10000x10000 pixels max
+	MaxImagePixels      = 50_000_000                      // 50 megapixels
+	MaxDecodingPixels   = 2 * MaxImagePixels              // Pixels of all images being re-encoded at once
 	MaxVideoMetadata    = 10 * 1024 * 1024                // 10MB metadata limit
 )
 
 var UploadDir = getUploadDir()
 
-var allowedImageTypes = map[string]bool{
-	"image/jpeg": true,
-	"image/jpg":  true,
-	"image/png":  true,
-	"image/gif":  true,
-	// "image/webp": WebP 已禁止用户上传
-	// "image/svg+xml": SVG 已禁止用户上传 (安全原因: XSS/SSRF/XXE/DoS 风险)
-	// 注意: 系统内置 SVG 图标 (/public/**/*.svg) 不受此限制影响
-}
-
-var allowedVideoTypes = map[string]bool{
-	"video/mp4": true,
-	"video/avi": true,
-	"video/mov": true,
-	// "video/webm": WebM 已禁止
-	// "video/ogg": OGG 已禁止
-}
-
 func getUploadDir() string {
-	if dir := os.Getenv("UPLOAD_DIR"); dir != "" {
-		return dir
-	}
-	return "/app/data/uploads" // Default path
+	return config.Get().Storage.UploadDir
 }
 
 func init() {
@@ -214,15 +208,70 @@ func validateGIF(content []byte) error {
 		return fmt.Errorf("invalid GIF: missing terminator")
 	}
 
-	// Prevent GIF bombs by counting frames
+	// Prevent GIF bombs by counting frames, and the pixels decoding all of
+	// them would take
 	frameCount := bytes.Count(content, []byte{0x21, 0xF9, 0x04}) // Graphics Control Extension
+	frames, pixels := gifFrames(content)
+	if frames > frameCount {
+		frameCount = frames
+	}
 	if frameCount > MaxGIFFrames {
 		return fmt.Errorf("suspicious GIF: too many frames (potential GIF bomb): %d", frameCount)
 	}
+	if pixels > MaxGIFPixels {
+		return fmt.Errorf("suspicious GIF: frames too large (potential GIF bomb): %d pixels", pixels)
+	}
 
 	return nil
 }
 
+// gifFrames walks the blocks of a GIF and returns its number of frames and
+// their total area in pixels
+func gifFrames(content []byte) (frames int, pixels int64) {
+	if len(content) < 13 {
+		return 0, 0
+	}
+	pos := 13
+	if content[10]&0x80 != 0 {
+		pos += 3 << (content[10]&0x07 + 1)
+	}
+	skipSubBlocks := func() {
+		for pos < len(content) {
+			size := int(content[pos])
+			pos++
+			if size == 0 {
+				return
+			}
+			pos += size
+		}
+	}
+	for pos < len(content) {
+		switch content[pos] {
+		case 0x21: // Extension
+			pos += 2
+			skipSubBlocks()
+		case 0x2C: // Image descriptor
+			if pos+10 > len(content) {
+				return frames, pixels
+			}
+			width := int64(binary.LittleEndian.Uint16(content[pos+5:]))
+			height := int64(binary.LittleEndian.Uint16(content[pos+7:]))
+			flags := content[pos+9]
+			frames++
+			pixels += width * height
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			pos++ // LZW minimum code size
+			skipSubBlocks()
+		default: // Trailer, or data that is not a GIF block
+			return frames, pixels
+		}
+	}
+	return frames, pixels
+}
+
 // validateWebP checks WebP file integrity
 func validateWebP(content []byte) error {
 	if len(content) < 12 {
@@ -336,19 +385,20 @@ func detectPolyglot(content []byte) bool {
 	contentStr := string(content)
 	for _, pattern := range suspiciousPatterns {
 		if strings.Contains(contentStr, pattern) {
-			fmt.Printf("Warning: Suspicious pattern detected in file: %s\n", pattern)
+			slog.Warn("Suspicious pattern detected in file", "pattern", pattern)
 			return true
 		}
 	}
 
-	magicCount := 0
+	// Formats sharing a signature, such as WebP and AVI in RIFF, count once
+	matched := map[string]bool{}
 	for _, magic := range fileMagicNumbers {
 		if bytes.HasPrefix(content, magic) {
-			magicCount++
+			matched[string(magic)] = true
 		}
 	}
 
-	return magicCount > 1
+	return len(matched) > 1
 }
 
 // validateFileIntegrity performs comprehensive integrity checks based on file type
@@ -404,11 +454,79 @@ func validateFileContent(content []byte, declaredType string) bool {
 	return bytes.HasPrefix(content, magic)
 }
 
+// validateImageDimensions reads the size of a JPEG, PNG or GIF image from
+// its header and rejects images too large to be decoded safely
+func validateImageDimensions(content []byte) (image.Config, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return config, fmt.Errorf("cannot read image size: %v", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return config, fmt.Errorf("invalid image size: %dx%d", config.Width, config.Height)
+	}
+	if config.Width > MaxImageDimension || config.Height > MaxImageDimension {
+		return config, fmt.Errorf("image is %dx%d pixels, at most %d pixels wide and high are allowed", config.Width, config.Height, MaxImageDimension)
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return config, fmt.Errorf("image has %d pixels, at most %d are allowed", config.Width*config.Height, MaxImagePixels)
+	}
+	return config, nil
+}
+
+// pixelBudget bounds the pixels of the images being decoded at once, so
+// concurrent uploads of large images cannot exhaust memory together
+type pixelBudget struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	available int64
+}
+
+func newPixelBudget(pixels int64) *pixelBudget {
+	b := &pixelBudget{available: pixels}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// acquire waits until n pixels are available and takes them
+func (b *pixelBudget) acquire(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.available < n {
+		b.cond.Wait()
+	}
+	b.available -= n
+}
+
+func (b *pixelBudget) release(n int64) {
+	b.mu.Lock()
+	b.available += n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+var decodingPixels = newPixelBudget(MaxDecodingPixels)
+
 // stripImageMetadata removes all metadata from images by re-encoding them
 // This prevents XSS attacks via EXIF (JPEG), tEXt chunks (PNG), or comment blocks (GIF)
+// The EXIF orientation is applied to the pixels first, so photos stay upright
+// Images are only decoded within the limits and the budget of decoding pixels
 func stripImageMetadata(content []byte, mimeType string) ([]byte, error) {
+	config, err := validateImageDimensions(content)
+	if err != nil {
+		return nil, err
+	}
+	pixels := int64(config.Width * config.Height)
+	if mimeType == "image/gif" {
+		_, pixels = gifFrames(content)
+	}
+	if pixels > MaxDecodingPixels {
+		pixels = MaxDecodingPixels
+	}
+	decodingPixels.acquire(pixels)
+	defer decodingPixels.release(pixels)
+
 	var img image.Image
-	var err error
+	orientation := services.ScanImageMetadata(content, mimeType).Orientation()
 
 	// Decode image based on type
 	reader := bytes.NewReader(content)
@@ -418,6 +536,7 @@ func stripImageMetadata(content []byte, mimeType string) ([]byte, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to decode JPEG: %v", err)
 		}
+		img = services.OrientImage(img, orientation)
 
 		// Re-encode as JPEG without metadata
 		buf := new(bytes.Buffer)
@@ -432,6 +551,7 @@ func stripImageMetadata(content []byte, mimeType string) ([]byte, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to decode PNG: %v", err)
 		}
+		img = services.OrientImage(img, orientation)
 
 		// Re-encode as PNG without metadata (tEXt/zTXt/iTXt chunks removed)
 		buf := new(bytes.Buffer)
@@ -442,15 +562,19 @@ func stripImageMetadata(content []byte, mimeType string) ([]byte, error) {
 		return buf.Bytes(), nil
 
 	case "image/gif":
-		img, err = gif.Decode(reader)
+		// Decode every frame so animations keep their frames, delays and loops
+		anim, err := gif.DecodeAll(reader)
 		if err != nil {
 			return nil, fmt.Errorf("failed to decode GIF: %v", err)
 		}
+		if len(anim.Image) > MaxGIFFrames {
+			return nil, fmt.Errorf("GIF has too many frames: %d", len(anim.Image))
+		}
 
-		// Re-encode as GIF without metadata (comment blocks removed)
+		// Re-encode as GIF without metadata (comment and application
+		// extensions other than the loop count are removed)
 		buf := new(bytes.Buffer)
-		opts := &gif.Options{}
-		if err := gif.Encode(buf, img, opts); err != nil {
+		if err := gif.EncodeAll(buf, anim); err != nil {
 			return nil, fmt.Errorf("failed to encode GIF: %v", err)
 		}
 		return buf.Bytes(), nil
@@ -557,12 +681,13 @@ func UploadMedia(c *gin.Context) {
 	}
 
 	alt := c.PostForm("alt")
-	media, statusCode, uploadErr := processMediaUpload(fileHeader, alt)
+	media, statusCode, uploadErr := processMediaUpload(c, fileHeader, alt)
 	if uploadErr != nil {
 		c.JSON(statusCode, gin.H{"error": uploadErr.Error()})
 		return
 	}
 
+	events.Dispatch(c.Request.Context(), hooks.MediaUploaded, &media)
 	c.JSON(http.StatusOK, media)
 }
 
@@ -605,7 +730,7 @@ func UploadMediaBatch(c *gin.Context) {
 			alt = strings.TrimSpace(alts[i])
 		}
 
-		media, _, uploadErr := processMediaUpload(fileHeader, alt)
+		media, _, uploadErr := processMediaUpload(c, fileHeader, alt)
 		if uploadErr != nil {
 			failed = append(failed, gin.H{
 				"index":     i,
@@ -615,6 +740,7 @@ func UploadMediaBatch(c *gin.Context) {
 			continue
 		}
 
+		events.Dispatch(c.Request.Context(), hooks.MediaUploaded, &media)
 		uploaded = append(uploaded, media)
 	}
 
@@ -646,106 +772,174 @@ func validateMediaBatchUpload(files []*multipart.FileHeader) (int, error) {
 	return http.StatusOK, nil
 }
 
-func processMediaUpload(header *multipart.FileHeader, alt string) (models.MediaLibrary, int, error) {
-	var emptyMedia models.MediaLibrary
-
-	file, err := header.Open()
+// checkMediaType verifies the site's upload policy accepts the declared
+// type, extension and size, and returns the media type with its upload
+// subdirectory
+func checkMediaType(c *gin.Context, contentType, fileName string, size int64) (models.MediaType, string, int, error) {
+	policy, err := services.GetGlobalUploadPolicyService().Get(c.Request.Context())
 	if err != nil {
-		return emptyMedia, http.StatusInternalServerError, fmt.Errorf("failed to open file")
+		logging.FromGin(c).Error("Failed to load upload policy", "error", err)
+		return "", "", http.StatusInternalServerError, fmt.Errorf("failed to load upload policy")
 	}
-	defer file.Close()
+	mediaType, err := policy.Check(contentType, fileName, size)
+	if err != nil {
+		return "", "", http.StatusBadRequest, err
+	}
+	if mediaType == models.MediaTypeVideo {
+		return mediaType, "videos", http.StatusOK, nil
+	}
+	return mediaType, "images", http.StatusOK, nil
+}
 
-	if header.Size > MaxFileSize {
-		return emptyMedia, http.StatusBadRequest, fmt.Errorf("file size exceeds 100MB limit")
+// validateMediaContent runs the content security checks and returns the
+// content to store (images are re-encoded without metadata) with a report
+// of the metadata images carried
+func validateMediaContent(fileContent []byte, contentType string, mediaType models.MediaType, fileName string) ([]byte, *models.ImageMetadataReport, int, error) {
+	if !validateFileContent(fileContent, contentType) {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("file content does not match declared type. Possible Content-Type spoofing detected")
 	}
 
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		return emptyMedia, http.StatusInternalServerError, fmt.Errorf("failed to read file content")
+	if err := validateFileIntegrity(fileContent, contentType); err != nil {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("file integrity check failed: %v", err)
 	}
 
-	contentType := header.Header.Get("Content-Type")
-	if contentType == "" {
-		contentType = http.DetectContentType(fileContent)
+	// Images too large to decode are rejected before they are processed
+	if mediaType == models.MediaTypeImage && contentType != services.SVGMimeType && contentType != "image/webp" {
+		if _, err := validateImageDimensions(fileContent); err != nil {
+			return nil, nil, http.StatusBadRequest, err
+		}
 	}
 
-	fileExt := strings.ToLower(filepath.Ext(header.Filename))
-	if contentType == "image/svg+xml" || fileExt == ".svg" {
-		return emptyMedia, http.StatusBadRequest, fmt.Errorf("SVG 文件由于安全原因不允许上传。请使用 PNG、JPEG、WebP 或 GIF 格式代替。")
+	// SVG images are only accepted sanitized; what remains is checked below
+	if contentType == services.SVGMimeType {
+		sanitized, err := sanitizeSVG(fileContent)
+		if err != nil {
+			return nil, nil, http.StatusBadRequest, fmt.Errorf("invalid SVG: %v", err)
+		}
+		fileContent = sanitized
 	}
 
-	var mediaType models.MediaType
-	var subDir string
-	if allowedImageTypes[contentType] {
-		mediaType = models.MediaTypeImage
-		subDir = "images"
-	} else if allowedVideoTypes[contentType] {
-		mediaType = models.MediaTypeVideo
-		subDir = "videos"
-	} else {
-		return emptyMedia, http.StatusBadRequest, fmt.Errorf("unsupported file type")
+	if detectPolyglot(fileContent) {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("suspicious file detected: file contains multiple format signatures or executable content")
 	}
 
-	if !validateFileContent(fileContent, contentType) {
-		return emptyMedia, http.StatusBadRequest, fmt.Errorf("file content does not match declared type. Possible Content-Type spoofing detected")
+	var report *models.ImageMetadataReport
+	if mediaType == models.MediaTypeImage && contentType != services.SVGMimeType {
+		metadata := services.ScanImageMetadata(fileContent, contentType)
+		cleanContent, err := stripImageMetadata(fileContent, contentType)
+		if err != nil {
+			slog.Warn("Failed to strip metadata, uploading original", "file", fileName, "error", err)
+		} else {
+			var kept []string
+			fileContent, kept = metadata.Restore(cleanContent)
+			report = metadata.Report(kept)
+			slog.Debug("Metadata stripped from image", "file", fileName, "found", report.Found, "kept", kept)
+		}
 	}
 
-	if err := validateFileIntegrity(fileContent, contentType); err != nil {
-		return emptyMedia, http.StatusBadRequest, fmt.Errorf("file integrity check failed: %v", err)
+	return fileContent, report, http.StatusOK, nil
+}
+
+// recordImageMetadata adds the metadata an uploaded image carried to the
+// site's privacy stats
+func recordImageMetadata(c *gin.Context, media *models.MediaLibrary) {
+	if media.MetadataReport == nil {
+		return
+	}
+	if err := services.GetGlobalImageMetadataStats().Record(c.Request.Context(), media.MetadataReport); err != nil {
+		logging.FromGin(c).Warn("Failed to record image metadata stats", "media_id", media.ID, "error", err)
 	}
+}
 
-	if detectPolyglot(fileContent) {
-		return emptyMedia, http.StatusBadRequest, fmt.Errorf("suspicious file detected: file contains multiple format signatures or executable content")
+// GetMediaMetadataStats returns how many uploaded images carried each kind
+// of metadata
+func GetMediaMetadataStats(c *gin.Context) {
+	totals, err := services.GetGlobalImageMetadataStats().Totals(c.Request.Context())
+	if err != nil {
+		logging.FromGin(c).Error("Failed to load image metadata stats", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load image metadata stats"})
+		return
 	}
+	c.JSON(http.StatusOK, totals)
+}
 
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	allowedExtensions := map[string]bool{
-		".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
-		".mp4": true, ".avi": true, ".mov": true,
+func processMediaUpload(c *gin.Context, header *multipart.FileHeader, alt string) (models.MediaLibrary, int, error) {
+	var emptyMedia models.MediaLibrary
+
+	file, err := header.Open()
+	if err != nil {
+		return emptyMedia, http.StatusInternalServerError, fmt.Errorf("failed to open file")
 	}
-	if !allowedExtensions[ext] {
-		return emptyMedia, http.StatusBadRequest, fmt.Errorf("file extension not allowed")
+	defer file.Close()
+
+	if header.Size > MaxFileSize {
+		return emptyMedia, http.StatusBadRequest, fmt.Errorf("file size exceeds 100MB limit")
 	}
 
-	if mediaType == models.MediaTypeImage && contentType != "image/svg+xml" {
-		cleanContent, err := stripImageMetadata(fileContent, contentType)
-		if err != nil {
-			fmt.Printf("Warning: Failed to strip metadata from %s: %v (uploading original)\n", header.Filename, err)
-		} else {
-			fileContent = cleanContent
-			fmt.Printf("Metadata stripped from image: %s (JPEG EXIF/PNG tEXt/GIF Comment removed)\n", header.Filename)
-		}
+	fileContent, err := io.ReadAll(file)
+	if err != nil {
+		return emptyMedia, http.StatusInternalServerError, fmt.Errorf("failed to read file content")
 	}
 
-	fileName := fmt.Sprintf("%s%s", uuid.New().String(), ext)
-	filePath := filepath.Join(UploadDir, subDir, fileName)
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(fileContent)
+	}
 
-	dir := filepath.Dir(filePath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		fmt.Printf("Failed to create directory %s: %v\n", dir, err)
-		return emptyMedia, http.StatusInternalServerError, fmt.Errorf("failed to create upload directory")
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	mediaType, subDir, statusCode, err := checkMediaType(c, contentType, header.Filename, header.Size)
+	if err != nil {
+		return emptyMedia, statusCode, err
 	}
 
-	if err := os.WriteFile(filePath, fileContent, 0644); err != nil {
-		fmt.Printf("Failed to write file %s: %v\n", filePath, err)
-		return emptyMedia, http.StatusInternalServerError, fmt.Errorf("failed to save file")
+	fileContent, report, statusCode, err := validateMediaContent(fileContent, contentType, mediaType, header.Filename)
+	if err != nil {
+		return emptyMedia, statusCode, err
 	}
 
+	subDir = siteUploadDir(c, subDir)
+	fileName := fmt.Sprintf("%s%s", uuid.New().String(), ext)
 	media := models.MediaLibrary{
 		FileName:     fileName,
 		OriginalName: header.Filename,
-		FilePath:     filePath,
 		FileSize:     int64(len(fileContent)),
 		MimeType:     contentType,
 		MediaType:    mediaType,
 		URL:          fmt.Sprintf("/uploads/%s/%s", subDir, fileName),
 		Alt:          strings.TrimSpace(alt),
 	}
+	media.MetadataReport = report
+
+	if storage.IsRemote() {
+		// Remote storage keeps media under uploads/, like direct uploads
+		driver := storage.GetGlobalDriver()
+		objectKey := path.Join("uploads", subDir, fileName)
+		if err := driver.Put(objectKey, bytes.NewReader(fileContent), int64(len(fileContent)), contentType); err != nil {
+			slog.Error("Failed to store file", "driver", driver.Name(), "key", objectKey, "error", err)
+			return emptyMedia, http.StatusBadGateway, fmt.Errorf("failed to save file")
+		}
+		media.FilePath = driver.Name() + "://" + objectKey
+		media.URL = driver.URL(objectKey)
+	} else {
+		filePath := filepath.Join(UploadDir, subDir, fileName)
+		dir := filepath.Dir(filePath)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			slog.Error("Failed to create directory", "dir", dir, "error", err)
+			return emptyMedia, http.StatusInternalServerError, fmt.Errorf("failed to create upload directory")
+		}
+
+		if err := os.WriteFile(filePath, fileContent, 0644); err != nil {
+			slog.Error("Failed to write file", "path", filePath, "error", err)
+			return emptyMedia, http.StatusInternalServerError, fmt.Errorf("failed to save file")
+		}
+		media.FilePath = filePath
+	}
 
-	if err := database.DB.Create(&media).Error; err != nil {
-		os.Remove(filePath)
+	if err := siteDB(c).Create(&media).Error; err != nil {
+		removeMediaFile(media)
 		return emptyMedia, http.StatusInternalServerError, fmt.Errorf("failed to save media record")
 	}
+	recordImageMetadata(c, &media)
 
 	return media, http.StatusOK, nil
 }
@@ -755,14 +949,12 @@ func GetMediaList(c *gin.Context) {
 
 	// Parse query parameters
 	mediaType := c.Query("type")
-	page := c.DefaultQuery("page", "1")
-	limit := c.DefaultQuery("limit", "20")
-
-	pageInt, _ := strconv.Atoi(page)
-	limitInt, _ := strconv.Atoi(limit)
-	offset := (pageInt - 1) * limitInt
+	paging, ok := bindPage(c, 20, maxAdminPageSize)
+	if !ok {
+		return
+	}
 
-	query := database.DB.Model(&models.MediaLibrary{})
+	query := siteDB(c).Model(&models.MediaLibrary{})
 
 	// Filter by media type if specified
 	if mediaType != "" && (mediaType == "image" || mediaType == "video") {
@@ -774,7 +966,7 @@ func GetMediaList(c *gin.Context) {
 	query.Count(&total)
 
 	// Get paginated results
-	if err := query.Order("created_at DESC").Offset(offset).Limit(limitInt).Find(&media).Error; err != nil {
+	if err := query.Order("created_at DESC").Offset(paging.Offset()).Limit(paging.Limit).Find(&media).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch media"})
 		return
 	}
@@ -782,8 +974,8 @@ func GetMediaList(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"media": media,
 		"total": total,
-		"page":  pageInt,
-		"limit": limitInt,
+		"page":  paging.Page,
+		"limit": paging.Limit,
 	})
 }
 
@@ -795,7 +987,7 @@ func GetMedia(c *gin.Context) {
 	}
 
 	var media models.MediaLibrary
-	if err := database.DB.First(&media, id).Error; err != nil {
+	if err := siteDB(c).First(&media, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
 		return
 	}
@@ -803,6 +995,71 @@ func GetMedia(c *gin.Context) {
 	c.JSON(http.StatusOK, media)
 }
 
+// SignMediaURL returns a link to a media file that other sites may embed
+// while hotlink protection is on. ?ttl= is how long it works, such as 2h
+// or 30m, by default HOTLINK_TOKEN_TTL.
+func SignMediaURL(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media ID"})
+		return
+	}
+	var ttl time.Duration
+	if raw := c.Query("ttl"); raw != "" {
+		if ttl, err = time.ParseDuration(raw); err != nil || ttl < time.Minute {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ttl must be a duration of at least 1m"})
+			return
+		}
+	}
+
+	var media models.MediaLibrary
+	if err := siteDB(c).First(&media, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+	name, ok := strings.CutPrefix(media.URL, "/uploads")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Media in remote storage is not served by the blog"})
+		return
+	}
+
+	query, expires, err := services.GetGlobalHotlinkService().Sign(name, ttl)
+	if errors.Is(err, services.ErrHotlinkNoSecret) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Set HOTLINK_SECRET to sign media links"})
+		return
+	}
+	if err != nil {
+		logging.FromGin(c).Error("Failed to sign media link", "media_id", media.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign media link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"url": media.URL + "?" + query.Encode(), "expires_at": expires})
+}
+
+// GetMediaOriginal downloads a media file as it was uploaded, without the
+// watermark images are served with
+func GetMediaOriginal(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media ID"})
+		return
+	}
+	var media models.MediaLibrary
+	if err := siteDB(c).First(&media, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return
+	}
+	if strings.Contains(media.FilePath, "://") {
+		// Remote storage serves the file itself, without a watermark
+		c.Redirect(http.StatusFound, media.URL)
+		return
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "default-src 'none'")
+	c.Header("Cache-Control", "private, no-store")
+	c.FileAttachment(media.FilePath, media.OriginalName)
+}
+
 func UpdateMedia(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -811,7 +1068,7 @@ func UpdateMedia(c *gin.Context) {
 	}
 
 	var media models.MediaLibrary
-	if err := database.DB.First(&media, id).Error; err != nil {
+	if err := siteDB(c).First(&media, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
 		return
 	}
@@ -820,16 +1077,16 @@ func UpdateMedia(c *gin.Context) {
 		Alt string `json:"alt"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	media.Alt = req.Alt
-	if err := database.DB.Save(&media).Error; err != nil {
+	if err := siteDB(c).Save(&media).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update media"})
 		return
 	}
+	events.Dispatch(c.Request.Context(), hooks.MediaUpdated, &media)
 
 	c.JSON(http.StatusOK, media)
 }
@@ -842,22 +1099,23 @@ func DeleteMedia(c *gin.Context) {
 	}
 
 	var media models.MediaLibrary
-	if err := database.DB.First(&media, id).Error; err != nil {
+	if err := siteDB(c).First(&media, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
 		return
 	}
 
 	// Delete the file
-	if err := os.Remove(media.FilePath); err != nil {
+	if err := removeMediaFile(media); err != nil {
 		// Log the error but continue with database deletion
-		fmt.Printf("Warning: Failed to delete file %s: %v\n", media.FilePath, err)
+		slog.Warn("Failed to delete file", "path", media.FilePath, "error", err)
 	}
 
 	// Delete from database
-	if err := database.DB.Delete(&media).Error; err != nil {
+	if err := siteDB(c).Delete(&media).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete media"})
 		return
 	}
+	events.Dispatch(c.Request.Context(), hooks.MediaUpdated, &media)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Media deleted successfully"})
 }
@@ -868,8 +1126,7 @@ func BulkDeleteMedia(c *gin.Context) {
 		IDs []int `json:"ids" binding:"required"`
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -889,7 +1146,7 @@ func BulkDeleteMedia(c *gin.Context) {
 
 	// Get all media files to be deleted
 	var mediaFiles []models.MediaLibrary
-	if err := database.DB.Where("id IN ?", req.IDs).Find(&mediaFiles).Error; err != nil {
+	if err := siteDB(c).Where("id IN ?", req.IDs).Find(&mediaFiles).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch media files"})
 		return
 	}
@@ -897,13 +1154,13 @@ func BulkDeleteMedia(c *gin.Context) {
 	// Delete each file
 	for _, media := range mediaFiles {
 		// Delete the file from filesystem
-		if err := os.Remove(media.FilePath); err != nil {
+		if err := removeMediaFile(media); err != nil {
 			// Log the error but continue with database deletion
-			fmt.Printf("Warning: Failed to delete file %s: %v\n", media.FilePath, err)
+			slog.Warn("Failed to delete file", "path", media.FilePath, "error", err)
 		}
 
 		// Delete from database
-		if err := database.DB.Delete(&media).Error; err != nil {
+		if err := siteDB(c).Delete(&media).Error; err != nil {
 			failedDeletions = append(failedDeletions, map[string]interface{}{
 				"id":       media.ID,
 				"filename": media.OriginalName,
@@ -911,6 +1168,7 @@ func BulkDeleteMedia(c *gin.Context) {
 			})
 		} else {
 			successCount++
+			events.Dispatch(c.Request.Context(), hooks.MediaUpdated, &media)
 		}
 	}
 
@@ -929,26 +1187,46 @@ func BulkDeleteMedia(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-func ServeMedia(c *gin.Context) {
-	subDir := c.Param("subdir")
-	fileName := c.Param("filename")
+// mediaCacheControl is the Cache-Control of media library files by kind.
+// They are named by random IDs and never change, so clients keep them
+// long; other uploads, such as logos, are revalidated on every use.
+var mediaCacheControl = map[string]string{
+	"image": "public, max-age=2592000",
+	"video": "public, max-age=604800",
+}
 
-	// Validate subdirectory
-	if subDir != "images" && subDir != "videos" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media type"})
+// ServeMedia serves uploaded files with strict security headers. It answers
+// conditional requests from the ETag and modification time, serves byte
+// ranges so videos can seek, and gzips SVG images for clients accepting it.
+func ServeMedia(c *gin.Context) {
+	name := path.Clean("/" + c.Param("filepath"))
+	file, err := os.Open(filepath.Join(UploadDir, filepath.FromSlash(name)))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 		return
 	}
-
-	filePath := filepath.Join(UploadDir, subDir, fileName)
-
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
 		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 		return
 	}
 
 	// Security Layer 6: Set strict security response headers for all media files
-	ext := strings.ToLower(filepath.Ext(fileName))
+	ext := strings.ToLower(path.Ext(name))
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		// Systems without a MIME type table may not know video extensions
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(file, head)
+		contentType = http.DetectContentType(head[:n])
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+			return
+		}
+	}
+	kind, _, _ := strings.Cut(contentType, "/")
+	c.Header("Content-Type", contentType)
 
 	// Prevent MIME type sniffing (critical for preventing MIME confusion attacks)
 	c.Header("X-Content-Type-Options", "nosniff")
@@ -958,21 +1236,127 @@ func ServeMedia(c *gin.Context) {
 
 	// Apply strict CSP to ALL media files to prevent any potential script execution
 	// This protects against metadata-based XSS attacks in JPEG EXIF, PNG tEXt, MP4 metadata, etc.
-	if subDir == "images" {
-		if ext == ".svg" {
-			// Extra strict CSP for SVG files
-			c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src 'self' data:; script-src 'none'")
-			c.Header("Content-Type", "image/svg+xml")
-			c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", fileName))
-		} else {
-			// Strict CSP for all other images (JPEG, PNG, GIF)
-			// Even though metadata is stripped, defense in depth
-			c.Header("Content-Security-Policy", "default-src 'none'; img-src 'self'; script-src 'none'; style-src 'none'")
-		}
-	} else if subDir == "videos" {
+	switch {
+	case ext == ".svg":
+		// Extra strict CSP for SVG files
+		c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src 'self' data:; script-src 'none'")
+		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", path.Base(name)))
+	case kind == "image":
+		// Strict CSP for all other images (JPEG, PNG, GIF)
+		// Even though metadata is stripped, defense in depth
+		c.Header("Content-Security-Policy", "default-src 'none'; img-src 'self'; script-src 'none'; style-src 'none'")
+	case kind == "video":
 		// Strict CSP for video files to prevent script execution from metadata
 		c.Header("Content-Security-Policy", "default-src 'none'; media-src 'self'; script-src 'none'; style-src 'none'")
 	}
 
-	c.File(filePath)
+	// The ETag changes with the file; http.ServeContent checks it and the
+	// modification time against If-None-Match, If-Modified-Since and If-Range
+	etag := fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
+
+	if hotlink := services.GetGlobalHotlinkService(); hotlink.Enabled() && (kind == "image" || kind == "video") {
+		// Caches must not hand the copy served to one site to another
+		c.Writer.Header().Add("Vary", "Referer")
+		switch hotlink.Check(name, c.Request.Referer(), c.Request.Host, c.Request.URL.Query()) {
+		case services.HotlinkRedirect:
+			c.Header("Cache-Control", "private, no-store")
+			c.Redirect(http.StatusFound, hotlink.RedirectURL())
+			return
+		case services.HotlinkDeny:
+			c.Header("Cache-Control", "private, no-store")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Embedding this file on other sites is not allowed"})
+			return
+		case services.HotlinkWatermark:
+			c.Header("Cache-Control", "private, no-store")
+			if kind != "image" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Embedding this file on other sites is not allowed"})
+				return
+			}
+			marked, markedType, err := hotlink.Watermark(name, etag, c.Request.Host, file)
+			if errors.Is(err, services.ErrHotlinkUnsupported) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Embedding this file on other sites is not allowed"})
+				return
+			}
+			if err != nil {
+				logging.FromGin(c).Error("Failed to watermark image", "file", name, "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+				return
+			}
+			c.Header("Content-Type", markedType)
+			// No modification time, so the copy is never answered with 304
+			http.ServeContent(c.Writer, c.Request, name, time.Time{}, bytes.NewReader(marked))
+			return
+		}
+	}
+
+	var content io.ReadSeeker = file
+	if (contentType == "image/jpeg" || contentType == "image/png") && path.Base(path.Dir(name)) == "images" {
+		marked, err := services.GetGlobalWatermarkService().Apply(c.Request.Context(), name, etag, file)
+		if err != nil {
+			logging.FromGin(c).Error("Failed to watermark image", "file", name, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+			return
+		}
+		if marked != nil {
+			c.Header("Content-Type", marked.ContentType)
+			etag += "-" + marked.Tag
+			content = bytes.NewReader(marked.Data)
+		}
+	}
+
+	cacheControl := "public, no-cache"
+	if dir := path.Base(path.Dir(name)); dir == "images" || dir == "videos" {
+		if policy, ok := mediaCacheControl[kind]; ok {
+			cacheControl = policy
+		}
+	}
+	c.Header("Cache-Control", cacheControl)
+
+	if ext == ".svg" {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if acceptsGzip(c.Request.Header.Get("Accept-Encoding")) {
+			compressed, err := gzipContent(file)
+			if err != nil {
+				logging.FromGin(c).Error("Failed to compress SVG", "file", name, "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+				return
+			}
+			c.Header("Content-Encoding", "gzip")
+			etag += "-gzip"
+			content = bytes.NewReader(compressed)
+		}
+	}
+	c.Header("ETag", `"`+etag+`"`)
+
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), content)
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(acceptEncoding string) bool {
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		if strings.TrimSpace(name) != "gzip" {
+			continue
+		}
+		// gzip;q=0 refuses it
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if quality, err := strconv.ParseFloat(q, 64); err == nil && quality == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func gzipContent(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := io.Copy(gz, r); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
@@ -1,11 +1,28 @@
 package api
 
 import (
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
 	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"io"
 	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 // Test SVG sanitization
@@ -550,6 +567,16 @@ func TestValidatePNG(t *testing.T) {
 
 // Test GIF file integrity validation
 func TestValidateGIF(t *testing.T) {
+	// Frames without a Graphics Control Extension still count
+	var bomb bytes.Buffer
+	bomb.WriteString("GIF89a\x01\x00\x01\x00\x00\x00\x00")
+	for i := 0; i <= MaxGIFFrames; i++ {
+		bomb.Write([]byte{0x2C, 0, 0, 0, 0, 1, 0, 1, 0, 0, 0x02, 0x02, 0x44, 0x01, 0x00})
+	}
+	bomb.WriteByte(0x3B)
+	// Two 65535x65535 frames
+	hugeFrame := "\x2c\x00\x00\x00\x00\xff\xff\xff\xff\x00\x02\x02\x44\x01\x00"
+
 	tests := []struct {
 		name    string
 		content []byte
@@ -581,6 +608,16 @@ func TestValidateGIF(t *testing.T) {
 			content: append([]byte("GIF89a"), make([]byte, 10)...),
 			wantErr: true,
 		},
+		{
+			name:    "GIF with too many frames",
+			content: bomb.Bytes(),
+			wantErr: true,
+		},
+		{
+			name:    "GIF with frames too large",
+			content: []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00" + hugeFrame + hugeFrame + "\x3b"),
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -745,6 +782,11 @@ func TestDetectPolyglot(t *testing.T) {
 			content:    append(make([]byte, 100), []byte("eval(atob('YWxlcnQoMSk='))")...),
 			shouldFlag: true,
 		},
+		{
+			name:       "WebP sharing the RIFF signature with AVI",
+			content:    append([]byte("RIFF\x00\x01\x00\x00WEBPVP8 "), make([]byte, 200)...),
+			shouldFlag: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -836,58 +878,47 @@ func BenchmarkDetectPolyglot(b *testing.B) {
 	}
 }
 
-// Test file type rejection - verify that disallowed file types are properly rejected
+// Test file type rejection - verify that the default upload policy rejects
+// the types sites have to opt in to
 func TestFileTypeRejection(t *testing.T) {
-	// Verify SVG MIME type is not in allowed image types
-	if allowedImageTypes["image/svg+xml"] {
-		t.Error("image/svg+xml should not be in allowedImageTypes")
-	}
-
-	// Verify WebP MIME type is not in allowed image types
-	if allowedImageTypes["image/webp"] {
-		t.Error("image/webp should not be in allowedImageTypes")
-	}
-
-	// Verify WebM and OGG MIME types are not in allowed video types
-	if allowedVideoTypes["video/webm"] {
-		t.Error("video/webm should not be in allowedVideoTypes")
-	}
-
-	if allowedVideoTypes["video/ogg"] {
-		t.Error("video/ogg should not be in allowedVideoTypes")
-	}
-
-	// Verify disallowed extensions
-	allowedExtensions := map[string]bool{
-		".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
-		".mp4": true, ".avi": true, ".mov": true,
-	}
-
-	if allowedExtensions[".svg"] {
-		t.Error(".svg should not be in allowedExtensions")
-	}
-
-	if allowedExtensions[".webp"] {
-		t.Error(".webp should not be in allowedExtensions")
-	}
-
-	if allowedExtensions[".webm"] {
-		t.Error(".webm should not be in allowedExtensions")
-	}
-
-	if allowedExtensions[".ogg"] {
-		t.Error(".ogg should not be in allowedExtensions")
+	policy := services.DefaultUploadPolicy()
+
+	// SVG, WebP, WebM and OGG are refused by type and by extension
+	for _, upload := range []struct{ contentType, fileName string }{
+		{"image/svg+xml", "logo.svg"},
+		{"image/png", "logo.svg"},
+		{"image/webp", "photo.webp"},
+		{"image/jpeg", "photo.webp"},
+		{"video/webm", "clip.webm"},
+		{"video/ogg", "clip.ogg"},
+		{"video/mp4", "clip.ogg"},
+		{"text/html", "page.html"},
+	} {
+		if _, err := policy.Check(upload.contentType, upload.fileName, 1024); !errors.Is(err, services.ErrUploadNotAllowed) {
+			t.Errorf("Check(%s, %s) = %v, want ErrUploadNotAllowed", upload.contentType, upload.fileName, err)
+		}
 	}
 
-	// Count allowed types
-	expectedImageTypes := 4 // jpg, jpeg, png, gif
-	if len(allowedImageTypes) != expectedImageTypes {
-		t.Errorf("Expected %d allowed image types, got %d", expectedImageTypes, len(allowedImageTypes))
+	// jpg, jpeg, png, gif, mp4, avi and mov are accepted
+	for _, upload := range []struct {
+		contentType, fileName string
+		mediaType             models.MediaType
+	}{
+		{"image/jpeg", "photo.jpg", models.MediaTypeImage},
+		{"image/jpg", "photo.JPEG", models.MediaTypeImage},
+		{"image/png", "image.png", models.MediaTypeImage},
+		{"image/gif", "anim.gif", models.MediaTypeImage},
+		{"video/mp4", "clip.mp4", models.MediaTypeVideo},
+		{"video/avi", "clip.avi", models.MediaTypeVideo},
+		{"video/mov", "clip.mov", models.MediaTypeVideo},
+	} {
+		if mediaType, err := policy.Check(upload.contentType, upload.fileName, 1024); err != nil || mediaType != upload.mediaType {
+			t.Errorf("Check(%s, %s) = %s, %v", upload.contentType, upload.fileName, mediaType, err)
+		}
 	}
 
-	expectedVideoTypes := 3 // mp4, avi, mov
-	if len(allowedVideoTypes) != expectedVideoTypes {
-		t.Errorf("Expected %d allowed video types, got %d", expectedVideoTypes, len(allowedVideoTypes))
+	if _, err := policy.Check("image/png", "image.png", MaxFileSize+1); !errors.Is(err, services.ErrUploadNotAllowed) {
+		t.Errorf("Check() of a file over 100MB = %v, want ErrUploadNotAllowed", err)
 	}
 }
 
@@ -971,3 +1002,229 @@ func TestStripImageMetadata(t *testing.T) {
 		})
 	}
 }
+
+// Stripping turns photos upright and keeps their color profile, but not
+// the rest of their EXIF data
+func TestStripImageMetadataOrientation(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 4, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	// EXIF with orientation 6 (rotate 90° clockwise) and a camera make
+	exif := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x02" +
+		"\x01\x0f\x00\x02\x00\x00\x00\x04Acm\x00" +
+		"\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06\x00\x00" +
+		"\x00\x00\x00\x00")
+	icc := []byte("ICC_PROFILE\x00\x01\x01profile")
+	var photo bytes.Buffer
+	photo.Write(encoded.Bytes()[:2])
+	for _, segment := range []struct {
+		marker  byte
+		payload []byte
+	}{{0xE1, exif}, {0xE2, icc}} {
+		photo.Write([]byte{0xFF, segment.marker, 0, byte(len(segment.payload) + 2)})
+		photo.Write(segment.payload)
+	}
+	photo.Write(encoded.Bytes()[2:])
+
+	cleaned, report, _, err := validateMediaContent(photo.Bytes(), "image/jpeg", models.MediaTypeImage, "photo.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(cleaned))
+	if err != nil || config.Width != 2 || config.Height != 4 {
+		t.Errorf("cleaned photo is %dx%d (%v), want it turned to 2x4", config.Width, config.Height, err)
+	}
+	if bytes.Contains(cleaned, []byte("Acm")) || !bytes.Contains(cleaned, icc) {
+		t.Error("cleaned photo should keep only the color profile")
+	}
+	if strings.Join(report.Found, ",") != "camera,orientation,color_profile" || strings.Join(report.Kept, ",") != "color_profile" {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+// Animated GIFs keep every frame, their delays and loop count, but not
+// their comments
+func TestStripImageMetadataAnimatedGIF(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{LoopCount: 3}
+	for i := 0; i < 3; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 2, 2), palette)
+		frame.SetColorIndex(i%2, 0, 1)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10*(i+1))
+	}
+	var encoded bytes.Buffer
+	if err := gif.EncodeAll(&encoded, anim); err != nil {
+		t.Fatal(err)
+	}
+	// A comment extension before the trailer
+	content := append(encoded.Bytes()[:encoded.Len()-1:encoded.Len()-1], 0x21, 0xFE, 5, 'h', 'e', 'l', 'l', 'o', 0, 0x3B)
+
+	cleaned, err := stripImageMetadata(content, "image/gif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(cleaned, []byte("hello")) {
+		t.Error("cleaned GIF still has its comment")
+	}
+	decoded, err := gif.DecodeAll(bytes.NewReader(cleaned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Image) != 3 || decoded.LoopCount != 3 || decoded.Delay[2] != 30 {
+		t.Errorf("cleaned GIF has %d frames, loop count %d and delays %v", len(decoded.Image), decoded.LoopCount, decoded.Delay)
+	}
+	if decoded.Image[1].ColorIndexAt(1, 0) != 1 {
+		t.Error("second frame lost its pixels")
+	}
+}
+
+// SVG images are stored sanitized
+func TestValidateMediaContentSVG(t *testing.T) {
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="10" height="10">` +
+		`<script>alert(1)</script><rect width="10" height="10" onclick="alert(2)" fill="red"/></svg>`)
+	cleaned, report, _, err := validateMediaContent(svg, "image/svg+xml", models.MediaTypeImage, "logo.svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(cleaned, []byte("script")) || bytes.Contains(cleaned, []byte("onclick")) || !bytes.Contains(cleaned, []byte(`fill="red"`)) {
+		t.Errorf("cleaned SVG = %s", cleaned)
+	}
+	if report != nil {
+		t.Errorf("SVG metadata report = %+v, want none", report)
+	}
+}
+
+func TestServeMedia(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	previous := UploadDir
+	UploadDir = dir
+	t.Cleanup(func() { UploadDir = previous })
+	video := bytes.Repeat([]byte("0123456789"), 100)
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg">` + strings.Repeat(`<rect width="1" height="1"/>`, 20) + `</svg>`)
+	for name, content := range map[string][]byte{"videos/clip.mp4": video, "images/logo.svg": svg, "branding/logo.png": {1}} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := gin.New()
+	r.GET("/uploads/*filepath", ServeMedia)
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/uploads/videos/clip.mp4")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.Len() != len(video) || etag == "" || w.Header().Get("Accept-Ranges") != "bytes" ||
+		w.Header().Get("Cache-Control") != mediaCacheControl["video"] || w.Header().Get("Last-Modified") == "" {
+		t.Fatalf("GET video: %d, headers %v", w.Code, w.Header())
+	}
+	if w := get("/uploads/videos/clip.mp4", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("GET with a matching ETag: %d, want 304", w.Code)
+	}
+	if w := get("/uploads/videos/clip.mp4", "Range", "bytes=10-19"); w.Code != http.StatusPartialContent ||
+		w.Body.String() != "0123456789" || w.Header().Get("Content-Range") != "bytes 10-19/1000" {
+		t.Errorf("GET a range: %d, %q, Content-Range %q", w.Code, w.Body.String(), w.Header().Get("Content-Range"))
+	}
+	if w := get("/uploads/videos/clip.mp4", "Range", "bytes=10-19", "If-Range", `"stale"`); w.Code != http.StatusOK {
+		t.Errorf("GET a range of a changed file: %d, want the whole file", w.Code)
+	}
+
+	w = get("/uploads/images/logo.svg", "Accept-Encoding", "br, gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Type") != "image/svg+xml" ||
+		!strings.Contains(w.Header().Get("Content-Security-Policy"), "script-src 'none'") || w.Body.Len() >= len(svg) {
+		t.Fatalf("GET SVG with gzip: %d bytes, headers %v", w.Body.Len(), w.Header())
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(gz); !bytes.Equal(body, svg) {
+		t.Errorf("gzipped SVG = %s", body)
+	}
+	if w := get("/uploads/images/logo.svg", "Accept-Encoding", "gzip;q=0"); w.Header().Get("Content-Encoding") != "" || !bytes.Equal(w.Body.Bytes(), svg) {
+		t.Errorf("GET SVG refusing gzip: Content-Encoding %q", w.Header().Get("Content-Encoding"))
+	}
+
+	if w := get("/uploads/branding/logo.png"); w.Header().Get("Cache-Control") != "public, no-cache" {
+		t.Errorf("GET logo: Cache-Control %q, want it revalidated", w.Header().Get("Cache-Control"))
+	}
+	for _, path := range []string{"/uploads/videos/missing.mp4", "/uploads/videos", "/uploads/../media_test.go"} {
+		if w := get(path); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: %d, want 404", path, w.Code)
+		}
+	}
+}
+
+// pngHeader returns the start of a PNG claiming the given size, enough for
+// its size to be read
+func pngHeader(width, height uint32) []byte {
+	ihdr := []byte("IHDR")
+	ihdr = binary.BigEndian.AppendUint32(ihdr, width)
+	ihdr = binary.BigEndian.AppendUint32(ihdr, height)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+	header := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0d")
+	header = append(header, ihdr...)
+	header = binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(ihdr))
+	return append(header, []byte("\x00\x00\x00\x00IEND\xaeB`\x82")...)
+}
+
+func TestValidateImageDimensions(t *testing.T) {
+	for _, tt := range []struct {
+		name          string
+		width, height uint32
+		wantErr       bool
+	}{
+		{"small", 4, 4, false},
+		{"at the limit", MaxImageDimension, 5000, false},
+		{"too wide", MaxImageDimension + 1, 1, true},
+		{"too many pixels", 8000, 8000, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := validateImageDimensions(pngHeader(tt.width, tt.height)); (err != nil) != tt.wantErr {
+				t.Errorf("validateImageDimensions(%dx%d) error = %v, wantErr %v", tt.width, tt.height, err, tt.wantErr)
+			}
+		})
+	}
+
+	// Oversized images are rejected, not stored with their metadata
+	_, _, status, err := validateMediaContent(pngHeader(20000, 20000), "image/png", models.MediaTypeImage, "bomb.png")
+	if err == nil || status != http.StatusBadRequest || !strings.Contains(err.Error(), "20000x20000") {
+		t.Errorf("validateMediaContent() of a decompression bomb = %d, %v", status, err)
+	}
+	if _, err := stripImageMetadata(pngHeader(20000, 20000), "image/png"); err == nil {
+		t.Error("stripImageMetadata() decoded a decompression bomb")
+	}
+}
+
+func TestPixelBudget(t *testing.T) {
+	budget := newPixelBudget(100)
+	budget.acquire(60)
+	acquired := make(chan struct{})
+	go func() {
+		budget.acquire(60)
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired more pixels than the budget has")
+	case <-time.After(20 * time.Millisecond):
+	}
+	budget.release(60)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("released pixels were not handed on")
+	}
+}
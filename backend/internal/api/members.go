@@ -0,0 +1,204 @@
+package api
+
+import (
+	"blog-backend/internal/auth"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MemberTokenHeader carries the membership token of a reader. It is kept
+// apart from Authorization, which public endpoints take as a sign of an
+// admin.
+const MemberTokenHeader = "X-Member-Token"
+
+// membersOnlyAccessKey memoizes canReadMembersOnly for the request
+const membersOnlyAccessKey = "membersOnlyAccess"
+
+// canReadMembersOnly reports whether the request may see the full content
+// of members-only articles: it comes from an admin of the site, or carries
+// a valid membership token
+func canReadMembersOnly(c *gin.Context) bool {
+	if allowed, ok := c.Get(membersOnlyAccessKey); ok {
+		return allowed.(bool)
+	}
+	allowed := false
+	if auth.RequestClaims(c) != nil {
+		allowed = true
+	} else if token := c.GetHeader(MemberTokenHeader); token != "" {
+		_, err := services.GetGlobalMemberService().Verify(c.Request.Context(), token)
+		if err != nil && !errors.Is(err, services.ErrMemberTokenRejected) {
+			logging.FromGin(c).Error("Member token verification failed", "error", err)
+		}
+		allowed = err == nil
+	}
+	c.Set(membersOnlyAccessKey, allowed)
+	return allowed
+}
+
+// lockMembersOnly cuts members-only articles down to their teaser unless
+// the request may read them
+func lockMembersOnly(c *gin.Context, articles []models.Article) {
+	for i := range articles {
+		lockMembersOnlyArticle(c, &articles[i])
+	}
+}
+
+func lockMembersOnlyArticle(c *gin.Context, article *models.Article) {
+	if !article.MembersOnly {
+		return
+	}
+	// The response depends on who asks, so shared caches must not hand the
+	// full article to readers without access
+	c.Header("Vary", "Authorization, "+MemberTokenHeader)
+	if canReadMembersOnly(c) {
+		c.Header("Cache-Control", "private, no-store")
+		return
+	}
+	services.GetGlobalMemberService().Lock(article)
+}
+
+// MemberLogin exchanges a member's email and passcode for a membership
+// token, which readers send back in the X-Member-Token header
+func MemberLogin(c *gin.Context) {
+	var req struct {
+		Email    string `json:"email" binding:"required"`
+		Passcode string `json:"passcode" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	session, err := services.GetGlobalMemberService().Login(c.Request.Context(), req.Email, req.Passcode)
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// MemberLogout revokes the membership token of the request
+func MemberLogout(c *gin.Context) {
+	token := c.GetHeader(MemberTokenHeader)
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": MemberTokenHeader + " header required"})
+		return
+	}
+	if err := services.GetGlobalMemberService().Logout(c.Request.Context(), token); err != nil {
+		respondMemberError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Signed out"})
+}
+
+// GetCurrentMember returns the member the request's token belongs to
+func GetCurrentMember(c *gin.Context) {
+	identity, err := services.GetGlobalMemberService().Verify(c.Request.Context(), c.GetHeader(MemberTokenHeader))
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, identity)
+}
+
+// ListMembers returns the site's members, filtered by ?q= on email and name
+func ListMembers(c *gin.Context) {
+	members, err := services.GetGlobalMemberService().List(c.Request.Context(), c.Query("q"))
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+// CreateMember adds a member and returns its passcode, which is not shown
+// again
+func CreateMember(c *gin.Context) {
+	var input services.MemberInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	member, passcode, err := services.GetGlobalMemberService().Create(c.Request.Context(), input)
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"member": member, "passcode": passcode})
+}
+
+// UpdateMember edits a member. Disabling it or moving its expiry into the
+// past signs it out everywhere.
+func UpdateMember(c *gin.Context) {
+	id, ok := memberID(c)
+	if !ok {
+		return
+	}
+	var input services.MemberInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	member, err := services.GetGlobalMemberService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, member)
+}
+
+// ResetMemberPasscode gives a member a new passcode and signs it out
+func ResetMemberPasscode(c *gin.Context) {
+	id, ok := memberID(c)
+	if !ok {
+		return
+	}
+	member, passcode, err := services.GetGlobalMemberService().ResetPasscode(c.Request.Context(), id)
+	if err != nil {
+		respondMemberError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"member": member, "passcode": passcode})
+}
+
+// DeleteMember removes a member and its tokens
+func DeleteMember(c *gin.Context) {
+	id, ok := memberID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalMemberService().Delete(c.Request.Context(), id); err != nil {
+		respondMemberError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Member deleted"})
+}
+
+func memberID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondMemberError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidMember):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMemberExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMemberLoginFailed), errors.Is(err, services.ErrMemberTokenRejected):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMemberInactive):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Member operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Member operation failed"})
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/metrics"
+	"bytes"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServeMetrics exposes metrics in the Prometheus text format to scrapers
+// presenting METRICS_TOKEN as a bearer token. Without a token configured
+// the endpoint does not exist.
+func ServeMetrics(c *gin.Context) {
+	token := config.Get().Metrics.Token
+	if token == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	presented, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+		c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+		return
+	}
+
+	families, err := metrics.Gather()
+	if err != nil {
+		// Serve what was collected; a missing series is easier to alert on
+		// than a failed scrape of everything
+		logging.FromGin(c).Warn("Failed to collect some metrics", "error", err)
+	}
+	var body bytes.Buffer
+	if err := metrics.Write(&body, families); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write metrics"})
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", body.Bytes())
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListMoments returns a page of public moments, newest first
+func ListMoments(c *gin.Context) {
+	page, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	moments, total, err := services.GetGlobalMomentService().List(c.Request.Context(), []string{models.MomentPublic}, page, limit)
+	if err != nil {
+		respondMomentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"moments":    moments,
+		"pagination": gin.H{"page": page, "limit": limit, "total": total},
+	})
+}
+
+// GetMoment returns a public or unlisted moment
+func GetMoment(c *gin.Context) {
+	id, ok := momentID(c)
+	if !ok {
+		return
+	}
+	moment, err := services.GetGlobalMomentService().Get(c.Request.Context(), id, models.MomentPublic, models.MomentUnlisted)
+	if err != nil {
+		respondMomentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, moment)
+}
+
+// ListAllMoments returns a page of moments of every visibility for admins.
+// Filter with ?visibility=public,unlisted,private.
+func ListAllMoments(c *gin.Context) {
+	page, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	var visibilities []string
+	if value := c.Query("visibility"); value != "" {
+		visibilities = strings.Split(value, ",")
+	}
+	moments, total, err := services.GetGlobalMomentService().List(c.Request.Context(), visibilities, page, limit)
+	if err != nil {
+		respondMomentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"moments":    moments,
+		"pagination": gin.H{"page": page, "limit": limit, "total": total},
+	})
+}
+
+// CreateMoment posts a moment
+func CreateMoment(c *gin.Context) {
+	var input services.MomentInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	moment, err := services.GetGlobalMomentService().Create(c.Request.Context(), input)
+	if err != nil {
+		respondMomentError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, moment)
+}
+
+// UpdateMoment edits a moment or changes its visibility
+func UpdateMoment(c *gin.Context) {
+	id, ok := momentID(c)
+	if !ok {
+		return
+	}
+	var input services.MomentInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	moment, err := services.GetGlobalMomentService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondMomentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, moment)
+}
+
+// DeleteMoment removes a moment
+func DeleteMoment(c *gin.Context) {
+	id, ok := momentID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalMomentService().Delete(c.Request.Context(), id); err != nil {
+		respondMomentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Moment deleted"})
+}
+
+// pageQuery reads ?page= and ?limit= for public lists of 20 by default.
+// It answers the request when they are invalid.
+func pageQuery(c *gin.Context) (int, int, bool) {
+	paging, ok := bindPage(c, 20, maxPageSize)
+	return paging.Page, paging.Limit, ok
+}
+
+func momentID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondMomentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrMomentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidMoment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Moment operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Moment operation failed"})
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Subscribe signs an email address up for new posts and mails it a
+// confirmation link. The answer is the same whether or not the address was
+// already subscribed.
+func Subscribe(c *gin.Context) {
+	var req struct {
+		Email    string `json:"email" binding:"required"`
+		Language string `json:"language"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	err := services.GetGlobalNewsletterService().Subscribe(c.Request.Context(), req.Email, req.Language, getBaseURL(c))
+	if err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check your inbox to confirm your subscription"})
+}
+
+// ConfirmSubscription activates a subscription from the link in the
+// confirmation email
+func ConfirmSubscription(c *gin.Context) {
+	if _, err := services.GetGlobalNewsletterService().Confirm(c.Query("token")); err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription confirmed"})
+}
+
+// Unsubscribe ends a subscription from the link in a newsletter. It also
+// answers the one-click POST mail clients send for List-Unsubscribe.
+func Unsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.PostForm("token")
+	}
+	if _, err := services.GetGlobalNewsletterService().Unsubscribe(token); err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "You have been unsubscribed"})
+}
+
+// ListSubscribers returns a page of subscribers with counts by status.
+// Filter with ?status=pending|active|unsubscribed|bounced.
+func ListSubscribers(c *gin.Context) {
+	paging, ok := bindPage(c, 50, maxAdminPageSize)
+	if !ok {
+		return
+	}
+	service := services.GetGlobalNewsletterService()
+	subscribers, total, err := service.ListSubscribers(c.Request.Context(), c.Query("status"), paging.Page, paging.Limit)
+	if err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	stats, err := service.SubscriberStats(c.Request.Context())
+	if err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"subscribers": subscribers,
+		"stats":       stats,
+		"pagination":  gin.H{"page": paging.Page, "limit": paging.Limit, "total": total},
+	})
+}
+
+// DeleteSubscriber removes a subscriber
+func DeleteSubscriber(c *gin.Context) {
+	id, ok := newsletterID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalNewsletterService().DeleteSubscriber(c.Request.Context(), id); err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Subscriber deleted"})
+}
+
+// RecordBounces counts bounces reported by the mail provider
+// ({"emails": [...]}); addresses past NEWSLETTER_MAX_BOUNCES stop receiving
+// newsletters
+func RecordBounces(c *gin.Context) {
+	var req struct {
+		Emails []string `json:"emails" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	updated, err := services.GetGlobalNewsletterService().RecordBounces(c.Request.Context(), req.Emails)
+	if err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// ListNewsletters returns drafts and sent newsletters with delivery stats
+func ListNewsletters(c *gin.Context) {
+	newsletters, err := services.GetGlobalNewsletterService().ListNewsletters(c.Request.Context())
+	if err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"newsletters": newsletters})
+}
+
+// GetNewsletter returns one newsletter with its delivery stats
+func GetNewsletter(c *gin.Context) {
+	id, ok := newsletterID(c)
+	if !ok {
+		return
+	}
+	newsletter, err := services.GetGlobalNewsletterService().GetNewsletter(c.Request.Context(), id)
+	if err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newsletter)
+}
+
+// CreateNewsletter saves a draft. With {"kind": "digest"} or
+// {"kind": "announcement", "article_id": N} the draft is composed from
+// articles instead of the subject and body given. {"kind": "ai_digest"}
+// queues a job that drafts a digest written by the AI provider, optionally
+// over the last "days" and for the subscribers of one "language".
+func CreateNewsletter(c *gin.Context) {
+	var req struct {
+		services.NewsletterInput
+		Kind      string `json:"kind"`
+		ArticleID uint   `json:"article_id"`
+		Days      int    `json:"days"`
+		Limit     int    `json:"limit"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	service := services.GetGlobalNewsletterService()
+	ctx := c.Request.Context()
+	var newsletter *models.Newsletter
+	var err error
+	switch req.Kind {
+	case "", models.NewsletterCustom:
+		newsletter, err = service.CreateNewsletter(ctx, req.NewsletterInput, getBaseURL(c))
+	case models.NewsletterDigest:
+		newsletter, err = service.ComposeDigest(ctx, currentSiteID(c), getBaseURL(c))
+	case "ai_digest":
+		options := services.AIDigestOptions{Days: req.Days, Limit: req.Limit}
+		if req.Language != nil {
+			options.Language = *req.Language
+		}
+		job, err := service.QueueAIDigest(ctx, currentSiteID(c), getBaseURL(c), options)
+		if err != nil {
+			respondNewsletterError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+		return
+	case models.NewsletterAnnouncement:
+		var article models.Article
+		if err := siteDB(c).First(&article, req.ArticleID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+			return
+		}
+		newsletter, err = service.ComposeAnnouncement(ctx, &article, getBaseURL(c))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be custom, digest, ai_digest or announcement"})
+		return
+	}
+	if err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, newsletter)
+}
+
+// UpdateNewsletter edits a draft
+func UpdateNewsletter(c *gin.Context) {
+	id, ok := newsletterID(c)
+	if !ok {
+		return
+	}
+	var input services.NewsletterInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	newsletter, err := services.GetGlobalNewsletterService().UpdateNewsletter(c.Request.Context(), id, input)
+	if err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newsletter)
+}
+
+// DeleteNewsletter removes a newsletter that is not being sent
+func DeleteNewsletter(c *gin.Context) {
+	id, ok := newsletterID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalNewsletterService().DeleteNewsletter(c.Request.Context(), id); err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Newsletter deleted"})
+}
+
+// SendNewsletter queues a draft for delivery to every active subscriber
+func SendNewsletter(c *gin.Context) {
+	id, ok := newsletterID(c)
+	if !ok {
+		return
+	}
+	job, err := services.GetGlobalNewsletterService().Send(c.Request.Context(), id)
+	if err != nil {
+		respondNewsletterError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// currentSiteID returns the site of the request, or the default site when
+// multi-site mode is off
+func currentSiteID(c *gin.Context) uint {
+	if site := currentSite(c); site != nil {
+		return site.ID
+	}
+	return models.DefaultSiteID
+}
+
+func newsletterID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondNewsletterError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNewsletterNotFound), errors.Is(err, services.ErrSubscriberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSubscriptionToken):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidEmail), errors.Is(err, services.ErrInvalidNewsletter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNewsletterNotDraft), errors.Is(err, services.ErrNoArticlesForDigest):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMailNotConfigured), errors.Is(err, services.ErrLLMNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Newsletter operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Newsletter operation failed"})
+	}
+}
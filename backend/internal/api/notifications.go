@@ -0,0 +1,236 @@
+package api
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// notificationTicketTTL is how long a stream ticket can be redeemed
+	notificationTicketTTL = 30 * time.Second
+	// notificationResend is how often a stream sends the summary even when
+	// nothing changed on this instance. It keeps proxies from closing the
+	// connection and picks up notifications of instances that do not share
+	// a cache with this one.
+	notificationResend = 30 * time.Second
+	// notificationWriteTimeout bounds a send to a stalled client
+	notificationWriteTimeout = 10 * time.Second
+)
+
+// notificationTickets holds the single-use tickets that authorize a
+// notification stream. Browsers cannot set the Authorization header on a
+// websocket, so the token is exchanged for a ticket first.
+var notificationTickets = cache.New("notification_tickets", notificationTicketTTL)
+
+// ListNotifications returns a page of the notification center with the
+// unread summary. Filter with ?source=, ?type=, ?severity= and
+// ?is_read=true|false.
+func ListNotifications(c *gin.Context) {
+	paging, ok := bindPage(c, 50, maxAdminPageSize)
+	if !ok {
+		return
+	}
+	filter := services.NotificationFilter{
+		Source:   c.Query("source"),
+		Type:     c.Query("type"),
+		Severity: c.Query("severity"),
+		Page:     paging.Page,
+		Limit:    paging.Limit,
+	}
+	if isRead := c.Query("is_read"); isRead != "" {
+		read := isRead == "true"
+		filter.IsRead = &read
+	}
+
+	service := services.GetGlobalNotificationService()
+	notifications, total, err := service.List(c.Request.Context(), filter)
+	if err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+	summary, err := service.Summary(c.Request.Context())
+	if err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"summary":       summary,
+		"pagination":    gin.H{"page": paging.Page, "limit": paging.Limit, "total": total},
+	})
+}
+
+// GetNotificationSummary counts the unread notifications
+func GetNotificationSummary(c *gin.Context) {
+	summary, err := services.GetGlobalNotificationService().Summary(c.Request.Context())
+	if err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// UpdateNotification marks a notification read or unread ({"is_read": ...})
+func UpdateNotification(c *gin.Context) {
+	id, ok := notificationID(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		IsRead *bool `json:"is_read" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	notification, err := services.GetGlobalNotificationService().SetRead(c.Request.Context(), id, *req.IsRead)
+	if err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead marks every unread notification, or those of
+// ?source=, read
+func MarkAllNotificationsRead(c *gin.Context) {
+	marked, err := services.GetGlobalNotificationService().MarkAllRead(c.Request.Context(), c.Query("source"))
+	if err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// DeleteNotification removes a notification
+func DeleteNotification(c *gin.Context) {
+	id, ok := notificationID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalNotificationService().Delete(c.Request.Context(), id); err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notification deleted"})
+}
+
+// CreateNotificationStreamTicket issues a ticket that opens one
+// notification stream within notificationTicketTTL
+func CreateNotificationStreamTicket(c *gin.Context) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		respondNotificationError(c, err)
+		return
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(raw)
+	notificationTickets.Set(ticket, c.GetUint("userID"))
+	c.JSON(http.StatusOK, gin.H{
+		"ticket":     ticket,
+		"expires_in": int(notificationTicketTTL.Seconds()),
+	})
+}
+
+// StreamNotifications is a websocket that sends the unread summary as
+// {"type": "summary", ...} when it connects and whenever notifications
+// change. It is opened with ?ticket= from CreateNotificationStreamTicket.
+func StreamNotifications(c *gin.Context) {
+	var userID uint
+	ticket := c.Query("ticket")
+	if ticket == "" || !notificationTickets.Take(ticket, &userID) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired stream ticket"})
+		return
+	}
+	log := logging.FromGin(c).With("user_id", userID)
+	server := websocket.Server{
+		// The ticket authorizes the connection, whatever its origin
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			streamNotifications(ws, log)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+func streamNotifications(ws *websocket.Conn, log *slog.Logger) {
+	defer ws.Close()
+	service := services.GetGlobalNotificationService()
+	changed, stop := service.Watch()
+	defer stop()
+
+	// The client sends nothing; reading notices when it goes away
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		io.Copy(io.Discard, ws)
+		cancel()
+	}()
+
+	resend := time.NewTicker(notificationResend)
+	defer resend.Stop()
+	var last []byte
+	for {
+		summary, err := service.Summary(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error("Failed to count notifications for stream", "error", err)
+			}
+			return
+		}
+		message, _ := json.Marshal(struct {
+			Type string `json:"type"`
+			*services.NotificationSummary
+		}{"summary", summary})
+		if !bytes.Equal(message, last) {
+			ws.SetWriteDeadline(time.Now().Add(notificationWriteTimeout))
+			if err := websocket.Message.Send(ws, string(message)); err != nil {
+				return
+			}
+			last = message
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		case <-resend.C:
+			// Sent again even when unchanged
+			last = nil
+		}
+	}
+}
+
+func notificationID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondNotificationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotificationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidNotification):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Notification operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Notification operation failed"})
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestNotificationStream(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "blog.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/notifications/stream/ticket", func(c *gin.Context) {
+		c.Set("userID", uint(1))
+		CreateNotificationStreamTicket(c)
+	})
+	router.GET("/api/notifications/stream", StreamNotifications)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ticket := func() string {
+		resp, err := http.Post(server.URL+"/api/notifications/stream/ticket", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body struct {
+			Ticket string `json:"ticket"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body.Ticket
+	}
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/notifications/stream?ticket="
+
+	first := ticket()
+	ws, err := websocket.Dial(wsURL+first, "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	receive := func() services.NotificationSummary {
+		t.Helper()
+		ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var message struct {
+			Type string `json:"type"`
+			services.NotificationSummary
+		}
+		if err := websocket.JSON.Receive(ws, &message); err != nil {
+			t.Fatal(err)
+		}
+		if message.Type != "summary" {
+			t.Fatalf("message type = %q", message.Type)
+		}
+		return message.NotificationSummary
+	}
+
+	if summary := receive(); summary.Unread != 0 {
+		t.Fatalf("initial summary = %+v", summary)
+	}
+	services.GetGlobalNotificationService().Notify(services.NotificationInput{
+		Source: models.NotificationSourceBackup, Type: "backup_failed", Severity: models.SeverityError, Title: "Scheduled backup failed",
+	})
+	if summary := receive(); summary.Unread != 1 || summary.Latest == nil || summary.Latest.Title != "Scheduled backup failed" {
+		t.Fatalf("summary after a notification = %+v", summary)
+	}
+
+	// A ticket opens one stream only
+	resp, err := http.Get(server.URL + "/api/notifications/stream?ticket=" + first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("reused ticket answered %d", resp.StatusCode)
+	}
+}
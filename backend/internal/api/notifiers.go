@@ -0,0 +1,107 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListNotifiers returns the site's chat notifiers. Bot tokens and webhook
+// URLs are never returned.
+func ListNotifiers(c *gin.Context) {
+	notifiers, err := services.GetGlobalNotifierService().List(c.Request.Context())
+	if err != nil {
+		respondNotifierError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"notifiers": notifiers})
+}
+
+// CreateNotifier adds a Telegram, Discord or Slack notifier
+func CreateNotifier(c *gin.Context) {
+	var input services.NotifierInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	notifier, err := services.GetGlobalNotifierService().Create(c.Request.Context(), input)
+	if err != nil {
+		respondNotifierError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, notifier)
+}
+
+// UpdateNotifier changes a notifier; leave credential out to keep it
+func UpdateNotifier(c *gin.Context) {
+	id, ok := notifierID(c)
+	if !ok {
+		return
+	}
+	var input services.NotifierInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	notifier, err := services.GetGlobalNotifierService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondNotifierError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, notifier)
+}
+
+// DeleteNotifier removes a notifier
+func DeleteNotifier(c *gin.Context) {
+	id, ok := notifierID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalNotifierService().Delete(c.Request.Context(), id); err != nil {
+		respondNotifierError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notifier deleted"})
+}
+
+// TestNotifier posts the latest article to a notifier and passes on the
+// chat service's error, if any
+func TestNotifier(c *gin.Context) {
+	id, ok := notifierID(c)
+	if !ok {
+		return
+	}
+	err := services.GetGlobalNotifierService().Test(c.Request.Context(), id)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Test notification sent"})
+	case errors.Is(err, services.ErrNotifierNotFound):
+		respondNotifierError(c, err)
+	default:
+		logging.FromGin(c).Warn("Test notification failed", "notifier_id", id, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	}
+}
+
+func notifierID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notifier ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondNotifierError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotifierNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidNotifier):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Notifier operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Notifier operation failed"})
+	}
+}
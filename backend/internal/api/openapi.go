@@ -0,0 +1,287 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Who may call an API route, as recorded while the routes are registered
+const (
+	routeAccessPublic = "public"
+	routeAccessUser   = "user"
+	routeAccessAdmin  = "admin"
+)
+
+// openAPIPathParam matches the :name and *name parameters of gin paths
+var openAPIPathParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// apiRouteAccess records who may call the routes of an API version: each
+// mark labels the routes registered since the previous one. The OpenAPI
+// document is built from it, so it describes the routes as served.
+type apiRouteAccess struct {
+	engine *gin.Engine
+	prefix string
+	access map[string]string
+
+	once     sync.Once
+	document []byte
+	err      error
+}
+
+func newAPIRouteAccess(engine *gin.Engine, prefix string) *apiRouteAccess {
+	return &apiRouteAccess{engine: engine, prefix: prefix, access: make(map[string]string)}
+}
+
+// mark labels the routes registered since the last mark. It does nothing
+// on a nil recorder, as for copies of the routes at other prefixes.
+func (a *apiRouteAccess) mark(level string) {
+	if a == nil {
+		return
+	}
+	for _, route := range a.engine.Routes() {
+		if !strings.HasPrefix(route.Path, a.prefix+"/") {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		if _, ok := a.access[key]; !ok {
+			a.access[key] = level
+		}
+	}
+}
+
+// apiRoutes is the recorder of the routes of the current API version, set
+// up by SetupRoutes
+var apiRoutes *apiRouteAccess
+
+// ServeOpenAPI returns the OpenAPI 3 description of the current API
+// version, generated from its routes
+func ServeOpenAPI(c *gin.Context) {
+	if apiRoutes == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "API description not available"})
+		return
+	}
+	document, err := apiRoutes.openAPI()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build API description"})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", document)
+}
+
+// openAPI returns the OpenAPI document, built on first use once every
+// route is registered
+func (a *apiRouteAccess) openAPI() ([]byte, error) {
+	a.once.Do(func() {
+		a.document, a.err = json.Marshal(a.buildOpenAPI(getEnvOrDefault("NEXT_PUBLIC_APP_VERSION", "1.0.0")))
+	})
+	return a.document, a.err
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Servers    []openAPIServer                         `json:"servers"`
+	Tags       []openAPITag                            `json:"tags"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components map[string]interface{}                  `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPITag struct {
+	Name string `json:"name"`
+}
+
+type openAPIParameter struct {
+	Name     string            `json:"name"`
+	In       string            `json:"in"`
+	Required bool              `json:"required"`
+	Schema   map[string]string `json:"schema"`
+}
+
+type openAPIOperation struct {
+	OperationID string                 `json:"operationId"`
+	Summary     string                 `json:"summary"`
+	Tags        []string               `json:"tags"`
+	Parameters  []openAPIParameter     `json:"parameters,omitempty"`
+	RequestBody map[string]interface{} `json:"requestBody,omitempty"`
+	Responses   map[string]interface{} `json:"responses"`
+	Security    []map[string][]string  `json:"security"`
+	Access      string                 `json:"x-access"`
+}
+
+// buildOpenAPI describes the recorded routes. Handlers do not declare
+// their payloads, so request and response bodies are left open; the paths,
+// parameters, access and error responses are exact.
+func (a *apiRouteAccess) buildOpenAPI(version string) *openAPIDocument {
+	routes := a.engine.Routes()
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	document := &openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title: "KUNO API",
+			Description: "Version " + APIVersion1 + " of the blog API, also served at the unversioned /api. " +
+				"Public routes may be called anonymously or with an API key in the " + APIKeyHeader + " header; " +
+				"the others need a login token, an admin's for admin routes (x-access). " +
+				"Failed requests answer with the error envelope, " +
+				"whose codes GET /errors lists.",
+			Version: version,
+		},
+		Servers:    []openAPIServer{{URL: a.prefix}},
+		Paths:      make(map[string]map[string]*openAPIOperation),
+		Components: openAPIComponents(),
+	}
+	tags := make(map[string]bool)
+	operationIDs := make(map[string]int)
+	for _, route := range routes {
+		level, ok := a.access[route.Method+" "+route.Path]
+		if !ok {
+			continue
+		}
+		path := strings.TrimPrefix(route.Path, a.prefix)
+		operation := &openAPIOperation{
+			Summary:   handlerSummary(route.Handler),
+			Tags:      []string{routeTag(path)},
+			Responses: map[string]interface{}{"2XX": map[string]string{"description": "Success"}, "default": map[string]string{"$ref": "#/components/responses/Error"}},
+			Security:  []map[string][]string{{"bearerAuth": {}}},
+			Access:    level,
+		}
+		if level == routeAccessPublic {
+			operation.Security = []map[string][]string{{}}
+			if route.Method == http.MethodGet || route.Method == http.MethodHead {
+				operation.Security = append(operation.Security, map[string][]string{"apiKey": {}})
+			}
+		}
+		operationID := handlerName(route.Handler)
+		if operationIDs[operationID]++; operationIDs[operationID] > 1 {
+			operationID = fmt.Sprintf("%s%d", operationID, operationIDs[operationID])
+		}
+		operation.OperationID = operationID
+		for _, match := range openAPIPathParam.FindAllStringSubmatch(path, -1) {
+			operation.Parameters = append(operation.Parameters, openAPIParameter{
+				Name: match[1], In: "path", Required: true, Schema: map[string]string{"type": "string"},
+			})
+		}
+		switch route.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			operation.RequestBody = map[string]interface{}{
+				"content": map[string]interface{}{"application/json": map[string]interface{}{"schema": map[string]string{"type": "object"}}},
+			}
+		}
+
+		path = openAPIPathParam.ReplaceAllString(path, "{$1}")
+		if document.Paths[path] == nil {
+			document.Paths[path] = make(map[string]*openAPIOperation)
+		}
+		document.Paths[path][strings.ToLower(route.Method)] = operation
+		tags[operation.Tags[0]] = true
+	}
+	for tag := range tags {
+		document.Tags = append(document.Tags, openAPITag{Name: tag})
+	}
+	sort.Slice(document.Tags, func(i, j int) bool { return document.Tags[i].Name < document.Tags[j].Name })
+	return document
+}
+
+// openAPIComponents holds the security schemes and the error envelope
+func openAPIComponents() map[string]interface{} {
+	codes := make([]string, 0, len(errorCatalog))
+	for code := range errorCatalog {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+	return map[string]interface{}{
+		"securitySchemes": map[string]interface{}{
+			"bearerAuth": map[string]string{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			"apiKey":     map[string]string{"type": "apiKey", "in": "header", "name": APIKeyHeader},
+		},
+		"schemas": map[string]interface{}{
+			"Error": map[string]interface{}{
+				"type":     "object",
+				"required": []string{"error", "code", "message", "localized_message"},
+				"properties": map[string]interface{}{
+					"error":             map[string]string{"type": "string"},
+					"code":              map[string]interface{}{"type": "string", "enum": codes},
+					"message":           map[string]string{"type": "string"},
+					"localized_message": map[string]string{"type": "string"},
+					"request_id":        map[string]string{"type": "string"},
+					"details":           map[string]string{},
+				},
+			},
+		},
+		"responses": map[string]interface{}{
+			"Error": map[string]interface{}{
+				"description": "Error",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": map[string]string{"$ref": "#/components/schemas/Error"}},
+				},
+			},
+		},
+	}
+}
+
+// handlerName returns the name of a route's handler function, without its
+// package and receiver
+func handlerName(handler string) string {
+	handler = strings.TrimSuffix(handler, "-fm")
+	if i := strings.LastIndex(handler, "."); i >= 0 {
+		handler = handler[i+1:]
+	}
+	return handler
+}
+
+// handlerSummary turns a handler name such as GetArticleTranslations into
+// "Get article translations"
+func handlerSummary(handler string) string {
+	name := handlerName(handler)
+	var words []string
+	start := 0
+	runes := []rune(name)
+	for i := 1; i < len(runes); i++ {
+		upper := unicode.IsUpper(runes[i])
+		// A word starts at an upper-case letter after a lower-case one, or
+		// at the last capital of an acronym such as the S of SEOSettings
+		if upper && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+	for i := 1; i < len(words); i++ {
+		if word := words[i]; strings.ToUpper(word) != word {
+			words[i] = strings.ToLower(word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// routeTag groups a route by the first segment of its path
+func routeTag(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if segment == "" {
+		return "root"
+	}
+	return segment
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOpenAPIFromRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	access := newAPIRouteAccess(r, "/api/v1")
+	register := func(api *gin.RouterGroup, access *apiRouteAccess) {
+		api.GET("/articles/:id", GetArticle)
+		api.POST("/contact", SubmitContactMessage)
+		access.mark(routeAccessPublic)
+		api.GET("/me", GetCurrentUser)
+		access.mark(routeAccessUser)
+		api.DELETE("/articles/:id", DeleteArticle)
+		access.mark(routeAccessAdmin)
+	}
+	register(r.Group("/api/v1"), access)
+	register(r.Group("/api"), nil)
+
+	document := access.buildOpenAPI("1.2.3")
+	if len(document.Paths) != 3 {
+		t.Fatalf("paths = %v, want the three of /api/v1", document.Paths)
+	}
+	get := document.Paths["/articles/{id}"]["get"]
+	if get == nil || get.Access != routeAccessPublic || get.OperationID != "GetArticle" || get.Summary != "Get article" ||
+		len(get.Parameters) != 1 || get.Parameters[0].Name != "id" || len(get.Security) != 2 || get.Tags[0] != "articles" {
+		t.Errorf("GET /articles/{id} = %+v", get)
+	}
+	if post := document.Paths["/contact"]["post"]; post == nil || post.RequestBody == nil || len(post.Security) != 1 || len(post.Security[0]) != 0 {
+		t.Errorf("POST /contact = %+v", post)
+	}
+	if me := document.Paths["/me"]["get"]; me == nil || me.Access != routeAccessUser || me.Security[0]["bearerAuth"] == nil {
+		t.Errorf("GET /me = %+v", me)
+	}
+	if del := document.Paths["/articles/{id}"]["delete"]; del == nil || del.Access != routeAccessAdmin {
+		t.Errorf("DELETE /articles/{id} = %+v", del)
+	}
+	if document.Servers[0].URL != "/api/v1" || document.Info.Version != "1.2.3" {
+		t.Errorf("servers %v, version %q", document.Servers, document.Info.Version)
+	}
+
+	for name, want := range map[string]string{
+		"GetSEOSettings":                  "Get SEO settings",
+		"(*SEOController).GetKeywords-fm": "Get keywords",
+		"GetAIUsageStats":                 "Get AI usage stats",
+	} {
+		if got := handlerSummary("blog-backend/internal/api." + name); got != want {
+			t.Errorf("handlerSummary(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
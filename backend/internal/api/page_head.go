@@ -0,0 +1,198 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageHeadResponse is a page head with its tags rendered
+type pageHeadResponse struct {
+	*services.PageHead
+	HTML string `json:"html"`
+}
+
+// GetPageHead returns the prepared <head> of a frontend route, such as
+// /en/article/my-post, so SSR and static frontends and custom themes show
+// the same title, Open Graph, hreflang and schema.org data without
+// building them. ?path= is the route, with or without its language prefix,
+// and ?lang= the language when the path has none. ?format=html answers
+// with the tags alone.
+func GetPageHead(c *gin.Context) {
+	var query struct {
+		Path   string `form:"path" binding:"required,max=500"`
+		Lang   string `form:"lang" binding:"omitempty,max=10"`
+		Format string `form:"format" binding:"omitempty,oneof=json html"`
+	}
+	if !bindQuery(c, &query) {
+		return
+	}
+	route, err := url.Parse(query.Path)
+	if err != nil || route.IsAbs() || route.Host != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path must be a path on the site"})
+		return
+	}
+
+	languages := languageConfig(c)
+	segments := strings.FieldsFunc(route.Path, func(r rune) bool { return r == '/' })
+	language := query.Lang
+	if len(segments) > 0 {
+		if _, ok := languages.SupportedLanguages[segments[0]]; ok {
+			if language == "" {
+				language = segments[0]
+			}
+			segments = segments[1:]
+		}
+	}
+	if language == "" {
+		language = services.DefaultRouteLanguage
+	}
+
+	site, err := pageHeadSite(c, languages, language)
+	if err != nil {
+		logging.FromGin(c).Error("Failed to load the site for a page head", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare the page head"})
+		return
+	}
+
+	var head *services.PageHead
+	regional, scheduled := false, false
+	if len(segments) == 2 && segments[0] == "article" {
+		article, ok := pageHeadArticle(c, segments[1], language)
+		if !ok {
+			return
+		}
+		// Scheduled articles are seen by admins and through preview links,
+		// neither of which search engines should index
+		scheduled = article.Article.CreatedAt.After(time.Now())
+		site.NoIndex = site.NoIndex || scheduled
+		head = services.ArticlePageHead(site, *article, language)
+		regional = article.Article.RegionRule != ""
+	} else {
+		head = services.SitePageHead(site, "/"+strings.Join(segments, "/"), language)
+	}
+
+	// Admins and preview links see scheduled articles, and whether an
+	// article with a region rule is served depends on the visitor, so none
+	// of these is cached for everyone
+	if isAdminRequest(c) || scheduled || regional {
+		c.Header("Cache-Control", "private, no-cache")
+	} else {
+		c.Header("Cache-Control", "public, max-age=300")
+	}
+	c.Header("Vary", "Host, X-Forwarded-Host, X-Forwarded-Proto, X-Forwarded-Prefix")
+	if query.Format == "html" {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(head.HTML()))
+		return
+	}
+	c.JSON(http.StatusOK, pageHeadResponse{PageHead: head, HTML: head.HTML()})
+}
+
+// pageHeadSite loads what a page head tells about the site, in language
+// where the site title is translated
+func pageHeadSite(c *gin.Context, languages LanguageConfig, language string) (services.HeadSite, error) {
+	var settings models.SiteSettings
+	if err := siteDB(c).Preload("Translations").Limit(1).Find(&settings).Error; err != nil {
+		return services.HeadSite{}, err
+	}
+	if language != languages.DefaultLanguage {
+		for _, translation := range settings.Translations {
+			if translation.Language == language {
+				if translation.SiteTitle != "" {
+					settings.SiteTitle = translation.SiteTitle
+				}
+				if translation.SiteSubtitle != "" {
+					settings.SiteSubtitle = translation.SiteSubtitle
+				}
+			}
+		}
+	}
+	verification, err := services.GetGlobalSiteVerificationService().Meta(c.Request.Context())
+	if err != nil {
+		return services.HeadSite{}, err
+	}
+
+	// The default language leads, as the alternate of last resort
+	enabled := []string{languages.DefaultLanguage}
+	for _, enabledLanguage := range languages.EnabledLanguages {
+		if enabledLanguage != languages.DefaultLanguage {
+			enabled = append(enabled, enabledLanguage)
+		}
+	}
+	site := services.HeadSite{
+		BaseURL:      getBaseURL(c),
+		Title:        settings.SiteTitle,
+		Description:  settings.SiteSubtitle,
+		FaviconURL:   settings.FaviconURL,
+		Languages:    enabled,
+		NoIndex:      settings.BlockSearchEngines,
+		Verification: verification,
+	}
+	if services.GetGlobalFeatureService().Enabled(services.FeatureWebmention) {
+		site.Webmention = site.BaseURL + "/api/webmention"
+	}
+	return site, nil
+}
+
+// pageHeadArticle loads the article a route names by ID or slug, as its
+// page shows it in language, answering 404 for missing articles and for
+// scheduled ones outside admin and preview requests, and 451 for those
+// withheld in the visitor's region, as the article endpoint does
+func pageHeadArticle(c *gin.Context, slug, language string) (*services.HeadArticle, bool) {
+	articleID, err := strconv.ParseUint(slug, 10, 32)
+	if err != nil {
+		var resolved uint
+		if resolved, _, err = services.ResolveArticleSlug(siteDB(c), slug, language); err != nil {
+			logging.FromGin(c).Error("Failed to resolve an article slug", "slug", slug, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare the page head"})
+			return nil, false
+		}
+		articleID = uint64(resolved)
+	}
+	var article models.Article
+	if articleID == 0 || siteDB(c).Preload("Translations").Limit(1).Find(&article, articleID).Error != nil ||
+		article.ID == 0 || scheduledHidden(c, &article) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return nil, false
+	}
+	if respondRegionWithheld(c, &article) {
+		return nil, false
+	}
+
+	// The languages the article can be read in, its own first
+	available := []string{article.DefaultLang}
+	for _, translation := range article.Translations {
+		if translation.Language != article.DefaultLang && (strings.TrimSpace(translation.Title) != "" ||
+			strings.TrimSpace(translation.Summary) != "" || strings.TrimSpace(translation.Content) != "") {
+			available = append(available, translation.Language)
+		}
+	}
+	if language != article.DefaultLang {
+		services.ApplyTranslation(&article, language)
+	}
+
+	ctx := c.Request.Context()
+	articles := []models.Article{article}
+	if err := services.GetGlobalAuthorService().Attribute(ctx, articles); err != nil {
+		logging.FromGin(c).Warn("Failed to credit the article's author", "article_id", article.ID, "error", err)
+	}
+	if err := services.ApplyLicenses(siteDB(c), articles); err != nil {
+		logging.FromGin(c).Warn("Failed to resolve the article's license", "article_id", article.ID, "error", err)
+	}
+	var crumbs []models.CategoryCrumb
+	if article.CategoryID != 0 {
+		crumbs, err = services.GetGlobalCategoryService().Path(ctx, article.CategoryID, language)
+		if err != nil && !errors.Is(err, services.ErrCategoryNotFound) {
+			logging.FromGin(c).Warn("Failed to load the article's category path", "article_id", article.ID, "error", err)
+		}
+	}
+	return &services.HeadArticle{Article: &articles[0], Languages: available, Crumbs: crumbs}, true
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListPages returns the published pages, in the language of ?lang= where
+// translated. ?nav=header or ?nav=footer lists the pages of a navigation.
+func ListPages(c *gin.Context) {
+	var nav *string
+	if value, ok := c.GetQuery("nav"); ok {
+		nav = &value
+	}
+	pages, err := services.GetGlobalPageService().List(c.Request.Context(), true, nav)
+	if err != nil {
+		respondPageError(c, err)
+		return
+	}
+	if lang := c.Query("lang"); lang != "" {
+		for i := range pages {
+			services.ApplyPageTranslation(&pages[i], lang)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"pages": pages})
+}
+
+// GetPage returns a published page by slug, in the language of ?lang=
+// where translated
+func GetPage(c *gin.Context) {
+	page, err := services.GetGlobalPageService().GetBySlug(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		respondPageError(c, err)
+		return
+	}
+	if lang := c.Query("lang"); lang != "" {
+		services.ApplyPageTranslation(page, lang)
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// ListAllPages returns every page, including drafts, with all its
+// translations
+func ListAllPages(c *gin.Context) {
+	pages, err := services.GetGlobalPageService().List(c.Request.Context(), false, nil)
+	if err != nil {
+		respondPageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pages": pages})
+}
+
+// CreatePage adds a page at the end of the navigation
+func CreatePage(c *gin.Context) {
+	var input services.PageInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	page, err := services.GetGlobalPageService().Create(c.Request.Context(), input)
+	if err != nil {
+		respondPageError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, page)
+}
+
+// UpdatePage edits a page and, when the body has them, replaces its
+// translations
+func UpdatePage(c *gin.Context) {
+	id, ok := pageID(c)
+	if !ok {
+		return
+	}
+	var input services.PageInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	page, err := services.GetGlobalPageService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondPageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// DeletePage removes a page
+func DeletePage(c *gin.Context) {
+	id, ok := pageID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalPageService().Delete(c.Request.Context(), id); err != nil {
+		respondPageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Page deleted"})
+}
+
+// UpdatePageOrder sets the navigation order of pages
+// ([{"id": 1, "order": 2}, ...])
+func UpdatePageOrder(c *gin.Context) {
+	var orders []services.PageOrder
+	if !bindJSON(c, &orders) {
+		return
+	}
+	if err := services.GetGlobalPageService().Reorder(c.Request.Context(), orders); err != nil {
+		respondPageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Order updated"})
+}
+
+func pageID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondPageError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Page operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Page operation failed"})
+	}
+}
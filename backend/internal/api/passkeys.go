@@ -0,0 +1,414 @@
+package api
+
+import (
+	"blog-backend/internal/auth"
+	"blog-backend/internal/database"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ceremony purposes for pending passkey sessions
+const (
+	passkeyPurposeRegister = "register"
+	passkeyPurposeLogin    = "login"
+	passkeyPurposeMFA      = "mfa"
+)
+
+type passkeyCredentialResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON"`
+	AttestationObject string   `json:"attestationObject"`
+	AuthenticatorData string   `json:"authenticatorData"`
+	Signature         string   `json:"signature"`
+	UserHandle        string   `json:"userHandle"`
+	Transports        []string `json:"transports"`
+}
+
+type passkeyCredential struct {
+	ID       string                    `json:"id"`
+	RawID    string                    `json:"rawId"`
+	Type     string                    `json:"type"`
+	Response passkeyCredentialResponse `json:"response"`
+}
+
+// credentialID returns the base64url credential ID, preferring rawId
+func (p passkeyCredential) credentialID() string {
+	if p.RawID != "" {
+		return strings.TrimRight(p.RawID, "=")
+	}
+	return strings.TrimRight(p.ID, "=")
+}
+
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// passkeyUserHandle is the opaque user.id sent to authenticators
+func passkeyUserHandle(userID uint) string {
+	return encodeBase64URL([]byte(strconv.FormatUint(uint64(userID), 10)))
+}
+
+// beginPasskeyAssertion creates a login challenge, limited to the user's own
+// credentials when userID is known
+func beginPasskeyAssertion(c *gin.Context, userID uint, purpose string) (gin.H, error) {
+	challenge, err := auth.NewWebAuthnChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := auth.SaveWebAuthnSession(&auth.WebAuthnSession{
+		Challenge: challenge,
+		UserID:    userID,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	allowCredentials := []gin.H{}
+	if userID != 0 {
+		var credentials []models.WebAuthnCredential
+		database.DB.Where("user_id = ?", userID).Find(&credentials)
+		for _, credential := range credentials {
+			entry := gin.H{"type": "public-key", "id": credential.CredentialID}
+			if credential.Transports != "" {
+				entry["transports"] = strings.Split(credential.Transports, ",")
+			}
+			allowCredentials = append(allowCredentials, entry)
+		}
+	}
+
+	cfg := auth.GetWebAuthnConfig(c.Request.Host)
+	return gin.H{
+		"session_id": sessionID,
+		"options": gin.H{
+			"challenge":        encodeBase64URL(challenge),
+			"rpId":             cfg.RPID,
+			"timeout":          300000,
+			"userVerification": "preferred",
+			"allowCredentials": allowCredentials,
+		},
+	}, nil
+}
+
+// BeginPasskeyRegistration returns creation options for registering a new passkey
+func BeginPasskeyRegistration(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	challenge, err := auth.NewWebAuthnChallenge()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate challenge"})
+		return
+	}
+
+	sessionID, err := auth.SaveWebAuthnSession(&auth.WebAuthnSession{
+		Challenge: challenge,
+		UserID:    user.ID,
+		Purpose:   passkeyPurposeRegister,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start registration"})
+		return
+	}
+
+	// Prevent registering the same authenticator twice
+	var existing []models.WebAuthnCredential
+	database.DB.Where("user_id = ?", user.ID).Find(&existing)
+	excludeCredentials := make([]gin.H, 0, len(existing))
+	for _, credential := range existing {
+		excludeCredentials = append(excludeCredentials, gin.H{"type": "public-key", "id": credential.CredentialID})
+	}
+
+	cfg := auth.GetWebAuthnConfig(c.Request.Host)
+	c.JSON(http.StatusOK, gin.H{
+		"session_id": sessionID,
+		"options": gin.H{
+			"challenge": encodeBase64URL(challenge),
+			"rp": gin.H{
+				"id":   cfg.RPID,
+				"name": cfg.RPName,
+			},
+			"user": gin.H{
+				"id":          passkeyUserHandle(user.ID),
+				"name":        user.Username,
+				"displayName": user.Username,
+			},
+			"pubKeyCredParams": []gin.H{
+				{"type": "public-key", "alg": auth.COSEAlgES256},
+				{"type": "public-key", "alg": auth.COSEAlgEdDSA},
+				{"type": "public-key", "alg": auth.COSEAlgRS256},
+			},
+			"timeout":     300000,
+			"attestation": "none",
+			"authenticatorSelection": gin.H{
+				"residentKey":      "preferred",
+				"userVerification": "preferred",
+			},
+			"excludeCredentials": excludeCredentials,
+		},
+	})
+}
+
+// FinishPasskeyRegistration verifies the attestation and stores the passkey
+func FinishPasskeyRegistration(c *gin.Context) {
+	var req struct {
+		SessionID  string            `json:"session_id" binding:"required"`
+		DeviceName string            `json:"device_name"`
+		Credential passkeyCredential `json:"credential" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	session, ok := auth.ConsumeWebAuthnSession(req.SessionID, passkeyPurposeRegister)
+	if !ok || session.UserID != c.GetUint("userID") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Registration session expired or invalid"})
+		return
+	}
+
+	clientDataJSON, err1 := auth.DecodeBase64URL(req.Credential.Response.ClientDataJSON)
+	attestationObject, err2 := auth.DecodeBase64URL(req.Credential.Response.AttestationObject)
+	if err1 != nil || err2 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Malformed credential response"})
+		return
+	}
+
+	cfg := auth.GetWebAuthnConfig(c.Request.Host)
+	data, err := auth.VerifyRegistration(cfg, session.Challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		logging.FromGin(c).Warn("Passkey registration failed", "user_id", session.UserID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Passkey verification failed: " + err.Error()})
+		return
+	}
+
+	deviceName := strings.TrimSpace(req.DeviceName)
+	if deviceName == "" {
+		deviceName = "Passkey " + time.Now().Format("2006-01-02")
+	}
+	if len(deviceName) > 100 {
+		deviceName = deviceName[:100]
+	}
+
+	credential := models.WebAuthnCredential{
+		UserID:       session.UserID,
+		CredentialID: encodeBase64URL(data.ID),
+		PublicKey:    data.PublicKey,
+		SignCount:    data.SignCount,
+		AAGUID:       formatAAGUID(data.AAGUID),
+		Transports:   strings.Join(req.Credential.Response.Transports, ","),
+		DeviceName:   deviceName,
+	}
+
+	var count int64
+	database.DB.Model(&models.WebAuthnCredential{}).Where("credential_id = ?", credential.CredentialID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This passkey is already registered"})
+		return
+	}
+
+	if err := database.DB.Create(&credential).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save passkey"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, credential)
+}
+
+// formatAAGUID renders the authenticator model GUID in its canonical form
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	h := hex.EncodeToString(aaguid)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+}
+
+// ListPasskeys returns the current user's registered passkeys
+func ListPasskeys(c *gin.Context) {
+	var credentials []models.WebAuthnCredential
+	if err := database.DB.Where("user_id = ?", c.GetUint("userID")).Order("created_at DESC").Find(&credentials).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+// RenamePasskey updates the device name of a passkey
+func RenamePasskey(c *gin.Context) {
+	var req struct {
+		DeviceName string `json:"device_name" binding:"required,max=100"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var credential models.WebAuthnCredential
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("userID")).First(&credential).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passkey not found"})
+		return
+	}
+
+	credential.DeviceName = strings.TrimSpace(req.DeviceName)
+	if err := database.DB.Save(&credential).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update passkey"})
+		return
+	}
+
+	c.JSON(http.StatusOK, credential)
+}
+
+// RevokePasskey deletes a passkey. Removing the last one also turns off the
+// passkey requirement so the account cannot be locked out.
+func RevokePasskey(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	result := database.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).Delete(&models.WebAuthnCredential{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke passkey"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passkey not found"})
+		return
+	}
+
+	var remaining int64
+	database.DB.Model(&models.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&remaining)
+	if remaining == 0 {
+		database.DB.Model(&models.User{}).Where("id = ?", userID).Update("passkey_required", false)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey revoked successfully"})
+}
+
+// SetPasskeyRequirement enables or disables passkeys as a second factor
+func SetPasskeyRequirement(c *gin.Context) {
+	var req struct {
+		Required bool `json:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userID := c.GetUint("userID")
+	if req.Required {
+		var count int64
+		database.DB.Model(&models.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Register a passkey before requiring it"})
+			return
+		}
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).Update("passkey_required", req.Required).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update passkey requirement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey requirement updated", "passkey_required": req.Required})
+}
+
+// BeginPasskeyLogin starts a passwordless login. Without a username the
+// browser offers any discoverable passkey for this site.
+func BeginPasskeyLogin(c *gin.Context) {
+	var req struct {
+		Username string `json:"username"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	var userID uint
+	if req.Username != "" {
+		var user models.User
+		if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err == nil {
+			userID = user.ID
+		}
+	}
+
+	response, err := beginPasskeyAssertion(c, userID, passkeyPurposeLogin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// FinishPasskeyLogin verifies an assertion and issues a token. It completes
+// both passwordless logins and the second step of a password login.
+func FinishPasskeyLogin(c *gin.Context) {
+	var req struct {
+		SessionID  string            `json:"session_id" binding:"required"`
+		Credential passkeyCredential `json:"credential" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	session, ok := auth.ConsumeWebAuthnSession(req.SessionID, passkeyPurposeLogin, passkeyPurposeMFA)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login session expired or invalid"})
+		return
+	}
+
+	var credential models.WebAuthnCredential
+	if err := database.DB.Where("credential_id = ?", req.Credential.credentialID()).First(&credential).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unknown passkey"})
+		return
+	}
+	if session.UserID != 0 && credential.UserID != session.UserID {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey does not belong to this user"})
+		return
+	}
+
+	clientDataJSON, err1 := auth.DecodeBase64URL(req.Credential.Response.ClientDataJSON)
+	authenticatorData, err2 := auth.DecodeBase64URL(req.Credential.Response.AuthenticatorData)
+	signature, err3 := auth.DecodeBase64URL(req.Credential.Response.Signature)
+	if err1 != nil || err2 != nil || err3 != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Malformed credential response"})
+		return
+	}
+
+	cfg := auth.GetWebAuthnConfig(c.Request.Host)
+	signCount, err := auth.VerifyAssertion(cfg, session.Challenge, clientDataJSON, authenticatorData, signature, credential.PublicKey, credential.SignCount)
+	if err != nil {
+		logging.FromGin(c).Warn("Passkey login failed", "credential_id", credential.ID, "error", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey verification failed"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, credential.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	now := time.Now()
+	credential.SignCount = signCount
+	credential.LastUsedAt = &now
+	database.DB.Save(&credential)
+
+	token, err := startLoginSession(c, user, "passkey")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Token: token,
+		User:  user,
+	})
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdatePinnedOrder saves the order of the pinned articles, as dragged in
+// the admin panel
+func UpdatePinnedOrder(c *gin.Context) {
+	var orders []services.PinOrder
+	if !bindJSON(c, &orders) {
+		return
+	}
+	if err := services.GetGlobalPinService().Reorder(c.Request.Context(), orders); err != nil {
+		respondPinError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Order updated"})
+}
+
+func respondPinError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPin), errors.Is(err, services.ErrPinLimitReached):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Pin operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Pin operation failed"})
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListPlugins returns the configured webhook plugins
+func ListPlugins(c *gin.Context) {
+	plugins, err := services.GetGlobalPluginService().List()
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"plugins": plugins})
+}
+
+// ListHooks returns the hook events and the hooks registered for each
+func ListHooks(c *gin.Context) {
+	registered := hooks.Registered()
+	events := make([]gin.H, 0, len(hooks.Events))
+	for _, name := range hooks.EventNames() {
+		event := hooks.Event(name)
+		events = append(events, gin.H{
+			"event":  name,
+			"filter": hooks.IsFilter(event),
+			"hooks":  registered[event],
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// CreatePlugin adds a webhook plugin and returns its signing secret once
+func CreatePlugin(c *gin.Context) {
+	var input services.PluginInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	plugin, secret, err := services.GetGlobalPluginService().Create(input)
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"plugin": plugin, "secret": secret})
+}
+
+// UpdatePlugin changes a plugin's URL, events, secret or enabled state
+func UpdatePlugin(c *gin.Context) {
+	id, ok := pluginID(c)
+	if !ok {
+		return
+	}
+	var input services.PluginInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	plugin, err := services.GetGlobalPluginService().Update(id, input)
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, plugin)
+}
+
+// DeletePlugin removes a plugin
+func DeletePlugin(c *gin.Context) {
+	id, ok := pluginID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalPluginService().Delete(id); err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Plugin deleted"})
+}
+
+// TestPlugin sends a ping event to a plugin
+func TestPlugin(c *gin.Context) {
+	id, ok := pluginID(c)
+	if !ok {
+		return
+	}
+	result, err := services.GetGlobalPluginService().Test(id)
+	if err != nil {
+		respondPluginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func pluginID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid plugin ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondPluginError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPluginNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPlugin):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Plugin operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Plugin operation failed"})
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/profiling"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProfilingMiddleware records requests slower than the slow request
+// threshold together with the queries they ran. Only queries issued with the
+// request context (see siteDB) are attributed to a request; slow queries
+// from elsewhere still show up in the slow query list.
+func ProfilingMiddleware() gin.HandlerFunc {
+	threshold := config.Get().Logging.SlowRequest.Std()
+	return func(c *gin.Context) {
+		if threshold <= 0 {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		profile := profiling.NewProfile(logging.GetRequestID(c))
+		c.Request = c.Request.WithContext(profiling.WithProfile(c.Request.Context(), profile))
+		c.Next()
+
+		duration := time.Since(start)
+		if duration < threshold {
+			return
+		}
+		queries, queryTime, statements := profile.Summary()
+		profiling.Default().AddRequest(profiling.Request{
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			Route:      c.FullPath(),
+			Status:     c.Writer.Status(),
+			Duration:   duration,
+			At:         start,
+			RequestID:  profile.RequestID,
+			Queries:    queries,
+			QueryTime:  queryTime,
+			Statements: statements,
+		})
+		logging.FromGin(c).Warn("Slow request",
+			"path", c.Request.URL.Path,
+			"latency_ms", duration.Milliseconds(),
+			"queries", queries,
+			"query_ms", queryTime.Milliseconds())
+	}
+}
+
+// GetProfiling returns the slow requests and queries recorded by this
+// instance
+func GetProfiling(c *gin.Context) {
+	settings := config.Get().Logging
+	recorder := profiling.Default()
+	c.JSON(http.StatusOK, gin.H{
+		"slow_request_threshold": settings.SlowRequest.Std().String(),
+		"slow_query_threshold":   settings.SlowQuery.Std().String(),
+		"pprof":                  settings.Pprof,
+		"requests":               recorder.Requests(),
+		"queries":                recorder.Queries(),
+	})
+}
+
+// ResetProfiling clears the recorded slow requests and queries
+func ResetProfiling(c *gin.Context) {
+	profiling.Default().Reset()
+	c.JSON(http.StatusOK, gin.H{"message": "Profiling data cleared"})
+}
+
+// registerPprof exposes the Go profiler under group when enabled in the
+// configuration, e.g. go tool pprof with an admin token against
+// /api/system/pprof/profile
+func registerPprof(group *gin.RouterGroup) {
+	if !config.Get().Logging.Pprof {
+		return
+	}
+	pprofGroup := group.Group("/pprof")
+	{
+		pprofGroup.GET("/", gin.WrapF(pprof.Index))
+		pprofGroup.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		pprofGroup.GET("/profile", gin.WrapF(pprof.Profile))
+		pprofGroup.GET("/symbol", gin.WrapF(pprof.Symbol))
+		pprofGroup.POST("/symbol", gin.WrapF(pprof.Symbol))
+		pprofGroup.GET("/trace", gin.WrapF(pprof.Trace))
+		pprofGroup.GET("/:profile", func(c *gin.Context) {
+			pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListProjects returns the active projects for the portfolio page, in the
+// language of ?lang= where translated. Filter with ?status=.
+func ListProjects(c *gin.Context) {
+	projects, err := services.GetGlobalProjectService().List(c.Request.Context(), true, c.Query("status"))
+	if err != nil {
+		respondProjectError(c, err)
+		return
+	}
+	if lang := c.Query("lang"); lang != "" {
+		for i := range projects {
+			services.ApplyProjectTranslation(&projects[i], lang)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"projects": projects})
+}
+
+// GetProject returns an active project, in the language of ?lang= where
+// translated
+func GetProject(c *gin.Context) {
+	id, ok := projectID(c)
+	if !ok {
+		return
+	}
+	project, err := services.GetGlobalProjectService().Get(c.Request.Context(), id, true)
+	if err != nil {
+		respondProjectError(c, err)
+		return
+	}
+	if lang := c.Query("lang"); lang != "" {
+		services.ApplyProjectTranslation(project, lang)
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// ListAllProjects returns every project, including hidden ones, with all
+// its translations. Filter with ?status=.
+func ListAllProjects(c *gin.Context) {
+	projects, err := services.GetGlobalProjectService().List(c.Request.Context(), false, c.Query("status"))
+	if err != nil {
+		respondProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"projects": projects})
+}
+
+// CreateProject adds a project at the end of the portfolio
+func CreateProject(c *gin.Context) {
+	var input services.ProjectInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	project, err := services.GetGlobalProjectService().Create(c.Request.Context(), input)
+	if err != nil {
+		respondProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, project)
+}
+
+// UpdateProject edits a project and, when the body has them, replaces its
+// translations
+func UpdateProject(c *gin.Context) {
+	id, ok := projectID(c)
+	if !ok {
+		return
+	}
+	var input services.ProjectInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	project, err := services.GetGlobalProjectService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProject removes a project
+func DeleteProject(c *gin.Context) {
+	id, ok := projectID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalProjectService().Delete(c.Request.Context(), id); err != nil {
+		respondProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted"})
+}
+
+// UpdateProjectOrder sets the display order of projects
+// ([{"id": 1, "order": 2}, ...])
+func UpdateProjectOrder(c *gin.Context) {
+	var orders []services.ProjectOrder
+	if !bindJSON(c, &orders) {
+		return
+	}
+	if err := services.GetGlobalProjectService().Reorder(c.Request.Context(), orders); err != nil {
+		respondProjectError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Order updated"})
+}
+
+func projectID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondProjectError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrProjectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidProject):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Project operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Project operation failed"})
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkPublishChecklist refuses to publish or schedule an article that
+// fails the publish checklist, answering 422 with every failed item.
+// Articles already live are not checked again.
+func checkPublishChecklist(c *gin.Context, article *models.Article) bool {
+	checklist := services.NewPublishChecklist(config.Get().Publish)
+	if !checklist.Enabled() {
+		return true
+	}
+	failures := checklist.Check(article, getBaseURL(c))
+	if len(failures) == 0 {
+		return true
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":    "The article does not meet the publish checklist",
+		"code":     ErrCodeChecklistFailed,
+		"failures": failures,
+	})
+	return false
+}
+
+// GetPublishChecklist checks a saved article against the publish checklist
+// without publishing it, so editors can see what is left to do
+func GetPublishChecklist(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+	var article models.Article
+	if err := siteDB(c).First(&article, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+	checklist := services.NewPublishChecklist(config.Get().Publish)
+	failures := []services.ChecklistFailure{}
+	if checklist.Enabled() {
+		failures = append(failures, checklist.Check(&article, getBaseURL(c))...)
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": checklist.Enabled(), "passed": len(failures) == 0, "failures": failures})
+}
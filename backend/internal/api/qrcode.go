@@ -0,0 +1,151 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/qrcode"
+	"blog-backend/internal/services"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// qrDefaultSize and qrMaxSize bound the width of a QR code, in pixels
+	qrDefaultSize = 256
+	qrMaxSize     = 1024
+	// maxLogoSize is the largest logo file laid over a QR code
+	maxLogoSize = 5 << 20
+)
+
+// GetArticleQRCode returns a QR code of the article's canonical address,
+// for sharing where links cannot be opened directly, such as WeChat.
+// ?format=svg returns SVG instead of PNG, ?size= is the width in pixels,
+// ?lang= picks the translation linked to and ?logo=true lays the site logo
+// over the center.
+func GetArticleQRCode(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+	var article models.Article
+	if err := siteDB(c).Preload("Translations").First(&article, id).Error; err != nil ||
+		(!isAdminRequest(c) && article.CreatedAt.After(time.Now())) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "png")
+	if format != "png" && format != "svg" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be png or svg"})
+		return
+	}
+	size := qrDefaultSize
+	if value := c.Query("size"); value != "" {
+		if size, err = strconv.Atoi(value); err != nil || size < 64 || size > qrMaxSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("size must be between 64 and %d", qrMaxSize)})
+			return
+		}
+	}
+	language := c.DefaultQuery("lang", article.DefaultLang)
+	if len(language) > 10 || strings.ContainsAny(language, "/?#") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid language"})
+		return
+	}
+	link := fmt.Sprintf("%s/%s/article/%s", getBaseURL(c), language, url.PathEscape(services.ArticleSlug(&article, language)))
+
+	// A logo hides part of the code, which High error correction makes up for
+	var logo []byte
+	var logoType string
+	level := qrcode.Medium
+	if c.Query("logo") == "true" {
+		if logo, logoType, err = readSiteLogo(c); err != nil {
+			logging.FromGin(c).Warn("Failed to read the site logo for a QR code", "error", err)
+		}
+		if logo != nil {
+			level = qrcode.High
+		}
+	}
+	code, err := qrcode.Encode(link, level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	moduleSize := max(size/(code.Size+2*qrcode.QuietZone), 1)
+
+	var body bytes.Buffer
+	contentType := "image/png"
+	if format == "svg" {
+		contentType = "image/svg+xml"
+		href := ""
+		if logo != nil {
+			// Images in an SVG shown as an image cannot load other files
+			href = "data:" + logoType + ";base64," + base64.StdEncoding.EncodeToString(logo)
+		}
+		err = code.SVG(&body, moduleSize, href)
+	} else {
+		var logoImage image.Image
+		if logo != nil {
+			// Formats Go cannot decode, such as SVG logos, are left out
+			logoImage, _, _ = image.Decode(bytes.NewReader(logo))
+		}
+		err = png.Encode(&body, code.Image(moduleSize, logoImage))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render QR code"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
+
+// readSiteLogo returns the site's uploaded logo and its content type, or
+// nil when it has none. Only logos uploaded to the site are read, never
+// remote addresses.
+func readSiteLogo(c *gin.Context) ([]byte, string, error) {
+	var settings models.SiteSettings
+	if err := siteDB(c).Limit(1).Find(&settings).Error; err != nil {
+		return nil, "", err
+	}
+	logoURL := strings.TrimPrefix(settings.LogoURL, "/api")
+	if !strings.HasPrefix(logoURL, "/uploads/") {
+		return nil, "", nil
+	}
+	relative := path.Clean(strings.TrimPrefix(logoURL, "/uploads/"))
+	if relative == "." || strings.HasPrefix(relative, "..") {
+		return nil, "", errors.New("logo is outside the upload directory")
+	}
+	file, err := os.Open(filepath.Join(UploadDir, filepath.FromSlash(relative)))
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxLogoSize))
+	if err != nil {
+		return nil, "", err
+	}
+	contentType := mime.TypeByExtension(path.Ext(relative))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	return data, contentType, nil
+}
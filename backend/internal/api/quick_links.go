@@ -0,0 +1,126 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListQuickLinks returns the active links of the quick links page in
+// display order
+func ListQuickLinks(c *gin.Context) {
+	links, err := services.GetGlobalQuickLinkService().Public(c.Request.Context())
+	if err != nil {
+		respondQuickLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+// TrackQuickLinkClick counts a reader opening a quick link
+func TrackQuickLinkClick(c *gin.Context) {
+	id, ok := quickLinkID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalQuickLinkService().Click(c.Request.Context(), id, c.ClientIP()); err != nil {
+		respondQuickLinkError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListAllQuickLinks returns every quick link, including inactive ones, with
+// its click count
+func ListAllQuickLinks(c *gin.Context) {
+	links, err := services.GetGlobalQuickLinkService().List(c.Request.Context())
+	if err != nil {
+		respondQuickLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+// CreateQuickLink adds a quick link at the end of the page
+func CreateQuickLink(c *gin.Context) {
+	var input services.QuickLinkInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	link, err := services.GetGlobalQuickLinkService().Create(c.Request.Context(), input)
+	if err != nil {
+		respondQuickLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, link)
+}
+
+// UpdateQuickLink edits a quick link
+func UpdateQuickLink(c *gin.Context) {
+	id, ok := quickLinkID(c)
+	if !ok {
+		return
+	}
+	var input services.QuickLinkInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	link, err := services.GetGlobalQuickLinkService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondQuickLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// DeleteQuickLink removes a quick link
+func DeleteQuickLink(c *gin.Context) {
+	id, ok := quickLinkID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalQuickLinkService().Delete(c.Request.Context(), id); err != nil {
+		respondQuickLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Quick link deleted"})
+}
+
+// UpdateQuickLinkOrder sets the display order of quick links
+// ([{"id": 1, "order": 2}, ...])
+func UpdateQuickLinkOrder(c *gin.Context) {
+	var orders []services.QuickLinkOrder
+	if !bindJSON(c, &orders) {
+		return
+	}
+	if err := services.GetGlobalQuickLinkService().Reorder(c.Request.Context(), orders); err != nil {
+		respondQuickLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Order updated"})
+}
+
+func quickLinkID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondQuickLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrQuickLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidQuickLink):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Quick link operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Quick link operation failed"})
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListReadingPositions returns the articles the reader has started but not
+// finished, most recent first, for a "continue reading" list
+func ListReadingPositions(c *gin.Context) {
+	_, limit, ok := pageQuery(c)
+	if !ok {
+		return
+	}
+	positions, err := services.GetGlobalReadingPositionService().InProgress(c.Request.Context(), readerID(c), limit)
+	if err != nil {
+		respondReadingPositionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"positions": positions})
+}
+
+// GetReadingPosition returns where the reader left off in an article
+func GetReadingPosition(c *gin.Context) {
+	articleID, ok := readingPositionArticleID(c)
+	if !ok {
+		return
+	}
+	position, err := services.GetGlobalReadingPositionService().Get(c.Request.Context(), readerID(c), articleID)
+	if err != nil {
+		respondReadingPositionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, position)
+}
+
+// SaveReadingPosition stores where the reader is in an article
+// ({"progress": 0.42, "anchor": "installation", "language": "en"})
+func SaveReadingPosition(c *gin.Context) {
+	articleID, ok := readingPositionArticleID(c)
+	if !ok {
+		return
+	}
+	var input services.ReadingPositionInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	position, err := services.GetGlobalReadingPositionService().Save(c.Request.Context(), readerID(c), articleID, input)
+	if err != nil {
+		respondReadingPositionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, position)
+}
+
+// DeleteReadingPosition forgets where the reader was in an article
+func DeleteReadingPosition(c *gin.Context) {
+	articleID, ok := readingPositionArticleID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalReadingPositionService().Delete(c.Request.Context(), readerID(c), articleID); err != nil {
+		respondReadingPositionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Reading position deleted"})
+}
+
+// readerID is the user ID the frontend sends to behavior tracking, given
+// as ?user_id= or, like for personalized recommendations, the X-Session-ID
+// header
+func readerID(c *gin.Context) string {
+	if userID := c.Query("user_id"); userID != "" {
+		return userID
+	}
+	return c.GetHeader("X-Session-ID")
+}
+
+func readingPositionArticleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("article_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondReadingPositionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrReadingPositionNotFound), errors.Is(err, services.ErrArticleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidReadingPosition):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Reading position operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Reading position operation failed"})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"blog-backend/internal/database"
 	"blog-backend/internal/models"
 	"blog-backend/internal/services"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -90,11 +91,7 @@ type ReadingPathRequest struct {
 // TrackBehavior tracks user reading behavior
 func (rc *RecommendationsController) TrackBehavior(c *gin.Context) {
 	var req TrackUserBehaviorRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -111,6 +108,8 @@ func (rc *RecommendationsController) TrackBehavior(c *gin.Context) {
 		ReferrerType:    req.ReferrerType,
 		Language:        req.Language,
 		Timestamp:       time.Now(),
+		IPAddress:       getClientIP(c),
+		UserAgent:       c.GetHeader("User-Agent"),
 	}
 
 	// Track the interaction
@@ -150,26 +149,23 @@ func (rc *RecommendationsController) GetPersonalizedRecommendations(c *gin.Conte
 	}
 
 	language := c.DefaultQuery("language", "en")
-	limitStr := c.DefaultQuery("limit", "10")
 	excludeReadStr := c.DefaultQuery("exclude_read", "true")
 	includeReasonStr := c.DefaultQuery("include_reason", "true")
-	minConfidenceStr := c.DefaultQuery("min_confidence", "0.1")
 	diversifyStr := c.DefaultQuery("diversify", "true")
 
-	limit, _ := strconv.Atoi(limitStr)
-	if limit <= 0 || limit > 50 {
-		limit = 10
+	query := struct {
+		Limit         int     `form:"limit" binding:"min=1,max=50"`
+		MinConfidence float64 `form:"min_confidence" binding:"min=0,max=1"`
+	}{Limit: 10, MinConfidence: 0.1}
+	if !bindQuery(c, &query) {
+		return
 	}
+	limit, minConfidence := query.Limit, query.MinConfidence
 
 	excludeRead := excludeReadStr == "true"
 	includeReason := includeReasonStr == "true"
 	diversify := diversifyStr == "true"
 
-	minConfidence, _ := strconv.ParseFloat(minConfidenceStr, 64)
-	if minConfidence < 0 || minConfidence > 1 {
-		minConfidence = 0.1
-	}
-
 	// Parse categories if provided
 	var categories []string
 	if categoriesParam := c.Query("categories"); categoriesParam != "" {
@@ -178,6 +174,13 @@ func (rc *RecommendationsController) GetPersonalizedRecommendations(c *gin.Conte
 		categories = []string{categoriesParam}
 	}
 
+	// The device the frontend reports to the behavior tracker, or else the
+	// one the user agent tells
+	deviceType := c.Query("device_type")
+	if !services.ValidDeviceType(deviceType) {
+		deviceType = services.ParseUserAgent(c.Request.UserAgent()).DeviceType
+	}
+
 	// Create options
 	options := services.RecommendationOptions{
 		UserID:        userID,
@@ -188,10 +191,11 @@ func (rc *RecommendationsController) GetPersonalizedRecommendations(c *gin.Conte
 		MinConfidence: minConfidence,
 		Categories:    categories,
 		Diversify:     diversify,
+		DeviceType:    deviceType,
 	}
 
 	// Get recommendations
-	recommendations, err := rc.recommendationEngine.GetPersonalizedRecommendations(options)
+	recommendations, err := rc.recommendationEngine.GetPersonalizedRecommendations(c.Request.Context(), options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get recommendations",
@@ -202,9 +206,17 @@ func (rc *RecommendationsController) GetPersonalizedRecommendations(c *gin.Conte
 
 	// Final validation to ensure no null recommendations are returned
 	validatedRecommendations := rc.validateAPIRecommendations(recommendations)
+	list, err := recommendationList(c, validatedRecommendations)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"recommendations": validatedRecommendations,
+		"recommendations": list,
 		"count":           len(validatedRecommendations),
 		"user_id":         userID,
 		"message":         "Personalized recommendations generated successfully",
@@ -214,11 +226,7 @@ func (rc *RecommendationsController) GetPersonalizedRecommendations(c *gin.Conte
 // GenerateReadingPath generates a personalized reading path
 func (rc *RecommendationsController) GenerateReadingPath(c *gin.Context) {
 	var req ReadingPathRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -241,6 +249,11 @@ func (rc *RecommendationsController) GenerateReadingPath(c *gin.Context) {
 		return
 	}
 
+	path.Articles = withholdRegionalRecommendations(c, path.Articles)
+	for i := range path.Articles {
+		lockMembersOnlyArticle(c, &path.Articles[i].Article)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"reading_path": path,
 		"message":      "Reading path generated successfully",
@@ -294,11 +307,13 @@ func (rc *RecommendationsController) GetReadingPatterns(c *gin.Context) {
 		return
 	}
 
-	daysStr := c.DefaultQuery("days", "30")
-	days, _ := strconv.Atoi(daysStr)
-	if days <= 0 || days > 365 {
-		days = 30
+	query := struct {
+		Days int `form:"days" binding:"min=1,max=365"`
+	}{Days: 30}
+	if !bindQuery(c, &query) {
+		return
 	}
+	days := query.Days
 
 	// Get reading patterns
 	patterns, err := rc.behaviorTracker.GetReadingPatterns(userID, days)
@@ -327,11 +342,13 @@ func (rc *RecommendationsController) GetSimilarUsers(c *gin.Context) {
 		return
 	}
 
-	limitStr := c.DefaultQuery("limit", "10")
-	limit, _ := strconv.Atoi(limitStr)
-	if limit <= 0 || limit > 50 {
-		limit = 10
+	query := struct {
+		Limit int `form:"limit" binding:"min=1,max=50"`
+	}{Limit: 10}
+	if !bindQuery(c, &query) {
+		return
 	}
+	limit := query.Limit
 
 	// Get similar users
 	similarUsers, err := rc.behaviorTracker.GetSimilarUsers(userID, limit)
@@ -360,11 +377,13 @@ func (rc *RecommendationsController) GetRecommendationAnalytics(c *gin.Context)
 		return
 	}
 
-	daysStr := c.DefaultQuery("days", "30")
-	days, _ := strconv.Atoi(daysStr)
-	if days <= 0 || days > 365 {
-		days = 30
+	query := struct {
+		Days int `form:"days" binding:"min=1,max=365"`
+	}{Days: 30}
+	if !bindQuery(c, &query) {
+		return
 	}
+	days := query.Days
 
 	// Get recommendation analytics
 	analytics, err := rc.recommendationEngine.GetRecommendationAnalytics(userID, days)
@@ -430,27 +449,43 @@ type RecentUserResponse struct {
 
 // GetRecentUsers returns a list of recently active users
 func (rc *RecommendationsController) GetRecentUsers(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "20")
-	offsetStr := c.DefaultQuery("offset", "0")
-	daysStr := c.DefaultQuery("days", "7")
-
-	limit, _ := strconv.Atoi(limitStr)
-	if limit <= 0 || limit > 100 {
-		limit = 20
+	query := struct {
+		Limit  int `form:"limit" binding:"min=1,max=100"`
+		Offset int `form:"offset" binding:"min=0"`
+		Days   int `form:"days" binding:"min=1,max=30"`
+	}{Limit: 20, Days: 7}
+	if !bindQuery(c, &query) {
+		return
 	}
+	limit, offset, days := query.Limit, query.Offset, query.Days
 
-	offset, _ := strconv.Atoi(offsetStr)
-	if offset < 0 {
-		offset = 0
+	fields, err := parseListFields(c, services.RecentUser{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
 	}
 
-	days, _ := strconv.Atoi(daysStr)
-	if days <= 0 || days > 30 {
-		days = 7
+	// Get recent users from behavior tracker
+	recentUsers, nextCursor, err := rc.behaviorTracker.GetRecentUsers(limit, offset, days, c.Query("cursor"))
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get recent users",
+			"details": err.Error(),
+		})
+		return
 	}
 
-	// Get recent users from behavior tracker
-	recentUsers, err := rc.behaviorTracker.GetRecentUsers(limit, offset, days)
+	users, err := fields.apply(recentUsers)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get recent users",
@@ -460,12 +495,70 @@ func (rc *RecommendationsController) GetRecentUsers(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"users":   recentUsers,
-		"count":   len(recentUsers),
-		"limit":   limit,
-		"offset":  offset,
-		"days":    days,
-		"message": "Recent users retrieved successfully",
+		"users":       users,
+		"count":       len(recentUsers),
+		"limit":       limit,
+		"offset":      offset,
+		"days":        days,
+		"next_cursor": nextCursor,
+		"message":     "Recent users retrieved successfully",
+	})
+}
+
+// GetUserBehaviors returns a page of a user's tracked behaviors, newest
+// first, without their articles
+func (rc *RecommendationsController) GetUserBehaviors(c *gin.Context) {
+	userID := c.Param("user_id")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "User ID is required",
+		})
+		return
+	}
+
+	slice, ok := bindLimit(c, 50, maxAdminPageSize)
+	if !ok {
+		return
+	}
+	limit := slice.Limit
+	fields, err := parseListFields(c, services.BehaviorSummary{})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	behaviors, nextCursor, err := rc.behaviorTracker.ListBehaviors(userID, c.Query("cursor"), limit)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get user behaviors",
+			"details": err.Error(),
+		})
+		return
+	}
+	list, err := fields.apply(behaviors)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get user behaviors",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"behaviors":   list,
+		"count":       len(behaviors),
+		"limit":       limit,
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -490,7 +583,7 @@ func (rc *RecommendationsController) ForceGenerateRecommendations(c *gin.Context
 		Diversify:     true,
 	}
 
-	recommendations, err := rc.recommendationEngine.GetPersonalizedRecommendations(options)
+	recommendations, err := rc.recommendationEngine.GetPersonalizedRecommendations(c.Request.Context(), options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to generate recommendations",
@@ -630,18 +723,14 @@ func (rc *RecommendationsController) CreateTestBehavior(c *gin.Context) {
 // GetPopularContent returns currently popular content
 func (rc *RecommendationsController) GetPopularContent(c *gin.Context) {
 	language := c.DefaultQuery("language", "en")
-	limitStr := c.DefaultQuery("limit", "10")
-	daysStr := c.DefaultQuery("days", "7")
-
-	limit, _ := strconv.Atoi(limitStr)
-	if limit <= 0 || limit > 50 {
-		limit = 10
-	}
-
-	days, _ := strconv.Atoi(daysStr)
-	if days <= 0 || days > 30 {
-		days = 7
+	query := struct {
+		Limit int `form:"limit" binding:"min=1,max=50"`
+		Days  int `form:"days" binding:"min=1,max=30"`
+	}{Limit: 10, Days: 7}
+	if !bindQuery(c, &query) {
+		return
 	}
+	limit, days := query.Limit, query.Days
 
 	// Get popular content using trending recommendations
 	options := services.RecommendationOptions{
@@ -651,7 +740,7 @@ func (rc *RecommendationsController) GetPopularContent(c *gin.Context) {
 		Diversify: true,
 	}
 
-	recommendations, err := rc.recommendationEngine.GetPersonalizedRecommendations(options)
+	recommendations, err := rc.recommendationEngine.GetPersonalizedRecommendations(c.Request.Context(), options)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get popular content",
@@ -668,8 +757,17 @@ func (rc *RecommendationsController) GetPopularContent(c *gin.Context) {
 		}
 	}
 
+	list, err := recommendationList(c, popularContent)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"popular_content": popularContent,
+		"popular_content": list,
 		"count":           len(popularContent),
 		"days":            days,
 		"message":         "Popular content retrieved successfully",
@@ -716,3 +814,27 @@ func (rc *RecommendationsController) validateAPIRecommendations(recommendations
 	log.Printf("✅ API validation complete: %d valid recommendations out of %d", len(validRecommendations), len(recommendations))
 	return validRecommendations
 }
+
+// recommendationList shapes recommendations for a response: with article
+// summaries unless ?include=article asks for whole articles, and trimmed
+// to the ?fields= selection
+func recommendationList(c *gin.Context, recommendations []services.RecommendationResult) (interface{}, error) {
+	recommendations = withholdRegionalRecommendations(c, recommendations)
+	var items interface{}
+	switch include := c.Query("include"); include {
+	case "":
+		items = services.SummarizeRecommendations(recommendations)
+	case "article":
+		for i := range recommendations {
+			lockMembersOnlyArticle(c, &recommendations[i].Article)
+		}
+		items = recommendations
+	default:
+		return nil, fmt.Errorf("unknown include %q, use article", include)
+	}
+	fields, err := parseListFields(c, items)
+	if err != nil {
+		return nil, err
+	}
+	return fields.apply(items)
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"blog-backend/internal/auth"
+	"blog-backend/internal/config"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Headers telling clients an article was withheld in their region. They
+// are set on the 451 answer for an article and on lists leaving articles
+// out.
+const (
+	withheldReasonHeader  = "X-Withheld-Reason"
+	withheldCountryHeader = "X-Withheld-Country"
+	withheldCountHeader   = "X-Withheld-Count"
+)
+
+// visitorCountryKey memoizes visitorCountry for the request
+const visitorCountryKey = "visitorCountry"
+
+// cdnCountryHeaders are the headers the CDNs of TRUSTED_CDN put the
+// visitor's country in
+var cdnCountryHeaders = map[string]string{
+	"cloudflare": "CF-IPCountry",
+	"cloudfront": "CloudFront-Viewer-Country",
+}
+
+// visitorCountry returns the visitor's country code, or "" when it is not
+// known. The trusted CDN in front of the site knows it already; otherwise
+// it comes from the GeoIP lookup. Country headers are only believed from
+// the configured CDN, which replaces any a client sends.
+func visitorCountry(c *gin.Context) string {
+	if country, ok := c.Get(visitorCountryKey); ok {
+		return country.(string)
+	}
+	var country string
+	if header, ok := cdnCountryHeaders[config.Get().Server.TrustedCDN]; ok {
+		country = c.GetHeader(header)
+	}
+	if country == "" {
+		country = services.GetGeoIPWithCache(getClientIP(c)).Country
+	}
+	country = services.CountryCode(country)
+	c.Set(visitorCountryKey, country)
+	return country
+}
+
+// regionWithheld returns why an article is not served to the visitor, or
+// "" when it is. Admins are served every article.
+func regionWithheld(c *gin.Context, article *models.Article) string {
+	if article.RegionRule == "" || auth.RequestClaims(c) != nil {
+		return ""
+	}
+	// What is served now depends on where the visitor is, so shared caches
+	// must not keep it
+	c.Header("Cache-Control", "private")
+	return services.RegionWithheld(article, visitorCountry(c))
+}
+
+// withholdRegional leaves out the articles not served in the visitor's
+// region and counts them in the response headers
+func withholdRegional(c *gin.Context, articles []models.Article) []models.Article {
+	served := articles[:0]
+	withheld := 0
+	for i := range articles {
+		if regionWithheld(c, &articles[i]) != "" {
+			withheld++
+			continue
+		}
+		served = append(served, articles[i])
+	}
+	countWithheld(c, withheld)
+	return served
+}
+
+// withholdRegionalRecommendations leaves out the recommended articles not
+// served in the visitor's region
+func withholdRegionalRecommendations(c *gin.Context, recommendations []services.RecommendationResult) []services.RecommendationResult {
+	served := recommendations[:0]
+	withheld := 0
+	for i := range recommendations {
+		if regionWithheld(c, &recommendations[i].Article) != "" {
+			withheld++
+			continue
+		}
+		served = append(served, recommendations[i])
+	}
+	countWithheld(c, withheld)
+	return served
+}
+
+// withholdRegionalArchive leaves out of the archive the articles not
+// served in the visitor's region
+func withholdRegionalArchive(c *gin.Context, archive *services.Archive) *services.Archive {
+	withheld := 0
+	served := archive.Without(func(entry *services.ArchiveArticle) bool {
+		if regionWithheld(c, &models.Article{RegionRule: entry.RegionRule, Regions: entry.Regions}) == "" {
+			return false
+		}
+		withheld++
+		return true
+	})
+	countWithheld(c, withheld)
+	return served
+}
+
+// unrestrictedArticles leaves out the articles with a region rule, for
+// content that is cached and read from everywhere
+func unrestrictedArticles(db *gorm.DB) *gorm.DB {
+	return db.Where("COALESCE(articles.region_rule, '') = ''")
+}
+
+// respondRegionWithheld answers 451 if the article is not served in the
+// visitor's region and reports whether it did
+func respondRegionWithheld(c *gin.Context, article *models.Article) bool {
+	reason := regionWithheld(c, article)
+	if reason == "" {
+		return false
+	}
+	setWithheldHeaders(c, reason)
+	c.JSON(http.StatusUnavailableForLegalReasons, gin.H{
+		"error":   "This article is not available in your region",
+		"code":    ErrCodeUnavailableInRegion,
+		"reason":  reason,
+		"country": visitorCountry(c),
+	})
+	return true
+}
+
+// countWithheld tells clients how many articles a list left out. Articles
+// are left out for different reasons, so the reason is just "region".
+func countWithheld(c *gin.Context, withheld int) {
+	if withheld > 0 {
+		setWithheldHeaders(c, "region")
+		c.Header(withheldCountHeader, strconv.Itoa(withheld))
+	}
+}
+
+func setWithheldHeaders(c *gin.Context, reason string) {
+	country := visitorCountry(c)
+	if country == "" {
+		country = "unknown"
+	}
+	c.Header(withheldReasonHeader, reason)
+	c.Header(withheldCountryHeader, country)
+}
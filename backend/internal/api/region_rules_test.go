@@ -0,0 +1,41 @@
+package api
+
+import (
+	"blog-backend/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVisitorCountryTrustsOnlyTheConfiguredCDN(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &config.Get().Server
+	previous := server.TrustedCDN
+	t.Cleanup(func() { server.TrustedCDN = previous })
+
+	country := func(headers map[string]string) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.Header.Set("X-Forwarded-For", "10.0.0.1")
+		for name, value := range headers {
+			c.Request.Header.Set(name, value)
+		}
+		return visitorCountry(c)
+	}
+	both := map[string]string{"CF-IPCountry": "DE", "CloudFront-Viewer-Country": "FR"}
+
+	server.TrustedCDN = ""
+	if got := country(both); got != "" {
+		t.Errorf("without a trusted CDN country = %q, want unknown", got)
+	}
+	server.TrustedCDN = "cloudflare"
+	if got := country(both); got != "DE" {
+		t.Errorf("behind Cloudflare country = %q, want DE", got)
+	}
+	server.TrustedCDN = "cloudfront"
+	if got := country(both); got != "FR" {
+		t.Errorf("behind CloudFront country = %q, want FR", got)
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/events"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/metrics"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCachedResponse is the largest body kept in the response cache
+const maxCachedResponse = 1 << 20
+
+// cachedResponseHeaders are the headers set by handlers that are stored
+// with a cached response. Middleware sets the others on every request.
+var cachedResponseHeaders = []string{"Content-Type", "Content-Language", "Cache-Control", "Vary", "ETag", "Last-Modified"}
+
+// Invalidation topics of the cached public endpoints
+var (
+	articleResponseTopics = []string{
+		cache.TopicArticles, cache.TopicCategories, cache.TopicAuthors, cache.TopicSettings,
+		events.Topic(hooks.ArticlePublished), events.Topic(hooks.ArticleUpdated), events.Topic(hooks.SettingsChanged),
+	}
+	categoryResponseTopics = []string{cache.TopicCategories, cache.TopicArticles, cache.TopicSettings, events.Topic(hooks.SettingsChanged)}
+	settingsResponseTopics = []string{cache.TopicSettings, events.Topic(hooks.SettingsChanged)}
+)
+
+// Responses served by the response cache, by cache and result
+var responseCacheCounter = metrics.NewCounterVec("cache", "result")
+
+func init() {
+	metrics.Register(func() ([]metrics.Family, error) {
+		return []metrics.Family{{
+			Name:    "kuno_http_cache_requests_total",
+			Help:    "Requests to cached public endpoints by cache and result",
+			Type:    metrics.Counter,
+			Samples: responseCacheCounter.Samples(),
+		}}, nil
+	})
+}
+
+// sharedResponse is a response as stored in the response cache
+type sharedResponse struct {
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body"`
+}
+
+// CacheResponses serves anonymous GET requests from the shared cache for
+// HTTP_CACHE_TTL and stores the successful responses of the handlers. The
+// entries of the cache name are dropped, on every instance, when one of
+// topics is published, so the handlers' data is never older than the
+// writes that changed it. Requests with credentials always reach the
+// handlers, since what they see depends on who sends them.
+func CacheResponses(name string, topics ...string) gin.HandlerFunc {
+	return cacheResponses(name, config.Get().Cache.HTTPTTL.Std(), topics...)
+}
+
+func cacheResponses(name string, ttl time.Duration, topics ...string) gin.HandlerFunc {
+	if ttl <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	responses := cache.New("http_"+name, ttl)
+	// generation changes on every invalidation, so a response rendered
+	// from data that changed while it was built is not stored
+	var generation atomic.Int64
+	for _, topic := range topics {
+		cache.Subscribe(topic, func() {
+			generation.Add(1)
+			responses.Clear()
+		})
+	}
+
+	return func(c *gin.Context) {
+		if (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) || hasCredentials(c) {
+			c.Next()
+			return
+		}
+		key := responseCacheKey(c)
+		var cached sharedResponse
+		if responses.Get(key, &cached) {
+			responseCacheCounter.Add(1, name, "hit")
+			writeCachedResponse(c, &cached)
+			c.Abort()
+			return
+		}
+		responseCacheCounter.Add(1, name, "miss")
+
+		started := generation.Load()
+		original := c.Writer
+		buffered := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = buffered
+		c.Header("X-Cache", "MISS")
+		c.Next()
+		c.Writer = original
+
+		if buffered.status == http.StatusOK && c.Request.Method == http.MethodGet && buffered.body.Len() <= maxCachedResponse &&
+			generation.Load() == started && cacheable(original.Header()) {
+			cached = sharedResponse{Headers: make(map[string]string), Body: buffered.body.Bytes()}
+			for _, header := range cachedResponseHeaders {
+				if value := original.Header().Get(header); value != "" {
+					cached.Headers[header] = value
+				}
+			}
+			responses.Set(key, cached)
+		}
+		original.WriteHeader(buffered.status)
+		original.Write(buffered.body.Bytes())
+	}
+}
+
+// hasCredentials reports whether a request is made as an admin, a member
+// or an API client
+func hasCredentials(c *gin.Context) bool {
+	return c.GetHeader("Authorization") != "" || c.GetHeader(MemberTokenHeader) != "" || c.GetHeader(APIKeyHeader) != ""
+}
+
+// responseCacheKey identifies a response by the site resolved for the
+// request, path and query, with the query parameters sorted so their order
+// does not matter. Keying on the site rather than the Host header keeps the
+// hosts of one site on one entry, and unknown hosts from adding entries.
+func responseCacheKey(c *gin.Context) string {
+	site := strconv.FormatUint(uint64(currentSiteID(c)), 10)
+	sum := sha256.Sum256([]byte(site + "|" + c.Request.URL.Path + "?" + c.Request.URL.Query().Encode()))
+	return hex.EncodeToString(sum[:16])
+}
+
+// cacheable reports whether a response may be shared with other readers
+func cacheable(header http.Header) bool {
+	if header.Get("Set-Cookie") != "" {
+		return false
+	}
+	control := strings.ToLower(header.Get("Cache-Control"))
+	return !strings.Contains(control, "private") && !strings.Contains(control, "no-store")
+}
+
+func writeCachedResponse(c *gin.Context, cached *sharedResponse) {
+	for header, value := range cached.Headers {
+		c.Header(header, value)
+	}
+	c.Header("X-Cache", "HIT")
+	if etag := cached.Headers["ETag"]; etag != "" && c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Status(http.StatusOK)
+	if c.Request.Method == http.MethodGet {
+		c.Writer.Write(cached.Body)
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCacheResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	calls := 0
+	r.GET("/list", cacheResponses("test_list", time.Minute, "test_list_topic"), func(c *gin.Context) {
+		calls++
+		if c.Query("private") != "" {
+			c.Header("Cache-Control", "private, no-store")
+		}
+		if c.Query("fail") != "" {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"calls": calls, "page": c.Query("page")})
+	})
+	get := func(target string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			if header[i] == "Host" {
+				req.Host = header[i+1]
+				continue
+			}
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("/list?page=1&lang=en")
+	second := get("/list?lang=en&page=1")
+	if calls != 1 || second.Header().Get("X-Cache") != "HIT" || second.Body.String() != first.Body.String() {
+		t.Fatalf("second request: %d handler calls, X-Cache %q, body %s", calls, second.Header().Get("X-Cache"), second.Body.String())
+	}
+	if second.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Errorf("cached Content-Type = %q", second.Header().Get("Content-Type"))
+	}
+	get("/list?page=2&lang=en")
+	if calls != 2 {
+		t.Errorf("another query was served from the cache")
+	}
+
+	// Admins, members and API clients always reach the handler
+	for _, header := range []string{"Authorization", MemberTokenHeader, APIKeyHeader} {
+		if w := get("/list?page=1&lang=en", header, "secret"); w.Header().Get("X-Cache") == "HIT" {
+			t.Errorf("request with %s served from the cache", header)
+		}
+	}
+	calls = 10
+
+	// Errors and private responses are not stored
+	get("/list?fail=1")
+	get("/list?private=1")
+	before := calls
+	get("/list?fail=1")
+	get("/list?private=1")
+	if calls != before+2 {
+		t.Errorf("errors or private responses were cached")
+	}
+
+	cache.Publish("test_list_topic")
+	if w := get("/list?page=1&lang=en"); w.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("after invalidation X-Cache = %q, want MISS", w.Header().Get("X-Cache"))
+	}
+	if w := get("/list?page=1&lang=en"); w.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("X-Cache = %q, want the refreshed response cached", w.Header().Get("X-Cache"))
+	}
+
+	// Entries are kept per site, whichever of its hosts was asked
+	selectSite := func(c *gin.Context) {
+		if id, err := strconv.ParseUint(c.GetHeader("X-Test-Site"), 10, 32); err == nil {
+			c.Set("site", &models.Site{ID: uint(id)})
+		}
+	}
+	r.GET("/sites", selectSite, cacheResponses("test_sites", time.Minute), func(c *gin.Context) {
+		c.String(http.StatusOK, "site %d", currentSiteID(c))
+	})
+	get("/sites", "Host", "a.example", "X-Test-Site", "2")
+	if w := get("/sites", "Host", "www.a.example", "X-Test-Site", "2"); w.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("another host of the site: X-Cache = %q, want HIT", w.Header().Get("X-Cache"))
+	}
+	if w := get("/sites", "Host", "a.example", "X-Test-Site", "3"); w.Header().Get("X-Cache") != "MISS" || w.Body.String() != "site 3" {
+		t.Errorf("another site: X-Cache = %q, body %q", w.Header().Get("X-Cache"), w.Body.String())
+	}
+
+	// Without a TTL the middleware does nothing
+	r.GET("/off", cacheResponses("test_off", 0), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	if w := get("/off"); w.Header().Get("X-Cache") != "" {
+		t.Errorf("disabled cache set X-Cache %q", w.Header().Get("X-Cache"))
+	}
+}
@@ -2,35 +2,76 @@ package api
 
 import (
 	"blog-backend/internal/auth"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
 	"fmt"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"log"
 	"net/http"
+	"sync/atomic"
 )
 
 func SetupRoutes() *gin.Engine {
-	r := gin.Default()
-
-	// Enhanced request logging middleware
-	r.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		return fmt.Sprintf("🌐 [%s] %s %s %d %s %s %s\n",
-			param.TimeStamp.Format("15:04:05"),
-			param.Method,
-			param.Path,
-			param.StatusCode,
-			param.Latency,
-			param.ClientIP,
-			param.ErrorMessage,
-		)
-	}))
+	r := gin.New()
+
+	registerJobHandlers(services.GetGlobalJobQueue())
+	registerSchedules(services.GetGlobalScheduler())
+
+	// Webhook plugins subscribe to the content hooks
+	services.GetGlobalPluginService()
+
+	// New posts are announced to newsletter subscribers
+	services.GetGlobalNewsletterService()
+
+	// ... and to Telegram, Discord and Slack channels
+	services.GetGlobalNotifierService()
+
+	// ... and to fediverse followers
+	services.GetGlobalActivityPubService()
+
+	// WebSub hubs are told when the feeds change
+	services.GetGlobalWebSubService()
+
+	// Baidu, Sogou and 360 are sent the links of new articles
+	services.GetGlobalSearchPushService()
+
+	// ... and the pages articles link to are sent Webmentions
+	services.GetGlobalWebmentionService()
+
+	// Admins are emailed about comments waiting for moderation
+	services.GetGlobalCommentNotifier()
+
+	// Updated articles can have their translations refreshed by the AI provider
+	services.GetGlobalTranslationRefresher()
+
+	// Mermaid diagrams of saved articles are rendered for feeds and exports
+	services.GetGlobalDiagramRenderer()
+
+	// Requests being served, which startup warm-up makes way for
+	var inFlight atomic.Int64
+	r.Use(countInFlight(&inFlight))
+
+	// Request IDs and structured access logs
+	r.Use(logging.RequestID())
+	r.Use(logging.AccessLog())
 
-	// Recovery middleware with enhanced logging
+	// JSON error responses in one shape, with codes from the error catalog
+	r.Use(ErrorEnvelope())
+
+	// Slow request and query recording, when thresholds are configured
+	r.Use(ProfilingMiddleware())
+
+	// Recovery middleware with structured logging
 	r.Use(gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
-		log.Printf("❌ PANIC RECOVERED: %v", recovered)
-		c.AbortWithStatus(http.StatusInternalServerError)
+		logging.FromGin(c).Error("panic recovered", "panic", fmt.Sprint(recovered), "path", c.Request.URL.Path)
+		respondError(c, http.StatusInternalServerError, ErrCodeInternal, "Internal server error")
 	}))
 
+	// Liveness and readiness probes, ahead of the site and maintenance
+	// middleware so they answer for any host and in maintenance mode
+	r.GET("/healthz", Healthz)
+	r.GET("/readyz", Readyz)
+
 	// Increase maximum multipart memory for large file uploads
 	r.MaxMultipartMemory = 100 << 20 // 100 MB
 
@@ -38,289 +79,885 @@ func SetupRoutes() *gin.Engine {
 	// Allow all origins for embed functionality
 	config.AllowAllOrigins = true
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "Cache-Control"}
-	config.ExposeHeaders = []string{"Content-Length"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "Cache-Control", "Range", logging.RequestIDHeader, APIKeyHeader, MemberTokenHeader}
+	config.ExposeHeaders = []string{"Content-Length", logging.RequestIDHeader, APIVersionHeader, "Deprecation", "Sunset", "Link",
+		"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After", "Accept-Ranges", "Content-Range", "ETag"}
 	config.AllowCredentials = true
 	config.MaxAge = 12 * 3600
 	r.Use(cors.New(config))
 
+	// Select the blog by host name in multi-site mode
+	r.Use(SiteMiddleware())
+
+	// Read-only maintenance mode
+	r.Use(MaintenanceMiddleware())
+
+	// Prometheus metrics, when METRICS_TOKEN is set
+	r.GET("/metrics", ServeMetrics)
+
 	// Root level LLMs.txt endpoint for AI crawlers
 	r.GET("/llms.txt", ServeLLMsTxt)
 
-	api := r.Group("/api")
+	// WebFinger lookup of the fediverse account
+	r.GET("/.well-known/webfinger", RequireFeature(services.FeatureActivityPub), WebFinger)
+
+	// Search engine verification files, whose names depend on the codes
+	r.NoRoute(ServeSiteVerificationFile)
+
+	// The API is served at /api/v1 and at the unversioned /api, which stays
+	// v1 for existing clients, stored upload URLs and feed subscriptions.
+	// Third-party apps may read either with an API key.
+	// The routes of the current version are recorded for the OpenAPI
+	// description
+	apiRoutes = newAPIRouteAccess(r, "/api/"+APIVersion1)
+	registerAPIRoutes(r.Group("/api/"+APIVersion1, apiVersion(APIVersion1), APIKeyMiddleware()), apiRoutes)
+	registerAPIRoutes(r.Group("/api", apiVersion(APIVersion1), APIKeyMiddleware()), nil)
+
+	// Caches filled in the background once the server starts
+	registerWarmupTasks(services.GetGlobalWarmup(), r, &inFlight)
+
+	return r
+}
+
+// registerAPIRoutes registers the routes of version 1 of the API. Routes
+// replaced in a later version stay here, marked Deprecated, until their
+// sunset. access, when set, records who may call each route.
+func registerAPIRoutes(api *gin.RouterGroup, access *apiRouteAccess) {
+	// Public routes
+	api.POST("/login", Login)
+	api.GET("/recovery-status", GetRecoveryStatus)
+	api.POST("/passkeys/login/begin", BeginPasskeyLogin)
+	api.POST("/passkeys/login/finish", FinishPasskeyLogin)
+	api.GET("/sessions/revoke", RevokeSessionByLink)
+	// Admin notification badge stream, authorized by a single-use ticket
+	api.GET("/notifications/stream", StreamNotifications)
+
+	// Setup routes - public access for initial setup
+	setup := api.Group("/setup")
+	{
+		setup.GET("/status", GetSetupStatus)
+		setup.POST("/initialize", InitializeSetup)
+	}
+
+	// Public read-only routes
+	articles := api.Group("/articles")
 	{
-		// Public routes
-		api.POST("/login", Login)
-		api.GET("/recovery-status", GetRecoveryStatus)
+		articles.GET("", CacheResponses("articles", articleResponseTopics...), GetArticles)
+		articles.GET("/search", SearchArticles)
+		articles.GET("/:id", GetArticle)
+		articles.GET("/:id/qrcode", GetArticleQRCode)
+		articles.GET("/:id/share", GetArticleShareCard)
+		articles.GET("/:id/views", GetArticleViews)
+		articles.GET("/:id/html", GetArticleHTML)
+		articles.GET("/:id/export/pdf", ExportArticlePDF)
+	}
 
-		// Setup routes - public access for initial setup
-		setup := api.Group("/setup")
-		{
-			setup.GET("/status", GetSetupStatus)
-			setup.POST("/initialize", InitializeSetup)
-		}
+	// Articles grouped by year and month for the archive page - public access
+	api.GET("/archive", CacheResponses("archive", articleResponseTopics...), GetArchive)
 
-		// Public read-only routes
-		articles := api.Group("/articles")
-		{
-			articles.GET("", GetArticles)
-			articles.GET("/search", SearchArticles)
-			articles.GET("/:id", GetArticle)
-		}
+	// Semantic search endpoints - public access
+	embeddingController := NewEmbeddingController()
+	search := api.Group("/search")
+	{
+		search.POST("/semantic", AIEndpoint("search.semantic"), embeddingController.SemanticSearch)
+		search.POST("/hybrid", AIEndpoint("search.hybrid"), embeddingController.HybridSearch)
+		search.GET("/similar/:id", AIEndpoint("search.similar"), embeddingController.GetSimilarArticles)
+	}
 
-		// Semantic search endpoints - public access
-		embeddingController := NewEmbeddingController()
-		search := api.Group("/search")
-		{
-			search.POST("/semantic", embeddingController.SemanticSearch)
-			search.POST("/hybrid", embeddingController.HybridSearch)
-			search.GET("/similar/:id", embeddingController.GetSimilarArticles)
-		}
+	// RAG service status - public access
+	api.GET("/rag/status", embeddingController.GetRAGServiceStatus)
 
-		// RAG service status - public access
-		api.GET("/rag/status", embeddingController.GetRAGServiceStatus)
+	// Personalized recommendations - public access
+	recommendationsController := NewRecommendationsController()
+	recommendations := api.Group("/recommendations")
+	{
+		recommendations.POST("/track", recommendationsController.TrackBehavior)
+		recommendations.GET("/personalized", AIEndpoint("recommendations.personalized"), recommendationsController.GetPersonalizedRecommendations)
+		recommendations.POST("/reading-path", AIEndpoint("recommendations.reading_path"), recommendationsController.GenerateReadingPath)
+		recommendations.GET("/popular", recommendationsController.GetPopularContent)
+	}
 
-		// Personalized recommendations - public access
-		recommendationsController := NewRecommendationsController()
-		recommendations := api.Group("/recommendations")
-		{
-			recommendations.POST("/track", recommendationsController.TrackBehavior)
-			recommendations.GET("/personalized", recommendationsController.GetPersonalizedRecommendations)
-			recommendations.POST("/reading-path", recommendationsController.GenerateReadingPath)
-			recommendations.GET("/popular", recommendationsController.GetPopularContent)
-		}
+	// Reading positions for resuming articles - keyed by the tracked user ID
+	readingPositions := api.Group("/reading-positions")
+	{
+		readingPositions.GET("", ListReadingPositions)
+		readingPositions.GET("/:article_id", GetReadingPosition)
+		readingPositions.PUT("/:article_id", SaveReadingPosition)
+		readingPositions.DELETE("/:article_id", DeleteReadingPosition)
+	}
 
-		categories := api.Group("/categories")
-		{
-			categories.GET("", GetCategories)
-			categories.GET("/:id", GetCategory)
-		}
+	categories := api.Group("/categories", CacheResponses("categories", categoryResponseTopics...))
+	{
+		categories.GET("", GetCategories)
+		categories.GET("/tree", GetCategoryTree)
+		categories.GET("/:id", GetCategory)
+		categories.GET("/:id/breadcrumb", GetCategoryBreadcrumb)
+	}
+
+	settings := api.Group("/settings", CacheResponses("settings", settingsResponseTopics...))
+	{
+		settings.GET("", GetSettings)
+		settings.GET("/branding", GetBranding)
+	}
+
+	// Web app manifest with the generated favicon set
+	api.GET("/manifest.webmanifest", GetWebManifest)
+
+	// Site statistics for footers and widgets - public when opted into
+	api.GET("/stats", GetSiteStats)
+
+	// Writing statistics of the published articles - public access
+	api.GET("/stats/writing", GetSiteWritingStats)
+
+	// Language configuration - public access
+	api.GET("/languages", GetLanguageConfig)
+	api.GET("/languages/negotiate", NegotiateLanguage)
 
-		settings := api.Group("/settings")
+	// Error codes of the API - public access
+	api.GET("/errors", GetErrorCatalog)
+
+	// RSS feeds - public access
+	rss := api.Group("/rss")
+	{
+		rss.GET("", GetRSSFeed)
+		rss.GET("/category/:id", GetRSSFeedByCategory)
+		rss.GET("/moments", GetMomentsFeed)
+		rss.GET("/author/:id", GetRSSFeedByAuthor)
+	}
+
+	// Media serving - public access
+	api.GET("/uploads/*filepath", ServeMedia)
+	api.HEAD("/uploads/*filepath", ServeMedia)
+
+	// Social media links - public access
+	api.GET("/social-media", GetSocialMediaList)
+
+	// Moments, short status updates - public access to public and unlisted ones
+	api.GET("/moments", ListMoments)
+	api.GET("/moments/:id", GetMoment)
+
+	// Friend links for the blogroll page - public access
+	api.GET("/friend-links", GetBlogroll)
+
+	// Projects for the portfolio page - public access to active ones
+	api.GET("/projects", ListProjects)
+	api.GET("/projects/:id", GetProject)
+
+	// Standalone pages such as About or Now - public access to published ones
+	api.GET("/pages", ListPages)
+	api.GET("/pages/:slug", GetPage)
+
+	// Images and fonts of installed themes - public access
+	api.GET("/themes/:name/assets/*path", ServeThemeAsset)
+
+	// Site-wide notices currently shown - public access
+	api.GET("/announcements", ListCurrentAnnouncements)
+
+	// Similar topics and representative articles of categories and tags - public access
+	api.GET("/topics/:kind/:key/similar", GetSimilarTopics)
+	api.GET("/topics/:kind/:key/articles", GetTopicArticles)
+
+	// Member sign-in for members-only articles - public access
+	api.POST("/members/login", MemberLogin)
+	api.POST("/members/logout", MemberLogout)
+	api.GET("/members/me", GetCurrentMember)
+
+	// Author profiles and their articles - public access to authors with a profile
+	api.GET("/authors/:id", GetAuthor)
+	api.GET("/authors/:id/articles", ListAuthorArticles)
+	api.GET("/authors/:id/writing-stats", GetAuthorWritingStats)
+
+	// Homepage sections and their order - public access
+	api.GET("/homepage-layout", GetHomepageLayout)
+
+	// Search engine verification meta tags for the page head
+	api.GET("/site-verification", GetSiteVerification)
+
+	// The prepared <head> of a frontend route - public access
+	api.GET("/head", GetPageHead)
+
+	// Newsletter sign-up with double opt-in - public access
+	newsletter := api.Group("/newsletter")
+	{
+		newsletter.POST("/subscribe", Subscribe)
+		newsletter.GET("/confirm", ConfirmSubscription)
+		newsletter.GET("/unsubscribe", Unsubscribe)
+		newsletter.POST("/unsubscribe", Unsubscribe)
+	}
+
+	// Contact form - public access, rate limited per address
+	api.POST("/contact", SubmitContactMessage)
+
+	// Guestbook - public access to approved entries, signing is rate limited
+	api.GET("/guestbook", ListGuestbook)
+	api.POST("/guestbook", SignGuestbook)
+	api.GET("/comments/unsubscribe", UnsubscribeReplies)
+	api.POST("/comments/unsubscribe", UnsubscribeReplies)
+
+	// Donation methods for the sponsor page and under articles
+	api.GET("/donations", ListDonationMethods)
+	api.POST("/donations/:id/click", TrackDonationClick)
+
+	// Links of the quick links ("link in bio") page - public access to active ones
+	api.GET("/quick-links", ListQuickLinks)
+	api.POST("/quick-links/:id/click", TrackQuickLinkClick)
+
+	// Bounce and complaint webhooks of the mail provider - token in the URL
+	api.POST("/mail/webhooks/:provider", MailWebhook)
+
+	// ActivityPub actor, outbox and inbox - public access, signed inbox posts
+	activitypub := api.Group("/activitypub", RequireFeature(services.FeatureActivityPub))
+	{
+		activitypub.GET("/actor", GetActivityPubActor)
+		activitypub.GET("/outbox", GetActivityPubOutbox)
+		activitypub.GET("/followers", GetActivityPubFollowers)
+		activitypub.GET("/objects/:id", GetActivityPubObject)
+		activitypub.POST("/inbox", PostActivityPubInbox)
+		activitypub.GET("/replies", ListArticleFediverseReplies)
+	}
+
+	// Webmentions of articles from other sites - public access
+	webmention := api.Group("/webmention", RequireFeature(services.FeatureWebmention))
+	{
+		webmention.POST("", ReceiveWebmention)
+		webmention.GET("/mentions", ListArticleWebmentions)
+	}
+
+	// Runtime URLs and enabled features for the frontend - public access
+	api.GET("/config", GetBootstrapConfig)
+
+	// System information - public access
+	api.GET("/system/info", GetSystemInfo)
+
+	// Previews of links in published articles
+	api.GET("/link-preview", GetLinkPreview)
+
+	// LLMs.txt - public access for AI crawlers
+	api.GET("/llms.txt", ServeLLMsTxt)
+
+	access.mark(routeAccessPublic)
+
+	// Protected routes - require authentication
+	protected := api.Group("/")
+	protected.Use(auth.AuthMiddleware())
+	{
+		// User routes
+		protected.GET("/me", GetCurrentUser)
+		protected.PUT("/change-password", ChangePassword)
+
+		// Author profile of the current user, which articles credit
+		protected.GET("/profile", GetMyAuthorProfile)
+		protected.PUT("/profile", UpdateMyAuthorProfile)
+
+		// Login sessions for the current user
+		protected.GET("/sessions", ListSessions)
+		protected.DELETE("/sessions/:id", RevokeSession)
+
+		// Passkey management for the current user
+		passkeys := protected.Group("/passkeys")
 		{
-			settings.GET("", GetSettings)
+			passkeys.GET("", ListPasskeys)
+			passkeys.POST("/register/begin", BeginPasskeyRegistration)
+			passkeys.POST("/register/finish", FinishPasskeyRegistration)
+			passkeys.PUT("/requirement", SetPasskeyRequirement)
+			passkeys.PUT("/:id", RenamePasskey)
+			passkeys.DELETE("/:id", RevokePasskey)
 		}
 
-		// Language configuration - public access
-		api.GET("/languages", GetLanguageConfig)
+		access.mark(routeAccessUser)
 
-		// RSS feeds - public access
-		rss := api.Group("/rss")
+		// Admin routes - require admin role
+		admin := protected.Group("/")
+		admin.Use(auth.AdminMiddleware())
 		{
-			rss.GET("", GetRSSFeed)
-			rss.GET("/category/:id", GetRSSFeedByCategory)
-		}
+			// Article management
+			adminArticles := admin.Group("/articles")
+			{
+				adminArticles.POST("", CreateArticle)
+				adminArticles.PUT("/:id", UpdateArticle)
+				adminArticles.DELETE("/:id", DeleteArticle)
+				// Who has an article open, so editors are warned of each other
+				adminArticles.GET("/:id/presence", GetArticlePresence)
+				adminArticles.POST("/:id/presence", UpdateArticlePresence)
+				adminArticles.DELETE("/:id/presence", LeaveArticlePresence)
+				adminArticles.GET("/:id/checklist", GetPublishChecklist)
+				adminArticles.POST("/:id/preview-link", CreateArticlePreviewLink)
+				adminArticles.PUT("/pinned/order", UpdatePinnedOrder)
+				adminArticles.POST("/import", ImportMarkdown)
+				adminArticles.POST("/parse-wordpress", ParseWordPress)
+				adminArticles.POST("/import-wordpress", ImportWordPress)
+			}
 
-		// Media serving - public access
-		api.Static("/uploads", UploadDir)
+			// Category management
+			adminCategories := admin.Group("/categories")
+			{
+				adminCategories.POST("", CreateCategory)
+				adminCategories.PUT("/:id", UpdateCategory)
+				adminCategories.DELETE("/:id", DeleteCategory)
+				adminCategories.POST("/:id/merge-into/:target", MergeCategory)
+			}
+
+			// Settings management
+			adminSettings := admin.Group("/settings")
+			{
+				adminSettings.PUT("", UpdateSettings)
+				adminSettings.GET("/custom-code/audits", ListCustomCodeAudits)
+				adminSettings.POST("/upload-logo", UploadLogo)
+				adminSettings.POST("/upload-logo-dark", UploadDarkLogo)
+				adminSettings.POST("/upload-favicon", UploadFavicon)
+				adminSettings.POST("/generate-favicons", GenerateFavicons)
+				adminSettings.POST("/upload-background", UploadBackgroundImage)
+				adminSettings.DELETE("/background", RemoveBackgroundImage)
+			}
 
-		// Social media links - public access
-		api.GET("/social-media", GetSocialMediaList)
+			// Media management
+			adminMedia := admin.Group("/media")
+			{
+				adminMedia.POST("/upload", UploadMedia)
+				adminMedia.POST("/upload/batch", UploadMediaBatch)
+				adminMedia.POST("/direct-uploads", CreateDirectUpload)
+				adminMedia.POST("/direct-uploads/:token/finalize", FinalizeDirectUpload)
+				adminMedia.GET("", GetMediaList)
+				adminMedia.GET("/metadata-stats", GetMediaMetadataStats)
+				adminMedia.GET("/upload-policy", GetUploadPolicy)
+				adminMedia.PUT("/upload-policy", UpdateUploadPolicy)
+				adminMedia.GET("/watermark", GetWatermarkPolicy)
+				adminMedia.PUT("/watermark", UpdateWatermarkPolicy)
+				adminMedia.GET("/:id", GetMedia)
+				adminMedia.POST("/:id/signed-url", SignMediaURL)
+				adminMedia.GET("/:id/original", GetMediaOriginal)
+				adminMedia.PUT("/:id", UpdateMedia)
+				adminMedia.DELETE("/:id", DeleteMedia)
+				adminMedia.DELETE("/bulk", BulkDeleteMedia)
+			}
 
-		// System information - public access
-		api.GET("/system/info", GetSystemInfo)
+			// Analytics
+			admin.GET("/analytics", GetAnalytics)
+			admin.GET("/analytics/articles/:id", GetArticleAnalytics)
+			admin.POST("/analytics/articles/:id/share-counts", RefreshArticleShareCounts)
+			admin.GET("/analytics/geographic", GetGeographicAnalytics)
+			admin.GET("/analytics/browsers", GetBrowserAnalytics)
+			admin.GET("/analytics/trends", GetTrendAnalytics)
+			admin.POST("/analytics/recount-views", RecountArticleViews)
+
+			// Command palette search across everything the admin manages
+			admin.GET("/admin/search", AdminSearch)
+
+			// Search index statistics and rebuilds without downtime
+			admin.GET("/admin/search/index", GetSearchIndexStatus)
+			admin.POST("/admin/search/rebuild", RebuildSearchIndex)
+
+			// Link previews for the editor
+			admin.POST("/link-preview", PreviewLink)
+
+			// Translation coverage
+			admin.GET("/translations/status", GetTranslationStatus)
+			admin.POST("/translations/review", ReviewTranslation)
+			admin.GET("/translations/stale", GetStaleTranslations)
+			admin.POST("/translations/refresh", RefreshTranslations)
+			admin.GET("/translations/export", ExportTranslations)
+			admin.POST("/translations/import", ImportTranslations)
+			admin.POST("/translations/memory/lookup", LookupTranslationMemory)
+			admin.POST("/translations/memory", RememberTranslations)
+			admin.GET("/translations/memory", ListTranslationMemory)
+			admin.DELETE("/translations/memory/:id", DeleteTranslationMemory)
+			admin.DELETE("/translations/memory", ClearTranslationMemory)
+
+			// Export functions
+			admin.GET("/export/article/:id", ExportArticle)
+			admin.GET("/export/articles", ExportArticles)
+			admin.GET("/export/all", ExportAllArticles)
+			admin.GET("/export/site", ExportSite)
+			admin.POST("/import/site", ImportSite)
+			admin.POST("/import/article", ImportArticle)
+
+			// Social media management
+			adminSocialMedia := admin.Group("/social-media")
+			{
+				adminSocialMedia.GET("/all", GetAllSocialMedia)
+				adminSocialMedia.GET("/:id", GetSocialMedia)
+				adminSocialMedia.POST("", CreateSocialMedia)
+				adminSocialMedia.PUT("/:id", UpdateSocialMedia)
+				adminSocialMedia.DELETE("/:id", DeleteSocialMedia)
+				adminSocialMedia.PUT("/order", UpdateSocialMediaOrder)
+			}
 
-		// LLMs.txt - public access for AI crawlers
-		api.GET("/llms.txt", ServeLLMsTxt)
+			// Moments management
+			adminMoments := admin.Group("/moments")
+			{
+				adminMoments.GET("/all", ListAllMoments)
+				adminMoments.POST("", CreateMoment)
+				adminMoments.PUT("/:id", UpdateMoment)
+				adminMoments.DELETE("/:id", DeleteMoment)
+			}
 
-		// Protected routes - require authentication
-		protected := api.Group("/")
-		protected.Use(auth.AuthMiddleware())
-		{
-			// User routes
-			protected.GET("/me", GetCurrentUser)
-			protected.PUT("/change-password", ChangePassword)
-
-			// Admin routes - require admin role
-			admin := protected.Group("/")
-			admin.Use(auth.AdminMiddleware())
-			{
-				// Article management
-				adminArticles := admin.Group("/articles")
-				{
-					adminArticles.POST("", CreateArticle)
-					adminArticles.PUT("/:id", UpdateArticle)
-					adminArticles.DELETE("/:id", DeleteArticle)
-					adminArticles.POST("/import", ImportMarkdown)
-					adminArticles.POST("/parse-wordpress", ParseWordPress)
-					adminArticles.POST("/import-wordpress", ImportWordPress)
-				}
-
-				// Category management
-				adminCategories := admin.Group("/categories")
-				{
-					adminCategories.POST("", CreateCategory)
-					adminCategories.PUT("/:id", UpdateCategory)
-					adminCategories.DELETE("/:id", DeleteCategory)
-				}
-
-				// Settings management
-				adminSettings := admin.Group("/settings")
-				{
-					adminSettings.PUT("", UpdateSettings)
-					adminSettings.POST("/upload-logo", UploadLogo)
-					adminSettings.POST("/upload-favicon", UploadFavicon)
-					adminSettings.POST("/upload-background", UploadBackgroundImage)
-					adminSettings.DELETE("/background", RemoveBackgroundImage)
-				}
-
-				// Media management
-				adminMedia := admin.Group("/media")
-				{
-					adminMedia.POST("/upload", UploadMedia)
-					adminMedia.POST("/upload/batch", UploadMediaBatch)
-					adminMedia.GET("", GetMediaList)
-					adminMedia.GET("/:id", GetMedia)
-					adminMedia.PUT("/:id", UpdateMedia)
-					adminMedia.DELETE("/:id", DeleteMedia)
-					adminMedia.DELETE("/bulk", BulkDeleteMedia)
-				}
-
-				// Analytics
-				admin.GET("/analytics", GetAnalytics)
-				admin.GET("/analytics/articles/:id", GetArticleAnalytics)
-				admin.GET("/analytics/geographic", GetGeographicAnalytics)
-				admin.GET("/analytics/browsers", GetBrowserAnalytics)
-				admin.GET("/analytics/trends", GetTrendAnalytics)
-
-				// Export functions
-				admin.GET("/export/article/:id", ExportArticle)
-				admin.GET("/export/articles", ExportArticles)
-				admin.GET("/export/all", ExportAllArticles)
-
-				// Social media management
-				adminSocialMedia := admin.Group("/social-media")
-				{
-					adminSocialMedia.GET("/all", GetAllSocialMedia)
-					adminSocialMedia.GET("/:id", GetSocialMedia)
-					adminSocialMedia.POST("", CreateSocialMedia)
-					adminSocialMedia.PUT("/:id", UpdateSocialMedia)
-					adminSocialMedia.DELETE("/:id", DeleteSocialMedia)
-					adminSocialMedia.PUT("/order", UpdateSocialMediaOrder)
-				}
-
-				// System management
-				adminSystem := admin.Group("/system")
-				{
-					adminSystem.GET("/check-updates", CheckUpdates)
-					adminSystem.POST("/clear-cache", ClearUpdateCache)
-				}
-
-				// AI Usage tracking
-				aiUsageController := NewAIUsageController()
-				adminAIUsage := admin.Group("/ai-usage")
-				{
-					adminAIUsage.POST("/track", aiUsageController.TrackUsage)
-					adminAIUsage.GET("/stats", aiUsageController.GetUsageStats)
-					adminAIUsage.GET("/cost", aiUsageController.GetTotalCost)
-					adminAIUsage.GET("/recent", aiUsageController.GetRecentUsage)
-					adminAIUsage.GET("/daily", aiUsageController.GetDailyUsage)
-					adminAIUsage.GET("/article/:id", aiUsageController.GetUsageByArticle)
-					adminAIUsage.DELETE("/cleanup", aiUsageController.CleanupOldRecords)
-					adminAIUsage.GET("/cost-limits", aiUsageController.GetCostLimits)
-					adminAIUsage.PUT("/cost-limits", aiUsageController.SetCostLimits)
-				}
-
-				// LLMs.txt management
-				adminLLMs := admin.Group("/llms-txt")
-				{
-					adminLLMs.GET("/generate", AdminGenerateLLMsTxt)
-					adminLLMs.GET("/preview", AdminPreviewLLMsTxt)
-					adminLLMs.POST("/clear-cache", func(c *gin.Context) {
-						ClearLLMsTxtCache()
-						c.JSON(http.StatusOK, gin.H{"message": "LLMs.txt cache cleared successfully"})
-					})
-					adminLLMs.GET("/cache-stats", func(c *gin.Context) {
-						stats := GetCacheStats()
-						c.JSON(http.StatusOK, stats)
-					})
-					adminLLMs.GET("/usage-stats", GetLLMsTxtUsageStats)
-				}
-
-				// Embedding management
-				adminEmbeddings := admin.Group("/embeddings")
-				{
-					adminEmbeddings.GET("/stats", embeddingController.GetEmbeddingStats)
-					adminEmbeddings.GET("/providers", embeddingController.GetProviderStatus)
-					adminEmbeddings.POST("/providers/default", embeddingController.SetDefaultProvider)
-					adminEmbeddings.GET("/trends", embeddingController.GetEmbeddingTrends)
-					adminEmbeddings.POST("/process/:id", embeddingController.ProcessArticleEmbeddings)
-					adminEmbeddings.POST("/batch-process", embeddingController.BatchProcessEmbeddings)
-					adminEmbeddings.POST("/rebuild", embeddingController.RebuildEmbeddings)
-					adminEmbeddings.DELETE("/article/:id", embeddingController.DeleteArticleEmbeddings)
-					// Visualization endpoints
-					adminEmbeddings.GET("/vectors", embeddingController.GetEmbeddingVectors)
-					adminEmbeddings.GET("/similarity-graph", embeddingController.GetSimilarityGraph)
-					adminEmbeddings.GET("/quality-metrics", embeddingController.GetQualityMetrics)
-					adminEmbeddings.GET("/rag-process", embeddingController.GetRAGProcessVisualization)
-				}
-
-				// Content Assistant management
-				contentAssistantController := NewContentAssistantController()
-				adminContentAssistant := admin.Group("/content-assistant")
-				{
-					adminContentAssistant.GET("/topic-gaps", contentAssistantController.AnalyzeTopicGaps)
-					adminContentAssistant.GET("/writing-inspiration", contentAssistantController.GetWritingInspiration)
-					adminContentAssistant.POST("/smart-tags", contentAssistantController.GenerateSmartTags)
-					adminContentAssistant.POST("/seo-keywords", contentAssistantController.RecommendSEOKeywords)
-					adminContentAssistant.GET("/stats", contentAssistantController.GetContentAssistantStats)
-					adminContentAssistant.GET("/trends", contentAssistantController.GetTopicTrends)
-					adminContentAssistant.POST("/validate-idea", contentAssistantController.ValidateContentIdea)
-				}
-
-				// Personalized recommendations management
-				adminRecommendations := admin.Group("/recommendations")
-				{
-					adminRecommendations.GET("/users/recent", recommendationsController.GetRecentUsers)
-					adminRecommendations.GET("/users/:user_id/profile", recommendationsController.GetUserProfile)
-					adminRecommendations.GET("/users/:user_id/patterns", recommendationsController.GetReadingPatterns)
-					adminRecommendations.GET("/users/:user_id/similar", recommendationsController.GetSimilarUsers)
-					adminRecommendations.GET("/users/:user_id/analytics", recommendationsController.GetRecommendationAnalytics)
-					adminRecommendations.PUT("/users/:user_id/recommendations/:recommendation_id/click", recommendationsController.MarkRecommendationClicked)
-
-					// Debug and testing endpoints
-					adminRecommendations.GET("/users/:user_id/status", recommendationsController.GetUserDataStatus)
-					adminRecommendations.POST("/users/:user_id/force-generate", recommendationsController.ForceGenerateRecommendations)
-					adminRecommendations.POST("/users/:user_id/create-test-behavior", recommendationsController.CreateTestBehavior)
-				}
-
-				// SEO management
-				seoController := NewSEOController()
-				adminSEO := admin.Group("/seo")
-				{
-					// Health check endpoints
-					adminSEO.GET("/health", seoController.GetSEOHealth)
-					adminSEO.POST("/health/check", seoController.RunSEOHealthCheck)
-					adminSEO.GET("/health/history", seoController.GetSEOHealthHistory)
-
-					// Article SEO endpoints
-					adminSEO.GET("/articles/:id", seoController.GetArticleSEO)
-					adminSEO.PUT("/articles/:id", seoController.UpdateArticleSEO)
-					adminSEO.POST("/articles/:id/analyze", seoController.AnalyzeArticleSEO)
-					adminSEO.POST("/articles/:id/generate", seoController.GenerateArticleSEO)
-
-					// Keyword management endpoints
-					adminSEO.GET("/keywords", seoController.GetKeywords)
-					adminSEO.POST("/keywords", seoController.CreateKeyword)
-					adminSEO.PUT("/keywords/:id", seoController.UpdateKeyword)
-					adminSEO.DELETE("/keywords/:id", seoController.DeleteKeyword)
-					adminSEO.POST("/keywords/suggest", seoController.SuggestKeywords)
-					adminSEO.POST("/keywords/bulk-import", seoController.BulkImportKeywords)
-					adminSEO.POST("/keywords/update-rankings", seoController.UpdateKeywordRankings)
-					adminSEO.GET("/keywords/stats", seoController.GetKeywordStats)
-					adminSEO.GET("/keywords/groups", seoController.GetKeywordGroups)
-					adminSEO.POST("/keywords/groups", seoController.CreateKeywordGroup)
-					adminSEO.GET("/keywords/by-group", seoController.GetKeywordsByGroup)
-
-					// Metrics and automation
-					adminSEO.GET("/metrics", seoController.GetSEOMetrics)
-					adminSEO.GET("/automation/rules", seoController.GetAutomationRules)
-					adminSEO.GET("/notifications", seoController.GetSEONotifications)
-					adminSEO.PUT("/notifications/:id/read", seoController.MarkNotificationRead)
-				}
+			// Friend links (blogroll) management
+			adminFriendLinks := admin.Group("/friend-links")
+			{
+				adminFriendLinks.GET("/all", ListFriendLinks)
+				adminFriendLinks.GET("/:id", GetFriendLink)
+				adminFriendLinks.POST("", CreateFriendLink)
+				adminFriendLinks.PUT("/:id", UpdateFriendLink)
+				adminFriendLinks.DELETE("/:id", DeleteFriendLink)
+				adminFriendLinks.PUT("/order", UpdateFriendLinkOrder)
+				adminFriendLinks.POST("/:id/check", CheckFriendLink)
+			}
+
+			// Feeds posts are imported from, and the posts staged from them
+			adminFeedImports := admin.Group("/feed-imports")
+			{
+				adminFeedImports.GET("", ListFeedImports)
+				adminFeedImports.POST("", AddFeedImport)
+				adminFeedImports.PUT("/:id", UpdateFeedImport)
+				adminFeedImports.DELETE("/:id", DeleteFeedImport)
+				adminFeedImports.POST("/:id/fetch", FetchFeedImport)
+				adminFeedImports.GET("/posts", ListFeedImportPosts)
+				adminFeedImports.POST("/posts/publish", PublishFeedImportPosts)
+				adminFeedImports.POST("/posts/dismiss", DismissFeedImportPosts)
+			}
+
+			// Portfolio projects management
+			adminProjects := admin.Group("/projects")
+			{
+				adminProjects.GET("/all", ListAllProjects)
+				adminProjects.POST("", CreateProject)
+				adminProjects.PUT("/:id", UpdateProject)
+				adminProjects.DELETE("/:id", DeleteProject)
+				adminProjects.PUT("/order", UpdateProjectOrder)
+			}
+
+			// Standalone pages management
+			adminPages := admin.Group("/pages")
+			{
+				adminPages.GET("/all", ListAllPages)
+				adminPages.POST("", CreatePage)
+				adminPages.PUT("/:id", UpdatePage)
+				adminPages.DELETE("/:id", DeletePage)
+				adminPages.PUT("/order", UpdatePageOrder)
+			}
+
+			// Announcement bar management
+			adminAnnouncements := admin.Group("/announcements")
+			{
+				adminAnnouncements.GET("/all", ListAllAnnouncements)
+				adminAnnouncements.POST("", CreateAnnouncement)
+				adminAnnouncements.PUT("/:id", UpdateAnnouncement)
+				adminAnnouncements.DELETE("/:id", DeleteAnnouncement)
+			}
+
+			// Recompute the topic centroids now rather than at night
+			admin.POST("/topics/rebuild", RebuildTopics)
+
+			// Members who may read members-only articles
+			adminMembers := admin.Group("/members")
+			{
+				adminMembers.GET("", ListMembers)
+				adminMembers.POST("", CreateMember)
+				adminMembers.PUT("/:id", UpdateMember)
+				adminMembers.POST("/:id/passcode", ResetMemberPasscode)
+				adminMembers.DELETE("/:id", DeleteMember)
+			}
+
+			// Homepage layout, validated against its schema
+			admin.PUT("/homepage-layout", UpdateHomepageLayout)
+
+			// Theme packages: install, activate and share themes
+			adminThemes := admin.Group("/themes")
+			{
+				adminThemes.GET("", ListThemes)
+				adminThemes.GET("/export", ExportCurrentTheme)
+				adminThemes.GET("/:name/export", ExportTheme)
+				adminThemes.POST("/validate", ValidateTheme)
+				adminThemes.POST("/import", ImportTheme)
+				adminThemes.PUT("/:name/activate", ActivateTheme)
+				adminThemes.DELETE("/:name", DeleteTheme)
+			}
+
+			// System management
+			adminSystem := admin.Group("/system")
+			{
+				adminSystem.GET("/check-updates", CheckUpdates)
+				adminSystem.POST("/clear-cache", ClearUpdateCache)
+				adminSystem.GET("/config", GetRuntimeConfig)
+				adminSystem.GET("/secrets", GetSecretsStatus)
+				adminSystem.POST("/secrets/refresh", RefreshSecrets)
+				adminSystem.GET("/migrations", GetMigrationStatus)
+				adminSystem.GET("/database", GetDatabaseStats)
+				adminSystem.GET("/database/optimize", GetDatabaseOptimize)
+				adminSystem.POST("/database/optimize", StartDatabaseOptimize)
+				adminSystem.GET("/disk", GetDiskUsage)
+				adminSystem.GET("/monitor", GetSiteMonitor)
+				adminSystem.POST("/monitor/check", RunSiteMonitor)
+				adminSystem.GET("/maintenance", GetMaintenance)
+				adminSystem.PUT("/maintenance", SetMaintenance)
+				adminSystem.GET("/profiling", GetProfiling)
+				adminSystem.DELETE("/profiling", ResetProfiling)
+				registerPprof(adminSystem)
+			}
+
+			// Cache statistics and purges
+			adminCache := admin.Group("/cache")
+			{
+				adminCache.GET("", GetCacheOverview)
+				adminCache.DELETE("", PurgeAllCaches)
+				adminCache.DELETE("/smart/:group", PurgeSmartCacheGroup)
+				adminCache.DELETE("/:name", PurgeCacheNamespace)
+			}
+
+			// Feature flags
+			adminFeatures := admin.Group("/features")
+			{
+				adminFeatures.GET("", ListFeatures)
+				adminFeatures.PUT("/:name", UpdateFeature)
+				adminFeatures.DELETE("/:name", ResetFeature)
+			}
+
+			// Backup and restore
+			adminBackups := admin.Group("/backups")
+			{
+				adminBackups.GET("", ListBackups)
+				adminBackups.POST("", CreateBackup)
+				adminBackups.POST("/upload", UploadBackup)
+				adminBackups.GET("/status", GetBackupStatus)
+				adminBackups.POST("/run", RunScheduledBackup)
+				adminBackups.GET("/:name/download", DownloadBackup)
+				adminBackups.POST("/:name/restore", RestoreBackup)
+				adminBackups.DELETE("/:name", DeleteBackup)
+			}
+
+			// EPUB exports of article collections
+			adminEbooks := admin.Group("/ebooks")
+			{
+				adminEbooks.GET("", ListEbooks)
+				adminEbooks.POST("", CreateEbook)
+				adminEbooks.GET("/:name/download", DownloadEbook)
+				adminEbooks.DELETE("/:name", DeleteEbook)
+			}
+
+			// Read-only API keys of third-party apps
+			adminAPIKeys := admin.Group("/api-keys")
+			{
+				adminAPIKeys.GET("", ListAPIKeys)
+				adminAPIKeys.POST("", CreateAPIKey)
+				adminAPIKeys.PUT("/:id", UpdateAPIKey)
+				adminAPIKeys.DELETE("/:id", RevokeAPIKey)
+				adminAPIKeys.GET("/:id/usage", GetAPIKeyUsage)
+			}
+
+			// Notification center of SEO, AI cost, backup, link, monitor and security events
+			adminNotifications := admin.Group("/notifications")
+			{
+				adminNotifications.GET("", ListNotifications)
+				adminNotifications.GET("/summary", GetNotificationSummary)
+				adminNotifications.POST("/read-all", MarkAllNotificationsRead)
+				adminNotifications.POST("/stream/ticket", CreateNotificationStreamTicket)
+				adminNotifications.PUT("/:id", UpdateNotification)
+				adminNotifications.DELETE("/:id", DeleteNotification)
+			}
+
+			// Background job queue
+			adminJobs := admin.Group("/jobs")
+			{
+				adminJobs.GET("", ListJobs)
+				adminJobs.POST("", CreateJob)
+				adminJobs.GET("/stats", GetJobStats)
+				adminJobs.GET("/:id", GetJob)
+				adminJobs.GET("/:id/progress", StreamJobProgress)
+				adminJobs.POST("/:id/retry", RetryJob)
+				adminJobs.POST("/:id/cancel", CancelJob)
+				adminJobs.DELETE("/:id", DeleteJob)
+			}
+
+			// Webhook plugins
+			adminPlugins := admin.Group("/plugins")
+			{
+				adminPlugins.GET("", ListPlugins)
+				adminPlugins.POST("", CreatePlugin)
+				adminPlugins.GET("/hooks", ListHooks)
+				adminPlugins.PUT("/:id", UpdatePlugin)
+				adminPlugins.DELETE("/:id", DeletePlugin)
+				adminPlugins.POST("/:id/test", TestPlugin)
+			}
+
+			// Newsletter subscribers and campaigns
+			adminNewsletters := admin.Group("/newsletters")
+			{
+				adminNewsletters.GET("", ListNewsletters)
+				adminNewsletters.POST("", CreateNewsletter)
+				adminNewsletters.GET("/subscribers", ListSubscribers)
+				adminNewsletters.DELETE("/subscribers/:id", DeleteSubscriber)
+				adminNewsletters.POST("/bounces", RecordBounces)
+				adminNewsletters.GET("/:id", GetNewsletter)
+				adminNewsletters.PUT("/:id", UpdateNewsletter)
+				adminNewsletters.DELETE("/:id", DeleteNewsletter)
+				adminNewsletters.POST("/:id/send", SendNewsletter)
+			}
+
+			// Contact form inbox
+			adminContact := admin.Group("/contact/messages")
+			{
+				adminContact.GET("", ListContactMessages)
+				adminContact.GET("/:id", GetContactMessage)
+				adminContact.PUT("/:id", UpdateContactMessage)
+				adminContact.DELETE("/:id", DeleteContactMessage)
+			}
+
+			// Guestbook moderation
+			adminGuestbook := admin.Group("/guestbook")
+			{
+				adminGuestbook.GET("/all", ListAllGuestbookEntries)
+				adminGuestbook.PUT("/:id", UpdateGuestbookEntry)
+				adminGuestbook.PUT("/:id/reply", ReplyGuestbookEntry)
+				adminGuestbook.DELETE("/:id", DeleteGuestbookEntry)
+			}
+
+			// Donation methods management
+			adminDonations := admin.Group("/donations")
+			{
+				adminDonations.GET("/all", ListAllDonationMethods)
+				adminDonations.POST("", CreateDonationMethod)
+				adminDonations.PUT("/:id", UpdateDonationMethod)
+				adminDonations.DELETE("/:id", DeleteDonationMethod)
+				adminDonations.PUT("/order", UpdateDonationMethodOrder)
+			}
+
+			// Quick links page management
+			adminQuickLinks := admin.Group("/quick-links")
+			{
+				adminQuickLinks.GET("/all", ListAllQuickLinks)
+				adminQuickLinks.POST("", CreateQuickLink)
+				adminQuickLinks.PUT("/:id", UpdateQuickLink)
+				adminQuickLinks.DELETE("/:id", DeleteQuickLink)
+				adminQuickLinks.PUT("/order", UpdateQuickLinkOrder)
+			}
+
+			// Telegram, Discord and Slack publish notifications
+			adminNotifiers := admin.Group("/notifiers")
+			{
+				adminNotifiers.GET("", ListNotifiers)
+				adminNotifiers.POST("", CreateNotifier)
+				adminNotifiers.PUT("/:id", UpdateNotifier)
+				adminNotifiers.DELETE("/:id", DeleteNotifier)
+				adminNotifiers.POST("/:id/test", TestNotifier)
+			}
+
+			// Baidu, Sogou and 360 URL push
+			adminSearchPush := admin.Group("/search-push")
+			{
+				adminSearchPush.GET("/engines", ListSearchPushEngines)
+				adminSearchPush.POST("/engines", CreateSearchPushEngine)
+				adminSearchPush.PUT("/engines/:id", UpdateSearchPushEngine)
+				adminSearchPush.DELETE("/engines/:id", DeleteSearchPushEngine)
+				adminSearchPush.POST("/engines/:id/submit", SubmitSearchPush)
+				adminSearchPush.GET("/logs", ListSearchPushLogs)
+			}
+
+			// Fediverse followers and reply moderation
+			adminFediverse := admin.Group("/fediverse")
+			{
+				adminFediverse.GET("/followers", ListFediverseFollowers)
+				adminFediverse.DELETE("/followers/:id", RemoveFediverseFollower)
+				adminFediverse.GET("/replies", ListFediverseReplies)
+				adminFediverse.PUT("/replies/:id", UpdateFediverseReply)
+				adminFediverse.DELETE("/replies/:id", DeleteFediverseReply)
+				adminFediverse.POST("/replies/import", ImportComments)
+			}
+
+			// Comment counts synced from an external comment system
+			admin.GET("/admin/comment-bridge", GetCommentBridgeStatus)
+			admin.POST("/admin/comment-bridge/sync", SyncCommentBridge)
+
+			// Outgoing email settings and templates
+			adminMail := admin.Group("/mail")
+			{
+				adminMail.GET("/settings", GetMailSettings)
+				adminMail.PUT("/settings", UpdateMailSettings)
+				adminMail.POST("/test", SendTestMail)
+				adminMail.GET("/templates", ListMailTemplates)
+				adminMail.GET("/templates/:name/:lang", GetMailTemplate)
+				adminMail.PUT("/templates/:name/:lang", UpdateMailTemplate)
+				adminMail.DELETE("/templates/:name/:lang", ResetMailTemplate)
+				adminMail.GET("/templates/:name/:lang/preview", PreviewMailTemplate)
+				adminMail.GET("/suppressions", ListMailSuppressions)
+				adminMail.POST("/suppressions", AddMailSuppression)
+				adminMail.DELETE("/suppressions/:id", DeleteMailSuppression)
+			}
+
+			// Stop words left out of extracted keywords, per language
+			adminStopWords := admin.Group("/stop-words")
+			{
+				adminStopWords.GET("", ListStopWords)
+				adminStopWords.GET("/:lang", GetStopWords)
+				adminStopWords.PUT("/:lang", UpdateStopWords)
+				adminStopWords.DELETE("/:lang", ResetStopWords)
+				adminStopWords.POST("/:lang/preview", PreviewKeywords)
+			}
+
+			// Content quality of every article, weakest first
+			adminContentQuality := admin.Group("/content-quality")
+			{
+				adminContentQuality.GET("", GetContentQualityReport)
+				adminContentQuality.POST("/run", RunContentQuality)
+				adminContentQuality.GET("/articles/:id", GetArticleContentQuality)
+			}
+
+			// What to write next, from the gaps in the blog's coverage
+			adminWritingSuggestions := admin.Group("/writing-suggestions")
+			{
+				adminWritingSuggestions.GET("", ListWritingSuggestions)
+				adminWritingSuggestions.POST("/generate", GenerateWritingSuggestions)
+				adminWritingSuggestions.POST("/:id/accept", AcceptWritingSuggestion)
+				adminWritingSuggestions.POST("/:id/dismiss", DismissWritingSuggestion)
+			}
+
+			// Sites served by this instance (multi-site mode)
+			adminSites := admin.Group("/sites")
+			{
+				adminSites.GET("", ListSites)
+				adminSites.POST("", CreateSite)
+				adminSites.PUT("/:id", UpdateSite)
+				adminSites.DELETE("/:id", DeleteSite)
+			}
+
+			// Periodic tasks
+			adminSchedules := admin.Group("/schedules")
+			{
+				adminSchedules.GET("", ListSchedules)
+				adminSchedules.GET("/:name", GetSchedule)
+				adminSchedules.POST("/:name/run", RunSchedule)
+			}
+
+			// AI Usage tracking
+			aiUsageController := NewAIUsageController()
+			adminAIUsage := admin.Group("/ai-usage")
+			{
+				adminAIUsage.POST("/track", aiUsageController.TrackUsage)
+				adminAIUsage.GET("/stats", aiUsageController.GetUsageStats)
+				adminAIUsage.GET("/cost", aiUsageController.GetTotalCost)
+				adminAIUsage.GET("/recent", aiUsageController.GetRecentUsage)
+				adminAIUsage.GET("/daily", aiUsageController.GetDailyUsage)
+				adminAIUsage.GET("/article/:id", aiUsageController.GetUsageByArticle)
+				adminAIUsage.DELETE("/cleanup", aiUsageController.CleanupOldRecords)
+				adminAIUsage.GET("/cost-limits", aiUsageController.GetCostLimits)
+				adminAIUsage.PUT("/cost-limits", aiUsageController.SetCostLimits)
+			}
+
+			// LLMs.txt management
+			adminLLMs := admin.Group("/llms-txt")
+			{
+				adminLLMs.GET("/generate", AdminGenerateLLMsTxt)
+				adminLLMs.GET("/preview", AdminPreviewLLMsTxt)
+				adminLLMs.POST("/clear-cache", func(c *gin.Context) {
+					ClearLLMsTxtCache()
+					c.JSON(http.StatusOK, gin.H{"message": "LLMs.txt cache cleared successfully"})
+				})
+				adminLLMs.GET("/cache-stats", func(c *gin.Context) {
+					stats := GetCacheStats()
+					c.JSON(http.StatusOK, stats)
+				})
+				adminLLMs.GET("/usage-stats", GetLLMsTxtUsageStats)
+			}
+
+			// Embedding management
+			adminEmbeddings := admin.Group("/embeddings")
+			{
+				adminEmbeddings.GET("/stats", embeddingController.GetEmbeddingStats)
+				adminEmbeddings.GET("/providers", embeddingController.GetProviderStatus)
+				adminEmbeddings.POST("/providers/default", embeddingController.SetDefaultProvider)
+				adminEmbeddings.GET("/trends", embeddingController.GetEmbeddingTrends)
+				adminEmbeddings.POST("/process/:id", AIEndpoint("embeddings.process"), embeddingController.ProcessArticleEmbeddings)
+				adminEmbeddings.POST("/batch-process", embeddingController.BatchProcessEmbeddings)
+				adminEmbeddings.POST("/rebuild", embeddingController.RebuildEmbeddings)
+				adminEmbeddings.DELETE("/article/:id", embeddingController.DeleteArticleEmbeddings)
+				// Visualization endpoints
+				adminEmbeddings.GET("/vectors", embeddingController.GetEmbeddingVectors)
+				adminEmbeddings.GET("/similarity-graph", embeddingController.GetSimilarityGraph)
+				adminEmbeddings.GET("/quality-metrics", embeddingController.GetQualityMetrics)
+				adminEmbeddings.GET("/rag-process", AIEndpoint("embeddings.rag_process"), embeddingController.GetRAGProcessVisualization)
+			}
+
+			// Content Assistant management
+			contentAssistantController := NewContentAssistantController()
+			adminContentAssistant := admin.Group("/content-assistant")
+			{
+				adminContentAssistant.GET("/topic-gaps", AIEndpoint("content_assistant.topic_gaps"), contentAssistantController.AnalyzeTopicGaps)
+				adminContentAssistant.GET("/writing-inspiration", AIEndpoint("content_assistant.writing_inspiration"), contentAssistantController.GetWritingInspiration)
+				adminContentAssistant.POST("/smart-tags", AIEndpoint("content_assistant.smart_tags"), contentAssistantController.GenerateSmartTags)
+				adminContentAssistant.POST("/seo-keywords", AIEndpoint("content_assistant.seo_keywords"), contentAssistantController.RecommendSEOKeywords)
+				adminContentAssistant.GET("/stats", contentAssistantController.GetContentAssistantStats)
+				adminContentAssistant.GET("/trends", contentAssistantController.GetTopicTrends)
+				adminContentAssistant.POST("/validate-idea", AIEndpoint("content_assistant.validate_idea"), contentAssistantController.ValidateContentIdea)
+			}
+
+			// Personalized recommendations management
+			adminRecommendations := admin.Group("/recommendations")
+			{
+				adminRecommendations.GET("/users/recent", recommendationsController.GetRecentUsers)
+				adminRecommendations.GET("/users/:user_id/profile", recommendationsController.GetUserProfile)
+				adminRecommendations.GET("/users/:user_id/behaviors", recommendationsController.GetUserBehaviors)
+				adminRecommendations.GET("/users/:user_id/patterns", recommendationsController.GetReadingPatterns)
+				adminRecommendations.GET("/users/:user_id/similar", recommendationsController.GetSimilarUsers)
+				adminRecommendations.GET("/users/:user_id/analytics", recommendationsController.GetRecommendationAnalytics)
+				adminRecommendations.PUT("/users/:user_id/recommendations/:recommendation_id/click", recommendationsController.MarkRecommendationClicked)
+
+				// Debug and testing endpoints
+				adminRecommendations.GET("/users/:user_id/status", recommendationsController.GetUserDataStatus)
+				adminRecommendations.POST("/users/:user_id/force-generate", recommendationsController.ForceGenerateRecommendations)
+				adminRecommendations.POST("/users/:user_id/create-test-behavior", recommendationsController.CreateTestBehavior)
+			}
+
+			// SEO management
+			seoController := NewSEOController()
+			adminSEO := admin.Group("/seo")
+			{
+				// Health check endpoints
+				adminSEO.GET("/health", seoController.GetSEOHealth)
+				adminSEO.POST("/health/check", AIEndpoint("seo.health_check"), seoController.RunSEOHealthCheck)
+				adminSEO.GET("/health/history", seoController.GetSEOHealthHistory)
+
+				// Article SEO endpoints
+				adminSEO.GET("/articles/:id", seoController.GetArticleSEO)
+				adminSEO.PUT("/articles/:id", seoController.UpdateArticleSEO)
+				adminSEO.POST("/articles/:id/analyze", AIEndpoint("seo.analyze"), seoController.AnalyzeArticleSEO)
+				adminSEO.POST("/articles/:id/generate", AIEndpoint("seo.generate"), seoController.GenerateArticleSEO)
+
+				// Bulk SEO metadata generation, reviewed before it is applied
+				adminSEO.POST("/metadata/bulk", seoController.QueueBulkSEOMetadata)
+				adminSEO.GET("/metadata/proposals", seoController.ListSEOMetadataProposals)
+				adminSEO.POST("/metadata/proposals/apply", seoController.ApplySEOMetadataProposals)
+				adminSEO.POST("/metadata/proposals/dismiss", seoController.DismissSEOMetadataProposals)
+
+				// Analysis thresholds per language
+				adminSEO.GET("/rules", seoController.GetSEORules)
+				adminSEO.PUT("/rules/:lang", seoController.UpdateSEORules)
+				adminSEO.DELETE("/rules/:lang", seoController.ResetSEORules)
+
+				// Keyword management endpoints
+				adminSEO.GET("/keywords", seoController.GetKeywords)
+				adminSEO.POST("/keywords", seoController.CreateKeyword)
+				adminSEO.PUT("/keywords/:id", seoController.UpdateKeyword)
+				adminSEO.DELETE("/keywords/:id", seoController.DeleteKeyword)
+				adminSEO.POST("/keywords/suggest", AIEndpoint("seo.keyword_suggestions"), seoController.SuggestKeywords)
+				adminSEO.POST("/keywords/bulk-import", seoController.BulkImportKeywords)
+				adminSEO.POST("/keywords/update-rankings", seoController.UpdateKeywordRankings)
+				adminSEO.GET("/keywords/stats", seoController.GetKeywordStats)
+				adminSEO.GET("/keywords/groups", seoController.GetKeywordGroups)
+				adminSEO.POST("/keywords/groups", seoController.CreateKeywordGroup)
+				adminSEO.GET("/keywords/by-group", seoController.GetKeywordsByGroup)
+				adminSEO.GET("/keywords/data", seoController.GetKeywordDataStatus)
+				adminSEO.POST("/keywords/data/refresh", seoController.RefreshKeywordData)
+				adminSEO.GET("/keywords/mapping", AIEndpoint("seo.keyword_mapping"), seoController.GetKeywordMapping)
+
+				// Metrics and automation
+				adminSEO.GET("/metrics", seoController.GetSEOMetrics)
+				adminSEO.GET("/automation/rules", seoController.GetAutomationRules)
+				adminSEO.GET("/notifications", seoController.GetSEONotifications)
+				adminSEO.PUT("/notifications/:id/read", seoController.MarkNotificationRead)
 			}
+
+			// OpenAPI description of the API, for the Swagger UI in the admin
+			admin.GET("/openapi.json", ServeOpenAPI)
 		}
 	}
-
-	return r
+	access.mark(routeAccessAdmin)
 }
@@ -1,12 +1,17 @@
 package api
 
 import (
-	"blog-backend/internal/database"
+	"blog-backend/internal/cache"
 	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"blog-backend/internal/services"
+	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"strconv"
+	"net/url"
 	"strings"
 	"time"
 
@@ -15,19 +20,31 @@ import (
 
 // RSS 2.0 structure
 type RSS struct {
-	XMLName xml.Name `xml:"rss"`
-	Version string   `xml:"version,attr"`
-	Channel Channel  `xml:"channel"`
+	XMLName      xml.Name `xml:"rss"`
+	Version      string   `xml:"version,attr"`
+	XMLNSAtom    string   `xml:"xmlns:atom,attr"`
+	XMLNSContent string   `xml:"xmlns:content,attr"`
+	XMLNSDC      string   `xml:"xmlns:dc,attr"`
+	Channel      Channel  `xml:"channel"`
 }
 
 type Channel struct {
-	Title         string `xml:"title"`
-	Link          string `xml:"link"`
-	Description   string `xml:"description"`
-	Language      string `xml:"language"`
-	LastBuildDate string `xml:"lastBuildDate"`
-	Generator     string `xml:"generator"`
-	Items         []Item `xml:"item"`
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	AtomLinks     []AtomLink `xml:"atom:link"`
+	Description   string     `xml:"description"`
+	Language      string     `xml:"language"`
+	Copyright     string     `xml:"copyright,omitempty"`
+	LastBuildDate string     `xml:"lastBuildDate"`
+	Generator     string     `xml:"generator"`
+	Items         []Item     `xml:"item"`
+}
+
+// AtomLink advertises the feed's own URL and its WebSub hubs
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
 }
 
 type Item struct {
@@ -37,29 +54,58 @@ type Item struct {
 	PubDate     string `xml:"pubDate"`
 	GUID        string `xml:"guid"`
 	Category    string `xml:"category,omitempty"`
+	// Rights is the license the article may be used under
+	Rights string `xml:"dc:rights,omitempty"`
+	// Content is the full article as HTML, with its code highlighted
+	Content string `xml:"content:encoded,omitempty"`
+}
+
+// Rendered feeds, cleared whenever articles, categories, settings, moments
+// or author profiles change
+var feedCache = cache.New("feeds", time.Hour)
+
+func init() {
+	for _, topic := range []string{cache.TopicArticles, cache.TopicCategories, cache.TopicSettings, cache.TopicMoments, cache.TopicAuthors} {
+		cache.Subscribe(topic, feedCache.Clear)
+	}
 }
 
 // GetRSSFeed generates RSS feed for articles
 func GetRSSFeed(c *gin.Context) {
 	lang := c.Query("lang")
 	if lang == "" {
-		lang = "zh" // Default language
+		lang = services.DefaultFeedLanguage
 	}
 
+	var feed struct {
+		CategoryID uint `form:"category_id"`
+		Limit      int  `form:"limit" binding:"omitempty,min=1,max=100"`
+	}
+	if !bindQuery(c, &feed) {
+		return
+	}
 	categoryID := c.Query("category_id")
-	limit := c.Query("limit")
+	limitInt := feed.Limit
+	if limitInt == 0 {
+		limitInt = 20
+	}
 
-	// Parse limit, default to 20
-	limitInt := 20
-	if limit != "" {
-		if parsed, err := strconv.Atoi(limit); err == nil && parsed > 0 && parsed <= 100 {
-			limitInt = parsed
-		}
+	baseURL := getBaseURL(c)
+	selfURL := services.FeedURL(baseURL, feed.CategoryID, lang)
+	hubs := services.GetGlobalWebSubService().Hubs()
+	setWebSubLinkHeader(c, selfURL, hubs)
+
+	cacheKey := fmt.Sprintf("rss:%s:%s:%d:%s", lang, categoryID, limitInt, baseURL)
+	var cached string
+	if feedCache.Get(cacheKey, &cached) {
+		c.Header("Cache-Control", "public, max-age=3600")
+		c.Data(http.StatusOK, "application/rss+xml; charset=utf-8", []byte(cached))
+		return
 	}
 
 	// Get site settings for RSS metadata
 	var settings models.SiteSettings
-	if err := database.DB.Preload("Translations").First(&settings).Error; err != nil {
+	if err := siteDB(c).Preload("Translations").First(&settings).Error; err != nil {
 		c.XML(http.StatusInternalServerError, gin.H{"error": "Failed to fetch site settings"})
 		return
 	}
@@ -68,12 +114,12 @@ func GetRSSFeed(c *gin.Context) {
 	applySiteSettingsTranslation(&settings, lang)
 
 	// Build query for articles
-	query := database.DB.Preload("Category").Preload("Translations").
+	query := siteDB(c).Preload("Category").Preload("Translations").
 		Where("created_at <= ?", time.Now()).
 		Order("created_at DESC").Limit(limitInt)
 
 	if categoryID != "" {
-		query = query.Where("category_id = ?", categoryID)
+		query = query.Where("category_id = ?", feed.CategoryID)
 	}
 
 	var articles []models.Article
@@ -83,14 +129,26 @@ func GetRSSFeed(c *gin.Context) {
 	}
 
 	// Generate RSS feed
-	baseURL := getBaseURL(c)
-	rss := generateRSSFeed(articles, settings, lang, baseURL)
+	articles = feedArticles(articles)
+	rss := generateRSSFeed(c.Request.Context(), articles, settings, lang, baseURL)
+	if moments := services.GetGlobalMomentService(); categoryID == "" && moments.InFeed() {
+		public, _, err := moments.List(c.Request.Context(), []string{models.MomentPublic}, 1, limitInt)
+		if err != nil {
+			c.XML(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moments"})
+			return
+		}
+		rss.Channel.Items = mergeMomentItems(c.Request.Context(), articles, rss.Channel.Items, public, lang, baseURL, limitInt)
+	}
+	rss.Channel.AtomLinks = feedAtomLinks(selfURL, hubs)
+	data, err := xml.Marshal(rss)
+	if err != nil {
+		c.XML(http.StatusInternalServerError, gin.H{"error": "Failed to render RSS feed"})
+		return
+	}
+	feedCache.Set(cacheKey, string(data))
 
-	// Set appropriate headers
-	c.Header("Content-Type", "application/rss+xml; charset=utf-8")
 	c.Header("Cache-Control", "public, max-age=3600") // Cache for 1 hour
-
-	c.XML(http.StatusOK, rss)
+	c.Data(http.StatusOK, "application/rss+xml; charset=utf-8", data)
 }
 
 // GetRSSFeedByCategory generates RSS feed for specific category
@@ -98,12 +156,12 @@ func GetRSSFeedByCategory(c *gin.Context) {
 	categoryID := c.Param("id")
 	lang := c.Query("lang")
 	if lang == "" {
-		lang = "zh"
+		lang = services.DefaultFeedLanguage
 	}
 
 	// Verify category exists
 	var category models.Category
-	if err := database.DB.Preload("Translations").First(&category, categoryID).Error; err != nil {
+	if err := siteDB(c).Preload("Translations").First(&category, categoryID).Error; err != nil {
 		c.XML(http.StatusNotFound, gin.H{"error": "Category not found"})
 		return
 	}
@@ -116,13 +174,72 @@ func GetRSSFeedByCategory(c *gin.Context) {
 	GetRSSFeed(c)
 }
 
+// GetRSSFeedByAuthor generates the RSS feed of an author's articles
+func GetRSSFeedByAuthor(c *gin.Context) {
+	id, ok := authorID(c)
+	if !ok {
+		return
+	}
+	lang := c.Query("lang")
+	if lang == "" {
+		lang = services.DefaultFeedLanguage
+	}
+	baseURL := getBaseURL(c)
+	cacheKey := fmt.Sprintf("rss:author:%d:%s:%s", id, lang, baseURL)
+	var cached string
+	if feedCache.Get(cacheKey, &cached) {
+		c.Header("Cache-Control", "public, max-age=3600")
+		c.Data(http.StatusOK, "application/rss+xml; charset=utf-8", []byte(cached))
+		return
+	}
+
+	authors := services.GetGlobalAuthorService()
+	author, err := authors.Get(c.Request.Context(), id, lang)
+	if errors.Is(err, services.ErrAuthorNotFound) {
+		c.XML(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	} else if err != nil {
+		c.XML(http.StatusInternalServerError, gin.H{"error": "Failed to fetch author"})
+		return
+	}
+	var settings models.SiteSettings
+	if err := siteDB(c).Preload("Translations").First(&settings).Error; err != nil {
+		c.XML(http.StatusInternalServerError, gin.H{"error": "Failed to fetch site settings"})
+		return
+	}
+	applySiteSettingsTranslation(&settings, lang)
+	articles, _, err := authors.Articles(c.Request.Context(), id, 1, 20)
+	if err != nil {
+		c.XML(http.StatusInternalServerError, gin.H{"error": "Failed to fetch articles"})
+		return
+	}
+
+	rss := generateRSSFeed(c.Request.Context(), feedArticles(articles), settings, lang, baseURL)
+	rss.Channel.Title = author.Name + " - " + settings.SiteTitle
+	if author.Bio != "" {
+		rss.Channel.Description = author.Bio
+	}
+	selfURL := fmt.Sprintf("%s/api/rss/author/%d?lang=%s", strings.TrimRight(baseURL, "/"), id, url.QueryEscape(lang))
+	rss.Channel.AtomLinks = feedAtomLinks(selfURL, nil)
+	data, err := xml.Marshal(rss)
+	if err != nil {
+		c.XML(http.StatusInternalServerError, gin.H{"error": "Failed to render RSS feed"})
+		return
+	}
+	feedCache.Set(cacheKey, string(data))
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, "application/rss+xml; charset=utf-8", data)
+}
+
 // generateRSSFeed creates RSS structure from articles
-func generateRSSFeed(articles []models.Article, settings models.SiteSettings, lang string, baseURL string) RSS {
+func generateRSSFeed(ctx context.Context, articles []models.Article, settings models.SiteSettings, lang string, baseURL string) RSS {
 	channel := Channel{
 		Title:         settings.SiteTitle,
 		Link:          baseURL,
 		Description:   settings.SiteSubtitle,
 		Language:      lang,
+		Copyright:     services.LicenseText(services.ResolveLicense(&models.Article{}, &settings)),
 		LastBuildDate: time.Now().Format(time.RFC1123Z),
 		Generator:     "KUNO RSS Generator",
 		Items:         make([]Item, 0, len(articles)),
@@ -130,8 +247,10 @@ func generateRSSFeed(articles []models.Article, settings models.SiteSettings, la
 
 	for _, article := range articles {
 		// Apply translation to article
-		applyTranslation(&article, lang)
+		services.ApplyTranslation(&article, lang)
 		applyCategoryTranslation(&article.Category, lang)
+		// Feeds are cached and shared, so members-only articles only ever carry their teaser
+		services.GetGlobalMemberService().Lock(&article)
 
 		// Generate article URL
 		articleURL := fmt.Sprintf("%s/%s/article/%d", baseURL, lang, article.ID)
@@ -143,6 +262,8 @@ func generateRSSFeed(articles []models.Article, settings models.SiteSettings, la
 			Description: generateItemDescription(article),
 			PubDate:     article.CreatedAt.Format(time.RFC1123Z),
 			GUID:        articleURL,
+			Content:     articleHTML(ctx, article),
+			Rights:      services.LicenseText(services.ResolveLicense(&article, &settings)),
 		}
 
 		if article.Category.Name != "" {
@@ -153,11 +274,128 @@ func generateRSSFeed(articles []models.Article, settings models.SiteSettings, la
 	}
 
 	return RSS{
-		Version: "2.0",
-		Channel: channel,
+		Version:      "2.0",
+		XMLNSAtom:    "http://www.w3.org/2005/Atom",
+		XMLNSContent: "http://purl.org/rss/1.0/modules/content/",
+		XMLNSDC:      "http://purl.org/dc/elements/1.1/",
+		Channel:      channel,
 	}
 }
 
+// GetMomentsFeed generates the RSS feed of public moments
+func GetMomentsFeed(c *gin.Context) {
+	lang := c.Query("lang")
+	if lang == "" {
+		lang = services.DefaultFeedLanguage
+	}
+	baseURL := getBaseURL(c)
+	cacheKey := fmt.Sprintf("rss:moments:%s:%s", lang, baseURL)
+	var cached string
+	if feedCache.Get(cacheKey, &cached) {
+		c.Header("Cache-Control", "public, max-age=3600")
+		c.Data(http.StatusOK, "application/rss+xml; charset=utf-8", []byte(cached))
+		return
+	}
+
+	var settings models.SiteSettings
+	if err := siteDB(c).Preload("Translations").First(&settings).Error; err != nil {
+		c.XML(http.StatusInternalServerError, gin.H{"error": "Failed to fetch site settings"})
+		return
+	}
+	applySiteSettingsTranslation(&settings, lang)
+
+	moments, _, err := services.GetGlobalMomentService().List(c.Request.Context(), []string{models.MomentPublic}, 1, 20)
+	if err != nil {
+		c.XML(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moments"})
+		return
+	}
+	rss := generateRSSFeed(c.Request.Context(), nil, settings, lang, baseURL)
+	for i := range moments {
+		rss.Channel.Items = append(rss.Channel.Items, momentItem(c.Request.Context(), &moments[i], lang, baseURL))
+	}
+	selfURL := strings.TrimRight(baseURL, "/") + "/api/rss/moments?lang=" + url.QueryEscape(lang)
+	rss.Channel.AtomLinks = feedAtomLinks(selfURL, nil)
+	data, err := xml.Marshal(rss)
+	if err != nil {
+		c.XML(http.StatusInternalServerError, gin.H{"error": "Failed to render RSS feed"})
+		return
+	}
+	feedCache.Set(cacheKey, string(data))
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, "application/rss+xml; charset=utf-8", data)
+}
+
+// momentItem creates the RSS item of a moment
+func momentItem(ctx context.Context, moment *models.Moment, lang, baseURL string) Item {
+	service := services.GetGlobalMomentService()
+	momentURL := fmt.Sprintf("%s/%s/moments/%d", baseURL, lang, moment.ID)
+	description := strings.Join(strings.Fields(moment.Content), " ")
+	if len([]rune(description)) > 200 {
+		description = string([]rune(description)[:200]) + "..."
+	}
+	return Item{
+		Title:       service.Title(moment),
+		Link:        momentURL,
+		Description: description,
+		PubDate:     moment.CreatedAt.Format(time.RFC1123Z),
+		GUID:        momentURL,
+		Content:     service.HTML(ctx, moment, baseURL),
+	}
+}
+
+// feedArticles leaves out the articles limited to some regions, as feeds
+// are cached and reach readers everywhere
+func feedArticles(articles []models.Article) []models.Article {
+	served := make([]models.Article, 0, len(articles))
+	for _, article := range articles {
+		if article.RegionRule == "" {
+			served = append(served, article)
+		}
+	}
+	return served
+}
+
+// mergeMomentItems mixes moments into the items of articles by date, both
+// newest first, keeping at most limit items
+func mergeMomentItems(ctx context.Context, articles []models.Article, items []Item, moments []models.Moment, lang, baseURL string, limit int) []Item {
+	merged := make([]Item, 0, limit)
+	i, j := 0, 0
+	for len(merged) < limit && (i < len(items) || j < len(moments)) {
+		if j == len(moments) || (i < len(items) && !articles[i].CreatedAt.Before(moments[j].CreatedAt)) {
+			merged = append(merged, items[i])
+			i++
+		} else {
+			merged = append(merged, momentItem(ctx, &moments[j], lang, baseURL))
+			j++
+		}
+	}
+	return merged
+}
+
+// feedAtomLinks returns the self and WebSub hub links of a feed
+func feedAtomLinks(selfURL string, hubs []string) []AtomLink {
+	links := []AtomLink{{Href: selfURL, Rel: "self", Type: "application/rss+xml"}}
+	for _, hub := range hubs {
+		links = append(links, AtomLink{Href: hub, Rel: "hub"})
+	}
+	return links
+}
+
+// setWebSubLinkHeader repeats the WebSub discovery links in a Link header,
+// which subscribers check before parsing the feed
+func setWebSubLinkHeader(c *gin.Context, selfURL string, hubs []string) {
+	if len(hubs) == 0 {
+		return
+	}
+	links := make([]string, 0, len(hubs)+1)
+	for _, hub := range hubs {
+		links = append(links, fmt.Sprintf(`<%s>; rel="hub"`, hub))
+	}
+	links = append(links, fmt.Sprintf(`<%s>; rel="self"`, selfURL))
+	c.Header("Link", strings.Join(links, ", "))
+}
+
 // generateItemDescription creates description for RSS item
 func generateItemDescription(article models.Article) string {
 	if article.Summary != "" {
@@ -175,6 +413,20 @@ func generateItemDescription(article models.Article) string {
 	return content
 }
 
+// articleHTML renders the article's content as HTML for readers that do not
+// run the site's scripts, or returns "" when it cannot be rendered
+func articleHTML(ctx context.Context, article models.Article) string {
+	if article.ContentType == "html" {
+		return security.GetGlobalHTMLPolicy().Sanitize(article.Content)
+	}
+	content, err := services.GetGlobalMarkdownRenderer().Render(ctx, article.Content)
+	if err != nil {
+		slog.Warn("Failed to render article content", "article_id", article.ID, "error", err)
+		return ""
+	}
+	return content
+}
+
 // applySiteSettingsTranslation applies translation to site settings
 func applySiteSettingsTranslation(settings *models.SiteSettings, lang string) {
 	if lang == "zh" || lang == "" {
@@ -195,18 +447,3 @@ func applySiteSettingsTranslation(settings *models.SiteSettings, lang string) {
 }
 
 // Note: applyCategoryTranslation and applyTranslation functions are already defined in categories.go and articles.go
-
-// getBaseURL extracts base URL from request
-func getBaseURL(c *gin.Context) string {
-	scheme := "http"
-	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
-		scheme = "https"
-	}
-
-	host := c.Request.Host
-	if forwardedHost := c.GetHeader("X-Forwarded-Host"); forwardedHost != "" {
-		host = forwardedHost
-	}
-
-	return fmt.Sprintf("%s://%s", scheme, host)
-}
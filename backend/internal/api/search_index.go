@@ -0,0 +1,39 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RebuildSearchIndex queues a rebuild of the search indexes. Search keeps
+// answering from the current index until the new one is swapped in; follow
+// the returned job through the jobs API for its progress.
+func RebuildSearchIndex(c *gin.Context) {
+	job, err := services.GetGlobalSearchIndexService().Start()
+	if err != nil {
+		if errors.Is(err, services.ErrSearchRebuildRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		logging.FromGin(c).Error("Failed to queue a search index rebuild", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue the rebuild"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Search index rebuild queued", "job": job})
+}
+
+// GetSearchIndexStatus returns the documents in each search index by
+// language and the latest rebuild
+func GetSearchIndexStatus(c *gin.Context) {
+	indexes, job, err := services.GetGlobalSearchIndexService().Status()
+	if err != nil {
+		logging.FromGin(c).Error("Failed to load the search index status", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the search index status"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"indexes": indexes, "last_rebuild": job})
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSearchPushEngines returns the search engines the site pushes links
+// to, with today's submissions. Push tokens are never returned.
+func ListSearchPushEngines(c *gin.Context) {
+	engines, err := services.GetGlobalSearchPushService().List(c.Request.Context())
+	if err != nil {
+		respondSearchPushError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"engines": engines})
+}
+
+// CreateSearchPushEngine adds Baidu, Sogou or 360 with its push token
+func CreateSearchPushEngine(c *gin.Context) {
+	var input services.SearchPushEngineInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	engine, err := services.GetGlobalSearchPushService().Create(c.Request.Context(), input)
+	if err != nil {
+		respondSearchPushError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, engine)
+}
+
+// UpdateSearchPushEngine changes an engine; leave token out to keep it
+func UpdateSearchPushEngine(c *gin.Context) {
+	id, ok := searchPushEngineID(c)
+	if !ok {
+		return
+	}
+	var input services.SearchPushEngineInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	engine, err := services.GetGlobalSearchPushService().Update(c.Request.Context(), id, input)
+	if err != nil {
+		respondSearchPushError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, engine)
+}
+
+// DeleteSearchPushEngine removes an engine and its submission log
+func DeleteSearchPushEngine(c *gin.Context) {
+	id, ok := searchPushEngineID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalSearchPushService().Delete(c.Request.Context(), id); err != nil {
+		respondSearchPushError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Search push engine deleted"})
+}
+
+// SubmitSearchPush pushes one article to an engine now, for example to try
+// a new token, and returns the logged submission. A failed submission is
+// still a 200 with its status and error.
+func SubmitSearchPush(c *gin.Context) {
+	id, ok := searchPushEngineID(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		ArticleID uint `json:"article_id" binding:"required"`
+	}
+	if !bindJSON(c, &input) {
+		return
+	}
+	entry, err := services.GetGlobalSearchPushService().Submit(c.Request.Context(), id, input.ArticleID)
+	if err != nil {
+		respondSearchPushError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// ListSearchPushLogs returns the submissions of the last 90 days, newest
+// first. Filter with ?engine_id= and ?article_id=.
+func ListSearchPushLogs(c *gin.Context) {
+	paging, ok := bindPage(c, 50, maxAdminPageSize)
+	if !ok {
+		return
+	}
+	var filter struct {
+		EngineID  uint `form:"engine_id"`
+		ArticleID uint `form:"article_id"`
+	}
+	if !bindQuery(c, &filter) {
+		return
+	}
+	logs, total, err := services.GetGlobalSearchPushService().Logs(c.Request.Context(), filter.EngineID, filter.ArticleID, paging.Page, paging.Limit)
+	if err != nil {
+		respondSearchPushError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"logs":       logs,
+		"pagination": gin.H{"page": paging.Page, "limit": paging.Limit, "total": total},
+	})
+}
+
+func searchPushEngineID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search push engine ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondSearchPushError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSearchPushEngineNotFound), errors.Is(err, services.ErrArticleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidSearchPushEngine), errors.Is(err, services.ErrSearchPushNotAllowed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Search push operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search push operation failed"})
+	}
+}
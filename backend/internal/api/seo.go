@@ -53,7 +53,7 @@ func (ctrl *SEOController) RunSEOHealthCheck(c *gin.Context) {
 	var err error
 
 	if checkType == "site" {
-		healthCheck, err = ctrl.healthChecker.RunSiteWideHealthCheck()
+		healthCheck, err = ctrl.healthChecker.RunSiteWideHealthCheck(c.Request.Context())
 	} else if checkType == "article" {
 		articleIDStr := c.Query("article_id")
 		if articleIDStr == "" {
@@ -98,10 +98,12 @@ func (ctrl *SEOController) GetSEOHealthHistory(c *gin.Context) {
 		filters["check_type"] = checkType
 	}
 
-	if limit := c.Query("limit"); limit != "" {
-		if l, err := strconv.Atoi(limit); err == nil {
-			filters["limit"] = l
-		}
+	slice, ok := bindLimit(c, 0, maxAdminPageSize)
+	if !ok {
+		return
+	}
+	if slice.Limit > 0 {
+		filters["limit"] = slice.Limit
 	}
 
 	history, err := ctrl.healthChecker.GetHealthHistory(filters)
@@ -176,8 +178,7 @@ func (ctrl *SEOController) UpdateArticleSEO(c *gin.Context) {
 		SEOSlug        string `json:"seo_slug"`
 	}
 
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &updateData) {
 		return
 	}
 
@@ -199,9 +200,13 @@ func (ctrl *SEOController) UpdateArticleSEO(c *gin.Context) {
 	if updateData.SEOKeywords != "" {
 		updates["seo_keywords"] = updateData.SEOKeywords
 	}
-	if updateData.SEOSlug != "" {
+	if updateData.SEOSlug != "" && updateData.SEOSlug != article.SEOSlug {
+		if !checkArticleSlug(c, article.ID, "", updateData.SEOSlug) {
+			return
+		}
 		updates["seo_slug"] = updateData.SEOSlug
 	}
+	oldSlug := article.SEOSlug
 
 	if err := db.Model(&article).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update article"})
@@ -210,6 +215,7 @@ func (ctrl *SEOController) UpdateArticleSEO(c *gin.Context) {
 
 	// Reload article
 	db.First(&article, articleID)
+	recordSlugChange(c, &article, "", oldSlug, article.SEOSlug)
 
 	c.JSON(http.StatusOK, gin.H{
 		"article": article,
@@ -231,17 +237,24 @@ func (ctrl *SEOController) AnalyzeArticleSEO(c *gin.Context) {
 		Language     string `json:"language"`
 	}
 
-	if err := c.ShouldBindJSON(&requestData); err != nil {
-		requestData.Language = "zh" // Default language
-	}
+	// Without a body the article is analyzed in its default language
+	_ = c.ShouldBindJSON(&requestData)
 
 	db := database.DB
 	var article models.Article
-	if err := db.First(&article, articleID).Error; err != nil {
+	if err := db.Preload("Translations").First(&article, articleID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
 		return
 	}
 
+	// Another language is analyzed in its translation, so right-to-left
+	// text is measured as it is served
+	if requestData.Language == "" {
+		requestData.Language = article.DefaultLang
+	} else if requestData.Language != article.DefaultLang {
+		services.ApplyTranslation(&article, requestData.Language)
+	}
+
 	// Use article's SEO keywords as focus keyword if not provided
 	focusKeyword := requestData.FocusKeyword
 	if focusKeyword == "" {
@@ -284,8 +297,7 @@ func (ctrl *SEOController) GenerateArticleSEO(c *gin.Context) {
 		Language            string `json:"language"`
 	}
 
-	if err := c.ShouldBindJSON(&requestData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &requestData) {
 		return
 	}
 
@@ -353,6 +365,10 @@ func (ctrl *SEOController) GetKeywords(c *gin.Context) {
 		filters["difficulty"] = difficulty
 	}
 
+	if c.Query("stale") == "true" {
+		filters["stale"] = true
+	}
+
 	if search := c.Query("search"); search != "" {
 		filters["search"] = search
 	}
@@ -373,8 +389,7 @@ func (ctrl *SEOController) GetKeywords(c *gin.Context) {
 func (ctrl *SEOController) CreateKeyword(c *gin.Context) {
 	var keyword models.SEOKeyword
 
-	if err := c.ShouldBindJSON(&keyword); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &keyword) {
 		return
 	}
 
@@ -400,8 +415,7 @@ func (ctrl *SEOController) UpdateKeyword(c *gin.Context) {
 	}
 
 	var updates map[string]interface{}
-	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &updates) {
 		return
 	}
 
@@ -443,8 +457,7 @@ func (ctrl *SEOController) SuggestKeywords(c *gin.Context) {
 		BaseKeyword string `json:"base_keyword"`
 	}
 
-	if err := c.ShouldBindJSON(&requestData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &requestData) {
 		return
 	}
 
@@ -491,8 +504,7 @@ func (ctrl *SEOController) GetKeywordGroups(c *gin.Context) {
 func (ctrl *SEOController) CreateKeywordGroup(c *gin.Context) {
 	var group models.SEOKeywordGroup
 
-	if err := c.ShouldBindJSON(&group); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &group) {
 		return
 	}
 
@@ -549,10 +561,12 @@ func (ctrl *SEOController) GetSEONotifications(c *gin.Context) {
 		filters["severity"] = severity
 	}
 
-	if limit := c.Query("limit"); limit != "" {
-		if l, err := strconv.Atoi(limit); err == nil {
-			filters["limit"] = l
-		}
+	slice, ok := bindLimit(c, 0, maxAdminPageSize)
+	if !ok {
+		return
+	}
+	if slice.Limit > 0 {
+		filters["limit"] = slice.Limit
 	}
 
 	notifications, err := ctrl.healthChecker.GetSEONotifications(filters)
@@ -710,8 +724,7 @@ func (ctrl *SEOController) BulkImportKeywords(c *gin.Context) {
 		Language  string   `json:"language"`
 	}
 
-	if err := c.ShouldBindJSON(&requestData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &requestData) {
 		return
 	}
 
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueueBulkSEOMetadata queues a job that fills in the SEO titles,
+// descriptions and slugs articles lack with the AI provider. Its progress
+// is followed through the jobs API; unless apply is set, the results wait
+// in the proposals for review.
+func (ctrl *SEOController) QueueBulkSEOMetadata(c *gin.Context) {
+	var options services.SEOBulkOptions
+	if c.Request.ContentLength > 0 {
+		if !bindJSON(c, &options) {
+			return
+		}
+	}
+	job, err := services.GetGlobalSEOBulkService().Queue(c.Request.Context(), currentSiteID(c), options)
+	if err != nil {
+		respondSEOBulkError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListSEOMetadataProposals lists the proposals of bulk generation jobs,
+// newest first. status defaults to pending.
+func (ctrl *SEOController) ListSEOMetadataProposals(c *gin.Context) {
+	slice, ok := bindLimit(c, 0, 0)
+	if !ok {
+		return
+	}
+	proposals, err := services.GetGlobalSEOBulkService().Proposals(c.Request.Context(), currentSiteID(c),
+		c.DefaultQuery("status", services.SEOProposalPending), slice.Limit)
+	if err != nil {
+		respondSEOBulkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"proposals": proposals})
+}
+
+// ApplySEOMetadataProposals writes pending proposals to their articles
+func (ctrl *SEOController) ApplySEOMetadataProposals(c *gin.Context) {
+	decideSEOMetadataProposals(c, services.SEOProposalApplied)
+}
+
+// DismissSEOMetadataProposals discards pending proposals, so their
+// articles are picked up by the next job again
+func (ctrl *SEOController) DismissSEOMetadataProposals(c *gin.Context) {
+	decideSEOMetadataProposals(c, services.SEOProposalDismissed)
+}
+
+func decideSEOMetadataProposals(c *gin.Context, status string) {
+	var req struct {
+		IDs []uint `json:"ids" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	proposals, err := services.GetGlobalSEOBulkService().Decide(c.Request.Context(), currentSiteID(c), req.IDs, status)
+	if err != nil {
+		respondSEOBulkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"proposals": proposals})
+}
+
+func respondSEOBulkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSEOProposalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidSEOBulk):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNoArticlesMissingSEO):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLLMNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Bulk SEO metadata operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Bulk SEO metadata operation failed"})
+	}
+}
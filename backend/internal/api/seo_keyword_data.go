@@ -0,0 +1,35 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetKeywordDataStatus reports the keyword data provider, its cost this
+// month and how many keywords have stale search volume and difficulty
+func (ctrl *SEOController) GetKeywordDataStatus(c *gin.Context) {
+	status, err := services.GetGlobalKeywordDataService().Status()
+	if err != nil {
+		logging.FromGin(c).Error("Failed to read keyword data status", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read keyword data status"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// RefreshKeywordData queues a run of the keyword data schedule now
+func (ctrl *SEOController) RefreshKeywordData(c *gin.Context) {
+	if !services.GetGlobalKeywordDataService().Enabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrKeywordDataNotConfigured.Error()})
+		return
+	}
+	job, err := services.GetGlobalScheduler().Trigger(services.KeywordDataScheduleName)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetKeywordMapping maps the tracked keywords to the articles that match
+// them best, flagging keywords several articles compete for and those no
+// article covers. Query: language, threshold, margin.
+func (ctrl *SEOController) GetKeywordMapping(c *gin.Context) {
+	opts := services.KeywordMappingOptions{Language: c.Query("language")}
+	for name, target := range map[string]*float64{"threshold": &opts.Threshold, "margin": &opts.Margin} {
+		if raw := c.Query(name); raw != "" {
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+				return
+			}
+			*target = value
+		}
+	}
+
+	report, err := services.GetGlobalKeywordMappingService().Map(c.Request.Context(), opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidKeywordMapping):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrKeywordEmbedding):
+			logging.FromGin(c).Warn("Failed to embed keywords for mapping", "error", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			logging.FromGin(c).Error("Failed to map keywords", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to map keywords"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
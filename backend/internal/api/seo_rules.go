@@ -0,0 +1,55 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSEORules lists the SEO analysis rules of every language with built-in
+// or saved ones, the default rules first
+func (ctrl *SEOController) GetSEORules(c *gin.Context) {
+	rules, err := services.GetGlobalSEORuleService().List()
+	if err != nil {
+		respondSEORuleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// UpdateSEORules replaces the SEO analysis rules of a language, or the
+// default rules for "default"
+func (ctrl *SEOController) UpdateSEORules(c *gin.Context) {
+	var req services.SEORules
+	if !bindJSON(c, &req) {
+		return
+	}
+	rules, err := services.GetGlobalSEORuleService().Save(c.Param("lang"), req)
+	if err != nil {
+		respondSEORuleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// ResetSEORules goes back to the built-in SEO analysis rules of a language
+func (ctrl *SEOController) ResetSEORules(c *gin.Context) {
+	rules, err := services.GetGlobalSEORuleService().Reset(c.Param("lang"))
+	if err != nil {
+		respondSEORuleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+func respondSEORuleError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidSEORules) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logging.FromGin(c).Error("SEO rule operation failed", "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "SEO rule operation failed"})
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"blog-backend/internal/auth"
+	"blog-backend/internal/database"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+	"time"
+)
+
+// loginSessionRetention is how long expired login sessions stay in the
+// session history before they are purged
+const loginSessionRetention = 30 * 24 * time.Hour
+
+// startLoginSession records a login session, checks it against the user's
+// known devices and IPs, sends an alert for unseen ones and returns a token
+func startLoginSession(c *gin.Context, user models.User, method string) (string, error) {
+	revokeToken := make([]byte, 32)
+	if _, err := rand.Read(revokeToken); err != nil {
+		return "", err
+	}
+	revokeTokenHex := hex.EncodeToString(revokeToken)
+
+	ip := getClientIP(c)
+	userAgent := c.GetHeader("User-Agent")
+	fingerprint := loginDeviceFingerprint(userAgent, c.GetHeader("X-Device-Fingerprint"))
+	uaInfo := services.ParseUserAgent(userAgent)
+
+	session := models.LoginSession{
+		UserID:          user.ID,
+		SessionID:       uuid.New().String(),
+		RevokeTokenHash: hashRevokeToken(revokeTokenHex),
+		IPAddress:       ip,
+		UserAgent:       truncateString(userAgent, 500),
+		Fingerprint:     fingerprint,
+		Browser:         uaInfo.Browser,
+		OS:              uaInfo.OS,
+		LoginMethod:     method,
+		ExpiresAt:       time.Now().Add(auth.TokenLifetime),
+	}
+	session.NewDevice, session.NewIP = recordLoginDevice(user.ID, fingerprint, ip, session.UserAgent)
+
+	if err := database.DB.Create(&session).Error; err != nil {
+		return "", err
+	}
+
+	token, err := auth.GenerateSessionToken(user.ID, user.Username, user.IsAdmin, user.SiteID, session.SessionID)
+	if err != nil {
+		return "", err
+	}
+
+	if session.NewDevice || session.NewIP {
+		// The login goes to the notification center even without an alert
+		// channel configured
+		alerts := services.GetGlobalSecurityAlertService()
+		revokeURL := fmt.Sprintf("%s/api/sessions/revoke?token=%s", getBaseURL(c), revokeTokenHex)
+		go func() {
+			geo := services.GetGeoIPWithCache(ip)
+			location := ""
+			if geo.Country != "Unknown" {
+				location = fmt.Sprintf("%s, %s", geo.City, geo.Country)
+				database.DB.Model(&models.LoginSession{}).Where("id = ?", session.ID).Update("location", location)
+			}
+			alerts.SendLoginAlert(services.LoginAlert{
+				Username:    user.Username,
+				IPAddress:   ip,
+				Location:    location,
+				Browser:     uaInfo.Browser,
+				OS:          uaInfo.OS,
+				UserAgent:   userAgent,
+				LoginMethod: method,
+				NewDevice:   session.NewDevice,
+				NewIP:       session.NewIP,
+				Time:        session.CreatedAt,
+				RevokeURL:   revokeURL,
+			})
+		}()
+		logging.FromGin(c).Warn("Login from unrecognized "+describeNovelty(session), "username", user.Username, "ip", ip)
+	}
+
+	return token, nil
+}
+
+// recordLoginDevice updates the user's known devices and reports whether the
+// fingerprint and IP were new. A user's first ever login sets the baseline.
+func recordLoginDevice(userID uint, fingerprint, ip, userAgent string) (bool, bool) {
+	var known int64
+	database.DB.Model(&models.KnownLoginDevice{}).Where("user_id = ?", userID).Count(&known)
+
+	var fingerprintSeen, ipSeen int64
+	database.DB.Model(&models.KnownLoginDevice{}).Where("user_id = ? AND fingerprint = ?", userID, fingerprint).Count(&fingerprintSeen)
+	database.DB.Model(&models.KnownLoginDevice{}).Where("user_id = ? AND ip_address = ?", userID, ip).Count(&ipSeen)
+
+	now := time.Now()
+	var device models.KnownLoginDevice
+	err := database.DB.Where("user_id = ? AND fingerprint = ? AND ip_address = ?", userID, fingerprint, ip).First(&device).Error
+	if err != nil {
+		database.DB.Create(&models.KnownLoginDevice{
+			UserID:      userID,
+			Fingerprint: fingerprint,
+			IPAddress:   ip,
+			UserAgent:   userAgent,
+			FirstSeenAt: now,
+			LastSeenAt:  now,
+		})
+	} else {
+		database.DB.Model(&device).Update("last_seen_at", now)
+	}
+
+	if known == 0 {
+		return false, false
+	}
+	return fingerprintSeen == 0, ipSeen == 0
+}
+
+// loginDeviceFingerprint hashes the user agent with an optional client-side fingerprint
+func loginDeviceFingerprint(userAgent, clientFingerprint string) string {
+	hash := sha256.Sum256([]byte(userAgent + "|" + clientFingerprint))
+	return hex.EncodeToString(hash[:])
+}
+
+func hashRevokeToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func describeNovelty(session models.LoginSession) string {
+	switch {
+	case session.NewDevice && session.NewIP:
+		return "device and IP"
+	case session.NewDevice:
+		return "device"
+	default:
+		return "IP"
+	}
+}
+
+func truncateString(value string, maxLen int) string {
+	if len(value) > maxLen {
+		return value[:maxLen]
+	}
+	return value
+}
+
+// ListSessions returns the current user's recent login sessions
+func ListSessions(c *gin.Context) {
+	var sessions []models.LoginSession
+	if err := database.DB.Where("user_id = ?", c.GetUint("userID")).Order("created_at DESC").Limit(50).Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentSessionID := c.GetString("sessionID")
+	result := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, gin.H{
+			"session": session,
+			"current": session.SessionID == currentSessionID,
+			"active":  session.RevokedAt == nil && session.ExpiresAt.After(time.Now()),
+		})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RevokeSession revokes one of the current user's sessions
+func RevokeSession(c *gin.Context) {
+	now := time.Now()
+	result := database.DB.Model(&models.LoginSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("id"), c.GetUint("userID")).
+		Update("revoked_at", &now)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// RevokeSessionByLink handles the one-click revoke link from login alerts
+func RevokeSessionByLink(c *gin.Context) {
+	token := c.Query("token")
+	if len(token) != 64 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revoke token"})
+		return
+	}
+
+	var session models.LoginSession
+	if err := database.DB.Where("revoke_token_hash = ?", hashRevokeToken(token)).First(&session).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+		if err := database.DB.Save(&session).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+			return
+		}
+		logging.FromGin(c).Info("Session revoked via alert link", "session_id", session.ID, "user_id", session.UserID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked. Change your password if you did not sign in from this device.",
+	})
+}
+
+// purgeLoginSessions deletes sessions that expired more than
+// loginSessionRetention ago
+func purgeLoginSessions() (int64, error) {
+	cutoff := time.Now().Add(-loginSessionRetention)
+	result := database.DB.Where("expires_at < ?", cutoff).Delete(&models.LoginSession{})
+	return result.RowsAffected, result.Error
+}
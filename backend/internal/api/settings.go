@@ -1,10 +1,15 @@
 package api
 
 import (
+	"blog-backend/internal/cache"
 	"blog-backend/internal/database"
+	"blog-backend/internal/events"
+	"blog-backend/internal/hooks"
 	"blog-backend/internal/models"
 	"blog-backend/internal/security"
+	"blog-backend/internal/services"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -31,7 +36,7 @@ func getDefaultLanguage() string {
 
 func GetSettings(c *gin.Context) {
 	var settings models.SiteSettings
-	if err := database.DB.Preload("Translations").First(&settings).Error; err != nil {
+	if err := siteDB(c).Preload("Translations").First(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -88,41 +93,63 @@ func applySettingsTranslation(settings *models.SiteSettings, lang string) {
 
 func UpdateSettings(c *gin.Context) {
 	var settings models.SiteSettings
-	if err := database.DB.Preload("Translations").First(&settings).Error; err != nil {
+	if err := siteDB(c).Preload("Translations").First(&settings).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Settings not found"})
 		return
 	}
 
 	var input struct {
-		SiteTitle          string `json:"site_title"`
-		SiteSubtitle       string `json:"site_subtitle"`
-		FooterText         string `json:"footer_text"`
-		ICPFiling          string `json:"icp_filing"`
-		PSBFiling          string `json:"psb_filing"`
-		ShowViewCount      *bool  `json:"show_view_count"`
-		ShowSiteTitle      *bool  `json:"show_site_title"`
-		EnableSoundEffects *bool  `json:"enable_sound_effects"`
-		DefaultLanguage    string `json:"default_language"`
-		LogoURL            string `json:"logo_url"`
-		FaviconURL         string `json:"favicon_url"`
-		CustomCSS          string `json:"custom_css"`
-		CustomJS           string `json:"custom_js"`
-		ThemeConfig        string `json:"theme_config"`
-		ActiveTheme        string `json:"active_theme"`
+		SiteTitle          string  `json:"site_title"`
+		SiteSubtitle       string  `json:"site_subtitle"`
+		FooterText         string  `json:"footer_text"`
+		ICPFiling          string  `json:"icp_filing"`
+		PSBFiling          string  `json:"psb_filing"`
+		ShowViewCount      *bool   `json:"show_view_count"`
+		ShowSiteTitle      *bool   `json:"show_site_title"`
+		EnableSoundEffects *bool   `json:"enable_sound_effects"`
+		DefaultLanguage    string  `json:"default_language"`
+		LogoURL            string  `json:"logo_url"`
+		LogoDarkURL        *string `json:"logo_dark_url"`
+		FaviconURL         string  `json:"favicon_url"`
+		FaviconSetURL      *string `json:"favicon_set_url"`
+		CustomCSS          string  `json:"custom_css"`
+		CustomJS           string  `json:"custom_js"`
+		ThemeConfig        string  `json:"theme_config"`
+		ActiveTheme        string  `json:"active_theme"`
 		// Background Settings
 		BackgroundType     string   `json:"background_type"`
 		BackgroundColor    string   `json:"background_color"`
 		BackgroundImageURL string   `json:"background_image_url"`
 		BackgroundOpacity  *float64 `json:"background_opacity"`
 		AIConfig           string   `json:"ai_config"`
+		// Public statistics
+		StatsShowPosts       *bool `json:"stats_show_posts"`
+		StatsShowWords       *bool `json:"stats_show_words"`
+		StatsShowDaysRunning *bool `json:"stats_show_days_running"`
+		StatsShowViews       *bool `json:"stats_show_views"`
+		StatsShowLastUpdated *bool `json:"stats_show_last_updated"`
+		// Donations
+		DonationsOnArticles *bool `json:"donations_on_articles"`
+		// View counting
+		ViewDedupHours  *int  `json:"view_dedup_hours"`
+		CountAdminViews *bool `json:"count_admin_views"`
+		// License of articles that do not choose one
+		DefaultLicense       *string `json:"default_license"`
+		DefaultLicenseCustom string  `json:"default_license_custom"`
+		// Articles that can be pinned at once
+		MaxPinnedArticles *int `json:"max_pinned_articles"`
+		// Search engine site verification
+		GoogleSiteVerification *string `json:"google_site_verification"`
+		BingSiteVerification   *string `json:"bing_site_verification"`
+		BaiduSiteVerification  *string `json:"baidu_site_verification"`
+		YandexSiteVerification *string `json:"yandex_site_verification"`
 		// Privacy and Indexing Control
 		BlockSearchEngines *bool                            `json:"block_search_engines"`
 		BlockAITraining    *bool                            `json:"block_ai_training"`
 		Translations       []models.SiteSettingsTranslation `json:"translations"`
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &input) {
 		return
 	}
 
@@ -145,7 +172,27 @@ func UpdateSettings(c *gin.Context) {
 		settings.DefaultLanguage = input.DefaultLanguage
 	}
 	settings.LogoURL = input.LogoURL
+	if input.LogoDarkURL != nil {
+		settings.LogoDarkURL = *input.LogoDarkURL
+	}
 	settings.FaviconURL = input.FaviconURL
+	if input.FaviconSetURL != nil {
+		settings.FaviconSetURL = *input.FaviconSetURL
+	}
+	// Custom code runs on every page; changes pass the security scan and
+	// are audited
+	customCode := services.GetGlobalCustomCodeService()
+	review := customCode.Review(settings.SiteID,
+		services.CustomCodeEdit{Field: services.CustomCodeCSS, Previous: settings.CustomCSS, Code: input.CustomCSS},
+		services.CustomCodeEdit{Field: services.CustomCodeJS, Previous: settings.CustomJS, Code: input.CustomJS})
+	actor := services.CustomCodeActor{UserID: c.GetUint("userID"), IPAddress: getClientIP(c), UserAgent: c.Request.UserAgent()}
+	if review.Blocked() {
+		if err := customCode.Record(c.Request.Context(), review, actor, models.CustomCodeRejected); err != nil {
+			log.Printf("Failed to audit custom code change: %v", err)
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": services.ErrUnsafeCustomCode.Error(), "findings": review.Findings})
+		return
+	}
 	settings.CustomCSS = input.CustomCSS
 	settings.CustomJS = input.CustomJS
 	settings.ThemeConfig = input.ThemeConfig
@@ -169,6 +216,73 @@ func UpdateSettings(c *gin.Context) {
 		settings.BlockAITraining = *input.BlockAITraining
 	}
 
+	// Update which statistics are public
+	if input.StatsShowPosts != nil {
+		settings.StatsShowPosts = *input.StatsShowPosts
+	}
+	if input.StatsShowWords != nil {
+		settings.StatsShowWords = *input.StatsShowWords
+	}
+	if input.StatsShowDaysRunning != nil {
+		settings.StatsShowDaysRunning = *input.StatsShowDaysRunning
+	}
+	if input.StatsShowViews != nil {
+		settings.StatsShowViews = *input.StatsShowViews
+	}
+	if input.StatsShowLastUpdated != nil {
+		settings.StatsShowLastUpdated = *input.StatsShowLastUpdated
+	}
+	if input.DonationsOnArticles != nil {
+		settings.DonationsOnArticles = *input.DonationsOnArticles
+	}
+	if input.ViewDedupHours != nil {
+		if *input.ViewDedupHours < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "view_dedup_hours cannot be negative"})
+			return
+		}
+		settings.ViewDedupHours = *input.ViewDedupHours
+	}
+	if input.CountAdminViews != nil {
+		settings.CountAdminViews = *input.CountAdminViews
+	}
+	if input.DefaultLicense != nil {
+		license, custom, err := services.NormalizeLicense(*input.DefaultLicense, input.DefaultLicenseCustom, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		settings.DefaultLicense, settings.DefaultLicenseCustom = license, custom
+	}
+	if input.MaxPinnedArticles != nil {
+		if err := services.ValidateMaxPinned(*input.MaxPinnedArticles); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		settings.MaxPinnedArticles = *input.MaxPinnedArticles
+	}
+
+	// Update the site verification codes of search engines
+	for _, verification := range []struct {
+		engine string
+		input  *string
+		code   *string
+	}{
+		{"google", input.GoogleSiteVerification, &settings.GoogleSiteVerification},
+		{"bing", input.BingSiteVerification, &settings.BingSiteVerification},
+		{"baidu", input.BaiduSiteVerification, &settings.BaiduSiteVerification},
+		{"yandex", input.YandexSiteVerification, &settings.YandexSiteVerification},
+	} {
+		if verification.input == nil {
+			continue
+		}
+		code, err := services.NormalizeVerificationCode(verification.engine, *verification.input)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		*verification.code = code
+	}
+
 	// Update AI configuration with encryption
 	if input.AIConfig != "" {
 		aiConfigService := security.GetGlobalAIConfigService()
@@ -199,6 +313,10 @@ func UpdateSettings(c *gin.Context) {
 			secureConfig, err = aiConfigService.EncryptAIConfig(&inputAIConfig)
 		}
 
+		if errors.Is(err, security.ErrSecretRefNotAllowed) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process AI configuration: " + err.Error()})
 			return
@@ -217,24 +335,41 @@ func UpdateSettings(c *gin.Context) {
 		settings.AIConfig = ""
 	}
 
-	if err := database.DB.Save(&settings).Error; err != nil {
+	if err := siteDB(c).Save(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if err := customCode.Record(c.Request.Context(), review, actor, models.CustomCodeSaved); err != nil {
+		log.Printf("Failed to audit custom code change: %v", err)
+	}
+
+	// Unchanged translations keep the source they were made from, changed
+	// ones are stamped with the current one
+	previous := make(map[string]models.SiteSettingsTranslation, len(settings.Translations))
+	for _, translation := range settings.Translations {
+		previous[translation.Language] = translation
+	}
 
 	// Delete existing translations
-	database.DB.Where("settings_id = ?", settings.ID).Delete(&models.SiteSettingsTranslation{})
+	siteDB(c).Where("settings_id = ?", settings.ID).Delete(&models.SiteSettingsTranslation{})
 
 	// Create new translations
 	for _, translation := range input.Translations {
 		if translation.SiteTitle != "" || translation.SiteSubtitle != "" {
 			translation.SettingsID = settings.ID
-			database.DB.Create(&translation)
+			translation.SourceHash = settings.SourceHash()
+			if old, ok := previous[translation.Language]; ok && old.SiteTitle == translation.SiteTitle && old.SiteSubtitle == translation.SiteSubtitle {
+				translation.SourceHash = old.SourceHash
+				translation.MachineTranslated = translation.MachineTranslated || old.MachineTranslated
+			}
+			siteDB(c).Create(&translation)
 		}
 	}
 
 	// Reload with translations
-	database.DB.Preload("Translations").First(&settings)
+	siteDB(c).Preload("Translations").First(&settings)
+	cache.Publish(cache.TopicSettings)
+	dispatchSettingsChanged(c, settings)
 
 	// Always reload embedding service when settings are updated
 	// This ensures AI configuration changes are applied immediately
@@ -260,11 +395,40 @@ func UpdateSettings(c *gin.Context) {
 	c.JSON(http.StatusOK, settings)
 }
 
+// dispatchSettingsChanged tells the settings.changed hooks and subscribers
+// about saved settings. The hooks include webhook plugins, so the AI
+// configuration, which holds provider keys, is left out.
+// ListCustomCodeAudits lists the changes to the site's custom CSS and
+// JavaScript, saved and refused, newest first
+func ListCustomCodeAudits(c *gin.Context) {
+	slice, ok := bindLimit(c, 0, 0)
+	if !ok {
+		return
+	}
+	audits, err := services.GetGlobalCustomCodeService().Audits(c.Request.Context(), currentSiteID(c), slice.Limit)
+	if err != nil {
+		log.Printf("Failed to list custom code audits: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list custom code audits"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"audits": audits})
+}
+
+func dispatchSettingsChanged(c *gin.Context, settings models.SiteSettings) {
+	settings.AIConfig = ""
+	events.Dispatch(c.Request.Context(), hooks.SettingsChanged, &settings)
+}
+
 // UploadLogo handles logo file upload
 func UploadLogo(c *gin.Context) {
 	uploadBrandingFile(c, "logo")
 }
 
+// UploadDarkLogo handles upload of the logo shown in dark mode
+func UploadDarkLogo(c *gin.Context) {
+	uploadBrandingFile(c, "logo_dark")
+}
+
 // UploadFavicon handles favicon file upload
 func UploadFavicon(c *gin.Context) {
 	uploadBrandingFile(c, "favicon")
@@ -278,22 +442,23 @@ func UploadBackgroundImage(c *gin.Context) {
 // RemoveBackgroundImage removes the current background image
 func RemoveBackgroundImage(c *gin.Context) {
 	var settings models.SiteSettings
-	if err := database.DB.First(&settings).Error; err != nil {
+	if err := siteDB(c).First(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find settings"})
 		return
 	}
 
 	// Remove the file if exists
 	if settings.BackgroundImageURL != "" {
+		subDir := siteUploadDir(c, "backgrounds")
 		var fileName string
-		if strings.HasPrefix(settings.BackgroundImageURL, "/api/uploads/backgrounds/") {
-			fileName = strings.TrimPrefix(settings.BackgroundImageURL, "/api/uploads/backgrounds/")
+		if strings.HasPrefix(settings.BackgroundImageURL, "/api/uploads/"+subDir+"/") {
+			fileName = strings.TrimPrefix(settings.BackgroundImageURL, "/api/uploads/"+subDir+"/")
 		} else {
-			fileName = strings.TrimPrefix(settings.BackgroundImageURL, "/uploads/backgrounds/")
+			fileName = strings.TrimPrefix(settings.BackgroundImageURL, "/uploads/"+subDir+"/")
 		}
 
 		if fileName != "" {
-			uploadDir := filepath.Join(UploadDir, "backgrounds")
+			uploadDir := filepath.Join(UploadDir, subDir)
 			filePath := filepath.Join(uploadDir, fileName)
 			os.Remove(filePath)
 		}
@@ -303,10 +468,11 @@ func RemoveBackgroundImage(c *gin.Context) {
 	settings.BackgroundImageURL = ""
 	settings.BackgroundType = "none"
 
-	if err := database.DB.Save(&settings).Error; err != nil {
+	if err := siteDB(c).Save(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
 		return
 	}
+	dispatchSettingsChanged(c, settings)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Background image removed successfully"})
 }
@@ -320,7 +486,7 @@ func uploadBrandingFile(c *gin.Context, fileType string) {
 
 	// Validate file type
 	allowedTypes := make(map[string][]string)
-	if fileType == "logo" {
+	if fileType == "logo" || fileType == "logo_dark" {
 		allowedTypes["image"] = []string{".png", ".jpg", ".jpeg", ".svg", ".webp"}
 	} else if fileType == "favicon" {
 		allowedTypes["icon"] = []string{".ico", ".png", ".svg"}
@@ -364,6 +530,7 @@ func uploadBrandingFile(c *gin.Context, fileType string) {
 	} else {
 		uploadSubDir = "branding"
 	}
+	uploadSubDir = siteUploadDir(c, uploadSubDir)
 	uploadDir := filepath.Join(UploadDir, uploadSubDir)
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
@@ -386,7 +553,7 @@ func uploadBrandingFile(c *gin.Context, fileType string) {
 
 	// Update settings
 	var settings models.SiteSettings
-	if err := database.DB.First(&settings).Error; err != nil {
+	if err := siteDB(c).First(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find settings"})
 		return
 	}
@@ -396,39 +563,50 @@ func uploadBrandingFile(c *gin.Context, fileType string) {
 	if fileType == "logo" {
 		if settings.LogoURL != "" {
 			// Handle both old and new URL patterns
-			if strings.HasPrefix(settings.LogoURL, "/api/uploads/branding/") {
-				oldFile = strings.TrimPrefix(settings.LogoURL, "/api/uploads/branding/")
+			if strings.HasPrefix(settings.LogoURL, "/api/uploads/"+uploadSubDir+"/") {
+				oldFile = strings.TrimPrefix(settings.LogoURL, "/api/uploads/"+uploadSubDir+"/")
 			} else {
-				oldFile = strings.TrimPrefix(settings.LogoURL, "/uploads/branding/")
+				oldFile = strings.TrimPrefix(settings.LogoURL, "/uploads/"+uploadSubDir+"/")
 			}
 		}
 		settings.LogoURL = fileURL
+	} else if fileType == "logo_dark" {
+		if settings.LogoDarkURL != "" {
+			// Handle both old and new URL patterns
+			if strings.HasPrefix(settings.LogoDarkURL, "/api/uploads/"+uploadSubDir+"/") {
+				oldFile = strings.TrimPrefix(settings.LogoDarkURL, "/api/uploads/"+uploadSubDir+"/")
+			} else {
+				oldFile = strings.TrimPrefix(settings.LogoDarkURL, "/uploads/"+uploadSubDir+"/")
+			}
+		}
+		settings.LogoDarkURL = fileURL
 	} else if fileType == "favicon" {
 		if settings.FaviconURL != "" {
 			// Handle both old and new URL patterns
-			if strings.HasPrefix(settings.FaviconURL, "/api/uploads/branding/") {
-				oldFile = strings.TrimPrefix(settings.FaviconURL, "/api/uploads/branding/")
+			if strings.HasPrefix(settings.FaviconURL, "/api/uploads/"+uploadSubDir+"/") {
+				oldFile = strings.TrimPrefix(settings.FaviconURL, "/api/uploads/"+uploadSubDir+"/")
 			} else {
-				oldFile = strings.TrimPrefix(settings.FaviconURL, "/uploads/branding/")
+				oldFile = strings.TrimPrefix(settings.FaviconURL, "/uploads/"+uploadSubDir+"/")
 			}
 		}
 		settings.FaviconURL = fileURL
 	} else if fileType == "background" {
 		if settings.BackgroundImageURL != "" {
 			// Handle both old and new URL patterns
-			if strings.HasPrefix(settings.BackgroundImageURL, "/api/uploads/backgrounds/") {
-				oldFile = strings.TrimPrefix(settings.BackgroundImageURL, "/api/uploads/backgrounds/")
+			if strings.HasPrefix(settings.BackgroundImageURL, "/api/uploads/"+uploadSubDir+"/") {
+				oldFile = strings.TrimPrefix(settings.BackgroundImageURL, "/api/uploads/"+uploadSubDir+"/")
 			} else {
-				oldFile = strings.TrimPrefix(settings.BackgroundImageURL, "/uploads/backgrounds/")
+				oldFile = strings.TrimPrefix(settings.BackgroundImageURL, "/uploads/"+uploadSubDir+"/")
 			}
 		}
 		settings.BackgroundImageURL = fileURL
 	}
 
-	if err := database.DB.Save(&settings).Error; err != nil {
+	if err := siteDB(c).Save(&settings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
 		return
 	}
+	dispatchSettingsChanged(c, settings)
 
 	// Remove old file
 	if oldFile != "" {
@@ -438,7 +616,7 @@ func uploadBrandingFile(c *gin.Context, fileType string) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"url":     fileURL,
-		"message": fmt.Sprintf("%s uploaded successfully", strings.Title(fileType)),
+		"message": fmt.Sprintf("%s uploaded successfully", strings.Title(strings.ReplaceAll(fileType, "_", " "))),
 	})
 }
 
@@ -447,15 +625,24 @@ type LanguageConfig struct {
 	DefaultLanguage    string            `json:"default_language"`
 	EnabledLanguages   []string          `json:"enabled_languages"`
 	SupportedLanguages map[string]string `json:"supported_languages"`
+	// Directions maps each supported language to its text direction, ltr
+	// or rtl
+	Directions map[string]string `json:"directions"`
 }
 
 // GetLanguageConfig returns the current language configuration
 func GetLanguageConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, languageConfig(c))
+}
+
+// languageConfig returns the site's default language and the languages it
+// has content in
+func languageConfig(c *gin.Context) LanguageConfig {
 	var settings models.SiteSettings
-	if err := database.DB.Preload("Translations").First(&settings).Error; err != nil {
+	if err := siteDB(c).Preload("Translations").First(&settings).Error; err != nil {
 		log.Printf("Failed to get settings for language config: %v", err)
 		// Return fallback configuration
-		c.JSON(http.StatusOK, LanguageConfig{
+		config := LanguageConfig{
 			DefaultLanguage:  "zh",
 			EnabledLanguages: []string{"zh", "en", "ja", "ko", "es", "fr", "de", "ru", "ar"},
 			SupportedLanguages: map[string]string{
@@ -472,8 +659,9 @@ func GetLanguageConfig(c *gin.Context) {
 				"ar": "العربية (Arabic)",
 				"hi": "हिन्दी (Hindi)",
 			},
-		})
-		return
+		}
+		config.Directions = languageDirections(config.SupportedLanguages)
+		return config
 	}
 
 	defaultLanguage := settings.DefaultLanguage
@@ -512,7 +700,7 @@ func GetLanguageConfig(c *gin.Context) {
 	}
 
 	var articleLanguages []string
-	if err := database.DB.Model(&models.ArticleTranslation{}).
+	if err := siteDB(c).Model(&models.ArticleTranslation{}).
 		Distinct("language").
 		Where("TRIM(title) <> '' OR TRIM(content) <> '' OR TRIM(summary) <> ''").
 		Pluck("language", &articleLanguages).Error; err != nil {
@@ -540,11 +728,45 @@ func GetLanguageConfig(c *gin.Context) {
 		enabledLanguages = []string{defaultLanguage}
 	}
 
-	config := LanguageConfig{
+	return LanguageConfig{
 		DefaultLanguage:    defaultLanguage,
 		EnabledLanguages:   enabledLanguages,
 		SupportedLanguages: supportedLanguages,
+		Directions:         languageDirections(supportedLanguages),
+	}
+}
+
+// languageDirections returns the text direction of each language
+func languageDirections(languages map[string]string) map[string]string {
+	directions := make(map[string]string, len(languages))
+	for language := range languages {
+		directions[language] = models.LanguageDirection(language)
+	}
+	return directions
+}
+
+// visitorLanguageCookie is the cookie the frontend keeps a visitor's chosen
+// language in
+const visitorLanguageCookie = "NEXT_LOCALE"
+
+// NegotiateLanguage returns the content language to serve a visitor, from
+// the stored preference cookie, Accept-Language and the visitor's country,
+// falling back to the site default. The SSR layer forwards the visitor's
+// headers; ?preference= stands in for the cookie when it cannot.
+func NegotiateLanguage(c *gin.Context) {
+	config := languageConfig(c)
+	preference := c.Query("preference")
+	if preference == "" {
+		preference, _ = c.Cookie(visitorLanguageCookie)
 	}
+	country := func() string { return visitorCountry(c) }
+	language := services.NegotiateLanguage(config.EnabledLanguages, config.DefaultLanguage, preference, c.GetHeader("Accept-Language"), country)
 
-	c.JSON(http.StatusOK, config)
+	c.Header("Vary", "Accept-Language, Cookie")
+	c.JSON(http.StatusOK, gin.H{
+		"language":          language.Language,
+		"source":            language.Source,
+		"default_language":  config.DefaultLanguage,
+		"enabled_languages": config.EnabledLanguages,
+	})
 }
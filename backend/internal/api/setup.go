@@ -1,7 +1,6 @@
 package api
 
 import (
-	"blog-backend/internal/database"
 	"blog-backend/internal/models"
 	"log"
 	"net/http"
@@ -32,7 +31,7 @@ type SetupResponse struct {
 // GetSetupStatus checks if the initial setup has been completed
 func GetSetupStatus(c *gin.Context) {
 	var settings models.SiteSettings
-	if err := database.DB.First(&settings).Error; err != nil {
+	if err := siteDB(c).First(&settings).Error; err != nil {
 		// If no settings exist, setup is not completed
 		c.JSON(http.StatusOK, SetupStatusResponse{
 			SetupCompleted: false,
@@ -51,7 +50,7 @@ func InitializeSetup(c *gin.Context) {
 
 	// Check if setup is already completed
 	var existingSettings models.SiteSettings
-	if err := database.DB.First(&existingSettings).Error; err == nil && existingSettings.SetupCompleted {
+	if err := siteDB(c).First(&existingSettings).Error; err == nil && existingSettings.SetupCompleted {
 		log.Printf("⚠️  Setup already completed, rejecting request")
 		c.JSON(http.StatusBadRequest, SetupResponse{
 			Success: false,
@@ -101,7 +100,7 @@ func InitializeSetup(c *gin.Context) {
 
 	// Start transaction
 	log.Printf("🔄 Starting database transaction for setup")
-	tx := database.DB.Begin()
+	tx := siteDB(c).Begin()
 	if tx.Error != nil {
 		log.Printf("❌ Failed to start database transaction: %v", tx.Error)
 		c.JSON(http.StatusInternalServerError, SetupResponse{
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetArticleShareCard returns an article's share metadata for WeChat,
+// Weibo and QQ. ?lang= picks the translation shared and ?url= is the
+// address of the page the WeChat JS-SDK runs on, which must be on the
+// site; it defaults to the article's canonical address.
+func GetArticleShareCard(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid article ID"})
+		return
+	}
+	var article models.Article
+	if err := siteDB(c).Preload("Translations").First(&article, id).Error; err != nil ||
+		(!isAdminRequest(c) && article.CreatedAt.After(time.Now())) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Article not found"})
+		return
+	}
+	if respondRegionWithheld(c, &article) {
+		return
+	}
+	language := c.DefaultQuery("lang", article.DefaultLang)
+	if len(language) > 10 || strings.ContainsAny(language, "/?#") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid language"})
+		return
+	}
+
+	baseURL := getBaseURL(c)
+	link := fmt.Sprintf("%s/%s/article/%s", baseURL, language, url.PathEscape(services.ArticleSlug(&article, language)))
+	pageURL := c.Query("url")
+	if pageURL != "" && !sameSiteURL(pageURL, baseURL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an address on this site"})
+		return
+	}
+
+	var settings models.SiteSettings
+	siteDB(c).Select("logo_url").Limit(1).Find(&settings)
+	if language != article.DefaultLang {
+		services.ApplyTranslation(&article, language)
+	}
+	content := services.ArticleShareContent(&article, language, link, baseURL, settings.LogoURL)
+
+	card, err := services.GetGlobalShareCardService().Card(c.Request.Context(), content, pageURL)
+	if err != nil {
+		// The share links and tags still work without the signature
+		logging.FromGin(c).Warn("Failed to sign the WeChat share card", "article_id", article.ID, "error", err)
+	}
+	c.JSON(http.StatusOK, card)
+}
+
+// sameSiteURL reports whether address is an http(s) address on the host
+// of baseURL
+func sameSiteURL(address, baseURL string) bool {
+	u, err := url.Parse(address)
+	base, baseErr := url.Parse(baseURL)
+	return err == nil && baseErr == nil && (u.Scheme == "http" || u.Scheme == "https") && strings.EqualFold(u.Host, base.Host)
+}
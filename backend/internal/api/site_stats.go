@@ -0,0 +1,27 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSiteStats returns the statistics the site has chosen to make public,
+// such as its number of posts and total views. Sites that expose none
+// answer 404.
+func GetSiteStats(c *gin.Context) {
+	stats, err := services.GetGlobalSiteStatsService().Get(c.Request.Context())
+	if errors.Is(err, services.ErrSiteStatsDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.FromGin(c).Error("Failed to compute site statistics", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute site statistics"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSiteVerification returns the search engine verification meta tags
+// for the frontend to render in the head of its pages
+func GetSiteVerification(c *gin.Context) {
+	meta, err := services.GetGlobalSiteVerificationService().Meta(c.Request.Context())
+	if err != nil {
+		logging.FromGin(c).Error("Failed to load site verification", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load site verification"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"meta": meta})
+}
+
+// ServeSiteVerificationFile answers requests no route matched with the
+// search engine verification file of that name, such as /BingSiteAuth.xml,
+// when the site's codes call for one
+func ServeSiteVerificationFile(c *gin.Context) {
+	name := strings.TrimPrefix(c.Request.URL.Path, "/")
+	if (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) && name != "" && !strings.Contains(name, "/") {
+		file, err := services.GetGlobalSiteVerificationService().File(c.Request.Context(), name)
+		if err != nil {
+			logging.FromGin(c).Error("Failed to load site verification", "error", err)
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		if file != nil {
+			c.Data(http.StatusOK, file.ContentType, []byte(file.Body))
+			return
+		}
+	}
+	c.String(http.StatusNotFound, "404 page not found")
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SiteMiddleware selects the site a request is for by its host when
+// multi-site mode is enabled. Like getBaseURL it honours X-Forwarded-Host,
+// so a reverse proxy in front of the backend must pass the original host.
+// Queries made through siteDB are then limited to that site.
+func SiteMiddleware() gin.HandlerFunc {
+	enabled := config.Get().Server.MultiSite
+	return func(c *gin.Context) {
+		if !enabled {
+			c.Next()
+			return
+		}
+		host := c.Request.Host
+		if forwardedHost := c.GetHeader("X-Forwarded-Host"); forwardedHost != "" {
+			host = forwardedHost
+		}
+		site, err := services.GetGlobalSiteService().Resolve(host)
+		if err != nil {
+			logging.FromGin(c).Error("Failed to resolve site", "host", host, "error", err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Site not available"})
+			return
+		}
+		c.Set("site", site)
+		c.Set("siteID", site.ID)
+		c.Request = c.Request.WithContext(database.WithSite(c.Request.Context(), site.ID))
+		c.Next()
+	}
+}
+
+// siteDB returns the database handle for a request, scoped to the request's
+// site in multi-site mode
+func siteDB(c *gin.Context) *gorm.DB {
+	return database.DB.WithContext(c.Request.Context())
+}
+
+// currentSite returns the site selected for the request, or nil when
+// multi-site mode is off
+func currentSite(c *gin.Context) *models.Site {
+	if site, ok := c.Get("site"); ok {
+		return site.(*models.Site)
+	}
+	return nil
+}
+
+// siteUploadDir returns the upload subdirectory for the request's site.
+// The default site keeps the original layout; other sites get their own
+// directory under sites/ so their media never collide.
+func siteUploadDir(c *gin.Context, subDir string) string {
+	if site := currentSite(c); site != nil && site.ID != models.DefaultSiteID {
+		return path.Join("sites", site.Slug, subDir)
+	}
+	return subDir
+}
+
+// requireDefaultSite allows managing sites only from the default site, so
+// administrators of hosted blogs cannot see or change each other
+func requireDefaultSite(c *gin.Context) bool {
+	if site := currentSite(c); site != nil && site.ID != models.DefaultSiteID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Sites can only be managed from the default site"})
+		return false
+	}
+	return true
+}
+
+// ListSites returns every site served by this instance
+func ListSites(c *gin.Context) {
+	if !requireDefaultSite(c) {
+		return
+	}
+	sites, err := services.GetGlobalSiteService().List()
+	if err != nil {
+		respondSiteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sites": sites, "multi_site": config.Get().Server.MultiSite})
+}
+
+// CreateSite adds a site with its settings and first administrator
+func CreateSite(c *gin.Context) {
+	if !requireDefaultSite(c) {
+		return
+	}
+	var req struct {
+		services.SiteInput
+		Admin services.SiteAdmin `json:"admin"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	site, err := services.GetGlobalSiteService().Create(req.SiteInput, req.Admin)
+	if err != nil {
+		respondSiteError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, site)
+}
+
+// UpdateSite changes a site's name, slug or host names
+func UpdateSite(c *gin.Context) {
+	if !requireDefaultSite(c) {
+		return
+	}
+	id, ok := siteID(c)
+	if !ok {
+		return
+	}
+	var input services.SiteInput
+	if !bindJSON(c, &input) {
+		return
+	}
+	site, err := services.GetGlobalSiteService().Update(id, input)
+	if err != nil {
+		respondSiteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, site)
+}
+
+// DeleteSite removes a site that has no content left
+func DeleteSite(c *gin.Context) {
+	if !requireDefaultSite(c) {
+		return
+	}
+	id, ok := siteID(c)
+	if !ok {
+		return
+	}
+	if err := services.GetGlobalSiteService().Delete(id); err != nil {
+		respondSiteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Site deleted"})
+}
+
+func siteID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid site ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondSiteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrSiteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSiteInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Delete the site's articles, categories and media first"})
+	case errors.Is(err, services.ErrInvalidSite):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Site operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Site operation failed"})
+	}
+}
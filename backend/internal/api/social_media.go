@@ -1,7 +1,6 @@
 package api
 
 import (
-	"blog-backend/internal/database"
 	"blog-backend/internal/models"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -13,7 +12,7 @@ func GetSocialMediaList(c *gin.Context) {
 	var socialMedia []models.SocialMedia
 
 	// Get only active links for public access
-	query := database.DB.Where("is_active = ?", true).Order("display_order ASC, id ASC")
+	query := siteDB(c).Where("is_active = ?", true).Order("display_order ASC, id ASC")
 
 	if err := query.Find(&socialMedia).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch social media links"})
@@ -27,7 +26,7 @@ func GetSocialMediaList(c *gin.Context) {
 func GetAllSocialMedia(c *gin.Context) {
 	var socialMedia []models.SocialMedia
 
-	if err := database.DB.Order("display_order ASC, id ASC").Find(&socialMedia).Error; err != nil {
+	if err := siteDB(c).Order("display_order ASC, id ASC").Find(&socialMedia).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch social media links"})
 		return
 	}
@@ -44,7 +43,7 @@ func GetSocialMedia(c *gin.Context) {
 	}
 
 	var socialMedia models.SocialMedia
-	if err := database.DB.First(&socialMedia, id).Error; err != nil {
+	if err := siteDB(c).First(&socialMedia, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Social media link not found"})
 		return
 	}
@@ -55,17 +54,16 @@ func GetSocialMedia(c *gin.Context) {
 // CreateSocialMedia creates a new social media link
 func CreateSocialMedia(c *gin.Context) {
 	var socialMedia models.SocialMedia
-	if err := c.ShouldBindJSON(&socialMedia); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &socialMedia) {
 		return
 	}
 
 	// Get the max display order and set new item to last
 	var maxOrder int
-	database.DB.Model(&models.SocialMedia{}).Select("COALESCE(MAX(display_order), 0)").Scan(&maxOrder)
+	siteDB(c).Model(&models.SocialMedia{}).Select("COALESCE(MAX(display_order), 0)").Scan(&maxOrder)
 	socialMedia.DisplayOrder = maxOrder + 1
 
-	if err := database.DB.Create(&socialMedia).Error; err != nil {
+	if err := siteDB(c).Create(&socialMedia).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create social media link"})
 		return
 	}
@@ -82,14 +80,13 @@ func UpdateSocialMedia(c *gin.Context) {
 	}
 
 	var socialMedia models.SocialMedia
-	if err := database.DB.First(&socialMedia, id).Error; err != nil {
+	if err := siteDB(c).First(&socialMedia, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Social media link not found"})
 		return
 	}
 
 	var updateData models.SocialMedia
-	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &updateData) {
 		return
 	}
 
@@ -100,7 +97,7 @@ func UpdateSocialMedia(c *gin.Context) {
 	socialMedia.DisplayOrder = updateData.DisplayOrder
 	socialMedia.IsActive = updateData.IsActive
 
-	if err := database.DB.Save(&socialMedia).Error; err != nil {
+	if err := siteDB(c).Save(&socialMedia).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update social media link"})
 		return
 	}
@@ -116,7 +113,7 @@ func DeleteSocialMedia(c *gin.Context) {
 		return
 	}
 
-	result := database.DB.Delete(&models.SocialMedia{}, id)
+	result := siteDB(c).Delete(&models.SocialMedia{}, id)
 	if result.Error != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete social media link"})
 		return
@@ -137,14 +134,13 @@ func UpdateSocialMediaOrder(c *gin.Context) {
 		Order int  `json:"order"`
 	}
 
-	if err := c.ShouldBindJSON(&orderData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &orderData) {
 		return
 	}
 
 	// Update each item's order
 	for _, item := range orderData {
-		database.DB.Model(&models.SocialMedia{}).Where("id = ?", item.ID).Update("display_order", item.Order)
+		siteDB(c).Model(&models.SocialMedia{}).Where("id = ?", item.ID).Update("display_order", item.Order)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Order updated successfully"})
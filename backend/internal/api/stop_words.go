@@ -0,0 +1,83 @@
+package api
+
+import (
+	"blog-backend/internal/keywords"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListStopWords lists the stop words of every language with built-in or
+// saved ones
+func ListStopWords(c *gin.Context) {
+	lists, err := services.GetGlobalStopWordService().Lists()
+	if err != nil {
+		respondStopWordError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"lists": lists})
+}
+
+// GetStopWords returns the stop words used for a language
+func GetStopWords(c *gin.Context) {
+	list, err := services.GetGlobalStopWordService().List(c.Param("lang"))
+	if err != nil {
+		respondStopWordError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// UpdateStopWords replaces the stop words of a language
+func UpdateStopWords(c *gin.Context) {
+	var req struct {
+		Words []string `json:"words" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	list, err := services.GetGlobalStopWordService().Save(c.Param("lang"), req.Words)
+	if err != nil {
+		respondStopWordError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// ResetStopWords goes back to the built-in stop words of a language
+func ResetStopWords(c *gin.Context) {
+	if err := services.GetGlobalStopWordService().Reset(c.Param("lang")); err != nil {
+		respondStopWordError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Stop words reset"})
+}
+
+// PreviewKeywords shows how text in a language is split into words and
+// which of them are kept as keywords, to try out a stop word list
+func PreviewKeywords(c *gin.Context) {
+	var req struct {
+		Text string `json:"text" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	language := c.Param("lang")
+	c.JSON(http.StatusOK, gin.H{
+		"language": keywords.BaseLanguage(language),
+		"words":    keywords.Tokenize(req.Text, language),
+		"keywords": services.ExtractKeywords(req.Text, language),
+	})
+}
+
+func respondStopWordError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidStopWords) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logging.FromGin(c).Error("Stop word operation failed", "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Stop word operation failed"})
+}
@@ -1,7 +1,14 @@
 package api
 
 import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/logging"
+	"blog-backend/internal/security"
+	"blog-backend/internal/services"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -66,9 +73,7 @@ type SystemInfo struct {
 }
 
 var (
-	dockerHubCache  *UpdateInfo
-	cacheExpiry     time.Time
-	cacheDuration   = 1 * time.Hour // Cache for 1 hour
+	updateInfoCache = cache.New("updates", 1*time.Hour) // Cache for 1 hour
 	dockerHubAPIURL = "https://hub.docker.com/v2/repositories/ictrun/kuno/tags"
 	dockerImageName = "ictrun/kuno"
 	gitHubAPIURL    = "https://api.github.com/repos/xuemian168/kuno/releases/latest"
@@ -92,8 +97,9 @@ func GetSystemInfo(c *gin.Context) {
 // CheckUpdates checks for available updates from Docker Hub
 func CheckUpdates(c *gin.Context) {
 	// Check cache first
-	if dockerHubCache != nil && time.Now().Before(cacheExpiry) {
-		c.JSON(http.StatusOK, dockerHubCache)
+	var cached UpdateInfo
+	if updateInfoCache.Get("latest", &cached) {
+		c.JSON(http.StatusOK, &cached)
 		return
 	}
 
@@ -110,8 +116,7 @@ func CheckUpdates(c *gin.Context) {
 	}
 
 	// Cache the result
-	dockerHubCache = updateInfo
-	cacheExpiry = time.Now().Add(cacheDuration)
+	updateInfoCache.Set("latest", updateInfo)
 
 	c.JSON(http.StatusOK, updateInfo)
 }
@@ -559,10 +564,148 @@ func getEnvOrDefault(key, defaultValue string) string {
 
 // ClearUpdateCache clears the update cache (for testing or manual refresh)
 func ClearUpdateCache(c *gin.Context) {
-	dockerHubCache = nil
-	cacheExpiry = time.Time{}
+	updateInfoCache.Clear()
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Update cache cleared",
 	})
 }
+
+// GetSecretsStatus reports which external secret providers are configured and
+// which references are cached (values are never returned)
+func GetSecretsStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, security.GetGlobalSecretManager().Status())
+}
+
+// RefreshSecrets re-fetches all cached secret references, applying any rotations
+func RefreshSecrets(c *gin.Context) {
+	failures := security.GetGlobalSecretManager().Refresh()
+	if len(failures) > 0 {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":    "Some secrets could not be refreshed",
+			"failures": failures,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Secrets refreshed successfully"})
+}
+
+// GetRuntimeConfig returns the effective configuration with secrets redacted,
+// along with the config file in use and where each setting came from
+func GetRuntimeConfig(c *gin.Context) {
+	cfg := config.Get()
+	response := gin.H{
+		"file":    cfg.File,
+		"config":  cfg.Redacted(),
+		"sources": cfg.Sources,
+	}
+	if err := config.LoadError(); err != nil {
+		response["error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetMigrationStatus lists versioned schema migrations and whether each is applied
+func GetMigrationStatus(c *gin.Context) {
+	statuses, err := database.GetMigrationStatus(database.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if !status.Applied {
+			pending++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"migrations": statuses,
+		"pending":    pending,
+	})
+}
+
+// GetDatabaseStats returns database file size, WAL size, pragma settings and
+// connection pool usage
+func GetDatabaseStats(c *gin.Context) {
+	stats, err := database.GetStats()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetDatabaseOptimize reports the optimization schedule and the progress of
+// the latest run
+func GetDatabaseOptimize(c *gin.Context) {
+	status, err := services.GetGlobalDatabaseOptimizer().Status()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// StartDatabaseOptimize queues an integrity check, ANALYZE and VACUUM, or
+// the steps given in the body. Progress is reported by GetDatabaseOptimize.
+func StartDatabaseOptimize(c *gin.Context) {
+	var req services.OptimizePayload
+	if c.Request.ContentLength > 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+	job, err := services.GetGlobalDatabaseOptimizer().Start(req.Steps)
+	switch {
+	case errors.Is(err, services.ErrUnknownOptimizeStep):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOptimizeRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		logging.FromGin(c).Error("Failed to queue database optimization", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue database optimization"})
+	default:
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// GetDiskUsage reports the size of the database and uploads, the volumes
+// holding them, their growth and any low disk warnings. Pass refresh=true
+// to measure again instead of using the last few minutes' report.
+func GetDiskUsage(c *gin.Context) {
+	usage, err := services.GetGlobalStorageService().Usage(c.Query("refresh") == "true")
+	if err != nil {
+		logging.FromGin(c).Error("Failed to measure disk usage", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to measure disk usage"})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// GetSiteMonitor reports the site monitor's settings and its latest check
+func GetSiteMonitor(c *gin.Context) {
+	status, err := services.GetGlobalSiteMonitor().Status()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// RunSiteMonitor checks the public URL and certificate now, raising any
+// alerts that are due, and returns the result
+func RunSiteMonitor(c *gin.Context) {
+	check, err := services.GetGlobalSiteMonitor().Check(c.Request.Context())
+	switch {
+	case errors.Is(err, services.ErrMonitorDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		logging.FromGin(c).Error("Failed to run site monitor", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run site monitor"})
+	default:
+		c.JSON(http.StatusOK, check)
+	}
+}
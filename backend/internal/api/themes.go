@@ -0,0 +1,156 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServeThemeAsset serves an image or font of an installed theme
+func ServeThemeAsset(c *gin.Context) {
+	asset, err := services.GetGlobalThemeService().Asset(c.Request.Context(), c.Param("name"), strings.TrimPrefix(c.Param("path"), "/"))
+	if err != nil {
+		respondThemeError(c, err)
+		return
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	// SVG images may carry scripts; they are not to run on the site
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, asset.ContentType, asset.Data)
+}
+
+// ListThemes returns the installed themes with their previews and the name
+// of the active theme
+func ListThemes(c *gin.Context) {
+	themes, active, err := services.GetGlobalThemeService().List(c.Request.Context())
+	if err != nil {
+		respondThemeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"themes": themes, "active": active})
+}
+
+// ExportCurrentTheme downloads the site's theme, with the theme settings
+// as they are now, as a theme package
+func ExportCurrentTheme(c *gin.Context) {
+	pkg, err := services.GetGlobalThemeService().PackageCurrent(c.Request.Context())
+	sendThemePackage(c, pkg, err)
+}
+
+// ExportTheme downloads an installed theme as a theme package
+func ExportTheme(c *gin.Context) {
+	pkg, err := services.GetGlobalThemeService().Package(c.Request.Context(), c.Param("name"))
+	sendThemePackage(c, pkg, err)
+}
+
+// ValidateTheme checks an uploaded theme package without installing it and
+// describes what it holds
+func ValidateTheme(c *gin.Context) {
+	pkg, ok := uploadedThemePackage(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"valid": true, "manifest": pkg.Manifest, "assets": pkg.Assets, "css_size": len(pkg.CSS)})
+}
+
+// ImportTheme installs an uploaded theme package, replacing the installed
+// theme of the same name. Activate it with ?activate=true.
+func ImportTheme(c *gin.Context) {
+	pkg, ok := uploadedThemePackage(c)
+	if !ok {
+		return
+	}
+	themes := services.GetGlobalThemeService()
+	theme, err := themes.Install(c.Request.Context(), pkg)
+	if err != nil {
+		respondThemeError(c, err)
+		return
+	}
+	if c.Query("activate") == "true" {
+		if err := themes.Activate(c.Request.Context(), theme.Name); err != nil {
+			respondThemeError(c, err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, theme)
+}
+
+// ActivateTheme makes an installed theme the site's theme
+func ActivateTheme(c *gin.Context) {
+	if err := services.GetGlobalThemeService().Activate(c.Request.Context(), c.Param("name")); err != nil {
+		respondThemeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Theme activated"})
+}
+
+// DeleteTheme removes an installed theme other than the active one
+func DeleteTheme(c *gin.Context) {
+	if err := services.GetGlobalThemeService().Delete(c.Request.Context(), c.Param("name")); err != nil {
+		respondThemeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Theme deleted"})
+}
+
+func uploadedThemePackage(c *gin.Context) (*services.ThemePackage, bool) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No theme package provided"})
+		return nil, false
+	}
+	if header.Size > services.MaxThemePackageSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Theme packages are at most %d MB", services.MaxThemePackageSize>>20)})
+		return nil, false
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return nil, false
+	}
+	defer file.Close()
+
+	pkg, err := services.ReadThemePackage(file, header.Size)
+	if err != nil {
+		respondThemeError(c, err)
+		return nil, false
+	}
+	return pkg, true
+}
+
+func sendThemePackage(c *gin.Context, pkg *services.ThemePackage, err error) {
+	if err != nil {
+		respondThemeError(c, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := pkg.Write(&buf); err != nil {
+		respondThemeError(c, err)
+		return
+	}
+	filename := pkg.Manifest.Name
+	if pkg.Manifest.Version != "" {
+		filename += "-" + pkg.Manifest.Version
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", services.SafeFilename(filename)))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+func respondThemeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrThemeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTheme), errors.Is(err, services.ErrUnsupportedTheme):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Theme operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Theme operation failed"})
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSimilarTopics returns the categories and tags closest to a topic,
+// compared by the centroids of their articles' embeddings
+func GetSimilarTopics(c *gin.Context) {
+	language, limit := topicQuery(c)
+	topics, err := services.GetGlobalTopicService().Similar(c.Request.Context(), c.Param("kind"), c.Param("key"), language, limit)
+	if err != nil {
+		respondTopicError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"topics": topics, "language": language})
+}
+
+// GetTopicArticles returns the articles of a topic closest to its
+// centroid, the ones that represent it best
+func GetTopicArticles(c *gin.Context) {
+	language, limit := topicQuery(c)
+	articles, err := services.GetGlobalTopicService().Representative(c.Request.Context(), c.Param("kind"), c.Param("key"), language, limit)
+	if err != nil {
+		respondTopicError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"articles": articles, "language": language})
+}
+
+// RebuildTopics queues a recomputation of the topic centroids, which
+// otherwise runs nightly
+func RebuildTopics(c *gin.Context) {
+	job, err := services.GetGlobalJobQueue().Enqueue(services.JobTopicCentroids, nil)
+	if err != nil {
+		respondTopicError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Topic rebuild queued", "job": job})
+}
+
+// topicQuery reads ?lang=, by default the site's article language, and
+// ?limit=, 10 by default and at most 50
+func topicQuery(c *gin.Context) (string, int) {
+	language := c.Query("lang")
+	if language == "" {
+		language = getArticleDefaultLanguage(c)
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+	return language, min(limit, 50)
+}
+
+func respondTopicError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTopicNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTopic):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Topic operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Topic operation failed"})
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTranslationFileSize is the largest translation file an import reads
+const maxTranslationFileSize = 20 << 20
+
+// translationFileTypes are the extension and content type of each
+// translation file format
+var translationFileTypes = map[string][2]string{
+	services.TranslationFormatXLIFF: {".xlf", "application/x-xliff+xml; charset=utf-8"},
+	services.TranslationFormatPO:    {".po", "text/x-gettext-translation; charset=utf-8"},
+}
+
+// ExportTranslations downloads the site's strings for translation into
+// ?language= as an XLIFF (?format=xliff, the default) or PO (?format=po)
+// file, with their current translations. ?missing=true leaves out strings
+// that already have an up to date, reviewed translation.
+func ExportTranslations(c *gin.Context) {
+	language := c.Query("language")
+	if language == "" || len(language) > 10 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "language is required"})
+		return
+	}
+	format := c.DefaultQuery("format", services.TranslationFormatXLIFF)
+	fileType, ok := translationFileTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be xliff or po"})
+		return
+	}
+
+	units, err := services.TranslationUnits(siteDB(c), language, c.Query("missing") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var file bytes.Buffer
+	if err := services.WriteTranslationFile(&file, format, language, units); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("kuno-%s%s", services.SafeFilename(language), fileType[0])
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	c.Data(http.StatusOK, fileType[1], file.Bytes())
+}
+
+// ImportTranslations applies an uploaded XLIFF or PO file, creating or
+// updating the translations of its language. The format is told by the
+// file name or ?format=, and the language is the file's own or ?language=
+// for files that do not name one. Strings whose source changed since the
+// file was exported are skipped and listed in the result.
+func ImportTranslations(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No translation file provided"})
+		return
+	}
+	if header.Size > maxTranslationFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Translation file is too large"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxTranslationFileSize))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".xlf", ".xliff":
+			format = services.TranslationFormatXLIFF
+		case ".po":
+			format = services.TranslationFormatPO
+		}
+	}
+	language, units, err := services.ParseTranslationFile(data, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if requested := c.Query("language"); requested != "" {
+		if language != "" && language != requested {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("file translates into %s, not %s", language, requested)})
+			return
+		}
+		language = requested
+	}
+	if language == "" || len(language) > 10 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file names no target language, set ?language="})
+		return
+	}
+
+	result, err := services.ImportTranslationUnits(siteDB(c), language, units)
+	if err != nil {
+		logging.FromGin(c).Error("Failed to import translations", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import translations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Translations imported", "result": result})
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// translationMemoryRequest is the language pair a lookup or save is for
+type translationMemoryRequest struct {
+	SourceLanguage string `json:"source_language" binding:"required"`
+	TargetLanguage string `json:"target_language" binding:"required"`
+}
+
+// LookupTranslationMemory returns the remembered translations for segments
+// about to be translated, so only the rest needs a translation service
+func LookupTranslationMemory(c *gin.Context) {
+	var req struct {
+		translationMemoryRequest
+		Segments []string `json:"segments" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	matches, err := services.GetGlobalTranslationMemory().Lookup(c.Request.Context(), currentSiteID(c),
+		req.SourceLanguage, req.TargetLanguage, req.Segments)
+	if err != nil {
+		respondTranslationMemoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"matches": matches})
+}
+
+// RememberTranslations adds translated segments to the translation memory
+func RememberTranslations(c *gin.Context) {
+	var req struct {
+		translationMemoryRequest
+		Pairs []services.TranslationPair `json:"pairs" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	stored, err := services.GetGlobalTranslationMemory().Remember(c.Request.Context(), currentSiteID(c),
+		req.SourceLanguage, req.TargetLanguage, req.Pairs)
+	if err != nil {
+		respondTranslationMemoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"stored": stored})
+}
+
+// ListTranslationMemory lists the remembered segments, filtered by
+// ?source_language=, ?target_language= and ?q=
+func ListTranslationMemory(c *gin.Context) {
+	paging, ok := bindPage(c, 50, maxAdminPageSize)
+	if !ok {
+		return
+	}
+	memory := services.GetGlobalTranslationMemory()
+	entries, total, err := memory.List(c.Request.Context(), currentSiteID(c),
+		c.Query("source_language"), c.Query("target_language"), c.Query("q"), paging.Page, paging.Limit)
+	if err != nil {
+		respondTranslationMemoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"entries":    entries,
+		"enabled":    memory.Enabled(),
+		"pagination": gin.H{"page": paging.Page, "limit": paging.Limit, "total": total},
+	})
+}
+
+// DeleteTranslationMemory forgets one remembered segment
+func DeleteTranslationMemory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid translation memory ID"})
+		return
+	}
+	if err := services.GetGlobalTranslationMemory().Delete(c.Request.Context(), currentSiteID(c), uint(id)); err != nil {
+		respondTranslationMemoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Translation memory entry deleted"})
+}
+
+// ClearTranslationMemory forgets every remembered segment of the site
+func ClearTranslationMemory(c *gin.Context) {
+	if err := services.GetGlobalTranslationMemory().Delete(c.Request.Context(), currentSiteID(c), 0); err != nil {
+		respondTranslationMemoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Translation memory cleared"})
+}
+
+func respondTranslationMemoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTranslationMemory):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTranslationMemoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTranslationStatus reports, per language, how much of the site's
+// articles, categories and settings is translated, which translations are
+// older than their source and which machine translations wait for review.
+// ?language= limits the report to one language.
+func GetTranslationStatus(c *gin.Context) {
+	status, err := services.GetTranslationStatus(siteDB(c), c.Query("language"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// ReviewTranslation marks a machine translation as reviewed
+func ReviewTranslation(c *gin.Context) {
+	var req struct {
+		Kind     string `json:"kind" binding:"required"`
+		ID       uint   `json:"id" binding:"required"`
+		Language string `json:"language" binding:"required"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	err := services.ReviewTranslation(siteDB(c), req.Kind, req.ID, req.Language)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Translation marked as reviewed"})
+	case errors.Is(err, services.ErrInvalidTranslationKind):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTranslationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetStaleTranslations lists the article translations whose article
+// changed since they were written, with how many of its sections changed.
+// ?article_id= limits the list to one article.
+func GetStaleTranslations(c *gin.Context) {
+	var query struct {
+		ArticleID uint `form:"article_id"`
+	}
+	if !bindQuery(c, &query) {
+		return
+	}
+	stale, err := services.StaleTranslations(siteDB(c), query.ArticleID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"translations": stale})
+}
+
+// RefreshTranslations queues the machine re-translation of the changed
+// sections of an article's stale translations, or of the one in language
+func RefreshTranslations(c *gin.Context) {
+	var req struct {
+		ArticleID uint   `json:"article_id" binding:"required"`
+		Language  string `json:"language"`
+	}
+	if !bindJSON(c, &req) {
+		return
+	}
+	jobs, err := services.GetGlobalTranslationRefresher().QueueRefresh(c.Request.Context(), currentSiteID(c), req.ArticleID, req.Language)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusAccepted, gin.H{"message": "Translation refresh queued", "jobs": jobs})
+	case errors.Is(err, services.ErrArticleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLLMNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// checkRequiredTranslations refuses to save an article that lacks a
+// translation in one of the languages publishing requires, answering 422
+// with the missing languages. allowMissing is the editor's explicit
+// override.
+func checkRequiredTranslations(c *gin.Context, article *models.Article, translations []models.ArticleTranslation, allowMissing bool) bool {
+	required := config.Get().Publish.RequiredLanguages
+	if len(required) == 0 || allowMissing {
+		return true
+	}
+	missing := services.MissingTranslations(required, article.DefaultLang, translations)
+	if len(missing) == 0 {
+		return true
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":             fmt.Sprintf("Publishing requires a title and content in every required language, missing: %s", strings.Join(missing, ", ")),
+		"code":              ErrCodeMissingTranslations,
+		"missing_languages": missing,
+	})
+	return false
+}
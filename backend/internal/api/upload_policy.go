@@ -0,0 +1,45 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUploadPolicySize is the largest upload policy body accepted
+const maxUploadPolicySize = 64 << 10
+
+// GetUploadPolicy returns the types, extensions and sizes the site accepts
+// as media uploads
+func GetUploadPolicy(c *gin.Context) {
+	policy, err := services.GetGlobalUploadPolicyService().Get(c.Request.Context())
+	if err != nil {
+		respondUploadPolicyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateUploadPolicy validates and saves the upload policy in the body
+func UpdateUploadPolicy(c *gin.Context) {
+	body := io.LimitReader(c.Request.Body, maxUploadPolicySize)
+	policy, err := services.GetGlobalUploadPolicyService().Update(c.Request.Context(), body)
+	if err != nil {
+		respondUploadPolicyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+func respondUploadPolicyError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidUploadPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logging.FromGin(c).Error("Upload policy operation failed", "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Upload policy operation failed"})
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// maxPageSize is the most items a public list returns at once
+	maxPageSize = 100
+	// maxAdminPageSize is the most items an admin list returns at once
+	maxAdminPageSize = 200
+)
+
+func init() {
+	// Validation errors name fields as clients send them
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form", "uri"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// PageQuery is the ?page= and ?limit= of a paginated list
+type PageQuery struct {
+	Page  int `form:"page" binding:"min=1"`
+	Limit int `form:"limit" binding:"min=1"`
+}
+
+// Offset is the number of items before the page
+func (q PageQuery) Offset() int {
+	return (q.Page - 1) * q.Limit
+}
+
+// LimitQuery is the ?limit= and ?offset= of a list read in slices. A
+// limit of 0 stands for the default.
+type LimitQuery struct {
+	Limit  int `form:"limit" binding:"omitempty,min=1"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
+
+// FieldError says why a field of a request was refused
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// bindPage reads the page of a list of defaultLimit items a page, refusing
+// limits above maxLimit
+func bindPage(c *gin.Context, defaultLimit, maxLimit int) (PageQuery, bool) {
+	query := PageQuery{Page: 1, Limit: defaultLimit}
+	if !bindQuery(c, &query) {
+		return query, false
+	}
+	if query.Limit > maxLimit {
+		respondValidation(c, []FieldError{maxFieldError("limit", maxLimit)})
+		return query, false
+	}
+	return query, true
+}
+
+// bindLimit reads the slice of a list, refusing limits above maxLimit.
+// With maxLimit 0 the service caps the limit, and a limit of 0 leaves the
+// service's default.
+func bindLimit(c *gin.Context, defaultLimit, maxLimit int) (LimitQuery, bool) {
+	query := LimitQuery{Limit: defaultLimit}
+	if !bindQuery(c, &query) {
+		return query, false
+	}
+	if maxLimit > 0 && query.Limit > maxLimit {
+		respondValidation(c, []FieldError{maxFieldError("limit", maxLimit)})
+		return query, false
+	}
+	return query, true
+}
+
+func maxFieldError(field string, max int) FieldError {
+	return FieldError{Field: field, Rule: "max", Param: strconv.Itoa(max), Message: fmt.Sprintf("%s must be at most %d", field, max)}
+}
+
+// bindJSON decodes and validates the JSON body of a request into obj,
+// answering 400 with what is wrong when it does not fit
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		respondValidation(c, fieldErrors(err))
+		return false
+	}
+	return true
+}
+
+// bindQuery decodes and validates the query parameters of a request into
+// obj, answering 400 with what is wrong when they do not fit
+func bindQuery(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindQuery(obj); err != nil {
+		fields := fieldErrors(err)
+		if len(fields) == 1 && fields[0].Field == "" {
+			// Form binding does not say which parameter failed to parse
+			if field := unparsableQueryField(c, reflect.TypeOf(obj)); field != nil {
+				fields = []FieldError{*field}
+			}
+		}
+		respondValidation(c, fields)
+		return false
+	}
+	return true
+}
+
+// respondValidation answers 400 with the refused fields
+func respondValidation(c *gin.Context, fields []FieldError) {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+		"error":   strings.Join(messages, "; "),
+		"code":    ErrCodeValidation,
+		"details": gin.H{"fields": fields},
+	})
+}
+
+// fieldErrors describes a binding error field by field
+func fieldErrors(err error) []FieldError {
+	var invalid validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &invalid):
+		fields := make([]FieldError, len(invalid))
+		for i, fe := range invalid {
+			fields[i] = FieldError{Field: fieldPath(fe), Rule: fe.Tag(), Param: fe.Param(), Message: validationMessage(fe)}
+		}
+		return fields
+	case errors.As(err, &typeErr):
+		return []FieldError{{Field: typeErr.Field, Rule: "type", Param: jsonKind(typeErr.Type),
+			Message: fmt.Sprintf("%s must be %s", typeErr.Field, jsonKind(typeErr.Type))}}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return []FieldError{{Rule: "json", Message: "The request body is not valid JSON"}}
+	case errors.Is(err, io.EOF):
+		return []FieldError{{Rule: "required", Message: "The request body is empty"}}
+	}
+	return []FieldError{{Rule: "invalid", Message: err.Error()}}
+}
+
+// fieldPath names a field as clients send it, such as items[2].title,
+// without the name of the struct it belongs to
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+// validationMessage explains a failed validation rule
+func validationMessage(fe validator.FieldError) string {
+	field := fieldPath(fe)
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+	switch fe.Tag() {
+	case "required":
+		return field + " is required"
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s%s", field, fe.Param(), unit)
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s%s", field, fe.Param(), unit)
+	case "gt":
+		return fmt.Sprintf("%s must be more than %s%s", field, fe.Param(), unit)
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s%s", field, fe.Param(), unit)
+	case "len":
+		return fmt.Sprintf("%s must be exactly %s%s", field, fe.Param(), unit)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", field, strings.Join(strings.Fields(fe.Param()), ", "))
+	case "email":
+		return field + " must be an email address"
+	case "url", "http_url":
+		return field + " must be a URL"
+	case "dive":
+		return field + " has an invalid item"
+	}
+	return fmt.Sprintf("%s is invalid (%s)", field, fe.Tag())
+}
+
+// jsonKind names the JSON type a Go type is decoded from
+func jsonKind(t reflect.Type) string {
+	if t == nil {
+		return "a different type"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Ptr:
+		return jsonKind(t.Elem())
+	}
+	return "a different type"
+}
+
+// unparsableQueryField finds the query parameter that does not parse as
+// the type of its field in t
+func unparsableQueryField(c *gin.Context, t reflect.Type) *FieldError {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			if found := unparsableQueryField(c, field.Type); found != nil {
+				return found
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		value, ok := c.GetQuery(name)
+		if name == "" || name == "-" || !ok || value == "" {
+			continue
+		}
+		kind := field.Type
+		if kind.Kind() == reflect.Ptr {
+			kind = kind.Elem()
+		}
+		var err error
+		switch kind.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			_, err = strconv.ParseInt(value, 10, kind.Bits())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			_, err = strconv.ParseUint(value, 10, kind.Bits())
+		case reflect.Float32, reflect.Float64:
+			_, err = strconv.ParseFloat(value, kind.Bits())
+		case reflect.Bool:
+			_, err = strconv.ParseBool(value)
+		}
+		if err != nil {
+			return &FieldError{Field: name, Rule: "type", Param: jsonKind(kind), Message: fmt.Sprintf("%s must be %s", name, jsonKind(kind))}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorEnvelope())
+	r.GET("/list", func(c *gin.Context) {
+		paging, ok := bindPage(c, 20, maxPageSize)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"page": paging.Page, "limit": paging.Limit, "offset": paging.Offset()})
+	})
+	r.POST("/items", func(c *gin.Context) {
+		var req struct {
+			Title  string   `json:"title" binding:"required,max=10"`
+			Status string   `json:"status" binding:"omitempty,oneof=draft published"`
+			Tags   []string `json:"tags" binding:"max=2"`
+			Count  int      `json:"count"`
+		}
+		if !bindJSON(c, &req) {
+			return
+		}
+		c.JSON(http.StatusOK, req)
+	})
+
+	do := func(method, path, body string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var decoded map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &decoded)
+		return w.Code, decoded
+	}
+	fields := func(body map[string]interface{}) []map[string]interface{} {
+		var list []map[string]interface{}
+		details, _ := body["details"].(map[string]interface{})
+		items, _ := details["fields"].([]interface{})
+		for _, item := range items {
+			list = append(list, item.(map[string]interface{}))
+		}
+		return list
+	}
+
+	if status, body := do(http.MethodGet, "/list?page=3", ""); status != http.StatusOK || body["limit"] != float64(20) || body["offset"] != float64(40) {
+		t.Errorf("defaults: %d %v", status, body)
+	}
+
+	for query, want := range map[string]string{
+		"limit=500": "limit must be at most 100",
+		"limit=abc": "limit must be a whole number",
+		"page=0":    "page must be at least 1",
+	} {
+		status, body := do(http.MethodGet, "/list?"+query, "")
+		if status != http.StatusBadRequest || body["code"] != string(ErrCodeValidation) || body["error"] != want {
+			t.Errorf("%s: %d %v", query, status, body)
+		}
+	}
+
+	status, body := do(http.MethodPost, "/items", `{"title":"a much longer title","status":"gone","tags":["a","b","c"]}`)
+	got := fields(body)
+	if status != http.StatusBadRequest || len(got) != 3 {
+		t.Fatalf("invalid body: %d %v", status, body)
+	}
+	if got[0]["field"] != "title" || got[0]["rule"] != "max" || got[0]["message"] != "title must be at most 10 characters" {
+		t.Errorf("title: %v", got[0])
+	}
+	if got[1]["message"] != "status must be one of draft, published" {
+		t.Errorf("status: %v", got[1])
+	}
+	if got[2]["message"] != "tags must be at most 2 items" {
+		t.Errorf("tags: %v", got[2])
+	}
+
+	if _, body := do(http.MethodPost, "/items", `{}`); body["error"] != "title is required" {
+		t.Errorf("required: %v", body)
+	}
+	if _, body := do(http.MethodPost, "/items", `{"title":"a","count":"3"}`); body["error"] != "count must be a whole number" {
+		t.Errorf("type: %v", body)
+	}
+	if _, body := do(http.MethodPost, "/items", `{"title":`); body["error"] != "The request body is not valid JSON" {
+		t.Errorf("syntax: %v", body)
+	}
+	if _, body := do(http.MethodPost, "/items", ``); body["error"] != "The request body is empty" {
+		t.Errorf("empty: %v", body)
+	}
+	if status, _ := do(http.MethodPost, "/items", `{"title":"ok","status":"draft"}`); status != http.StatusOK {
+		t.Errorf("valid body: %d", status)
+	}
+}
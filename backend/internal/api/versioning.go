@@ -0,0 +1,77 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIVersion1 is the first version of the API, served at /api/v1 and at
+// the unversioned /api
+const APIVersion1 = "v1"
+
+// APIVersionHeader names the API version that answered a request
+const APIVersionHeader = "API-Version"
+
+// Deprecation announces that a route is going away. Since is when it was
+// deprecated and Sunset when it stops working, or zero while undecided.
+// Successor is the path of the route replacing it, relative to the API
+// version, such as "/urls/:id".
+type Deprecation struct {
+	Since     time.Time
+	Sunset    time.Time
+	Successor string
+}
+
+// apiVersion tags responses with the API version serving them
+func apiVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header(APIVersionHeader, version)
+		c.Next()
+	}
+}
+
+// Deprecated marks a route as deprecated, so clients learn of it before it
+// breaks: responses carry the Deprecation (RFC 9745) and Sunset (RFC 8594)
+// headers and a Link to the successor. From the sunset on, the route
+// answers 410 Gone. Register it in front of the route's handler.
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+		if d.Successor != "" {
+			c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, versionPrefix(c.Request.URL.Path)+d.Successor))
+		}
+		if d.Sunset.IsZero() {
+			c.Next()
+			return
+		}
+		c.Header("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		if !time.Now().Before(d.Sunset) {
+			response := gin.H{"error": "This endpoint has been removed"}
+			if d.Successor != "" {
+				response["successor"] = versionPrefix(c.Request.URL.Path) + d.Successor
+			}
+			c.AbortWithStatusJSON(http.StatusGone, response)
+			return
+		}
+		c.Next()
+	}
+}
+
+// versionPrefix returns the API prefix of the request path: /api/v1, or
+// /api for unversioned paths
+func versionPrefix(path string) string {
+	if prefix := "/api/" + APIVersion1; path == prefix || strings.HasPrefix(path, prefix+"/") {
+		return prefix
+	}
+	return "/api"
+}
+
+// unversionedPath returns the request path as served at the unversioned
+// /api, for matching paths whatever the version asked for
+func unversionedPath(path string) string {
+	return "/api" + strings.TrimPrefix(path, versionPrefix(path))
+}
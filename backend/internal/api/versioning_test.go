@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeprecatedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, group := range []*gin.RouterGroup{r.Group("/api/"+APIVersion1, apiVersion(APIVersion1)), r.Group("/api", apiVersion(APIVersion1))} {
+		group.GET("/current", ok)
+		group.GET("/old", Deprecated(Deprecation{Since: since, Sunset: time.Now().Add(time.Hour), Successor: "/current"}), ok)
+		group.GET("/gone", Deprecated(Deprecation{Since: since, Sunset: time.Now().Add(-time.Hour), Successor: "/current"}), ok)
+	}
+
+	for _, test := range []struct {
+		path, link string
+		status     int
+	}{
+		{"/api/v1/current", "", http.StatusOK},
+		{"/api/v1/old", "</api/v1/current>", http.StatusOK},
+		{"/api/old", "</api/current>", http.StatusOK},
+		{"/api/v1/gone", "</api/v1/current>", http.StatusGone},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.status || w.Header().Get(APIVersionHeader) != APIVersion1 {
+			t.Errorf("%s: %d, version %q", test.path, w.Code, w.Header().Get(APIVersionHeader))
+		}
+		if !strings.HasPrefix(w.Header().Get("Link"), test.link) {
+			t.Errorf("%s: Link %q, want %s", test.path, w.Header().Get("Link"), test.link)
+		}
+		if deprecated := w.Header().Get("Deprecation"); (test.link != "") != (deprecated == "@1767225600") || (test.link != "") == (w.Header().Get("Sunset") == "") {
+			t.Errorf("%s: Deprecation %q, Sunset %q", test.path, deprecated, w.Header().Get("Sunset"))
+		}
+	}
+
+	if path := unversionedPath("/api/v1/backups/x"); path != "/api/backups/x" {
+		t.Errorf("unversionedPath() = %q", path)
+	}
+	if path := unversionedPath("/api/v10/x"); path != "/api/v10/x" {
+		t.Errorf("unversionedPath() of an unknown version = %q", path)
+	}
+}
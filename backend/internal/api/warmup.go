@@ -0,0 +1,152 @@
+package api
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// warmupEmbeddingBatch is how many stored embeddings are read at a time
+// while warming the embedding pool
+const warmupEmbeddingBatch = 200
+
+type warmupRequestKey struct{}
+
+// countInFlight counts the requests being served, which startup warm-up
+// waits on before starting another task. Warm-up's own requests are not
+// counted.
+func countInFlight(inFlight *atomic.Int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Context().Value(warmupRequestKey{}) != nil {
+			c.Next()
+			return
+		}
+		inFlight.Add(1)
+		defer inFlight.Add(-1)
+		c.Next()
+	}
+}
+
+// registerWarmupTasks fills the response caches visitors hit first after a
+// deploy by serving their requests once through the router, so the first
+// visitors don't pay for the cold paths
+func registerWarmupTasks(warmup *services.Warmup, r *gin.Engine, inFlight *atomic.Int64) {
+	warmup.SetLoad(func() int { return int(inFlight.Load()) })
+
+	get := func(ctx context.Context, path string) ([]byte, error) {
+		return warmupGet(ctx, r, path)
+	}
+	warmup.Register(services.WarmupTask{
+		Name:     "settings",
+		Priority: 0,
+		Critical: true,
+		Run: func(ctx context.Context) error {
+			for _, path := range []string{"/api/settings", "/api/settings/branding", "/api/languages"} {
+				if _, err := get(ctx, path); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	warmup.Register(services.WarmupTask{
+		Name:     "categories",
+		Priority: 0,
+		Critical: true,
+		Run: func(ctx context.Context) error {
+			for _, path := range []string{"/api/categories", "/api/categories/tree"} {
+				if _, err := get(ctx, path); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+	warmup.Register(services.WarmupTask{
+		Name:     "llms.txt",
+		Priority: 1,
+		Critical: true,
+		Run: func(ctx context.Context) error {
+			body, err := get(ctx, "/api/languages")
+			if err != nil {
+				return err
+			}
+			var languages LanguageConfig
+			if err := json.Unmarshal(body, &languages); err != nil {
+				return fmt.Errorf("read language config: %w", err)
+			}
+			if languages.DefaultLanguage == "" {
+				return nil
+			}
+			_, err = get(ctx, "/llms.txt?lang="+url.QueryEscape(languages.DefaultLanguage))
+			return err
+		},
+	})
+	warmup.Register(services.WarmupTask{
+		Name:     "embeddings",
+		Priority: 2,
+		Run:      warmEmbeddingPool,
+	})
+}
+
+// warmEmbeddingPool reads the stored article embeddings that semantic
+// search compares queries against, so the first search doesn't wait on the
+// disk. No provider is called.
+func warmEmbeddingPool(ctx context.Context) error {
+	if len(GetGlobalEmbeddingService().GetAvailableProviders()) == 0 {
+		return nil
+	}
+	var batch []models.ArticleEmbedding
+	return database.DB.WithContext(ctx).Where("content_type = ?", "combined").
+		FindInBatches(&batch, warmupEmbeddingBatch, func(*gorm.DB, int) error {
+			return ctx.Err()
+		}).Error
+}
+
+// warmupGet serves a GET request through the router as if a visitor of the
+// public address made it
+func warmupGet(ctx context.Context, r *gin.Engine, path string) ([]byte, error) {
+	host := "localhost"
+	if public, err := url.Parse(config.Get().Server.PublicURL); err == nil && public.Host != "" {
+		host = public.Host
+	}
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, warmupRequestKey{}, true), http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Host = host
+	req.RemoteAddr = "127.0.0.1:0"
+
+	w := &warmupWriter{header: make(http.Header), status: http.StatusOK}
+	r.ServeHTTP(w, req)
+	if w.status >= http.StatusBadRequest {
+		return nil, fmt.Errorf("GET %s: status %d", path, w.status)
+	}
+	return w.body, nil
+}
+
+// warmupWriter keeps the response to a warm-up request
+type warmupWriter struct {
+	header http.Header
+	status int
+	body   []byte
+}
+
+func (w *warmupWriter) Header() http.Header { return w.header }
+
+func (w *warmupWriter) Write(b []byte) (int, error) {
+	w.body = append(w.body, b...)
+	return len(b), nil
+}
+
+func (w *warmupWriter) WriteHeader(status int) { w.status = status }
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxWatermarkPolicySize is the largest watermark policy body accepted
+const maxWatermarkPolicySize = 16 << 10
+
+// GetWatermarkPolicy returns how the site watermarks the images it serves
+func GetWatermarkPolicy(c *gin.Context) {
+	policy, err := services.GetGlobalWatermarkService().Get(c.Request.Context())
+	if err != nil {
+		respondWatermarkPolicyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateWatermarkPolicy validates and saves the watermark policy in the
+// body. It applies to images served from then on, old and new alike.
+func UpdateWatermarkPolicy(c *gin.Context) {
+	body := io.LimitReader(c.Request.Body, maxWatermarkPolicySize)
+	policy, err := services.GetGlobalWatermarkService().Update(c.Request.Context(), body)
+	if err != nil {
+		respondWatermarkPolicyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+func respondWatermarkPolicyError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidWatermarkPolicy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	logging.FromGin(c).Error("Watermark policy operation failed", "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Watermark policy operation failed"})
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// ReceiveWebmention accepts a Webmention, a form with the source page and
+// the target article it links to. The source is fetched and checked
+// later, so the mention is answered 202 and waits for moderation with the
+// other replies.
+func ReceiveWebmention(c *gin.Context) {
+	var input struct {
+		Source string `form:"source" binding:"required,max=2048"`
+		Target string `form:"target" binding:"required,max=2048"`
+	}
+	if err := c.ShouldBindWith(&input, binding.Form); err != nil {
+		respondValidation(c, fieldErrors(err))
+		return
+	}
+	err := services.GetGlobalWebmentionService().Receive(c.Request.Context(), currentSiteID(c), getBaseURL(c), input.Source, input.Target)
+	if err != nil {
+		respondWebmentionError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Webmention accepted for verification"})
+}
+
+// ListArticleWebmentions returns the approved Webmentions of an article
+func ListArticleWebmentions(c *gin.Context) {
+	var query struct {
+		ArticleID uint `form:"article_id" binding:"required"`
+	}
+	if !bindQuery(c, &query) {
+		return
+	}
+	mentions, err := services.GetGlobalWebmentionService().Mentions(c.Request.Context(), query.ArticleID)
+	if err != nil {
+		respondWebmentionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"mentions": mentions})
+}
+
+func respondWebmentionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebmention):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Webmention request failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process the Webmention"})
+	}
+}
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"blog-backend/internal/cache"
 	"blog-backend/internal/database"
 	"blog-backend/internal/models"
+	"blog-backend/internal/services"
 	"bytes"
 	"encoding/xml"
 	"fmt"
@@ -12,6 +14,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"path"
 	"regexp"
 	"strings"
 	"time"
@@ -133,33 +136,68 @@ func isValidXMLChar(r rune) bool {
 		(r >= 0x10000 && r <= 0x10FFFF)
 }
 
-// ImportWordPress handles WordPress WXR file imports
-func ImportWordPress(c *gin.Context) {
-	// Parse multipart form
-	err := c.Request.ParseMultipartForm(100 << 20) // 100 MB limit
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form data"})
-		return
-	}
-
-	// Get the uploaded file
+// readWXRUpload reads the uploaded WXR file, which may also come zipped.
+// Zip files are checked before anything in them is read, and must hold
+// exactly one XML file.
+func readWXRUpload(c *gin.Context) ([]byte, bool) {
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
-		return
+		return nil, false
 	}
 	defer file.Close()
 
+	name := strings.ToLower(header.Filename)
+	if strings.HasSuffix(name, ".zip") {
+		archive, err := services.OpenImportArchive(file, header.Size, services.DefaultImportArchiveLimits)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		var exports []*services.ImportArchiveFile
+		for _, f := range archive.Files {
+			if strings.EqualFold(path.Ext(f.Name), ".xml") {
+				exports = append(exports, f)
+			}
+		}
+		if len(exports) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The zip file must hold exactly one XML file"})
+			return nil, false
+		}
+		content, err := archive.ReadFile(exports[0])
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		return content, true
+	}
+
 	// Validate file type
-	if !strings.HasSuffix(strings.ToLower(header.Filename), ".xml") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File must be an XML file"})
-		return
+	if !strings.HasSuffix(name, ".xml") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File must be an XML or zip file"})
+		return nil, false
 	}
 
 	// Read file content
 	fileContent, err := io.ReadAll(file)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return nil, false
+	}
+	return fileContent, true
+}
+
+// ImportWordPress handles WordPress WXR file imports
+func ImportWordPress(c *gin.Context) {
+	// Parse multipart form
+	err := c.Request.ParseMultipartForm(100 << 20) // 100 MB limit
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form data"})
+		return
+	}
+
+	fileContent, ok := readWXRUpload(c)
+	if !ok {
 		return
 	}
 
@@ -317,6 +355,7 @@ func ImportWordPress(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit import"})
 		return
 	}
+	cache.Publish(cache.TopicArticles, cache.TopicCategories)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "WordPress import completed",
@@ -446,24 +485,8 @@ func ParseWordPress(c *gin.Context) {
 		return
 	}
 
-	// Get the uploaded file
-	file, header, err := c.Request.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
-		return
-	}
-	defer file.Close()
-
-	// Validate file type
-	if !strings.HasSuffix(strings.ToLower(header.Filename), ".xml") {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File must be an XML file"})
-		return
-	}
-
-	// Read file content
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+	fileContent, ok := readWXRUpload(c)
+	if !ok {
 		return
 	}
 
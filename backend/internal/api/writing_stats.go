@@ -0,0 +1,61 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type writingStatsQuery struct {
+	Year int `form:"year" binding:"omitempty,min=1970"`
+}
+
+// GetSiteWritingStats returns how the site writes: article length,
+// vocabulary richness, posting cadence and the most used terms per
+// language, over every year or month by month for ?year=
+func GetSiteWritingStats(c *gin.Context) {
+	var query writingStatsQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+	stats, err := services.GetGlobalWritingStatsService().Site(c.Request.Context(), query.Year)
+	if err != nil {
+		respondWritingStatsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetAuthorWritingStats returns the writing statistics of an author's
+// published articles, for a "year in review" with ?year=
+func GetAuthorWritingStats(c *gin.Context) {
+	id, ok := authorID(c)
+	if !ok {
+		return
+	}
+	var query writingStatsQuery
+	if !bindQuery(c, &query) {
+		return
+	}
+	stats, err := services.GetGlobalWritingStatsService().Author(c.Request.Context(), id, query.Year)
+	if err != nil {
+		respondWritingStatsError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+func respondWritingStatsError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWritingStats):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAuthorNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Failed to compute writing statistics", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute writing statistics"})
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"blog-backend/internal/logging"
+	"blog-backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListWritingSuggestions lists what to write next, most relevant first.
+// language, status (pending by default) and type narrow the list.
+func ListWritingSuggestions(c *gin.Context) {
+	slice, ok := bindLimit(c, 0, 0)
+	if !ok {
+		return
+	}
+	suggestions, err := services.GetGlobalWritingSuggestionService().List(services.WritingSuggestionFilter{
+		Language: c.Query("language"),
+		Status:   c.DefaultQuery("status", services.SuggestionPending),
+		Type:     c.Query("type"),
+		Limit:    slice.Limit,
+	})
+	if err != nil {
+		respondWritingSuggestionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// GenerateWritingSuggestions queues a topic gap analysis now
+func GenerateWritingSuggestions(c *gin.Context) {
+	job, err := services.GetGlobalScheduler().Trigger(services.WritingSuggestionsScheduleName)
+	if err != nil {
+		respondJobError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// AcceptWritingSuggestion marks a suggestion as taken up
+func AcceptWritingSuggestion(c *gin.Context) {
+	decideWritingSuggestion(c, services.SuggestionAccepted)
+}
+
+// DismissWritingSuggestion stops suggesting a topic
+func DismissWritingSuggestion(c *gin.Context) {
+	decideWritingSuggestion(c, services.SuggestionDismissed)
+}
+
+func decideWritingSuggestion(c *gin.Context, status string) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid suggestion ID"})
+		return
+	}
+	suggestion, err := services.GetGlobalWritingSuggestionService().Decide(uint(id), status)
+	if err != nil {
+		respondWritingSuggestionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, suggestion)
+}
+
+func respondWritingSuggestionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrWritingSuggestionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidWritingSuggestion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.FromGin(c).Error("Writing suggestion operation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Writing suggestion operation failed"})
+	}
+}
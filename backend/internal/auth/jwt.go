@@ -1,10 +1,13 @@
 package auth
 
 import (
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
 	"crypto/rand"
 	"errors"
 	"github.com/golang-jwt/jwt/v5"
 	"log"
+	"log/slog"
 	"os"
 	"sync"
 	"time"
@@ -14,12 +17,27 @@ type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
 	IsAdmin  bool   `json:"is_admin"`
+	SiteID   uint   `json:"site_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Site returns the site the token was issued for. Tokens issued before
+// multi-site support belong to the default site.
+func (c *Claims) Site() uint {
+	if c.SiteID == 0 {
+		return models.DefaultSiteID
+	}
+	return c.SiteID
+}
+
 var (
 	jwtSecret     []byte
 	jwtSecretOnce sync.Once
+	// jwtSecretRef is set when JWT_SECRET points at an external secret manager
+	jwtSecretRef string
+	// jwtPreviousSecret keeps tokens signed before a rotation valid until they expire
+	jwtPreviousSecret []byte
+	jwtSecretMu       sync.RWMutex
 )
 
 // generateSecureRandomKey generates a cryptographically secure random key
@@ -42,22 +60,70 @@ func getJWTSecret() []byte {
 				log.Fatal("Failed to generate JWT secret:", err)
 			}
 			jwtSecret = randomKey
-			log.Println("WARNING: Using auto-generated JWT secret. Set JWT_SECRET environment variable for production use.")
+			slog.Warn("Using auto-generated JWT secret. Set JWT_SECRET environment variable for production use.")
+		} else if security.IsSecretRef(secret) {
+			resolved, err := security.ResolveSecret(secret)
+			if err != nil {
+				log.Fatal("Failed to resolve JWT secret from secret manager:", err)
+			}
+			jwtSecretRef = secret
+			jwtSecret = []byte(resolved)
+			// Rotations are picked up in the background, so signing and
+			// validating tokens never waits on the secret manager
+			security.GetGlobalSecretManager().OnRotate(rotateJWTSecret)
+			security.GetGlobalSecretManager().WatchRotations()
+			slog.Info("Using JWT secret from external secret manager")
 		} else {
 			jwtSecret = []byte(secret)
-			log.Println("Using JWT secret from environment variable")
+			slog.Info("Using JWT secret from environment variable")
 		}
 	})
+
+	jwtSecretMu.RLock()
+	defer jwtSecretMu.RUnlock()
 	return jwtSecret
 }
 
-func GenerateToken(userID uint, username string, isAdmin bool) (string, error) {
+// rotateJWTSecret swaps in a rotated secret and keeps the old one for verification
+func rotateJWTSecret(ref string) {
+	if ref != jwtSecretRef {
+		return
+	}
+
+	resolved, err := security.ResolveSecret(ref)
+	if err != nil {
+		slog.Error("Failed to load rotated JWT secret", "error", err)
+		return
+	}
+
+	jwtSecretMu.Lock()
+	defer jwtSecretMu.Unlock()
+	jwtPreviousSecret = jwtSecret
+	jwtSecret = []byte(resolved)
+	slog.Info("JWT secret rotated; previous secret remains valid for existing tokens")
+}
+
+// getPreviousJWTSecret returns the secret in use before the last rotation, if any
+func getPreviousJWTSecret() []byte {
+	jwtSecretMu.RLock()
+	defer jwtSecretMu.RUnlock()
+	return jwtPreviousSecret
+}
+
+// TokenLifetime is how long issued login tokens remain valid
+const TokenLifetime = 24 * time.Hour
+
+// GenerateSessionToken issues a token for a user of siteID bound to a
+// revocable login session
+func GenerateSessionToken(userID uint, username string, isAdmin bool, siteID uint, sessionID string) (string, error) {
 	claims := &Claims{
 		UserID:   userID,
 		Username: username,
 		IsAdmin:  isAdmin,
+		SiteID:   siteID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenLifetime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -72,11 +138,26 @@ func GenerateToken(userID uint, username string, isAdmin bool) (string, error) {
 }
 
 func ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := parseTokenWithSecret(tokenString, getJWTSecret())
+	if err != nil {
+		// Tokens issued before a secret rotation are still accepted until they expire
+		if previous := getPreviousJWTSecret(); previous != nil {
+			if claims, prevErr := parseTokenWithSecret(tokenString, previous); prevErr == nil {
+				return claims, nil
+			}
+		}
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func parseTokenWithSecret(tokenString string, secret []byte) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return getJWTSecret(), nil
+		return secret, nil
 	})
 
 	if err != nil {
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strings"
@@ -30,10 +32,24 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// In multi-site mode a token only works on the site it was issued for
+		if siteID, ok := c.Get("siteID"); ok && siteID.(uint) != claims.Site() {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+
+		if !isSessionActive(claims.ID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
 		// Set user information in context
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("isAdmin", claims.IsAdmin)
+		c.Set("sessionID", claims.ID)
 
 		c.Next()
 	}
@@ -50,3 +66,37 @@ func AdminMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// isSessionActive reports whether the login session behind a token exists
+// and was not revoked. Every token is issued for a session, so one without
+// a session id, such as a token from before sessions, is refused rather
+// than escaping "log out everywhere".
+func isSessionActive(sessionID string) bool {
+	if sessionID == "" {
+		return false
+	}
+	var count int64
+	database.DB.Model(&models.LoginSession{}).Where("session_id = ? AND revoked_at IS NULL", sessionID).Count(&count)
+	return count > 0
+}
+
+// RequestClaims returns the claims of a valid, unrevoked token sent with a
+// request to a public endpoint, or nil when there is none. Unlike
+// AuthMiddleware it never rejects the request.
+func RequestClaims(c *gin.Context) *Claims {
+	tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return nil
+	}
+	claims, err := ValidateToken(tokenString)
+	if err != nil {
+		return nil
+	}
+	if siteID, ok := c.Get("siteID"); ok && siteID.(uint) != claims.Site() {
+		return nil
+	}
+	if !isSessionActive(claims.ID) {
+		return nil
+	}
+	return claims
+}
@@ -0,0 +1,500 @@
+package auth
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Authenticator data flags
+const (
+	webauthnFlagUserPresent  = 0x01
+	webauthnFlagUserVerified = 0x04
+	webauthnFlagAttestedData = 0x40
+)
+
+// COSE algorithm identifiers supported for passkeys
+const (
+	COSEAlgES256 = -7
+	COSEAlgEdDSA = -8
+	COSEAlgRS256 = -257
+)
+
+const webauthnSessionTTL = 5 * time.Minute
+
+// WebAuthnConfig describes the relying party passkeys are bound to
+type WebAuthnConfig struct {
+	RPID    string
+	RPName  string
+	Origins []string
+}
+
+// WebAuthnCredentialData is the result of a successful registration
+type WebAuthnCredentialData struct {
+	ID        []byte
+	PublicKey []byte // COSE encoded
+	SignCount uint32
+	AAGUID    []byte
+}
+
+// WebAuthnSession holds a pending registration or login challenge
+type WebAuthnSession struct {
+	Challenge []byte
+	UserID    uint
+	Purpose   string
+	ExpiresAt time.Time
+}
+
+// Pending ceremonies live in the shared cache, so a login begun on one
+// instance can be finished on another
+var webauthnSessions = cache.New("webauthn", webauthnSessionTTL)
+
+// GetWebAuthnConfig builds the relying party configuration from the auth
+// config. The configured RP ID and origins take precedence; otherwise the
+// request host is used.
+func GetWebAuthnConfig(requestHost string) WebAuthnConfig {
+	loaded := config.Get().Auth.WebAuthn
+	cfg := WebAuthnConfig{
+		RPID:   loaded.RPID,
+		RPName: loaded.RPName,
+	}
+	if cfg.RPName == "" {
+		cfg.RPName = "KUNO"
+	}
+	if cfg.RPID == "" {
+		host := requestHost
+		if h, _, found := strings.Cut(host, ":"); found {
+			host = h
+		}
+		cfg.RPID = host
+	}
+	for _, origin := range loaded.Origins {
+		cfg.Origins = append(cfg.Origins, strings.TrimRight(origin, "/"))
+	}
+	return cfg
+}
+
+// isAllowedOrigin checks the client origin against the configured list, or
+// against the RP ID when no list is configured
+func (cfg WebAuthnConfig) isAllowedOrigin(origin string) bool {
+	if len(cfg.Origins) > 0 {
+		for _, allowed := range cfg.Origins {
+			if origin == allowed {
+				return true
+			}
+		}
+		return false
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := parsed.Hostname()
+	if host != cfg.RPID && !strings.HasSuffix(host, "."+cfg.RPID) {
+		return false
+	}
+	// Browsers only allow plain http for localhost
+	return parsed.Scheme == "https" || host == "localhost"
+}
+
+// NewWebAuthnChallenge returns 32 random bytes for a ceremony challenge
+func NewWebAuthnChallenge() ([]byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// SaveWebAuthnSession stores a pending ceremony and returns its ID
+func SaveWebAuthnSession(session *WebAuthnSession) (string, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	id := base64.RawURLEncoding.EncodeToString(idBytes)
+	session.ExpiresAt = time.Now().Add(webauthnSessionTTL)
+	webauthnSessions.Set(id, session)
+	return id, nil
+}
+
+// ConsumeWebAuthnSession returns and removes a session; each challenge is single use
+func ConsumeWebAuthnSession(id string, purposes ...string) (*WebAuthnSession, bool) {
+	var session *WebAuthnSession
+	if !webauthnSessions.Take(id, &session) {
+		return nil, false
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, false
+	}
+	for _, purpose := range purposes {
+		if session.Purpose == purpose {
+			return session, true
+		}
+	}
+	return nil, false
+}
+
+// DecodeBase64URL accepts padded or unpadded base64url as sent by browsers
+func DecodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+type collectedClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func verifyClientData(cfg WebAuthnConfig, clientDataJSON []byte, ceremony string, challenge []byte) error {
+	var clientData collectedClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return fmt.Errorf("invalid client data: %v", err)
+	}
+	if clientData.Type != ceremony {
+		return fmt.Errorf("unexpected ceremony type %q", clientData.Type)
+	}
+	received, err := DecodeBase64URL(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return errors.New("challenge mismatch")
+	}
+	if !cfg.isAllowedOrigin(clientData.Origin) {
+		return fmt.Errorf("origin %q is not allowed", clientData.Origin)
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	aaguid    []byte
+	credID    []byte
+	publicKey []byte
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data too short")
+	}
+	ad := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	if ad.flags&webauthnFlagAttestedData != 0 {
+		rest := data[37:]
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data too short")
+		}
+		ad.aaguid = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLen {
+			return nil, errors.New("credential ID truncated")
+		}
+		ad.credID = rest[:idLen]
+		rest = rest[idLen:]
+
+		// The public key is a single CBOR item; extensions may follow it
+		_, n, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential public key: %v", err)
+		}
+		ad.publicKey = rest[:n]
+	}
+
+	return ad, nil
+}
+
+func (ad *authenticatorData) verify(cfg WebAuthnConfig) error {
+	expected := sha256.Sum256([]byte(cfg.RPID))
+	if !bytes.Equal(ad.rpIDHash, expected[:]) {
+		return errors.New("relying party ID mismatch")
+	}
+	if ad.flags&webauthnFlagUserPresent == 0 {
+		return errors.New("user presence was not confirmed")
+	}
+	if config.Get().Auth.WebAuthn.RequireUserVerification && ad.flags&webauthnFlagUserVerified == 0 {
+		return errors.New("user verification is required")
+	}
+	return nil
+}
+
+// VerifyRegistration validates an attestation response and extracts the new
+// credential. Attestation statements are not verified: the server requests
+// "none" conveyance and trusts the authenticator the admin chose to enrol.
+func VerifyRegistration(cfg WebAuthnConfig, challenge, clientDataJSON, attestationObject []byte) (*WebAuthnCredentialData, error) {
+	if err := verifyClientData(cfg, clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %v", err)
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("attestation object is not a map")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("attestation object has no authenticator data")
+	}
+
+	ad, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := ad.verify(cfg); err != nil {
+		return nil, err
+	}
+	if ad.credID == nil {
+		return nil, errors.New("no credential was attested")
+	}
+	if _, err := parseCOSEKey(ad.publicKey); err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnCredentialData{
+		ID:        ad.credID,
+		PublicKey: ad.publicKey,
+		SignCount: ad.signCount,
+		AAGUID:    ad.aaguid,
+	}, nil
+}
+
+// VerifyAssertion validates a login assertion against a stored credential and
+// returns the authenticator's new signature counter
+func VerifyAssertion(cfg WebAuthnConfig, challenge, clientDataJSON, rawAuthData, signature, publicKey []byte, storedSignCount uint32) (uint32, error) {
+	if err := verifyClientData(cfg, clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	ad, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+	if err := ad.verify(cfg); err != nil {
+		return 0, err
+	}
+
+	key, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+	if err := key.verify(signed, signature); err != nil {
+		return 0, err
+	}
+
+	// A counter that does not increase suggests a cloned authenticator.
+	// Authenticators that do not implement counters always report zero.
+	if (ad.signCount != 0 || storedSignCount != 0) && ad.signCount <= storedSignCount {
+		return 0, errors.New("signature counter did not increase")
+	}
+
+	return ad.signCount, nil
+}
+
+type coseKey struct {
+	alg    int64
+	public crypto.PublicKey
+}
+
+func parseCOSEKey(data []byte) (*coseKey, error) {
+	decoded, _, err := decodeCBOR(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid COSE key: %v", err)
+	}
+	m, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("COSE key is not a map")
+	}
+
+	kty, _ := m[int64(1)].(int64)
+	alg, _ := m[int64(3)].(int64)
+
+	switch alg {
+	case COSEAlgES256:
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if kty != 2 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid EC2 key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("EC2 key is not on curve")
+		}
+		return &coseKey{alg: alg, public: pub}, nil
+	case COSEAlgRS256:
+		n, _ := m[int64(-1)].([]byte)
+		e, _ := m[int64(-2)].([]byte)
+		if kty != 3 || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA key")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return &coseKey{alg: alg, public: pub}, nil
+	case COSEAlgEdDSA:
+		x, _ := m[int64(-2)].([]byte)
+		if kty != 1 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid OKP key")
+		}
+		return &coseKey{alg: alg, public: ed25519.PublicKey(x)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported COSE algorithm %d", alg)
+	}
+}
+
+func (k *coseKey) verify(data, signature []byte) error {
+	switch pub := k.public.(type) {
+	case *ecdsa.PublicKey:
+		hash := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(pub, hash[:], signature) {
+			return errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		hash := sha256.Sum256(data)
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], signature); err != nil {
+			return errors.New("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, data, signature) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return errors.New("unsupported key type")
+	}
+	return nil
+}
+
+// decodeCBOR decodes the subset of CBOR used by WebAuthn (RFC 8949 major types
+// 0-5 and simple values) and returns the item plus the number of bytes read.
+func decodeCBOR(data []byte) (interface{}, int, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, int, error) {
+	if depth > 16 {
+		return nil, 0, errors.New("cbor nesting too deep")
+	}
+	if len(data) == 0 {
+		return nil, 0, errors.New("unexpected end of cbor data")
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	pos := 1
+
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(data) < pos+size {
+			return nil, 0, errors.New("truncated cbor header")
+		}
+		for i := 0; i < size; i++ {
+			arg = arg<<8 | uint64(data[pos+i])
+		}
+		pos += size
+	default:
+		return nil, 0, errors.New("indefinite-length cbor items are not supported")
+	}
+
+	switch major {
+	case 0:
+		if arg > 1<<63-1 {
+			return nil, 0, errors.New("cbor integer overflow")
+		}
+		return int64(arg), pos, nil
+	case 1:
+		if arg > 1<<63-1 {
+			return nil, 0, errors.New("cbor integer overflow")
+		}
+		return -1 - int64(arg), pos, nil
+	case 2, 3:
+		if uint64(len(data)-pos) < arg {
+			return nil, 0, errors.New("truncated cbor string")
+		}
+		end := pos + int(arg)
+		if major == 2 {
+			return data[pos:end], end, nil
+		}
+		return string(data[pos:end]), end, nil
+	case 4:
+		if arg > uint64(len(data)) {
+			return nil, 0, errors.New("cbor array too long")
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, n, err := decodeCBORItem(data[pos:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, item)
+			pos += n
+		}
+		return items, pos, nil
+	case 5:
+		if arg > uint64(len(data)) {
+			return nil, 0, errors.New("cbor map too long")
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, n, err := decodeCBORItem(data[pos:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			pos += n
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, 0, errors.New("only integer and text map keys are supported")
+			}
+			value, n, err := decodeCBORItem(data[pos:], depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			pos += n
+			m[key] = value
+		}
+		return m, pos, nil
+	case 6:
+		// Tags are not used by WebAuthn; decode the tagged item as-is
+		item, n, err := decodeCBORItem(data[pos:], depth+1)
+		return item, pos + n, err
+	default:
+		switch info {
+		case 20:
+			return false, pos, nil
+		case 21:
+			return true, pos, nil
+		case 22, 23:
+			return nil, pos, nil
+		}
+		return nil, 0, fmt.Errorf("unsupported cbor simple value %d", info)
+	}
+}
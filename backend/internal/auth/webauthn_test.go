@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+// es256COSEKey encodes a P-256 public key as a COSE_Key map
+func es256COSEKey(pub *ecdsa.PublicKey) []byte {
+	x := pub.X.FillBytes(make([]byte, 32))
+	y := pub.Y.FillBytes(make([]byte, 32))
+	key := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x58, 0x20}
+	key = append(key, x...)
+	key = append(key, 0x22, 0x58, 0x20)
+	return append(key, y...)
+}
+
+func testAuthenticatorData(rpID string, flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+func testClientData(t *testing.T, ceremony string, challenge []byte, origin string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestVerifyAssertionES256(t *testing.T) {
+	cfg := WebAuthnConfig{RPID: "blog.example.com"}
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := es256COSEKey(&privateKey.PublicKey)
+	challenge := []byte("0123456789abcdef0123456789abcdef")
+
+	sign := func(authData, clientData []byte) []byte {
+		clientDataHash := sha256.Sum256(clientData)
+		digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+		signature, err := ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+
+	authData := testAuthenticatorData(cfg.RPID, webauthnFlagUserPresent, 5)
+	clientData := testClientData(t, "webauthn.get", challenge, "https://blog.example.com")
+
+	signCount, err := VerifyAssertion(cfg, challenge, clientData, authData, sign(authData, clientData), publicKey, 4)
+	if err != nil {
+		t.Fatalf("VerifyAssertion() error = %v", err)
+	}
+	if signCount != 5 {
+		t.Fatalf("signCount = %d, want 5", signCount)
+	}
+
+	if _, err := VerifyAssertion(cfg, challenge, clientData, authData, sign(authData, clientData), publicKey, 5); err == nil {
+		t.Fatal("expected replayed signature counter to be rejected")
+	}
+
+	evilClientData := testClientData(t, "webauthn.get", challenge, "https://evil.example.net")
+	if _, err := VerifyAssertion(cfg, challenge, evilClientData, authData, sign(authData, evilClientData), publicKey, 0); err == nil {
+		t.Fatal("expected foreign origin to be rejected")
+	}
+
+	otherRPData := testAuthenticatorData("evil.example.net", webauthnFlagUserPresent, 6)
+	if _, err := VerifyAssertion(cfg, challenge, clientData, otherRPData, sign(otherRPData, clientData), publicKey, 0); err == nil {
+		t.Fatal("expected RP ID mismatch to be rejected")
+	}
+
+	tampered := sign(authData, clientData)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := VerifyAssertion(cfg, challenge, clientData, authData, tampered, publicKey, 0); err == nil {
+		t.Fatal("expected tampered signature to be rejected")
+	}
+}
+
+func TestDecodeCBORRejectsMalformedInput(t *testing.T) {
+	inputs := [][]byte{
+		{},
+		{0x5a, 0xff, 0xff, 0xff, 0xff},       // byte string longer than input
+		{0xbf},                               // indefinite-length map
+		{0xa1, 0x81, 0x01, 0x01},             // array used as map key
+		{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff}, // truncated header
+	}
+
+	for _, input := range inputs {
+		if _, _, err := decodeCBOR(input); err == nil {
+			t.Errorf("decodeCBOR(%x) expected error", input)
+		}
+	}
+}
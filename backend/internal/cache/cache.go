@@ -0,0 +1,410 @@
+// Package cache is the shared cache layer. Values are stored as JSON under
+// namespaced keys in a Driver (in-process memory or Redis), and invalidation
+// topics let writers bust every cache derived from the data they changed,
+// on this instance and, with Redis, on every other instance too.
+package cache
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/security"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Driver stores raw values under fully qualified keys
+type Driver interface {
+	Name() string
+	Get(key string) ([]byte, bool)
+	// Take returns and removes a value in one step, so of several
+	// instances racing for the same key only one gets it
+	Take(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(keys ...string) error
+	DeletePrefix(prefix string) error
+	Keys(prefix string) ([]string, error)
+	// Usage counts the entries under prefix and the bytes their values take
+	Usage(prefix string) (Usage, error)
+	// Publish and Subscribe carry invalidations between instances. The
+	// memory driver has no other instances, so both are no-ops there.
+	Publish(channel string, message []byte) error
+	Subscribe(channel string, handler func([]byte)) (stop func(), err error)
+	Stats() Stats
+	Close() error
+}
+
+// Stats describes a driver's contents and hit rate
+type Stats struct {
+	Driver  string  `json:"driver"`
+	Entries int64   `json:"entries"`
+	Bytes   int64   `json:"bytes"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// Usage is the size of a group of entries. Bytes counts the stored
+// values, not the driver's own overhead.
+type Usage struct {
+	Entries int64 `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// NamespaceStats describes a namespace. Hits and misses are counted by
+// this instance since it started.
+type NamespaceStats struct {
+	Name       string  `json:"name"`
+	TTLSeconds float64 `json:"ttl_seconds"`
+	Entries    int64   `json:"entries"`
+	Bytes      int64   `json:"bytes"`
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	HitRate    float64 `json:"hit_rate"`
+}
+
+// Invalidation topics published when the underlying data changes
+const (
+	TopicArticles    = "articles"
+	TopicCategories  = "categories"
+	TopicSettings    = "settings"
+	TopicPlugins     = "plugins"
+	TopicSites       = "sites"
+	TopicMaintenance = "maintenance"
+	TopicFeatures    = "features"
+	TopicMoments     = "moments"
+	TopicAuthors     = "authors"
+	TopicStopWords   = "stop_words"
+	TopicSEORules    = "seo_rules"
+	// TopicNotifications is published when admin notifications change
+	TopicNotifications = "notifications"
+)
+
+const defaultPrefix = "kuno:"
+
+var (
+	mu         sync.RWMutex
+	driver     Driver = NewMemory(10000)
+	keyPrefix         = defaultPrefix
+	stopRemote func()
+
+	handlersMu sync.RWMutex
+	handlers   = make(map[string][]func())
+
+	instanceID = uuid.NewString()
+
+	namespacesMu sync.Mutex
+	namespaces   = make(map[string]*namespaceCounters)
+)
+
+// namespaceCounters are shared by the Namespace values created with the
+// same name
+type namespaceCounters struct {
+	ttl    time.Duration
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// Init replaces the default in-process driver with the configured one and,
+// for Redis, starts listening for invalidations from other instances
+func Init(cfg config.CacheConfig) error {
+	prefix := cfg.Prefix
+	if prefix == "" {
+		prefix = defaultPrefix
+	}
+
+	var next Driver
+	switch cfg.Driver {
+	case "", "memory":
+		next = NewMemory(cfg.MaxItems)
+	case "redis":
+		redisURL, err := security.ResolveSecret(cfg.RedisURL)
+		if err != nil {
+			return fmt.Errorf("failed to resolve redis url: %v", err)
+		}
+		redis, err := NewRedis(redisURL, prefix)
+		if err != nil {
+			return err
+		}
+		next = redis
+	default:
+		return fmt.Errorf("unknown cache driver %q", cfg.Driver)
+	}
+
+	stop, err := next.Subscribe(prefix+"invalidate", handleRemote)
+	if err != nil {
+		next.Close()
+		return fmt.Errorf("failed to subscribe to invalidations: %v", err)
+	}
+
+	mu.Lock()
+	previous, previousStop := driver, stopRemote
+	driver, keyPrefix, stopRemote = next, prefix, stop
+	mu.Unlock()
+
+	if previousStop != nil {
+		previousStop()
+	}
+	previous.Close()
+	slog.Info("Cache initialized", "driver", next.Name())
+	return nil
+}
+
+// Close stops the invalidation listener and releases the driver
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if stopRemote != nil {
+		stopRemote()
+		stopRemote = nil
+	}
+	return driver.Close()
+}
+
+// Current returns the active driver
+func Current() Driver {
+	mu.RLock()
+	defer mu.RUnlock()
+	return driver
+}
+
+func current() (Driver, string) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return driver, keyPrefix
+}
+
+// Namespace is a group of keys with a default TTL that can be cleared together
+type Namespace struct {
+	name     string
+	ttl      time.Duration
+	counters *namespaceCounters
+}
+
+// New returns the namespace with the given name. A zero TTL never expires.
+// Namespaces are listed by Namespaces under the name, with the TTL of the
+// first one created.
+func New(name string, ttl time.Duration) *Namespace {
+	namespacesMu.Lock()
+	defer namespacesMu.Unlock()
+	counters, ok := namespaces[name]
+	if !ok {
+		counters = &namespaceCounters{ttl: ttl}
+		namespaces[name] = counters
+	}
+	return &Namespace{name: name, ttl: ttl, counters: counters}
+}
+
+// Namespaces describes every namespace created so far, by name
+func Namespaces() []NamespaceStats {
+	namespacesMu.Lock()
+	names := make([]string, 0, len(namespaces))
+	all := make(map[string]*namespaceCounters, len(namespaces))
+	for name, counters := range namespaces {
+		names = append(names, name)
+		all[name] = counters
+	}
+	namespacesMu.Unlock()
+	sort.Strings(names)
+
+	d, prefix := current()
+	stats := make([]NamespaceStats, 0, len(names))
+	for _, name := range names {
+		counters := all[name]
+		usage, err := d.Usage(prefix + name + ":")
+		if err != nil {
+			slog.Warn("Failed to measure cache namespace", "namespace", name, "error", err)
+		}
+		s := newStats(d.Name(), usage.Entries, counters.hits.Load(), counters.misses.Load())
+		stats = append(stats, NamespaceStats{
+			Name:       name,
+			TTLSeconds: counters.ttl.Seconds(),
+			Entries:    usage.Entries,
+			Bytes:      usage.Bytes,
+			Hits:       s.Hits,
+			Misses:     s.Misses,
+			HitRate:    s.HitRate,
+		})
+	}
+	return stats
+}
+
+// Purge removes every key of the named namespace and reports whether such
+// a namespace exists
+func Purge(name string) bool {
+	namespacesMu.Lock()
+	counters, ok := namespaces[name]
+	namespacesMu.Unlock()
+	if ok {
+		(&Namespace{name: name, ttl: counters.ttl, counters: counters}).Clear()
+	}
+	return ok
+}
+
+// PurgeAll removes every key of every namespace
+func PurgeAll() error {
+	d, prefix := current()
+	return d.DeletePrefix(prefix)
+}
+
+func (n *Namespace) count(hit bool) {
+	if hit {
+		n.counters.hits.Add(1)
+	} else {
+		n.counters.misses.Add(1)
+	}
+}
+
+func (n *Namespace) prefix() (Driver, string) {
+	d, prefix := current()
+	return d, prefix + n.name + ":"
+}
+
+// Get decodes the value stored under key into dest and reports whether it was found
+func (n *Namespace) Get(key string, dest interface{}) bool {
+	d, prefix := n.prefix()
+	data, ok := d.Get(prefix + key)
+	if !ok {
+		n.count(false)
+		return false
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		slog.Warn("Discarding undecodable cache entry", "namespace", n.name, "key", key, "error", err)
+		d.Delete(prefix + key)
+		n.count(false)
+		return false
+	}
+	n.count(true)
+	return true
+}
+
+// Take decodes the value stored under key into dest and removes it. It
+// reports false if the key was missing or another caller took it first.
+func (n *Namespace) Take(key string, dest interface{}) bool {
+	d, prefix := n.prefix()
+	data, ok := d.Take(prefix + key)
+	if !ok {
+		n.count(false)
+		return false
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		slog.Warn("Discarding undecodable cache entry", "namespace", n.name, "key", key, "error", err)
+		n.count(false)
+		return false
+	}
+	n.count(true)
+	return true
+}
+
+// Set stores value under key with the namespace TTL
+func (n *Namespace) Set(key string, value interface{}) {
+	n.SetWithTTL(key, value, n.ttl)
+}
+
+// SetWithTTL stores value under key with an explicit TTL
+func (n *Namespace) SetWithTTL(key string, value interface{}, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		slog.Warn("Failed to encode cache entry", "namespace", n.name, "key", key, "error", err)
+		return
+	}
+	d, prefix := n.prefix()
+	if err := d.Set(prefix+key, data, ttl); err != nil {
+		slog.Warn("Failed to store cache entry", "namespace", n.name, "key", key, "error", err)
+	}
+}
+
+// Delete removes key from the namespace
+func (n *Namespace) Delete(key string) {
+	d, prefix := n.prefix()
+	if err := d.Delete(prefix + key); err != nil {
+		slog.Warn("Failed to delete cache entry", "namespace", n.name, "key", key, "error", err)
+	}
+}
+
+// DeletePrefix removes every key in the namespace that starts with keyPrefix
+func (n *Namespace) DeletePrefix(keyPrefix string) {
+	d, prefix := n.prefix()
+	if err := d.DeletePrefix(prefix + keyPrefix); err != nil {
+		slog.Warn("Failed to clear cache entries", "namespace", n.name, "prefix", keyPrefix, "error", err)
+	}
+}
+
+// Clear removes every key in the namespace
+func (n *Namespace) Clear() {
+	n.DeletePrefix("")
+}
+
+// Keys lists the keys in the namespace, without the namespace prefix
+func (n *Namespace) Keys() []string {
+	d, prefix := n.prefix()
+	keys, err := d.Keys(prefix)
+	if err != nil {
+		slog.Warn("Failed to list cache entries", "namespace", n.name, "error", err)
+		return nil
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, prefix)
+	}
+	return keys
+}
+
+// Usage measures the entries of the namespace whose keys start with
+// keyPrefix
+func (n *Namespace) Usage(keyPrefix string) Usage {
+	d, prefix := n.prefix()
+	usage, err := d.Usage(prefix + keyPrefix)
+	if err != nil {
+		slog.Warn("Failed to measure cache entries", "namespace", n.name, "prefix", keyPrefix, "error", err)
+	}
+	return usage
+}
+
+// Subscribe registers fn to run whenever topic is published, on this
+// instance or any other sharing the cache
+func Subscribe(topic string, fn func()) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers[topic] = append(handlers[topic], fn)
+}
+
+type invalidation struct {
+	Topic  string `json:"topic"`
+	Origin string `json:"origin"`
+}
+
+// Publish runs the handlers subscribed to each topic and notifies other instances
+func Publish(topics ...string) {
+	d, prefix := current()
+	for _, topic := range topics {
+		runHandlers(topic)
+
+		message, _ := json.Marshal(invalidation{Topic: topic, Origin: instanceID})
+		if err := d.Publish(prefix+"invalidate", message); err != nil {
+			slog.Warn("Failed to broadcast cache invalidation", "topic", topic, "error", err)
+		}
+	}
+}
+
+func handleRemote(message []byte) {
+	var msg invalidation
+	if err := json.Unmarshal(message, &msg); err != nil || msg.Origin == instanceID {
+		return
+	}
+	runHandlers(msg.Topic)
+}
+
+func runHandlers(topic string) {
+	handlersMu.RLock()
+	fns := append([]func(){}, handlers[topic]...)
+	handlersMu.RUnlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestMemoryExpiryAndEviction(t *testing.T) {
+	m := NewMemory(2)
+	defer m.Close()
+
+	m.Set("a", []byte("1"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := m.Get("a"); ok {
+		t.Error("expired entry was returned")
+	}
+
+	m.Set("a", []byte("1"), 0)
+	m.Set("b", []byte("2"), 0)
+	m.Get("a")
+	m.Set("c", []byte("3"), 0)
+	if _, ok := m.Get("b"); ok {
+		t.Error("least accessed entry should have been evicted")
+	}
+	if _, ok := m.Get("a"); !ok {
+		t.Error("frequently accessed entry was evicted")
+	}
+}
+
+func TestNamespaceRoundTripAndClear(t *testing.T) {
+	type profile struct {
+		Name  string
+		Score float64
+	}
+	users := New("test-users", time.Minute)
+	other := New("test-other", time.Minute)
+	defer users.Clear()
+	defer other.Clear()
+
+	users.Set("alice", profile{Name: "alice", Score: 0.5})
+	users.Set("bob", &profile{Name: "bob"})
+	other.Set("alice", "unrelated")
+
+	var got profile
+	if !users.Get("alice", &got) || got.Name != "alice" || got.Score != 0.5 {
+		t.Fatalf("Get = %+v", got)
+	}
+	keys := users.Keys()
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "alice" || keys[1] != "bob" {
+		t.Errorf("Keys = %v", keys)
+	}
+
+	if !users.Take("alice", &got) || got.Name != "alice" {
+		t.Fatalf("Take = %+v", got)
+	}
+	if users.Take("alice", &got) || users.Get("alice", &got) {
+		t.Error("entry survived Take")
+	}
+
+	users.Clear()
+	if users.Get("bob", &got) {
+		t.Error("entry survived Clear")
+	}
+	var s string
+	if !other.Get("alice", &s) || s != "unrelated" {
+		t.Error("Clear removed entries from another namespace")
+	}
+}
+
+func TestNamespaceStatsAndPurge(t *testing.T) {
+	pages := New("test-pages", time.Minute)
+	// A second namespace of the same name shares its statistics
+	again := New("test-pages", time.Hour)
+	defer pages.Clear()
+
+	pages.Set("home", "<html>")
+	var page string
+	pages.Get("home", &page)
+	again.Get("about", &page)
+
+	var stats *NamespaceStats
+	for _, ns := range Namespaces() {
+		if ns.Name == "test-pages" {
+			stats = &ns
+		}
+	}
+	if stats == nil || stats.Entries != 1 || stats.Bytes == 0 || stats.Hits != 1 || stats.Misses != 1 ||
+		stats.HitRate != 0.5 || stats.TTLSeconds != 60 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if usage := pages.Usage("ho"); usage.Entries != 1 {
+		t.Errorf("Usage = %+v", usage)
+	}
+
+	if Purge("test-missing") {
+		t.Error("purged an unknown namespace")
+	}
+	if !Purge("test-pages") || pages.Get("home", &page) {
+		t.Error("entry survived Purge")
+	}
+}
+
+func TestPublishRunsLocalAndRemoteHandlers(t *testing.T) {
+	topic := "test-topic"
+	calls := 0
+	Subscribe(topic, func() { calls++ })
+
+	Publish(topic)
+	if calls != 1 {
+		t.Fatalf("local publish ran %d handlers", calls)
+	}
+
+	// Messages echoed back from our own publish are ignored
+	own, _ := json.Marshal(invalidation{Topic: topic, Origin: instanceID})
+	handleRemote(own)
+	if calls != 1 {
+		t.Errorf("own invalidation was handled twice")
+	}
+
+	remote, _ := json.Marshal(invalidation{Topic: topic, Origin: "other-instance"})
+	handleRemote(remote)
+	if calls != 2 {
+		t.Errorf("remote invalidation was not handled")
+	}
+}
+
+func TestEscapeGlob(t *testing.T) {
+	if got := escapeGlob("kuno:smart:search_[1]*"); got != `kuno:smart:search_\[1\]\*` {
+		t.Errorf("escapeGlob = %q", got)
+	}
+}
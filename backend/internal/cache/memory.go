@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	value       []byte
+	expiresAt   time.Time // zero never expires
+	accessCount int64
+	createdAt   time.Time
+}
+
+func (item *memoryItem) expired(now time.Time) bool {
+	return !item.expiresAt.IsZero() && now.After(item.expiresAt)
+}
+
+// Memory is an in-process driver. When full it evicts the least accessed
+// entry, oldest first among ties.
+type Memory struct {
+	mu       sync.Mutex
+	items    map[string]*memoryItem
+	maxItems int
+	hits     int64
+	misses   int64
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// NewMemory creates a memory driver holding at most maxItems entries
+func NewMemory(maxItems int) *Memory {
+	if maxItems <= 0 {
+		maxItems = 10000
+	}
+	m := &Memory{
+		items:    make(map[string]*memoryItem),
+		maxItems: maxItems,
+		stop:     make(chan struct{}),
+	}
+	go m.cleanup()
+	return m
+}
+
+// Name implements Driver
+func (m *Memory) Name() string {
+	return "memory"
+}
+
+// Get implements Driver
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[key]
+	if !ok || item.expired(time.Now()) {
+		if ok {
+			delete(m.items, key)
+		}
+		m.misses++
+		return nil, false
+	}
+	item.accessCount++
+	m.hits++
+	return item.value, true
+}
+
+// Take implements Driver
+func (m *Memory) Take(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[key]
+	delete(m.items, key)
+	if !ok || item.expired(time.Now()) {
+		m.misses++
+		return nil, false
+	}
+	m.hits++
+	return item.value, true
+}
+
+// Set implements Driver
+func (m *Memory) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.items[key]; !exists && len(m.items) >= m.maxItems {
+		m.evict()
+	}
+	now := time.Now()
+	item := &memoryItem{value: value, accessCount: 1, createdAt: now}
+	if ttl > 0 {
+		item.expiresAt = now.Add(ttl)
+	}
+	m.items[key] = item
+	return nil
+}
+
+// Delete implements Driver
+func (m *Memory) Delete(keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.items, key)
+	}
+	return nil
+}
+
+// DeletePrefix implements Driver
+func (m *Memory) DeletePrefix(prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.items {
+		if strings.HasPrefix(key, prefix) {
+			delete(m.items, key)
+		}
+	}
+	return nil
+}
+
+// Keys implements Driver
+func (m *Memory) Keys(prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var keys []string
+	for key, item := range m.items {
+		if strings.HasPrefix(key, prefix) && !item.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Usage implements Driver
+func (m *Memory) Usage(prefix string) (Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var usage Usage
+	for key, item := range m.items {
+		if strings.HasPrefix(key, prefix) && !item.expired(now) {
+			usage.Entries++
+			usage.Bytes += int64(len(key) + len(item.value))
+		}
+	}
+	return usage, nil
+}
+
+// Publish implements Driver. There are no other instances to notify.
+func (m *Memory) Publish(channel string, message []byte) error {
+	return nil
+}
+
+// Subscribe implements Driver. No messages arrive from other instances.
+func (m *Memory) Subscribe(channel string, handler func([]byte)) (func(), error) {
+	return func() {}, nil
+}
+
+// Stats implements Driver
+func (m *Memory) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := newStats(m.Name(), int64(len(m.items)), m.hits, m.misses)
+	for key, item := range m.items {
+		stats.Bytes += int64(len(key) + len(item.value))
+	}
+	return stats
+}
+
+// Close stops the expiry sweeper
+func (m *Memory) Close() error {
+	m.once.Do(func() { close(m.stop) })
+	return nil
+}
+
+// evict removes the least accessed entry. Callers hold mu.
+func (m *Memory) evict() {
+	var victim string
+	var victimItem *memoryItem
+	for key, item := range m.items {
+		if victimItem == nil || item.accessCount < victimItem.accessCount ||
+			(item.accessCount == victimItem.accessCount && item.createdAt.Before(victimItem.createdAt)) {
+			victim, victimItem = key, item
+		}
+	}
+	if victimItem != nil {
+		delete(m.items, victim)
+	}
+}
+
+// cleanup removes expired entries periodically
+func (m *Memory) cleanup() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-m.stop:
+			return
+		}
+		m.mu.Lock()
+		now := time.Now()
+		for key, item := range m.items {
+			if item.expired(now) {
+				delete(m.items, key)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+func newStats(driver string, entries, hits, misses int64) Stats {
+	stats := Stats{Driver: driver, Entries: entries, Hits: hits, Misses: misses}
+	if total := hits + misses; total > 0 {
+		stats.HitRate = float64(hits) / float64(total)
+	}
+	return stats
+}
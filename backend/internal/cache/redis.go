@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisTimeout = 2 * time.Second
+
+// Redis is a driver backed by a Redis server shared between instances.
+// Invalidations travel over Redis pub/sub.
+type Redis struct {
+	client *redis.Client
+	prefix string
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewRedis connects to the server at rawURL (redis:// or rediss://).
+// prefix scopes the keys counted in Stats.
+func NewRedis(rawURL, prefix string) (*Redis, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %v", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %v", err)
+	}
+	return &Redis{client: client, prefix: prefix}, nil
+}
+
+func redisContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), redisTimeout)
+}
+
+// Name implements Driver
+func (r *Redis) Name() string {
+	return "redis"
+}
+
+// Get implements Driver. Connection errors count as misses.
+func (r *Redis) Get(key string) ([]byte, bool) {
+	ctx, cancel := redisContext()
+	defer cancel()
+	value, err := r.client.Get(ctx, key).Bytes()
+	if err != nil {
+		r.misses.Add(1)
+		return nil, false
+	}
+	r.hits.Add(1)
+	return value, true
+}
+
+// Take implements Driver with GETDEL, which needs Redis 6.2 or later
+func (r *Redis) Take(key string) ([]byte, bool) {
+	ctx, cancel := redisContext()
+	defer cancel()
+	value, err := r.client.GetDel(ctx, key).Bytes()
+	if err != nil {
+		r.misses.Add(1)
+		return nil, false
+	}
+	r.hits.Add(1)
+	return value, true
+}
+
+// Set implements Driver
+func (r *Redis) Set(key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := redisContext()
+	defer cancel()
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+// Delete implements Driver
+func (r *Redis) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	ctx, cancel := redisContext()
+	defer cancel()
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// DeletePrefix implements Driver
+func (r *Redis) DeletePrefix(prefix string) error {
+	keys, err := r.Keys(prefix)
+	if err != nil {
+		return err
+	}
+	for len(keys) > 0 {
+		n := min(len(keys), 500)
+		if err := r.Delete(keys[:n]...); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
+
+// Keys implements Driver. It uses SCAN so large keyspaces don't block the server.
+func (r *Redis) Keys(prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*redisTimeout)
+	defer cancel()
+
+	var keys []string
+	iter := r.client.Scan(ctx, 0, escapeGlob(prefix)+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// Usage implements Driver. The sizes are read in pipelined batches of
+// STRLEN, so it costs a round trip per 500 keys.
+func (r *Redis) Usage(prefix string) (Usage, error) {
+	keys, err := r.Keys(prefix)
+	if err != nil {
+		return Usage{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*redisTimeout)
+	defer cancel()
+
+	var usage Usage
+	for len(keys) > 0 {
+		n := min(len(keys), 500)
+		pipe := r.client.Pipeline()
+		lengths := make([]*redis.IntCmd, n)
+		for i, key := range keys[:n] {
+			lengths[i] = pipe.StrLen(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return usage, err
+		}
+		for i, length := range lengths {
+			// Keys that expired since the scan have no length
+			if length.Val() > 0 {
+				usage.Entries++
+				usage.Bytes += int64(len(keys[i])) + length.Val()
+			}
+		}
+		keys = keys[n:]
+	}
+	return usage, nil
+}
+
+// Publish implements Driver
+func (r *Redis) Publish(channel string, message []byte) error {
+	ctx, cancel := redisContext()
+	defer cancel()
+	return r.client.Publish(ctx, channel, message).Err()
+}
+
+// Subscribe implements Driver. The subscription reconnects on its own
+// until stop is called.
+func (r *Redis) Subscribe(channel string, handler func([]byte)) (func(), error) {
+	ctx, cancel := redisContext()
+	defer cancel()
+
+	pubsub := r.client.Subscribe(context.Background(), channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	go func() {
+		for msg := range pubsub.Channel() {
+			handler([]byte(msg.Payload))
+		}
+	}()
+	return func() { pubsub.Close() }, nil
+}
+
+// Stats implements Driver
+func (r *Redis) Stats() Stats {
+	usage, _ := r.Usage(r.prefix)
+	stats := newStats(r.Name(), usage.Entries, r.hits.Load(), r.misses.Load())
+	stats.Bytes = usage.Bytes
+	return stats
+}
+
+// Close implements Driver
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+// escapeGlob escapes the characters SCAN MATCH treats as wildcards
+func escapeGlob(s string) string {
+	var escaped []byte
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '*', '?', '[', ']', '\\':
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, s[i])
+	}
+	return string(escaped)
+}
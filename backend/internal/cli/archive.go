@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/services"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// archive exports and imports the default site as a portable .kuno file
+func archive(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "export":
+		flags := flag.NewFlagSet("export", flag.ExitOnError)
+		out := flags.String("out", fmt.Sprintf("kuno-site-%s%s", time.Now().Format("2006-01-02"), services.SiteArchiveExtension), "output file")
+		remote := flags.Bool("remote", false, "store the archive in remote storage under exports/ instead")
+		flags.Parse(args[1:])
+
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		file, err := createOutput(*out, *remote)
+		if err != nil {
+			return err
+		}
+		manifest, err := services.NewSiteArchive(db, config.Get().Storage.UploadDir, "").Export(file)
+		if err != nil {
+			discardOutput(file)
+			return err
+		}
+		stored, err := storeOutput(file, *out, *remote)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d articles, %d categories and %d media files to %s\n",
+			manifest.Tables["articles"], manifest.Tables["categories"], manifest.Media, stored)
+		return nil
+	case "import":
+		flags := flag.NewFlagSet("import", flag.ExitOnError)
+		keepSettings := flags.Bool("keep-settings", false, "keep the current site settings instead of the archive's")
+		flags.Parse(args[1:])
+		if flags.NArg() != 1 {
+			return errUsage
+		}
+
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+		// A new database, such as a backend being moved to, gets its schema first
+		if err := database.Migrate(db); err != nil {
+			return err
+		}
+
+		result, err := services.NewSiteArchive(db, config.Get().Storage.UploadDir, "").Import(file, info.Size(), !*keepSettings)
+		if err != nil {
+			return err
+		}
+		tables := make([]string, 0, len(result.Created)+len(result.Skipped))
+		seen := map[string]bool{}
+		for _, counts := range []map[string]int{result.Created, result.Skipped} {
+			for table := range counts {
+				if !seen[table] {
+					seen[table] = true
+					tables = append(tables, table)
+				}
+			}
+		}
+		sort.Strings(tables)
+		for _, table := range tables {
+			fmt.Printf("%-30s %d created, %d already present\n", table, result.Created[table], result.Skipped[table])
+		}
+		fmt.Printf("%-30s %d written, %d already present\n", "media files", result.MediaWritten, result.MediaSkipped)
+		return nil
+	default:
+		return errUsage
+	}
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/services"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// backup restores in place while the server may be running; the server
+// keeps data it has cached in memory until it is restarted
+func backup(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	if _, err := openDatabase(); err != nil {
+		return err
+	}
+	defer database.Close()
+
+	backups := services.NewBackupService()
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("create", flag.ExitOnError)
+		offsite := flags.Bool("offsite", false, "run the scheduled backup: verify, upload to BACKUP_TARGET and apply retention")
+		flags.Parse(args[1:])
+
+		if *offsite {
+			result, err := backups.RunScheduledBackup()
+			if err != nil {
+				return err
+			}
+			if result.Uploaded {
+				fmt.Printf("Created %s (%d bytes) and copied it to %s\n", result.Name, result.Size, result.Target)
+			} else {
+				fmt.Printf("Created %s (%d bytes)\n", result.Name, result.Size)
+			}
+			for _, name := range result.Pruned {
+				fmt.Println("Pruned", name)
+			}
+			return nil
+		}
+		backup, err := backups.Create()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s (%d bytes)\n", backup.Name, backup.Size)
+		return nil
+	case "list":
+		return printBackups(backups)
+	case "restore":
+		if len(args) < 2 {
+			return errUsage
+		}
+		safety, err := backups.Restore(args[1])
+		if safety != "" {
+			fmt.Println("Saved current state as", safety)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println("Restored", args[1])
+		return nil
+	default:
+		return errUsage
+	}
+}
+
+func printBackups(backups *services.BackupService) error {
+	list, err := backups.List()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Println("No backups in", backups.Dir())
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCREATED AT\tSIZE\tFILES")
+	for _, backup := range list {
+		files := ""
+		if backup.Manifest != nil {
+			files = fmt.Sprint(backup.Manifest.Files)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", backup.Name, backup.CreatedAt.Format("2006-01-02 15:04:05"), backup.Size, files)
+	}
+	return w.Flush()
+}
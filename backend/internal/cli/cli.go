@@ -0,0 +1,98 @@
+// Package cli implements the kuno admin command. The standalone migrate and
+// backup commands run the same subcommands. Every command reads the same
+// configuration as the server (config file and environment).
+package cli
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// errUsage makes a command print its usage and exit with status 2
+var errUsage = errors.New("usage")
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"reset-password", "reset-password [-username admin] [-password P]", "set a user's password and sign out their sessions", resetPassword},
+	{"migrate", "migrate status | up | down [-steps N]", "manage versioned schema migrations", migrate},
+	{"backup", "backup create [-offsite] | list | restore <name>", "create, list and restore backup archives", backup},
+	{"embeddings", "embeddings reindex [-missing] [-rebuild]", "regenerate article embeddings for semantic search", embeddings},
+	{"export", "export [-out FILE] [-lang LANG] [-flat] [-remote]", "export all articles as markdown in a zip archive", export},
+	{"archive", "archive export [-out FILE] [-remote] | import [-keep-settings] FILE", "export or import the site as a portable .kuno archive", archive},
+	{"maintenance", "maintenance on [-message M] [-retry-after SECONDS] | off | status", "switch read-only maintenance mode", maintenance},
+	{"features", "features list | enable NAME | disable NAME | reset NAME", "list and switch experimental feature flags", features},
+}
+
+// Main runs the kuno subcommand named by args[0] and exits on failure
+func Main(args []string) {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printHelp()
+		os.Exit(2)
+	}
+	Run(args[0], args[1:])
+}
+
+// Run runs one subcommand with its arguments and exits on failure
+func Run(name string, args []string) {
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(args)
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "usage:", cmd.usage)
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	printHelp()
+	os.Exit(2)
+}
+
+func printHelp() {
+	var b strings.Builder
+	b.WriteString("usage: kuno <command> [arguments]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "  %-16s %s\n", cmd.name, cmd.summary)
+	}
+	b.WriteString("\nRun with the same config file or environment as the server.\n")
+	fmt.Fprint(os.Stderr, b.String())
+}
+
+// openDatabase connects to the configured database and makes it the
+// global connection used by the services
+func openDatabase() (*gorm.DB, error) {
+	cfg := config.Get()
+	if err := config.LoadError(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+
+	dialector, err := database.OpenDialector(cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure database: %v", err)
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %v", err)
+	}
+	database.DB = db
+	return db, nil
+}
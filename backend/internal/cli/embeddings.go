@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"context"
+	"flag"
+	"fmt"
+)
+
+// embeddings regenerates embeddings in this process, so it works while the
+// server is stopped. AI provider settings are read from the database.
+func embeddings(args []string) error {
+	if len(args) == 0 || args[0] != "reindex" {
+		return errUsage
+	}
+	flags := flag.NewFlagSet("reindex", flag.ExitOnError)
+	missing := flags.Bool("missing", false, "only process articles that have no embeddings")
+	rebuild := flags.Bool("rebuild", false, "delete all embeddings before reindexing")
+	flags.Parse(args[1:])
+	if *missing && *rebuild {
+		return fmt.Errorf("-missing and -rebuild cannot be combined")
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	es := services.NewEmbeddingService()
+	switch {
+	case *missing:
+		var count int64
+		db.Model(&models.Article{}).Count(&count)
+		if err := es.BatchProcessMissingEmbeddings(context.Background(), int(count)); err != nil {
+			return err
+		}
+	case *rebuild:
+		if err := db.Exec("DELETE FROM article_embeddings").Error; err != nil {
+			return fmt.Errorf("failed to clear existing embeddings: %v", err)
+		}
+		fallthrough
+	default:
+		if err := es.BatchProcessAllArticles(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	var total int64
+	db.Model(&models.ArticleEmbedding{}).Count(&total)
+	fmt.Printf("Reindex complete: %d embeddings stored\n", total)
+	return nil
+}
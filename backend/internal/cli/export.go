@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/services"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	out := flags.String("out", fmt.Sprintf("blog-export-%s.zip", time.Now().Format("2006-01-02")), `output file, or "-" for stdout`)
+	lang := flags.String("lang", "zh", "language to export; articles without a translation keep their original text")
+	flat := flags.Bool("flat", false, "put every article at the top level instead of one folder per category")
+	remote := flags.Bool("remote", false, "store the export in remote storage under exports/ instead")
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		return errUsage
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	var articles []models.Article
+	if err := db.Preload("Category").Preload("Translations").Find(&articles).Error; err != nil {
+		return fmt.Errorf("failed to fetch articles: %v", err)
+	}
+
+	if *out == "-" && !*remote {
+		return services.WriteArticlesZip(os.Stdout, articles, *lang, !*flat)
+	}
+
+	file, err := createOutput(*out, *remote)
+	if err != nil {
+		return err
+	}
+	if err := services.WriteArticlesZip(file, articles, *lang, !*flat); err != nil {
+		discardOutput(file)
+		return err
+	}
+	stored, err := storeOutput(file, *out, *remote)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d articles to %s\n", len(articles), stored)
+	return nil
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/services"
+	"fmt"
+)
+
+// features lists and switches feature flags. Running servers pick up the
+// change within a few seconds.
+func features(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	if _, err := openDatabase(); err != nil {
+		return err
+	}
+	defer database.Close()
+
+	service := services.NewFeatureService()
+	switch args[0] {
+	case "list":
+		for _, feature := range service.List() {
+			state := "off"
+			if feature.Enabled {
+				state = "on"
+			}
+			fmt.Printf("%-12s %-3s  (%s)  %s\n", feature.Name, state, feature.Source, feature.Description)
+		}
+	case "enable", "disable", "reset":
+		if len(args) != 2 {
+			return errUsage
+		}
+		var feature services.Feature
+		var err error
+		if args[0] == "reset" {
+			feature, err = service.Reset(args[1])
+		} else {
+			feature, err = service.Set(args[1], args[0] == "enable")
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Feature %s is now %v (%s)\n", feature.Name, map[bool]string{true: "on", false: "off"}[feature.Enabled], feature.Source)
+	default:
+		return errUsage
+	}
+	return nil
+}
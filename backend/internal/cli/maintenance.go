@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/services"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// maintenance switches read-only maintenance mode. Running servers pick up
+// the change within a few seconds.
+func maintenance(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	if _, err := openDatabase(); err != nil {
+		return err
+	}
+	defer database.Close()
+
+	service := services.NewMaintenanceService()
+	switch args[0] {
+	case "on":
+		flags := flag.NewFlagSet("on", flag.ExitOnError)
+		message := flags.String("message", "", "message shown to visitors")
+		retryAfter := flags.Int("retry-after", 0, "seconds clients should wait before retrying (default 300)")
+		flags.Parse(args[1:])
+
+		state, err := service.Enable(*message, *retryAfter)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Maintenance mode enabled (Retry-After: %ds)\n", state.RetryAfter)
+	case "off":
+		if err := service.Disable(); err != nil {
+			return err
+		}
+		fmt.Println("Maintenance mode disabled")
+	case "status":
+		state := service.State()
+		if !state.Enabled {
+			fmt.Println("Maintenance mode is off")
+			return nil
+		}
+		fmt.Printf("Maintenance mode is on since %s (Retry-After: %ds)\n", state.Since.Format(time.RFC3339), state.RetryAfter)
+		if state.Message != "" {
+			fmt.Println("Message:", state.Message)
+		}
+	default:
+		return errUsage
+	}
+	return nil
+}
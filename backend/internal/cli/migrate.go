@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"blog-backend/internal/database"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func migrate(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	switch args[0] {
+	case "status":
+		statuses, err := database.GetMigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSTATUS\tAPPLIED AT\tDESCRIPTION")
+		for _, status := range statuses {
+			state, appliedAt := "pending", ""
+			if status.Applied {
+				state = "applied"
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.ID, state, appliedAt, status.Description)
+		}
+		return w.Flush()
+	case "up":
+		if err := database.Migrate(db); err != nil {
+			return err
+		}
+		fmt.Println("All migrations applied")
+		return nil
+	case "down":
+		flags := flag.NewFlagSet("down", flag.ExitOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		flags.Parse(args[1:])
+
+		reverted, err := database.Rollback(db, *steps)
+		for _, id := range reverted {
+			fmt.Println("Reverted", id)
+		}
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("Nothing to revert")
+		}
+		return nil
+	default:
+		return errUsage
+	}
+}
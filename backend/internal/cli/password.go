@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minPasswordLength matches the change-password API
+const minPasswordLength = 6
+
+// resetPassword replaces RECOVERY_MODE: it sets a new password without
+// restarting the server and revokes the user's existing sessions
+func resetPassword(args []string) error {
+	flags := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	username := flags.String("username", "admin", "user whose password is reset")
+	password := flags.String("password", "", "new password (a random one is generated if empty)")
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		return errUsage
+	}
+
+	generated := *password == ""
+	if generated {
+		buf := make([]byte, 12)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		*password = base64.RawURLEncoding.EncodeToString(buf)
+	}
+	if len(*password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	var user models.User
+	result := db.Where("username = ?", *username).Limit(1).Find(&user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user %q not found", *username)
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := db.Model(&user).Update("password", string(hashed)).Error; err != nil {
+		return err
+	}
+
+	revoked := db.Model(&models.LoginSession{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Update("revoked_at", time.Now())
+	if revoked.Error != nil {
+		return fmt.Errorf("password updated but sessions were not revoked: %v", revoked.Error)
+	}
+
+	fmt.Printf("Password for %s has been reset; %d active sessions signed out\n", user.Username, revoked.RowsAffected)
+	if generated {
+		fmt.Println("New password:", *password)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"blog-backend/internal/storage"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// createOutput opens the file an export is written to. With remote set it
+// is a temporary file that storeOutput uploads and removes.
+func createOutput(out string, remote bool) (*os.File, error) {
+	if !remote {
+		return os.Create(out)
+	}
+	if err := storage.DriverError(); err != nil {
+		return nil, err
+	}
+	if !storage.IsRemote() {
+		return nil, fmt.Errorf("-remote needs a remote STORAGE_DRIVER")
+	}
+	return os.CreateTemp("", ".kuno-export-*")
+}
+
+// storeOutput closes a finished export and, for remote exports, uploads it
+// to exports/ in remote storage. It returns where the export ended up.
+func storeOutput(file *os.File, out string, remote bool) (string, error) {
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	if !remote {
+		return out, nil
+	}
+	defer os.Remove(file.Name())
+
+	upload, err := os.Open(file.Name())
+	if err != nil {
+		return "", err
+	}
+	defer upload.Close()
+	info, err := upload.Stat()
+	if err != nil {
+		return "", err
+	}
+	driver := storage.GetGlobalDriver()
+	key := path.Join("exports", filepath.Base(out))
+	if err := driver.Put(key, upload, info.Size(), mime.TypeByExtension(filepath.Ext(out))); err != nil {
+		return "", err
+	}
+	return driver.Name() + "://" + key, nil
+}
+
+// discardOutput removes an export that failed
+func discardOutput(file *os.File) {
+	file.Close()
+	os.Remove(file.Name())
+}
@@ -0,0 +1,1529 @@
+package config
+
+import (
+	"blog-backend/internal/cron"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the typed server configuration. Values come from built-in
+// defaults, then an optional YAML/TOML file, then environment variables.
+// Every field is tagged with the environment variable that overrides it;
+// fields tagged secret:"true" are redacted in introspection output.
+type Config struct {
+	Server      ServerConfig      `yaml:"server" toml:"server" json:"server"`
+	Database    DatabaseConfig    `yaml:"database" toml:"database" json:"database"`
+	Storage     StorageConfig     `yaml:"storage" toml:"storage" json:"storage"`
+	Backup      BackupConfig      `yaml:"backup" toml:"backup" json:"backup"`
+	Cache       CacheConfig       `yaml:"cache" toml:"cache" json:"cache"`
+	Jobs        JobsConfig        `yaml:"jobs" toml:"jobs" json:"jobs"`
+	Tracking    TrackingConfig    `yaml:"tracking" toml:"tracking" json:"tracking"`
+	Auth        AuthConfig        `yaml:"auth" toml:"auth" json:"auth"`
+	AI          AIConfig          `yaml:"ai" toml:"ai" json:"ai"`
+	Logging     LoggingConfig     `yaml:"logging" toml:"logging" json:"logging"`
+	Sanitize    SanitizeConfig    `yaml:"sanitize" toml:"sanitize" json:"sanitize"`
+	CustomCode  CustomCodeConfig  `yaml:"custom_code" toml:"custom_code" json:"custom_code"`
+	Secrets     SecretsConfig     `yaml:"secrets" toml:"secrets" json:"secrets"`
+	Alerts      AlertsConfig      `yaml:"alerts" toml:"alerts" json:"alerts"`
+	SMTP        SMTPConfig        `yaml:"smtp" toml:"smtp" json:"smtp"`
+	Features    FeaturesConfig    `yaml:"features" toml:"features" json:"features"`
+	Metrics     MetricsConfig     `yaml:"metrics" toml:"metrics" json:"metrics"`
+	Monitor     MonitorConfig     `yaml:"monitor" toml:"monitor" json:"monitor"`
+	Newsletter  NewsletterConfig  `yaml:"newsletter" toml:"newsletter" json:"newsletter"`
+	ActivityPub ActivityPubConfig `yaml:"activitypub" toml:"activitypub" json:"activitypub"`
+	WebSub      WebSubConfig      `yaml:"websub" toml:"websub" json:"websub"`
+	Comments    CommentsConfig    `yaml:"comments" toml:"comments" json:"comments"`
+	Publish     PublishConfig     `yaml:"publish" toml:"publish" json:"publish"`
+	Highlight   HighlightConfig   `yaml:"highlight" toml:"highlight" json:"highlight"`
+	Diagrams    DiagramsConfig    `yaml:"diagrams" toml:"diagrams" json:"diagrams"`
+	Contact     ContactConfig     `yaml:"contact" toml:"contact" json:"contact"`
+	FriendLinks FriendLinksConfig `yaml:"friend_links" toml:"friend_links" json:"friend_links"`
+	FeedImport  FeedImportConfig  `yaml:"feed_import" toml:"feed_import" json:"feed_import"`
+	Moments     MomentsConfig     `yaml:"moments" toml:"moments" json:"moments"`
+	Guestbook   GuestbookConfig   `yaml:"guestbook" toml:"guestbook" json:"guestbook"`
+	Share       ShareConfig       `yaml:"share" toml:"share" json:"share"`
+	ShareCounts ShareCountsConfig `yaml:"share_counts" toml:"share_counts" json:"share_counts"`
+	APIKeys     APIKeysConfig     `yaml:"api_keys" toml:"api_keys" json:"api_keys"`
+	KeywordData KeywordDataConfig `yaml:"keyword_data" toml:"keyword_data" json:"keyword_data"`
+
+	Notifications NotificationsConfig `yaml:"notifications" toml:"notifications" json:"notifications"`
+	Members       MembersConfig       `yaml:"members" toml:"members" json:"members"`
+	Hotlink       HotlinkConfig       `yaml:"hotlink" toml:"hotlink" json:"hotlink"`
+	Health        HealthConfig        `yaml:"health" toml:"health" json:"health"`
+	Warmup        WarmupConfig        `yaml:"warmup" toml:"warmup" json:"warmup"`
+	CommentBridge CommentBridgeConfig `yaml:"comment_bridge" toml:"comment_bridge" json:"comment_bridge"`
+
+	Recommendations RecommendationsConfig `yaml:"recommendations" toml:"recommendations" json:"recommendations"`
+}
+
+// ServerConfig holds HTTP server settings. MultiSite serves several blogs
+// from one instance, selected by the request's Host header.
+type ServerConfig struct {
+	Port            string   `yaml:"port" toml:"port" json:"port" env:"PORT"`
+	GinMode         string   `yaml:"gin_mode" toml:"gin_mode" json:"gin_mode" env:"GIN_MODE"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	MultiSite       bool     `yaml:"multi_site" toml:"multi_site" json:"multi_site" env:"MULTI_SITE"`
+	// PublicURL is the canonical address of the blog, including any subpath
+	// it is served under. When empty it is detected from each request.
+	PublicURL string `yaml:"public_url" toml:"public_url" json:"public_url" env:"PUBLIC_URL"`
+	// TrustedCDN names the CDN every request comes through, cloudflare or
+	// cloudfront, whose visitor country header is then believed. Without
+	// it such headers are ignored, since any client can send them.
+	TrustedCDN string `yaml:"trusted_cdn" toml:"trusted_cdn" json:"trusted_cdn" env:"TRUSTED_CDN"`
+}
+
+// DatabaseConfig holds database settings. Path is used by the sqlite
+// driver; postgres and mysql connect with DSN instead.
+type DatabaseConfig struct {
+	Driver       string `yaml:"driver" toml:"driver" json:"driver" env:"DB_DRIVER"`
+	Path         string `yaml:"path" toml:"path" json:"path" env:"DB_PATH"`
+	DSN          string `yaml:"dsn" toml:"dsn" json:"dsn" env:"DB_DSN" secret:"true"`
+	MaxOpenConns int    `yaml:"max_open_conns" toml:"max_open_conns" json:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns int    `yaml:"max_idle_conns" toml:"max_idle_conns" json:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	RecoveryMode bool   `yaml:"recovery_mode" toml:"recovery_mode" json:"recovery_mode" env:"RECOVERY_MODE"`
+
+	// SQLite connection settings, applied to every pooled connection
+	JournalMode string   `yaml:"journal_mode" toml:"journal_mode" json:"journal_mode" env:"DB_JOURNAL_MODE"`
+	Synchronous string   `yaml:"synchronous" toml:"synchronous" json:"synchronous" env:"DB_SYNCHRONOUS"`
+	BusyTimeout Duration `yaml:"busy_timeout" toml:"busy_timeout" json:"busy_timeout" env:"DB_BUSY_TIMEOUT"`
+	ForeignKeys bool     `yaml:"foreign_keys" toml:"foreign_keys" json:"foreign_keys" env:"DB_FOREIGN_KEYS"`
+
+	// OptimizeSchedule is the cron schedule for the integrity check, ANALYZE
+	// and VACUUM run; empty disables it
+	OptimizeSchedule string `yaml:"optimize_schedule" toml:"optimize_schedule" json:"optimize_schedule" env:"DB_OPTIMIZE_SCHEDULE"`
+}
+
+// StorageConfig holds upload and object storage settings. DiskWarnPercent
+// is the volume usage, in percent, at which the admin dashboard warns that
+// the disk is close to full; 0 disables the warning. Driver selects where
+// media, off-site backups and exports are stored: local keeps media in
+// UploadDir, while s3, azure, gcs and webdav store them remotely.
+type StorageConfig struct {
+	UploadDir       string       `yaml:"upload_dir" toml:"upload_dir" json:"upload_dir" env:"UPLOAD_DIR"`
+	DiskWarnPercent int          `yaml:"disk_warn_percent" toml:"disk_warn_percent" json:"disk_warn_percent" env:"DISK_WARN_PERCENT"`
+	Driver          string       `yaml:"driver" toml:"driver" json:"driver" env:"STORAGE_DRIVER"`
+	S3              S3Config     `yaml:"s3" toml:"s3" json:"s3"`
+	Azure           AzureConfig  `yaml:"azure" toml:"azure" json:"azure"`
+	GCS             GCSConfig    `yaml:"gcs" toml:"gcs" json:"gcs"`
+	WebDAV          WebDAVConfig `yaml:"webdav" toml:"webdav" json:"webdav"`
+}
+
+// BackupConfig holds backup archive settings. An empty Dir keeps archives
+// in a backups directory next to the SQLite database. When Schedule is set
+// backups are created on that cron schedule and, if Target is set, copied
+// to the off-site location at TargetURL.
+type BackupConfig struct {
+	Dir            string `yaml:"dir" toml:"dir" json:"dir" env:"BACKUP_DIR"`
+	Schedule       string `yaml:"schedule" toml:"schedule" json:"schedule" env:"BACKUP_SCHEDULE"`
+	Target         string `yaml:"target" toml:"target" json:"target" env:"BACKUP_TARGET"`
+	TargetURL      string `yaml:"target_url" toml:"target_url" json:"target_url" env:"BACKUP_TARGET_URL"`
+	TargetUsername string `yaml:"target_username" toml:"target_username" json:"target_username" env:"BACKUP_TARGET_USERNAME"`
+	TargetPassword string `yaml:"target_password" toml:"target_password" json:"target_password" env:"BACKUP_TARGET_PASSWORD" secret:"true"`
+	RetentionCount int    `yaml:"retention_count" toml:"retention_count" json:"retention_count" env:"BACKUP_RETENTION_COUNT"`
+	RetentionDays  int    `yaml:"retention_days" toml:"retention_days" json:"retention_days" env:"BACKUP_RETENTION_DAYS"`
+}
+
+// CacheConfig holds the shared cache backend. The memory driver keeps
+// entries in-process; redis shares entries and invalidations between
+// instances. With a positive HTTPTTL the responses of busy public
+// endpoints are cached too.
+type CacheConfig struct {
+	Driver   string   `yaml:"driver" toml:"driver" json:"driver" env:"CACHE_DRIVER"`
+	RedisURL string   `yaml:"redis_url" toml:"redis_url" json:"redis_url" env:"REDIS_URL" secret:"true"`
+	Prefix   string   `yaml:"prefix" toml:"prefix" json:"prefix" env:"CACHE_PREFIX"`
+	MaxItems int      `yaml:"max_items" toml:"max_items" json:"max_items" env:"CACHE_MAX_ITEMS"`
+	HTTPTTL  Duration `yaml:"http_ttl" toml:"http_ttl" json:"http_ttl" env:"HTTP_CACHE_TTL"`
+}
+
+// JobsConfig holds background job queue settings. A job still running
+// after Timeout is assumed lost (for example to a crash) and is retried.
+type JobsConfig struct {
+	Workers       int      `yaml:"workers" toml:"workers" json:"workers" env:"JOB_WORKERS"`
+	MaxAttempts   int      `yaml:"max_attempts" toml:"max_attempts" json:"max_attempts" env:"JOB_MAX_ATTEMPTS"`
+	Timeout       Duration `yaml:"timeout" toml:"timeout" json:"timeout" env:"JOB_TIMEOUT"`
+	RetentionDays int      `yaml:"retention_days" toml:"retention_days" json:"retention_days" env:"JOB_RETENTION_DAYS"`
+}
+
+// TrackingConfig holds the queue of reader behavior waiting to be saved in
+// batches. Overflow decides what happens to behavior tracked while
+// QueueSize entries wait: "sync" saves it during the request, "drop"
+// discards it and "spill" appends it to a file in SpillDir, saved once the
+// queue has room again. An empty SpillDir is a behavior-spill directory
+// next to the SQLite database.
+//
+// Views and behavior are attributed to visitor IDs hashed with VisitorSalt.
+// VisitorRotation "daily" derives a new key every day, so a visitor cannot
+// be followed from one day to the next. StrictPrivacy rotates daily, keeps
+// no IP address, user agent, region, city or session ID with what it tracks
+// and ignores the user IDs sent by readers.
+type TrackingConfig struct {
+	QueueSize int    `yaml:"queue_size" toml:"queue_size" json:"queue_size" env:"BEHAVIOR_QUEUE_SIZE"`
+	Overflow  string `yaml:"overflow" toml:"overflow" json:"overflow" env:"BEHAVIOR_QUEUE_OVERFLOW"`
+	SpillDir  string `yaml:"spill_dir" toml:"spill_dir" json:"spill_dir" env:"BEHAVIOR_SPILL_DIR"`
+
+	VisitorSalt     string `yaml:"visitor_salt" toml:"visitor_salt" json:"visitor_salt" env:"VISITOR_ID_SALT" secret:"true"`
+	VisitorRotation string `yaml:"visitor_rotation" toml:"visitor_rotation" json:"visitor_rotation" env:"VISITOR_ID_ROTATION"`
+	StrictPrivacy   bool   `yaml:"strict_privacy" toml:"strict_privacy" json:"strict_privacy" env:"TRACKING_STRICT_PRIVACY"`
+}
+
+// RecommendationsConfig holds how personalized recommendations are ranked. With
+// DeviceAware, recommendations lean towards the reading length that suits
+// the reader's device: the length of what they finish on it, or else
+// short reads on phones and long-form on desktops.
+type RecommendationsConfig struct {
+	DeviceAware bool `yaml:"device_aware" toml:"device_aware" json:"device_aware" env:"RECOMMENDATIONS_DEVICE_AWARE"`
+}
+
+// S3Config holds S3-compatible object storage settings
+type S3Config struct {
+	Bucket          string `yaml:"bucket" toml:"bucket" json:"bucket" env:"S3_BUCKET"`
+	Region          string `yaml:"region" toml:"region" json:"region" env:"S3_REGION"`
+	Endpoint        string `yaml:"endpoint" toml:"endpoint" json:"endpoint" env:"S3_ENDPOINT"`
+	AccessKeyID     string `yaml:"access_key_id" toml:"access_key_id" json:"access_key_id" env:"S3_ACCESS_KEY_ID" secret:"true"`
+	SecretAccessKey string `yaml:"secret_access_key" toml:"secret_access_key" json:"secret_access_key" env:"S3_SECRET_ACCESS_KEY" secret:"true"`
+	PublicURL       string `yaml:"public_url" toml:"public_url" json:"public_url" env:"S3_PUBLIC_URL"`
+	Prefix          string `yaml:"prefix" toml:"prefix" json:"prefix" env:"S3_PREFIX"`
+	ForcePathStyle  bool   `yaml:"force_path_style" toml:"force_path_style" json:"force_path_style" env:"S3_FORCE_PATH_STYLE"`
+}
+
+// AzureConfig holds Azure Blob Storage settings. Endpoint defaults to the
+// account's blob endpoint; set it for Azurite or sovereign clouds.
+type AzureConfig struct {
+	Account   string `yaml:"account" toml:"account" json:"account" env:"AZURE_STORAGE_ACCOUNT"`
+	Key       string `yaml:"key" toml:"key" json:"key" env:"AZURE_STORAGE_KEY" secret:"true"`
+	Container string `yaml:"container" toml:"container" json:"container" env:"AZURE_STORAGE_CONTAINER"`
+	Endpoint  string `yaml:"endpoint" toml:"endpoint" json:"endpoint" env:"AZURE_STORAGE_ENDPOINT"`
+	PublicURL string `yaml:"public_url" toml:"public_url" json:"public_url" env:"AZURE_STORAGE_PUBLIC_URL"`
+	Prefix    string `yaml:"prefix" toml:"prefix" json:"prefix" env:"AZURE_STORAGE_PREFIX"`
+}
+
+// GCSConfig holds Google Cloud Storage settings. Without a credentials
+// file the service account of the GCE or GKE instance is used.
+type GCSConfig struct {
+	Bucket          string `yaml:"bucket" toml:"bucket" json:"bucket" env:"GCS_BUCKET"`
+	CredentialsFile string `yaml:"credentials_file" toml:"credentials_file" json:"credentials_file" env:"GCS_CREDENTIALS_FILE"`
+	Endpoint        string `yaml:"endpoint" toml:"endpoint" json:"endpoint" env:"GCS_ENDPOINT"`
+	PublicURL       string `yaml:"public_url" toml:"public_url" json:"public_url" env:"GCS_PUBLIC_URL"`
+	Prefix          string `yaml:"prefix" toml:"prefix" json:"prefix" env:"GCS_PREFIX"`
+}
+
+// WebDAVConfig holds the WebDAV collection objects are stored in
+type WebDAVConfig struct {
+	URL       string `yaml:"url" toml:"url" json:"url" env:"WEBDAV_URL"`
+	Username  string `yaml:"username" toml:"username" json:"username" env:"WEBDAV_USERNAME"`
+	Password  string `yaml:"password" toml:"password" json:"password" env:"WEBDAV_PASSWORD" secret:"true"`
+	PublicURL string `yaml:"public_url" toml:"public_url" json:"public_url" env:"WEBDAV_PUBLIC_URL"`
+}
+
+// AuthConfig holds authentication settings
+type AuthConfig struct {
+	JWTSecret    string         `yaml:"jwt_secret" toml:"jwt_secret" json:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	MasterKey    string         `yaml:"master_key" toml:"master_key" json:"master_key" env:"BLOG_MASTER_KEY" secret:"true"`
+	SecretPhrase string         `yaml:"secret_phrase" toml:"secret_phrase" json:"secret_phrase" env:"BLOG_SECRET_PHRASE" secret:"true"`
+	WebAuthn     WebAuthnConfig `yaml:"webauthn" toml:"webauthn" json:"webauthn"`
+}
+
+// WebAuthnConfig holds passkey relying party settings
+type WebAuthnConfig struct {
+	RPID                    string   `yaml:"rp_id" toml:"rp_id" json:"rp_id" env:"WEBAUTHN_RP_ID"`
+	RPName                  string   `yaml:"rp_name" toml:"rp_name" json:"rp_name" env:"WEBAUTHN_RP_NAME"`
+	Origins                 []string `yaml:"origins" toml:"origins" json:"origins" env:"WEBAUTHN_ORIGINS"`
+	RequireUserVerification bool     `yaml:"require_user_verification" toml:"require_user_verification" json:"require_user_verification" env:"WEBAUTHN_REQUIRE_USER_VERIFICATION"`
+}
+
+// AIConfig holds environment-level AI provider keys and automation
+type AIConfig struct {
+	OpenAIAPIKey         string `yaml:"openai_api_key" toml:"openai_api_key" json:"openai_api_key" env:"OPENAI_API_KEY" secret:"true"`
+	OpenAIEmbeddingModel string `yaml:"openai_embedding_model" toml:"openai_embedding_model" json:"openai_embedding_model" env:"OPENAI_EMBEDDING_MODEL"`
+	GeminiAPIKey         string `yaml:"gemini_api_key" toml:"gemini_api_key" json:"gemini_api_key" env:"GEMINI_API_KEY" secret:"true"`
+	GeminiEmbeddingModel string `yaml:"gemini_embedding_model" toml:"gemini_embedding_model" json:"gemini_embedding_model" env:"GEMINI_EMBEDDING_MODEL"`
+
+	// AutoRetranslate has the AI provider redo the changed parts of an
+	// article's translations whenever the article is updated
+	AutoRetranslate bool `yaml:"auto_retranslate" toml:"auto_retranslate" json:"auto_retranslate" env:"AI_AUTO_RETRANSLATE"`
+	// TranslationMemoryMatch is how similar, in percent, a remembered
+	// translation's source must be to be reused; 100 reuses exact matches
+	// only and 0 turns the translation memory off
+	TranslationMemoryMatch int `yaml:"translation_memory_match" toml:"translation_memory_match" json:"translation_memory_match" env:"TRANSLATION_MEMORY_MATCH"`
+
+	// Endpoints that call AI providers or analyze every article while the
+	// client waits each run at most MaxConcurrent requests at a time. More
+	// requests wait up to QueueTimeout for a turn before getting 429, and a
+	// request still running after RequestTimeout is cancelled with 503.
+	MaxConcurrent  int      `yaml:"max_concurrent" toml:"max_concurrent" json:"max_concurrent" env:"AI_MAX_CONCURRENT"`
+	QueueTimeout   Duration `yaml:"queue_timeout" toml:"queue_timeout" json:"queue_timeout" env:"AI_QUEUE_TIMEOUT"`
+	RequestTimeout Duration `yaml:"request_timeout" toml:"request_timeout" json:"request_timeout" env:"AI_REQUEST_TIMEOUT"`
+}
+
+// LoggingConfig holds log output and profiling settings
+type LoggingConfig struct {
+	Level  string `yaml:"level" toml:"level" json:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" toml:"format" json:"format" env:"LOG_FORMAT"`
+
+	// Profiling: requests and queries slower than these thresholds are kept
+	// for GET /api/system/profiling; zero disables them. Pprof exposes the Go
+	// profiler to administrators under /api/system/pprof.
+	SlowRequest Duration `yaml:"slow_request" toml:"slow_request" json:"slow_request" env:"SLOW_REQUEST_THRESHOLD"`
+	SlowQuery   Duration `yaml:"slow_query" toml:"slow_query" json:"slow_query" env:"SLOW_QUERY_THRESHOLD"`
+	Pprof       bool     `yaml:"pprof" toml:"pprof" json:"pprof" env:"PPROF_ENABLED"`
+}
+
+// SanitizeConfig holds HTML sanitizer settings
+type SanitizeConfig struct {
+	Mode     string `yaml:"mode" toml:"mode" json:"mode" env:"HTML_SANITIZE_MODE"`
+	Allow    string `yaml:"allow" toml:"allow" json:"allow" env:"HTML_SANITIZE_ALLOW"`
+	NoFollow bool   `yaml:"nofollow" toml:"nofollow" json:"nofollow" env:"HTML_SANITIZE_NOFOLLOW"`
+	OnRender bool   `yaml:"on_render" toml:"on_render" json:"on_render" env:"HTML_SANITIZE_ON_RENDER"`
+	// EmbedDomains lists the sites iframes may embed, comma-separated;
+	// empty keeps the built-in list and "none" allows no embeds
+	EmbedDomains string `yaml:"embed_domains" toml:"embed_domains" json:"embed_domains" env:"HTML_EMBED_DOMAINS"`
+}
+
+// CustomCodeConfig holds the scan custom CSS and JavaScript in the site
+// settings pass before they are saved. AllowedHosts are the hosts, with
+// their subdomains, that custom code may load scripts and stylesheets from
+// besides the site itself. It is only set here, so an admin session in the
+// wrong hands cannot widen it.
+type CustomCodeConfig struct {
+	AllowedHosts []string `yaml:"allowed_hosts" toml:"allowed_hosts" json:"allowed_hosts" env:"CUSTOM_CODE_ALLOWED_HOSTS"`
+}
+
+// SecretsConfig holds external secret manager settings
+type SecretsConfig struct {
+	VaultAddr       string `yaml:"vault_addr" toml:"vault_addr" json:"vault_addr" env:"VAULT_ADDR"`
+	VaultToken      string `yaml:"vault_token" toml:"vault_token" json:"vault_token" env:"VAULT_TOKEN" secret:"true"`
+	VaultTokenFile  string `yaml:"vault_token_file" toml:"vault_token_file" json:"vault_token_file" env:"VAULT_TOKEN_FILE"`
+	VaultNamespace  string `yaml:"vault_namespace" toml:"vault_namespace" json:"vault_namespace" env:"VAULT_NAMESPACE"`
+	CacheTTL        int    `yaml:"cache_ttl" toml:"cache_ttl" json:"cache_ttl" env:"SECRETS_CACHE_TTL"`
+	RefreshInterval int    `yaml:"refresh_interval" toml:"refresh_interval" json:"refresh_interval" env:"SECRETS_REFRESH_INTERVAL"`
+}
+
+// AlertsConfig holds security alert destinations
+type AlertsConfig struct {
+	WebhookURL string `yaml:"webhook_url" toml:"webhook_url" json:"webhook_url" env:"SECURITY_ALERT_WEBHOOK_URL" secret:"true"`
+	Email      string `yaml:"email" toml:"email" json:"email" env:"SECURITY_ALERT_EMAIL"`
+}
+
+// SMTPConfig holds outgoing mail settings. WebhookSecret authorizes the
+// bounce and complaint webhooks of the mail provider, which are off while
+// it is empty.
+type SMTPConfig struct {
+	Host          string `yaml:"host" toml:"host" json:"host" env:"SMTP_HOST"`
+	Port          string `yaml:"port" toml:"port" json:"port" env:"SMTP_PORT"`
+	Username      string `yaml:"username" toml:"username" json:"username" env:"SMTP_USERNAME"`
+	Password      string `yaml:"password" toml:"password" json:"password" env:"SMTP_PASSWORD" secret:"true"`
+	From          string `yaml:"from" toml:"from" json:"from" env:"SMTP_FROM"`
+	WebhookSecret string `yaml:"webhook_secret" toml:"webhook_secret" json:"webhook_secret" env:"SMTP_WEBHOOK_SECRET" secret:"true"`
+}
+
+// FeaturesConfig holds the experimental features switched on for this
+// deployment. Admins can still turn each one on or off at runtime.
+type FeaturesConfig struct {
+	Enabled []string `yaml:"enabled" toml:"enabled" json:"enabled" env:"FEATURES_ENABLED"`
+}
+
+// MetricsConfig protects the Prometheus endpoint. It is served only when a
+// token is set, and scrapers send it as a bearer token.
+type MetricsConfig struct {
+	Token string `yaml:"token" toml:"token" json:"token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+// MonitorConfig holds the check of the site's own public URL and TLS
+// certificate. URL defaults to server.public_url; with neither set, or an
+// empty Schedule, the monitor is off. Alerts are raised after Failures
+// failed checks in a row, and when the certificate expires within
+// CertWarnDays. They go to WebhookURL and Email, falling back to the
+// security alert destinations.
+type MonitorConfig struct {
+	Schedule     string   `yaml:"schedule" toml:"schedule" json:"schedule" env:"MONITOR_SCHEDULE"`
+	URL          string   `yaml:"url" toml:"url" json:"url" env:"MONITOR_URL"`
+	Timeout      Duration `yaml:"timeout" toml:"timeout" json:"timeout" env:"MONITOR_TIMEOUT"`
+	Failures     int      `yaml:"failures" toml:"failures" json:"failures" env:"MONITOR_FAILURES"`
+	CertWarnDays int      `yaml:"cert_warn_days" toml:"cert_warn_days" json:"cert_warn_days" env:"MONITOR_CERT_WARN_DAYS"`
+	WebhookURL   string   `yaml:"webhook_url" toml:"webhook_url" json:"webhook_url" env:"MONITOR_WEBHOOK_URL" secret:"true"`
+	Email        string   `yaml:"email" toml:"email" json:"email" env:"MONITOR_ALERT_EMAIL"`
+}
+
+// NewsletterConfig holds email subscription settings. Newsletters are sent
+// through SMTP in batches of BatchSize with BatchDelay between them; a
+// subscriber whose address bounces MaxBounces times stops receiving mail.
+// AnnouncePosts emails subscribers when an article is published, and
+// DigestSchedule sends a digest of new articles on a cron schedule, and
+// AIDigestSchedule drafts one written by the AI provider for review.
+type NewsletterConfig struct {
+	BatchSize        int      `yaml:"batch_size" toml:"batch_size" json:"batch_size" env:"NEWSLETTER_BATCH_SIZE"`
+	BatchDelay       Duration `yaml:"batch_delay" toml:"batch_delay" json:"batch_delay" env:"NEWSLETTER_BATCH_DELAY"`
+	MaxBounces       int      `yaml:"max_bounces" toml:"max_bounces" json:"max_bounces" env:"NEWSLETTER_MAX_BOUNCES"`
+	AnnouncePosts    bool     `yaml:"announce_posts" toml:"announce_posts" json:"announce_posts" env:"NEWSLETTER_ANNOUNCE_POSTS"`
+	DigestSchedule   string   `yaml:"digest_schedule" toml:"digest_schedule" json:"digest_schedule" env:"NEWSLETTER_DIGEST_SCHEDULE"`
+	AIDigestSchedule string   `yaml:"ai_digest_schedule" toml:"ai_digest_schedule" json:"ai_digest_schedule" env:"NEWSLETTER_AI_DIGEST_SCHEDULE"`
+}
+
+// ActivityPubConfig holds fediverse settings, used while the activitypub
+// feature is on. Each blog can be followed as @Username@host. Replies to its
+// articles from the fediverse are kept for moderation when AcceptReplies is
+// set, and ignored otherwise.
+type ActivityPubConfig struct {
+	Username      string `yaml:"username" toml:"username" json:"username" env:"ACTIVITYPUB_USERNAME"`
+	AcceptReplies bool   `yaml:"accept_replies" toml:"accept_replies" json:"accept_replies" env:"ACTIVITYPUB_ACCEPT_REPLIES"`
+}
+
+// WebSubConfig lists the WebSub (PubSubHubbub) hubs told about feed
+// updates. Feeds advertise the hubs so readers can subscribe to them for
+// push updates instead of polling.
+type WebSubConfig struct {
+	Hubs []string `yaml:"hubs" toml:"hubs" json:"hubs" env:"WEBSUB_HUBS"`
+}
+
+// CommentsConfig holds comment moderation notices. When NotifyEmail is set,
+// the first comment waiting for moderation starts a batch: NotifyDelay later
+// one email lists every comment received in between.
+type CommentsConfig struct {
+	NotifyEmail string   `yaml:"notify_email" toml:"notify_email" json:"notify_email" env:"COMMENTS_NOTIFY_EMAIL"`
+	NotifyDelay Duration `yaml:"notify_delay" toml:"notify_delay" json:"notify_delay" env:"COMMENTS_NOTIFY_DELAY"`
+}
+
+// PublishConfig holds checks made before an article is published. When
+// RequiredLanguages is set, saving an article is refused until it has a
+// title and content in each of those languages, unless the save explicitly
+// allows missing translations. The other fields are the publish checklist,
+// checked when an article goes live or is scheduled to: a cover image, a
+// summary, an SEO score of at least MinSEOScore and at least
+// MinInternalLinks links to other pages of the site. Zero values skip a
+// check.
+type PublishConfig struct {
+	RequiredLanguages []string `yaml:"required_languages" toml:"required_languages" json:"required_languages" env:"PUBLISH_REQUIRED_LANGUAGES"`
+	RequireCoverImage bool     `yaml:"require_cover_image" toml:"require_cover_image" json:"require_cover_image" env:"PUBLISH_REQUIRE_COVER_IMAGE"`
+	RequireSummary    bool     `yaml:"require_summary" toml:"require_summary" json:"require_summary" env:"PUBLISH_REQUIRE_SUMMARY"`
+	MinSEOScore       int      `yaml:"min_seo_score" toml:"min_seo_score" json:"min_seo_score" env:"PUBLISH_MIN_SEO_SCORE"`
+	MinInternalLinks  int      `yaml:"min_internal_links" toml:"min_internal_links" json:"min_internal_links" env:"PUBLISH_MIN_INTERNAL_LINKS"`
+}
+
+// HighlightConfig styles code blocks rendered by the server, in feeds and
+// other HTML that cannot load the site's stylesheets. Style is a chroma
+// style name; LineNumbers numbers every block, not only those that ask.
+type HighlightConfig struct {
+	Style       string `yaml:"style" toml:"style" json:"style" env:"HIGHLIGHT_STYLE"`
+	LineNumbers bool   `yaml:"line_numbers" toml:"line_numbers" json:"line_numbers" env:"HIGHLIGHT_LINE_NUMBERS"`
+}
+
+// DiagramsConfig points at the Kroki server (https://kroki.io, or a
+// self-hosted one) that renders Mermaid diagrams as SVG for feeds and other
+// server-rendered output. Without one, diagrams stay code blocks there.
+type DiagramsConfig struct {
+	KrokiURL string `yaml:"kroki_url" toml:"kroki_url" json:"kroki_url" env:"KROKI_URL"`
+}
+
+// ContactConfig holds the contact form. Each address may send RateLimit
+// messages per RateWindow; when ForwardEmail is set, every message that is
+// not spam is also emailed there, with Reply-To set to the sender.
+type ContactConfig struct {
+	ForwardEmail string   `yaml:"forward_email" toml:"forward_email" json:"forward_email" env:"CONTACT_FORWARD_EMAIL"`
+	RateLimit    int      `yaml:"rate_limit" toml:"rate_limit" json:"rate_limit" env:"CONTACT_RATE_LIMIT"`
+	RateWindow   Duration `yaml:"rate_window" toml:"rate_window" json:"rate_window" env:"CONTACT_RATE_WINDOW"`
+}
+
+// FriendLinksConfig holds the blogroll. CheckSchedule is the cron schedule
+// on which friend links that ask for it are checked for a link back; empty
+// turns the periodic check off.
+type FriendLinksConfig struct {
+	CheckSchedule string `yaml:"check_schedule" toml:"check_schedule" json:"check_schedule" env:"FRIEND_LINKS_CHECK_SCHEDULE"`
+}
+
+// FeedImportConfig holds the import of posts from RSS and Atom feeds.
+// Schedule is the cron schedule on which followed feeds are fetched for
+// new posts; empty stops following, leaving one-off imports.
+type FeedImportConfig struct {
+	Schedule string `yaml:"schedule" toml:"schedule" json:"schedule" env:"FEED_IMPORT_SCHEDULE"`
+}
+
+// MomentsConfig holds the short status updates posted beside articles.
+// Public moments always have a feed of their own; with InFeed they are
+// also mixed into the main feed.
+type MomentsConfig struct {
+	InFeed bool `yaml:"in_feed" toml:"in_feed" json:"in_feed" env:"MOMENTS_IN_FEED"`
+}
+
+// GuestbookConfig holds the guestbook. Each address may sign it RateLimit
+// times per RateWindow; entries wait for moderation like comments do.
+type GuestbookConfig struct {
+	RateLimit  int      `yaml:"rate_limit" toml:"rate_limit" json:"rate_limit" env:"GUESTBOOK_RATE_LIMIT"`
+	RateWindow Duration `yaml:"rate_window" toml:"rate_window" json:"rate_window" env:"GUESTBOOK_RATE_WINDOW"`
+}
+
+// ShareConfig holds the accounts used for sharing inside Chinese apps.
+// With a WeChat official account's AppID and AppSecret, article share
+// cards carry a JS-SDK signature so pages opened in WeChat can set their
+// own share title, description and image. WeiboAppKey credits shares on
+// Weibo to the app.
+type ShareConfig struct {
+	WeChatAppID     string `yaml:"wechat_app_id" toml:"wechat_app_id" json:"wechat_app_id" env:"SHARE_WECHAT_APP_ID"`
+	WeChatAppSecret string `yaml:"wechat_app_secret" toml:"wechat_app_secret" json:"wechat_app_secret" env:"SHARE_WECHAT_APP_SECRET" secret:"true"`
+	WeiboAppKey     string `yaml:"weibo_app_key" toml:"weibo_app_key" json:"weibo_app_key" env:"SHARE_WEIBO_APP_KEY"`
+}
+
+// ShareCountsConfig holds the collection of how often articles are shared
+// on social platforms. On Schedule, the counts of articles published within
+// MaxAge are fetched from each of Platforms: "hackernews", "v2ex",
+// "facebook" (needs FacebookToken, an app access token) and "x" (needs
+// XBearerToken). An empty schedule turns collection off.
+type ShareCountsConfig struct {
+	Schedule      string   `yaml:"schedule" toml:"schedule" json:"schedule" env:"SHARE_COUNTS_SCHEDULE"`
+	Platforms     []string `yaml:"platforms" toml:"platforms" json:"platforms" env:"SHARE_COUNTS_PLATFORMS"`
+	MaxAge        Duration `yaml:"max_age" toml:"max_age" json:"max_age" env:"SHARE_COUNTS_MAX_AGE"`
+	FacebookToken string   `yaml:"facebook_token" toml:"facebook_token" json:"facebook_token" env:"SHARE_COUNTS_FACEBOOK_TOKEN" secret:"true"`
+	XBearerToken  string   `yaml:"x_bearer_token" toml:"x_bearer_token" json:"x_bearer_token" env:"SHARE_COUNTS_X_BEARER_TOKEN" secret:"true"`
+}
+
+// CommentBridgeConfig holds the read-only bridge to an external comment
+// system a site keeps: "giscus", the GitHub Discussions of GiscusRepository
+// ("owner/name") in GiscusCategory, read with GiscusToken, or "disqus", the
+// threads of DisqusForum read with DisqusAPIKey. On Schedule the comment
+// count of each discussion is synced to its article, and with SyncContent
+// the comments as well. An empty provider turns the bridge off.
+type CommentBridgeConfig struct {
+	Provider         string `yaml:"provider" toml:"provider" json:"provider" env:"COMMENT_BRIDGE_PROVIDER"`
+	Schedule         string `yaml:"schedule" toml:"schedule" json:"schedule" env:"COMMENT_BRIDGE_SCHEDULE"`
+	SyncContent      bool   `yaml:"sync_content" toml:"sync_content" json:"sync_content" env:"COMMENT_BRIDGE_SYNC_CONTENT"`
+	GiscusRepository string `yaml:"giscus_repository" toml:"giscus_repository" json:"giscus_repository" env:"COMMENT_BRIDGE_GISCUS_REPOSITORY"`
+	GiscusCategory   string `yaml:"giscus_category" toml:"giscus_category" json:"giscus_category" env:"COMMENT_BRIDGE_GISCUS_CATEGORY"`
+	GiscusToken      string `yaml:"giscus_token" toml:"giscus_token" json:"giscus_token" env:"COMMENT_BRIDGE_GISCUS_TOKEN" secret:"true"`
+	DisqusForum      string `yaml:"disqus_forum" toml:"disqus_forum" json:"disqus_forum" env:"COMMENT_BRIDGE_DISQUS_FORUM"`
+	DisqusAPIKey     string `yaml:"disqus_api_key" toml:"disqus_api_key" json:"disqus_api_key" env:"COMMENT_BRIDGE_DISQUS_API_KEY" secret:"true"`
+}
+
+// APIKeysConfig holds the read-only API keys of third-party apps. A key
+// without a limit of its own may make RateLimit requests an hour, and the
+// hourly usage of keys is kept for UsageRetention.
+type APIKeysConfig struct {
+	RateLimit      int      `yaml:"rate_limit" toml:"rate_limit" json:"rate_limit" env:"API_KEY_RATE_LIMIT"`
+	UsageRetention Duration `yaml:"usage_retention" toml:"usage_retention" json:"usage_retention" env:"API_KEY_USAGE_RETENTION"`
+}
+
+// KeywordDataConfig holds the provider tracked SEO keywords get their
+// search volume and difficulty from: "dataforseo", "google_ads" or empty
+// for none. On Schedule, up to BatchSize keywords whose data is older than
+// MaxAge are refreshed, unless the provider's cost this month has reached
+// MonthlyBudget (in USD, 0 for no limit). Location is the provider's
+// location code, 2840 for the United States.
+type KeywordDataConfig struct {
+	Provider      string           `yaml:"provider" toml:"provider" json:"provider" env:"KEYWORD_DATA_PROVIDER"`
+	Schedule      string           `yaml:"schedule" toml:"schedule" json:"schedule" env:"KEYWORD_DATA_SCHEDULE"`
+	MaxAge        Duration         `yaml:"max_age" toml:"max_age" json:"max_age" env:"KEYWORD_DATA_MAX_AGE"`
+	BatchSize     int              `yaml:"batch_size" toml:"batch_size" json:"batch_size" env:"KEYWORD_DATA_BATCH_SIZE"`
+	MonthlyBudget float64          `yaml:"monthly_budget" toml:"monthly_budget" json:"monthly_budget" env:"KEYWORD_DATA_MONTHLY_BUDGET"`
+	Location      int              `yaml:"location" toml:"location" json:"location" env:"KEYWORD_DATA_LOCATION"`
+	DataForSEO    DataForSEOConfig `yaml:"dataforseo" toml:"dataforseo" json:"dataforseo"`
+	GoogleAds     GoogleAdsConfig  `yaml:"google_ads" toml:"google_ads" json:"google_ads"`
+}
+
+// NotificationsConfig holds the email digest of the admin notification
+// center. On DigestSchedule, unread notifications of at least
+// DigestSeverity not sent before are mailed to DigestEmail, which falls
+// back to the security alert email. An empty schedule turns digests off.
+type NotificationsConfig struct {
+	DigestSchedule string `yaml:"digest_schedule" toml:"digest_schedule" json:"digest_schedule" env:"NOTIFICATION_DIGEST_SCHEDULE"`
+	DigestEmail    string `yaml:"digest_email" toml:"digest_email" json:"digest_email" env:"NOTIFICATION_DIGEST_EMAIL"`
+	DigestSeverity string `yaml:"digest_severity" toml:"digest_severity" json:"digest_severity" env:"NOTIFICATION_DIGEST_SEVERITY"`
+}
+
+// MembersConfig holds the members-only tier. Readers who are not members
+// get a teaser of members-only articles: the text before a <!--more-->
+// marker, or the first paragraphs up to TeaserLength characters. Members
+// sign in with their passcode for a token that is valid for TokenTTL.
+// Tokens issued by another system are checked with a POST to VerifyURL,
+// when it is set, and the answer is kept for VerifyCacheTTL.
+type MembersConfig struct {
+	TeaserLength   int      `yaml:"teaser_length" toml:"teaser_length" json:"teaser_length" env:"MEMBERS_TEASER_LENGTH"`
+	TokenTTL       Duration `yaml:"token_ttl" toml:"token_ttl" json:"token_ttl" env:"MEMBERS_TOKEN_TTL"`
+	VerifyURL      string   `yaml:"verify_url" toml:"verify_url" json:"verify_url" env:"MEMBERS_VERIFY_URL"`
+	VerifyCacheTTL Duration `yaml:"verify_cache_ttl" toml:"verify_cache_ttl" json:"verify_cache_ttl" env:"MEMBERS_VERIFY_CACHE_TTL"`
+}
+
+// HotlinkConfig keeps other sites from embedding uploaded images and
+// videos. When Enabled, a request whose Referer is neither the blog nor one
+// of AllowedDomains, or their subdomains, is handled by Policy: watermark
+// serves images with WatermarkText across them and refuses other media,
+// redirect sends the request to RedirectURL, and deny answers 403.
+// Requests without a Referer, such as direct visits and feed readers, pass
+// unless BlockEmptyReferer is set. Links signed with Secret work from any
+// site until they expire, TokenTTL after signing by default.
+type HotlinkConfig struct {
+	Enabled           bool     `yaml:"enabled" toml:"enabled" json:"enabled" env:"HOTLINK_PROTECTION"`
+	AllowedDomains    []string `yaml:"allowed_domains" toml:"allowed_domains" json:"allowed_domains" env:"HOTLINK_ALLOWED_DOMAINS"`
+	Policy            string   `yaml:"policy" toml:"policy" json:"policy" env:"HOTLINK_POLICY"`
+	RedirectURL       string   `yaml:"redirect_url" toml:"redirect_url" json:"redirect_url" env:"HOTLINK_REDIRECT_URL"`
+	WatermarkText     string   `yaml:"watermark_text" toml:"watermark_text" json:"watermark_text" env:"HOTLINK_WATERMARK_TEXT"`
+	BlockEmptyReferer bool     `yaml:"block_empty_referer" toml:"block_empty_referer" json:"block_empty_referer" env:"HOTLINK_BLOCK_EMPTY_REFERER"`
+	Secret            string   `yaml:"secret" toml:"secret" json:"secret" env:"HOTLINK_SECRET" secret:"true"`
+	TokenTTL          Duration `yaml:"token_ttl" toml:"token_ttl" json:"token_ttl" env:"HOTLINK_TOKEN_TTL"`
+}
+
+// HealthConfig holds the readiness checks of /readyz and the alerts sent
+// when they fail. The job queue counts as stalled once a due job has waited
+// JobStall. Every Interval the checks run and failures are posted to
+// WebhookURL in Format: generic JSON, a PagerDuty event with Secret as the
+// routing key, or a Feishu or DingTalk bot message signed with Secret when
+// set. A failing check is posted at most once per Repeat, and once more
+// when it passes again.
+type HealthConfig struct {
+	JobStall   Duration `yaml:"job_stall" toml:"job_stall" json:"job_stall" env:"HEALTH_JOB_STALL"`
+	Interval   Duration `yaml:"interval" toml:"interval" json:"interval" env:"HEALTH_ALERT_INTERVAL"`
+	Repeat     Duration `yaml:"repeat" toml:"repeat" json:"repeat" env:"HEALTH_ALERT_REPEAT"`
+	Format     string   `yaml:"format" toml:"format" json:"format" env:"HEALTH_ALERT_FORMAT"`
+	WebhookURL string   `yaml:"webhook_url" toml:"webhook_url" json:"webhook_url" env:"HEALTH_ALERT_WEBHOOK_URL" secret:"true"`
+	Secret     string   `yaml:"secret" toml:"secret" json:"secret" env:"HEALTH_ALERT_SECRET" secret:"true"`
+}
+
+// WarmupConfig holds the caches warmed at startup, so the first visitors
+// after a deploy are not served from cold caches. The warm-up tasks run in
+// order of priority, Concurrency at a time, and wait while more than
+// MaxInFlight requests are being served. /readyz answers 503 until the
+// critical tasks are done, or for at most Timeout.
+type WarmupConfig struct {
+	Enabled     bool     `yaml:"enabled" toml:"enabled" json:"enabled" env:"WARMUP_ENABLED"`
+	Concurrency int      `yaml:"concurrency" toml:"concurrency" json:"concurrency" env:"WARMUP_CONCURRENCY"`
+	MaxInFlight int      `yaml:"max_in_flight" toml:"max_in_flight" json:"max_in_flight" env:"WARMUP_MAX_IN_FLIGHT"`
+	Timeout     Duration `yaml:"timeout" toml:"timeout" json:"timeout" env:"WARMUP_TIMEOUT"`
+}
+
+// DataForSEOConfig holds the DataForSEO API credentials
+type DataForSEOConfig struct {
+	Login    string `yaml:"login" toml:"login" json:"login" env:"DATAFORSEO_LOGIN"`
+	Password string `yaml:"password" toml:"password" json:"password" env:"DATAFORSEO_PASSWORD" secret:"true"`
+}
+
+// GoogleAdsConfig holds the Google Ads API account whose keyword planner
+// reports search volume. The OAuth client and refresh token authorize the
+// API calls; LoginCustomerID is the manager account, if any.
+type GoogleAdsConfig struct {
+	CustomerID      string `yaml:"customer_id" toml:"customer_id" json:"customer_id" env:"GOOGLE_ADS_CUSTOMER_ID"`
+	LoginCustomerID string `yaml:"login_customer_id" toml:"login_customer_id" json:"login_customer_id" env:"GOOGLE_ADS_LOGIN_CUSTOMER_ID"`
+	DeveloperToken  string `yaml:"developer_token" toml:"developer_token" json:"developer_token" env:"GOOGLE_ADS_DEVELOPER_TOKEN" secret:"true"`
+	ClientID        string `yaml:"client_id" toml:"client_id" json:"client_id" env:"GOOGLE_ADS_CLIENT_ID"`
+	ClientSecret    string `yaml:"client_secret" toml:"client_secret" json:"client_secret" env:"GOOGLE_ADS_CLIENT_SECRET" secret:"true"`
+	RefreshToken    string `yaml:"refresh_token" toml:"refresh_token" json:"refresh_token" env:"GOOGLE_ADS_REFRESH_TOKEN" secret:"true"`
+	APIVersion      string `yaml:"api_version" toml:"api_version" json:"api_version" env:"GOOGLE_ADS_API_VERSION"`
+}
+
+// Duration is a time.Duration that reads and writes strings such as "30s"
+type Duration time.Duration
+
+// UnmarshalText parses a Go duration string
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText formats the duration as a Go duration string
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Std returns the value as a time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// Value sources reported by Sources
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+)
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            "8085",
+			ShutdownTimeout: Duration(30 * time.Second),
+		},
+		Database: DatabaseConfig{
+			Driver:      "sqlite",
+			Path:        "./data/blog.db",
+			JournalMode: "WAL",
+			Synchronous: "NORMAL",
+			BusyTimeout: Duration(5 * time.Second),
+			ForeignKeys: true,
+
+			OptimizeSchedule: "0 4 * * 0",
+		},
+		Storage: StorageConfig{
+			UploadDir:       "/app/data/uploads",
+			DiskWarnPercent: 90,
+			Driver:          "local",
+		},
+		Backup: BackupConfig{
+			RetentionCount: 7,
+		},
+		Auth: AuthConfig{
+			WebAuthn: WebAuthnConfig{RPName: "KUNO"},
+		},
+		Cache: CacheConfig{
+			Driver:   "memory",
+			Prefix:   "kuno:",
+			MaxItems: 10000,
+		},
+		Jobs: JobsConfig{
+			Workers:       2,
+			MaxAttempts:   3,
+			Timeout:       Duration(30 * time.Minute),
+			RetentionDays: 7,
+		},
+		Tracking: TrackingConfig{
+			QueueSize:       1000,
+			Overflow:        "sync",
+			VisitorRotation: "none",
+		},
+		Sanitize: SanitizeConfig{
+			Mode:     "untrusted",
+			NoFollow: true,
+		},
+		Secrets: SecretsConfig{
+			CacheTTL: 300,
+		},
+		SMTP: SMTPConfig{
+			Port: "587",
+		},
+		Newsletter: NewsletterConfig{
+			BatchSize:  50,
+			BatchDelay: Duration(2 * time.Second),
+			MaxBounces: 3,
+		},
+		ActivityPub: ActivityPubConfig{
+			Username: "blog",
+		},
+		Comments: CommentsConfig{
+			NotifyDelay: Duration(10 * time.Minute),
+		},
+		AI: AIConfig{
+			TranslationMemoryMatch: 95,
+			MaxConcurrent:          4,
+			QueueTimeout:           Duration(5 * time.Second),
+			RequestTimeout:         Duration(60 * time.Second),
+		},
+		Highlight: HighlightConfig{
+			Style: "github",
+		},
+		Contact: ContactConfig{
+			RateLimit:  5,
+			RateWindow: Duration(time.Hour),
+		},
+		FriendLinks: FriendLinksConfig{
+			CheckSchedule: "0 5 * * 1",
+		},
+		FeedImport: FeedImportConfig{
+			Schedule: "20 */6 * * *",
+		},
+		Guestbook: GuestbookConfig{
+			RateLimit:  3,
+			RateWindow: Duration(time.Hour),
+		},
+		ShareCounts: ShareCountsConfig{
+			Platforms: []string{"hackernews", "v2ex"},
+			MaxAge:    Duration(90 * 24 * time.Hour),
+		},
+		CommentBridge: CommentBridgeConfig{
+			Schedule: "20 * * * *",
+		},
+		APIKeys: APIKeysConfig{
+			RateLimit:      1000,
+			UsageRetention: Duration(90 * 24 * time.Hour),
+		},
+		KeywordData: KeywordDataConfig{
+			Schedule:  "0 4 * * *",
+			MaxAge:    Duration(30 * 24 * time.Hour),
+			BatchSize: 100,
+			Location:  2840,
+			GoogleAds: GoogleAdsConfig{APIVersion: "v21"},
+		},
+		Notifications: NotificationsConfig{
+			DigestSchedule: "0 8 * * *",
+			DigestSeverity: "warning",
+		},
+		Members: MembersConfig{
+			TeaserLength:   500,
+			TokenTTL:       Duration(30 * 24 * time.Hour),
+			VerifyCacheTTL: Duration(5 * time.Minute),
+		},
+		Hotlink: HotlinkConfig{
+			Policy:   "watermark",
+			TokenTTL: Duration(24 * time.Hour),
+		},
+		Health: HealthConfig{
+			JobStall: Duration(15 * time.Minute),
+			Interval: Duration(time.Minute),
+			Repeat:   Duration(time.Hour),
+			Format:   "generic",
+		},
+		Warmup: WarmupConfig{
+			Enabled:     true,
+			Concurrency: 2,
+			MaxInFlight: 8,
+			Timeout:     Duration(30 * time.Second),
+		},
+		Monitor: MonitorConfig{
+			Schedule:     "*/5 * * * *",
+			Timeout:      Duration(10 * time.Second),
+			Failures:     2,
+			CertWarnDays: 14,
+		},
+	}
+}
+
+// Loaded is a validated configuration together with where each value came from
+type Loaded struct {
+	*Config
+	File    string            `json:"file"`
+	Sources map[string]string `json:"sources"` // env var name -> source
+}
+
+// Load builds the configuration from defaults, the given file (if any) and
+// the environment, then validates it. An empty path skips the file.
+func Load(path string) (*Loaded, error) {
+	loaded := &Loaded{
+		Config:  Default(),
+		File:    path,
+		Sources: make(map[string]string),
+	}
+	for _, field := range envFields(loaded.Config) {
+		loaded.Sources[field.env] = SourceDefault
+	}
+
+	// A broken file is reported but environment overrides still apply, so
+	// callers that ignore the error get the best available configuration
+	var errs []error
+	if path != "" {
+		if err := loaded.loadFile(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, field := range envFields(loaded.Config) {
+		value, ok := os.LookupEnv(field.env)
+		if !ok {
+			continue
+		}
+		if err := setField(field.value, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", field.env, err))
+			continue
+		}
+		loaded.Sources[field.env] = SourceEnv
+	}
+
+	errs = append(errs, loaded.Validate()...)
+	return loaded, errors.Join(errs...)
+}
+
+// Validate checks the configuration for invalid values
+func (c *Config) Validate() []error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port: %q is not a valid port", c.Server.Port))
+	}
+	if !oneOf(c.Server.GinMode, "", "debug", "release", "test") {
+		errs = append(errs, fmt.Errorf("server.gin_mode: must be debug, release or test"))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("server.shutdown_timeout: must be positive"))
+	}
+	if !oneOf(c.Server.TrustedCDN, "", "cloudflare", "cloudfront") {
+		errs = append(errs, fmt.Errorf("server.trusted_cdn: must be cloudflare or cloudfront"))
+	}
+	switch c.Database.Driver {
+	case "sqlite":
+		if c.Database.Path == "" {
+			errs = append(errs, fmt.Errorf("database.path: must not be empty"))
+		}
+	case "postgres", "mysql":
+		if c.Database.DSN == "" {
+			errs = append(errs, fmt.Errorf("database.dsn: required for the %s driver", c.Database.Driver))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.driver: must be sqlite, postgres or mysql"))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, fmt.Errorf("database: connection pool sizes must not be negative"))
+	}
+	if !oneOf(strings.ToUpper(c.Database.JournalMode), "", "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF") {
+		errs = append(errs, fmt.Errorf("database.journal_mode: must be DELETE, TRUNCATE, PERSIST, MEMORY, WAL or OFF"))
+	}
+	if !oneOf(strings.ToUpper(c.Database.Synchronous), "", "OFF", "NORMAL", "FULL", "EXTRA") {
+		errs = append(errs, fmt.Errorf("database.synchronous: must be OFF, NORMAL, FULL or EXTRA"))
+	}
+	if c.Database.BusyTimeout < 0 {
+		errs = append(errs, fmt.Errorf("database.busy_timeout: must not be negative"))
+	}
+	if c.Storage.UploadDir == "" {
+		errs = append(errs, fmt.Errorf("storage.upload_dir: must not be empty"))
+	}
+	if c.Storage.DiskWarnPercent < 0 || c.Storage.DiskWarnPercent > 100 {
+		errs = append(errs, fmt.Errorf("storage.disk_warn_percent: must be between 0 and 100"))
+	}
+	if c.Storage.S3.Bucket != "" && c.Storage.S3.Region == "" && c.Storage.S3.Endpoint == "" {
+		errs = append(errs, fmt.Errorf("storage.s3: region or endpoint is required when a bucket is set"))
+	}
+	switch c.Storage.Driver {
+	case "", "local":
+	case "s3":
+		if c.Storage.S3.Bucket == "" {
+			errs = append(errs, fmt.Errorf("storage.s3.bucket: required for the s3 driver"))
+		}
+	case "azure":
+		if c.Storage.Azure.Account == "" || c.Storage.Azure.Key == "" || c.Storage.Azure.Container == "" {
+			errs = append(errs, fmt.Errorf("storage.azure: account, key and container are required for the azure driver"))
+		}
+	case "gcs":
+		if c.Storage.GCS.Bucket == "" {
+			errs = append(errs, fmt.Errorf("storage.gcs.bucket: required for the gcs driver"))
+		}
+	case "webdav":
+		if u, err := url.Parse(c.Storage.WebDAV.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("storage.webdav.url: an http(s) URL is required for the webdav driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("storage.driver: must be local, s3, azure, gcs or webdav"))
+	}
+	if c.Database.OptimizeSchedule != "" {
+		if _, err := cron.Parse(c.Database.OptimizeSchedule); err != nil {
+			errs = append(errs, fmt.Errorf("database.optimize_schedule: %v", err))
+		}
+	}
+	if c.Backup.Schedule != "" {
+		if _, err := cron.Parse(c.Backup.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("backup.schedule: %v", err))
+		}
+	}
+	switch c.Backup.Target {
+	case "":
+	case "s3", "webdav", "ftp":
+		if c.Backup.TargetURL == "" {
+			errs = append(errs, fmt.Errorf("backup.target_url: required for the %s target", c.Backup.Target))
+		}
+	case "storage":
+		if c.Storage.Driver == "" || c.Storage.Driver == "local" {
+			errs = append(errs, fmt.Errorf("backup.target: the storage target needs a remote storage.driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("backup.target: must be s3, webdav, ftp or storage"))
+	}
+	if c.Backup.RetentionCount < 0 || c.Backup.RetentionDays < 0 {
+		errs = append(errs, fmt.Errorf("backup: retention_count and retention_days must not be negative"))
+	}
+	switch c.Cache.Driver {
+	case "memory":
+	case "redis":
+		if c.Cache.RedisURL == "" {
+			errs = append(errs, fmt.Errorf("cache.redis_url: required for the redis driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("cache.driver: must be memory or redis"))
+	}
+	if c.Cache.MaxItems < 0 {
+		errs = append(errs, fmt.Errorf("cache.max_items: must not be negative"))
+	}
+	if c.Cache.HTTPTTL < 0 {
+		errs = append(errs, fmt.Errorf("cache.http_ttl: must not be negative"))
+	}
+	if c.Jobs.Workers < 1 {
+		errs = append(errs, fmt.Errorf("jobs.workers: must be at least 1"))
+	}
+	if c.Jobs.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("jobs.max_attempts: must be at least 1"))
+	}
+	if c.Jobs.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("jobs.timeout: must be positive"))
+	}
+	if c.Jobs.RetentionDays < 0 {
+		errs = append(errs, fmt.Errorf("jobs.retention_days: must not be negative"))
+	}
+	if c.Tracking.QueueSize < 1 {
+		errs = append(errs, fmt.Errorf("tracking.queue_size: must be at least 1"))
+	}
+	switch c.Tracking.Overflow {
+	case "sync", "drop", "spill":
+	default:
+		errs = append(errs, fmt.Errorf("tracking.overflow: must be sync, drop or spill"))
+	}
+	switch c.Tracking.VisitorRotation {
+	case "none", "daily":
+	default:
+		errs = append(errs, fmt.Errorf("tracking.visitor_rotation: must be none or daily"))
+	}
+	if c.Server.PublicURL != "" {
+		if u, err := url.Parse(c.Server.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("server.public_url: %q is not an absolute http(s) URL", c.Server.PublicURL))
+		}
+	}
+	if c.Monitor.Schedule != "" {
+		if _, err := cron.Parse(c.Monitor.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("monitor.schedule: %v", err))
+		}
+	}
+	if c.Monitor.URL != "" {
+		if u, err := url.Parse(c.Monitor.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("monitor.url: %q is not an absolute http(s) URL", c.Monitor.URL))
+		}
+	}
+	if c.Monitor.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("monitor.timeout: must be positive"))
+	}
+	if c.Monitor.Failures < 1 {
+		errs = append(errs, fmt.Errorf("monitor.failures: must be at least 1"))
+	}
+	if c.Monitor.CertWarnDays < 0 {
+		errs = append(errs, fmt.Errorf("monitor.cert_warn_days: must not be negative"))
+	}
+	if c.Newsletter.BatchSize < 1 {
+		errs = append(errs, fmt.Errorf("newsletter.batch_size: must be at least 1"))
+	}
+	if c.Newsletter.BatchDelay < 0 {
+		errs = append(errs, fmt.Errorf("newsletter.batch_delay: must not be negative"))
+	}
+	if c.Newsletter.MaxBounces < 1 {
+		errs = append(errs, fmt.Errorf("newsletter.max_bounces: must be at least 1"))
+	}
+	if c.Newsletter.DigestSchedule != "" {
+		if _, err := cron.Parse(c.Newsletter.DigestSchedule); err != nil {
+			errs = append(errs, fmt.Errorf("newsletter.digest_schedule: %v", err))
+		}
+	}
+	if c.Newsletter.AIDigestSchedule != "" {
+		if _, err := cron.Parse(c.Newsletter.AIDigestSchedule); err != nil {
+			errs = append(errs, fmt.Errorf("newsletter.ai_digest_schedule: %v", err))
+		}
+	}
+	if !activityPubUsername.MatchString(c.ActivityPub.Username) {
+		errs = append(errs, fmt.Errorf("activitypub.username: must be 1 to 30 letters, digits or underscores"))
+	}
+	if c.Comments.NotifyDelay < Duration(time.Minute) {
+		errs = append(errs, fmt.Errorf("comments.notify_delay: must be at least 1m"))
+	}
+	for _, hub := range c.WebSub.Hubs {
+		if u, err := url.Parse(hub); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("websub.hubs: %q is not an absolute http(s) URL", hub))
+		}
+	}
+	if c.Diagrams.KrokiURL != "" {
+		if u, err := url.Parse(c.Diagrams.KrokiURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("diagrams.kroki_url: %q is not an absolute http(s) URL", c.Diagrams.KrokiURL))
+		}
+	}
+	if c.Contact.ForwardEmail != "" {
+		if _, err := mail.ParseAddress(c.Contact.ForwardEmail); err != nil {
+			errs = append(errs, fmt.Errorf("contact.forward_email: %q is not an email address", c.Contact.ForwardEmail))
+		}
+	}
+	if c.FriendLinks.CheckSchedule != "" {
+		if _, err := cron.Parse(c.FriendLinks.CheckSchedule); err != nil {
+			errs = append(errs, fmt.Errorf("friend_links.check_schedule: %v", err))
+		}
+	}
+	if c.FeedImport.Schedule != "" {
+		if _, err := cron.Parse(c.FeedImport.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("feed_import.schedule: %v", err))
+		}
+	}
+	if c.Contact.RateLimit < 1 {
+		errs = append(errs, fmt.Errorf("contact.rate_limit: must be at least 1"))
+	}
+	if c.Contact.RateWindow < Duration(time.Minute) {
+		errs = append(errs, fmt.Errorf("contact.rate_window: must be at least 1m"))
+	}
+	if c.Guestbook.RateLimit < 1 {
+		errs = append(errs, fmt.Errorf("guestbook.rate_limit: must be at least 1"))
+	}
+	if c.Guestbook.RateWindow < Duration(time.Minute) {
+		errs = append(errs, fmt.Errorf("guestbook.rate_window: must be at least 1m"))
+	}
+	if c.APIKeys.RateLimit < 1 {
+		errs = append(errs, fmt.Errorf("api_keys.rate_limit: must be at least 1"))
+	}
+	if c.APIKeys.UsageRetention < Duration(24*time.Hour) {
+		errs = append(errs, fmt.Errorf("api_keys.usage_retention: must be at least 24h"))
+	}
+	switch c.KeywordData.Provider {
+	case "":
+	case "dataforseo":
+		if c.KeywordData.DataForSEO.Login == "" || c.KeywordData.DataForSEO.Password == "" {
+			errs = append(errs, fmt.Errorf("keyword_data.dataforseo: login and password are required"))
+		}
+	case "google_ads":
+		ads := c.KeywordData.GoogleAds
+		if ads.CustomerID == "" || ads.DeveloperToken == "" || ads.ClientID == "" || ads.ClientSecret == "" || ads.RefreshToken == "" {
+			errs = append(errs, fmt.Errorf("keyword_data.google_ads: customer_id, developer_token, client_id, client_secret and refresh_token are required"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("keyword_data.provider: must be dataforseo, google_ads or empty"))
+	}
+	if c.KeywordData.Provider != "" {
+		if _, err := cron.Parse(c.KeywordData.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("keyword_data.schedule: %v", err))
+		}
+	}
+	if c.KeywordData.MaxAge < Duration(24*time.Hour) {
+		errs = append(errs, fmt.Errorf("keyword_data.max_age: must be at least 24h"))
+	}
+	if c.KeywordData.BatchSize < 1 || c.KeywordData.BatchSize > 1000 {
+		errs = append(errs, fmt.Errorf("keyword_data.batch_size: must be between 1 and 1000"))
+	}
+	if c.KeywordData.MonthlyBudget < 0 {
+		errs = append(errs, fmt.Errorf("keyword_data.monthly_budget: must not be negative"))
+	}
+	if c.Notifications.DigestSchedule != "" {
+		if _, err := cron.Parse(c.Notifications.DigestSchedule); err != nil {
+			errs = append(errs, fmt.Errorf("notifications.digest_schedule: %v", err))
+		}
+	}
+	switch c.Notifications.DigestSeverity {
+	case "info", "warning", "error", "critical":
+	default:
+		errs = append(errs, fmt.Errorf("notifications.digest_severity: must be info, warning, error or critical"))
+	}
+	if c.Members.TeaserLength < 1 {
+		errs = append(errs, fmt.Errorf("members.teaser_length: must be at least 1"))
+	}
+	if c.Members.TokenTTL < Duration(time.Hour) {
+		errs = append(errs, fmt.Errorf("members.token_ttl: must be at least 1h"))
+	}
+	if c.Members.VerifyURL != "" {
+		if u, err := url.Parse(c.Members.VerifyURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("members.verify_url: must be an http or https URL"))
+		}
+	}
+	if c.Members.VerifyCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("members.verify_cache_ttl: must not be negative"))
+	}
+	switch c.Hotlink.Policy {
+	case "watermark", "deny":
+	case "redirect":
+		if u, err := url.Parse(c.Hotlink.RedirectURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("hotlink.redirect_url: must be an http or https URL with the redirect policy"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("hotlink.policy: must be watermark, redirect or deny"))
+	}
+	for _, host := range c.CustomCode.AllowedHosts {
+		if host == "" || strings.ContainsAny(host, "/:* ") {
+			errs = append(errs, fmt.Errorf("custom_code.allowed_hosts: %q is not a host name", host))
+		}
+	}
+	for _, domain := range c.Hotlink.AllowedDomains {
+		if domain == "" || strings.ContainsAny(domain, "/: ") {
+			errs = append(errs, fmt.Errorf("hotlink.allowed_domains: %q is not a domain", domain))
+		}
+	}
+	if c.Hotlink.TokenTTL < Duration(time.Minute) {
+		errs = append(errs, fmt.Errorf("hotlink.token_ttl: must be at least 1m"))
+	}
+	if c.Health.JobStall < Duration(time.Minute) {
+		errs = append(errs, fmt.Errorf("health.job_stall: must be at least 1m"))
+	}
+	if c.Health.Interval < Duration(10*time.Second) {
+		errs = append(errs, fmt.Errorf("health.interval: must be at least 10s"))
+	}
+	if c.Health.Repeat < c.Health.Interval {
+		errs = append(errs, fmt.Errorf("health.repeat: must not be shorter than health.interval"))
+	}
+	switch c.Health.Format {
+	case "generic", "feishu", "dingtalk":
+	case "pagerduty":
+		if c.Health.Secret == "" {
+			errs = append(errs, fmt.Errorf("health.secret: the pagerduty format needs the routing key"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("health.format: must be generic, pagerduty, feishu or dingtalk"))
+	}
+	if c.Health.WebhookURL != "" {
+		if u, err := url.Parse(c.Health.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("health.webhook_url: must be an http or https URL"))
+		}
+	}
+	if c.Warmup.Concurrency < 1 {
+		errs = append(errs, fmt.Errorf("warmup.concurrency: must be at least 1"))
+	}
+	if c.Warmup.MaxInFlight < 1 {
+		errs = append(errs, fmt.Errorf("warmup.max_in_flight: must be at least 1"))
+	}
+	if c.Warmup.Timeout < Duration(time.Second) {
+		errs = append(errs, fmt.Errorf("warmup.timeout: must be at least 1s"))
+	}
+	if (c.Share.WeChatAppID == "") != (c.Share.WeChatAppSecret == "") {
+		errs = append(errs, fmt.Errorf("share.wechat_app_id, share.wechat_app_secret: must be set together"))
+	}
+	if c.ShareCounts.Schedule != "" {
+		if _, err := cron.Parse(c.ShareCounts.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("share_counts.schedule: %v", err))
+		}
+	}
+	for _, platform := range c.ShareCounts.Platforms {
+		switch platform {
+		case "hackernews", "v2ex":
+		case "facebook":
+			if c.ShareCounts.FacebookToken == "" {
+				errs = append(errs, fmt.Errorf("share_counts.facebook_token: required for the facebook platform"))
+			}
+		case "x":
+			if c.ShareCounts.XBearerToken == "" {
+				errs = append(errs, fmt.Errorf("share_counts.x_bearer_token: required for the x platform"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("share_counts.platforms: unknown platform %q, must be hackernews, v2ex, facebook or x", platform))
+		}
+	}
+	if c.ShareCounts.MaxAge < Duration(24*time.Hour) {
+		errs = append(errs, fmt.Errorf("share_counts.max_age: must be at least 24h"))
+	}
+	switch c.CommentBridge.Provider {
+	case "":
+	case "giscus":
+		if owner, name, ok := strings.Cut(c.CommentBridge.GiscusRepository, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			errs = append(errs, fmt.Errorf("comment_bridge.giscus_repository: must be owner/name"))
+		}
+		if c.CommentBridge.GiscusToken == "" {
+			errs = append(errs, fmt.Errorf("comment_bridge.giscus_token: required for giscus"))
+		}
+	case "disqus":
+		if c.CommentBridge.DisqusForum == "" || c.CommentBridge.DisqusAPIKey == "" {
+			errs = append(errs, fmt.Errorf("comment_bridge.disqus_forum, comment_bridge.disqus_api_key: required for disqus"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("comment_bridge.provider: unknown provider %q, must be giscus or disqus", c.CommentBridge.Provider))
+	}
+	if c.CommentBridge.Schedule != "" {
+		if _, err := cron.Parse(c.CommentBridge.Schedule); err != nil {
+			errs = append(errs, fmt.Errorf("comment_bridge.schedule: %v", err))
+		}
+	}
+	for _, language := range c.Publish.RequiredLanguages {
+		if language == "" || len(language) > 10 {
+			errs = append(errs, fmt.Errorf("publish.required_languages: %q is not a language code", language))
+		}
+	}
+	if c.Publish.MinSEOScore < 0 || c.Publish.MinSEOScore > 100 {
+		errs = append(errs, fmt.Errorf("publish.min_seo_score: must be between 0 and 100"))
+	}
+	if c.Publish.MinInternalLinks < 0 {
+		errs = append(errs, fmt.Errorf("publish.min_internal_links: must not be negative"))
+	}
+	if _, ok := styles.Registry[strings.ToLower(c.Highlight.Style)]; !ok {
+		errs = append(errs, fmt.Errorf("highlight.style: unknown style %q", c.Highlight.Style))
+	}
+	if c.AI.TranslationMemoryMatch < 0 || c.AI.TranslationMemoryMatch > 100 {
+		errs = append(errs, fmt.Errorf("ai.translation_memory_match: must be between 0 and 100"))
+	}
+	if c.AI.MaxConcurrent < 1 {
+		errs = append(errs, fmt.Errorf("ai.max_concurrent: must be at least 1"))
+	}
+	if c.AI.QueueTimeout < 0 {
+		errs = append(errs, fmt.Errorf("ai.queue_timeout: must not be negative"))
+	}
+	if c.AI.RequestTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("ai.request_timeout: must be positive"))
+	}
+	if !oneOf(strings.ToLower(c.Logging.Level), "", "debug", "info", "warn", "warning", "error") {
+		errs = append(errs, fmt.Errorf("logging.level: must be debug, info, warn or error"))
+	}
+	if !oneOf(strings.ToLower(c.Logging.Format), "", "json", "text") {
+		errs = append(errs, fmt.Errorf("logging.format: must be json or text"))
+	}
+	if c.Logging.SlowRequest < 0 || c.Logging.SlowQuery < 0 {
+		errs = append(errs, fmt.Errorf("logging.slow_request, logging.slow_query: must not be negative"))
+	}
+	if !oneOf(strings.ToLower(c.Sanitize.Mode), "", "off", "untrusted", "all") {
+		errs = append(errs, fmt.Errorf("sanitize.mode: must be off, untrusted or all"))
+	}
+	if c.Secrets.CacheTTL < 0 || c.Secrets.RefreshInterval < 0 {
+		errs = append(errs, fmt.Errorf("secrets: cache_ttl and refresh_interval must not be negative"))
+	}
+	if rpID := c.Auth.WebAuthn.RPID; rpID != "" && strings.ContainsAny(rpID, "/: ") {
+		errs = append(errs, fmt.Errorf("auth.webauthn.rp_id: %q is not a domain", rpID))
+	}
+	for _, origin := range c.Auth.WebAuthn.Origins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("auth.webauthn.origins: %q is not an http(s) origin", origin))
+			continue
+		}
+		if rpID := c.Auth.WebAuthn.RPID; rpID != "" && u.Hostname() != rpID && !strings.HasSuffix(u.Hostname(), "."+rpID) {
+			errs = append(errs, fmt.Errorf("auth.webauthn.origins: %q is not on the rp_id domain %s", origin, rpID))
+		}
+	}
+	if c.SMTP.Host != "" {
+		if _, err := strconv.Atoi(c.SMTP.Port); err != nil {
+			errs = append(errs, fmt.Errorf("smtp.port: %q is not a valid port", c.SMTP.Port))
+		}
+		if c.SMTP.From == "" {
+			errs = append(errs, fmt.Errorf("smtp.from: required when smtp.host is set"))
+		}
+	}
+
+	return errs
+}
+
+// Export publishes file-provided values as environment variables so code
+// that reads the environment directly sees them. Real environment variables
+// are never overwritten.
+func (l *Loaded) Export() {
+	for _, field := range envFields(l.Config) {
+		if l.Sources[field.env] != SourceFile {
+			continue
+		}
+		if _, exists := os.LookupEnv(field.env); exists {
+			continue
+		}
+		os.Setenv(field.env, formatField(field.value))
+	}
+}
+
+// Redacted returns the configuration as a nested map with secrets masked.
+// Secret manager references are shown as-is since they are not secrets.
+func (c *Config) Redacted() map[string]interface{} {
+	return redactStruct(reflect.ValueOf(c).Elem())
+}
+
+func redactStruct(v reflect.Value) map[string]interface{} {
+	result := make(map[string]interface{})
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		value := v.Field(i)
+
+		switch {
+		case value.Kind() == reflect.Struct && field.Type != reflect.TypeOf(Duration(0)):
+			result[name] = redactStruct(value)
+		case field.Tag.Get("secret") == "true":
+			result[name] = redactSecret(value.String())
+		case field.Type == reflect.TypeOf(Duration(0)):
+			result[name] = formatField(value)
+		default:
+			result[name] = value.Interface()
+		}
+	}
+	return result
+}
+
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if isSecretRef(value) {
+		return value
+	}
+	return "[REDACTED]"
+}
+
+// isSecretRef mirrors security.IsSecretRef without importing the package
+func isSecretRef(value string) bool {
+	for _, prefix := range []string{"env://", "file://", "vault://", "aws-sm://"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultPath returns CONFIG_FILE, or the first of kuno.yaml, kuno.yml,
+// kuno.toml found in the working directory or ./data
+func DefaultPath() string {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return path
+	}
+	for _, dir := range []string{".", "./data"} {
+		for _, name := range []string{"kuno.yaml", "kuno.yml", "kuno.toml"} {
+			candidate := filepath.Join(dir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// loadFile decodes a config file over the current values and records which
+// values it set by comparing against the previous snapshot
+func (l *Loaded) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	before := snapshot(l.Config)
+	if err := decodeFile(path, data, l.Config); err != nil {
+		return err
+	}
+	for env, value := range snapshot(l.Config) {
+		if before[env] != value {
+			l.Sources[env] = SourceFile
+		}
+	}
+	return nil
+}
+
+// decodeFile decodes YAML or TOML based on the file extension, rejecting unknown keys
+func decodeFile(path string, data []byte, cfg *Config) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		// An empty document decodes to io.EOF and leaves the defaults untouched
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(cfg); err != nil {
+			return fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported config file type %q (use .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+	return nil
+}
+
+type envField struct {
+	env   string
+	value reflect.Value
+}
+
+// envFields lists every field with an env tag, depth first
+func envFields(cfg *Config) []envField {
+	var fields []envField
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if env := field.Tag.Get("env"); env != "" {
+				fields = append(fields, envField{env: env, value: v.Field(i)})
+			} else if field.Type.Kind() == reflect.Struct {
+				walk(v.Field(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem())
+	return fields
+}
+
+func snapshot(cfg *Config) map[string]string {
+	values := make(map[string]string)
+	for _, field := range envFields(cfg) {
+		values[field.env] = formatField(field.value)
+	}
+	return values
+}
+
+// setField parses an environment value into a config field
+func setField(field reflect.Value, value string) error {
+	switch field.Interface().(type) {
+	case Duration:
+		var d Duration
+		if err := d.UnmarshalText([]byte(value)); err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(d))
+	case string:
+		field.SetString(value)
+	case bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		field.SetBool(parsed)
+	case int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		field.SetInt(int64(parsed))
+	case float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		field.SetFloat(parsed)
+	case []string:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported config field type %s", field.Type())
+	}
+	return nil
+}
+
+// formatField renders a field the way the environment variable expects it
+func formatField(field reflect.Value) string {
+	switch value := field.Interface().(type) {
+	case Duration:
+		return value.Std().String()
+	case []string:
+		return strings.Join(value, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// activityPubUsername is the form of the blog's fediverse handle
+var activityPubUsername = regexp.MustCompile(`^[A-Za-z0-9_]{1,30}$`)
+
+func oneOf(value string, options ...string) bool {
+	for _, option := range options {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Global configuration instance
+var (
+	globalConfig     *Loaded
+	globalConfigErr  error
+	globalConfigOnce sync.Once
+)
+
+// Get returns the global configuration, loading it on first use from
+// DefaultPath() and the environment. File values are exported to the
+// environment so legacy os.Getenv readers observe them.
+func Get() *Loaded {
+	globalConfigOnce.Do(func() {
+		globalConfig, globalConfigErr = Load(DefaultPath())
+		globalConfig.Export()
+	})
+	return globalConfig
+}
+
+// LoadError returns the error from loading the global configuration, if any
+func LoadError() error {
+	Get()
+	return globalConfigErr
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadYAMLWithEnvOverride(t *testing.T) {
+	path := writeConfig(t, "kuno.yaml", `
+server:
+  port: "9000"
+  shutdown_timeout: 10s
+database:
+  path: /tmp/file.db
+auth:
+  jwt_secret: file-secret-value
+  webauthn:
+    origins: [https://a.example, https://b.example]
+`)
+	t.Setenv("DB_PATH", "/tmp/env.db")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Server.Port != "9000" || cfg.Server.ShutdownTimeout.Std() != 10*time.Second {
+		t.Errorf("file values not applied: %+v", cfg.Server)
+	}
+	if cfg.Database.Path != "/tmp/env.db" || cfg.Sources["DB_PATH"] != SourceEnv {
+		t.Errorf("env override not applied: %q (%s)", cfg.Database.Path, cfg.Sources["DB_PATH"])
+	}
+	if cfg.Sources["PORT"] != SourceFile || cfg.Sources["UPLOAD_DIR"] != SourceDefault {
+		t.Errorf("unexpected sources: %v", cfg.Sources)
+	}
+	if len(cfg.Auth.WebAuthn.Origins) != 2 {
+		t.Errorf("origins = %v", cfg.Auth.WebAuthn.Origins)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeConfig(t, "kuno.toml", `
+[server]
+port = "8100"
+
+[sanitize]
+mode = "all"
+nofollow = false
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Server.Port != "8100" || cfg.Sanitize.Mode != "all" || cfg.Sanitize.NoFollow {
+		t.Errorf("TOML values not applied: %+v %+v", cfg.Server, cfg.Sanitize)
+	}
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	path := writeConfig(t, "kuno.yaml", "server:\n  port: \"99999\"\nlogging:\n  level: loud\n"+
+		"auth:\n  webauthn:\n    rp_id: blog.example.com\n    origins: [blog.example.com, \"https://other.example.com\"]\n")
+
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"server.port", "logging.level", `"blog.example.com" is not an http(s) origin`, `"https://other.example.com" is not on the rp_id domain`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+
+	unknown := writeConfig(t, "kuno.yaml", "server:\n  prot: \"8085\"\n")
+	if _, err := Load(unknown); err == nil {
+		t.Error("expected unknown key to be rejected")
+	}
+}
+
+func TestExportDoesNotOverrideEnvironment(t *testing.T) {
+	path := writeConfig(t, "kuno.yaml", "smtp:\n  host: smtp.example.com\n  from: blog@example.com\n")
+	t.Setenv("SMTP_FROM", "env@example.com")
+	os.Unsetenv("SMTP_HOST")
+	t.Cleanup(func() { os.Unsetenv("SMTP_HOST") })
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	cfg.Export()
+
+	if os.Getenv("SMTP_HOST") != "smtp.example.com" {
+		t.Errorf("SMTP_HOST = %q, expected file value", os.Getenv("SMTP_HOST"))
+	}
+	if os.Getenv("SMTP_FROM") != "env@example.com" {
+		t.Errorf("SMTP_FROM = %q, expected env value to win", os.Getenv("SMTP_FROM"))
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.JWTSecret = "super-secret"
+	cfg.AI.OpenAIAPIKey = "vault://secret/data/kuno#openai"
+
+	redacted := cfg.Redacted()
+	auth := redacted["auth"].(map[string]interface{})
+	if auth["jwt_secret"] != "[REDACTED]" {
+		t.Errorf("jwt_secret = %v", auth["jwt_secret"])
+	}
+	ai := redacted["ai"].(map[string]interface{})
+	if ai["openai_api_key"] != "vault://secret/data/kuno#openai" {
+		t.Errorf("secret reference should be shown, got %v", ai["openai_api_key"])
+	}
+	server := redacted["server"].(map[string]interface{})
+	if server["shutdown_timeout"] != "30s" {
+		t.Errorf("shutdown_timeout = %v", server["shutdown_timeout"])
+	}
+}
@@ -0,0 +1,54 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	base := time.Date(2025, 3, 14, 10, 17, 30, 0, time.Local)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 3, 14, 10, 18, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2025, 3, 14, 10, 30, 0, 0, time.Local)},
+		{"0 3 * * *", time.Date(2025, 3, 15, 3, 0, 0, 0, time.Local)},
+		{"@daily", time.Date(2025, 3, 15, 0, 0, 0, 0, time.Local)},
+		{"30 2 1 * *", time.Date(2025, 4, 1, 2, 30, 0, 0, time.Local)},
+		{"0 9 * * mon-fri", time.Date(2025, 3, 17, 9, 0, 0, 0, time.Local)},
+		{"0 0 * * 7", time.Date(2025, 3, 16, 0, 0, 0, 0, time.Local)},
+		{"0 0 1 jan *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)},
+		// Restricted day of month and day of week match on either
+		{"0 0 20 * 6", time.Date(2025, 3, 15, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if got := schedule.Next(base); !got.Equal(tt.want) {
+			t.Errorf("Next(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestNextNeverMatches(t *testing.T) {
+	schedule, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := schedule.Next(time.Now()); !got.IsZero() {
+		t.Errorf("expected no run time, got %v", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", expr)
+		}
+	}
+}
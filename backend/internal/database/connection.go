@@ -1,42 +1,77 @@
 package database
 
 import (
+	"blog-backend/internal/config"
 	"blog-backend/internal/models"
 	"log"
+	"log/slog"
 	"os"
 	"strings"
 
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
 func InitDatabase() {
-	dbPath := getEnv("DB_PATH", "./data/blog.db")
+	dbConfig := config.Get().Database
 
 	// Enhanced logging for database initialization
-	log.Printf("🔍 Database initialization starting...")
-	log.Printf("📁 Database path: %s", dbPath)
-
-	// Check if database file exists
-	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-		log.Printf("📄 Database file does not exist, will be created: %s", dbPath)
-	} else if err != nil {
-		log.Printf("⚠️ Error checking database file: %v", err)
+	if dbConfig.Driver == DriverSQLite {
+		dbPath := dbConfig.Path
+		slog.Info("Database initialization starting", "driver", dbConfig.Driver, "path", dbPath)
+
+		// Check if database file exists
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			slog.Info("Database file does not exist, will be created", "path", dbPath)
+		} else if err != nil {
+			slog.Warn("Error checking database file", "error", err)
+		} else {
+			info, _ := os.Stat(dbPath)
+			slog.Info("Existing database file found", "path", dbPath, "size_bytes", info.Size())
+		}
 	} else {
-		info, _ := os.Stat(dbPath)
-		log.Printf("📊 Existing database file found: %s (size: %d bytes)", dbPath, info.Size())
+		slog.Info("Database initialization starting", "driver", dbConfig.Driver)
 	}
 
-	var err error
-	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	dialector, err := OpenDialector(dbConfig)
+	if err != nil {
+		log.Fatal("Failed to configure database:", err)
+	}
+
+	DB, err = gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		log.Fatal("Failed to connect database:", err)
 	}
+	if err := DB.Use(SiteScope{}); err != nil {
+		log.Fatal("Failed to register site scope:", err)
+	}
+	// The profiler is always registered, as query budgets count statements
+	// through it; without thresholds it only records attached profiles
+	if err := DB.Use(QueryProfiler{SlowQuery: config.Get().Logging.SlowQuery.Std()}); err != nil {
+		log.Fatal("Failed to register query profiler:", err)
+	}
 
-	err = DB.AutoMigrate(&models.Article{}, &models.Category{}, &models.SiteSettings{}, &models.User{}, &models.MediaLibrary{}, &models.ArticleTranslation{}, &models.CategoryTranslation{}, &models.SiteSettingsTranslation{}, &models.ArticleView{}, &models.SocialMedia{}, &models.AIUsageRecord{}, &models.ArticleEmbedding{}, &models.SearchIndex{}, &models.SEOKeyword{}, &models.SEOHealthCheck{}, &models.SEOMetrics{}, &models.SEOKeywordGroup{}, &models.SEOKeywordGroupMember{}, &models.SEOAutomationRule{}, &models.SEONotification{}, &models.SEOTemplate{}, &models.SearchCache{}, &models.PopularQuery{}, &models.ContentQualityAnalysis{}, &models.WritingSuggestion{}, &models.UserReadingBehavior{}, &models.PersonalizedRecommendation{}, &models.UserProfile{})
+	if sqlDB, err := DB.DB(); err == nil {
+		maxOpen, maxIdle := dbConfig.MaxOpenConns, dbConfig.MaxIdleConns
+		if Dialect() == DriverSQLite {
+			if maxOpen == 0 {
+				maxOpen = defaultSQLiteMaxConns
+			}
+			if maxIdle == 0 {
+				maxIdle = maxOpen
+			}
+		}
+		if maxOpen > 0 {
+			sqlDB.SetMaxOpenConns(maxOpen)
+		}
+		if maxIdle > 0 {
+			sqlDB.SetMaxIdleConns(maxIdle)
+		}
+	}
+
+	err = Migrate(DB)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
@@ -44,7 +79,7 @@ func InitDatabase() {
 	// Initialize default site settings if none exist
 	var settingsCount int64
 	DB.Model(&models.SiteSettings{}).Count(&settingsCount)
-	log.Printf("📊 Found %d site settings records", settingsCount)
+	slog.Debug("Site settings records found", "count", settingsCount)
 	if settingsCount == 0 {
 		defaultSettings := models.SiteSettings{
 			SiteTitle:          "KUNO",
@@ -57,7 +92,7 @@ func InitDatabase() {
 			FaviconURL:         "",
 		}
 		DB.Create(&defaultSettings)
-		log.Println("Default site settings created")
+		slog.Info("Default site settings created")
 	} else {
 		// Update existing settings to ensure all fields have proper defaults
 		var existingSettings models.SiteSettings
@@ -75,12 +110,12 @@ func InitDatabase() {
 				if userCount > 0 {
 					existingSettings.SetupCompleted = true
 					updated = true
-					log.Println("Marked setup as completed for existing installation")
+					slog.Info("Marked setup as completed for existing installation")
 				}
 			}
 			if updated {
 				DB.Save(&existingSettings)
-				log.Println("Site settings updated with missing defaults")
+				slog.Info("Site settings updated with missing defaults")
 			}
 		}
 	}
@@ -88,7 +123,7 @@ func InitDatabase() {
 	// Initialize default admin user if none exist
 	var userCount int64
 	DB.Model(&models.User{}).Count(&userCount)
-	log.Printf("👥 Found %d user records", userCount)
+	slog.Debug("User records found", "count", userCount)
 	if userCount == 0 {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("xuemian168"), bcrypt.DefaultCost)
 		if err != nil {
@@ -101,19 +136,31 @@ func InitDatabase() {
 			IsAdmin:  true,
 		}
 		DB.Create(&defaultUser)
-		log.Println("Default admin user created (username: admin)")
+		slog.Info("Default admin user created", "username", "admin")
 	}
 
 	// Initialize default content (category and hello world article)
 	initializeDefaultContent()
 
-	log.Println("Database connected and migrated successfully")
+	slog.Info("Database connected and migrated successfully")
 
 	// Check recovery mode
 	checkRecoveryMode()
 }
 
 // checkRecoveryMode handles password recovery functionality
+// Close closes the underlying database connection pool
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
 func checkRecoveryMode() {
 	recoveryMode := strings.ToLower(getEnv("RECOVERY_MODE", "false"))
 
@@ -173,23 +220,23 @@ func initializeDefaultContent() {
 	// Check if we already have categories
 	var categoryCount int64
 	DB.Model(&models.Category{}).Count(&categoryCount)
-	log.Printf("📂 Found %d category records", categoryCount)
+	slog.Debug("Category records found", "count", categoryCount)
 
 	// Check if we already have articles
 	var articleCount int64
 	DB.Model(&models.Article{}).Count(&articleCount)
-	log.Printf("📝 Found %d article records", articleCount)
+	slog.Debug("Article records found", "count", articleCount)
 
 	// Only initialize if we have no categories and no articles
 	if categoryCount == 0 && articleCount == 0 {
-		log.Printf("🆕 No existing content found, initializing default content...")
+		slog.Info("No existing content found, initializing default content")
 		// Create default category
 		defaultCategory := models.Category{
 			Name:        "Welcome",
 			Description: "Welcome to my blog",
 		}
 		if err := DB.Create(&defaultCategory).Error; err != nil {
-			log.Printf("Failed to create default category: %v", err)
+			slog.Error("Failed to create default category", "error", err)
 			return
 		}
 
@@ -216,8 +263,9 @@ func initializeDefaultContent() {
 		}
 
 		for _, translation := range categoryTranslations {
+			translation.SourceHash = defaultCategory.SourceHash()
 			if err := DB.Create(&translation).Error; err != nil {
-				log.Printf("Failed to create category translation for %s: %v", translation.Language, err)
+				slog.Error("Failed to create category translation", "language", translation.Language, "error", err)
 			}
 		}
 
@@ -234,9 +282,14 @@ func initializeDefaultContent() {
 			SEOKeywords:    "hello world, markdown, blog, multilingual, sample",
 			SEOSlug:        "hello-world",
 		}
+		// Credit it to the admin created above
+		var admin models.User
+		if DB.Select("id").Order("id").Limit(1).Find(&admin).Error == nil && admin.ID != 0 {
+			helloWorldArticle.AuthorID = &admin.ID
+		}
 
 		if err := DB.Create(&helloWorldArticle).Error; err != nil {
-			log.Printf("Failed to create Hello World article: %v", err)
+			slog.Error("Failed to create Hello World article", "error", err)
 			return
 		}
 
@@ -266,14 +319,15 @@ func initializeDefaultContent() {
 		}
 
 		for _, translation := range articleTranslations {
+			translation.StampSource(&helloWorldArticle)
 			if err := DB.Create(&translation).Error; err != nil {
-				log.Printf("Failed to create article translation for %s: %v", translation.Language, err)
+				slog.Error("Failed to create article translation", "language", translation.Language, "error", err)
 			}
 		}
 
-		log.Println("Default content initialized: Welcome category and Hello World article created")
+		slog.Info("Default content initialized: Welcome category and Hello World article created")
 	} else {
-		log.Printf("✅ Existing content found, skipping default content initialization (categories: %d, articles: %d)", categoryCount, articleCount)
+		slog.Info("Existing content found, skipping default content initialization", "categories", categoryCount, "articles", articleCount)
 	}
 }
 
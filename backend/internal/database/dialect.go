@@ -0,0 +1,150 @@
+package database
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"fmt"
+	"strings"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Supported database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// Models returns every persisted model in migration order
+func Models() []interface{} {
+	return append(baselineModels(),
+		&models.Job{},
+		&models.Plugin{},
+		&models.Site{},
+		&models.SystemSetting{},
+		&models.StorageSnapshot{},
+		&models.Subscriber{},
+		&models.Newsletter{},
+		&models.NewsletterDelivery{},
+		&models.EmailTemplate{},
+		&models.Notifier{},
+		&models.Follower{},
+		&models.FederatedReply{},
+		&models.MailSuppression{},
+		&models.ArticleSlugRedirect{},
+		&models.TranslationMemory{},
+		&models.ContactMessage{},
+		&models.FriendLink{},
+		&models.Moment{},
+		&models.Project{},
+		&models.ProjectTranslation{},
+		&models.GuestbookEntry{},
+		&models.DonationMethod{},
+		&models.ReadingPosition{},
+		&models.Page{},
+		&models.PageTranslation{},
+		&models.Theme{},
+		&models.ThemeAsset{},
+		&models.Announcement{},
+		&models.AnnouncementTranslation{},
+		&models.AuthorProfile{},
+		&models.AuthorProfileTranslation{},
+		&models.APIKey{},
+		&models.APIKeyUsage{},
+		&models.MediaMetadataStat{},
+		&models.StopWords{},
+		&models.UnansweredSearch{},
+		&models.Notification{},
+		&models.Member{},
+		&models.MemberToken{},
+		&models.TopicCentroid{},
+		&models.SEOMetadataProposal{},
+		&models.ArticleShareCount{},
+		&models.CustomCodeAudit{},
+		&models.FeedSource{},
+		&models.FeedItem{},
+		&models.QuickLink{},
+		&models.SearchPushEngine{},
+		&models.SearchPushLog{},
+		&models.ExternalDiscussion{},
+	)
+}
+
+// siteScopedModels are the models that belong to a site in multi-site mode
+func siteScopedModels() []interface{} {
+	return []interface{}{
+		&models.Article{}, &models.Category{}, &models.SiteSettings{}, &models.User{},
+		&models.MediaLibrary{}, &models.SocialMedia{},
+	}
+}
+
+// baselineModels are the tables created by the 0001_baseline migration.
+// Tables added later belong in Models and their own migration.
+func baselineModels() []interface{} {
+	return []interface{}{
+		&models.Article{}, &models.Category{}, &models.SiteSettings{}, &models.User{}, &models.MediaLibrary{},
+		&models.ArticleTranslation{}, &models.CategoryTranslation{}, &models.SiteSettingsTranslation{},
+		&models.ArticleView{}, &models.SocialMedia{}, &models.AIUsageRecord{}, &models.ArticleEmbedding{},
+		&models.SearchIndex{}, &models.SEOKeyword{}, &models.SEOHealthCheck{}, &models.SEOMetrics{},
+		&models.SEOKeywordGroup{}, &models.SEOKeywordGroupMember{}, &models.SEOAutomationRule{},
+		&models.SEONotification{}, &models.SEOTemplate{}, &models.SearchCache{}, &models.PopularQuery{},
+		&models.ContentQualityAnalysis{}, &models.WritingSuggestion{}, &models.UserReadingBehavior{},
+		&models.PersonalizedRecommendation{}, &models.UserProfile{}, &models.WebAuthnCredential{},
+		&models.DirectUpload{}, &models.LoginSession{}, &models.KnownLoginDevice{},
+	}
+}
+
+// OpenDialector returns the gorm dialector for the configured driver. DSNs
+// may be secret manager references.
+func OpenDialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "", DriverSQLite:
+		return sqlite.Open(sqliteDSN(cfg)), nil
+	case DriverPostgres, DriverMySQL:
+		dsn, err := security.ResolveSecret(cfg.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve database DSN: %v", err)
+		}
+		if cfg.Driver == DriverPostgres {
+			return postgres.Open(dsn), nil
+		}
+		return mysql.New(mysql.Config{DSN: dsn}), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+}
+
+// Dialect returns the name of the active database driver
+func Dialect() string {
+	if DB == nil {
+		return DriverSQLite
+	}
+	return DB.Dialector.Name()
+}
+
+// DateExpr returns an expression that formats a timestamp column as
+// YYYY-MM-DD text, so daily buckets scan into strings on every driver
+func DateExpr(column string) string {
+	switch Dialect() {
+	case DriverPostgres:
+		return fmt.Sprintf("TO_CHAR(%s, 'YYYY-MM-DD')", column)
+	case DriverMySQL:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d')", column)
+	default:
+		return fmt.Sprintf("DATE(%s)", column)
+	}
+}
+
+// Like rewrites LIKE to ILIKE on PostgreSQL, where LIKE is case-sensitive,
+// so text search matches the SQLite and MySQL behavior
+func Like(condition string) string {
+	if Dialect() == DriverPostgres {
+		return strings.ReplaceAll(condition, " LIKE ", " ILIKE ")
+	}
+	return condition
+}
@@ -0,0 +1,988 @@
+package database
+
+import (
+	"blog-backend/internal/models"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned schema change. IDs sort lexically in the order
+// migrations must run, so they are prefixed with a zero-padded sequence.
+//
+// New schema changes are added as new migrations at the end of the list;
+// released migrations are never edited. A migration that creates a table may
+// AutoMigrate that model, but column changes to existing tables should use
+// explicit Migrator calls so they behave the same on every release.
+type Migration struct {
+	ID          string
+	Description string
+	Up          func(tx *gorm.DB) error
+	Down        func(tx *gorm.DB) error
+}
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	ID          string     `json:"id"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+// Migrations returns all known migrations in order
+func Migrations() []Migration {
+	return []Migration{
+		{
+			// Databases created before versioned migrations already have most of
+			// these tables; AutoMigrate brings them up to the baseline in place.
+			ID:          "0001_baseline",
+			Description: "Baseline schema",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(baselineModels()...)
+			},
+			Down: func(tx *gorm.DB) error {
+				tables := baselineModels()
+				for i := len(tables) - 1; i >= 0; i-- {
+					if err := tx.Migrator().DropTable(tables[i]); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0002_add_jobs",
+			Description: "Add background job queue",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Job{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.Job{})
+			},
+		},
+		{
+			ID:          "0003_add_plugins",
+			Description: "Add webhook plugins",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Plugin{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.Plugin{})
+			},
+		},
+		{
+			ID:          "0004_add_sites",
+			Description: "Add sites for multi-site mode",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.Site{}); err != nil {
+					return err
+				}
+				for _, model := range siteScopedModels() {
+					if tx.Migrator().HasColumn(model, "SiteID") {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(model, "SiteID"); err != nil {
+						return err
+					}
+					if err := tx.Migrator().CreateIndex(model, "SiteID"); err != nil {
+						return err
+					}
+				}
+				return tx.Where(models.Site{ID: models.DefaultSiteID}).
+					FirstOrCreate(&models.Site{Name: "Default", Slug: "default"}).Error
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, model := range siteScopedModels() {
+					if tx.Migrator().HasIndex(model, "SiteID") {
+						if err := tx.Migrator().DropIndex(model, "SiteID"); err != nil {
+							return err
+						}
+					}
+					if err := tx.Migrator().DropColumn(model, "SiteID"); err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().DropTable(&models.Site{})
+			},
+		},
+		{
+			ID:          "0005_add_system_settings",
+			Description: "Add instance-wide system settings",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SystemSetting{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.SystemSetting{})
+			},
+		},
+		{
+			ID:          "0006_add_job_unique_key",
+			Description: "Add unique keys to jobs so replicas queue each scheduled run once",
+			Up: func(tx *gorm.DB) error {
+				if tx.Migrator().HasColumn(&models.Job{}, "UniqueKey") {
+					return nil
+				}
+				if err := tx.Migrator().AddColumn(&models.Job{}, "UniqueKey"); err != nil {
+					return err
+				}
+				return tx.Migrator().CreateIndex(&models.Job{}, "UniqueKey")
+			},
+			Down: func(tx *gorm.DB) error {
+				if tx.Migrator().HasIndex(&models.Job{}, "UniqueKey") {
+					if err := tx.Migrator().DropIndex(&models.Job{}, "UniqueKey"); err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().DropColumn(&models.Job{}, "UniqueKey")
+			},
+		},
+		{
+			ID:          "0007_add_storage_snapshots",
+			Description: "Add daily storage snapshots for disk usage trends",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.StorageSnapshot{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.StorageSnapshot{})
+			},
+		},
+		{
+			ID:          "0008_add_newsletter",
+			Description: "Add newsletter subscribers, newsletters and their deliveries",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Subscriber{}, &models.Newsletter{}, &models.NewsletterDelivery{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.NewsletterDelivery{}, &models.Newsletter{}, &models.Subscriber{})
+			},
+		},
+		{
+			ID:          "0009_add_email_templates",
+			Description: "Add per-language overrides of the built-in email templates",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.EmailTemplate{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.EmailTemplate{})
+			},
+		},
+		{
+			ID:          "0010_add_notifiers",
+			Description: "Add Telegram, Discord and Slack publish notifiers",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Notifier{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.Notifier{})
+			},
+		},
+		{
+			ID:          "0011_add_activitypub",
+			Description: "Add ActivityPub followers and replies from the fediverse",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Follower{}, &models.FederatedReply{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.FederatedReply{}, &models.Follower{})
+			},
+		},
+		{
+			ID:          "0012_add_mail_suppressions",
+			Description: "Add the list of addresses email is never sent to",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.MailSuppression{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.MailSuppression{})
+			},
+		},
+		{
+			ID:          "0013_add_translation_slugs",
+			Description: "Add per-language article slugs and redirects from old slugs",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.ArticleTranslation{}, &models.ArticleSlugRedirect{})
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropTable(&models.ArticleSlugRedirect{}); err != nil {
+					return err
+				}
+				return tx.Migrator().DropColumn(&models.ArticleTranslation{}, "SEOSlug")
+			},
+		},
+		{
+			ID:          "0014_add_translation_status",
+			Description: "Track the source text and machine translation of translations",
+			Up: func(tx *gorm.DB) error {
+				translations := []interface{}{&models.ArticleTranslation{}, &models.CategoryTranslation{}, &models.SiteSettingsTranslation{}}
+				if err := tx.AutoMigrate(translations...); err != nil {
+					return err
+				}
+				return stampTranslationSources(tx)
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, model := range []interface{}{&models.ArticleTranslation{}, &models.CategoryTranslation{}, &models.SiteSettingsTranslation{}} {
+					for _, column := range []string{"SourceHash", "MachineTranslated"} {
+						if err := tx.Migrator().DropColumn(model, column); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0015_add_translation_sections",
+			Description: "Keep the section hashes of the source text of article translations",
+			Up: func(tx *gorm.DB) error {
+				// Translations without sections are re-translated whole
+				return tx.AutoMigrate(&models.ArticleTranslation{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropColumn(&models.ArticleTranslation{}, "SourceSections")
+			},
+		},
+		{
+			ID:          "0016_add_translation_memory",
+			Description: "Remember translated segments for reuse",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.TranslationMemory{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.TranslationMemory{})
+			},
+		},
+		{
+			ID:          "0017_add_contact_messages",
+			Description: "Store messages sent through the contact form",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.ContactMessage{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.ContactMessage{})
+			},
+		},
+		{
+			ID:          "0018_add_friend_links",
+			Description: "Add the blogroll of friend links",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.FriendLink{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.FriendLink{})
+			},
+		},
+		{
+			ID:          "0019_add_moments",
+			Description: "Add moments, short status updates beside articles",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Moment{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.Moment{})
+			},
+		},
+		{
+			ID:          "0020_add_projects",
+			Description: "Add portfolio projects and their translations",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Project{}, &models.ProjectTranslation{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.ProjectTranslation{}, &models.Project{})
+			},
+		},
+		{
+			ID:          "0021_add_guestbook",
+			Description: "Add guestbook entries",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.GuestbookEntry{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.GuestbookEntry{})
+			},
+		},
+		{
+			ID:          "0022_add_site_stats_settings",
+			Description: "Add the settings choosing which site statistics are public",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SiteSettings{})
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"StatsShowPosts", "StatsShowWords", "StatsShowDaysRunning", "StatsShowViews", "StatsShowLastUpdated"} {
+					if err := tx.Migrator().DropColumn(&models.SiteSettings{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0023_add_donations",
+			Description: "Add donation methods and the settings showing them under articles",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.DonationMethod{}, &models.Article{}, &models.SiteSettings{})
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropColumn(&models.Article{}, "ShowDonation"); err != nil {
+					return err
+				}
+				if err := tx.Migrator().DropColumn(&models.SiteSettings{}, "DonationsOnArticles"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable(&models.DonationMethod{})
+			},
+		},
+		{
+			ID:          "0024_add_view_count_settings",
+			Description: "Add the settings deciding which article views are counted",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SiteSettings{})
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"ViewDedupHours", "CountAdminViews"} {
+					if err := tx.Migrator().DropColumn(&models.SiteSettings{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0025_add_reading_positions",
+			Description: "Remember where readers left off in articles",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.ReadingPosition{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.ReadingPosition{})
+			},
+		},
+		{
+			ID:          "0026_add_pages",
+			Description: "Add standalone pages and their translations",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Page{}, &models.PageTranslation{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.PageTranslation{}, &models.Page{})
+			},
+		},
+		{
+			ID:          "0027_add_site_verification",
+			Description: "Add the site verification codes of search engines",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SiteSettings{})
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"GoogleSiteVerification", "BingSiteVerification", "BaiduSiteVerification", "YandexSiteVerification"} {
+					if err := tx.Migrator().DropColumn(&models.SiteSettings{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0028_add_themes",
+			Description: "Add installed theme packages and their assets",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Theme{}, &models.ThemeAsset{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.ThemeAsset{}, &models.Theme{})
+			},
+		},
+		{
+			ID:          "0029_add_branding_variants",
+			Description: "Add the dark mode logo and the generated favicon set",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SiteSettings{})
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"LogoDarkURL", "FaviconSetURL"} {
+					if err := tx.Migrator().DropColumn(&models.SiteSettings{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0030_add_announcements",
+			Description: "Add site-wide announcements and their translations",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Announcement{}, &models.AnnouncementTranslation{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.AnnouncementTranslation{}, &models.Announcement{})
+			},
+		},
+		{
+			ID:          "0031_add_homepage_layout",
+			Description: "Add the homepage layout to site settings",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SiteSettings{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropColumn(&models.SiteSettings{}, "HomepageLayout")
+			},
+		},
+		{
+			ID:          "0032_add_author_profiles",
+			Description: "Credit articles to their authors and add author profiles",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.Article{}, &models.AuthorProfile{}, &models.AuthorProfileTranslation{}); err != nil {
+					return err
+				}
+				// Existing articles were written by the first user of their site
+				firstUser := tx.Model(&models.User{}).Select("MIN(id)").Where("users.site_id = articles.site_id")
+				return tx.Unscoped().Model(&models.Article{}).Where("author_id IS NULL").Update("author_id", firstUser).Error
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropTable(&models.AuthorProfileTranslation{}, &models.AuthorProfile{}); err != nil {
+					return err
+				}
+				return tx.Migrator().DropColumn(&models.Article{}, "AuthorID")
+			},
+		},
+		{
+			ID:          "0033_add_content_licenses",
+			Description: "Add the content license of articles and the site's default",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Article{}, &models.SiteSettings{})
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"License", "LicenseCustom"} {
+					if err := tx.Migrator().DropColumn(&models.Article{}, column); err != nil {
+						return err
+					}
+				}
+				for _, column := range []string{"DefaultLicense", "DefaultLicenseCustom"} {
+					if err := tx.Migrator().DropColumn(&models.SiteSettings{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0034_add_api_keys",
+			Description: "Add read-only API keys and their hourly usage",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.APIKey{}, &models.APIKeyUsage{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.APIKeyUsage{}, &models.APIKey{})
+			},
+		},
+		{
+			ID:          "0035_add_category_parents",
+			Description: "Nest categories under parent categories",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Category{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropColumn(&models.Category{}, "ParentID")
+			},
+		},
+		{
+			ID:          "0036_add_pin_scheduling",
+			Description: "Add the end time of article pins and the site's pin limit",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Article{}, &models.SiteSettings{})
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropColumn(&models.Article{}, "UnpinAt"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropColumn(&models.SiteSettings{}, "MaxPinnedArticles")
+			},
+		},
+		{
+			ID:          "0037_add_reply_threads",
+			Description: "Add the parent and origin of federated replies, for imported comment threads",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.FederatedReply{})
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropColumn(&models.FederatedReply{}, "ParentID"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropColumn(&models.FederatedReply{}, "Source")
+			},
+		},
+		{
+			ID:          "0038_add_media_metadata_stats",
+			Description: "Add counts of the metadata found in uploaded images",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.MediaMetadataStat{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.MediaMetadataStat{})
+			},
+		},
+		{
+			ID:          "0039_add_upload_policy",
+			Description: "Add the upload policy to site settings",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SiteSettings{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropColumn(&models.SiteSettings{}, "UploadPolicy")
+			},
+		},
+		{
+			ID:          "0040_add_stop_words",
+			Description: "Add the stop word lists admins manage per language",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.StopWords{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.StopWords{})
+			},
+		},
+		{
+			ID:          "0041_add_keyword_data",
+			Description: "Add the provider search volume and difficulty data to SEO keywords",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SEOKeyword{})
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"DifficultyScore", "DataSource", "DataUpdatedAt"} {
+					if err := tx.Migrator().DropColumn(&models.SEOKeyword{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0042_extend_content_quality",
+			Description: "Add the language, length and most similar article to content quality analyses",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.ContentQualityAnalysis{})
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"Language", "WordCount", "SimilarArticleID", "Similarity"} {
+					if err := tx.Migrator().DropColumn(&models.ContentQualityAnalysis{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0043_add_writing_gaps",
+			Description: "Record unanswered searches and the status of writing suggestions",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.UnansweredSearch{}, &models.WritingSuggestion{})
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"Topic", "Signal", "Status", "DecidedAt"} {
+					if err := tx.Migrator().DropColumn(&models.WritingSuggestion{}, column); err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().DropTable(&models.UnansweredSearch{})
+			},
+		},
+		{
+			ID:          "0044_add_job_progress",
+			Description: "Record the progress of running jobs",
+			Up: func(tx *gorm.DB) error {
+				for _, column := range []string{"Progress", "Step", "ItemErrors"} {
+					if tx.Migrator().HasColumn(&models.Job{}, column) {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(&models.Job{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"Progress", "Step", "ItemErrors"} {
+					if err := tx.Migrator().DropColumn(&models.Job{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0045_add_notifications",
+			Description: "Create the admin notification center and move SEO notifications into it",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.Notification{}); err != nil {
+					return err
+				}
+				return tx.Exec(`INSERT INTO notifications
+					(source, type, severity, title, message, action_url, article_id, keyword_id, is_read, created_at, updated_at)
+					SELECT ?, type, severity, title, message, action_url, article_id, keyword_id, is_read, created_at, updated_at
+					FROM seo_notifications WHERE deleted_at IS NULL AND is_archived = ?`,
+					models.NotificationSourceSEO, false).Error
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.Notification{})
+			},
+		},
+		{
+			ID:          "0046_add_members",
+			Description: "Add members, their tokens and members-only articles",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Member{}, &models.MemberToken{}, &models.Article{})
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropColumn(&models.Article{}, "MembersOnly"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable(&models.MemberToken{}, &models.Member{})
+			},
+		},
+		{
+			ID:          "0047_add_topic_centroids",
+			Description: "Add the precomputed embedding centroids of categories and tags",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.TopicCentroid{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.TopicCentroid{})
+			},
+		},
+		{
+			ID:          "0048_add_watermark_policy",
+			Description: "Add the image watermark policy to site settings",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SiteSettings{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropColumn(&models.SiteSettings{}, "WatermarkPolicy")
+			},
+		},
+		{
+			ID:          "0049_add_seo_metadata_proposals",
+			Description: "Add the SEO metadata proposals of bulk generation jobs",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SEOMetadataProposal{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.SEOMetadataProposal{})
+			},
+		},
+		{
+			ID:          "0050_add_article_share_counts",
+			Description: "Add the share count history of articles on social platforms",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.ArticleShareCount{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.ArticleShareCount{})
+			},
+		},
+		{
+			ID:          "0051_add_custom_code_audits",
+			Description: "Add the audit trail of custom CSS and JavaScript changes",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.CustomCodeAudit{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.CustomCodeAudit{})
+			},
+		},
+		{
+			ID:          "0052_add_feed_import",
+			Description: "Add feeds posts are imported from, their staged posts and article source attribution",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.FeedSource{}, &models.FeedItem{}, &models.Article{})
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"SourceName", "SourceURL", "CanonicalURL"} {
+					if err := tx.Migrator().DropColumn(&models.Article{}, column); err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().DropTable(&models.FeedItem{}, &models.FeedSource{})
+			},
+		},
+		{
+			ID:          "0053_add_quick_links",
+			Description: "Add the links of the quick links page",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.QuickLink{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.QuickLink{})
+			},
+		},
+		{
+			ID:          "0054_add_search_push",
+			Description: "Add the search engines article links are pushed to and their submission log",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.SearchPushEngine{}, &models.SearchPushLog{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.SearchPushLog{}, &models.SearchPushEngine{})
+			},
+		},
+		{
+			ID:          "0055_add_external_discussions",
+			Description: "Add the comment counts synced from external comment systems",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.ExternalDiscussion{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.ExternalDiscussion{})
+			},
+		},
+		{
+			ID:          "0056_add_article_region_rules",
+			Description: "Add the regions an article is withheld from or limited to",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Article{})
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"RegionRule", "Regions"} {
+					if err := tx.Migrator().DropColumn(&models.Article{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0057_scope_category_names_to_sites",
+			Description: "Make category names unique per site instead of across all sites",
+			Up: func(tx *gorm.DB) error {
+				// Older versions made the name unique with a constraint or
+				// with an index of its own
+				if tx.Migrator().HasConstraint(&models.Category{}, "uni_categories_name") {
+					if err := tx.Migrator().DropConstraint(&models.Category{}, "uni_categories_name"); err != nil {
+						return err
+					}
+				}
+				if tx.Migrator().HasIndex(&models.Category{}, "idx_categories_name") {
+					if err := tx.Migrator().DropIndex(&models.Category{}, "idx_categories_name"); err != nil {
+						return err
+					}
+				}
+				if tx.Migrator().HasIndex(&models.Category{}, "idx_category_site_name") {
+					return nil
+				}
+				return tx.Migrator().CreateIndex(&models.Category{}, "idx_category_site_name")
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropIndex(&models.Category{}, "idx_category_site_name"); err != nil {
+					return err
+				}
+				return tx.Exec("CREATE UNIQUE INDEX idx_categories_name ON categories(name)").Error
+			},
+		},
+		{
+			ID:          "0058_add_guestbook_replies",
+			Description: "Add replies to guestbook entries and the senders' opt-in to hear about them",
+			Up: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.GuestbookEntry{})
+			},
+			Down: func(tx *gorm.DB) error {
+				for _, column := range []string{"NotifyReplies", "Reply", "RepliedAt"} {
+					if err := tx.Migrator().DropColumn(&models.GuestbookEntry{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID:          "0059_add_direct_upload_sites",
+			Description: "Record the site of direct uploads so each site finalizes only its own",
+			Up: func(tx *gorm.DB) error {
+				if tx.Migrator().HasColumn(&models.DirectUpload{}, "SiteID") {
+					return nil
+				}
+				if err := tx.Migrator().AddColumn(&models.DirectUpload{}, "SiteID"); err != nil {
+					return err
+				}
+				return tx.Migrator().CreateIndex(&models.DirectUpload{}, "SiteID")
+			},
+			Down: func(tx *gorm.DB) error {
+				if tx.Migrator().HasIndex(&models.DirectUpload{}, "SiteID") {
+					if err := tx.Migrator().DropIndex(&models.DirectUpload{}, "SiteID"); err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().DropColumn(&models.DirectUpload{}, "SiteID")
+			},
+		},
+	}
+}
+
+// stampTranslationSources takes the existing translations to be up to date
+// with their current source text
+func stampTranslationSources(tx *gorm.DB) error {
+	unstamped := "source_hash IS NULL OR source_hash = ''"
+	var articles []models.Article
+	err := tx.Unscoped().Select("id", "title", "summary", "content").FindInBatches(&articles, 100, func(batch *gorm.DB, _ int) error {
+		for _, article := range articles {
+			if err := tx.Model(&models.ArticleTranslation{}).Where("article_id = ?", article.ID).Where(unstamped).
+				Update("source_hash", article.SourceHash()).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	var categories []models.Category
+	if err := tx.Unscoped().Find(&categories).Error; err != nil {
+		return err
+	}
+	for _, category := range categories {
+		if err := tx.Model(&models.CategoryTranslation{}).Where("category_id = ?", category.ID).Where(unstamped).
+			Update("source_hash", category.SourceHash()).Error; err != nil {
+			return err
+		}
+	}
+
+	var settings []models.SiteSettings
+	if err := tx.Find(&settings).Error; err != nil {
+		return err
+	}
+	for _, setting := range settings {
+		if err := tx.Model(&models.SiteSettingsTranslation{}).Where("settings_id = ?", setting.ID).Where(unstamped).
+			Update("source_hash", setting.SourceHash()).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Migrate applies all pending migrations in order
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.SchemaMigration{}); err != nil {
+		return fmt.Errorf("failed to create migrations table: %v", err)
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range sortedMigrations() {
+		if _, ok := applied[migration.ID]; ok {
+			continue
+		}
+
+		slog.Info("Applying migration", "id", migration.ID, "description", migration.Description)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&models.SchemaMigration{
+				ID:          migration.ID,
+				Description: migration.Description,
+				AppliedAt:   time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %v", migration.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Rollback reverts the most recently applied migrations, up to steps
+func Rollback(db *gorm.DB, steps int) ([]string, error) {
+	if err := db.AutoMigrate(&models.SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %v", err)
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := sortedMigrations()
+	var reverted []string
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.ID]; !ok {
+			continue
+		}
+		if migration.Down == nil {
+			return reverted, fmt.Errorf("migration %s cannot be reverted", migration.ID)
+		}
+
+		slog.Info("Reverting migration", "id", migration.ID)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&models.SchemaMigration{}, "id = ?", migration.ID).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("reverting %s failed: %v", migration.ID, err)
+		}
+		reverted = append(reverted, migration.ID)
+	}
+
+	return reverted, nil
+}
+
+// GetMigrationStatus lists every known migration with its applied state.
+// Applied migrations unknown to this build (from a newer release) are included.
+func GetMigrationStatus(db *gorm.DB) ([]MigrationStatus, error) {
+	applied := map[string]models.SchemaMigration{}
+	if db.Migrator().HasTable(&models.SchemaMigration{}) {
+		var err error
+		if applied, err = appliedMigrations(db); err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []MigrationStatus
+	known := make(map[string]bool)
+	for _, migration := range sortedMigrations() {
+		known[migration.ID] = true
+		status := MigrationStatus{ID: migration.ID, Description: migration.Description}
+		if record, ok := applied[migration.ID]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	for id, record := range applied {
+		if !known[id] {
+			appliedAt := record.AppliedAt
+			statuses = append(statuses, MigrationStatus{ID: id, Description: record.Description + " (unknown to this version)", Applied: true, AppliedAt: &appliedAt})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+
+	return statuses, nil
+}
+
+func appliedMigrations(db *gorm.DB) (map[string]models.SchemaMigration, error) {
+	var records []models.SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %v", err)
+	}
+	applied := make(map[string]models.SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.ID] = record
+	}
+	return applied, nil
+}
+
+func sortedMigrations() []Migration {
+	migrations := Migrations()
+	sort.SliceStable(migrations, func(i, j int) bool { return migrations[i].ID < migrations[j].ID })
+	return migrations
+}
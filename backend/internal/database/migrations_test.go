@@ -0,0 +1,123 @@
+package database
+
+import (
+	"blog-backend/internal/models"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrationIDsAreUniqueAndOrdered(t *testing.T) {
+	seen := make(map[string]bool)
+	previous := ""
+	for _, migration := range Migrations() {
+		if seen[migration.ID] {
+			t.Errorf("duplicate migration ID %s", migration.ID)
+		}
+		seen[migration.ID] = true
+		if migration.ID <= previous {
+			t.Errorf("migration %s is listed after %s", migration.ID, previous)
+		}
+		previous = migration.ID
+		if migration.Up == nil {
+			t.Errorf("migration %s has no Up step", migration.ID)
+		}
+	}
+}
+
+func TestMigrateAndRollback(t *testing.T) {
+	db := openTestDB(t)
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	// Running again must be a no-op
+	if err := Migrate(db); err != nil {
+		t.Fatalf("second Migrate failed: %v", err)
+	}
+	if !db.Migrator().HasTable(&models.Article{}) {
+		t.Fatal("articles table was not created")
+	}
+
+	statuses, err := GetMigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("migration %s not applied", status.ID)
+		}
+	}
+
+	reverted, err := Rollback(db, len(Migrations()))
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if len(reverted) != len(Migrations()) {
+		t.Errorf("reverted %v, expected all migrations", reverted)
+	}
+	if db.Migrator().HasTable(&models.Article{}) {
+		t.Error("articles table still exists after rollback")
+	}
+}
+
+func TestMigrationStatusReportsUnknownMigrations(t *testing.T) {
+	db := openTestDB(t)
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&models.SchemaMigration{ID: "9999_future", Description: "From a newer release"})
+
+	statuses, err := GetMigrationStatus(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := statuses[len(statuses)-1]
+	if last.ID != "9999_future" || !strings.Contains(last.Description, "unknown") {
+		t.Errorf("unexpected status for unknown migration: %+v", last)
+	}
+}
+
+func TestCategoryNamesAreUniquePerSite(t *testing.T) {
+	db := openTestDB(t)
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	// Before the migration a name could only be used once in all sites
+	steps := 0
+	for _, migration := range Migrations() {
+		if migration.ID >= "0057_scope_category_names_to_sites" {
+			steps++
+		}
+	}
+	if _, err := Rollback(db, steps); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&models.Category{SiteID: 1, Name: "Go"})
+	if err := db.Create(&models.Category{SiteID: 2, Name: "Go"}).Error; err == nil {
+		t.Fatal("names were already unique per site before the migration")
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Category{SiteID: 2, Name: "Go"}).Error; err != nil {
+		t.Errorf("another site cannot use the name: %v", err)
+	}
+	if err := db.Create(&models.Category{SiteID: 1, Name: "Go"}).Error; err == nil {
+		t.Error("a site can use the same name twice")
+	}
+}
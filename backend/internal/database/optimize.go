@@ -0,0 +1,113 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotSupported is returned for maintenance operations the active
+// driver does not offer
+var ErrNotSupported = errors.New("not supported by this database driver")
+
+// IntegrityCheck verifies the database structure and returns the problems
+// found, or none when it is intact. Postgres has no equivalent check.
+func IntegrityCheck(db *gorm.DB) ([]string, error) {
+	switch Dialect() {
+	case DriverPostgres:
+		return nil, ErrNotSupported
+	case DriverMySQL:
+		var problems []string
+		for _, table := range tableNames(db) {
+			rows, err := db.Raw("CHECK TABLE " + quoteMySQL(table)).Rows()
+			if err != nil {
+				return nil, err
+			}
+			for rows.Next() {
+				var name, op, msgType, msgText string
+				if err := rows.Scan(&name, &op, &msgType, &msgText); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				if !strings.EqualFold(msgText, "OK") && !strings.EqualFold(msgType, "info") {
+					problems = append(problems, fmt.Sprintf("%s: %s", name, msgText))
+				}
+			}
+			rows.Close()
+		}
+		return problems, nil
+	}
+
+	rows, err := db.Raw("PRAGMA integrity_check").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			return nil, err
+		}
+		if message != "ok" {
+			problems = append(problems, message)
+		}
+	}
+	return problems, rows.Err()
+}
+
+// Analyze refreshes the statistics the query planner uses to pick indexes
+func Analyze(db *gorm.DB) error {
+	switch Dialect() {
+	case DriverPostgres:
+		return db.Exec("ANALYZE").Error
+	case DriverMySQL:
+		return db.Exec("ANALYZE TABLE " + joinMySQL(tableNames(db))).Error
+	}
+	if err := db.Exec("ANALYZE").Error; err != nil {
+		return err
+	}
+	return db.Exec("PRAGMA optimize").Error
+}
+
+// Vacuum rebuilds the database to return the space of deleted rows. On
+// SQLite this needs free disk space about the size of the database and
+// blocks writes until it finishes.
+func Vacuum(db *gorm.DB) error {
+	switch Dialect() {
+	case DriverPostgres:
+		return db.Exec("VACUUM").Error
+	case DriverMySQL:
+		return db.Exec("OPTIMIZE TABLE " + joinMySQL(tableNames(db))).Error
+	}
+	if err := db.Exec("VACUUM").Error; err != nil {
+		return err
+	}
+	// Fold the rewritten pages back into the main file and shrink the WAL
+	return db.Exec("PRAGMA wal_checkpoint(TRUNCATE)").Error
+}
+
+func tableNames(db *gorm.DB) []string {
+	var tables []string
+	for _, model := range Models() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err == nil {
+			tables = append(tables, stmt.Schema.Table)
+		}
+	}
+	return tables
+}
+
+func quoteMySQL(table string) string {
+	return "`" + strings.ReplaceAll(table, "`", "``") + "`"
+}
+
+func joinMySQL(tables []string) string {
+	quoted := make([]string, len(tables))
+	for i, table := range tables {
+		quoted[i] = quoteMySQL(table)
+	}
+	return strings.Join(quoted, ", ")
+}
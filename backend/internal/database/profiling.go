@@ -0,0 +1,89 @@
+package database
+
+import (
+	"blog-backend/internal/profiling"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils"
+)
+
+const queryStartKey = "kuno:query_start"
+
+// QueryProfiler is a gorm plugin that times every statement. Statements run
+// with a context carrying a profiling.Profile are added to it, and those
+// taking at least SlowQuery are kept in the default recorder together with
+// the file and line that issued them, which also catches services that do
+// not pass a request context down.
+type QueryProfiler struct {
+	SlowQuery time.Duration
+}
+
+// Name implements gorm.Plugin
+func (QueryProfiler) Name() string {
+	return "kuno:query_profiler"
+}
+
+// Initialize implements gorm.Plugin
+func (p QueryProfiler) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	processors := []struct {
+		before, after interface {
+			Register(string, func(*gorm.DB)) error
+		}
+	}{
+		{callbacks.Create().Before("gorm:create"), callbacks.Create().After("gorm:create")},
+		{callbacks.Query().Before("gorm:query"), callbacks.Query().After("gorm:query")},
+		{callbacks.Update().Before("gorm:update"), callbacks.Update().After("gorm:update")},
+		{callbacks.Delete().Before("gorm:delete"), callbacks.Delete().After("gorm:delete")},
+		{callbacks.Row().Before("gorm:row"), callbacks.Row().After("gorm:row")},
+		{callbacks.Raw().Before("gorm:raw"), callbacks.Raw().After("gorm:raw")},
+	}
+	for _, processor := range processors {
+		if err := processor.before.Register("kuno:profile_start", startQuery); err != nil {
+			return err
+		}
+		if err := processor.after.Register("kuno:profile_end", p.endQuery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (p QueryProfiler) endQuery(db *gorm.DB) {
+	value, ok := db.InstanceGet(queryStartKey)
+	if !ok {
+		return
+	}
+	start, ok := value.(time.Time)
+	if !ok {
+		return
+	}
+	duration := time.Since(start)
+
+	profile := profiling.FromContext(db.Statement.Context)
+	slow := p.SlowQuery > 0 && duration >= p.SlowQuery
+	if profile == nil && !slow {
+		return
+	}
+	query := profiling.Query{
+		SQL:      db.Statement.SQL.String(),
+		Duration: duration,
+		Rows:     db.RowsAffected,
+		Source:   utils.FileWithLineNum(),
+		At:       start,
+	}
+	if profile != nil {
+		profile.Add(query)
+	}
+	if slow {
+		if profile != nil {
+			query.Request = profile.RequestID
+		}
+		profiling.Default().AddQuery(query)
+	}
+}
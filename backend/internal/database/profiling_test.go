@@ -0,0 +1,64 @@
+package database
+
+import (
+	"blog-backend/internal/models"
+	"blog-backend/internal/profiling"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryProfilerGroupsStatementsOfARequest(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.Use(QueryProfiler{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.Category{}); err != nil {
+		t.Fatal(err)
+	}
+
+	profile := profiling.NewProfile("req-1")
+	scoped := db.WithContext(profiling.WithProfile(context.Background(), profile))
+	for i := 0; i < 3; i++ {
+		var category models.Category
+		scoped.Limit(1).Find(&category, i+1)
+	}
+	var count int64
+	scoped.Model(&models.Category{}).Count(&count)
+	db.Find(&[]models.Category{}) // no profile in context
+
+	queries, total, statements := profile.Summary()
+	if queries != 4 || total <= 0 {
+		t.Fatalf("got %d queries in %v, want 4", queries, total)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements, want 2: %+v", len(statements), statements)
+	}
+	for _, statement := range statements {
+		if strings.Contains(statement.SQL, "count(") {
+			continue
+		}
+		if statement.Count != 3 || !strings.Contains(statement.SQL, "?") {
+			t.Errorf("lookup statement = %+v, want 3 executions with placeholders", statement)
+		}
+		if !strings.Contains(statement.Source, "profiling_test.go") {
+			t.Errorf("source = %q, want the test file", statement.Source)
+		}
+	}
+}
+
+func TestQueryProfilerRecordsSlowQueries(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.Use(QueryProfiler{SlowQuery: time.Nanosecond}); err != nil {
+		t.Fatal(err)
+	}
+	profiling.Default().Reset()
+	defer profiling.Default().Reset()
+
+	db.Exec("SELECT 1")
+	queries := profiling.Default().Queries()
+	if len(queries) == 0 || queries[0].SQL != "SELECT 1" {
+		t.Fatalf("slow queries = %+v, want SELECT 1", queries)
+	}
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type siteKey struct{}
+
+// WithSite returns a context that scopes queries run with it to siteID
+func WithSite(ctx context.Context, siteID uint) context.Context {
+	return context.WithValue(ctx, siteKey{}, siteID)
+}
+
+// SiteFromContext returns the site queries in ctx are scoped to, if any
+func SiteFromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	siteID, ok := ctx.Value(siteKey{}).(uint)
+	return siteID, ok && siteID != 0
+}
+
+// SiteScope is a gorm plugin that restricts statements on models with a
+// SiteID field to the site in the statement context, and stamps that site on
+// rows being created. Statements without a site in their context, raw SQL and
+// queries on plain table names are left alone.
+type SiteScope struct{}
+
+// Name implements gorm.Plugin
+func (SiteScope) Name() string {
+	return "kuno:site_scope"
+}
+
+// Initialize implements gorm.Plugin
+func (SiteScope) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("kuno:site_scope", scopeToSite); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("kuno:site_scope", scopeToSite); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("kuno:site_scope", scopeToSite); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("kuno:site_scope", scopeToSite); err != nil {
+		return err
+	}
+	return callbacks.Create().Before("gorm:create").Register("kuno:site_scope", stampSite)
+}
+
+// siteField returns the statement's site and the model's SiteID field, or a
+// nil field when the statement is not site scoped
+func siteField(db *gorm.DB) (uint, *schema.Field) {
+	siteID, ok := SiteFromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return 0, nil
+	}
+	return siteID, db.Statement.Schema.LookUpField("SiteID")
+}
+
+func scopeToSite(db *gorm.DB) {
+	siteID, field := siteField(db)
+	if field == nil {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: siteID},
+	}})
+}
+
+func stampSite(db *gorm.DB) {
+	siteID, field := siteField(db)
+	if field == nil {
+		return
+	}
+	stamp := func(row reflect.Value) {
+		row = reflect.Indirect(row)
+		if row.Kind() != reflect.Struct {
+			return
+		}
+		if _, zero := field.ValueOf(db.Statement.Context, row); zero {
+			if err := field.Set(db.Statement.Context, row, siteID); err != nil {
+				db.AddError(err)
+			}
+		}
+	}
+
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			stamp(value.Index(i))
+		}
+	default:
+		stamp(value)
+	}
+}
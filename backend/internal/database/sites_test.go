@@ -0,0 +1,52 @@
+package database
+
+import (
+	"blog-backend/internal/models"
+	"context"
+	"testing"
+)
+
+func TestSiteScopeIsolatesSites(t *testing.T) {
+	db := openTestDB(t)
+	if err := db.Use(SiteScope{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&models.Site{ID: 2, Name: "Second", Slug: "second"})
+
+	siteOne := db.WithContext(WithSite(context.Background(), 1))
+	siteTwo := db.WithContext(WithSite(context.Background(), 2))
+
+	first := models.Category{Name: "First"}
+	if err := siteOne.Create(&first).Error; err != nil {
+		t.Fatal(err)
+	}
+	second := []models.Category{{Name: "Second A"}, {Name: "Second B"}}
+	if err := siteTwo.Create(&second).Error; err != nil {
+		t.Fatal(err)
+	}
+	if first.SiteID != 1 || second[0].SiteID != 2 || second[1].SiteID != 2 {
+		t.Fatalf("site not stamped on create: %d %d %d", first.SiteID, second[0].SiteID, second[1].SiteID)
+	}
+
+	var count int64
+	siteTwo.Model(&models.Category{}).Count(&count)
+	if count != 2 {
+		t.Errorf("site 2 sees %d categories, want 2", count)
+	}
+	var found models.Category
+	if err := siteTwo.First(&found, first.ID).Error; err == nil {
+		t.Error("site 2 loaded a category of site 1")
+	}
+	if result := siteTwo.Delete(&models.Category{}, first.ID); result.RowsAffected != 0 {
+		t.Error("site 2 deleted a category of site 1")
+	}
+
+	// Without a site in the context nothing is filtered
+	db.Model(&models.Category{}).Count(&count)
+	if count != 3 {
+		t.Errorf("unscoped count = %d, want 3", count)
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrSnapshotUnsupported is returned for drivers without an online backup API
+var ErrSnapshotUnsupported = errors.New("online snapshots are only supported for SQLite; use pg_dump or mysqldump for other databases")
+
+// SnapshotSQLite writes a consistent copy of the live database to destPath
+// using the SQLite online backup API, without blocking writers for long
+func SnapshotSQLite(destPath string) error {
+	if Dialect() != DriverSQLite {
+		return ErrSnapshotUnsupported
+	}
+
+	dest, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	return copySQLite(dest, liveSQLDB())
+}
+
+// RestoreSQLite replaces the contents of the live database with the SQLite
+// file at srcPath. Existing connections stay valid and see the new data.
+func RestoreSQLite(srcPath string) error {
+	if Dialect() != DriverSQLite {
+		return ErrSnapshotUnsupported
+	}
+
+	src, err := sql.Open("sqlite3", "file:"+srcPath+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return copySQLite(liveSQLDB(), src)
+}
+
+func liveSQLDB() *sql.DB {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return nil
+	}
+	return sqlDB
+}
+
+// copySQLite copies the main database of src into dest page by page
+func copySQLite(dest, src *sql.DB) error {
+	if dest == nil || src == nil {
+		return errors.New("database is not initialized")
+	}
+
+	ctx := context.Background()
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriver interface{}) error {
+		destSQLite, ok := destDriver.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected destination driver %T", destDriver)
+		}
+		return srcConn.Raw(func(srcDriver interface{}) error {
+			srcSQLite, ok := srcDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected source driver %T", srcDriver)
+			}
+
+			backup, err := destSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Close()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
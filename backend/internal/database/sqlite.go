@@ -0,0 +1,148 @@
+package database
+
+import (
+	"blog-backend/internal/config"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default pool size for SQLite when DB_MAX_OPEN_CONNS is not set. WAL mode
+// lets readers proceed alongside the single writer, so a small pool of
+// connections serves concurrent reads without piling up lock waits.
+const defaultSQLiteMaxConns = 8
+
+// sqliteDSN appends the configured connection settings to the database path
+// as go-sqlite3 parameters, so every pooled connection gets them rather than
+// only the one a PRAGMA happens to run on. Parameters already present in
+// the path win.
+func sqliteDSN(cfg config.DatabaseConfig) string {
+	dsn := cfg.Path
+	existing := url.Values{}
+	if i := strings.IndexRune(dsn, '?'); i >= 0 {
+		existing, _ = url.ParseQuery(dsn[i+1:])
+	}
+
+	params := url.Values{}
+	set := func(key, value string) {
+		if value != "" && existing.Get(key) == "" {
+			params.Set(key, value)
+		}
+	}
+	set("_journal_mode", strings.ToUpper(cfg.JournalMode))
+	set("_synchronous", strings.ToUpper(cfg.Synchronous))
+	if cfg.BusyTimeout > 0 {
+		set("_busy_timeout", strconv.FormatInt(cfg.BusyTimeout.Std().Milliseconds(), 10))
+	}
+	if cfg.ForeignKeys {
+		set("_foreign_keys", "1")
+	}
+	// Take the write lock when a transaction starts instead of upgrading a
+	// read lock later, which fails immediately when another writer is active
+	set("_txlock", "immediate")
+
+	separator := "?"
+	if strings.ContainsRune(dsn, '?') {
+		separator = "&"
+	}
+	return dsn + separator + params.Encode()
+}
+
+// DatabaseStats describes the database file and connection pool
+type DatabaseStats struct {
+	Driver string `json:"driver"`
+
+	// SQLite only
+	Path          string `json:"path,omitempty"`
+	PageSize      int64  `json:"page_size,omitempty"`
+	PageCount     int64  `json:"page_count,omitempty"`
+	FreelistCount int64  `json:"freelist_count,omitempty"`
+	DatabaseSize  int64  `json:"database_size,omitempty"`
+	WALSize       int64  `json:"wal_size,omitempty"`
+	JournalMode   string `json:"journal_mode,omitempty"`
+	Synchronous   string `json:"synchronous,omitempty"`
+	BusyTimeoutMS int64  `json:"busy_timeout_ms,omitempty"`
+	ForeignKeys   bool   `json:"foreign_keys"`
+
+	Pool PoolStats `json:"pool"`
+}
+
+// PoolStats is a JSON-friendly view of sql.DBStats
+type PoolStats struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+}
+
+// GetStats returns file and pool statistics for the live database
+func GetStats() (*DatabaseStats, error) {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := sqlDB.Stats()
+	stats := &DatabaseStats{
+		Driver: Dialect(),
+		Pool: PoolStats{
+			MaxOpenConnections: pool.MaxOpenConnections,
+			OpenConnections:    pool.OpenConnections,
+			InUse:              pool.InUse,
+			Idle:               pool.Idle,
+			WaitCount:          pool.WaitCount,
+			WaitDuration:       pool.WaitDuration.Round(time.Millisecond).String(),
+			MaxIdleClosed:      pool.MaxIdleClosed,
+			MaxLifetimeClosed:  pool.MaxLifetimeClosed,
+		},
+	}
+	if stats.Driver != DriverSQLite {
+		return stats, nil
+	}
+
+	var synchronous, foreignKeys int64
+	pragmas := []struct {
+		name  string
+		value interface{}
+	}{
+		{"page_size", &stats.PageSize},
+		{"page_count", &stats.PageCount},
+		{"freelist_count", &stats.FreelistCount},
+		{"journal_mode", &stats.JournalMode},
+		{"synchronous", &synchronous},
+		{"busy_timeout", &stats.BusyTimeoutMS},
+		{"foreign_keys", &foreignKeys},
+	}
+	for _, pragma := range pragmas {
+		if err := DB.Raw("PRAGMA " + pragma.name).Row().Scan(pragma.value); err != nil {
+			return nil, err
+		}
+	}
+	stats.ForeignKeys = foreignKeys == 1
+	if synchronous >= 0 && synchronous < 4 {
+		stats.Synchronous = []string{"OFF", "NORMAL", "FULL", "EXTRA"}[synchronous]
+	}
+	stats.DatabaseSize = stats.PageSize * stats.PageCount
+
+	stats.Path = sqlitePath()
+	if info, err := os.Stat(stats.Path + "-wal"); err == nil {
+		stats.WALSize = info.Size()
+	}
+	return stats, nil
+}
+
+// sqlitePath returns the database file path, which is everything before the
+// connection parameters
+func sqlitePath() string {
+	path := strings.TrimPrefix(config.Get().Database.Path, "file:")
+	if i := strings.IndexRune(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"blog-backend/internal/config"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSQLiteDSN(t *testing.T) {
+	cfg := config.DatabaseConfig{
+		Path:        "./data/blog.db",
+		JournalMode: "wal",
+		Synchronous: "normal",
+		BusyTimeout: config.Duration(5 * time.Second),
+		ForeignKeys: true,
+	}
+
+	dsn := sqliteDSN(cfg)
+	for _, want := range []string{"_journal_mode=WAL", "_synchronous=NORMAL", "_busy_timeout=5000", "_foreign_keys=1", "_txlock=immediate"} {
+		if !strings.Contains(dsn, want) {
+			t.Errorf("DSN %q is missing %s", dsn, want)
+		}
+	}
+	if !strings.HasPrefix(dsn, "./data/blog.db?") {
+		t.Errorf("DSN %q should start with the path", dsn)
+	}
+
+	// Parameters already in the path are kept
+	cfg.Path = "file:blog.db?_busy_timeout=100"
+	dsn = sqliteDSN(cfg)
+	if strings.Count(dsn, "_busy_timeout") != 1 || !strings.Contains(dsn, "_busy_timeout=100&") {
+		t.Errorf("DSN %q should keep the existing busy timeout", dsn)
+	}
+}
+
+func TestSQLiteSettingsApplyToEveryConnection(t *testing.T) {
+	cfg := config.DatabaseConfig{
+		Path:        t.TempDir() + "/test.db",
+		JournalMode: "WAL",
+		BusyTimeout: config.Duration(2 * time.Second),
+		ForeignKeys: true,
+	}
+	dialector, err := OpenDialector(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := openWith(t, dialector)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Check several pooled connections, not just the first one
+	for i := 0; i < 3; i++ {
+		conn, err := sqlDB.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		var mode string
+		var timeout, fk int
+		conn.QueryRowContext(context.Background(), "PRAGMA journal_mode").Scan(&mode)
+		conn.QueryRowContext(context.Background(), "PRAGMA busy_timeout").Scan(&timeout)
+		conn.QueryRowContext(context.Background(), "PRAGMA foreign_keys").Scan(&fk)
+		if strings.ToUpper(mode) != "WAL" || timeout != 2000 || fk != 1 {
+			t.Errorf("connection %d: journal_mode=%s busy_timeout=%d foreign_keys=%d", i, mode, timeout, fk)
+		}
+	}
+}
+
+func openWith(t *testing.T, dialector gorm.Dialector) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
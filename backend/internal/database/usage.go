@@ -0,0 +1,67 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// TableRows is the number of rows stored in one table
+type TableRows struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+// CountRows returns the row count of every table in Models, largest first.
+// Soft deleted rows are counted since they still take up space.
+func CountRows(db *gorm.DB) ([]TableRows, error) {
+	var counts []TableRows
+	for _, table := range tableNames(db) {
+		var rows int64
+		if err := db.Table(table).Count(&rows).Error; err != nil {
+			return nil, err
+		}
+		counts = append(counts, TableRows{Table: table, Rows: rows})
+	}
+	sort.SliceStable(counts, func(i, j int) bool { return counts[i].Rows > counts[j].Rows })
+	return counts, nil
+}
+
+// Size returns the space the database takes in bytes. For SQLite this is
+// the main file plus its write-ahead log; the server drivers report the
+// size of the current database.
+func Size(db *gorm.DB) (int64, error) {
+	var size int64
+	switch Dialect() {
+	case DriverPostgres:
+		err := db.Raw("SELECT pg_database_size(current_database())").Row().Scan(&size)
+		return size, err
+	case DriverMySQL:
+		err := db.Raw("SELECT COALESCE(SUM(data_length + index_length), 0) FROM information_schema.tables WHERE table_schema = DATABASE()").Row().Scan(&size)
+		return size, err
+	}
+
+	var pageSize, pageCount int64
+	if err := db.Raw("PRAGMA page_size").Row().Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	if err := db.Raw("PRAGMA page_count").Row().Scan(&pageCount); err != nil {
+		return 0, err
+	}
+	size = pageSize * pageCount
+	if info, err := os.Stat(sqlitePath() + "-wal"); err == nil {
+		size += info.Size()
+	}
+	return size, nil
+}
+
+// DataDir returns the directory holding the SQLite database, or "" for the
+// server drivers whose data lives elsewhere
+func DataDir() string {
+	if Dialect() != DriverSQLite {
+		return ""
+	}
+	return filepath.Dir(sqlitePath())
+}
@@ -0,0 +1,34 @@
+// Package events dispatches domain events such as an article being
+// published or the settings being saved. An event runs its action hooks on
+// the instance where it happened and is then broadcast on the cache's
+// invalidation channel, so caches on every instance can drop what it made
+// stale instead of checking their data on each read.
+package events
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/hooks"
+	"context"
+)
+
+// Topic is the cache topic an event is broadcast on
+func Topic(event hooks.Event) string {
+	return "event:" + string(event)
+}
+
+// Dispatch runs the hooks for an action event, then the subscribers to it
+// here and on other instances
+func Dispatch(ctx context.Context, event hooks.Event, payload interface{}) {
+	hooks.Notify(ctx, event, payload)
+	cache.Publish(Topic(event))
+}
+
+// Subscribe registers fn to run whenever one of events is dispatched. It
+// is meant for invalidating caches: fn learns which kind of change
+// happened, not what changed, and also runs for events dispatched on
+// other instances.
+func Subscribe(fn func(), events ...hooks.Event) {
+	for _, event := range events {
+		cache.Subscribe(Topic(event), fn)
+	}
+}
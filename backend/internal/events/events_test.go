@@ -0,0 +1,32 @@
+package events
+
+import (
+	"blog-backend/internal/hooks"
+	"context"
+	"testing"
+)
+
+func TestDispatchRunsHooksThenSubscribers(t *testing.T) {
+	event := hooks.Event("test.changed")
+	defer hooks.Unregister(event, "test")
+
+	var calls []string
+	hooks.Register(event, "test", func(ctx context.Context, e hooks.Event, payload interface{}) error {
+		calls = append(calls, "hook:"+payload.(string))
+		return nil
+	})
+	Subscribe(func() { calls = append(calls, "first") }, event, hooks.Event("test.other"))
+	Subscribe(func() { calls = append(calls, "second") }, event)
+
+	Dispatch(context.Background(), event, "payload")
+	if len(calls) != 3 || calls[0] != "hook:payload" || calls[1] != "first" || calls[2] != "second" {
+		t.Fatalf("calls = %v", calls)
+	}
+
+	// Subscribers only hear the events they asked for
+	calls = nil
+	Dispatch(context.Background(), hooks.Event("test.other"), nil)
+	if len(calls) != 1 || calls[0] != "first" {
+		t.Errorf("calls for another event = %v", calls)
+	}
+}
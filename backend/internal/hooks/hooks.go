@@ -0,0 +1,181 @@
+// Package hooks is the extension point for code that reacts to content
+// changes. Filter events run before the change and may modify the payload
+// or reject it; action events run after it and can only observe. Webhook
+// plugins (see services/plugins.go) are registered here like any other hook.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// Event names a point where hooks run
+type Event string
+
+// Filter events. The payload is a pointer the hook may modify.
+const (
+	// BeforeArticleSave receives the *models.Article about to be created or updated
+	BeforeArticleSave Event = "article.before_save"
+	// ArticleRender receives the *models.Article about to be returned to a reader
+	ArticleRender Event = "article.render"
+)
+
+// Action events
+const (
+	// AfterArticleSave receives the saved *models.Article
+	AfterArticleSave Event = "article.after_save"
+	// ArticlePublished receives a *models.Article when it first becomes visible
+	ArticlePublished Event = "article.published"
+	// ArticleUpdated receives a *models.Article that was already visible and
+	// has been saved again
+	ArticleUpdated Event = "article.updated"
+	// MediaUploaded receives the new *models.MediaLibrary record
+	MediaUploaded Event = "media.uploaded"
+	// MediaUpdated receives a *models.MediaLibrary record whose details
+	// were changed, or that was deleted
+	MediaUpdated Event = "media.updated"
+	// SettingsChanged receives the saved *models.SiteSettings, without
+	// their AI configuration
+	SettingsChanged Event = "settings.changed"
+	// CommentPending receives a new *models.FederatedReply or
+	// *models.GuestbookEntry waiting for moderation
+	CommentPending Event = "comment.pending"
+)
+
+// Events lists every event with whether it is a filter event
+var Events = map[Event]bool{
+	BeforeArticleSave: true,
+	ArticleRender:     true,
+	AfterArticleSave:  false,
+	ArticlePublished:  false,
+	ArticleUpdated:    false,
+	MediaUploaded:     false,
+	MediaUpdated:      false,
+	SettingsChanged:   false,
+	CommentPending:    false,
+}
+
+// IsFilter reports whether hooks for event run before the change
+func IsFilter(event Event) bool {
+	return Events[event]
+}
+
+// Hook handles one event. Returning an error from a filter hook stops the
+// remaining hooks and, for BeforeArticleSave, rejects the save.
+type Hook func(ctx context.Context, event Event, payload interface{}) error
+
+// RejectError is returned by a filter hook to refuse a change with a reason
+// that is shown to the user
+type RejectError struct {
+	Hook   string
+	Reason string
+}
+
+func (e *RejectError) Error() string {
+	return fmt.Sprintf("rejected by %s: %s", e.Hook, e.Reason)
+}
+
+type registration struct {
+	name string
+	hook Hook
+}
+
+var (
+	mu    sync.RWMutex
+	hooks = make(map[Event][]registration)
+)
+
+// Register adds a hook for event under name, replacing an earlier hook with
+// the same name. Hooks run in registration order.
+func Register(event Event, name string, hook Hook) {
+	mu.Lock()
+	defer mu.Unlock()
+	list := hooks[event]
+	for i := range list {
+		if list[i].name == name {
+			list[i].hook = hook
+			return
+		}
+	}
+	hooks[event] = append(list, registration{name: name, hook: hook})
+}
+
+// Unregister removes the named hook from event
+func Unregister(event Event, name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	list := hooks[event]
+	for i := range list {
+		if list[i].name == name {
+			hooks[event] = append(list[:i:i], list[i+1:]...)
+			return
+		}
+	}
+}
+
+// Registered returns the hook names for each event that has any
+func Registered() map[Event][]string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make(map[Event][]string, len(hooks))
+	for event, list := range hooks {
+		for _, r := range list {
+			names[event] = append(names[event], r.name)
+		}
+	}
+	return names
+}
+
+// EventNames returns every event name, sorted
+func EventNames() []string {
+	names := make([]string, 0, len(Events))
+	for event := range Events {
+		names = append(names, string(event))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func snapshot(event Event) []registration {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]registration(nil), hooks[event]...)
+}
+
+// Filter runs the hooks for a filter event in order and returns the first
+// error. A panicking hook is treated as failing.
+func Filter(ctx context.Context, event Event, payload interface{}) error {
+	for _, r := range snapshot(event) {
+		if err := call(ctx, r, event, payload); err != nil {
+			var reject *RejectError
+			if !errors.As(err, &reject) {
+				err = fmt.Errorf("hook %s: %w", r.name, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Notify runs every hook for an action event. Failures are logged and do
+// not stop the other hooks.
+func Notify(ctx context.Context, event Event, payload interface{}) {
+	for _, r := range snapshot(event) {
+		if err := call(ctx, r, event, payload); err != nil {
+			slog.Error("Hook failed", "event", event, "hook", r.name, "error", err)
+		}
+	}
+}
+
+func call(ctx context.Context, r registration, event Event, payload interface{}) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return r.hook(ctx, event, payload)
+}
@@ -0,0 +1,73 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFilterRunsInOrderAndStopsOnError(t *testing.T) {
+	event := Event("test.filter")
+	defer Unregister(event, "first")
+	defer Unregister(event, "second")
+	defer Unregister(event, "third")
+
+	var calls []string
+	Register(event, "first", func(ctx context.Context, e Event, payload interface{}) error {
+		*payload.(*string) += "-first"
+		calls = append(calls, "first")
+		return nil
+	})
+	Register(event, "second", func(ctx context.Context, e Event, payload interface{}) error {
+		calls = append(calls, "second")
+		return &RejectError{Hook: "second", Reason: "not allowed"}
+	})
+	Register(event, "third", func(ctx context.Context, e Event, payload interface{}) error {
+		calls = append(calls, "third")
+		return nil
+	})
+
+	title := "post"
+	err := Filter(context.Background(), event, &title)
+	var reject *RejectError
+	if !errors.As(err, &reject) || reject.Reason != "not allowed" {
+		t.Fatalf("Filter error = %v", err)
+	}
+	if title != "post-first" || len(calls) != 2 {
+		t.Errorf("title = %q, calls = %v", title, calls)
+	}
+
+	// Re-registering a name replaces the hook in place
+	Register(event, "second", func(ctx context.Context, e Event, payload interface{}) error {
+		return nil
+	})
+	if err := Filter(context.Background(), event, &title); err != nil {
+		t.Errorf("Filter after replacing hook = %v", err)
+	}
+	if names := Registered()[event]; len(names) != 3 || names[1] != "second" {
+		t.Errorf("Registered = %v", names)
+	}
+}
+
+func TestNotifyContinuesAfterFailure(t *testing.T) {
+	event := Event("test.action")
+	defer Unregister(event, "panics")
+	defer Unregister(event, "counts")
+
+	count := 0
+	Register(event, "panics", func(ctx context.Context, e Event, payload interface{}) error {
+		panic("boom")
+	})
+	Register(event, "counts", func(ctx context.Context, e Event, payload interface{}) error {
+		count++
+		return nil
+	})
+
+	Notify(context.Background(), event, nil)
+	if count != 1 {
+		t.Errorf("hook after a panicking hook ran %d times", count)
+	}
+	if err := Filter(context.Background(), event, nil); err == nil {
+		t.Error("Filter should report a panicking hook")
+	}
+}
@@ -0,0 +1,88 @@
+// Package imaging scales images and writes icon files with the standard
+// library alone
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Fit returns the largest rectangle with the proportions of src centered in
+// box
+func Fit(src, box image.Rectangle) image.Rectangle {
+	width, height := box.Dx(), box.Dy()
+	if src.Dx()*height > src.Dy()*width {
+		height = max(src.Dy()*width/src.Dx(), 1)
+	} else {
+		width = max(src.Dx()*height/src.Dy(), 1)
+	}
+	left, top := box.Min.X+(box.Dx()-width)/2, box.Min.Y+(box.Dy()-height)/2
+	return image.Rect(left, top, left+width, top+height)
+}
+
+// Resize scales an image to width by height, averaging the source pixels
+// each target pixel covers
+func Resize(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		top, bottom := bounds.Min.Y+y*bounds.Dy()/height, bounds.Min.Y+(y+1)*bounds.Dy()/height
+		bottom = max(bottom, top+1)
+		for x := 0; x < width; x++ {
+			left, right := bounds.Min.X+x*bounds.Dx()/width, bounds.Min.X+(x+1)*bounds.Dx()/width
+			right = max(right, left+1)
+			var r, g, b, a, n uint64
+			for sy := top; sy < bottom; sy++ {
+				for sx := left; sx < right; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			// Averaged premultiplied colors, converted back for NRGBA
+			c := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)}
+			dst.Set(x, y, c)
+		}
+	}
+	return dst
+}
+
+// ICO encodes square images of at most 256 pixels as a Windows icon file
+// holding one PNG per size, which every browser reads
+func ICO(images ...image.Image) ([]byte, error) {
+	if len(images) == 0 {
+		return nil, errors.New("an icon needs at least one image")
+	}
+	encoded := make([][]byte, len(images))
+	for i, img := range images {
+		if img.Bounds().Dx() > 256 || img.Bounds().Dy() > 256 {
+			return nil, errors.New("icon images are at most 256 pixels")
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		encoded[i] = buf.Bytes()
+	}
+
+	var out bytes.Buffer
+	// ICONDIR: reserved, type 1 for icons, image count
+	binary.Write(&out, binary.LittleEndian, [3]uint16{0, 1, uint16(len(images))})
+	offset := 6 + 16*len(images)
+	for i, img := range images {
+		// ICONDIRENTRY: a width or height of 0 stands for 256
+		out.WriteByte(byte(img.Bounds().Dx()))
+		out.WriteByte(byte(img.Bounds().Dy()))
+		out.Write([]byte{0, 0})
+		binary.Write(&out, binary.LittleEndian, [2]uint16{1, 32})
+		binary.Write(&out, binary.LittleEndian, [2]uint32{uint32(len(encoded[i])), uint32(offset)})
+		offset += len(encoded[i])
+	}
+	for _, data := range encoded {
+		out.Write(data)
+	}
+	return out.Bytes(), nil
+}
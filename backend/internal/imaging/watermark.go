@@ -0,0 +1,218 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+// glyphs is a 5 by 7 pixel font of the capital letters, digits and the
+// punctuation found in domain names and short notices. Each row is five
+// bits, the leftmost pixel first.
+var glyphs = map[rune][glyphHeight]uint8{
+	'A':  {0b01110, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'B':  {0b11110, 0b10001, 0b10001, 0b11110, 0b10001, 0b10001, 0b11110},
+	'C':  {0b01110, 0b10001, 0b10000, 0b10000, 0b10000, 0b10001, 0b01110},
+	'D':  {0b11110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b11110},
+	'E':  {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b11111},
+	'F':  {0b11111, 0b10000, 0b10000, 0b11110, 0b10000, 0b10000, 0b10000},
+	'G':  {0b01110, 0b10001, 0b10000, 0b10111, 0b10001, 0b10001, 0b01111},
+	'H':  {0b10001, 0b10001, 0b10001, 0b11111, 0b10001, 0b10001, 0b10001},
+	'I':  {0b01110, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'J':  {0b00111, 0b00010, 0b00010, 0b00010, 0b00010, 0b10010, 0b01100},
+	'K':  {0b10001, 0b10010, 0b10100, 0b11000, 0b10100, 0b10010, 0b10001},
+	'L':  {0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b10000, 0b11111},
+	'M':  {0b10001, 0b11011, 0b10101, 0b10101, 0b10001, 0b10001, 0b10001},
+	'N':  {0b10001, 0b10001, 0b11001, 0b10101, 0b10011, 0b10001, 0b10001},
+	'O':  {0b01110, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'P':  {0b11110, 0b10001, 0b10001, 0b11110, 0b10000, 0b10000, 0b10000},
+	'Q':  {0b01110, 0b10001, 0b10001, 0b10001, 0b10101, 0b10010, 0b01101},
+	'R':  {0b11110, 0b10001, 0b10001, 0b11110, 0b10100, 0b10010, 0b10001},
+	'S':  {0b01111, 0b10000, 0b10000, 0b01110, 0b00001, 0b00001, 0b11110},
+	'T':  {0b11111, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0b00100},
+	'U':  {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01110},
+	'V':  {0b10001, 0b10001, 0b10001, 0b10001, 0b10001, 0b01010, 0b00100},
+	'W':  {0b10001, 0b10001, 0b10001, 0b10101, 0b10101, 0b10101, 0b01010},
+	'X':  {0b10001, 0b10001, 0b01010, 0b00100, 0b01010, 0b10001, 0b10001},
+	'Y':  {0b10001, 0b10001, 0b01010, 0b00100, 0b00100, 0b00100, 0b00100},
+	'Z':  {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b10000, 0b11111},
+	'0':  {0b01110, 0b10001, 0b10011, 0b10101, 0b11001, 0b10001, 0b01110},
+	'1':  {0b00100, 0b01100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110},
+	'2':  {0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0b01000, 0b11111},
+	'3':  {0b11111, 0b00010, 0b00100, 0b00010, 0b00001, 0b10001, 0b01110},
+	'4':  {0b00010, 0b00110, 0b01010, 0b10010, 0b11111, 0b00010, 0b00010},
+	'5':  {0b11111, 0b10000, 0b11110, 0b00001, 0b00001, 0b10001, 0b01110},
+	'6':  {0b00110, 0b01000, 0b10000, 0b11110, 0b10001, 0b10001, 0b01110},
+	'7':  {0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b01000, 0b01000},
+	'8':  {0b01110, 0b10001, 0b10001, 0b01110, 0b10001, 0b10001, 0b01110},
+	'9':  {0b01110, 0b10001, 0b10001, 0b01111, 0b00001, 0b00010, 0b01100},
+	'.':  {0, 0, 0, 0, 0, 0b01100, 0b01100},
+	',':  {0, 0, 0, 0, 0b01100, 0b00100, 0b01000},
+	'-':  {0, 0, 0, 0b11111, 0, 0, 0},
+	'_':  {0, 0, 0, 0, 0, 0, 0b11111},
+	':':  {0, 0b01100, 0b01100, 0, 0b01100, 0b01100, 0},
+	'/':  {0b00001, 0b00001, 0b00010, 0b00100, 0b01000, 0b10000, 0b10000},
+	'@':  {0b01110, 0b10001, 0b00001, 0b01101, 0b10101, 0b10101, 0b01110},
+	'&':  {0b01100, 0b10010, 0b10100, 0b01000, 0b10101, 0b10010, 0b01101},
+	'\'': {0b00100, 0b00100, 0b01000, 0, 0, 0, 0},
+	'!':  {0b00100, 0b00100, 0b00100, 0b00100, 0b00100, 0, 0b00100},
+	'?':  {0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0, 0b00100},
+	'(':  {0b00010, 0b00100, 0b01000, 0b01000, 0b01000, 0b00100, 0b00010},
+	')':  {0b01000, 0b00100, 0b00010, 0b00010, 0b00010, 0b00100, 0b01000},
+	'+':  {0, 0b00100, 0b00100, 0b11111, 0b00100, 0b00100, 0},
+	'#':  {0b01010, 0b01010, 0b11111, 0b01010, 0b11111, 0b01010, 0b01010},
+	' ':  {},
+}
+
+// symbols are written with the letters of the font
+var symbols = strings.NewReplacer("©", "(C)", "®", "(R)", "™", "TM")
+
+var (
+	watermarkBand = color.NRGBA{A: 110}
+	watermarkText = color.NRGBA{R: 255, G: 255, B: 255, A: 210}
+	textInk       = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	textOutline   = color.NRGBA{R: 0, G: 0, B: 0, A: 180}
+)
+
+// Positions are the places Stamp can put a mark. Tile repeats it across
+// the whole image.
+var Positions = []string{
+	"top-left", "top", "top-right",
+	"left", "center", "right",
+	"bottom-left", "bottom", "bottom-right",
+	"tile",
+}
+
+// Watermark returns a copy of src with text written across it in evenly
+// spaced bands, light letters on dark translucent strips. Letters are
+// drawn as capitals; characters the font lacks become question marks.
+func Watermark(src image.Image, text string) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+
+	text = symbols.Replace(strings.ToUpper(strings.TrimSpace(text)))
+	if text == "" || dst.Bounds().Empty() {
+		return dst
+	}
+	width, height := dst.Bounds().Dx(), dst.Bounds().Dy()
+	columns := textColumns(text)
+	// Letters take up to four fifths of the width and a tenth of the height
+	scale := max(1, min(width*4/5/columns, height/10/glyphHeight))
+	padding := 2 * scale
+	bandHeight := glyphHeight*scale + 2*padding
+	bands := max(1, height/(3*bandHeight))
+	textWidth := columns * scale
+
+	band := image.NewUniform(watermarkBand)
+	ink := image.NewUniform(watermarkText)
+	for i := 0; i < bands; i++ {
+		top := (2*i+1)*height/(2*bands) - bandHeight/2
+		draw.Draw(dst, image.Rect(0, top, width, top+bandHeight), band, image.Point{}, draw.Over)
+		drawText(dst, text, (width-textWidth)/2, top+padding, scale, ink)
+	}
+	return dst
+}
+
+// Text renders text in capitals, as wide as width allows, as white letters
+// with a dark outline on a transparent background, so it reads on light
+// and dark images alike
+func Text(text string, width int) *image.NRGBA {
+	text = symbols.Replace(strings.ToUpper(strings.TrimSpace(text)))
+	columns := textColumns(text)
+	if columns <= 0 {
+		return image.NewNRGBA(image.Rectangle{})
+	}
+	scale := max(1, width/(columns+2))
+	outline := max(1, scale/2)
+	dst := image.NewNRGBA(image.Rect(0, 0, columns*scale+2*outline, glyphHeight*scale+2*outline))
+	dark := image.NewUniform(textOutline)
+	for _, offset := range []image.Point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		drawText(dst, text, outline+offset.X*outline, outline+offset.Y*outline, scale, dark)
+	}
+	drawText(dst, text, outline, outline, scale, image.NewUniform(textInk))
+	return dst
+}
+
+// Stamp draws mark over dst at one of the Positions, with opacity from 0
+// to 1. Marks keep a margin of a fortieth of the image from its edges, and
+// tiled marks the width and height of a mark from each other.
+func Stamp(dst draw.Image, mark image.Image, position string, opacity float64) {
+	canvas, size := dst.Bounds(), mark.Bounds().Size()
+	if size.X == 0 || size.Y == 0 {
+		return
+	}
+	mask := image.NewUniform(color.Alpha{A: uint8(max(0, min(1, opacity)) * 255)})
+	stamp := func(at image.Point) {
+		draw.DrawMask(dst, image.Rectangle{at, at.Add(size)}, mark, mark.Bounds().Min, mask, image.Point{}, draw.Over)
+	}
+	if position == "tile" {
+		for y := canvas.Min.Y + size.Y/2; y < canvas.Max.Y; y += 2 * size.Y {
+			// Every other row is shifted by half a step, like bricks
+			shift := ((y - canvas.Min.Y) / (2 * size.Y) % 2) * size.X
+			for x := canvas.Min.X + size.X/2 - shift; x < canvas.Max.X; x += 2 * size.X {
+				stamp(image.Pt(x, y))
+			}
+		}
+		return
+	}
+
+	margin := min(canvas.Dx(), canvas.Dy()) / 40
+	vertical, horizontal, _ := strings.Cut(position, "-")
+	if horizontal == "" {
+		switch vertical {
+		case "left", "right":
+			vertical, horizontal = "center", vertical
+		default:
+			horizontal = "center"
+		}
+	}
+	at := image.Pt(canvas.Min.X+(canvas.Dx()-size.X)/2, canvas.Min.Y+(canvas.Dy()-size.Y)/2)
+	switch horizontal {
+	case "left":
+		at.X = canvas.Min.X + margin
+	case "right":
+		at.X = canvas.Max.X - margin - size.X
+	}
+	switch vertical {
+	case "top":
+		at.Y = canvas.Min.Y + margin
+	case "bottom":
+		at.Y = canvas.Max.Y - margin - size.Y
+	}
+	stamp(at)
+}
+
+// textColumns is the width of text in font pixels, with one pixel between
+// letters
+func textColumns(text string) int {
+	return utf8.RuneCountInString(text)*(glyphWidth+1) - 1
+}
+
+// drawText writes text, already in capitals, with its top left corner at
+// left and top
+func drawText(dst draw.Image, text string, left, top, scale int, ink image.Image) {
+	for _, r := range text {
+		glyph, ok := glyphs[r]
+		if !ok {
+			glyph = glyphs['?']
+		}
+		for row, bits := range glyph {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				x, y := left+col*scale, top+row*scale
+				draw.Draw(dst, image.Rect(x, y, x+scale, y+scale), ink, image.Point{}, draw.Over)
+			}
+		}
+		left += (glyphWidth + 1) * scale
+	}
+}
@@ -0,0 +1,144 @@
+// Package keywords splits text into words per language and picks out the
+// ones worth keeping as keywords. Chinese and Japanese, written without
+// spaces between words, have segmenters of their own; other languages
+// split wherever letters and digits end.
+package keywords
+
+import (
+	"bufio"
+	"embed"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed data
+var data embed.FS
+
+// Tokenizer splits text into lowercase words
+type Tokenizer interface {
+	Tokenize(text string) []string
+}
+
+// TokenizerFunc adapts a function to Tokenizer
+type TokenizerFunc func(text string) []string
+
+// Tokenize calls f
+func (f TokenizerFunc) Tokenize(text string) []string {
+	return f(text)
+}
+
+var (
+	tokenizersMu sync.RWMutex
+	tokenizers   = map[string]Tokenizer{
+		"zh": newChineseTokenizer(append(readWords("data/zh_dict.txt"), readWords("data/stopwords_zh.txt")...)),
+		"ja": TokenizerFunc(tokenizeJapanese),
+	}
+)
+
+// Register sets the tokenizer of a base language such as "zh", replacing
+// the built-in one. A segmenter with a full dictionary can be plugged in
+// this way.
+func Register(language string, t Tokenizer) {
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+	tokenizers[BaseLanguage(language)] = t
+}
+
+// TokenizerFor returns the tokenizer of a language tag
+func TokenizerFor(language string) Tokenizer {
+	tokenizersMu.RLock()
+	defer tokenizersMu.RUnlock()
+	if t, ok := tokenizers[BaseLanguage(language)]; ok {
+		return t
+	}
+	return TokenizerFunc(tokenizeWords)
+}
+
+// Tokenize splits text into lowercase words with the tokenizer of language
+func Tokenize(text, language string) []string {
+	return TokenizerFor(language).Tokenize(text)
+}
+
+// Extract returns the words of text worth keeping as keywords, in order and
+// with repeats: stop words, numbers and words too short to mean much (fewer
+// than two Chinese or Japanese characters, or three letters) are left out
+func Extract(text, language string, stopWords map[string]bool) []string {
+	var words []string
+	for _, word := range Tokenize(text, language) {
+		if !stopWords[word] && meaningful(word) {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// BaseLanguage returns the language of a tag such as zh-CN or pt_BR, "en"
+// for an empty tag
+func BaseLanguage(language string) string {
+	tag := strings.ToLower(strings.TrimSpace(language))
+	if tag == "" {
+		return "en"
+	}
+	base, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	return base
+}
+
+// Languages returns the languages with built-in stop words
+func Languages() []string {
+	entries, _ := data.ReadDir("data")
+	var languages []string
+	for _, entry := range entries {
+		if language, ok := strings.CutPrefix(strings.TrimSuffix(entry.Name(), ".txt"), "stopwords_"); ok {
+			languages = append(languages, language)
+		}
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// DefaultStopWords returns the built-in stop words of a language tag, none
+// for a language without a list
+func DefaultStopWords(language string) []string {
+	return readWords("data/stopwords_" + BaseLanguage(language) + ".txt")
+}
+
+// readWords reads an embedded list of one word per line. Blank lines and
+// lines starting with # are skipped.
+func readWords(name string) []string {
+	f, err := data.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word != "" && !strings.HasPrefix(word, "#") {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func meaningful(word string) bool {
+	letters, cjk := 0, false
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) {
+			cjk = true
+		}
+	}
+	if letters == 0 {
+		return false
+	}
+	if cjk {
+		return utf8.RuneCountInString(word) >= 2
+	}
+	return utf8.RuneCountInString(word) >= 3
+}
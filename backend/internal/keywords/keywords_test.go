@@ -0,0 +1,56 @@
+package keywords
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	for _, tc := range []struct {
+		language, text string
+		want           []string
+	}{
+		{"en", "Go's generics, in 2024!", []string{"go", "s", "generics", "in", "2024"}},
+		{"zh-CN", "我们使用机器学习优化数据库性能", []string{"我们", "使用", "机器学习", "优化", "数据库", "性能"}},
+		{"zh", "Go语言并发编程", []string{"go", "语言", "并发", "编程"}},
+		// 犬猫鸟鱼 is not in the dictionary and too long to keep whole
+		{"zh", "学习犬猫鸟鱼", []string{"学习", "犬猫", "猫鸟", "鸟鱼"}},
+		{"ja", "データベースの性能を最適化する", []string{"データベース", "性能", "最適化"}},
+		{"ja", "機械学習入門講座", []string{"機械", "械学", "学習", "習入", "入門", "門講", "講座"}},
+		{"fr", "Le café 東京都 résumé", []string{"le", "café", "東京", "京都", "résumé"}},
+	} {
+		if got := Tokenize(tc.text, tc.language); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Tokenize(%q, %q) = %q, want %q", tc.text, tc.language, got, tc.want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	stopWords := map[string]bool{}
+	for _, word := range DefaultStopWords("zh") {
+		stopWords[word] = true
+	}
+	if got, want := Extract("我们可以使用缓存提升性能，这个方案需要测试", "zh", stopWords), []string{"缓存", "提升", "性能", "方案", "测试"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+
+	stopWords = map[string]bool{}
+	for _, word := range DefaultStopWords("en-US") {
+		stopWords[word] = true
+	}
+	if got, want := Extract("How to use the Go 1.22 router with TLS", "en-US", stopWords), []string{"router", "tls"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %q, want %q", got, want)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Cleanup(func() { Register("ko", TokenizerFunc(tokenizeWords)) })
+	Register("ko", TokenizerFunc(strings.Fields))
+	if got := Tokenize("한국어 텍스트", "ko-KR"); !reflect.DeepEqual(got, []string{"한국어", "텍스트"}) {
+		t.Errorf("the registered tokenizer was not used: %q", got)
+	}
+	if languages := Languages(); !reflect.DeepEqual(languages, []string{"en", "ja", "zh"}) {
+		t.Errorf("Languages() = %q", languages)
+	}
+}
@@ -0,0 +1,189 @@
+package keywords
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// script is the writing system of a run of text
+type script int
+
+const (
+	scriptWord script = iota // letters and digits of alphabetic scripts
+	scriptHan
+	scriptHiragana
+	scriptKatakana
+)
+
+// run is a stretch of text in one script, between separators
+type run struct {
+	text   string
+	script script
+}
+
+// longVowelMark lengthens katakana vowels; Unicode files it under no script
+const longVowelMark = 'ー'
+
+func scriptOf(r rune) (script, bool) {
+	switch {
+	case unicode.Is(unicode.Han, r):
+		return scriptHan, true
+	case unicode.Is(unicode.Hiragana, r):
+		return scriptHiragana, true
+	case unicode.Is(unicode.Katakana, r) || r == longVowelMark:
+		return scriptKatakana, true
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+		return scriptWord, true
+	}
+	return 0, false
+}
+
+// runs splits lowercased text into runs of one script. Punctuation, spaces
+// and symbols separate runs and are dropped.
+func runs(text string) []run {
+	text = strings.ToLower(text)
+	var out []run
+	start, current := -1, scriptWord
+	for i, r := range text {
+		s, ok := scriptOf(r)
+		if start >= 0 && (!ok || s != current) {
+			out = append(out, run{text[start:i], current})
+			start = -1
+		}
+		if ok && start < 0 {
+			start, current = i, s
+		}
+	}
+	if start >= 0 {
+		out = append(out, run{text[start:], current})
+	}
+	return out
+}
+
+// bigrams splits a run of ideographs into overlapping pairs, which match
+// most two-character words without a dictionary
+func bigrams(text string) []string {
+	chars := []rune(text)
+	if len(chars) < 3 {
+		return []string{text}
+	}
+	out := make([]string, 0, len(chars)-1)
+	for i := 0; i+1 < len(chars); i++ {
+		out = append(out, string(chars[i:i+2]))
+	}
+	return out
+}
+
+// tokenizeWords is the tokenizer of languages without one of their own.
+// Chinese or Japanese quoted in such text is split into bigrams.
+func tokenizeWords(text string) []string {
+	var words []string
+	for _, r := range runs(text) {
+		if r.script == scriptHan {
+			words = append(words, bigrams(r.text)...)
+			continue
+		}
+		words = append(words, r.text)
+	}
+	return words
+}
+
+// chineseTokenizer segments Chinese by forward maximum matching against a
+// dictionary: at each position it takes the longest dictionary word.
+// Characters no word covers are grouped and kept whole when short enough
+// to be a word, or split into bigrams.
+type chineseTokenizer struct {
+	dict    map[string]bool
+	maxLen  int
+	unknown int // longest run of unknown characters kept whole
+}
+
+func newChineseTokenizer(words []string) *chineseTokenizer {
+	t := &chineseTokenizer{dict: make(map[string]bool, len(words)), unknown: 3}
+	for _, word := range words {
+		t.dict[word] = true
+		if n := utf8.RuneCountInString(word); n > t.maxLen {
+			t.maxLen = n
+		}
+	}
+	return t
+}
+
+// Tokenize splits text into words
+func (t *chineseTokenizer) Tokenize(text string) []string {
+	var words []string
+	for _, r := range runs(text) {
+		if r.script != scriptHan {
+			words = append(words, r.text)
+			continue
+		}
+		words = append(words, t.segment([]rune(r.text))...)
+	}
+	return words
+}
+
+func (t *chineseTokenizer) segment(chars []rune) []string {
+	var words []string
+	unknownStart := -1
+	flush := func(end int) {
+		if unknownStart < 0 {
+			return
+		}
+		if end-unknownStart <= t.unknown {
+			words = append(words, string(chars[unknownStart:end]))
+		} else {
+			words = append(words, bigrams(string(chars[unknownStart:end]))...)
+		}
+		unknownStart = -1
+	}
+	for i := 0; i < len(chars); {
+		n := t.longestWord(chars[i:])
+		if n == 0 {
+			if unknownStart < 0 {
+				unknownStart = i
+			}
+			i++
+			continue
+		}
+		flush(i)
+		words = append(words, string(chars[i:i+n]))
+		i += n
+	}
+	flush(len(chars))
+	return words
+}
+
+// longestWord returns the length of the longest dictionary word chars
+// start with, 0 if none of at least two characters
+func (t *chineseTokenizer) longestWord(chars []rune) int {
+	for n := min(t.maxLen, len(chars)); n >= 2; n-- {
+		if t.dict[string(chars[:n])] {
+			return n
+		}
+	}
+	return 0
+}
+
+// tokenizeJapanese splits Japanese where the script changes. Katakana runs
+// are mostly loanwords and kept whole; kanji runs are kept whole up to
+// four characters, the usual length of a compound, and split into bigrams
+// beyond. Hiragana is dropped: it writes particles and verb endings
+// rather than the nouns keywords are made of.
+func tokenizeJapanese(text string) []string {
+	var words []string
+	for _, r := range runs(text) {
+		switch r.script {
+		case scriptHiragana:
+		case scriptHan:
+			if utf8.RuneCountInString(r.text) <= 4 {
+				words = append(words, r.text)
+			} else {
+				words = append(words, bigrams(r.text)...)
+			}
+		default:
+			words = append(words, r.text)
+		}
+	}
+	return words
+}
@@ -0,0 +1,214 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Setup configures the default slog logger from LOG_LEVEL and LOG_FORMAT and
+// routes the standard log package through it.
+//
+// LOG_LEVEL: debug, info (default), warn, error
+// LOG_FORMAT: json or text; defaults to json when GIN_MODE=release
+func Setup() *slog.Logger {
+	return SetupWithOutput(os.Stdout, os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+}
+
+// SetupWithOutput configures logging with explicit settings
+func SetupWithOutput(out io.Writer, level, format string) *slog.Logger {
+	if format == "" {
+		if os.Getenv("GIN_MODE") == "release" {
+			format = "json"
+		} else {
+			format = "text"
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: ParseLevel(level)}
+	var handler slog.Handler
+	if strings.ToLower(format) == "json" {
+		handler = slog.NewJSONHandler(out, options)
+	} else {
+		handler = slog.NewTextHandler(out, options)
+	}
+
+	logger := slog.New(&redactingHandler{next: handler})
+	slog.SetDefault(logger)
+
+	// Legacy log.Printf calls become structured records with an inferred level
+	log.SetFlags(0)
+	log.SetOutput(&legacyWriter{logger: logger})
+
+	return logger
+}
+
+// ParseLevel converts a level name to a slog level, defaulting to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// legacyWriter adapts standard log output to slog
+type legacyWriter struct {
+	logger *slog.Logger
+}
+
+func (w *legacyWriter) Write(p []byte) (int, error) {
+	message := CleanMessage(string(p))
+	if message != "" {
+		w.logger.Log(context.Background(), inferLevel(string(p)), message, "source", "legacy")
+	}
+	return len(p), nil
+}
+
+var (
+	errorMarkers = []string{"❌", "error", "failed", "panic", "fatal"}
+	warnMarkers  = []string{"⚠️", "warning", "warn"}
+	debugMarkers = []string{"🔍", "debug"}
+)
+
+// inferLevel guesses a level for unstructured legacy messages
+func inferLevel(message string) slog.Level {
+	lower := strings.ToLower(message)
+	for _, marker := range errorMarkers {
+		if strings.Contains(lower, marker) {
+			return slog.LevelError
+		}
+	}
+	for _, marker := range warnMarkers {
+		if strings.Contains(lower, marker) {
+			return slog.LevelWarn
+		}
+	}
+	for _, marker := range debugMarkers {
+		if strings.HasPrefix(strings.TrimSpace(lower), marker) {
+			return slog.LevelDebug
+		}
+	}
+	return slog.LevelInfo
+}
+
+// CleanMessage strips emoji decorations and surrounding whitespace
+func CleanMessage(message string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '\uFE0F' || r == '\u200D' || (r >= 0x1F000 && r <= 0x1FAFF) || (r >= 0x2600 && r <= 0x27BF) {
+			return -1
+		}
+		if !unicode.IsPrint(r) && r != '\t' {
+			return ' '
+		}
+		return r
+	}, message)
+	return strings.TrimSpace(cleaned)
+}
+
+// Attribute keys whose values are always masked
+var sensitiveKeys = []string{"api_key", "apikey", "password", "secret", "token", "authorization", "cookie"}
+
+// Value patterns for credentials that may end up in free-form messages
+var sensitivePatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`sk-[A-Za-z0-9_-]{16,}`), "[REDACTED]"},                                     // OpenAI/Anthropic style keys
+	{regexp.MustCompile(`AIza[0-9A-Za-z_-]{30,}`), "[REDACTED]"},                                    // Google API keys
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]+`), "[REDACTED]"}, // JWTs
+	{regexp.MustCompile(`(?i)\b(bearer)\s+[A-Za-z0-9._~+/-]+=*`), "${1} [REDACTED]"},                // Authorization headers
+	{regexp.MustCompile(`(?i)\b(api[_-]?key|password|secret|token)=[^\s&"']+`), "${1}=[REDACTED]"},  // query/form values
+}
+
+// Redact masks credentials in a free-form string
+func Redact(value string) string {
+	for _, sensitive := range sensitivePatterns {
+		value = sensitive.pattern.ReplaceAllString(value, sensitive.replacement)
+	}
+	return value
+}
+
+// MaskSecret shows only the last four characters of a credential
+func MaskSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return "****" + secret[len(secret)-4:]
+}
+
+func isSensitiveKey(key string) bool {
+	lower := strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(lower, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactingHandler masks sensitive attributes and credential-like values
+type redactingHandler struct {
+	next slog.Handler
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, Redact(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+	return &redactingHandler{next: h.next.WithAttrs(redacted)}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+
+	// Numeric values such as token counts are never credentials
+	if isSensitiveKey(attr.Key) && (value.Kind() == slog.KindString || value.Kind() == slog.KindAny) {
+		return slog.String(attr.Key, "[REDACTED]")
+	}
+
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, len(group))
+		for i, member := range group {
+			redacted[i] = redactAttr(member)
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return slog.String(attr.Key, Redact(err.Error()))
+		}
+	}
+	return attr
+}
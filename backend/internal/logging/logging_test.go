@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	cases := []string{
+		"provider key sk-abcdefghijklmnopqrstuvwxyz123456",
+		"google key AIzaSyA1234567890abcdefghijklmnopqrstu",
+		"Authorization: Bearer abc.def.ghi",
+		"GET /api/feed?api_key=supersecretvalue&page=2",
+		"token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxMjM0NTY3ODkwIn0.signature",
+	}
+	for _, input := range cases {
+		output := Redact(input)
+		if !strings.Contains(output, "[REDACTED]") {
+			t.Errorf("Redact(%q) = %q, expected redaction", input, output)
+		}
+	}
+
+	if got := Redact("page=2&limit=10"); got != "page=2&limit=10" {
+		t.Errorf("Redact changed harmless input: %q", got)
+	}
+}
+
+func TestHandlerRedactsAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := SetupWithOutput(&buf, "debug", "json")
+
+	logger.Info("configured", "api_key", "plain-value", "tokens", 42, "note", "uses sk-abcdefghijklmnopqrstuvwxyz")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON log output: %v", err)
+	}
+	if record["api_key"] != "[REDACTED]" {
+		t.Errorf("api_key = %v, expected redaction", record["api_key"])
+	}
+	if record["tokens"] != float64(42) {
+		t.Errorf("tokens = %v, numeric counts should not be redacted", record["tokens"])
+	}
+	if strings.Contains(record["note"].(string), "sk-") {
+		t.Errorf("note was not redacted: %v", record["note"])
+	}
+}
+
+func TestLegacyLogLevels(t *testing.T) {
+	var buf bytes.Buffer
+	SetupWithOutput(&buf, "info", "json")
+	defer slog.SetDefault(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+
+	log.Printf("❌ Failed to connect")
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON log output: %v", err)
+	}
+	if record["level"] != "ERROR" || record["msg"] != "Failed to connect" {
+		t.Errorf("unexpected legacy record: %v", record)
+	}
+}
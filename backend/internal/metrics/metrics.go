@@ -0,0 +1,184 @@
+// Package metrics exposes counters and gauges in the Prometheus text
+// exposition format. Collectors registered by other packages are gathered
+// on every scrape, so values read from the database stay shared between
+// instances while process counters can be summed across them.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric types
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+)
+
+// Labels are the label names and values of one sample
+type Labels map[string]string
+
+// Sample is one value of a metric family
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+// Family is a named metric with its samples
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Collector returns metric families when the metrics are scraped
+type Collector func() ([]Family, error)
+
+var (
+	mu         sync.RWMutex
+	collectors []Collector
+)
+
+// Register adds a collector gathered on every scrape
+func Register(collector Collector) {
+	mu.Lock()
+	defer mu.Unlock()
+	collectors = append(collectors, collector)
+}
+
+// Gather runs every collector and returns their families sorted by name. A
+// failing collector does not hide the others; the first error is returned
+// with everything that was collected.
+func Gather() ([]Family, error) {
+	mu.RLock()
+	registered := append([]Collector(nil), collectors...)
+	mu.RUnlock()
+
+	var families []Family
+	var firstErr error
+	for _, collect := range registered {
+		collected, err := collect()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		families = append(families, collected...)
+	}
+	sort.SliceStable(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families, firstErr
+}
+
+// Write writes families in the Prometheus text format
+func Write(w io.Writer, families []Family) error {
+	var b strings.Builder
+	for _, family := range families {
+		fmt.Fprintf(&b, "# HELP %s %s\n", family.Name, escapeHelp(family.Help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", family.Name, family.Type)
+		for _, sample := range family.Samples {
+			b.WriteString(family.Name)
+			writeLabels(&b, sample.Labels)
+			b.WriteByte(' ')
+			b.WriteString(formatValue(sample.Value))
+			b.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// CounterVec is a process-local counter with one value per label set
+type CounterVec struct {
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*Sample
+}
+
+// NewCounterVec creates a counter with the given label names
+func NewCounterVec(labels ...string) *CounterVec {
+	return &CounterVec{labels: labels, values: make(map[string]*Sample)}
+}
+
+// Add increases the counter for the label values, given in the order of
+// the label names. Negative values are ignored, since counters only grow.
+func (v *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 || math.IsNaN(value) {
+		return
+	}
+	key := strings.Join(labelValues, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	sample, ok := v.values[key]
+	if !ok {
+		labels := make(Labels, len(v.labels))
+		for i, name := range v.labels {
+			if i < len(labelValues) {
+				labels[name] = labelValues[i]
+			}
+		}
+		sample = &Sample{Labels: labels}
+		v.values[key] = sample
+	}
+	sample.Value += value
+}
+
+// Samples returns the current values ordered by their labels
+func (v *CounterVec) Samples() []Sample {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	samples := make([]Sample, 0, len(keys))
+	for _, key := range keys {
+		samples = append(samples, Sample{Labels: v.values[key].Labels, Value: v.values[key].Value})
+	}
+	return samples
+}
+
+func writeLabels(b *strings.Builder, labels Labels) {
+	if len(labels) == 0 {
+		return
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(b, "%s=\"%s\"", name, escapeLabel(labels[name]))
+	}
+	b.WriteByte('}')
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(value string) string { return labelEscaper.Replace(value) }
+
+func escapeHelp(help string) string { return helpEscaper.Replace(help) }
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
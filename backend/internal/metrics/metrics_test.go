@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	requests := NewCounterVec("provider", "status")
+	requests.Add(1, "openai", "success")
+	requests.Add(2, "openai", "success")
+	requests.Add(1, `we"ird`, "error")
+	requests.Add(-5, "openai", "success")
+
+	var b strings.Builder
+	err := Write(&b, []Family{
+		{Name: "kuno_requests_total", Help: "Requests\nmade", Type: Counter, Samples: requests.Samples()},
+		{Name: "kuno_limit", Help: "Limit", Type: Gauge, Samples: []Sample{{Value: 1.5}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP kuno_requests_total Requests\nmade
+# TYPE kuno_requests_total counter
+kuno_requests_total{provider="openai",status="success"} 3
+kuno_requests_total{provider="we\"ird",status="error"} 1
+# HELP kuno_limit Limit
+# TYPE kuno_limit gauge
+kuno_limit 1.5
+`
+	if b.String() != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Follower is a fediverse account following a blog through ActivityPub
+type Follower struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	SiteID      uint      `gorm:"not null;default:1;uniqueIndex:idx_followers_site_actor,priority:1" json:"site_id"`
+	ActorID     string    `gorm:"size:500;not null;uniqueIndex:idx_followers_site_actor,priority:2" json:"actor_id"`
+	Username    string    `gorm:"size:255" json:"username"` // user@host
+	Inbox       string    `gorm:"size:500;not null" json:"inbox"`
+	SharedInbox string    `gorm:"size:500" json:"shared_inbox,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Federated reply moderation states
+const (
+	ReplyPending  = "pending"
+	ReplyApproved = "approved"
+	ReplyRejected = "rejected"
+)
+
+// Origins of federated replies besides the fediverse: comments imported
+// or synced from other comment systems and Webmentions from other sites
+const (
+	ReplySourceFediverse  = "fediverse"
+	ReplySourceDisqus     = "disqus"
+	ReplySourceArtalk     = "artalk"
+	ReplySourceWaline     = "waline"
+	ReplySourceGiscus     = "giscus"
+	ReplySourceWebmention = "webmention"
+)
+
+// FederatedReply is a fediverse post replying to an article, a comment
+// imported from another comment system or a page mentioning the article.
+// Replies wait for moderation before they are shown.
+type FederatedReply struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	SiteID      uint      `gorm:"not null;default:1;index" json:"site_id"`
+	ArticleID   uint      `gorm:"not null;index" json:"article_id"`
+	ParentID    *uint     `gorm:"index" json:"parent_id,omitempty"` // reply this one answers
+	Source      string    `gorm:"size:20;not null;default:fediverse" json:"source"`
+	ObjectID    string    `gorm:"size:500;not null;uniqueIndex" json:"object_id"` // ActivityPub id of the reply
+	ActorID     string    `gorm:"size:500;not null;index" json:"actor_id"`
+	AuthorName  string    `gorm:"size:255" json:"author_name"`
+	AuthorURL   string    `gorm:"size:500" json:"author_url"`
+	Content     string    `gorm:"type:text" json:"content"` // sanitized HTML
+	URL         string    `gorm:"size:500" json:"url"`
+	Status      string    `gorm:"size:20;not null;index" json:"status"`
+	PublishedAt time.Time `json:"published_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Types of announcements, which themes show in matching colors
+const (
+	AnnouncementInfo    = "info"
+	AnnouncementSuccess = "success"
+	AnnouncementWarning = "warning"
+	AnnouncementDanger  = "danger"
+)
+
+// Announcement is a site-wide notice shown in a bar above the pages while
+// it is active: from StartsAt, or as soon as it is saved, until EndsAt, or
+// until it is turned off. Message is Markdown in the site's default
+// language; Translations hold it in other languages. Readers may close
+// dismissible announcements.
+type Announcement struct {
+	ID           uint                      `gorm:"primaryKey" json:"id"`
+	SiteID       uint                      `gorm:"not null;default:1;index" json:"site_id"`
+	Message      string                    `gorm:"type:text;not null" json:"message"`
+	LinkURL      string                    `gorm:"size:500" json:"link_url"`
+	Type         string                    `gorm:"size:20;not null;default:'info'" json:"type"`
+	StartsAt     *time.Time                `gorm:"index" json:"starts_at"`
+	EndsAt       *time.Time                `gorm:"index" json:"ends_at"`
+	Dismissible  bool                      `gorm:"not null" json:"dismissible"`
+	IsActive     bool                      `gorm:"not null" json:"is_active"`
+	Translations []AnnouncementTranslation `gorm:"foreignKey:AnnouncementID" json:"translations,omitempty"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+}
+
+// AnnouncementTranslation is an announcement's message in another language
+type AnnouncementTranslation struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	AnnouncementID uint      `gorm:"not null;uniqueIndex:idx_announcement_translations_language,priority:1" json:"announcement_id"`
+	Language       string    `gorm:"size:10;not null;uniqueIndex:idx_announcement_translations_language,priority:2" json:"language"`
+	Message        string    `gorm:"type:text" json:"message"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// APIKey lets a third-party app, such as a mobile client or a widget on
+// another site, read the public API. Only the SHA-256 hash of the key is
+// kept; Prefix, its first characters, tells keys apart in lists. A key may
+// make RateLimit requests an hour, or the configured default when 0.
+// Revoked keys are kept for their usage history.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	SiteID     uint       `gorm:"not null;default:1;index" json:"site_id"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:20;not null" json:"prefix"`
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	RateLimit  int        `gorm:"not null;default:0" json:"rate_limit"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// APIKeyUsage counts the requests made with a key in an hour, and those of
+// them turned away for going over the key's rate limit
+type APIKeyUsage struct {
+	ID       uint      `gorm:"primaryKey" json:"-"`
+	KeyID    uint      `gorm:"not null;uniqueIndex:idx_api_key_usages_key_hour,priority:1" json:"key_id"`
+	Hour     time.Time `gorm:"not null;uniqueIndex:idx_api_key_usages_key_hour,priority:2;index" json:"hour"`
+	Requests int64     `gorm:"not null;default:0" json:"requests"`
+	Limited  int64     `gorm:"not null;default:0" json:"limited"`
+}
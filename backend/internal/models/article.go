@@ -2,28 +2,69 @@ package models
 
 import (
 	"gorm.io/gorm"
+	"strings"
 	"time"
 )
 
 type Article struct {
 	ID           uint                 `gorm:"primaryKey" json:"id"`
+	SiteID       uint                 `gorm:"not null;default:1;index" json:"site_id"`
 	Title        string               `gorm:"not null" json:"title"`
 	Content      string               `gorm:"type:text" json:"content"`
 	ContentType  string               `gorm:"default:'markdown'" json:"content_type"`
 	Summary      string               `gorm:"type:text" json:"summary"`
 	CategoryID   uint                 `json:"category_id"`
 	Category     Category             `gorm:"foreignKey:CategoryID" json:"category"`
+	CategoryPath []CategoryCrumb      `gorm:"-" json:"category_path,omitempty"` // the category and its parents, top first
 	DefaultLang  string               `gorm:"default:'zh'" json:"default_lang"`
+	Dir          string               `gorm:"-" json:"dir"` // text direction of the language served
 	Translations []ArticleTranslation `gorm:"foreignKey:ArticleID" json:"translations,omitempty"`
 	ViewCount    uint                 `gorm:"default:0" json:"view_count"`
+	// AuthorID is the user who wrote the article; Author credits them when
+	// they have an author profile
+	AuthorID *uint          `gorm:"index" json:"author_id,omitempty"`
+	Author   *ArticleAuthor `gorm:"-" json:"author,omitempty"`
 	// Cover Image Fields
 	CoverImageURL *string `gorm:"size:500" json:"cover_image_url,omitempty"`
 	CoverImageID  *uint   `json:"cover_image_id,omitempty"`
 	CoverImageAlt string  `gorm:"size:255" json:"cover_image_alt"`
-	// Pinned Fields
+	// Pinned Fields; a pin with UnpinAt ends at that time
 	IsPinned bool       `gorm:"default:false" json:"is_pinned"`
 	PinOrder int        `gorm:"default:0" json:"pin_order"`
 	PinnedAt *time.Time `json:"pinned_at,omitempty"`
+	UnpinAt  *time.Time `gorm:"index" json:"unpin_at,omitempty"`
+	// ShowDonation shows or hides the donation methods under the article;
+	// nil follows the site's DonationsOnArticles setting
+	ShowDonation *bool `json:"show_donation"`
+	// License is the terms readers and crawlers may use the article under,
+	// one of the codes in services/licenses.go; empty follows the site's
+	// DefaultLicense. LicenseCustom holds the terms of a custom license.
+	// LicenseInfo is the license in effect, filled in when served.
+	License       string          `gorm:"size:50" json:"license"`
+	LicenseCustom string          `gorm:"size:500" json:"license_custom"`
+	LicenseInfo   *ContentLicense `gorm:"-" json:"license_info,omitempty"`
+	// SourceName and SourceURL credit where an imported article first
+	// appeared. CanonicalURL, when set, is the address search engines are
+	// pointed to as the original, such as the post on an old blog that
+	// does not redirect yet.
+	SourceName   string `gorm:"size:255" json:"source_name,omitempty"`
+	SourceURL    string `gorm:"size:500" json:"source_url,omitempty"`
+	CanonicalURL string `gorm:"size:500" json:"canonical_url,omitempty"`
+	// MembersOnly articles are read in full by members only; everyone else
+	// is served a teaser, with Locked set
+	MembersOnly bool `gorm:"default:false;index" json:"members_only"`
+	Locked      bool `gorm:"-" json:"locked,omitempty"`
+	// RegionRule limits where the article is served: "block" withholds it
+	// from visitors in Regions, "allow" serves it only to them. Regions are
+	// comma-separated ISO 3166-1 alpha-2 country codes. Admins see every
+	// article.
+	RegionRule string `gorm:"size:10" json:"region_rule,omitempty"`
+	Regions    string `gorm:"size:500" json:"regions,omitempty"`
+	// Discussion is the article's thread on the external comment system
+	// the site keeps, filled in on article pages and lists
+	Discussion *ArticleDiscussion `gorm:"-" json:"discussion,omitempty"`
+	// Highlight is filled in on search results
+	Highlight *SearchHighlight `gorm:"-" json:"highlight,omitempty"`
 	// SEO Fields
 	SEOTitle       string         `gorm:"size:255" json:"seo_title"`
 	SEODescription string         `gorm:"size:500" json:"seo_description"`
@@ -34,11 +75,24 @@ type Article struct {
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// RegionList returns the country codes of the article's region rule
+func (a *Article) RegionList() []string {
+	var regions []string
+	for _, region := range strings.Split(a.Regions, ",") {
+		if region = strings.TrimSpace(region); region != "" {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
 type Category struct {
 	ID           uint                  `gorm:"primaryKey" json:"id"`
-	Name         string                `gorm:"unique;not null" json:"name"`
+	SiteID       uint                  `gorm:"not null;default:1;index;uniqueIndex:idx_category_site_name,priority:1" json:"site_id"`
+	Name         string                `gorm:"not null;uniqueIndex:idx_category_site_name,priority:2" json:"name"`
 	Description  string                `json:"description"`
 	DefaultLang  string                `gorm:"default:'zh'" json:"default_lang"`
+	ParentID     *uint                 `gorm:"index" json:"parent_id"`
 	Articles     []Article             `gorm:"foreignKey:CategoryID" json:"articles,omitempty"`
 	Translations []CategoryTranslation `gorm:"foreignKey:CategoryID" json:"translations,omitempty"`
 	CreatedAt    time.Time             `json:"created_at"`
@@ -46,8 +100,16 @@ type Category struct {
 	DeletedAt    gorm.DeletedAt        `gorm:"index" json:"-"`
 }
 
+// CategoryCrumb is a category on the path from the top of the category
+// tree, for breadcrumbs
+type CategoryCrumb struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
 type SiteSettings struct {
 	ID                 uint   `gorm:"primaryKey" json:"id"`
+	SiteID             uint   `gorm:"not null;default:1;index" json:"site_id"`
 	SiteTitle          string `gorm:"default:'Blog'" json:"site_title"`
 	SiteSubtitle       string `gorm:"default:'A minimalist space for thoughts and ideas'" json:"site_subtitle"`
 	FooterText         string `gorm:"default:'© 2025 xuemian168'" json:"footer_text"`
@@ -58,11 +120,16 @@ type SiteSettings struct {
 	EnableSoundEffects bool   `gorm:"default:true" json:"enable_sound_effects"`
 	DefaultLanguage    string `gorm:"default:'zh';size:10" json:"default_language"`
 	LogoURL            string `gorm:"size:255" json:"logo_url"`
+	LogoDarkURL        string `gorm:"size:255" json:"logo_dark_url"` // dark mode logo, LogoURL when empty
 	FaviconURL         string `gorm:"size:255" json:"favicon_url"`
+	FaviconSetURL      string `gorm:"size:255" json:"favicon_set_url"` // folder of the icons generated from one image
 	CustomCSS          string `gorm:"type:text" json:"custom_css"`
 	CustomJS           string `gorm:"type:text" json:"custom_js"`
 	ThemeConfig        string `gorm:"type:text" json:"theme_config"`
 	ActiveTheme        string `gorm:"size:100" json:"active_theme"`
+	HomepageLayout     string `gorm:"type:text" json:"homepage_layout"`  // JSON of services.HomepageLayout, the default when empty
+	UploadPolicy       string `gorm:"type:text" json:"upload_policy"`    // JSON of services.UploadPolicy, the default when empty
+	WatermarkPolicy    string `gorm:"type:text" json:"watermark_policy"` // JSON of services.WatermarkPolicy, off when empty
 	// Background Settings
 	BackgroundType     string  `gorm:"default:'none';size:20" json:"background_type"` // "none", "color", "image"
 	BackgroundColor    string  `gorm:"size:20" json:"background_color"`               // hex color value
@@ -72,21 +139,66 @@ type SiteSettings struct {
 	// AI API Configuration
 	AIConfig string `gorm:"type:text" json:"ai_config"`
 	// Privacy and Indexing Control
-	BlockSearchEngines bool                      `gorm:"default:false" json:"block_search_engines"`
-	BlockAITraining    bool                      `gorm:"default:false" json:"block_ai_training"`
-	Translations       []SiteSettingsTranslation `gorm:"foreignKey:SettingsID" json:"translations,omitempty"`
-	CreatedAt          time.Time                 `json:"created_at"`
-	UpdatedAt          time.Time                 `json:"updated_at"`
+	BlockSearchEngines bool `gorm:"default:false" json:"block_search_engines"`
+	BlockAITraining    bool `gorm:"default:false" json:"block_ai_training"`
+	// Public statistics, each exposed only when the admin opts in
+	StatsShowPosts       bool `gorm:"default:false" json:"stats_show_posts"`
+	StatsShowWords       bool `gorm:"default:false" json:"stats_show_words"`
+	StatsShowDaysRunning bool `gorm:"default:false" json:"stats_show_days_running"`
+	StatsShowViews       bool `gorm:"default:false" json:"stats_show_views"`
+	StatsShowLastUpdated bool `gorm:"default:false" json:"stats_show_last_updated"`
+	// DonationsOnArticles shows the donation methods under articles that
+	// do not choose for themselves
+	DonationsOnArticles bool `gorm:"default:false" json:"donations_on_articles"`
+	// View counting: a visitor's repeat views of an article count again
+	// after ViewDedupHours (never when 0), and admins' own views count only
+	// with CountAdminViews
+	ViewDedupHours  int  `gorm:"default:0" json:"view_dedup_hours"`
+	CountAdminViews bool `gorm:"default:false" json:"count_admin_views"`
+	// DefaultLicense is the license of articles that do not choose one
+	DefaultLicense       string `gorm:"size:50;default:'all-rights-reserved'" json:"default_license"`
+	DefaultLicenseCustom string `gorm:"size:500" json:"default_license_custom"`
+	// MaxPinnedArticles is how many articles can be pinned at once
+	MaxPinnedArticles int `gorm:"default:2" json:"max_pinned_articles"`
+	// Search engine site verification codes, served as meta tags and
+	// verification files
+	GoogleSiteVerification string                    `gorm:"size:255" json:"google_site_verification"`
+	BingSiteVerification   string                    `gorm:"size:255" json:"bing_site_verification"`
+	BaiduSiteVerification  string                    `gorm:"size:255" json:"baidu_site_verification"`
+	YandexSiteVerification string                    `gorm:"size:255" json:"yandex_site_verification"`
+	Translations           []SiteSettingsTranslation `gorm:"foreignKey:SettingsID" json:"translations,omitempty"`
+	CreatedAt              time.Time                 `json:"created_at"`
+	UpdatedAt              time.Time                 `json:"updated_at"`
+}
+
+// SearchHighlight shows where a search matched an article. Its fields are
+// HTML, escaped, with the matched terms in <mark>: the title and summary
+// whole, and a snippet of the content around the matches.
+type SearchHighlight struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Snippet string `json:"snippet"`
+}
+
+// ContentLicense is a license as shown to readers: its code, its name and,
+// for published licenses, the URL of its terms
+type ContentLicense struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
 }
 
 type SiteSettingsTranslation struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	SettingsID   uint      `gorm:"not null;index" json:"settings_id"`
-	Language     string    `gorm:"not null;size:10;index" json:"language"`
-	SiteTitle    string    `gorm:"not null" json:"site_title"`
-	SiteSubtitle string    `gorm:"not null" json:"site_subtitle"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	SettingsID   uint   `gorm:"not null;index" json:"settings_id"`
+	Language     string `gorm:"not null;size:10;index" json:"language"`
+	SiteTitle    string `gorm:"not null" json:"site_title"`
+	SiteSubtitle string `gorm:"not null" json:"site_subtitle"`
+	// Translation status, as on ArticleTranslation
+	SourceHash        string    `gorm:"size:64" json:"source_hash"`
+	MachineTranslated bool      `gorm:"default:false" json:"machine_translated"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
 
 // AIProviderConfig represents AI API configuration for different providers
@@ -109,34 +221,62 @@ type AIConfig struct {
 }
 
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Username  string         `gorm:"unique;not null" json:"username"`
-	Password  string         `gorm:"not null" json:"-"`
-	IsAdmin   bool           `gorm:"default:true" json:"is_admin"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	SiteID   uint   `gorm:"not null;default:1;index" json:"site_id"`
+	Username string `gorm:"unique;not null" json:"username"`
+	Password string `gorm:"not null" json:"-"`
+	IsAdmin  bool   `gorm:"default:true" json:"is_admin"`
+	// PasskeyRequired makes a passkey mandatory as a second factor after password login
+	PasskeyRequired bool           `gorm:"default:false" json:"passkey_required"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 type ArticleTranslation struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ArticleID uint   `gorm:"not null;index" json:"article_id"`
+	Language  string `gorm:"not null;size:10;index" json:"language"`
+	Title     string `gorm:"not null" json:"title"`
+	Content   string `gorm:"type:text" json:"content"`
+	Summary   string `gorm:"type:text" json:"summary"`
+	SEOSlug   string `gorm:"size:255;index" json:"seo_slug"`
+	Dir       string `gorm:"-" json:"dir"` // text direction of Language
+	// SourceHash is the hash of the source text when the translation was
+	// last written, and MachineTranslated marks a translation from a
+	// translation service that no one has reviewed yet
+	SourceHash        string `gorm:"size:64" json:"source_hash"`
+	MachineTranslated bool   `gorm:"default:false" json:"machine_translated"`
+	// SourceSections holds the ArticleSource the translation was made from,
+	// as JSON, so only the changed parts need translating again
+	SourceSections string    `gorm:"type:text" json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ArticleSlugRedirect is a slug an article used to have. Requests for it
+// are redirected to the article's current slug in the same language; an
+// empty Language is the article's own slug rather than a translation's.
+type ArticleSlugRedirect struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
+	SiteID    uint      `gorm:"not null;default:1;index" json:"site_id"`
 	ArticleID uint      `gorm:"not null;index" json:"article_id"`
-	Language  string    `gorm:"not null;size:10;index" json:"language"`
-	Title     string    `gorm:"not null" json:"title"`
-	Content   string    `gorm:"type:text" json:"content"`
-	Summary   string    `gorm:"type:text" json:"summary"`
+	Language  string    `gorm:"size:10" json:"language"`
+	Slug      string    `gorm:"size:255;not null;index" json:"slug"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type CategoryTranslation struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	CategoryID  uint      `gorm:"not null;index" json:"category_id"`
-	Language    string    `gorm:"not null;size:10;index" json:"language"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	CategoryID  uint   `gorm:"not null;index" json:"category_id"`
+	Language    string `gorm:"not null;size:10;index" json:"language"`
+	Name        string `gorm:"not null" json:"name"`
+	Description string `json:"description"`
+	// Translation status, as on ArticleTranslation
+	SourceHash        string    `gorm:"size:64" json:"source_hash"`
+	MachineTranslated bool      `gorm:"default:false" json:"machine_translated"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
 
 // ArticleView tracks unique visitors for each article with detailed analytics
@@ -166,6 +306,20 @@ type ArticleView struct {
 	Platform   string `gorm:"size:30;index" json:"platform"`    // Windows, macOS, iOS, Android, Linux
 }
 
+// ArticleShareCount is a sample of how often an article was shared on a
+// social platform. Count is the shares, likes or points the platform
+// reports and Comments the discussion it reports; a sample is recorded
+// whenever either changes, so the samples of a platform form its history.
+type ArticleShareCount struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SiteID    uint      `gorm:"not null;default:1;index" json:"site_id"`
+	ArticleID uint      `gorm:"not null;index:idx_article_share_counts,priority:1" json:"article_id"`
+	Platform  string    `gorm:"size:20;not null;index:idx_article_share_counts,priority:2" json:"platform"`
+	Count     int64     `gorm:"not null;default:0" json:"count"`
+	Comments  int64     `gorm:"not null;default:0" json:"comments"`
+	FetchedAt time.Time `gorm:"not null;index:idx_article_share_counts,priority:3" json:"fetched_at"`
+}
+
 // Analytics statistics structures for efficient querying
 type GeographicStats struct {
 	Country      string `json:"country"`
@@ -194,6 +348,7 @@ type PlatformStats struct {
 // SocialMedia represents social media links
 type SocialMedia struct {
 	ID           uint           `gorm:"primaryKey" json:"id"`
+	SiteID       uint           `gorm:"not null;default:1;index" json:"site_id"`
 	Platform     string         `gorm:"not null;size:50" json:"platform"`
 	URL          string         `gorm:"not null" json:"url"`
 	IconName     string         `gorm:"size:50" json:"icon_name"`
@@ -281,13 +436,34 @@ type EmbeddingSearchResult struct {
 	ArticleID    uint      `json:"article_id"`
 	Title        string    `json:"title"`
 	Summary      string    `json:"summary"`
-	CategoryName string    `json:"category_name"`
+	CategoryID   uint      `json:"category_id"`
+	CategoryName string    `json:"category_name"` // in Language
 	Language     string    `json:"language"`
 	Similarity   float64   `json:"similarity"`
 	ViewCount    uint      `json:"view_count"`
 	CreatedAt    time.Time `json:"created_at"`
 }
 
+// TopicCentroid is the mean embedding of the articles of a topic in one
+// language: a category with the categories below it, or a tag from the
+// articles' SEO keywords. Key is the category ID or the lowercased tag.
+// Centroids are recomputed from the stored embeddings, so they only use
+// the provider and model most of the site's embeddings in that language
+// were made with.
+type TopicCentroid struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SiteID       uint      `gorm:"not null;default:1;uniqueIndex:idx_topic_centroids_topic,priority:1" json:"site_id"`
+	Kind         string    `gorm:"size:20;not null;uniqueIndex:idx_topic_centroids_topic,priority:2" json:"kind"`
+	Key          string    `gorm:"column:topic_key;size:191;not null;uniqueIndex:idx_topic_centroids_topic,priority:3" json:"key"`
+	Language     string    `gorm:"size:10;not null;uniqueIndex:idx_topic_centroids_topic,priority:4" json:"language"`
+	Provider     string    `gorm:"size:50" json:"provider"`
+	Model        string    `gorm:"size:100" json:"model"`
+	Embedding    string    `gorm:"type:text;not null" json:"-"` // JSON array of floats, of unit length
+	Dimensions   int       `gorm:"not null" json:"dimensions"`
+	ArticleCount int       `gorm:"not null;default:0" json:"article_count"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
 // SearchIndex tracks search performance and caching
 type SearchIndex struct {
 	ID               uint      `gorm:"primaryKey" json:"id"`
@@ -324,16 +500,23 @@ type PopularQuery struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
-// ContentQualityAnalysis stores article content quality analysis results
+// ContentQualityAnalysis stores article content quality analysis results.
+// Originality compares the article's embedding with those of the other
+// articles; without an embedding Similarity is nil and OriginalityScore
+// is left out of the content score.
 type ContentQualityAnalysis struct {
 	ID               uint      `gorm:"primaryKey" json:"id"`
 	ArticleID        uint      `gorm:"not null;index" json:"article_id"`
-	TopicClusters    string    `gorm:"type:text" json:"topic_clusters"`    // JSON format
-	ContentScore     float64   `gorm:"default:0" json:"content_score"`     // Content quality score
-	KeywordDensity   string    `gorm:"type:text" json:"keyword_density"`   // JSON format
-	ReadabilityScore float64   `gorm:"default:0" json:"readability_score"` // Readability score
-	OriginalityScore float64   `gorm:"default:0" json:"originality_score"` // Originality score
-	SEOScore         float64   `gorm:"default:0" json:"seo_score"`         // SEO optimization score
+	Language         string    `gorm:"size:10" json:"language"`
+	WordCount        int       `gorm:"default:0" json:"word_count"`
+	TopicClusters    string    `gorm:"type:text" json:"topic_clusters"`      // JSON format
+	ContentScore     float64   `gorm:"default:0;index" json:"content_score"` // Content quality score
+	KeywordDensity   string    `gorm:"type:text" json:"keyword_density"`     // JSON format
+	ReadabilityScore float64   `gorm:"default:0" json:"readability_score"`   // Readability score
+	OriginalityScore float64   `gorm:"default:0" json:"originality_score"`   // Originality score
+	SimilarArticleID *uint     `json:"similar_article_id,omitempty"`         // Most similar other article
+	Similarity       *float64  `json:"similarity,omitempty"`                 // Cosine similarity to it
+	SEOScore         float64   `gorm:"default:0" json:"seo_score"`           // SEO optimization score
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
 
@@ -341,22 +524,40 @@ type ContentQualityAnalysis struct {
 	Article Article `gorm:"foreignKey:ArticleID" json:"article,omitempty"`
 }
 
-// WritingSuggestion stores AI-generated writing suggestions
+// WritingSuggestion stores AI-generated writing suggestions and the topic
+// gaps found in searches, tracked keywords and categories. For gaps, Topic
+// is what is missing and Signal its weight: the searches without results,
+// the keyword's search volume or the articles in the category.
 type WritingSuggestion struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	SuggestionType string    `gorm:"not null;size:50;index" json:"suggestion_type"` // 'topic_gap', 'keyword', 'tag', 'seo'
-	Content        string    `gorm:"type:text;not null" json:"content"`
-	RelevanceScore float64   `gorm:"default:0" json:"relevance_score"`
-	Language       string    `gorm:"size:10;index" json:"language"`
-	CategoryID     *uint     `gorm:"index" json:"category_id"`
-	IsUsed         bool      `gorm:"default:false" json:"is_used"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SuggestionType string     `gorm:"not null;size:50;index" json:"suggestion_type"` // 'search_gap', 'keyword_gap', 'category_gap', 'inspiration'
+	Topic          string     `gorm:"size:200;index" json:"topic"`
+	Content        string     `gorm:"type:text;not null" json:"content"`
+	RelevanceScore float64    `gorm:"default:0" json:"relevance_score"`
+	Signal         int        `gorm:"default:0" json:"signal"`
+	Language       string     `gorm:"size:10;index" json:"language"`
+	CategoryID     *uint      `gorm:"index" json:"category_id"`
+	IsUsed         bool       `gorm:"default:false" json:"is_used"`
+	Status         string     `gorm:"size:20;default:'pending';index" json:"status"` // pending/accepted/dismissed
+	DecidedAt      *time.Time `json:"decided_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 
 	// Foreign key relationships
 	Category *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
 }
 
+// UnansweredSearch counts the searches for a query that found no article,
+// as a hint of what readers look for and the blog lacks
+type UnansweredSearch struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Query          string    `gorm:"size:200;not null;uniqueIndex:idx_unanswered_search" json:"query"`
+	Language       string    `gorm:"size:10;not null;uniqueIndex:idx_unanswered_search" json:"language"`
+	Count          int       `gorm:"default:1" json:"count"`
+	LastSearchedAt time.Time `gorm:"index" json:"last_searched_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 // UserReadingBehavior tracks user reading patterns and engagement
 type UserReadingBehavior struct {
 	ID              uint      `gorm:"primaryKey" json:"id"`
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// AuthorProfile is the public face of a user who writes articles: the name
+// articles are credited to, a bio in the site's default language with
+// Translations in others, an avatar from the media library and links to
+// the author's profiles elsewhere. Users without a profile are not shown
+// as authors.
+type AuthorProfile struct {
+	ID            uint                       `gorm:"primaryKey" json:"id"`
+	SiteID        uint                       `gorm:"not null;default:1;index" json:"site_id"`
+	UserID        uint                       `gorm:"not null;uniqueIndex" json:"user_id"`
+	DisplayName   string                     `gorm:"size:100;not null" json:"display_name"`
+	Bio           string                     `gorm:"type:text" json:"bio"`
+	AvatarMediaID *uint                      `json:"avatar_media_id"`
+	SocialLinks   string                     `gorm:"type:text" json:"social_links"` // JSON array of AuthorLink
+	Translations  []AuthorProfileTranslation `gorm:"foreignKey:ProfileID" json:"translations,omitempty"`
+	CreatedAt     time.Time                  `json:"created_at"`
+	UpdatedAt     time.Time                  `json:"updated_at"`
+}
+
+// AuthorProfileTranslation is an author's bio in another language
+type AuthorProfileTranslation struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProfileID uint      `gorm:"not null;uniqueIndex:idx_author_profile_translations_language,priority:1" json:"profile_id"`
+	Language  string    `gorm:"size:10;not null;uniqueIndex:idx_author_profile_translations_language,priority:2" json:"language"`
+	Bio       string    `gorm:"type:text" json:"bio"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AuthorLink is a link to one of the author's profiles on another site
+type AuthorLink struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// ArticleAuthor is how an article credits its author
+type ArticleAuthor struct {
+	ID          uint         `json:"id"`
+	Name        string       `json:"name"`
+	AvatarURL   string       `json:"avatar_url,omitempty"`
+	SocialLinks []AuthorLink `json:"social_links,omitempty"`
+}
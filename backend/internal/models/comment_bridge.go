@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// ExternalDiscussion is the discussion of an article on the external
+// comment system a site keeps, as last synced. Comments counts every
+// comment and reply the system shows.
+type ExternalDiscussion struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	SiteID        uint       `gorm:"not null;default:1;index" json:"site_id"`
+	ArticleID     uint       `gorm:"not null;uniqueIndex:idx_external_discussions_article,priority:1" json:"article_id"`
+	Provider      string     `gorm:"size:20;not null;uniqueIndex:idx_external_discussions_article,priority:2" json:"provider"`
+	ThreadID      string     `gorm:"size:255;not null" json:"thread_id"`
+	URL           string     `gorm:"size:500" json:"url"`
+	Comments      int        `gorm:"not null;default:0" json:"comments"`
+	LastCommentAt *time.Time `json:"last_comment_at,omitempty"`
+	SyncedAt      time.Time  `gorm:"index" json:"synced_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ArticleDiscussion is how an article shows its discussion on an external
+// comment system
+type ArticleDiscussion struct {
+	Provider string `json:"provider"`
+	Comments int    `json:"comments"`
+	URL      string `json:"url,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Contact message states. Messages that look like spam are kept apart from
+// the inbox instead of being dropped, so false positives can be recovered.
+const (
+	ContactNew      = "new"
+	ContactRead     = "read"
+	ContactArchived = "archived"
+	ContactSpam     = "spam"
+)
+
+// ContactMessage is a message sent through a site's contact form.
+// RepliedAt is set by the admin once the sender has been answered.
+type ContactMessage struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	SiteID    uint       `gorm:"not null;default:1;index" json:"site_id"`
+	Name      string     `gorm:"size:100;not null" json:"name"`
+	Email     string     `gorm:"size:254;not null" json:"email"`
+	Subject   string     `gorm:"size:200" json:"subject"`
+	Message   string     `gorm:"type:text;not null" json:"message"`
+	Status    string     `gorm:"size:20;not null;index" json:"status"`
+	IPAddress string     `gorm:"size:45;index" json:"ip_address"`
+	UserAgent string     `gorm:"size:500" json:"user_agent"`
+	RepliedAt *time.Time `json:"replied_at,omitempty"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
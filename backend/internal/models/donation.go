@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Donation method types. QR code methods show an image to scan; the others
+// link to the donation page.
+const (
+	DonationWeChatPay      = "wechat_pay"
+	DonationAlipay         = "alipay"
+	DonationKofi           = "kofi"
+	DonationGitHubSponsors = "github_sponsors"
+	DonationLink           = "link"
+)
+
+// DonationMethod is a way readers can support a site, shown on its sponsor
+// page and, where enabled, under articles. Clicks counts the readers who
+// opened it.
+type DonationMethod struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SiteID       uint      `gorm:"not null;default:1;index" json:"site_id"`
+	Type         string    `gorm:"size:20;not null" json:"type"`
+	Label        string    `gorm:"size:100" json:"label"`
+	URL          string    `gorm:"size:500" json:"url"`
+	QRCodeURL    string    `gorm:"size:500" json:"qr_code_url"`
+	DisplayOrder int       `gorm:"default:0" json:"display_order"`
+	IsActive     bool      `gorm:"not null" json:"is_active"`
+	Clicks       int64     `gorm:"not null;default:0" json:"clicks"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// IsQRCode reports whether readers donate by scanning the method's QR code
+func (m *DonationMethod) IsQRCode() bool {
+	return m.Type == DonationWeChatPay || m.Type == DonationAlipay
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Feed item statuses. Posts pulled from a feed wait as drafts until an
+// admin publishes them as articles or dismisses them.
+const (
+	FeedItemDraft     = "draft"
+	FeedItemPublished = "published"
+	FeedItemDismissed = "dismissed"
+)
+
+// FeedSource is an RSS or Atom feed posts are imported from, typically
+// the feed of an older blog being moved onto the site. Posts are staged
+// once when the feed is added; a followed feed is fetched again on
+// FEED_IMPORT_SCHEDULE and its new posts staged as well. Published posts
+// go into CategoryID, in Language.
+type FeedSource struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	SiteID        uint       `gorm:"not null;default:1;uniqueIndex:idx_feed_sources_url" json:"site_id"`
+	URL           string     `gorm:"size:500;not null;uniqueIndex:idx_feed_sources_url" json:"url"`
+	Title         string     `gorm:"size:255" json:"title"`
+	SiteURL       string     `gorm:"size:500" json:"site_url"` // the website the feed belongs to
+	CategoryID    uint       `json:"category_id"`
+	Language      string     `gorm:"size:10" json:"language"`
+	Follow        bool       `gorm:"not null;default:false;index" json:"follow"`
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+	LastError     string     `gorm:"size:500" json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// FeedItem is a post pulled from a feed. GUID is the post's id in the
+// feed, or its link when it has none, and keeps a post from being staged
+// twice. Content is the post converted to Markdown. ArticleID is the
+// article it was published as.
+type FeedItem struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	SiteID      uint       `gorm:"not null;default:1;index" json:"site_id"`
+	SourceID    uint       `gorm:"not null;uniqueIndex:idx_feed_items_guid" json:"source_id"`
+	GUID        string     `gorm:"column:guid;size:500;not null;uniqueIndex:idx_feed_items_guid" json:"guid"`
+	Link        string     `gorm:"size:500" json:"link"`
+	Title       string     `gorm:"size:500" json:"title"`
+	Summary     string     `gorm:"type:text" json:"summary"`
+	Content     string     `gorm:"type:text" json:"content"`
+	Author      string     `gorm:"size:255" json:"author,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Status      string     `gorm:"size:20;not null;default:'draft';index" json:"status"` // draft/published/dismissed
+	ArticleID   *uint      `gorm:"index" json:"article_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	Source *FeedSource `gorm:"foreignKey:SourceID" json:"source,omitempty"`
+}
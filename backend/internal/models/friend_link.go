@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Reciprocal link check results. A friend link that asks for checking is
+// unchecked until the check job first fetches the friend's page.
+const (
+	ReciprocalUnchecked   = "unchecked"
+	ReciprocalFound       = "found"
+	ReciprocalMissing     = "missing"
+	ReciprocalUnreachable = "unreachable"
+)
+
+// FriendLink is an entry of a site's blogroll. Links are grouped by
+// Category on the blogroll page and sorted by DisplayOrder within it. With
+// CheckReciprocal set, a background job checks that ReciprocalURL, or URL
+// when empty, links back to the site.
+type FriendLink struct {
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	SiteID              uint       `gorm:"not null;default:1;index" json:"site_id"`
+	Name                string     `gorm:"size:100;not null" json:"name"`
+	URL                 string     `gorm:"size:500;not null" json:"url"`
+	Description         string     `gorm:"size:500" json:"description"`
+	AvatarURL           string     `gorm:"size:500" json:"avatar_url"`
+	Category            string     `gorm:"size:50;index" json:"category"`
+	DisplayOrder        int        `gorm:"default:0" json:"display_order"`
+	IsActive            bool       `gorm:"not null" json:"is_active"`
+	CheckReciprocal     bool       `gorm:"not null" json:"check_reciprocal"`
+	ReciprocalURL       string     `gorm:"size:500" json:"reciprocal_url"`
+	ReciprocalStatus    string     `gorm:"size:20;not null;default:unchecked" json:"reciprocal_status"`
+	ReciprocalError     string     `gorm:"size:500" json:"reciprocal_error,omitempty"`
+	ReciprocalCheckedAt *time.Time `json:"reciprocal_checked_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// ReciprocalPage returns the page expected to link back to the site
+func (l *FriendLink) ReciprocalPage() string {
+	if l.ReciprocalURL != "" {
+		return l.ReciprocalURL
+	}
+	return l.URL
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// GuestbookSpam is the state of entries the spam checks caught. Otherwise
+// entries move through the comment states: ReplyPending until moderated,
+// then ReplyApproved or ReplyRejected.
+const GuestbookSpam = "spam"
+
+// GuestbookEntry is a message left on a site's guestbook. Language is the
+// language of the page it was written on, so each translation of the site
+// can show its own message wall. Reply is the site's public answer; the
+// sender is emailed about it when they gave an address and NotifyReplies.
+type GuestbookEntry struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	SiteID    uint   `gorm:"not null;default:1;index" json:"site_id"`
+	Name      string `gorm:"size:100;not null" json:"name"`
+	Email     string `gorm:"size:254" json:"email"`
+	Website   string `gorm:"size:500" json:"website"`
+	Message   string `gorm:"type:text;not null" json:"message"`
+	Language  string `gorm:"size:10;not null;index" json:"language"`
+	Status    string `gorm:"size:20;not null;index" json:"status"`
+	IPAddress string `gorm:"size:45;index" json:"ip_address"`
+	UserAgent string `gorm:"size:500" json:"user_agent"`
+	// NotifyReplies is the sender's opt-in to reply emails, withdrawn
+	// through the unsubscribe link in them
+	NotifyReplies bool       `gorm:"not null;default:false" json:"notify_replies"`
+	Reply         string     `gorm:"type:text" json:"reply"`
+	RepliedAt     *time.Time `json:"replied_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
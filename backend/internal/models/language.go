@@ -0,0 +1,70 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Text directions of a language, as the HTML dir attribute takes them
+const (
+	DirectionLTR = "ltr"
+	DirectionRTL = "rtl"
+)
+
+// rtlLanguages are the languages written from right to left
+var rtlLanguages = map[string]bool{
+	"ar": true, "arc": true, "ckb": true, "dv": true, "fa": true, "he": true, "iw": true,
+	"ks": true, "ku-arab": true, "ps": true, "sd": true, "ug": true, "ur": true, "yi": true,
+}
+
+// LanguageDirection returns the text direction of a language tag such as
+// ar or fa-IR: rtl for the languages written from right to left, ltr for
+// every other language and for an empty tag
+func LanguageDirection(language string) string {
+	tag := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+	base, _, _ := strings.Cut(tag, "-")
+	if rtlLanguages[tag] || rtlLanguages[base] {
+		return DirectionRTL
+	}
+	return DirectionLTR
+}
+
+// bidiControls are the invisible marks that steer the direction of mixed
+// right-to-left and left-to-right text
+var bidiControls = strings.NewReplacer(
+	"‎", "", "‏", "", "؜", "",
+	"‪", "", "‫", "", "‬", "", "‭", "", "‮", "",
+	"⁦", "", "⁧", "", "⁨", "", "⁩", "",
+)
+
+// StripBidiControls removes the direction marks from text, which take no
+// space on screen but count as characters and split words when text is
+// measured or searched
+func StripBidiControls(text string) string {
+	return bidiControls.Replace(text)
+}
+
+// AfterFind sets the direction of the article's own language; serving the
+// article in a translation's language changes it
+func (a *Article) AfterFind(tx *gorm.DB) error {
+	a.Dir = LanguageDirection(a.DefaultLang)
+	return nil
+}
+
+// AfterFind sets the direction of the translation's language
+func (t *ArticleTranslation) AfterFind(tx *gorm.DB) error {
+	t.Dir = LanguageDirection(t.Language)
+	return nil
+}
+
+// StopWords replaces the built-in stop words of a base language such as zh
+// when keywords are extracted. Words holds one word per line.
+type StopWords struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Language  string    `gorm:"size:10;not null;uniqueIndex" json:"language"`
+	Words     string    `gorm:"type:text;not null" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
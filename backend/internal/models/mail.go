@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// EmailTemplate replaces a built-in email template for one language. Names
+// are the template names of the mailer service, such as "password_reset";
+// subject and body are Go text/template sources.
+type EmailTemplate struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:50;not null;uniqueIndex:idx_email_templates_name_language,priority:1" json:"name"`
+	Language  string    `gorm:"size:10;not null;uniqueIndex:idx_email_templates_name_language,priority:2" json:"language"`
+	Subject   string    `gorm:"size:255;not null" json:"subject"`
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Reasons an address is suppressed
+const (
+	SuppressionBounce    = "bounce"
+	SuppressionComplaint = "complaint"
+	SuppressionManual    = "manual"
+)
+
+// MailSuppression is an address no email is sent to, because it bounced
+// for good, its owner reported a message as spam, or an admin added it.
+// The list is instance-wide as every site sends through the same server.
+type MailSuppression struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Email     string    `gorm:"size:254;not null;uniqueIndex" json:"email"`
+	Reason    string    `gorm:"size:20;not null;index" json:"reason"`
+	Source    string    `gorm:"size:50" json:"source"` // the mail provider that reported it, or "admin"
+	Detail    string    `gorm:"size:500" json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
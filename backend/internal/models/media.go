@@ -14,6 +14,7 @@ const (
 
 type MediaLibrary struct {
 	ID           uint           `gorm:"primaryKey" json:"id"`
+	SiteID       uint           `gorm:"not null;default:1;index" json:"site_id"`
 	FileName     string         `gorm:"not null" json:"file_name"`
 	OriginalName string         `gorm:"not null" json:"original_name"`
 	FilePath     string         `gorm:"not null" json:"file_path"`
@@ -25,4 +26,54 @@ type MediaLibrary struct {
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// MetadataReport is set on upload, for images
+	MetadataReport *ImageMetadataReport `gorm:"-" json:"metadata_report,omitempty"`
+}
+
+// ImageMetadataReport tells what metadata an uploaded image carried.
+// Everything found is removed when the image is re-encoded, except the
+// kinds the uploader chose to keep.
+type ImageMetadataReport struct {
+	Found     []string `json:"found"`
+	Sensitive []string `json:"sensitive"` // GPS position, serial numbers, owner
+	Kept      []string `json:"kept"`
+}
+
+// MediaMetadataStat counts a site's uploaded images that carried a kind of
+// metadata. The "images" kind counts every image checked.
+type MediaMetadataStat struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SiteID    uint      `gorm:"not null;default:1;uniqueIndex:idx_media_metadata_stats_site_kind,priority:1" json:"site_id"`
+	Kind      string    `gorm:"size:30;not null;uniqueIndex:idx_media_metadata_stats_site_kind,priority:2" json:"kind"`
+	Images    int64     `gorm:"not null;default:0" json:"images"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DirectUpload statuses
+const (
+	DirectUploadPending   = "pending"
+	DirectUploadCompleted = "completed"
+	DirectUploadFailed    = "failed"
+	DirectUploadExpired   = "expired"
+)
+
+// DirectUpload tracks a presigned upload that goes straight to object storage
+// and is validated by the server when the client finalizes it
+type DirectUpload struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SiteID       uint      `gorm:"not null;default:1;index" json:"site_id"`
+	Token        string    `gorm:"uniqueIndex;not null;size:64" json:"token"`
+	ObjectKey    string    `gorm:"not null" json:"object_key"`
+	OriginalName string    `gorm:"not null" json:"original_name"`
+	MimeType     string    `gorm:"not null" json:"mime_type"`
+	MediaType    MediaType `gorm:"not null" json:"media_type"`
+	Size         int64     `gorm:"not null" json:"size"`
+	Alt          string    `json:"alt"`
+	Status       string    `gorm:"size:20;default:'pending';index" json:"status"`
+	Error        string    `json:"error,omitempty"`
+	MediaID      *uint     `json:"media_id,omitempty"`
+	ExpiresAt    time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
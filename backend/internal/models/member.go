@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Member is a reader with access to members-only articles. Members sign in
+// with their email and a passcode given to them by an admin; only the
+// SHA-256 hash of the passcode is kept. A disabled member, or one whose
+// ExpiresAt has passed, can no longer sign in or use their tokens.
+type Member struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	SiteID       uint       `gorm:"not null;default:1;uniqueIndex:idx_members_site_email,priority:1" json:"site_id"`
+	Email        string     `gorm:"size:255;not null;uniqueIndex:idx_members_site_email,priority:2" json:"email"`
+	Name         string     `gorm:"size:100" json:"name"`
+	Note         string     `gorm:"size:500" json:"note"`
+	PasscodeHash string     `gorm:"size:64;not null" json:"-"`
+	Disabled     bool       `gorm:"not null;default:false" json:"disabled"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	LastLoginAt  *time.Time `json:"last_login_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Active reports whether the member may read members-only articles at now
+func (m *Member) Active(now time.Time) bool {
+	return !m.Disabled && (m.ExpiresAt == nil || m.ExpiresAt.After(now))
+}
+
+// MemberToken is a membership token issued when a member signs in. Only
+// its hash is kept.
+type MemberToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	MemberID  uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Moment visibilities. Public moments are listed and appear in feeds,
+// unlisted ones are only reachable by their link, and private ones only by
+// admins.
+const (
+	MomentPublic   = "public"
+	MomentUnlisted = "unlisted"
+	MomentPrivate  = "private"
+)
+
+// Moment is a short status update posted alongside articles: a few lines
+// of Markdown and optionally some images, without the titles, categories,
+// translations and SEO data of an article.
+type Moment struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	SiteID     uint      `gorm:"not null;default:1;index" json:"site_id"`
+	Content    string    `gorm:"type:text;not null" json:"content"`
+	Images     string    `gorm:"type:text" json:"-"` // newline-separated image URLs
+	ImageList  []string  `gorm:"-" json:"images"`
+	Visibility string    `gorm:"size:20;not null;index" json:"visibility"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SetImages stores the image URLs of the moment
+func (m *Moment) SetImages(images []string) {
+	m.ImageList = images
+	m.Images = strings.Join(images, "\n")
+}
+
+// AfterFind splits the stored image URLs
+func (m *Moment) AfterFind(tx *gorm.DB) error {
+	m.ImageList = []string{}
+	for _, image := range strings.Split(m.Images, "\n") {
+		if image != "" {
+			m.ImageList = append(m.ImageList, image)
+		}
+	}
+	return nil
+}
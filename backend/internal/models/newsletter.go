@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// Subscriber states. New subscribers stay pending until they confirm their
+// address (double opt-in); bounced addresses are no longer mailed.
+const (
+	SubscriberPending      = "pending"
+	SubscriberActive       = "active"
+	SubscriberUnsubscribed = "unsubscribed"
+	SubscriberBounced      = "bounced"
+)
+
+// Subscriber is an email address signed up for a site's newsletter. Token
+// authorizes the confirmation and unsubscribe links sent to the address.
+type Subscriber struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SiteID         uint       `gorm:"not null;default:1;uniqueIndex:idx_subscribers_site_email,priority:1" json:"site_id"`
+	Email          string     `gorm:"size:254;not null;uniqueIndex:idx_subscribers_site_email,priority:2" json:"email"`
+	Language       string     `gorm:"size:10" json:"language"`
+	Status         string     `gorm:"size:20;not null;index" json:"status"`
+	Token          string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	BounceCount    int        `gorm:"not null;default:0" json:"bounce_count"`
+	LastBounceAt   *time.Time `json:"last_bounce_at,omitempty"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty"`
+	UnsubscribedAt *time.Time `json:"unsubscribed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Newsletter kinds and states
+const (
+	NewsletterAnnouncement = "announcement"
+	NewsletterDigest       = "digest"
+	NewsletterCustom       = "custom"
+
+	NewsletterDraft   = "draft"
+	NewsletterSending = "sending"
+	NewsletterSent    = "sent"
+)
+
+// Newsletter is an email sent to a site's active subscribers, either
+// written by hand or composed from new articles. The counters summarize
+// its deliveries.
+type Newsletter struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	SiteID     uint       `gorm:"not null;default:1;index" json:"site_id"`
+	Kind       string     `gorm:"size:20;not null" json:"kind"`
+	Subject    string     `gorm:"size:255;not null" json:"subject"`
+	Body       string     `gorm:"type:text" json:"body"`                 // plain text
+	ArticleIDs string     `gorm:"size:500" json:"article_ids,omitempty"` // comma-separated
+	Language   string     `gorm:"size:10" json:"language,omitempty"`     // only subscribers of this language when set
+	BaseURL    string     `gorm:"size:500" json:"base_url"`
+	Status     string     `gorm:"size:20;not null;index" json:"status"`
+	Recipients int        `gorm:"not null;default:0" json:"recipients"`
+	Sent       int        `gorm:"not null;default:0" json:"sent"`
+	Failed     int        `gorm:"not null;default:0" json:"failed"`
+	Bounced    int        `gorm:"not null;default:0" json:"bounced"`
+	SentAt     *time.Time `json:"sent_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Newsletter delivery states
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliveryBounced = "bounced"
+)
+
+// NewsletterDelivery is one newsletter sent to one subscriber
+type NewsletterDelivery struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	NewsletterID uint       `gorm:"not null;uniqueIndex:idx_deliveries_newsletter_subscriber,priority:1" json:"newsletter_id"`
+	SubscriberID uint       `gorm:"not null;uniqueIndex:idx_deliveries_newsletter_subscriber,priority:2" json:"subscriber_id"`
+	Email        string     `gorm:"size:254;not null" json:"email"`
+	Status       string     `gorm:"size:20;not null;index" json:"status"`
+	Error        string     `gorm:"type:text" json:"error,omitempty"`
+	SentAt       *time.Time `json:"sent_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Notification severities, from least to most urgent
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityError    = "error"
+	SeverityCritical = "critical"
+)
+
+// Notification sources, the parts of the system that raise them
+const (
+	NotificationSourceSEO      = "seo"
+	NotificationSourceAI       = "ai"
+	NotificationSourceBackup   = "backup"
+	NotificationSourceLinks    = "links"
+	NotificationSourceSecurity = "security"
+	NotificationSourceMonitor  = "monitor"
+	NotificationSourceSystem   = "system"
+)
+
+// Notification is an event shown in the admin notification center.
+// DedupeKey identifies the condition it reports, so a condition that
+// persists is reported once. DigestedAt is set once it has been emailed in
+// a digest.
+type Notification struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Source     string     `gorm:"size:20;not null;index" json:"source"`
+	Type       string     `gorm:"size:50;not null" json:"type"` // e.g. "health_alert", "backup_failed"
+	Severity   string     `gorm:"size:20;not null;default:'info';index" json:"severity"`
+	Title      string     `gorm:"size:255;not null" json:"title"`
+	Message    string     `gorm:"type:text" json:"message"`
+	ActionURL  string     `gorm:"size:500" json:"action_url,omitempty"`
+	ArticleID  *uint      `gorm:"index" json:"article_id,omitempty"`
+	KeywordID  *uint      `gorm:"index" json:"keyword_id,omitempty"`
+	DedupeKey  string     `gorm:"size:191;index" json:"-"`
+	IsRead     bool       `gorm:"not null;default:false;index" json:"is_read"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	DigestedAt *time.Time `json:"-"`
+	CreatedAt  time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
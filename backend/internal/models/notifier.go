@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Notifier kinds
+const (
+	NotifierTelegram = "telegram"
+	NotifierDiscord  = "discord"
+	NotifierSlack    = "slack"
+)
+
+// Notifier posts a card with the title, summary and cover image of an
+// article to a chat channel when the article is published or updated
+type Notifier struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	SiteID uint   `gorm:"not null;default:1;index" json:"site_id"`
+	Name   string `gorm:"size:100;not null" json:"name"`
+	Kind   string `gorm:"size:20;not null" json:"kind"`
+	// Credential is the bot token for Telegram and the webhook URL for
+	// Discord and Slack, encrypted at rest
+	Credential    string `gorm:"type:text;not null" json:"-"`
+	CredentialSet bool   `gorm:"-" json:"credential_set"`
+	ChatID        string `gorm:"size:100" json:"chat_id,omitempty"` // Telegram chat or @channel
+	// Language selects the article translation to post; the article's
+	// default language when empty or untranslated
+	Language   string     `gorm:"size:10" json:"language,omitempty"`
+	OnPublish  bool       `gorm:"not null" json:"on_publish"`
+	OnUpdate   bool       `gorm:"not null" json:"on_update"`
+	Enabled    bool       `gorm:"not null" json:"enabled"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	LastError  string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
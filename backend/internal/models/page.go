@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Navigation placements of a page
+const (
+	PageNavNone   = ""
+	PageNavHeader = "header"
+	PageNavFooter = "footer"
+)
+
+// Page is a standalone page of a site, such as About, Now or Uses. Unlike
+// articles pages have no category, date listing or feed entry; they are
+// reached by slug and optionally linked from the header or footer
+// navigation. Title, Summary and the Markdown Content are in the site's
+// default language; Translations hold them in other languages.
+type Page struct {
+	ID           uint              `gorm:"primaryKey" json:"id"`
+	SiteID       uint              `gorm:"not null;default:1;uniqueIndex:idx_pages_site_slug,priority:1" json:"site_id"`
+	Slug         string            `gorm:"size:100;not null;uniqueIndex:idx_pages_site_slug,priority:2" json:"slug"`
+	Title        string            `gorm:"size:200;not null" json:"title"`
+	Summary      string            `gorm:"size:500" json:"summary"`
+	Content      string            `gorm:"type:text" json:"content"`
+	NavPlacement string            `gorm:"size:20;index" json:"nav_placement"`
+	DisplayOrder int               `gorm:"default:0" json:"display_order"`
+	IsPublished  bool              `gorm:"not null" json:"is_published"`
+	Translations []PageTranslation `gorm:"foreignKey:PageID" json:"translations,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// PageTranslation is a page's title, summary and content in another
+// language
+type PageTranslation struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PageID    uint      `gorm:"not null;uniqueIndex:idx_page_translations_language,priority:1" json:"page_id"`
+	Language  string    `gorm:"size:10;not null;uniqueIndex:idx_page_translations_language,priority:2" json:"language"`
+	Title     string    `gorm:"size:200" json:"title"`
+	Summary   string    `gorm:"size:500" json:"summary"`
+	Content   string    `gorm:"type:text" json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Plugin is an external extension reached by webhook. It subscribes to hook
+// events; filter events are called synchronously and may modify or reject
+// the payload, action events are delivered in the background with retries.
+type Plugin struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	Name           string     `gorm:"size:100;not null;uniqueIndex" json:"name"`
+	URL            string     `gorm:"size:500;not null" json:"url"`
+	Secret         string     `gorm:"size:128" json:"-"`       // HMAC key for X-Kuno-Signature
+	Events         string     `gorm:"type:text" json:"events"` // comma-separated hook events
+	Enabled        bool       `gorm:"default:true" json:"enabled"`
+	TimeoutSeconds int        `gorm:"default:5" json:"timeout_seconds"`
+	LastCalledAt   *time.Time `json:"last_called_at,omitempty"`
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
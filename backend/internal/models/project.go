@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Project states, shown as a badge on the portfolio page
+const (
+	ProjectActive       = "active"
+	ProjectMaintained   = "maintained"
+	ProjectCompleted    = "completed"
+	ProjectArchived     = "archived"
+	ProjectDiscontinued = "discontinued"
+)
+
+// ProjectLink is a labelled link of a project, such as its repository or
+// live demo
+type ProjectLink struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// Project is an entry of a site's portfolio. Name and Description are in
+// the site's default language; Translations hold them in other languages.
+// Hidden projects (IsActive false) are only listed for admins.
+type Project struct {
+	ID           uint                 `gorm:"primaryKey" json:"id"`
+	SiteID       uint                 `gorm:"not null;default:1;index" json:"site_id"`
+	Name         string               `gorm:"size:100;not null" json:"name"`
+	Description  string               `gorm:"type:text" json:"description"`
+	CoverImage   string               `gorm:"size:500" json:"cover_image"`
+	Links        string               `gorm:"type:text" json:"-"` // JSON array of ProjectLink
+	LinkList     []ProjectLink        `gorm:"-" json:"links"`
+	TechStack    string               `gorm:"size:1000" json:"-"` // comma-separated
+	TechList     []string             `gorm:"-" json:"tech_stack"`
+	Status       string               `gorm:"size:20;not null;index" json:"status"`
+	Featured     bool                 `gorm:"not null" json:"featured"`
+	DisplayOrder int                  `gorm:"default:0" json:"display_order"`
+	IsActive     bool                 `gorm:"not null" json:"is_active"`
+	Translations []ProjectTranslation `gorm:"foreignKey:ProjectID" json:"translations,omitempty"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+}
+
+// ProjectTranslation is a project's name and description in another
+// language
+type ProjectTranslation struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ProjectID   uint      `gorm:"not null;uniqueIndex:idx_project_translations_language,priority:1" json:"project_id"`
+	Language    string    `gorm:"size:10;not null;uniqueIndex:idx_project_translations_language,priority:2" json:"language"`
+	Name        string    `gorm:"size:100" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SetLinks stores the links of the project
+func (p *Project) SetLinks(links []ProjectLink) {
+	p.LinkList = links
+	data, _ := json.Marshal(links)
+	p.Links = string(data)
+}
+
+// SetTechStack stores the technologies of the project
+func (p *Project) SetTechStack(tech []string) {
+	p.TechList = tech
+	p.TechStack = strings.Join(tech, ",")
+}
+
+// AfterFind decodes the stored links and technologies
+func (p *Project) AfterFind(tx *gorm.DB) error {
+	p.LinkList = []ProjectLink{}
+	if p.Links != "" {
+		if err := json.Unmarshal([]byte(p.Links), &p.LinkList); err != nil {
+			return err
+		}
+	}
+	p.TechList = []string{}
+	for _, tech := range strings.Split(p.TechStack, ",") {
+		if tech != "" {
+			p.TechList = append(p.TechList, tech)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// QuickLink is an entry of a site's quick links page, the "link in bio"
+// landing page shared on social profiles. Icon is the name of an icon of
+// the theme, such as "github", or the URL of an image. Clicks counts the
+// readers who opened the link.
+type QuickLink struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SiteID       uint      `gorm:"not null;default:1;index" json:"site_id"`
+	Title        string    `gorm:"size:100;not null" json:"title"`
+	URL          string    `gorm:"size:500;not null" json:"url"`
+	Icon         string    `gorm:"size:500" json:"icon"`
+	DisplayOrder int       `gorm:"default:0" json:"display_order"`
+	IsActive     bool      `gorm:"not null" json:"is_active"`
+	Clicks       int64     `gorm:"not null;default:0" json:"clicks"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// ReadingPosition is where a reader left off in an article, so they can
+// resume on another visit or device. UserID is the same reader ID the
+// behavior tracker records. Progress is the fraction of the article read
+// (0-1) and Anchor optionally names the heading or paragraph in view;
+// MaxProgress is the furthest the reader has got.
+type ReadingPosition struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	SiteID      uint      `gorm:"not null;default:1;index" json:"-"`
+	UserID      string    `gorm:"size:255;not null;uniqueIndex:idx_reading_positions_user_article,priority:1" json:"user_id"`
+	ArticleID   uint      `gorm:"not null;uniqueIndex:idx_reading_positions_user_article,priority:2;index" json:"article_id"`
+	Language    string    `gorm:"size:10" json:"language"`
+	Progress    float64   `gorm:"not null;default:0" json:"progress"`
+	MaxProgress float64   `gorm:"not null;default:0" json:"max_progress"`
+	Anchor      string    `gorm:"size:255" json:"anchor"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
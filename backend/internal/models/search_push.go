@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Search engines articles can be pushed to
+const (
+	SearchPushBaidu = "baidu"
+	SearchPushSogou = "sogou"
+	SearchPush360   = "360"
+)
+
+// Search push statuses
+const (
+	SearchPushSucceeded = "succeeded"
+	SearchPushFailed    = "failed"
+	SearchPushSkipped   = "skipped"
+)
+
+// SearchPushEngine submits the links of published articles to a search
+// engine's URL push API, so Baidu, Sogou and 360 crawl them within hours
+// instead of waiting to find them in the sitemap. Each site configures an
+// engine at most once.
+type SearchPushEngine struct {
+	ID     uint   `gorm:"primaryKey" json:"id"`
+	SiteID uint   `gorm:"not null;default:1;uniqueIndex:idx_search_push_engines_engine" json:"site_id"`
+	Engine string `gorm:"size:20;not null;uniqueIndex:idx_search_push_engines_engine" json:"engine"`
+	// Endpoint is the push API of the engine; Baidu's is used when empty
+	Endpoint string `gorm:"size:500" json:"endpoint,omitempty"`
+	// Token is the push token from the engine's webmaster tools, encrypted
+	// at rest
+	Token    string `gorm:"type:text;not null" json:"-"`
+	TokenSet bool   `gorm:"-" json:"token_set"`
+	// DailyQuota caps the links submitted per day, counted in Beijing time;
+	// 0 leaves it to the quota the engine reports
+	DailyQuota int  `gorm:"not null;default:0" json:"daily_quota"`
+	OnUpdate   bool `gorm:"not null" json:"on_update"`
+	Enabled    bool `gorm:"not null" json:"enabled"`
+	// QuotaRemaining is what the engine last said was left of the quota of
+	// QuotaDate (YYYY-MM-DD)
+	QuotaRemaining *int       `json:"quota_remaining,omitempty"`
+	QuotaDate      string     `gorm:"size:10" json:"quota_date,omitempty"`
+	SubmittedToday int        `gorm:"-" json:"submitted_today"`
+	LastPushedAt   *time.Time `json:"last_pushed_at,omitempty"`
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// SearchPushLog records one submission of an article's links to an engine.
+// Accepted is the number of links the engine took.
+type SearchPushLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SiteID    uint      `gorm:"not null;default:1;index" json:"site_id"`
+	EngineID  uint      `gorm:"not null;index" json:"engine_id"`
+	Engine    string    `gorm:"size:20;not null" json:"engine"`
+	ArticleID uint      `gorm:"index" json:"article_id"`
+	URLs      string    `gorm:"type:text" json:"urls"` // one per line
+	Submitted int       `gorm:"not null;default:0" json:"submitted"`
+	Accepted  int       `gorm:"not null;default:0" json:"accepted"`
+	Status    string    `gorm:"size:20;not null;index" json:"status"` // succeeded/failed/skipped
+	Error     string    `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
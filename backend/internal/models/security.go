@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+)
+
+// WebAuthnCredential is a passkey registered by a user
+type WebAuthnCredential struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	CredentialID string     `gorm:"uniqueIndex;not null;size:512" json:"credential_id"` // base64url
+	PublicKey    []byte     `gorm:"not null" json:"-"`                                  // COSE encoded
+	SignCount    uint32     `gorm:"default:0" json:"-"`
+	AAGUID       string     `gorm:"size:36" json:"aaguid"`
+	Transports   string     `gorm:"size:100" json:"transports"` // comma-separated hints from the browser
+	DeviceName   string     `gorm:"size:100" json:"device_name"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// LoginSession is an issued login token that can be listed and revoked
+type LoginSession struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	UserID          uint       `gorm:"not null;index" json:"user_id"`
+	SessionID       string     `gorm:"uniqueIndex;not null;size:64" json:"-"`
+	RevokeTokenHash string     `gorm:"index;size:64" json:"-"` // sha256 of the one-click revoke token
+	IPAddress       string     `gorm:"size:45" json:"ip_address"`
+	UserAgent       string     `gorm:"size:500" json:"user_agent"`
+	Fingerprint     string     `gorm:"size:64;index" json:"-"`
+	Browser         string     `gorm:"size:50" json:"browser"`
+	OS              string     `gorm:"size:50" json:"os"`
+	Location        string     `gorm:"size:100" json:"location"`
+	LoginMethod     string     `gorm:"size:20" json:"login_method"` // password, passkey
+	NewDevice       bool       `gorm:"default:false" json:"new_device"`
+	NewIP           bool       `gorm:"default:false" json:"new_ip"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// KnownLoginDevice records device fingerprints and IPs a user has logged in from
+type KnownLoginDevice struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"user_id"`
+	Fingerprint string    `gorm:"size:64;index" json:"fingerprint"`
+	IPAddress   string    `gorm:"size:45;index" json:"ip_address"`
+	UserAgent   string    `gorm:"size:500" json:"user_agent"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// Outcomes of a change to the custom code of the site settings
+const (
+	CustomCodeSaved    = "saved"
+	CustomCodeRejected = "rejected"
+)
+
+// CustomCodeAudit records a change to the custom CSS or JavaScript of the
+// site settings, saved or refused, with who made it and what the security
+// scan found. Content is the submitted code and Findings a JSON array of
+// the scan's findings.
+type CustomCodeAudit struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SiteID       uint      `gorm:"not null;default:1;index" json:"site_id"`
+	UserID       uint      `gorm:"index" json:"user_id"`
+	IPAddress    string    `gorm:"size:45" json:"ip_address"`
+	UserAgent    string    `gorm:"size:500" json:"user_agent"`
+	Field        string    `gorm:"size:10;not null" json:"field"` // css, js
+	Outcome      string    `gorm:"size:20;not null;index" json:"outcome"`
+	PreviousHash string    `gorm:"size:64" json:"previous_hash"` // sha256 of the code it replaced
+	ContentHash  string    `gorm:"size:64" json:"content_hash"`
+	Content      string    `gorm:"type:text" json:"content"`
+	Findings     string    `gorm:"type:text" json:"findings"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
@@ -7,21 +7,25 @@ import (
 
 // SEOKeyword represents a tracked keyword for SEO monitoring
 type SEOKeyword struct {
-	ID             uint           `gorm:"primaryKey" json:"id"`
-	ArticleID      *uint          `gorm:"index" json:"article_id,omitempty"` // 可选，全站关键词跟踪
-	Keyword        string         `gorm:"not null;index" json:"keyword"`
-	Language       string         `gorm:"size:10;not null;index" json:"language"`
-	TargetURL      string         `gorm:"size:500" json:"target_url"`                      // 目标页面
-	CurrentRank    int            `gorm:"default:0" json:"current_rank"`                   // 当前排名
-	BestRank       int            `gorm:"default:0" json:"best_rank"`                      // 历史最佳排名
-	SearchVolume   int            `gorm:"default:0" json:"search_volume"`                  // 搜索量估值
-	Difficulty     string         `gorm:"size:20;default:'medium'" json:"difficulty"`      // easy/medium/hard
-	TrackingStatus string         `gorm:"size:20;default:'active'" json:"tracking_status"` // active/paused
-	Notes          string         `gorm:"type:text" json:"notes"`                          // 备注
-	Tags           string         `gorm:"size:500" json:"tags"`                            // 标签，逗号分隔
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	ArticleID       *uint          `gorm:"index" json:"article_id,omitempty"` // 可选，全站关键词跟踪
+	Keyword         string         `gorm:"not null;index" json:"keyword"`
+	Language        string         `gorm:"size:10;not null;index" json:"language"`
+	TargetURL       string         `gorm:"size:500" json:"target_url"`                      // 目标页面
+	CurrentRank     int            `gorm:"default:0" json:"current_rank"`                   // 当前排名
+	BestRank        int            `gorm:"default:0" json:"best_rank"`                      // 历史最佳排名
+	SearchVolume    int            `gorm:"default:0" json:"search_volume"`                  // 搜索量估值
+	Difficulty      string         `gorm:"size:20;default:'medium'" json:"difficulty"`      // easy/medium/hard
+	DifficultyScore int            `gorm:"default:0" json:"difficulty_score"`               // 难度评分 0-100
+	DataSource      string         `gorm:"size:30" json:"data_source"`                      // 搜索量与难度的数据来源
+	DataUpdatedAt   *time.Time     `gorm:"index" json:"data_updated_at"`                    // 数据更新时间，null表示仅为估值
+	DataStale       bool           `gorm:"-" json:"data_stale"`                             // 数据是否过期
+	TrackingStatus  string         `gorm:"size:20;default:'active'" json:"tracking_status"` // active/paused
+	Notes           string         `gorm:"type:text" json:"notes"`                          // 备注
+	Tags            string         `gorm:"size:500" json:"tags"`                            // 标签，逗号分隔
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// Foreign key relationships
 	Article *Article `gorm:"foreignKey:ArticleID" json:"article,omitempty"`
@@ -119,7 +123,8 @@ type SEOAutomationRule struct {
 	DeletedAt            gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
-// SEONotification represents SEO-related notifications
+// SEONotification represents SEO-related notifications. Superseded by
+// Notification; the table is kept for the baseline migration.
 type SEONotification struct {
 	ID         uint           `gorm:"primaryKey" json:"id"`
 	Type       string         `gorm:"not null;size:50" json:"type"`           // "health_alert", "ranking_change", "keyword_opportunity"
@@ -161,6 +166,27 @@ type SEOTemplate struct {
 	Category *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
 }
 
+// SEOMetadataProposal is SEO metadata the AI provider wrote, in a bulk
+// generation job, for an article that lacked it. Only the fields the
+// article was missing are set. Pending proposals wait for an admin to
+// apply or dismiss them.
+type SEOMetadataProposal struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	SiteID         uint       `gorm:"not null;default:1;index" json:"site_id"`
+	ArticleID      uint       `gorm:"not null;index" json:"article_id"`
+	JobID          uint       `gorm:"index" json:"job_id"`
+	Language       string     `gorm:"size:10" json:"language"`
+	SEOTitle       string     `gorm:"size:255" json:"seo_title"`
+	SEODescription string     `gorm:"size:500" json:"seo_description"`
+	SEOSlug        string     `gorm:"size:255" json:"seo_slug"`
+	Status         string     `gorm:"size:20;default:'pending';index" json:"status"` // pending/applied/dismissed
+	DecidedAt      *time.Time `json:"decided_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Foreign key relationships
+	Article *Article `gorm:"foreignKey:ArticleID" json:"article,omitempty"`
+}
+
 // Helper structs for API responses
 
 // SEODashboardData represents comprehensive SEO dashboard data
@@ -220,6 +246,7 @@ type SEOAnalysisResult struct {
 	ReadabilityAnalysis ReadabilityAnalysis `json:"readability_analysis"`
 	TechnicalAnalysis   TechnicalAnalysis   `json:"technical_analysis"`
 	Suggestions         []string            `json:"suggestions"`
+	Language            string              `json:"language"`
 	CreatedAt           time.Time           `json:"created_at"`
 }
 
@@ -277,6 +304,7 @@ type ReadabilityAnalysis struct {
 	AvgParagraphLength        float64  `json:"avg_paragraph_length"`
 	PassiveVoicePercentage    float64  `json:"passive_voice_percentage"`
 	TransitionWordsPercentage float64  `json:"transition_words_percentage"`
+	Direction                 string   `json:"direction"`
 	Issues                    []string `json:"issues"`
 	Suggestions               []string `json:"suggestions"`
 }
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultSiteID is the site that owns content created before multi-site mode
+// was enabled and serves requests whose host matches no other site
+const DefaultSiteID = 1
+
+// Site is one blog served by this instance in multi-site mode. Requests are
+// matched to a site by their Host header; articles, categories, settings,
+// media, social links and users belong to exactly one site.
+type Site struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	Slug      string    `gorm:"size:50;not null;uniqueIndex" json:"slug"` // media directory under uploads/sites
+	Hosts     string    `gorm:"type:text" json:"hosts"`                   // comma-separated host names
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// HostList returns the site's host names, lowercased and without ports
+func (s *Site) HostList() []string {
+	var hosts []string
+	for _, host := range strings.Split(s.Hosts, ",") {
+		if host = NormalizeHost(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// NormalizeHost lowercases a host name and strips any port
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if i := strings.LastIndex(host, ":"); i != -1 && !strings.HasSuffix(host, "]") {
+		host = host[:i]
+	}
+	return strings.Trim(host, "[]")
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+)
+
+// SchemaMigration records a versioned migration applied to the database
+type SchemaMigration struct {
+	ID          string    `gorm:"primaryKey;size:191" json:"id"`
+	Description string    `gorm:"size:255" json:"description"`
+	AppliedAt   time.Time `json:"applied_at"`
+}
+
+// Job states in the background job queue. Jobs that fail MaxAttempts times
+// are moved to JobDead, the dead-letter state, and only run again when
+// retried by an admin.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+	JobCancelled = "cancelled"
+)
+
+// Job is a unit of work in the persistent background job queue. UniqueKey,
+// when set, allows only one job with that key, so several instances can
+// queue the same scheduled run without it running twice. Running jobs
+// report their progress in Progress and Step, and the items that failed
+// without failing the job in ItemErrors. All three are cleared when an
+// attempt starts.
+type Job struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Type        string     `gorm:"size:100;not null;index" json:"type"`
+	UniqueKey   *string    `gorm:"size:191;uniqueIndex" json:"unique_key,omitempty"`
+	Payload     string     `gorm:"type:text" json:"payload,omitempty"` // JSON
+	Status      string     `gorm:"size:20;not null;index:idx_jobs_status_run_at,priority:1" json:"status"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"not null;default:3" json:"max_attempts"`
+	RunAt       time.Time  `gorm:"index:idx_jobs_status_run_at,priority:2" json:"run_at"`
+	LockedBy    string     `gorm:"size:100" json:"locked_by,omitempty"`
+	LockedAt    *time.Time `json:"locked_at,omitempty"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	Result      string     `gorm:"type:text" json:"result,omitempty"`  // JSON
+	Progress    int        `gorm:"not null;default:0" json:"progress"` // percent
+	Step        string     `gorm:"size:255" json:"step,omitempty"`
+	ItemErrors  string     `gorm:"type:text" json:"item_errors,omitempty"` // JSON
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// SystemSetting is an instance-wide value shared by every server process
+// and the admin CLI, such as the maintenance mode switch
+type SystemSetting struct {
+	Key       string    `gorm:"primaryKey;size:100" json:"key"`
+	Value     string    `gorm:"type:text" json:"value"` // JSON
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StorageSnapshot records the size of the database and uploads at one point
+// in time, taken daily to show how fast the data grows
+type StorageSnapshot struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	DatabaseBytes int64     `json:"database_bytes"`
+	UploadBytes   int64     `json:"upload_bytes"`
+	UploadFiles   int64     `json:"upload_files"`
+	Rows          int64     `json:"rows"`
+	VolumeFree    int64     `json:"volume_free"`
+	CreatedAt     time.Time `gorm:"index" json:"created_at"`
+}
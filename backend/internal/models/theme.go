@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Theme is a theme package installed on a site: its metadata, default
+// configuration, stylesheet and assets. Activating a theme copies its
+// configuration and stylesheet into the site settings (ThemeConfig and
+// CustomCSS), where they can be adjusted without changing the package.
+type Theme struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	SiteID      uint         `gorm:"not null;default:1;uniqueIndex:idx_themes_site_name,priority:1" json:"site_id"`
+	Name        string       `gorm:"size:64;not null;uniqueIndex:idx_themes_site_name,priority:2" json:"name"`
+	Version     string       `gorm:"size:32" json:"version"`
+	Title       string       `gorm:"size:100;not null" json:"title"`
+	Description string       `gorm:"type:text" json:"description"`
+	Author      string       `gorm:"size:100" json:"author"`
+	Homepage    string       `gorm:"size:500" json:"homepage"`
+	License     string       `gorm:"size:50" json:"license"`
+	Preview     string       `gorm:"size:255" json:"-"` // asset path of the preview image
+	PreviewURL  string       `gorm:"-" json:"preview_url,omitempty"`
+	Config      string       `gorm:"type:text" json:"config"` // JSON object
+	CSS         string       `gorm:"type:text" json:"css"`
+	Assets      []ThemeAsset `gorm:"foreignKey:ThemeID" json:"assets,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// ThemeAsset is an image or font shipped in a theme package, served at
+// AssetURL
+type ThemeAsset struct {
+	ID          uint   `gorm:"primaryKey" json:"-"`
+	ThemeID     uint   `gorm:"not null;uniqueIndex:idx_theme_assets_path,priority:1" json:"-"`
+	Path        string `gorm:"size:255;not null;uniqueIndex:idx_theme_assets_path,priority:2" json:"path"`
+	ContentType string `gorm:"size:100" json:"content_type"`
+	Size        int64  `json:"size"`
+	Data        []byte `json:"-"`
+}
+
+// AssetURL is where the asset of a theme at path is served
+func (t *Theme) AssetURL(path string) string {
+	return "/api/themes/" + t.Name + "/assets/" + path
+}
+
+// AfterFind sets the address of the preview image
+func (t *Theme) AfterFind(tx *gorm.DB) error {
+	if t.Preview != "" {
+		t.PreviewURL = t.AssetURL(t.Preview)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Translation kinds reported by the translation status
+const (
+	TranslationArticle  = "article"
+	TranslationCategory = "category"
+	TranslationSettings = "settings"
+)
+
+// SourceHash returns the hash of the article text translations are made
+// from; a translation stamped with another hash is out of date
+func (a *Article) SourceHash() string {
+	return sourceHash(a.Title, a.Summary, a.Content)
+}
+
+// ArticleSource is the hashes of the parts of an article a translation is
+// made from: the title, the summary and each block of the content
+type ArticleSource struct {
+	Title   string   `json:"title"`
+	Summary string   `json:"summary"`
+	Content []string `json:"content"`
+}
+
+// Source returns the hashes of the article's title, summary and content
+// blocks
+func (a *Article) Source() ArticleSource {
+	blocks := ContentBlocks(a.Content)
+	source := ArticleSource{Title: sourceHash(a.Title), Summary: sourceHash(a.Summary), Content: make([]string, len(blocks))}
+	for i, block := range blocks {
+		source.Content[i] = sourceHash(block)
+	}
+	return source
+}
+
+// StampSource records the article text a translation is made from
+func (t *ArticleTranslation) StampSource(article *Article) {
+	t.SourceHash = article.SourceHash()
+	data, _ := json.Marshal(article.Source())
+	t.SourceSections = string(data)
+}
+
+// TranslatedSource returns what StampSource recorded, and false for a
+// translation stamped before sections were kept
+func (t *ArticleTranslation) TranslatedSource() (ArticleSource, bool) {
+	var source ArticleSource
+	if t.SourceSections == "" || json.Unmarshal([]byte(t.SourceSections), &source) != nil {
+		return ArticleSource{}, false
+	}
+	return source, true
+}
+
+// ContentBlocks splits content into its paragraphs, headings, lists and
+// other blocks at blank lines, keeping fenced code blocks whole. Joining
+// the blocks with blank lines gives the content back up to spacing.
+func ContentBlocks(content string) []string {
+	var blocks, block []string
+	fence := ""
+	flush := func() {
+		if len(block) > 0 {
+			blocks = append(blocks, strings.Join(block, "\n"))
+			block = nil
+		}
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"):
+			fence = "```"
+		case strings.HasPrefix(trimmed, "~~~"):
+			fence = "~~~"
+		case trimmed == "":
+			flush()
+			continue
+		}
+		block = append(block, line)
+	}
+	flush()
+	return blocks
+}
+
+// SourceHash returns the hash of the category text translations are made
+// from
+func (c *Category) SourceHash() string {
+	return sourceHash(c.Name, c.Description)
+}
+
+// SourceHash returns the hash of the settings text translations are made
+// from
+func (s *SiteSettings) SourceHash() string {
+	return sourceHash(s.SiteTitle, s.SiteSubtitle)
+}
+
+func sourceHash(parts ...string) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// TranslationMemory is a segment of text, such as a paragraph or a title,
+// and its translation into another language. Segments translated again,
+// in the same article or another, reuse it instead of a paid translation.
+type TranslationMemory struct {
+	ID             uint   `gorm:"primaryKey" json:"id"`
+	SiteID         uint   `gorm:"not null;default:1;uniqueIndex:idx_translation_memory_source,priority:1" json:"site_id"`
+	SourceLanguage string `gorm:"size:10;not null;uniqueIndex:idx_translation_memory_source,priority:2" json:"source_language"`
+	TargetLanguage string `gorm:"size:10;not null;uniqueIndex:idx_translation_memory_source,priority:3" json:"target_language"`
+	// SourceHash identifies the source with its spacing normalized, and
+	// SourceLength, in characters, narrows the search for similar sources
+	SourceHash   string    `gorm:"size:64;not null;uniqueIndex:idx_translation_memory_source,priority:4" json:"-"`
+	SourceLength int       `gorm:"not null;index" json:"-"`
+	Source       string    `gorm:"type:text;not null" json:"source"`
+	Target       string    `gorm:"type:text;not null" json:"target"`
+	Uses         int       `gorm:"not null;default:0" json:"uses"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
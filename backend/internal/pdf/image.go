@@ -0,0 +1,93 @@
+package pdf
+
+import (
+	"blog-backend/internal/imaging"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+const (
+	// maxImagePixels bounds the images decoded, so a small file cannot
+	// expand into gigabytes
+	maxImagePixels = 40 << 20
+	// maxImageSide is the longest side images are stored at when they are
+	// re-encoded; more is wasted at the size pages show them
+	maxImageSide = 2000
+)
+
+var ErrUnsupportedImage = errors.New("unsupported image")
+
+// Image is an image added to a document, Width by Height pixels
+type Image struct {
+	id            int
+	Width, Height int
+	colorSpace    string
+	filter        string
+	data          []byte
+}
+
+// AddImage adds a JPEG, PNG or GIF image to the document. JPEG files are
+// stored as they are; other images are flattened onto white, as PDF
+// images have no alpha channel here, and compressed.
+func (d *Document) AddImage(data []byte) (*Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrUnsupportedImage, config.Width, config.Height)
+	}
+	img := &Image{id: len(d.images), Width: config.Width, Height: config.Height}
+	if format == "jpeg" && (config.ColorModel == color.YCbCrModel || config.ColorModel == color.GrayModel) {
+		img.colorSpace, img.filter, img.data = "DeviceRGB", "DCTDecode", data
+		if config.ColorModel == color.GrayModel {
+			img.colorSpace = "DeviceGray"
+		}
+		d.images = append(d.images, img)
+		return img, nil
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedImage, err)
+	}
+	if side := max(config.Width, config.Height); side > maxImageSide {
+		decoded = imaging.Resize(decoded, max(config.Width*maxImageSide/side, 1), max(config.Height*maxImageSide/side, 1))
+	}
+	if format == "jpeg" {
+		// CMYK photos are converted to RGB once and stored as JPEG again
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, decoded, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, err
+		}
+		return d.AddImage(buf.Bytes())
+	}
+
+	bounds := decoded.Bounds()
+	img.Width, img.Height = bounds.Dx(), bounds.Dy()
+	pixels := make([]byte, 0, img.Width*img.Height*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := decoded.At(x, y).RGBA()
+			// Premultiplied channels over white
+			white := 0xFFFF - a
+			pixels = append(pixels, byte((r+white)>>8), byte((g+white)>>8), byte((b+white)>>8))
+		}
+	}
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(pixels)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	img.colorSpace, img.filter, img.data = "DeviceRGB", "FlateDecode", buf.Bytes()
+	d.images = append(d.images, img)
+	return img, nil
+}
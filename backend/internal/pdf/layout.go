@@ -0,0 +1,384 @@
+package pdf
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Span is a run of text in one style, linking to Link when set
+type Span struct {
+	Text  string
+	Font  Font
+	Size  float64
+	Color Color
+	Link  string
+}
+
+// Block styles a paragraph laid out by Layout.Paragraph
+type Block struct {
+	// Indent moves the paragraph right of the left margin
+	Indent float64
+	// LineHeight is the height of a line as a multiple of its largest
+	// text; 1.4 when zero
+	LineHeight float64
+	// Before and After are the space above and below the paragraph
+	Before, After float64
+	// Keep is the room needed below the first line to start the paragraph
+	// on the current page, so headings stay with what follows them
+	Keep float64
+	// Marker, such as a list bullet, is drawn left of the first line
+	Marker *Span
+	// Background fills the paragraph's box, Padding wide around the text
+	Background *Color
+	Padding    float64
+	// Bar draws a line left of the text, as for quotes
+	Bar *Color
+	// Pre keeps spaces and line breaks and breaks long lines anywhere
+	Pre bool
+}
+
+// barSpace is the room a Bar takes left of the text
+const barSpace = 12
+
+// Layout flows paragraphs, images and tables down the pages of a document,
+// adding pages as they fill up
+type Layout struct {
+	doc    *Document
+	page   *Page
+	margin float64
+	y      float64
+}
+
+// NewLayout creates a layout for doc with margin points around each page
+func NewLayout(doc *Document, margin float64) *Layout {
+	return &Layout{doc: doc, margin: margin}
+}
+
+// Width returns the width between the margins
+func (l *Layout) Width() float64 {
+	return l.doc.width - 2*l.margin
+}
+
+// Space adds vertical space, unless at the top of a page
+func (l *Layout) Space(height float64) {
+	if l.page != nil && l.y > l.margin {
+		l.y += height
+	}
+}
+
+// ensure starts a new page unless height fits on the current one
+func (l *Layout) ensure(height float64) {
+	if l.page == nil || (l.y+height > l.doc.height-l.margin && l.y > l.margin) {
+		l.page = l.doc.AddPage()
+		l.y = l.margin
+	}
+}
+
+// Paragraph lays out spans as a paragraph, breaking lines at spaces and
+// between CJK characters
+func (l *Layout) Paragraph(spans []Span, block Block) {
+	lineHeight := block.LineHeight
+	if lineHeight == 0 {
+		lineHeight = 1.4
+	}
+	left := l.margin + block.Indent + block.Padding
+	if block.Bar != nil {
+		left += barSpace
+	}
+	width := l.doc.width - l.margin - block.Padding - left
+	lines := wrap(spans, width, block.Pre)
+	if len(lines) == 0 {
+		return
+	}
+
+	l.Space(block.Before)
+	for i, line := range lines {
+		size := line.size
+		if size == 0 && len(spans) > 0 {
+			size = spans[0].Size
+		}
+		height := size * lineHeight
+		top, bottom := 0.0, 0.0
+		if i == 0 {
+			top = block.Padding
+		}
+		if i == len(lines)-1 {
+			bottom = block.Padding
+		}
+		needed := top + height + bottom
+		if i == 0 {
+			needed += block.Keep
+		}
+		if l.page == nil || l.y+needed > l.doc.height-l.margin {
+			l.ensure(needed)
+			if i > 0 {
+				top = block.Padding
+			}
+		}
+
+		boxLeft := l.margin + block.Indent
+		if block.Background != nil {
+			l.page.Rect(boxLeft, l.y, l.doc.width-l.margin-boxLeft, top+height+bottom, *block.Background)
+		}
+		if block.Bar != nil {
+			l.page.Rect(boxLeft, l.y, 3, top+height+bottom, *block.Bar)
+		}
+		l.y += top
+		baseline := l.y + (height-size)/2 + size*0.8
+		if i == 0 && block.Marker != nil {
+			marker := block.Marker
+			l.page.Text(left-Width(marker.Font, marker.Size, marker.Text)-6, baseline, marker.Font, marker.Size, marker.Color, marker.Text)
+		}
+		x := left
+		for _, piece := range line.pieces {
+			span := piece.span
+			l.page.Text(x, baseline, span.Font, span.Size, span.Color, piece.text)
+			if span.Link != "" {
+				l.page.Link(x, l.y, piece.width, height, span.Link)
+			}
+			x += piece.width
+		}
+		l.y += height + bottom
+	}
+	l.y += block.After
+}
+
+// Image draws an image centered, at most width wide and no taller than a
+// page; images are shown at 96 pixels per inch unless that is larger
+func (l *Layout) Image(img *Image, width, after float64) {
+	width = min(width, l.Width(), float64(img.Width)*0.75)
+	height := width * float64(img.Height) / float64(img.Width)
+	if maxHeight := l.doc.height - 2*l.margin; height > maxHeight {
+		width, height = width*maxHeight/height, maxHeight
+	}
+	l.ensure(height)
+	l.page.Image(img, l.margin+(l.Width()-width)/2, l.y, width, height)
+	l.y += height + after
+}
+
+// Rule draws a horizontal line across the page
+func (l *Layout) Rule(color Color, before, after float64) {
+	l.Space(before)
+	l.ensure(1)
+	l.page.Line(l.margin, l.y, l.doc.width-l.margin, l.y, 0.5, color)
+	l.y += after
+}
+
+// Table lays out rows of cells in equal columns with borders; the first
+// row is shaded as a header when header is set. Rows are kept whole.
+func (l *Layout) Table(rows [][][]Span, header bool, border Color, shade Color, after float64) {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return
+	}
+	const padding = 4
+	columnWidth := l.Width() / float64(columns)
+	for r, row := range rows {
+		cells := make([][]line, columns)
+		height := 0.0
+		for c := range cells {
+			if c < len(row) {
+				cells[c] = wrap(row[c], columnWidth-2*padding, false)
+			}
+			cellHeight := 0.0
+			for _, line := range cells[c] {
+				cellHeight += line.size * 1.3
+			}
+			height = max(height, cellHeight)
+		}
+		height += 2 * padding
+		l.ensure(height)
+		for c, lines := range cells {
+			x := l.margin + float64(c)*columnWidth
+			if header && r == 0 {
+				l.page.Rect(x, l.y, columnWidth, height, shade)
+			}
+			l.page.StrokeRect(x, l.y, columnWidth, height, 0.5, border)
+			y := l.y + padding
+			for _, line := range lines {
+				lineX := x + padding
+				for _, piece := range line.pieces {
+					l.page.Text(lineX, y+line.size*1.05, piece.span.Font, piece.span.Size, piece.span.Color, piece.text)
+					lineX += piece.width
+				}
+				y += line.size * 1.3
+			}
+		}
+		l.y += height
+	}
+	l.y += after
+}
+
+// Footer draws text centered below the bottom margin of every page, given
+// the page number and count
+func (l *Layout) Footer(text func(page, pages int) string, font Font, size float64, color Color) {
+	pages := l.doc.Pages()
+	for i, page := range pages {
+		s := text(i+1, len(pages))
+		page.Text((l.doc.width-Width(font, size, s))/2, l.doc.height-l.margin/2, font, size, color, s)
+	}
+}
+
+// line is a laid out line of text; size is its largest text size
+type line struct {
+	pieces []piece
+	size   float64
+}
+
+// piece is text of one span placed on a line
+type piece struct {
+	span  *Span
+	text  string
+	width float64
+	space bool
+}
+
+// wrap breaks spans into lines at most width wide
+func wrap(spans []Span, width float64, pre bool) []line {
+	var lines []line
+	var current line
+	used := 0.0
+	flush := func() {
+		for len(current.pieces) > 0 && current.pieces[len(current.pieces)-1].space && !pre {
+			current.pieces = current.pieces[:len(current.pieces)-1]
+		}
+		lines = append(lines, merge(current))
+		current, used = line{}, 0
+	}
+	add := func(p piece) {
+		if p.space && len(current.pieces) == 0 && !pre {
+			return
+		}
+		if used+p.width > width && len(current.pieces) > 0 {
+			flush()
+			if p.space && !pre {
+				return
+			}
+		}
+		// Words wider than a line are broken between characters
+		for p.width > width && !p.space {
+			head, tail := splitAt(p, width)
+			if tail.text == "" {
+				break
+			}
+			current.pieces = append(current.pieces, head)
+			current.size = max(current.size, head.span.Size)
+			flush()
+			p = tail
+		}
+		current.pieces = append(current.pieces, p)
+		current.size = max(current.size, p.span.Size)
+		used += p.width
+	}
+
+	for i := range spans {
+		span := &spans[i]
+		for _, token := range tokenize(span.Text) {
+			if token == "\n" {
+				current.size = max(current.size, span.Size)
+				flush()
+				continue
+			}
+			space := strings.TrimSpace(token) == ""
+			if space && !pre {
+				token = " "
+			}
+			add(piece{span: span, text: token, width: Width(span.Font, span.Size, token), space: space})
+		}
+	}
+	if len(current.pieces) > 0 || pre {
+		flush()
+	}
+	return lines
+}
+
+// splitAt splits a piece into the most characters that fit in width, at
+// least one, and the rest
+func splitAt(p piece, width float64) (piece, piece) {
+	used := 0.0
+	runes := []rune(p.text)
+	n := 0
+	for n < len(runes) {
+		w := Width(p.span.Font, p.span.Size, string(runes[n]))
+		if used+w > width && n > 0 {
+			break
+		}
+		used += w
+		n++
+	}
+	if n == len(runes) {
+		return p, piece{span: p.span}
+	}
+	head := piece{span: p.span, text: string(runes[:n]), width: used}
+	rest := string(runes[n:])
+	return head, piece{span: p.span, text: rest, width: Width(p.span.Font, p.span.Size, rest)}
+}
+
+// merge joins the neighboring pieces of a line that share a span, so they
+// are drawn as one
+func merge(l line) line {
+	var merged []piece
+	for _, p := range l.pieces {
+		if n := len(merged); n > 0 && merged[n-1].span == p.span {
+			merged[n-1].text += p.text
+			merged[n-1].width += p.width
+			continue
+		}
+		merged = append(merged, p)
+	}
+	l.pieces = merged
+	return l
+}
+
+// tokenize splits text into words, runs of spaces, line breaks and single
+// CJK characters, the places lines may break. Tabs are four spaces.
+func tokenize(text string) []string {
+	var tokens []string
+	var current strings.Builder
+	currentSpace := false
+	emit := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+	for _, c := range text {
+		switch {
+		case c == '\n':
+			emit()
+			currentSpace = false
+			tokens = append(tokens, "\n")
+		case c == '\t' || unicode.IsSpace(c):
+			if !currentSpace {
+				emit()
+			}
+			currentSpace = true
+			if c == '\t' {
+				current.WriteString("    ")
+			} else {
+				current.WriteRune(' ')
+			}
+		case isCJK(c):
+			emit()
+			currentSpace = false
+			tokens = append(tokens, string(c))
+		default:
+			if currentSpace {
+				emit()
+			}
+			currentSpace = false
+			current.WriteRune(c)
+		}
+	}
+	emit()
+	return tokens
+}
+
+// isCJK reports whether lines may break before and after a character
+func isCJK(c rune) bool {
+	return unicode.In(c, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(c >= 0x3000 && c <= 0x303F) || (c >= 0xFF00 && c <= 0xFFEF)
+}
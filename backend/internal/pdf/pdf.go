@@ -0,0 +1,485 @@
+// Package pdf writes PDF documents with the standard fonts and JPEG, PNG
+// or GIF images, enough to lay out articles without external tools. Text
+// the standard fonts cannot show, such as Chinese, is set in the CJK fonts
+// PDF readers provide, so no font is embedded.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 page size in points
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Color is an RGB color
+type Color struct {
+	R, G, B uint8
+}
+
+// Black is the default text color
+var Black = Color{}
+
+// Font is one of the standard fonts
+type Font int
+
+const (
+	Sans Font = iota
+	SansBold
+	SansItalic
+	Mono
+	MonoBold
+	numFonts
+)
+
+var fontNames = [numFonts]string{"Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Courier", "Courier-Bold"}
+
+// cjkFont is one of the Adobe CJK fonts readers provide, with the CMap
+// that maps UCS-2 to its glyphs
+type cjkFont struct {
+	name, cmap, ordering string
+	supplement           int
+}
+
+var (
+	simplifiedChinese  = cjkFont{"STSong-Light", "UniGB-UCS2-H", "GB1", 2}
+	traditionalChinese = cjkFont{"MSung-Light", "UniCNS-UCS2-H", "CNS1", 0}
+	japanese           = cjkFont{"HeiseiMin-W3", "UniJIS-UCS2-H", "Japan1", 2}
+	korean             = cjkFont{"HYSMyeongJo-Medium", "UniKS-UCS2-H", "Korea1", 1}
+)
+
+// cjkFontFor picks the CJK font for a language; Simplified Chinese covers
+// the most scripts, so it is the default
+func cjkFontFor(language string) cjkFont {
+	language = strings.ToLower(language)
+	switch {
+	case strings.HasPrefix(language, "ja"):
+		return japanese
+	case strings.HasPrefix(language, "ko"):
+		return korean
+	case strings.HasPrefix(language, "zh-tw"), strings.HasPrefix(language, "zh-hk"), strings.HasPrefix(language, "zh-hant"):
+		return traditionalChinese
+	default:
+		return simplifiedChinese
+	}
+}
+
+// Document is a PDF document being built. Pages share one set of
+// resources, holding the fonts used and every image added.
+type Document struct {
+	width, height float64
+	language      string
+	title, author string
+	cjk           cjkFont
+	pages         []*Page
+	images        []*Image
+	fontUsed      [numFonts]bool
+	cjkUsed       bool
+}
+
+// New creates a document with pages of width by height points, in
+// language, which picks the font for CJK text
+func New(width, height float64, language string) *Document {
+	return &Document{width: width, height: height, language: language, cjk: cjkFontFor(language)}
+}
+
+// SetInfo sets the title and author readers show
+func (d *Document) SetInfo(title, author string) {
+	d.title, d.author = title, author
+}
+
+// Size returns the page size
+func (d *Document) Size() (width, height float64) {
+	return d.width, d.height
+}
+
+// AddPage appends a page
+func (d *Document) AddPage() *Page {
+	page := &Page{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the pages added so far
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// Page is a page of a document. Coordinates are in points from the top
+// left corner.
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+	links   []link
+}
+
+type link struct {
+	x, y, width, height float64
+	uri                 string
+}
+
+// Text draws s with its baseline at y. Runs of characters the standard
+// fonts lack are set in the document's CJK font.
+func (p *Page) Text(x, y float64, font Font, size float64, color Color, s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(&p.content, "BT %s rg 1 0 0 1 %s %s Tm", rgb(color), num(x), num(p.doc.height-y))
+	for _, r := range splitRuns(s) {
+		if r.cjk {
+			p.doc.cjkUsed = true
+			fmt.Fprintf(&p.content, " /C0 %s Tf <", num(size))
+			for _, c := range r.text {
+				fmt.Fprintf(&p.content, "%04X", c)
+			}
+			p.content.WriteString("> Tj")
+			continue
+		}
+		p.doc.fontUsed[font] = true
+		fmt.Fprintf(&p.content, " /F%d %s Tf (", font, num(size))
+		for _, c := range r.text {
+			b, _ := winAnsi(c)
+			switch {
+			case b == '(' || b == ')' || b == '\\':
+				p.content.WriteByte('\\')
+				p.content.WriteByte(b)
+			case b < 32 || b > 126:
+				fmt.Fprintf(&p.content, "\\%03o", b)
+			default:
+				p.content.WriteByte(b)
+			}
+		}
+		p.content.WriteString(") Tj")
+	}
+	p.content.WriteString(" ET\n")
+}
+
+// Rect fills a rectangle whose top left corner is at x, y
+func (p *Page) Rect(x, y, width, height float64, color Color) {
+	fmt.Fprintf(&p.content, "%s rg %s %s %s %s re f\n", rgb(color), num(x), num(p.doc.height-y-height), num(width), num(height))
+}
+
+// StrokeRect outlines a rectangle whose top left corner is at x, y
+func (p *Page) StrokeRect(x, y, width, height, lineWidth float64, color Color) {
+	fmt.Fprintf(&p.content, "%s RG %s w %s %s %s %s re S\n", rgb(color), num(lineWidth), num(x), num(p.doc.height-y-height), num(width), num(height))
+}
+
+// Line draws a line from x1, y1 to x2, y2
+func (p *Page) Line(x1, y1, x2, y2, lineWidth float64, color Color) {
+	fmt.Fprintf(&p.content, "%s RG %s w %s %s m %s %s l S\n", rgb(color), num(lineWidth), num(x1), num(p.doc.height-y1), num(x2), num(p.doc.height-y2))
+}
+
+// Image draws an image scaled to width by height, its top left corner at
+// x, y
+func (p *Page) Image(img *Image, x, y, width, height float64) {
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num(width), num(height), num(x), num(p.doc.height-y-height), img.id)
+}
+
+// Link makes a rectangle open uri when clicked
+func (p *Page) Link(x, y, width, height float64, uri string) {
+	p.links = append(p.links, link{x, y, width, height, uri})
+}
+
+// Width returns the width of s in points, as Text draws it
+func Width(font Font, size float64, s string) float64 {
+	var units int
+	for _, c := range s {
+		units += runeWidth(font, c)
+	}
+	return float64(units) * size / 1000
+}
+
+func runeWidth(font Font, c rune) int {
+	b, ok := winAnsi(printable(c))
+	if !ok {
+		return 1000
+	}
+	switch {
+	case font == Mono || font == MonoBold:
+		return 600
+	case b >= 32 && b <= 126:
+		if font == SansBold {
+			return helveticaBoldWidths[b-32]
+		}
+		return helveticaWidths[b-32]
+	case b == 0x85 || b == 0x97 || b == 0x89:
+		return 1000
+	case b == 0x91 || b == 0x92 || b == 0x82:
+		return 222
+	case b == 0x93 || b == 0x94 || b == 0x84:
+		return 333
+	case b == 0x95:
+		return 350
+	case b >= 0xC0 && b <= 0xDE:
+		return 722
+	default:
+		return 556
+	}
+}
+
+// run is a stretch of text set in one font
+type run struct {
+	text string
+	cjk  bool
+}
+
+// splitRuns splits s into runs for the standard fonts and the CJK font
+func splitRuns(s string) []run {
+	var runs []run
+	var current strings.Builder
+	cjk := false
+	for _, c := range s {
+		c = printable(c)
+		_, latin := winAnsi(c)
+		if current.Len() > 0 && latin == cjk {
+			runs = append(runs, run{current.String(), cjk})
+			current.Reset()
+		}
+		cjk = !latin
+		current.WriteRune(c)
+	}
+	if current.Len() > 0 {
+		runs = append(runs, run{current.String(), cjk})
+	}
+	return runs
+}
+
+// printable replaces control characters with spaces, and characters beyond
+// the Basic Multilingual Plane, which the CJK fonts' UCS-2 encodings cannot
+// address, with question marks
+func printable(c rune) rune {
+	switch {
+	case c < 32:
+		return ' '
+	case c > 0xFFFF:
+		return '?'
+	}
+	return c
+}
+
+// winAnsiSpecials are the characters of Windows-1252 outside Latin-1
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// winAnsi returns the WinAnsiEncoding byte of a character, if it has one
+func winAnsi(c rune) (byte, bool) {
+	switch {
+	case c >= 32 && c <= 126, c >= 0xA0 && c <= 0xFF:
+		return byte(c), true
+	}
+	b, ok := winAnsiSpecials[c]
+	return b, ok
+}
+
+// Helvetica and Helvetica-Bold widths of the printable ASCII characters, in
+// thousandths of the font size, from the Adobe font metrics
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// Write writes the document to w
+func (d *Document) Write(w io.Writer) error {
+	out := &objectWriter{}
+	out.buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	// Objects 1 to 4 are the catalog, page tree, shared resources and
+	// document information; fonts, images and pages follow
+	const catalogID, pagesID, resourcesID, infoID = 1, 2, 3, 4
+	next := infoID + 1
+	fontIDs := map[string]int{}
+	for i, used := range d.fontUsed {
+		if used {
+			fontIDs[fmt.Sprintf("F%d", i)] = next
+			next++
+		}
+	}
+	cjkID := 0
+	if d.cjkUsed {
+		cjkID = next
+		next += 3
+	}
+	imageIDs := make([]int, len(d.images))
+	for i := range d.images {
+		imageIDs[i] = next
+		next++
+	}
+	pageIDs := make([]int, len(d.pages))
+	for i, page := range d.pages {
+		pageIDs[i] = next
+		next += 2 + len(page.links)
+	}
+
+	out.object(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R /Lang %s /ViewerPreferences << /DisplayDocTitle true >> >>", pagesID, textString(d.language)))
+	kids := make([]string, len(pageIDs))
+	for i, id := range pageIDs {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	out.object(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %s %s] >>", strings.Join(kids, " "), len(kids), num(d.width), num(d.height)))
+
+	var resources strings.Builder
+	resources.WriteString("<< /ProcSet [/PDF /Text /ImageB /ImageC] /Font <<")
+	for i := Font(0); i < numFonts; i++ {
+		if id, ok := fontIDs[fmt.Sprintf("F%d", i)]; ok {
+			fmt.Fprintf(&resources, " /F%d %d 0 R", i, id)
+		}
+	}
+	if cjkID != 0 {
+		fmt.Fprintf(&resources, " /C0 %d 0 R", cjkID)
+	}
+	resources.WriteString(" >> /XObject <<")
+	for i, img := range d.images {
+		fmt.Fprintf(&resources, " /Im%d %d 0 R", img.id, imageIDs[i])
+	}
+	resources.WriteString(" >> >>")
+	out.object(resourcesID, resources.String())
+	out.object(infoID, fmt.Sprintf("<< /Title %s /Author %s /Producer (kuno) >>", textString(d.title), textString(d.author)))
+
+	for i, used := range d.fontUsed {
+		if used {
+			out.object(fontIDs[fmt.Sprintf("F%d", i)], fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", fontNames[i]))
+		}
+	}
+	if d.cjkUsed {
+		f := d.cjk
+		out.object(cjkID, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s-%s /Encoding /%s /DescendantFonts [%d 0 R] >>", f.name, f.cmap, f.cmap, cjkID+1))
+		out.object(cjkID+1, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (%s) /Supplement %d >> /FontDescriptor %d 0 R /DW 1000 >>", f.name, f.ordering, f.supplement, cjkID+2))
+		out.object(cjkID+2, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>", f.name))
+	}
+	for i, img := range d.images {
+		dict := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s /Length %d >>",
+			img.Width, img.Height, img.colorSpace, img.filter, len(img.data))
+		out.stream(imageIDs[i], dict, img.data)
+	}
+	for i, page := range d.pages {
+		id := pageIDs[i]
+		annots := ""
+		if len(page.links) > 0 {
+			refs := make([]string, len(page.links))
+			for j := range page.links {
+				refs[j] = fmt.Sprintf("%d 0 R", id+2+j)
+			}
+			annots = fmt.Sprintf(" /Annots [%s]", strings.Join(refs, " "))
+		}
+		out.object(id, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Resources %d 0 R /Contents %d 0 R%s >>", pagesID, resourcesID, id+1, annots))
+		var content bytes.Buffer
+		zw := zlib.NewWriter(&content)
+		zw.Write(page.content.Bytes())
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		out.stream(id+1, fmt.Sprintf("<< /Filter /FlateDecode /Length %d >>", content.Len()), content.Bytes())
+		for j, l := range page.links {
+			out.object(id+2+j, fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect [%s %s %s %s] /Border [0 0 0] /A << /S /URI /URI %s >> >>",
+				num(l.x), num(d.height-l.y-l.height), num(l.x+l.width), num(d.height-l.y), literalString(l.uri)))
+		}
+	}
+
+	xref := out.buf.Len()
+	fmt.Fprintf(&out.buf, "xref\n0 %d\n0000000000 65535 f \n", next)
+	for id := 1; id < next; id++ {
+		fmt.Fprintf(&out.buf, "%010d 00000 n \n", out.offsets[id])
+	}
+	fmt.Fprintf(&out.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", next, catalogID, infoID, xref)
+	_, err := w.Write(out.buf.Bytes())
+	return err
+}
+
+// objectWriter writes numbered objects, remembering where each starts for
+// the cross-reference table
+type objectWriter struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+}
+
+func (o *objectWriter) object(id int, body string) {
+	o.start(id)
+	o.buf.WriteString(body)
+	o.buf.WriteString("\nendobj\n")
+}
+
+func (o *objectWriter) stream(id int, dict string, data []byte) {
+	o.start(id)
+	o.buf.WriteString(dict)
+	o.buf.WriteString("\nstream\n")
+	o.buf.Write(data)
+	o.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func (o *objectWriter) start(id int) {
+	if o.offsets == nil {
+		o.offsets = map[int]int{}
+	}
+	o.offsets[id] = o.buf.Len()
+	fmt.Fprintf(&o.buf, "%d 0 obj\n", id)
+}
+
+// textString encodes s as a UTF-16 text string, for text readers show
+// outside pages
+func textString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, c := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", c)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// literalString encodes ASCII text such as a URI as a string
+func literalString(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 32 || c > 126:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func rgb(c Color) string {
+	return fmt.Sprintf("%s %s %s", num(float64(c.R)/255), num(float64(c.G)/255), num(float64(c.B)/255))
+}
+
+// num formats a number with at most two decimals
+func num(v float64) string {
+	s := strings.TrimRight(fmt.Sprintf("%.2f", v), "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,147 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	doc := New(A4Width, A4Height, "zh")
+	doc.SetInfo("标题", "Blog")
+	page := doc.AddPage()
+	page.Text(50, 60, Sans, 12, Black, "Hello (world) é 中文")
+	page.Link(50, 50, 100, 12, "https://example.com/a")
+
+	var picture bytes.Buffer
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	src.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	if err := png.Encode(&picture, src); err != nil {
+		t.Fatal(err)
+	}
+	img, err := doc.AddImage(picture.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	page.Image(img, 50, 100, 40, 20)
+	if _, err := doc.AddImage([]byte("not an image")); err == nil {
+		t.Error("AddImage accepted text")
+	}
+
+	var out bytes.Buffer
+	if err := doc.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	data := out.Bytes()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("not a PDF file: %q", data[:20])
+	}
+
+	// Every cross-reference entry points at its object
+	start, err := strconv.Atoi(string(regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(data)[1]))
+	if err != nil || !bytes.HasPrefix(data[start:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the table", start)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[start:], -1)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Errorf("object %d is not at offset %d", i+1, offset)
+		}
+	}
+	if len(entries) != 12 {
+		t.Errorf("%d objects, want 12", len(entries))
+	}
+
+	for _, want := range []string{
+		"/BaseFont /Helvetica /Encoding /WinAnsiEncoding",
+		"/BaseFont /STSong-Light-UniGB-UCS2-H /Encoding /UniGB-UCS2-H",
+		"/Width 4 /Height 2 /ColorSpace /DeviceRGB",
+		"/URI (https://example.com/a)",
+		"/Title <FEFF68079898>",
+		"/Lang <FEFF007A0068>",
+	} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("document lacks %q", want)
+		}
+	}
+	content := pageContent(t, data)
+	if want := `/F0 12 Tf (Hello \(world\) \351 ) Tj /C0 12 Tf <4E2D6587> Tj`; !strings.Contains(content, want) {
+		t.Errorf("page content %q lacks %q", content, want)
+	}
+	if want := "q 40 0 0 20 50 721.89 cm /Im0 Do Q"; !strings.Contains(content, want) {
+		t.Errorf("page content %q lacks %q", content, want)
+	}
+}
+
+// pageContent returns the decompressed content of the first page
+func pageContent(t *testing.T, data []byte) string {
+	t.Helper()
+	match := regexp.MustCompile(`(?s)<< /Filter /FlateDecode /Length (\d+) >>\nstream\n`).FindSubmatchIndex(data)
+	length, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+	r, err := zlib.NewReader(bytes.NewReader(data[match[1] : match[1]+length]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestWrap(t *testing.T) {
+	text := func(lines []line) []string {
+		var out []string
+		for _, l := range lines {
+			var b strings.Builder
+			for _, p := range l.pieces {
+				b.WriteString(p.text)
+			}
+			out = append(out, b.String())
+		}
+		return out
+	}
+	for _, test := range []struct {
+		name  string
+		text  string
+		width float64
+		pre   bool
+		want  []string
+	}{
+		// "aaaa" is 22.24 points wide at size 10, a space 2.78
+		{"words", "aaaa aaaa  aaaa", 50, false, []string{"aaaa aaaa", "aaaa"}},
+		{"breaks", "aaaa\naaaa", 100, false, []string{"aaaa", "aaaa"}},
+		{"cjk", "中文排版测试", 35, false, []string{"中文排", "版测试"}},
+		{"long words", "aaaaaaaaaa", 25, false, []string{"aaaa", "aaaa", "aa"}},
+		{"pre", "  if x {\n\n  }", 200, true, []string{"  if x {", "", "  }"}},
+	} {
+		lines := wrap([]Span{{Text: test.text, Font: Sans, Size: 10}}, test.width, test.pre)
+		if got := text(lines); strings.Join(got, "|") != strings.Join(test.want, "|") {
+			t.Errorf("%s: lines %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLayoutPaginates(t *testing.T) {
+	doc := New(A4Width, A4Height, "en")
+	layout := NewLayout(doc, 50)
+	for i := 0; i < 80; i++ {
+		layout.Paragraph([]Span{{Text: "A paragraph of text that fills one line.", Font: Sans, Size: 10}}, Block{After: 8})
+	}
+	layout.Footer(func(page, pages int) string { return fmt.Sprintf("%d / %d", page, pages) }, Sans, 8, Black)
+	if len(doc.Pages()) != 3 {
+		t.Fatalf("%d pages, want 3", len(doc.Pages()))
+	}
+	if content := doc.Pages()[2].content.String(); !strings.Contains(content, "(3 / 3) Tj") {
+		t.Errorf("last page lacks its number: %q", content)
+	}
+}
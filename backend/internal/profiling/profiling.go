@@ -0,0 +1,202 @@
+// Package profiling records slow requests and slow database queries so the
+// expensive paths of a running instance can be found without a debugger.
+// A Profile collects the queries of one request; the Recorder keeps the most
+// recent slow requests and queries of this process in memory.
+package profiling
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxStatements bounds the distinct statements kept per request, so a
+// request looping over thousands of different queries stays cheap to record
+const maxStatements = 50
+
+// Query is one executed statement. SQL keeps its placeholders, so recorded
+// queries never contain user data.
+type Query struct {
+	SQL      string        `json:"sql"`
+	Duration time.Duration `json:"duration_ns"`
+	Rows     int64         `json:"rows"`
+	Source   string        `json:"source"` // file:line of the caller
+	At       time.Time     `json:"at"`
+	Request  string        `json:"request_id,omitempty"`
+}
+
+// Statement aggregates the executions of one SQL statement in a request.
+// A high Count for the same statement is the usual sign of an N+1 query.
+type Statement struct {
+	SQL    string        `json:"sql"`
+	Source string        `json:"source"`
+	Count  int           `json:"count"`
+	Total  time.Duration `json:"total_ns"`
+	Max    time.Duration `json:"max_ns"`
+	Rows   int64         `json:"rows"`
+}
+
+// Profile collects the queries run on behalf of one request
+type Profile struct {
+	RequestID string
+
+	mu         sync.Mutex
+	count      int
+	total      time.Duration
+	statements map[string]*Statement
+}
+
+// NewProfile creates an empty profile for the request with the given ID
+func NewProfile(requestID string) *Profile {
+	return &Profile{RequestID: requestID, statements: make(map[string]*Statement)}
+}
+
+// Add records an executed query
+func (p *Profile) Add(query Query) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.count++
+	p.total += query.Duration
+
+	statement, ok := p.statements[query.SQL]
+	if !ok {
+		if len(p.statements) >= maxStatements {
+			return
+		}
+		statement = &Statement{SQL: query.SQL, Source: query.Source}
+		p.statements[query.SQL] = statement
+	}
+	statement.Count++
+	statement.Total += query.Duration
+	statement.Rows += query.Rows
+	if query.Duration > statement.Max {
+		statement.Max = query.Duration
+	}
+}
+
+// Summary returns the number of queries, their total time and the statements
+// ordered by total time, most expensive first
+func (p *Profile) Summary() (int, time.Duration, []Statement) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	statements := make([]Statement, 0, len(p.statements))
+	for _, statement := range p.statements {
+		statements = append(statements, *statement)
+	}
+	sort.Slice(statements, func(i, j int) bool {
+		return statements[i].Total > statements[j].Total
+	})
+	return p.count, p.total, statements
+}
+
+type profileKey struct{}
+
+// WithProfile returns a context whose queries are recorded in profile
+func WithProfile(ctx context.Context, profile *Profile) context.Context {
+	return context.WithValue(ctx, profileKey{}, profile)
+}
+
+// FromContext returns the profile attached to ctx, or nil
+func FromContext(ctx context.Context) *Profile {
+	if ctx == nil {
+		return nil
+	}
+	profile, _ := ctx.Value(profileKey{}).(*Profile)
+	return profile
+}
+
+// Request is a request that took longer than the slow request threshold
+type Request struct {
+	Method     string        `json:"method"`
+	Path       string        `json:"path"`
+	Route      string        `json:"route"`
+	Status     int           `json:"status"`
+	Duration   time.Duration `json:"duration_ns"`
+	At         time.Time     `json:"at"`
+	RequestID  string        `json:"request_id"`
+	Queries    int           `json:"queries"`
+	QueryTime  time.Duration `json:"query_time_ns"`
+	Statements []Statement   `json:"statements"`
+}
+
+// Recorder keeps the most recent slow requests and queries in ring buffers
+type Recorder struct {
+	mu       sync.Mutex
+	keep     int
+	requests []Request
+	queries  []Query
+}
+
+// NewRecorder creates a recorder keeping up to keep entries of each kind
+func NewRecorder(keep int) *Recorder {
+	if keep <= 0 {
+		keep = 100
+	}
+	return &Recorder{keep: keep}
+}
+
+// AddRequest records a slow request
+func (r *Recorder) AddRequest(request Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = appendBounded(r.requests, request, r.keep)
+}
+
+// AddQuery records a slow query
+func (r *Recorder) AddQuery(query Query) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries = appendBounded(r.queries, query, r.keep)
+}
+
+// Requests returns the recorded slow requests, newest first
+func (r *Recorder) Requests() []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return newestFirst(r.requests)
+}
+
+// Queries returns the recorded slow queries, newest first
+func (r *Recorder) Queries() []Query {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return newestFirst(r.queries)
+}
+
+// Reset forgets everything recorded so far
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests, r.queries = nil, nil
+}
+
+func appendBounded[T any](items []T, item T, keep int) []T {
+	items = append(items, item)
+	if len(items) > keep {
+		items = append(items[:0:0], items[len(items)-keep:]...)
+	}
+	return items
+}
+
+func newestFirst[T any](items []T) []T {
+	result := make([]T, len(items))
+	for i, item := range items {
+		result[len(items)-1-i] = item
+	}
+	return result
+}
+
+var (
+	defaultRecorder *Recorder
+	recorderOnce    sync.Once
+)
+
+// Default returns the recorder shared by the query profiler and the
+// request middleware
+func Default() *Recorder {
+	recorderOnce.Do(func() {
+		defaultRecorder = NewRecorder(0)
+	})
+	return defaultRecorder
+}
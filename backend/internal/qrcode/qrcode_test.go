@@ -0,0 +1,193 @@
+package qrcode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// HELLO WORLD at 1-M, from the ISO/IEC 18004 worked example
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := reedSolomonRemainder(data, reedSolomonDivisor(len(want))); !bytes.Equal(got, want) {
+		t.Errorf("error correction = %v, want %v", got, want)
+	}
+}
+
+func TestEncodeDecodes(t *testing.T) {
+	for _, test := range []struct {
+		text    string
+		level   Level
+		version int
+	}{
+		{"https://example.com/zh/article/1", Medium, 3},
+		{"https://example.com/en/article/" + strings.Repeat("a-long-slug-", 12), High, 13},
+		{strings.Repeat("博客", 200), Low, 25},
+	} {
+		c, err := Encode(test.text, test.level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.version != test.version || c.Size != test.version*4+17 {
+			t.Errorf("%d bytes at level %d: version %d, want %d", len(test.text), test.level, c.version, test.version)
+		}
+		if text := decode(t, c); text != test.text {
+			t.Errorf("decoded %q, want %q", text, test.text)
+		}
+	}
+
+	if _, err := Encode(strings.Repeat("x", 3000), High); err != ErrTooLong {
+		t.Errorf("3000 bytes at High = %v, want ErrTooLong", err)
+	}
+}
+
+func TestVersionInformation(t *testing.T) {
+	c, err := Encode(strings.Repeat("x", 60), High)
+	if err != nil || c.version != 7 {
+		t.Fatalf("version %d, %v, want 7", c.version, err)
+	}
+	// Version 7 is written as 000111110010010100, least significant bit first
+	const want = 0b000111110010010100
+	for i := 0; i < 18; i++ {
+		if c.Dark(c.Size-11+i%3, i/3) != bit(want, i) || c.Dark(i/3, c.Size-11+i%3) != bit(want, i) {
+			t.Fatalf("version bit %d is wrong", i)
+		}
+	}
+}
+
+func TestImageAndSVG(t *testing.T) {
+	c, err := Encode("https://example.com", High)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logo := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for i := range logo.Pix {
+		logo.Pix[i] = 0xff
+	}
+	img := c.Image(3, logo)
+	if width := (c.Size + 2*QuietZone) * 3; img.Bounds().Dx() != width || img.Bounds().Dy() != width {
+		t.Errorf("image is %v, want %d pixels wide", img.Bounds(), width)
+	}
+	if r, _, _, _ := img.At(QuietZone*3, QuietZone*3).RGBA(); r != 0 {
+		t.Error("the finder pattern corner is not dark")
+	}
+	center := img.Bounds().Dx() / 2
+	if img.At(center, center) != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("center = %v, want the white logo", img.At(center, center))
+	}
+
+	var svg bytes.Buffer
+	if err := c.SVG(&svg, 4, `/logo.png?a=1&b=2`); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(svg.String(), `href="/logo.png?a=1&amp;b=2"`) || !strings.HasSuffix(svg.String(), "</svg>\n") {
+		t.Errorf("svg = %s", svg.String())
+	}
+}
+
+// decode reads a code back as a scanner would: the format bits, the
+// unmasked codewords, the blocks checked against their error correction
+// and the byte mode segment
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+	format := 0
+	for i := 0; i <= 5; i++ {
+		if c.Dark(8, i) {
+			format |= 1 << i
+		}
+	}
+	for i, p := range [][2]int{{8, 7}, {8, 8}, {7, 8}} {
+		if c.Dark(p[0], p[1]) {
+			format |= 1 << (6 + i)
+		}
+	}
+	for i := 9; i < 15; i++ {
+		if c.Dark(14-i, 8) {
+			format |= 1 << i
+		}
+	}
+	format ^= 0x5412
+	if format>>13 != formatLevel[c.level] {
+		t.Fatalf("format level %d, want %d", format>>13, formatLevel[c.level])
+	}
+	mask := format >> 10 & 7
+	c.applyMask(mask)
+	defer c.applyMask(mask)
+
+	raw := make([]byte, rawDataModules(c.version)/8)
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vertical := 0; vertical < c.Size; vertical++ {
+			y := vertical
+			if (right+1)&2 == 0 {
+				y = c.Size - 1 - vertical
+			}
+			for j := 0; j < 2; j++ {
+				if x := right - j; !c.function[y][x] && i < len(raw)*8 {
+					if c.modules[y][x] {
+						raw[i>>3] |= 1 << (7 - i&7)
+					}
+					i++
+				}
+			}
+		}
+	}
+
+	blocks := eccBlocks[c.level][c.version]
+	blockECC := eccPerBlock[c.level][c.version]
+	short := blocks - len(raw)%blocks
+	shortData := len(raw)/blocks - blockECC
+	split := make([][]byte, blocks)
+	k := 0
+	for column := 0; column <= shortData; column++ {
+		for b := range split {
+			if column < shortData || b >= short {
+				split[b] = append(split[b], raw[k])
+				k++
+			}
+		}
+	}
+	ecc := make([][]byte, blocks)
+	for column := 0; column < blockECC; column++ {
+		for b := range ecc {
+			ecc[b] = append(ecc[b], raw[k])
+			k++
+		}
+	}
+	var data []byte
+	for b := range split {
+		if !bytes.Equal(reedSolomonRemainder(split[b], reedSolomonDivisor(blockECC)), ecc[b]) {
+			t.Fatalf("block %d does not match its error correction", b)
+		}
+		data = append(data, split[b]...)
+	}
+	return readByteSegment(t, data, countBits(c.version))
+}
+
+// readByteSegment reads the single byte mode segment of decoded data
+func readByteSegment(t *testing.T, data []byte, countLength int) string {
+	t.Helper()
+	position := 0
+	read := func(n int) int {
+		value := 0
+		for ; n > 0; n-- {
+			value = value<<1 | int(data[position>>3]>>(7-position&7)&1)
+			position++
+		}
+		return value
+	}
+	if mode := read(4); mode != 0b0100 {
+		t.Fatalf("mode %04b, want byte mode", mode)
+	}
+	text := make([]byte, read(countLength))
+	for i := range text {
+		text[i] = byte(read(8))
+	}
+	return string(text)
+}
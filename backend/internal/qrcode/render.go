@@ -0,0 +1,107 @@
+package qrcode
+
+import (
+	"blog-backend/internal/imaging"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"strings"
+)
+
+// QuietZone is the light border around a code, in modules, that scanners
+// need to find it
+const QuietZone = 4
+
+// logoShare is the part of the code's width a logo covers, small enough
+// for High error correction to read through it
+const logoShare = 5
+
+// Image renders the code at moduleSize pixels per module with its quiet
+// zone. A logo, when given, is scaled into the center on a light square;
+// encode at High when using one.
+func (c *Code) Image(moduleSize int, logo image.Image) image.Image {
+	moduleSize = max(moduleSize, 1)
+	width := (c.Size + 2*QuietZone) * moduleSize
+	img := image.NewRGBA(image.Rect(0, 0, width, width))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.modules[y][x] {
+				left, top := (x+QuietZone)*moduleSize, (y+QuietZone)*moduleSize
+				draw.Draw(img, image.Rect(left, top, left+moduleSize, top+moduleSize), image.Black, image.Point{}, draw.Src)
+			}
+		}
+	}
+	if logo == nil || logo.Bounds().Empty() {
+		return img
+	}
+
+	box := c.logoBox()
+	padding := image.Rect(box.Min.X-1, box.Min.Y-1, box.Max.X+1, box.Max.Y+1)
+	draw.Draw(img, scale(padding, moduleSize), image.White, image.Point{}, draw.Src)
+	target := imaging.Fit(logo.Bounds(), scale(box, moduleSize))
+	draw.Draw(img, target, imaging.Resize(logo, target.Dx(), target.Dy()), image.Point{}, draw.Over)
+	return img
+}
+
+// SVG writes the code as an SVG image sized moduleSize pixels per module.
+// logoHref, when set, is the address or data URI of an image shown in the
+// center on a light square; encode at High when using one.
+func (c *Code) SVG(w io.Writer, moduleSize int, logoHref string) error {
+	width := c.Size + 2*QuietZone
+	var path strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			// One rectangle per horizontal run of dark modules
+			run := 1
+			for x+run < c.Size && c.modules[y][x+run] {
+				run++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", x+QuietZone, y+QuietZone, run, run)
+			x += run - 1
+		}
+	}
+
+	pixels := width * max(moduleSize, 1)
+	if _, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">
+<rect width="100%%" height="100%%" fill="#fff"/>
+<path d="%s" fill="#000"/>
+`, pixels, pixels, width, width, path.String()); err != nil {
+		return err
+	}
+	if logoHref != "" {
+		box := c.logoBox()
+		var href strings.Builder
+		if err := xml.EscapeText(&href, []byte(logoHref)); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="#fff"/>
+<image href="%s" x="%d" y="%d" width="%d" height="%d" preserveAspectRatio="xMidYMid meet"/>
+`, box.Min.X-1, box.Min.Y-1, box.Dx()+2, box.Dy()+2, href.String(), box.Min.X, box.Min.Y, box.Dx(), box.Dy()); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "</svg>\n")
+	return err
+}
+
+// logoBox is the square left for a logo, in modules including the quiet
+// zone
+func (c *Code) logoBox() image.Rectangle {
+	side := c.Size / logoShare
+	if side%2 != c.Size%2 {
+		side++
+	}
+	start := QuietZone + (c.Size-side)/2
+	return image.Rect(start, start, start+side, start+side)
+}
+
+func scale(r image.Rectangle, factor int) image.Rectangle {
+	return image.Rect(r.Min.X*factor, r.Min.Y*factor, r.Max.X*factor, r.Max.Y*factor)
+}
@@ -0,0 +1,327 @@
+package search
+
+import (
+	"blog-backend/internal/keywords"
+	"bytes"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DefaultSnippetLength is the length of a snippet, in characters, when the
+// request does not choose one
+const DefaultSnippetLength = 160
+
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+	ellipsis  = "…"
+)
+
+// unspacedLanguages are written without spaces between words, so snippets
+// may be cut between any two characters and prefer to start and end at
+// punctuation
+var unspacedLanguages = map[string]bool{"zh": true, "ja": true, "th": true, "lo": true, "km": true, "my": true}
+
+// clausePunctuation ends a sentence or clause in unspaced languages
+const clausePunctuation = "。！？；，、.!?;,"
+
+// markdown renders Markdown for PlainText, with the extensions articles use
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// span is a match of a term in text, in runes
+type span struct {
+	start, end int
+	term       int
+}
+
+// Terms returns the words the query looks for in the text of articles: the
+// free text and the values of title, content and summary filters that do
+// not exclude. Longer terms come first, so a term within another is only
+// marked where the longer one does not match.
+func (pq *ParsedQuery) Terms() []string {
+	seen := make(map[string]bool)
+	var terms []string
+	add := func(term string) {
+		term = strings.TrimSpace(term)
+		key := strings.ToLower(term)
+		if term == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		terms = append(terms, term)
+	}
+	for _, term := range pq.FreeText {
+		add(term)
+	}
+	for _, filter := range pq.Filters {
+		if filter.Exclude {
+			continue
+		}
+		switch filter.Field {
+		case "title", "content", "summary":
+			if value, ok := filter.Value.(string); ok {
+				add(value)
+			}
+		}
+	}
+	sort.SliceStable(terms, func(i, j int) bool {
+		return utf8.RuneCountInString(terms[i]) > utf8.RuneCountInString(terms[j])
+	})
+	return terms
+}
+
+// PlainText returns the readable text of article content, without Markdown
+// syntax or HTML tags. Blocks are separated by newlines.
+func PlainText(content, contentType string) string {
+	source := content
+	if contentType != "html" {
+		var rendered bytes.Buffer
+		if err := markdown.Convert([]byte(content), &rendered); err != nil {
+			return content
+		}
+		source = rendered.String()
+	}
+	nodes, err := xhtml.ParseFragment(strings.NewReader(source), &xhtml.Node{Type: xhtml.ElementNode, DataAtom: atom.Body, Data: "body"})
+	if err != nil {
+		return content
+	}
+	var b strings.Builder
+	var walk func(*xhtml.Node)
+	walk = func(n *xhtml.Node) {
+		switch {
+		case n.Type == xhtml.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == xhtml.ElementNode && (n.DataAtom == atom.Script || n.DataAtom == atom.Style):
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if n.Type == xhtml.ElementNode && blockElement(n.DataAtom) {
+			b.WriteByte('\n')
+		}
+	}
+	for _, node := range nodes {
+		walk(node)
+	}
+	lines := strings.Split(b.String(), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func blockElement(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Br, atom.Li, atom.Pre, atom.Blockquote, atom.Tr, atom.Td, atom.Th,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Hr, atom.Figcaption, atom.Dt, atom.Dd:
+		return true
+	}
+	return false
+}
+
+// Highlight returns text HTML-escaped, with every match of the terms in
+// <mark>. Matching ignores case.
+func Highlight(text string, terms []string) string {
+	chars := []rune(text)
+	return mark(chars, findMatches(chars, terms), 0, len(chars))
+}
+
+// Snippet returns the passage of about length characters of text that
+// holds the most of the terms, HTML-escaped with the matches in <mark> and
+// an ellipsis where text was cut. Text without a match gives its start.
+// The passage begins and ends between words, or for languages written
+// without spaces at punctuation where there is some nearby.
+func Snippet(text string, terms []string, language string, length int) string {
+	if length <= 0 {
+		length = DefaultSnippetLength
+	}
+	chars := []rune(strings.ReplaceAll(text, "\n", " "))
+	if len(chars) == 0 {
+		return ""
+	}
+	matches := findMatches(chars, terms)
+	unspaced := unspacedLanguages[keywords.BaseLanguage(language)]
+
+	start := 0
+	if len(matches) > 0 {
+		first, last := bestWindow(matches, length)
+		// Lead in with some context, more when the matches leave room for it
+		lead := min(length/4, max(0, length-(matches[last].end-matches[first].start))/2)
+		start = max(0, matches[first].start-lead)
+	}
+	end := min(len(chars), start+length)
+	if end == len(chars) {
+		start = max(0, end-length)
+	}
+	if start > 0 {
+		start = snapStart(chars, start, unspaced, matchStartAtOrAfter(matches, start))
+	}
+	if end < len(chars) {
+		end = snapEnd(chars, start, end, unspaced)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(ellipsis)
+	}
+	b.WriteString(strings.TrimSpace(mark(chars, matches, start, end)))
+	if end < len(chars) {
+		b.WriteString(ellipsis)
+	}
+	return b.String()
+}
+
+// findMatches returns the matches of the terms in chars, in order and not
+// overlapping. Where terms match at the same place the earlier, longer
+// term wins.
+func findMatches(chars []rune, terms []string) []span {
+	lower := foldRunes(chars)
+	patterns := make([][]rune, 0, len(terms))
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			patterns = append(patterns, foldRunes([]rune(term)))
+		}
+	}
+	var matches []span
+	for i := 0; i < len(lower); {
+		matched := false
+		for t, pattern := range patterns {
+			if hasPrefixRunes(lower[i:], pattern) {
+				matches = append(matches, span{i, i + len(pattern), t})
+				i += len(pattern)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			i++
+		}
+	}
+	return matches
+}
+
+// bestWindow returns the first and last of the run of matches fitting in
+// length characters with the most distinct terms, then the most matches.
+// Ties go to the earliest run.
+func bestWindow(matches []span, length int) (int, int) {
+	bestFirst, bestLast, bestTerms, bestCount := 0, 0, 0, 0
+	for first := range matches {
+		terms := make(map[int]bool)
+		last := first
+		for j := first; j < len(matches) && matches[j].end-matches[first].start <= length; j++ {
+			terms[matches[j].term] = true
+			last = j
+		}
+		if count := last - first + 1; len(terms) > bestTerms || len(terms) == bestTerms && count > bestCount {
+			bestFirst, bestLast, bestTerms, bestCount = first, last, len(terms), count
+		}
+	}
+	return bestFirst, bestLast
+}
+
+// snapStart moves a cut at the start of a snippet forward to where a word
+// or, in unspaced languages, a clause begins, without passing the first
+// match
+func snapStart(chars []rune, start int, unspaced bool, limit int) int {
+	if unspaced {
+		for i := start; i < limit && i < start+16; i++ {
+			if strings.ContainsRune(clausePunctuation, chars[i-1]) {
+				return i
+			}
+		}
+		return start
+	}
+	for i := start; i < limit; i++ {
+		if wordBoundary(chars[i-1], chars[i]) {
+			return i
+		}
+	}
+	return start
+}
+
+// snapEnd moves a cut at the end of a snippet back to where a word or
+// clause ends
+func snapEnd(chars []rune, start, end int, unspaced bool) int {
+	floor := start + (end-start)*3/4
+	if unspaced {
+		for i := end; i > floor; i-- {
+			if strings.ContainsRune(clausePunctuation, chars[i-1]) {
+				return i
+			}
+		}
+		return end
+	}
+	for i := end; i > floor; i-- {
+		if wordBoundary(chars[i-1], chars[i]) {
+			return i
+		}
+	}
+	return end
+}
+
+// wordBoundary reports whether text may be cut between a and b without
+// splitting a word. Ideographs are words of their own in any language.
+func wordBoundary(a, b rune) bool {
+	return unicode.IsSpace(a) || unicode.IsSpace(b) || unicode.Is(unicode.Han, a) || unicode.Is(unicode.Han, b)
+}
+
+func matchStartAtOrAfter(matches []span, pos int) int {
+	for _, m := range matches {
+		if m.start >= pos {
+			return m.start
+		}
+	}
+	return pos + 1
+}
+
+// mark renders chars[from:to] HTML-escaped with the matches in <mark>.
+// Matches cut by the edges are marked as far as they reach.
+func mark(chars []rune, matches []span, from, to int) string {
+	var b strings.Builder
+	pos := from
+	for _, m := range matches {
+		if m.end <= from || m.start >= to {
+			continue
+		}
+		start, end := max(m.start, from), min(m.end, to)
+		b.WriteString(html.EscapeString(string(chars[pos:start])))
+		b.WriteString(markOpen + html.EscapeString(string(chars[start:end])) + markClose)
+		pos = end
+	}
+	b.WriteString(html.EscapeString(string(chars[pos:to])))
+	return b.String()
+}
+
+// foldRunes lowercases rune by rune, so positions in the result are those
+// of the original
+func foldRunes(chars []rune) []rune {
+	folded := make([]rune, len(chars))
+	for i, r := range chars {
+		folded[i] = unicode.ToLower(r)
+	}
+	return folded
+}
+
+func hasPrefixRunes(s, prefix []rune) bool {
+	if len(prefix) == 0 || len(s) < len(prefix) {
+		return false
+	}
+	for i, r := range prefix {
+		if s[i] != r {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	parsed, err := ParseSearchQuery(`go title:"worker pool" -java Golang content:channels`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(parsed.Terms(), "|")
+	if got != "worker pool|channels|Golang|go" {
+		t.Errorf("Terms() = %q", got)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"Go <generics> in Go", []string{"go"}, "<mark>Go</mark> &lt;generics&gt; in <mark>Go</mark>"},
+		{"Goroutines", []string{"goroutine", "go"}, "<mark>Goroutine</mark>s"},
+		{"搜索引擎优化指南", []string{"引擎"}, "搜索<mark>引擎</mark>优化指南"},
+		{"İstanbul ünd Straße", []string{"straße"}, "İstanbul ünd <mark>Straße</mark>"},
+		{"nothing here", []string{"absent"}, "nothing here"},
+		{"no terms", nil, "no terms"},
+	}
+	for _, tt := range tests {
+		if got := Highlight(tt.text, tt.terms); got != tt.want {
+			t.Errorf("Highlight(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	filler := strings.Repeat("lorem ipsum dolor sit amet ", 20)
+	text := filler + "The scheduler parks idle goroutines. " + filler + "Channels and goroutines work together. " + filler
+
+	got := Snippet(text, []string{"goroutines", "channels"}, "en", 80)
+	if !strings.Contains(got, "<mark>Channels</mark> and <mark>goroutines</mark>") {
+		t.Errorf("snippet misses the passage with both terms: %q", got)
+	}
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("snippet cut from the middle is not marked: %q", got)
+	}
+	inner := strings.Trim(got, "…")
+	for _, word := range strings.Fields(strings.NewReplacer("<mark>", "", "</mark>", "").Replace(inner)) {
+		if !strings.Contains(text, " "+word) && !strings.HasPrefix(text, word) {
+			t.Errorf("snippet splits a word: %q in %q", word, got)
+		}
+	}
+
+	if got := Snippet("Short text about Go.", []string{"go"}, "en", 80); got != "Short text about <mark>Go</mark>." {
+		t.Errorf("short snippet = %q", got)
+	}
+	if got := Snippet(filler, []string{"absent"}, "en", 30); !strings.HasPrefix(got, "lorem ipsum dolor") || !strings.HasSuffix(got, "…") {
+		t.Errorf("snippet without matches = %q", got)
+	}
+
+	// Chinese is cut between characters, at punctuation where there is some
+	chinese := strings.Repeat("这是一段没有关键词的文字，", 10) + "数据库索引可以加快查询。" + strings.Repeat("后面还有很多别的内容。", 10)
+	got = Snippet(chinese, []string{"索引"}, "zh-CN", 40)
+	if !strings.Contains(got, "<mark>索引</mark>") || !strings.HasPrefix(got, "…") {
+		t.Errorf("Chinese snippet = %q", got)
+	}
+	if n := len([]rune(strings.NewReplacer("<mark>", "", "</mark>", "", "…", "").Replace(got))); n > 40 {
+		t.Errorf("Chinese snippet of %d characters, want at most 40", n)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	markdown := "# Title\n\nSome **bold** text with a [link](https://example.com).\n\n```go\nfmt.Println(\"hi\")\n```\n\n<script>alert(1)</script>"
+	if got := PlainText(markdown, "markdown"); got != "Title\nSome bold text with a link.\nfmt.Println(\"hi\")" {
+		t.Errorf("PlainText(markdown) = %q", got)
+	}
+	html := "<h2>Heading</h2><p>First &amp; <em>second</em></p><style>p{}</style><ul><li>one</li><li>two</li></ul>"
+	if got := PlainText(html, "html"); got != "Heading\nFirst & second\none\ntwo" {
+		t.Errorf("PlainText(html) = %q", got)
+	}
+}
@@ -3,8 +3,9 @@ package security
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
+	"sync"
 )
 
 // SecureAIConfig represents AI configuration with secure key handling
@@ -18,6 +19,7 @@ type SecureAIConfig struct {
 type SecureProviderConfig struct {
 	Provider        string            `json:"provider"`
 	EncryptedAPIKey string            `json:"encrypted_api_key"`
+	SecretRef       string            `json:"secret_ref,omitempty"` // External secret reference, resolved at runtime
 	Model           string            `json:"model"`
 	Enabled         bool              `json:"enabled"`
 	Settings        map[string]string `json:"settings,omitempty"` // Custom settings like base_url
@@ -43,6 +45,7 @@ type ClientProviderConfig struct {
 	Model        string            `json:"model"`
 	Enabled      bool              `json:"enabled"`
 	IsConfigured bool              `json:"is_configured"` // Whether a real key is configured
+	SecretRef    string            `json:"secret_ref,omitempty"` // External secret reference, if used
 	Settings     map[string]string `json:"settings,omitempty"`  // Custom settings like base_url
 }
 
@@ -99,10 +102,17 @@ func (acs *AIConfigService) EncryptAIConfig(input *InputAIConfig) (*SecureAIConf
 
 	for name, provider := range input.Providers {
 		encryptedKey := ""
+		secretRef := ""
 		var err error
 
-		// Only encrypt if it's a real API key (not a placeholder)
-		if provider.APIKey != "" && !acs.isPlaceholder(provider.APIKey) {
+		if err := CheckAPISecretRef(provider.APIKey); err != nil {
+			return nil, fmt.Errorf("API key for provider %s: %w", name, err)
+		}
+		// Secret references are stored as-is so the key itself never reaches the database
+		if IsSecretRef(provider.APIKey) {
+			secretRef = provider.APIKey
+		} else if provider.APIKey != "" && !acs.isPlaceholder(provider.APIKey) {
+			// Only encrypt if it's a real API key (not a placeholder)
 			encryptedKey, err = acs.crypto.EncryptAPIKey(provider.APIKey)
 			if err != nil {
 				return nil, fmt.Errorf("failed to encrypt API key for provider %s: %v", name, err)
@@ -112,6 +122,7 @@ func (acs *AIConfigService) EncryptAIConfig(input *InputAIConfig) (*SecureAIConf
 		secure.Providers[name] = SecureProviderConfig{
 			Provider:        provider.Provider,
 			EncryptedAPIKey: encryptedKey,
+			SecretRef:       secretRef,
 			Model:           provider.Model,
 			Enabled:         provider.Enabled,
 			Settings:        provider.Settings, // Pass through Settings
@@ -136,10 +147,17 @@ func (acs *AIConfigService) DecryptAIConfig(secure *SecureAIConfig) (*InputAICon
 		decryptedKey := ""
 		var err error
 
-		if provider.EncryptedAPIKey != "" {
+		if provider.SecretRef != "" {
+			decryptedKey, err = ResolveSecret(provider.SecretRef)
+			if err != nil {
+				slog.Error("Failed to resolve API key reference", "provider", name, "error", err)
+				// Continue with empty key rather than failing completely
+				decryptedKey = ""
+			}
+		} else if provider.EncryptedAPIKey != "" {
 			decryptedKey, err = acs.crypto.DecryptAPIKey(provider.EncryptedAPIKey)
 			if err != nil {
-				log.Printf("Failed to decrypt API key for provider %s: %v", name, err)
+				slog.Error("Failed to decrypt API key", "provider", name, "error", err)
 				// Continue with empty key rather than failing completely
 				decryptedKey = ""
 			}
@@ -172,7 +190,12 @@ func (acs *AIConfigService) ToClientConfig(secure *SecureAIConfig) *ClientAIConf
 		maskedKey := ""
 		isConfigured := false
 
-		if provider.EncryptedAPIKey != "" {
+		if provider.SecretRef != "" {
+			// References are not sensitive; show them so the admin can see where the key lives
+			maskedKey = provider.SecretRef
+			_, err := ResolveSecret(provider.SecretRef)
+			isConfigured = err == nil
+		} else if provider.EncryptedAPIKey != "" {
 			// Decrypt temporarily to create mask
 			if decryptedKey, err := acs.crypto.DecryptAPIKey(provider.EncryptedAPIKey); err == nil && decryptedKey != "" {
 				maskedKey = acs.crypto.GetMaskedDisplayKey(decryptedKey)
@@ -186,6 +209,7 @@ func (acs *AIConfigService) ToClientConfig(secure *SecureAIConfig) *ClientAIConf
 			Model:        provider.Model,
 			Enabled:      provider.Enabled,
 			IsConfigured: isConfigured,
+			SecretRef:    provider.SecretRef,
 			Settings:     provider.Settings, // Pass through Settings
 		}
 	}
@@ -212,9 +236,9 @@ func getProviderNames(providers interface{}) []string {
 // MergeWithExisting merges input config with existing secure config
 // This handles the case where client sends placeholders for unchanged keys
 func (acs *AIConfigService) MergeWithExisting(input *InputAIConfig, existing *SecureAIConfig) (*SecureAIConfig, error) {
-	log.Printf("[AI Config Merge] Starting merge process...")
-	log.Printf("[AI Config Merge] Input providers: %v", getProviderNames(input.Providers))
-	log.Printf("[AI Config Merge] Existing providers: %v", getProviderNames(existing.Providers))
+	slog.Debug("AI config merge starting",
+		"input_providers", getProviderNames(input.Providers),
+		"existing_providers", getProviderNames(existing.Providers))
 
 	merged := &SecureAIConfig{
 		DefaultProvider: input.DefaultProvider,
@@ -227,33 +251,37 @@ func (acs *AIConfigService) MergeWithExisting(input *InputAIConfig, existing *Se
 
 	for name, inputProvider := range input.Providers {
 		var encryptedKey string
+		var secretRef string
 		var err error
 
-		log.Printf("[AI Config Merge] Processing provider '%s':", name)
-		log.Printf("  - Input key: '%s'", inputProvider.APIKey)
-		log.Printf("  - Is placeholder: %v", acs.isPlaceholder(inputProvider.APIKey))
-
+		// Never log the submitted key itself, only whether it is a placeholder
 		existingProvider, exists := existing.Providers[name]
-		if exists {
-			log.Printf("  - Has existing encrypted key: %v", existingProvider.EncryptedAPIKey != "")
-		} else {
-			log.Printf("  - No existing provider found")
-		}
+		slog.Debug("AI config merge processing provider",
+			"provider", name,
+			"is_placeholder", acs.isPlaceholder(inputProvider.APIKey),
+			"has_existing_key", exists && existingProvider.EncryptedAPIKey != "")
 
+		if err := CheckAPISecretRef(inputProvider.APIKey); err != nil {
+			return nil, fmt.Errorf("API key for provider %s: %w", name, err)
+		}
 		// Check if this is a new/updated key or should preserve existing
-		if inputProvider.APIKey != "" && !acs.isPlaceholder(inputProvider.APIKey) {
+		if IsSecretRef(inputProvider.APIKey) {
+			slog.Debug("AI config merge using external secret reference", "provider", name)
+			secretRef = inputProvider.APIKey
+		} else if inputProvider.APIKey != "" && !acs.isPlaceholder(inputProvider.APIKey) {
 			// New key provided - encrypt it
-			log.Printf("  → Encrypting new/changed key")
+			slog.Debug("AI config merge encrypting new key", "provider", name)
 			encryptedKey, err = acs.crypto.EncryptAPIKey(inputProvider.APIKey)
 			if err != nil {
 				return nil, fmt.Errorf("failed to encrypt API key for provider %s: %v", name, err)
 			}
 		} else if exists {
 			// Preserve existing encrypted key
-			log.Printf("  → Preserving existing encrypted key")
+			slog.Debug("AI config merge preserving existing key", "provider", name)
 			encryptedKey = existingProvider.EncryptedAPIKey
+			secretRef = existingProvider.SecretRef
 		} else {
-			log.Printf("  → No key to preserve, leaving empty")
+			slog.Debug("AI config merge leaving key empty", "provider", name)
 		}
 		// If no existing key and input is placeholder/empty, encryptedKey remains empty
 
@@ -281,12 +309,13 @@ func (acs *AIConfigService) MergeWithExisting(input *InputAIConfig, existing *Se
 		merged.Providers[name] = SecureProviderConfig{
 			Provider:        inputProvider.Provider,
 			EncryptedAPIKey: encryptedKey,
+			SecretRef:       secretRef,
 			Model:           inputProvider.Model,
 			Enabled:         inputProvider.Enabled,
 			Settings:        settings, // Save merged Settings
 		}
 
-		log.Printf("  ✓ Final encrypted key length: %d", len(encryptedKey))
+		slog.Debug("AI config merge provider done", "provider", name, "encrypted_len", len(encryptedKey))
 	}
 
 	return merged, nil
@@ -410,12 +439,15 @@ func (acs *AIConfigService) ToClientConfigJSON(encryptedData string) (string, er
 }
 
 // Global AI config service instance
-var globalAIConfigService *AIConfigService
+var (
+	globalAIConfigService     *AIConfigService
+	globalAIConfigServiceOnce sync.Once
+)
 
 // GetGlobalAIConfigService returns the global AI config service instance
 func GetGlobalAIConfigService() *AIConfigService {
-	if globalAIConfigService == nil {
+	globalAIConfigServiceOnce.Do(func() {
 		globalAIConfigService = NewAIConfigService()
-	}
+	})
 	return globalAIConfigService
 }
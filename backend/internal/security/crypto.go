@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 )
 
 // CryptoService handles encryption and decryption operations
@@ -186,12 +187,15 @@ func (cs *CryptoService) IsKeyConfigured(apiKey string) bool {
 }
 
 // Global crypto service instance
-var globalCryptoService *CryptoService
+var (
+	globalCryptoService     *CryptoService
+	globalCryptoServiceOnce sync.Once
+)
 
 // GetGlobalCryptoService returns the global crypto service instance
 func GetGlobalCryptoService() *CryptoService {
-	if globalCryptoService == nil {
+	globalCryptoServiceOnce.Do(func() {
 		globalCryptoService = NewCryptoService()
-	}
+	})
 	return globalCryptoService
 }
@@ -0,0 +1,492 @@
+package security
+
+import (
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// Sanitize modes controlled by HTML_SANITIZE_MODE
+const (
+	SanitizeModeOff       = "off"       // never sanitize
+	SanitizeModeUntrusted = "untrusted" // sanitize content from non-admin authors and UGC
+	SanitizeModeAll       = "all"       // sanitize everything, including admin content
+)
+
+// HTMLPolicy is an allowlist of tags, attributes and URL schemes.
+// Anything not listed is stripped; text content is preserved.
+type HTMLPolicy struct {
+	// AllowedTags maps a tag name to the attributes allowed on it
+	AllowedTags map[string]map[string]bool
+	// GlobalAttrs are allowed on every allowed tag
+	GlobalAttrs map[string]bool
+	// URLSchemes lists schemes accepted in href/src style attributes.
+	// Relative URLs and fragments are always accepted.
+	URLSchemes map[string]bool
+	// RequireNoFollow adds rel="nofollow noopener" to every link
+	RequireNoFollow bool
+	// EmbedDomains are the sites whose players may be embedded in iframes,
+	// each with its subdomains. Other iframes are dropped.
+	EmbedDomains []string
+}
+
+// DefaultEmbedDomains are the video, music and code playground sites
+// embeds are accepted from unless HTML_EMBED_DOMAINS says otherwise
+var DefaultEmbedDomains = []string{
+	"youtube.com", "youtube-nocookie.com", "player.vimeo.com", "player.bilibili.com",
+	"open.spotify.com", "w.soundcloud.com", "music.163.com",
+	"codepen.io", "codesandbox.io", "stackblitz.com", "jsfiddle.net",
+}
+
+// Attributes kept on embedded iframes, and the permissions a player may
+// ask for through allow
+var (
+	embedAttrs       = map[string]bool{"width": true, "height": true, "title": true, "allowfullscreen": true}
+	embedPermissions = map[string]bool{
+		"autoplay": true, "fullscreen": true, "encrypted-media": true, "picture-in-picture": true,
+		"clipboard-write": true, "accelerometer": true, "gyroscope": true, "web-share": true,
+	}
+)
+
+// embedSandbox lets players run in their own origin, never the site's,
+// without navigating the page or opening dialogs
+const embedSandbox = "allow-scripts allow-same-origin allow-popups allow-popups-to-escape-sandbox allow-presentation allow-forms"
+
+// Elements whose content is dropped along with the tag
+var htmlDropContentTags = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   true,
+	"object":   true,
+	"embed":    true,
+	"applet":   true,
+	"noscript": true,
+	"template": true,
+	"frame":    true,
+	"frameset": true,
+	"textarea": true,
+	"select":   true,
+	"svg":      true,
+	"math":     true,
+	// Raw text elements: their content is never tokenized, so it cannot be kept
+	"title":     true,
+	"xmp":       true,
+	"noembed":   true,
+	"noframes":  true,
+	"plaintext": true,
+}
+
+// Attributes whose value is a URL and must pass the scheme check
+var htmlURLAttrs = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"cite":   true,
+	"poster": true,
+}
+
+// NewUGCPolicy returns the default policy for untrusted content: the formatting
+// tags markdown renderers produce, links, images and tables.
+func NewUGCPolicy() *HTMLPolicy {
+	p := &HTMLPolicy{
+		AllowedTags:     make(map[string]map[string]bool),
+		GlobalAttrs:     map[string]bool{"title": true, "lang": true, "dir": true},
+		URLSchemes:      map[string]bool{"http": true, "https": true, "mailto": true},
+		RequireNoFollow: true,
+	}
+
+	for _, tag := range []string{
+		"p", "br", "hr", "h1", "h2", "h3", "h4", "h5", "h6",
+		"b", "strong", "i", "em", "u", "s", "del", "ins", "mark", "small", "sub", "sup",
+		"blockquote", "pre", "code", "kbd", "samp", "var",
+		"ul", "ol", "li", "dl", "dt", "dd",
+		"table", "thead", "tbody", "tfoot", "tr", "caption",
+		"figure", "figcaption", "details", "summary", "abbr", "span", "div",
+	} {
+		p.AllowTag(tag)
+	}
+
+	p.AllowTag("a", "href", "name", "target", "rel")
+	p.AllowTag("img", "src", "alt", "width", "height", "loading")
+	p.AllowTag("th", "colspan", "rowspan", "align", "scope")
+	p.AllowTag("td", "colspan", "rowspan", "align")
+	p.AllowTag("ol", "start", "reversed", "type")
+	p.AllowTag("li", "value")
+	p.AllowTag("blockquote", "cite")
+	p.AllowTag("q", "cite")
+	p.AllowTag("code", "class") // language-xxx classes for syntax highlighting
+
+	return p
+}
+
+// AllowTag adds a tag (and optionally attributes for it) to the allowlist
+func (p *HTMLPolicy) AllowTag(tag string, attrs ...string) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return
+	}
+	if p.AllowedTags[tag] == nil {
+		p.AllowedTags[tag] = make(map[string]bool)
+	}
+	for _, attr := range attrs {
+		attr = strings.ToLower(strings.TrimSpace(attr))
+		if attr != "" {
+			p.AllowedTags[tag][attr] = true
+		}
+	}
+}
+
+// AllowFromSpec extends the allowlist from a spec like "div:class;span:class,style;video:src,controls"
+func (p *HTMLPolicy) AllowFromSpec(spec string) {
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tag, attrList, _ := strings.Cut(entry, ":")
+		var attrs []string
+		if attrList != "" {
+			attrs = strings.Split(attrList, ",")
+		}
+		p.AllowTag(tag, attrs...)
+	}
+}
+
+// Sanitize cleans an HTML fragment against the policy. Text between tags is
+// passed through untouched so markdown syntax around inline HTML survives.
+func (p *HTMLPolicy) Sanitize(input string) string {
+	if !strings.Contains(input, "<") {
+		return input
+	}
+
+	var out strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(input))
+	dropDepth := 0
+	var dropTag string
+
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			return out.String()
+		}
+
+		token := tokenizer.Token()
+
+		if dropDepth > 0 {
+			switch tt {
+			case html.StartTagToken:
+				if token.Data == dropTag {
+					dropDepth++
+				}
+			case html.EndTagToken:
+				if token.Data == dropTag {
+					dropDepth--
+				}
+			}
+			continue
+		}
+
+		switch tt {
+		case html.TextToken:
+			out.Write(tokenizer.Raw())
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if token.Data == "iframe" {
+				if embed, ok := p.embed(token); ok {
+					out.WriteString(embed.String())
+					out.WriteString("</iframe>")
+				}
+			}
+			if htmlDropContentTags[token.Data] {
+				if tt == html.StartTagToken {
+					dropTag = token.Data
+					dropDepth = 1
+				}
+				continue
+			}
+			allowedAttrs, ok := p.AllowedTags[token.Data]
+			if !ok {
+				continue
+			}
+			token.Attr = p.filterAttrs(token.Data, token.Attr, allowedAttrs)
+			out.WriteString(token.String())
+
+		case html.EndTagToken:
+			if _, ok := p.AllowedTags[token.Data]; ok {
+				out.WriteString(token.String())
+			}
+
+		case html.CommentToken, html.DoctypeToken:
+			// Comments can hide conditional markup; drop them entirely
+		}
+	}
+}
+
+func (p *HTMLPolicy) filterAttrs(tag string, attrs []html.Attribute, allowed map[string]bool) []html.Attribute {
+	filtered := make([]html.Attribute, 0, len(attrs))
+	hasHref := false
+
+	for _, attr := range attrs {
+		key := strings.ToLower(attr.Key)
+		if attr.Namespace != "" || strings.HasPrefix(key, "on") {
+			continue
+		}
+		if !allowed[key] && !p.GlobalAttrs[key] {
+			continue
+		}
+		if htmlURLAttrs[key] {
+			if !p.isSafeURL(attr.Val) {
+				continue
+			}
+			hasHref = hasHref || key == "href"
+		}
+		if key == "rel" && p.RequireNoFollow {
+			continue // replaced below
+		}
+		filtered = append(filtered, html.Attribute{Key: key, Val: attr.Val})
+	}
+
+	if tag == "a" && hasHref && p.RequireNoFollow {
+		filtered = append(filtered, html.Attribute{Key: "rel", Val: "nofollow noopener noreferrer"})
+	}
+
+	return filtered
+}
+
+// embed returns an iframe as it is kept: from an allowed site over https,
+// sandboxed and loaded lazily with only known attributes. Plain http and
+// protocol-relative addresses are rewritten to https.
+func (p *HTMLPolicy) embed(token html.Token) (html.Token, bool) {
+	var src string
+	for _, attr := range token.Attr {
+		if strings.ToLower(attr.Key) == "src" && attr.Namespace == "" {
+			src = strings.TrimSpace(attr.Val)
+		}
+	}
+	if strings.HasPrefix(src, "//") {
+		src = "https:" + src
+	}
+	u, err := url.Parse(src)
+	if err != nil || u.User != nil || u.Port() != "" || !p.AllowsEmbed(u.Hostname()) {
+		return token, false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		u.Scheme = "https"
+	default:
+		return token, false
+	}
+
+	attrs := []html.Attribute{{Key: "src", Val: u.String()}}
+	for _, attr := range token.Attr {
+		key := strings.ToLower(attr.Key)
+		switch {
+		case attr.Namespace != "":
+		case embedAttrs[key]:
+			attrs = append(attrs, html.Attribute{Key: key, Val: attr.Val})
+		case key == "allow":
+			var allowed []string
+			for _, permission := range strings.Split(attr.Val, ";") {
+				// Permissions may name origins, as in "fullscreen 'self'"
+				name, _, _ := strings.Cut(strings.TrimSpace(permission), " ")
+				if embedPermissions[strings.ToLower(name)] {
+					allowed = append(allowed, strings.ToLower(name))
+				}
+			}
+			if len(allowed) > 0 {
+				attrs = append(attrs, html.Attribute{Key: "allow", Val: strings.Join(allowed, "; ")})
+			}
+		}
+	}
+	attrs = append(attrs,
+		html.Attribute{Key: "sandbox", Val: embedSandbox},
+		html.Attribute{Key: "loading", Val: "lazy"},
+		html.Attribute{Key: "referrerpolicy", Val: "strict-origin-when-cross-origin"},
+	)
+	return html.Token{Type: html.StartTagToken, Data: "iframe", Attr: attrs}, true
+}
+
+// AllowsEmbed reports whether iframes from host are kept
+func (p *HTMLPolicy) AllowsEmbed(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, domain := range p.EmbedDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowEmbedsFrom sets the embed allowlist from a comma-separated list of
+// domains; "none" allows no embeds
+func (p *HTMLPolicy) AllowEmbedsFrom(list string) {
+	p.EmbedDomains = nil
+	for _, domain := range strings.Split(list, ",") {
+		domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" && domain != "none" {
+			p.EmbedDomains = append(p.EmbedDomains, domain)
+		}
+	}
+}
+
+var htmlURLSchemePattern = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*):`)
+
+func (p *HTMLPolicy) isSafeURL(raw string) bool {
+	// Markdown renderers decode character references in link targets
+	// ("javascript&colon;"), and browsers ignore whitespace and control
+	// characters inside schemes ("java\tscript:")
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, html.UnescapeString(raw))
+
+	match := htmlURLSchemePattern.FindStringSubmatch(cleaned)
+	if match == nil {
+		// Relative path, fragment or protocol-relative URL
+		return true
+	}
+	return p.URLSchemes[strings.ToLower(match[1])]
+}
+
+var markdownFencePattern = regexp.MustCompile("^\\s{0,3}(```|~~~)")
+var markdownInlineCodePattern = regexp.MustCompile("`[^`\n]+`")
+var markdownLinkTargetPattern = regexp.MustCompile(`\]\(\s*<?([^()\s>]*(?:\([^()\s]*\)[^()\s>]*)*)`)
+
+// markdownLinkDefinitionPattern matches link reference definitions such as
+// "[x]: https://example.com", whose target may start on the next line
+var markdownLinkDefinitionPattern = regexp.MustCompile(`(?m)^ {0,3}\[[^\]\n]+\]:\s*<?([^\s>]+)`)
+
+// SanitizeMarkdown sanitizes inline HTML in markdown while leaving fenced and
+// inline code untouched, so HTML shown as code examples is not mangled.
+func (p *HTMLPolicy) SanitizeMarkdown(input string) string {
+	if !strings.Contains(input, "<") && !strings.Contains(input, "](") && !strings.Contains(input, "]:") {
+		return input
+	}
+
+	var out strings.Builder
+	var pending strings.Builder
+	inFence := false
+	fence := ""
+
+	flush := func() {
+		out.WriteString(p.sanitizeOutsideInlineCode(pending.String()))
+		pending.Reset()
+	}
+
+	lines := strings.SplitAfter(input, "\n")
+	for _, line := range lines {
+		match := markdownFencePattern.FindStringSubmatch(line)
+		switch {
+		case inFence:
+			out.WriteString(line)
+			if match != nil && match[1] == fence {
+				inFence = false
+			}
+		case match != nil:
+			flush()
+			inFence = true
+			fence = match[1]
+			out.WriteString(line)
+		default:
+			pending.WriteString(line)
+		}
+	}
+	flush()
+
+	return out.String()
+}
+
+func (p *HTMLPolicy) sanitizeOutsideInlineCode(segment string) string {
+	var out strings.Builder
+	last := 0
+	for _, loc := range markdownInlineCodePattern.FindAllStringIndex(segment, -1) {
+		out.WriteString(p.sanitizeMarkdownText(segment[last:loc[0]]))
+		out.WriteString(segment[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	out.WriteString(p.sanitizeMarkdownText(segment[last:]))
+	return out.String()
+}
+
+// sanitizeMarkdownText sanitizes inline HTML and neutralizes markdown link
+// targets with unsafe schemes, inline such as [x](javascript:...) and in
+// reference definitions such as [x]: javascript:...
+func (p *HTMLPolicy) sanitizeMarkdownText(text string) string {
+	text = p.Sanitize(text)
+	for _, pattern := range []*regexp.Regexp{markdownLinkTargetPattern, markdownLinkDefinitionPattern} {
+		text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+			target := pattern.FindStringSubmatch(match)[1]
+			if p.isSafeURL(target) {
+				return match
+			}
+			return match[:strings.LastIndex(match, target)] + "#"
+		})
+	}
+	return text
+}
+
+// SanitizeContent sanitizes content according to its content type
+func (p *HTMLPolicy) SanitizeContent(content, contentType string) string {
+	if contentType == "html" {
+		return p.Sanitize(content)
+	}
+	return p.SanitizeMarkdown(content)
+}
+
+var (
+	globalHTMLPolicy     *HTMLPolicy
+	globalHTMLPolicyOnce sync.Once
+)
+
+// GetGlobalHTMLPolicy returns the UGC policy extended with HTML_SANITIZE_ALLOW
+// and embedding players from HTML_EMBED_DOMAINS, DefaultEmbedDomains when
+// unset
+func GetGlobalHTMLPolicy() *HTMLPolicy {
+	globalHTMLPolicyOnce.Do(func() {
+		globalHTMLPolicy = NewUGCPolicy()
+		if spec := os.Getenv("HTML_SANITIZE_ALLOW"); spec != "" {
+			globalHTMLPolicy.AllowFromSpec(spec)
+		}
+		globalHTMLPolicy.EmbedDomains = DefaultEmbedDomains
+		if domains := os.Getenv("HTML_EMBED_DOMAINS"); domains != "" {
+			globalHTMLPolicy.AllowEmbedsFrom(domains)
+		}
+		if os.Getenv("HTML_SANITIZE_NOFOLLOW") == "false" {
+			globalHTMLPolicy.RequireNoFollow = false
+		}
+	})
+	return globalHTMLPolicy
+}
+
+// GetSanitizeMode returns the configured HTML_SANITIZE_MODE, defaulting to untrusted
+func GetSanitizeMode() string {
+	switch mode := strings.ToLower(os.Getenv("HTML_SANITIZE_MODE")); mode {
+	case SanitizeModeOff, SanitizeModeAll:
+		return mode
+	default:
+		return SanitizeModeUntrusted
+	}
+}
+
+// ShouldSanitize reports whether content from an author with the given trust
+// level must be sanitized under the current mode
+func ShouldSanitize(trusted bool) bool {
+	switch GetSanitizeMode() {
+	case SanitizeModeOff:
+		return false
+	case SanitizeModeAll:
+		return true
+	default:
+		return !trusted
+	}
+}
+
+// SanitizeOnRender reports whether stored content is sanitized again when served
+func SanitizeOnRender() bool {
+	return GetSanitizeMode() != SanitizeModeOff && os.Getenv("HTML_SANITIZE_ON_RENDER") == "true"
+}
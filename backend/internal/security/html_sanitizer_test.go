@@ -0,0 +1,119 @@
+package security
+
+import "testing"
+
+func TestHTMLPolicySanitize(t *testing.T) {
+	policy := NewUGCPolicy()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain text", "a < b && c > d", "a < b && c > d"},
+		{"script removed with content", "hi<script>alert(1)</script>!", "hi!"},
+		{"event handler stripped", `<img src="/a.png" onerror="alert(1)">`, `<img src="/a.png">`},
+		{"javascript url stripped", `<a href="java&#x09;script:alert(1)">x</a>`, `<a>x</a>`},
+		{"link gets nofollow", `<a href="https://example.com">x</a>`, `<a href="https://example.com" rel="nofollow noopener noreferrer">x</a>`},
+		{"unknown tag unwrapped", "<blink>hi</blink>", "hi"},
+		{"raw text element dropped", "<xmp><script>alert(1)</script></xmp>ok", "ok"},
+		{"comment dropped", "a<!-- <script> -->b", "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Sanitize(tt.input); got != tt.want {
+				t.Fatalf("Sanitize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLPolicySanitizeMarkdown(t *testing.T) {
+	policy := NewUGCPolicy()
+
+	input := "# Title\n\nUse `<script>` tags carefully.\n\n```html\n<script>alert(1)</script>\n```\n\n<script>alert(2)</script>[x](javascript:alert(3))\n"
+	want := "# Title\n\nUse `<script>` tags carefully.\n\n```html\n<script>alert(1)</script>\n```\n\n[x](#)\n"
+
+	if got := policy.SanitizeMarkdown(input); got != want {
+		t.Fatalf("SanitizeMarkdown() = %q, want %q", got, want)
+	}
+}
+
+func TestHTMLPolicySanitizeMarkdownLinkDefinitions(t *testing.T) {
+	policy := NewUGCPolicy()
+
+	input := "[x] and [y] and [z]\n\n[x]: javascript:alert(1)\n  [y]:\n    JavaScript:alert(2) \"t\"\n[z]: https://example.com/a \"ok\"\n\n```\n[w]: javascript:kept(3)\n```\n"
+	want := "[x] and [y] and [z]\n\n[x]: #\n  [y]:\n    # \"t\"\n[z]: https://example.com/a \"ok\"\n\n```\n[w]: javascript:kept(3)\n```\n"
+
+	if got := policy.SanitizeMarkdown(input); got != want {
+		t.Fatalf("SanitizeMarkdown() = %q, want %q", got, want)
+	}
+}
+
+func TestHTMLPolicySanitizeMarkdownEncodedSchemes(t *testing.T) {
+	policy := NewUGCPolicy()
+
+	for _, colon := range []string{"&colon;", "&#58;", "&#x3a;", "&#X3A;"} {
+		input := "[x](javascript" + colon + "alert(1)) [y]\n\n[y]: javascript" + colon + "alert(2)\n"
+		want := "[x](#) [y]\n\n[y]: #\n"
+		if got := policy.SanitizeMarkdown(input); got != want {
+			t.Errorf("SanitizeMarkdown(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	// Encoded characters in safe targets are kept
+	input := "[x](https&colon;//example.com/?a=1&amp;b=2) [y](/search?q=a&#58;b)\n"
+	if got := policy.SanitizeMarkdown(input); got != input {
+		t.Errorf("SanitizeMarkdown(%q) = %q, want it unchanged", input, got)
+	}
+}
+
+func TestHTMLPolicyAllowFromSpec(t *testing.T) {
+	policy := NewUGCPolicy()
+	policy.AllowFromSpec("video:src,controls; span:class")
+
+	got := policy.Sanitize(`<video src="/v.mp4" controls autoplay></video><span class="x" style="color:red">y</span>`)
+	want := `<video src="/v.mp4" controls=""></video><span class="x">y</span>`
+	if got != want {
+		t.Fatalf("Sanitize() = %q, want %q", got, want)
+	}
+}
+
+func TestHTMLPolicyEmbeds(t *testing.T) {
+	policy := NewUGCPolicy()
+	if got := policy.Sanitize(`<iframe src="https://www.youtube.com/embed/x"></iframe>ok`); got != "ok" {
+		t.Fatalf("iframe kept without an allowlist: %q", got)
+	}
+	policy.AllowEmbedsFrom("youtube.com, player.bilibili.com")
+
+	const kept = ` sandbox="` + embedSandbox + `" loading="lazy" referrerpolicy="strict-origin-when-cross-origin"></iframe>`
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"allowed", `<iframe src="https://www.youtube.com/embed/x" width="560" allow="autoplay; camera; fullscreen 'self'" onload="alert(1)" srcdoc="<script>">fallback</iframe>!`,
+			`<iframe src="https://www.youtube.com/embed/x" width="560" allow="autoplay; fullscreen"` + kept + `!`},
+		{"rewritten to https", `<iframe src="//player.bilibili.com/player.html?bvid=1&amp;page=1"/>`,
+			`<iframe src="https://player.bilibili.com/player.html?bvid=1&amp;page=1"` + kept},
+		{"other origin", `<iframe src="https://evil.example/youtube.com"><script>alert(1)</script></iframe>ok`, "ok"},
+		{"lookalike domain", `<iframe src="https://notyoutube.com/embed/x"></iframe>`, ""},
+		{"javascript url", `<iframe src="javascript://youtube.com/%0aalert(1)"></iframe>`, ""},
+		{"credentials", `<iframe src="https://youtube.com@evil.example/"></iframe>`, ""},
+		{"spec cannot allow iframes", `<iframe src="https://evil.example/"></iframe>`, ""},
+	}
+	policy.AllowFromSpec("iframe:src")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Sanitize(tt.input); got != tt.want {
+				t.Fatalf("Sanitize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	policy.AllowEmbedsFrom("none")
+	if policy.AllowsEmbed("youtube.com") {
+		t.Error(`"none" left embeds allowed`)
+	}
+}
@@ -0,0 +1,625 @@
+package security
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret references have the form "<scheme>://<path>[#<field>]", for example:
+//
+//	env://OPENAI_API_KEY
+//	file:///run/secrets/jwt_secret
+//	file:///etc/kuno/secrets.env#JWT_SECRET
+//	vault://secret/data/kuno#openai_api_key
+//	aws-sm://prod/kuno#JWT_SECRET
+//
+// Values referenced this way are resolved at runtime and never written to the database.
+var secretSchemes = []string{"env", "file", "vault", "aws-sm"}
+
+// ErrSecretRefNotAllowed is returned for references an admin submits with a
+// scheme the operator has not allowed over the API
+var ErrSecretRefNotAllowed = errors.New("secret reference scheme not allowed")
+
+// defaultAPISecretSchemes are the schemes admins may use in settings. env://
+// and file:// would let them read the server's environment and files, and
+// send them to a provider URL of their choosing.
+var defaultAPISecretSchemes = []string{"vault", "aws-sm"}
+
+// SecretProvider resolves secret references for a single backend
+type SecretProvider interface {
+	Scheme() string
+	Fetch(path, field string) (string, error)
+}
+
+// SecretRotationHook is called when a cached secret changes value
+type SecretRotationHook func(ref string)
+
+// cachedSecret is a resolved secret with its fetch time
+type cachedSecret struct {
+	value     string
+	fetchedAt time.Time
+}
+
+// SecretManager resolves secret references with caching and rotation detection
+type SecretManager struct {
+	mu        sync.RWMutex
+	providers map[string]SecretProvider
+	cache     map[string]cachedSecret
+	ttl       time.Duration
+	hooks     []SecretRotationHook
+	watching  bool
+}
+
+// NewSecretManager creates a secret manager with the built-in providers
+func NewSecretManager(ttl time.Duration) *SecretManager {
+	sm := &SecretManager{
+		providers: make(map[string]SecretProvider),
+		cache:     make(map[string]cachedSecret),
+		ttl:       ttl,
+	}
+
+	sm.RegisterProvider(&EnvSecretProvider{})
+	sm.RegisterProvider(&FileSecretProvider{})
+	sm.RegisterProvider(NewVaultSecretProvider())
+	sm.RegisterProvider(NewAWSSecretsManagerProvider())
+
+	return sm
+}
+
+// RegisterProvider adds or replaces a provider for its scheme
+func (sm *SecretManager) RegisterProvider(provider SecretProvider) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.providers[provider.Scheme()] = provider
+}
+
+// OnRotate registers a hook that fires whenever a cached secret changes
+func (sm *SecretManager) OnRotate(hook SecretRotationHook) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.hooks = append(sm.hooks, hook)
+}
+
+// IsSecretRef reports whether a value is a secret reference rather than a literal
+func IsSecretRef(value string) bool {
+	for _, scheme := range secretSchemes {
+		if strings.HasPrefix(value, scheme+"://") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSecretRef splits a reference into scheme, path and optional field
+func parseSecretRef(ref string) (scheme, path, field string, err error) {
+	parts := strings.SplitN(ref, "://", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", "", fmt.Errorf("invalid secret reference: %s", ref)
+	}
+
+	scheme = parts[0]
+	path = parts[1]
+	if idx := strings.LastIndex(path, "#"); idx >= 0 {
+		field = path[idx+1:]
+		path = path[:idx]
+	}
+
+	return scheme, path, field, nil
+}
+
+// Resolve returns the value for a reference, using the cache while it is fresh.
+// Literal (non-reference) values are returned unchanged.
+func (sm *SecretManager) Resolve(ref string) (string, error) {
+	if !IsSecretRef(ref) {
+		return ref, nil
+	}
+
+	sm.mu.RLock()
+	cached, ok := sm.cache[ref]
+	sm.mu.RUnlock()
+	if ok && time.Since(cached.fetchedAt) < sm.ttl {
+		return cached.value, nil
+	}
+
+	value, err := sm.fetch(ref)
+	if err != nil {
+		// Serve the stale value rather than failing if the backend is briefly unavailable
+		if ok {
+			slog.Warn("Secret backend error, using cached value", "ref", redactSecretRef(ref), "error", err)
+			return cached.value, nil
+		}
+		return "", err
+	}
+
+	sm.store(ref, value)
+	return value, nil
+}
+
+// fetch resolves a reference directly against its provider
+func (sm *SecretManager) fetch(ref string) (string, error) {
+	scheme, path, field, err := parseSecretRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	sm.mu.RLock()
+	provider, ok := sm.providers[scheme]
+	sm.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no secret provider registered for scheme %q", scheme)
+	}
+
+	value, err := provider.Fetch(path, field)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %s: %v", redactSecretRef(ref), err)
+	}
+	if value == "" {
+		return "", fmt.Errorf("secret %s resolved to an empty value", redactSecretRef(ref))
+	}
+
+	return value, nil
+}
+
+// store updates the cache and fires rotation hooks when the value changed
+func (sm *SecretManager) store(ref, value string) {
+	sm.mu.Lock()
+	previous, existed := sm.cache[ref]
+	sm.cache[ref] = cachedSecret{value: value, fetchedAt: time.Now()}
+	hooks := append([]SecretRotationHook(nil), sm.hooks...)
+	sm.mu.Unlock()
+
+	if existed && previous.value != value {
+		slog.Info("Secret rotated", "ref", redactSecretRef(ref))
+		for _, hook := range hooks {
+			hook(ref)
+		}
+	}
+}
+
+// Refresh re-fetches every cached reference, firing rotation hooks for changed values
+func (sm *SecretManager) Refresh() map[string]string {
+	sm.mu.RLock()
+	refs := make([]string, 0, len(sm.cache))
+	for ref := range sm.cache {
+		refs = append(refs, ref)
+	}
+	sm.mu.RUnlock()
+
+	failures := make(map[string]string)
+	for _, ref := range refs {
+		value, err := sm.fetch(ref)
+		if err != nil {
+			failures[redactSecretRef(ref)] = err.Error()
+			continue
+		}
+		sm.store(ref, value)
+	}
+
+	return failures
+}
+
+// StartRotationWatcher periodically refreshes cached secrets to pick up
+// rotations. Only the first call starts a watcher.
+func (sm *SecretManager) StartRotationWatcher(interval time.Duration) {
+	sm.mu.Lock()
+	if sm.watching {
+		sm.mu.Unlock()
+		return
+	}
+	sm.watching = true
+	sm.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if failures := sm.Refresh(); len(failures) > 0 {
+				slog.Warn("Secret refresh completed with failures", "failures", len(failures))
+			}
+		}
+	}()
+}
+
+// WatchRotations makes sure cached secrets are refreshed in the background,
+// every cache TTL unless a watcher already runs, so callers can keep a
+// resolved value and learn of rotations through OnRotate
+func (sm *SecretManager) WatchRotations() {
+	sm.StartRotationWatcher(sm.ttl)
+}
+
+// Status returns provider availability and cached references (never values)
+func (sm *SecretManager) Status() map[string]interface{} {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	providers := make(map[string]bool)
+	for scheme, provider := range sm.providers {
+		configured := true
+		if checker, ok := provider.(interface{ IsConfigured() bool }); ok {
+			configured = checker.IsConfigured()
+		}
+		providers[scheme] = configured
+	}
+
+	cached := make([]map[string]interface{}, 0, len(sm.cache))
+	for ref, entry := range sm.cache {
+		cached = append(cached, map[string]interface{}{
+			"ref":        redactSecretRef(ref),
+			"fetched_at": entry.fetchedAt,
+		})
+	}
+
+	return map[string]interface{}{
+		"providers":   providers,
+		"cached_refs": cached,
+		"cache_ttl":   sm.ttl.String(),
+	}
+}
+
+// redactSecretRef strips any query string, which may carry tokens, from a reference for logging
+func redactSecretRef(ref string) string {
+	if idx := strings.Index(ref, "?"); idx >= 0 {
+		return ref[:idx]
+	}
+	return ref
+}
+
+// EnvSecretProvider resolves env://NAME references
+type EnvSecretProvider struct{}
+
+func (p *EnvSecretProvider) Scheme() string { return "env" }
+
+func (p *EnvSecretProvider) Fetch(path, _ string) (string, error) {
+	value, ok := os.LookupEnv(path)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", path)
+	}
+	return value, nil
+}
+
+// FileSecretProvider resolves file:// references. Without a field the whole file
+// (trimmed) is the secret, as with Docker/Kubernetes secret mounts. With a field the
+// file is parsed as KEY=VALUE lines, as with .env files.
+type FileSecretProvider struct{}
+
+func (p *FileSecretProvider) Scheme() string { return "file" }
+
+func (p *FileSecretProvider) Fetch(path, field string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	if field == "" {
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	values := parseEnvFile(string(data))
+	value, ok := values[field]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in %s", field, path)
+	}
+	return value, nil
+}
+
+// parseEnvFile parses KEY=VALUE lines, ignoring comments and optional quotes
+func parseEnvFile(content string) map[string]string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	return values
+}
+
+// VaultSecretProvider resolves vault://mount/data/path#field references against a
+// HashiCorp Vault KV store using VAULT_ADDR and VAULT_TOKEN (or VAULT_TOKEN_FILE).
+type VaultSecretProvider struct {
+	addr      string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+// NewVaultSecretProvider creates a Vault provider from environment configuration
+func NewVaultSecretProvider() *VaultSecretProvider {
+	token := os.Getenv("VAULT_TOKEN")
+	if tokenFile := os.Getenv("VAULT_TOKEN_FILE"); token == "" && tokenFile != "" {
+		if data, err := os.ReadFile(tokenFile); err == nil {
+			token = strings.TrimSpace(string(data))
+		}
+	}
+
+	return &VaultSecretProvider{
+		addr:      strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"),
+		token:     token,
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *VaultSecretProvider) Scheme() string { return "vault" }
+
+func (p *VaultSecretProvider) IsConfigured() bool {
+	return p.addr != "" && p.token != ""
+}
+
+func (p *VaultSecretProvider) Fetch(path, field string) (string, error) {
+	if !p.IsConfigured() {
+		return "", fmt.Errorf("vault is not configured (set VAULT_ADDR and VAULT_TOKEN)")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, p.addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	if p.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %v", err)
+	}
+
+	// KV v2 nests the secret under data.data, KV v1 returns it directly under data
+	data := payload.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	if field == "" {
+		field = "value"
+	}
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("field %s not found in vault secret", field)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// AWSSecretsManagerProvider resolves aws-sm://secret-id#json-key references using
+// the standard AWS_* credential environment variables and SigV4 request signing.
+type AWSSecretsManagerProvider struct {
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	endpoint     string
+	client       *http.Client
+}
+
+// NewAWSSecretsManagerProvider creates an AWS Secrets Manager provider from the environment
+func NewAWSSecretsManagerProvider() *AWSSecretsManagerProvider {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	endpoint := os.Getenv("AWS_SECRETS_MANAGER_ENDPOINT")
+	if endpoint == "" && region != "" {
+		endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region)
+	}
+
+	return &AWSSecretsManagerProvider{
+		region:       region,
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		endpoint:     strings.TrimRight(endpoint, "/"),
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *AWSSecretsManagerProvider) Scheme() string { return "aws-sm" }
+
+func (p *AWSSecretsManagerProvider) IsConfigured() bool {
+	return p.region != "" && p.accessKey != "" && p.secretKey != ""
+}
+
+func (p *AWSSecretsManagerProvider) Fetch(path, field string) (string, error) {
+	if !p.IsConfigured() {
+		return "", fmt.Errorf("AWS Secrets Manager is not configured (set AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)")
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": path})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.endpoint+"/", strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	p.signRequest(req, body, time.Now().UTC())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secrets manager returned status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.Unmarshal(respBody, &payload); err != nil {
+		return "", fmt.Errorf("failed to decode secrets manager response: %v", err)
+	}
+
+	if field == "" {
+		return payload.SecretString, nil
+	}
+
+	// Secrets that hold several values are stored as a JSON object
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(payload.SecretString), &values); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, cannot select key %s", field)
+	}
+	value, ok := values[field]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in secret", field)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// signRequest applies AWS Signature Version 4 to the request
+func (p *AWSSecretsManagerProvider) signRequest(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.sessionToken)
+	}
+
+	host := req.URL.Host
+	signedHeaders := "content-type;host;x-amz-date;x-amz-target"
+	canonicalHeaders := "content-type:" + req.Header.Get("Content-Type") + "\n" +
+		"host:" + host + "\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"x-amz-target:" + req.Header.Get("X-Amz-Target") + "\n"
+	if p.sessionToken != "" {
+		signedHeaders += ";x-amz-security-token"
+		canonicalHeaders += "x-amz-security-token:" + p.sessionToken + "\n"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	credentialScope := dateStamp + "/" + p.region + "/secretsmanager/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		credentialScope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+p.secretKey), dateStamp)
+	signingKey = hmacSHA256(signingKey, p.region)
+	signingKey = hmacSHA256(signingKey, "secretsmanager")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.accessKey, credentialScope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Global secret manager instance
+var (
+	globalSecretManager     *SecretManager
+	globalSecretManagerOnce sync.Once
+)
+
+// GetGlobalSecretManager returns the global secret manager instance.
+// SECRETS_CACHE_TTL (seconds, default 300) controls how long resolved values are
+// reused; SECRETS_REFRESH_INTERVAL (seconds, 0 disables) enables rotation polling.
+func GetGlobalSecretManager() *SecretManager {
+	globalSecretManagerOnce.Do(func() {
+		ttl := 5 * time.Minute
+		if seconds, err := strconv.Atoi(os.Getenv("SECRETS_CACHE_TTL")); err == nil && seconds > 0 {
+			ttl = time.Duration(seconds) * time.Second
+		}
+		globalSecretManager = NewSecretManager(ttl)
+
+		if seconds, err := strconv.Atoi(os.Getenv("SECRETS_REFRESH_INTERVAL")); err == nil && seconds > 0 {
+			globalSecretManager.StartRotationWatcher(time.Duration(seconds) * time.Second)
+		}
+	})
+	return globalSecretManager
+}
+
+// ResolveSecret resolves a reference via the global secret manager
+func ResolveSecret(ref string) (string, error) {
+	return GetGlobalSecretManager().Resolve(ref)
+}
+
+// CheckAPISecretRef returns ErrSecretRefNotAllowed when value is a secret
+// reference admins may not submit through the API. SECRETS_API_SCHEMES
+// lists the allowed schemes, vault and aws-sm by default.
+func CheckAPISecretRef(value string) error {
+	if !IsSecretRef(value) {
+		return nil
+	}
+	allowed := defaultAPISecretSchemes
+	if schemes := os.Getenv("SECRETS_API_SCHEMES"); schemes != "" {
+		allowed = strings.Split(schemes, ",")
+	}
+	scheme, _, _ := strings.Cut(value, "://")
+	for _, candidate := range allowed {
+		if strings.TrimSpace(candidate) == scheme {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s://", ErrSecretRefNotAllowed, scheme)
+}
@@ -0,0 +1,39 @@
+package security
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckAPISecretRef(t *testing.T) {
+	for value, allowed := range map[string]bool{
+		"sk-literal":                  true,
+		"vault://secret/data/kuno#ai": true,
+		"aws-sm://prod/kuno#AI":       true,
+		"env://JWT_SECRET":            false,
+		"file:///etc/passwd":          false,
+	} {
+		if err := CheckAPISecretRef(value); (err == nil) != allowed || (err != nil && !errors.Is(err, ErrSecretRefNotAllowed)) {
+			t.Errorf("CheckAPISecretRef(%q) = %v, allowed %v", value, err, allowed)
+		}
+	}
+
+	t.Setenv("SECRETS_API_SCHEMES", "vault, env")
+	if err := CheckAPISecretRef("env://OPENAI_API_KEY"); err != nil {
+		t.Errorf("env:// with SECRETS_API_SCHEMES: %v", err)
+	}
+	if err := CheckAPISecretRef("aws-sm://prod/kuno"); !errors.Is(err, ErrSecretRefNotAllowed) {
+		t.Errorf("aws-sm:// left out of SECRETS_API_SCHEMES: %v", err)
+	}
+}
+
+func TestEncryptAIConfigRejectsLocalSecretRefs(t *testing.T) {
+	input := &InputAIConfig{Providers: map[string]InputProviderConfig{"openai": {Provider: "openai", APIKey: "file:///etc/kuno/secrets.env#JWT_SECRET"}}}
+	service := &AIConfigService{}
+	if _, err := service.EncryptAIConfig(input); !errors.Is(err, ErrSecretRefNotAllowed) {
+		t.Errorf("EncryptAIConfig err = %v, want ErrSecretRefNotAllowed", err)
+	}
+	if _, err := service.MergeWithExisting(input, &SecureAIConfig{}); !errors.Is(err, ErrSecretRefNotAllowed) {
+		t.Errorf("MergeWithExisting err = %v, want ErrSecretRefNotAllowed", err)
+	}
+}
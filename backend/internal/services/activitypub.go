@@ -0,0 +1,843 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnknownActor           = errors.New("unknown actor")
+	ErrInvalidActivity        = errors.New("invalid activity")
+	ErrFederatedReplyNotFound = errors.New("reply not found")
+)
+
+const (
+	// ActivityContentType is the media type of ActivityPub documents
+	ActivityContentType = "application/activity+json"
+	// MaxActivitySize limits the body of an incoming activity
+	MaxActivitySize = 1 << 20
+
+	activityStreamsContext = "https://www.w3.org/ns/activitystreams"
+	securityContext        = "https://w3id.org/security/v1"
+	activityStreamsPublic  = "https://www.w3.org/ns/activitystreams#Public"
+
+	activityPubPath = "/api/activitypub"
+	// activityPubKeyPrefix is the SystemSetting holding a site's signing key
+	activityPubKeyPrefix = "activitypub_key_"
+	outboxPageSize       = 20
+	activityPubTimeout   = 15 * time.Second
+)
+
+// remoteActor is the part of a fediverse account's actor document used here
+type remoteActor struct {
+	ID                string      `json:"id"`
+	Type              string      `json:"type"`
+	PreferredUsername string      `json:"preferredUsername"`
+	Name              string      `json:"name"`
+	URL               interface{} `json:"url"`
+	Inbox             string      `json:"inbox"`
+	Endpoints         struct {
+		SharedInbox string `json:"sharedInbox"`
+	} `json:"endpoints"`
+	PublicKey struct {
+		ID           string `json:"id"`
+		Owner        string `json:"owner"`
+		PublicKeyPem string `json:"publicKeyPem"`
+	} `json:"publicKey"`
+}
+
+// activity is an incoming activity. Object is either the id of an object or
+// the object itself.
+type activity struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Actor  string          `json:"actor"`
+	Object json.RawMessage `json:"object"`
+}
+
+// activityObject is the part of an embedded object used here
+type activityObject struct {
+	ID           string      `json:"id"`
+	Type         string      `json:"type"`
+	AttributedTo string      `json:"attributedTo"`
+	Content      string      `json:"content"`
+	URL          interface{} `json:"url"`
+	InReplyTo    string      `json:"inReplyTo"`
+	Published    time.Time   `json:"published"`
+}
+
+// activityPubDelivery is the job payload for JobActivityPubDeliver
+type activityPubDelivery struct {
+	SiteID   uint            `json:"site_id"`
+	Inbox    string          `json:"inbox"`
+	KeyID    string          `json:"key_id"`
+	Activity json.RawMessage `json:"activity"`
+}
+
+// ActivityPubService lets fediverse accounts follow each blog. It serves the
+// blog's actor, outbox and WebFinger documents, handles follows and replies
+// posted to its inbox and sends new and updated articles to followers
+// through the job queue.
+type ActivityPubService struct {
+	db            func() *gorm.DB
+	client        *http.Client
+	checkURL      func(*url.URL) error
+	username      string
+	acceptReplies bool
+	enabled       func() bool
+	baseURL       func(siteID uint) string
+	now           func() time.Time
+	actors        *cache.Namespace
+
+	keysMu sync.Mutex
+	keys   map[uint]*rsa.PrivateKey
+}
+
+// NewActivityPubService creates an ActivityPub service from the
+// configuration. Actor and inbox addresses come from remote servers, so it
+// only fetches and delivers to public addresses.
+func NewActivityPubService() *ActivityPubService {
+	cfg := config.Get().ActivityPub
+	client := newPublicClient()
+	client.Timeout = activityPubTimeout
+	return &ActivityPubService{
+		db:            func() *gorm.DB { return database.DB },
+		client:        client,
+		checkURL:      checkPreviewURL,
+		username:      cfg.Username,
+		acceptReplies: cfg.AcceptReplies,
+		enabled:       func() bool { return GetGlobalFeatureService().Enabled(FeatureActivityPub) },
+		baseURL:       siteBaseURL,
+		now:           time.Now,
+		actors:        cache.New("activitypub_actors", time.Hour),
+		keys:          make(map[uint]*rsa.PrivateKey),
+	}
+}
+
+func actorURL(base string) string {
+	return strings.TrimRight(base, "/") + activityPubPath + "/actor"
+}
+
+func actorKeyID(base string) string {
+	return actorURL(base) + "#main-key"
+}
+
+func inboxURL(base string) string {
+	return strings.TrimRight(base, "/") + activityPubPath + "/inbox"
+}
+
+func outboxURL(base string) string {
+	return strings.TrimRight(base, "/") + activityPubPath + "/outbox"
+}
+
+func followersURL(base string) string {
+	return strings.TrimRight(base, "/") + activityPubPath + "/followers"
+}
+
+func objectsURL(base string) string {
+	return strings.TrimRight(base, "/") + activityPubPath + "/objects/"
+}
+
+func objectURL(base string, articleID uint) string {
+	return objectsURL(base) + strconv.FormatUint(uint64(articleID), 10)
+}
+
+// Actor returns the actor document of a site
+func (s *ActivityPubService) Actor(siteID uint, base string) (map[string]interface{}, error) {
+	key, err := s.key(siteID)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	var settings models.SiteSettings
+	if err := s.db().Where("site_id = ?", siteID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	name := settings.SiteTitle
+	if name == "" {
+		name = "Blog"
+	}
+
+	actor := map[string]interface{}{
+		"@context":                  []string{activityStreamsContext, securityContext},
+		"id":                        actorURL(base),
+		"type":                      "Person",
+		"preferredUsername":         s.username,
+		"name":                      name,
+		"summary":                   "<p>" + html.EscapeString(settings.SiteSubtitle) + "</p>",
+		"url":                       base,
+		"inbox":                     inboxURL(base),
+		"outbox":                    outboxURL(base),
+		"followers":                 followersURL(base),
+		"endpoints":                 map[string]string{"sharedInbox": inboxURL(base)},
+		"manuallyApprovesFollowers": false,
+		"discoverable":              true,
+		"publicKey": map[string]string{
+			"id":           actorKeyID(base),
+			"owner":        actorURL(base),
+			"publicKeyPem": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		},
+	}
+	if settings.LogoURL != "" {
+		actor["icon"] = map[string]string{"type": "Image", "url": absoluteURL(base, settings.LogoURL)}
+	}
+	return actor, nil
+}
+
+// WebFinger resolves acct:username@host, or the actor URL itself, to the
+// actor of a site
+func (s *ActivityPubService) WebFinger(base, resource string) (map[string]interface{}, error) {
+	parsed, err := url.Parse(base)
+	if err != nil || parsed.Host == "" {
+		return nil, ErrUnknownActor
+	}
+	subject := "acct:" + s.username + "@" + parsed.Host
+	if !strings.EqualFold(resource, subject) && resource != actorURL(base) {
+		return nil, ErrUnknownActor
+	}
+	return map[string]interface{}{
+		"subject": subject,
+		"aliases": []string{actorURL(base), base},
+		"links": []map[string]string{
+			{"rel": "self", "type": ActivityContentType, "href": actorURL(base)},
+			{"rel": "http://webfinger.net/rel/profile-page", "type": "text/html", "href": base},
+		},
+	}, nil
+}
+
+// Outbox returns the outbox collection of a site, or one page of it when
+// page is at least 1. Each page lists the Create activities of published
+// articles, newest first.
+func (s *ActivityPubService) Outbox(ctx context.Context, siteID uint, base string, page int) (map[string]interface{}, error) {
+	query := s.db().WithContext(ctx).Model(&models.Article{}).Where("site_id = ? AND created_at <= ?", siteID, s.now())
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		collection := map[string]interface{}{
+			"@context":   activityStreamsContext,
+			"id":         outboxURL(base),
+			"type":       "OrderedCollection",
+			"totalItems": total,
+		}
+		if total > 0 {
+			pages := (total + outboxPageSize - 1) / outboxPageSize
+			collection["first"] = outboxURL(base) + "?page=1"
+			collection["last"] = outboxURL(base) + "?page=" + strconv.FormatInt(pages, 10)
+		}
+		return collection, nil
+	}
+
+	var articles []models.Article
+	if err := query.Order("created_at DESC").Offset((page - 1) * outboxPageSize).Limit(outboxPageSize).
+		Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	items := make([]map[string]interface{}, len(articles))
+	for i := range articles {
+		items[i] = s.articleActivity(base, &articles[i], "Create")
+	}
+	collection := map[string]interface{}{
+		"@context":     activityStreamsContext,
+		"id":           outboxURL(base) + "?page=" + strconv.Itoa(page),
+		"type":         "OrderedCollectionPage",
+		"partOf":       outboxURL(base),
+		"totalItems":   total,
+		"orderedItems": items,
+	}
+	if int64(page*outboxPageSize) < total {
+		collection["next"] = outboxURL(base) + "?page=" + strconv.Itoa(page+1)
+	}
+	if page > 1 {
+		collection["prev"] = outboxURL(base) + "?page=" + strconv.Itoa(page-1)
+	}
+	return collection, nil
+}
+
+// Followers returns the followers collection of a site. Accounts are not
+// listed, only their number.
+func (s *ActivityPubService) Followers(ctx context.Context, siteID uint, base string) (map[string]interface{}, error) {
+	var total int64
+	if err := s.db().WithContext(ctx).Model(&models.Follower{}).Where("site_id = ?", siteID).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"@context":   activityStreamsContext,
+		"id":         followersURL(base),
+		"type":       "OrderedCollection",
+		"totalItems": total,
+	}, nil
+}
+
+// Object returns the ActivityPub object of a published article
+func (s *ActivityPubService) Object(ctx context.Context, siteID uint, base string, articleID uint) (map[string]interface{}, error) {
+	var article models.Article
+	if err := s.db().WithContext(ctx).Where("site_id = ? AND created_at <= ?", siteID, s.now()).
+		First(&article, articleID).Error; err != nil {
+		return nil, err
+	}
+	object := s.articleObject(base, &article)
+	object["@context"] = activityStreamsContext
+	return object, nil
+}
+
+// articleObject describes an article as an ActivityPub Article: the title,
+// summary and a link to the full text on the blog
+func (s *ActivityPubService) articleObject(base string, article *models.Article) map[string]interface{} {
+	link := articleLink(base, article)
+	content := ""
+	if summary := strings.TrimSpace(article.Summary); summary != "" {
+		content = "<p>" + html.EscapeString(summary) + "</p>"
+	}
+	content += fmt.Sprintf(`<p><a href="%s">%s</a></p>`, html.EscapeString(link), html.EscapeString(link))
+
+	object := map[string]interface{}{
+		"id":           objectURL(base, article.ID),
+		"type":         "Article",
+		"attributedTo": actorURL(base),
+		"name":         article.Title,
+		"content":      content,
+		"url":          link,
+		"published":    article.CreatedAt.UTC().Format(time.RFC3339),
+		"to":           []string{activityStreamsPublic},
+		"cc":           []string{followersURL(base)},
+	}
+	if article.DefaultLang != "" {
+		object["contentMap"] = map[string]string{article.DefaultLang: content}
+	}
+	if article.UpdatedAt.Sub(article.CreatedAt) > time.Minute {
+		object["updated"] = article.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	if article.CoverImageURL != nil && *article.CoverImageURL != "" {
+		image := map[string]string{"type": "Image", "url": absoluteURL(base, *article.CoverImageURL), "name": article.CoverImageAlt}
+		object["image"] = image
+		object["attachment"] = []map[string]string{image}
+	}
+	return object
+}
+
+// articleActivity wraps an article in a Create or Update activity
+func (s *ActivityPubService) articleActivity(base string, article *models.Article, kind string) map[string]interface{} {
+	id := objectURL(base, article.ID) + "#create"
+	published := article.CreatedAt
+	if kind == "Update" {
+		id = fmt.Sprintf("%s#update-%d", objectURL(base, article.ID), article.UpdatedAt.Unix())
+		published = article.UpdatedAt
+	}
+	return map[string]interface{}{
+		"@context":  activityStreamsContext,
+		"id":        id,
+		"type":      kind,
+		"actor":     actorURL(base),
+		"published": published.UTC().Format(time.RFC3339),
+		"to":        []string{activityStreamsPublic},
+		"cc":        []string{followersURL(base)},
+		"object":    s.articleObject(base, article),
+	}
+}
+
+// HandleInbox processes an activity posted to a site's inbox after checking
+// its HTTP signature. Follows are accepted right away; replies to articles
+// are kept for moderation when accepting replies is enabled.
+func (s *ActivityPubService) HandleInbox(ctx context.Context, siteID uint, base string, req *http.Request, body []byte) error {
+	var act activity
+	if err := json.Unmarshal(body, &act); err != nil || act.Type == "" || act.Actor == "" {
+		return fmt.Errorf("%w: not an activity", ErrInvalidActivity)
+	}
+
+	var signer *remoteActor
+	if _, err := verifyRequest(req, body, s.now(), func(keyID string) (*rsa.PublicKey, error) {
+		actor, err := s.fetchActor(ctx, siteID, base, keyID)
+		if err != nil {
+			return nil, err
+		}
+		signer = actor
+		return parsePublicKeyPEM(actor.PublicKey.PublicKeyPem)
+	}); err != nil {
+		return err
+	}
+	if signer.ID != act.Actor {
+		return fmt.Errorf("%w: signed by %s on behalf of %s", ErrInvalidSignature, signer.ID, act.Actor)
+	}
+
+	switch act.Type {
+	case "Follow":
+		return s.follow(ctx, siteID, base, &act, signer)
+	case "Undo":
+		var inner activity
+		if err := json.Unmarshal(act.Object, &inner); err == nil && inner.Type == "Follow" {
+			return s.db().WithContext(ctx).Where("site_id = ? AND actor_id = ?", siteID, act.Actor).
+				Delete(&models.Follower{}).Error
+		}
+	case "Create", "Update":
+		return s.reply(ctx, siteID, base, &act, signer)
+	case "Delete":
+		id := activityObjectID(act.Object)
+		if id == act.Actor {
+			// The account was deleted
+			if err := s.db().WithContext(ctx).Where("site_id = ? AND actor_id = ?", siteID, act.Actor).
+				Delete(&models.Follower{}).Error; err != nil {
+				return err
+			}
+			return s.db().WithContext(ctx).Where("site_id = ? AND actor_id = ?", siteID, act.Actor).
+				Delete(&models.FederatedReply{}).Error
+		}
+		return s.db().WithContext(ctx).Where("site_id = ? AND object_id = ? AND actor_id = ?", siteID, id, act.Actor).
+			Delete(&models.FederatedReply{}).Error
+	}
+	return nil
+}
+
+// follow records a new follower and queues the Accept
+func (s *ActivityPubService) follow(ctx context.Context, siteID uint, base string, act *activity, signer *remoteActor) error {
+	if activityObjectID(act.Object) != actorURL(base) {
+		return fmt.Errorf("%w: follow is not for this blog", ErrInvalidActivity)
+	}
+	if signer.Inbox == "" {
+		return fmt.Errorf("%w: follower has no inbox", ErrInvalidActivity)
+	}
+	username := signer.PreferredUsername
+	if parsed, err := url.Parse(signer.ID); err == nil {
+		username += "@" + parsed.Host
+	}
+	follower := models.Follower{
+		SiteID:      siteID,
+		ActorID:     signer.ID,
+		Username:    username,
+		Inbox:       signer.Inbox,
+		SharedInbox: signer.Endpoints.SharedInbox,
+	}
+	if err := s.db().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "site_id"}, {Name: "actor_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"username", "inbox", "shared_inbox"}),
+	}).Create(&follower).Error; err != nil {
+		return err
+	}
+
+	accept := map[string]interface{}{
+		"@context": activityStreamsContext,
+		"id":       fmt.Sprintf("%s#accept-%d", actorURL(base), s.now().UnixNano()),
+		"type":     "Accept",
+		"actor":    actorURL(base),
+		"object":   act,
+	}
+	return s.enqueue(siteID, base, signer.Inbox, accept)
+}
+
+// reply stores a fediverse post replying to one of the site's articles
+func (s *ActivityPubService) reply(ctx context.Context, siteID uint, base string, act *activity, signer *remoteActor) error {
+	var object activityObject
+	if err := json.Unmarshal(act.Object, &object); err != nil || object.ID == "" {
+		return nil
+	}
+	if object.AttributedTo != "" && object.AttributedTo != act.Actor {
+		return fmt.Errorf("%w: object is not attributed to the actor", ErrInvalidActivity)
+	}
+	content := security.GetGlobalHTMLPolicy().Sanitize(object.Content)
+	if act.Type == "Update" {
+		return s.db().WithContext(ctx).Model(&models.FederatedReply{}).
+			Where("site_id = ? AND object_id = ? AND actor_id = ?", siteID, object.ID, act.Actor).
+			Update("content", content).Error
+	}
+	if !s.acceptReplies || (object.Type != "Note" && object.Type != "Article") {
+		return nil
+	}
+	prefix := objectsURL(base)
+	if !strings.HasPrefix(object.InReplyTo, prefix) {
+		return nil
+	}
+	articleID, err := strconv.ParseUint(strings.TrimPrefix(object.InReplyTo, prefix), 10, 32)
+	if err != nil {
+		return nil
+	}
+	var count int64
+	if err := s.db().WithContext(ctx).Model(&models.Article{}).Where("id = ? AND site_id = ?", articleID, siteID).
+		Count(&count).Error; err != nil || count == 0 {
+		return err
+	}
+
+	name := signer.Name
+	if name == "" {
+		name = signer.PreferredUsername
+	}
+	published := object.Published
+	if published.IsZero() {
+		published = s.now()
+	}
+	reply := models.FederatedReply{
+		SiteID:      siteID,
+		ArticleID:   uint(articleID),
+		Source:      models.ReplySourceFediverse,
+		ObjectID:    object.ID,
+		ActorID:     act.Actor,
+		AuthorName:  name,
+		AuthorURL:   firstLink(signer.URL, signer.ID),
+		Content:     content,
+		URL:         firstLink(object.URL, object.ID),
+		Status:      models.ReplyPending,
+		PublishedAt: published,
+	}
+	result := s.db().WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&reply)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		hooks.Notify(ctx, hooks.CommentPending, &reply)
+	}
+	return nil
+}
+
+// fetchActor loads and caches the actor owning keyID. The request is signed
+// so servers requiring authorized fetch answer it.
+func (s *ActivityPubService) fetchActor(ctx context.Context, siteID uint, base, keyID string) (*remoteActor, error) {
+	id, _, _ := strings.Cut(keyID, "#")
+	var actor remoteActor
+	if s.actors.Get(id, &actor) {
+		return &actor, nil
+	}
+	parsed, err := url.Parse(id)
+	if err != nil || parsed.Scheme != "https" {
+		return nil, fmt.Errorf("actor %q is not an https URL", id)
+	}
+	if err := s.checkURL(parsed); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ActivityContentType)
+	key, err := s.key(siteID)
+	if err != nil {
+		return nil, err
+	}
+	if err := signRequest(req, actorKeyID(base), key, nil, s.now()); err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching actor returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxActivitySize)).Decode(&actor); err != nil {
+		return nil, err
+	}
+	if actor.ID != id || actor.PublicKey.ID != keyID {
+		return nil, errors.New("actor document does not match the key id")
+	}
+	s.actors.Set(id, actor)
+	return &actor, nil
+}
+
+// registerHooks federates published and updated articles
+func (s *ActivityPubService) registerHooks() {
+	hooks.Register(hooks.ArticlePublished, "activitypub", s.publish)
+	hooks.Register(hooks.ArticleUpdated, "activitypub", s.publish)
+}
+
+// publish queues a Create or Update of the article to every follower inbox,
+// sending once per shared inbox
+func (s *ActivityPubService) publish(ctx context.Context, event hooks.Event, payload interface{}) error {
+	article, ok := payload.(*models.Article)
+	if !ok || !s.enabled() {
+		return nil
+	}
+	base := s.baseURL(article.SiteID)
+	if base == "" {
+		return nil
+	}
+	var followers []models.Follower
+	if err := s.db().Where("site_id = ?", article.SiteID).Find(&followers).Error; err != nil {
+		return err
+	}
+	if len(followers) == 0 {
+		return nil
+	}
+
+	kind := "Create"
+	if event == hooks.ArticleUpdated {
+		kind = "Update"
+	}
+	message := s.articleActivity(base, article, kind)
+	seen := make(map[string]bool)
+	for _, follower := range followers {
+		inbox := follower.Inbox
+		if follower.SharedInbox != "" {
+			inbox = follower.SharedInbox
+		}
+		if seen[inbox] {
+			continue
+		}
+		seen[inbox] = true
+		if err := s.enqueue(article.SiteID, base, inbox, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ActivityPubService) enqueue(siteID uint, base, inbox string, message interface{}) error {
+	delivery := activityPubDelivery{SiteID: siteID, Inbox: inbox, KeyID: actorKeyID(base), Activity: mustMarshal(message)}
+	_, err := GetGlobalJobQueue().Enqueue(JobActivityPubDeliver, delivery)
+	return err
+}
+
+// Deliver posts a queued activity to a remote inbox. Followers whose inbox
+// is gone are removed.
+func (s *ActivityPubService) Deliver(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var delivery activityPubDelivery
+	if err := json.Unmarshal(payload, &delivery); err != nil {
+		return nil, err
+	}
+	key, err := s.key(delivery.SiteID)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Inbox, bytes.NewReader(delivery.Activity))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", ActivityContentType)
+	if err := signRequest(req, delivery.KeyID, key, delivery.Activity, s.now()); err != nil {
+		return nil, err
+	}
+	// Inboxes on private addresses never become deliverable, so they are
+	// not retried
+	if err := s.checkURL(req.URL); err != nil {
+		return map[string]string{"skipped": "inbox is not a public address"}, nil
+	}
+	resp, err := s.client.Do(req)
+	if errors.Is(err, ErrPreviewBlocked) {
+		return map[string]string{"skipped": "inbox is not a public address"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		s.db().Where("site_id = ? AND (inbox = ? OR shared_inbox = ?)", delivery.SiteID, delivery.Inbox, delivery.Inbox).
+			Delete(&models.Follower{})
+		return map[string]string{"skipped": "inbox gone"}, nil
+	case resp.StatusCode >= 300:
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("inbox returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return map[string]interface{}{"inbox": delivery.Inbox, "status": resp.StatusCode}, nil
+}
+
+// ListFollowers returns the followers of the site in ctx, newest first
+func (s *ActivityPubService) ListFollowers(ctx context.Context) ([]models.Follower, error) {
+	var followers []models.Follower
+	err := s.db().WithContext(ctx).Order("created_at DESC").Find(&followers).Error
+	return followers, err
+}
+
+// RemoveFollower stops sending articles to a follower of the site in ctx
+func (s *ActivityPubService) RemoveFollower(ctx context.Context, id uint) error {
+	return s.db().WithContext(ctx).Delete(&models.Follower{}, id).Error
+}
+
+// ListReplies returns the federated replies of the site in ctx, optionally
+// limited to one status or article
+func (s *ActivityPubService) ListReplies(ctx context.Context, status string, articleID uint) ([]models.FederatedReply, error) {
+	query := s.db().WithContext(ctx).Order("published_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if articleID != 0 {
+		query = query.Where("article_id = ?", articleID)
+	}
+	var replies []models.FederatedReply
+	err := query.Find(&replies).Error
+	return replies, err
+}
+
+// SetReplyStatus approves or rejects a federated reply
+func (s *ActivityPubService) SetReplyStatus(ctx context.Context, id uint, status string) (*models.FederatedReply, error) {
+	switch status {
+	case models.ReplyPending, models.ReplyApproved, models.ReplyRejected:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidActivity, status)
+	}
+	var reply models.FederatedReply
+	if err := s.db().WithContext(ctx).First(&reply, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFederatedReplyNotFound
+		}
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Model(&reply).Update("status", status).Error; err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
+
+// DeleteReply removes a federated reply
+func (s *ActivityPubService) DeleteReply(ctx context.Context, id uint) error {
+	result := s.db().WithContext(ctx).Delete(&models.FederatedReply{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFederatedReplyNotFound
+	}
+	return nil
+}
+
+// key returns the RSA key a site signs its requests with, creating and
+// storing it encrypted on first use
+func (s *ActivityPubService) key(siteID uint) (*rsa.PrivateKey, error) {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	if key, ok := s.keys[siteID]; ok {
+		return key, nil
+	}
+
+	settingKey := activityPubKeyPrefix + strconv.FormatUint(uint64(siteID), 10)
+	var setting models.SystemSetting
+	if err := s.db().Limit(1).Find(&setting, models.SystemSetting{Key: settingKey}).Error; err != nil {
+		return nil, err
+	}
+	if setting.Value == "" {
+		generated, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(generated)
+		if err != nil {
+			return nil, err
+		}
+		encrypted, err := security.GetGlobalCryptoService().EncryptAPIKey(string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+		if err != nil {
+			return nil, err
+		}
+		value, _ := json.Marshal(map[string]string{"private_key": encrypted})
+		// Another instance may have stored a key first; use whichever won
+		if err := s.db().Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.SystemSetting{Key: settingKey, Value: string(value)}).Error; err != nil {
+			return nil, err
+		}
+		if err := s.db().Limit(1).Find(&setting, models.SystemSetting{Key: settingKey}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var stored struct {
+		PrivateKey string `json:"private_key"`
+	}
+	if err := json.Unmarshal([]byte(setting.Value), &stored); err != nil {
+		return nil, err
+	}
+	decrypted, err := security.GetGlobalCryptoService().DecryptAPIKey(stored.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(decrypted))
+	if block == nil {
+		return nil, errors.New("stored ActivityPub key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("stored ActivityPub key is not an RSA key")
+	}
+	s.keys[siteID] = key
+	return key, nil
+}
+
+// activityObjectID returns the id of an activity's object, which may be
+// given as a plain id or embedded
+func activityObjectID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	var object struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(raw, &object)
+	return object.ID
+}
+
+// firstLink returns the first URL of an ActivityStreams url property, which
+// may be a string, a Link or a list of either
+func firstLink(value interface{}, fallback string) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if href, ok := v["href"].(string); ok {
+			return href
+		}
+	case []interface{}:
+		for _, item := range v {
+			if link := firstLink(item, ""); link != "" {
+				return link
+			}
+		}
+	}
+	return fallback
+}
+
+func absoluteURL(base, path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	out, _ := json.Marshal(v)
+	return out
+}
+
+var (
+	globalActivityPubService *ActivityPubService
+	activityPubServiceOnce   sync.Once
+)
+
+// GetGlobalActivityPubService returns the global ActivityPub service,
+// registering its publish and update hooks on first use
+func GetGlobalActivityPubService() *ActivityPubService {
+	activityPubServiceOnce.Do(func() {
+		globalActivityPubService = NewActivityPubService()
+		globalActivityPubService.registerHooks()
+	})
+	return globalActivityPubService
+}
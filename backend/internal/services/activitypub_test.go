@@ -0,0 +1,232 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestHTTPSignatureRoundTrip(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	body := []byte(`{"type":"Follow"}`)
+	req := httptest.NewRequest(http.MethodPost, "https://blog.example.com/api/activitypub/inbox", bytes.NewReader(body))
+	if err := signRequest(req, "https://remote.example/users/alice#main-key", key, body, now); err != nil {
+		t.Fatal(err)
+	}
+	publicKey := func(string) (*rsa.PublicKey, error) { return &key.PublicKey, nil }
+
+	if keyID, err := verifyRequest(req, body, now, publicKey); err != nil || keyID != "https://remote.example/users/alice#main-key" {
+		t.Fatalf("signature did not verify: %q %v", keyID, err)
+	}
+	if _, err := verifyRequest(req, []byte(`{"type":"Delete"}`), now, publicKey); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for a changed body, got %v", err)
+	}
+	if _, err := verifyRequest(req, body, now.Add(24*time.Hour), publicKey); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for a stale date, got %v", err)
+	}
+	req.Host = "other.example.com"
+	if _, err := verifyRequest(req, body, now, publicKey); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for another host, got %v", err)
+	}
+}
+
+func TestActivityPubFederation(t *testing.T) {
+	newTestDB(t)
+	const base = "https://blog.example.com"
+	remoteKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKIXPublicKey(&remoteKey.PublicKey)
+	var delivered []string
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			delivered = append(delivered, r.URL.Path+" "+r.Header.Get("Signature")+" "+string(body))
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":                server.URL + "/users/alice",
+			"type":              "Person",
+			"preferredUsername": "alice",
+			"name":              "Alice",
+			"url":               server.URL + "/@alice",
+			"inbox":             server.URL + "/users/alice/inbox",
+			"endpoints":         map[string]string{"sharedInbox": server.URL + "/inbox"},
+			"publicKey": map[string]string{
+				"id":           server.URL + "/users/alice#main-key",
+				"owner":        server.URL + "/users/alice",
+				"publicKeyPem": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+			},
+		})
+	}))
+	defer server.Close()
+
+	s := &ActivityPubService{
+		db:            func() *gorm.DB { return database.DB },
+		client:        server.Client(),
+		checkURL:      func(*url.URL) error { return nil },
+		username:      "blog",
+		acceptReplies: true,
+		enabled:       func() bool { return true },
+		baseURL:       func(uint) string { return base },
+		now:           time.Now,
+		actors:        cache.New("activitypub_actors_test", time.Minute),
+		keys:          make(map[uint]*rsa.PrivateKey),
+	}
+	ctx := context.Background()
+	alice := server.URL + "/users/alice"
+	post := func(activity map[string]interface{}, key *rsa.PrivateKey) error {
+		body, _ := json.Marshal(activity)
+		req := httptest.NewRequest(http.MethodPost, base+"/api/activitypub/inbox", bytes.NewReader(body))
+		if err := signRequest(req, alice+"#main-key", key, body, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		return s.HandleInbox(ctx, models.DefaultSiteID, base, req, body)
+	}
+
+	finger, err := s.WebFinger(base, "acct:blog@blog.example.com")
+	if err != nil || finger["subject"] != "acct:blog@blog.example.com" {
+		t.Fatalf("unexpected webfinger %v %v", finger, err)
+	}
+	if _, err := s.WebFinger(base, "acct:someone@blog.example.com"); !errors.Is(err, ErrUnknownActor) {
+		t.Errorf("expected ErrUnknownActor, got %v", err)
+	}
+	actor, err := s.Actor(models.DefaultSiteID, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actor["inbox"] != base+"/api/activitypub/inbox" || !strings.Contains(actor["publicKey"].(map[string]string)["publicKeyPem"], "PUBLIC KEY") {
+		t.Errorf("unexpected actor %v", actor)
+	}
+
+	// A follow signed with another key is rejected
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	follow := map[string]interface{}{"id": alice + "#follow", "type": "Follow", "actor": alice, "object": actorURL(base)}
+	if err := post(follow, otherKey); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	if err := post(follow, remoteKey); err != nil {
+		t.Fatal(err)
+	}
+	var follower models.Follower
+	if err := database.DB.First(&follower, "actor_id = ?", alice).Error; err != nil || follower.SharedInbox != server.URL+"/inbox" {
+		t.Fatalf("follower not stored %+v %v", follower, err)
+	}
+	var jobs []models.Job
+	database.DB.Where("type = ?", JobActivityPubDeliver).Find(&jobs)
+	if len(jobs) != 1 || !strings.Contains(jobs[0].Payload, `"type":"Accept"}`) {
+		t.Fatalf("expected an Accept delivery, got %+v", jobs)
+	}
+	if _, err := s.Deliver(ctx, json.RawMessage(jobs[0].Payload)); err != nil {
+		t.Fatal(err)
+	}
+	if len(delivered) != 1 || !strings.HasPrefix(delivered[0], "/users/alice/inbox keyId=\""+base+"/api/activitypub/actor#main-key\"") {
+		t.Errorf("unexpected delivery %q", delivered)
+	}
+
+	article := models.Article{Title: "Hello", Summary: "A first post", DefaultLang: "en"}
+	database.DB.Create(&article)
+	reply := map[string]interface{}{
+		"id": alice + "/statuses/1#create", "type": "Create", "actor": alice,
+		"object": map[string]interface{}{
+			"id": alice + "/statuses/1", "type": "Note", "attributedTo": alice, "inReplyTo": objectURL(base, article.ID),
+			"content": `<p>Nice post<script>alert(1)</script></p>`, "url": server.URL + "/@alice/1",
+		},
+	}
+	if err := post(reply, remoteKey); err != nil {
+		t.Fatal(err)
+	}
+	replies, err := s.ListReplies(ctx, models.ReplyPending, article.ID)
+	if err != nil || len(replies) != 1 {
+		t.Fatalf("reply not stored %+v %v", replies, err)
+	}
+	if strings.Contains(replies[0].Content, "script") || replies[0].AuthorName != "Alice" || replies[0].URL != server.URL+"/@alice/1" {
+		t.Errorf("unexpected reply %+v", replies[0])
+	}
+
+	// New articles go to the shared inbox
+	if err := s.publish(ctx, hooks.ArticlePublished, &article); err != nil {
+		t.Fatal(err)
+	}
+	database.DB.Where("type = ?", JobActivityPubDeliver).Order("id").Find(&jobs)
+	if len(jobs) != 2 || !strings.Contains(jobs[1].Payload, `"inbox":"`+server.URL+`/inbox"`) || !strings.Contains(jobs[1].Payload, `"type":"Create"`) {
+		t.Fatalf("unexpected publish jobs %+v", jobs)
+	}
+
+	undo := map[string]interface{}{"id": alice + "#undo", "type": "Undo", "actor": alice, "object": follow}
+	if err := post(undo, remoteKey); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	database.DB.Model(&models.Follower{}).Count(&count)
+	if count != 0 {
+		t.Errorf("follower not removed after Undo")
+	}
+}
+
+func TestActivityPubOnlyReachesPublicAddresses(t *testing.T) {
+	newTestDB(t)
+	const base = "https://blog.example.com"
+	var hits int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	s := NewActivityPubService()
+	s.enabled = func() bool { return true }
+	s.baseURL = func(uint) string { return base }
+	s.actors = cache.New("activitypub_actors_public_test", time.Minute)
+	ctx := context.Background()
+
+	// A signature's keyId names the actor fetched to check it
+	for _, keyID := range []string{"https://localhost/users/eve#main-key", server.URL + "/users/eve#main-key"} {
+		if _, err := s.fetchActor(ctx, models.DefaultSiteID, base, keyID); !errors.Is(err, ErrPreviewBlocked) && !errors.Is(err, ErrInvalidPreviewURL) {
+			t.Errorf("fetching %s: expected it to be blocked, got %v", keyID, err)
+		}
+	}
+
+	// Addresses that only resolve to loopback are refused when dialed, after
+	// the URL check
+	s.checkURL = func(*url.URL) error { return nil }
+	if _, err := s.fetchActor(ctx, models.DefaultSiteID, base, server.URL+"/users/eve#main-key"); !errors.Is(err, ErrPreviewBlocked) {
+		t.Errorf("expected the dial to 127.0.0.1 to be blocked, got %v", err)
+	}
+	payload := mustMarshal(activityPubDelivery{
+		SiteID: models.DefaultSiteID, Inbox: server.URL + "/inbox", KeyID: actorKeyID(base), Activity: json.RawMessage(`{}`),
+	})
+	result, err := s.Deliver(ctx, payload)
+	if err != nil || !strings.Contains(fmt.Sprint(result), "not a public address") {
+		t.Errorf("expected the delivery to 127.0.0.1 to be skipped, got %v %v", result, err)
+	}
+	if hits != 0 {
+		t.Errorf("the loopback server was reached %d times", hits)
+	}
+}
@@ -0,0 +1,390 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Admin search result groups, in the order they are returned
+const (
+	AdminSearchArticles   = "articles"
+	AdminSearchMedia      = "media"
+	AdminSearchCategories = "categories"
+	AdminSearchSettings   = "settings"
+	AdminSearchKeywords   = "keywords"
+	AdminSearchComments   = "comments"
+)
+
+var adminSearchGroups = []string{
+	AdminSearchArticles, AdminSearchMedia, AdminSearchCategories,
+	AdminSearchSettings, AdminSearchKeywords, AdminSearchComments,
+}
+
+const (
+	// maxAdminSearchQuery is the longest query accepted, in characters
+	maxAdminSearchQuery = 100
+	// adminSearchExcerpt is the length of the text shown around a match
+	adminSearchExcerpt = 120
+	// DefaultAdminSearchLimit and MaxAdminSearchLimit bound the hits
+	// returned per group
+	DefaultAdminSearchLimit = 5
+	MaxAdminSearchLimit     = 20
+)
+
+var ErrInvalidAdminSearch = errors.New("invalid search")
+
+// adminSearchHiddenSettings are site settings never searched: bookkeeping
+// and the AI configuration, which holds API keys
+var adminSearchHiddenSettings = map[string]bool{
+	"id": true, "site_id": true, "created_at": true, "updated_at": true,
+	"translations": true, "ai_config": true,
+}
+
+// AdminSearchHit is one result. Field names what matched, Language the
+// translation it matched in, and Path the admin page showing the result,
+// below the locale prefix, when there is one.
+type AdminSearchHit struct {
+	ID       uint   `json:"id"`
+	Kind     string `json:"kind,omitempty"`
+	Title    string `json:"title"`
+	Excerpt  string `json:"excerpt,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Language string `json:"language,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+// AdminSearchGroup holds the first hits of one type and how many there are
+type AdminSearchGroup struct {
+	Type  string           `json:"type"`
+	Total int64            `json:"total"`
+	Hits  []AdminSearchHit `json:"hits"`
+}
+
+// AdminSearchResults are the groups matching a query, empty ones left out
+type AdminSearchResults struct {
+	Query  string             `json:"query"`
+	Groups []AdminSearchGroup `json:"groups"`
+}
+
+// AdminSearchService searches everything an administrator manages at
+// once, for a command palette: articles in every language, media, categories,
+// site settings, tracked keywords and comments. Matching is a
+// case-insensitive substring search.
+type AdminSearchService struct {
+	db func() *gorm.DB
+}
+
+// NewAdminSearchService creates an admin search service
+func NewAdminSearchService() *AdminSearchService {
+	return &AdminSearchService{db: func() *gorm.DB { return database.DB }}
+}
+
+// Search returns up to limit hits per group for query on the site of ctx,
+// from the groups in types or all of them when types is empty
+func (s *AdminSearchService) Search(ctx context.Context, query string, types []string, limit int) (*AdminSearchResults, error) {
+	query = strings.TrimSpace(query)
+	switch {
+	case query == "":
+		return nil, fmt.Errorf("%w: a query is required", ErrInvalidAdminSearch)
+	case utf8.RuneCountInString(query) > maxAdminSearchQuery:
+		return nil, fmt.Errorf("%w: the query is longer than %d characters", ErrInvalidAdminSearch, maxAdminSearchQuery)
+	}
+	if limit <= 0 {
+		limit = DefaultAdminSearchLimit
+	}
+	limit = min(limit, MaxAdminSearchLimit)
+	for _, t := range types {
+		if !contains(adminSearchGroups, t) {
+			return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidAdminSearch, t)
+		}
+	}
+
+	searches := map[string]func(*gorm.DB, string, int) (AdminSearchGroup, error){
+		AdminSearchArticles:   s.articles,
+		AdminSearchMedia:      s.media,
+		AdminSearchCategories: s.categories,
+		AdminSearchSettings:   s.settings,
+		AdminSearchKeywords:   s.keywords,
+		AdminSearchComments:   s.comments,
+	}
+	results := &AdminSearchResults{Query: query, Groups: []AdminSearchGroup{}}
+	db := s.db().WithContext(ctx)
+	for _, t := range adminSearchGroups {
+		if len(types) > 0 && !contains(types, t) {
+			continue
+		}
+		group, err := searches[t](db, query, limit)
+		if err != nil {
+			return nil, fmt.Errorf("searching %s: %w", t, err)
+		}
+		if group.Total > 0 {
+			group.Type = t
+			results.Groups = append(results.Groups, group)
+		}
+	}
+	return results, nil
+}
+
+// articles matches the text, summary and SEO fields of articles and their
+// translations
+func (s *AdminSearchService) articles(db *gorm.DB, query string, limit int) (AdminSearchGroup, error) {
+	pattern := "%" + query + "%"
+	translated := db.Model(&models.ArticleTranslation{}).Select("article_id").
+		Where(database.Like("title LIKE ? OR summary LIKE ? OR content LIKE ?"), pattern, pattern, pattern)
+	matches := db.Model(&models.Article{}).Where(database.Like(
+		"title LIKE ? OR summary LIKE ? OR content LIKE ? OR seo_title LIKE ? OR seo_keywords LIKE ? OR seo_slug LIKE ?"),
+		pattern, pattern, pattern, pattern, pattern, pattern).
+		Or("id IN (?)", translated)
+
+	var group AdminSearchGroup
+	if err := db.Model(&models.Article{}).Where(matches).Count(&group.Total).Error; err != nil {
+		return group, err
+	}
+	var articles []models.Article
+	if err := db.Preload("Translations").Where(matches).Order("updated_at DESC").Limit(limit).Find(&articles).Error; err != nil {
+		return group, err
+	}
+	for _, article := range articles {
+		hit := AdminSearchHit{ID: article.ID, Title: article.Title, Path: fmt.Sprintf("/admin/articles/%d", article.ID)}
+		hit.Field, hit.Excerpt = firstMatch(query, []adminSearchField{
+			{"title", article.Title}, {"summary", article.Summary}, {"content", article.Content},
+			{"seo_title", article.SEOTitle}, {"seo_keywords", article.SEOKeywords}, {"seo_slug", article.SEOSlug},
+		})
+		if hit.Field == "" {
+			for _, translation := range article.Translations {
+				hit.Field, hit.Excerpt = firstMatch(query, []adminSearchField{
+					{"title", translation.Title}, {"summary", translation.Summary}, {"content", translation.Content},
+				})
+				if hit.Field != "" {
+					hit.Language = translation.Language
+					break
+				}
+			}
+		}
+		group.Hits = append(group.Hits, hit)
+	}
+	return group, nil
+}
+
+// media matches the names and alternative text of uploads
+func (s *AdminSearchService) media(db *gorm.DB, query string, limit int) (AdminSearchGroup, error) {
+	pattern := "%" + query + "%"
+	matches := db.Model(&models.MediaLibrary{}).
+		Where(database.Like("file_name LIKE ? OR original_name LIKE ? OR alt LIKE ?"), pattern, pattern, pattern)
+
+	var group AdminSearchGroup
+	if err := db.Model(&models.MediaLibrary{}).Where(matches).Count(&group.Total).Error; err != nil {
+		return group, err
+	}
+	var media []models.MediaLibrary
+	if err := db.Where(matches).Order("created_at DESC").Limit(limit).Find(&media).Error; err != nil {
+		return group, err
+	}
+	for _, item := range media {
+		hit := AdminSearchHit{ID: item.ID, Kind: string(item.MediaType), Title: item.OriginalName, Path: "/admin/media"}
+		hit.Field, hit.Excerpt = firstMatch(query, []adminSearchField{
+			{"original_name", item.OriginalName}, {"file_name", item.FileName}, {"alt", item.Alt},
+		})
+		group.Hits = append(group.Hits, hit)
+	}
+	return group, nil
+}
+
+// categories matches category names and descriptions in every language
+func (s *AdminSearchService) categories(db *gorm.DB, query string, limit int) (AdminSearchGroup, error) {
+	pattern := "%" + query + "%"
+	translated := db.Model(&models.CategoryTranslation{}).Select("category_id").
+		Where(database.Like("name LIKE ? OR description LIKE ?"), pattern, pattern)
+	matches := db.Model(&models.Category{}).Where(database.Like("name LIKE ? OR description LIKE ?"), pattern, pattern).
+		Or("id IN (?)", translated)
+
+	var group AdminSearchGroup
+	if err := db.Model(&models.Category{}).Where(matches).Count(&group.Total).Error; err != nil {
+		return group, err
+	}
+	var categories []models.Category
+	if err := db.Preload("Translations").Where(matches).Order("name ASC").Limit(limit).Find(&categories).Error; err != nil {
+		return group, err
+	}
+	for _, category := range categories {
+		hit := AdminSearchHit{ID: category.ID, Title: category.Name, Path: fmt.Sprintf("/admin/categories/%d", category.ID)}
+		hit.Field, hit.Excerpt = firstMatch(query, []adminSearchField{{"name", category.Name}, {"description", category.Description}})
+		if hit.Field == "" {
+			for _, translation := range category.Translations {
+				hit.Field, hit.Excerpt = firstMatch(query, []adminSearchField{{"name", translation.Name}, {"description", translation.Description}})
+				if hit.Field != "" {
+					hit.Language = translation.Language
+					break
+				}
+			}
+		}
+		group.Hits = append(group.Hits, hit)
+	}
+	return group, nil
+}
+
+// settings matches the site settings by name, such as "favicon" for
+// favicon_url, and by value, including the translated title and subtitle
+func (s *AdminSearchService) settings(db *gorm.DB, query string, limit int) (AdminSearchGroup, error) {
+	var group AdminSearchGroup
+	var settings models.SiteSettings
+	if err := db.Preload("Translations").Limit(1).Find(&settings).Error; err != nil || settings.ID == 0 {
+		return group, err
+	}
+	lower := strings.ToLower(query)
+	value := reflect.ValueOf(settings)
+	for i := 0; i < value.NumField(); i++ {
+		name, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || adminSearchHiddenSettings[name] {
+			continue
+		}
+		// Values are matched for text settings only, or "true" would find
+		// every switch
+		field := value.Field(i)
+		text := fmt.Sprint(field.Interface())
+		hit := AdminSearchHit{ID: settings.ID, Title: name, Field: name, Path: "/admin/settings"}
+		if _, excerpt := firstMatch(query, []adminSearchField{{name, text}}); excerpt != "" && field.Kind() == reflect.String {
+			hit.Excerpt = excerpt
+		} else if strings.Contains(strings.ReplaceAll(name, "_", " "), lower) || strings.Contains(name, lower) {
+			hit.Excerpt = searchExcerpt(text, "")
+		} else {
+			continue
+		}
+		group.Hits = append(group.Hits, hit)
+	}
+	for _, translation := range settings.Translations {
+		field, excerpt := firstMatch(query, []adminSearchField{{"site_title", translation.SiteTitle}, {"site_subtitle", translation.SiteSubtitle}})
+		if field != "" {
+			group.Hits = append(group.Hits, AdminSearchHit{
+				ID: settings.ID, Title: field, Field: field, Excerpt: excerpt, Language: translation.Language, Path: "/admin/settings",
+			})
+		}
+	}
+	group.Total = int64(len(group.Hits))
+	if len(group.Hits) > limit {
+		group.Hits = group.Hits[:limit]
+	}
+	return group, nil
+}
+
+// keywords matches the SEO keywords being tracked
+func (s *AdminSearchService) keywords(db *gorm.DB, query string, limit int) (AdminSearchGroup, error) {
+	pattern := "%" + query + "%"
+	matches := db.Model(&models.SEOKeyword{}).Where(database.Like("keyword LIKE ? OR tags LIKE ? OR notes LIKE ?"), pattern, pattern, pattern)
+
+	var group AdminSearchGroup
+	if err := db.Model(&models.SEOKeyword{}).Where(matches).Count(&group.Total).Error; err != nil {
+		return group, err
+	}
+	var keywords []models.SEOKeyword
+	if err := db.Where(matches).Order("keyword ASC").Limit(limit).Find(&keywords).Error; err != nil {
+		return group, err
+	}
+	for _, keyword := range keywords {
+		hit := AdminSearchHit{ID: keyword.ID, Title: keyword.Keyword, Language: keyword.Language}
+		hit.Field, hit.Excerpt = firstMatch(query, []adminSearchField{{"keyword", keyword.Keyword}, {"tags", keyword.Tags}, {"notes", keyword.Notes}})
+		group.Hits = append(group.Hits, hit)
+	}
+	return group, nil
+}
+
+// comments matches fediverse replies and guestbook entries, newest first
+func (s *AdminSearchService) comments(db *gorm.DB, query string, limit int) (AdminSearchGroup, error) {
+	pattern := "%" + query + "%"
+	replies := db.Model(&models.FederatedReply{}).Where(database.Like("author_name LIKE ? OR content LIKE ?"), pattern, pattern)
+	entries := db.Model(&models.GuestbookEntry{}).Where(database.Like("name LIKE ? OR email LIKE ? OR message LIKE ?"), pattern, pattern, pattern)
+
+	var group AdminSearchGroup
+	var replyCount, entryCount int64
+	if err := db.Model(&models.FederatedReply{}).Where(replies).Count(&replyCount).Error; err != nil {
+		return group, err
+	}
+	if err := db.Model(&models.GuestbookEntry{}).Where(entries).Count(&entryCount).Error; err != nil {
+		return group, err
+	}
+	group.Total = replyCount + entryCount
+
+	var matchedReplies []models.FederatedReply
+	if err := db.Where(replies).Order("created_at DESC").Limit(limit).Find(&matchedReplies).Error; err != nil {
+		return group, err
+	}
+	var matchedEntries []models.GuestbookEntry
+	if err := db.Where(entries).Order("created_at DESC").Limit(limit).Find(&matchedEntries).Error; err != nil {
+		return group, err
+	}
+	// Merge the two newest-first lists
+	for len(group.Hits) < limit && (len(matchedReplies) > 0 || len(matchedEntries) > 0) {
+		if len(matchedEntries) == 0 || (len(matchedReplies) > 0 && matchedReplies[0].CreatedAt.After(matchedEntries[0].CreatedAt)) {
+			reply := matchedReplies[0]
+			matchedReplies = matchedReplies[1:]
+			hit := AdminSearchHit{ID: reply.ID, Kind: "reply", Title: reply.AuthorName}
+			hit.Field, hit.Excerpt = firstMatch(query, []adminSearchField{
+				{"content", htmlTag.ReplaceAllString(reply.Content, " ")}, {"author_name", reply.AuthorName},
+			})
+			group.Hits = append(group.Hits, hit)
+			continue
+		}
+		entry := matchedEntries[0]
+		matchedEntries = matchedEntries[1:]
+		hit := AdminSearchHit{ID: entry.ID, Kind: "guestbook", Title: entry.Name, Language: entry.Language}
+		hit.Field, hit.Excerpt = firstMatch(query, []adminSearchField{{"message", entry.Message}, {"name", entry.Name}, {"email", entry.Email}})
+		group.Hits = append(group.Hits, hit)
+	}
+	return group, nil
+}
+
+// adminSearchField is a named text a query may match
+type adminSearchField struct {
+	name, text string
+}
+
+// firstMatch returns the first field containing query, ignoring case, and
+// the text around the match
+func firstMatch(query string, fields []adminSearchField) (string, string) {
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field.text), strings.ToLower(query)) {
+			return field.name, searchExcerpt(field.text, query)
+		}
+	}
+	return "", ""
+}
+
+// searchExcerpt returns adminSearchExcerpt characters of text on one line,
+// starting a little before the first match of query
+func searchExcerpt(text, query string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	start := 0
+	if query != "" {
+		lower := []rune(strings.ToLower(string(runes)))
+		if i := strings.Index(string(lower), strings.ToLower(query)); i > 0 {
+			start = max(utf8.RuneCountInString(string(lower)[:i])-adminSearchExcerpt/4, 0)
+		}
+	}
+	excerpt := string(runes[start:])
+	if start > 0 {
+		excerpt = "…" + excerpt
+	}
+	return truncateRunes(excerpt, adminSearchExcerpt)
+}
+
+var (
+	globalAdminSearchService     *AdminSearchService
+	globalAdminSearchServiceOnce sync.Once
+)
+
+// GetGlobalAdminSearchService returns the global admin search service
+func GetGlobalAdminSearchService() *AdminSearchService {
+	globalAdminSearchServiceOnce.Do(func() {
+		globalAdminSearchService = NewAdminSearchService()
+	})
+	return globalAdminSearchService
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminSearch(t *testing.T) {
+	newTestDB(t)
+	if err := database.DB.Use(database.SiteScope{}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewAdminSearchService()
+	ctx := database.WithSite(context.Background(), models.DefaultSiteID)
+
+	category := models.Category{Name: "Gophers", Translations: []models.CategoryTranslation{{Language: "zh", Name: "地鼠"}}}
+	now := time.Now()
+	for _, record := range []interface{}{
+		&models.SiteSettings{SiteTitle: "Gopher Notes", FaviconURL: "/favicon.ico",
+			Translations: []models.SiteSettingsTranslation{{Language: "zh", SiteTitle: "地鼠笔记", SiteSubtitle: "Go"}}},
+		&category,
+		&models.Article{Title: "Concurrency", Content: "Channels connect GOPHERS.", SEOKeywords: "go"},
+		&models.Article{Title: "Untitled", Content: "Nothing", Translations: []models.ArticleTranslation{{Language: "zh", Title: "地鼠的故事"}}},
+		&models.Article{SiteID: 2, Title: "Gophers elsewhere"},
+		&models.MediaLibrary{FileName: "a1b2.png", OriginalName: "gopher.png", FilePath: "x", MimeType: "image/png", MediaType: models.MediaTypeImage, URL: "/uploads/a1b2.png"},
+		&models.SEOKeyword{Keyword: "gopher tutorial", Language: "en"},
+		&models.GuestbookEntry{Name: "Ann", Message: "Love the gopher posts", Language: "en", Status: "approved", CreatedAt: now.Add(-time.Hour)},
+		&models.FederatedReply{ArticleID: 1, ObjectID: "https://social.example/1", ActorID: "https://social.example/u", AuthorName: "Bob",
+			Content: "<p>Nice <b>gopher</b></p>", Status: "pending", CreatedAt: now},
+	} {
+		if err := database.DB.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := s.Search(ctx, "gopher", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups := map[string]AdminSearchGroup{}
+	var order []string
+	for _, group := range results.Groups {
+		groups[group.Type] = group
+		order = append(order, group.Type)
+	}
+	if want := "articles media categories settings keywords comments"; strings.Join(order, " ") != want {
+		t.Fatalf("groups %v, want %s", order, want)
+	}
+	if articles := groups[AdminSearchArticles]; articles.Total != 1 || articles.Hits[0].Field != "content" || articles.Hits[0].Path != "/admin/articles/1" {
+		t.Errorf("articles = %+v, want the one article of this site", articles)
+	}
+	if settings := groups[AdminSearchSettings]; settings.Hits[0].Field != "site_title" || settings.Hits[0].Excerpt != "Gopher Notes" {
+		t.Errorf("settings = %+v", settings)
+	}
+	if comments := groups[AdminSearchComments]; comments.Total != 2 || comments.Hits[0].Kind != "reply" || comments.Hits[0].Excerpt != "Nice gopher" ||
+		comments.Hits[1].Kind != "guestbook" {
+		t.Errorf("comments = %+v, want the reply then the guestbook entry", comments)
+	}
+
+	// Translations are searched, and settings are found by name
+	results, err = s.Search(ctx, "地鼠", []string{AdminSearchArticles, AdminSearchCategories}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Groups) != 2 || results.Groups[0].Hits[0].Language != "zh" || results.Groups[1].Hits[0].ID != category.ID {
+		t.Errorf("translated results = %+v", results.Groups)
+	}
+	results, err = s.Search(ctx, "favicon", []string{AdminSearchSettings}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Groups) != 1 || results.Groups[0].Hits[0].Excerpt != "/favicon.ico" {
+		t.Errorf("setting by name = %+v", results.Groups)
+	}
+
+	for _, bad := range []struct {
+		query string
+		types []string
+	}{{" ", nil}, {"gopher", []string{"users"}}} {
+		if _, err := s.Search(ctx, bad.query, bad.types, 0); !errors.Is(err, ErrInvalidAdminSearch) {
+			t.Errorf("Search(%q, %v) = %v, want ErrInvalidAdminSearch", bad.query, bad.types, err)
+		}
+	}
+	if excerpt := searchExcerpt(strings.Repeat("word ", 40)+"the gopher", "GOPHER"); excerpt[:3] != "…" {
+		t.Errorf("excerpt of a late match = %q", excerpt)
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/metrics"
+	"time"
+)
+
+// AI usage counted by this process since it started. Prometheus sums them
+// across instances and rate() turns them into spend per hour.
+var (
+	aiRequestsCounter = metrics.NewCounterVec("provider", "service_type", "status")
+	aiTokensCounter   = metrics.NewCounterVec("provider", "service_type", "direction")
+	aiCostCounter     = metrics.NewCounterVec("provider", "service_type", "currency")
+)
+
+// The usage windows are aggregated from the database, shared by every
+// instance, and reused between scrapes for a short while
+var aiMetricsCache = cache.New("ai-metrics", 30*time.Second)
+
+// aiMetricsWindows are the usage windows exported as gauges, in days
+var aiMetricsWindows = []struct {
+	label string
+	days  int
+}{
+	{"24h", 1},
+	{"30d", 30},
+}
+
+func init() {
+	metrics.Register(collectAIMetrics)
+}
+
+// recordAIMetrics counts one tracked AI call
+func recordAIMetrics(usage UsageMetrics) {
+	provider, serviceType := metricLabel(usage.Provider), metricLabel(usage.ServiceType)
+	status := "success"
+	if !usage.Success {
+		status = "error"
+	}
+	aiRequestsCounter.Add(1, provider, serviceType, status)
+	aiTokensCounter.Add(float64(usage.InputTokens), provider, serviceType, "input")
+	aiTokensCounter.Add(float64(usage.OutputTokens), provider, serviceType, "output")
+	if usage.EstimatedCost > 0 {
+		aiCostCounter.Add(usage.EstimatedCost, provider, serviceType, metricLabel(usage.Currency))
+	}
+}
+
+// aiWindowStats are the database aggregates for one usage window. Costs
+// are kept per currency; the other figures are per provider and service.
+type aiWindowStats struct {
+	Window string          `json:"window"`
+	Usage  []aiWindowUsage `json:"usage"`
+	Costs  []aiWindowCost  `json:"costs"`
+}
+
+type aiWindowUsage struct {
+	Provider    string `json:"provider"`
+	ServiceType string `json:"service_type"`
+	Requests    int64  `json:"requests"`
+	Errors      int64  `json:"errors"`
+	Tokens      int64  `json:"tokens"`
+}
+
+type aiWindowCost struct {
+	Provider    string  `json:"provider"`
+	ServiceType string  `json:"service_type"`
+	Currency    string  `json:"currency"`
+	Cost        float64 `json:"cost"`
+}
+
+func collectAIMetrics() ([]metrics.Family, error) {
+	families := []metrics.Family{
+		{Name: "kuno_ai_requests_total", Help: "AI requests made by this process", Type: metrics.Counter, Samples: aiRequestsCounter.Samples()},
+		{Name: "kuno_ai_tokens_total", Help: "AI tokens used by this process", Type: metrics.Counter, Samples: aiTokensCounter.Samples()},
+		{Name: "kuno_ai_cost_total", Help: "Estimated AI cost incurred by this process", Type: metrics.Counter, Samples: aiCostCounter.Samples()},
+	}
+
+	tracker := NewAIUsageTracker()
+	families = append(families, metrics.Family{
+		Name: "kuno_ai_cost_limit", Help: "AI cost limit that triggers a warning", Type: metrics.Gauge,
+		Samples: []metrics.Sample{
+			{Labels: metrics.Labels{"period": "daily"}, Value: tracker.dailyCostLimit},
+			{Labels: metrics.Labels{"period": "monthly"}, Value: tracker.monthlyCostLimit},
+		},
+	})
+
+	windows, err := aiUsageWindows(tracker)
+	if err != nil {
+		return families, err
+	}
+	requests := metrics.Family{Name: "kuno_ai_window_requests", Help: "AI requests recorded in the window by all instances", Type: metrics.Gauge}
+	errorRatio := metrics.Family{Name: "kuno_ai_window_error_ratio", Help: "Share of failed AI requests in the window", Type: metrics.Gauge}
+	tokens := metrics.Family{Name: "kuno_ai_window_tokens", Help: "AI tokens recorded in the window by all instances", Type: metrics.Gauge}
+	cost := metrics.Family{Name: "kuno_ai_window_cost", Help: "Estimated AI cost recorded in the window by all instances", Type: metrics.Gauge}
+	for _, window := range windows {
+		for _, usage := range window.Usage {
+			labels := metrics.Labels{"window": window.Window, "provider": usage.Provider, "service_type": usage.ServiceType}
+			ratio := 0.0
+			if usage.Requests > 0 {
+				ratio = float64(usage.Errors) / float64(usage.Requests)
+			}
+			requests.Samples = append(requests.Samples, metrics.Sample{Labels: labels, Value: float64(usage.Requests)})
+			errorRatio.Samples = append(errorRatio.Samples, metrics.Sample{Labels: labels, Value: ratio})
+			tokens.Samples = append(tokens.Samples, metrics.Sample{Labels: labels, Value: float64(usage.Tokens)})
+		}
+		for _, spent := range window.Costs {
+			labels := metrics.Labels{"window": window.Window, "provider": spent.Provider, "service_type": spent.ServiceType, "currency": spent.Currency}
+			cost.Samples = append(cost.Samples, metrics.Sample{Labels: labels, Value: spent.Cost})
+		}
+	}
+	return append(families, requests, errorRatio, tokens, cost), nil
+}
+
+// aiUsageWindows returns the usage aggregates per window, cached briefly so
+// frequent scrapes do not rescan the usage table
+func aiUsageWindows(tracker *AIUsageTracker) ([]aiWindowStats, error) {
+	var windows []aiWindowStats
+	if aiMetricsCache.Get("windows", &windows) {
+		return windows, nil
+	}
+	for _, window := range aiMetricsWindows {
+		stats, err := tracker.GetUsageStats("", "", window.days)
+		if err != nil {
+			return nil, err
+		}
+		result := aiWindowStats{Window: window.label}
+		usageIndex := map[[2]string]int{}
+		costIndex := map[[3]string]int{}
+		for _, stat := range stats {
+			provider, serviceType := metricLabel(stat.Provider), metricLabel(stat.ServiceType)
+			key := [2]string{provider, serviceType}
+			i, ok := usageIndex[key]
+			if !ok {
+				i = len(result.Usage)
+				usageIndex[key] = i
+				result.Usage = append(result.Usage, aiWindowUsage{Provider: provider, ServiceType: serviceType})
+			}
+			result.Usage[i].Requests += stat.TotalRequests
+			result.Usage[i].Errors += stat.TotalRequests - stat.SuccessRequests
+			result.Usage[i].Tokens += stat.TotalTokens
+
+			costKey := [3]string{provider, serviceType, metricLabel(stat.Currency)}
+			j, ok := costIndex[costKey]
+			if !ok {
+				j = len(result.Costs)
+				costIndex[costKey] = j
+				result.Costs = append(result.Costs, aiWindowCost{Provider: provider, ServiceType: serviceType, Currency: costKey[2]})
+			}
+			result.Costs[j].Cost += stat.TotalCost
+		}
+		windows = append(windows, result)
+	}
+	aiMetricsCache.Set("windows", windows)
+	return windows, nil
+}
+
+// metricLabel keeps empty values visible as "unknown"
+func metricLabel(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
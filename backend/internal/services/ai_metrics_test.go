@@ -0,0 +1,45 @@
+package services
+
+import (
+	"blog-backend/internal/metrics"
+	"strings"
+	"testing"
+)
+
+func TestAIMetrics(t *testing.T) {
+	newTestDB(t)
+	aiMetricsCache.Clear()
+	tracker := NewAIUsageTracker()
+	calls := []UsageMetrics{
+		{ServiceType: "embedding", Provider: "openai", InputTokens: 100, TotalTokens: 100, EstimatedCost: 0.5, Currency: "USD", Success: true},
+		{ServiceType: "embedding", Provider: "openai", InputTokens: 50, TotalTokens: 50, Currency: "USD", Success: false},
+		{ServiceType: "summary", Provider: "gemini", InputTokens: 10, OutputTokens: 20, TotalTokens: 30, EstimatedCost: 0.1, Currency: "USD", Success: true},
+	}
+	for _, call := range calls {
+		if err := tracker.TrackUsage(call); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	families, err := collectAIMetrics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := metrics.Write(&b, families); err != nil {
+		t.Fatal(err)
+	}
+	output := b.String()
+	for _, want := range []string{
+		`kuno_ai_requests_total{provider="openai",service_type="embedding",status="error"} `,
+		`kuno_ai_window_requests{provider="openai",service_type="embedding",window="24h"} 2`,
+		`kuno_ai_window_error_ratio{provider="openai",service_type="embedding",window="24h"} 0.5`,
+		`kuno_ai_window_tokens{provider="gemini",service_type="summary",window="30d"} 30`,
+		`kuno_ai_window_cost{currency="USD",provider="openai",service_type="embedding",window="24h"} 0.5`,
+		`kuno_ai_cost_limit{period="daily"} 1`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q in:\n%s", want, output)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"blog-backend/internal/database"
 	"blog-backend/internal/models"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -50,7 +51,12 @@ func (tracker *AIUsageTracker) TrackUsage(metrics UsageMetrics) error {
 	if err := tracker.checkCostLimits(metrics.EstimatedCost); err != nil {
 		log.Printf("⚠️ Cost limit warning: %v", err)
 		// Don't block the operation, just warn
+		var limitErr *costLimitError
+		if errors.As(err, &limitErr) {
+			limitErr.notify()
+		}
 	}
+	recordAIMetrics(metrics)
 
 	record := models.AIUsageRecord{
 		ServiceType:   metrics.ServiceType,
@@ -73,7 +79,15 @@ func (tracker *AIUsageTracker) TrackUsage(metrics UsageMetrics) error {
 		IPAddress:     metrics.IPAddress,
 	}
 
-	return database.DB.Create(&record).Error
+	if err := database.DB.Create(&record).Error; err != nil {
+		return err
+	}
+	// Create fills the zero value of Success with the column default of
+	// true, so failed calls are marked afterwards
+	if !metrics.Success {
+		return database.DB.Model(&record).Update("success", false).Error
+	}
+	return nil
 }
 
 // GetUsageStats retrieves aggregated usage statistics
@@ -85,7 +99,7 @@ func (tracker *AIUsageTracker) GetUsageStats(serviceType, provider string, days
 			service_type,
 			provider,
 			COUNT(*) as total_requests,
-			SUM(CASE WHEN success = 1 THEN 1 ELSE 0 END) as success_requests,
+			SUM(CASE WHEN success THEN 1 ELSE 0 END) as success_requests,
 			SUM(total_tokens) as total_tokens,
 			SUM(estimated_cost) as total_cost,
 			currency,
@@ -164,16 +178,15 @@ func (tracker *AIUsageTracker) GetDailyUsage(days int) (map[string]models.AIUsag
 	}
 
 	err := database.DB.Model(&models.AIUsageRecord{}).
-		Select(`
-			DATE(created_at) as date,
+		Select(database.DateExpr("created_at") + ` as date,
 			COUNT(*) as total_requests,
-			SUM(CASE WHEN success = 1 THEN 1 ELSE 0 END) as success_requests,
+			SUM(CASE WHEN success THEN 1 ELSE 0 END) as success_requests,
 			SUM(total_tokens) as total_tokens,
 			SUM(estimated_cost) as total_cost,
 			AVG(response_time) as avg_response_time
 		`).
 		Where("created_at >= ?", time.Now().AddDate(0, 0, -days)).
-		Group("DATE(created_at)").
+		Group(database.DateExpr("created_at")).
 		Order("date DESC").
 		Scan(&results).Error
 
@@ -213,8 +226,7 @@ func (tracker *AIUsageTracker) checkCostLimits(newCost float64) error {
 	}
 	
 	if dailyCost+newCost > tracker.dailyCostLimit {
-		return fmt.Errorf("daily cost limit exceeded: current=$%.6f, limit=$%.6f, new request would add=$%.6f", 
-			dailyCost, tracker.dailyCostLimit, newCost)
+		return &costLimitError{period: "daily", current: dailyCost, limit: tracker.dailyCostLimit, cost: newCost}
 	}
 	
 	// Check monthly cost
@@ -224,13 +236,42 @@ func (tracker *AIUsageTracker) checkCostLimits(newCost float64) error {
 	}
 	
 	if monthlyCost+newCost > tracker.monthlyCostLimit {
-		return fmt.Errorf("monthly cost limit exceeded: current=$%.6f, limit=$%.6f, new request would add=$%.6f", 
-			monthlyCost, tracker.monthlyCostLimit, newCost)
+		return &costLimitError{period: "monthly", current: monthlyCost, limit: tracker.monthlyCostLimit, cost: newCost}
 	}
 	
 	return nil
 }
 
+// costLimitError reports a request that takes AI spending past a limit
+type costLimitError struct {
+	period  string // "daily" or "monthly"
+	current float64
+	limit   float64
+	cost    float64
+}
+
+func (e *costLimitError) Error() string {
+	return fmt.Sprintf("%s cost limit exceeded: current=$%.6f, limit=$%.6f, new request would add=$%.6f",
+		e.period, e.current, e.limit, e.cost)
+}
+
+// notify tells the admin the limit was reached, once per day or month
+func (e *costLimitError) notify() {
+	period := time.Now().Format("2006-01-02")
+	if e.period == "monthly" {
+		period = time.Now().Format("2006-01")
+	}
+	notifyAdmin(NotificationInput{
+		Source:   models.NotificationSourceAI,
+		Type:     "cost_limit",
+		Severity: models.SeverityWarning,
+		Title:    fmt.Sprintf("AI %s cost limit reached", e.period),
+		Message: fmt.Sprintf("AI usage has cost $%.4f against a %s limit of $%.2f. Requests are not blocked; "+
+			"review the AI usage or raise the limit.", e.current+e.cost, e.period, e.limit),
+		Key: "ai.cost_limit." + e.period + ":" + period,
+	})
+}
+
 // GetCostSummary returns current cost summary and limits
 func (tracker *AIUsageTracker) GetCostSummary() (map[string]interface{}, error) {
 	dailyCost, err := tracker.GetTotalCost(1)
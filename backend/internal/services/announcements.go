@@ -0,0 +1,263 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// maxAnnouncementLength is the longest announcement in characters; a bar
+// holds a sentence or two
+const maxAnnouncementLength = 1000
+
+var (
+	ErrInvalidAnnouncement  = errors.New("invalid announcement")
+	ErrAnnouncementNotFound = errors.New("announcement not found")
+)
+
+// AnnouncementInput holds the editable fields of an announcement; nil
+// fields are left unchanged. StartsAt and EndsAt are RFC 3339 times, or
+// empty for none. Translations, when given, replace every translation of
+// the announcement.
+type AnnouncementInput struct {
+	Message      *string                        `json:"message"`
+	LinkURL      *string                        `json:"link_url"`
+	Type         *string                        `json:"type"`
+	StartsAt     *string                        `json:"starts_at"`
+	EndsAt       *string                        `json:"ends_at"`
+	Dismissible  *bool                          `json:"dismissible"`
+	IsActive     *bool                          `json:"is_active"`
+	Translations []AnnouncementTranslationInput `json:"translations"`
+}
+
+// AnnouncementTranslationInput is an announcement's message in another
+// language
+type AnnouncementTranslationInput struct {
+	Language string `json:"language"`
+	Message  string `json:"message"`
+}
+
+// AnnouncementService manages the announcements of each site. The current
+// ones are read on every page, so they are cached for a minute per site;
+// changes clear the cache.
+type AnnouncementService struct {
+	db    func() *gorm.DB
+	now   func() time.Time
+	cache *cache.Namespace
+}
+
+// NewAnnouncementService creates an announcement service
+func NewAnnouncementService() *AnnouncementService {
+	return &AnnouncementService{
+		db:    func() *gorm.DB { return database.DB },
+		now:   time.Now,
+		cache: cache.New("announcements", time.Minute),
+	}
+}
+
+// Current returns the site's announcements that are turned on and within
+// their time window, newest first
+func (s *AnnouncementService) Current(ctx context.Context) ([]models.Announcement, error) {
+	siteID, _ := database.SiteFromContext(ctx)
+	key := fmt.Sprint(siteID)
+	announcements := []models.Announcement{}
+	if s.cache.Get(key, &announcements) {
+		return announcements, nil
+	}
+	now := s.now()
+	err := s.db().WithContext(ctx).Preload("Translations").
+		Where("is_active = ?", true).
+		Where("starts_at IS NULL OR starts_at <= ?", now).
+		Where("ends_at IS NULL OR ends_at > ?", now).
+		Order("COALESCE(starts_at, created_at) DESC, id DESC").
+		Find(&announcements).Error
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Set(key, announcements)
+	return announcements, nil
+}
+
+// List returns every announcement of the site, newest first
+func (s *AnnouncementService) List(ctx context.Context) ([]models.Announcement, error) {
+	announcements := []models.Announcement{}
+	err := s.db().WithContext(ctx).Preload("Translations").Order("created_at DESC, id DESC").Find(&announcements).Error
+	return announcements, err
+}
+
+// Get returns one of the site's announcements
+func (s *AnnouncementService) Get(ctx context.Context, id uint) (*models.Announcement, error) {
+	var announcement models.Announcement
+	if err := s.db().WithContext(ctx).Preload("Translations").First(&announcement, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAnnouncementNotFound
+		}
+		return nil, err
+	}
+	return &announcement, nil
+}
+
+// Create adds an announcement, an active info notice unless the input
+// says otherwise
+func (s *AnnouncementService) Create(ctx context.Context, input AnnouncementInput) (*models.Announcement, error) {
+	announcement := &models.Announcement{Type: models.AnnouncementInfo, IsActive: true}
+	if err := applyAnnouncementInput(announcement, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Create(announcement).Error; err != nil {
+		return nil, err
+	}
+	s.cache.Clear()
+	return announcement, nil
+}
+
+// Update edits an announcement and, when given, replaces its translations
+func (s *AnnouncementService) Update(ctx context.Context, id uint, input AnnouncementInput) (*models.Announcement, error) {
+	announcement, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyAnnouncementInput(announcement, input); err != nil {
+		return nil, err
+	}
+	err = s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if input.Translations != nil {
+			if err := tx.Where("announcement_id = ?", announcement.ID).Delete(&models.AnnouncementTranslation{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(announcement).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Clear()
+	return announcement, nil
+}
+
+// Delete removes an announcement and its translations
+func (s *AnnouncementService) Delete(ctx context.Context, id uint) error {
+	announcement, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("announcement_id = ?", announcement.ID).Delete(&models.AnnouncementTranslation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(announcement).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.cache.Clear()
+	return nil
+}
+
+// ApplyAnnouncementTranslation shows an announcement in lang where it has
+// been translated
+func ApplyAnnouncementTranslation(announcement *models.Announcement, lang string) {
+	for _, translation := range announcement.Translations {
+		if translation.Language == lang && translation.Message != "" {
+			announcement.Message = translation.Message
+			break
+		}
+	}
+}
+
+func applyAnnouncementInput(announcement *models.Announcement, input AnnouncementInput) error {
+	if input.Message != nil {
+		announcement.Message = strings.TrimSpace(*input.Message)
+	}
+	if input.LinkURL != nil {
+		announcement.LinkURL = strings.TrimSpace(*input.LinkURL)
+	}
+	if input.Type != nil {
+		announcement.Type = *input.Type
+	}
+	for _, field := range []struct {
+		name  string
+		input *string
+		time  **time.Time
+	}{
+		{"starts_at", input.StartsAt, &announcement.StartsAt},
+		{"ends_at", input.EndsAt, &announcement.EndsAt},
+	} {
+		if field.input == nil {
+			continue
+		}
+		if *field.input == "" {
+			*field.time = nil
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, *field.input)
+		if err != nil {
+			return fmt.Errorf("%w: %s must be an RFC 3339 time", ErrInvalidAnnouncement, field.name)
+		}
+		*field.time = &parsed
+	}
+	if input.Dismissible != nil {
+		announcement.Dismissible = *input.Dismissible
+	}
+	if input.IsActive != nil {
+		announcement.IsActive = *input.IsActive
+	}
+	if input.Translations != nil {
+		announcement.Translations = make([]models.AnnouncementTranslation, 0, len(input.Translations))
+		seen := map[string]bool{}
+		for _, t := range input.Translations {
+			language := strings.TrimSpace(t.Language)
+			if !languageCode.MatchString(language) || len(language) > 10 || seen[language] {
+				return fmt.Errorf("%w: each translation needs a distinct language code", ErrInvalidAnnouncement)
+			}
+			seen[language] = true
+			message := strings.TrimSpace(t.Message)
+			if utf8.RuneCountInString(message) > maxAnnouncementLength {
+				return fmt.Errorf("%w: the %s message is at most %d characters", ErrInvalidAnnouncement, language, maxAnnouncementLength)
+			}
+			announcement.Translations = append(announcement.Translations, models.AnnouncementTranslation{
+				AnnouncementID: announcement.ID,
+				Language:       language,
+				Message:        message,
+			})
+		}
+	}
+
+	if announcement.Message == "" || utf8.RuneCountInString(announcement.Message) > maxAnnouncementLength {
+		return fmt.Errorf("%w: message is required and at most %d characters", ErrInvalidAnnouncement, maxAnnouncementLength)
+	}
+	if announcement.LinkURL != "" && !validMediaURL(announcement.LinkURL) {
+		return fmt.Errorf("%w: link_url must be an http(s) URL or a path on the site", ErrInvalidAnnouncement)
+	}
+	switch announcement.Type {
+	case models.AnnouncementInfo, models.AnnouncementSuccess, models.AnnouncementWarning, models.AnnouncementDanger:
+	default:
+		return fmt.Errorf("%w: type must be info, success, warning or danger", ErrInvalidAnnouncement)
+	}
+	if announcement.StartsAt != nil && announcement.EndsAt != nil && !announcement.EndsAt.After(*announcement.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidAnnouncement)
+	}
+	return nil
+}
+
+var (
+	globalAnnouncementService     *AnnouncementService
+	globalAnnouncementServiceOnce sync.Once
+)
+
+// GetGlobalAnnouncementService returns the global announcement service
+func GetGlobalAnnouncementService() *AnnouncementService {
+	globalAnnouncementServiceOnce.Do(func() {
+		globalAnnouncementService = NewAnnouncementService()
+	})
+	return globalAnnouncementService
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestAnnouncements(t *testing.T) {
+	newTestDB(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := &AnnouncementService{
+		db:    func() *gorm.DB { return database.DB },
+		now:   func() time.Time { return now },
+		cache: cache.New("test_announcements", time.Minute),
+	}
+	t.Cleanup(s.cache.Clear)
+	ctx := context.Background()
+
+	for _, bad := range []AnnouncementInput{
+		{Message: strPtr("  ")},
+		{Message: strPtr(strings.Repeat("a", maxAnnouncementLength+1))},
+		{Message: strPtr("Hi"), Type: strPtr("urgent")},
+		{Message: strPtr("Hi"), LinkURL: strPtr("javascript:alert(1)")},
+		{Message: strPtr("Hi"), StartsAt: strPtr("tomorrow")},
+		{Message: strPtr("Hi"), StartsAt: strPtr("2026-05-02T00:00:00Z"), EndsAt: strPtr("2026-05-01T00:00:00Z")},
+		{Message: strPtr("Hi"), Translations: []AnnouncementTranslationInput{{Language: "en", Message: "Hi"}, {Language: "en", Message: "Hello"}}},
+	} {
+		if _, err := s.Create(ctx, bad); !errors.Is(err, ErrInvalidAnnouncement) {
+			t.Errorf("Create(%+v) = %v, want ErrInvalidAnnouncement", bad, err)
+		}
+	}
+
+	dismissible := true
+	current, err := s.Create(ctx, AnnouncementInput{
+		Message: strPtr("站点维护"), Type: strPtr(models.AnnouncementWarning), Dismissible: &dismissible,
+		EndsAt:       strPtr("2026-05-02T00:00:00Z"),
+		Translations: []AnnouncementTranslationInput{{Language: "en", Message: "Maintenance tonight"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, AnnouncementInput{Message: strPtr("Scheduled"), StartsAt: strPtr("2026-05-03T00:00:00Z")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, AnnouncementInput{Message: strPtr("Over"), EndsAt: strPtr("2026-05-01T11:00:00Z")}); err != nil {
+		t.Fatal(err)
+	}
+	inactive := false
+	off, err := s.Create(ctx, AnnouncementInput{Message: strPtr("Off"), IsActive: &inactive})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shown, err := s.Current(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shown) != 1 || shown[0].ID != current.ID || !shown[0].Dismissible {
+		t.Fatalf("current = %+v", shown)
+	}
+	ApplyAnnouncementTranslation(&shown[0], "en")
+	if shown[0].Message != "Maintenance tonight" {
+		t.Errorf("translated message = %q", shown[0].Message)
+	}
+
+	// Changes show at once; time passing within the cache's minute
+	if _, err := s.Update(ctx, off.ID, AnnouncementInput{IsActive: &dismissible}); err != nil {
+		t.Fatal(err)
+	}
+	if shown, _ := s.Current(ctx); len(shown) != 2 {
+		t.Errorf("%d current after turning one on, want 2", len(shown))
+	}
+	now = now.Add(48 * time.Hour)
+	s.cache.Clear()
+	shown, _ = s.Current(ctx)
+	if len(shown) != 2 || (shown[0].Message != "Scheduled" && shown[1].Message != "Scheduled") {
+		t.Errorf("two days later = %+v", shown)
+	}
+
+	// Clearing the end time keeps the announcement up
+	updated, err := s.Update(ctx, current.ID, AnnouncementInput{EndsAt: strPtr(""), Translations: []AnnouncementTranslationInput{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.EndsAt != nil || len(updated.Translations) != 0 {
+		t.Errorf("after update %+v", updated)
+	}
+	if shown, _ := s.Current(ctx); len(shown) != 3 {
+		t.Errorf("%d current after clearing the end, want 3", len(shown))
+	}
+	if err := s.Delete(ctx, current.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, current.ID); !errors.Is(err, ErrAnnouncementNotFound) {
+		t.Errorf("second delete = %v", err)
+	}
+	var translations int64
+	database.DB.Model(&models.AnnouncementTranslation{}).Count(&translations)
+	if translations != 0 {
+		t.Errorf("%d translations left", translations)
+	}
+}
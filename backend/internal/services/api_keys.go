@@ -0,0 +1,315 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Limits of API keys
+const (
+	apiKeySecretPrefix   = "kuno_"
+	apiKeyPrefixLength   = len(apiKeySecretPrefix) + 8
+	maxAPIKeyNameLength  = 100
+	maxAPIKeyRateLimit   = 1000000
+	maxAPIKeyUsageDays   = 90
+	apiKeyLastUsedWindow = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey     = errors.New("invalid API key")
+	ErrAPIKeyNotFound    = errors.New("API key not found")
+	ErrAPIKeyRejected    = errors.New("API key is unknown or revoked")
+	ErrAPIKeyRateLimited = errors.New("API key rate limit exceeded")
+)
+
+// APIKeyInput holds the editable fields of an API key; nil fields are left
+// unchanged. A RateLimit of 0 follows the configured default.
+type APIKeyInput struct {
+	Name      *string `json:"name"`
+	RateLimit *int    `json:"rate_limit"`
+}
+
+// APIKeyInfo is an API key with the requests made with it recently
+type APIKeyInfo struct {
+	models.APIKey
+	Requests24h int64 `json:"requests_24h"`
+	Requests30d int64 `json:"requests_30d"`
+}
+
+// APIKeyDailyUsage counts the requests made with a key on a day, in UTC
+type APIKeyDailyUsage struct {
+	Date     string `json:"date"`
+	Requests int64  `json:"requests"`
+	Limited  int64  `json:"limited"`
+}
+
+// APIKeyAllowance is what a request made with a key may still do: Remaining
+// requests until the limit resets at Reset, the start of the next hour
+type APIKeyAllowance struct {
+	Key       *models.APIKey
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// APIKeyService issues the read-only API keys third-party apps use to read
+// the public API, and enforces their hourly rate limits. The secret of a
+// key is shown once, when it is created; only its hash is stored.
+type APIKeyService struct {
+	db           func() *gorm.DB
+	now          func() time.Time
+	defaultLimit int
+	retention    time.Duration
+}
+
+// NewAPIKeyService creates an API key service configured from API_KEY_*
+// settings
+func NewAPIKeyService() *APIKeyService {
+	cfg := config.Get().APIKeys
+	return &APIKeyService{
+		db:           func() *gorm.DB { return database.DB },
+		now:          time.Now,
+		defaultLimit: cfg.RateLimit,
+		retention:    time.Duration(cfg.UsageRetention),
+	}
+}
+
+// List returns the site's API keys, newest first, with their recent usage
+func (s *APIKeyService) List(ctx context.Context) ([]APIKeyInfo, error) {
+	var keys []models.APIKey
+	if err := s.db().WithContext(ctx).Order("created_at DESC, id DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	infos := make([]APIKeyInfo, len(keys))
+	if len(keys) == 0 {
+		return infos, nil
+	}
+	ids := make([]uint, len(keys))
+	for i, key := range keys {
+		ids[i] = key.ID
+	}
+	now := s.now().UTC()
+	day, month := now.Add(-24*time.Hour), now.Add(-30*24*time.Hour)
+	var usages []models.APIKeyUsage
+	err := s.db().WithContext(ctx).Where("key_id IN ? AND hour > ?", ids, month.Truncate(time.Hour)).Find(&usages).Error
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[uint]*APIKeyInfo{}
+	for i, key := range keys {
+		infos[i].APIKey = key
+		byKey[key.ID] = &infos[i]
+	}
+	for _, usage := range usages {
+		info := byKey[usage.KeyID]
+		info.Requests30d += usage.Requests
+		if usage.Hour.After(day.Truncate(time.Hour)) {
+			info.Requests24h += usage.Requests
+		}
+	}
+	return infos, nil
+}
+
+// Create issues a key, returning it with its secret, which is not shown
+// again
+func (s *APIKeyService) Create(ctx context.Context, input APIKeyInput) (*models.APIKey, string, error) {
+	key := &models.APIKey{}
+	if err := applyAPIKeyInput(key, input); err != nil {
+		return nil, "", err
+	}
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", err
+	}
+	secret := apiKeySecretPrefix + hex.EncodeToString(buf)
+	key.Prefix = secret[:apiKeyPrefixLength]
+	key.KeyHash = hashAPIKey(secret)
+	if err := s.db().WithContext(ctx).Create(key).Error; err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// Update renames a key or changes its rate limit
+func (s *APIKeyService) Update(ctx context.Context, id uint, input APIKeyInput) (*models.APIKey, error) {
+	key, err := s.key(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyAPIKeyInput(key, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Save(key).Error; err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Revoke stops a key from working for good. Its usage is kept until it
+// ages out.
+func (s *APIKeyService) Revoke(ctx context.Context, id uint) (*models.APIKey, error) {
+	key, err := s.key(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+	now := s.now()
+	if err := s.db().WithContext(ctx).Model(key).Update("revoked_at", now).Error; err != nil {
+		return nil, err
+	}
+	key.RevokedAt = &now
+	return key, nil
+}
+
+// Usage returns the requests made with a key on each of the last days,
+// oldest first, including today
+func (s *APIKeyService) Usage(ctx context.Context, id uint, days int) ([]APIKeyDailyUsage, error) {
+	if days < 1 || days > maxAPIKeyUsageDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidAPIKey, maxAPIKeyUsageDays)
+	}
+	if _, err := s.key(ctx, id); err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1-days)
+	var usages []models.APIKeyUsage
+	if err := s.db().WithContext(ctx).Where("key_id = ? AND hour >= ?", id, start).Find(&usages).Error; err != nil {
+		return nil, err
+	}
+	daily := make([]APIKeyDailyUsage, days)
+	for i := range daily {
+		daily[i].Date = start.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	for _, usage := range usages {
+		i := int(usage.Hour.UTC().Sub(start) / (24 * time.Hour))
+		if i >= 0 && i < days {
+			daily[i].Requests += usage.Requests
+			daily[i].Limited += usage.Limited
+		}
+	}
+	return daily, nil
+}
+
+// Authorize counts a request made with the secret against its key's hourly
+// limit. It returns ErrAPIKeyRejected for a key of another site, unknown or
+// revoked, and ErrAPIKeyRateLimited with the allowance once the limit is
+// reached.
+func (s *APIKeyService) Authorize(ctx context.Context, secret string) (*APIKeyAllowance, error) {
+	if !strings.HasPrefix(secret, apiKeySecretPrefix) {
+		return nil, ErrAPIKeyRejected
+	}
+	var key models.APIKey
+	err := s.db().WithContext(ctx).Where("key_hash = ? AND revoked_at IS NULL", hashAPIKey(secret)).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyRejected
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	hour := now.UTC().Truncate(time.Hour)
+	usage := models.APIKeyUsage{KeyID: key.ID, Hour: hour, Requests: 1}
+	db := s.db().WithContext(ctx)
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key_id"}, {Name: "hour"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"requests": gorm.Expr("api_key_usages.requests + 1")}),
+	}).Create(&usage).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Where("key_id = ? AND hour = ?", key.ID, hour).First(&usage).Error; err != nil {
+		return nil, err
+	}
+
+	allowance := &APIKeyAllowance{Key: &key, Limit: key.RateLimit, Reset: hour.Add(time.Hour)}
+	if allowance.Limit == 0 {
+		allowance.Limit = s.defaultLimit
+	}
+	if usage.Requests > int64(allowance.Limit) {
+		err := db.Model(&models.APIKeyUsage{}).Where("id = ?", usage.ID).
+			Update("limited", gorm.Expr("limited + 1")).Error
+		if err != nil {
+			return nil, err
+		}
+		return allowance, ErrAPIKeyRateLimited
+	}
+	allowance.Remaining = allowance.Limit - int(usage.Requests)
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyLastUsedWindow {
+		if err := db.Model(&key).UpdateColumn("last_used_at", now).Error; err != nil {
+			return nil, err
+		}
+	}
+	return allowance, nil
+}
+
+// PurgeUsage deletes the usage of keys older than the configured retention
+func (s *APIKeyService) PurgeUsage(ctx context.Context) (int64, error) {
+	cutoff := s.now().UTC().Add(-s.retention)
+	result := s.db().WithContext(ctx).Where("hour < ?", cutoff).Delete(&models.APIKeyUsage{})
+	return result.RowsAffected, result.Error
+}
+
+// key returns the site's key with the ID
+func (s *APIKeyService) key(ctx context.Context, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := s.db().WithContext(ctx).First(&key, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func applyAPIKeyInput(key *models.APIKey, input APIKeyInput) error {
+	if input.Name != nil {
+		key.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.RateLimit != nil {
+		key.RateLimit = *input.RateLimit
+	}
+	if key.Name == "" || utf8.RuneCountInString(key.Name) > maxAPIKeyNameLength {
+		return fmt.Errorf("%w: name is required and at most %d characters", ErrInvalidAPIKey, maxAPIKeyNameLength)
+	}
+	if key.RateLimit < 0 || key.RateLimit > maxAPIKeyRateLimit {
+		return fmt.Errorf("%w: rate_limit must be between 0, for the default, and %d", ErrInvalidAPIKey, maxAPIKeyRateLimit)
+	}
+	return nil
+}
+
+// hashAPIKey returns the hex SHA-256 of a key's secret, as stored
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	globalAPIKeyService     *APIKeyService
+	globalAPIKeyServiceOnce sync.Once
+)
+
+// GetGlobalAPIKeyService returns the global API key service
+func GetGlobalAPIKeyService() *APIKeyService {
+	globalAPIKeyServiceOnce.Do(func() {
+		globalAPIKeyService = NewAPIKeyService()
+	})
+	return globalAPIKeyService
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	newTestDB(t)
+	if err := database.DB.Use(database.SiteScope{}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+	s := NewAPIKeyService()
+	s.now = func() time.Time { return now }
+	s.defaultLimit = 3
+	s.retention = 7 * 24 * time.Hour
+	ctx := database.WithSite(context.Background(), models.DefaultSiteID)
+
+	name, blank, negative := "Mobile app", " ", -1
+	for _, bad := range []APIKeyInput{{}, {Name: &blank}, {Name: &name, RateLimit: &negative}} {
+		if _, _, err := s.Create(ctx, bad); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("Create(%+v) = %v, want ErrInvalidAPIKey", bad, err)
+		}
+	}
+	key, secret, err := s.Create(ctx, APIKeyInput{Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) < 40 || key.Prefix != secret[:len(key.Prefix)] || key.KeyHash == secret {
+		t.Fatalf("Create() = %+v, %q", key, secret)
+	}
+
+	// The default limit applies until the key has its own
+	for i := 0; i < 3; i++ {
+		allowance, err := s.Authorize(ctx, secret)
+		if err != nil || allowance.Remaining != 2-i || allowance.Limit != 3 {
+			t.Fatalf("request %d = %+v, %v", i+1, allowance, err)
+		}
+	}
+	allowance, err := s.Authorize(ctx, secret)
+	if !errors.Is(err, ErrAPIKeyRateLimited) || !allowance.Reset.Equal(time.Date(2026, 3, 10, 13, 0, 0, 0, time.UTC)) {
+		t.Fatalf("request over the limit = %+v, %v, want ErrAPIKeyRateLimited", allowance, err)
+	}
+	limit := 10
+	if _, err := s.Update(ctx, key.ID, APIKeyInput{RateLimit: &limit}); err != nil {
+		t.Fatal(err)
+	}
+	if allowance, err = s.Authorize(ctx, secret); err != nil || allowance.Remaining != 5 {
+		t.Errorf("request after raising the limit = %+v, %v", allowance, err)
+	}
+
+	// The next hour starts afresh
+	now = now.Add(time.Hour)
+	if allowance, err = s.Authorize(ctx, secret); err != nil || allowance.Remaining != 9 {
+		t.Errorf("request in the next hour = %+v, %v", allowance, err)
+	}
+
+	keys, err := s.List(ctx)
+	if err != nil || len(keys) != 1 || keys[0].Requests24h != 6 || keys[0].LastUsedAt == nil {
+		t.Fatalf("List() = %+v, %v", keys, err)
+	}
+	usage, err := s.Usage(ctx, key.ID, 2)
+	if err != nil || len(usage) != 2 || usage[1].Date != "2026-03-10" || usage[1].Requests != 6 || usage[1].Limited != 1 {
+		t.Errorf("Usage() = %+v, %v", usage, err)
+	}
+
+	// Keys work on their own site only, and not once revoked
+	other := database.WithSite(context.Background(), 2)
+	if _, err := s.Authorize(other, secret); !errors.Is(err, ErrAPIKeyRejected) {
+		t.Errorf("key on another site = %v, want ErrAPIKeyRejected", err)
+	}
+	if _, err := s.Revoke(other, key.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Revoke() from another site = %v, want ErrAPIKeyNotFound", err)
+	}
+	if _, err := s.Revoke(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authorize(ctx, secret); !errors.Is(err, ErrAPIKeyRejected) {
+		t.Errorf("revoked key = %v, want ErrAPIKeyRejected", err)
+	}
+
+	now = now.Add(8 * 24 * time.Hour)
+	if deleted, err := s.PurgeUsage(ctx); err != nil || deleted != 2 {
+		t.Errorf("PurgeUsage() = %d, %v, want 2 rows deleted", deleted, err)
+	}
+}
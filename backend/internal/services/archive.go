@@ -0,0 +1,183 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// archiveTTL is how long a built archive is kept when no scheduled article
+// is due sooner
+const archiveTTL = time.Hour
+
+// ArchiveArticle is an article as listed on the archive page
+type ArchiveArticle struct {
+	ID         uint      `json:"id"`
+	Title      string    `json:"title"`
+	Slug       string    `json:"slug"`
+	CategoryID uint      `json:"category_id"`
+	RegionRule string    `json:"region_rule,omitempty"`
+	Regions    string    `json:"regions,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ArchiveMonth holds the articles published in one month, newest first
+type ArchiveMonth struct {
+	Month    int              `json:"month"`
+	Count    int              `json:"count"`
+	Articles []ArchiveArticle `json:"articles"`
+}
+
+// ArchiveYear holds the months of one year that have articles, newest first
+type ArchiveYear struct {
+	Year   int            `json:"year"`
+	Count  int            `json:"count"`
+	Months []ArchiveMonth `json:"months"`
+}
+
+// Archive is a site's published articles grouped by year and month
+type Archive struct {
+	Total int           `json:"total"`
+	Years []ArchiveYear `json:"years"`
+}
+
+// Without returns the archive without the articles withheld reports, with
+// the counts of those left and without the months and years left empty
+func (a *Archive) Without(withheld func(*ArchiveArticle) bool) *Archive {
+	served := &Archive{Years: []ArchiveYear{}}
+	for _, year := range a.Years {
+		servedYear := ArchiveYear{Year: year.Year}
+		for _, month := range year.Months {
+			servedMonth := ArchiveMonth{Month: month.Month}
+			for i := range month.Articles {
+				if !withheld(&month.Articles[i]) {
+					servedMonth.Articles = append(servedMonth.Articles, month.Articles[i])
+				}
+			}
+			if servedMonth.Count = len(servedMonth.Articles); servedMonth.Count > 0 {
+				servedYear.Months = append(servedYear.Months, servedMonth)
+				servedYear.Count += servedMonth.Count
+			}
+		}
+		if servedYear.Count > 0 {
+			served.Years = append(served.Years, servedYear)
+			served.Total += servedYear.Count
+		}
+	}
+	return served
+}
+
+// ArchiveService builds the archive page: every visible article of a site,
+// grouped by the UTC year and month it was published in. Archives are
+// cached per site, language and year until an article changes or the next
+// scheduled article comes out.
+type ArchiveService struct {
+	db    func() *gorm.DB
+	cache *cache.Namespace
+	now   func() time.Time
+}
+
+// NewArchiveService creates an archive service
+func NewArchiveService() *ArchiveService {
+	s := &ArchiveService{
+		db:    func() *gorm.DB { return database.DB },
+		cache: cache.New("archive", archiveTTL),
+		now:   time.Now,
+	}
+	for _, topic := range []string{cache.TopicArticles, cache.TopicSettings} {
+		cache.Subscribe(topic, s.cache.Clear)
+	}
+	return s
+}
+
+// Get returns the archive of the site in ctx with titles and slugs in lang,
+// or in the articles' own language when lang is the site's defaultLang.
+// A non-zero year limits it to that year.
+func (s *ArchiveService) Get(ctx context.Context, lang, defaultLang string, year int) (*Archive, error) {
+	siteID, _ := database.SiteFromContext(ctx)
+	key := fmt.Sprintf("%d:%s:%d", siteID, lang, year)
+	var archive Archive
+	if s.cache.Get(key, &archive) {
+		return &archive, nil
+	}
+
+	now := s.now()
+	db := s.db().WithContext(ctx)
+	query := db.Model(&models.Article{}).
+		Select("id", "title", "seo_slug", "category_id", "region_rule", "regions", "created_at").
+		Preload("Translations", func(tx *gorm.DB) *gorm.DB {
+			return tx.Select("id", "article_id", "language", "title", "seo_slug")
+		}).
+		Where("created_at <= ?", now).
+		Order("created_at DESC, id DESC")
+	if year != 0 {
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		query = query.Where("created_at >= ? AND created_at < ?", start, start.AddDate(1, 0, 0))
+	}
+	var articles []models.Article
+	if err := query.Find(&articles).Error; err != nil {
+		return nil, err
+	}
+
+	archive = Archive{Years: []ArchiveYear{}}
+	for i := range articles {
+		article := &articles[i]
+		if lang != "" && lang != defaultLang {
+			ApplyTranslation(article, lang)
+		}
+		entry := ArchiveArticle{
+			ID:         article.ID,
+			Title:      article.Title,
+			Slug:       ArticleSlug(article, lang),
+			CategoryID: article.CategoryID,
+			RegionRule: article.RegionRule,
+			Regions:    article.Regions,
+			CreatedAt:  article.CreatedAt,
+		}
+		created := article.CreatedAt.UTC()
+		if n := len(archive.Years); n == 0 || archive.Years[n-1].Year != created.Year() {
+			archive.Years = append(archive.Years, ArchiveYear{Year: created.Year()})
+		}
+		y := &archive.Years[len(archive.Years)-1]
+		if n := len(y.Months); n == 0 || y.Months[n-1].Month != int(created.Month()) {
+			y.Months = append(y.Months, ArchiveMonth{Month: int(created.Month())})
+		}
+		m := &y.Months[len(y.Months)-1]
+		m.Articles = append(m.Articles, entry)
+		m.Count++
+		y.Count++
+		archive.Total++
+	}
+
+	// Scheduled articles appear without being saved again, so the archive
+	// must not outlive the next one
+	ttl := archiveTTL
+	var next models.Article
+	if err := db.Select("created_at").Where("created_at > ?", now).Order("created_at ASC").Limit(1).Find(&next).Error; err != nil {
+		return nil, err
+	}
+	if !next.CreatedAt.IsZero() && next.CreatedAt.Sub(now) < ttl {
+		ttl = next.CreatedAt.Sub(now)
+	}
+	s.cache.SetWithTTL(key, archive, ttl)
+	return &archive, nil
+}
+
+var (
+	globalArchiveService     *ArchiveService
+	globalArchiveServiceOnce sync.Once
+)
+
+// GetGlobalArchiveService returns the global archive service
+func GetGlobalArchiveService() *ArchiveService {
+	globalArchiveServiceOnce.Do(func() {
+		globalArchiveService = NewArchiveService()
+	})
+	return globalArchiveService
+}
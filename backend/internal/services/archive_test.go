@@ -0,0 +1,65 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	newTestDB(t)
+	s := NewArchiveService()
+	now := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, article := range []models.Article{
+		{Title: "First", SEOSlug: "first", CreatedAt: time.Date(2025, time.December, 31, 23, 0, 0, 0, time.UTC)},
+		{Title: "Second", CreatedAt: time.Date(2026, time.February, 1, 8, 0, 0, 0, time.UTC),
+			Translations: []models.ArticleTranslation{{Language: "en", Title: "Second (en)", SEOSlug: "second-en"}}},
+		{Title: "Third", CreatedAt: time.Date(2026, time.February, 20, 8, 0, 0, 0, time.UTC)},
+		{Title: "Scheduled", CreatedAt: now.Add(30 * time.Minute)},
+	} {
+		if err := database.DB.Create(&article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive, err := s.Get(ctx, "en", "zh", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archive.Total != 3 || len(archive.Years) != 2 {
+		t.Fatalf("archive = %+v, want 3 articles in 2 years without the scheduled one", archive)
+	}
+	y2026 := archive.Years[0]
+	if y2026.Year != 2026 || y2026.Count != 2 || len(y2026.Months) != 1 || y2026.Months[0].Month != 2 {
+		t.Fatalf("2026 = %+v", y2026)
+	}
+	if feb := y2026.Months[0].Articles; feb[0].Title != "Third" || feb[1].Title != "Second (en)" || feb[1].Slug != "second-en" {
+		t.Errorf("February = %+v", feb)
+	}
+	if dec := archive.Years[1]; dec.Year != 2025 || dec.Months[0].Month != 12 || dec.Months[0].Articles[0].Slug != "first" {
+		t.Errorf("2025 = %+v", dec)
+	}
+
+	if archive, _ := s.Get(ctx, "zh", "zh", 2025); archive.Total != 1 || archive.Years[0].Year != 2025 {
+		t.Errorf("2025 only = %+v", archive)
+	}
+	if archive, _ := s.Get(ctx, "zh", "zh", 0); archive.Years[0].Months[0].Articles[1].Title != "Second" {
+		t.Errorf("default language titles = %+v", archive.Years[0].Months[0].Articles)
+	}
+
+	// Cached until articles change
+	database.DB.Create(&models.Article{Title: "Fourth", CreatedAt: now.Add(-time.Hour)})
+	if archive, _ := s.Get(ctx, "en", "zh", 0); archive.Total != 3 {
+		t.Errorf("cached archive has %d articles, want 3", archive.Total)
+	}
+	cache.Publish(cache.TopicArticles)
+	if archive, _ := s.Get(ctx, "en", "zh", 0); archive.Total != 4 || archive.Years[0].Months[0].Month != 3 {
+		t.Errorf("archive after publishing = %+v", archive)
+	}
+}
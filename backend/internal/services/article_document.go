@@ -0,0 +1,532 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Article documents carry one article, its translations, SEO fields,
+// category and the media it uses from one KUNO site to another as a single
+// JSON document. Ids are those of the exporting site and are remapped on
+// import; media files stay on the exporting site unless the importing site
+// has the same URL in its library.
+const (
+	ArticleDocumentFormat  = "kuno-article"
+	ArticleDocumentVersion = 1
+)
+
+// ErrInvalidArticleDocument is returned for documents that are not article
+// documents or lack what an article needs
+var ErrInvalidArticleDocument = errors.New("invalid article document")
+
+// ErrUnsupportedArticleDocument is returned for documents written by a
+// newer format version
+var ErrUnsupportedArticleDocument = errors.New("article document version not supported")
+
+// ErrArticleAlreadyImported is returned when the site already has an
+// article imported from the same source
+var ErrArticleAlreadyImported = errors.New("article already imported")
+
+// uploadURLPattern finds the links to uploaded media in article content
+var uploadURLPattern = regexp.MustCompile(`/uploads/[^\s"'()<>\[\]]+`)
+
+// ArticleDocument is a self-contained export of one article
+type ArticleDocument struct {
+	Format       string                       `json:"format"`
+	Version      int                          `json:"version"`
+	ExportedAt   time.Time                    `json:"exported_at"`
+	Site         ArticleDocumentSite          `json:"site"`
+	Article      ArticleDocumentArticle       `json:"article"`
+	Translations []ArticleDocumentTranslation `json:"translations"`
+	Category     *ArticleDocumentCategory     `json:"category,omitempty"`
+	Media        []ArticleDocumentMedia       `json:"media"`
+}
+
+// ArticleDocumentSite is the site an article was exported from. Media URLs
+// of the document are relative to URL.
+type ArticleDocumentSite struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// ArticleDocumentArticle is the article itself. URL is its address on the
+// exporting site.
+type ArticleDocumentArticle struct {
+	ID             uint      `json:"id"`
+	URL            string    `json:"url"`
+	Title          string    `json:"title"`
+	Content        string    `json:"content"`
+	ContentType    string    `json:"content_type"`
+	Summary        string    `json:"summary"`
+	DefaultLang    string    `json:"default_lang"`
+	CoverImageURL  string    `json:"cover_image_url,omitempty"`
+	CoverImageID   *uint     `json:"cover_image_id,omitempty"`
+	CoverImageAlt  string    `json:"cover_image_alt,omitempty"`
+	ShowDonation   *bool     `json:"show_donation,omitempty"`
+	License        string    `json:"license,omitempty"`
+	LicenseCustom  string    `json:"license_custom,omitempty"`
+	SourceName     string    `json:"source_name,omitempty"`
+	SourceURL      string    `json:"source_url,omitempty"`
+	CanonicalURL   string    `json:"canonical_url,omitempty"`
+	MembersOnly    bool      `json:"members_only"`
+	RegionRule     string    `json:"region_rule,omitempty"`
+	Regions        string    `json:"regions,omitempty"`
+	SEOTitle       string    `json:"seo_title"`
+	SEODescription string    `json:"seo_description"`
+	SEOKeywords    string    `json:"seo_keywords"`
+	SEOSlug        string    `json:"seo_slug"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ArticleDocumentTranslation is a translation of the article
+type ArticleDocumentTranslation struct {
+	Language          string `json:"language"`
+	Title             string `json:"title"`
+	Content           string `json:"content"`
+	Summary           string `json:"summary"`
+	SEOSlug           string `json:"seo_slug"`
+	MachineTranslated bool   `json:"machine_translated"`
+}
+
+// ArticleDocumentCategory is the article's category, matched by name on
+// import
+type ArticleDocumentCategory struct {
+	ID           uint                                 `json:"id"`
+	Name         string                               `json:"name"`
+	Description  string                               `json:"description"`
+	DefaultLang  string                               `json:"default_lang"`
+	Translations []ArticleDocumentCategoryTranslation `json:"translations,omitempty"`
+}
+
+// ArticleDocumentCategoryTranslation is a translation of the category
+type ArticleDocumentCategoryTranslation struct {
+	Language    string `json:"language"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ArticleDocumentMedia is an uploaded file the article links to or uses as
+// its cover
+type ArticleDocumentMedia struct {
+	ID           uint             `json:"id"`
+	URL          string           `json:"url"`
+	OriginalName string           `json:"original_name"`
+	MimeType     string           `json:"mime_type"`
+	MediaType    models.MediaType `json:"media_type"`
+	FileSize     int64            `json:"file_size"`
+	Alt          string           `json:"alt,omitempty"`
+}
+
+// ArticleImportResult tells what importing a document created and how the
+// exporting site's ids map to this site's. Media the site does not have is
+// linked on the exporting site and listed in RemoteMedia.
+type ArticleImportResult struct {
+	SourceID        uint          `json:"source_id"`
+	ArticleID       uint          `json:"article_id"`
+	CategoryID      uint          `json:"category_id,omitempty"`
+	CategoryCreated bool          `json:"category_created"`
+	Translations    int           `json:"translations"`
+	Media           map[uint]uint `json:"media"`
+	RemoteMedia     []string      `json:"remote_media,omitempty"`
+	// ClearedSlugs lists the languages whose slug another article of the
+	// site already had, "" for the article's own
+	ClearedSlugs []string `json:"cleared_slugs,omitempty"`
+}
+
+// ExportArticleDocument returns the article with the given id as a
+// document. baseURL is the address of the site, used for the article's
+// URL and to resolve media.
+func ExportArticleDocument(db *gorm.DB, id uint, baseURL string) (*ArticleDocument, error) {
+	var article models.Article
+	if err := db.Preload("Category.Translations").Preload("Translations").First(&article, id).Error; err != nil {
+		return nil, err
+	}
+	var settings models.SiteSettings
+	if err := db.Select("site_title").Order("id").Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+
+	baseURL = strings.TrimRight(baseURL, "/")
+	doc := &ArticleDocument{
+		Format:     ArticleDocumentFormat,
+		Version:    ArticleDocumentVersion,
+		ExportedAt: time.Now().UTC(),
+		Site:       ArticleDocumentSite{Title: settings.SiteTitle, URL: baseURL},
+		Article: ArticleDocumentArticle{
+			ID:             article.ID,
+			URL:            localizedArticleLink(baseURL, article.DefaultLang, article.ID),
+			Title:          article.Title,
+			Content:        article.Content,
+			ContentType:    article.ContentType,
+			Summary:        article.Summary,
+			DefaultLang:    article.DefaultLang,
+			CoverImageID:   article.CoverImageID,
+			CoverImageAlt:  article.CoverImageAlt,
+			ShowDonation:   article.ShowDonation,
+			License:        article.License,
+			LicenseCustom:  article.LicenseCustom,
+			SourceName:     article.SourceName,
+			SourceURL:      article.SourceURL,
+			CanonicalURL:   article.CanonicalURL,
+			MembersOnly:    article.MembersOnly,
+			RegionRule:     article.RegionRule,
+			Regions:        article.Regions,
+			SEOTitle:       article.SEOTitle,
+			SEODescription: article.SEODescription,
+			SEOKeywords:    article.SEOKeywords,
+			SEOSlug:        article.SEOSlug,
+			CreatedAt:      article.CreatedAt,
+			UpdatedAt:      article.UpdatedAt,
+		},
+		Translations: []ArticleDocumentTranslation{},
+		Media:        []ArticleDocumentMedia{},
+	}
+	if article.CoverImageURL != nil {
+		doc.Article.CoverImageURL = *article.CoverImageURL
+	}
+	for _, translation := range article.Translations {
+		doc.Translations = append(doc.Translations, ArticleDocumentTranslation{
+			Language:          translation.Language,
+			Title:             translation.Title,
+			Content:           translation.Content,
+			Summary:           translation.Summary,
+			SEOSlug:           translation.SEOSlug,
+			MachineTranslated: translation.MachineTranslated,
+		})
+	}
+	if article.Category.ID != 0 {
+		category := &ArticleDocumentCategory{
+			ID:          article.Category.ID,
+			Name:        article.Category.Name,
+			Description: article.Category.Description,
+			DefaultLang: article.Category.DefaultLang,
+		}
+		for _, translation := range article.Category.Translations {
+			category.Translations = append(category.Translations, ArticleDocumentCategoryTranslation{
+				Language: translation.Language, Name: translation.Name, Description: translation.Description,
+			})
+		}
+		doc.Category = category
+	}
+
+	media, err := articleMedia(db, &article)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range media {
+		doc.Media = append(doc.Media, ArticleDocumentMedia{
+			ID:           item.ID,
+			URL:          item.URL,
+			OriginalName: item.OriginalName,
+			MimeType:     item.MimeType,
+			MediaType:    item.MediaType,
+			FileSize:     item.FileSize,
+			Alt:          item.Alt,
+		})
+	}
+	return doc, nil
+}
+
+// articleMedia returns the library entries of the uploads an article and
+// its translations link to, and of its cover
+func articleMedia(db *gorm.DB, article *models.Article) ([]models.MediaLibrary, error) {
+	texts := []string{article.Content}
+	if article.CoverImageURL != nil {
+		texts = append(texts, *article.CoverImageURL)
+	}
+	for _, translation := range article.Translations {
+		texts = append(texts, translation.Content)
+	}
+	urls := map[string]bool{}
+	for _, text := range texts {
+		for _, url := range uploadURLPattern.FindAllString(text, -1) {
+			urls[url] = true
+		}
+	}
+	if len(urls) == 0 && article.CoverImageID == nil {
+		return nil, nil
+	}
+
+	query := db.Where("url IN ?", sortedKeys(urls))
+	if article.CoverImageID != nil {
+		query = query.Or("id = ?", *article.CoverImageID)
+	}
+	var media []models.MediaLibrary
+	if err := query.Order("id").Find(&media).Error; err != nil {
+		return nil, fmt.Errorf("failed to read the article's media: %v", err)
+	}
+	return media, nil
+}
+
+// ValidateArticleDocument checks a document's format, version and content
+func ValidateArticleDocument(doc *ArticleDocument) error {
+	if doc.Format != ArticleDocumentFormat || doc.Version < 1 {
+		return fmt.Errorf("%w: format must be %q", ErrInvalidArticleDocument, ArticleDocumentFormat)
+	}
+	if doc.Version > ArticleDocumentVersion {
+		return fmt.Errorf("%w: version %d, this release reads up to %d",
+			ErrUnsupportedArticleDocument, doc.Version, ArticleDocumentVersion)
+	}
+	if strings.TrimSpace(doc.Article.Title) == "" {
+		return fmt.Errorf("%w: the article has no title", ErrInvalidArticleDocument)
+	}
+	if doc.Category != nil && strings.TrimSpace(doc.Category.Name) == "" {
+		return fmt.Errorf("%w: the category has no name", ErrInvalidArticleDocument)
+	}
+	return nil
+}
+
+// ImportArticleDocument creates the document's article on the site db is
+// scoped to, credited to authorID. The category is matched by name and
+// created when the site has none by that name. Media is linked to the
+// site's library entry with the same URL; the rest keeps pointing at the
+// exporting site. Slugs another article already has are left out. Nothing
+// is imported if any part fails.
+func ImportArticleDocument(ctx context.Context, db *gorm.DB, doc *ArticleDocument, authorID *uint) (*models.Article, *ArticleImportResult, error) {
+	if err := ValidateArticleDocument(doc); err != nil {
+		return nil, nil, err
+	}
+	license, licenseCustom, err := NormalizeLicense(doc.Article.License, doc.Article.LicenseCustom, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArticleDocument, err)
+	}
+	regionRule, regions, err := NormalizeRegionRule(doc.Article.RegionRule, doc.Article.Regions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArticleDocument, err)
+	}
+
+	db = db.WithContext(ctx)
+	// The article is credited to where it first appeared, which also tells
+	// a second import of it apart
+	sourceName, sourceURL := doc.Article.SourceName, doc.Article.SourceURL
+	if sourceURL == "" {
+		sourceName, sourceURL = doc.Site.Title, doc.Article.URL
+	}
+	if sourceURL != "" {
+		var existing models.Article
+		if err := db.Select("id").Where("source_url = ?", sourceURL).Limit(1).Find(&existing).Error; err != nil {
+			return nil, nil, err
+		}
+		if existing.ID != 0 {
+			return nil, nil, fmt.Errorf("%w as article %d", ErrArticleAlreadyImported, existing.ID)
+		}
+	}
+
+	siteID := uint(models.DefaultSiteID)
+	if id, ok := database.SiteFromContext(ctx); ok {
+		siteID = id
+	}
+	result := &ArticleImportResult{SourceID: doc.Article.ID, Media: map[uint]uint{}}
+	article := &models.Article{
+		SiteID:         siteID,
+		Title:          doc.Article.Title,
+		Content:        doc.Article.Content,
+		ContentType:    firstNonEmpty(doc.Article.ContentType, "markdown"),
+		Summary:        doc.Article.Summary,
+		DefaultLang:    firstNonEmpty(doc.Article.DefaultLang, "zh"),
+		CoverImageAlt:  doc.Article.CoverImageAlt,
+		ShowDonation:   doc.Article.ShowDonation,
+		License:        license,
+		LicenseCustom:  licenseCustom,
+		SourceName:     truncateRunes(sourceName, 255),
+		SourceURL:      sourceURL,
+		CanonicalURL:   doc.Article.CanonicalURL,
+		MembersOnly:    doc.Article.MembersOnly,
+		RegionRule:     regionRule,
+		Regions:        regions,
+		SEOTitle:       doc.Article.SEOTitle,
+		SEODescription: doc.Article.SEODescription,
+		SEOKeywords:    doc.Article.SEOKeywords,
+		SEOSlug:        doc.Article.SEOSlug,
+		AuthorID:       authorID,
+		CreatedAt:      doc.Article.CreatedAt,
+		UpdatedAt:      doc.Article.UpdatedAt,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		rewrite, err := importDocumentMedia(tx, doc, article, result)
+		if err != nil {
+			return err
+		}
+		sanitize := security.ShouldSanitize(true)
+		policy := security.GetGlobalHTMLPolicy()
+		content := func(text string) string {
+			text = rewrite.Replace(text)
+			if sanitize {
+				text = policy.SanitizeContent(text, article.ContentType)
+			}
+			return text
+		}
+		article.Content = content(article.Content)
+
+		if doc.Category != nil {
+			if article.CategoryID, err = importDocumentCategory(tx, doc.Category, siteID, result); err != nil {
+				return err
+			}
+		}
+		if err := CheckArticleSlug(tx, 0, "", article.SEOSlug); err != nil {
+			if !errors.Is(err, ErrSlugInUse) {
+				return err
+			}
+			article.SEOSlug = ""
+			result.ClearedSlugs = append(result.ClearedSlugs, "")
+		}
+		if err := hooks.Filter(ctx, hooks.BeforeArticleSave, article); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(article).Error; err != nil {
+			return fmt.Errorf("failed to import article %q: %v", article.Title, err)
+		}
+		// Create stores the column default for false
+		if article.ShowDonation != nil && !*article.ShowDonation {
+			if err := tx.Model(article).Update("show_donation", false).Error; err != nil {
+				return err
+			}
+		}
+		if err := RecordSlugChange(tx, article, "", "", article.SEOSlug); err != nil {
+			return err
+		}
+
+		for _, item := range doc.Translations {
+			if item.Language == "" || item.Language == article.DefaultLang {
+				continue
+			}
+			translation := models.ArticleTranslation{
+				ArticleID:         article.ID,
+				Language:          item.Language,
+				Title:             item.Title,
+				Content:           content(item.Content),
+				Summary:           item.Summary,
+				SEOSlug:           item.SEOSlug,
+				MachineTranslated: item.MachineTranslated,
+			}
+			if err := CheckArticleSlug(tx, 0, translation.Language, translation.SEOSlug); err != nil {
+				if !errors.Is(err, ErrSlugInUse) {
+					return err
+				}
+				translation.SEOSlug = ""
+				result.ClearedSlugs = append(result.ClearedSlugs, translation.Language)
+			}
+			translation.StampSource(article)
+			if err := tx.Create(&translation).Error; err != nil {
+				return fmt.Errorf("failed to import the %s translation: %v", translation.Language, err)
+			}
+			if err := RecordSlugChange(tx, article, translation.Language, "", translation.SEOSlug); err != nil {
+				return err
+			}
+			result.Translations++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	result.ArticleID = article.ID
+	cache.Publish(cache.TopicArticles, cache.TopicCategories)
+
+	if err := db.Preload("Category").Preload("Translations").First(article, article.ID).Error; err != nil {
+		return nil, nil, err
+	}
+	return article, result, nil
+}
+
+// importDocumentMedia links the document's media to the site's library
+// entries with the same URL and points the rest at the exporting site. It
+// sets the article's cover and returns the replacer for its content.
+func importDocumentMedia(tx *gorm.DB, doc *ArticleDocument, article *models.Article, result *ArticleImportResult) (*strings.Replacer, error) {
+	var pairs []string
+	remote := func(url string) string {
+		if !strings.HasPrefix(url, "/") || doc.Site.URL == "" {
+			return url
+		}
+		return strings.TrimRight(doc.Site.URL, "/") + url
+	}
+	for _, media := range doc.Media {
+		if media.URL == "" {
+			continue
+		}
+		var existing models.MediaLibrary
+		if err := tx.Select("id").Where("url = ?", media.URL).Limit(1).Find(&existing).Error; err != nil {
+			return nil, err
+		}
+		if existing.ID != 0 {
+			result.Media[media.ID] = existing.ID
+			continue
+		}
+		if url := remote(media.URL); url != media.URL {
+			pairs = append(pairs, media.URL, url)
+		}
+		result.RemoteMedia = append(result.RemoteMedia, remote(media.URL))
+	}
+	rewrite := strings.NewReplacer(pairs...)
+
+	if doc.Article.CoverImageID != nil {
+		if id, ok := result.Media[*doc.Article.CoverImageID]; ok {
+			article.CoverImageID = &id
+		}
+	}
+	if doc.Article.CoverImageURL != "" {
+		url := doc.Article.CoverImageURL
+		if article.CoverImageID == nil {
+			url = remote(url)
+		}
+		article.CoverImageURL = &url
+	}
+	return rewrite, nil
+}
+
+// importDocumentCategory returns the id of the site's category named as
+// the document's, creating it with its translations when there is none
+func importDocumentCategory(tx *gorm.DB, category *ArticleDocumentCategory, siteID uint, result *ArticleImportResult) (uint, error) {
+	var existing models.Category
+	if err := tx.Where("name = ?", category.Name).Limit(1).Find(&existing).Error; err != nil {
+		return 0, err
+	}
+	if existing.ID != 0 {
+		result.CategoryID = existing.ID
+		return existing.ID, nil
+	}
+	created := models.Category{
+		SiteID:      siteID,
+		Name:        category.Name,
+		Description: category.Description,
+		DefaultLang: firstNonEmpty(category.DefaultLang, "zh"),
+	}
+	if err := tx.Omit(clause.Associations).Create(&created).Error; err != nil {
+		return 0, fmt.Errorf("failed to import category %q: %v", category.Name, err)
+	}
+	for _, item := range category.Translations {
+		if item.Language == "" || item.Name == "" {
+			continue
+		}
+		translation := models.CategoryTranslation{CategoryID: created.ID, Language: item.Language, Name: item.Name, Description: item.Description}
+		if err := tx.Create(&translation).Error; err != nil {
+			return 0, err
+		}
+	}
+	result.CategoryID, result.CategoryCreated = created.ID, true
+	return created.ID, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestArticleDocumentRoundTrip(t *testing.T) {
+	src, _ := openArchiveTestDB(t)
+	src.Create(&models.SiteSettings{SiteTitle: "Source"})
+	category := models.Category{Name: "Travel", DefaultLang: "zh"}
+	src.Create(&category)
+	src.Create(&models.CategoryTranslation{CategoryID: category.ID, Language: "en", Name: "Travel"})
+	cover := models.MediaLibrary{FileName: "cover.png", OriginalName: "cover.png", FilePath: "cover.png", FileSize: 3,
+		MimeType: "image/png", MediaType: models.MediaTypeImage, URL: "/uploads/images/cover.png"}
+	photo := models.MediaLibrary{FileName: "photo.jpg", OriginalName: "photo.jpg", FilePath: "photo.jpg", FileSize: 3,
+		MimeType: "image/jpeg", MediaType: models.MediaTypeImage, URL: "/uploads/images/photo.jpg"}
+	src.Create(&cover)
+	src.Create(&photo)
+	coverURL := cover.URL
+	article := models.Article{Title: "Trip", Content: "![photo](/uploads/images/photo.jpg)", CategoryID: category.ID,
+		DefaultLang: "zh", CoverImageID: &cover.ID, CoverImageURL: &coverURL, SEOSlug: "trip", SEOTitle: "A trip"}
+	src.Create(&article)
+	src.Create(&models.ArticleTranslation{ArticleID: article.ID, Language: "en", Title: "Trip", Content: "![photo](/uploads/images/photo.jpg)", SEOSlug: "trip-en"})
+
+	exported, err := ExportArticleDocument(src, article.ID, "https://source.example/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported.Article.URL != "https://source.example/zh/article/1" || len(exported.Media) != 2 || len(exported.Translations) != 1 ||
+		exported.Category == nil || len(exported.Category.Translations) != 1 || exported.Site.Title != "Source" {
+		t.Fatalf("exported = %+v", exported)
+	}
+
+	data, _ := json.Marshal(exported)
+	var doc ArticleDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	newTestDB(t)
+	dst := database.DB
+	// The destination has the cover already and an article with the slug
+	dst.Create(&models.Category{Name: "Other"})
+	local := models.MediaLibrary{FileName: "cover.png", OriginalName: "cover.png", FilePath: "cover.png", FileSize: 3,
+		MimeType: "image/png", MediaType: models.MediaTypeImage, URL: "/uploads/images/cover.png"}
+	dst.Create(&local)
+	dst.Create(&models.Article{Title: "Mine", SEOSlug: "trip"})
+
+	imported, result, err := ImportArticleDocument(context.Background(), dst, &doc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.ID != 2 || result.ArticleID != imported.ID || result.SourceID != article.ID {
+		t.Errorf("imported id = %d, result = %+v", imported.ID, result)
+	}
+	if !result.CategoryCreated || imported.Category.Name != "Travel" {
+		t.Errorf("category = %+v, result = %+v", imported.Category, result)
+	}
+	if imported.CoverImageID == nil || *imported.CoverImageID != local.ID || result.Media[cover.ID] != local.ID {
+		t.Errorf("cover = %v, media = %v", imported.CoverImageID, result.Media)
+	}
+	if imported.Content != "![photo](https://source.example/uploads/images/photo.jpg)" ||
+		len(result.RemoteMedia) != 1 || result.RemoteMedia[0] != "https://source.example/uploads/images/photo.jpg" {
+		t.Errorf("content = %q, remote = %v", imported.Content, result.RemoteMedia)
+	}
+	if imported.SEOSlug != "" || len(result.ClearedSlugs) != 1 || result.ClearedSlugs[0] != "" {
+		t.Errorf("slug = %q, cleared = %v", imported.SEOSlug, result.ClearedSlugs)
+	}
+	if len(imported.Translations) != 1 || imported.Translations[0].SEOSlug != "trip-en" ||
+		imported.Translations[0].Content != imported.Content {
+		t.Errorf("translations = %+v", imported.Translations)
+	}
+	if imported.SourceName != "Source" || imported.SourceURL != exported.Article.URL || imported.SEOTitle != "A trip" {
+		t.Errorf("source = %q %q, seo title = %q", imported.SourceName, imported.SourceURL, imported.SEOTitle)
+	}
+
+	if _, _, err := ImportArticleDocument(context.Background(), dst, &doc, nil); !errors.Is(err, ErrArticleAlreadyImported) {
+		t.Errorf("second import err = %v, want ErrArticleAlreadyImported", err)
+	}
+}
+
+func TestValidateArticleDocument(t *testing.T) {
+	valid := ArticleDocument{Format: ArticleDocumentFormat, Version: 1, Article: ArticleDocumentArticle{Title: "Trip"}}
+	if err := ValidateArticleDocument(&valid); err != nil {
+		t.Errorf("valid document: %v", err)
+	}
+	for name, tc := range map[string]struct {
+		mutate func(*ArticleDocument)
+		want   error
+	}{
+		"format":   {func(d *ArticleDocument) { d.Format = "kuno" }, ErrInvalidArticleDocument},
+		"version":  {func(d *ArticleDocument) { d.Version = ArticleDocumentVersion + 1 }, ErrUnsupportedArticleDocument},
+		"title":    {func(d *ArticleDocument) { d.Article.Title = " " }, ErrInvalidArticleDocument},
+		"category": {func(d *ArticleDocument) { d.Category = &ArticleDocumentCategory{} }, ErrInvalidArticleDocument},
+	} {
+		doc := valid
+		tc.mutate(&doc)
+		if err := ValidateArticleDocument(&doc); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,670 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"blog-backend/internal/pdf"
+	"blog-backend/internal/security"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	pdfMargin   = 56
+	pdfBodySize = 10.5
+	// maxPDFImages and maxPDFImageSize bound the images fetched for one
+	// document, and pdfImageTimeout how long fetching them all may take
+	maxPDFImages    = 50
+	maxPDFImageSize = 10 << 20
+	pdfImageTimeout = 30 * time.Second
+)
+
+var (
+	pdfTextColor  = pdf.Color{R: 34, G: 34, B: 34}
+	pdfMutedColor = pdf.Color{R: 110, G: 110, B: 110}
+	pdfLinkColor  = pdf.Color{R: 29, G: 78, B: 216}
+	pdfRuleColor  = pdf.Color{R: 210, G: 210, B: 210}
+	pdfShadeColor = pdf.Color{R: 243, G: 244, B: 246}
+	pdfCodeColor  = pdf.Color{R: 246, G: 248, B: 250}
+)
+
+// pdfHeadingSizes are the text sizes of headings by level
+var pdfHeadingSizes = [7]float64{0, 20, 16.5, 14, 12, 11, 11}
+
+// ArticlePDFOptions holds what an article's PDF needs from the site
+type ArticlePDFOptions struct {
+	// BaseURL is the site address relative links and images resolve against
+	BaseURL string
+	// UploadDir holds the files behind /uploads/ addresses, which are read
+	// from disk rather than fetched
+	UploadDir string
+	// SiteTitle is shown under the article title and as the author
+	SiteTitle string
+}
+
+// ArticlePDFRenderer renders articles as paginated A4 PDF documents for
+// offline reading and archiving. Code is highlighted in the configured
+// style and images are embedded; uploads are read from disk and other
+// images fetched like link previews, from public addresses only. Math is
+// shown as its TeX source and Mermaid diagrams as code.
+type ArticlePDFRenderer struct {
+	markdown    *MarkdownRenderer
+	highlighter *CodeHighlighter
+	previews    *LinkPreviewService
+}
+
+// NewArticlePDFRenderer creates a PDF renderer parsing Markdown like
+// markdown, highlighting code with highlighter and fetching remote images
+// through previews
+func NewArticlePDFRenderer(markdown *MarkdownRenderer, highlighter *CodeHighlighter, previews *LinkPreviewService) *ArticlePDFRenderer {
+	return &ArticlePDFRenderer{markdown: markdown, highlighter: highlighter, previews: previews}
+}
+
+// Render writes article, with the translation to language applied, to w as
+// a PDF document
+func (r *ArticlePDFRenderer) Render(ctx context.Context, w io.Writer, article models.Article, language string, opts ArticlePDFOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, pdfImageTimeout)
+	defer cancel()
+	doc := pdf.New(pdf.A4Width, pdf.A4Height, language)
+	doc.SetInfo(article.Title, opts.SiteTitle)
+	a := &articlePDF{
+		renderer: r,
+		ctx:      ctx,
+		opts:     opts,
+		doc:      doc,
+		layout:   pdf.NewLayout(doc, pdfMargin),
+		images:   map[string]*pdf.Image{},
+	}
+
+	a.layout.Paragraph([]pdf.Span{{Text: article.Title, Font: pdf.SansBold, Size: 22, Color: pdfTextColor}}, pdf.Block{LineHeight: 1.3, After: 6})
+	meta := []string{article.CreatedAt.Format("2006-01-02")}
+	if article.Category.Name != "" {
+		meta = append(meta, article.Category.Name)
+	}
+	if opts.SiteTitle != "" {
+		meta = append(meta, opts.SiteTitle)
+	}
+	a.layout.Paragraph([]pdf.Span{{Text: strings.Join(meta, " · "), Font: pdf.Sans, Size: 9, Color: pdfMutedColor}}, pdf.Block{After: 8})
+	if article.CoverImageURL != nil && *article.CoverImageURL != "" {
+		a.image(*article.CoverImageURL, "", &pdfContext{})
+	}
+	a.layout.Rule(pdfRuleColor, 0, 14)
+
+	if article.ContentType == "html" {
+		root, err := html.Parse(strings.NewReader(security.GetGlobalHTMLPolicy().Sanitize(article.Content)))
+		if err != nil {
+			return err
+		}
+		p := a.paragraph(&pdfContext{})
+		a.htmlNodes(root, pdfStyle{size: pdfBodySize, color: pdfTextColor}, p)
+		p.flush()
+	} else {
+		src := []byte(article.Content)
+		doc := r.markdown.markdown.Parser().Parse(text.NewReader(src))
+		a.markdownBlocks(doc, src, &pdfContext{})
+	}
+
+	a.layout.Footer(func(page, pages int) string {
+		return fmt.Sprintf("%d / %d", page, pages)
+	}, pdf.Sans, 8, pdfMutedColor)
+	return doc.Write(w)
+}
+
+// articlePDF is the state of one document being rendered
+type articlePDF struct {
+	renderer *ArticlePDFRenderer
+	ctx      context.Context
+	opts     ArticlePDFOptions
+	doc      *pdf.Document
+	layout   *pdf.Layout
+	// images are the images loaded by address, nil for those that failed
+	images map[string]*pdf.Image
+}
+
+// pdfContext is where blocks are laid out: indented in lists and quotes,
+// with the marker of a list item until its first paragraph takes it
+type pdfContext struct {
+	indent float64
+	quote  bool
+	marker string
+}
+
+// nested returns the context of the content of a list item or quote
+func (c *pdfContext) nested(indent float64, quote bool, marker string) *pdfContext {
+	return &pdfContext{indent: c.indent + indent, quote: c.quote || quote, marker: marker}
+}
+
+// block applies the context to a block style
+func (c *pdfContext) block(block pdf.Block) pdf.Block {
+	block.Indent += c.indent
+	if c.quote {
+		block.Bar = &pdfRuleColor
+	}
+	if c.marker != "" {
+		block.Marker = &pdf.Span{Text: c.marker, Font: pdf.Sans, Size: pdfBodySize, Color: pdfTextColor}
+		c.marker = ""
+	}
+	return block
+}
+
+// pdfStyle is the style of inline text
+type pdfStyle struct {
+	size               float64
+	bold, italic, code bool
+	color              pdf.Color
+	link               string
+}
+
+func (s pdfStyle) span(text string) pdf.Span {
+	font := pdf.Sans
+	switch {
+	case s.code && s.bold:
+		font = pdf.MonoBold
+	case s.code:
+		font = pdf.Mono
+	case s.bold:
+		font = pdf.SansBold
+	case s.italic:
+		font = pdf.SansItalic
+	}
+	return pdf.Span{Text: text, Font: font, Size: s.size, Color: s.color, Link: s.link}
+}
+
+// pdfParagraph collects the inline text of a paragraph. Images end it: the
+// text so far is laid out, then the image below it. In table cells, where
+// images cannot go, their text is shown instead.
+type pdfParagraph struct {
+	a      *articlePDF
+	ctx    *pdfContext
+	block  pdf.Block
+	spans  []pdf.Span
+	inline bool
+}
+
+func (a *articlePDF) paragraph(ctx *pdfContext) *pdfParagraph {
+	return &pdfParagraph{a: a, ctx: ctx, block: pdf.Block{LineHeight: 1.55, After: 8}}
+}
+
+func (p *pdfParagraph) add(style pdfStyle, text string) {
+	if text != "" {
+		p.spans = append(p.spans, style.span(text))
+	}
+}
+
+func (p *pdfParagraph) image(src, alt string) {
+	if p.inline {
+		if alt != "" {
+			p.add(pdfStyle{size: pdfBodySize, italic: true, color: pdfMutedColor}, alt)
+		}
+		return
+	}
+	p.flush()
+	p.a.image(src, alt, p.ctx)
+}
+
+// flush lays out the text collected so far
+func (p *pdfParagraph) flush() {
+	empty := true
+	for _, span := range p.spans {
+		if strings.TrimSpace(span.Text) != "" {
+			empty = false
+		}
+	}
+	if !empty {
+		p.a.layout.Paragraph(p.spans, p.ctx.block(p.block))
+	}
+	p.spans = nil
+}
+
+// markdownBlocks lays out the block children of a Markdown node
+func (a *articlePDF) markdownBlocks(parent ast.Node, src []byte, ctx *pdfContext) {
+	for node := parent.FirstChild(); node != nil; node = node.NextSibling() {
+		switch node := node.(type) {
+		case *ast.Heading:
+			a.heading(node.Level, func(p *pdfParagraph, style pdfStyle) { a.markdownInlines(node, src, style, p) }, ctx)
+		case *ast.Paragraph, *ast.TextBlock:
+			p := a.paragraph(ctx)
+			if _, tight := node.(*ast.TextBlock); tight {
+				p.block.After = 3
+			}
+			a.markdownInlines(node, src, pdfStyle{size: pdfBodySize, color: pdfTextColor}, p)
+			p.flush()
+		case *ast.List:
+			number := node.Start
+			for item := node.FirstChild(); item != nil; item = item.NextSibling() {
+				marker := "•"
+				if node.IsOrdered() {
+					marker = fmt.Sprintf("%d.", number)
+					number++
+				}
+				a.markdownBlocks(item, src, ctx.nested(18, false, marker))
+			}
+			a.layout.Space(5)
+		case *ast.Blockquote:
+			a.markdownBlocks(node, src, ctx.nested(0, true, ""))
+			a.layout.Space(4)
+		case *ast.FencedCodeBlock:
+			info := ""
+			if node.Info != nil {
+				info = string(node.Info.Segment.Value(src))
+			}
+			a.code(blockText(node, src), ParseFenceInfo(info).Language, ctx)
+		case *ast.CodeBlock:
+			a.code(blockText(node, src), "", ctx)
+		case *mathBlock:
+			a.code(strings.TrimSpace(blockText(node, src)), "tex", ctx)
+		case *ast.ThematicBreak:
+			a.layout.Rule(pdfRuleColor, 6, 14)
+		case *east.Table:
+			var rows [][][]pdf.Span
+			for row := node.FirstChild(); row != nil; row = row.NextSibling() {
+				var cells [][]pdf.Span
+				for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+					p := &pdfParagraph{a: a, ctx: ctx, inline: true}
+					_, header := row.(*east.TableHeader)
+					a.markdownInlines(cell, src, pdfStyle{size: 9.5, bold: header, color: pdfTextColor}, p)
+					cells = append(cells, p.spans)
+				}
+				rows = append(rows, cells)
+			}
+			a.layout.Table(rows, true, pdfRuleColor, pdfShadeColor, 10)
+		case *ast.HTMLBlock:
+			// Raw HTML is left out, as in the HTML rendering
+		default:
+			a.markdownBlocks(node, src, ctx)
+		}
+	}
+}
+
+// markdownInlines adds the inline children of a Markdown node to p
+func (a *articlePDF) markdownInlines(parent ast.Node, src []byte, style pdfStyle, p *pdfParagraph) {
+	for node := parent.FirstChild(); node != nil; node = node.NextSibling() {
+		s := style
+		switch node := node.(type) {
+		case *ast.Text:
+			p.add(style, string(node.Segment.Value(src)))
+			if node.HardLineBreak() {
+				p.add(style, "\n")
+			} else if node.SoftLineBreak() {
+				p.add(style, " ")
+			}
+		case *ast.String:
+			p.add(style, string(node.Value))
+		case *ast.CodeSpan:
+			s.code = true
+			a.markdownInlines(node, src, s, p)
+		case *ast.Emphasis:
+			if node.Level >= 2 {
+				s.bold = true
+			} else {
+				s.italic = true
+			}
+			a.markdownInlines(node, src, s, p)
+		case *ast.Link:
+			s.link, s.color = a.linkTarget(string(node.Destination)), pdfLinkColor
+			a.markdownInlines(node, src, s, p)
+		case *ast.AutoLink:
+			s.link, s.color = a.linkTarget(string(node.URL(src))), pdfLinkColor
+			p.add(s, string(node.Label(src)))
+		case *ast.Image:
+			alt := &pdfParagraph{a: a, ctx: p.ctx, inline: true}
+			a.markdownInlines(node, src, style, alt)
+			var altText strings.Builder
+			for _, span := range alt.spans {
+				altText.WriteString(span.Text)
+			}
+			p.image(string(node.Destination), altText.String())
+		case *mathInline:
+			s.code = true
+			p.add(s, node.tex)
+		case *east.TaskCheckBox:
+			if node.IsChecked {
+				p.add(style, "[x] ")
+			} else {
+				p.add(style, "[ ] ")
+			}
+		case *ast.RawHTML:
+		default:
+			a.markdownInlines(node, src, style, p)
+		}
+	}
+}
+
+// htmlNodes lays out the children of an HTML node, adding inline content
+// to p
+func (a *articlePDF) htmlNodes(parent *html.Node, style pdfStyle, p *pdfParagraph) {
+	for node := parent.FirstChild; node != nil; node = node.NextSibling {
+		if node.Type == html.TextNode {
+			p.add(style, strings.ReplaceAll(node.Data, "\n", " "))
+			continue
+		}
+		if node.Type != html.ElementNode && node.Type != html.DocumentNode {
+			continue
+		}
+		s := style
+		switch node.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+			p.flush()
+			level := int(node.Data[1] - '0')
+			a.heading(level, func(p *pdfParagraph, style pdfStyle) { a.htmlNodes(node, style, p) }, p.ctx)
+		case atom.P, atom.Div, atom.Section, atom.Article, atom.Figure, atom.Figcaption, atom.Header, atom.Footer,
+			atom.Main, atom.Aside, atom.Details, atom.Summary, atom.Dl, atom.Dt, atom.Dd, atom.Li:
+			p.flush()
+			inner := a.paragraph(p.ctx)
+			a.htmlNodes(node, style, inner)
+			inner.flush()
+		case atom.Ul, atom.Ol:
+			p.flush()
+			number := 1
+			for item := node.FirstChild; item != nil; item = item.NextSibling {
+				if item.DataAtom != atom.Li {
+					continue
+				}
+				marker := "•"
+				if node.DataAtom == atom.Ol {
+					marker = fmt.Sprintf("%d.", number)
+					number++
+				}
+				inner := a.paragraph(p.ctx.nested(18, false, marker))
+				inner.block.After = 3
+				a.htmlNodes(item, style, inner)
+				inner.flush()
+			}
+			a.layout.Space(5)
+		case atom.Blockquote:
+			p.flush()
+			inner := a.paragraph(p.ctx.nested(0, true, ""))
+			a.htmlNodes(node, style, inner)
+			inner.flush()
+			a.layout.Space(4)
+		case atom.Pre:
+			p.flush()
+			language := ""
+			for _, code := range []*html.Node{node, node.FirstChild} {
+				if code == nil {
+					continue
+				}
+				for _, class := range strings.Fields(htmlAttr(code, "class")) {
+					if lang, ok := strings.CutPrefix(class, "language-"); ok {
+						language = lang
+					}
+				}
+			}
+			a.code(htmlText(node), language, p.ctx)
+		case atom.Hr:
+			p.flush()
+			a.layout.Rule(pdfRuleColor, 6, 14)
+		case atom.Img:
+			p.image(htmlAttr(node, "src"), htmlAttr(node, "alt"))
+		case atom.Br:
+			p.add(style, "\n")
+		case atom.Table:
+			p.flush()
+			var rows [][][]pdf.Span
+			header := false
+			var walk func(*html.Node)
+			walk = func(n *html.Node) {
+				for child := n.FirstChild; child != nil; child = child.NextSibling {
+					if child.DataAtom != atom.Tr {
+						walk(child)
+						continue
+					}
+					var cells [][]pdf.Span
+					for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+						if cell.DataAtom != atom.Td && cell.DataAtom != atom.Th {
+							continue
+						}
+						header = header || (len(rows) == 0 && cell.DataAtom == atom.Th)
+						inner := &pdfParagraph{a: a, ctx: p.ctx, inline: true}
+						a.htmlNodes(cell, pdfStyle{size: 9.5, bold: cell.DataAtom == atom.Th, color: pdfTextColor}, inner)
+						cells = append(cells, inner.spans)
+					}
+					rows = append(rows, cells)
+				}
+			}
+			walk(node)
+			a.layout.Table(rows, header, pdfRuleColor, pdfShadeColor, 10)
+		case atom.Strong, atom.B:
+			s.bold = true
+			a.htmlNodes(node, s, p)
+		case atom.Em, atom.I:
+			s.italic = true
+			a.htmlNodes(node, s, p)
+		case atom.Code, atom.Kbd, atom.Samp:
+			s.code = true
+			a.htmlNodes(node, s, p)
+		case atom.A:
+			if href := htmlAttr(node, "href"); href != "" {
+				s.link, s.color = a.linkTarget(href), pdfLinkColor
+			}
+			a.htmlNodes(node, s, p)
+		case atom.Script, atom.Style, atom.Iframe, atom.Video, atom.Audio:
+		default:
+			a.htmlNodes(node, style, p)
+		}
+	}
+}
+
+func htmlAttr(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// htmlText returns the text within a node
+func htmlText(node *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return b.String()
+}
+
+// heading lays out a heading, its text added to a paragraph by inlines
+func (a *articlePDF) heading(level int, inlines func(*pdfParagraph, pdfStyle), ctx *pdfContext) {
+	level = min(max(level, 1), 6)
+	p := a.paragraph(ctx)
+	p.block = pdf.Block{LineHeight: 1.3, Before: 10, After: 6, Keep: 40}
+	inlines(p, pdfStyle{size: pdfHeadingSizes[level], bold: true, color: pdfTextColor})
+	p.flush()
+}
+
+// code lays out a block of code highlighted in the configured style, on
+// the style's background
+func (a *articlePDF) code(code, language string, ctx *pdfContext) {
+	code = strings.TrimRight(code, "\n")
+	if code == "" {
+		return
+	}
+	style := a.renderer.highlighter.style
+	background := style.Get(chroma.Background)
+	fill, textColor := pdfCodeColor, pdfTextColor
+	if background.Background.IsSet() {
+		fill = chromaColor(background.Background)
+	}
+	if background.Colour.IsSet() {
+		textColor = chromaColor(background.Colour)
+	}
+
+	var spans []pdf.Span
+	iterator, err := detectLexer(code, language).Tokenise(nil, code)
+	if err != nil {
+		spans = []pdf.Span{{Text: code, Font: pdf.Mono, Size: 9, Color: textColor}}
+	} else {
+		for token := iterator(); token != chroma.EOF; token = iterator() {
+			entry := style.Get(token.Type)
+			span := pdf.Span{Text: token.Value, Font: pdf.Mono, Size: 9, Color: textColor}
+			if entry.Colour.IsSet() {
+				span.Color = chromaColor(entry.Colour)
+			}
+			if entry.Bold == chroma.Yes {
+				span.Font = pdf.MonoBold
+			}
+			spans = append(spans, span)
+		}
+	}
+	block := ctx.block(pdf.Block{LineHeight: 1.35, Before: 2, After: 10, Padding: 8, Background: &fill, Pre: true})
+	block.Marker = nil
+	a.layout.Paragraph(spans, block)
+}
+
+func chromaColor(c chroma.Colour) pdf.Color {
+	return pdf.Color{R: c.Red(), G: c.Green(), B: c.Blue()}
+}
+
+// image lays out an image, or its alternative text when it cannot be
+// loaded
+func (a *articlePDF) image(src, alt string, ctx *pdfContext) {
+	img, err := a.loadImage(src)
+	if err != nil {
+		slog.Debug("Failed to load image for PDF", "src", src, "error", err)
+		if alt != "" {
+			a.layout.Paragraph([]pdf.Span{{Text: "[" + alt + "]", Font: pdf.SansItalic, Size: pdfBodySize, Color: pdfMutedColor}}, ctx.block(pdf.Block{After: 8}))
+		}
+		return
+	}
+	a.layout.Space(2)
+	a.layout.Image(img, a.layout.Width()-ctx.indent, 10)
+}
+
+// loadImage adds the image at src to the document, once per address
+func (a *articlePDF) loadImage(src string) (*pdf.Image, error) {
+	if img, ok := a.images[src]; ok {
+		if img == nil {
+			return nil, errors.New("image failed to load before")
+		}
+		return img, nil
+	}
+	a.images[src] = nil
+	if len(a.images) > maxPDFImages {
+		return nil, fmt.Errorf("more than %d images", maxPDFImages)
+	}
+	data, err := a.renderer.imageData(a.ctx, src, a.opts)
+	if err != nil {
+		return nil, err
+	}
+	img, err := a.doc.AddImage(data)
+	if err != nil {
+		return nil, err
+	}
+	a.images[src] = img
+	return img, nil
+}
+
+// imageData reads an image: uploads from disk, data URLs as they are and
+// anything else from the web
+func (r *ArticlePDFRenderer) imageData(ctx context.Context, src string, opts ArticlePDFOptions) ([]byte, error) {
+	address := strings.TrimSpace(src)
+	if base := strings.TrimRight(opts.BaseURL, "/"); base != "" && strings.HasPrefix(address, base+"/") {
+		address = strings.TrimPrefix(address, base)
+	}
+	for _, prefix := range []string{"/api/uploads/", "/uploads/"} {
+		if rel, ok := strings.CutPrefix(address, prefix); ok && opts.UploadDir != "" {
+			file, err := os.Open(filepath.Join(opts.UploadDir, filepath.FromSlash(path.Clean("/"+rel))))
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			return readImage(file)
+		}
+	}
+	if data, ok := strings.CutPrefix(address, "data:"); ok {
+		_, encoded, found := strings.Cut(data, ";base64,")
+		if !found || len(encoded) > maxPDFImageSize*4/3 {
+			return nil, errors.New("unsupported data URL")
+		}
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+
+	target, err := ParsePreviewURL(absoluteURL(opts.BaseURL, address))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.previews.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "kuno-pdf")
+	req.Header.Set("Accept", "image/*")
+	resp, err := r.previews.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %s", req.URL.Host, resp.Status)
+	}
+	return readImage(resp.Body)
+}
+
+func readImage(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxPDFImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPDFImageSize {
+		return nil, errors.New("image too large")
+	}
+	return data, nil
+}
+
+// linkTarget resolves a link against the site; links within the page have
+// no target in a PDF
+func (a *articlePDF) linkTarget(href string) string {
+	href = strings.TrimSpace(href)
+	switch {
+	case href == "" || strings.HasPrefix(href, "#"):
+		return ""
+	case strings.HasPrefix(href, "mailto:"):
+		return href
+	case strings.Contains(href, "://"):
+		if strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") {
+			return href
+		}
+		return ""
+	}
+	return absoluteURL(a.opts.BaseURL, href)
+}
+
+var (
+	globalArticlePDFRenderer     *ArticlePDFRenderer
+	globalArticlePDFRendererOnce sync.Once
+)
+
+// GetGlobalArticlePDFRenderer returns the global article PDF renderer
+func GetGlobalArticlePDFRenderer() *ArticlePDFRenderer {
+	globalArticlePDFRendererOnce.Do(func() {
+		globalArticlePDFRenderer = NewArticlePDFRenderer(GetGlobalMarkdownRenderer(), GetGlobalCodeHighlighter(), GetGlobalLinkPreviewService())
+	})
+	return globalArticlePDFRenderer
+}
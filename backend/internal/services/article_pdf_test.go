@@ -0,0 +1,58 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestArticlePDF(t *testing.T) {
+	uploads := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(uploads, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var picture bytes.Buffer
+	if err := png.Encode(&picture, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(uploads, "images", "a.png"), picture.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := NewArticlePDFRenderer(NewMarkdownRenderer(NewCodeHighlighter(), &DiagramRenderer{}), NewCodeHighlighter(), NewLinkPreviewService())
+	opts := ArticlePDFOptions{BaseURL: "https://blog.example.com", UploadDir: uploads, SiteTitle: "Blog"}
+	// Uploads are read from disk whether addressed by path or full URL;
+	// paths cannot leave the upload directory
+	images := regexp.MustCompile(`/Subtype /Image`)
+
+	for _, test := range []struct {
+		name    string
+		article models.Article
+		images  int
+	}{
+		{"markdown", models.Article{Title: "标题", Content: "# Heading\n\nSome **bold** text with [a link](/about).\n\n" +
+			"![local](/api/uploads/images/a.png) ![again](https://blog.example.com/uploads/images/a.png) ![escape](/uploads/../../etc/passwd)\n\n" +
+			"- one\n- two\n  1. nested\n\n> 引用\n\n```go\nfunc main() {}\n```\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n$$\nx^2\n$$\n"}, 2},
+		{"html", models.Article{Title: "HTML", ContentType: "html", Content: `<h2>Heading</h2><p>Text <a href="https://example.com">link</a><img src="/uploads/images/a.png"></p>` +
+			`<ul><li>one</li></ul><pre><code class="language-go">package main</code></pre><table><tr><th>a</th></tr><tr><td>1</td></tr></table>`}, 1},
+	} {
+		var out bytes.Buffer
+		if err := r.Render(context.Background(), &out, test.article, "zh", opts); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !bytes.HasPrefix(out.Bytes(), []byte("%PDF-")) {
+			t.Fatalf("%s: not a PDF file", test.name)
+		}
+		if got := len(images.FindAll(out.Bytes(), -1)); got != test.images {
+			t.Errorf("%s: %d images, want %d", test.name, got, test.images)
+		}
+		if !bytes.Contains(out.Bytes(), []byte("/URI (")) {
+			t.Errorf("%s: the link is lost", test.name)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// What an admin is doing with an open article
+const (
+	PresenceViewing = "viewing"
+	PresenceEditing = "editing"
+)
+
+const (
+	// ArticlePresenceHeartbeat is how often an open article reports that it
+	// is still open
+	ArticlePresenceHeartbeat = 15 * time.Second
+	// articlePresenceTTL is how long a presence lasts without a heartbeat,
+	// long enough to ride out one or two missed ones
+	articlePresenceTTL = 3 * ArticlePresenceHeartbeat
+)
+
+var ErrInvalidPresence = errors.New("invalid presence")
+
+// presenceSessionID matches the IDs clients pick for each open tab
+var presenceSessionID = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+
+// ArticlePresence is one admin tab open on an article
+type ArticlePresence struct {
+	SessionID string    `json:"session_id"`
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	Mode      string    `json:"mode"`
+	Since     time.Time `json:"since"`
+	SeenAt    time.Time `json:"seen_at"`
+}
+
+// ArticlePresenceState is who else has an article open
+type ArticlePresenceState struct {
+	Others []ArticlePresence `json:"others"`
+	// EditingByOthers is set while another tab edits the article, so the
+	// editor can warn before changes are saved over each other
+	EditingByOthers  bool `json:"editing_by_others"`
+	HeartbeatSeconds int  `json:"heartbeat_seconds"`
+}
+
+// ArticlePresenceService tracks which admins have an article open in the
+// editor. Presence lives in the shared cache, so every instance sees it,
+// and lapses unless the tab keeps sending heartbeats.
+type ArticlePresenceService struct {
+	db       func() *gorm.DB
+	presence *cache.Namespace
+	now      func() time.Time
+}
+
+// NewArticlePresenceService creates an article presence service
+func NewArticlePresenceService() *ArticlePresenceService {
+	return &ArticlePresenceService{
+		db:       func() *gorm.DB { return database.DB },
+		presence: cache.New("article_presence", articlePresenceTTL),
+		now:      time.Now,
+	}
+}
+
+// presenceKey groups the tabs of an article, each keyed by its user so a
+// tab cannot replace another user's presence
+func presenceKey(siteID, articleID uint) string {
+	return fmt.Sprintf("%d:%d:", siteID, articleID)
+}
+
+// Heartbeat records that a tab has the article open and returns who else
+// does
+func (s *ArticlePresenceService) Heartbeat(ctx context.Context, siteID, articleID uint, presence ArticlePresence) (*ArticlePresenceState, error) {
+	if !presenceSessionID.MatchString(presence.SessionID) {
+		return nil, fmt.Errorf("%w: session_id must be 8 to 64 letters, digits, - or _", ErrInvalidPresence)
+	}
+	if presence.Mode != PresenceViewing && presence.Mode != PresenceEditing {
+		return nil, fmt.Errorf("%w: mode must be %s or %s", ErrInvalidPresence, PresenceViewing, PresenceEditing)
+	}
+	var count int64
+	if err := s.db().WithContext(ctx).Model(&models.Article{}).Where("id = ?", articleID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrArticleNotFound
+	}
+
+	key := presenceKey(siteID, articleID) + fmt.Sprintf("%d:%s", presence.UserID, presence.SessionID)
+	now := s.now()
+	var previous ArticlePresence
+	if s.presence.Get(key, &previous) {
+		presence.Since = previous.Since
+	} else {
+		presence.Since = now
+	}
+	presence.SeenAt = now
+	s.presence.Set(key, presence)
+	return s.State(siteID, articleID, presence.SessionID), nil
+}
+
+// State returns who has the article open, leaving out the tab sessionID
+func (s *ArticlePresenceService) State(siteID, articleID uint, sessionID string) *ArticlePresenceState {
+	state := &ArticlePresenceState{Others: []ArticlePresence{}, HeartbeatSeconds: int(ArticlePresenceHeartbeat / time.Second)}
+	prefix := presenceKey(siteID, articleID)
+	for _, key := range s.presence.Keys() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		var presence ArticlePresence
+		if !s.presence.Get(key, &presence) || presence.SessionID == sessionID {
+			continue
+		}
+		state.Others = append(state.Others, presence)
+		if presence.Mode == PresenceEditing {
+			state.EditingByOthers = true
+		}
+	}
+	// Editors first, then by who arrived first
+	sort.Slice(state.Others, func(i, j int) bool {
+		a, b := state.Others[i], state.Others[j]
+		if (a.Mode == PresenceEditing) != (b.Mode == PresenceEditing) {
+			return a.Mode == PresenceEditing
+		}
+		if !a.Since.Equal(b.Since) {
+			return a.Since.Before(b.Since)
+		}
+		return a.SessionID < b.SessionID
+	})
+	return state
+}
+
+// Leave removes a user's tab from the article as it is closed
+func (s *ArticlePresenceService) Leave(siteID, articleID, userID uint, sessionID string) {
+	s.presence.Delete(presenceKey(siteID, articleID) + fmt.Sprintf("%d:%s", userID, sessionID))
+}
+
+var (
+	globalArticlePresenceService     *ArticlePresenceService
+	globalArticlePresenceServiceOnce sync.Once
+)
+
+// GetGlobalArticlePresenceService returns the global article presence
+// service
+func GetGlobalArticlePresenceService() *ArticlePresenceService {
+	globalArticlePresenceServiceOnce.Do(func() {
+		globalArticlePresenceService = NewArticlePresenceService()
+	})
+	return globalArticlePresenceService
+}
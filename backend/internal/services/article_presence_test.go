@@ -0,0 +1,88 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestArticlePresence(t *testing.T) {
+	newTestDB(t)
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	s := &ArticlePresenceService{
+		db:       func() *gorm.DB { return database.DB },
+		presence: cache.New("test_article_presence", time.Minute),
+		now:      func() time.Time { return now },
+	}
+	s.presence.Clear()
+	ctx := context.Background()
+
+	article := models.Article{Title: "Draft", DefaultLang: "en"}
+	if err := database.DB.Create(&article).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Heartbeat(ctx, 1, article.ID, ArticlePresence{SessionID: "short", Mode: PresenceEditing}); !errors.Is(err, ErrInvalidPresence) {
+		t.Errorf("short session ID: %v", err)
+	}
+	if _, err := s.Heartbeat(ctx, 1, article.ID, ArticlePresence{SessionID: "tab-alice-1", Mode: "typing"}); !errors.Is(err, ErrInvalidPresence) {
+		t.Errorf("unknown mode: %v", err)
+	}
+	if _, err := s.Heartbeat(ctx, 1, article.ID+100, ArticlePresence{SessionID: "tab-alice-1", Mode: PresenceEditing}); !errors.Is(err, ErrArticleNotFound) {
+		t.Errorf("missing article: %v", err)
+	}
+
+	alice := ArticlePresence{SessionID: "tab-alice-1", UserID: 1, Username: "alice", Mode: PresenceViewing}
+	state, err := s.Heartbeat(ctx, 1, article.ID, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Others) != 0 || state.EditingByOthers || state.HeartbeatSeconds != 15 {
+		t.Errorf("alone: %+v", state)
+	}
+
+	now = now.Add(10 * time.Second)
+	bob := ArticlePresence{SessionID: "tab-bob-1", UserID: 2, Username: "bob", Mode: PresenceEditing}
+	state, err = s.Heartbeat(ctx, 1, article.ID, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Others) != 1 || state.Others[0].Username != "alice" || state.EditingByOthers {
+		t.Errorf("bob sees: %+v", state)
+	}
+
+	// Alice's next heartbeat keeps when she arrived and sees Bob editing
+	now = now.Add(5 * time.Second)
+	state, err = s.Heartbeat(ctx, 1, article.ID, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Others) != 1 || state.Others[0].Username != "bob" || !state.EditingByOthers {
+		t.Errorf("alice sees: %+v", state)
+	}
+	all := s.State(1, article.ID, "")
+	if len(all.Others) != 2 || all.Others[0].Username != "bob" || !all.Others[1].Since.Equal(now.Add(-15*time.Second)) {
+		t.Errorf("everyone: %+v", all)
+	}
+
+	// Other sites and articles are apart
+	if other := s.State(2, article.ID, ""); len(other.Others) != 0 {
+		t.Errorf("other site: %+v", other)
+	}
+
+	// Only Bob can remove his own tab
+	s.Leave(1, article.ID, 1, bob.SessionID)
+	if state := s.State(1, article.ID, alice.SessionID); len(state.Others) != 1 {
+		t.Errorf("after alice removed bob's tab: %+v", state)
+	}
+	s.Leave(1, article.ID, 2, bob.SessionID)
+	if state := s.State(1, article.ID, alice.SessionID); len(state.Others) != 0 || state.EditingByOthers {
+		t.Errorf("after bob left: %+v", state)
+	}
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArticlePreviewTokens(t *testing.T) {
+	newTestDB(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := NewArticlePreviewService()
+	s.now = func() time.Time { return now }
+
+	token, err := s.Token(1, 42, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(1, 42, token); err != nil {
+		t.Fatalf("expected the token to work, got %v", err)
+	}
+
+	// Another instance signs with the same stored key
+	other := NewArticlePreviewService()
+	other.now = s.now
+	if err := other.Verify(1, 42, token); err != nil {
+		t.Fatalf("expected the key to be shared, got %v", err)
+	}
+
+	expired, err := s.Token(1, 42, now.Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(1, 42, expired); !errors.Is(err, ErrPreviewTokenExpired) {
+		t.Fatalf("expected an expired token, got %v", err)
+	}
+
+	expiry, signature, _ := strings.Cut(token, ".")
+	changed := "A"
+	if strings.HasSuffix(signature, changed) {
+		changed = "B"
+	}
+	for name, tampered := range map[string]string{
+		"other article":   token,
+		"extended expiry": strconv.FormatInt(now.Add(48*time.Hour).Unix(), 10) + "." + signature,
+		"changed mac":     expiry + "." + signature[:len(signature)-1] + changed,
+		"no expiry":       signature,
+		"empty":           "",
+	} {
+		articleID := uint(42)
+		if name == "other article" {
+			articleID = 43
+		}
+		if err := s.Verify(1, articleID, tampered); !errors.Is(err, ErrInvalidPreviewToken) {
+			t.Errorf("%s: expected an invalid token, got %v", name, err)
+		}
+	}
+	if err := s.Verify(2, 42, token); !errors.Is(err, ErrInvalidPreviewToken) {
+		t.Errorf("expected the token not to work on another site, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// ErrSlugInUse is returned when another article of the site answers to a slug
+var ErrSlugInUse = errors.New("SEO slug already in use")
+
+// ArticleSlug returns the slug an article is served at in a language: the
+// translation's slug, else the article's own, else its ID
+func ArticleSlug(article *models.Article, language string) string {
+	for _, translation := range article.Translations {
+		if translation.Language == language && translation.SEOSlug != "" {
+			return translation.SEOSlug
+		}
+	}
+	if article.SEOSlug != "" {
+		return article.SEOSlug
+	}
+	return strconv.FormatUint(uint64(article.ID), 10)
+}
+
+// CheckArticleSlug returns ErrSlugInUse when giving an article slug would
+// make another article of the site unreachable. An empty language is the
+// article's own slug, which is looked up whatever the language requested,
+// so it must differ from every slug of other articles; a translated slug
+// only from their own slugs and their slugs in the same language. An
+// article may reuse a slug across its languages.
+func CheckArticleSlug(db *gorm.DB, articleID uint, language, slug string) error {
+	if slug == "" {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&models.Article{}).Where("seo_slug = ? AND id != ?", slug, articleID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSlugInUse
+	}
+	owner, err := translatedSlugOwner(db, slug, language, articleID)
+	if err != nil {
+		return err
+	}
+	if owner != 0 {
+		return ErrSlugInUse
+	}
+	return nil
+}
+
+// ResolveArticleSlug returns the article a slug belongs to, preferring a
+// translated slug of the requested language, then the articles' own slugs,
+// then translated slugs of other languages. moved is true for a slug the
+// article no longer uses, which should be redirected to its current one. A
+// zero ID means no article has the slug.
+func ResolveArticleSlug(db *gorm.DB, slug, language string) (articleID uint, moved bool, err error) {
+	if language != "" {
+		if articleID, err = translatedSlugOwner(db, slug, language, 0); err != nil || articleID != 0 {
+			return articleID, false, err
+		}
+	}
+	var article models.Article
+	if err := db.Select("id").Where("seo_slug = ?", slug).Limit(1).Find(&article).Error; err != nil {
+		return 0, false, err
+	}
+	if article.ID != 0 {
+		return article.ID, false, nil
+	}
+	if articleID, err = translatedSlugOwner(db, slug, "", 0); err != nil || articleID != 0 {
+		return articleID, false, err
+	}
+
+	var redirect models.ArticleSlugRedirect
+	if language != "" {
+		if err := db.Where("slug = ? AND language = ?", slug, language).Order("id DESC").Limit(1).Find(&redirect).Error; err != nil {
+			return 0, false, err
+		}
+	}
+	if redirect.ID == 0 {
+		if err := db.Where("slug = ?", slug).Order("id DESC").Limit(1).Find(&redirect).Error; err != nil {
+			return 0, false, err
+		}
+	}
+	return redirect.ArticleID, redirect.ID != 0, nil
+}
+
+// RecordSlugChange keeps links to a slug an article stops using working by
+// redirecting them to its new slug. The new slug no longer redirects to any
+// article it used to belong to.
+func RecordSlugChange(db *gorm.DB, article *models.Article, language, oldSlug, newSlug string) error {
+	if oldSlug == newSlug {
+		return nil
+	}
+	if newSlug != "" {
+		if err := db.Where("slug = ?", newSlug).Delete(&models.ArticleSlugRedirect{}).Error; err != nil {
+			return err
+		}
+	}
+	if oldSlug == "" {
+		return nil
+	}
+	if err := db.Where("slug = ? AND language = ?", oldSlug, language).Delete(&models.ArticleSlugRedirect{}).Error; err != nil {
+		return err
+	}
+	return db.Create(&models.ArticleSlugRedirect{
+		SiteID:    article.SiteID,
+		ArticleID: article.ID,
+		Language:  language,
+		Slug:      oldSlug,
+	}).Error
+}
+
+// translatedSlugOwner returns the first article of the site other than
+// exclude with a translation at slug, in language unless it is empty.
+// Translations have no site of their own, so the articles they belong to
+// are looked up through db, which is scoped to the site.
+func translatedSlugOwner(db *gorm.DB, slug, language string, exclude uint) (uint, error) {
+	query := db.Model(&models.ArticleTranslation{}).Where("seo_slug = ? AND article_id != ?", slug, exclude)
+	if language != "" {
+		query = query.Where("language = ?", language)
+	}
+	var articleIDs []uint
+	if err := query.Distinct().Pluck("article_id", &articleIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(articleIDs) == 0 {
+		return 0, nil
+	}
+	var article models.Article
+	if err := db.Select("id").Where("id IN ?", articleIDs).Order("id").Limit(1).Find(&article).Error; err != nil {
+		return 0, err
+	}
+	return article.ID, nil
+}
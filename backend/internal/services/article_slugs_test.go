@@ -0,0 +1,84 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"errors"
+	"testing"
+)
+
+func TestArticleSlugs(t *testing.T) {
+	newTestDB(t)
+	db := database.DB
+
+	first := models.Article{Title: "Hello", SEOSlug: "hello", DefaultLang: "zh",
+		Translations: []models.ArticleTranslation{{Language: "en", Title: "Hello", SEOSlug: "hello-en"}}}
+	second := models.Article{Title: "Other", SEOSlug: "other", DefaultLang: "zh",
+		Translations: []models.ArticleTranslation{{Language: "ja", Title: "Other", SEOSlug: "hello-en"}}}
+	for _, article := range []*models.Article{&first, &second} {
+		if err := db.Create(article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		articleID uint
+		language  string
+		slug      string
+		want      error
+	}{
+		{second.ID, "", "hello", ErrSlugInUse},
+		{second.ID, "", "hello-en", ErrSlugInUse},
+		{second.ID, "en", "hello-en", ErrSlugInUse},
+		{second.ID, "en", "hello", ErrSlugInUse},
+		{second.ID, "fr", "hello-en", nil},
+		{first.ID, "fr", "hello", nil},
+		{0, "", "new", nil},
+	} {
+		if err := CheckArticleSlug(db, tc.articleID, tc.language, tc.slug); !errors.Is(err, tc.want) {
+			t.Errorf("CheckArticleSlug(%d, %q, %q) = %v, want %v", tc.articleID, tc.language, tc.slug, err, tc.want)
+		}
+	}
+
+	resolve := func(slug, language string, wantID uint, wantMoved bool) {
+		t.Helper()
+		id, moved, err := ResolveArticleSlug(db, slug, language)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != wantID || moved != wantMoved {
+			t.Errorf("ResolveArticleSlug(%q, %q) = %d, %v, want %d, %v", slug, language, id, moved, wantID, wantMoved)
+		}
+	}
+	resolve("hello", "", first.ID, false)
+	resolve("hello-en", "ja", second.ID, false)
+	resolve("hello-en", "en", first.ID, false)
+	resolve("missing", "en", 0, false)
+
+	if err := db.Model(&first).Update("seo_slug", "hello-world").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordSlugChange(db, &first, "", "hello", "hello-world"); err != nil {
+		t.Fatal(err)
+	}
+	resolve("hello", "en", first.ID, true)
+	resolve("hello-world", "", first.ID, false)
+
+	// Giving the old slug to another article ends its redirect
+	if err := RecordSlugChange(db, &second, "", "other", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	var redirects []models.ArticleSlugRedirect
+	db.Order("slug").Find(&redirects)
+	if len(redirects) != 1 || redirects[0].Slug != "other" || redirects[0].ArticleID != second.ID {
+		t.Errorf("redirects = %+v, want only other -> %d", redirects, second.ID)
+	}
+
+	db.Preload("Translations").First(&first, first.ID)
+	if slug := ArticleSlug(&first, "en"); slug != "hello-en" {
+		t.Errorf("ArticleSlug(en) = %q, want hello-en", slug)
+	}
+	if slug := ArticleSlug(&first, "fr"); slug != "hello-world" {
+		t.Errorf("ArticleSlug(fr) = %q, want hello-world", slug)
+	}
+}
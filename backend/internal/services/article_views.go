@@ -0,0 +1,300 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// viewFlushInterval bounds how long tracked views wait in the queue before
+// they are counted
+const viewFlushInterval = 5 * time.Second
+
+// ArticleViewService decides which article views are counted and keeps
+// Article.ViewCount in step with the recorded views. A visitor, known by
+// the fingerprint of their address and user agent, counts once per article
+// or, with the ViewDedupHours setting, again once that many hours have
+// passed since their last counted view.
+//
+// Tracked views are queued and counted by one writer, which stores a batch
+// of views and the increments of their articles' counts in one transaction,
+// so concurrent requests of a visitor count once. Instances do not see each
+// other's queues; the scheduled recount corrects what they miss.
+type ArticleViewService struct {
+	db  func() *gorm.DB
+	now func() time.Time
+
+	mu            sync.Mutex // serializes deciding which views count
+	batchSize     int
+	flushInterval time.Duration
+	queue         chan trackedView
+	stopChan      chan struct{}
+	doneChan      chan struct{}
+	stopOnce      sync.Once
+}
+
+// trackedView is a view waiting to be counted, with the context carrying
+// its site
+type trackedView struct {
+	ctx   context.Context
+	view  models.ArticleView
+	admin bool
+}
+
+// ArticleViewCounts are the views of an article: Views counted under the
+// site's settings and the number of distinct visitors behind them
+type ArticleViewCounts struct {
+	ArticleID      uint  `json:"article_id"`
+	Views          int64 `json:"views"`
+	UniqueVisitors int64 `json:"unique_visitors"`
+}
+
+// NewArticleViewService creates an article view service and starts its
+// writer
+func NewArticleViewService() *ArticleViewService {
+	s := &ArticleViewService{
+		db:            func() *gorm.DB { return database.DB },
+		now:           time.Now,
+		batchSize:     100,
+		flushInterval: viewFlushInterval,
+		queue:         make(chan trackedView, 1000),
+		stopChan:      make(chan struct{}),
+		doneChan:      make(chan struct{}),
+	}
+	go s.processQueue()
+	return s
+}
+
+// Counts reports whether a view of an article by the visitor with the given
+// fingerprint is counted. Views by admins count only when the site settings
+// say so.
+func (s *ArticleViewService) Counts(ctx context.Context, articleID uint, fingerprint string, admin bool) (bool, error) {
+	db := s.db().WithContext(ctx)
+	var settings models.SiteSettings
+	if err := db.Select("view_dedup_hours", "count_admin_views").Limit(1).Find(&settings).Error; err != nil {
+		return false, err
+	}
+	if admin && !settings.CountAdminViews {
+		return false, nil
+	}
+	query := db.Model(&models.ArticleView{}).Where("article_id = ? AND fingerprint = ?", articleID, fingerprint)
+	if settings.ViewDedupHours > 0 {
+		query = query.Where("created_at > ?", s.now().Add(-time.Duration(settings.ViewDedupHours)*time.Hour))
+	}
+	var seen int64
+	if err := query.Count(&seen).Error; err != nil {
+		return false, err
+	}
+	return seen == 0, nil
+}
+
+// Track queues a view by a visitor to be counted if the site settings say
+// so. When the queue is full or stopped, the view is counted right away.
+func (s *ArticleViewService) Track(ctx context.Context, view models.ArticleView, admin bool) error {
+	tracked := trackedView{ctx: ctx, view: view, admin: admin}
+	select {
+	case <-s.stopChan:
+		return s.count([]trackedView{tracked})
+	default:
+	}
+	select {
+	case s.queue <- tracked:
+		return nil
+	default:
+		return s.count([]trackedView{tracked})
+	}
+}
+
+// Record stores a counted view and adds it to the article's view count
+func (s *ArticleViewService) Record(ctx context.Context, view *models.ArticleView) error {
+	return s.store(ctx, []*models.ArticleView{view})
+}
+
+// Get returns the view counts of an article of the site. Scheduled articles
+// are not found unless includeScheduled.
+func (s *ArticleViewService) Get(ctx context.Context, articleID uint, includeScheduled bool) (*ArticleViewCounts, error) {
+	var article models.Article
+	if err := s.db().WithContext(ctx).Select("id", "view_count", "created_at").First(&article, articleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrArticleNotFound
+		}
+		return nil, err
+	}
+	if !includeScheduled && article.CreatedAt.After(s.now()) {
+		return nil, ErrArticleNotFound
+	}
+	counts := &ArticleViewCounts{ArticleID: article.ID, Views: int64(article.ViewCount)}
+	err := s.db().WithContext(ctx).Model(&models.ArticleView{}).Where("article_id = ?", article.ID).
+		Distinct("fingerprint").Count(&counts.UniqueVisitors).Error
+	return counts, err
+}
+
+// Recount rebuilds the view count of every article of the site from the
+// recorded views, for after the counting settings changed or views were
+// pruned. It returns the number of articles whose count changed.
+func (s *ArticleViewService) Recount(ctx context.Context) (int64, error) {
+	views := s.db().Model(&models.ArticleView{}).Select("COUNT(*)").Where("article_views.article_id = articles.id")
+	result := s.db().WithContext(ctx).Model(&models.Article{}).
+		Where("view_count <> (?)", views).
+		UpdateColumn("view_count", views)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		cache.Publish(cache.TopicArticles)
+	}
+	return result.RowsAffected, nil
+}
+
+// Stop stops the writer once the queued views are counted
+func (s *ArticleViewService) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+	})
+	<-s.doneChan
+}
+
+// processQueue counts queued views in batches
+func (s *ArticleViewService) processQueue() {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	defer close(s.doneChan)
+
+	batch := make([]trackedView, 0, s.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.count(batch); err != nil {
+			slog.Warn("Failed to count article views", "views", len(batch), "error", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case tracked := <-s.queue:
+			batch = append(batch, tracked)
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-s.stopChan:
+			for {
+				select {
+				case tracked := <-s.queue:
+					batch = append(batch, tracked)
+					if len(batch) >= s.batchSize {
+						flush()
+					}
+					continue
+				default:
+				}
+				break
+			}
+			flush()
+			return
+		}
+	}
+}
+
+// count stores the views that count, each site's in one transaction. A
+// visitor's repeated views in the batch count once, as the earlier one is
+// not stored yet when the later is checked.
+func (s *ArticleViewService) count(batch []trackedView) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type visit struct {
+		articleID   uint
+		fingerprint string
+	}
+	seen := map[visit]bool{}
+	var order []context.Context
+	bySite := map[uint][]*models.ArticleView{}
+	for i := range batch {
+		tracked := &batch[i]
+		key := visit{tracked.view.ArticleID, tracked.view.Fingerprint}
+		if seen[key] {
+			continue
+		}
+		counts, err := s.Counts(tracked.ctx, key.articleID, key.fingerprint, tracked.admin)
+		if err != nil {
+			return err
+		}
+		if !counts {
+			continue
+		}
+		seen[key] = true
+		site, _ := database.SiteFromContext(tracked.ctx)
+		if _, ok := bySite[site]; !ok {
+			order = append(order, tracked.ctx)
+		}
+		bySite[site] = append(bySite[site], &tracked.view)
+	}
+	for _, ctx := range order {
+		site, _ := database.SiteFromContext(ctx)
+		views := bySite[site]
+		if err := s.store(ctx, views); err == nil {
+			continue
+		}
+		// One bad view, such as of an article deleted meanwhile, fails
+		// the whole batch, so store the views one by one
+		dropped := 0
+		for _, view := range views {
+			view.ID = 0 // may hold an id from the rolled back batch
+			if err := s.store(ctx, []*models.ArticleView{view}); err != nil {
+				dropped++
+			}
+		}
+		if dropped > 0 {
+			slog.Warn("Dropped article views that could not be stored", "dropped", dropped, "views", len(views))
+		}
+	}
+	return nil
+}
+
+// store saves counted views and adds them to their articles' view counts in
+// one transaction. It is the only place view counts are incremented.
+func (s *ArticleViewService) store(ctx context.Context, views []*models.ArticleView) error {
+	if len(views) == 0 {
+		return nil
+	}
+	increments := map[uint]int{}
+	for _, view := range views {
+		increments[view.ArticleID]++
+	}
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(views, 100).Error; err != nil {
+			return err
+		}
+		for articleID, n := range increments {
+			err := tx.Model(&models.Article{}).Where("id = ?", articleID).
+				UpdateColumn("view_count", gorm.Expr("view_count + ?", n)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+var (
+	globalArticleViewService     *ArticleViewService
+	globalArticleViewServiceOnce sync.Once
+)
+
+// GetGlobalArticleViewService returns the global article view service
+func GetGlobalArticleViewService() *ArticleViewService {
+	globalArticleViewServiceOnce.Do(func() {
+		globalArticleViewService = NewArticleViewService()
+	})
+	return globalArticleViewService
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestArticleViewCounting(t *testing.T) {
+	newTestDB(t)
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := &ArticleViewService{
+		db:  func() *gorm.DB { return database.DB },
+		now: func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	article := models.Article{Title: "Hello", CategoryID: 1}
+	if err := database.DB.Create(&article).Error; err != nil {
+		t.Fatal(err)
+	}
+	settings := models.SiteSettings{}
+	if err := database.DB.Create(&settings).Error; err != nil {
+		t.Fatal(err)
+	}
+	view := func(fingerprint string, admin bool) {
+		t.Helper()
+		counts, err := s.Counts(ctx, article.ID, fingerprint, admin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counts {
+			if err := s.Record(ctx, &models.ArticleView{ArticleID: article.ID, IPAddress: "192.0.2.1", Fingerprint: fingerprint, CreatedAt: now}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	viewCount := func() uint {
+		t.Helper()
+		var reloaded models.Article
+		if err := database.DB.First(&reloaded, article.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return reloaded.ViewCount
+	}
+
+	// By default a visitor counts once and admins not at all
+	view("alice", false)
+	view("alice", false)
+	view("admin", true)
+	if got := viewCount(); got != 1 {
+		t.Fatalf("view count = %d, want 1", got)
+	}
+
+	// With a window of a day, Alice counts again the next day
+	database.DB.Model(&settings).Updates(map[string]interface{}{"view_dedup_hours": 24, "count_admin_views": true})
+	view("alice", false)
+	now = now.Add(25 * time.Hour)
+	view("alice", false)
+	view("admin", true)
+	view("admin", true)
+	if got := viewCount(); got != 3 {
+		t.Fatalf("view count = %d, want 3", got)
+	}
+
+	// Recounting restores counts that drifted from the recorded views
+	database.DB.Model(&article).UpdateColumn("view_count", 40)
+	updated, err := s.Recount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != 1 || viewCount() != 3 {
+		t.Errorf("recount updated %d articles to %d views, want 1 to 3", updated, viewCount())
+	}
+	if updated, _ := s.Recount(ctx); updated != 0 {
+		t.Errorf("second recount updated %d articles", updated)
+	}
+}
+
+func TestArticleViewQueue(t *testing.T) {
+	newTestDB(t)
+	s := NewArticleViewService()
+	ctx := context.Background()
+
+	article := models.Article{Title: "Hello", CategoryID: 1}
+	scheduled := models.Article{Title: "Later", CategoryID: 1, CreatedAt: time.Now().Add(time.Hour)}
+	for _, record := range []interface{}{&article, &scheduled, &models.SiteSettings{}} {
+		if err := database.DB.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Concurrent views of a visitor count once
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		fingerprint := "alice"
+		if i%5 == 0 {
+			fingerprint = fmt.Sprintf("visitor-%d", i)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			view := models.ArticleView{ArticleID: article.ID, IPAddress: "192.0.2.1", Fingerprint: fingerprint}
+			if err := s.Track(ctx, view, false); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	s.Stop()
+
+	counts, err := s.Get(ctx, article.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts.Views != 5 || counts.UniqueVisitors != 5 {
+		t.Errorf("Get() = %+v, want 5 views by 5 visitors", counts)
+	}
+
+	// Once stopped, views are counted right away
+	if err := s.Track(ctx, models.ArticleView{ArticleID: article.ID, IPAddress: "192.0.2.2", Fingerprint: "bob"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if counts, _ := s.Get(ctx, article.ID, false); counts.Views != 6 {
+		t.Errorf("views after stopping = %d, want 6", counts.Views)
+	}
+
+	if _, err := s.Get(ctx, scheduled.ID, false); !errors.Is(err, ErrArticleNotFound) {
+		t.Errorf("Get() of a scheduled article = %v, want ErrArticleNotFound", err)
+	}
+	if _, err := s.Get(ctx, scheduled.ID, true); err != nil {
+		t.Errorf("Get() of a scheduled article for admins = %v", err)
+	}
+}
@@ -0,0 +1,350 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Limits of author profiles
+const (
+	maxAuthorNameLength = 100
+	maxAuthorBioLength  = 2000
+	maxAuthorLinks      = 10
+	maxAuthorLinkLabel  = 50
+)
+
+var (
+	ErrInvalidAuthor  = errors.New("invalid author profile")
+	ErrAuthorNotFound = errors.New("author not found")
+)
+
+// Author is the public profile of an author, with the bio in the language
+// asked for where translated
+type Author struct {
+	ID           uint                `json:"id"`
+	Name         string              `json:"name"`
+	Bio          string              `json:"bio"`
+	AvatarURL    string              `json:"avatar_url,omitempty"`
+	SocialLinks  []models.AuthorLink `json:"social_links"`
+	ArticleCount int64               `json:"article_count"`
+}
+
+// AuthorProfileInput holds the editable fields of an author profile; nil
+// fields are left unchanged. An AvatarMediaID of 0 removes the avatar.
+// SocialLinks and Translations, when given, replace the current ones.
+type AuthorProfileInput struct {
+	DisplayName   *string                         `json:"display_name"`
+	Bio           *string                         `json:"bio"`
+	AvatarMediaID *uint                           `json:"avatar_media_id"`
+	SocialLinks   []models.AuthorLink             `json:"social_links"`
+	Translations  []AuthorProfileTranslationInput `json:"translations"`
+}
+
+// AuthorProfileTranslationInput is an author's bio in another language
+type AuthorProfileTranslationInput struct {
+	Language string `json:"language"`
+	Bio      string `json:"bio"`
+}
+
+// AuthorService manages the author profiles of users and credits articles
+// to their authors. Authors are addressed by user ID; their usernames,
+// which sign them in, are never shown.
+type AuthorService struct {
+	db  func() *gorm.DB
+	now func() time.Time
+}
+
+// NewAuthorService creates an author service
+func NewAuthorService() *AuthorService {
+	return &AuthorService{
+		db:  func() *gorm.DB { return database.DB },
+		now: time.Now,
+	}
+}
+
+// Get returns the public profile of the site's author with the user ID,
+// with the number of articles they have published
+func (s *AuthorService) Get(ctx context.Context, userID uint, lang string) (*Author, error) {
+	profile, err := s.profile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile.ID == 0 {
+		return nil, ErrAuthorNotFound
+	}
+	ApplyAuthorTranslation(profile, lang)
+	author := &Author{
+		ID:          profile.UserID,
+		Name:        profile.DisplayName,
+		Bio:         profile.Bio,
+		SocialLinks: authorLinks(profile),
+	}
+	avatars, err := s.avatars(ctx, []models.AuthorProfile{*profile})
+	if err != nil {
+		return nil, err
+	}
+	author.AvatarURL = avatars[profile.ID]
+	err = s.published(ctx, userID).Model(&models.Article{}).Count(&author.ArticleCount).Error
+	return author, err
+}
+
+// Articles returns a page of the articles the author has published, newest
+// first, with their category and translations
+func (s *AuthorService) Articles(ctx context.Context, userID uint, page, limit int) ([]models.Article, int64, error) {
+	profile, err := s.profile(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if profile.ID == 0 {
+		return nil, 0, ErrAuthorNotFound
+	}
+	var total int64
+	if err := s.published(ctx, userID).Model(&models.Article{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	articles := []models.Article{}
+	err = s.published(ctx, userID).Preload("Category").Preload("Translations").
+		Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&articles).Error
+	return articles, total, err
+}
+
+// Profile returns the user's own author profile for editing, an empty one
+// when they have none yet
+func (s *AuthorService) Profile(ctx context.Context, userID uint) (*models.AuthorProfile, error) {
+	return s.profile(ctx, userID)
+}
+
+// UpdateProfile edits the user's author profile, creating it on first save
+func (s *AuthorService) UpdateProfile(ctx context.Context, userID uint, input AuthorProfileInput) (*models.AuthorProfile, error) {
+	profile, err := s.profile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyAuthorProfileInput(profile, input); err != nil {
+		return nil, err
+	}
+	if profile.AvatarMediaID != nil {
+		var count int64
+		err := s.db().WithContext(ctx).Model(&models.MediaLibrary{}).
+			Where("id = ? AND media_type = ?", *profile.AvatarMediaID, models.MediaTypeImage).Count(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("%w: avatar_media_id must be an image in the media library", ErrInvalidAuthor)
+		}
+	}
+	err = s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if input.Translations != nil && profile.ID != 0 {
+			if err := tx.Where("profile_id = ?", profile.ID).Delete(&models.AuthorProfileTranslation{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(profile).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	cache.Publish(cache.TopicAuthors)
+	return profile, nil
+}
+
+// Attribute credits each of the articles to its author, where the author
+// has a profile
+func (s *AuthorService) Attribute(ctx context.Context, articles []models.Article) error {
+	var userIDs []uint
+	for _, article := range articles {
+		if article.AuthorID != nil {
+			userIDs = append(userIDs, *article.AuthorID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	var profiles []models.AuthorProfile
+	if err := s.db().WithContext(ctx).Where("user_id IN ?", userIDs).Find(&profiles).Error; err != nil {
+		return err
+	}
+	avatars, err := s.avatars(ctx, profiles)
+	if err != nil {
+		return err
+	}
+	authors := make(map[uint]*models.ArticleAuthor, len(profiles))
+	for _, profile := range profiles {
+		authors[profile.UserID] = &models.ArticleAuthor{
+			ID:          profile.UserID,
+			Name:        profile.DisplayName,
+			AvatarURL:   avatars[profile.ID],
+			SocialLinks: authorLinks(&profile),
+		}
+	}
+	for i := range articles {
+		if articles[i].AuthorID != nil {
+			articles[i].Author = authors[*articles[i].AuthorID]
+		}
+	}
+	return nil
+}
+
+// ApplyAuthorTranslation shows an author's bio in lang where it has been
+// translated
+func ApplyAuthorTranslation(profile *models.AuthorProfile, lang string) {
+	for _, translation := range profile.Translations {
+		if translation.Language == lang && translation.Bio != "" {
+			profile.Bio = translation.Bio
+			break
+		}
+	}
+}
+
+// profile returns the user's author profile, or an unsaved one for a user
+// of the site without a profile
+func (s *AuthorService) profile(ctx context.Context, userID uint) (*models.AuthorProfile, error) {
+	var user models.User
+	if err := s.db().WithContext(ctx).Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAuthorNotFound
+		}
+		return nil, err
+	}
+	profile := &models.AuthorProfile{UserID: user.ID}
+	err := s.db().WithContext(ctx).Preload("Translations").Where("user_id = ?", user.ID).Limit(1).Find(profile).Error
+	return profile, err
+}
+
+// published selects the author's articles that are out
+func (s *AuthorService) published(ctx context.Context, userID uint) *gorm.DB {
+	return s.db().WithContext(ctx).Where("author_id = ? AND created_at <= ?", userID, s.now())
+}
+
+// avatars returns the URLs of the profiles' avatars by profile ID
+func (s *AuthorService) avatars(ctx context.Context, profiles []models.AuthorProfile) (map[uint]string, error) {
+	var ids []uint
+	for _, profile := range profiles {
+		if profile.AvatarMediaID != nil {
+			ids = append(ids, *profile.AvatarMediaID)
+		}
+	}
+	avatars := map[uint]string{}
+	if len(ids) == 0 {
+		return avatars, nil
+	}
+	var media []models.MediaLibrary
+	if err := s.db().WithContext(ctx).Select("id", "url").Where("id IN ?", ids).Find(&media).Error; err != nil {
+		return nil, err
+	}
+	urls := make(map[uint]string, len(media))
+	for _, m := range media {
+		urls[m.ID] = m.URL
+	}
+	for _, profile := range profiles {
+		if profile.AvatarMediaID != nil {
+			avatars[profile.ID] = urls[*profile.AvatarMediaID]
+		}
+	}
+	return avatars, nil
+}
+
+// authorLinks decodes the profile's social links; links that cannot be
+// decoded are left out
+func authorLinks(profile *models.AuthorProfile) []models.AuthorLink {
+	links := []models.AuthorLink{}
+	if profile.SocialLinks != "" {
+		if err := json.Unmarshal([]byte(profile.SocialLinks), &links); err != nil {
+			return []models.AuthorLink{}
+		}
+	}
+	return links
+}
+
+func applyAuthorProfileInput(profile *models.AuthorProfile, input AuthorProfileInput) error {
+	if input.DisplayName != nil {
+		profile.DisplayName = strings.TrimSpace(*input.DisplayName)
+	}
+	if input.Bio != nil {
+		profile.Bio = strings.TrimSpace(*input.Bio)
+	}
+	if input.AvatarMediaID != nil {
+		if *input.AvatarMediaID == 0 {
+			profile.AvatarMediaID = nil
+		} else {
+			id := *input.AvatarMediaID
+			profile.AvatarMediaID = &id
+		}
+	}
+	if input.SocialLinks != nil {
+		if len(input.SocialLinks) > maxAuthorLinks {
+			return fmt.Errorf("%w: at most %d social links", ErrInvalidAuthor, maxAuthorLinks)
+		}
+		links := make([]models.AuthorLink, 0, len(input.SocialLinks))
+		for _, link := range input.SocialLinks {
+			link.Label = strings.TrimSpace(link.Label)
+			link.URL = strings.TrimSpace(link.URL)
+			if link.Label == "" || utf8.RuneCountInString(link.Label) > maxAuthorLinkLabel {
+				return fmt.Errorf("%w: each social link needs a label of at most %d characters", ErrInvalidAuthor, maxAuthorLinkLabel)
+			}
+			if !validMediaURL(link.URL) || strings.HasPrefix(link.URL, "/") {
+				return fmt.Errorf("%w: social link %q must be an absolute http(s) URL", ErrInvalidAuthor, link.Label)
+			}
+			links = append(links, link)
+		}
+		data, err := json.Marshal(links)
+		if err != nil {
+			return err
+		}
+		profile.SocialLinks = string(data)
+	}
+	if input.Translations != nil {
+		profile.Translations = make([]models.AuthorProfileTranslation, 0, len(input.Translations))
+		seen := map[string]bool{}
+		for _, t := range input.Translations {
+			language := strings.TrimSpace(t.Language)
+			if !languageCode.MatchString(language) || len(language) > 10 || seen[language] {
+				return fmt.Errorf("%w: each translation needs a distinct language code", ErrInvalidAuthor)
+			}
+			seen[language] = true
+			bio := strings.TrimSpace(t.Bio)
+			if utf8.RuneCountInString(bio) > maxAuthorBioLength {
+				return fmt.Errorf("%w: the %s bio is at most %d characters", ErrInvalidAuthor, language, maxAuthorBioLength)
+			}
+			profile.Translations = append(profile.Translations, models.AuthorProfileTranslation{
+				ProfileID: profile.ID,
+				Language:  language,
+				Bio:       bio,
+			})
+		}
+	}
+
+	if profile.DisplayName == "" || utf8.RuneCountInString(profile.DisplayName) > maxAuthorNameLength {
+		return fmt.Errorf("%w: display_name is required and at most %d characters", ErrInvalidAuthor, maxAuthorNameLength)
+	}
+	if utf8.RuneCountInString(profile.Bio) > maxAuthorBioLength {
+		return fmt.Errorf("%w: bio is at most %d characters", ErrInvalidAuthor, maxAuthorBioLength)
+	}
+	return nil
+}
+
+var (
+	globalAuthorService     *AuthorService
+	globalAuthorServiceOnce sync.Once
+)
+
+// GetGlobalAuthorService returns the global author service
+func GetGlobalAuthorService() *AuthorService {
+	globalAuthorServiceOnce.Do(func() {
+		globalAuthorService = NewAuthorService()
+	})
+	return globalAuthorService
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAuthorProfiles(t *testing.T) {
+	newTestDB(t)
+	if err := database.DB.Use(database.SiteScope{}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewAuthorService()
+	ctx := database.WithSite(context.Background(), models.DefaultSiteID)
+
+	writer := models.User{Username: "writer", Password: "x"}
+	stranger := models.User{SiteID: 2, Username: "stranger", Password: "x"}
+	avatar := models.MediaLibrary{FileName: "me.png", FilePath: "x", MimeType: "image/png", MediaType: models.MediaTypeImage, URL: "/uploads/me.png"}
+	video := models.MediaLibrary{FileName: "me.mp4", FilePath: "y", MimeType: "video/mp4", MediaType: models.MediaTypeVideo, URL: "/uploads/me.mp4"}
+	for _, record := range []interface{}{&writer, &stranger, &avatar, &video} {
+		if err := database.DB.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	for _, article := range []models.Article{
+		{Title: "Out", AuthorID: &writer.ID, CreatedAt: now.Add(-time.Hour)},
+		{Title: "Scheduled", AuthorID: &writer.ID, CreatedAt: now.Add(time.Hour)},
+		{Title: "Anonymous", CreatedAt: now.Add(-time.Hour)},
+	} {
+		if err := database.DB.Create(&article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Users are not authors until they save a profile
+	if _, err := s.Get(ctx, writer.ID, ""); !errors.Is(err, ErrAuthorNotFound) {
+		t.Fatalf("Get() without a profile = %v, want ErrAuthorNotFound", err)
+	}
+	if profile, err := s.Profile(ctx, writer.ID); err != nil || profile.ID != 0 || profile.UserID != writer.ID {
+		t.Fatalf("Profile() = %+v, %v, want an empty profile", profile, err)
+	}
+
+	name, bio, zero := "Ann Writer", "Writes about Go.", uint(0)
+	for _, bad := range []AuthorProfileInput{
+		{},
+		{DisplayName: &name, AvatarMediaID: &video.ID},
+		{DisplayName: &name, SocialLinks: []models.AuthorLink{{Label: "Site", URL: "/about"}}},
+		{DisplayName: &name, SocialLinks: []models.AuthorLink{{URL: "https://example.com"}}},
+		{DisplayName: &name, Translations: []AuthorProfileTranslationInput{{Language: "zh"}, {Language: "zh"}}},
+	} {
+		if _, err := s.UpdateProfile(ctx, writer.ID, bad); !errors.Is(err, ErrInvalidAuthor) {
+			t.Errorf("UpdateProfile(%+v) = %v, want ErrInvalidAuthor", bad, err)
+		}
+	}
+	_, err := s.UpdateProfile(ctx, writer.ID, AuthorProfileInput{
+		DisplayName:   &name,
+		Bio:           &bio,
+		AvatarMediaID: &avatar.ID,
+		SocialLinks:   []models.AuthorLink{{Label: "Mastodon", URL: "https://social.example/@ann"}},
+		Translations:  []AuthorProfileTranslationInput{{Language: "zh", Bio: "写 Go。"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	author, err := s.Get(ctx, writer.ID, "zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if author.Name != name || author.Bio != "写 Go。" || author.AvatarURL != "/uploads/me.png" ||
+		len(author.SocialLinks) != 1 || author.ArticleCount != 1 {
+		t.Errorf("Get() = %+v", author)
+	}
+	articles, total, err := s.Articles(ctx, writer.ID, 1, 10)
+	if err != nil || total != 1 || articles[0].Title != "Out" {
+		t.Errorf("Articles() = %v, %d, %v, want the published article", articles, total, err)
+	}
+	if err := s.Attribute(ctx, articles); err != nil {
+		t.Fatal(err)
+	}
+	if credit := articles[0].Author; credit == nil || credit.Name != name || credit.AvatarURL != "/uploads/me.png" {
+		t.Errorf("article credited to %+v", credit)
+	}
+
+	// Editing keeps what is left out and replaces the translations given
+	_, err = s.UpdateProfile(ctx, writer.ID, AuthorProfileInput{AvatarMediaID: &zero, Translations: []AuthorProfileTranslationInput{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if author, err = s.Get(ctx, writer.ID, "zh"); err != nil || author.Bio != bio || author.AvatarURL != "" || len(author.SocialLinks) != 1 {
+		t.Errorf("Get() after editing = %+v, %v", author, err)
+	}
+
+	if _, err := s.Get(ctx, stranger.ID, ""); !errors.Is(err, ErrAuthorNotFound) {
+		t.Errorf("another site's user = %v, want ErrAuthorNotFound", err)
+	}
+}
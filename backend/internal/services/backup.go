@@ -0,0 +1,549 @@
+package services
+
+import (
+	"archive/tar"
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupManifestName = "manifest.json"
+	backupDatabaseName = "blog.db"
+	backupUploadsDir   = "uploads"
+	backupVersion      = 1
+)
+
+var backupNamePattern = regexp.MustCompile(`^kuno-backup-[A-Za-z0-9._-]+\.tar\.gz$`)
+
+// ErrBackupNotFound is returned when a named backup does not exist
+var ErrBackupNotFound = errors.New("backup not found")
+
+// ErrInvalidBackupName is returned for names outside the backup naming scheme
+var ErrInvalidBackupName = errors.New("invalid backup name")
+
+// BackupManifest describes the contents of a backup archive
+type BackupManifest struct {
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"created_at"`
+	Driver     string    `json:"driver"`
+	Migrations []string  `json:"migrations"`
+	Files      int       `json:"files"`
+}
+
+// BackupInfo describes a backup archive on disk
+type BackupInfo struct {
+	Name      string          `json:"name"`
+	Size      int64           `json:"size"`
+	CreatedAt time.Time       `json:"created_at"`
+	Manifest  *BackupManifest `json:"manifest,omitempty"`
+}
+
+// BackupService creates and restores archives containing a SQLite snapshot
+// and the uploads directory
+type BackupService struct {
+	dir       string
+	uploadDir string
+	mu        sync.Mutex
+
+	// Scheduled run state, see backup_offsite.go
+	statusMu sync.Mutex
+	running  bool
+}
+
+// NewBackupService creates a service from the backup and storage settings
+func NewBackupService() *BackupService {
+	cfg := config.Get()
+	dir := cfg.Backup.Dir
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(cfg.Database.Path), "backups")
+	}
+	return newBackupService(dir, cfg.Storage.UploadDir)
+}
+
+func newBackupService(dir, uploadDir string) *BackupService {
+	return &BackupService{dir: dir, uploadDir: uploadDir}
+}
+
+// Dir returns the directory backups are stored in
+func (s *BackupService) Dir() string {
+	return s.dir
+}
+
+// Create writes a new backup archive and returns its details
+func (s *BackupService) Create() (*BackupInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.create()
+}
+
+func (s *BackupService) create() (*BackupInfo, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	workDir, err := os.MkdirTemp(s.dir, ".snapshot-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	dbFile := filepath.Join(workDir, backupDatabaseName)
+	if err := database.SnapshotSQLite(dbFile); err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	now := time.Now().UTC()
+	manifest := BackupManifest{
+		Version:   backupVersion,
+		CreatedAt: now,
+		Driver:    database.Dialect(),
+	}
+	if statuses, err := database.GetMigrationStatus(database.DB); err == nil {
+		for _, status := range statuses {
+			if status.Applied {
+				manifest.Migrations = append(manifest.Migrations, status.ID)
+			}
+		}
+	}
+
+	name := fmt.Sprintf("kuno-backup-%s.tar.gz", now.Format("20060102-150405"))
+	for i := 1; fileExists(filepath.Join(s.dir, name)); i++ {
+		name = fmt.Sprintf("kuno-backup-%s-%d.tar.gz", now.Format("20060102-150405"), i)
+	}
+
+	// Write to a temporary name so a partial archive is never listed
+	tmpPath := filepath.Join(workDir, name)
+	if err := writeBackupArchive(tmpPath, dbFile, s.uploadDir, &manifest); err != nil {
+		return nil, err
+	}
+	finalPath := filepath.Join(s.dir, name)
+	if err := os.Rename(tmpPath, finalPath); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Backup created", "name", name, "files", manifest.Files)
+	return backupInfo(finalPath, &manifest)
+}
+
+// List returns all backups, newest first
+func (s *BackupService) List() ([]BackupInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return []BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []BackupInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !backupNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(s.dir, entry.Name())
+		manifest, err := readBackupManifest(path)
+		if err != nil {
+			slog.Warn("Skipping unreadable backup", "name", entry.Name(), "error", err)
+		}
+		info, err := backupInfo(path, manifest)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, *info)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// Path returns the file path of a named backup
+func (s *BackupService) Path(name string) (string, error) {
+	if !backupNamePattern.MatchString(name) {
+		return "", ErrInvalidBackupName
+	}
+	path := filepath.Join(s.dir, name)
+	if !fileExists(path) {
+		return "", ErrBackupNotFound
+	}
+	return path, nil
+}
+
+// Delete removes a named backup
+func (s *BackupService) Delete(name string) error {
+	path, err := s.Path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Import stores an uploaded archive after checking that it is a valid backup
+func (s *BackupService) Import(r io.Reader) (*BackupInfo, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+
+	manifest, err := readBackupManifest(tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("not a valid backup archive: %w", err)
+	}
+
+	name := fmt.Sprintf("kuno-backup-%s-imported.tar.gz", manifest.CreatedAt.UTC().Format("20060102-150405"))
+	for i := 1; fileExists(filepath.Join(s.dir, name)); i++ {
+		name = fmt.Sprintf("kuno-backup-%s-imported-%d.tar.gz", manifest.CreatedAt.UTC().Format("20060102-150405"), i)
+	}
+	path := filepath.Join(s.dir, name)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	return backupInfo(path, manifest)
+}
+
+// Restore replaces the database and uploads with the contents of a backup.
+// A safety backup of the current state is taken first and its name returned.
+func (s *BackupService) Restore(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.Path(name)
+	if err != nil {
+		return "", err
+	}
+
+	workDir, err := os.MkdirTemp(s.dir, ".restore-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(workDir)
+
+	manifest, err := extractBackupArchive(path, workDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+	if manifest.Version > backupVersion {
+		return "", fmt.Errorf("backup version %d is newer than supported version %d", manifest.Version, backupVersion)
+	}
+	if manifest.Driver != database.DriverSQLite {
+		return "", fmt.Errorf("backup was taken from a %s database", manifest.Driver)
+	}
+
+	safety, err := s.create()
+	if err != nil {
+		return "", fmt.Errorf("failed to create safety backup: %w", err)
+	}
+
+	if err := database.RestoreSQLite(filepath.Join(workDir, backupDatabaseName)); err != nil {
+		return safety.Name, fmt.Errorf("failed to restore database: %w", err)
+	}
+	// Bring older backups up to the current schema
+	if err := database.Migrate(database.DB); err != nil {
+		return safety.Name, fmt.Errorf("failed to migrate restored database: %w", err)
+	}
+
+	if err := replaceDirContents(s.uploadDir, filepath.Join(workDir, backupUploadsDir)); err != nil {
+		return safety.Name, fmt.Errorf("failed to restore uploads: %w", err)
+	}
+
+	GetGlobalCache().InvalidatePattern("*")
+	cache.Publish(cache.TopicArticles, cache.TopicCategories, cache.TopicSettings)
+	slog.Info("Backup restored", "name", name, "safety_backup", safety.Name)
+	return safety.Name, nil
+}
+
+func writeBackupArchive(path, dbFile, uploadDir string, manifest *BackupManifest) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	if err := addFileToTar(tw, dbFile, backupDatabaseName); err != nil {
+		return err
+	}
+
+	if fileExists(uploadDir) {
+		err = filepath.WalkDir(uploadDir, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(uploadDir, p)
+			if err != nil {
+				return err
+			}
+			manifest.Files++
+			return addFileToTar(tw, p, backupUploadsDir+"/"+filepath.ToSlash(rel))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to archive uploads: %w", err)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: backupManifestName, Mode: 0644, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func addFileToTar(tw *tar.Writer, path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// walkBackupArchive calls fn for each regular file in the archive after
+// rejecting entries that could escape the extraction directory
+func walkBackupArchive(path string, fn func(name string, r io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.ToSlash(filepath.Clean(header.Name))
+		if filepath.IsAbs(header.Name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("unsafe path in archive: %s", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeReg:
+			if err := fn(name, tr); err != nil {
+				return err
+			}
+		case tar.TypeDir:
+		default:
+			return fmt.Errorf("unsupported entry in archive: %s", header.Name)
+		}
+	}
+}
+
+func readBackupManifest(path string) (*BackupManifest, error) {
+	var manifest *BackupManifest
+	hasDatabase := false
+	err := walkBackupArchive(path, func(name string, r io.Reader) error {
+		switch name {
+		case backupManifestName:
+			manifest = &BackupManifest{}
+			return json.NewDecoder(r).Decode(manifest)
+		case backupDatabaseName:
+			hasDatabase = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, errors.New("archive has no manifest")
+	}
+	if !hasDatabase {
+		return nil, errors.New("archive has no database")
+	}
+	return manifest, nil
+}
+
+func extractBackupArchive(path, dir string) (*BackupManifest, error) {
+	if err := walkBackupArchive(path, func(name string, r io.Reader) error {
+		if name != backupManifestName && name != backupDatabaseName && !strings.HasPrefix(name, backupUploadsDir+"/") {
+			return nil
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, r); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	}); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, backupManifestName))
+	if err != nil {
+		return nil, errors.New("archive has no manifest")
+	}
+	if !fileExists(filepath.Join(dir, backupDatabaseName)) {
+		return nil, errors.New("archive has no database")
+	}
+	var manifest BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// replaceDirContents empties dst and moves the files from src into it. The
+// directory itself is kept because it is often a mounted volume.
+func replaceDirContents(dst, src string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dst, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	if !fileExists(src) {
+		return nil
+	}
+	return filepath.WalkDir(src, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(p, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func backupInfo(path string, manifest *BackupManifest) (*BackupInfo, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	info := &BackupInfo{
+		Name:      filepath.Base(path),
+		Size:      stat.Size(),
+		CreatedAt: stat.ModTime().UTC(),
+		Manifest:  manifest,
+	}
+	if manifest != nil {
+		info.CreatedAt = manifest.CreatedAt
+	}
+	return info, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+var (
+	globalBackupService     *BackupService
+	globalBackupServiceOnce sync.Once
+)
+
+// GetGlobalBackupService returns the shared backup service
+func GetGlobalBackupService() *BackupService {
+	globalBackupServiceOnce.Do(func() {
+		globalBackupService = NewBackupService()
+	})
+	return globalBackupService
+}
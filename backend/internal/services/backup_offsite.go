@@ -0,0 +1,569 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"blog-backend/internal/storage"
+	"crypto/sha256"
+	"crypto/tls"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+)
+
+const backupStatusFile = ".offsite-status.json"
+
+// RemoteBackup describes a backup archive stored off-site
+type RemoteBackup struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// BackupTarget is an off-site location backup archives are copied to
+type BackupTarget interface {
+	Name() string
+	Upload(name string, r io.Reader, size int64) error
+	Download(name string) (io.ReadCloser, error)
+	List() ([]RemoteBackup, error)
+	Delete(name string) error
+}
+
+// BackupRunResult describes one scheduled backup run
+type BackupRunResult struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256"`
+	Target     string    `json:"target,omitempty"`
+	Uploaded   bool      `json:"uploaded"`
+	Pruned     []string  `json:"pruned,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// OffsiteBackupStatus reports the scheduler state and the outcome of the
+// last scheduled runs. It is persisted next to the archives so it survives
+// restarts and database restores.
+type OffsiteBackupStatus struct {
+	Schedule       string           `json:"schedule"`
+	Target         string           `json:"target"`
+	RetentionCount int              `json:"retention_count"`
+	RetentionDays  int              `json:"retention_days"`
+	NextRun        *time.Time       `json:"next_run,omitempty"`
+	Running        bool             `json:"running"`
+	LastRunAt      *time.Time       `json:"last_run_at,omitempty"`
+	LastError      string           `json:"last_error,omitempty"`
+	LastSuccess    *BackupRunResult `json:"last_success,omitempty"`
+}
+
+// NewBackupTarget builds the off-site target described by the backup
+// settings, or returns nil when no target is configured
+func NewBackupTarget(cfg config.BackupConfig) (BackupTarget, error) {
+	if cfg.Target == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(cfg.TargetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup target URL: %v", err)
+	}
+	password, err := security.ResolveSecret(cfg.TargetPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve backup target password: %v", err)
+	}
+
+	switch cfg.Target {
+	case "s3":
+		if u.Scheme != "s3" || u.Host == "" {
+			return nil, fmt.Errorf("S3 backup target URL must look like s3://bucket/prefix")
+		}
+		// Endpoint, region and default credentials are shared with media storage
+		s3cfg := storage.LoadS3ConfigFromEnv()
+		s3cfg.Bucket = u.Host
+		s3cfg.Prefix = strings.Trim(u.Path, "/")
+		if cfg.TargetUsername != "" {
+			s3cfg.AccessKey = cfg.TargetUsername
+			s3cfg.SecretKey = password
+		}
+		client := storage.NewS3Client(s3cfg)
+		if !client.IsConfigured() {
+			return nil, fmt.Errorf("S3 backup target has no credentials")
+		}
+		return &objectBackupTarget{driver: client}, nil
+	case "webdav":
+		driver, err := storage.NewWebDAV(config.WebDAVConfig{URL: cfg.TargetURL, Username: cfg.TargetUsername, Password: cfg.TargetPassword})
+		if err != nil {
+			return nil, err
+		}
+		return &objectBackupTarget{driver: driver}, nil
+	case "storage":
+		// The remote store shared with media and exports
+		if err := storage.DriverError(); err != nil {
+			return nil, err
+		}
+		if !storage.IsRemote() {
+			return nil, fmt.Errorf("the storage backup target needs a remote STORAGE_DRIVER")
+		}
+		return &objectBackupTarget{driver: storage.GetGlobalDriver(), prefix: "backups/"}, nil
+	case "ftp":
+		if u.Scheme != "ftp" && u.Scheme != "ftps" {
+			return nil, fmt.Errorf("FTP backup target URL must use ftp or ftps")
+		}
+		return &ftpBackupTarget{
+			url:      u,
+			username: cfg.TargetUsername,
+			password: password,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported backup target %q", cfg.Target)
+	}
+}
+
+// BackupScheduleName is the scheduler entry for BACKUP_SCHEDULE
+const BackupScheduleName = "backup"
+
+// RunScheduledBackup creates a backup, verifies it, copies it to the
+// off-site target, verifies the copy and applies the retention policy
+func (s *BackupService) RunScheduledBackup() (*BackupRunResult, error) {
+	cfg := config.Get().Backup
+	result := &BackupRunResult{Target: cfg.Target, StartedAt: time.Now().UTC()}
+
+	s.statusMu.Lock()
+	if s.running {
+		s.statusMu.Unlock()
+		return nil, errors.New("a backup run is already in progress")
+	}
+	s.running = true
+	s.statusMu.Unlock()
+
+	err := s.runScheduledBackup(cfg, result)
+
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	s.running = false
+	status := s.loadStatus()
+	now := time.Now().UTC()
+	status.LastRunAt = &now
+	if err != nil {
+		status.LastError = err.Error()
+	} else {
+		result.FinishedAt = now
+		status.LastError = ""
+		status.LastSuccess = result
+	}
+	s.saveStatus(status)
+
+	if err != nil {
+		notifyAdmin(NotificationInput{
+			Source:   models.NotificationSourceBackup,
+			Type:     "backup_failed",
+			Severity: models.SeverityError,
+			Title:    "Scheduled backup failed",
+			Message:  err.Error(),
+		})
+		return nil, err
+	}
+	slog.Info("Scheduled backup completed", "name", result.Name, "target", result.Target, "pruned", len(result.Pruned))
+	message := fmt.Sprintf("%s, %d bytes, was verified", result.Name, result.Size)
+	if result.Uploaded {
+		message += " and copied to " + result.Target
+	}
+	if len(result.Pruned) > 0 {
+		message += fmt.Sprintf("; %d old backups were removed", len(result.Pruned))
+	}
+	notifyAdmin(NotificationInput{
+		Source:   models.NotificationSourceBackup,
+		Type:     "backup_completed",
+		Severity: models.SeverityInfo,
+		Title:    "Scheduled backup completed",
+		Message:  message + ".",
+	})
+	return result, nil
+}
+
+func (s *BackupService) runScheduledBackup(cfg config.BackupConfig, result *BackupRunResult) error {
+	target, err := NewBackupTarget(cfg)
+	if err != nil {
+		return err
+	}
+
+	backup, err := s.Create()
+	if err != nil {
+		return err
+	}
+	result.Name = backup.Name
+	result.Size = backup.Size
+
+	path := filepath.Join(s.dir, backup.Name)
+	if result.SHA256, err = VerifyBackupArchive(path); err != nil {
+		return fmt.Errorf("backup %s failed verification: %w", backup.Name, err)
+	}
+
+	if target != nil {
+		if err := uploadAndVerify(target, path, backup.Name, backup.Size, result.SHA256); err != nil {
+			return fmt.Errorf("off-site copy to %s failed: %w", target.Name(), err)
+		}
+		result.Uploaded = true
+	}
+
+	pruned, err := s.applyRetention(target, cfg.RetentionCount, cfg.RetentionDays)
+	result.Pruned = pruned
+	if err != nil {
+		return fmt.Errorf("retention failed: %w", err)
+	}
+	return nil
+}
+
+// BackupStatus returns the scheduler configuration and last run results
+func (s *BackupService) BackupStatus() OffsiteBackupStatus {
+	cfg := config.Get().Backup
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	status := s.loadStatus()
+	status.Schedule = cfg.Schedule
+	status.Target = cfg.Target
+	status.RetentionCount = cfg.RetentionCount
+	status.RetentionDays = cfg.RetentionDays
+	status.Running = s.running
+	status.NextRun = GetGlobalScheduler().NextRun(BackupScheduleName)
+	return status
+}
+
+func (s *BackupService) loadStatus() OffsiteBackupStatus {
+	var status OffsiteBackupStatus
+	data, err := os.ReadFile(filepath.Join(s.dir, backupStatusFile))
+	if err == nil {
+		json.Unmarshal(data, &status)
+	}
+	return status
+}
+
+func (s *BackupService) saveStatus(status OffsiteBackupStatus) {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(s.dir, backupStatusFile), data, 0644)
+	}
+	if err != nil {
+		slog.Warn("Failed to save backup status", "error", err)
+	}
+}
+
+// VerifyBackupArchive reads the whole archive, checks the manifest and runs
+// SQLite's integrity check on the database, returning the archive SHA-256
+func VerifyBackupArchive(path string) (string, error) {
+	if _, err := readBackupManifest(path); err != nil {
+		return "", err
+	}
+
+	tmpDir, err := os.MkdirTemp(filepath.Dir(path), ".verify-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dbFile := filepath.Join(tmpDir, backupDatabaseName)
+	err = walkBackupArchive(path, func(name string, r io.Reader) error {
+		if name != backupDatabaseName {
+			_, err := io.Copy(io.Discard, r)
+			return err
+		}
+		out, err := os.Create(dbFile)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, r); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := checkSQLiteIntegrity(dbFile); err != nil {
+		return "", err
+	}
+
+	return fileSHA256(path)
+}
+
+func checkSQLiteIntegrity(path string) error {
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("database integrity check failed: %s", result)
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return readerSHA256(f)
+}
+
+func readerSHA256(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// uploadAndVerify copies an archive to the target and downloads it again
+// to confirm the stored copy matches
+func uploadAndVerify(target BackupTarget, path, name string, size int64, checksum string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = target.Upload(name, f, size)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	remote, err := target.Download(name)
+	if err != nil {
+		return fmt.Errorf("failed to read back uploaded archive: %w", err)
+	}
+	defer remote.Close()
+	remoteChecksum, err := readerSHA256(remote)
+	if err != nil {
+		return fmt.Errorf("failed to read back uploaded archive: %w", err)
+	}
+	if remoteChecksum != checksum {
+		return fmt.Errorf("uploaded archive checksum mismatch")
+	}
+	return nil
+}
+
+// applyRetention removes local and off-site archives beyond the newest
+// keep archives or older than maxDays. Zero disables either rule.
+func (s *BackupService) applyRetention(target BackupTarget, keep, maxDays int) ([]string, error) {
+	var pruned []string
+
+	local, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	localItems := make([]RemoteBackup, len(local))
+	for i, backup := range local {
+		localItems[i] = RemoteBackup{Name: backup.Name, Size: backup.Size, ModTime: backup.CreatedAt}
+	}
+	for _, name := range expiredBackups(localItems, keep, maxDays, time.Now()) {
+		if err := s.Delete(name); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, name)
+	}
+
+	if target == nil {
+		return pruned, nil
+	}
+	remote, err := target.List()
+	if err != nil {
+		return pruned, err
+	}
+	for _, name := range expiredBackups(remote, keep, maxDays, time.Now()) {
+		if err := target.Delete(name); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, target.Name()+":"+name)
+	}
+	return pruned, nil
+}
+
+// expiredBackups returns the names that fall outside the retention policy
+func expiredBackups(backups []RemoteBackup, keep, maxDays int, now time.Time) []string {
+	sorted := make([]RemoteBackup, 0, len(backups))
+	for _, backup := range backups {
+		if backupNamePattern.MatchString(backup.Name) {
+			sorted = append(sorted, backup)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ModTime.After(sorted[j].ModTime)
+	})
+
+	var expired []string
+	cutoff := now.AddDate(0, 0, -maxDays)
+	for i, backup := range sorted {
+		// The newest archive is never removed
+		if i == 0 {
+			continue
+		}
+		if (keep > 0 && i >= keep) || (maxDays > 0 && backup.ModTime.Before(cutoff)) {
+			expired = append(expired, backup.Name)
+		}
+	}
+	return expired
+}
+
+// objectBackupTarget stores archives in a storage driver, under prefix
+type objectBackupTarget struct {
+	driver storage.Driver
+	prefix string
+}
+
+func (t *objectBackupTarget) Name() string { return t.driver.Name() }
+
+func (t *objectBackupTarget) Upload(name string, r io.Reader, size int64) error {
+	return t.driver.Put(t.prefix+name, r, size, "application/gzip")
+}
+
+func (t *objectBackupTarget) Download(name string) (io.ReadCloser, error) {
+	body, _, err := t.driver.Get(t.prefix + name)
+	return body, err
+}
+
+func (t *objectBackupTarget) List() ([]RemoteBackup, error) {
+	objects, err := t.driver.List(t.prefix + "kuno-backup-")
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]RemoteBackup, 0, len(objects))
+	for _, object := range objects {
+		name := strings.TrimPrefix(object.Key, t.prefix)
+		if strings.Contains(name, "/") {
+			continue
+		}
+		backups = append(backups, RemoteBackup{Name: name, Size: object.Size, ModTime: object.LastModified})
+	}
+	return backups, nil
+}
+
+func (t *objectBackupTarget) Delete(name string) error {
+	return t.driver.Delete(t.prefix + name)
+}
+
+// ftpBackupTarget stores archives in a directory on an FTP server. ftps://
+// URLs use explicit TLS.
+type ftpBackupTarget struct {
+	url      *url.URL
+	username string
+	password string
+}
+
+func (t *ftpBackupTarget) Name() string { return "ftp" }
+
+func (t *ftpBackupTarget) connect() (*ftp.ServerConn, error) {
+	host := t.url.Host
+	if t.url.Port() == "" {
+		host += ":21"
+	}
+	options := []ftp.DialOption{ftp.DialWithTimeout(30 * time.Second)}
+	if t.url.Scheme == "ftps" {
+		options = append(options, ftp.DialWithExplicitTLS(&tls.Config{ServerName: t.url.Hostname()}))
+	}
+
+	conn, err := ftp.Dial(host, options...)
+	if err != nil {
+		return nil, fmt.Errorf("FTP connection failed: %v", err)
+	}
+	username := t.username
+	if username == "" {
+		username = "anonymous"
+	}
+	if err := conn.Login(username, t.password); err != nil {
+		conn.Quit()
+		return nil, fmt.Errorf("FTP login failed: %v", err)
+	}
+	return conn, nil
+}
+
+func (t *ftpBackupTarget) remotePath(name string) string {
+	return path.Join("/", t.url.Path, name)
+}
+
+func (t *ftpBackupTarget) Upload(name string, r io.Reader, size int64) error {
+	conn, err := t.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Quit()
+
+	// Create the directory on first use; an error usually means it exists
+	conn.MakeDir(t.remotePath(""))
+	return conn.Stor(t.remotePath(name), r)
+}
+
+func (t *ftpBackupTarget) Download(name string) (io.ReadCloser, error) {
+	conn, err := t.connect()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := conn.Retr(t.remotePath(name))
+	if err != nil {
+		conn.Quit()
+		return nil, err
+	}
+	return &ftpReadCloser{Response: resp, conn: conn}, nil
+}
+
+func (t *ftpBackupTarget) List() ([]RemoteBackup, error) {
+	conn, err := t.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Quit()
+
+	entries, err := conn.List(t.remotePath(""))
+	if err != nil {
+		return nil, err
+	}
+	var backups []RemoteBackup
+	for _, entry := range entries {
+		if entry.Type == ftp.EntryTypeFile && backupNamePattern.MatchString(entry.Name) {
+			backups = append(backups, RemoteBackup{Name: entry.Name, Size: int64(entry.Size), ModTime: entry.Time})
+		}
+	}
+	return backups, nil
+}
+
+func (t *ftpBackupTarget) Delete(name string) error {
+	conn, err := t.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Quit()
+	return conn.Delete(t.remotePath(name))
+}
+
+// ftpReadCloser closes the control connection along with the transfer
+type ftpReadCloser struct {
+	*ftp.Response
+	conn *ftp.ServerConn
+}
+
+func (r *ftpReadCloser) Close() error {
+	err := r.Response.Close()
+	r.conn.Quit()
+	return err
+}
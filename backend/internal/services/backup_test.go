@@ -0,0 +1,243 @@
+package services
+
+import (
+	"archive/tar"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupBackupTest(t *testing.T) *BackupService {
+	t.Helper()
+	newTestDB(t)
+	dir := t.TempDir()
+	uploadDir := filepath.Join(dir, "uploads")
+	if err := os.MkdirAll(filepath.Join(uploadDir, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return newBackupService(filepath.Join(dir, "backups"), uploadDir)
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	s := setupBackupTest(t)
+	image := filepath.Join(s.uploadDir, "images", "a.png")
+	if err := os.WriteFile(image, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	database.DB.Create(&models.Category{Name: "Before"})
+
+	backup, err := s.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup.Manifest == nil || backup.Manifest.Files != 1 || len(backup.Manifest.Migrations) == 0 {
+		t.Fatalf("unexpected manifest: %+v", backup.Manifest)
+	}
+
+	// Change everything after the backup
+	database.DB.Create(&models.Category{Name: "After"})
+	os.WriteFile(image, []byte("changed"), 0644)
+	os.WriteFile(filepath.Join(s.uploadDir, "new.png"), []byte("new"), 0644)
+
+	safety, err := s.Restore(backup.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if safety == "" || safety == backup.Name {
+		t.Errorf("expected a separate safety backup, got %q", safety)
+	}
+
+	var names []string
+	database.DB.Model(&models.Category{}).Pluck("name", &names)
+	if len(names) != 1 || names[0] != "Before" {
+		t.Errorf("categories after restore = %v, want [Before]", names)
+	}
+	if data, _ := os.ReadFile(image); string(data) != "original" {
+		t.Errorf("upload content after restore = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(s.uploadDir, "new.png")); !os.IsNotExist(err) {
+		t.Errorf("file added after the backup should be removed")
+	}
+
+	list, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Errorf("expected 2 backups, got %d", len(list))
+	}
+}
+
+func TestBackupRejectsInvalidNames(t *testing.T) {
+	s := setupBackupTest(t)
+	for _, name := range []string{"../blog.db", "kuno-backup-../x.tar.gz", "other.tar.gz"} {
+		if _, err := s.Path(name); err != ErrInvalidBackupName {
+			t.Errorf("Path(%q) error = %v, want ErrInvalidBackupName", name, err)
+		}
+	}
+	if err := s.Delete("kuno-backup-missing.tar.gz"); err != ErrBackupNotFound {
+		t.Errorf("Delete of missing backup error = %v", err)
+	}
+}
+
+func TestBackupImportRejectsUnsafeArchive(t *testing.T) {
+	s := setupBackupTest(t)
+
+	archive := filepath.Join(t.TempDir(), "evil.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	content := []byte("x")
+	tw.WriteHeader(&tar.Header{Name: "../escape.txt", Mode: 0644, Size: int64(len(content))})
+	tw.Write(content)
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	in, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+
+	_, err = s.Import(in)
+	if err == nil || !strings.Contains(err.Error(), "unsafe path") {
+		t.Errorf("Import error = %v, want unsafe path error", err)
+	}
+}
+
+func TestExpiredBackups(t *testing.T) {
+	now := time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC)
+	var backups []RemoteBackup
+	for day := 1; day <= 9; day++ {
+		backups = append(backups, RemoteBackup{
+			Name:    fmt.Sprintf("kuno-backup-202506%02d-030000.tar.gz", day),
+			ModTime: time.Date(2025, 6, day, 3, 0, 0, 0, time.UTC),
+		})
+	}
+	backups = append(backups, RemoteBackup{Name: "unrelated.txt", ModTime: now.AddDate(-1, 0, 0)})
+
+	if got := expiredBackups(backups, 7, 0, now); len(got) != 2 || got[0] != "kuno-backup-20250602-030000.tar.gz" {
+		t.Errorf("count retention expired %v", got)
+	}
+	if got := expiredBackups(backups, 0, 3, now); len(got) != 7 {
+		t.Errorf("age retention expired %v, want 7 archives", got)
+	}
+	// The newest archive is kept even when it is older than the limit
+	if got := expiredBackups(backups[:1], 0, 1, now); len(got) != 0 {
+		t.Errorf("expired the only archive: %v", got)
+	}
+}
+
+// fakeWebDAV is a minimal in-memory WebDAV collection
+type fakeWebDAV struct {
+	files    map[string][]byte
+	modified map[string]time.Time
+}
+
+func (f *fakeWebDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(r.URL.Path)
+	switch r.Method {
+	case "MKCOL":
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.files[name] = data
+		f.modified[name] = time.Now()
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		data, ok := f.files[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.files, name)
+		w.WriteHeader(http.StatusNoContent)
+	case "PROPFIND":
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:"><d:response><d:href>/dav/</d:href></d:response>`)
+		for name, data := range f.files {
+			fmt.Fprintf(w, `<d:response><d:href>/dav/%s</d:href><d:propstat><d:prop><d:getcontentlength>%d</d:getcontentlength><d:getlastmodified>%s</d:getlastmodified></d:prop></d:propstat></d:response>`,
+				name, len(data), f.modified[name].UTC().Format(http.TimeFormat))
+		}
+		fmt.Fprint(w, `</d:multistatus>`)
+	}
+}
+
+func TestWebDAVTargetUploadVerifyAndRetention(t *testing.T) {
+	s := setupBackupTest(t)
+	old := "kuno-backup-20200101-000000.tar.gz"
+	dav := &fakeWebDAV{
+		files:    map[string][]byte{old: []byte("old")},
+		modified: map[string]time.Time{old: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	server := httptest.NewServer(dav)
+	defer server.Close()
+
+	target, err := NewBackupTarget(config.BackupConfig{Target: "webdav", TargetURL: server.URL + "/dav"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := s.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(s.dir, backup.Name)
+	checksum, err := VerifyBackupArchive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uploadAndVerify(target, path, backup.Name, backup.Size, checksum); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dav.files[backup.Name]; !ok {
+		t.Fatalf("archive was not uploaded")
+	}
+
+	pruned, err := s.applyRetention(target, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 1 || pruned[0] != "webdav:"+old {
+		t.Errorf("pruned %v", pruned)
+	}
+	if len(dav.files) != 1 {
+		t.Errorf("expected only the new archive to remain, got %d files", len(dav.files))
+	}
+}
+
+func TestVerifyBackupArchiveDetectsCorruption(t *testing.T) {
+	s := setupBackupTest(t)
+	backup, err := s.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(s.dir, backup.Name)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(path, data[:len(data)/2], 0644)
+
+	if _, err := VerifyBackupArchive(path); err == nil {
+		t.Error("expected a truncated archive to fail verification")
+	}
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/metrics"
+	"blog-backend/internal/models"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Overflow policies of the behavior queue, see config.TrackingConfig
+const (
+	BehaviorOverflowSync  = "sync"
+	BehaviorOverflowDrop  = "drop"
+	BehaviorOverflowSpill = "spill"
+)
+
+// BehaviorOverflowEvent is the webhook event of a behavior queue overflow
+const BehaviorOverflowEvent = "behavior_queue.overflow"
+
+// behaviorOverflowAlertInterval spaces out the overflow alerts, which
+// report every overflow since the previous one
+const behaviorOverflowAlertInterval = time.Hour
+
+// Tracked behavior by the way it was saved: queued, sync, spilled or
+// dropped. Everything but queued means the queue was full.
+var behaviorsTrackedCounter = metrics.NewCounterVec("path")
+
+func init() {
+	metrics.Register(collectBehaviorQueueMetrics)
+}
+
+func collectBehaviorQueueMetrics() ([]metrics.Family, error) {
+	families := []metrics.Family{
+		{Name: "kuno_behaviors_tracked_total", Help: "Tracked reader behavior by how it was saved", Type: metrics.Counter, Samples: behaviorsTrackedCounter.Samples()},
+	}
+	// Only the queue of the global tracker is exported, and only once
+	// something built it
+	c := globalContainer.Load()
+	if c == nil {
+		return families, nil
+	}
+	bt := c.BehaviorTracker
+	families = append(families,
+		metrics.Family{Name: "kuno_behavior_queue_length", Help: "Tracked behavior waiting in the queue", Type: metrics.Gauge, Samples: []metrics.Sample{{Value: float64(len(bt.behaviorQueue))}}},
+		metrics.Family{Name: "kuno_behavior_queue_capacity", Help: "Tracked behavior the queue holds before it overflows", Type: metrics.Gauge, Samples: []metrics.Sample{{Value: float64(cap(bt.behaviorQueue))}}},
+	)
+	if bt.spill != nil {
+		families = append(families, metrics.Family{Name: "kuno_behavior_spill_pending", Help: "Spilled behavior not saved yet", Type: metrics.Gauge, Samples: []metrics.Sample{{Value: float64(bt.spill.Pending())}}})
+	}
+	return families, nil
+}
+
+// BehaviorOverflowAlert reports the behavior that overflowed the queue
+// since the previous alert
+type BehaviorOverflowAlert struct {
+	Policy     string    `json:"policy"`
+	QueueSize  int       `json:"queue_size"`
+	Overflowed int       `json:"overflowed"`
+	Since      time.Time `json:"since"`
+}
+
+// behaviorOverflow counts overflows between alerts
+type behaviorOverflow struct {
+	mu        sync.Mutex
+	count     int
+	since     time.Time
+	lastAlert time.Time
+}
+
+// configureQueue applies the tracking settings to a new tracker
+func (bt *BehaviorTracker) configureQueue(cfg config.TrackingConfig, databasePath string) {
+	bt.behaviorQueue = make(chan models.UserReadingBehavior, cfg.QueueSize)
+	bt.overflowPolicy = cfg.Overflow
+	if cfg.Overflow == BehaviorOverflowSpill {
+		dir := cfg.SpillDir
+		if dir == "" {
+			dir = filepath.Join(filepath.Dir(databasePath), "behavior-spill")
+		}
+		bt.spill = newBehaviorSpill(dir)
+	}
+	bt.alert = func(alert BehaviorOverflowAlert) {
+		GetGlobalSecurityAlertService().SendAlert(BehaviorOverflowEvent,
+			"Reader behavior queue overflowing",
+			fmt.Sprintf("%d tracked behaviors found the queue of %d full since %s and were handled with the %q policy.\n\n"+
+				"Raise BEHAVIOR_QUEUE_SIZE, or check the database if batches are slow to save.\n",
+				alert.Overflowed, alert.QueueSize, alert.Since.Format(time.RFC1123), alert.Policy),
+			alert)
+	}
+}
+
+// overflow handles behavior tracked while the queue is full
+func (bt *BehaviorTracker) overflow(behavior models.UserReadingBehavior) error {
+	bt.noteOverflow()
+	switch bt.overflowPolicy {
+	case BehaviorOverflowDrop:
+		behaviorsTrackedCounter.Add(1, "dropped")
+		return nil
+	case BehaviorOverflowSpill:
+		err := bt.spill.Append(behavior)
+		if err == nil {
+			behaviorsTrackedCounter.Add(1, "spilled")
+			return nil
+		}
+		slog.Error("Failed to spill tracked behavior, saving it now", "error", err)
+	}
+	behaviorsTrackedCounter.Add(1, "sync")
+	return bt.storeBehavior(behavior)
+}
+
+// noteOverflow counts an overflow and raises an alert when the previous one
+// is at least behaviorOverflowAlertInterval old
+func (bt *BehaviorTracker) noteOverflow() {
+	now := time.Now()
+	o := &bt.overflows
+	o.mu.Lock()
+	if o.count == 0 {
+		o.since = now
+	}
+	o.count++
+	if now.Sub(o.lastAlert) < behaviorOverflowAlertInterval {
+		o.mu.Unlock()
+		return
+	}
+	alert := BehaviorOverflowAlert{Policy: bt.overflowPolicy, QueueSize: cap(bt.behaviorQueue), Overflowed: o.count, Since: o.since}
+	o.count, o.lastAlert = 0, now
+	o.mu.Unlock()
+
+	slog.Warn("Behavior queue overflowing", "policy", alert.Policy, "queue_size", alert.QueueSize, "overflowed", alert.Overflowed)
+	if bt.alert != nil {
+		go bt.alert(alert)
+	}
+}
+
+// replaySpill saves spilled behavior once the queue is at most half full,
+// so replaying does not itself overflow it
+func (bt *BehaviorTracker) replaySpill() {
+	if bt.spill == nil || bt.spill.Pending() == 0 || len(bt.behaviorQueue) > cap(bt.behaviorQueue)/2 {
+		return
+	}
+	if err := bt.spill.Replay(bt.batchSize, bt.flushBehaviors); err != nil {
+		slog.Error("Failed to replay spilled behavior", "error", err)
+	}
+}
+
+// behaviorSpill is a file of behavior that overflowed the queue, one JSON
+// object per line. Replaying moves it aside first, so behavior spilled
+// meanwhile goes to a new file.
+type behaviorSpill struct {
+	path string
+
+	mu      sync.Mutex
+	pending int
+}
+
+func newBehaviorSpill(dir string) *behaviorSpill {
+	s := &behaviorSpill{path: filepath.Join(dir, "behaviors.jsonl")}
+	// Behavior left from a previous run is saved with the next replay
+	for _, path := range []string{s.path, s.replayPath()} {
+		if n, err := countLines(path); err == nil {
+			s.pending += n
+		}
+	}
+	return s
+}
+
+func (s *behaviorSpill) replayPath() string {
+	return s.path + ".replay"
+}
+
+// Pending returns the spilled behavior not saved yet
+func (s *behaviorSpill) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// Append writes behavior to the end of the file
+func (s *behaviorSpill) Append(behavior models.UserReadingBehavior) error {
+	line, err := json.Marshal(behavior)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.pending++
+	return nil
+}
+
+// Replay hands the spilled behavior to save in batches of up to size and
+// removes it. A replay file left by an interrupted replay goes first.
+func (s *behaviorSpill) Replay(size int, save func([]models.UserReadingBehavior)) error {
+	s.mu.Lock()
+	if _, err := os.Stat(s.replayPath()); errors.Is(err, fs.ErrNotExist) {
+		if err := os.Rename(s.path, s.replayPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	s.mu.Unlock()
+
+	f, err := os.Open(s.replayPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	batch := make([]models.UserReadingBehavior, 0, size)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		save(batch)
+		s.mu.Lock()
+		s.pending -= len(batch)
+		s.mu.Unlock()
+		batch = make([]models.UserReadingBehavior, 0, size)
+	}
+	scanner := bufio.NewScanner(f)
+	skipped := 0
+	for scanner.Scan() {
+		var behavior models.UserReadingBehavior
+		if err := json.Unmarshal(scanner.Bytes(), &behavior); err != nil {
+			skipped++
+			continue
+		}
+		batch = append(batch, behavior)
+		if len(batch) >= size {
+			flush()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	flush()
+	if skipped > 0 {
+		slog.Warn("Skipped unreadable spilled behavior", "count", skipped)
+		s.mu.Lock()
+		s.pending -= skipped
+		s.mu.Unlock()
+	}
+	f.Close()
+	return os.Remove(s.replayPath())
+}
+
+func countLines(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		n++
+	}
+	return n, scanner.Err()
+}
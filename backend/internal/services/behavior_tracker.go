@@ -1,15 +1,17 @@
 package services
 
 import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
 	"blog-backend/internal/database"
 	"blog-backend/internal/models"
-	"crypto/sha256"
+	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"log"
 	"math"
 	"sort"
-	"strings"
 	"sync"
 	"time"
 )
@@ -17,12 +19,21 @@ import (
 // BehaviorTracker tracks and analyzes user reading behavior
 type BehaviorTracker struct {
 	cache         *SmartCache
-	profileCache  sync.Map // Cache for user profiles
+	profiles      *cache.Namespace // Cache for user profiles
 	batchSize     int
 	flushInterval time.Duration
 	behaviorQueue chan models.UserReadingBehavior
 	stopChan      chan struct{}
+	doneChan      chan struct{}
+	stopOnce      sync.Once
 	mu            sync.RWMutex
+
+	// Handling of behavior tracked while the queue is full, see
+	// behavior_queue.go
+	overflowPolicy string
+	spill          *behaviorSpill
+	overflows      behaviorOverflow
+	alert          func(BehaviorOverflowAlert) // replaced in tests
 }
 
 // ReadingSession represents a user's reading session
@@ -57,6 +68,10 @@ type UserInteraction struct {
 	ReferrerType    string            `json:"referrer_type"`
 	Language        string            `json:"language"`
 	Timestamp       time.Time         `json:"timestamp"`
+
+	// Address and user agent of the request, for strict privacy mode
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 // UserInterests represents user's aggregated interests
@@ -67,28 +82,41 @@ type UserInterests struct {
 	Languages  map[string]float64 `json:"languages"`  // language -> preference score
 }
 
+// behaviorFlushInterval bounds how long tracked behavior is held in this
+// instance's queue. It is kept short because other replicas only see
+// behavior, and the profiles derived from it, once it is in the database.
+const behaviorFlushInterval = 10 * time.Second
+
 // NewBehaviorTracker creates a new behavior tracker
-func NewBehaviorTracker() *BehaviorTracker {
+func NewBehaviorTracker(smartCache *SmartCache) *BehaviorTracker {
 	bt := &BehaviorTracker{
-		cache:         GetGlobalCache(),
+		cache:         smartCache,
+		profiles:      cache.New("profiles", 30*time.Minute),
 		batchSize:     100,
-		flushInterval: time.Minute * 5,
-		behaviorQueue: make(chan models.UserReadingBehavior, 1000),
+		flushInterval: behaviorFlushInterval,
 		stopChan:      make(chan struct{}),
+		doneChan:      make(chan struct{}),
 	}
+	cfg := config.Get()
+	bt.configureQueue(cfg.Tracking, cfg.Database.Path)
 
-	// Start background processors
+	// Start the batch writer; profile updates run on the scheduler
 	go bt.processBehaviorQueue()
-	go bt.periodicProfileUpdate()
 
 	return bt
 }
 
 // TrackInteraction tracks a user interaction
 func (bt *BehaviorTracker) TrackInteraction(interaction UserInteraction) error {
-	// Generate user ID if not provided (based on session/fingerprint)
-	if interaction.UserID == "" {
-		interaction.UserID = bt.generateUserID(interaction.SessionID, interaction.DeviceInfo)
+	// Generate user ID if not provided (based on session/fingerprint).
+	// In strict privacy mode readers cannot choose it or keep a session.
+	visitors := GetGlobalVisitorService()
+	if visitors.StrictPrivacy() {
+		interaction.UserID = visitors.BehaviorID("", "", interaction.UserAgent, interaction.IPAddress)
+		interaction.SessionID = ""
+	} else if interaction.UserID == "" {
+		interaction.UserID = visitors.BehaviorID(interaction.SessionID, interaction.DeviceInfo.DeviceType,
+			interaction.DeviceInfo.UserAgent, interaction.IPAddress)
 	}
 
 	// Create behavior record
@@ -108,17 +136,22 @@ func (bt *BehaviorTracker) TrackInteraction(interaction UserInteraction) error {
 		CreatedAt:       interaction.Timestamp,
 	}
 
-	// Queue for batch processing
+	// Once stopped nothing drains the queue, so write through
 	select {
-	case bt.behaviorQueue <- behavior:
-		// Successfully queued
-	default:
-		// Queue is full, process immediately
+	case <-bt.stopChan:
 		return bt.storeBehavior(behavior)
+	default:
 	}
 
-	// Update user profile asynchronously
-	go bt.updateUserProfile(interaction.UserID)
+	// Queue for batch processing; the profile is updated once the batch
+	// is saved
+	select {
+	case bt.behaviorQueue <- behavior:
+		behaviorsTrackedCounter.Add(1, "queued")
+	default:
+		// Queue is full, handle it by the overflow policy
+		return bt.overflow(behavior)
+	}
 
 	return nil
 }
@@ -126,10 +159,9 @@ func (bt *BehaviorTracker) TrackInteraction(interaction UserInteraction) error {
 // GetUserProfile retrieves or creates a user profile
 func (bt *BehaviorTracker) GetUserProfile(userID string) (*models.UserProfile, error) {
 	// Check cache first
-	if cached, exists := bt.profileCache.Load(userID); exists {
-		if profile, ok := cached.(*models.UserProfile); ok {
-			return profile, nil
-		}
+	var cached models.UserProfile
+	if bt.profiles.Get(userID, &cached) {
+		return &cached, nil
 	}
 
 	// Check database
@@ -160,7 +192,7 @@ func (bt *BehaviorTracker) GetUserProfile(userID string) (*models.UserProfile, e
 	}
 
 	// Cache the profile
-	bt.profileCache.Store(userID, &profile)
+	bt.profiles.Set(userID, &profile)
 
 	return &profile, nil
 }
@@ -170,10 +202,9 @@ func (bt *BehaviorTracker) GetUserInterests(userID string) (*UserInterests, erro
 	cacheKey := fmt.Sprintf("user_interests_%s", userID)
 
 	// Check cache first
-	if cached, exists := bt.cache.Get(cacheKey); exists {
-		if interests, ok := cached.(*UserInterests); ok {
-			return interests, nil
-		}
+	var cached UserInterests
+	if bt.cache.Get(cacheKey, &cached) {
+		return &cached, nil
 	}
 
 	// Calculate interests from behavior
@@ -201,7 +232,15 @@ func (bt *BehaviorTracker) GetSimilarUsers(userID string, limit int) ([]string,
 		return nil, fmt.Errorf("failed to parse user interest vector: %v", err)
 	}
 
-	if len(userVector) == 0 {
+	if len(userVector) != interestVectorSize {
+		// Not calculated yet, or stored before feature hashing
+		interests, err := bt.calculateUserInterests(userID)
+		if err != nil {
+			return nil, err
+		}
+		userVector = bt.interestsToVector(interests)
+	}
+	if bt.cosineSimilarity(userVector, userVector) == 0 {
 		return []string{}, nil // No interests yet
 	}
 
@@ -221,7 +260,7 @@ func (bt *BehaviorTracker) GetSimilarUsers(userID string, limit int) ([]string,
 	var similarities []userSimilarity
 	for _, profile := range profiles {
 		var otherVector []float64
-		if err := json.Unmarshal([]byte(profile.InterestVector), &otherVector); err != nil {
+		if err := json.Unmarshal([]byte(profile.InterestVector), &otherVector); err != nil || len(otherVector) != interestVectorSize {
 			continue
 		}
 
@@ -235,9 +274,12 @@ func (bt *BehaviorTracker) GetSimilarUsers(userID string, limit int) ([]string,
 		}
 	}
 
-	// Sort by similarity
+	// Sort by similarity, then by user for a stable order
 	sort.Slice(similarities, func(i, j int) bool {
-		return similarities[i].similarity > similarities[j].similarity
+		if similarities[i].similarity != similarities[j].similarity {
+			return similarities[i].similarity > similarities[j].similarity
+		}
+		return similarities[i].userID < similarities[j].userID
 	})
 
 	// Return top similar users
@@ -286,22 +328,20 @@ type ReadingPatterns struct {
 
 // Helper methods
 
-// generateUserID generates a consistent user ID from session and device info
-func (bt *BehaviorTracker) generateUserID(sessionID string, deviceInfo DeviceInfo) string {
-	data := fmt.Sprintf("%s:%s:%s", sessionID, deviceInfo.DeviceType, deviceInfo.UserAgent)
-	hash := sha256.Sum256([]byte(data))
-	return fmt.Sprintf("user_%x", hash[:8])
-}
-
 // storeBehavior stores a behavior record in the database
 func (bt *BehaviorTracker) storeBehavior(behavior models.UserReadingBehavior) error {
-	return database.DB.Create(&behavior).Error
+	if err := database.DB.Create(&behavior).Error; err != nil {
+		return err
+	}
+	go bt.refreshUsers([]models.UserReadingBehavior{behavior})
+	return nil
 }
 
 // processBehaviorQueue processes queued behaviors in batches
 func (bt *BehaviorTracker) processBehaviorQueue() {
 	ticker := time.NewTicker(bt.flushInterval)
 	defer ticker.Stop()
+	defer close(bt.doneChan)
 
 	behaviors := make([]models.UserReadingBehavior, 0, bt.batchSize)
 
@@ -320,9 +360,23 @@ func (bt *BehaviorTracker) processBehaviorQueue() {
 				bt.flushBehaviors(behaviors)
 				behaviors = behaviors[:0] // Reset slice
 			}
+			bt.replaySpill()
 
 		case <-bt.stopChan:
-			// Flush remaining behaviors before stopping
+			// Drain anything still buffered in the queue, then flush before stopping
+			for {
+				select {
+				case behavior := <-bt.behaviorQueue:
+					behaviors = append(behaviors, behavior)
+					if len(behaviors) >= bt.batchSize {
+						bt.flushBehaviors(behaviors)
+						behaviors = behaviors[:0]
+					}
+					continue
+				default:
+				}
+				break
+			}
 			if len(behaviors) > 0 {
 				bt.flushBehaviors(behaviors)
 			}
@@ -338,7 +392,34 @@ func (bt *BehaviorTracker) flushBehaviors(behaviors []models.UserReadingBehavior
 	}
 
 	if err := database.DB.CreateInBatches(behaviors, bt.batchSize).Error; err != nil {
-		log.Printf("Failed to flush behaviors: %v", err)
+		// One bad row (e.g. an article that no longer exists, rejected by a
+		// foreign key) fails the whole batch, so save the rows one by one
+		log.Printf("Failed to flush behaviors, retrying individually: %v", err)
+		dropped := 0
+		for i := range behaviors {
+			behaviors[i].ID = 0 // may hold an id from the rolled back batch
+			if err := database.DB.Create(&behaviors[i]).Error; err != nil {
+				dropped++
+			}
+		}
+		if dropped > 0 {
+			log.Printf("Dropped %d of %d behaviors that could not be saved", dropped, len(behaviors))
+		}
+	}
+	bt.refreshUsers(behaviors)
+}
+
+// refreshUsers recomputes the profiles of the users in a saved batch and
+// drops their cached interests, so every replica reads the new behavior
+func (bt *BehaviorTracker) refreshUsers(behaviors []models.UserReadingBehavior) {
+	seen := make(map[string]bool)
+	for _, behavior := range behaviors {
+		if seen[behavior.UserID] {
+			continue
+		}
+		seen[behavior.UserID] = true
+		bt.cache.Delete(fmt.Sprintf("user_interests_%s", behavior.UserID))
+		bt.updateUserProfile(behavior.UserID)
 	}
 }
 
@@ -435,7 +516,7 @@ func (bt *BehaviorTracker) updateUserProfile(userID string) {
 	}
 
 	// Update cache
-	bt.profileCache.Store(userID, profile)
+	bt.profiles.Set(userID, profile)
 }
 
 // calculateUserInterests calculates user interests from reading behavior
@@ -473,7 +554,7 @@ func (bt *BehaviorTracker) calculateUserInterests(userID string) (*UserInterests
 		interests.Languages[behavior.Language] += weight
 
 		// Extract keywords from title and summary
-		keywords := bt.extractKeywordsFromText(article.Title + " " + article.Summary)
+		keywords := ExtractKeywords(article.Title+" "+article.Summary, behavior.Language)
 		for _, keyword := range keywords {
 			interests.Keywords[keyword] += weight * 0.5 // Lower weight for keywords
 		}
@@ -504,28 +585,6 @@ func (bt *BehaviorTracker) calculateInterestWeight(readingTime int, scrollDepth
 	return weight
 }
 
-// extractKeywordsFromText extracts keywords from text (simplified)
-func (bt *BehaviorTracker) extractKeywordsFromText(text string) []string {
-	words := strings.Fields(strings.ToLower(text))
-
-	// Filter out common stop words and short words
-	stopWords := map[string]bool{
-		"the": true, "and": true, "or": true, "but": true, "in": true, "on": true, "at": true,
-		"to": true, "for": true, "of": true, "with": true, "by": true, "is": true, "are": true,
-		"was": true, "were": true, "be": true, "been": true, "have": true, "has": true, "had": true,
-		"一个": true, "这个": true, "那个": true, "我们": true, "它们": true, "可以": true, "应该": true,
-	}
-
-	var keywords []string
-	for _, word := range words {
-		if len(word) > 2 && !stopWords[word] {
-			keywords = append(keywords, word)
-		}
-	}
-
-	return keywords
-}
-
 // normalizeInterests normalizes interest scores
 func (bt *BehaviorTracker) normalizeInterests(interests *UserInterests) {
 	// Normalize each category separately
@@ -559,40 +618,57 @@ func (bt *BehaviorTracker) normalizeMap(m map[string]float64) {
 	}
 }
 
-// interestsToVector converts interests to a numerical vector
-func (bt *BehaviorTracker) interestsToVector(interests *UserInterests) []float64 {
-	// Simplified: create a vector based on top interests
-	vector := make([]float64, 50) // Fixed size vector
+// interestVectorSize is the length of interest vectors. Vectors of another
+// length were stored before feature hashing and are not compared.
+const interestVectorSize = 64
 
-	index := 0
+// Weights of the kinds of interest in a vector. Most readers share a
+// language, so it counts for little next to what they read.
+const (
+	categoryFeatureWeight = 1.0
+	keywordFeatureWeight  = 0.5
+	languageFeatureWeight = 0.25
+)
 
-	// Add category interests
-	for _, score := range interests.Categories {
-		if index >= len(vector) {
-			break
+// interestsToVector converts interests to a vector by feature hashing: each
+// category, language and keyword is hashed to a position and a sign, so the
+// same interest always lands in the same place whatever the map order or the
+// other interests. The vector has unit length, or is all zeros without any
+// interests.
+func (bt *BehaviorTracker) interestsToVector(interests *UserInterests) []float64 {
+	vector := make([]float64, interestVectorSize)
+	add := func(kind string, scores map[string]float64, weight float64) {
+		// Sum in a fixed order so vectors are identical bit for bit
+		features := make([]string, 0, len(scores))
+		for feature := range scores {
+			features = append(features, feature)
 		}
-		vector[index] = score
-		index++
-	}
-
-	// Add language preferences
-	for _, score := range interests.Languages {
-		if index >= len(vector) {
-			break
+		sort.Strings(features)
+		for _, feature := range features {
+			h := fnv.New64a()
+			h.Write([]byte(kind + ":" + feature))
+			sum := h.Sum64()
+			sign := 1.0
+			if sum>>63 == 1 {
+				sign = -1.0
+			}
+			vector[sum%interestVectorSize] += sign * weight * scores[feature]
 		}
-		vector[index] = score
-		index++
 	}
+	add("category", interests.Categories, categoryFeatureWeight)
+	add("language", interests.Languages, languageFeatureWeight)
+	add("keyword", interests.Keywords, keywordFeatureWeight)
 
-	// Fill remaining with keyword interests
-	for _, score := range interests.Keywords {
-		if index >= len(vector) {
-			break
+	var norm float64
+	for _, value := range vector {
+		norm += value * value
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vector {
+			vector[i] /= norm
 		}
-		vector[index] = score * 0.5 // Lower weight for keywords
-		index++
 	}
-
 	return vector
 }
 
@@ -686,24 +762,9 @@ func (bt *BehaviorTracker) analyzeReadingPatterns(behaviors []models.UserReading
 	return patterns
 }
 
-// periodicProfileUpdate runs periodic profile updates
-func (bt *BehaviorTracker) periodicProfileUpdate() {
-	ticker := time.NewTicker(time.Hour) // Update profiles every hour
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			bt.updateAllActiveProfiles()
-		case <-bt.stopChan:
-			return
-		}
-	}
-}
-
-// updateAllActiveProfiles updates profiles for recently active users
-func (bt *BehaviorTracker) updateAllActiveProfiles() {
-	// Get users active in the last 24 hours
+// UpdateActiveProfiles recomputes the profiles of users active in the last
+// 24 hours and returns how many were updated. It runs as a scheduled job.
+func (bt *BehaviorTracker) UpdateActiveProfiles(ctx context.Context) (int, error) {
 	since := time.Now().Add(-24 * time.Hour)
 
 	var userIDs []string
@@ -711,14 +772,16 @@ func (bt *BehaviorTracker) updateAllActiveProfiles() {
 		Where("created_at >= ?", since).
 		Distinct("user_id").
 		Pluck("user_id", &userIDs).Error; err != nil {
-		log.Printf("Failed to get active users: %v", err)
-		return
+		return 0, fmt.Errorf("failed to get active users: %v", err)
 	}
 
-	// Update profiles in background
-	for _, userID := range userIDs {
-		go bt.updateUserProfile(userID)
+	for i, userID := range userIDs {
+		if ctx.Err() != nil {
+			return i, ctx.Err()
+		}
+		bt.updateUserProfile(userID)
 	}
+	return len(userIDs), nil
 }
 
 // RecentUser represents a recently active user with summary info
@@ -732,8 +795,11 @@ type RecentUser struct {
 	AvgScrollDepth   float64   `json:"avg_scroll_depth"`
 }
 
-// GetRecentUsers returns a list of recently active users with summary information
-func (bt *BehaviorTracker) GetRecentUsers(limit, offset, days int) ([]RecentUser, error) {
+// GetRecentUsers returns a list of recently active users with summary
+// information, most recently active first. A page is either skipped to by
+// offset or follows the one whose next cursor is given; the returned cursor
+// is empty after the last page.
+func (bt *BehaviorTracker) GetRecentUsers(limit, offset, days int, cursor string) ([]RecentUser, string, error) {
 	since := time.Now().AddDate(0, 0, -days)
 
 	// Query to get recent users with aggregated data
@@ -747,7 +813,7 @@ func (bt *BehaviorTracker) GetRecentUsers(limit, offset, days int) ([]RecentUser
 	}
 
 	// Get aggregated user data from reading behaviors
-	err := database.DB.Table("user_reading_behaviors").
+	query := database.DB.Table("user_reading_behaviors").
 		Select(`
 			user_id,
 			MAX(created_at) as last_active,
@@ -762,13 +828,27 @@ func (bt *BehaviorTracker) GetRecentUsers(limit, offset, days int) ([]RecentUser
 		Where("created_at >= ? AND interaction_type = 'view'", since).
 		Group("user_id, language").
 		Having("COUNT(*) > 0").
-		Order("last_active DESC").
-		Limit(limit).
-		Offset(offset).
-		Find(&results).Error
+		Order("last_active DESC, user_id, language").
+		Limit(limit + 1)
+	if cursor != "" {
+		key, err := decodeCursor(cursor, 3)
+		if err != nil {
+			return nil, "", err
+		}
+		query = query.Having("MAX(created_at) < ? OR (MAX(created_at) = ? AND (user_id > ? OR (user_id = ? AND language > ?)))",
+			key[0], key[0], key[1], key[1], key[2])
+	} else {
+		query = query.Offset(offset)
+	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to get recent users: %w", err)
+	if err := query.Find(&results).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to get recent users: %w", err)
+	}
+	var nextCursor string
+	if len(results) > limit {
+		results = results[:limit]
+		last := results[limit-1]
+		nextCursor = encodeCursor(last.LastActive, last.UserID, last.Language)
 	}
 
 	// Convert results to RecentUser structs and enrich with profile data
@@ -827,21 +907,18 @@ func (bt *BehaviorTracker) GetRecentUsers(limit, offset, days int) ([]RecentUser
 		recentUsers = append(recentUsers, user)
 	}
 
-	return recentUsers, nil
+	return recentUsers, nextCursor, nil
 }
 
-// Stop stops the behavior tracker
+// Stop stops the behavior tracker and waits until queued behaviors are flushed
 func (bt *BehaviorTracker) Stop() {
-	close(bt.stopChan)
+	bt.stopOnce.Do(func() {
+		close(bt.stopChan)
+	})
+	<-bt.doneChan
 }
 
-// Global behavior tracker instance
-var globalBehaviorTracker *BehaviorTracker
-
-// GetGlobalBehaviorTracker returns the global behavior tracker instance
+// GetGlobalBehaviorTracker returns the behavior tracker of the global container
 func GetGlobalBehaviorTracker() *BehaviorTracker {
-	if globalBehaviorTracker == nil {
-		globalBehaviorTracker = NewBehaviorTracker()
-	}
-	return globalBehaviorTracker
+	return GetGlobalContainer().BehaviorTracker
 }
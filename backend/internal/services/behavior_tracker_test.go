@@ -0,0 +1,141 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestInterestsToVector(t *testing.T) {
+	bt := &BehaviorTracker{}
+	interests := func(category string, keywords ...string) *UserInterests {
+		in := &UserInterests{
+			Categories: map[string]float64{category: 1},
+			Languages:  map[string]float64{"en": 1},
+			Keywords:   make(map[string]float64),
+		}
+		for i, keyword := range keywords {
+			in.Keywords[keyword] = 1 / float64(i+1)
+		}
+		return in
+	}
+
+	golang := interests("Go", "goroutines", "channels", "generics", "modules", "testing")
+	vector := bt.interestsToVector(golang)
+	if len(vector) != interestVectorSize {
+		t.Fatalf("len = %d, want %d", len(vector), interestVectorSize)
+	}
+	for i := 0; i < 20; i++ {
+		if again := bt.interestsToVector(interests("Go", "goroutines", "channels", "generics", "modules", "testing")); !reflect.DeepEqual(again, vector) {
+			t.Fatal("the same interests gave different vectors")
+		}
+	}
+
+	similar := bt.interestsToVector(interests("Go", "goroutines", "channels"))
+	other := bt.interestsToVector(interests("Cooking", "bread", "sourdough"))
+	if s, o := bt.cosineSimilarity(vector, similar), bt.cosineSimilarity(vector, other); s <= o || s < 0.5 {
+		t.Errorf("similarity to a Go reader = %.2f, to a cook = %.2f", s, o)
+	}
+	if zero := bt.interestsToVector(&UserInterests{}); bt.cosineSimilarity(zero, zero) != 0 {
+		t.Errorf("a reader without interests has the vector %v", zero)
+	}
+}
+
+func TestGetSimilarUsers(t *testing.T) {
+	newTestDB(t)
+	bt := NewBehaviorTracker(NewSmartCache(DefaultCacheConfig()))
+	t.Cleanup(bt.Stop)
+
+	vectorOf := func(in *UserInterests) string {
+		data, _ := json.Marshal(bt.interestsToVector(in))
+		return string(data)
+	}
+	gopher := &UserInterests{Categories: map[string]float64{"Go": 1}, Languages: map[string]float64{"en": 1}}
+	cook := &UserInterests{Categories: map[string]float64{"Cooking": 1}, Languages: map[string]float64{"fr": 1}}
+	stale, _ := json.Marshal(make([]float64, 50))
+	for _, profile := range []models.UserProfile{
+		{UserID: "me", InterestVector: vectorOf(gopher)},
+		{UserID: "b", InterestVector: vectorOf(gopher)},
+		{UserID: "a", InterestVector: vectorOf(gopher)},
+		{UserID: "cook", InterestVector: vectorOf(cook)},
+		{UserID: "stale", InterestVector: string(stale)},
+	} {
+		profile.PreferredTopics, profile.ActiveHours = "[]", "[]"
+		if err := database.DB.Create(&profile).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	similar, err := bt.GetSimilarUsers("me", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(similar, want) {
+		t.Errorf("GetSimilarUsers() = %v, want %v", similar, want)
+	}
+}
+
+func TestBehaviorQueueOverflow(t *testing.T) {
+	for _, policy := range []string{BehaviorOverflowDrop, BehaviorOverflowSpill} {
+		t.Run(policy, func(t *testing.T) {
+			dir := t.TempDir()
+			bt := &BehaviorTracker{batchSize: 2}
+			bt.configureQueue(config.TrackingConfig{QueueSize: 1, Overflow: policy, SpillDir: dir}, "")
+			alerts := make(chan BehaviorOverflowAlert, 10)
+			bt.alert = func(alert BehaviorOverflowAlert) { alerts <- alert }
+
+			for i := 0; i < 4; i++ {
+				if err := bt.TrackInteraction(UserInteraction{UserID: "reader", ArticleID: uint(i + 1), InteractionType: "view"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if len(bt.behaviorQueue) != 1 {
+				t.Fatalf("queue holds %d behaviors, want 1", len(bt.behaviorQueue))
+			}
+			// The three overflows raise one alert, the later ones wait
+			// for the alert interval
+			if alert := <-alerts; alert.Policy != policy || alert.QueueSize != 1 || alert.Overflowed != 1 {
+				t.Errorf("alert = %+v", alert)
+			}
+			if bt.overflows.count != 2 {
+				t.Errorf("%d overflows wait for the next alert, want 2", bt.overflows.count)
+			}
+
+			if policy == BehaviorOverflowDrop {
+				if bt.spill != nil {
+					t.Error("the drop policy has a spill file")
+				}
+				return
+			}
+			// A restarted tracker finds the spilled behavior
+			if pending := newBehaviorSpill(dir).Pending(); pending != 3 {
+				t.Errorf("a new spill finds %d behaviors, want 3", pending)
+			}
+
+			// Replaying hands them over in batches and empties the file
+			var batches []int
+			var articles []uint
+			err := bt.spill.Replay(bt.batchSize, func(behaviors []models.UserReadingBehavior) {
+				batches = append(batches, len(behaviors))
+				for _, behavior := range behaviors {
+					articles = append(articles, behavior.ArticleID)
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(batches, []int{2, 1}) || !reflect.DeepEqual(articles, []uint{2, 3, 4}) {
+				t.Errorf("replayed batches %v of articles %v", batches, articles)
+			}
+			if pending := bt.spill.Pending(); pending != 0 {
+				t.Errorf("%d behaviors pending after the replay", pending)
+			}
+			if pending := newBehaviorSpill(dir).Pending(); pending != 0 {
+				t.Errorf("the spill file still holds %d behaviors", pending)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"blog-backend/internal/imaging"
+	"blog-backend/internal/models"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// FaviconICO is the name of the multi-size icon in a favicon set
+	FaviconICO = "favicon.ico"
+	// minFaviconSource is the smallest image a favicon set is made from;
+	// smaller ones make blurry touch icons
+	minFaviconSource = 48
+	// maxFaviconSourcePixels bounds the decoded size of uploaded images
+	maxFaviconSourcePixels = 25_000_000
+)
+
+// faviconImages are the PNG files of a favicon set besides FaviconICO,
+// sized for browser tabs, iOS home screens and web app manifests
+var faviconImages = []struct {
+	name string
+	size int
+	rel  string
+}{
+	{"favicon-16x16.png", 16, "icon"},
+	{"favicon-32x32.png", 32, "icon"},
+	{"apple-touch-icon.png", 180, "apple-touch-icon"},
+	{"icon-192x192.png", 192, "manifest"},
+	{"icon-512x512.png", 512, "manifest"},
+}
+
+// faviconICOSizes are the sizes held in FaviconICO
+var faviconICOSizes = []int{16, 32, 48}
+
+var ErrInvalidFavicon = errors.New("invalid favicon image")
+
+// FaviconIcon is an icon of a favicon set as linked from a page head, or,
+// with Rel "manifest", listed in the web app manifest
+type FaviconIcon struct {
+	Rel   string `json:"rel"`
+	Href  string `json:"href"`
+	Sizes string `json:"sizes"`
+	Type  string `json:"type"`
+}
+
+// DecodeFaviconSource reads a PNG, JPEG or GIF image to make a favicon
+// set from
+func DecodeFaviconSource(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: use a PNG, JPEG or GIF image", ErrInvalidFavicon)
+	}
+	if config.Width < minFaviconSource || config.Height < minFaviconSource {
+		return nil, fmt.Errorf("%w: the image must be at least %dx%d pixels", ErrInvalidFavicon, minFaviconSource, minFaviconSource)
+	}
+	if config.Width*config.Height > maxFaviconSourcePixels {
+		return nil, fmt.Errorf("%w: the image is too large", ErrInvalidFavicon)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFavicon, err)
+	}
+	return img, nil
+}
+
+// WriteFaviconSet writes the favicon set made from src into dir. Images
+// that are not square are centered on a transparent square.
+func WriteFaviconSet(src image.Image, dir string) error {
+	side := max(src.Bounds().Dx(), src.Bounds().Dy())
+	square := image.NewNRGBA(image.Rect(0, 0, side, side))
+	target := imaging.Fit(src.Bounds(), square.Bounds())
+	draw.Draw(square, target, src, src.Bounds().Min, draw.Src)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, icon := range faviconImages {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, imaging.Resize(square, icon.size, icon.size)); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, icon.name), buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	sizes := make([]image.Image, len(faviconICOSizes))
+	for i, size := range faviconICOSizes {
+		sizes[i] = imaging.Resize(square, size, size)
+	}
+	ico, err := imaging.ICO(sizes...)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, FaviconICO), ico, 0644)
+}
+
+// FaviconSet lists the icons of the favicon set at setURL, the address of
+// the folder WriteFaviconSet wrote; favicon.ico is linked through the
+// site's favicon URL instead
+func FaviconSet(setURL string) []FaviconIcon {
+	icons := []FaviconIcon{}
+	if setURL == "" {
+		return icons
+	}
+	for _, icon := range faviconImages {
+		icons = append(icons, FaviconIcon{
+			Rel:   icon.rel,
+			Href:  strings.TrimRight(setURL, "/") + "/" + icon.name,
+			Sizes: fmt.Sprintf("%dx%d", icon.size, icon.size),
+			Type:  "image/png",
+		})
+	}
+	return icons
+}
+
+// WebManifest returns the web app manifest of a site, with the manifest
+// icons of its favicon set
+func WebManifest(settings *models.SiteSettings) map[string]interface{} {
+	icons := []map[string]string{}
+	for _, icon := range FaviconSet(settings.FaviconSetURL) {
+		if icon.Rel == "manifest" {
+			icons = append(icons, map[string]string{"src": icon.Href, "sizes": icon.Sizes, "type": icon.Type})
+		}
+	}
+	return map[string]interface{}{
+		"name":        settings.SiteTitle,
+		"short_name":  settings.SiteTitle,
+		"description": settings.SiteSubtitle,
+		"lang":        settings.DefaultLanguage,
+		"start_url":   "/",
+		"display":     "standalone",
+		"icons":       icons,
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFaviconSet(t *testing.T) {
+	// A wide red image, centered on a transparent square in the icons
+	src := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			src.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, src)
+	decoded, err := DecodeFaviconSource(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "favicons_1")
+	if err := WriteFaviconSet(decoded, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, icon := range faviconImages {
+		file, err := os.Open(filepath.Join(dir, icon.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", icon.name, err)
+		}
+		if img.Bounds().Dx() != icon.size || img.Bounds().Dy() != icon.size {
+			t.Errorf("%s is %v", icon.name, img.Bounds())
+		}
+		if _, _, _, a := img.At(icon.size/2, 0).RGBA(); a != 0 {
+			t.Errorf("%s is not padded at the top", icon.name)
+		}
+		if r, _, _, a := img.At(icon.size/2, icon.size/2).RGBA(); r != 0xffff || a != 0xffff {
+			t.Errorf("%s is not red in the middle", icon.name)
+		}
+	}
+
+	ico, err := os.ReadFile(filepath.Join(dir, FaviconICO))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header [3]uint16
+	binary.Read(bytes.NewReader(ico), binary.LittleEndian, &header)
+	if header != [3]uint16{0, 1, uint16(len(faviconICOSizes))} {
+		t.Errorf("icon header = %v", header)
+	}
+	// Each directory entry points at a PNG of its size
+	for i, size := range faviconICOSizes {
+		entry := ico[6+16*i:]
+		length, offset := binary.LittleEndian.Uint32(entry[8:]), binary.LittleEndian.Uint32(entry[12:])
+		img, err := png.Decode(bytes.NewReader(ico[offset : offset+length]))
+		if int(entry[0]) != size || err != nil || img.Bounds().Dx() != size {
+			t.Errorf("icon entry %d: width %d, %v", i, entry[0], err)
+		}
+	}
+
+	icons := FaviconSet("/uploads/branding/favicons_1")
+	if len(icons) != len(faviconImages) || icons[2].Rel != "apple-touch-icon" || icons[2].Href != "/uploads/branding/favicons_1/apple-touch-icon.png" {
+		t.Errorf("icons = %+v", icons)
+	}
+	manifest := WebManifest(&models.SiteSettings{SiteTitle: "Blog", FaviconSetURL: "/uploads/branding/favicons_1"})
+	if manifestIcons := manifest["icons"].([]map[string]string); len(manifestIcons) != 2 || manifestIcons[1]["sizes"] != "512x512" {
+		t.Errorf("manifest icons = %v", manifestIcons)
+	}
+
+	small := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	buf.Reset()
+	png.Encode(&buf, small)
+	if _, err := DecodeFaviconSource(&buf); !errors.Is(err, ErrInvalidFavicon) {
+		t.Errorf("16 pixel source = %v, want ErrInvalidFavicon", err)
+	}
+	if _, err := DecodeFaviconSource(bytes.NewReader([]byte("<svg></svg>"))); !errors.Is(err, ErrInvalidFavicon) {
+		t.Errorf("SVG source = %v, want ErrInvalidFavicon", err)
+	}
+}
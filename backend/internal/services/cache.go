@@ -1,6 +1,7 @@
 package services
 
 import (
+	"blog-backend/internal/cache"
 	"blog-backend/internal/database"
 	"blog-backend/internal/models"
 	"crypto/sha256"
@@ -8,174 +9,13 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"runtime"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-// CacheItem represents a cached item with TTL
-type CacheItem struct {
-	Value       interface{}
-	ExpiresAt   time.Time
-	AccessCount int64
-	CreatedAt   time.Time
-}
-
-// MemoryCache represents thread-safe in-memory cache
-type MemoryCache struct {
-	mu        sync.RWMutex
-	items     map[string]*CacheItem
-	maxSize   int
-	ttl       time.Duration
-	hitCount  int64
-	missCount int64
-}
-
-// NewMemoryCache creates a new memory cache
-func NewMemoryCache(maxSize int, ttl time.Duration) *MemoryCache {
-	cache := &MemoryCache{
-		items:   make(map[string]*CacheItem),
-		maxSize: maxSize,
-		ttl:     ttl,
-	}
-
-	// Start cleanup goroutine
-	go cache.cleanup()
-
-	return cache
-}
-
-// Get retrieves a value from cache
-func (mc *MemoryCache) Get(key string) (interface{}, bool) {
-	mc.mu.RLock()
-	defer mc.mu.RUnlock()
-
-	item, exists := mc.items[key]
-	if !exists {
-		mc.missCount++
-		return nil, false
-	}
-
-	// Check if expired
-	if time.Now().After(item.ExpiresAt) {
-		delete(mc.items, key)
-		mc.missCount++
-		return nil, false
-	}
-
-	item.AccessCount++
-	mc.hitCount++
-	return item.Value, true
-}
-
-// Set stores a value in cache
-func (mc *MemoryCache) Set(key string, value interface{}) {
-	mc.mu.Lock()
-	defer mc.mu.Unlock()
-
-	// Check if cache is full and evict LRU if necessary
-	if len(mc.items) >= mc.maxSize {
-		mc.evictLRU()
-	}
-
-	mc.items[key] = &CacheItem{
-		Value:       value,
-		ExpiresAt:   time.Now().Add(mc.ttl),
-		AccessCount: 1,
-		CreatedAt:   time.Now(),
-	}
-}
-
-// Delete removes a value from cache
-func (mc *MemoryCache) Delete(key string) {
-	mc.mu.Lock()
-	defer mc.mu.Unlock()
-	delete(mc.items, key)
-}
-
-// Clear removes all items from cache
-func (mc *MemoryCache) Clear() {
-	mc.mu.Lock()
-	defer mc.mu.Unlock()
-	mc.items = make(map[string]*CacheItem)
-}
-
-// Stats returns cache statistics
-func (mc *MemoryCache) Stats() map[string]interface{} {
-	mc.mu.RLock()
-	defer mc.mu.RUnlock()
-
-	totalRequests := mc.hitCount + mc.missCount
-	hitRate := float64(0)
-	if totalRequests > 0 {
-		hitRate = float64(mc.hitCount) / float64(totalRequests)
-	}
-
-	return map[string]interface{}{
-		"size":       len(mc.items),
-		"max_size":   mc.maxSize,
-		"hit_count":  mc.hitCount,
-		"miss_count": mc.missCount,
-		"hit_rate":   hitRate,
-	}
-}
-
-// evictLRU removes least recently used item
-func (mc *MemoryCache) evictLRU() {
-	if len(mc.items) == 0 {
-		return
-	}
-
-	var lruKey string
-	var minAccessCount int64 = -1
-	var oldestTime time.Time
-
-	for key, item := range mc.items {
-		if minAccessCount == -1 || item.AccessCount < minAccessCount ||
-			(item.AccessCount == minAccessCount && item.CreatedAt.Before(oldestTime)) {
-			minAccessCount = item.AccessCount
-			oldestTime = item.CreatedAt
-			lruKey = key
-		}
-	}
-
-	if lruKey != "" {
-		delete(mc.items, lruKey)
-	}
-}
-
-// cleanup removes expired items periodically
-func (mc *MemoryCache) cleanup() {
-	ticker := time.NewTicker(time.Minute * 5)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		mc.mu.Lock()
-		now := time.Now()
-		expiredKeys := make([]string, 0)
-
-		for key, item := range mc.items {
-			if now.After(item.ExpiresAt) {
-				expiredKeys = append(expiredKeys, key)
-			}
-		}
-
-		for _, key := range expiredKeys {
-			delete(mc.items, key)
-		}
-
-		// Force GC if memory usage is high
-		if len(expiredKeys) > 100 {
-			runtime.GC()
-		}
-
-		mc.mu.Unlock()
-	}
-}
-
 // SQLiteCache represents persistent cache using SQLite
 type SQLiteCache struct {
 	db *gorm.DB
@@ -188,8 +28,8 @@ func NewSQLiteCache() *SQLiteCache {
 	}
 }
 
-// Get retrieves a value from SQLite cache
-func (sc *SQLiteCache) Get(key string) (interface{}, bool) {
+// Get retrieves the JSON-encoded value from SQLite cache
+func (sc *SQLiteCache) Get(key string) (json.RawMessage, bool) {
 	var cache models.SearchCache
 	result := sc.db.Where("cache_key = ? AND (expires_at IS NULL OR expires_at > ?)",
 		key, time.Now()).First(&cache)
@@ -201,14 +41,7 @@ func (sc *SQLiteCache) Get(key string) (interface{}, bool) {
 	// Update access count
 	sc.db.Model(&cache).UpdateColumn("access_count", gorm.Expr("access_count + 1"))
 
-	// Parse JSON value
-	var value interface{}
-	if err := json.Unmarshal([]byte(cache.CacheValue), &value); err != nil {
-		log.Printf("Failed to unmarshal cache value: %v", err)
-		return nil, false
-	}
-
-	return value, true
+	return json.RawMessage(cache.CacheValue), true
 }
 
 // Set stores a value in SQLite cache
@@ -255,6 +88,27 @@ func (sc *SQLiteCache) Delete(key string) error {
 	return sc.db.Where("cache_key = ?", key).Delete(&models.SearchCache{}).Error
 }
 
+// DeletePrefix removes every value whose key starts with prefix
+func (sc *SQLiteCache) DeletePrefix(prefix string) error {
+	if prefix == "" {
+		return sc.db.Where("1 = 1").Delete(&models.SearchCache{}).Error
+	}
+	// substr rather than LIKE, where the underscores in keys are wildcards
+	return sc.db.Where("substr(cache_key, 1, ?) = ?", len(prefix), prefix).
+		Delete(&models.SearchCache{}).Error
+}
+
+// Usage measures the unexpired values whose key starts with prefix
+func (sc *SQLiteCache) Usage(prefix string) (cache.Usage, error) {
+	var usage cache.Usage
+	err := sc.db.Model(&models.SearchCache{}).
+		Select("COUNT(*) AS entries, COALESCE(SUM(LENGTH(cache_key) + LENGTH(cache_value)), 0) AS bytes").
+		Where("substr(cache_key, 1, ?) = ?", len(prefix), prefix).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Scan(&usage).Error
+	return usage, err
+}
+
 // Cleanup removes expired and least used items
 func (sc *SQLiteCache) Cleanup(maxItems int) error {
 	// Remove expired items
@@ -286,237 +140,247 @@ func (sc *SQLiteCache) Cleanup(maxItems int) error {
 	return nil
 }
 
-// SmartCache combines memory and SQLite caching with precomputation
+// SmartCache layers the shared cache (memory or Redis, see internal/cache)
+// over a persistent tier in the database. Both tiers are shared by every
+// instance, so replicas behind a load balancer see the same results. Values
+// come back JSON-decoded into the caller's type whichever tier they were
+// found in.
 type SmartCache struct {
-	memoryCache     *MemoryCache
-	sqliteCache     *SQLiteCache
-	precomputeCache *PrecomputeCache
-	config          CacheConfig
+	shared      *cache.Namespace
+	sqliteCache *SQLiteCache
+	config      CacheConfig
 }
 
 // CacheConfig holds cache configuration
 type CacheConfig struct {
-	MaxMemoryItems    int           `json:"max_memory_items"`
-	MemoryTTL         time.Duration `json:"memory_ttl"`
-	MaxSQLiteItems    int           `json:"max_sqlite_items"`
-	SQLiteTTL         time.Duration `json:"sqlite_ttl"`
-	PrecomputeEnabled bool          `json:"precompute_enabled"`
-	CleanupInterval   time.Duration `json:"cleanup_interval"`
+	MemoryTTL       time.Duration `json:"memory_ttl"`
+	MaxSQLiteItems  int           `json:"max_sqlite_items"`
+	SQLiteTTL       time.Duration `json:"sqlite_ttl"`
+	CleanupInterval time.Duration `json:"cleanup_interval"`
 }
 
 // DefaultCacheConfig returns default cache configuration
 func DefaultCacheConfig() CacheConfig {
 	return CacheConfig{
-		MaxMemoryItems:    1000,
-		MemoryTTL:         time.Hour,
-		MaxSQLiteItems:    10000,
-		SQLiteTTL:         time.Hour * 24,
-		PrecomputeEnabled: true,
-		CleanupInterval:   time.Hour,
+		MemoryTTL:       time.Hour,
+		MaxSQLiteItems:  10000,
+		SQLiteTTL:       time.Hour * 24,
+		CleanupInterval: time.Hour,
 	}
 }
 
 // NewSmartCache creates a new smart cache system
 func NewSmartCache(config CacheConfig) *SmartCache {
-	cache := &SmartCache{
-		memoryCache:     NewMemoryCache(config.MaxMemoryItems, config.MemoryTTL),
-		sqliteCache:     NewSQLiteCache(),
-		precomputeCache: NewPrecomputeCache(),
-		config:          config,
+	sc := &SmartCache{
+		shared:      cache.New("smart", config.MemoryTTL),
+		sqliteCache: NewSQLiteCache(),
+		config:      config,
 	}
 
 	// Start background cleanup
-	go cache.backgroundCleanup()
+	go sc.backgroundCleanup()
 
-	return cache
+	return sc
 }
 
-// Get retrieves a value using three-tier strategy
-func (sc *SmartCache) Get(key string) (interface{}, bool) {
-	// 1. Try memory cache first
-	if value, exists := sc.memoryCache.Get(key); exists {
-		return value, true
+// Get decodes the value stored under key into dest using a two-tier
+// lookup and reports whether it was found
+func (sc *SmartCache) Get(key string, dest interface{}) bool {
+	found := sc.get(key, dest)
+	if group := smartCacheGroupOf(key); group != nil {
+		if found {
+			group.hits.Add(1)
+		} else {
+			group.misses.Add(1)
+		}
 	}
+	return found
+}
 
-	// 2. Try SQLite cache
-	if value, exists := sc.sqliteCache.Get(key); exists {
-		// Promote to memory cache
-		sc.memoryCache.Set(key, value)
-		return value, true
+func (sc *SmartCache) get(key string, dest interface{}) bool {
+	// 1. Try the shared cache first
+	if sc.shared.Get(key, dest) {
+		return true
 	}
 
-	// 3. Try precompute cache
-	if value, exists := sc.precomputeCache.Get(key); exists {
-		// Store in both caches
-		sc.memoryCache.Set(key, value)
-		sc.sqliteCache.Set(key, value, &sc.config.SQLiteTTL)
-		return value, true
+	// 2. Try SQLite cache
+	if data, exists := sc.sqliteCache.Get(key); exists {
+		if err := json.Unmarshal(data, dest); err == nil {
+			// Promote to the shared cache
+			sc.shared.Set(key, data)
+			return true
+		}
 	}
 
-	return nil, false
+	return false
 }
 
 // Set stores a value in appropriate cache tiers
 func (sc *SmartCache) Set(key string, value interface{}) {
-	// Store in memory cache
-	sc.memoryCache.Set(key, value)
-
-	// Store in SQLite cache
+	sc.shared.Set(key, value)
 	sc.sqliteCache.Set(key, value, &sc.config.SQLiteTTL)
 }
 
+// SetWithTTL stores a value in both tiers, keeping it in SQLite for ttl
+// instead of the default
+func (sc *SmartCache) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	sc.shared.Set(key, value)
+	return sc.sqliteCache.Set(key, value, &ttl)
+}
+
 // Delete removes a value from all cache tiers
 func (sc *SmartCache) Delete(key string) {
-	sc.memoryCache.Delete(key)
+	sc.shared.Delete(key)
 	sc.sqliteCache.Delete(key)
-	sc.precomputeCache.Delete(key)
 }
 
-// InvalidatePattern removes all keys matching a pattern
-func (sc *SmartCache) InvalidatePattern(pattern string) {
-	// For simplicity, we'll clear memory cache and mark SQLite items for cleanup
-	// In a production system, you'd implement proper pattern matching
-	if strings.Contains(pattern, "*") {
-		sc.memoryCache.Clear()
-		// Schedule SQLite cleanup
-		go func() {
-			// Remove items matching pattern from SQLite
-			// This is a simplified implementation
-		}()
+// InvalidatePrefix removes every key starting with one of the prefixes
+// from all cache tiers
+func (sc *SmartCache) InvalidatePrefix(prefixes ...string) {
+	for _, prefix := range prefixes {
+		sc.shared.DeletePrefix(prefix)
+		if err := sc.sqliteCache.DeletePrefix(prefix); err != nil {
+			log.Printf("Failed to invalidate SQLite cache entries with prefix %q: %v", prefix, err)
+		}
 	}
 }
 
+// InvalidatePattern removes all keys matching a pattern. Only trailing
+// wildcards are supported: "search_*" clears every search result and "*"
+// clears everything.
+func (sc *SmartCache) InvalidatePattern(pattern string) {
+	sc.InvalidatePrefix(strings.TrimSuffix(pattern, "*"))
+}
+
 // Stats returns comprehensive cache statistics
 func (sc *SmartCache) Stats() map[string]interface{} {
-	memStats := sc.memoryCache.Stats()
-
 	var sqliteCount int64
 	sc.sqliteCache.db.Model(&models.SearchCache{}).Count(&sqliteCount)
 
 	return map[string]interface{}{
-		"memory_cache": memStats,
+		"shared_cache": cache.Current().Stats(),
 		"sqlite_cache": map[string]interface{}{
 			"size":     sqliteCount,
 			"max_size": sc.config.MaxSQLiteItems,
 		},
-		"precompute_cache": sc.precomputeCache.Stats(),
-		"config":           sc.config,
-	}
-}
-
-// backgroundCleanup runs periodic maintenance tasks
-func (sc *SmartCache) backgroundCleanup() {
-	ticker := time.NewTicker(sc.config.CleanupInterval)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		// Cleanup SQLite cache
-		if err := sc.sqliteCache.Cleanup(sc.config.MaxSQLiteItems); err != nil {
-			log.Printf("SQLite cache cleanup failed: %v", err)
-		}
-
-		// Cleanup precompute cache
-		sc.precomputeCache.Cleanup()
-
-		// Force garbage collection if needed
-		runtime.GC()
+		"groups": sc.Groups(),
+		"config": sc.config,
 	}
 }
 
-// PrecomputeCache handles precomputed results
-type PrecomputeCache struct {
-	mu    sync.RWMutex
-	items map[string]PrecomputeItem
+// smartCacheGroup is a kind of result kept in the smart cache, told apart
+// by the prefixes of its keys. Hits and misses are counted by this
+// instance.
+type smartCacheGroup struct {
+	name     string
+	prefixes []string
+	hits     atomic.Int64
+	misses   atomic.Int64
 }
 
-// PrecomputeItem represents a precomputed cache item
-type PrecomputeItem struct {
-	Value       interface{}
-	ComputedAt  time.Time
-	AccessCount int64
+var smartCacheGroups = []*smartCacheGroup{
+	{name: "search", prefixes: []string{"search_"}},
+	{name: "recommendations", prefixes: []string{"recommend"}},
+	{name: "user_interests", prefixes: []string{"user_interests_"}},
+	{name: "content_assistant", prefixes: []string{"topic_gaps_", "writing_inspiration_", "smart_tags_", "seo_keywords_"}},
 }
 
-// NewPrecomputeCache creates a new precompute cache
-func NewPrecomputeCache() *PrecomputeCache {
-	return &PrecomputeCache{
-		items: make(map[string]PrecomputeItem),
+func smartCacheGroupOf(key string) *smartCacheGroup {
+	for _, group := range smartCacheGroups {
+		for _, prefix := range group.prefixes {
+			if strings.HasPrefix(key, prefix) {
+				return group
+			}
+		}
 	}
+	return nil
 }
 
-// Get retrieves a precomputed value
-func (pc *PrecomputeCache) Get(key string) (interface{}, bool) {
-	pc.mu.RLock()
-	defer pc.mu.RUnlock()
-
-	item, exists := pc.items[key]
-	if !exists {
-		return nil, false
+// SmartCacheGroupStats describes a group of the smart cache in both tiers
+type SmartCacheGroupStats struct {
+	Name       string      `json:"name"`
+	Shared     cache.Usage `json:"shared"`
+	Persistent cache.Usage `json:"persistent"`
+	Hits       int64       `json:"hits"`
+	Misses     int64       `json:"misses"`
+	HitRate    float64     `json:"hit_rate"`
+}
+
+// Groups describes the groups of results in the cache
+func (sc *SmartCache) Groups() []SmartCacheGroupStats {
+	stats := make([]SmartCacheGroupStats, 0, len(smartCacheGroups))
+	for _, group := range smartCacheGroups {
+		s := SmartCacheGroupStats{Name: group.name, Hits: group.hits.Load(), Misses: group.misses.Load()}
+		for _, prefix := range group.prefixes {
+			shared := sc.shared.Usage(prefix)
+			s.Shared.Entries += shared.Entries
+			s.Shared.Bytes += shared.Bytes
+			persistent, err := sc.sqliteCache.Usage(prefix)
+			if err != nil {
+				log.Printf("Failed to measure SQLite cache entries with prefix %q: %v", prefix, err)
+			}
+			s.Persistent.Entries += persistent.Entries
+			s.Persistent.Bytes += persistent.Bytes
+		}
+		if total := s.Hits + s.Misses; total > 0 {
+			s.HitRate = float64(s.Hits) / float64(total)
+		}
+		stats = append(stats, s)
 	}
-
-	item.AccessCount++
-	pc.items[key] = item
-	return item.Value, true
+	return stats
 }
 
-// Set stores a precomputed value
-func (pc *PrecomputeCache) Set(key string, value interface{}) {
-	pc.mu.Lock()
-	defer pc.mu.Unlock()
-
-	pc.items[key] = PrecomputeItem{
-		Value:       value,
-		ComputedAt:  time.Now(),
-		AccessCount: 0,
+// PurgeGroup removes the results of the named group from both tiers and
+// reports whether the group exists
+func (sc *SmartCache) PurgeGroup(name string) bool {
+	for _, group := range smartCacheGroups {
+		if group.name == name {
+			sc.InvalidatePrefix(group.prefixes...)
+			return true
+		}
 	}
+	return false
 }
 
-// Delete removes a precomputed value
-func (pc *PrecomputeCache) Delete(key string) {
-	pc.mu.Lock()
-	defer pc.mu.Unlock()
-	delete(pc.items, key)
-}
+// backgroundCleanup runs periodic maintenance tasks
+func (sc *SmartCache) backgroundCleanup() {
+	ticker := time.NewTicker(sc.config.CleanupInterval)
+	defer ticker.Stop()
 
-// Cleanup removes old precomputed items
-func (pc *PrecomputeCache) Cleanup() {
-	pc.mu.Lock()
-	defer pc.mu.Unlock()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ShuttingDown():
+			return
+		}
 
-	// Remove items older than 24 hours with low access count
-	cutoff := time.Now().Add(-24 * time.Hour)
-	for key, item := range pc.items {
-		if item.ComputedAt.Before(cutoff) && item.AccessCount < 5 {
-			delete(pc.items, key)
+		// Cleanup SQLite cache
+		if err := sc.sqliteCache.Cleanup(sc.config.MaxSQLiteItems); err != nil {
+			log.Printf("SQLite cache cleanup failed: %v", err)
 		}
 	}
 }
 
-// Stats returns precompute cache statistics
-func (pc *PrecomputeCache) Stats() map[string]interface{} {
-	pc.mu.RLock()
-	defer pc.mu.RUnlock()
-
-	totalAccess := int64(0)
-	for _, item := range pc.items {
-		totalAccess += item.AccessCount
-	}
-
-	return map[string]interface{}{
-		"size":         len(pc.items),
-		"total_access": totalAccess,
-	}
+// GetGlobalCache returns the cache of the global container
+func GetGlobalCache() *SmartCache {
+	return GetGlobalContainer().Cache
 }
 
-// Global cache instance
-var globalSmartCache *SmartCache
-var cacheInitOnce sync.Once
+// Key prefixes of cached results derived from articles
+var articleCachePrefixes = []string{
+	"search_",
+	"recommend", // recommend_ and recommendations_
+	"user_interests_",
+	"topic_gaps_",
+	"writing_inspiration_",
+}
 
-// GetGlobalCache returns the global cache instance
-func GetGlobalCache() *SmartCache {
-	cacheInitOnce.Do(func() {
-		globalSmartCache = NewSmartCache(DefaultCacheConfig())
-	})
-	return globalSmartCache
+func init() {
+	invalidateArticleCaches := func() {
+		GetGlobalCache().InvalidatePrefix(articleCachePrefixes...)
+	}
+	cache.Subscribe(cache.TopicArticles, invalidateArticleCaches)
+	cache.Subscribe(cache.TopicCategories, invalidateArticleCaches)
 }
 
 // Cache key generators
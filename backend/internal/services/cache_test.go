@@ -0,0 +1,86 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"testing"
+)
+
+func TestSmartCacheInvalidatePrefix(t *testing.T) {
+	newTestDB(t)
+	sc := NewSmartCache(DefaultCacheConfig())
+	defer sc.InvalidatePattern("*")
+
+	sc.Set("search_abc", []string{"a"})
+	sc.Set("searchXabc", []string{"b"}) // underscore must not act as a wildcard
+	sc.Set("stats_views", 42)
+
+	sc.InvalidatePrefix("search_")
+
+	var results []string
+	if sc.Get("search_abc", &results) {
+		t.Error("search_abc survived invalidation")
+	}
+	if !sc.Get("searchXabc", &results) || results[0] != "b" {
+		t.Error("searchXabc should not match the search_ prefix")
+	}
+	var views int
+	if !sc.Get("stats_views", &views) || views != 42 {
+		t.Error("unrelated entry was invalidated")
+	}
+}
+
+func TestSmartCacheReadsPersistentTier(t *testing.T) {
+	newTestDB(t)
+	sc := NewSmartCache(DefaultCacheConfig())
+	defer sc.InvalidatePattern("*")
+
+	type result struct {
+		ID    uint    `json:"id"`
+		Score float64 `json:"score"`
+	}
+	sc.Set("recommend_1", []result{{ID: 1, Score: 0.9}})
+
+	// Simulate a restart: the shared tier is empty, SQLite still has the entry
+	cache.New("smart", 0).Clear()
+
+	var got []result
+	if !sc.Get("recommend_1", &got) || len(got) != 1 || got[0].ID != 1 {
+		t.Fatalf("Get from SQLite tier = %+v", got)
+	}
+}
+
+func TestSmartCacheGroups(t *testing.T) {
+	newTestDB(t)
+	sc := NewSmartCache(DefaultCacheConfig())
+	defer sc.InvalidatePattern("*")
+
+	sc.Set("search_abc", []string{"a"})
+	sc.Set("recommendations_u1_en_5_true", []uint{1})
+	var results []string
+	sc.Get("search_abc", &results)
+	sc.Get("search_missing", &results)
+
+	groups := map[string]SmartCacheGroupStats{}
+	for _, group := range sc.Groups() {
+		groups[group.Name] = group
+	}
+	search := groups["search"]
+	if search.Shared.Entries != 1 || search.Persistent.Entries != 1 || search.Persistent.Bytes == 0 ||
+		search.Hits < 1 || search.Misses < 1 || search.HitRate == 0 {
+		t.Errorf("search = %+v", search)
+	}
+
+	if sc.PurgeGroup("nothing") {
+		t.Error("purged an unknown group")
+	}
+	if !sc.PurgeGroup("search") {
+		t.Fatal("search group not purged")
+	}
+	if sc.Get("search_abc", &results) {
+		t.Error("search_abc survived the purge")
+	}
+	var ids []uint
+	if !sc.Get("recommendations_u1_en_5_true", &ids) {
+		t.Error("purging search removed recommendations")
+	}
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCategory  = errors.New("invalid category")
+	ErrCategoryNotFound = errors.New("category not found")
+)
+
+// CategoryNode is a category in the category tree. ArticleCount counts its
+// own published articles and TotalArticleCount adds those of the
+// categories below it.
+type CategoryNode struct {
+	ID                uint            `json:"id"`
+	Name              string          `json:"name"`
+	Description       string          `json:"description"`
+	ParentID          *uint           `json:"parent_id"`
+	ArticleCount      int64           `json:"article_count"`
+	TotalArticleCount int64           `json:"total_article_count"`
+	Children          []*CategoryNode `json:"children"`
+}
+
+// CategoryService nests categories under parent categories. A category
+// whose parent was deleted is shown at the top of the tree.
+type CategoryService struct {
+	db  func() *gorm.DB
+	now func() time.Time
+}
+
+// CategoryMerge reports what merging the source category into the target
+// moves, or would move on a dry run. The article translations and
+// embeddings follow their articles.
+type CategoryMerge struct {
+	Source        models.CategoryCrumb `json:"source"`
+	Target        models.CategoryCrumb `json:"target"`
+	Articles      int64                `json:"articles"`
+	Subcategories int64                `json:"subcategories"`
+	SEOTemplates  int64                `json:"seo_templates"`
+	HomepageRows  int                  `json:"homepage_rows"`
+	DryRun        bool                 `json:"dry_run"`
+}
+
+// NewCategoryService creates a category service
+func NewCategoryService() *CategoryService {
+	return &CategoryService{
+		db:  func() *gorm.DB { return database.DB },
+		now: time.Now,
+	}
+}
+
+// Tree returns the site's categories as a tree, named in lang where
+// translated and sorted by name, with their article counts. Scheduled
+// articles are counted only with includeScheduled.
+func (s *CategoryService) Tree(ctx context.Context, lang string, includeScheduled bool) ([]*CategoryNode, error) {
+	var categories []models.Category
+	if err := s.db().WithContext(ctx).Preload("Translations").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		CategoryID uint
+		Count      int64
+	}
+	query := s.db().WithContext(ctx).Model(&models.Article{}).Select("category_id, COUNT(*) AS count").Group("category_id")
+	if !includeScheduled {
+		query = query.Where("created_at <= ?", s.now())
+	}
+	if err := query.Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[uint]*CategoryNode, len(categories))
+	for i := range categories {
+		category := &categories[i]
+		if lang != "" {
+			ApplyCategoryTranslation(category, lang)
+		}
+		nodes[category.ID] = &CategoryNode{
+			ID:          category.ID,
+			Name:        category.Name,
+			Description: category.Description,
+			ParentID:    category.ParentID,
+			Children:    []*CategoryNode{},
+		}
+	}
+	for _, count := range counts {
+		if node, ok := nodes[count.CategoryID]; ok {
+			node.ArticleCount = count.Count
+		}
+	}
+	roots := []*CategoryNode{}
+	for _, node := range nodes {
+		if node.ParentID != nil {
+			if parent, ok := nodes[*node.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	sortCategoryNodes(roots)
+	return roots, nil
+}
+
+// Path returns the category and the categories above it, top first, named
+// in lang where translated
+func (s *CategoryService) Path(ctx context.Context, categoryID uint, lang string) ([]models.CategoryCrumb, error) {
+	categories, err := s.categories(ctx, lang != "")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := categories[categoryID]; !ok {
+		return nil, ErrCategoryNotFound
+	}
+	var path []models.CategoryCrumb
+	for _, id := range ancestry(categories, categoryID) {
+		category := categories[id]
+		if lang != "" {
+			ApplyCategoryTranslation(category, lang)
+		}
+		path = append([]models.CategoryCrumb{{ID: category.ID, Name: category.Name}}, path...)
+	}
+	return path, nil
+}
+
+// Descendants returns the IDs of the category and of the categories below
+// it
+func (s *CategoryService) Descendants(ctx context.Context, categoryID uint) ([]uint, error) {
+	categories, err := s.categories(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := categories[categoryID]; !ok {
+		return nil, ErrCategoryNotFound
+	}
+	children := map[uint][]uint{}
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+	ids := []uint{categoryID}
+	seen := map[uint]bool{categoryID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// ValidateParent checks that the category with the ID, 0 for a new one, can
+// be put under the parent: the parent must be another category of the site
+// and not one below the category, which would make a cycle
+func (s *CategoryService) ValidateParent(ctx context.Context, categoryID uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == categoryID {
+		return fmt.Errorf("%w: a category cannot be its own parent", ErrInvalidCategory)
+	}
+	categories, err := s.categories(ctx, false)
+	if err != nil {
+		return err
+	}
+	if _, ok := categories[*parentID]; !ok {
+		return fmt.Errorf("%w: parent_id must be a category of the site", ErrInvalidCategory)
+	}
+	for _, id := range ancestry(categories, *parentID) {
+		if id == categoryID {
+			return fmt.Errorf("%w: a category cannot be put under one of its subcategories", ErrInvalidCategory)
+		}
+	}
+	return nil
+}
+
+// Delete deletes a category, moving its subcategories up to its parent
+func (s *CategoryService) Delete(ctx context.Context, categoryID uint) error {
+	err := s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		if err := tx.First(&category, categoryID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCategoryNotFound
+			}
+			return err
+		}
+		err := tx.Model(&models.Category{}).Where("parent_id = ?", category.ID).
+			Update("parent_id", category.ParentID).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&category).Error
+	})
+	if err != nil {
+		return err
+	}
+	cache.Publish(cache.TopicCategories)
+	return nil
+}
+
+// Merge moves the articles, subcategories, SEO templates and homepage rows
+// of the source category to the target and deletes the source. With dryRun
+// nothing changes and the result previews the merge.
+func (s *CategoryService) Merge(ctx context.Context, sourceID, targetID uint, dryRun bool) (*CategoryMerge, error) {
+	if sourceID == targetID {
+		return nil, fmt.Errorf("%w: a category cannot be merged into itself", ErrInvalidCategory)
+	}
+	categories, err := s.categories(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	source, target := categories[sourceID], categories[targetID]
+	if source == nil || target == nil {
+		return nil, ErrCategoryNotFound
+	}
+	for _, id := range ancestry(categories, targetID) {
+		if id == sourceID {
+			return nil, fmt.Errorf("%w: a category cannot be merged into one of its subcategories", ErrInvalidCategory)
+		}
+	}
+	merge := &CategoryMerge{
+		Source: models.CategoryCrumb{ID: source.ID, Name: source.Name},
+		Target: models.CategoryCrumb{ID: target.ID, Name: target.Name},
+		DryRun: dryRun,
+	}
+
+	err = s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		moves := []struct {
+			model  interface{}
+			column string
+			count  *int64
+		}{
+			{&models.Article{}, "category_id", &merge.Articles},
+			{&models.Category{}, "parent_id", &merge.Subcategories},
+			{&models.SEOTemplate{}, "category_id", &merge.SEOTemplates},
+		}
+		for _, move := range moves {
+			query := tx.Model(move.model).Where(move.column+" = ?", sourceID)
+			if dryRun {
+				if err := query.Count(move.count).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			result := query.Update(move.column, targetID)
+			if result.Error != nil {
+				return result.Error
+			}
+			*move.count = result.RowsAffected
+		}
+		rows, err := repointCategoryRows(tx, sourceID, targetID, !dryRun)
+		if err != nil {
+			return err
+		}
+		merge.HomepageRows = rows
+		if dryRun {
+			return nil
+		}
+		if err := tx.Where("category_id = ?", sourceID).Delete(&models.CategoryTranslation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Category{}, sourceID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		cache.Publish(cache.TopicArticles, cache.TopicCategories, cache.TopicSettings)
+	}
+	return merge, nil
+}
+
+// categories returns the site's categories by ID, with their translations
+// when asked for
+func (s *CategoryService) categories(ctx context.Context, translations bool) (map[uint]*models.Category, error) {
+	query := s.db().WithContext(ctx)
+	if translations {
+		query = query.Preload("Translations")
+	}
+	var list []models.Category
+	if err := query.Find(&list).Error; err != nil {
+		return nil, err
+	}
+	categories := make(map[uint]*models.Category, len(list))
+	for i := range list {
+		categories[list[i].ID] = &list[i]
+	}
+	return categories, nil
+}
+
+// ancestry returns the category and the categories above it, nearest
+// first. It stops at a missing parent or, should the data hold one, a cycle.
+func ancestry(categories map[uint]*models.Category, categoryID uint) []uint {
+	var ids []uint
+	seen := map[uint]bool{}
+	for category, ok := categories[categoryID]; ok && !seen[category.ID]; {
+		seen[category.ID] = true
+		ids = append(ids, category.ID)
+		if category.ParentID == nil {
+			break
+		}
+		category, ok = categories[*category.ParentID]
+	}
+	return ids
+}
+
+// sortCategoryNodes sorts the nodes by name, sums the article counts of
+// their subtrees and does the same for their children
+func sortCategoryNodes(nodes []*CategoryNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Name != nodes[j].Name {
+			return nodes[i].Name < nodes[j].Name
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+	for _, node := range nodes {
+		sortCategoryNodes(node.Children)
+		node.TotalArticleCount = node.ArticleCount
+		for _, child := range node.Children {
+			node.TotalArticleCount += child.TotalArticleCount
+		}
+	}
+}
+
+// ApplyCategoryTranslation names a category in lang where translated,
+// whether or not its translations were preloaded
+func ApplyCategoryTranslation(category *models.Category, lang string) {
+	GetGlobalCategoryTranslator().Apply(category, lang)
+}
+
+var (
+	globalCategoryService     *CategoryService
+	globalCategoryServiceOnce sync.Once
+)
+
+// GetGlobalCategoryService returns the global category service
+func GetGlobalCategoryService() *CategoryService {
+	globalCategoryServiceOnce.Do(func() {
+		globalCategoryService = NewCategoryService()
+	})
+	return globalCategoryService
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCategoryTree(t *testing.T) {
+	newTestDB(t)
+	if err := database.DB.Use(database.SiteScope{}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewCategoryService()
+	ctx := database.WithSite(context.Background(), models.DefaultSiteID)
+
+	tech := models.Category{Name: "Tech", Translations: []models.CategoryTranslation{{Language: "zh", Name: "技术"}}}
+	if err := database.DB.WithContext(ctx).Create(&tech).Error; err != nil {
+		t.Fatal(err)
+	}
+	golang := models.Category{Name: "Go", ParentID: &tech.ID}
+	travel := models.Category{Name: "Travel"}
+	for _, category := range []*models.Category{&golang, &travel} {
+		if err := database.DB.WithContext(ctx).Create(category).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	generics := models.Category{Name: "Generics", ParentID: &golang.ID}
+	if err := database.DB.WithContext(ctx).Create(&generics).Error; err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, article := range []models.Article{
+		{Title: "Why Go", CategoryID: golang.ID, CreatedAt: now.Add(-time.Hour)},
+		{Title: "Type sets", CategoryID: generics.ID, CreatedAt: now.Add(-time.Hour)},
+		{Title: "Soon", CategoryID: generics.ID, CreatedAt: now.Add(time.Hour)},
+		{Title: "Kyoto", CategoryID: travel.ID, CreatedAt: now.Add(-time.Hour)},
+	} {
+		if err := database.DB.WithContext(ctx).Create(&article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tree, err := s.Tree(ctx, "zh", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree) != 2 || tree[0].Name != "Travel" || tree[1].Name != "技术" {
+		t.Fatalf("roots = %+v, want Travel and 技术", tree)
+	}
+	top := tree[1]
+	if top.ArticleCount != 0 || top.TotalArticleCount != 2 || len(top.Children) != 1 ||
+		top.Children[0].TotalArticleCount != 2 || top.Children[0].Children[0].ArticleCount != 1 {
+		t.Errorf("Tech subtree = %+v / %+v", top, top.Children[0])
+	}
+	if tree, _ := s.Tree(ctx, "", true); tree[0].TotalArticleCount != 3 {
+		t.Errorf("Tech with scheduled articles = %d, want 3", tree[0].TotalArticleCount)
+	}
+
+	path, err := s.Path(ctx, generics.ID, "zh")
+	if err != nil || len(path) != 3 || path[0].Name != "技术" || path[2].ID != generics.ID {
+		t.Errorf("Path() = %+v, %v", path, err)
+	}
+	ids, err := s.Descendants(ctx, tech.ID)
+	if err != nil || len(ids) != 3 {
+		t.Errorf("Descendants() = %v, %v", ids, err)
+	}
+
+	// A category cannot end up below itself
+	for _, parent := range []uint{tech.ID, generics.ID, 999} {
+		if err := s.ValidateParent(ctx, tech.ID, &parent); !errors.Is(err, ErrInvalidCategory) {
+			t.Errorf("ValidateParent(tech, %d) = %v, want ErrInvalidCategory", parent, err)
+		}
+	}
+	if err := s.ValidateParent(ctx, travel.ID, &generics.ID); err != nil {
+		t.Errorf("ValidateParent(travel, generics) = %v", err)
+	}
+	other := database.WithSite(context.Background(), 2)
+	if err := s.ValidateParent(other, 0, &tech.ID); !errors.Is(err, ErrInvalidCategory) {
+		t.Errorf("parent from another site = %v, want ErrInvalidCategory", err)
+	}
+
+	// Deleting a category moves its subcategories up
+	if err := s.Delete(ctx, golang.ID); err != nil {
+		t.Fatal(err)
+	}
+	if path, _ := s.Path(ctx, generics.ID, ""); len(path) != 2 || path[0].ID != tech.ID {
+		t.Errorf("Path() after deleting the parent = %+v", path)
+	}
+	if err := s.Delete(ctx, golang.ID); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("deleting again = %v, want ErrCategoryNotFound", err)
+	}
+}
+
+func TestCategoryMerge(t *testing.T) {
+	newTestDB(t)
+	if err := database.DB.Use(database.SiteScope{}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewCategoryService()
+	ctx := database.WithSite(context.Background(), models.DefaultSiteID)
+	db := database.DB.WithContext(ctx)
+
+	golang := models.Category{Name: "Go"}
+	golang2 := models.Category{Name: "Golang", Translations: []models.CategoryTranslation{{Language: "zh", Name: "Go 语言"}}}
+	for _, category := range []*models.Category{&golang, &golang2} {
+		if err := db.Create(category).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	child := models.Category{Name: "Tooling", ParentID: &golang2.ID}
+	if err := db.Create(&child).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range []interface{}{
+		&models.Article{Title: "One", CategoryID: golang2.ID},
+		&models.Article{Title: "Two", CategoryID: golang2.ID},
+		&models.Article{Title: "Three", CategoryID: golang.ID},
+		&models.SEOTemplate{Name: "Go posts", Type: "title", Language: "en", Template: "{title}", CategoryID: &golang2.ID},
+		&models.SiteSettings{HomepageLayout: fmt.Sprintf(`{"category_rows":[{"category_id":%d,"limit":3}]}`, golang2.ID)},
+	} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := s.Merge(ctx, golang2.ID, child.ID, true); !errors.Is(err, ErrInvalidCategory) {
+		t.Errorf("merging into a subcategory = %v, want ErrInvalidCategory", err)
+	}
+	if _, err := s.Merge(ctx, golang2.ID, 999, true); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("merging into a missing category = %v, want ErrCategoryNotFound", err)
+	}
+
+	want := CategoryMerge{
+		Source:        models.CategoryCrumb{ID: golang2.ID, Name: "Golang"},
+		Target:        models.CategoryCrumb{ID: golang.ID, Name: "Go"},
+		Articles:      2,
+		Subcategories: 1,
+		SEOTemplates:  1,
+		HomepageRows:  1,
+		DryRun:        true,
+	}
+	preview, err := s.Merge(ctx, golang2.ID, golang.ID, true)
+	if err != nil || *preview != want {
+		t.Fatalf("dry run = %+v, %v, want %+v", preview, err, want)
+	}
+	var moved int64
+	db.Model(&models.Article{}).Where("category_id = ?", golang.ID).Count(&moved)
+	if moved != 1 {
+		t.Fatalf("dry run moved articles: %d in the target", moved)
+	}
+
+	merge, err := s.Merge(ctx, golang2.ID, golang.ID, false)
+	want.DryRun = false
+	if err != nil || *merge != want {
+		t.Fatalf("Merge() = %+v, %v, want %+v", merge, err, want)
+	}
+	db.Model(&models.Article{}).Where("category_id = ?", golang.ID).Count(&moved)
+	if moved != 3 {
+		t.Errorf("target has %d articles, want 3", moved)
+	}
+	if path, _ := s.Path(ctx, child.ID, ""); len(path) != 2 || path[0].ID != golang.ID {
+		t.Errorf("subcategory path = %+v, want under Go", path)
+	}
+	layout, err := NewHomepageLayoutService().Get(ctx)
+	if err != nil || layout.CategoryRows[0].CategoryID != golang.ID {
+		t.Errorf("homepage rows = %+v, %v", layout, err)
+	}
+	if _, err := s.Path(ctx, golang2.ID, ""); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("source still there: %v", err)
+	}
+}
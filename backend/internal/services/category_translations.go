@@ -0,0 +1,125 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"log/slog"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// CategoryTranslator names categories in a language for every response
+// that shows them. Queries that preload Category.Translations are
+// translated from those; the many that preload only the category are
+// translated from a cache of all category translations, which is dropped
+// whenever categories change.
+type CategoryTranslator struct {
+	db func() *gorm.DB
+
+	mu         sync.RWMutex
+	byCategory map[uint]map[string]models.CategoryTranslation // nil until loaded
+}
+
+// NewCategoryTranslator creates a category translator
+func NewCategoryTranslator() *CategoryTranslator {
+	return &CategoryTranslator{db: func() *gorm.DB { return database.DB }}
+}
+
+// Apply replaces the category's name and description with their
+// translation in lang, where one exists. Empty translated fields keep the
+// original.
+func (t *CategoryTranslator) Apply(category *models.Category, lang string) {
+	if category == nil || category.ID == 0 || lang == "" {
+		return
+	}
+	translation, ok := t.lookup(category, lang)
+	if !ok {
+		return
+	}
+	if translation.Name != "" {
+		category.Name = translation.Name
+	}
+	if translation.Description != "" {
+		category.Description = translation.Description
+	}
+}
+
+// ApplyToArticles translates the category of each article
+func (t *CategoryTranslator) ApplyToArticles(articles []models.Article, lang string) {
+	for i := range articles {
+		t.Apply(&articles[i].Category, lang)
+	}
+}
+
+// lookup finds the translation of a category, in its preloaded
+// translations first
+func (t *CategoryTranslator) lookup(category *models.Category, lang string) (models.CategoryTranslation, bool) {
+	if len(category.Translations) > 0 {
+		for _, translation := range category.Translations {
+			if translation.Language == lang {
+				return translation, true
+			}
+		}
+		return models.CategoryTranslation{}, false
+	}
+	byCategory, err := t.current()
+	if err != nil {
+		// The category is shown untranslated; the next call retries
+		slog.Warn("Failed to load category translations", "error", err)
+		return models.CategoryTranslation{}, false
+	}
+	translation, ok := byCategory[category.ID][lang]
+	return translation, ok
+}
+
+// current returns the cached translations, loading them on first use
+func (t *CategoryTranslator) current() (map[uint]map[string]models.CategoryTranslation, error) {
+	t.mu.RLock()
+	byCategory := t.byCategory
+	t.mu.RUnlock()
+	if byCategory != nil {
+		return byCategory, nil
+	}
+	if t.db() == nil {
+		return nil, nil
+	}
+
+	var translations []models.CategoryTranslation
+	if err := t.db().Select("category_id", "language", "name", "description").Find(&translations).Error; err != nil {
+		return nil, err
+	}
+	byCategory = make(map[uint]map[string]models.CategoryTranslation)
+	for _, translation := range translations {
+		if byCategory[translation.CategoryID] == nil {
+			byCategory[translation.CategoryID] = make(map[string]models.CategoryTranslation)
+		}
+		byCategory[translation.CategoryID][translation.Language] = translation
+	}
+	t.mu.Lock()
+	t.byCategory = byCategory
+	t.mu.Unlock()
+	return byCategory, nil
+}
+
+func (t *CategoryTranslator) reset() {
+	t.mu.Lock()
+	t.byCategory = nil
+	t.mu.Unlock()
+}
+
+// Global category translator instance
+var (
+	globalCategoryTranslator     *CategoryTranslator
+	globalCategoryTranslatorOnce sync.Once
+)
+
+// GetGlobalCategoryTranslator returns the global category translator
+func GetGlobalCategoryTranslator() *CategoryTranslator {
+	globalCategoryTranslatorOnce.Do(func() {
+		globalCategoryTranslator = NewCategoryTranslator()
+		cache.Subscribe(cache.TopicCategories, globalCategoryTranslator.reset)
+	})
+	return globalCategoryTranslator
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"testing"
+)
+
+func TestCategoryTranslatorWithoutPreload(t *testing.T) {
+	newTestDB(t)
+	// Drop whatever an earlier test left in the shared cache
+	GetGlobalCategoryTranslator().reset()
+
+	category := models.Category{Name: "数据库", Description: "存储", Translations: []models.CategoryTranslation{
+		{Language: "en", Name: "Databases", Description: "Storage"},
+		{Language: "ja", Name: "データベース"},
+	}}
+	database.DB.Create(&category)
+	database.DB.Create(&models.Article{Title: "Indexes", CategoryID: category.ID})
+
+	// Like most article queries, only the category is preloaded
+	var articles []models.Article
+	database.DB.Preload("Category").Find(&articles)
+	GetGlobalCategoryTranslator().ApplyToArticles(articles, "en")
+	if got := articles[0].Category; got.Name != "Databases" || got.Description != "Storage" {
+		t.Errorf("en category = %q, %q", got.Name, got.Description)
+	}
+
+	// An empty translated field keeps the original, and a language without
+	// a translation leaves the category alone
+	ja := category
+	ja.Translations = nil
+	ApplyCategoryTranslation(&ja, "ja")
+	if ja.Name != "データベース" || ja.Description != "存储" {
+		t.Errorf("ja category = %q, %q", ja.Name, ja.Description)
+	}
+	fr := models.Category{ID: category.ID, Name: "数据库"}
+	ApplyCategoryTranslation(&fr, "fr")
+	if fr.Name != "数据库" {
+		t.Errorf("fr category = %q", fr.Name)
+	}
+
+	// A renamed translation shows once categories are published as changed
+	database.DB.Model(&models.CategoryTranslation{}).Where("language = ?", "en").Update("name", "Data stores")
+	renamed := models.Category{ID: category.ID, Name: "数据库"}
+	ApplyCategoryTranslation(&renamed, "en")
+	if renamed.Name != "Databases" {
+		t.Errorf("translation reloaded before categories changed: %q", renamed.Name)
+	}
+	cache.Publish(cache.TopicCategories)
+	renamed.Name = "数据库"
+	ApplyCategoryTranslation(&renamed, "en")
+	if renamed.Name != "Data stores" {
+		t.Errorf("renamed translation = %q", renamed.Name)
+	}
+
+	// Preloaded translations are used as they are
+	preloaded := models.Category{ID: category.ID, Name: "数据库", Translations: []models.CategoryTranslation{{Language: "en", Name: "Preloaded"}}}
+	ApplyCategoryTranslation(&preloaded, "en")
+	if preloaded.Name != "Preloaded" {
+		t.Errorf("preloaded translation = %q", preloaded.Name)
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// HighlightOptions selects how a block of code is highlighted
+type HighlightOptions struct {
+	// Language is a language name, alias or file name; an empty or unknown
+	// language is guessed from the code
+	Language    string
+	LineNumbers bool
+	// HighlightLines are the 1-based, inclusive line ranges to emphasize
+	HighlightLines [][2]int
+}
+
+// CodeHighlighter renders code as HTML for output the server builds itself,
+// such as feeds and emails. Colors are inline styles, since feed readers and
+// mail clients do not load the site's stylesheets.
+type CodeHighlighter struct {
+	style       *chroma.Style
+	lineNumbers bool
+}
+
+// NewCodeHighlighter creates a highlighter with the configured style
+func NewCodeHighlighter() *CodeHighlighter {
+	cfg := config.Get().Highlight
+	return &CodeHighlighter{
+		style:       styles.Get(cfg.Style),
+		lineNumbers: cfg.LineNumbers,
+	}
+}
+
+// Highlight returns code as a highlighted <pre> block, along with the name
+// of the language it was highlighted as
+func (h *CodeHighlighter) Highlight(code string, opts HighlightOptions) (string, string, error) {
+	lexer := detectLexer(code, opts.Language)
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return "", "", err
+	}
+	formatter := chromahtml.New(
+		chromahtml.WithClasses(false),
+		chromahtml.TabWidth(4),
+		chromahtml.WithLineNumbers(h.lineNumbers || opts.LineNumbers),
+		chromahtml.HighlightLines(opts.HighlightLines),
+	)
+	var out bytes.Buffer
+	if err := formatter.Format(&out, h.style, iterator); err != nil {
+		return "", "", err
+	}
+	return out.String(), lexer.Config().Name, nil
+}
+
+// detectLexer finds the lexer of a language name, alias or file name,
+// guessing from the code itself when there is none
+func detectLexer(code, language string) chroma.Lexer {
+	var lexer chroma.Lexer
+	if language != "" {
+		lexer = lexers.Get(language)
+	}
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		lexer = lexers.Get("plaintext")
+	}
+	return chroma.Coalesce(lexer)
+}
+
+// ParseFenceInfo reads the info string of a fenced code block, the text
+// after the opening fence: a language, then optionally line ranges to
+// highlight in braces and showLineNumbers, as in "go {1,4-6} showLineNumbers"
+func ParseFenceInfo(info string) HighlightOptions {
+	var opts HighlightOptions
+	for _, field := range strings.Fields(info) {
+		switch {
+		case strings.HasPrefix(field, "{") && strings.HasSuffix(field, "}"):
+			opts.HighlightLines = append(opts.HighlightLines, parseLineRanges(field[1:len(field)-1])...)
+		case field == "showLineNumbers" || field == "linenos":
+			opts.LineNumbers = true
+		case opts.Language == "" && !strings.Contains(field, "="):
+			opts.Language = field
+		}
+	}
+	return opts
+}
+
+// parseLineRanges parses "1,4-6" into line ranges, skipping invalid ones
+func parseLineRanges(spec string) [][2]int {
+	var ranges [][2]int
+	for _, part := range strings.Split(spec, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, err := strconv.Atoi(first)
+		end := start
+		if err == nil && isRange {
+			end, err = strconv.Atoi(last)
+		}
+		if err != nil || start < 1 || end < start {
+			continue
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges
+}
+
+var (
+	globalCodeHighlighter     *CodeHighlighter
+	globalCodeHighlighterOnce sync.Once
+)
+
+// GetGlobalCodeHighlighter returns the global code highlighter
+func GetGlobalCodeHighlighter() *CodeHighlighter {
+	globalCodeHighlighterOnce.Do(func() {
+		globalCodeHighlighter = NewCodeHighlighter()
+	})
+	return globalCodeHighlighter
+}
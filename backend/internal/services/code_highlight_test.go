@@ -0,0 +1,49 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFenceInfo(t *testing.T) {
+	for info, want := range map[string]HighlightOptions{
+		"":                               {},
+		"go":                             {Language: "go"},
+		"go {1,4-6} showLineNumbers":     {Language: "go", LineNumbers: true, HighlightLines: [][2]int{{1, 1}, {4, 6}}},
+		`title="main.py" python {x,3-2}`: {Language: "python"},
+		"{2} linenos":                    {LineNumbers: true, HighlightLines: [][2]int{{2, 2}}},
+	} {
+		if got := ParseFenceInfo(info); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseFenceInfo(%q) = %+v, want %+v", info, got, want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	h := NewCodeHighlighter()
+	code := "package main\n\nfunc main() {\n\tprintln(\"<hi>\")\n}\n"
+	out, language, err := h.Highlight(code, HighlightOptions{Language: "go", LineNumbers: true, HighlightLines: [][2]int{{3, 3}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if language != "Go" {
+		t.Errorf("language = %q, want Go", language)
+	}
+	if strings.Contains(out, "class=") || !strings.Contains(out, `style="`) {
+		t.Errorf("want inline styles without classes: %s", out)
+	}
+	if !strings.Contains(out, "&lt;hi&gt;") || strings.Contains(out, "<hi>") {
+		t.Errorf("code is not escaped: %s", out)
+	}
+	if !strings.Contains(out, ">5</span>") {
+		t.Errorf("want line numbers: %s", out)
+	}
+
+	if _, language, _ := h.Highlight("#!/bin/bash\necho hello\n", HighlightOptions{}); language != "Bash" {
+		t.Errorf("detected %q, want Bash", language)
+	}
+	if _, language, _ := h.Highlight("just some words", HighlightOptions{Language: "no-such-language"}); language != "plaintext" {
+		t.Errorf("detected %q, want plaintext", language)
+	}
+}
@@ -0,0 +1,858 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCommentBridgeNotConfigured is returned when no external comment
+	// system is configured
+	ErrCommentBridgeNotConfigured = errors.New("no external comment system is configured")
+	// ErrCommentSyncRunning is returned when a sync is queued while another
+	// is pending or running
+	ErrCommentSyncRunning = errors.New("a comment sync is already running")
+)
+
+// CommentBridgeScheduleName is the scheduler entry for
+// COMMENT_BRIDGE_SCHEDULE
+const CommentBridgeScheduleName = "comment-bridge"
+
+// Limits of a sync: pages read of each list, and the pages listed in a
+// sync result that match no article
+const (
+	maxCommentBridgePages = 100
+	maxUnmatchedPages     = 50
+)
+
+// externalThread is a discussion as an external comment system lists it
+type externalThread struct {
+	ID            string
+	Page          []string // title, URL or identifiers of the page discussed
+	URL           string   // where readers join the discussion
+	Comments      int
+	LastCommentAt *time.Time
+}
+
+// commentPlatform reads the discussions of an external comment system
+type commentPlatform interface {
+	Name() string
+	Threads(ctx context.Context) ([]externalThread, error)
+	Comments(ctx context.Context, thread externalThread) ([]importedComment, error)
+}
+
+// CommentBridgeSync describes one sync of the external comment system
+type CommentBridgeSync struct {
+	Provider       string   `json:"provider"`
+	Threads        int      `json:"threads"`
+	Articles       int      `json:"articles"`
+	Comments       int      `json:"comments"`
+	ContentSynced  int      `json:"content_synced"`
+	Removed        int      `json:"removed"`
+	Failed         int      `json:"failed"`
+	UnmatchedPages []string `json:"unmatched_pages"`
+}
+
+// CommentBridgeStatus is what the bridge has synced so far
+type CommentBridgeStatus struct {
+	Provider    string      `json:"provider"`
+	SyncContent bool        `json:"sync_content"`
+	Discussions int64       `json:"discussions"`
+	Comments    int64       `json:"comments"`
+	LastSync    *models.Job `json:"last_sync"`
+}
+
+// DiscussedArticle is an article with its comment count on the external
+// comment system
+type DiscussedArticle struct {
+	ID       uint   `json:"id"`
+	Title    string `json:"title"`
+	Comments int    `json:"comments"`
+	URL      string `json:"url,omitempty"`
+}
+
+// DiscussionStats sums up the engagement on the external comment system
+type DiscussionStats struct {
+	Provider     string             `json:"provider"`
+	Comments     int64              `json:"comments"`
+	Discussions  int64              `json:"discussions"`
+	LastComment  *time.Time         `json:"last_comment_at,omitempty"`
+	MostComments []DiscussedArticle `json:"most_comments"`
+}
+
+// CommentBridgeService keeps a read-only copy of the discussions of an
+// external comment system, for sites that keep Giscus or Disqus: the
+// comment count of each article and, with content sync, its comments as
+// approved replies. Discussions are matched to articles the way imported
+// comments are. Replies synced before keep the moderation state an admin
+// gave them, and comments removed from the external system are removed
+// here.
+type CommentBridgeService struct {
+	db          func() *gorm.DB
+	now         func() time.Time
+	platform    commentPlatform
+	syncContent bool
+}
+
+// NewCommentBridgeService creates a comment bridge from the
+// COMMENT_BRIDGE_* settings
+func NewCommentBridgeService() *CommentBridgeService {
+	cfg := config.Get().CommentBridge
+	s := &CommentBridgeService{
+		db:          func() *gorm.DB { return database.DB },
+		now:         time.Now,
+		syncContent: cfg.SyncContent,
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	switch cfg.Provider {
+	case models.ReplySourceGiscus:
+		token, err := security.ResolveSecret(cfg.GiscusToken)
+		if err != nil {
+			slog.Error("Giscus comment bridge disabled", "error", err)
+			break
+		}
+		owner, name, _ := strings.Cut(cfg.GiscusRepository, "/")
+		s.platform = &giscusComments{
+			endpoint: "https://api.github.com/graphql",
+			owner:    owner,
+			name:     name,
+			category: cfg.GiscusCategory,
+			token:    token,
+			client:   client,
+		}
+	case models.ReplySourceDisqus:
+		key, err := security.ResolveSecret(cfg.DisqusAPIKey)
+		if err != nil {
+			slog.Error("Disqus comment bridge disabled", "error", err)
+			break
+		}
+		s.platform = &disqusComments{baseURL: "https://disqus.com/api/3.0", forum: cfg.DisqusForum, apiKey: key, client: client}
+	}
+	return s
+}
+
+// Enabled reports whether an external comment system is configured
+func (s *CommentBridgeService) Enabled() bool {
+	return s.platform != nil
+}
+
+// Queue queues a sync now, unless one is already pending or running
+func (s *CommentBridgeService) Queue() (*models.Job, error) {
+	if !s.Enabled() {
+		return nil, ErrCommentBridgeNotConfigured
+	}
+	queue := GetGlobalJobQueue()
+	active, err := queue.Active(JobCommentBridgeSync)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, ErrCommentSyncRunning
+	}
+	return queue.Enqueue(JobCommentBridgeSync, nil)
+}
+
+// Status returns the discussions and comments synced and the latest sync
+func (s *CommentBridgeService) Status(ctx context.Context) (*CommentBridgeStatus, error) {
+	if !s.Enabled() {
+		return nil, ErrCommentBridgeNotConfigured
+	}
+	status := &CommentBridgeStatus{Provider: s.platform.Name(), SyncContent: s.syncContent}
+	var totals struct {
+		Discussions int64
+		Comments    int64
+	}
+	if err := s.db().WithContext(ctx).Model(&models.ExternalDiscussion{}).Where("provider = ?", status.Provider).
+		Select("COUNT(*) AS discussions, COALESCE(SUM(comments), 0) AS comments").Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	status.Discussions, status.Comments = totals.Discussions, totals.Comments
+	job, err := GetGlobalJobQueue().Latest(JobCommentBridgeSync)
+	status.LastSync = job
+	return status, err
+}
+
+// Sync reads every discussion of the external comment system and updates
+// the comment counts of their articles. With content sync, the comments of
+// discussions that changed are copied too. Discussions that match no
+// article are listed in the result; ones that disappeared are removed.
+func (s *CommentBridgeService) Sync(ctx context.Context) (*CommentBridgeSync, error) {
+	if !s.Enabled() {
+		return nil, ErrCommentBridgeNotConfigured
+	}
+	provider := s.platform.Name()
+	result := &CommentBridgeSync{Provider: provider, UnmatchedPages: []string{}}
+	progress := JobProgressFrom(ctx)
+	progress.Step("Listing the discussions on %s", provider)
+	threads, err := s.platform.Threads(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result.Threads = len(threads)
+
+	// A page can have a discussion per language, which are added up
+	db := s.db().WithContext(ctx)
+	matches := map[string]uint{}
+	byArticle := map[uint][]externalThread{}
+	var articleIDs []uint
+	for _, thread := range threads {
+		articleID, err := matchCommentPage(db, matches, thread.Page)
+		if err != nil {
+			return nil, err
+		}
+		if articleID == 0 {
+			if len(thread.Page) > 0 && len(result.UnmatchedPages) < maxUnmatchedPages {
+				result.UnmatchedPages = append(result.UnmatchedPages, thread.Page[0])
+			}
+			continue
+		}
+		if _, ok := byArticle[articleID]; !ok {
+			articleIDs = append(articleIDs, articleID)
+		}
+		byArticle[articleID] = append(byArticle[articleID], thread)
+	}
+	var articles []models.Article
+	if err := db.Select("id", "site_id", "title").Where("id IN ?", articleIDs).Order("id").Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	result.Articles = len(articles)
+
+	now := s.now()
+	progress.SetTotal(len(articles))
+	for i := range articles {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		article := &articles[i]
+		threads := byArticle[article.ID]
+		progress.Step("Syncing the discussion of %s", article.Title)
+		stored, err := s.record(ctx, article, provider, threads, now)
+		if err != nil {
+			return result, err
+		}
+		result.Comments += stored.Comments
+		if s.syncContent {
+			synced, err := s.syncComments(ctx, article, threads, stored.Comments)
+			if err != nil {
+				result.Failed++
+				progress.ItemFailed(article.Title, err)
+				continue
+			}
+			if synced {
+				result.ContentSynced++
+			}
+		}
+		progress.Advance(1)
+	}
+
+	// Discussions not listed any more were deleted
+	removed := db.Where("provider = ? AND synced_at < ?", provider, now).Delete(&models.ExternalDiscussion{})
+	if removed.Error != nil {
+		return result, removed.Error
+	}
+	result.Removed = int(removed.RowsAffected)
+	return result, nil
+}
+
+// record stores the comment count of an article's discussions
+func (s *CommentBridgeService) record(ctx context.Context, article *models.Article, provider string, threads []externalThread, now time.Time) (*models.ExternalDiscussion, error) {
+	var discussion models.ExternalDiscussion
+	if err := s.db().WithContext(ctx).Where("article_id = ? AND provider = ?", article.ID, provider).
+		Limit(1).Find(&discussion).Error; err != nil {
+		return nil, err
+	}
+	discussion.SiteID, discussion.ArticleID, discussion.Provider = article.SiteID, article.ID, provider
+	// The busiest discussion is the one readers are pointed to
+	busiest := threads[0]
+	comments := 0
+	var last *time.Time
+	for _, thread := range threads {
+		comments += thread.Comments
+		if thread.Comments > busiest.Comments {
+			busiest = thread
+		}
+		if thread.LastCommentAt != nil && (last == nil || thread.LastCommentAt.After(*last)) {
+			last = thread.LastCommentAt
+		}
+	}
+	discussion.ThreadID, discussion.URL = busiest.ID, busiest.URL
+	discussion.Comments, discussion.LastCommentAt, discussion.SyncedAt = comments, last, now
+	return &discussion, s.db().WithContext(ctx).Save(&discussion).Error
+}
+
+// syncComments copies the comments of an article's discussions unless the
+// replies synced before are as many as the discussions have. Nothing is
+// changed when a discussion cannot be read.
+func (s *CommentBridgeService) syncComments(ctx context.Context, article *models.Article, threads []externalThread, total int) (bool, error) {
+	provider := s.platform.Name()
+	var stored int64
+	if err := s.db().WithContext(ctx).Model(&models.FederatedReply{}).
+		Where("article_id = ? AND source = ?", article.ID, provider).Count(&stored).Error; err != nil {
+		return false, err
+	}
+	if int(stored) == total {
+		return false, nil
+	}
+	var comments []importedComment
+	for _, thread := range threads {
+		some, err := s.platform.Comments(ctx, thread)
+		if err != nil {
+			return false, err
+		}
+		comments = append(comments, some...)
+	}
+
+	return true, s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		objectIDs := make([]string, 0, len(comments))
+		for _, comment := range comments {
+			objectIDs = append(objectIDs, provider+":"+comment.ID)
+		}
+		var existing []string
+		if len(objectIDs) > 0 {
+			if err := tx.Model(&models.FederatedReply{}).Where("object_id IN ?", objectIDs).
+				Pluck("object_id", &existing).Error; err != nil {
+				return err
+			}
+		}
+		known := make(map[string]bool, len(existing))
+		for _, objectID := range existing {
+			known[objectID] = true
+		}
+
+		for _, comment := range comments {
+			objectID := provider + ":" + comment.ID
+			if comment.CreatedAt.IsZero() {
+				comment.CreatedAt = s.now()
+			}
+			authorURL := webLink(comment.AuthorURL)
+			actorID := authorURL
+			if actorID == "" {
+				actorID = provider + ":" + comment.Author
+			}
+			// Comments edited on the platform are updated, keeping the
+			// moderation state an admin gave them here
+			if known[objectID] {
+				err := tx.Model(&models.FederatedReply{}).Where("object_id = ?", objectID).
+					Updates(map[string]interface{}{
+						"actor_id": actorID, "author_name": comment.Author, "author_url": authorURL,
+						"content": comment.Content, "url": comment.URL,
+					}).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			reply := models.FederatedReply{
+				SiteID:      article.SiteID,
+				ArticleID:   article.ID,
+				Source:      provider,
+				ObjectID:    objectID,
+				ActorID:     actorID,
+				AuthorName:  comment.Author,
+				AuthorURL:   authorURL,
+				Content:     comment.Content,
+				URL:         comment.URL,
+				Status:      comment.Status,
+				PublishedAt: comment.CreatedAt,
+			}
+			if err := tx.Create(&reply).Error; err != nil {
+				return err
+			}
+		}
+
+		stale := tx.Where("article_id = ? AND source = ?", article.ID, provider)
+		if len(objectIDs) > 0 {
+			stale = stale.Where("object_id NOT IN ?", objectIDs)
+		}
+		if err := stale.Delete(&models.FederatedReply{}).Error; err != nil {
+			return err
+		}
+
+		var rows []models.FederatedReply
+		if err := tx.Select("id", "object_id").Where("article_id = ? AND source = ?", article.ID, provider).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		ids := make(map[string]uint, len(rows))
+		for _, row := range rows {
+			ids[row.ObjectID] = row.ID
+		}
+		for _, comment := range comments {
+			var parentID *uint
+			if id, ok := ids[provider+":"+comment.ParentID]; ok && comment.ParentID != "" {
+				parentID = &id
+			}
+			if err := tx.Model(&models.FederatedReply{}).Where("id = ?", ids[provider+":"+comment.ID]).
+				UpdateColumn("parent_id", parentID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Attach fills in the discussion of each article that has one
+func (s *CommentBridgeService) Attach(ctx context.Context, articles []models.Article) error {
+	if !s.Enabled() || len(articles) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(articles))
+	for i, article := range articles {
+		ids[i] = article.ID
+	}
+	var discussions []models.ExternalDiscussion
+	if err := s.db().WithContext(ctx).Where("provider = ? AND article_id IN ?", s.platform.Name(), ids).
+		Find(&discussions).Error; err != nil {
+		return err
+	}
+	byArticle := make(map[uint]*models.ArticleDiscussion, len(discussions))
+	for _, discussion := range discussions {
+		byArticle[discussion.ArticleID] = &models.ArticleDiscussion{
+			Provider: discussion.Provider,
+			Comments: discussion.Comments,
+			URL:      discussion.URL,
+		}
+	}
+	for i := range articles {
+		articles[i].Discussion = byArticle[articles[i].ID]
+	}
+	return nil
+}
+
+// Discussion returns an article's discussion, nil when it has none
+func (s *CommentBridgeService) Discussion(ctx context.Context, articleID uint) (*models.ExternalDiscussion, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+	var discussion models.ExternalDiscussion
+	found := s.db().WithContext(ctx).Where("provider = ? AND article_id = ?", s.platform.Name(), articleID).
+		Limit(1).Find(&discussion)
+	if found.Error != nil || found.RowsAffected == 0 {
+		return nil, found.Error
+	}
+	return &discussion, nil
+}
+
+// Stats sums up the comments of the site's discussions, with the limit
+// most commented articles; nil when the bridge is off
+func (s *CommentBridgeService) Stats(ctx context.Context, limit int) (*DiscussionStats, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+	db := s.db().WithContext(ctx)
+	stats := &DiscussionStats{Provider: s.platform.Name(), MostComments: []DiscussedArticle{}}
+	var totals struct {
+		Discussions int64
+		Comments    int64
+	}
+	if err := db.Model(&models.ExternalDiscussion{}).Where("provider = ?", stats.Provider).
+		Select("COUNT(*) AS discussions, COALESCE(SUM(comments), 0) AS comments").Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	stats.Discussions, stats.Comments = totals.Discussions, totals.Comments
+
+	var latest models.ExternalDiscussion
+	if err := db.Where("provider = ? AND last_comment_at IS NOT NULL", stats.Provider).
+		Order("last_comment_at DESC").Limit(1).Find(&latest).Error; err != nil {
+		return nil, err
+	}
+	stats.LastComment = latest.LastCommentAt
+
+	err := db.Model(&models.ExternalDiscussion{}).
+		Select("articles.id, articles.title, external_discussions.comments, external_discussions.url").
+		Joins("JOIN articles ON articles.id = external_discussions.article_id AND articles.deleted_at IS NULL").
+		Where("external_discussions.provider = ? AND external_discussions.comments > 0", stats.Provider).
+		Order("external_discussions.comments DESC, articles.id").Limit(limit).
+		Scan(&stats.MostComments).Error
+	return stats, err
+}
+
+// giscusComments reads the GitHub Discussions Giscus keeps its comments
+// in. Giscus names a discussion after the page, by its path or URL with
+// the default mappings, which is matched to the article.
+type giscusComments struct {
+	endpoint string
+	owner    string
+	name     string
+	category string
+	token    string
+	client   *http.Client
+}
+
+func (p *giscusComments) Name() string { return models.ReplySourceGiscus }
+
+const giscusThreadsQuery = `query($owner: String!, $name: String!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    discussions(first: 50, after: $after) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        id title url
+        category { name }
+        comments(last: 100) { totalCount nodes { createdAt replies(last: 1) { totalCount nodes { createdAt } } } }
+      }
+    }
+  }
+}`
+
+// giscusAt is when a comment was written
+type giscusAt struct {
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (p *giscusComments) Threads(ctx context.Context) ([]externalThread, error) {
+	var threads []externalThread
+	var after *string
+	for page := 0; page < maxCommentBridgePages; page++ {
+		var response struct {
+			Repository *struct {
+				Discussions struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []struct {
+						ID       string `json:"id"`
+						Title    string `json:"title"`
+						URL      string `json:"url"`
+						Category struct {
+							Name string `json:"name"`
+						} `json:"category"`
+						Comments struct {
+							TotalCount int `json:"totalCount"`
+							Nodes      []struct {
+								giscusAt
+								Replies struct {
+									TotalCount int        `json:"totalCount"`
+									Nodes      []giscusAt `json:"nodes"`
+								} `json:"replies"`
+							} `json:"nodes"`
+						} `json:"comments"`
+					} `json:"nodes"`
+				} `json:"discussions"`
+			} `json:"repository"`
+		}
+		variables := map[string]interface{}{"owner": p.owner, "name": p.name, "after": after}
+		if err := p.query(ctx, giscusThreadsQuery, variables, &response); err != nil {
+			return nil, err
+		}
+		if response.Repository == nil {
+			return nil, fmt.Errorf("GitHub repository %s/%s not found", p.owner, p.name)
+		}
+		discussions := response.Repository.Discussions
+		for _, node := range discussions.Nodes {
+			if p.category != "" && !strings.EqualFold(node.Category.Name, p.category) {
+				continue
+			}
+			// Giscus counts replies with the comments; those of the 100
+			// latest comments are read
+			thread := externalThread{ID: node.ID, Page: nonEmpty(node.Title), URL: node.URL, Comments: node.Comments.TotalCount}
+			for _, comment := range node.Comments.Nodes {
+				thread.Comments += comment.Replies.TotalCount
+				at := comment.CreatedAt
+				for _, reply := range comment.Replies.Nodes {
+					if reply.CreatedAt.After(at) {
+						at = reply.CreatedAt
+					}
+				}
+				if thread.LastCommentAt == nil || at.After(*thread.LastCommentAt) {
+					thread.LastCommentAt = &at
+				}
+			}
+			threads = append(threads, thread)
+		}
+		if !discussions.PageInfo.HasNextPage {
+			break
+		}
+		after = &discussions.PageInfo.EndCursor
+	}
+	return threads, nil
+}
+
+const giscusCommentsQuery = `query($id: ID!, $after: String) {
+  node(id: $id) {
+    ... on Discussion {
+      comments(first: 20, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes {
+          id url bodyHTML createdAt isMinimized author { login url }
+          replies(first: 100) { nodes { id url bodyHTML createdAt isMinimized author { login url } } }
+        }
+      }
+    }
+  }
+}`
+
+// giscusComment is a comment or reply of a discussion; the author of a
+// deleted account is null
+type giscusComment struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	BodyHTML    string    `json:"bodyHTML"`
+	CreatedAt   time.Time `json:"createdAt"`
+	IsMinimized bool      `json:"isMinimized"`
+	Author      *struct {
+		Login string `json:"login"`
+		URL   string `json:"url"`
+	} `json:"author"`
+}
+
+func (c giscusComment) imported(parentID string) importedComment {
+	comment := importedComment{
+		ID:        c.ID,
+		ParentID:  parentID,
+		Author:    "ghost",
+		Content:   security.GetGlobalHTMLPolicy().Sanitize(c.BodyHTML),
+		URL:       c.URL,
+		Status:    models.ReplyApproved,
+		CreatedAt: c.CreatedAt,
+	}
+	if c.Author != nil {
+		comment.Author, comment.AuthorURL = c.Author.Login, c.Author.URL
+	}
+	return comment
+}
+
+func (p *giscusComments) Comments(ctx context.Context, thread externalThread) ([]importedComment, error) {
+	var comments []importedComment
+	var after *string
+	for page := 0; page < maxCommentBridgePages; page++ {
+		var response struct {
+			Node struct {
+				Comments struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []struct {
+						giscusComment
+						Replies struct {
+							Nodes []giscusComment `json:"nodes"`
+						} `json:"replies"`
+					} `json:"nodes"`
+				} `json:"comments"`
+			} `json:"node"`
+		}
+		if err := p.query(ctx, giscusCommentsQuery, map[string]interface{}{"id": thread.ID, "after": after}, &response); err != nil {
+			return nil, err
+		}
+		// Minimized comments are hidden on GitHub, so they are left out
+		for _, node := range response.Node.Comments.Nodes {
+			if node.IsMinimized {
+				continue
+			}
+			comments = append(comments, node.giscusComment.imported(""))
+			for _, reply := range node.Replies.Nodes {
+				if !reply.IsMinimized {
+					comments = append(comments, reply.imported(node.ID))
+				}
+			}
+		}
+		if !response.Node.Comments.PageInfo.HasNextPage {
+			break
+		}
+		after = &response.Node.Comments.PageInfo.EndCursor
+	}
+	return comments, nil
+}
+
+// query runs a GitHub GraphQL query, whose errors come in a 200 answer
+func (p *giscusComments) query(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+p.token)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub answered %d: %s", resp.StatusCode, truncateRunes(strings.TrimSpace(string(data)), 200))
+	}
+	var answer struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &answer); err != nil {
+		return err
+	}
+	if len(answer.Errors) > 0 {
+		return fmt.Errorf("GitHub query failed: %s", answer.Errors[0].Message)
+	}
+	return json.Unmarshal(answer.Data, out)
+}
+
+// disqusComments reads the threads and posts of a Disqus forum through the
+// Disqus API. Threads are matched by their link and identifiers, as in a
+// Disqus export, and posts get the IDs an imported export gives them.
+type disqusComments struct {
+	baseURL string
+	forum   string
+	apiKey  string
+	client  *http.Client
+}
+
+func (p *disqusComments) Name() string { return models.ReplySourceDisqus }
+
+// disqusCursor pages through Disqus lists
+type disqusCursor struct {
+	HasNext bool   `json:"hasNext"`
+	Next    string `json:"next"`
+}
+
+func (p *disqusComments) Threads(ctx context.Context) ([]externalThread, error) {
+	var threads []externalThread
+	cursor := ""
+	for page := 0; page < maxCommentBridgePages; page++ {
+		var response struct {
+			Cursor   disqusCursor `json:"cursor"`
+			Response []struct {
+				ID          exportID `json:"id"`
+				Link        string   `json:"link"`
+				Slug        string   `json:"slug"`
+				Identifiers []string `json:"identifiers"`
+				Posts       int      `json:"posts"`
+			} `json:"response"`
+		}
+		query := url.Values{"forum": {p.forum}, "limit": {"100"}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		if err := p.get(ctx, "/threads/list.json", query, &response); err != nil {
+			return nil, err
+		}
+		for _, thread := range response.Response {
+			found := externalThread{
+				ID:       string(thread.ID),
+				Page:     nonEmpty(append([]string{thread.Link}, thread.Identifiers...)...),
+				Comments: thread.Posts,
+			}
+			if thread.Slug != "" {
+				found.URL = fmt.Sprintf("https://disqus.com/home/discussion/%s/%s/", url.PathEscape(p.forum), url.PathEscape(thread.Slug))
+			}
+			threads = append(threads, found)
+		}
+		if !response.Cursor.HasNext {
+			break
+		}
+		cursor = response.Cursor.Next
+	}
+	return threads, nil
+}
+
+func (p *disqusComments) Comments(ctx context.Context, thread externalThread) ([]importedComment, error) {
+	policy := security.GetGlobalHTMLPolicy()
+	var comments []importedComment
+	cursor := ""
+	for page := 0; page < maxCommentBridgePages; page++ {
+		var response struct {
+			Cursor   disqusCursor `json:"cursor"`
+			Response []struct {
+				ID         exportID `json:"id"`
+				Parent     exportID `json:"parent"`
+				Message    string   `json:"message"`
+				CreatedAt  string   `json:"createdAt"`
+				IsApproved bool     `json:"isApproved"`
+				IsDeleted  bool     `json:"isDeleted"`
+				IsSpam     bool     `json:"isSpam"`
+				Author     struct {
+					Name       string `json:"name"`
+					Username   string `json:"username"`
+					ProfileURL string `json:"profileUrl"`
+				} `json:"author"`
+			} `json:"response"`
+		}
+		query := url.Values{"forum": {p.forum}, "thread": {thread.ID}, "limit": {"100"}, "order": {"asc"}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		if err := p.get(ctx, "/posts/list.json", query, &response); err != nil {
+			return nil, err
+		}
+		for _, post := range response.Response {
+			if post.IsDeleted || post.IsSpam || !post.IsApproved || post.ID == "" {
+				continue
+			}
+			// Disqus dates are UTC without a zone
+			createdAt, _ := time.Parse("2006-01-02T15:04:05", post.CreatedAt)
+			comments = append(comments, importedComment{
+				ID:        string(post.ID),
+				ParentID:  string(post.Parent),
+				Author:    firstNonEmpty(post.Author.Name, post.Author.Username),
+				AuthorURL: post.Author.ProfileURL,
+				Content:   policy.Sanitize(strings.TrimSpace(post.Message)),
+				Status:    models.ReplyApproved,
+				CreatedAt: createdAt,
+			})
+		}
+		if !response.Cursor.HasNext {
+			break
+		}
+		cursor = response.Cursor.Next
+	}
+	sort.SliceStable(comments, func(i, j int) bool { return comments[i].CreatedAt.Before(comments[j].CreatedAt) })
+	return comments, nil
+}
+
+// get calls a Disqus API method; Disqus reports failures with a non-zero
+// code, which is kept in the error
+func (p *disqusComments) get(ctx context.Context, method string, query url.Values, out interface{}) error {
+	query.Set("api_key", p.apiKey)
+	var data json.RawMessage
+	if err := getShareJSON(ctx, p.client, p.baseURL+method+"?"+query.Encode(), "", &data); err != nil {
+		return fmt.Errorf("Disqus %s: %w", method, err)
+	}
+	var answer struct {
+		Code     int             `json:"code"`
+		Response json.RawMessage `json:"response"`
+	}
+	if err := json.Unmarshal(data, &answer); err != nil {
+		return err
+	}
+	if answer.Code != 0 {
+		return fmt.Errorf("Disqus %s failed with code %d: %s", method, answer.Code, truncateRunes(string(answer.Response), 200))
+	}
+	return json.Unmarshal(data, out)
+}
+
+var (
+	globalCommentBridgeService     *CommentBridgeService
+	globalCommentBridgeServiceOnce sync.Once
+)
+
+// GetGlobalCommentBridgeService returns the global comment bridge
+func GetGlobalCommentBridgeService() *CommentBridgeService {
+	globalCommentBridgeServiceOnce.Do(func() {
+		globalCommentBridgeService = NewCommentBridgeService()
+	})
+	return globalCommentBridgeService
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestCommentBridgeDisqus(t *testing.T) {
+	newTestDB(t)
+	hello := models.Article{Title: "Hello", DefaultLang: "en", SEOSlug: "hello"}
+	database.DB.Create(&hello)
+	second := models.Article{Title: "Second", DefaultLang: "en"}
+	database.DB.Create(&second)
+
+	threads := []string{
+		`{"id": "1", "link": "https://blog.example.com/en/article/hello", "slug": "hello", "identifiers": [], "posts": 2}`,
+		fmt.Sprintf(`{"id": 2, "link": "", "identifiers": ["%d"], "posts": 1}`, second.ID),
+		`{"id": "3", "link": "https://blog.example.com/en/article/missing", "identifiers": [], "posts": 4}`,
+	}
+	posts := map[string][]string{
+		"1": {
+			`{"id": "11", "parent": null, "message": "<p>First!</p><script>x()</script>", "createdAt": "2026-02-01T10:00:00", "isApproved": true, "author": {"name": "Ann", "profileUrl": "https://disqus.com/by/ann/"}}`,
+			`{"id": "12", "parent": 11, "message": "<p>Reply</p>", "createdAt": "2026-02-02T10:00:00", "isApproved": true, "author": {"name": "Bob"}}`,
+			`{"id": "13", "parent": null, "message": "Buy now", "createdAt": "2026-02-03T10:00:00", "isApproved": false, "isSpam": true, "author": {"name": "Spam"}}`,
+		},
+		"2": {`{"id": "21", "parent": null, "message": "<p>Nice</p>", "createdAt": "2026-02-04T10:00:00", "isApproved": true, "author": {"name": "Cy"}}`},
+	}
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("api_key") != "key" || query.Get("forum") != "blog" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		if failing {
+			fmt.Fprint(w, `{"code": 5, "response": "Invalid API key"}`)
+			return
+		}
+		switch r.URL.Path {
+		case "/threads/list.json":
+			// Two pages
+			if query.Get("cursor") == "" {
+				fmt.Fprintf(w, `{"code": 0, "cursor": {"hasNext": true, "next": "2"}, "response": [%s]}`, threads[0])
+				return
+			}
+			fmt.Fprintf(w, `{"code": 0, "cursor": {"hasNext": false}, "response": [%s]}`, strings.Join(threads[1:], ","))
+		case "/posts/list.json":
+			fmt.Fprintf(w, `{"code": 0, "cursor": {"hasNext": false}, "response": [%s]}`, strings.Join(posts[query.Get("thread")], ","))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	s := &CommentBridgeService{
+		db:          func() *gorm.DB { return database.DB },
+		now:         func() time.Time { return now },
+		platform:    &disqusComments{baseURL: server.URL, forum: "blog", apiKey: "key", client: server.Client()},
+		syncContent: true,
+	}
+	ctx := context.Background()
+	result, err := s.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Threads != 3 || result.Articles != 2 || result.Comments != 3 || result.ContentSynced != 2 ||
+		fmt.Sprint(result.UnmatchedPages) != "[https://blog.example.com/en/article/missing]" {
+		t.Errorf("unexpected sync %+v", result)
+	}
+	var replies []models.FederatedReply
+	database.DB.Order("published_at").Find(&replies)
+	if len(replies) != 3 || replies[0].ObjectID != "disqus:11" || replies[0].Content != "<p>First!</p>" ||
+		replies[0].Status != models.ReplyApproved || replies[0].AuthorURL != "https://disqus.com/by/ann/" ||
+		replies[1].ParentID == nil || *replies[1].ParentID != replies[0].ID || replies[2].ArticleID != second.ID {
+		t.Fatalf("unexpected replies %+v", replies)
+	}
+
+	articles := []models.Article{hello, second}
+	if err := s.Attach(ctx, articles); err != nil {
+		t.Fatal(err)
+	}
+	if d := articles[0].Discussion; d == nil || d.Comments != 2 || d.URL != "https://disqus.com/home/discussion/blog/hello/" {
+		t.Errorf("unexpected discussion %+v", d)
+	}
+
+	// Edited and new comments are synced, deleted ones removed, and a
+	// comment rejected here stays rejected
+	database.DB.Model(&replies[0]).Update("status", models.ReplyRejected)
+	threads = threads[:1]
+	threads[0] = strings.Replace(threads[0], `"posts": 2`, `"posts": 3`, 1)
+	posts["1"] = []string{
+		strings.Replace(posts["1"][0], "First!", "First, edited", 1),
+		`{"id": "14", "parent": 11, "message": "<p>Late</p>", "createdAt": "2026-02-05T10:00:00", "isApproved": true, "author": {"name": "Di"}}`,
+	}
+	now = now.Add(time.Hour)
+	result, err = s.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed != 1 || result.ContentSynced != 1 {
+		t.Errorf("unexpected sync %+v", result)
+	}
+	replies = nil
+	database.DB.Where("article_id = ?", hello.ID).Order("published_at").Find(&replies)
+	if len(replies) != 2 || replies[0].Content != "<p>First, edited</p>" || replies[0].Status != models.ReplyRejected ||
+		replies[1].ObjectID != "disqus:14" || replies[1].ParentID == nil || *replies[1].ParentID != replies[0].ID {
+		t.Errorf("unexpected replies %+v", replies)
+	}
+
+	stats, err := s.Stats(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Discussions != 1 || stats.Comments != 3 || len(stats.MostComments) != 1 || stats.MostComments[0].ID != hello.ID {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// A failing platform changes nothing
+	failing = true
+	if _, err := s.Sync(ctx); err == nil || !strings.Contains(err.Error(), "Invalid API key") {
+		t.Errorf("expected the Disqus error, got %v", err)
+	}
+	var count int64
+	database.DB.Model(&models.ExternalDiscussion{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected the synced discussion to be kept, got %d", count)
+	}
+}
+
+func TestCommentBridgeGiscus(t *testing.T) {
+	newTestDB(t)
+	article := models.Article{Title: "Hello", DefaultLang: "en"}
+	database.DB.Create(&article)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "bearer token" {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		var request struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		if strings.Contains(request.Query, "repository") {
+			// One discussion per language, and one in another category
+			fmt.Fprintf(w, `{"data": {"repository": {"discussions": {"pageInfo": {"hasNextPage": false}, "nodes": [
+				{"id": "D1", "title": "en/article/%[1]d", "url": "https://github.com/o/r/discussions/1", "category": {"name": "Comments"},
+				 "comments": {"totalCount": 2, "nodes": [
+					{"createdAt": "2026-02-01T10:00:00Z", "replies": {"totalCount": 1, "nodes": [{"createdAt": "2026-02-03T10:00:00Z"}]}},
+					{"createdAt": "2026-02-02T10:00:00Z", "replies": {"totalCount": 0, "nodes": []}}]}},
+				{"id": "D2", "title": "zh/article/%[1]d", "url": "https://github.com/o/r/discussions/2", "category": {"name": "comments"},
+				 "comments": {"totalCount": 1, "nodes": [{"createdAt": "2026-01-01T10:00:00Z", "replies": {"totalCount": 0, "nodes": []}}]}},
+				{"id": "D3", "title": "about", "url": "https://github.com/o/r/discussions/3", "category": {"name": "Ideas"},
+				 "comments": {"totalCount": 9, "nodes": []}}]}}}}`, article.ID)
+			return
+		}
+		if request.Variables["id"] != "D1" {
+			fmt.Fprint(w, `{"data": {"node": {"comments": {"pageInfo": {"hasNextPage": false}, "nodes": []}}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data": {"node": {"comments": {"pageInfo": {"hasNextPage": false}, "nodes": [
+			{"id": "C1", "url": "https://github.com/o/r/discussions/1#c1", "bodyHTML": "<p>Hi</p>", "createdAt": "2026-02-01T10:00:00Z",
+			 "author": {"login": "ann", "url": "https://github.com/ann"},
+			 "replies": {"nodes": [{"id": "C2", "bodyHTML": "<p>Hey</p>", "createdAt": "2026-02-03T10:00:00Z", "author": null}]}},
+			{"id": "C3", "bodyHTML": "<p>Off topic</p>", "createdAt": "2026-02-02T10:00:00Z", "isMinimized": true,
+			 "author": {"login": "bob", "url": "https://github.com/bob"}, "replies": {"nodes": []}}]}}}}`)
+	}))
+	defer server.Close()
+
+	s := &CommentBridgeService{
+		db:  func() *gorm.DB { return database.DB },
+		now: time.Now,
+		platform: &giscusComments{
+			endpoint: server.URL, owner: "o", name: "r", category: "Comments", token: "token", client: server.Client(),
+		},
+		syncContent: true,
+	}
+	result, err := s.Sync(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Threads != 2 || result.Articles != 1 || result.Comments != 4 {
+		t.Errorf("unexpected sync %+v", result)
+	}
+	discussion, err := s.Discussion(context.Background(), article.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if discussion == nil || discussion.Comments != 4 || discussion.ThreadID != "D1" || discussion.URL != "https://github.com/o/r/discussions/1" ||
+		discussion.LastCommentAt == nil || !discussion.LastCommentAt.Equal(time.Date(2026, 2, 3, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected discussion %+v", discussion)
+	}
+	var replies []models.FederatedReply
+	database.DB.Order("published_at").Find(&replies)
+	if len(replies) != 2 || replies[0].ObjectID != "giscus:C1" || replies[0].AuthorName != "ann" ||
+		replies[1].AuthorName != "ghost" || replies[1].ParentID == nil || *replies[1].ParentID != replies[0].ID {
+		t.Errorf("unexpected replies %+v", replies)
+	}
+}
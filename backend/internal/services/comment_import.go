@@ -0,0 +1,465 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidCommentImport = errors.New("invalid comment import")
+
+// commentMarkdown renders Artalk and Waline comments, which are Markdown.
+// Raw HTML in them is escaped.
+var commentMarkdown = goldmark.New()
+
+// importedComment is a comment read from an export, before it is matched
+// to an article
+type importedComment struct {
+	ID        string
+	ParentID  string
+	Page      []string // URL and identifiers of the page commented on
+	Author    string
+	AuthorURL string
+	Content   string // sanitized HTML
+	URL       string // where the comment is shown, when known
+	Status    string
+	CreatedAt time.Time
+}
+
+// CommentImport reports the outcome of a comment import. Comments of pages
+// that match no article are skipped and their pages listed.
+type CommentImport struct {
+	Format         string   `json:"format"`
+	Imported       int      `json:"imported"`
+	Duplicates     int      `json:"duplicates"`
+	Skipped        int      `json:"skipped"`
+	Threaded       int      `json:"threaded"`
+	UnmatchedPages []string `json:"unmatched_pages"`
+}
+
+// CommentImporter moves the discussion history of Disqus, Artalk and Waline
+// into the replies of articles, keeping who wrote each comment, when, and
+// what it answered. Imported comments keep the state they had: published
+// ones are approved, those waiting for moderation stay pending, and deleted
+// and spam comments are left out. Importing the same export twice adds
+// nothing.
+type CommentImporter struct {
+	db  func() *gorm.DB
+	now func() time.Time
+}
+
+// NewCommentImporter creates a comment importer
+func NewCommentImporter() *CommentImporter {
+	return &CommentImporter{
+		db:  func() *gorm.DB { return database.DB },
+		now: time.Now,
+	}
+}
+
+// Import reads a Disqus, Artalk or Waline export, the format being the
+// matching reply source, and adds its comments to the articles of the site
+// in ctx
+func (s *CommentImporter) Import(ctx context.Context, format string, data []byte) (*CommentImport, error) {
+	var comments []importedComment
+	var err error
+	switch format {
+	case models.ReplySourceDisqus:
+		comments, err = parseDisqusExport(data)
+	case models.ReplySourceArtalk:
+		comments, err = parseArtalkExport(data)
+	case models.ReplySourceWaline:
+		comments, err = parseWalineExport(data)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidCommentImport, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CommentImport{Format: format, UnmatchedPages: []string{}}
+	err = s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		articles := map[string]uint{}
+		unmatched := map[string]bool{}
+		ids := map[string]uint{}
+		var threaded []importedComment
+		for _, comment := range comments {
+			articleID, err := matchCommentPage(tx, articles, comment.Page)
+			if err != nil {
+				return err
+			}
+			if articleID == 0 {
+				result.Skipped++
+				if len(comment.Page) > 0 && !unmatched[comment.Page[0]] {
+					unmatched[comment.Page[0]] = true
+					result.UnmatchedPages = append(result.UnmatchedPages, comment.Page[0])
+				}
+				continue
+			}
+			if comment.CreatedAt.IsZero() {
+				comment.CreatedAt = s.now()
+			}
+			reply := models.FederatedReply{
+				ArticleID:   articleID,
+				Source:      format,
+				ObjectID:    format + ":" + comment.ID,
+				AuthorName:  comment.Author,
+				AuthorURL:   webLink(comment.AuthorURL),
+				Content:     comment.Content,
+				Status:      comment.Status,
+				PublishedAt: comment.CreatedAt,
+			}
+			created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reply)
+			if created.Error != nil {
+				return created.Error
+			}
+			if created.RowsAffected == 0 {
+				result.Duplicates++
+				continue
+			}
+			result.Imported++
+			ids[comment.ID] = reply.ID
+			if comment.ParentID != "" {
+				threaded = append(threaded, comment)
+			}
+		}
+
+		// Parents may come after their replies in an export, so threads
+		// are joined once every comment is in
+		for _, comment := range threaded {
+			parentID, ok := ids[comment.ParentID]
+			if !ok {
+				var parent models.FederatedReply
+				if err := tx.Select("id").Where("object_id = ?", format+":"+comment.ParentID).
+					Limit(1).Find(&parent).Error; err != nil {
+					return err
+				}
+				if parentID = parent.ID; parentID == 0 {
+					continue
+				}
+			}
+			err := tx.Model(&models.FederatedReply{}).Where("id = ?", ids[comment.ID]).
+				UpdateColumn("parent_id", parentID).Error
+			if err != nil {
+				return err
+			}
+			result.Threaded++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(result.UnmatchedPages)
+	return result, nil
+}
+
+// matchCommentPage finds the article of a page from its URL or identifiers:
+// the last part of the path is taken as the article's ID or one of its
+// slugs, the way article links are built. Matches are kept in articles.
+func matchCommentPage(db *gorm.DB, articles map[string]uint, page []string) (uint, error) {
+	for _, ref := range page {
+		if articleID, ok := articles[ref]; ok {
+			if articleID != 0 {
+				return articleID, nil
+			}
+			continue
+		}
+		articleID, err := findCommentPage(db, ref)
+		if err != nil {
+			return 0, err
+		}
+		articles[ref] = articleID
+		if articleID != 0 {
+			return articleID, nil
+		}
+	}
+	return 0, nil
+}
+
+func findCommentPage(db *gorm.DB, ref string) (uint, error) {
+	if parsed, err := url.Parse(ref); err == nil {
+		ref = parsed.Path
+	}
+	slug := path.Base(strings.TrimRight(ref, "/"))
+	slug = strings.TrimSuffix(slug, path.Ext(slug))
+	if slug == "" || slug == "." || slug == "/" {
+		return 0, nil
+	}
+	if id, err := strconv.ParseUint(slug, 10, 32); err == nil {
+		var article models.Article
+		if err := db.Select("id").Where("id = ?", id).Limit(1).Find(&article).Error; err != nil || article.ID != 0 {
+			return article.ID, err
+		}
+	}
+	articleID, _, err := ResolveArticleSlug(db, slug, "")
+	return articleID, err
+}
+
+// Disqus exports threads and posts side by side, posts naming their thread
+// and parent by Disqus ID
+type disqusExport struct {
+	Threads []struct {
+		DsqID      string `xml:"id,attr"`
+		Identifier string `xml:"id"`
+		Link       string `xml:"link"`
+	} `xml:"thread"`
+	Posts []struct {
+		DsqID     string `xml:"id,attr"`
+		Message   string `xml:"message"`
+		CreatedAt string `xml:"createdAt"`
+		IsDeleted bool   `xml:"isDeleted"`
+		IsSpam    bool   `xml:"isSpam"`
+		Author    struct {
+			Name     string `xml:"name"`
+			Username string `xml:"username"`
+		} `xml:"author"`
+		Thread struct {
+			DsqID string `xml:"id,attr"`
+		} `xml:"thread"`
+		Parent struct {
+			DsqID string `xml:"id,attr"`
+		} `xml:"parent"`
+	} `xml:"post"`
+}
+
+func parseDisqusExport(data []byte) ([]importedComment, error) {
+	var export disqusExport
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&export); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCommentImport, err)
+	}
+	threads := make(map[string][]string, len(export.Threads))
+	for _, thread := range export.Threads {
+		threads[thread.DsqID] = nonEmpty(thread.Link, thread.Identifier)
+	}
+	policy := security.GetGlobalHTMLPolicy()
+	var comments []importedComment
+	for _, post := range export.Posts {
+		if post.IsDeleted || post.IsSpam || post.DsqID == "" {
+			continue
+		}
+		createdAt, _ := time.Parse(time.RFC3339, strings.TrimSpace(post.CreatedAt))
+		author := firstNonEmpty(post.Author.Name, post.Author.Username)
+		authorURL := ""
+		if post.Author.Username != "" {
+			authorURL = "https://disqus.com/by/" + url.PathEscape(post.Author.Username) + "/"
+		}
+		comments = append(comments, importedComment{
+			ID:        post.DsqID,
+			ParentID:  post.Parent.DsqID,
+			Page:      threads[post.Thread.DsqID],
+			Author:    author,
+			AuthorURL: authorURL,
+			Content:   policy.Sanitize(strings.TrimSpace(post.Message)),
+			Status:    models.ReplyApproved,
+			CreatedAt: createdAt,
+		})
+	}
+	return comments, nil
+}
+
+// exportID reads IDs that exports write as strings or numbers
+type exportID string
+
+func (id *exportID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*id = ""
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = exportID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = exportID(n.String())
+	return nil
+}
+
+// Artalk exports comments in its Artrans format, which writes every value
+// as a string. rid is the comment replied to, "0" for none.
+type artalkComment struct {
+	ID        exportID `json:"id"`
+	RID       exportID `json:"rid"`
+	Content   string   `json:"content"`
+	Date      string   `json:"date"`
+	Nick      string   `json:"nick"`
+	Link      string   `json:"link"`
+	IsPending string   `json:"is_pending"`
+	PageKey   string   `json:"page_key"`
+}
+
+func parseArtalkExport(data []byte) ([]importedComment, error) {
+	var export []artalkComment
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCommentImport, err)
+	}
+	var comments []importedComment
+	for _, comment := range export {
+		if comment.ID == "" {
+			continue
+		}
+		content, err := renderCommentMarkdown(comment.Content)
+		if err != nil {
+			return nil, err
+		}
+		status := models.ReplyApproved
+		if comment.IsPending == "true" {
+			status = models.ReplyPending
+		}
+		parentID := string(comment.RID)
+		if parentID == "0" {
+			parentID = ""
+		}
+		comments = append(comments, importedComment{
+			ID:        string(comment.ID),
+			ParentID:  parentID,
+			Page:      nonEmpty(comment.PageKey),
+			Author:    comment.Nick,
+			AuthorURL: comment.Link,
+			Content:   content,
+			Status:    status,
+			CreatedAt: parseArtalkDate(comment.Date),
+		})
+	}
+	return comments, nil
+}
+
+// parseArtalkDate reads Artalk's dates, Go's default time format such as
+// "2021-10-28 20:50:15.123 +0800 CST"
+func parseArtalkDate(date string) time.Time {
+	fields := strings.Fields(date)
+	if len(fields) >= 3 {
+		if t, err := time.Parse("2006-01-02 15:04:05.999999999 -0700", strings.Join(fields[:3], " ")); err == nil {
+			return t
+		}
+	}
+	t, _ := time.Parse(time.RFC3339, date)
+	return t
+}
+
+// Waline exports every table of its storage; the comments are in
+// data.Comment, pid naming the comment replied to
+type walineExport struct {
+	Data struct {
+		Comment []struct {
+			ObjectID   exportID `json:"objectId"`
+			PID        exportID `json:"pid"`
+			Nick       string   `json:"nick"`
+			Link       string   `json:"link"`
+			Comment    string   `json:"comment"`
+			URL        string   `json:"url"`
+			Status     string   `json:"status"`
+			InsertedAt string   `json:"insertedAt"`
+			CreatedAt  string   `json:"createdAt"`
+		} `json:"Comment"`
+	} `json:"data"`
+}
+
+func parseWalineExport(data []byte) ([]importedComment, error) {
+	var export walineExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCommentImport, err)
+	}
+	var comments []importedComment
+	for _, comment := range export.Data.Comment {
+		status := models.ReplyApproved
+		switch comment.Status {
+		case "spam":
+			continue
+		case "waiting":
+			status = models.ReplyPending
+		}
+		if comment.ObjectID == "" {
+			continue
+		}
+		content, err := renderCommentMarkdown(comment.Comment)
+		if err != nil {
+			return nil, err
+		}
+		date := firstNonEmpty(comment.InsertedAt, comment.CreatedAt)
+		createdAt, err := time.Parse(time.RFC3339, date)
+		if err != nil {
+			createdAt, _ = time.Parse("2006-01-02 15:04:05", date)
+		}
+		comments = append(comments, importedComment{
+			ID:        string(comment.ObjectID),
+			ParentID:  string(comment.PID),
+			Page:      nonEmpty(comment.URL),
+			Author:    comment.Nick,
+			AuthorURL: comment.Link,
+			Content:   content,
+			Status:    status,
+			CreatedAt: createdAt,
+		})
+	}
+	return comments, nil
+}
+
+func renderCommentMarkdown(source string) (string, error) {
+	var out bytes.Buffer
+	if err := commentMarkdown.Convert([]byte(source), &out); err != nil {
+		return "", err
+	}
+	return security.GetGlobalHTMLPolicy().Sanitize(out.String()), nil
+}
+
+// nonEmpty returns the values that are not blank
+func nonEmpty(values ...string) []string {
+	var kept []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
+
+// webLink returns an http or https link, dropping anything else commenters
+// may have entered as their website
+func webLink(link string) string {
+	parsed, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ""
+	}
+	return parsed.String()
+}
+
+func firstNonEmpty(values ...string) string {
+	if kept := nonEmpty(values...); len(kept) > 0 {
+		return kept[0]
+	}
+	return ""
+}
+
+var (
+	globalCommentImporter     *CommentImporter
+	globalCommentImporterOnce sync.Once
+)
+
+// GetGlobalCommentImporter returns the global comment importer
+func GetGlobalCommentImporter() *CommentImporter {
+	globalCommentImporterOnce.Do(func() {
+		globalCommentImporter = NewCommentImporter()
+	})
+	return globalCommentImporter
+}
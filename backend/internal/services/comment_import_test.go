@@ -0,0 +1,142 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+const disqusExportXML = `<?xml version="1.0" encoding="utf-8"?>
+<disqus xmlns="http://disqus.com" xmlns:dsq="http://disqus.com/disqus-internals">
+  <thread dsq:id="100">
+    <id>hello-world</id>
+    <link>https://old.example.com/posts/hello-world/</link>
+  </thread>
+  <thread dsq:id="200">
+    <id>gone</id>
+    <link>https://old.example.com/posts/gone/</link>
+  </thread>
+  <post dsq:id="2">
+    <message><![CDATA[<p>Thanks!</p><script>alert(1)</script>]]></message>
+    <createdAt>2019-04-02T10:00:00Z</createdAt>
+    <isDeleted>false</isDeleted>
+    <isSpam>false</isSpam>
+    <author><name>Ada</name><username>ada</username></author>
+    <thread dsq:id="100"/>
+    <parent dsq:id="1"/>
+  </post>
+  <post dsq:id="1">
+    <message><![CDATA[<p>Great post</p>]]></message>
+    <createdAt>2019-04-01T09:30:00Z</createdAt>
+    <isDeleted>false</isDeleted>
+    <isSpam>false</isSpam>
+    <author><name>Bob</name></author>
+    <thread dsq:id="100"/>
+  </post>
+  <post dsq:id="3">
+    <message><![CDATA[<p>Buy now</p>]]></message>
+    <createdAt>2019-04-03T09:30:00Z</createdAt>
+    <isDeleted>false</isDeleted>
+    <isSpam>true</isSpam>
+    <author><name>Spammer</name></author>
+    <thread dsq:id="100"/>
+  </post>
+  <post dsq:id="4">
+    <message><![CDATA[<p>Lost</p>]]></message>
+    <createdAt>2019-04-03T09:30:00Z</createdAt>
+    <isDeleted>false</isDeleted>
+    <isSpam>false</isSpam>
+    <author><name>Eve</name></author>
+    <thread dsq:id="200"/>
+  </post>
+</disqus>`
+
+func TestCommentImport(t *testing.T) {
+	newTestDB(t)
+	if err := database.DB.Use(database.SiteScope{}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewCommentImporter()
+	ctx := database.WithSite(context.Background(), models.DefaultSiteID)
+	db := database.DB.WithContext(ctx)
+
+	hello := models.Article{Title: "Hello", SEOSlug: "hello-world"}
+	second := models.Article{Title: "Second"}
+	for _, article := range []*models.Article{&hello, &second} {
+		if err := db.Create(article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := s.Import(ctx, models.ReplySourceDisqus, []byte(disqusExportXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 2 || result.Threaded != 1 || result.Skipped != 1 ||
+		len(result.UnmatchedPages) != 1 || result.UnmatchedPages[0] != "https://old.example.com/posts/gone/" {
+		t.Fatalf("Import(disqus) = %+v", result)
+	}
+	var replies []models.FederatedReply
+	db.Where("article_id = ?", hello.ID).Order("published_at").Find(&replies)
+	if len(replies) != 2 {
+		t.Fatalf("imported replies = %+v", replies)
+	}
+	bob, ada := replies[0], replies[1]
+	if bob.AuthorName != "Bob" || bob.Status != models.ReplyApproved || bob.Source != models.ReplySourceDisqus ||
+		!bob.PublishedAt.Equal(time.Date(2019, 4, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("first comment = %+v", bob)
+	}
+	if ada.ParentID == nil || *ada.ParentID != bob.ID || strings.Contains(ada.Content, "script") ||
+		ada.AuthorURL != "https://disqus.com/by/ada/" {
+		t.Errorf("reply = %+v, want it under %d without the script", ada, bob.ID)
+	}
+
+	again, err := s.Import(ctx, models.ReplySourceDisqus, []byte(disqusExportXML))
+	if err != nil || again.Imported != 0 || again.Duplicates != 2 {
+		t.Errorf("importing again = %+v, %v", again, err)
+	}
+
+	// Artalk writes every value as a string and names pages by URL
+	artalk := `[
+		{"id": "7", "rid": "0", "content": "**Nice**", "date": "2021-10-28 20:50:15 +0800 CST",
+		 "nick": "Kim", "link": "javascript:alert(1)", "is_pending": "false", "page_key": "/articles/` + fmt.Sprint(second.ID) + `"},
+		{"id": "8", "rid": "7", "content": "Agreed", "date": "2021-10-29 08:00:00.5 +0800 CST",
+		 "nick": "Lee", "link": "https://lee.example", "is_pending": "true", "page_key": "/articles/` + fmt.Sprint(second.ID) + `"}
+	]`
+	result, err = s.Import(ctx, models.ReplySourceArtalk, []byte(artalk))
+	if err != nil || result.Imported != 2 || result.Threaded != 1 {
+		t.Fatalf("Import(artalk) = %+v, %v", result, err)
+	}
+	replies = nil
+	db.Where("article_id = ?", second.ID).Order("published_at").Find(&replies)
+	if len(replies) != 2 || replies[0].Content != "<p><strong>Nice</strong></p>\n" || replies[0].AuthorURL != "" ||
+		!replies[0].PublishedAt.Equal(time.Date(2021, 10, 28, 12, 50, 15, 0, time.UTC)) ||
+		replies[1].Status != models.ReplyPending || replies[1].AuthorURL != "https://lee.example" {
+		t.Errorf("Artalk replies = %+v", replies)
+	}
+
+	// Waline IDs may be numbers, and a reply can come before its parent
+	waline := `{"type": "waline", "data": {"Comment": [
+		{"objectId": 2, "pid": 1, "nick": "Mo", "comment": "Me too", "url": "/hello-world.html",
+		 "status": "approved", "insertedAt": "2022-01-02T00:00:00.000Z"},
+		{"objectId": 1, "pid": null, "nick": "Jo", "comment": "First", "url": "/hello-world.html",
+		 "status": "approved", "insertedAt": "2022-01-01T00:00:00.000Z"},
+		{"objectId": 3, "nick": "Bot", "comment": "spam", "url": "/hello-world.html", "status": "spam"}
+	]}}`
+	result, err = s.Import(ctx, models.ReplySourceWaline, []byte(waline))
+	if err != nil || result.Imported != 2 || result.Threaded != 1 || result.Skipped != 0 {
+		t.Fatalf("Import(waline) = %+v, %v", result, err)
+	}
+
+	if _, err := s.Import(ctx, "livere", nil); !errors.Is(err, ErrInvalidCommentImport) {
+		t.Errorf("unknown format = %v, want ErrInvalidCommentImport", err)
+	}
+	if _, err := s.Import(ctx, models.ReplySourceWaline, []byte("{")); !errors.Is(err, ErrInvalidCommentImport) {
+		t.Errorf("broken export = %v, want ErrInvalidCommentImport", err)
+	}
+}
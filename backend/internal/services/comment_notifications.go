@@ -0,0 +1,297 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// commentExcerptLength is the number of characters of a comment quoted in
+// a moderation notice
+const commentExcerptLength = 200
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// commentReplyKeySetting is the system setting holding the key that signs
+// reply unsubscribe links
+const commentReplyKeySetting = "comments.unsubscribe_key"
+
+// ErrInvalidUnsubscribeLink is returned for unsubscribe links that were not
+// signed by this site
+var ErrInvalidUnsubscribeLink = errors.New("invalid unsubscribe link")
+
+// commentReplyJob is the job payload for JobCommentsReply
+type commentReplyJob struct {
+	EntryID uint `json:"entry_id"`
+}
+
+// commentBatch is the job payload for JobCommentsNotify: the comments of a
+// site received in [Start, End)
+type commentBatch struct {
+	SiteID uint      `json:"site_id"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+}
+
+// CommentNotifier emails admins about comments waiting for moderation. The
+// first pending comment starts a batch and one email, sent when the batch
+// closes, lists every comment still pending from it, so a busy thread does
+// not flood the inbox.
+//
+// It also tells commenters about replies to them. Of the comments kept,
+// fediverse and webmention replies and imported comments carry no email
+// address, so these are the replies to guestbook entries whose senders
+// opted in. Each notice links to an unsubscribe URL signed with a key kept
+// in the database, which withdraws the opt-in of every entry of the address.
+type CommentNotifier struct {
+	db      func() *gorm.DB
+	email   string
+	delay   time.Duration
+	mailer  *Mailer
+	baseURL func(siteID uint) string
+
+	keyMu sync.Mutex
+	key   []byte
+}
+
+// NewCommentNotifier creates a comment notifier from the configuration
+func NewCommentNotifier() *CommentNotifier {
+	cfg := config.Get().Comments
+	return &CommentNotifier{
+		db:      func() *gorm.DB { return database.DB },
+		email:   cfg.NotifyEmail,
+		delay:   time.Duration(cfg.NotifyDelay),
+		mailer:  GetGlobalMailer(),
+		baseURL: siteBaseURL,
+	}
+}
+
+// registerHooks batches pending comments
+func (n *CommentNotifier) registerHooks() {
+	hooks.Register(hooks.CommentPending, "comment_notifications", n.schedule)
+}
+
+// schedule queues the notice for the batch a new pending comment falls in.
+// Batches are fixed windows of the notify delay, so every instance queues
+// the same job and only the first one is kept.
+func (n *CommentNotifier) schedule(ctx context.Context, event hooks.Event, payload interface{}) error {
+	if n.email == "" {
+		return nil
+	}
+	var siteID uint
+	var created time.Time
+	switch comment := payload.(type) {
+	case *models.FederatedReply:
+		siteID, created = comment.SiteID, comment.CreatedAt
+	case *models.GuestbookEntry:
+		siteID, created = comment.SiteID, comment.CreatedAt
+	default:
+		return nil
+	}
+	start := created.Truncate(n.delay)
+	batch := commentBatch{SiteID: siteID, Start: start, End: start.Add(n.delay)}
+	key := fmt.Sprintf("comments.notify:%d:%d", batch.SiteID, batch.Start.UnixNano())
+	_, err := GetGlobalJobQueue().EnqueueUniqueAt(JobCommentsNotify, batch, key, batch.End)
+	if errors.Is(err, ErrJobExists) {
+		return nil
+	}
+	return err
+}
+
+// Send emails the notice for a batch, skipping it when every comment was
+// moderated in the meantime
+func (n *CommentNotifier) Send(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var batch commentBatch
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		return nil, err
+	}
+	if n.email == "" {
+		return map[string]string{"skipped": "no notify email"}, nil
+	}
+
+	var replies []models.FederatedReply
+	if err := n.db().Where("site_id = ? AND status = ? AND created_at >= ? AND created_at < ?",
+		batch.SiteID, models.ReplyPending, batch.Start, batch.End).Order("created_at").Find(&replies).Error; err != nil {
+		return nil, err
+	}
+	var entries []models.GuestbookEntry
+	if err := n.db().Where("site_id = ? AND status = ? AND created_at >= ? AND created_at < ?",
+		batch.SiteID, models.ReplyPending, batch.Start, batch.End).Order("created_at").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	if len(replies) == 0 && len(entries) == 0 {
+		return map[string]string{"skipped": "no pending comments"}, nil
+	}
+
+	articleIDs := make([]uint, len(replies))
+	for i, reply := range replies {
+		articleIDs[i] = reply.ArticleID
+	}
+	var articles []models.Article
+	if err := n.db().Select("id", "title").Where("id IN ?", articleIDs).Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	titles := make(map[uint]string, len(articles))
+	for _, article := range articles {
+		titles[article.ID] = article.Title
+	}
+
+	language := siteLanguage(n.db(), batch.SiteID)
+	comments := make([]map[string]interface{}, 0, len(replies)+len(entries))
+	for _, reply := range replies {
+		comments = append(comments, map[string]interface{}{
+			"Author":       reply.AuthorName,
+			"ArticleTitle": titles[reply.ArticleID],
+			"Excerpt":      commentExcerpt(reply.Content),
+		})
+	}
+	for _, entry := range entries {
+		comments = append(comments, map[string]interface{}{
+			"Author":       entry.Name,
+			"ArticleTitle": guestbookTitle(language),
+			"Excerpt":      truncateRunes(strings.Join(strings.Fields(entry.Message), " "), commentExcerptLength),
+		})
+	}
+	data := map[string]interface{}{
+		"Count":    len(comments),
+		"Comments": comments,
+		"Link":     strings.TrimRight(n.baseURL(batch.SiteID), "/") + "/" + language + "/admin",
+	}
+	if err := n.mailer.SendTemplate(n.email, MailTemplateCommentPending, language, data, nil); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"comments": len(comments)}, nil
+}
+
+// SendReply emails the sender of a guestbook entry about the site's reply,
+// unless the reply was removed or they unsubscribed in the meantime
+func (n *CommentNotifier) SendReply(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var job commentReplyJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, err
+	}
+	var entry models.GuestbookEntry
+	if err := n.db().WithContext(ctx).First(&entry, job.EntryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return map[string]string{"skipped": "entry deleted"}, nil
+		}
+		return nil, err
+	}
+	if entry.Reply == "" || entry.Email == "" || !entry.NotifyReplies || entry.Status != models.ReplyApproved {
+		return map[string]string{"skipped": "no reply to notify"}, nil
+	}
+
+	unsubscribe, err := n.unsubscribeLink(entry.SiteID, entry.Email)
+	if err != nil {
+		return nil, err
+	}
+	var settings models.SiteSettings
+	if err := n.db().Where("site_id = ?", entry.SiteID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"Name":            entry.Name,
+		"ArticleTitle":    guestbookTitle(entry.Language),
+		"ReplyAuthor":     settings.SiteTitle,
+		"Reply":           entry.Reply,
+		"Link":            strings.TrimRight(n.baseURL(entry.SiteID), "/") + "/" + entry.Language + "/guestbook",
+		"UnsubscribeLink": unsubscribe,
+	}
+	headers := map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribe + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	if err := n.mailer.SendTemplate(entry.Email, MailTemplateCommentReply, entry.Language, data, headers); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"entry": entry.ID}, nil
+}
+
+// Unsubscribe withdraws the reply opt-in of every guestbook entry the
+// address left on the site, after checking the link's signature
+func (n *CommentNotifier) Unsubscribe(ctx context.Context, siteID uint, email, signature string) error {
+	want, err := n.unsubscribeSignature(siteID, email)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(signature), []byte(want)) {
+		return ErrInvalidUnsubscribeLink
+	}
+	return n.db().WithContext(ctx).Model(&models.GuestbookEntry{}).
+		Where("site_id = ? AND LOWER(email) = ?", siteID, strings.ToLower(email)).
+		Update("notify_replies", false).Error
+}
+
+// unsubscribeLink returns the signed link that stops reply emails to email
+func (n *CommentNotifier) unsubscribeLink(siteID uint, email string) (string, error) {
+	signature, err := n.unsubscribeSignature(siteID, email)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{"email": {email}, "sig": {signature}}
+	return strings.TrimRight(n.baseURL(siteID), "/") + "/api/comments/unsubscribe?" + query.Encode(), nil
+}
+
+// unsubscribeSignature signs a site and address, ignoring the case of the
+// address
+func (n *CommentNotifier) unsubscribeSignature(siteID uint, email string) (string, error) {
+	key, err := n.unsubscribeKey()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d|%s", siteID, strings.ToLower(email))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// unsubscribeKey returns the key signing unsubscribe links
+func (n *CommentNotifier) unsubscribeKey() ([]byte, error) {
+	n.keyMu.Lock()
+	defer n.keyMu.Unlock()
+	if n.key != nil {
+		return n.key, nil
+	}
+	key, err := loadSigningKey(n.db(), commentReplyKeySetting)
+	if err != nil {
+		return nil, err
+	}
+	n.key = key
+	return key, nil
+}
+
+// commentExcerpt turns a sanitized HTML comment into a line of plain text
+func commentExcerpt(content string) string {
+	text := html.UnescapeString(htmlTag.ReplaceAllString(content, " "))
+	return truncateRunes(strings.Join(strings.Fields(text), " "), commentExcerptLength)
+}
+
+var (
+	globalCommentNotifier *CommentNotifier
+	commentNotifierOnce   sync.Once
+)
+
+// GetGlobalCommentNotifier returns the global comment notifier, registering
+// its hook on first use
+func GetGlobalCommentNotifier() *CommentNotifier {
+	commentNotifierOnce.Do(func() {
+		globalCommentNotifier = NewCommentNotifier()
+		globalCommentNotifier.registerHooks()
+	})
+	return globalCommentNotifier
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestCommentNotifierBatches(t *testing.T) {
+	m, sent := newTestMailer(t)
+	if _, err := m.SaveSettings(MailSettings{Host: "smtp.example.com", From: "blog@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	n := &CommentNotifier{
+		db:      func() *gorm.DB { return database.DB },
+		email:   "admin@example.com",
+		delay:   10 * time.Minute,
+		mailer:  m,
+		baseURL: func(uint) string { return "https://blog.example.com" },
+	}
+	ctx := context.Background()
+
+	article := models.Article{Title: "Hello World"}
+	database.DB.Create(&article)
+	created := time.Now().Truncate(10 * time.Minute).Add(time.Minute)
+	for i, author := range []string{"Alice", "Bob", "Carol"} {
+		reply := models.FederatedReply{
+			ArticleID: article.ID, ObjectID: author, ActorID: author, AuthorName: author,
+			Content: "<p>Nice &amp; <b>clear</b></p>", Status: models.ReplyPending, CreatedAt: created.Add(time.Duration(i) * time.Second),
+		}
+		database.DB.Create(&reply)
+		if err := n.schedule(ctx, hooks.CommentPending, &reply); err != nil {
+			t.Fatal(err)
+		}
+	}
+	database.DB.Model(&models.FederatedReply{}).Where("author_name = ?", "Carol").Update("status", models.ReplyApproved)
+	entry := models.GuestbookEntry{Name: "Dave", Message: "Hello\nthere", Language: "en", Status: models.ReplyPending, CreatedAt: created.Add(time.Minute)}
+	database.DB.Create(&entry)
+	if err := n.schedule(ctx, hooks.CommentPending, &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	var jobs []models.Job
+	database.DB.Where("type = ?", JobCommentsNotify).Find(&jobs)
+	if len(jobs) != 1 || !jobs[0].RunAt.Equal(created.Truncate(10*time.Minute).Add(10*time.Minute)) {
+		t.Fatalf("expected one notice at the end of the batch, got %+v", jobs)
+	}
+	if _, err := n.Send(ctx, json.RawMessage(jobs[0].Payload)); err != nil {
+		t.Fatal(err)
+	}
+	if len(*sent) != 1 {
+		t.Fatalf("expected one email, got %d", len(*sent))
+	}
+	message := (*sent)[0]
+	if !strings.Contains(message, "Alice") || !strings.Contains(message, "Bob") || strings.Contains(message, "Carol") ||
+		!strings.Contains(message, "Nice & clear") || !strings.Contains(message, "Dave on \"Guestbook\":\n  Hello there") || !strings.Contains(message, "https://blog.example.com/en/admin") {
+		t.Errorf("unexpected notice %q", message)
+	}
+}
+
+func TestCommentNotifierReplies(t *testing.T) {
+	m, sent := newTestMailer(t)
+	if _, err := m.SaveSettings(MailSettings{Host: "smtp.example.com", From: "blog@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	n := &CommentNotifier{
+		db:      func() *gorm.DB { return database.DB },
+		mailer:  m,
+		baseURL: func(uint) string { return "https://blog.example.com" },
+	}
+	ctx := context.Background()
+	database.DB.Create(&models.SiteSettings{SiteTitle: "Kuno"})
+
+	guestbook := &GuestbookService{db: func() *gorm.DB { return database.DB }}
+	optedIn, _ := newGuestbookEntry(GuestbookInput{Name: "Alice", Email: "Alice@example.com", Message: "Hi", Language: "en", NotifyReplies: true})
+	silent, _ := newGuestbookEntry(GuestbookInput{Name: "Bob", Message: "Hey", Language: "en", NotifyReplies: true})
+	for _, entry := range []*models.GuestbookEntry{optedIn, silent} {
+		database.DB.Create(entry)
+	}
+	if silent.NotifyReplies {
+		t.Error("an entry without an address opted in to reply emails")
+	}
+	if _, err := guestbook.Reply(ctx, optedIn.ID, "Thanks!"); !errors.Is(err, ErrInvalidGuestbookEntry) {
+		t.Errorf("replying to a pending entry: err = %v", err)
+	}
+	database.DB.Model(&models.GuestbookEntry{}).Where("1 = 1").Update("status", models.ReplyApproved)
+	for _, entry := range []*models.GuestbookEntry{optedIn, silent} {
+		if _, err := guestbook.Reply(ctx, entry.ID, "Thanks!"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Editing the reply sends nothing more
+	if _, err := guestbook.Reply(ctx, optedIn.ID, "Thanks a lot!"); err != nil {
+		t.Fatal(err)
+	}
+
+	var jobs []models.Job
+	database.DB.Where("type = ?", JobCommentsReply).Find(&jobs)
+	if len(jobs) != 1 {
+		t.Fatalf("expected one reply notice, got %d", len(jobs))
+	}
+	if _, err := n.SendReply(ctx, json.RawMessage(jobs[0].Payload)); err != nil {
+		t.Fatal(err)
+	}
+	if len(*sent) != 1 {
+		t.Fatalf("expected one email, got %d", len(*sent))
+	}
+	message := (*sent)[0]
+	link, err := n.unsubscribeLink(1, "Alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(message, "Kuno replied to your comment on \"Guestbook\"") || !strings.Contains(message, "Thanks a lot!") ||
+		!strings.Contains(message, "List-Unsubscribe: <"+link+">") {
+		t.Errorf("unexpected notice %q", message)
+	}
+
+	signature := link[strings.LastIndex(link, "sig=")+4:]
+	if err := n.Unsubscribe(ctx, 2, "alice@example.com", signature); !errors.Is(err, ErrInvalidUnsubscribeLink) {
+		t.Errorf("link of another site: err = %v", err)
+	}
+	if err := n.Unsubscribe(ctx, 1, "bob@example.com", signature); !errors.Is(err, ErrInvalidUnsubscribeLink) {
+		t.Errorf("link of another address: err = %v", err)
+	}
+	if err := n.Unsubscribe(ctx, 1, "ALICE@example.com", signature); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := n.SendReply(ctx, json.RawMessage(jobs[0].Payload)); err != nil || len(*sent) != 1 {
+		t.Errorf("sent after unsubscribing: %v, %v", result, err)
+	}
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxMessageLinks is the most links a visitor's message may carry before
+	// it is filed as spam
+	maxMessageLinks = 3
+	// duplicateMessageWindow is how long an identical message from the same
+	// sender is treated as spam
+	duplicateMessageWindow = 24 * time.Hour
+)
+
+var (
+	ErrInvalidContactMessage  = errors.New("invalid contact message")
+	ErrContactMessageNotFound = errors.New("contact message not found")
+	ErrContactRateLimited     = errors.New("too many messages, please try again later")
+)
+
+var messageLink = regexp.MustCompile(`(?i)https?://|www\.`)
+
+// ContactInput is a message submitted through the contact form. Website is
+// a honeypot: the form hides it, so only bots fill it in.
+type ContactInput struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+	Website string `json:"website"`
+}
+
+// ContactUpdate changes the inbox state of a message; nil fields are left
+// unchanged
+type ContactUpdate struct {
+	Status  *string `json:"status"`
+	Replied *bool   `json:"replied"`
+}
+
+// ContactStats counts a site's contact messages by status
+type ContactStats map[string]int64
+
+// contactForwardJob is the payload of JobContactForward
+type contactForwardJob struct {
+	MessageID uint `json:"message_id"`
+}
+
+// ContactService stores the messages of the contact form in an inbox the
+// admin works through, and forwards them by email when CONTACT_FORWARD_EMAIL
+// is set. Senders are rate limited by IP address; honeypot submissions are
+// accepted and discarded, and link-heavy or repeated messages are filed as
+// spam.
+type ContactService struct {
+	db           func() *gorm.DB
+	forwardEmail string
+	rateLimit    int
+	rateWindow   time.Duration
+	// sendMail is replaced in tests
+	sendMail func(to, subject, body string, headers map[string]string) error
+	baseURL  func(siteID uint) string
+}
+
+// NewContactService creates a contact service from the CONTACT_* settings
+func NewContactService() *ContactService {
+	cfg := config.Get().Contact
+	return &ContactService{
+		db:           func() *gorm.DB { return database.DB },
+		forwardEmail: cfg.ForwardEmail,
+		rateLimit:    cfg.RateLimit,
+		rateWindow:   cfg.RateWindow.Std(),
+		sendMail:     sendEmail,
+		baseURL:      siteBaseURL,
+	}
+}
+
+// Submit stores a message for the site in ctx and queues it for forwarding.
+// The returned message is nil when the submission was discarded as spam.
+func (s *ContactService) Submit(ctx context.Context, input ContactInput, ip, userAgent string) (*models.ContactMessage, error) {
+	if input.Website != "" {
+		slog.Info("Discarding contact message that filled in the honeypot", "ip", ip)
+		return nil, nil
+	}
+	message, err := newContactMessage(input)
+	if err != nil {
+		return nil, err
+	}
+	message.IPAddress = truncateRunes(ip, 45)
+	message.UserAgent = truncateRunes(userAgent, 500)
+
+	db := s.db().WithContext(ctx)
+	if limited, err := rateLimited(db, &models.ContactMessage{}, message.IPAddress, s.rateLimit, s.rateWindow); err != nil {
+		return nil, err
+	} else if limited {
+		return nil, ErrContactRateLimited
+	}
+	if spam, err := looksLikeSpam(db, &models.ContactMessage{}, "email", message.Email, message.Message); err != nil {
+		return nil, err
+	} else if spam {
+		message.Status = models.ContactSpam
+	}
+	if err := db.Create(message).Error; err != nil {
+		return nil, err
+	}
+
+	if s.forwardEmail != "" && message.Status != models.ContactSpam {
+		if _, err := GetGlobalJobQueue().Enqueue(JobContactForward, contactForwardJob{MessageID: message.ID}); err != nil {
+			slog.Error("Failed to queue contact message forwarding", "message_id", message.ID, "error", err)
+		}
+	}
+	return message, nil
+}
+
+// rateLimited reports whether ip already sent limit submissions stored as
+// model within window
+func rateLimited(db *gorm.DB, model interface{}, ip string, limit int, window time.Duration) (bool, error) {
+	var recent int64
+	if err := db.Model(model).Where("ip_address = ? AND created_at > ?", ip, time.Now().Add(-window)).
+		Count(&recent).Error; err != nil {
+		return false, err
+	}
+	return recent >= int64(limit), nil
+}
+
+// looksLikeSpam reports whether a visitor's message carries too many links
+// or repeats one stored as model from the same sender, identified by the
+// sender column, within a day
+func looksLikeSpam(db *gorm.DB, model interface{}, senderColumn, sender, message string) (bool, error) {
+	if len(messageLink.FindAllStringIndex(message, -1)) > maxMessageLinks {
+		return true, nil
+	}
+	var duplicates int64
+	if err := db.Model(model).Where(senderColumn+" = ? AND message = ? AND created_at > ?", sender, message, time.Now().Add(-duplicateMessageWindow)).
+		Count(&duplicates).Error; err != nil {
+		return false, err
+	}
+	return duplicates > 0, nil
+}
+
+// newContactMessage validates and normalizes a submission
+func newContactMessage(input ContactInput) (*models.ContactMessage, error) {
+	name := strings.Join(strings.Fields(input.Name), " ")
+	subject := strings.Join(strings.Fields(input.Subject), " ")
+	body := strings.TrimSpace(input.Message)
+	address, err := mail.ParseAddress(strings.TrimSpace(input.Email))
+	switch {
+	case name == "" || utf8.RuneCountInString(name) > 100:
+		return nil, fmt.Errorf("%w: name is required and at most 100 characters", ErrInvalidContactMessage)
+	case err != nil || address.Address != strings.TrimSpace(input.Email) || len(address.Address) > 254:
+		return nil, ErrInvalidEmail
+	case utf8.RuneCountInString(subject) > 200:
+		return nil, fmt.Errorf("%w: subject is at most 200 characters", ErrInvalidContactMessage)
+	case body == "" || utf8.RuneCountInString(body) > 5000:
+		return nil, fmt.Errorf("%w: message is required and at most 5000 characters", ErrInvalidContactMessage)
+	}
+	return &models.ContactMessage{
+		Name:    name,
+		Email:   address.Address,
+		Subject: subject,
+		Message: body,
+		Status:  models.ContactNew,
+	}, nil
+}
+
+// Forward emails a stored message to the forward address, with Reply-To set
+// to the sender so the admin can answer from their mail client
+func (s *ContactService) Forward(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var job contactForwardJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, err
+	}
+	if s.forwardEmail == "" {
+		return map[string]string{"skipped": "no forward email"}, nil
+	}
+	var message models.ContactMessage
+	if err := s.db().WithContext(ctx).First(&message, job.MessageID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return map[string]string{"skipped": "message deleted"}, nil
+		}
+		return nil, err
+	}
+
+	language := siteLanguage(s.db(), message.SiteID)
+	subject, body, err := GetGlobalMailer().Render(MailTemplateContactMessage, language, map[string]interface{}{
+		"Name":    message.Name,
+		"Email":   message.Email,
+		"Subject": message.Subject,
+		"Message": message.Message,
+		"Link":    strings.TrimRight(s.baseURL(message.SiteID), "/") + "/" + language + "/admin",
+	})
+	if err != nil {
+		return nil, err
+	}
+	replyTo := (&mail.Address{Name: message.Name, Address: message.Email}).String()
+	if err := s.sendMail(s.forwardEmail, subject, body, map[string]string{"Reply-To": replyTo}); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"forwarded": message.ID}, nil
+}
+
+// List returns a page of the site's messages, newest first. Without a
+// status every message but spam is listed.
+func (s *ContactService) List(ctx context.Context, status string, page, limit int) ([]models.ContactMessage, int64, error) {
+	query := s.db().WithContext(ctx).Model(&models.ContactMessage{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status <> ?", models.ContactSpam)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	messages := []models.ContactMessage{}
+	err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&messages).Error
+	return messages, total, err
+}
+
+// Stats counts the site's messages by status
+func (s *ContactService) Stats(ctx context.Context) (ContactStats, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := s.db().WithContext(ctx).Model(&models.ContactMessage{}).
+		Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	stats := ContactStats{
+		models.ContactNew: 0, models.ContactRead: 0, models.ContactArchived: 0, models.ContactSpam: 0,
+	}
+	for _, row := range rows {
+		stats[row.Status] = row.Count
+	}
+	return stats, nil
+}
+
+// Get returns one of the site's messages
+func (s *ContactService) Get(ctx context.Context, id uint) (*models.ContactMessage, error) {
+	var message models.ContactMessage
+	if err := s.db().WithContext(ctx).First(&message, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactMessageNotFound
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// Update moves a message between the inbox states and records whether it
+// was replied to. Replying also marks a new message as read.
+func (s *ContactService) Update(ctx context.Context, id uint, update ContactUpdate) (*models.ContactMessage, error) {
+	message, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	updates := map[string]interface{}{}
+	if update.Status != nil {
+		switch *update.Status {
+		case models.ContactNew, models.ContactRead, models.ContactArchived, models.ContactSpam:
+			updates["status"] = *update.Status
+		default:
+			return nil, fmt.Errorf("%w: status must be new, read, archived or spam", ErrInvalidContactMessage)
+		}
+	}
+	if update.Replied != nil {
+		if !*update.Replied {
+			updates["replied_at"] = nil
+		} else if message.RepliedAt == nil {
+			updates["replied_at"] = time.Now()
+			if update.Status == nil && message.Status == models.ContactNew {
+				updates["status"] = models.ContactRead
+			}
+		}
+	}
+	if len(updates) > 0 {
+		if err := s.db().WithContext(ctx).Model(message).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return s.Get(ctx, id)
+}
+
+// Delete removes a message
+func (s *ContactService) Delete(ctx context.Context, id uint) error {
+	result := s.db().WithContext(ctx).Delete(&models.ContactMessage{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrContactMessageNotFound
+	}
+	return nil
+}
+
+var (
+	globalContactService     *ContactService
+	globalContactServiceOnce sync.Once
+)
+
+// GetGlobalContactService returns the global contact service
+func GetGlobalContactService() *ContactService {
+	globalContactServiceOnce.Do(func() {
+		globalContactService = NewContactService()
+	})
+	return globalContactService
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newTestContactService(t *testing.T) (*ContactService, *[]sentMail) {
+	newTestDB(t)
+	var outbox []sentMail
+	s := &ContactService{
+		db:           func() *gorm.DB { return database.DB },
+		forwardEmail: "owner@example.com",
+		rateLimit:    3,
+		rateWindow:   time.Hour,
+		sendMail: func(to, subject, body string, headers map[string]string) error {
+			outbox = append(outbox, sentMail{to, subject, body, headers})
+			return nil
+		},
+		baseURL: func(uint) string { return "https://blog.example.com" },
+	}
+	return s, &outbox
+}
+
+func TestContactSubmit(t *testing.T) {
+	s, outbox := newTestContactService(t)
+	ctx := context.Background()
+	input := ContactInput{Name: " Alice\r\nBcc: x ", Email: "alice@example.com", Subject: "Hi", Message: "Nice blog!"}
+
+	for _, bad := range []ContactInput{
+		{Email: "alice@example.com", Message: "Hi"},
+		{Name: "Alice", Email: "Alice <alice@example.com>", Message: "Hi"},
+		{Name: "Alice", Email: "alice@example.com", Message: "   "},
+	} {
+		if _, err := s.Submit(ctx, bad, "192.0.2.1", ""); !errors.Is(err, ErrInvalidContactMessage) && !errors.Is(err, ErrInvalidEmail) {
+			t.Errorf("Submit(%+v) = %v, want a validation error", bad, err)
+		}
+	}
+
+	honeypot := input
+	honeypot.Website = "http://spam.example"
+	if message, err := s.Submit(ctx, honeypot, "192.0.2.1", ""); err != nil || message != nil {
+		t.Errorf("honeypot submission = %v, %v; want it discarded", message, err)
+	}
+
+	message, err := s.Submit(ctx, input, "192.0.2.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Status != models.ContactNew || message.Name != "Alice Bcc: x" {
+		t.Errorf("stored %+v", message)
+	}
+
+	payload, _ := json.Marshal(contactForwardJob{MessageID: message.ID})
+	if _, err := s.Forward(ctx, payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(*outbox) != 1 || (*outbox)[0].to != "owner@example.com" || !strings.Contains((*outbox)[0].body, "Nice blog!") {
+		t.Fatalf("unexpected forwarded mail %+v", *outbox)
+	}
+	if replyTo := (*outbox)[0].headers["Reply-To"]; replyTo != `"Alice Bcc: x" <alice@example.com>` {
+		t.Errorf("Reply-To = %q", replyTo)
+	}
+
+	// The same message again and a message full of links are filed as spam
+	duplicate, err := s.Submit(ctx, input, "192.0.2.1", "")
+	if err != nil || duplicate.Status != models.ContactSpam {
+		t.Errorf("duplicate = %+v, %v; want spam", duplicate, err)
+	}
+	links := ContactInput{Name: "Bob", Email: "bob@example.com", Message: "http://a http://b www.c https://d"}
+	if spam, err := s.Submit(ctx, links, "192.0.2.1", ""); err != nil || spam.Status != models.ContactSpam {
+		t.Errorf("link spam = %+v, %v; want spam", spam, err)
+	}
+	if _, err := s.Submit(ctx, input, "192.0.2.1", ""); !errors.Is(err, ErrContactRateLimited) {
+		t.Errorf("fourth message = %v, want ErrContactRateLimited", err)
+	}
+	if _, err := s.Submit(ctx, links, "192.0.2.2", ""); err != nil {
+		t.Errorf("another address is limited: %v", err)
+	}
+
+	messages, total, err := s.List(ctx, "", 1, 10)
+	if err != nil || total != 1 || len(messages) != 1 || messages[0].ID != message.ID {
+		t.Errorf("inbox = %+v (%d), %v; want only the first message", messages, total, err)
+	}
+}
+
+func TestContactUpdate(t *testing.T) {
+	s, _ := newTestContactService(t)
+	ctx := context.Background()
+	message, err := s.Submit(ctx, ContactInput{Name: "Alice", Email: "alice@example.com", Message: "Hello"}, "192.0.2.1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replied := true
+	updated, err := s.Update(ctx, message.ID, ContactUpdate{Replied: &replied})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.RepliedAt == nil || updated.Status != models.ContactRead {
+		t.Errorf("after reply %+v, want read and replied", updated)
+	}
+
+	archived := models.ContactArchived
+	replied = false
+	if updated, err = s.Update(ctx, message.ID, ContactUpdate{Status: &archived, Replied: &replied}); err != nil {
+		t.Fatal(err)
+	}
+	if updated.RepliedAt != nil || updated.Status != models.ContactArchived {
+		t.Errorf("after archive %+v", updated)
+	}
+
+	invalid := "deleted"
+	if _, err := s.Update(ctx, message.ID, ContactUpdate{Status: &invalid}); !errors.Is(err, ErrInvalidContactMessage) {
+		t.Errorf("invalid status = %v", err)
+	}
+	stats, err := s.Stats(ctx)
+	if err != nil || stats[models.ContactArchived] != 1 || stats[models.ContactNew] != 0 {
+		t.Errorf("stats = %v, %v", stats, err)
+	}
+
+	if err := s.Delete(ctx, message.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, message.ID); !errors.Is(err, ErrContactMessageNotFound) {
+		t.Errorf("deleted message = %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrContainerInitialized is returned by InitContainer once the global
+// container is in use
+var ErrContainerInitialized = errors.New("service container already initialized")
+
+// Container holds the services behind recommendations and the content
+// assistant, which share one cache, embedding service and behavior tracker.
+// NewContainer wires them explicitly, so tests can build a container of
+// their own instead of going through the globals.
+type Container struct {
+	Cache                *SmartCache
+	Embeddings           *EmbeddingService
+	BehaviorTracker      *BehaviorTracker
+	RecommendationEngine *RecommendationEngine
+	ContentAssistant     *ContentAssistant
+}
+
+// NewContainer builds the services in dependency order. It needs the
+// database, which the embedding service reads its settings from.
+func NewContainer() *Container {
+	c := &Container{Cache: NewSmartCache(DefaultCacheConfig())}
+	c.Embeddings = NewEmbeddingService()
+	c.BehaviorTracker = NewBehaviorTracker(c.Cache)
+	c.RecommendationEngine = NewRecommendationEngine(c.Embeddings, c.BehaviorTracker, c.Cache)
+	c.ContentAssistant = NewContentAssistant(c.Embeddings, c.Cache)
+	return c
+}
+
+// Close stops the behavior tracker after saving its queue
+func (c *Container) Close() {
+	c.BehaviorTracker.Stop()
+}
+
+// The global container; the pointer is only set inside containerOnce but
+// Shutdown reads it without building one
+var (
+	globalContainer atomic.Pointer[Container]
+	containerOnce   sync.Once
+)
+
+// InitContainer installs c as the global container. main calls it at
+// startup, before requests or jobs can reach the GetGlobal accessors;
+// it fails once a container is installed.
+func InitContainer(c *Container) error {
+	installed := false
+	containerOnce.Do(func() {
+		globalContainer.Store(c)
+		installed = true
+	})
+	if !installed {
+		return ErrContainerInitialized
+	}
+	return nil
+}
+
+// GetGlobalContainer returns the global container, building one on first
+// use when InitContainer was not called, as in the CLI
+func GetGlobalContainer() *Container {
+	containerOnce.Do(func() {
+		globalContainer.Store(NewContainer())
+	})
+	return globalContainer.Load()
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestNewContainer(t *testing.T) {
+	newTestDB(t)
+	c := NewContainer()
+	t.Cleanup(c.Close)
+
+	if c.BehaviorTracker.cache != c.Cache || c.RecommendationEngine.cache != c.Cache || c.ContentAssistant.cache != c.Cache {
+		t.Error("the services do not share the container's cache")
+	}
+	if c.RecommendationEngine.behaviorTracker != c.BehaviorTracker {
+		t.Error("the recommendation engine has another behavior tracker")
+	}
+	if c.RecommendationEngine.embeddingService != c.Embeddings || c.ContentAssistant.embeddingService != c.Embeddings {
+		t.Error("the services do not share the container's embedding service")
+	}
+}
+
+// TestGetGlobalContainerConcurrent runs the accessors from many goroutines
+// at once, which go test -race reports if the first use races
+func TestGetGlobalContainerConcurrent(t *testing.T) {
+	newTestDB(t)
+
+	const n = 16
+	engines := make([]*RecommendationEngine, n)
+	trackers := make([]*BehaviorTracker, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			engines[i] = GetGlobalRecommendationEngine()
+			trackers[i] = GetGlobalBehaviorTracker()
+			GetGlobalCache()
+			GetGlobalEmbeddingService()
+			GetGlobalContentAssistant()
+		}(i)
+	}
+	wg.Wait()
+
+	c := GetGlobalContainer()
+	for i := 0; i < n; i++ {
+		if engines[i] != c.RecommendationEngine || trackers[i] != c.BehaviorTracker {
+			t.Fatalf("goroutine %d got services outside the global container", i)
+		}
+	}
+	if err := InitContainer(&Container{}); !errors.Is(err, ErrContainerInitialized) {
+		t.Errorf("InitContainer() after use = %v, want ErrContainerInitialized", err)
+	}
+	if GetGlobalContainer() != c {
+		t.Error("InitContainer replaced the global container")
+	}
+}
@@ -3,12 +3,12 @@ package services
 import (
 	"blog-backend/internal/database"
 	"blog-backend/internal/models"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"log"
 	"math"
-	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -86,10 +86,10 @@ type TopicCluster struct {
 }
 
 // NewContentAssistant creates a new content assistant instance
-func NewContentAssistant() *ContentAssistant {
+func NewContentAssistant(embeddingService *EmbeddingService, cache *SmartCache) *ContentAssistant {
 	return &ContentAssistant{
-		embeddingService: GetGlobalEmbeddingService(),
-		cache:            GetGlobalCache(),
+		embeddingService: embeddingService,
+		cache:            cache,
 		usageTracker:     NewAIUsageTracker(),
 	}
 }
@@ -99,10 +99,9 @@ func (ca *ContentAssistant) AnalyzeTopicGaps(language string) (*ContentGapAnalys
 	cacheKey := fmt.Sprintf("topic_gaps_%s_%d", language, time.Now().Unix()/3600)
 
 	// Try cache first
-	if cached, exists := ca.cache.Get(cacheKey); exists {
-		if analysis, ok := cached.(*ContentGapAnalysis); ok {
-			return analysis, nil
-		}
+	var cached ContentGapAnalysis
+	if ca.cache.Get(cacheKey, &cached) {
+		return &cached, nil
 	}
 
 	// Get all articles with embeddings
@@ -170,10 +169,9 @@ func (ca *ContentAssistant) GetWritingInspiration(category string, language stri
 	cacheKey := fmt.Sprintf("writing_inspiration_%s_%s_%d", category, language, limit)
 
 	// Try cache first
-	if cached, exists := ca.cache.Get(cacheKey); exists {
-		if ideas, ok := cached.([]WritingIdea); ok {
-			return ideas, nil
-		}
+	var cached []WritingIdea
+	if ca.cache.Get(cacheKey, &cached) {
+		return cached, nil
 	}
 
 	var ideas []WritingIdea
@@ -224,15 +222,14 @@ func (ca *ContentAssistant) GetWritingInspiration(category string, language stri
 }
 
 // GenerateSmartTags generates intelligent tags for content
-func (ca *ContentAssistant) GenerateSmartTags(content string, language string) ([]SmartTag, error) {
+func (ca *ContentAssistant) GenerateSmartTags(ctx context.Context, content string, language string) ([]SmartTag, error) {
 	// Quick cache check
 	contentHash := fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
 	cacheKey := fmt.Sprintf("smart_tags_%s", contentHash[:16])
 
-	if cached, exists := ca.cache.Get(cacheKey); exists {
-		if tags, ok := cached.([]SmartTag); ok {
-			return tags, nil
-		}
+	var cached []SmartTag
+	if ca.cache.Get(cacheKey, &cached) {
+		return cached, nil
 	}
 
 	var tags []SmartTag
@@ -243,7 +240,7 @@ func (ca *ContentAssistant) GenerateSmartTags(content string, language string) (
 
 	// Analyze content with embeddings for semantic tags
 	if ca.embeddingService != nil {
-		semanticTags, err := ca.generateSemanticTags(content, language)
+		semanticTags, err := ca.generateSemanticTags(ctx, content, language)
 		if err == nil {
 			tags = append(tags, semanticTags...)
 			log.Printf("Generated %d semantic tags", len(semanticTags))
@@ -289,10 +286,9 @@ func (ca *ContentAssistant) RecommendSEOKeywords(content string, targetLanguage
 	contentHash := fmt.Sprintf("%x", sha256.Sum256([]byte(content+primaryKeyword)))
 	cacheKey := fmt.Sprintf("seo_keywords_%s", contentHash[:16])
 
-	if cached, exists := ca.cache.Get(cacheKey); exists {
-		if keywords, ok := cached.([]SEOKeyword); ok {
-			return keywords, nil
-		}
+	var cached []SEOKeyword
+	if ca.cache.Get(cacheKey, &cached) {
+		return cached, nil
 	}
 
 	var keywords []SEOKeyword
@@ -466,7 +462,7 @@ func (ca *ContentAssistant) kMeansClustering(vectors [][]float64, embeddings []m
 			// Extract keywords from article title
 			if embeddings[i].Article.ID != 0 {
 				title := embeddings[i].Article.Title
-				keywords := ca.extractSimpleKeywords(title)
+				keywords := ExtractKeywords(title, embeddings[i].Language)
 				clusters[assignment].Keywords = append(clusters[assignment].Keywords, keywords...)
 			}
 		}
@@ -601,29 +597,6 @@ func (ca *ContentAssistant) identifyTopicGaps(clusters []TopicCluster, language
 // Helper methods for keyword and content analysis continue...
 // (The file is getting quite long, so I'll add the remaining helper methods in the next part)
 
-// extractSimpleKeywords extracts basic keywords from text
-func (ca *ContentAssistant) extractSimpleKeywords(text string) []string {
-	// Simple keyword extraction - split by common delimiters and filter
-	words := regexp.MustCompile(`[^\w\s]+`).ReplaceAllString(text, " ")
-	words = strings.ToLower(words)
-	tokens := strings.Fields(words)
-
-	// Filter out common stop words and short words
-	stopWords := map[string]bool{
-		"the": true, "and": true, "or": true, "but": true, "in": true, "on": true, "at": true, "to": true, "for": true, "of": true, "with": true, "by": true, "is": true, "are": true, "was": true, "were": true, "be": true, "been": true, "have": true, "has": true, "had": true, "do": true, "does": true, "did": true, "will": true, "would": true, "could": true, "should": true,
-		"一个": true, "这个": true, "那个": true, "我们": true, "它们": true, "这些": true, "那些": true, "可以": true, "应该": true, "需要": true, "已经": true, "正在": true, "如果": true, "因为": true, "所以": true, "但是": true, "然后": true, "现在": true, "之后": true, "之前": true,
-	}
-
-	var keywords []string
-	for _, token := range tokens {
-		if len(token) > 2 && !stopWords[token] {
-			keywords = append(keywords, token)
-		}
-	}
-
-	return keywords
-}
-
 // deduplicateStrings removes duplicate strings from slice
 func (ca *ContentAssistant) deduplicateStrings(slice []string) []string {
 	seen := make(map[string]bool)
@@ -656,13 +629,7 @@ func (ca *ContentAssistant) generateClusterName(keywords []string) string {
 // More helper methods will be added in subsequent parts...
 // (Due to length constraints, I'm splitting this into multiple files)
 
-// Global content assistant instance
-var globalContentAssistant *ContentAssistant
-
-// GetGlobalContentAssistant returns the global content assistant instance
+// GetGlobalContentAssistant returns the content assistant of the global container
 func GetGlobalContentAssistant() *ContentAssistant {
-	if globalContentAssistant == nil {
-		globalContentAssistant = NewContentAssistant()
-	}
-	return globalContentAssistant
+	return GetGlobalContainer().ContentAssistant
 }
@@ -3,6 +3,7 @@ package services
 import (
 	"blog-backend/internal/database"
 	"blog-backend/internal/models"
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -189,7 +190,7 @@ func (ca *ContentAssistant) estimateLength(pattern ContentPattern) int {
 // extractKeyPhrases extracts important phrases from content
 func (ca *ContentAssistant) extractKeyPhrases(content string, language string) []string {
 	// Simple n-gram extraction (2-3 words)
-	words := ca.tokenizeText(content, language)
+	words := ExtractKeywords(content, language)
 	var phrases []string
 
 	// For CJK languages, adjust minimum word length
@@ -259,14 +260,14 @@ func (ca *ContentAssistant) extractKeyPhrases(content string, language string) [
 }
 
 // generateSemanticTags generates tags using semantic analysis
-func (ca *ContentAssistant) generateSemanticTags(content string, language string) ([]SmartTag, error) {
+func (ca *ContentAssistant) generateSemanticTags(ctx context.Context, content string, language string) ([]SmartTag, error) {
 	// Use embeddings to find similar content and extract tags
 	if ca.embeddingService == nil {
 		return []SmartTag{}, nil
 	}
 
 	// Generate embedding for content
-	embedding, _, err := ca.embeddingService.GenerateEmbedding(content)
+	embedding, _, err := ca.embeddingService.GenerateEmbedding(ctx, content)
 	if err != nil {
 		return []SmartTag{}, err
 	}
@@ -426,14 +427,12 @@ func (ca *ContentAssistant) dedupAndSortTags(tags []SmartTag) []SmartTag {
 
 // extractKeywords extracts keywords from text
 func (ca *ContentAssistant) extractKeywords(text string, language string) []string {
-	words := ca.tokenizeText(text, language)
+	words := ExtractKeywords(text, language)
 
-	// Calculate word frequencies
+	// Calculate word frequencies; extraction already left out short words
 	wordFreq := make(map[string]int)
 	for _, word := range words {
-		if len(word) > 2 { // Filter short words
-			wordFreq[word]++
-		}
+		wordFreq[word]++
 	}
 
 	// Sort by frequency
@@ -463,42 +462,9 @@ func (ca *ContentAssistant) extractKeywords(text string, language string) []stri
 	return keywords
 }
 
-// tokenizeText tokenizes text based on language
-func (ca *ContentAssistant) tokenizeText(text string, language string) []string {
-	// Handle different languages properly
-	if language != "zh" && language != "ja" {
-		// For non-CJK languages, convert to lowercase
-		text = strings.ToLower(text)
-	}
-
-	// Remove punctuation and special characters but keep Unicode letters
-	reg := regexp.MustCompile(`[^\p{L}\p{N}\s]+`)
-	text = reg.ReplaceAllString(text, " ")
-
-	// Split into words
-	words := strings.Fields(text)
-
-	// Filter stop words and short words
-	stopWords := ca.getStopWords(language)
-	var filtered []string
-	for _, word := range words {
-		// For CJK languages, use original word; for others, use lowercase for stop word check
-		checkWord := word
-		if language != "zh" && language != "ja" {
-			checkWord = strings.ToLower(word)
-		}
-
-		if !stopWords[checkWord] && len(word) > 1 {
-			filtered = append(filtered, word)
-		}
-	}
-
-	return filtered
-}
-
 // calculateKeywordDensity calculates keyword density in content
 func (ca *ContentAssistant) calculateKeywordDensity(content string, keywords []string) map[string]float64 {
-	words := ca.tokenizeText(content, "")
+	words := ExtractKeywords(content, "")
 	totalWords := len(words)
 
 	if totalWords == 0 {
@@ -386,35 +386,6 @@ func (ca *ContentAssistant) estimateLengthFromKeywords(keywords []string) int {
 	return baseLength + variation
 }
 
-// getStopWords returns stop words for a language
-func (ca *ContentAssistant) getStopWords(language string) map[string]bool {
-	if language == "zh" {
-		return map[string]bool{
-			"的": true, "了": true, "在": true, "是": true, "我": true, "你": true, "他": true, "她": true, "它": true,
-			"们": true, "这": true, "那": true, "一": true, "个": true, "也": true, "就": true, "都": true, "会": true,
-			"要": true, "可": true, "以": true, "和": true, "或": true, "但": true, "因": true, "为": true, "所": true,
-			"有": true, "没": true, "很": true, "更": true, "最": true, "能": true, "应": true, "该": true, "让": true,
-		}
-	} else if language == "ja" {
-		return map[string]bool{
-			"の": true, "に": true, "は": true, "を": true, "が": true, "で": true, "と": true, "から": true, "まで": true,
-			"も": true, "や": true, "か": true, "だ": true, "である": true, "です": true, "ます": true, "した": true,
-			"する": true, "される": true, "として": true, "について": true, "により": true, "による": true, "では": true,
-			"には": true, "での": true, "への": true, "からの": true, "までの": true, "との": true, "という": true,
-			"こと": true, "もの": true, "ため": true, "など": true, "また": true, "さらに": true, "しかし": true, "でも": true,
-		}
-	} else {
-		return map[string]bool{
-			"the": true, "and": true, "or": true, "but": true, "in": true, "on": true, "at": true, "to": true,
-			"for": true, "of": true, "with": true, "by": true, "is": true, "are": true, "was": true, "were": true,
-			"be": true, "been": true, "have": true, "has": true, "had": true, "do": true, "does": true, "did": true,
-			"will": true, "would": true, "could": true, "should": true, "can": true, "may": true, "might": true,
-			"this": true, "that": true, "these": true, "those": true, "a": true, "an": true, "we": true, "you": true,
-			"they": true, "he": true, "she": true, "it": true, "i": true, "me": true, "my": true, "your": true,
-		}
-	}
-}
-
 // getTopicCategories returns topic categories for different languages
 func (ca *ContentAssistant) getTopicCategories(language string) map[string][]string {
 	if language == "zh" {
@@ -0,0 +1,314 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/keywords"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrContentQualityNotFound is returned for an article not analyzed yet
+var ErrContentQualityNotFound = errors.New("content quality analysis not found")
+
+// ContentQualityScheduleName is the scheduler entry of the nightly analysis
+const ContentQualityScheduleName = "content-quality"
+
+// Content quality scoring. An article is fully original while its closest
+// neighbour is at most originalSimilarity away and counts as a duplicate
+// from duplicateSimilarity; it has the full length score from fullLength
+// words. The content score weighs the part scores as below; without an
+// embedding the other weights are scaled up to make up for originality.
+const (
+	originalSimilarity    = 0.6
+	duplicateSimilarity   = 0.95
+	fullLength            = 800
+	qualityKeywords       = 10
+	qualityTopics         = 5
+	weightSEO             = 0.3
+	weightReadability     = 0.3
+	weightLength          = 0.2
+	weightOriginality     = 0.2
+	contentQualityPage    = 50
+	contentQualityMaxPage = 200
+)
+
+// ContentQualityRunResult describes one analysis of every article
+type ContentQualityRunResult struct {
+	Analyzed         int   `json:"analyzed"`
+	WithoutEmbedding int   `json:"without_embedding"`
+	Removed          int64 `json:"removed"`
+}
+
+// ContentQualityFilter selects the analyses of the report
+type ContentQualityFilter struct {
+	Language string
+	MaxScore *float64
+	Limit    int
+	Offset   int
+}
+
+// ContentQualityService scores every article on its SEO, readability,
+// length and originality, the last from embedding similarity to the other
+// articles, so weak and near-duplicate posts can be found and reworked
+type ContentQualityService struct {
+	db       func() *gorm.DB
+	analyzer *SEOAnalyzerService
+}
+
+// NewContentQualityService creates a content quality service
+func NewContentQualityService() *ContentQualityService {
+	return &ContentQualityService{
+		db:       func() *gorm.DB { return database.DB },
+		analyzer: NewSEOAnalyzerService(),
+	}
+}
+
+// AnalyzeAll analyzes every article in its default language and removes
+// the analyses of deleted articles
+func (s *ContentQualityService) AnalyzeAll(ctx context.Context) (*ContentQualityRunResult, error) {
+	vectors, err := s.loadVectors(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if err := s.db().WithContext(ctx).Model(&models.Article{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(int(total))
+
+	result := &ContentQualityRunResult{}
+	var articles []models.Article
+	err = s.db().WithContext(ctx).FindInBatches(&articles, 100, func(batch *gorm.DB, _ int) error {
+		for i := range articles {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			progress.Step("Analyzing article %d: %s", articles[i].ID, articles[i].Title)
+			analysis := s.Analyze(&articles[i], vectors)
+			if analysis.Similarity == nil {
+				result.WithoutEmbedding++
+			}
+			if err := s.save(ctx, analysis); err != nil {
+				return fmt.Errorf("failed to save the analysis of article %d: %w", articles[i].ID, err)
+			}
+			result.Analyzed++
+			progress.Advance(1)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return result, err
+	}
+
+	removed := s.db().WithContext(ctx).Where("article_id NOT IN (?)", s.db().Model(&models.Article{}).Select("id")).
+		Delete(&models.ContentQualityAnalysis{})
+	result.Removed = removed.RowsAffected
+	return result, removed.Error
+}
+
+// Analyze scores an article. vectors holds the combined embeddings of all
+// articles by language and article.
+func (s *ContentQualityService) Analyze(article *models.Article, vectors map[string]map[uint][]float64) *models.ContentQualityAnalysis {
+	language := article.DefaultLang
+	text := s.analyzer.stripMarkdown(models.StripBidiControls(article.Content))
+	words := keywords.Tokenize(text, language)
+	analysis := &models.ContentQualityAnalysis{
+		ArticleID: article.ID,
+		Language:  language,
+		WordCount: len(words),
+	}
+
+	density, topics := keywordDensity(ExtractKeywords(text, language), len(words))
+	densityJSON, _ := json.Marshal(density)
+	topicsJSON, _ := json.Marshal(topics)
+	analysis.KeywordDensity = string(densityJSON)
+	analysis.TopicClusters = string(topicsJSON)
+
+	if len(words) > 0 {
+		analysis.ReadabilityScore = float64(s.analyzer.analyzeReadability(article.Content, language).Score)
+		if seo, err := s.analyzer.AnalyzeContent(article, article.SEOKeywords, language); err == nil {
+			analysis.SEOScore = float64(seo.OverallScore)
+		}
+	}
+	lengthScore := math.Min(float64(len(words))/fullLength, 1) * 100
+
+	if own, ok := vectors[language][article.ID]; ok {
+		var closest uint
+		best := -1.0
+		for id, other := range vectors[language] {
+			if id == article.ID {
+				continue
+			}
+			if similarity := cosineSimilarity(own, other); similarity > best || (similarity == best && id < closest) {
+				best, closest = similarity, id
+			}
+		}
+		if closest == 0 {
+			// Nothing to compare with, so nothing it could repeat
+			best = 0
+		} else {
+			analysis.SimilarArticleID = &closest
+		}
+		similarity := roundTo(best, 4)
+		analysis.Similarity = &similarity
+		analysis.OriginalityScore = originalityScore(best)
+	}
+
+	score := weightSEO*analysis.SEOScore + weightReadability*analysis.ReadabilityScore + weightLength*lengthScore
+	if analysis.Similarity != nil {
+		score += weightOriginality * analysis.OriginalityScore
+	} else {
+		score /= 1 - weightOriginality
+	}
+	analysis.ContentScore = roundTo(score, 1)
+	return analysis
+}
+
+// Report returns the analyses weakest first, with the title of their
+// article, and how many match the filter
+func (s *ContentQualityService) Report(filter ContentQualityFilter) ([]models.ContentQualityAnalysis, int64, error) {
+	query := s.db().Model(&models.ContentQualityAnalysis{})
+	if filter.Language != "" {
+		query = query.Where("language = ?", filter.Language)
+	}
+	if filter.MaxScore != nil {
+		query = query.Where("content_score <= ?", *filter.MaxScore)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = contentQualityPage
+	}
+	limit = min(limit, contentQualityMaxPage)
+	analyses := []models.ContentQualityAnalysis{}
+	err := query.Preload("Article", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "title", "default_lang", "seo_slug", "created_at", "updated_at")
+	}).Order("content_score, article_id").Limit(limit).Offset(max(filter.Offset, 0)).Find(&analyses).Error
+	return analyses, total, err
+}
+
+// Get returns the analysis of an article
+func (s *ContentQualityService) Get(articleID uint) (*models.ContentQualityAnalysis, error) {
+	var analysis models.ContentQualityAnalysis
+	found := s.db().Where("article_id = ?", articleID).Limit(1).Find(&analysis)
+	if found.Error != nil {
+		return nil, found.Error
+	}
+	if found.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: article %d", ErrContentQualityNotFound, articleID)
+	}
+	return &analysis, nil
+}
+
+// save replaces the stored analysis of the article
+func (s *ContentQualityService) save(ctx context.Context, analysis *models.ContentQualityAnalysis) error {
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.ContentQualityAnalysis
+		found := tx.Where("article_id = ?", analysis.ArticleID).Limit(1).Find(&existing)
+		if found.Error != nil {
+			return found.Error
+		}
+		if found.RowsAffected > 0 {
+			analysis.ID, analysis.CreatedAt = existing.ID, existing.CreatedAt
+		}
+		return tx.Omit(clause.Associations).Save(analysis).Error
+	})
+}
+
+// loadVectors reads the combined embeddings of all articles
+func (s *ContentQualityService) loadVectors(ctx context.Context) (map[string]map[uint][]float64, error) {
+	var embeddings []models.ArticleEmbedding
+	if err := s.db().WithContext(ctx).Select("article_id", "language", "embedding").
+		Where("content_type = ?", "combined").Find(&embeddings).Error; err != nil {
+		return nil, err
+	}
+	vectors := make(map[string]map[uint][]float64)
+	for _, embedding := range embeddings {
+		var vector []float64
+		if err := json.Unmarshal([]byte(embedding.Embedding), &vector); err != nil || len(vector) == 0 {
+			continue
+		}
+		if vectors[embedding.Language] == nil {
+			vectors[embedding.Language] = make(map[uint][]float64)
+		}
+		vectors[embedding.Language][embedding.ArticleID] = vector
+	}
+	return vectors, nil
+}
+
+// keywordDensity counts the keywords of a text, most frequent first, with
+// their share of all its words in percent. The most frequent are also
+// returned as the text's topics.
+func keywordDensity(extracted []string, totalWords int) ([]models.KeywordDensity, []string) {
+	counts := make(map[string]int)
+	for _, keyword := range extracted {
+		counts[keyword]++
+	}
+	density := make([]models.KeywordDensity, 0, len(counts))
+	for keyword, count := range counts {
+		density = append(density, models.KeywordDensity{
+			Keyword: keyword,
+			Count:   count,
+			Density: roundTo(float64(count)/float64(totalWords)*100, 2),
+		})
+	}
+	sort.Slice(density, func(i, j int) bool {
+		if density[i].Count != density[j].Count {
+			return density[i].Count > density[j].Count
+		}
+		return density[i].Keyword < density[j].Keyword
+	})
+	if len(density) > qualityKeywords {
+		density = density[:qualityKeywords]
+	}
+	topics := []string{}
+	for _, d := range density[:min(len(density), qualityTopics)] {
+		topics = append(topics, d.Keyword)
+	}
+	return density, topics
+}
+
+// originalityScore maps the similarity to the closest other article to 0-100
+func originalityScore(similarity float64) float64 {
+	switch {
+	case similarity <= originalSimilarity:
+		return 100
+	case similarity >= duplicateSimilarity:
+		return 0
+	}
+	return roundTo((duplicateSimilarity-similarity)/(duplicateSimilarity-originalSimilarity)*100, 1)
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+// Global content quality service instance
+var (
+	globalContentQualityService     *ContentQualityService
+	globalContentQualityServiceOnce sync.Once
+)
+
+// GetGlobalContentQualityService returns the global content quality service
+func GetGlobalContentQualityService() *ContentQualityService {
+	globalContentQualityServiceOnce.Do(func() {
+		globalContentQualityService = NewContentQualityService()
+	})
+	return globalContentQualityService
+}
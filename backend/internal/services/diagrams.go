@@ -0,0 +1,196 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wyatt915/treeblood"
+	"gorm.io/gorm"
+)
+
+const (
+	// diagramTTL is how long a rendered diagram is kept; entries are keyed
+	// by the diagram's source, so an edited diagram is simply a new entry
+	diagramTTL = 30 * 24 * time.Hour
+	// maxDiagramSize bounds the SVG read back from the diagram server
+	maxDiagramSize = 2 << 20
+)
+
+var (
+	ErrDiagramsDisabled = errors.New("diagram rendering is not configured")
+	ErrInvalidDiagram   = errors.New("invalid diagram")
+)
+
+// diagramRenderJob is the payload of JobDiagramsRender
+type diagramRenderJob struct {
+	ArticleID uint `json:"article_id"`
+}
+
+// DiagramRenderer turns Mermaid diagrams into SVG through a Kroki server
+// (kroki.io or a self-hosted one), so they show where the client-side
+// renderer cannot run: feeds, exports and server-rendered pages. Diagrams
+// are cached by their source and rendered in the background when an
+// article is saved, so readers never wait for the diagram server.
+type DiagramRenderer struct {
+	krokiURL string
+	client   *http.Client
+	cache    *cache.Namespace
+	db       func() *gorm.DB
+}
+
+// NewDiagramRenderer creates a diagram renderer from the configuration
+func NewDiagramRenderer() *DiagramRenderer {
+	return &DiagramRenderer{
+		krokiURL: strings.TrimRight(config.Get().Diagrams.KrokiURL, "/"),
+		client:   &http.Client{Timeout: 30 * time.Second},
+		cache:    cache.New("diagrams", diagramTTL),
+		db:       func() *gorm.DB { return database.DB },
+	}
+}
+
+// Enabled reports whether a diagram server is configured
+func (r *DiagramRenderer) Enabled() bool {
+	return r.krokiURL != ""
+}
+
+// Mermaid returns a Mermaid diagram as SVG. Diagrams the server cannot
+// render fail with ErrInvalidDiagram.
+func (r *DiagramRenderer) Mermaid(ctx context.Context, source string) (string, error) {
+	if !r.Enabled() {
+		return "", ErrDiagramsDisabled
+	}
+	key := "mermaid:" + segmentHash(source)
+	var svg string
+	if r.cache.Get(key, &svg) {
+		return svg, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.krokiURL+"/mermaid/svg", strings.NewReader(source))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Accept", "image/svg+xml")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiagramSize+1))
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return "", fmt.Errorf("%w: %s", ErrInvalidDiagram, truncateRunes(strings.TrimSpace(string(body)), 200))
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("diagram server returned %s", resp.Status)
+	case len(body) > maxDiagramSize:
+		return "", fmt.Errorf("%w: the SVG is larger than %d bytes", ErrInvalidDiagram, maxDiagramSize)
+	}
+	svg = string(body)
+	r.cache.Set(key, svg)
+	return svg, nil
+}
+
+// Prerender renders the diagrams of an article and its translations into
+// the cache
+func (r *DiagramRenderer) Prerender(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var job diagramRenderJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, err
+	}
+	var article models.Article
+	if err := r.db().WithContext(ctx).Preload("Translations").First(&article, job.ArticleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	sources := MermaidDiagrams(article.Content)
+	for _, translation := range article.Translations {
+		sources = append(sources, MermaidDiagrams(translation.Content)...)
+	}
+	rendered, invalid := 0, 0
+	for _, source := range sources {
+		if _, err := r.Mermaid(ctx, source); errors.Is(err, ErrInvalidDiagram) {
+			invalid++
+		} else if err != nil {
+			return nil, err
+		} else {
+			rendered++
+		}
+	}
+	return map[string]int{"rendered": rendered, "invalid": invalid}, nil
+}
+
+// registerHooks renders the diagrams of saved articles in the background
+func (r *DiagramRenderer) registerHooks() {
+	hooks.Register(hooks.AfterArticleSave, "diagrams", r.dispatch)
+}
+
+func (r *DiagramRenderer) dispatch(ctx context.Context, event hooks.Event, payload interface{}) error {
+	article, ok := payload.(*models.Article)
+	if !ok || !r.Enabled() {
+		return nil
+	}
+	hasDiagrams := strings.Contains(article.Content, "mermaid")
+	for _, translation := range article.Translations {
+		hasDiagrams = hasDiagrams || strings.Contains(translation.Content, "mermaid")
+	}
+	if !hasDiagrams {
+		return nil
+	}
+	_, err := GetGlobalJobQueue().Enqueue(JobDiagramsRender, diagramRenderJob{ArticleID: article.ID})
+	return err
+}
+
+// RenderMath converts TeX math to MathML, which browsers and most feed
+// readers display without scripts or fonts. display renders a block
+// formula rather than one inline in text.
+func RenderMath(tex string, display bool) (mathML string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("invalid math: %v", p)
+		}
+	}()
+	if display {
+		mathML, err = treeblood.DisplayStyle(tex, nil)
+	} else {
+		mathML, err = treeblood.InlineStyle(tex, nil)
+	}
+	if err != nil {
+		// The message carries an HTML excerpt pointing at the error
+		message, _, _ := strings.Cut(err.Error(), "<pre>")
+		return "", fmt.Errorf("invalid math: %s", strings.TrimSpace(message))
+	}
+	return strings.TrimSpace(mathML), nil
+}
+
+var (
+	globalDiagramRenderer     *DiagramRenderer
+	globalDiagramRendererOnce sync.Once
+)
+
+// GetGlobalDiagramRenderer returns the global diagram renderer, registering
+// its save hook on first use
+func GetGlobalDiagramRenderer() *DiagramRenderer {
+	globalDiagramRendererOnce.Do(func() {
+		globalDiagramRenderer = NewDiagramRenderer()
+		globalDiagramRenderer.registerHooks()
+	})
+	return globalDiagramRenderer
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// donationClickWindow is how long repeated clicks from one address on one
+// method count once
+const donationClickWindow = time.Hour
+
+var (
+	ErrInvalidDonationMethod  = errors.New("invalid donation method")
+	ErrDonationMethodNotFound = errors.New("donation method not found")
+)
+
+// DonationMethodInput holds the editable fields of a donation method; nil
+// fields are left unchanged
+type DonationMethodInput struct {
+	Type      *string `json:"type"`
+	Label     *string `json:"label"`
+	URL       *string `json:"url"`
+	QRCodeURL *string `json:"qr_code_url"`
+	IsActive  *bool   `json:"is_active"`
+}
+
+// DonationMethodOrder moves a method to a position in the list
+type DonationMethodOrder struct {
+	ID    uint `json:"id"`
+	Order int  `json:"order"`
+}
+
+// PublicDonationMethod is a donation method as shown to readers, without
+// its click count
+type PublicDonationMethod struct {
+	ID        uint   `json:"id"`
+	Type      string `json:"type"`
+	Label     string `json:"label"`
+	URL       string `json:"url,omitempty"`
+	QRCodeURL string `json:"qr_code_url,omitempty"`
+}
+
+// DonationService manages the donation methods of each site and decides
+// where they are shown: always on the sponsor page, and under an article
+// when the article or, failing that, the site settings ask for it. Clicks
+// are counted per method, once per address and hour.
+type DonationService struct {
+	db     func() *gorm.DB
+	clicks *cache.Namespace
+}
+
+// NewDonationService creates a donation service
+func NewDonationService() *DonationService {
+	return &DonationService{
+		db:     func() *gorm.DB { return database.DB },
+		clicks: cache.New("donation_clicks", donationClickWindow),
+	}
+}
+
+// List returns the site's donation methods in display order, optionally
+// only the active ones
+func (s *DonationService) List(ctx context.Context, activeOnly bool) ([]models.DonationMethod, error) {
+	query := s.db().WithContext(ctx)
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	methods := []models.DonationMethod{}
+	err := query.Order("display_order ASC, id ASC").Find(&methods).Error
+	return methods, err
+}
+
+// Public returns the site's active donation methods for readers. With an
+// article ID, shown reports whether they belong under that article.
+func (s *DonationService) Public(ctx context.Context, articleID uint) (methods []PublicDonationMethod, shown bool, err error) {
+	shown = true
+	if articleID != 0 {
+		db := s.db().WithContext(ctx)
+		var article models.Article
+		if err := db.Select("id", "show_donation").Where("created_at <= ?", time.Now()).First(&article, articleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, false, ErrArticleNotFound
+			}
+			return nil, false, err
+		}
+		if article.ShowDonation != nil {
+			shown = *article.ShowDonation
+		} else {
+			var settings models.SiteSettings
+			if err := db.Select("donations_on_articles").Limit(1).Find(&settings).Error; err != nil {
+				return nil, false, err
+			}
+			shown = settings.DonationsOnArticles
+		}
+	}
+	active, err := s.List(ctx, true)
+	if err != nil {
+		return nil, false, err
+	}
+	methods = make([]PublicDonationMethod, len(active))
+	for i, method := range active {
+		methods[i] = PublicDonationMethod{
+			ID:        method.ID,
+			Type:      method.Type,
+			Label:     method.Label,
+			URL:       method.URL,
+			QRCodeURL: method.QRCodeURL,
+		}
+	}
+	return methods, shown && len(methods) > 0, nil
+}
+
+// Get returns one of the site's donation methods
+func (s *DonationService) Get(ctx context.Context, id uint) (*models.DonationMethod, error) {
+	var method models.DonationMethod
+	if err := s.db().WithContext(ctx).First(&method, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDonationMethodNotFound
+		}
+		return nil, err
+	}
+	return &method, nil
+}
+
+// Create adds an active donation method at the end of the list
+func (s *DonationService) Create(ctx context.Context, input DonationMethodInput) (*models.DonationMethod, error) {
+	method := &models.DonationMethod{IsActive: true}
+	if err := applyDonationMethodInput(method, input); err != nil {
+		return nil, err
+	}
+	db := s.db().WithContext(ctx)
+	var maxOrder int
+	if err := db.Model(&models.DonationMethod{}).Select("COALESCE(MAX(display_order), 0)").Scan(&maxOrder).Error; err != nil {
+		return nil, err
+	}
+	method.DisplayOrder = maxOrder + 1
+	if err := db.Create(method).Error; err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+// Update edits a donation method
+func (s *DonationService) Update(ctx context.Context, id uint, input DonationMethodInput) (*models.DonationMethod, error) {
+	method, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyDonationMethodInput(method, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Save(method).Error; err != nil {
+		return nil, err
+	}
+	return method, nil
+}
+
+// Delete removes a donation method
+func (s *DonationService) Delete(ctx context.Context, id uint) error {
+	result := s.db().WithContext(ctx).Delete(&models.DonationMethod{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDonationMethodNotFound
+	}
+	return nil
+}
+
+// Reorder sets the display order of the given methods
+func (s *DonationService) Reorder(ctx context.Context, orders []DonationMethodOrder) error {
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, order := range orders {
+			if err := tx.Model(&models.DonationMethod{}).Where("id = ?", order.ID).
+				Update("display_order", order.Order).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Click counts a reader opening an active donation method. Repeated clicks
+// from the same address within the hour are not counted again.
+func (s *DonationService) Click(ctx context.Context, id uint, ip string) error {
+	method, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !method.IsActive {
+		return ErrDonationMethodNotFound
+	}
+	key := fmt.Sprintf("%d:%s", method.ID, ip)
+	var seen bool
+	if s.clicks.Get(key, &seen) {
+		return nil
+	}
+	s.clicks.Set(key, true)
+	return s.db().WithContext(ctx).Model(method).UpdateColumn("clicks", gorm.Expr("clicks + 1")).Error
+}
+
+func applyDonationMethodInput(method *models.DonationMethod, input DonationMethodInput) error {
+	if input.Type != nil {
+		method.Type = strings.TrimSpace(*input.Type)
+	}
+	if input.Label != nil {
+		method.Label = strings.TrimSpace(*input.Label)
+	}
+	if input.URL != nil {
+		method.URL = strings.TrimSpace(*input.URL)
+	}
+	if input.QRCodeURL != nil {
+		method.QRCodeURL = strings.TrimSpace(*input.QRCodeURL)
+	}
+	if input.IsActive != nil {
+		method.IsActive = *input.IsActive
+	}
+
+	if utf8.RuneCountInString(method.Label) > 100 {
+		return fmt.Errorf("%w: label is at most 100 characters", ErrInvalidDonationMethod)
+	}
+	var host, path string
+	if method.URL != "" {
+		u, err := url.Parse(method.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(method.URL) > 500 {
+			return fmt.Errorf("%w: url must be an absolute http(s) URL of at most 500 characters", ErrInvalidDonationMethod)
+		}
+		host = strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+		path = strings.ToLower(u.Path)
+	}
+	if method.QRCodeURL != "" && !validMediaURL(method.QRCodeURL) {
+		return fmt.Errorf("%w: qr_code_url must be an http(s) URL or a path on the site", ErrInvalidDonationMethod)
+	}
+	switch method.Type {
+	case models.DonationWeChatPay, models.DonationAlipay:
+		if method.QRCodeURL == "" {
+			return fmt.Errorf("%w: %s needs the image of its payment QR code", ErrInvalidDonationMethod, method.Type)
+		}
+	case models.DonationKofi:
+		if host != "ko-fi.com" {
+			return fmt.Errorf("%w: kofi needs a ko-fi.com page URL", ErrInvalidDonationMethod)
+		}
+	case models.DonationGitHubSponsors:
+		if host != "github.com" || !strings.HasPrefix(path, "/sponsors/") {
+			return fmt.Errorf("%w: github_sponsors needs a github.com/sponsors/ URL", ErrInvalidDonationMethod)
+		}
+	case models.DonationLink:
+		if method.URL == "" || method.Label == "" {
+			return fmt.Errorf("%w: link needs a label and a URL", ErrInvalidDonationMethod)
+		}
+	default:
+		return fmt.Errorf("%w: type must be wechat_pay, alipay, kofi, github_sponsors or link", ErrInvalidDonationMethod)
+	}
+	return nil
+}
+
+var (
+	globalDonationService     *DonationService
+	globalDonationServiceOnce sync.Once
+)
+
+// GetGlobalDonationService returns the global donation service
+func GetGlobalDonationService() *DonationService {
+	globalDonationServiceOnce.Do(func() {
+		globalDonationService = NewDonationService()
+	})
+	return globalDonationService
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestDonationMethods(t *testing.T) {
+	newTestDB(t)
+	s := &DonationService{
+		db:     func() *gorm.DB { return database.DB },
+		clicks: cache.New("test_donation_clicks", time.Hour),
+	}
+	s.clicks.Clear()
+	ctx := context.Background()
+
+	for _, bad := range []DonationMethodInput{
+		{Type: strPtr("paypal"), URL: strPtr("https://paypal.me/alice")},
+		{Type: strPtr(models.DonationWeChatPay)},
+		{Type: strPtr(models.DonationAlipay), QRCodeURL: strPtr("javascript:alert(1)")},
+		{Type: strPtr(models.DonationKofi), URL: strPtr("https://buymeacoffee.com/alice")},
+		{Type: strPtr(models.DonationGitHubSponsors), URL: strPtr("https://github.com/alice")},
+		{Type: strPtr(models.DonationLink), URL: strPtr("https://liberapay.com/alice")},
+	} {
+		if _, err := s.Create(ctx, bad); !errors.Is(err, ErrInvalidDonationMethod) {
+			t.Errorf("Create(%+v) = %v, want ErrInvalidDonationMethod", bad, err)
+		}
+	}
+
+	inactive := false
+	var ids []uint
+	for _, input := range []DonationMethodInput{
+		{Type: strPtr(models.DonationWeChatPay), QRCodeURL: strPtr("/uploads/images/wechat.png")},
+		{Type: strPtr(models.DonationKofi), URL: strPtr("https://ko-fi.com/alice")},
+		{Type: strPtr(models.DonationGitHubSponsors), URL: strPtr("https://github.com/sponsors/alice")},
+		{Type: strPtr(models.DonationLink), Label: strPtr("Liberapay"), URL: strPtr("https://liberapay.com/alice"), IsActive: &inactive},
+	} {
+		method, err := s.Create(ctx, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, method.ID)
+	}
+	// GitHub Sponsors moves to the front
+	if err := s.Reorder(ctx, []DonationMethodOrder{{ID: ids[2], Order: 0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	methods, shown, err := s.Public(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !shown || len(methods) != 3 || methods[0].Type != models.DonationGitHubSponsors || methods[1].Type != models.DonationWeChatPay {
+		t.Fatalf("public methods = %+v (shown %v)", methods, shown)
+	}
+
+	// Under articles the methods follow the site setting unless the article
+	// overrides it, and scheduled articles are not there yet
+	off, on := false, true
+	plain := models.Article{Title: "Plain", CategoryID: 1}
+	optedOut := models.Article{Title: "Opted out", CategoryID: 1, ShowDonation: &off}
+	scheduled := models.Article{Title: "Scheduled", CategoryID: 1, ShowDonation: &on, CreatedAt: time.Now().Add(time.Hour)}
+	for _, article := range []*models.Article{&plain, &optedOut, &scheduled} {
+		if err := database.DB.Create(article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, shown, _ := s.Public(ctx, plain.ID); shown {
+		t.Error("methods shown under an article while the site setting is off")
+	}
+	if err := database.DB.Create(&models.SiteSettings{DonationsOnArticles: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, shown, _ := s.Public(ctx, plain.ID); !shown {
+		t.Error("methods not shown under an article with the site setting on")
+	}
+	if _, shown, _ := s.Public(ctx, optedOut.ID); shown {
+		t.Error("methods shown under an article that opted out")
+	}
+	if _, _, err := s.Public(ctx, scheduled.ID); !errors.Is(err, ErrArticleNotFound) {
+		t.Errorf("scheduled article = %v, want ErrArticleNotFound", err)
+	}
+
+	// Repeated clicks from one address count once
+	for _, ip := range []string{"192.0.2.1", "192.0.2.1", "192.0.2.2"} {
+		if err := s.Click(ctx, ids[1], ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if method, _ := s.Get(ctx, ids[1]); method.Clicks != 2 {
+		t.Errorf("clicks = %d, want 2", method.Clicks)
+	}
+	if err := s.Click(ctx, ids[3], "192.0.2.1"); !errors.Is(err, ErrDonationMethodNotFound) {
+		t.Errorf("click on an inactive method = %v", err)
+	}
+
+	if err := s.Delete(ctx, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, ids[0]); !errors.Is(err, ErrDonationMethodNotFound) {
+		t.Errorf("second delete = %v", err)
+	}
+}
@@ -0,0 +1,768 @@
+package services
+
+import (
+	"archive/zip"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxEbookChapters and maxEbookImages bound the size of one book, and
+	// ebookTimeout how long fetching its images may take
+	maxEbookChapters = 200
+	maxEbookImages   = 500
+	ebookTimeout     = 5 * time.Minute
+	// ebookRetention is how long exported books are kept for download
+	ebookRetention = 30 * 24 * time.Hour
+)
+
+var ebookNamePattern = regexp.MustCompile(`^ebook-\d{8}-\d{6}-[0-9a-f]{8}\.epub$`)
+
+var (
+	ErrInvalidEbook  = errors.New("invalid ebook")
+	ErrEbookNotFound = errors.New("ebook not found")
+)
+
+// ebookImageTypes are the image formats every EPUB reader shows, with the
+// extension they are stored under
+var ebookImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// EbookRequest selects the articles of a book: every published article of
+// a category, oldest first, or articles in the order given, as for a
+// series. Title and Author default to the category name and the site
+// title, Language to the site's language and the cover to the first
+// chapter's.
+type EbookRequest struct {
+	Title      string `json:"title"`
+	Author     string `json:"author"`
+	Language   string `json:"language"`
+	CategoryID uint   `json:"category_id"`
+	ArticleIDs []uint `json:"article_ids"`
+	CoverURL   string `json:"cover_url"`
+}
+
+// ebookJob is the payload of JobEbookExport
+type ebookJob struct {
+	EbookRequest
+	SiteID  uint   `json:"site_id"`
+	BaseURL string `json:"base_url"`
+	Name    string `json:"name"`
+}
+
+// EbookInfo describes an exported book on disk
+type EbookInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	Chapters  int       `json:"chapters,omitempty"`
+	Images    int       `json:"images,omitempty"`
+}
+
+// EbookExport is a queued export; the book is downloadable under Name
+// once Job has succeeded
+type EbookExport struct {
+	Name string      `json:"name"`
+	Job  *models.Job `json:"job"`
+}
+
+// EbookService bundles articles into EPUB 3 books in the background. Each
+// article is a chapter headed by its date, category and address, with its
+// images stored in the book, after a cover and a table of contents.
+// Books are kept per site for ebookRetention.
+type EbookService struct {
+	db        func() *gorm.DB
+	now       func() time.Time
+	dir       string
+	uploadDir string
+	markdown  *MarkdownRenderer
+	images    *ArticlePDFRenderer
+}
+
+// NewEbookService creates an ebook service storing books next to the
+// database
+func NewEbookService() *EbookService {
+	cfg := config.Get()
+	return &EbookService{
+		db:        func() *gorm.DB { return database.DB },
+		now:       time.Now,
+		dir:       filepath.Join(filepath.Dir(cfg.Database.Path), "ebooks"),
+		uploadDir: cfg.Storage.UploadDir,
+		markdown:  GetGlobalMarkdownRenderer(),
+		images:    GetGlobalArticlePDFRenderer(),
+	}
+}
+
+// Queue checks a request and queues the export of the book
+func (s *EbookService) Queue(ctx context.Context, siteID uint, baseURL string, req EbookRequest) (*EbookExport, error) {
+	if err := s.validate(siteID, &req); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("ebook-%s-%s.epub", s.now().UTC().Format("20060102-150405"), uuid.NewString()[:8])
+	job, err := GetGlobalJobQueue().Enqueue(JobEbookExport, ebookJob{EbookRequest: req, SiteID: siteID, BaseURL: baseURL, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	return &EbookExport{Name: name, Job: job}, nil
+}
+
+func (s *EbookService) validate(siteID uint, req *EbookRequest) error {
+	req.Title = strings.TrimSpace(req.Title)
+	req.Author = strings.TrimSpace(req.Author)
+	req.Language = strings.TrimSpace(req.Language)
+	req.CoverURL = strings.TrimSpace(req.CoverURL)
+	switch {
+	case (req.CategoryID == 0) == (len(req.ArticleIDs) == 0):
+		return fmt.Errorf("%w: choose either a category or articles", ErrInvalidEbook)
+	case len(req.ArticleIDs) > maxEbookChapters:
+		return fmt.Errorf("%w: at most %d articles", ErrInvalidEbook, maxEbookChapters)
+	case utf8.RuneCountInString(req.Title) > 200 || utf8.RuneCountInString(req.Author) > 200:
+		return fmt.Errorf("%w: title and author must be at most 200 characters", ErrInvalidEbook)
+	case req.Language != "" && (len(req.Language) > 10 || !languageCode.MatchString(req.Language)):
+		return fmt.Errorf("%w: invalid language %q", ErrInvalidEbook, req.Language)
+	case req.CoverURL != "" && !validMediaURL(req.CoverURL):
+		return fmt.Errorf("%w: invalid cover URL", ErrInvalidEbook)
+	}
+
+	if req.CategoryID != 0 {
+		var category models.Category
+		if err := s.db().Where("site_id = ?", siteID).Limit(1).Find(&category, req.CategoryID).Error; err != nil {
+			return err
+		}
+		if category.ID == 0 {
+			return fmt.Errorf("%w: category %d not found", ErrInvalidEbook, req.CategoryID)
+		}
+		var count int64
+		if err := s.db().Model(&models.Article{}).Where("site_id = ? AND category_id = ? AND created_at <= ?", siteID, req.CategoryID, s.now()).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 || count > maxEbookChapters {
+			return fmt.Errorf("%w: the category has %d published articles, books hold 1 to %d", ErrInvalidEbook, count, maxEbookChapters)
+		}
+		return nil
+	}
+
+	seen := map[uint]bool{}
+	for _, id := range req.ArticleIDs {
+		if seen[id] {
+			return fmt.Errorf("%w: article %d is listed twice", ErrInvalidEbook, id)
+		}
+		seen[id] = true
+	}
+	var ids []uint
+	if err := s.db().Model(&models.Article{}).Where("site_id = ? AND id IN ?", siteID, req.ArticleIDs).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		delete(seen, id)
+	}
+	for _, id := range req.ArticleIDs {
+		if seen[id] {
+			return fmt.Errorf("%w: article %d not found", ErrInvalidEbook, id)
+		}
+	}
+	return nil
+}
+
+// Export is the JobEbookExport handler: it writes the book and removes the
+// site's books older than ebookRetention
+func (s *EbookService) Export(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var job ebookJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, err
+	}
+	if !ebookNamePattern.MatchString(job.Name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidEbook, job.Name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, ebookTimeout)
+	defer cancel()
+
+	book, err := s.load(&job)
+	if err != nil {
+		return nil, err
+	}
+	dir := s.siteDir(job.SiteID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	// Write to a temporary name so a partial book is never listed
+	tmp, err := os.CreateTemp(dir, ".ebook-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if err := book.write(ctx, tmp); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, job.Name)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	s.prune(job.SiteID)
+
+	info, err := ebookInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	info.Chapters, info.Images = len(book.chapters), len(book.imageOrder)
+	slog.Info("Ebook exported", "name", job.Name, "site_id", job.SiteID, "chapters", info.Chapters, "images", info.Images)
+	return info, nil
+}
+
+// load reads the articles of a book, translated to its language where a
+// translation exists
+func (s *EbookService) load(job *ebookJob) (*ebook, error) {
+	var settings models.SiteSettings
+	if err := s.db().Where("site_id = ?", job.SiteID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	book := &ebook{
+		service:  s,
+		id:       uuid.NewString(),
+		title:    job.Title,
+		author:   job.Author,
+		language: job.Language,
+		cover:    job.CoverURL,
+		baseURL:  job.BaseURL,
+		modified: s.now().UTC(),
+		images:   map[string]*ebookImage{},
+	}
+	if book.language == "" {
+		book.language = siteLanguage(s.db(), job.SiteID)
+	}
+	if book.author == "" {
+		book.author = settings.SiteTitle
+	}
+
+	var articles []models.Article
+	query := s.db().Preload("Category").Preload("Translations").Where("site_id = ?", job.SiteID)
+	if job.CategoryID != 0 {
+		err := query.Where("category_id = ? AND created_at <= ?", job.CategoryID, s.now()).
+			Order("created_at ASC, id ASC").Limit(maxEbookChapters).Find(&articles).Error
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if err := query.Where("id IN ?", job.ArticleIDs).Find(&articles).Error; err != nil {
+			return nil, err
+		}
+		order := map[uint]int{}
+		for i, id := range job.ArticleIDs {
+			order[id] = i
+		}
+		sort.Slice(articles, func(i, j int) bool { return order[articles[i].ID] < order[articles[j].ID] })
+	}
+	if len(articles) == 0 {
+		return nil, fmt.Errorf("%w: no articles to export", ErrInvalidEbook)
+	}
+
+	if book.title == "" {
+		book.title = settings.SiteTitle
+		if job.CategoryID != 0 {
+			book.title = articles[0].Category.Name
+		}
+	}
+	for i := range articles {
+		article := &articles[i]
+		language := article.DefaultLang
+		for _, translation := range article.Translations {
+			if translation.Language == book.language && translation.Content != "" {
+				language = book.language
+			}
+		}
+		ApplyTranslation(article, language)
+		if book.cover == "" && article.CoverImageURL != nil {
+			book.cover = *article.CoverImageURL
+		}
+		book.chapters = append(book.chapters, &ebookChapter{article: article, language: language})
+	}
+	return book, nil
+}
+
+// List returns the site's books, newest first
+func (s *EbookService) List(siteID uint) ([]EbookInfo, error) {
+	entries, err := os.ReadDir(s.siteDir(siteID))
+	if os.IsNotExist(err) {
+		return []EbookInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	books := []EbookInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !ebookNamePattern.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := ebookInfo(filepath.Join(s.siteDir(siteID), entry.Name()))
+		if err != nil {
+			continue
+		}
+		books = append(books, *info)
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].CreatedAt.After(books[j].CreatedAt) })
+	return books, nil
+}
+
+// Path returns the location of a site's book on disk
+func (s *EbookService) Path(siteID uint, name string) (string, error) {
+	if !ebookNamePattern.MatchString(name) {
+		return "", ErrEbookNotFound
+	}
+	path := filepath.Join(s.siteDir(siteID), name)
+	if !fileExists(path) {
+		return "", ErrEbookNotFound
+	}
+	return path, nil
+}
+
+// Delete removes a site's book
+func (s *EbookService) Delete(siteID uint, name string) error {
+	path, err := s.Path(siteID, name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (s *EbookService) siteDir(siteID uint) string {
+	return filepath.Join(s.dir, fmt.Sprintf("site-%d", siteID))
+}
+
+func (s *EbookService) prune(siteID uint) {
+	books, err := s.List(siteID)
+	if err != nil {
+		return
+	}
+	for _, book := range books {
+		if s.now().Sub(book.CreatedAt) > ebookRetention {
+			if err := s.Delete(siteID, book.Name); err != nil {
+				slog.Warn("Failed to remove old ebook", "name", book.Name, "error", err)
+			}
+		}
+	}
+}
+
+func ebookInfo(path string) (*EbookInfo, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &EbookInfo{Name: filepath.Base(path), Size: stat.Size(), CreatedAt: stat.ModTime().UTC()}, nil
+}
+
+// ebook is a book being written
+type ebook struct {
+	service                     *EbookService
+	id, title, author, language string
+	cover, baseURL              string
+	modified                    time.Time
+	chapters                    []*ebookChapter
+	coverImage                  *ebookImage
+	images                      map[string]*ebookImage
+	imageOrder                  []*ebookImage
+	ctx                         context.Context
+}
+
+type ebookChapter struct {
+	article  *models.Article
+	language string
+	// properties are the EPUB manifest properties of the chapter, set for
+	// MathML and SVG content
+	properties []string
+}
+
+// ebookImage is an image stored in the book; data is nil for images that
+// could not be loaded
+type ebookImage struct {
+	href, mediaType string
+	data            []byte
+}
+
+func (c *ebookChapter) href() string {
+	return fmt.Sprintf("chapter-%03d.xhtml", c.article.ID)
+}
+
+// write writes the book as an EPUB archive. The mimetype entry comes first
+// and uncompressed, as readers identify books by it.
+func (b *ebook) write(ctx context.Context, w io.Writer) error {
+	b.ctx = ctx
+	zw := zip.NewWriter(w)
+	mimetype, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store, Modified: b.modified})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mimetype, "application/epub+zip"); err != nil {
+		return err
+	}
+	if err := b.writeFile(zw, "META-INF/container.xml", []byte(ebookContainer)); err != nil {
+		return err
+	}
+	if err := b.writeFile(zw, "OEBPS/style.css", []byte(ebookStylesheet)); err != nil {
+		return err
+	}
+	for _, chapter := range b.chapters {
+		content, err := b.chapter(chapter)
+		if err != nil {
+			return err
+		}
+		if err := b.writeFile(zw, "OEBPS/"+chapter.href(), content); err != nil {
+			return err
+		}
+	}
+	// The cover, contents and package come after the chapters, which
+	// decide the images they list
+	for _, file := range []struct {
+		name    string
+		content func() []byte
+	}{{"cover.xhtml", b.coverPage}, {"nav.xhtml", b.nav}, {"toc.ncx", b.ncx}} {
+		if err := b.writeFile(zw, "OEBPS/"+file.name, file.content()); err != nil {
+			return err
+		}
+	}
+	for _, img := range b.imageOrder {
+		if err := b.writeFile(zw, "OEBPS/"+img.href, img.data); err != nil {
+			return err
+		}
+	}
+	if err := b.writeFile(zw, "OEBPS/content.opf", b.opf()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (b *ebook) writeFile(zw *zip.Writer, name string, content []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: b.modified})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	return err
+}
+
+// chapter renders an article as an XHTML document headed by its metadata
+func (b *ebook) chapter(chapter *ebookChapter) ([]byte, error) {
+	article := chapter.article
+	var content string
+	if article.ContentType == "html" {
+		content = security.GetGlobalHTMLPolicy().Sanitize(article.Content)
+	} else {
+		rendered, err := b.service.markdown.Render(b.ctx, article.Content)
+		if err != nil {
+			return nil, err
+		}
+		content = rendered
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(content), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	for _, node := range nodes {
+		b.xhtmlNode(node, chapter)
+		if err := html.Render(&body, node); err != nil {
+			return nil, err
+		}
+	}
+
+	link := localizedArticleLink(b.baseURL, chapter.language, article.ID)
+	meta := []string{article.CreatedAt.Format("2006-01-02")}
+	if article.Category.Name != "" {
+		meta = append(meta, xmlEscape(article.Category.Name))
+	}
+	meta = append(meta, fmt.Sprintf(`<a href="%s">%s</a>`, xmlEscape(link), xmlEscape(link)))
+
+	var out bytes.Buffer
+	b.xhtmlHead(&out, chapter.language, article.Title)
+	fmt.Fprintf(&out, "<meta name=\"author\" content=\"%s\"/>\n", xmlEscape(b.author))
+	fmt.Fprintf(&out, "<meta name=\"dcterms.created\" content=\"%s\"/>\n", article.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&out, "<meta name=\"dcterms.modified\" content=\"%s\"/>\n", article.UpdatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&out, "<meta name=\"dcterms.source\" content=\"%s\"/>\n", xmlEscape(link))
+	if article.Category.Name != "" {
+		fmt.Fprintf(&out, "<meta name=\"dcterms.subject\" content=\"%s\"/>\n", xmlEscape(article.Category.Name))
+	}
+	if article.SEOKeywords != "" {
+		fmt.Fprintf(&out, "<meta name=\"keywords\" content=\"%s\"/>\n", xmlEscape(article.SEOKeywords))
+	}
+	if article.Summary != "" {
+		fmt.Fprintf(&out, "<meta name=\"description\" content=\"%s\"/>\n", xmlEscape(article.Summary))
+	}
+	fmt.Fprintf(&out, "</head>\n<body>\n<section epub:type=\"chapter\" id=\"chapter-%d\">\n<header>\n<h1>%s</h1>\n", article.ID, xmlEscape(article.Title))
+	fmt.Fprintf(&out, "<p class=\"meta\">%s</p>\n", strings.Join(meta, " · "))
+	if article.Summary != "" {
+		fmt.Fprintf(&out, "<p class=\"summary\">%s</p>\n", xmlEscape(article.Summary))
+	}
+	out.WriteString("</header>\n")
+	out.Write(body.Bytes())
+	out.WriteString("\n</section>\n</body>\n</html>\n")
+	return out.Bytes(), nil
+}
+
+// xhtmlNode makes rendered HTML fit an EPUB: images are stored in the book
+// or replaced by their alternative text, links to the site made absolute
+// and MathML and SVG given their namespaces
+func (b *ebook) xhtmlNode(node *html.Node, chapter *ebookChapter) {
+	if node.Type == html.ElementNode {
+		switch {
+		case node.Namespace == "math" || node.Namespace == "svg":
+			if node.Parent == nil || node.Parent.Namespace != node.Namespace {
+				namespace, property := "http://www.w3.org/1998/Math/MathML", "mathml"
+				if node.Namespace == "svg" {
+					namespace, property = "http://www.w3.org/2000/svg", "svg"
+				}
+				attrs := []html.Attribute{{Key: "xmlns", Val: namespace}}
+				if property == "svg" {
+					attrs = append(attrs, html.Attribute{Key: "xmlns:xlink", Val: "http://www.w3.org/1999/xlink"})
+				}
+				node.Attr = append(attrs, removeAttr(removeAttr(node.Attr, "xmlns"), "xmlns:xlink")...)
+				if !contains(chapter.properties, property) {
+					chapter.properties = append(chapter.properties, property)
+				}
+			}
+		case node.DataAtom == atom.Img:
+			img := b.image(htmlAttr(node, "src"))
+			if img == nil {
+				alt := htmlAttr(node, "alt")
+				node.Data, node.DataAtom = "span", atom.Span
+				node.Attr = []html.Attribute{{Key: "class", Val: "missing-image"}}
+				if alt != "" {
+					node.AppendChild(&html.Node{Type: html.TextNode, Data: "[" + alt + "]"})
+				}
+				return
+			}
+			attrs := removeAttr(removeAttr(removeAttr(node.Attr, "src"), "srcset"), "sizes")
+			if htmlAttr(node, "alt") == "" {
+				attrs = append(removeAttr(attrs, "alt"), html.Attribute{Key: "alt", Val: ""})
+			}
+			node.Attr = append(attrs, html.Attribute{Key: "src", Val: img.href})
+		case node.DataAtom == atom.A:
+			if href := strings.TrimSpace(htmlAttr(node, "href")); strings.HasPrefix(href, "/") && !strings.HasPrefix(href, "//") {
+				node.Attr = append(removeAttr(node.Attr, "href"), html.Attribute{Key: "href", Val: absoluteURL(b.baseURL, href)})
+			}
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		b.xhtmlNode(child, chapter)
+	}
+}
+
+// removeAttr removes the attribute with a qualified name, such as
+// xmlns:xlink
+func removeAttr(attrs []html.Attribute, name string) []html.Attribute {
+	kept := attrs[:0:0]
+	for _, attr := range attrs {
+		if attr.Key != name && attr.Namespace+":"+attr.Key != name {
+			kept = append(kept, attr)
+		}
+	}
+	return kept
+}
+
+// image stores the image at src in the book once, returning nil when it
+// cannot be loaded or is not a format readers show
+func (b *ebook) image(src string) *ebookImage {
+	if img, ok := b.images[src]; ok {
+		if img.data == nil {
+			return nil
+		}
+		return img
+	}
+	img := &ebookImage{}
+	b.images[src] = img
+	if src == "" || len(b.imageOrder) >= maxEbookImages {
+		return nil
+	}
+	data, err := b.service.images.imageData(b.ctx, src, ArticlePDFOptions{BaseURL: b.baseURL, UploadDir: b.service.uploadDir})
+	if err != nil {
+		slog.Debug("Failed to load image for ebook", "src", src, "error", err)
+		return nil
+	}
+	img.mediaType = http.DetectContentType(data)
+	ext, ok := ebookImageTypes[img.mediaType]
+	if !ok {
+		return nil
+	}
+	img.href = fmt.Sprintf("images/image-%03d%s", len(b.imageOrder)+1, ext)
+	img.data = data
+	b.imageOrder = append(b.imageOrder, img)
+	return img
+}
+
+// coverPage shows the cover image, or the title and author when the book
+// has none
+func (b *ebook) coverPage() []byte {
+	if b.cover != "" {
+		b.coverImage = b.image(b.cover)
+	}
+	var out bytes.Buffer
+	b.xhtmlHead(&out, b.language, b.title)
+	out.WriteString("</head>\n<body>\n<section epub:type=\"cover\" class=\"cover\">\n")
+	if b.coverImage != nil {
+		fmt.Fprintf(&out, "<img src=\"%s\" alt=\"%s\"/>\n", b.coverImage.href, xmlEscape(b.title))
+	} else {
+		fmt.Fprintf(&out, "<h1>%s</h1>\n<p class=\"author\">%s</p>\n", xmlEscape(b.title), xmlEscape(b.author))
+	}
+	out.WriteString("</section>\n</body>\n</html>\n")
+	return out.Bytes()
+}
+
+// nav is the EPUB 3 table of contents
+func (b *ebook) nav() []byte {
+	var out bytes.Buffer
+	b.xhtmlHead(&out, b.language, b.title)
+	out.WriteString("</head>\n<body>\n<nav epub:type=\"toc\" id=\"toc\">\n<h1>" + xmlEscape(b.title) + "</h1>\n<ol>\n")
+	for _, chapter := range b.chapters {
+		fmt.Fprintf(&out, "<li><a href=\"%s\">%s</a></li>\n", chapter.href(), xmlEscape(chapter.article.Title))
+	}
+	out.WriteString("</ol>\n</nav>\n<nav epub:type=\"landmarks\" hidden=\"hidden\">\n<ol>\n")
+	out.WriteString("<li><a epub:type=\"cover\" href=\"cover.xhtml\">Cover</a></li>\n")
+	out.WriteString("<li><a epub:type=\"toc\" href=\"nav.xhtml\">Contents</a></li>\n")
+	fmt.Fprintf(&out, "<li><a epub:type=\"bodymatter\" href=\"%s\">%s</a></li>\n", b.chapters[0].href(), xmlEscape(b.chapters[0].article.Title))
+	out.WriteString("</ol>\n</nav>\n</body>\n</html>\n")
+	return out.Bytes()
+}
+
+// ncx is the table of contents of EPUB 2, for older readers
+func (b *ebook) ncx() []byte {
+	var out bytes.Buffer
+	out.WriteString(xml.Header)
+	out.WriteString("<ncx xmlns=\"http://www.daisy.org/z3986/2005/ncx/\" version=\"2005-1\">\n<head>\n")
+	fmt.Fprintf(&out, "<meta name=\"dtb:uid\" content=\"urn:uuid:%s\"/>\n<meta name=\"dtb:depth\" content=\"1\"/>\n", b.id)
+	out.WriteString("<meta name=\"dtb:totalPageCount\" content=\"0\"/>\n<meta name=\"dtb:maxPageNumber\" content=\"0\"/>\n</head>\n")
+	fmt.Fprintf(&out, "<docTitle><text>%s</text></docTitle>\n<navMap>\n", xmlEscape(b.title))
+	for i, chapter := range b.chapters {
+		fmt.Fprintf(&out, "<navPoint id=\"nav-%d\" playOrder=\"%d\"><navLabel><text>%s</text></navLabel><content src=\"%s\"/></navPoint>\n",
+			i+1, i+1, xmlEscape(chapter.article.Title), chapter.href())
+	}
+	out.WriteString("</navMap>\n</ncx>\n")
+	return out.Bytes()
+}
+
+// opf is the package document listing the book's metadata, files and
+// reading order
+func (b *ebook) opf() []byte {
+	var out bytes.Buffer
+	out.WriteString(xml.Header)
+	fmt.Fprintf(&out, "<package xmlns=\"http://www.idpf.org/2007/opf\" version=\"3.0\" unique-identifier=\"book-id\" xml:lang=\"%s\">\n", xmlEscape(b.language))
+	out.WriteString("<metadata xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	fmt.Fprintf(&out, "<dc:identifier id=\"book-id\">urn:uuid:%s</dc:identifier>\n", b.id)
+	fmt.Fprintf(&out, "<dc:title>%s</dc:title>\n<dc:language>%s</dc:language>\n", xmlEscape(b.title), xmlEscape(b.language))
+	if b.author != "" {
+		fmt.Fprintf(&out, "<dc:creator>%s</dc:creator>\n", xmlEscape(b.author))
+	}
+	fmt.Fprintf(&out, "<dc:date>%s</dc:date>\n", b.modified.Format("2006-01-02"))
+	fmt.Fprintf(&out, "<meta property=\"dcterms:modified\">%s</meta>\n", b.modified.Format("2006-01-02T15:04:05Z"))
+	if b.coverImage != nil {
+		out.WriteString("<meta name=\"cover\" content=\"cover-image\"/>\n")
+	}
+	out.WriteString("</metadata>\n<manifest>\n")
+	out.WriteString("<item id=\"nav\" href=\"nav.xhtml\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n")
+	out.WriteString("<item id=\"ncx\" href=\"toc.ncx\" media-type=\"application/x-dtbncx+xml\"/>\n")
+	out.WriteString("<item id=\"style\" href=\"style.css\" media-type=\"text/css\"/>\n")
+	out.WriteString("<item id=\"cover\" href=\"cover.xhtml\" media-type=\"application/xhtml+xml\"/>\n")
+	for i, img := range b.imageOrder {
+		id, properties := fmt.Sprintf("image-%d", i+1), ""
+		if img == b.coverImage {
+			id, properties = "cover-image", " properties=\"cover-image\""
+		}
+		fmt.Fprintf(&out, "<item id=\"%s\" href=\"%s\" media-type=\"%s\"%s/>\n", id, img.href, img.mediaType, properties)
+	}
+	for _, chapter := range b.chapters {
+		properties := ""
+		if len(chapter.properties) > 0 {
+			properties = fmt.Sprintf(" properties=\"%s\"", strings.Join(chapter.properties, " "))
+		}
+		fmt.Fprintf(&out, "<item id=\"chapter-%d\" href=\"%s\" media-type=\"application/xhtml+xml\"%s/>\n", chapter.article.ID, chapter.href(), properties)
+	}
+	out.WriteString("</manifest>\n<spine toc=\"ncx\">\n<itemref idref=\"cover\"/>\n<itemref idref=\"nav\"/>\n")
+	for _, chapter := range b.chapters {
+		fmt.Fprintf(&out, "<itemref idref=\"chapter-%d\"/>\n", chapter.article.ID)
+	}
+	out.WriteString("</spine>\n</package>\n")
+	return out.Bytes()
+}
+
+// xhtmlHead starts an XHTML document in language, up to its open head
+func (b *ebook) xhtmlHead(out *bytes.Buffer, language, title string) {
+	out.WriteString(xml.Header)
+	out.WriteString("<!DOCTYPE html>\n")
+	fmt.Fprintf(out, "<html xmlns=\"http://www.w3.org/1999/xhtml\" xmlns:epub=\"http://www.idpf.org/2007/ops\" xml:lang=\"%[1]s\" lang=\"%[1]s\" dir=\"%[2]s\">\n",
+		xmlEscape(language), models.LanguageDirection(language))
+	fmt.Fprintf(out, "<head>\n<meta charset=\"utf-8\"/>\n<title>%s</title>\n<link rel=\"stylesheet\" type=\"text/css\" href=\"style.css\"/>\n", xmlEscape(title))
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const ebookContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
+
+const ebookStylesheet = `body { font-family: serif; line-height: 1.6; margin: 0 4%; }
+h1 { font-size: 1.6em; line-height: 1.3; margin: 1em 0 0.3em; }
+header { margin-bottom: 1.5em; }
+.meta { color: #666; font-size: 0.85em; }
+.summary { font-style: italic; }
+img, svg { max-width: 100%; height: auto; }
+pre { white-space: pre-wrap; font-size: 0.85em; padding: 0.6em; }
+code { font-family: monospace; }
+blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1em; color: #444; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.5em; }
+.missing-image { color: #666; font-style: italic; }
+.cover { text-align: center; }
+.cover img { max-height: 95vh; }
+.author { font-size: 1.2em; }
+`
+
+var (
+	globalEbookService     *EbookService
+	globalEbookServiceOnce sync.Once
+)
+
+// GetGlobalEbookService returns the global ebook service
+func GetGlobalEbookService() *EbookService {
+	globalEbookServiceOnce.Do(func() {
+		globalEbookService = NewEbookService()
+	})
+	return globalEbookService
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"archive/zip"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestEbookExport(t *testing.T) {
+	newTestDB(t)
+	uploads := t.TempDir()
+	var picture bytes.Buffer
+	if err := png.Encode(&picture, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(uploads, "a.png"), picture.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := &EbookService{
+		db:        func() *gorm.DB { return database.DB },
+		now:       func() time.Time { return now },
+		dir:       t.TempDir(),
+		uploadDir: uploads,
+		markdown:  NewMarkdownRenderer(NewCodeHighlighter(), &DiagramRenderer{}),
+		images:    NewArticlePDFRenderer(nil, nil, NewLinkPreviewService()),
+	}
+
+	category := models.Category{Name: "Go"}
+	other := models.Category{Name: "Other"}
+	for _, record := range []interface{}{&models.SiteSettings{SiteTitle: "Blog", DefaultLanguage: "en"}, &category, &other} {
+		if err := database.DB.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	cover := "/uploads/a.png"
+	articles := []models.Article{
+		{Title: "First", CategoryID: category.ID, DefaultLang: "en", CoverImageURL: &cover, CreatedAt: now.Add(-48 * time.Hour),
+			Content: "Intro with [a link](/about) & ![pic](/uploads/a.png) ![gone](/uploads/missing.png)\n\n$$\nx^2\n$$\n",
+			Translations: []models.ArticleTranslation{{Language: "zh", Title: "第一篇", Content: "中文内容"}}},
+		{Title: "Second <draft>", CategoryID: category.ID, DefaultLang: "en", ContentType: "html", CreatedAt: now.Add(-time.Hour),
+			Content: `<p>HTML<br>text<script>alert(1)</script></p>`},
+		{Title: "Scheduled", CategoryID: category.ID, DefaultLang: "en", CreatedAt: now.Add(time.Hour), Content: "Later"},
+	}
+	for i := range articles {
+		if err := database.DB.Create(&articles[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, bad := range []EbookRequest{
+		{},
+		{CategoryID: category.ID, ArticleIDs: []uint{articles[0].ID}},
+		{CategoryID: other.ID},
+		{CategoryID: 999},
+		{ArticleIDs: []uint{articles[0].ID, articles[0].ID}},
+		{ArticleIDs: []uint{articles[0].ID, 999}},
+		{ArticleIDs: []uint{articles[0].ID}, Language: "english!"},
+		{ArticleIDs: []uint{articles[0].ID}, CoverURL: "javascript:alert(1)"},
+	} {
+		if err := s.validate(models.DefaultSiteID, &bad); !errors.Is(err, ErrInvalidEbook) {
+			t.Errorf("validate(%+v) = %v, want ErrInvalidEbook", bad, err)
+		}
+	}
+
+	export := func(req EbookRequest, name string) map[string]string {
+		t.Helper()
+		if err := s.validate(models.DefaultSiteID, &req); err != nil {
+			t.Fatal(err)
+		}
+		payload, _ := json.Marshal(ebookJob{EbookRequest: req, SiteID: models.DefaultSiteID, BaseURL: "https://blog.example.com", Name: name})
+		if _, err := s.Export(context.Background(), payload); err != nil {
+			t.Fatal(err)
+		}
+		path, err := s.Path(models.DefaultSiteID, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer archive.Close()
+		if first := archive.File[0]; first.Name != "mimetype" || first.Method != zip.Store {
+			t.Errorf("first entry %s is not the stored mimetype", first.Name)
+		}
+		files := map[string]string{}
+		for _, f := range archive.File {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := io.ReadAll(r)
+			r.Close()
+			files[f.Name] = string(data)
+			if ext := filepath.Ext(f.Name); ext == ".xhtml" || ext == ".opf" || ext == ".ncx" || ext == ".xml" {
+				decoder := xml.NewDecoder(bytes.NewReader(data))
+				for {
+					if _, err := decoder.Token(); err == io.EOF {
+						break
+					} else if err != nil {
+						t.Fatalf("%s is not well-formed: %v\n%s", f.Name, err, data)
+					}
+				}
+			}
+		}
+		return files
+	}
+
+	files := export(EbookRequest{CategoryID: category.ID}, "ebook-20260501-120000-0000000a.epub")
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{
+		"<dc:title>Go</dc:title>", "<dc:language>en</dc:language>", "<dc:creator>Blog</dc:creator>",
+		`properties="cover-image"`, `href="chapter-001.xhtml" media-type="application/xhtml+xml" properties="mathml"`,
+		`<itemref idref="chapter-1"/>` + "\n" + `<itemref idref="chapter-2"/>` + "\n</spine>",
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("package lacks %q:\n%s", want, opf)
+		}
+	}
+	if strings.Contains(files["OEBPS/nav.xhtml"], "Scheduled") {
+		t.Error("the scheduled article was exported")
+	}
+	first := files["OEBPS/chapter-001.xhtml"]
+	for _, want := range []string{
+		`<a href="https://blog.example.com/en/article/1">`, `<a href="https://blog.example.com/about">`,
+		`src="images/image-001.png"`, `<span class="missing-image">[gone]</span>`,
+		`<math xmlns="http://www.w3.org/1998/Math/MathML"`, `<meta name="dcterms.subject" content="Go"/>`,
+	} {
+		if !strings.Contains(first, want) {
+			t.Errorf("first chapter lacks %q:\n%s", want, first)
+		}
+	}
+	second := files["OEBPS/chapter-002.xhtml"]
+	if !strings.Contains(second, "<h1>Second &lt;draft&gt;</h1>") || !strings.Contains(second, "<br/>") || strings.Contains(second, "alert") {
+		t.Errorf("second chapter is not sanitized XHTML:\n%s", second)
+	}
+	if len(files) != 10 {
+		t.Errorf("%d files in the book, want 10", len(files))
+	}
+
+	// Hand-picked articles keep their order and use the book's language
+	// where translated
+	files = export(EbookRequest{ArticleIDs: []uint{articles[1].ID, articles[0].ID}, Title: "Picks", Language: "zh"}, "ebook-20260501-120000-0000000b.epub")
+	nav := files["OEBPS/nav.xhtml"]
+	if strings.Index(nav, "Second") > strings.Index(nav, "第一篇") || !strings.Contains(files["OEBPS/chapter-001.xhtml"], `xml:lang="zh"`) {
+		t.Errorf("contents out of order or untranslated:\n%s", nav)
+	}
+
+	books, err := s.List(models.DefaultSiteID)
+	if err != nil || len(books) != 2 {
+		t.Fatalf("List() = %v, %v, want 2 books", books, err)
+	}
+	if _, err := s.Path(models.DefaultSiteID, "../blog.db"); !errors.Is(err, ErrEbookNotFound) {
+		t.Errorf("Path() outside the naming scheme = %v", err)
+	}
+	if _, err := s.Path(2, books[0].Name); !errors.Is(err, ErrEbookNotFound) {
+		t.Error("another site's book was found")
+	}
+	if err := s.Delete(models.DefaultSiteID, books[0].Name); err != nil {
+		t.Fatal(err)
+	}
+}
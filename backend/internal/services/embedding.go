@@ -5,6 +5,7 @@ import (
 	"blog-backend/internal/models"
 	"blog-backend/internal/security"
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
@@ -16,11 +17,13 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // EmbeddingProvider defines the interface for embedding providers
 type EmbeddingProvider interface {
-	GenerateEmbedding(text string) ([]float64, int, error)
+	GenerateEmbedding(ctx context.Context, text string) ([]float64, int, error)
 	GetProviderName() string
 	GetModelName() string
 	IsConfigured() bool
@@ -49,12 +52,13 @@ func NewEmbeddingService() *EmbeddingService {
 	// Initialize providers
 	service.initializeProviders()
 	
-	// Start precomputation scheduler to reduce AI API costs
-	service.SchedulePrecomputation()
-	
-	// Start embedding optimization scheduler
-	service.OptimizeEmbeddingProcessing()
-	
+	// Reload providers when an externally managed API key is rotated
+	security.GetGlobalSecretManager().OnRotate(func(string) {
+		if err := service.ReloadConfig(); err != nil {
+			log.Printf("Failed to reload embedding providers after secret rotation: %v", err)
+		}
+	})
+
 	return service
 }
 
@@ -153,7 +157,7 @@ func (es *EmbeddingService) initializeOpenAIProvider() {
 
 	// Fall back to environment variables
 	if apiKey == "" {
-		apiKey = os.Getenv("OPENAI_API_KEY")
+		apiKey = getEnvSecret("OPENAI_API_KEY")
 		model = getEnvOrDefault("OPENAI_EMBEDDING_MODEL", "text-embedding-ada-002")
 	}
 
@@ -185,7 +189,7 @@ func (es *EmbeddingService) initializeGeminiProvider() {
 
 	// Fall back to environment variables
 	if apiKey == "" {
-		apiKey = os.Getenv("GEMINI_API_KEY")
+		apiKey = getEnvSecret("GEMINI_API_KEY")
 		model = getEnvOrDefault("GEMINI_EMBEDDING_MODEL", "text-embedding-004")
 	}
 
@@ -199,6 +203,16 @@ func (es *EmbeddingService) initializeGeminiProvider() {
 	}
 }
 
+// getEnvSecret reads an environment variable that may hold a secret reference
+func getEnvSecret(key string) string {
+	value, err := security.ResolveSecret(os.Getenv(key))
+	if err != nil {
+		log.Printf("Failed to resolve %s: %v", key, err)
+		return ""
+	}
+	return value
+}
+
 // getEnvOrDefault returns environment variable or default value
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -213,7 +227,7 @@ type OpenAIEmbeddingProvider struct {
 	Model  string
 }
 
-func (p *OpenAIEmbeddingProvider) GenerateEmbedding(text string) ([]float64, int, error) {
+func (p *OpenAIEmbeddingProvider) GenerateEmbedding(ctx context.Context, text string) ([]float64, int, error) {
 	if !p.IsConfigured() {
 		return nil, 0, fmt.Errorf("OpenAI API key not configured")
 	}
@@ -233,7 +247,7 @@ func (p *OpenAIEmbeddingProvider) GenerateEmbedding(text string) ([]float64, int
 		return nil, 0, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://api.openai.com/v1/embeddings", bytes.NewBuffer(reqData))
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/embeddings", bytes.NewBuffer(reqData))
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to create request: %v", err)
 	}
@@ -307,7 +321,7 @@ type GeminiEmbeddingProvider struct {
 	Model  string
 }
 
-func (p *GeminiEmbeddingProvider) GenerateEmbedding(text string) ([]float64, int, error) {
+func (p *GeminiEmbeddingProvider) GenerateEmbedding(ctx context.Context, text string) ([]float64, int, error) {
 	if !p.IsConfigured() {
 		return nil, 0, fmt.Errorf("Gemini API key not configured")
 	}
@@ -332,7 +346,7 @@ func (p *GeminiEmbeddingProvider) GenerateEmbedding(text string) ([]float64, int
 	}
 
 	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:embedContent?key=%s", p.Model, p.APIKey)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(reqData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(reqData))
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to create request: %v", err)
 	}
@@ -414,12 +428,12 @@ type EmbeddingResponse struct {
 }
 
 // GenerateEmbedding generates embeddings using the default or specified provider
-func (es *EmbeddingService) GenerateEmbedding(text string) ([]float64, int, error) {
-	return es.GenerateEmbeddingWithProvider(text, "")
+func (es *EmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float64, int, error) {
+	return es.GenerateEmbeddingWithProvider(ctx, text, "")
 }
 
 // GenerateEmbeddingWithProvider generates embeddings using a specific provider
-func (es *EmbeddingService) GenerateEmbeddingWithProvider(text, providerName string) ([]float64, int, error) {
+func (es *EmbeddingService) GenerateEmbeddingWithProvider(ctx context.Context, text, providerName string) ([]float64, int, error) {
 	// Use default provider if none specified
 	if providerName == "" {
 		providerName = es.defaultProvider
@@ -434,7 +448,7 @@ func (es *EmbeddingService) GenerateEmbeddingWithProvider(text, providerName str
 		return nil, 0, fmt.Errorf("provider %s not configured", providerName)
 	}
 
-	embedding, tokenCount, err := provider.GenerateEmbedding(text)
+	embedding, tokenCount, err := provider.GenerateEmbedding(ctx, text)
 	if err != nil {
 		return nil, 0, fmt.Errorf("provider %s failed: %v", providerName, err)
 	}
@@ -445,6 +459,37 @@ func (es *EmbeddingService) GenerateEmbeddingWithProvider(text, providerName str
 	return embedding, tokenCount, nil
 }
 
+// DefaultModel returns the provider and model new embeddings are made
+// with, as stored on article embeddings
+func (es *EmbeddingService) DefaultModel() (string, string) {
+	return es.defaultProvider, es.getProviderModel(es.defaultProvider)
+}
+
+// EmbedText generates the embedding of a text with the default provider
+// and records the call in the AI usage records under operation
+func (es *EmbeddingService) EmbedText(ctx context.Context, text, operation string) ([]float64, error) {
+	embedding, tokenCount, err := es.GenerateEmbedding(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	usageMetrics := UsageMetrics{
+		ServiceType:   "embedding",
+		Provider:      es.defaultProvider,
+		Model:         es.getProviderModel(es.defaultProvider),
+		Operation:     operation,
+		InputTokens:   tokenCount,
+		TotalTokens:   tokenCount,
+		EstimatedCost: es.calculateEmbeddingCost(es.defaultProvider, tokenCount),
+		Currency:      "USD",
+		InputLength:   len(text),
+		Success:       true,
+	}
+	if err := es.usageTracker.TrackUsage(usageMetrics); err != nil {
+		log.Printf("Failed to track %s embedding usage: %v", operation, err)
+	}
+	return embedding, nil
+}
+
 // GetAvailableProviders returns list of configured providers
 func (es *EmbeddingService) GetAvailableProviders() []string {
 	var providers []string
@@ -513,7 +558,7 @@ func (es *EmbeddingService) ReloadConfig() error {
 }
 
 // ProcessArticleEmbeddings generates and stores embeddings for an article
-func (es *EmbeddingService) ProcessArticleEmbeddings(articleID uint) error {
+func (es *EmbeddingService) ProcessArticleEmbeddings(ctx context.Context, articleID uint) error {
 	// Get article from database
 	var article models.Article
 	result := database.DB.Preload("Translations").First(&article, articleID)
@@ -522,13 +567,13 @@ func (es *EmbeddingService) ProcessArticleEmbeddings(articleID uint) error {
 	}
 
 	// Process main article content
-	if err := es.processArticleContent(article, article.DefaultLang); err != nil {
+	if err := es.processArticleContent(ctx, article, article.DefaultLang); err != nil {
 		log.Printf("Error processing main article content: %v", err)
 	}
 
 	// Process translations
 	for _, translation := range article.Translations {
-		if err := es.processTranslationContent(article, translation); err != nil {
+		if err := es.processTranslationContent(ctx, article, translation); err != nil {
 			log.Printf("Error processing translation content (%s): %v", translation.Language, err)
 		}
 	}
@@ -537,7 +582,7 @@ func (es *EmbeddingService) ProcessArticleEmbeddings(articleID uint) error {
 }
 
 // processArticleContent generates embeddings for the main article content
-func (es *EmbeddingService) processArticleContent(article models.Article, language string) error {
+func (es *EmbeddingService) processArticleContent(ctx context.Context, article models.Article, language string) error {
 	// Process different content types
 	contentTypes := map[string]string{
 		"title":   article.Title,
@@ -554,7 +599,7 @@ func (es *EmbeddingService) processArticleContent(article models.Article, langua
 			continue
 		}
 
-		if err := es.generateAndStoreEmbedding(article.ID, contentType, language, text); err != nil {
+		if err := es.generateAndStoreEmbedding(ctx, article.ID, contentType, language, text); err != nil {
 			return fmt.Errorf("failed to process %s: %v", contentType, err)
 		}
 	}
@@ -563,7 +608,7 @@ func (es *EmbeddingService) processArticleContent(article models.Article, langua
 }
 
 // processTranslationContent generates embeddings for translated content
-func (es *EmbeddingService) processTranslationContent(article models.Article, translation models.ArticleTranslation) error {
+func (es *EmbeddingService) processTranslationContent(ctx context.Context, article models.Article, translation models.ArticleTranslation) error {
 	contentTypes := map[string]string{
 		"title":   translation.Title,
 		"content": translation.Content,
@@ -579,7 +624,7 @@ func (es *EmbeddingService) processTranslationContent(article models.Article, tr
 			continue
 		}
 
-		if err := es.generateAndStoreEmbedding(article.ID, contentType, translation.Language, text); err != nil {
+		if err := es.generateAndStoreEmbedding(ctx, article.ID, contentType, translation.Language, text); err != nil {
 			return fmt.Errorf("failed to process translation %s: %v", contentType, err)
 		}
 	}
@@ -588,7 +633,7 @@ func (es *EmbeddingService) processTranslationContent(article models.Article, tr
 }
 
 // generateAndStoreEmbedding generates embedding and stores it in database
-func (es *EmbeddingService) generateAndStoreEmbedding(articleID uint, contentType, language, text string) error {
+func (es *EmbeddingService) generateAndStoreEmbedding(ctx context.Context, articleID uint, contentType, language, text string) error {
 	// Generate content hash
 	hash := sha256.Sum256([]byte(text))
 	contentHash := fmt.Sprintf("%x", hash)
@@ -604,7 +649,7 @@ func (es *EmbeddingService) generateAndStoreEmbedding(articleID uint, contentTyp
 	}
 
 	// Generate embedding using default provider
-	embedding, tokenCount, err := es.GenerateEmbedding(text)
+	embedding, tokenCount, err := es.GenerateEmbedding(ctx, text)
 	if err != nil {
 		return fmt.Errorf("failed to generate embedding: %v", err)
 	}
@@ -671,20 +716,19 @@ func (es *EmbeddingService) generateAndStoreEmbedding(articleID uint, contentTyp
 }
 
 // SearchSimilarArticles performs semantic search using vector similarity
-func (es *EmbeddingService) SearchSimilarArticles(query string, language string, limit int, threshold float64) ([]models.EmbeddingSearchResult, error) {
+func (es *EmbeddingService) SearchSimilarArticles(ctx context.Context, query string, language string, limit int, threshold float64) ([]models.EmbeddingSearchResult, error) {
 	// Check cache first for frequently used queries
 	cacheKey := fmt.Sprintf("search_%s_%s_%d_%.2f",
 		fmt.Sprintf("%x", sha256.Sum256([]byte(query))), language, limit, threshold)
 
-	if cached, exists := GetGlobalCache().Get(cacheKey); exists {
-		if results, ok := cached.([]models.EmbeddingSearchResult); ok {
-			log.Printf("🔄 Using cached search results for query (no API call needed) - saving ~$%.6f", 0.00002)
-			return results, nil
-		}
+	var cached []models.EmbeddingSearchResult
+	if GetGlobalCache().Get(cacheKey, &cached) {
+		log.Printf("🔄 Using cached search results for query (no API call needed) - saving ~$%.6f", 0.00002)
+		return cached, nil
 	}
 
 	// Generate embedding for search query
-	queryEmbedding, tokenCount, err := es.GenerateEmbedding(query)
+	queryEmbedding, tokenCount, err := es.GenerateEmbedding(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate query embedding: %v", err)
 	}
@@ -766,11 +810,13 @@ func (es *EmbeddingService) SearchSimilarArticles(query string, language string,
 			log.Printf("Failed to fetch article %d: %v", sim.ArticleID, err)
 			continue
 		}
+		ApplyCategoryTranslation(&article.Category, language)
 
 		result := models.EmbeddingSearchResult{
 			ArticleID:    article.ID,
 			Title:        article.Title,
 			Summary:      article.Summary,
+			CategoryID:   article.CategoryID,
 			CategoryName: article.Category.Name,
 			Language:     language,
 			Similarity:   sim.Similarity,
@@ -879,10 +925,12 @@ func (es *EmbeddingService) SearchSimilarByArticleID(articleID uint, language st
 	for _, articleID := range articleIDs {
 		for _, article := range articles {
 			if article.ID == articleID {
+				ApplyCategoryTranslation(&article.Category, language)
 				result := models.EmbeddingSearchResult{
 					ArticleID:    article.ID,
 					Title:        article.Title,
 					Summary:      article.Summary,
+					CategoryID:   article.CategoryID,
 					CategoryName: article.Category.Name,
 					Language:     language,
 					Similarity:   similarityMap[articleID],
@@ -910,7 +958,7 @@ func (es *EmbeddingService) SearchSimilarByArticleID(articleID uint, language st
 }
 
 // BatchProcessAllArticles processes embeddings for all articles
-func (es *EmbeddingService) BatchProcessAllArticles() error {
+func (es *EmbeddingService) BatchProcessAllArticles(ctx context.Context) error {
 	var articles []models.Article
 	if err := database.DB.Find(&articles).Error; err != nil {
 		return fmt.Errorf("failed to fetch articles: %v", err)
@@ -918,41 +966,31 @@ func (es *EmbeddingService) BatchProcessAllArticles() error {
 
 	log.Printf("Processing embeddings for %d articles", len(articles))
 
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(articles))
 	for _, article := range articles {
-		if err := es.ProcessArticleEmbeddings(article.ID); err != nil {
+		if IsShuttingDown() {
+			return fmt.Errorf("batch processing interrupted by shutdown")
+		}
+		progress.Step("Embedding article %d: %s", article.ID, article.Title)
+		if err := es.ProcessArticleEmbeddings(ctx, article.ID); err != nil {
 			log.Printf("Failed to process embeddings for article %d: %v", article.ID, err)
+			progress.ItemFailed(fmt.Sprintf("article %d", article.ID), err)
+			continue
 		}
+		progress.Advance(1)
 	}
 
 	// Update search index
-	es.updateSearchIndex("embedding", "all")
+	es.updateSearchIndex()
 
 	return nil
 }
 
-// updateSearchIndex updates the search index statistics
-func (es *EmbeddingService) updateSearchIndex(indexType, language string) {
-	var count int64
-	database.DB.Model(&models.ArticleEmbedding{}).Where("language = ?", language).Count(&count)
-
-	var searchIndex models.SearchIndex
-	result := database.DB.Where("index_type = ? AND language = ?", indexType, language).First(&searchIndex)
-
-	if result.Error != nil {
-		// Create new index record
-		searchIndex = models.SearchIndex{
-			IndexType:      indexType,
-			Language:       language,
-			TotalDocuments: int(count),
-			LastUpdated:    time.Now(),
-			LastRebuild:    time.Now(),
-		}
-		database.DB.Create(&searchIndex)
-	} else {
-		// Update existing record
-		searchIndex.TotalDocuments = int(count)
-		searchIndex.LastUpdated = time.Now()
-		database.DB.Save(&searchIndex)
+// updateSearchIndex recounts the search index statistics from the tables
+func (es *EmbeddingService) updateSearchIndex() {
+	if err := GetGlobalSearchIndexService().RecordStats(); err != nil {
+		log.Printf("Failed to update search index statistics: %v", err)
 	}
 }
 
@@ -976,13 +1014,22 @@ func cosineSimilarity(a, b []float64) float64 {
 	return dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
 }
 
-// GetEmbeddingStats returns statistics about embeddings
-func (es *EmbeddingService) GetEmbeddingStats() (map[string]interface{}, error) {
+// SiteEmbeddings returns a query on the embeddings of the articles of the
+// site in db's context. Embeddings carry no site of their own, so they are
+// matched through their article.
+func SiteEmbeddings(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.ArticleEmbedding{}).Where("article_id IN (?)", db.Model(&models.Article{}).Select("id"))
+}
+
+// GetEmbeddingStats returns statistics about the embeddings of the site in
+// ctx
+func (es *EmbeddingService) GetEmbeddingStats(ctx context.Context) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
+	db := database.DB.WithContext(ctx)
 
 	// Total embeddings count
 	var totalCount int64
-	database.DB.Model(&models.ArticleEmbedding{}).Count(&totalCount)
+	SiteEmbeddings(db).Count(&totalCount)
 	stats["total_embeddings"] = totalCount
 
 	// Count by language
@@ -990,7 +1037,7 @@ func (es *EmbeddingService) GetEmbeddingStats() (map[string]interface{}, error)
 		Language string `json:"language"`
 		Count    int64  `json:"count"`
 	}
-	database.DB.Model(&models.ArticleEmbedding{}).
+	SiteEmbeddings(db).
 		Select("language, COUNT(*) as count").
 		Group("language").
 		Scan(&languageStats)
@@ -1001,7 +1048,7 @@ func (es *EmbeddingService) GetEmbeddingStats() (map[string]interface{}, error)
 		ContentType string `json:"content_type"`
 		Count       int64  `json:"count"`
 	}
-	database.DB.Model(&models.ArticleEmbedding{}).
+	SiteEmbeddings(db).
 		Select("content_type, COUNT(*) as count").
 		Group("content_type").
 		Scan(&contentTypeStats)
@@ -1009,7 +1056,7 @@ func (es *EmbeddingService) GetEmbeddingStats() (map[string]interface{}, error)
 
 	// Latest update
 	var latestEmbedding models.ArticleEmbedding
-	if err := database.DB.Order("created_at DESC").First(&latestEmbedding).Error; err == nil {
+	if err := SiteEmbeddings(db).Order("created_at DESC").First(&latestEmbedding).Error; err == nil {
 		stats["latest_update"] = latestEmbedding.CreatedAt
 	}
 
@@ -1338,10 +1385,10 @@ func (es *EmbeddingService) GetQualityMetrics() (*QualityMetrics, error) {
 }
 
 // GetRAGProcessVisualization provides data for RAG process visualization
-func (es *EmbeddingService) GetRAGProcessVisualization(query string, language string, limit int) (*RAGProcessVisualization, error) {
+func (es *EmbeddingService) GetRAGProcessVisualization(ctx context.Context, query string, language string, limit int) (*RAGProcessVisualization, error) {
 	// Step 1: Generate query embedding
 	step1Start := time.Now()
-	queryVector, _, err := es.GenerateEmbedding(query)
+	queryVector, _, err := es.GenerateEmbedding(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate query embedding: %v", err)
 	}
@@ -1349,7 +1396,7 @@ func (es *EmbeddingService) GetRAGProcessVisualization(query string, language st
 
 	// Step 2: Retrieve similar documents
 	step2Start := time.Now()
-	results, err := es.SearchSimilarArticles(query, language, limit, 0.0)
+	results, err := es.SearchSimilarArticles(ctx, query, language, limit, 0.0)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search embeddings: %v", err)
 	}
@@ -1432,20 +1479,15 @@ func (es *EmbeddingService) calculateEmbeddingCost(provider string, tokens int)
 // setSearchCache stores search results with extended TTL to reduce AI API costs
 func (es *EmbeddingService) setSearchCache(key string, results []models.EmbeddingSearchResult) {
 	// Use global cache with extended TTL for search results to minimize AI API calls
-	cache := GetGlobalCache()
-	
-	// Set in memory cache for immediate access
-	cache.Set(key, results)
-	
-	// Also set in SQLite cache with 4-hour TTL for search results
+	// Keep search results in the SQLite tier for 4 hours
 	extendedTTL := time.Hour * 4
-	if err := cache.sqliteCache.Set(key, results, &extendedTTL); err != nil {
+	if err := GetGlobalCache().SetWithTTL(key, results, extendedTTL); err != nil {
 		log.Printf("Failed to cache search results with extended TTL: %v", err)
 	}
 }
 
 // PrecomputePopularQueries precomputes embeddings for popular search queries to reduce AI API costs
-func (es *EmbeddingService) PrecomputePopularQueries() error {
+func (es *EmbeddingService) PrecomputePopularQueries(ctx context.Context) error {
 	log.Printf("🔄 Starting precomputation of popular queries to reduce AI API costs...")
 	
 	// Get popular queries from database
@@ -1458,17 +1500,22 @@ func (es *EmbeddingService) PrecomputePopularQueries() error {
 	
 	successCount := 0
 	for _, query := range popularQueries {
+		if IsShuttingDown() {
+			break
+		}
+
 		// Check if already cached
 		cacheKey := fmt.Sprintf("search_%s_%s_5_0.60",
 			fmt.Sprintf("%x", sha256.Sum256([]byte(query.QueryText))), query.Language)
 		
-		if _, exists := GetGlobalCache().Get(cacheKey); exists {
+		var cached []models.EmbeddingSearchResult
+		if GetGlobalCache().Get(cacheKey, &cached) {
 			log.Printf("⏭️ Skipping already cached query: %s", query.QueryText[:min(50, len(query.QueryText))])
 			continue
 		}
 		
 		// Precompute search results for popular queries
-		results, err := es.SearchSimilarArticles(query.QueryText, query.Language, 5, 0.6)
+		results, err := es.SearchSimilarArticles(ctx, query.QueryText, query.Language, 5, 0.6)
 		if err != nil {
 			log.Printf("❌ Failed to precompute query '%s': %v", query.QueryText, err)
 			continue
@@ -1486,31 +1533,8 @@ func (es *EmbeddingService) PrecomputePopularQueries() error {
 	return nil
 }
 
-// SchedulePrecomputation starts a background routine to precompute popular queries
-func (es *EmbeddingService) SchedulePrecomputation() {
-	go func() {
-		// Initial precomputation after 1 minute
-		time.Sleep(time.Minute)
-		if err := es.PrecomputePopularQueries(); err != nil {
-			log.Printf("Initial precomputation failed: %v", err)
-		}
-		
-		// Then every 6 hours
-		ticker := time.NewTicker(6 * time.Hour)
-		defer ticker.Stop()
-		
-		for range ticker.C {
-			if err := es.PrecomputePopularQueries(); err != nil {
-				log.Printf("Scheduled precomputation failed: %v", err)
-			}
-		}
-	}()
-	
-	log.Printf("📅 Scheduled precomputation service started (every 6 hours)")
-}
-
 // BatchProcessMissingEmbeddings processes articles without embeddings in batches to reduce API costs
-func (es *EmbeddingService) BatchProcessMissingEmbeddings(batchSize int) error {
+func (es *EmbeddingService) BatchProcessMissingEmbeddings(ctx context.Context, batchSize int) error {
 	log.Printf("🔄 Starting batch processing of missing embeddings (batch size: %d)", batchSize)
 	
 	// Get articles without embeddings
@@ -1530,16 +1554,25 @@ func (es *EmbeddingService) BatchProcessMissingEmbeddings(batchSize int) error {
 	
 	successCount := 0
 	totalCost := 0.0
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(articles))
 	
 	for i, article := range articles {
+		if IsShuttingDown() {
+			log.Printf("Shutdown requested, stopping batch after %d articles", i)
+			break
+		}
 		log.Printf("🔄 Processing article %d/%d: %s", i+1, len(articles), article.Title)
+		progress.Step("Embedding article %d: %s", article.ID, article.Title)
 		
-		if err := es.ProcessArticleEmbeddings(article.ID); err != nil {
+		if err := es.ProcessArticleEmbeddings(ctx, article.ID); err != nil {
 			log.Printf("❌ Failed to process article %d: %v", article.ID, err)
+			progress.ItemFailed(fmt.Sprintf("article %d", article.ID), err)
 			continue
 		}
 		
 		successCount++
+		progress.Advance(1)
 		
 		// Estimate cost saved by batching
 		estimatedCost := 0.00005 // Rough estimate per article
@@ -1557,29 +1590,6 @@ func (es *EmbeddingService) BatchProcessMissingEmbeddings(batchSize int) error {
 	return nil
 }
 
-// OptimizeEmbeddingProcessing provides intelligent embedding processing with cost optimization
-func (es *EmbeddingService) OptimizeEmbeddingProcessing() {
-	go func() {
-		// Wait a bit before starting optimization
-		time.Sleep(2 * time.Minute)
-		
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
-		
-		for {
-			select {
-			case <-ticker.C:
-				// Process missing embeddings in small batches
-				if err := es.BatchProcessMissingEmbeddings(5); err != nil {
-					log.Printf("Batch processing error: %v", err)
-				}
-			}
-		}
-	}()
-	
-	log.Printf("🤖 Embedding optimization scheduler started (processes 5 articles per hour)")
-}
-
 // getProviderModel returns the model name for a given provider
 func (es *EmbeddingService) getProviderModel(providerName string) string {
 	if provider, exists := es.providers[providerName]; exists {
@@ -1603,13 +1613,7 @@ func min(a, b int) int {
 	return b
 }
 
-// Global embedding service instance (moved from api package)
-var globalEmbeddingServiceForServices *EmbeddingService
-
-// GetGlobalEmbeddingService returns the global embedding service instance
+// GetGlobalEmbeddingService returns the embedding service of the global container
 func GetGlobalEmbeddingService() *EmbeddingService {
-	if globalEmbeddingServiceForServices == nil {
-		globalEmbeddingServiceForServices = NewEmbeddingService()
-	}
-	return globalEmbeddingServiceForServices
+	return GetGlobalContainer().Embeddings
 }
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"testing"
+)
+
+func TestEmbeddingStatsCountOnlyTheSite(t *testing.T) {
+	newTestDB(t)
+	if err := database.DB.Use(database.SiteScope{}); err != nil {
+		t.Fatal(err)
+	}
+	other := models.Site{Name: "Other", Slug: "other"}
+	if err := database.DB.Create(&other).Error; err != nil {
+		t.Fatal(err)
+	}
+	defaultSite := database.WithSite(context.Background(), models.DefaultSiteID)
+	otherSite := database.WithSite(context.Background(), other.ID)
+
+	for i, ctx := range []context.Context{defaultSite, otherSite, otherSite} {
+		article := models.Article{Title: "Article"}
+		if err := database.DB.WithContext(ctx).Create(&article).Error; err != nil {
+			t.Fatal(err)
+		}
+		embedding := models.ArticleEmbedding{
+			ArticleID: article.ID, ContentType: "combined", Language: []string{"en", "en", "zh"}[i],
+			Provider: "openai", Embedding: "[0.1]", Dimensions: 1,
+		}
+		if err := database.DB.Create(&embedding).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := NewEmbeddingService().GetEmbeddingStats(defaultSite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["total_embeddings"] != int64(1) {
+		t.Errorf("default site counts %v embeddings, want 1", stats["total_embeddings"])
+	}
+	var count int64
+	SiteEmbeddings(database.DB.WithContext(otherSite)).Where("language = ?", "zh").Count(&count)
+	if count != 1 {
+		t.Errorf("other site has %d zh embeddings, want 1", count)
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"archive/zip"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// ApplyTranslation replaces the article's title, content and summary with
+// its translation in lang, where one exists
+func ApplyTranslation(article *models.Article, lang string) {
+	for _, translation := range article.Translations {
+		if translation.Language == lang {
+			if translation.Title != "" {
+				article.Title = translation.Title
+			}
+			if translation.Content != "" {
+				article.Content = translation.Content
+			}
+			if translation.Summary != "" {
+				article.Summary = translation.Summary
+			}
+			if translation.Title != "" || translation.Content != "" {
+				article.Dir = models.LanguageDirection(lang)
+			}
+			break
+		}
+	}
+}
+
+// WriteArticlesZip writes articles as markdown files to a zip archive,
+// translated to lang unless it is the default "zh". With byCategory the
+// files are grouped in one folder per category.
+func WriteArticlesZip(w io.Writer, articles []models.Article, lang string, byCategory bool) error {
+	zipWriter := zip.NewWriter(w)
+	for _, article := range articles {
+		if lang != "zh" && lang != "" {
+			ApplyTranslation(&article, lang)
+		}
+
+		filename := SafeFilename(article.Title) + ".md"
+		if byCategory {
+			filename = SafeFilename(article.Category.Name) + "/" + filename
+		}
+		fileWriter, err := zipWriter.Create(filename)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fileWriter, ArticleMarkdown(article)); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// ArticleMarkdown renders an article as markdown with YAML front matter
+func ArticleMarkdown(article models.Article) string {
+	var builder strings.Builder
+
+	// Write front matter (YAML header)
+	builder.WriteString("---\n")
+	builder.WriteString(fmt.Sprintf("title: \"%s\"\n", strings.ReplaceAll(article.Title, "\"", "\\\"")))
+	builder.WriteString(fmt.Sprintf("category: \"%s\"\n", article.Category.Name))
+	builder.WriteString(fmt.Sprintf("date: \"%s\"\n", article.CreatedAt.Format("2006-01-02 15:04:05")))
+	if article.Summary != "" {
+		builder.WriteString(fmt.Sprintf("summary: \"%s\"\n", strings.ReplaceAll(article.Summary, "\"", "\\\"")))
+	}
+	if article.ViewCount > 0 {
+		builder.WriteString(fmt.Sprintf("views: %d\n", article.ViewCount))
+	}
+	builder.WriteString("---\n\n")
+
+	// Write title as H1
+	builder.WriteString(fmt.Sprintf("# %s\n\n", article.Title))
+
+	// Write summary if exists
+	if article.Summary != "" {
+		builder.WriteString(fmt.Sprintf("*%s*\n\n", article.Summary))
+	}
+
+	// Write main content
+	builder.WriteString(article.Content)
+
+	// Add metadata footer
+	builder.WriteString("\n\n---\n")
+	builder.WriteString(fmt.Sprintf("**Published:** %s  \n", article.CreatedAt.Format("2006-01-02 15:04:05")))
+	builder.WriteString(fmt.Sprintf("**Category:** %s  \n", article.Category.Name))
+	if article.ViewCount > 0 {
+		builder.WriteString(fmt.Sprintf("**Views:** %d  \n", article.ViewCount))
+	}
+
+	return builder.String()
+}
+
+// ArticleHTMLPage renders an article, written in language, as a standalone
+// HTML page with its code, math and diagrams already rendered, so it reads
+// the same offline
+func ArticleHTMLPage(ctx context.Context, article models.Article, language string) (string, error) {
+	content := article.Content
+	if article.ContentType == "html" {
+		content = security.GetGlobalHTMLPolicy().Sanitize(content)
+	} else {
+		rendered, err := GetGlobalMarkdownRenderer().Render(ctx, content)
+		if err != nil {
+			return "", err
+		}
+		content = rendered
+	}
+	var builder strings.Builder
+	builder.WriteString("<!DOCTYPE html>\n")
+	builder.WriteString(fmt.Sprintf("<html lang=\"%s\" dir=\"%s\">\n<head>\n<meta charset=\"utf-8\">\n", html.EscapeString(language), models.LanguageDirection(language)))
+	builder.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(article.Title)))
+	builder.WriteString("<style>body{max-width:48rem;margin:2rem auto;padding:0 1rem;font-family:sans-serif;line-height:1.6}pre{overflow-x:auto;padding:1rem}img{max-width:100%}</style>\n")
+	builder.WriteString("</head>\n<body>\n")
+	builder.WriteString(fmt.Sprintf("<h1>%s</h1>\n", html.EscapeString(article.Title)))
+	builder.WriteString(fmt.Sprintf("<p><time datetime=\"%s\">%s</time></p>\n", article.CreatedAt.Format(time.RFC3339), article.CreatedAt.Format("2006-01-02")))
+	builder.WriteString(content)
+	builder.WriteString("</body>\n</html>\n")
+	return builder.String(), nil
+}
+
+// SafeFilename removes or replaces invalid characters for filenames
+func SafeFilename(filename string) string {
+	// Replace invalid characters with underscores
+	invalidChars := []string{"/", "\\", ":", "*", "?", "\"", "<", ">", "|"}
+	result := filename
+
+	for _, char := range invalidChars {
+		result = strings.ReplaceAll(result, char, "_")
+	}
+
+	// Trim spaces and dots from the end
+	result = strings.TrimRight(result, " .")
+
+	// Limit filename length to 100 characters
+	if len(result) > 100 {
+		result = result[:100]
+	}
+
+	// Ensure filename is not empty
+	if result == "" {
+		result = "untitled"
+	}
+
+	return result
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Experimental features that can be switched on per deployment
+const (
+	FeatureRAGChat     = "rag_chat"
+	FeatureComments    = "comments"
+	FeatureActivityPub = "activitypub"
+	FeatureWebmention  = "webmention"
+)
+
+// Where a feature's state comes from
+const (
+	FeatureSourceDefault = "default"
+	FeatureSourceConfig  = "config"
+	FeatureSourceAdmin   = "admin"
+)
+
+// knownFeatures describes every feature flag. Features are off unless the
+// configuration or an admin turns them on.
+var knownFeatures = []struct {
+	name        string
+	description string
+}{
+	{FeatureRAGChat, "Chat with readers using answers grounded in the blog's articles"},
+	{FeatureComments, "Reader comments on articles"},
+	{FeatureActivityPub, "Publish articles to the fediverse through ActivityPub"},
+	{FeatureWebmention, "Send Webmentions to linked pages and accept them for articles"},
+}
+
+const (
+	// featuresSettingKey is the system setting holding admin overrides
+	featuresSettingKey = "features"
+	// featuresRefresh is how often overrides are reread from the database,
+	// so a change from the CLI or another instance takes effect without a
+	// restart
+	featuresRefresh = 5 * time.Second
+)
+
+// ErrUnknownFeature is returned for names that are not feature flags
+var ErrUnknownFeature = errors.New("unknown feature")
+
+// Feature is the current state of a feature flag
+type Feature struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Source      string     `json:"source"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// featureOverride is an admin's choice for one feature
+type featureOverride struct {
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FeatureService decides which experimental features are enabled. The
+// configuration gives the deployment's defaults and admin overrides are
+// stored in the database, so every instance and the admin CLI share them.
+type FeatureService struct {
+	db       func() *gorm.DB
+	defaults map[string]bool
+
+	mu        sync.RWMutex
+	overrides map[string]featureOverride
+	loadedAt  time.Time
+}
+
+// NewFeatureService creates a feature service with the features enabled in
+// the configuration as defaults
+func NewFeatureService() *FeatureService {
+	defaults := make(map[string]bool)
+	for _, name := range config.Get().Features.Enabled {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isKnownFeature(name) {
+			slog.Warn("Ignoring unknown feature in configuration", "feature", name)
+			continue
+		}
+		defaults[name] = true
+	}
+	s := &FeatureService{db: func() *gorm.DB { return database.DB }, defaults: defaults}
+	cache.Subscribe(cache.TopicFeatures, s.reset)
+	return s
+}
+
+// Enabled reports whether a feature is switched on. Unknown features are
+// always off.
+func (s *FeatureService) Enabled(name string) bool {
+	if override, ok := s.current()[name]; ok {
+		return override.Enabled
+	}
+	return s.defaults[name]
+}
+
+// EnabledNames returns the enabled features in name order
+func (s *FeatureService) EnabledNames() []string {
+	names := []string{}
+	for _, feature := range knownFeatures {
+		if s.Enabled(feature.name) {
+			names = append(names, feature.name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List returns every feature flag with its state and where it comes from
+func (s *FeatureService) List() []Feature {
+	overrides := s.current()
+	features := make([]Feature, 0, len(knownFeatures))
+	for _, known := range knownFeatures {
+		feature := Feature{
+			Name:        known.name,
+			Description: known.description,
+			Enabled:     s.defaults[known.name],
+			Source:      FeatureSourceDefault,
+		}
+		if feature.Enabled {
+			feature.Source = FeatureSourceConfig
+		}
+		if override, ok := overrides[known.name]; ok {
+			updatedAt := override.UpdatedAt
+			feature.Enabled, feature.Source, feature.UpdatedAt = override.Enabled, FeatureSourceAdmin, &updatedAt
+		}
+		features = append(features, feature)
+	}
+	return features
+}
+
+// Set overrides a feature's state for every instance
+func (s *FeatureService) Set(name string, enabled bool) (Feature, error) {
+	if !isKnownFeature(name) {
+		return Feature{}, ErrUnknownFeature
+	}
+	overrides, err := s.load()
+	if err != nil {
+		return Feature{}, err
+	}
+	overrides[name] = featureOverride{Enabled: enabled, UpdatedAt: time.Now()}
+	if err := s.save(overrides); err != nil {
+		return Feature{}, err
+	}
+	slog.Info("Feature flag changed", "feature", name, "enabled", enabled)
+	return s.feature(name), nil
+}
+
+// Reset drops the admin override so the configured default applies again
+func (s *FeatureService) Reset(name string) (Feature, error) {
+	if !isKnownFeature(name) {
+		return Feature{}, ErrUnknownFeature
+	}
+	overrides, err := s.load()
+	if err != nil {
+		return Feature{}, err
+	}
+	delete(overrides, name)
+	if err := s.save(overrides); err != nil {
+		return Feature{}, err
+	}
+	slog.Info("Feature flag reset to default", "feature", name, "enabled", s.defaults[name])
+	return s.feature(name), nil
+}
+
+func (s *FeatureService) feature(name string) Feature {
+	for _, feature := range s.List() {
+		if feature.Name == name {
+			return feature
+		}
+	}
+	return Feature{Name: name}
+}
+
+// current returns the cached overrides, rereading them when stale. If the
+// database cannot be read the last known overrides are kept.
+func (s *FeatureService) current() map[string]featureOverride {
+	s.mu.RLock()
+	overrides, fresh := s.overrides, time.Since(s.loadedAt) < featuresRefresh
+	s.mu.RUnlock()
+	if fresh {
+		return overrides
+	}
+
+	loaded, err := s.load()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Now()
+	if err != nil {
+		slog.Warn("Failed to read feature flags, keeping last known state", "error", err)
+		return s.overrides
+	}
+	s.overrides = loaded
+	return loaded
+}
+
+func (s *FeatureService) load() (map[string]featureOverride, error) {
+	overrides := make(map[string]featureOverride)
+	var setting models.SystemSetting
+	err := s.db().Limit(1).Find(&setting, models.SystemSetting{Key: featuresSettingKey}).Error
+	if err != nil || setting.Value == "" {
+		return overrides, err
+	}
+	err = json.Unmarshal([]byte(setting.Value), &overrides)
+	return overrides, err
+}
+
+func (s *FeatureService) save(overrides map[string]featureOverride) error {
+	value, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+	setting := models.SystemSetting{Key: featuresSettingKey, Value: string(value)}
+	err = s.db().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&setting).Error
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.overrides, s.loadedAt = overrides, time.Now()
+	s.mu.Unlock()
+	cache.Publish(cache.TopicFeatures)
+	return nil
+}
+
+func (s *FeatureService) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Time{}
+}
+
+func isKnownFeature(name string) bool {
+	for _, feature := range knownFeatures {
+		if feature.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	globalFeatureService *FeatureService
+	featureServiceOnce   sync.Once
+)
+
+// GetGlobalFeatureService returns the shared feature service
+func GetGlobalFeatureService() *FeatureService {
+	featureServiceOnce.Do(func() {
+		globalFeatureService = NewFeatureService()
+	})
+	return globalFeatureService
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	newTestDB(t)
+	s := &FeatureService{
+		db:       func() *gorm.DB { return database.DB },
+		defaults: map[string]bool{FeatureComments: true},
+	}
+
+	if !s.Enabled(FeatureComments) || s.Enabled(FeatureRAGChat) || s.Enabled("unknown") {
+		t.Fatal("expected only the configured feature to be enabled")
+	}
+
+	feature, err := s.Set(FeatureRAGChat, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !feature.Enabled || feature.Source != FeatureSourceAdmin || feature.UpdatedAt == nil {
+		t.Errorf("unexpected feature after enabling: %+v", feature)
+	}
+	if _, err := s.Set(FeatureComments, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second instance sees the overrides stored in the database
+	other := &FeatureService{db: s.db, defaults: s.defaults}
+	if names := other.EnabledNames(); len(names) != 1 || names[0] != FeatureRAGChat {
+		t.Errorf("expected only rag_chat enabled, got %v", names)
+	}
+
+	feature, err = s.Reset(FeatureComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !feature.Enabled || feature.Source != FeatureSourceConfig {
+		t.Errorf("expected the configured default after reset, got %+v", feature)
+	}
+
+	if _, err := s.Set("unknown", true); !errors.Is(err, ErrUnknownFeature) {
+		t.Errorf("expected ErrUnknownFeature, got %v", err)
+	}
+}
@@ -0,0 +1,813 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+	"gorm.io/gorm"
+)
+
+// FeedImportScheduleName is the scheduler entry for FEED_IMPORT_SCHEDULE
+const FeedImportScheduleName = "feed-import"
+
+var (
+	ErrInvalidFeed        = errors.New("invalid feed")
+	ErrFeedSourceNotFound = errors.New("feed not found")
+	ErrFeedItemNotFound   = errors.New("feed post not found")
+)
+
+const (
+	// feedImportMaxPages is how many pages of a paged feed the first import
+	// follows through rel="next" links
+	feedImportMaxPages = 20
+	feedItemsPage      = 100
+	feedMaxDecide      = 500
+	feedSummaryLength  = 200
+	feedAccept         = "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.8"
+)
+
+// feedDateLayouts are the date formats seen in RSS and Atom feeds
+var feedDateLayouts = []string{
+	time.RFC1123Z, time.RFC1123, time.RFC3339, time.RFC3339Nano, time.RFC822Z, time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST", "2 Jan 2006 15:04:05 -0700",
+	"Mon, 02 Jan 2006 15:04 -0700", "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02",
+}
+
+// FeedSourceInput holds the editable fields of a feed; nil fields are left
+// unchanged
+type FeedSourceInput struct {
+	URL        *string `json:"url"`
+	CategoryID *uint   `json:"category_id"`
+	Language   *string `json:"language"`
+	Follow     *bool   `json:"follow"`
+}
+
+// FeedSourceSummary is a feed with the number of its posts waiting for
+// review
+type FeedSourceSummary struct {
+	models.FeedSource
+	Drafts int64 `json:"drafts"`
+}
+
+// FeedImportResult describes one fetch of feeds
+type FeedImportResult struct {
+	Feeds  int `json:"feeds"`
+	Posts  int `json:"posts"`
+	Staged int `json:"staged"`
+	Failed int `json:"failed"`
+}
+
+// feedImportJob is the payload of JobFeedImport; without a SourceID every
+// followed feed is fetched. Pages is how many pages of a paged feed to read.
+type feedImportJob struct {
+	SourceID uint `json:"source_id,omitempty"`
+	Pages    int  `json:"pages,omitempty"`
+}
+
+// feedEntry is a post as read from an RSS item or Atom entry, its content
+// still HTML
+type feedEntry struct {
+	GUID      string
+	Link      string
+	Title     string
+	Author    string
+	Summary   string
+	Content   string
+	Published time.Time
+}
+
+// parsedFeed is a feed page as read from RSS or Atom
+type parsedFeed struct {
+	Title    string
+	Link     string
+	Language string
+	Next     string
+	Entries  []feedEntry
+}
+
+// FeedImportService imports posts from RSS and Atom feeds, such as those
+// of older blogs being moved onto the site. Posts are staged as drafts;
+// publishing one creates an article dated when the post first appeared,
+// crediting the feed and pointing its canonical URL at the original post.
+// Feeds are fetched like link previews, so a feed address cannot reach
+// the server's own network.
+type FeedImportService struct {
+	db  func() *gorm.DB
+	now func() time.Time
+	// fetch is replaced in tests
+	fetch func(ctx context.Context, address, accept string) ([]byte, *url.URL, error)
+}
+
+// NewFeedImportService creates a feed import service
+func NewFeedImportService() *FeedImportService {
+	return &FeedImportService{
+		db:    func() *gorm.DB { return database.DB },
+		now:   time.Now,
+		fetch: GetGlobalLinkPreviewService().get,
+	}
+}
+
+// Sources returns the site's feeds with the number of their draft posts
+func (s *FeedImportService) Sources(ctx context.Context) ([]FeedSourceSummary, error) {
+	db := s.db().WithContext(ctx)
+	var sources []models.FeedSource
+	if err := db.Order("id").Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		SourceID uint
+		Count    int64
+	}
+	if err := db.Model(&models.FeedItem{}).Select("source_id, COUNT(*) AS count").
+		Where("status = ?", models.FeedItemDraft).Group("source_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	drafts := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		drafts[count.SourceID] = count.Count
+	}
+	summaries := make([]FeedSourceSummary, len(sources))
+	for i, source := range sources {
+		summaries[i] = FeedSourceSummary{FeedSource: source, Drafts: drafts[source.ID]}
+	}
+	return summaries, nil
+}
+
+// Add adds a feed and queues its first import, which reads every page of
+// a paged feed
+func (s *FeedImportService) Add(ctx context.Context, input FeedSourceInput) (*models.FeedSource, *models.Job, error) {
+	source := &models.FeedSource{}
+	if input.URL == nil {
+		return nil, nil, fmt.Errorf("%w: url is required", ErrInvalidFeed)
+	}
+	if err := applyFeedSourceInput(source, input); err != nil {
+		return nil, nil, err
+	}
+	db := s.db().WithContext(ctx)
+	var count int64
+	if err := db.Model(&models.FeedSource{}).Where("url = ?", source.URL).Count(&count).Error; err != nil {
+		return nil, nil, err
+	}
+	if count > 0 {
+		return nil, nil, fmt.Errorf("%w: the feed has already been added", ErrInvalidFeed)
+	}
+	if err := db.Create(source).Error; err != nil {
+		return nil, nil, err
+	}
+	job, err := GetGlobalJobQueue().Enqueue(JobFeedImport, feedImportJob{SourceID: source.ID, Pages: feedImportMaxPages})
+	return source, job, err
+}
+
+// Update edits a feed
+func (s *FeedImportService) Update(ctx context.Context, id uint, input FeedSourceInput) (*models.FeedSource, error) {
+	source, err := s.source(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyFeedSourceInput(source, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Save(source).Error; err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// Delete removes a feed and its posts. Articles published from it stay.
+func (s *FeedImportService) Delete(ctx context.Context, id uint) error {
+	source, err := s.source(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_id = ?", source.ID).Delete(&models.FeedItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(source).Error
+	})
+}
+
+// Queue queues a fetch of one of the site's feeds for new posts
+func (s *FeedImportService) Queue(ctx context.Context, id uint) (*models.Job, error) {
+	source, err := s.source(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return GetGlobalJobQueue().Enqueue(JobFeedImport, feedImportJob{SourceID: source.ID})
+}
+
+// Items lists the site's posts with a status, newest first, optionally
+// only those of one feed
+func (s *FeedImportService) Items(ctx context.Context, sourceID uint, status string, limit int) ([]models.FeedItem, error) {
+	if status != models.FeedItemDraft && status != models.FeedItemPublished && status != models.FeedItemDismissed {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidFeed, status)
+	}
+	if limit <= 0 || limit > feedItemsPage*5 {
+		limit = feedItemsPage
+	}
+	query := s.db().WithContext(ctx).Preload("Source").Where("status = ?", status)
+	if sourceID != 0 {
+		query = query.Where("source_id = ?", sourceID)
+	}
+	items := []models.FeedItem{}
+	err := query.Order("published_at DESC, id DESC").Limit(limit).Find(&items).Error
+	return items, err
+}
+
+// Fetch is the JobFeedImport handler. It reads a feed, or every followed
+// feed when the payload names none, and stages the posts not seen before
+// as drafts. The admin is told about new drafts from followed feeds and
+// about feeds that start failing.
+func (s *FeedImportService) Fetch(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var job feedImportJob
+	if len(payload) > 0 && string(payload) != "null" {
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return nil, err
+		}
+	}
+	query := s.db().WithContext(ctx)
+	if job.SourceID != 0 {
+		query = query.Where("id = ?", job.SourceID)
+	} else {
+		query = query.Where("follow = ?", true)
+	}
+	var sources []models.FeedSource
+	if err := query.Order("id").Find(&sources).Error; err != nil {
+		return nil, err
+	}
+	if job.SourceID != 0 && len(sources) == 0 {
+		return nil, ErrFeedSourceNotFound
+	}
+
+	result := &FeedImportResult{Feeds: len(sources)}
+	var staged, broken []string
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(sources))
+	for i := range sources {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		source := &sources[i]
+		progress.Step("Reading %s", source.URL)
+		failing := source.LastError != ""
+		posts, added, err := s.fetchSource(ctx, source, min(max(job.Pages, 1), feedImportMaxPages))
+		result.Posts += posts
+		result.Staged += added
+		if err != nil {
+			progress.ItemFailed(source.URL, err)
+			result.Failed++
+			if !failing {
+				broken = append(broken, fmt.Sprintf("%s: %v", source.URL, err))
+			}
+			continue
+		}
+		if added > 0 && job.SourceID == 0 {
+			staged = append(staged, fmt.Sprintf("%s: %d", feedSourceName(source), added))
+		}
+		progress.Advance(1)
+	}
+	notifyFeedImport(staged, broken)
+	if job.SourceID != 0 && result.Failed > 0 {
+		return result, errors.New(sources[0].LastError)
+	}
+	return result, nil
+}
+
+// Decide publishes or dismisses draft posts and returns those decided.
+// Publishing creates an article in the feed's category and language,
+// dated when the post appeared. Imported posts are not announced to
+// subscribers or followers: they are old news moved to a new home.
+func (s *FeedImportService) Decide(ctx context.Context, ids []uint, status string) ([]models.FeedItem, error) {
+	if status != models.FeedItemPublished && status != models.FeedItemDismissed {
+		return nil, fmt.Errorf("%w: a post can only be published or dismissed", ErrInvalidFeed)
+	}
+	if len(ids) == 0 || len(ids) > feedMaxDecide {
+		return nil, fmt.Errorf("%w: give 1 to %d post ids", ErrInvalidFeed, feedMaxDecide)
+	}
+	db := s.db().WithContext(ctx)
+	var items []models.FeedItem
+	if err := db.Preload("Source").Where("id IN ? AND status = ?", ids, models.FeedItemDraft).
+		Order("published_at, id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, ErrFeedItemNotFound
+	}
+	published := false
+	for i := range items {
+		item := &items[i]
+		if status == models.FeedItemDismissed {
+			item.Status = models.FeedItemDismissed
+			if err := db.Model(item).Update("status", item.Status).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := s.publish(ctx, item); err != nil {
+			if published {
+				cache.Publish(cache.TopicArticles)
+			}
+			return nil, err
+		}
+		published = true
+	}
+	if published {
+		cache.Publish(cache.TopicArticles)
+	}
+	return items, nil
+}
+
+// publish creates the article of a draft post. The post's slug on the old
+// blog is kept when no article has it yet, which eases setting up
+// redirects.
+func (s *FeedImportService) publish(ctx context.Context, item *models.FeedItem) error {
+	source := item.Source
+	if source == nil {
+		source = &models.FeedSource{}
+	}
+	db := s.db().WithContext(ctx)
+	article := &models.Article{
+		SiteID:       item.SiteID,
+		Title:        item.Title,
+		Content:      item.Content,
+		ContentType:  "markdown",
+		Summary:      item.Summary,
+		CategoryID:   source.CategoryID,
+		DefaultLang:  source.Language,
+		SourceName:   truncateRunes(feedSourceName(source), 255),
+		SourceURL:    item.Link,
+		CanonicalURL: item.Link,
+	}
+	if article.DefaultLang == "" {
+		var settings models.SiteSettings
+		if err := db.Select("default_language").Where("site_id = ?", item.SiteID).Limit(1).Find(&settings).Error; err != nil {
+			return err
+		}
+		article.DefaultLang = firstNonEmpty(settings.DefaultLanguage, "zh")
+	}
+	if item.PublishedAt != nil {
+		article.CreatedAt, article.UpdatedAt = *item.PublishedAt, *item.PublishedAt
+	}
+	if link, err := url.Parse(item.Link); err == nil && link.Path != "" {
+		name := path.Base(strings.TrimSuffix(link.Path, "/"))
+		slug := normalizeSEOSlug(strings.TrimSuffix(name, path.Ext(name)))
+		if CheckArticleSlug(db, 0, "", slug) == nil {
+			article.SEOSlug = slug
+		}
+	}
+	if err := hooks.Filter(ctx, hooks.BeforeArticleSave, article); err != nil {
+		return err
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(article).Error; err != nil {
+			return err
+		}
+		item.Status, item.ArticleID = models.FeedItemPublished, &article.ID
+		return tx.Model(item).Select("status", "article_id").Updates(item).Error
+	})
+	if err != nil {
+		return err
+	}
+	hooks.Notify(ctx, hooks.AfterArticleSave, article)
+	return nil
+}
+
+// fetchSource reads up to pages pages of a feed, stages the posts not seen
+// before and records the outcome on the feed. It returns the number of
+// posts read and staged.
+func (s *FeedImportService) fetchSource(ctx context.Context, source *models.FeedSource, pages int) (int, int, error) {
+	db := s.db().WithContext(ctx)
+	posts, staged := 0, 0
+	address := source.URL
+	var fetchErr error
+	seen := make(map[string]bool)
+	for page := 0; page < pages && address != "" && !seen[address]; page++ {
+		seen[address] = true
+		data, final, err := s.fetch(ctx, address, feedAccept)
+		if err == nil {
+			var feed *parsedFeed
+			if feed, err = parseFeed(data, final); err == nil {
+				if page == 0 {
+					updateFeedSource(source, feed)
+				}
+				posts += len(feed.Entries)
+				var added int
+				added, err = s.stage(db, source, feed.Entries)
+				staged += added
+				address = feed.Next
+			}
+		}
+		if err != nil {
+			fetchErr = err
+			break
+		}
+	}
+
+	now := s.now()
+	source.LastFetchedAt = &now
+	source.LastError = ""
+	if fetchErr != nil {
+		source.LastError = truncateRunes(fetchErr.Error(), 500)
+	}
+	if err := db.Model(source).Select("title", "site_url", "language", "last_fetched_at", "last_error").
+		Updates(source).Error; err != nil {
+		return posts, staged, err
+	}
+	if fetchErr != nil {
+		slog.Warn("Feed import failed", "feed_id", source.ID, "url", source.URL, "error", fetchErr)
+	}
+	return posts, staged, fetchErr
+}
+
+// stage saves the entries of a feed not staged before as drafts
+func (s *FeedImportService) stage(db *gorm.DB, source *models.FeedSource, entries []feedEntry) (int, error) {
+	staged := 0
+	for _, entry := range entries {
+		guid := truncateRunes(firstNonEmpty(entry.GUID, entry.Link), 500)
+		if guid == "" || strings.TrimSpace(entry.Title) == "" && entry.Content == "" {
+			continue
+		}
+		var count int64
+		if err := db.Model(&models.FeedItem{}).Where("source_id = ? AND guid = ?", source.ID, guid).
+			Count(&count).Error; err != nil {
+			return staged, err
+		}
+		if count == 0 && entry.Link != "" {
+			// Published before, from a feed since removed and added again
+			if err := db.Model(&models.Article{}).Where("source_url = ?", entry.Link).Count(&count).Error; err != nil {
+				return staged, err
+			}
+		}
+		if count > 0 {
+			continue
+		}
+		var base *url.URL
+		if link, err := url.Parse(entry.Link); err == nil && link.IsAbs() {
+			base = link
+		}
+		content := htmlToMarkdown(firstNonEmpty(entry.Content, entry.Summary), base)
+		summary := feedPlainText(firstNonEmpty(entry.Summary, entry.Content))
+		title := strings.Join(strings.Fields(feedPlainText(entry.Title)), " ")
+		if title == "" {
+			title = truncateRunes(summary, 80)
+		}
+		item := &models.FeedItem{
+			SiteID:   source.SiteID,
+			SourceID: source.ID,
+			GUID:     guid,
+			Link:     truncateRunes(entry.Link, 500),
+			Title:    truncateRunes(title, 500),
+			Summary:  truncateRunes(summary, feedSummaryLength),
+			Content:  content,
+			Author:   truncateRunes(entry.Author, 255),
+			Status:   models.FeedItemDraft,
+		}
+		if !entry.Published.IsZero() {
+			published := entry.Published
+			item.PublishedAt = &published
+		}
+		if err := db.Create(item).Error; err != nil {
+			return staged, err
+		}
+		staged++
+	}
+	return staged, nil
+}
+
+func (s *FeedImportService) source(ctx context.Context, id uint) (*models.FeedSource, error) {
+	var source models.FeedSource
+	if err := s.db().WithContext(ctx).First(&source, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFeedSourceNotFound
+		}
+		return nil, err
+	}
+	return &source, nil
+}
+
+// updateFeedSource fills in what the feed says about itself where the
+// admin left it blank
+func updateFeedSource(source *models.FeedSource, feed *parsedFeed) {
+	if source.Title == "" {
+		source.Title = truncateRunes(strings.Join(strings.Fields(feed.Title), " "), 255)
+	}
+	if feed.Link != "" {
+		source.SiteURL = truncateRunes(feed.Link, 500)
+	}
+	if source.Language == "" && feed.Language != "" {
+		language, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(feed.Language)), "-")
+		source.Language = truncateRunes(language, 10)
+	}
+}
+
+// notifyFeedImport tells the admin about posts staged from followed feeds
+// and about feeds that started failing in this run
+func notifyFeedImport(staged, broken []string) {
+	if len(staged) > 0 {
+		notifyAdmin(NotificationInput{
+			Source:   models.NotificationSourceSystem,
+			Type:     "feed_posts_staged",
+			Severity: models.SeverityInfo,
+			Title:    "New posts from followed feeds are waiting for review",
+			Message:  strings.Join(staged, "\n"),
+		})
+	}
+	if len(broken) > 0 {
+		title := "A feed could not be imported"
+		if len(broken) > 1 {
+			title = fmt.Sprintf("%d feeds could not be imported", len(broken))
+		}
+		notifyAdmin(NotificationInput{
+			Source:   models.NotificationSourceSystem,
+			Type:     "feed_import_failed",
+			Severity: models.SeverityWarning,
+			Title:    title,
+			Message:  strings.Join(broken, "\n"),
+		})
+	}
+}
+
+// feedSourceName is how a feed is credited on its articles
+func feedSourceName(source *models.FeedSource) string {
+	if source.Title != "" {
+		return source.Title
+	}
+	if link, err := url.Parse(firstNonEmpty(source.SiteURL, source.URL)); err == nil && link.Host != "" {
+		return link.Host
+	}
+	return source.URL
+}
+
+func applyFeedSourceInput(source *models.FeedSource, input FeedSourceInput) error {
+	if input.URL != nil {
+		target, err := ParsePreviewURL(*input.URL)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+		}
+		if err := checkPreviewURL(target); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+		}
+		if len(target.String()) > 500 {
+			return fmt.Errorf("%w: url is too long", ErrInvalidFeed)
+		}
+		source.URL = target.String()
+	}
+	if input.CategoryID != nil {
+		source.CategoryID = *input.CategoryID
+	}
+	if input.Language != nil {
+		language := strings.TrimSpace(*input.Language)
+		if len(language) > 10 {
+			return fmt.Errorf("%w: %q is not a language code", ErrInvalidFeed, language)
+		}
+		source.Language = language
+	}
+	if input.Follow != nil {
+		source.Follow = *input.Follow
+	}
+	return nil
+}
+
+// rssFeed is an RSS 2.0 document. Channel links include atom:link
+// elements, which carry their address in href.
+type rssFeed struct {
+	Channel struct {
+		Title    string     `xml:"title"`
+		Links    []feedLink `xml:"link"`
+		Language string     `xml:"language"`
+		Items    []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			GUID        string `xml:"guid"`
+			PubDate     string `xml:"pubDate"`
+			Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+			Author      string `xml:"author"`
+			Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			Description string `xml:"description"`
+			Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// atomFeed is an Atom document
+type atomFeed struct {
+	Title    atomText   `xml:"title"`
+	Links    []feedLink `xml:"link"`
+	Language string     `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Entries  []struct {
+		ID        string     `xml:"id"`
+		Title     atomText   `xml:"title"`
+		Links     []feedLink `xml:"link"`
+		Published string     `xml:"published"`
+		Updated   string     `xml:"updated"`
+		Author    struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Summary atomText `xml:"summary"`
+		Content atomText `xml:"content"`
+	} `xml:"entry"`
+}
+
+// feedLink is a link of an RSS channel, in its text, or an Atom link
+type feedLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// atomText is an Atom text construct: plain text, escaped HTML or inline
+// XHTML
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// HTML returns the text as HTML
+func (t atomText) HTML() string {
+	switch t.Type {
+	case "html":
+		return t.Text
+	case "xhtml":
+		return t.Inner
+	default:
+		return html.EscapeString(t.Text)
+	}
+}
+
+// parseFeed reads an RSS 2.0 or Atom document, fetched from base.
+// Relative links are resolved against base.
+func parseFeed(data []byte, base *url.URL) (*parsedFeed, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.CharsetReader = charset.NewReaderLabel
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: the document is not an RSS or Atom feed", ErrInvalidFeed)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "rss":
+			var doc rssFeed
+			if err := decoder.DecodeElement(&doc, &start); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+			}
+			return doc.parsed(base), nil
+		case "feed":
+			var doc atomFeed
+			if err := decoder.DecodeElement(&doc, &start); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidFeed, err)
+			}
+			return doc.parsed(base), nil
+		default:
+			return nil, fmt.Errorf("%w: <%s> is not an RSS or Atom feed", ErrInvalidFeed, start.Name.Local)
+		}
+	}
+}
+
+func (doc *rssFeed) parsed(base *url.URL) *parsedFeed {
+	feed := &parsedFeed{Title: doc.Channel.Title, Language: doc.Channel.Language}
+	for _, link := range doc.Channel.Links {
+		switch {
+		case link.Href == "" && feed.Link == "":
+			feed.Link = resolveFeedURL(base, link.Text)
+		case link.Rel == "next":
+			feed.Next = resolveFeedURL(base, link.Href)
+		}
+	}
+	for _, item := range doc.Channel.Items {
+		link := resolveFeedURL(base, item.Link)
+		feed.Entries = append(feed.Entries, feedEntry{
+			GUID:      strings.TrimSpace(item.GUID),
+			Link:      link,
+			Title:     item.Title,
+			Author:    strings.TrimSpace(firstNonEmpty(item.Creator, item.Author)),
+			Summary:   item.Description,
+			Content:   item.Content,
+			Published: parseFeedDate(firstNonEmpty(item.PubDate, item.Date)),
+		})
+	}
+	return feed
+}
+
+func (doc *atomFeed) parsed(base *url.URL) *parsedFeed {
+	feed := &parsedFeed{Title: doc.Title.Text, Language: doc.Language}
+	for _, link := range doc.Links {
+		switch link.Rel {
+		case "", "alternate":
+			if feed.Link == "" && (link.Type == "" || strings.Contains(link.Type, "html")) {
+				feed.Link = resolveFeedURL(base, link.Href)
+			}
+		case "next":
+			feed.Next = resolveFeedURL(base, link.Href)
+		}
+	}
+	for _, entry := range doc.Entries {
+		link := ""
+		for _, candidate := range entry.Links {
+			if candidate.Rel == "" || candidate.Rel == "alternate" {
+				link = resolveFeedURL(base, candidate.Href)
+				break
+			}
+		}
+		content := entry.Content.HTML()
+		if strings.TrimSpace(entry.Content.Inner) == "" {
+			content = ""
+		}
+		feed.Entries = append(feed.Entries, feedEntry{
+			GUID:      strings.TrimSpace(entry.ID),
+			Link:      link,
+			Title:     entry.Title.HTML(),
+			Author:    strings.TrimSpace(entry.Author.Name),
+			Summary:   entry.Summary.HTML(),
+			Content:   content,
+			Published: parseFeedDate(firstNonEmpty(entry.Published, entry.Updated)),
+		})
+	}
+	return feed
+}
+
+// resolveFeedURL resolves a link of a feed against the feed's address and
+// drops anything but http(s) links
+func resolveFeedURL(base *url.URL, link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return ""
+	}
+	target, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		target = base.ResolveReference(target)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return ""
+	}
+	return target.String()
+}
+
+func parseFeedDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range feedDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+// feedPlainText returns the text of an HTML fragment on one line
+func feedPlainText(fragment string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+	if err != nil {
+		return strings.Join(strings.Fields(htmlTag.ReplaceAllString(fragment, " ")), " ")
+	}
+	var parts []string
+	for _, node := range nodes {
+		if node.DataAtom == atom.Script || node.DataAtom == atom.Style {
+			continue
+		}
+		parts = append(parts, htmlText(node))
+	}
+	return strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+}
+
+var (
+	globalFeedImportService     *FeedImportService
+	globalFeedImportServiceOnce sync.Once
+)
+
+// GetGlobalFeedImportService returns the global feed import service
+func GetGlobalFeedImportService() *FeedImportService {
+	globalFeedImportServiceOnce.Do(func() {
+		globalFeedImportService = NewFeedImportService()
+	})
+	return globalFeedImportService
+}
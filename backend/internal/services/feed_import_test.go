@@ -0,0 +1,261 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const testRSSFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+  <title>Old Blog</title>
+  <link>https://old.example.com/</link>
+  <atom:link href="https://old.example.com/feed" rel="self" type="application/rss+xml"/>
+  <atom:link href="/feed?paged=2" rel="next"/>
+  <language>en-US</language>
+  <item>
+    <title>Hello &amp; welcome</title>
+    <link>https://old.example.com/2015/03/hello-world/</link>
+    <guid isPermaLink="false">https://old.example.com/?p=1</guid>
+    <pubDate>Tue, 10 Mar 2015 08:30:00 +0000</pubDate>
+    <dc:creator>Alice</dc:creator>
+    <description>The first post.</description>
+    <content:encoded><![CDATA[<p>Read <a href="/about">about me</a>.</p><img src="/img/a.png" alt="A">]]></content:encoded>
+  </item>
+</channel>
+</rss>`
+
+const testRSSFeedPage2 = `<rss version="2.0"><channel><title>Old Blog</title>
+  <item>
+    <title>Older post</title>
+    <link>https://old.example.com/older.html</link>
+    <pubDate>Mon, 2 Feb 2015 10:00:00 GMT</pubDate>
+    <description>&lt;p&gt;Only a &lt;b&gt;description&lt;/b&gt;.&lt;/p&gt;</description>
+  </item>
+</channel></rss>`
+
+func newTestFeedImportService(t *testing.T, feeds map[string]string) *FeedImportService {
+	newTestDB(t)
+	return &FeedImportService{
+		db:  func() *gorm.DB { return database.DB },
+		now: func() time.Time { return time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC) },
+		fetch: func(ctx context.Context, address, accept string) ([]byte, *url.URL, error) {
+			feed, ok := feeds[address]
+			if !ok {
+				return nil, nil, errors.New("connection refused")
+			}
+			final, _ := url.Parse(address)
+			return []byte(feed), final, nil
+		},
+	}
+}
+
+func TestFeedImportFetch(t *testing.T) {
+	feeds := map[string]string{
+		"https://old.example.com/feed":         testRSSFeed,
+		"https://old.example.com/feed?paged=2": testRSSFeedPage2,
+	}
+	s := newTestFeedImportService(t, feeds)
+	ctx := context.Background()
+
+	source := &models.FeedSource{URL: "https://old.example.com/feed", Follow: true}
+	broken := &models.FeedSource{URL: "https://gone.example.com/feed"}
+	for _, feed := range []*models.FeedSource{source, broken} {
+		if err := database.DB.Create(feed).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	payload, _ := json.Marshal(feedImportJob{SourceID: source.ID, Pages: feedImportMaxPages})
+	out, err := s.Fetch(ctx, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := out.(*FeedImportResult); result.Posts != 2 || result.Staged != 2 || result.Failed != 0 {
+		t.Fatalf("result = %+v, want both pages staged", result)
+	}
+	database.DB.First(source, source.ID)
+	if source.Title != "Old Blog" || source.SiteURL != "https://old.example.com/" || source.Language != "en" ||
+		source.LastFetchedAt == nil || source.LastError != "" {
+		t.Errorf("source = %+v, want the feed's title, site and language filled in", source)
+	}
+
+	items, err := s.Items(ctx, source.ID, models.FeedItemDraft, 0)
+	if err != nil || len(items) != 2 {
+		t.Fatalf("Items = %d, %v, want 2 drafts", len(items), err)
+	}
+	hello, older := items[0], items[1]
+	if hello.Title != "Hello & welcome" || hello.GUID != "https://old.example.com/?p=1" || hello.Author != "Alice" ||
+		hello.Summary != "The first post." || hello.PublishedAt == nil || hello.PublishedAt.Day() != 10 {
+		t.Errorf("hello = %+v", hello)
+	}
+	if want := "Read [about me](https://old.example.com/about).\n\n![A](https://old.example.com/img/a.png)"; hello.Content != want {
+		t.Errorf("hello content = %q, want %q", hello.Content, want)
+	}
+	if older.GUID != older.Link || older.Content != "Only a **description**." || older.Summary != "Only a description." {
+		t.Errorf("older = %+v", older)
+	}
+
+	// Followed feeds are fetched again without staging a post twice, and
+	// only their first page is read
+	feeds["https://old.example.com/feed"] = strings.Replace(testRSSFeed, "<item>", `<item>
+    <title>New post</title><link>https://old.example.com/new/</link><description>New</description>
+  </item><item>`, 1)
+	if out, err = s.Fetch(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if result := out.(*FeedImportResult); result.Feeds != 1 || result.Posts != 2 || result.Staged != 1 {
+		t.Fatalf("result = %+v, want only the new post staged", result)
+	}
+	var notification models.Notification
+	if err := database.DB.Where("type = ?", "feed_posts_staged").First(&notification).Error; err != nil ||
+		notification.Message != "Old Blog: 1" {
+		t.Errorf("notification = %+v, %v", notification, err)
+	}
+
+	payload, _ = json.Marshal(feedImportJob{SourceID: broken.ID})
+	if _, err = s.Fetch(ctx, payload); err == nil {
+		t.Fatal("Fetch of an unreachable feed succeeded")
+	}
+	if database.DB.First(broken, broken.ID); broken.LastError != "connection refused" {
+		t.Errorf("broken = %+v, want the error recorded", broken)
+	}
+}
+
+func TestFeedImportDecide(t *testing.T) {
+	s := newTestFeedImportService(t, map[string]string{"https://old.example.com/feed": testRSSFeed})
+	ctx := context.Background()
+
+	category := models.Category{Name: "Archive"}
+	taken := models.Article{Title: "Taken", Content: "x", DefaultLang: "en", SEOSlug: "older"}
+	for _, row := range []interface{}{&category, &taken} {
+		if err := database.DB.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	source := &models.FeedSource{URL: "https://old.example.com/feed", CategoryID: category.ID}
+	if err := database.DB.Create(source).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.fetchSource(ctx, source, 1); err != nil {
+		t.Fatal(err)
+	}
+	published := time.Date(2015, 2, 2, 10, 0, 0, 0, time.UTC)
+	older := &models.FeedItem{SourceID: source.ID, GUID: "older", Link: "https://old.example.com/older.html",
+		Title: "Older", Content: "Old", Status: models.FeedItemDraft, PublishedAt: &published}
+	if err := database.DB.Create(older).Error; err != nil {
+		t.Fatal(err)
+	}
+	items, _ := s.Items(ctx, source.ID, models.FeedItemDraft, 0)
+	if len(items) != 2 {
+		t.Fatalf("items = %d, want 2", len(items))
+	}
+
+	if _, err := s.Decide(ctx, []uint{items[0].ID}, "applied"); !errors.Is(err, ErrInvalidFeed) {
+		t.Errorf("Decide with a bad status = %v, want ErrInvalidFeed", err)
+	}
+	decided, err := s.Decide(ctx, []uint{items[0].ID, items[1].ID}, models.FeedItemPublished)
+	if err != nil || len(decided) != 2 {
+		t.Fatalf("Decide = %d, %v", len(decided), err)
+	}
+	var articles []models.Article
+	database.DB.Where("source_url <> ''").Order("created_at").Find(&articles)
+	if len(articles) != 2 {
+		t.Fatalf("articles = %d, want 2", len(articles))
+	}
+	first, hello := articles[0], articles[1]
+	if first.SEOSlug != "" || !first.CreatedAt.Equal(published) {
+		t.Errorf("older article = %+v, want its date kept and no slug, as older is taken", first)
+	}
+	if hello.Title != "Hello & welcome" || hello.SEOSlug != "hello-world" || hello.DefaultLang != "en" ||
+		hello.CategoryID != category.ID || hello.SourceName != "Old Blog" ||
+		hello.SourceURL != "https://old.example.com/2015/03/hello-world/" || hello.CanonicalURL != hello.SourceURL {
+		t.Errorf("hello article = %+v", hello)
+	}
+	if decided[0].Status != models.FeedItemPublished || decided[0].ArticleID == nil {
+		t.Errorf("decided = %+v, want published with the article", decided[0])
+	}
+	if _, err := s.Decide(ctx, []uint{items[0].ID}, models.FeedItemDismissed); !errors.Is(err, ErrFeedItemNotFound) {
+		t.Errorf("Decide of a published post = %v, want ErrFeedItemNotFound", err)
+	}
+
+	// A feed added again does not stage posts already published
+	if err := s.Delete(ctx, source.ID); err != nil {
+		t.Fatal(err)
+	}
+	again := &models.FeedSource{URL: "https://old.example.com/feed"}
+	database.DB.Create(again)
+	if _, staged, err := s.fetchSource(ctx, again, 1); err != nil || staged != 0 {
+		t.Errorf("staged = %d, %v, want 0", staged, err)
+	}
+}
+
+func TestParseAtomFeed(t *testing.T) {
+	base, _ := url.Parse("https://atom.example.org/feed.xml")
+	feed, err := parseFeed([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="zh-CN">
+  <title type="text">Atom Blog</title>
+  <link href="https://atom.example.org/"/>
+  <link rel="self" href="https://atom.example.org/feed.xml"/>
+  <link rel="next" href="feed.xml?page=2"/>
+  <entry>
+    <id>tag:atom.example.org,2014:1</id>
+    <title type="html">Tips &amp;amp; &lt;em&gt;tricks&lt;/em&gt;</title>
+    <link rel="alternate" type="text/html" href="/posts/tips"/>
+    <updated>2014-05-01T09:00:00Z</updated>
+    <author><name>Bob</name></author>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><h2>Intro</h2><ul><li>One</li><li>Two<ol><li>Nested</li></ol></li></ul><pre><code class="language-go">x := 1</code></pre></div></content>
+  </entry>
+</feed>`), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title != "Atom Blog" || feed.Link != "https://atom.example.org/" || feed.Language != "zh-CN" ||
+		feed.Next != "https://atom.example.org/feed.xml?page=2" || len(feed.Entries) != 1 {
+		t.Fatalf("feed = %+v", feed)
+	}
+	entry := feed.Entries[0]
+	if entry.GUID != "tag:atom.example.org,2014:1" || entry.Link != "https://atom.example.org/posts/tips" ||
+		entry.Author != "Bob" || entry.Published.Year() != 2014 || feedPlainText(entry.Title) != "Tips & tricks" {
+		t.Errorf("entry = %+v", entry)
+	}
+	want := "## Intro\n\n- One\n- Two\n  1. Nested\n\n```go\nx := 1\n```"
+	if got := htmlToMarkdown(entry.Content, nil); got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{`<html><body>Not a feed</body></html>`, `<rss><channel>`, ``} {
+		if _, err := parseFeed([]byte(bad), base); !errors.Is(err, ErrInvalidFeed) {
+			t.Errorf("parseFeed(%q) = %v, want ErrInvalidFeed", bad, err)
+		}
+	}
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+	base, _ := url.Parse("https://old.example.com/2015/post/")
+	tests := []struct {
+		html, want string
+	}{
+		{"<p>Hello <strong>bold </strong>and <em>italic</em></p>", "Hello **bold** and *italic*"},
+		{"Line one<br>Line two", "Line one\\\nLine two"},
+		{`<blockquote><p>Quoted</p><p>Twice</p></blockquote>`, "> Quoted\n>\n> Twice"},
+		{`<a href="javascript:alert(1)">click</a> <a href="img.png">pic (1)</a>`, "click [pic (1)](https://old.example.com/2015/post/img.png)"},
+		{`<p>Use <code>a` + "`" + `b</code></p><script>alert(1)</script>`, "Use ``a`b``"},
+		{`<table><tr><th>A</th><th>B</th></tr><tr><td>1|2</td></tr></table>`, "| A | B |\n| --- | --- |\n| 1\\|2 |  |"},
+		{`<iframe src="https://www.youtube.com/embed/x"></iframe><hr>`, "<https://www.youtube.com/embed/x>\n\n---"},
+	}
+	for _, tt := range tests {
+		if got := htmlToMarkdown(tt.html, base); got != tt.want {
+			t.Errorf("htmlToMarkdown(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"gorm.io/gorm"
+)
+
+// FriendLinksScheduleName is the scheduler entry for FRIEND_LINKS_CHECK_SCHEDULE
+const FriendLinksScheduleName = "friend-links-check"
+
+var (
+	ErrInvalidFriendLink  = errors.New("invalid friend link")
+	ErrFriendLinkNotFound = errors.New("friend link not found")
+)
+
+// FriendLinkInput holds the editable fields of a friend link; nil fields
+// are left unchanged
+type FriendLinkInput struct {
+	Name            *string `json:"name"`
+	URL             *string `json:"url"`
+	Description     *string `json:"description"`
+	AvatarURL       *string `json:"avatar_url"`
+	Category        *string `json:"category"`
+	IsActive        *bool   `json:"is_active"`
+	CheckReciprocal *bool   `json:"check_reciprocal"`
+	ReciprocalURL   *string `json:"reciprocal_url"`
+}
+
+// FriendLinkOrder moves a link to a position in the blogroll
+type FriendLinkOrder struct {
+	ID    uint `json:"id"`
+	Order int  `json:"order"`
+}
+
+// BlogrollLink is a friend link as shown on the public blogroll
+type BlogrollLink struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+// BlogrollCategory is a group of the blogroll; links without a category are
+// in the group with an empty name
+type BlogrollCategory struct {
+	Name  string         `json:"name"`
+	Links []BlogrollLink `json:"links"`
+}
+
+// friendLinkCheckJob is the payload of JobFriendLinksCheck; without an ID
+// every link of every site that asks for checking is checked
+type friendLinkCheckJob struct {
+	ID uint `json:"id,omitempty"`
+}
+
+// FriendLinkService manages the blogroll of each site: the friend links,
+// their categories and order, and the optional check that a friend's site
+// links back. Friends' pages are fetched like link previews, so the check
+// cannot be pointed at the server's own network.
+type FriendLinkService struct {
+	db func() *gorm.DB
+	// fetch and baseURL are replaced in tests
+	fetch   func(ctx context.Context, address, accept string) ([]byte, *url.URL, error)
+	baseURL func(siteID uint) string
+}
+
+// NewFriendLinkService creates a friend link service
+func NewFriendLinkService() *FriendLinkService {
+	return &FriendLinkService{
+		db:      func() *gorm.DB { return database.DB },
+		fetch:   GetGlobalLinkPreviewService().get,
+		baseURL: siteBaseURL,
+	}
+}
+
+// Blogroll returns the site's active links grouped by category, in display
+// order. Categories come in the order of their first link.
+func (s *FriendLinkService) Blogroll(ctx context.Context) ([]BlogrollCategory, error) {
+	var links []models.FriendLink
+	if err := s.db().WithContext(ctx).Where("is_active = ?", true).
+		Order("display_order ASC, id ASC").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	categories := []BlogrollCategory{}
+	index := make(map[string]int)
+	for _, link := range links {
+		i, ok := index[link.Category]
+		if !ok {
+			i = len(categories)
+			index[link.Category] = i
+			categories = append(categories, BlogrollCategory{Name: link.Category})
+		}
+		categories[i].Links = append(categories[i].Links, BlogrollLink{
+			Name: link.Name, URL: link.URL, Description: link.Description, AvatarURL: link.AvatarURL,
+		})
+	}
+	return categories, nil
+}
+
+// List returns all of the site's links, including inactive ones, in
+// display order, optionally only those of a category
+func (s *FriendLinkService) List(ctx context.Context, category *string) ([]models.FriendLink, error) {
+	query := s.db().WithContext(ctx)
+	if category != nil {
+		query = query.Where("category = ?", *category)
+	}
+	links := []models.FriendLink{}
+	err := query.Order("display_order ASC, id ASC").Find(&links).Error
+	return links, err
+}
+
+// Categories returns the names of the site's link categories
+func (s *FriendLinkService) Categories(ctx context.Context) ([]string, error) {
+	categories := []string{}
+	err := s.db().WithContext(ctx).Model(&models.FriendLink{}).
+		Where("category <> ''").Distinct("category").Order("category").Pluck("category", &categories).Error
+	return categories, err
+}
+
+// Get returns one of the site's links
+func (s *FriendLinkService) Get(ctx context.Context, id uint) (*models.FriendLink, error) {
+	var link models.FriendLink
+	if err := s.db().WithContext(ctx).First(&link, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFriendLinkNotFound
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Create adds a link at the end of the blogroll
+func (s *FriendLinkService) Create(ctx context.Context, input FriendLinkInput) (*models.FriendLink, error) {
+	link := &models.FriendLink{IsActive: true, ReciprocalStatus: models.ReciprocalUnchecked}
+	if err := applyFriendLinkInput(link, input); err != nil {
+		return nil, err
+	}
+	db := s.db().WithContext(ctx)
+	var maxOrder int
+	if err := db.Model(&models.FriendLink{}).Select("COALESCE(MAX(display_order), 0)").Scan(&maxOrder).Error; err != nil {
+		return nil, err
+	}
+	link.DisplayOrder = maxOrder + 1
+	if err := db.Create(link).Error; err != nil {
+		return nil, err
+	}
+	s.queueCheck(link)
+	return link, nil
+}
+
+// Update edits a link. Changing the page to check starts its check over.
+func (s *FriendLinkService) Update(ctx context.Context, id uint, input FriendLinkInput) (*models.FriendLink, error) {
+	link, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	checked := link.CheckReciprocal
+	page := link.ReciprocalPage()
+	if err := applyFriendLinkInput(link, input); err != nil {
+		return nil, err
+	}
+	restart := link.CheckReciprocal && (!checked || link.ReciprocalPage() != page)
+	if restart {
+		link.ReciprocalStatus = models.ReciprocalUnchecked
+		link.ReciprocalError = ""
+		link.ReciprocalCheckedAt = nil
+	}
+	if err := s.db().WithContext(ctx).Save(link).Error; err != nil {
+		return nil, err
+	}
+	if restart {
+		s.queueCheck(link)
+	}
+	return link, nil
+}
+
+// Delete removes a link
+func (s *FriendLinkService) Delete(ctx context.Context, id uint) error {
+	result := s.db().WithContext(ctx).Delete(&models.FriendLink{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFriendLinkNotFound
+	}
+	return nil
+}
+
+// Reorder sets the display order of the given links
+func (s *FriendLinkService) Reorder(ctx context.Context, orders []FriendLinkOrder) error {
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, order := range orders {
+			if err := tx.Model(&models.FriendLink{}).Where("id = ?", order.ID).
+				Update("display_order", order.Order).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// QueueCheck queues the reciprocal check of one of the site's links
+func (s *FriendLinkService) QueueCheck(ctx context.Context, id uint) (*models.Job, error) {
+	link, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return GetGlobalJobQueue().Enqueue(JobFriendLinksCheck, friendLinkCheckJob{ID: link.ID})
+}
+
+func (s *FriendLinkService) queueCheck(link *models.FriendLink) {
+	if !link.CheckReciprocal {
+		return
+	}
+	if _, err := GetGlobalJobQueue().Enqueue(JobFriendLinksCheck, friendLinkCheckJob{ID: link.ID}); err != nil {
+		slog.Error("Failed to queue friend link check", "friend_link_id", link.ID, "error", err)
+	}
+}
+
+// Check runs the reciprocal check of one link, or of every link that asks
+// for it when the payload names none, and records the result on each link
+func (s *FriendLinkService) Check(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var job friendLinkCheckJob
+	if len(payload) > 0 && string(payload) != "null" {
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return nil, err
+		}
+	}
+	query := s.db().WithContext(ctx)
+	if job.ID != 0 {
+		query = query.Where("id = ?", job.ID)
+	} else {
+		query = query.Where("check_reciprocal = ?", true)
+	}
+	var links []models.FriendLink
+	if err := query.Find(&links).Error; err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{models.ReciprocalFound: 0, models.ReciprocalMissing: 0, models.ReciprocalUnreachable: 0}
+	var broken []string
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(links))
+	for i := range links {
+		if ctx.Err() != nil {
+			return counts, ctx.Err()
+		}
+		link := &links[i]
+		progress.Step("Checking %s", link.URL)
+		updates := map[string]interface{}{"reciprocal_error": ""}
+		base, err := url.Parse(s.baseURL(link.SiteID))
+		if err != nil || base.Hostname() == "" {
+			// Nothing to look for; the last result stands
+			updates["reciprocal_error"] = "the site has no public URL to look for, set PUBLIC_URL"
+			counts["skipped"]++
+		} else {
+			status, checkErr := s.checkLink(ctx, link, base.Hostname())
+			if checkErr != nil {
+				updates["reciprocal_error"] = truncateRunes(checkErr.Error(), 500)
+			}
+			if status != models.ReciprocalFound && status != link.ReciprocalStatus {
+				broken = append(broken, fmt.Sprintf("%s (%s): %s", link.Name, link.URL, checkErr))
+			}
+			updates["reciprocal_status"] = status
+			updates["reciprocal_checked_at"] = time.Now()
+			counts[status]++
+		}
+		if err := s.db().Model(link).Updates(updates).Error; err != nil {
+			return counts, err
+		}
+		progress.Advance(1)
+	}
+	notifyBrokenFriendLinks(broken)
+	return counts, nil
+}
+
+// notifyBrokenFriendLinks tells the admin about links whose reciprocal
+// check started failing in this run
+func notifyBrokenFriendLinks(broken []string) {
+	if len(broken) == 0 {
+		return
+	}
+	title := "A friend link no longer links back"
+	if len(broken) > 1 {
+		title = fmt.Sprintf("%d friend links no longer link back", len(broken))
+	}
+	notifyAdmin(NotificationInput{
+		Source:   models.NotificationSourceLinks,
+		Type:     "reciprocal_broken",
+		Severity: models.SeverityWarning,
+		Title:    title,
+		Message:  strings.Join(broken, "\n"),
+	})
+}
+
+// checkLink fetches the friend's page and looks for a link to host
+func (s *FriendLinkService) checkLink(ctx context.Context, link *models.FriendLink, host string) (string, error) {
+	page, final, err := s.fetch(ctx, link.ReciprocalPage(), "text/html,application/xhtml+xml")
+	if err != nil {
+		return models.ReciprocalUnreachable, err
+	}
+	if !linksToHost(page, final, host) {
+		return models.ReciprocalMissing, fmt.Errorf("no link to %s found on %s", host, final)
+	}
+	return models.ReciprocalFound, nil
+}
+
+// linksToHost reports whether an HTML page has a link to host, ignoring a
+// leading www. on either side
+func linksToHost(page []byte, base *url.URL, host string) bool {
+	want := strings.TrimPrefix(strings.ToLower(host), "www.")
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if name, hasAttr := z.TagName(); string(name) != "a" || !hasAttr {
+				continue
+			}
+			for {
+				key, value, more := z.TagAttr()
+				if string(key) == "href" {
+					if target, err := base.Parse(strings.TrimSpace(string(value))); err == nil &&
+						strings.TrimPrefix(strings.ToLower(target.Hostname()), "www.") == want {
+						return true
+					}
+				}
+				if !more {
+					break
+				}
+			}
+		}
+	}
+}
+
+func applyFriendLinkInput(link *models.FriendLink, input FriendLinkInput) error {
+	if input.Name != nil {
+		link.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.URL != nil {
+		link.URL = strings.TrimSpace(*input.URL)
+	}
+	if input.Description != nil {
+		link.Description = strings.TrimSpace(*input.Description)
+	}
+	if input.AvatarURL != nil {
+		link.AvatarURL = strings.TrimSpace(*input.AvatarURL)
+	}
+	if input.Category != nil {
+		link.Category = strings.TrimSpace(*input.Category)
+	}
+	if input.IsActive != nil {
+		link.IsActive = *input.IsActive
+	}
+	if input.CheckReciprocal != nil {
+		link.CheckReciprocal = *input.CheckReciprocal
+	}
+	if input.ReciprocalURL != nil {
+		link.ReciprocalURL = strings.TrimSpace(*input.ReciprocalURL)
+	}
+
+	if link.Name == "" || utf8.RuneCountInString(link.Name) > 100 {
+		return fmt.Errorf("%w: name is required and at most 100 characters", ErrInvalidFriendLink)
+	}
+	if utf8.RuneCountInString(link.Description) > 500 {
+		return fmt.Errorf("%w: description is at most 500 characters", ErrInvalidFriendLink)
+	}
+	if utf8.RuneCountInString(link.Category) > 50 {
+		return fmt.Errorf("%w: category is at most 50 characters", ErrInvalidFriendLink)
+	}
+	for field, value := range map[string]string{"url": link.URL, "avatar_url": link.AvatarURL, "reciprocal_url": link.ReciprocalURL} {
+		if value == "" && field != "url" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(value) > 500 {
+			return fmt.Errorf("%w: %s must be an absolute http(s) URL of at most 500 characters", ErrInvalidFriendLink, field)
+		}
+	}
+	return nil
+}
+
+var (
+	globalFriendLinkService     *FriendLinkService
+	globalFriendLinkServiceOnce sync.Once
+)
+
+// GetGlobalFriendLinkService returns the global friend link service
+func GetGlobalFriendLinkService() *FriendLinkService {
+	globalFriendLinkServiceOnce.Do(func() {
+		globalFriendLinkService = NewFriendLinkService()
+	})
+	return globalFriendLinkService
+}
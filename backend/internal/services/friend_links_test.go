@@ -0,0 +1,157 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newTestFriendLinkService(t *testing.T, pages map[string]string) *FriendLinkService {
+	newTestDB(t)
+	return &FriendLinkService{
+		db: func() *gorm.DB { return database.DB },
+		fetch: func(ctx context.Context, address, accept string) ([]byte, *url.URL, error) {
+			page, ok := pages[address]
+			if !ok {
+				return nil, nil, errors.New("connection refused")
+			}
+			final, _ := url.Parse(address)
+			return []byte(page), final, nil
+		},
+		baseURL: func(uint) string { return "https://blog.example.com" },
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestFriendLinkBlogroll(t *testing.T) {
+	s := newTestFriendLinkService(t, nil)
+	ctx := context.Background()
+
+	for _, bad := range []FriendLinkInput{
+		{URL: strPtr("https://alice.example")},
+		{Name: strPtr("Alice"), URL: strPtr("javascript:alert(1)")},
+		{Name: strPtr("Alice"), URL: strPtr("https://alice.example"), AvatarURL: strPtr("/avatar.png")},
+	} {
+		if _, err := s.Create(ctx, bad); !errors.Is(err, ErrInvalidFriendLink) {
+			t.Errorf("Create(%+v) = %v, want ErrInvalidFriendLink", bad, err)
+		}
+	}
+
+	inactive := false
+	var ids []uint
+	for _, input := range []FriendLinkInput{
+		{Name: strPtr("Alice"), URL: strPtr("https://alice.example"), Category: strPtr("Friends")},
+		{Name: strPtr("Bob"), URL: strPtr("https://bob.example")},
+		{Name: strPtr("Carol"), URL: strPtr("https://carol.example"), Category: strPtr("Friends")},
+		{Name: strPtr("Dave"), URL: strPtr("https://dave.example"), IsActive: &inactive},
+	} {
+		link, err := s.Create(ctx, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, link.ID)
+	}
+	// Carol moves ahead of Alice
+	if err := s.Reorder(ctx, []FriendLinkOrder{{ID: ids[2], Order: 0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	blogroll, err := s.Blogroll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blogroll) != 2 || blogroll[0].Name != "Friends" || blogroll[1].Name != "" {
+		t.Fatalf("blogroll = %+v", blogroll)
+	}
+	if links := blogroll[0].Links; len(links) != 2 || links[0].Name != "Carol" || links[1].Name != "Alice" {
+		t.Errorf("friends = %+v", links)
+	}
+	if links := blogroll[1].Links; len(links) != 1 || links[0].Name != "Bob" {
+		t.Errorf("uncategorized = %+v, want Bob without the inactive Dave", links)
+	}
+
+	categories, err := s.Categories(ctx)
+	if err != nil || len(categories) != 1 || categories[0] != "Friends" {
+		t.Errorf("categories = %v, %v", categories, err)
+	}
+}
+
+func TestFriendLinkReciprocalCheck(t *testing.T) {
+	s := newTestFriendLinkService(t, map[string]string{
+		"https://alice.example":       `<html><body><a href="https://www.blog.example.com/">My friend</a></body></html>`,
+		"https://bob.example/links":   `<html><body><a href="/about">About</a><a href="https://blog.example.org">Other</a></body></html>`,
+		"https://carol.example/links": `<a href="https://blog.example.com/en/article/1">`,
+	})
+	ctx := context.Background()
+	check := true
+
+	alice, err := s.Create(ctx, FriendLinkInput{Name: strPtr("Alice"), URL: strPtr("https://alice.example"), CheckReciprocal: &check})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := s.Create(ctx, FriendLinkInput{Name: strPtr("Bob"), URL: strPtr("https://bob.example"),
+		ReciprocalURL: strPtr("https://bob.example/links"), CheckReciprocal: &check})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dave, err := s.Create(ctx, FriendLinkInput{Name: strPtr("Dave"), URL: strPtr("https://dave.example"), CheckReciprocal: &check})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unchecked, err := s.Create(ctx, FriendLinkInput{Name: strPtr("Erin"), URL: strPtr("https://erin.example")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Check(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts := result.(map[string]int); counts[models.ReciprocalFound] != 1 || counts[models.ReciprocalMissing] != 1 || counts[models.ReciprocalUnreachable] != 1 {
+		t.Errorf("counts = %v", counts)
+	}
+	for id, want := range map[uint]string{
+		alice.ID: models.ReciprocalFound, bob.ID: models.ReciprocalMissing,
+		dave.ID: models.ReciprocalUnreachable, unchecked.ID: models.ReciprocalUnchecked,
+	} {
+		link, err := s.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if link.ReciprocalStatus != want {
+			t.Errorf("%s is %s (%s), want %s", link.Name, link.ReciprocalStatus, link.ReciprocalError, want)
+		}
+	}
+
+	// Pointing Bob's check at another page starts it over
+	updated, err := s.Update(ctx, bob.ID, FriendLinkInput{ReciprocalURL: strPtr("https://carol.example/links")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ReciprocalStatus != models.ReciprocalUnchecked || updated.ReciprocalCheckedAt != nil {
+		t.Errorf("after update %+v", updated)
+	}
+	payload, _ := json.Marshal(friendLinkCheckJob{ID: bob.ID})
+	if _, err := s.Check(ctx, payload); err != nil {
+		t.Fatal(err)
+	}
+	if link, _ := s.Get(ctx, bob.ID); link.ReciprocalStatus != models.ReciprocalFound {
+		t.Errorf("Bob is %s after the new page was checked", link.ReciprocalStatus)
+	}
+
+	// Without a public URL there is nothing to look for and results stand
+	s.baseURL = func(uint) string { return "" }
+	if _, err := s.Check(ctx, payload); err != nil {
+		t.Fatal(err)
+	}
+	if link, _ := s.Get(ctx, bob.ID); link.ReciprocalStatus != models.ReciprocalFound || link.ReciprocalError == "" {
+		t.Errorf("Bob is %s (%q) when the site has no public URL", link.ReciprocalStatus, link.ReciprocalError)
+	}
+}
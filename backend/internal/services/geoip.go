@@ -1,6 +1,7 @@
 package services
 
 import (
+	"blog-backend/internal/cache"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -165,28 +166,18 @@ func cleanString(s string) string {
 	return s
 }
 
-// GetGeoIPWithCache retrieves geographic information with basic caching
-// In a production environment, you might want to use Redis or similar
-var geoCache = make(map[string]GeoIPInfo)
-var cacheTimestamp = make(map[string]time.Time)
+// Lookups are cached for a day in the shared cache, so replicas do not
+// each query the GeoIP service for the same address
+var geoCache = cache.New("geoip", 24*time.Hour)
 
+// GetGeoIPWithCache retrieves geographic information with caching
 func GetGeoIPWithCache(ipAddress string) GeoIPInfo {
-	// Check cache first (cache for 24 hours)
-	if cachedInfo, exists := geoCache[ipAddress]; exists {
-		if time.Since(cacheTimestamp[ipAddress]) < 24*time.Hour {
-			return cachedInfo
-		}
-		// Remove expired cache entry
-		delete(geoCache, ipAddress)
-		delete(cacheTimestamp, ipAddress)
+	var info GeoIPInfo
+	if geoCache.Get(ipAddress, &info) {
+		return info
 	}
 
-	// Get fresh data
-	info := GetGeoIP(ipAddress)
-
-	// Cache the result
-	geoCache[ipAddress] = info
-	cacheTimestamp[ipAddress] = time.Now()
-
+	info = GetGeoIP(ipAddress)
+	geoCache.Set(ipAddress, info)
 	return info
 }
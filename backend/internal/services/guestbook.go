@@ -0,0 +1,297 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidGuestbookEntry  = errors.New("invalid guestbook entry")
+	ErrGuestbookEntryNotFound = errors.New("guestbook entry not found")
+	ErrGuestbookRateLimited   = errors.New("too many messages, please try again later")
+)
+
+var languageCode = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// GuestbookInput is a message left on the guestbook. Email is optional and
+// never shown; with NotifyReplies the sender is emailed when the site
+// replies. Company is a honeypot the form hides, so only bots fill it in.
+type GuestbookInput struct {
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	Website       string `json:"website"`
+	Message       string `json:"message"`
+	Language      string `json:"language"`
+	NotifyReplies bool   `json:"notify_replies"`
+	Company       string `json:"company"`
+}
+
+// PublicGuestbookEntry is a guestbook entry as shown on the message wall,
+// without the sender's email and address
+type PublicGuestbookEntry struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Website   string     `json:"website,omitempty"`
+	Message   string     `json:"message"`
+	Language  string     `json:"language"`
+	Reply     string     `json:"reply,omitempty"`
+	RepliedAt *time.Time `json:"replied_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// GuestbookService keeps the guestbook of each site. Entries go through the
+// same moderation as comments: they wait as pending, admins hear about them
+// in the comment notice batches, and only approved ones are shown. Senders
+// are rate limited and screened like contact form messages.
+type GuestbookService struct {
+	db         func() *gorm.DB
+	rateLimit  int
+	rateWindow time.Duration
+}
+
+// NewGuestbookService creates a guestbook service from the GUESTBOOK_*
+// settings
+func NewGuestbookService() *GuestbookService {
+	cfg := config.Get().Guestbook
+	return &GuestbookService{
+		db:         func() *gorm.DB { return database.DB },
+		rateLimit:  cfg.RateLimit,
+		rateWindow: cfg.RateWindow.Std(),
+	}
+}
+
+// Sign stores an entry for the site in ctx, pending moderation unless the
+// spam checks file it away. The returned entry is nil when the submission
+// was discarded as spam.
+func (s *GuestbookService) Sign(ctx context.Context, input GuestbookInput, ip, userAgent string) (*models.GuestbookEntry, error) {
+	if input.Company != "" {
+		slog.Info("Discarding guestbook entry that filled in the honeypot", "ip", ip)
+		return nil, nil
+	}
+	entry, err := newGuestbookEntry(input)
+	if err != nil {
+		return nil, err
+	}
+	entry.IPAddress = truncateRunes(ip, 45)
+	entry.UserAgent = truncateRunes(userAgent, 500)
+
+	db := s.db().WithContext(ctx)
+	if entry.Language == "" {
+		siteID, ok := database.SiteFromContext(ctx)
+		if !ok {
+			siteID = 1
+		}
+		entry.Language = siteLanguage(s.db(), siteID)
+	}
+	if limited, err := rateLimited(db, &models.GuestbookEntry{}, entry.IPAddress, s.rateLimit, s.rateWindow); err != nil {
+		return nil, err
+	} else if limited {
+		return nil, ErrGuestbookRateLimited
+	}
+	if spam, err := looksLikeSpam(db, &models.GuestbookEntry{}, "ip_address", entry.IPAddress, entry.Message); err != nil {
+		return nil, err
+	} else if spam {
+		entry.Status = models.GuestbookSpam
+	}
+	if err := db.Create(entry).Error; err != nil {
+		return nil, err
+	}
+	if entry.Status == models.ReplyPending {
+		hooks.Notify(ctx, hooks.CommentPending, entry)
+	}
+	return entry, nil
+}
+
+// newGuestbookEntry validates and normalizes a submission
+func newGuestbookEntry(input GuestbookInput) (*models.GuestbookEntry, error) {
+	name := strings.Join(strings.Fields(input.Name), " ")
+	email := strings.TrimSpace(input.Email)
+	website := strings.TrimSpace(input.Website)
+	body := strings.TrimSpace(input.Message)
+	language := strings.TrimSpace(input.Language)
+	switch {
+	case name == "" || utf8.RuneCountInString(name) > 100:
+		return nil, fmt.Errorf("%w: name is required and at most 100 characters", ErrInvalidGuestbookEntry)
+	case body == "" || utf8.RuneCountInString(body) > 2000:
+		return nil, fmt.Errorf("%w: message is required and at most 2000 characters", ErrInvalidGuestbookEntry)
+	case language != "" && (len(language) > 10 || !languageCode.MatchString(language)):
+		return nil, fmt.Errorf("%w: %q is not a language code", ErrInvalidGuestbookEntry, language)
+	}
+	if email != "" {
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email || len(email) > 254 {
+			return nil, ErrInvalidEmail
+		}
+	}
+	if website != "" {
+		if u, err := url.Parse(website); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(website) > 500 {
+			return nil, fmt.Errorf("%w: website must be an http(s) URL of at most 500 characters", ErrInvalidGuestbookEntry)
+		}
+	}
+	return &models.GuestbookEntry{
+		Name:          name,
+		Email:         email,
+		Website:       website,
+		Message:       body,
+		Language:      language,
+		NotifyReplies: input.NotifyReplies && email != "",
+		Status:        models.ReplyPending,
+	}, nil
+}
+
+// Public returns a page of the site's approved entries, newest first,
+// optionally only those written in language
+func (s *GuestbookService) Public(ctx context.Context, language string, page, limit int) ([]PublicGuestbookEntry, int64, error) {
+	entries, total, err := s.List(ctx, models.ReplyApproved, language, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	public := make([]PublicGuestbookEntry, len(entries))
+	for i, entry := range entries {
+		public[i] = PublicGuestbookEntry{
+			ID:        entry.ID,
+			Name:      entry.Name,
+			Website:   entry.Website,
+			Message:   entry.Message,
+			Language:  entry.Language,
+			Reply:     entry.Reply,
+			RepliedAt: entry.RepliedAt,
+			CreatedAt: entry.CreatedAt,
+		}
+	}
+	return public, total, nil
+}
+
+// List returns a page of the site's entries, newest first, optionally with
+// one status or language. Without a status every entry but spam is listed.
+func (s *GuestbookService) List(ctx context.Context, status, language string, page, limit int) ([]models.GuestbookEntry, int64, error) {
+	query := s.db().WithContext(ctx).Model(&models.GuestbookEntry{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status <> ?", models.GuestbookSpam)
+	}
+	if language != "" {
+		query = query.Where("language = ?", language)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	entries := []models.GuestbookEntry{}
+	err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&entries).Error
+	return entries, total, err
+}
+
+// SetStatus approves, rejects or files away an entry
+func (s *GuestbookService) SetStatus(ctx context.Context, id uint, status string) (*models.GuestbookEntry, error) {
+	switch status {
+	case models.ReplyPending, models.ReplyApproved, models.ReplyRejected, models.GuestbookSpam:
+	default:
+		return nil, fmt.Errorf("%w: status must be pending, approved, rejected or spam", ErrInvalidGuestbookEntry)
+	}
+	var entry models.GuestbookEntry
+	if err := s.db().WithContext(ctx).First(&entry, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGuestbookEntryNotFound
+		}
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Model(&entry).Update("status", status).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Reply sets the site's public reply to an approved entry, or removes it
+// when reply is empty. The sender is emailed about the first reply if they
+// asked to be.
+func (s *GuestbookService) Reply(ctx context.Context, id uint, reply string) (*models.GuestbookEntry, error) {
+	reply = strings.TrimSpace(reply)
+	if utf8.RuneCountInString(reply) > 2000 {
+		return nil, fmt.Errorf("%w: reply is at most 2000 characters", ErrInvalidGuestbookEntry)
+	}
+	var entry models.GuestbookEntry
+	if err := s.db().WithContext(ctx).First(&entry, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGuestbookEntryNotFound
+		}
+		return nil, err
+	}
+	if entry.Status != models.ReplyApproved {
+		return nil, fmt.Errorf("%w: only approved entries can be replied to", ErrInvalidGuestbookEntry)
+	}
+
+	first := entry.RepliedAt == nil && reply != ""
+	var repliedAt *time.Time
+	if reply != "" {
+		now := time.Now()
+		repliedAt = &now
+		if entry.RepliedAt != nil {
+			repliedAt = entry.RepliedAt
+		}
+	}
+	if err := s.db().WithContext(ctx).Model(&entry).Updates(map[string]interface{}{"reply": reply, "replied_at": repliedAt}).Error; err != nil {
+		return nil, err
+	}
+	entry.Reply, entry.RepliedAt = reply, repliedAt
+
+	if first && entry.NotifyReplies {
+		if _, err := GetGlobalJobQueue().Enqueue(JobCommentsReply, commentReplyJob{EntryID: entry.ID}); err != nil {
+			slog.Error("Failed to queue guestbook reply notice", "entry_id", entry.ID, "error", err)
+		}
+	}
+	return &entry, nil
+}
+
+// Delete removes an entry
+func (s *GuestbookService) Delete(ctx context.Context, id uint) error {
+	result := s.db().WithContext(ctx).Delete(&models.GuestbookEntry{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrGuestbookEntryNotFound
+	}
+	return nil
+}
+
+// guestbookTitle names the guestbook in moderation notices, where article
+// comments show the article's title
+func guestbookTitle(language string) string {
+	switch strings.SplitN(language, "-", 2)[0] {
+	case "zh":
+		return "留言板"
+	case "ja":
+		return "ゲストブック"
+	default:
+		return "Guestbook"
+	}
+}
+
+var (
+	globalGuestbookService     *GuestbookService
+	globalGuestbookServiceOnce sync.Once
+)
+
+// GetGlobalGuestbookService returns the global guestbook service
+func GetGlobalGuestbookService() *GuestbookService {
+	globalGuestbookServiceOnce.Do(func() {
+		globalGuestbookService = NewGuestbookService()
+	})
+	return globalGuestbookService
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestGuestbook(t *testing.T) {
+	newTestDB(t)
+	s := &GuestbookService{db: func() *gorm.DB { return database.DB }, rateLimit: 3, rateWindow: time.Hour}
+	ctx := context.Background()
+
+	for _, bad := range []GuestbookInput{
+		{Message: "Hi"},
+		{Name: "Alice", Message: "   "},
+		{Name: "Alice", Message: "Hi", Email: "not an email"},
+		{Name: "Alice", Message: "Hi", Website: "javascript:alert(1)"},
+		{Name: "Alice", Message: "Hi", Language: "<script>"},
+	} {
+		if _, err := s.Sign(ctx, bad, "10.0.0.1", ""); !errors.Is(err, ErrInvalidGuestbookEntry) && !errors.Is(err, ErrInvalidEmail) {
+			t.Errorf("Sign(%+v) = %v, want a validation error", bad, err)
+		}
+	}
+	if entry, err := s.Sign(ctx, GuestbookInput{Name: "Bot", Message: "Buy now", Company: "Spam Inc"}, "10.0.0.9", ""); entry != nil || err != nil {
+		t.Errorf("honeypot entry = %+v, %v", entry, err)
+	}
+
+	alice, err := s.Sign(ctx, GuestbookInput{Name: "Alice", Email: "alice@example.com", Website: "https://alice.example", Message: "Lovely blog!"}, "10.0.0.1", "Firefox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Status != models.ReplyPending || alice.Language != "en" {
+		t.Errorf("new entry is %s in %q, want pending in the site language", alice.Status, alice.Language)
+	}
+	bob, err := s.Sign(ctx, GuestbookInput{Name: "Bob", Message: "你好！", Language: "zh"}, "10.0.0.2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spam, err := s.Sign(ctx, GuestbookInput{Name: "Carol", Message: "http://a http://b http://c http://d"}, "10.0.0.3", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spam.Status != models.GuestbookSpam {
+		t.Errorf("link-heavy entry is %s, want spam", spam.Status)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.Sign(ctx, GuestbookInput{Name: "Alice", Message: "Again " + strings.Repeat("!", i)}, "10.0.0.1", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Sign(ctx, GuestbookInput{Name: "Alice", Message: "Once more"}, "10.0.0.1", ""); !errors.Is(err, ErrGuestbookRateLimited) {
+		t.Errorf("fourth entry from one address = %v, want ErrGuestbookRateLimited", err)
+	}
+
+	if public, total, _ := s.Public(ctx, "", 1, 10); total != 0 || len(public) != 0 {
+		t.Errorf("unmoderated entries are public: %+v", public)
+	}
+	for _, id := range []uint{alice.ID, bob.ID} {
+		if _, err := s.SetStatus(ctx, id, models.ReplyApproved); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.SetStatus(ctx, alice.ID, "published"); !errors.Is(err, ErrInvalidGuestbookEntry) {
+		t.Errorf("unknown status = %v", err)
+	}
+	public, total, err := s.Public(ctx, "zh", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || public[0].ID != bob.ID {
+		t.Errorf("Chinese wall = %+v", public)
+	}
+	if public, total, _ := s.Public(ctx, "", 1, 10); total != 2 || public[1].Website != "https://alice.example" {
+		t.Errorf("wall = %+v", public)
+	}
+	if _, total, _ := s.List(ctx, "", "", 1, 10); total != 4 {
+		t.Errorf("%d entries for moderation, want 4 without spam", total)
+	}
+
+	if err := s.Delete(ctx, spam.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, spam.ID); !errors.Is(err, ErrGuestbookEntryNotFound) {
+		t.Errorf("second delete = %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Readiness checks
+const (
+	HealthCheckDatabase = "database"
+	HealthCheckJobs     = "jobs"
+)
+
+// Health alert events, sent as the generic webhook's event field
+const (
+	HealthEventFailing  = "health.failing"
+	HealthEventResolved = "health.resolved"
+)
+
+// healthCheckTimeout bounds each readiness check, so /readyz answers while
+// the database hangs
+const healthCheckTimeout = 3 * time.Second
+
+// HealthCheck is the result of one readiness check
+type HealthCheck struct {
+	Name      string `json:"name"`
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// HealthReport is the result of the readiness checks
+type HealthReport struct {
+	Ready     bool          `json:"ready"`
+	Checks    []HealthCheck `json:"checks"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// HealthAlert is the payload of a health alert
+type HealthAlert struct {
+	Event    string      `json:"event"`
+	Message  string      `json:"message"`
+	Instance string      `json:"instance"`
+	Check    HealthCheck `json:"check"`
+	At       time.Time   `json:"at"`
+}
+
+// HealthService runs the readiness checks behind /readyz and, with a
+// webhook configured, watches them from a loop of its own, independent of
+// the job queue and scheduler it checks, to page the admin when the blog
+// breaks without anyone noticing
+type HealthService struct {
+	db         func() *gorm.DB
+	now        func() time.Time
+	cfg        config.HealthConfig
+	instance   string
+	httpClient *http.Client
+	// sent remembers the alerts posted recently, shared by the instances
+	// with a Redis cache, so a failing check is posted once per Repeat
+	sent *cache.Namespace
+
+	mu sync.Mutex
+	// alerted holds the checks this instance reported failing, which it
+	// reports resolved when they pass again
+	alerted map[string]bool
+	started bool
+	// send delivers an alert; replaced in tests
+	send func(ctx context.Context, alert HealthAlert) error
+	// warmup reports the critical caches still warming after startup;
+	// without it there is no warmup check
+	warmup func() error
+}
+
+// NewHealthService creates a health service from the HEALTH_* settings
+func NewHealthService() *HealthService {
+	cfg := config.Get().Health
+	instance, _ := os.Hostname()
+	s := &HealthService{
+		db:         func() *gorm.DB { return database.DB },
+		now:        time.Now,
+		cfg:        cfg,
+		instance:   instance,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		sent:       cache.New("health_alerts", time.Duration(cfg.Repeat)),
+		alerted:    make(map[string]bool),
+		warmup:     GetGlobalWarmup().Pending,
+	}
+	s.send = s.post
+	return s
+}
+
+// AlertsEnabled reports whether failing checks are posted anywhere
+func (s *HealthService) AlertsEnabled() bool {
+	return s.cfg.WebhookURL != "" || s.cfg.Format == "pagerduty"
+}
+
+// Check runs the readiness checks
+func (s *HealthService) Check(ctx context.Context) *HealthReport {
+	report := &HealthReport{Ready: true, CheckedAt: s.now()}
+	checks := []struct {
+		name string
+		run  func(context.Context) error
+	}{
+		{HealthCheckDatabase, s.checkDatabase},
+		{HealthCheckJobs, s.checkJobs},
+	}
+	if s.warmup != nil {
+		checks = append(checks, struct {
+			name string
+			run  func(context.Context) error
+		}{HealthCheckWarmup, func(context.Context) error { return s.warmup() }})
+	}
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+		started := time.Now()
+		err := check.run(checkCtx)
+		cancel()
+		result := HealthCheck{Name: check.name, OK: err == nil, LatencyMS: time.Since(started).Milliseconds()}
+		if err != nil {
+			result.Error = err.Error()
+			report.Ready = false
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	return report
+}
+
+func (s *HealthService) checkDatabase(ctx context.Context) error {
+	db := s.db()
+	if db == nil {
+		return fmt.Errorf("not connected")
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// checkJobs fails when due jobs wait longer than the stall threshold, as
+// they do when every worker is stuck or gone
+func (s *HealthService) checkJobs(ctx context.Context) error {
+	db := s.db()
+	if db == nil {
+		return fmt.Errorf("no database")
+	}
+	cutoff := s.now().Add(-time.Duration(s.cfg.JobStall))
+	waiting := func() *gorm.DB {
+		return db.WithContext(ctx).Model(&models.Job{}).Where("status = ? AND run_at < ?", models.JobPending, cutoff)
+	}
+	var count int64
+	if err := waiting().Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		var oldest models.Job
+		if err := waiting().Select("type", "run_at").Order("run_at").Limit(1).Find(&oldest).Error; err != nil {
+			return err
+		}
+		return fmt.Errorf("%d due jobs are waiting, the oldest (%s) for %s", count, oldest.Type,
+			s.now().Sub(oldest.RunAt).Round(time.Second))
+	}
+	return nil
+}
+
+// Start runs the checks every Interval and posts the alerts they call for,
+// until shutdown. It does nothing without a webhook.
+func (s *HealthService) Start() {
+	s.mu.Lock()
+	if s.started || !s.AlertsEnabled() {
+		s.mu.Unlock()
+		return
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(s.cfg.Interval))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Watch(context.Background())
+			case <-ShuttingDown():
+				return
+			}
+		}
+	}()
+	slog.Info("Health alerts started", "format", s.cfg.Format, "interval", time.Duration(s.cfg.Interval).String())
+}
+
+// Watch runs the checks once and posts an alert for each check that fails,
+// unless one was posted within Repeat, and for each check this instance
+// reported that passes again
+func (s *HealthService) Watch(ctx context.Context) {
+	report := s.Check(ctx)
+	for _, check := range report.Checks {
+		if check.Name == HealthCheckWarmup {
+			// Warming up is part of every start, not an outage
+			continue
+		}
+		alert := HealthAlert{Instance: s.instance, Check: check, At: report.CheckedAt}
+		s.mu.Lock()
+		alerted := s.alerted[check.Name]
+		s.mu.Unlock()
+
+		if check.OK {
+			if !alerted {
+				continue
+			}
+			alert.Event = HealthEventResolved
+			alert.Message = fmt.Sprintf("kuno health check %q on %s passes again", check.Name, s.instance)
+		} else {
+			var last time.Time
+			if s.sent.Get(check.Name, &last) {
+				continue
+			}
+			alert.Event = HealthEventFailing
+			alert.Message = fmt.Sprintf("kuno health check %q on %s fails: %s", check.Name, s.instance, check.Error)
+		}
+
+		slog.Warn("Health alert", "event", alert.Event, "check", check.Name, "error", check.Error)
+		if err := s.send(ctx, alert); err != nil {
+			slog.Error("Failed to send health alert", "event", alert.Event, "check", check.Name, "error", err)
+			// Tried again on the next run
+			continue
+		}
+		s.mu.Lock()
+		if check.OK {
+			delete(s.alerted, check.Name)
+		} else {
+			s.alerted[check.Name] = true
+			s.sent.Set(check.Name, report.CheckedAt)
+		}
+		s.mu.Unlock()
+		if !check.OK && check.Name != HealthCheckDatabase {
+			notifyAdmin(NotificationInput{
+				Source:   models.NotificationSourceMonitor,
+				Type:     alert.Event,
+				Severity: models.SeverityCritical,
+				Title:    alert.Message,
+			})
+		}
+	}
+}
+
+var (
+	globalHealthService     *HealthService
+	globalHealthServiceOnce sync.Once
+)
+
+// GetGlobalHealthService returns the global health service
+func GetGlobalHealthService() *HealthService {
+	globalHealthServiceOnce.Do(func() {
+		globalHealthService = NewHealthService()
+	})
+	return globalHealthService
+}
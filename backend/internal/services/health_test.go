@@ -0,0 +1,139 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newTestHealthService(cfg config.HealthConfig) *HealthService {
+	return &HealthService{
+		db:         func() *gorm.DB { return database.DB },
+		now:        time.Now,
+		cfg:        cfg,
+		instance:   "web-1",
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		sent:       cache.New("test_health_alerts_"+cfg.Format, time.Hour),
+		alerted:    make(map[string]bool),
+	}
+}
+
+func TestHealthWatch(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+	s := newTestHealthService(config.HealthConfig{JobStall: config.Duration(15 * time.Minute), Repeat: config.Duration(time.Hour), Format: "generic"})
+	var sent []HealthAlert
+	s.send = func(ctx context.Context, alert HealthAlert) error {
+		sent = append(sent, alert)
+		return nil
+	}
+
+	if report := s.Check(ctx); !report.Ready || len(report.Checks) != 2 {
+		t.Fatalf("Check() = %+v", report)
+	}
+	// A job due a minute ago is not a stall
+	database.DB.Create(&models.Job{Type: JobSiteMonitor, Status: models.JobPending, RunAt: time.Now().Add(-time.Minute)})
+	if report := s.Check(ctx); !report.Ready {
+		t.Fatalf("Check() with a fresh job = %+v", report)
+	}
+	stalled := models.Job{Type: JobBackupCreate, Status: models.JobPending, RunAt: time.Now().Add(-time.Hour)}
+	database.DB.Create(&stalled)
+	report := s.Check(ctx)
+	if report.Ready || report.Checks[1].OK || !strings.Contains(report.Checks[1].Error, JobBackupCreate) {
+		t.Fatalf("Check() with a stalled job = %+v", report)
+	}
+
+	s.Watch(ctx)
+	s.Watch(ctx)
+	if len(sent) != 1 || sent[0].Event != HealthEventFailing || sent[0].Check.Name != HealthCheckJobs {
+		t.Fatalf("alerts after two failing runs = %+v, want one", sent)
+	}
+
+	database.DB.Delete(&stalled)
+	s.Watch(ctx)
+	s.Watch(ctx)
+	if len(sent) != 2 || sent[1].Event != HealthEventResolved {
+		t.Fatalf("alerts after recovery = %+v, want one resolved", sent)
+	}
+
+	// Another failure within the repeat interval is not posted again
+	database.DB.Create(&models.Job{Type: JobBackupCreate, Status: models.JobPending, RunAt: time.Now().Add(-time.Hour)})
+	s.Watch(ctx)
+	if len(sent) != 2 {
+		t.Errorf("alerts = %d, want the repeat suppressed", len(sent))
+	}
+}
+
+func TestHealthWebhookFormats(t *testing.T) {
+	var got map[string]interface{}
+	var query string
+	answer := `{"code": 0}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got, query = nil, r.URL.RawQuery
+		json.Unmarshal(body, &got)
+		io.WriteString(w, answer)
+	}))
+	defer server.Close()
+
+	alert := HealthAlert{
+		Event: HealthEventFailing, Message: "database fails", Instance: "web-1", At: time.Now(),
+		Check: HealthCheck{Name: HealthCheckDatabase, Error: "connection refused"},
+	}
+	ctx := context.Background()
+
+	s := newTestHealthService(config.HealthConfig{Format: "pagerduty", WebhookURL: server.URL, Secret: "routing-key"})
+	if err := s.post(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+	if got["routing_key"] != "routing-key" || got["event_action"] != "trigger" || got["dedup_key"] != "kuno-web-1-database" {
+		t.Errorf("pagerduty trigger = %v", got)
+	}
+	resolved := alert
+	resolved.Event = HealthEventResolved
+	s.post(ctx, resolved)
+	if got["event_action"] != "resolve" || got["dedup_key"] != "kuno-web-1-database" {
+		t.Errorf("pagerduty resolve = %v", got)
+	}
+
+	s = newTestHealthService(config.HealthConfig{Format: "feishu", WebhookURL: server.URL, Secret: "bot-secret"})
+	if err := s.post(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := got["content"].(map[string]interface{})
+	if got["msg_type"] != "text" || !strings.Contains(content["text"].(string), "connection refused") || got["sign"] == nil {
+		t.Errorf("feishu message = %v", got)
+	}
+	answer = `{"code": 19021, "msg": "sign match fail"}`
+	if err := s.post(ctx, alert); err == nil || !strings.Contains(err.Error(), "sign match fail") {
+		t.Errorf("feishu error answer: %v", err)
+	}
+
+	answer = `{"errcode": 0, "errmsg": "ok"}`
+	s = newTestHealthService(config.HealthConfig{Format: "dingtalk", WebhookURL: server.URL + "/robot/send?access_token=abc", Secret: "SEC123"})
+	if err := s.post(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+	if got["msgtype"] != "text" || !strings.Contains(query, "access_token=abc") || !strings.Contains(query, "sign=") {
+		t.Errorf("dingtalk message = %v, query %q", got, query)
+	}
+
+	s = newTestHealthService(config.HealthConfig{Format: "generic", WebhookURL: server.URL})
+	if err := s.post(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+	if got["event"] != HealthEventFailing || got["data"] == nil {
+		t.Errorf("generic payload = %v", got)
+	}
+}
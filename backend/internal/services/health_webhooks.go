@@ -0,0 +1,165 @@
+package services
+
+import (
+	"blog-backend/internal/security"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pagerDutyEventsURL is the PagerDuty Events API v2 endpoint, used when no
+// webhook URL is set
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// post delivers an alert in the configured format
+func (s *HealthService) post(ctx context.Context, alert HealthAlert) error {
+	secret := ""
+	if s.cfg.Secret != "" {
+		var err error
+		if secret, err = security.ResolveSecret(s.cfg.Secret); err != nil {
+			return fmt.Errorf("resolve health alert secret: %w", err)
+		}
+	}
+	target := s.cfg.WebhookURL
+	var body interface{}
+	switch s.cfg.Format {
+	case "pagerduty":
+		if target == "" {
+			target = pagerDutyEventsURL
+		}
+		body = pagerDutyEvent(alert, secret)
+	case "feishu":
+		body = feishuMessage(alert, secret, s.now())
+	case "dingtalk":
+		var err error
+		if target, err = dingTalkURL(target, secret, s.now()); err != nil {
+			return err
+		}
+		body = map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": healthAlertText(alert)},
+		}
+	default:
+		body = map[string]interface{}{"event": alert.Event, "data": alert}
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kuno-health")
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	// Feishu and DingTalk bots answer 200 and report errors in the body
+	var result struct {
+		Code    *int   `json:"code"`
+		Msg     string `json:"msg"`
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if json.Unmarshal(answer, &result) == nil {
+		if result.Code != nil && *result.Code != 0 {
+			return fmt.Errorf("webhook returned code %d: %s", *result.Code, result.Msg)
+		}
+		if result.ErrCode != nil && *result.ErrCode != 0 {
+			return fmt.Errorf("webhook returned error %d: %s", *result.ErrCode, result.ErrMsg)
+		}
+	}
+	return nil
+}
+
+// pagerDutyEvent triggers or resolves the incident of a check on an
+// instance, which PagerDuty groups by the dedup key
+func pagerDutyEvent(alert HealthAlert, routingKey string) map[string]interface{} {
+	event := map[string]interface{}{
+		"routing_key":  routingKey,
+		"event_action": "trigger",
+		"dedup_key":    "kuno-" + alert.Instance + "-" + alert.Check.Name,
+	}
+	if alert.Event == HealthEventResolved {
+		event["event_action"] = "resolve"
+		return event
+	}
+	event["payload"] = map[string]interface{}{
+		"summary":        alert.Message,
+		"source":         alert.Instance,
+		"severity":       "critical",
+		"component":      "kuno",
+		"group":          alert.Check.Name,
+		"timestamp":      alert.At.Format(time.RFC3339),
+		"custom_details": alert.Check,
+	}
+	return event
+}
+
+// feishuMessage is a Feishu (Lark) bot text message, signed when the bot
+// has a secret
+func feishuMessage(alert HealthAlert, secret string, now time.Time) map[string]interface{} {
+	message := map[string]interface{}{
+		"msg_type": "text",
+		"content":  map[string]string{"text": healthAlertText(alert)},
+	}
+	if secret != "" {
+		timestamp := strconv.FormatInt(now.Unix(), 10)
+		// Feishu signs an empty message with the timestamp and secret as key
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+		message["timestamp"] = timestamp
+		message["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	return message
+}
+
+// dingTalkURL adds the signature of a DingTalk bot with a secret to its
+// webhook URL
+func dingTalkURL(webhook, secret string, now time.Time) (string, error) {
+	if secret == "" {
+		return webhook, nil
+	}
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return "", err
+	}
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// healthAlertText is an alert as chat text
+func healthAlertText(alert HealthAlert) string {
+	var b strings.Builder
+	b.WriteString(alert.Message)
+	fmt.Fprintf(&b, "\n\nCheck:     %s\n", alert.Check.Name)
+	fmt.Fprintf(&b, "Instance:  %s\n", alert.Instance)
+	fmt.Fprintf(&b, "At:        %s\n", alert.At.Format(time.RFC1123))
+	if alert.Check.Error != "" {
+		fmt.Fprintf(&b, "Error:     %s\n", alert.Check.Error)
+	}
+	return b.String()
+}
@@ -0,0 +1,297 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Homepage sections, the names used in HomepageLayout.Order
+const (
+	HomepageHero            = "hero"
+	HomepagePinned          = "pinned"
+	HomepageCategories      = "categories"
+	HomepageRecommendations = "recommendations"
+	HomepageMoments         = "moments"
+)
+
+// homepageSections is the default order of the homepage sections
+var homepageSections = []string{HomepageHero, HomepagePinned, HomepageCategories, HomepageRecommendations, HomepageMoments}
+
+// maxCategoryRows is the most category rows a homepage shows
+const maxCategoryRows = 10
+
+var ErrInvalidHomepageLayout = errors.New("invalid homepage layout")
+
+// LocalizedText is a text in several languages, keyed by language code;
+// pages fall back to the site's default language
+type LocalizedText map[string]string
+
+// HomepageLayout arranges a site's homepage. Order lists the sections
+// from the top; sections left out of it follow in the default order, and
+// each is only shown when enabled.
+type HomepageLayout struct {
+	Order           []string                     `json:"order"`
+	Hero            HomepageHeroSection          `json:"hero"`
+	Pinned          HomepageListSection          `json:"pinned"`
+	CategoryRows    []HomepageCategoryRow        `json:"category_rows"`
+	Recommendations HomepageRecommendationWidget `json:"recommendations"`
+	Moments         HomepageListSection          `json:"moments"`
+}
+
+// HomepageHeroSection is the banner at the top of the homepage, with an
+// optional background image and call to action
+type HomepageHeroSection struct {
+	Enabled  bool          `json:"enabled"`
+	Title    LocalizedText `json:"title"`
+	Subtitle LocalizedText `json:"subtitle"`
+	ImageURL string        `json:"image_url"`
+	CTAText  LocalizedText `json:"cta_text"`
+	CTAURL   string        `json:"cta_url"`
+}
+
+// HomepageListSection is a titled list of up to Limit items, used for the
+// pinned articles and the moments feed
+type HomepageListSection struct {
+	Enabled bool          `json:"enabled"`
+	Title   LocalizedText `json:"title"`
+	Limit   int           `json:"limit"`
+}
+
+// HomepageCategoryRow is a row of the latest articles of a category
+type HomepageCategoryRow struct {
+	CategoryID uint          `json:"category_id"`
+	Title      LocalizedText `json:"title"`
+	Limit      int           `json:"limit"`
+}
+
+// HomepageRecommendationWidget is the recommendation widget; Strategy is
+// "personalized" for each reader or "popular" for everyone
+type HomepageRecommendationWidget struct {
+	HomepageListSection
+	Strategy string `json:"strategy"`
+}
+
+// DefaultHomepageLayout is the layout of sites that have not configured
+// one: pinned articles above the article list, as before layouts existed
+func DefaultHomepageLayout() HomepageLayout {
+	return HomepageLayout{
+		Order:           append([]string(nil), homepageSections...),
+		Pinned:          HomepageListSection{Enabled: true, Limit: 3},
+		CategoryRows:    []HomepageCategoryRow{},
+		Recommendations: HomepageRecommendationWidget{HomepageListSection: HomepageListSection{Limit: 4}, Strategy: "popular"},
+		Moments:         HomepageListSection{Limit: 5},
+	}
+}
+
+// HomepageLayoutService stores each site's homepage layout in its settings
+type HomepageLayoutService struct {
+	db func() *gorm.DB
+}
+
+// NewHomepageLayoutService creates a homepage layout service
+func NewHomepageLayoutService() *HomepageLayoutService {
+	return &HomepageLayoutService{db: func() *gorm.DB { return database.DB }}
+}
+
+// Get returns the site's homepage layout, or the default layout
+func (s *HomepageLayoutService) Get(ctx context.Context) (*HomepageLayout, error) {
+	var settings models.SiteSettings
+	if err := s.db().WithContext(ctx).Select("homepage_layout").Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	layout := DefaultHomepageLayout()
+	if settings.HomepageLayout == "" {
+		return &layout, nil
+	}
+	if err := json.Unmarshal([]byte(settings.HomepageLayout), &layout); err != nil {
+		return nil, fmt.Errorf("stored homepage layout: %w", err)
+	}
+	layout.complete()
+	return &layout, nil
+}
+
+// Update validates a homepage layout given as JSON and saves it. Unknown
+// fields are rejected, so misspelled ones are not silently dropped.
+func (s *HomepageLayoutService) Update(ctx context.Context, body io.Reader) (*HomepageLayout, error) {
+	layout := DefaultHomepageLayout()
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&layout); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidHomepageLayout, err)
+	}
+	if err := layout.validate(); err != nil {
+		return nil, err
+	}
+	layout.complete()
+
+	db := s.db().WithContext(ctx)
+	for i, row := range layout.CategoryRows {
+		var count int64
+		if err := db.Model(&models.Category{}).Where("id = ?", row.CategoryID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("%w: category_rows[%d]: category %d does not exist", ErrInvalidHomepageLayout, i, row.CategoryID)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(layout); err != nil {
+		return nil, err
+	}
+	result := db.Model(&models.SiteSettings{}).Where("1 = 1").Update("homepage_layout", strings.TrimSpace(buf.String()))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: the site has no settings yet", ErrInvalidHomepageLayout)
+	}
+	cache.Publish(cache.TopicSettings)
+	return &layout, nil
+}
+
+// repointCategoryRows points the category rows of the site's homepage
+// layout showing one category at another, returning how many rows it
+// changed. With apply false it only counts them.
+func repointCategoryRows(db *gorm.DB, from, to uint, apply bool) (int, error) {
+	var settings models.SiteSettings
+	if err := db.Select("id", "homepage_layout").Limit(1).Find(&settings).Error; err != nil {
+		return 0, err
+	}
+	if settings.HomepageLayout == "" {
+		return 0, nil
+	}
+	var layout HomepageLayout
+	if err := json.Unmarshal([]byte(settings.HomepageLayout), &layout); err != nil {
+		return 0, fmt.Errorf("stored homepage layout: %w", err)
+	}
+	changed := 0
+	for i := range layout.CategoryRows {
+		if layout.CategoryRows[i].CategoryID == from {
+			layout.CategoryRows[i].CategoryID = to
+			changed++
+		}
+	}
+	if changed == 0 || !apply {
+		return changed, nil
+	}
+	data, err := json.Marshal(layout)
+	if err != nil {
+		return 0, err
+	}
+	return changed, db.Model(&settings).Update("homepage_layout", string(data)).Error
+}
+
+func (l *HomepageLayout) validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidHomepageLayout}, args...)...)
+	}
+	seen := map[string]bool{}
+	for _, section := range l.Order {
+		if !contains(homepageSections, section) {
+			return invalid("order: unknown section %q, use %s", section, strings.Join(homepageSections, ", "))
+		}
+		if seen[section] {
+			return invalid("order: %s is listed twice", section)
+		}
+		seen[section] = true
+	}
+
+	texts := map[string]LocalizedText{"hero.title": l.Hero.Title, "hero.subtitle": l.Hero.Subtitle, "hero.cta_text": l.Hero.CTAText,
+		"pinned.title": l.Pinned.Title, "recommendations.title": l.Recommendations.Title, "moments.title": l.Moments.Title}
+	for i, row := range l.CategoryRows {
+		texts[fmt.Sprintf("category_rows[%d].title", i)] = row.Title
+	}
+	for field, text := range texts {
+		for language, value := range text {
+			if !languageCode.MatchString(language) || len(language) > 10 {
+				return invalid("%s: %q is not a language code", field, language)
+			}
+			if utf8.RuneCountInString(value) > 200 {
+				return invalid("%s.%s: at most 200 characters", field, language)
+			}
+		}
+	}
+	if l.Hero.Enabled && len(l.Hero.Title) == 0 {
+		return invalid("hero.title: required when the hero is enabled")
+	}
+	for field, address := range map[string]string{"hero.image_url": l.Hero.ImageURL, "hero.cta_url": l.Hero.CTAURL} {
+		if address != "" && !validMediaURL(address) {
+			return invalid("%s: must be an http(s) URL or a path on the site", field)
+		}
+	}
+	if len(l.Hero.CTAText) > 0 && l.Hero.CTAURL == "" {
+		return invalid("hero.cta_url: required with cta_text")
+	}
+
+	for field, limit := range map[string]struct{ value, max int }{
+		"pinned.limit":          {l.Pinned.Limit, 12},
+		"recommendations.limit": {l.Recommendations.Limit, 12},
+		"moments.limit":         {l.Moments.Limit, 20},
+	} {
+		if limit.value < 1 || limit.value > limit.max {
+			return invalid("%s: must be between 1 and %d", field, limit.max)
+		}
+	}
+	if l.Recommendations.Strategy != "personalized" && l.Recommendations.Strategy != "popular" {
+		return invalid("recommendations.strategy: must be personalized or popular")
+	}
+	if len(l.CategoryRows) > maxCategoryRows {
+		return invalid("category_rows: at most %d rows", maxCategoryRows)
+	}
+	for i, row := range l.CategoryRows {
+		if row.CategoryID == 0 {
+			return invalid("category_rows[%d].category_id: required", i)
+		}
+		if row.Limit < 1 || row.Limit > 24 {
+			return invalid("category_rows[%d].limit: must be between 1 and 24", i)
+		}
+	}
+	return nil
+}
+
+// complete appends the sections missing from Order in the default order
+func (l *HomepageLayout) complete() {
+	for _, section := range homepageSections {
+		if !contains(l.Order, section) {
+			l.Order = append(l.Order, section)
+		}
+	}
+	if l.CategoryRows == nil {
+		l.CategoryRows = []HomepageCategoryRow{}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	globalHomepageLayoutService     *HomepageLayoutService
+	globalHomepageLayoutServiceOnce sync.Once
+)
+
+// GetGlobalHomepageLayoutService returns the global homepage layout service
+func GetGlobalHomepageLayoutService() *HomepageLayoutService {
+	globalHomepageLayoutServiceOnce.Do(func() {
+		globalHomepageLayoutService = NewHomepageLayoutService()
+	})
+	return globalHomepageLayoutService
+}
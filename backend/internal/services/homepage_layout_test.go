@@ -0,0 +1,87 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHomepageLayout(t *testing.T) {
+	newTestDB(t)
+	s := NewHomepageLayoutService()
+	ctx := context.Background()
+
+	layout, err := s.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*layout, DefaultHomepageLayout()) {
+		t.Errorf("Get() without settings = %+v, want the default layout", layout)
+	}
+
+	if _, err := s.Update(ctx, strings.NewReader(`{}`)); !errors.Is(err, ErrInvalidHomepageLayout) {
+		t.Errorf("Update() without settings = %v, want ErrInvalidHomepageLayout", err)
+	}
+	if err := database.DB.Create(&models.SiteSettings{SiteTitle: "Blog"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	category := models.Category{Name: "Go"}
+	if err := database.DB.Create(&category).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range []string{
+		`{"heroes": {}}`,
+		`{"order": ["hero", "sidebar"]}`,
+		`{"order": ["hero", "hero"]}`,
+		`{"hero": {"enabled": true}}`,
+		`{"hero": {"title": {"english!": "Hi"}}}`,
+		`{"hero": {"title": {"en": "Hi"}, "cta_text": {"en": "Read"}}}`,
+		`{"hero": {"image_url": "javascript:alert(1)"}}`,
+		`{"pinned": {"limit": 0}}`,
+		`{"moments": {"limit": 21}}`,
+		`{"recommendations": {"strategy": "random"}}`,
+		`{"category_rows": [{"limit": 6}]}`,
+		`{"category_rows": [{"category_id": 999, "limit": 6}]}`,
+		`{"category_rows": [{"category_id": 1, "limit": 0}]}`,
+		`{"order": "hero"}`,
+	} {
+		if _, err := s.Update(ctx, strings.NewReader(bad)); !errors.Is(err, ErrInvalidHomepageLayout) {
+			t.Errorf("Update(%s) = %v, want ErrInvalidHomepageLayout", bad, err)
+		}
+	}
+
+	saved, err := s.Update(ctx, strings.NewReader(fmt.Sprintf(`{
+		"order": ["moments", "hero"],
+		"hero": {"enabled": true, "title": {"en": "Welcome", "zh": "欢迎"}, "cta_text": {"en": "Start"}, "cta_url": "/about"},
+		"category_rows": [{"category_id": %d, "title": {"en": "Go"}, "limit": 6}],
+		"recommendations": {"enabled": true, "limit": 4, "strategy": "personalized"},
+		"moments": {"enabled": true, "limit": 10}
+	}`, category.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOrder := []string{HomepageMoments, HomepageHero, HomepagePinned, HomepageCategories, HomepageRecommendations}
+	if !reflect.DeepEqual(saved.Order, wantOrder) {
+		t.Errorf("Order = %v, want %v", saved.Order, wantOrder)
+	}
+	if saved.Pinned.Limit != 3 || !saved.Pinned.Enabled {
+		t.Errorf("Pinned = %+v, want the default pinned section", saved.Pinned)
+	}
+
+	layout, err = s.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(layout, saved) {
+		t.Errorf("Get() = %+v, want the saved layout %+v", layout, saved)
+	}
+	if layout.Hero.Title["zh"] != "欢迎" || layout.CategoryRows[0].CategoryID != category.ID || layout.Recommendations.Strategy != "personalized" {
+		t.Errorf("Get() = %+v, the saved fields are lost", layout)
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/imaging"
+	"blog-backend/internal/security"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	hotlinkWatermarkTTL = time.Hour
+	// maxHotlinkImage is the largest file watermarked; bigger ones are
+	// refused rather than decoded for another site
+	maxHotlinkImage = 20 << 20
+)
+
+var (
+	ErrHotlinkNoSecret    = errors.New("hotlink protection has no secret to sign links with")
+	ErrHotlinkUnsupported = errors.New("this file cannot be watermarked")
+)
+
+// HotlinkAction is what is done with a request for a media file
+type HotlinkAction int
+
+const (
+	HotlinkAllow HotlinkAction = iota
+	HotlinkWatermark
+	HotlinkRedirect
+	HotlinkDeny
+)
+
+// HotlinkService decides which requests for uploaded media come from pages
+// allowed to embed them, from the HOTLINK_* settings, and watermarks the
+// images served to the others
+type HotlinkService struct {
+	now           func() time.Time
+	enabled       bool
+	allowed       []string
+	policy        HotlinkAction
+	redirectURL   string
+	watermarkText string
+	blockEmpty    bool
+	secret        string
+	tokenTTL      time.Duration
+	watermarks    *cache.Namespace
+}
+
+// NewHotlinkService creates a hotlink service from the configuration
+func NewHotlinkService() *HotlinkService {
+	cfg := config.Get().Hotlink
+	allowed := make([]string, 0, len(cfg.AllowedDomains)+1)
+	for _, domain := range cfg.AllowedDomains {
+		allowed = append(allowed, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "*.")))
+	}
+	if public, err := url.Parse(config.Get().Server.PublicURL); err == nil && public.Hostname() != "" {
+		allowed = append(allowed, strings.ToLower(public.Hostname()))
+	}
+	policy := HotlinkWatermark
+	switch cfg.Policy {
+	case "redirect":
+		policy = HotlinkRedirect
+	case "deny":
+		policy = HotlinkDeny
+	}
+	return &HotlinkService{
+		now:           time.Now,
+		enabled:       cfg.Enabled,
+		allowed:       allowed,
+		policy:        policy,
+		redirectURL:   cfg.RedirectURL,
+		watermarkText: cfg.WatermarkText,
+		blockEmpty:    cfg.BlockEmptyReferer,
+		secret:        cfg.Secret,
+		tokenTTL:      time.Duration(cfg.TokenTTL),
+		watermarks:    cache.New("hotlink_watermarks", hotlinkWatermarkTTL),
+	}
+}
+
+// Enabled reports whether media is protected at all
+func (s *HotlinkService) Enabled() bool {
+	return s.enabled
+}
+
+// RedirectURL is where the redirect policy sends requests it refuses
+func (s *HotlinkService) RedirectURL() string {
+	return s.redirectURL
+}
+
+// Check returns what to do with a request for the file at name, the path
+// under the upload directory, given the request's Referer, the Host it was
+// sent to and its query, which may carry a signed token
+func (s *HotlinkService) Check(name, referer, host string, query url.Values) HotlinkAction {
+	if !s.enabled || s.validToken(name, query) {
+		return HotlinkAllow
+	}
+	if referer == "" {
+		if s.blockEmpty {
+			return s.policy
+		}
+		return HotlinkAllow
+	}
+	from, err := url.Parse(referer)
+	if err != nil || from.Hostname() == "" {
+		return s.policy
+	}
+	if s.allowedHost(strings.ToLower(from.Hostname()), host) {
+		return HotlinkAllow
+	}
+	return s.policy
+}
+
+// allowedHost reports whether pages on host may embed media: those of the
+// blog itself, whatever the port, and of the allowed domains and their
+// subdomains
+func (s *HotlinkService) allowedHost(host, requestHost string) bool {
+	if own, _, err := net.SplitHostPort(requestHost); err == nil {
+		requestHost = own
+	}
+	if strings.EqualFold(host, requestHost) {
+		return true
+	}
+	for _, domain := range s.allowed {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the query that lets the file at name be embedded anywhere
+// until it expires, ttl from now or the configured token TTL when ttl is
+// not positive
+func (s *HotlinkService) Sign(name string, ttl time.Duration) (url.Values, time.Time, error) {
+	if ttl <= 0 {
+		ttl = s.tokenTTL
+	}
+	expires := s.now().Add(ttl).Truncate(time.Second)
+	token, err := s.token(name, expires.Unix())
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return url.Values{"expires": {strconv.FormatInt(expires.Unix(), 10)}, "token": {token}}, expires, nil
+}
+
+func (s *HotlinkService) validToken(name string, query url.Values) bool {
+	given := query.Get("token")
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if given == "" || err != nil || s.now().Unix() >= expires {
+		return false
+	}
+	want, err := s.token(name, expires)
+	return err == nil && hmac.Equal([]byte(given), []byte(want))
+}
+
+// token signs a file and expiry time with the secret, resolved on every
+// use so rotations take effect
+func (s *HotlinkService) token(name string, expires int64) (string, error) {
+	if s.secret == "" {
+		return "", ErrHotlinkNoSecret
+	}
+	secret, err := security.ResolveSecret(s.secret)
+	if err != nil {
+		return "", fmt.Errorf("resolve hotlink secret: %w", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%d", name, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:18]), nil
+}
+
+// Watermark returns the image in content with the watermark text, or the
+// host the request was sent to when no text is configured, across it.
+// JPEG images stay JPEG and the others become PNG. Copies are cached by
+// version, the file's ETag.
+func (s *HotlinkService) Watermark(name, version, host string, content io.Reader) ([]byte, string, error) {
+	text := s.watermarkText
+	if text == "" {
+		text = host
+		if own, _, err := net.SplitHostPort(host); err == nil {
+			text = own
+		}
+	}
+	key := name + "|" + version + "|" + text
+	var cached WatermarkedImage
+	if s.watermarks.Get(key, &cached) {
+		return cached.Data, cached.ContentType, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, maxHotlinkImage+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxHotlinkImage {
+		return nil, "", ErrHotlinkUnsupported
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		// WebP, SVG and anything else the standard library cannot read
+		return nil, "", fmt.Errorf("%w: %v", ErrHotlinkUnsupported, err)
+	}
+	marked := imaging.Watermark(img, text)
+
+	var out bytes.Buffer
+	cached.ContentType = "image/png"
+	if format == "jpeg" {
+		cached.ContentType = "image/jpeg"
+		err = jpeg.Encode(&out, marked, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&out, marked)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	cached.Data = out.Bytes()
+	s.watermarks.Set(key, cached)
+	return cached.Data, cached.ContentType, nil
+}
+
+var (
+	globalHotlinkService     *HotlinkService
+	globalHotlinkServiceOnce sync.Once
+)
+
+// GetGlobalHotlinkService returns the global hotlink service
+func GetGlobalHotlinkService() *HotlinkService {
+	globalHotlinkServiceOnce.Do(func() {
+		globalHotlinkService = NewHotlinkService()
+	})
+	return globalHotlinkService
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestHotlinkService(now time.Time) *HotlinkService {
+	return &HotlinkService{
+		now:        func() time.Time { return now },
+		enabled:    true,
+		allowed:    []string{"friend.org", "blog.example.com"},
+		policy:     HotlinkWatermark,
+		secret:     "hotlink-test-secret",
+		tokenTTL:   time.Hour,
+		watermarks: cache.New("test_hotlink_watermarks", time.Minute),
+	}
+}
+
+func TestHotlinkCheck(t *testing.T) {
+	s := newTestHotlinkService(time.Now())
+	const name = "/images/photo.jpg"
+
+	tests := []struct {
+		referer, host string
+		want          HotlinkAction
+	}{
+		{"https://localhost:3000/posts/1", "localhost:8085", HotlinkAllow},
+		{"https://blog.example.com/posts/1", "api.example.com", HotlinkAllow},
+		{"https://friend.org/links", "blog.example.com", HotlinkAllow},
+		{"https://www.friend.org/links", "blog.example.com", HotlinkAllow},
+		{"https://notfriend.org/", "blog.example.com", HotlinkWatermark},
+		{"https://friend.org.thief.net/", "blog.example.com", HotlinkWatermark},
+		{"not a url", "blog.example.com", HotlinkWatermark},
+		{"", "blog.example.com", HotlinkAllow},
+	}
+	for _, tt := range tests {
+		if got := s.Check(name, tt.referer, tt.host, nil); got != tt.want {
+			t.Errorf("Check(%q, host %q) = %v, want %v", tt.referer, tt.host, got, tt.want)
+		}
+	}
+
+	s.blockEmpty, s.policy = true, HotlinkDeny
+	if got := s.Check(name, "", "blog.example.com", nil); got != HotlinkDeny {
+		t.Errorf("request without referer = %v, want deny", got)
+	}
+	s.enabled = false
+	if got := s.Check(name, "https://thief.net/", "blog.example.com", nil); got != HotlinkAllow {
+		t.Errorf("disabled protection = %v, want allow", got)
+	}
+}
+
+func TestHotlinkSignedLinks(t *testing.T) {
+	now := time.Now()
+	s := newTestHotlinkService(now)
+	s.policy = HotlinkRedirect
+	const name = "/images/photo.jpg"
+
+	query, expires, err := s.Sign(name, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := expires.Sub(now); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("link expires in %v, want the token TTL", d)
+	}
+	if got := s.Check(name, "https://thief.net/", "blog.example.com", query); got != HotlinkAllow {
+		t.Errorf("signed link = %v, want allow", got)
+	}
+	if got := s.Check("/images/other.jpg", "https://thief.net/", "blog.example.com", query); got != HotlinkRedirect {
+		t.Errorf("link signed for another file = %v, want redirect", got)
+	}
+	tampered := url.Values{"expires": {query.Get("expires") + "0"}, "token": {query.Get("token")}}
+	if got := s.Check(name, "https://thief.net/", "blog.example.com", tampered); got != HotlinkRedirect {
+		t.Errorf("extended link = %v, want redirect", got)
+	}
+
+	s.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if got := s.Check(name, "https://thief.net/", "blog.example.com", query); got != HotlinkRedirect {
+		t.Errorf("expired link = %v, want redirect", got)
+	}
+
+	s.secret = ""
+	if _, _, err := s.Sign(name, time.Hour); !errors.Is(err, ErrHotlinkNoSecret) {
+		t.Errorf("Sign() without a secret: %v", err)
+	}
+}
+
+func TestHotlinkWatermark(t *testing.T) {
+	s := newTestHotlinkService(time.Now())
+	src := image.NewNRGBA(image.Rect(0, 0, 200, 120))
+	for y := 0; y < 120; y++ {
+		for x := 0; x < 200; x++ {
+			src.Set(x, y, color.NRGBA{R: 40, G: 120, B: 200, A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, src); err != nil {
+		t.Fatal(err)
+	}
+
+	data, contentType, err := s.Watermark("/images/a.png", "v1", "blog.example.com:8085", bytes.NewReader(encoded.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "image/png" {
+		t.Errorf("content type = %q", contentType)
+	}
+	marked, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marked.Bounds() != src.Bounds() {
+		t.Errorf("watermarked size = %v, want %v", marked.Bounds(), src.Bounds())
+	}
+	changed := 0
+	for y := 0; y < 120; y++ {
+		for x := 0; x < 200; x++ {
+			r1, g1, b1, _ := marked.At(x, y).RGBA()
+			r2, g2, b2, _ := src.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 {
+				changed++
+			}
+		}
+	}
+	if changed == 0 || changed == 200*120 {
+		t.Errorf("%d of %d pixels changed, want a watermark over part of the image", changed, 200*120)
+	}
+
+	// The cached copy is served without reading the file again
+	again, _, err := s.Watermark("/images/a.png", "v1", "blog.example.com:8085", strings.NewReader(""))
+	if err != nil || !bytes.Equal(again, data) {
+		t.Errorf("cached watermark = %d bytes, %v", len(again), err)
+	}
+
+	if _, _, err := s.Watermark("/images/a.svg", "v1", "blog.example.com", strings.NewReader("<svg/>")); !errors.Is(err, ErrHotlinkUnsupported) {
+		t.Errorf("Watermark(svg) error = %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// markdownSpaces matches the runs of white space HTML collapses
+var markdownSpaces = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+// htmlBlockElements are the elements that start a Markdown block of their own
+var htmlBlockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Header: true, atom.Footer: true,
+	atom.Main: true, atom.Aside: true, atom.Nav: true, atom.Figure: true, atom.Figcaption: true, atom.Details: true,
+	atom.Summary: true, atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Address: true, atom.Center: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Blockquote: true, atom.Pre: true, atom.Hr: true, atom.Table: true,
+	atom.Iframe: true, atom.Video: true, atom.Audio: true, atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Form: true,
+}
+
+// htmlToMarkdown converts HTML, such as a post read from a feed, to
+// Markdown. Relative links and images are resolved against base, the
+// post's address.
+func htmlToMarkdown(fragment string, base *url.URL) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+	if err != nil {
+		return strings.TrimSpace(fragment)
+	}
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	for _, node := range nodes {
+		root.AppendChild(node)
+	}
+	return markdownConverter{base: base}.blocks(root)
+}
+
+// markdownConverter writes HTML as Markdown
+type markdownConverter struct {
+	base *url.URL
+}
+
+// blocks converts the children of a node to Markdown blocks separated by
+// blank lines. In a list item, a nested list follows the item's text on
+// the next line, keeping the list tight.
+func (m markdownConverter) blocks(parent *html.Node) string {
+	var b, line strings.Builder
+	add := func(block string, nested bool) {
+		if block == "" {
+			return
+		}
+		if b.Len() > 0 {
+			if nested && parent.DataAtom == atom.Li {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		b.WriteString(block)
+	}
+	flush := func() {
+		var lines []string
+		for _, text := range strings.Split(line.String(), "\n") {
+			if text = strings.TrimSpace(text); text != "" {
+				lines = append(lines, text)
+			}
+		}
+		add(strings.Join(lines, "\\\n"), false)
+		line.Reset()
+	}
+	for node := parent.FirstChild; node != nil; node = node.NextSibling {
+		if node.Type == html.ElementNode && htmlBlockElements[node.DataAtom] {
+			flush()
+			add(m.block(node), node.DataAtom == atom.Ul || node.DataAtom == atom.Ol)
+			continue
+		}
+		line.WriteString(m.inline(node))
+	}
+	flush()
+	return b.String()
+}
+
+// block converts a block element
+func (m markdownConverter) block(node *html.Node) string {
+	switch node.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := strings.Join(strings.Fields(m.children(node)), " ")
+		if text == "" {
+			return ""
+		}
+		return strings.Repeat("#", int(node.Data[1]-'0')) + " " + text
+	case atom.Ul, atom.Ol:
+		var items []string
+		number := 1
+		for item := node.FirstChild; item != nil; item = item.NextSibling {
+			if item.DataAtom != atom.Li {
+				continue
+			}
+			marker := "-"
+			if node.DataAtom == atom.Ol {
+				marker = fmt.Sprintf("%d.", number)
+				number++
+			}
+			lines := strings.Split(m.blocks(item), "\n")
+			for i := 1; i < len(lines); i++ {
+				if lines[i] != "" {
+					lines[i] = strings.Repeat(" ", len(marker)+1) + lines[i]
+				}
+			}
+			items = append(items, marker+" "+strings.Join(lines, "\n"))
+		}
+		return strings.Join(items, "\n")
+	case atom.Blockquote:
+		text := m.blocks(node)
+		if text == "" {
+			return ""
+		}
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return strings.Join(lines, "\n")
+	case atom.Pre:
+		language := ""
+		for _, code := range []*html.Node{node, node.FirstChild} {
+			if code == nil {
+				continue
+			}
+			for _, class := range strings.Fields(htmlAttr(code, "class")) {
+				if lang, ok := strings.CutPrefix(class, "language-"); ok {
+					language = lang
+				}
+			}
+		}
+		fence := "```"
+		code := strings.Trim(htmlText(node), "\n")
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return fence + language + "\n" + code + "\n" + fence
+	case atom.Hr:
+		return "---"
+	case atom.Table:
+		return m.table(node)
+	case atom.Iframe, atom.Video, atom.Audio:
+		// Embedded media becomes a link on its own line
+		if src := m.resolve(htmlAttr(node, "src")); src != "" {
+			return "<" + src + ">"
+		}
+		return ""
+	case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Form:
+		return ""
+	default:
+		return m.blocks(node)
+	}
+}
+
+// table converts a table to a Markdown table, its first row the header
+func (m markdownConverter) table(node *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.DataAtom != atom.Tr {
+				walk(child)
+				continue
+			}
+			var cells []string
+			for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+					text := strings.Join(strings.Fields(m.children(cell)), " ")
+					cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+				}
+			}
+			if len(cells) > 0 {
+				rows = append(rows, cells)
+			}
+		}
+	}
+	walk(node)
+	if len(rows) == 0 {
+		return ""
+	}
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// children converts the inline content of a node
+func (m markdownConverter) children(node *html.Node) string {
+	var b strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(m.inline(child))
+	}
+	return b.String()
+}
+
+// inline converts an inline node. Line breaks come out as "\n", which the
+// enclosing block turns into Markdown hard breaks.
+func (m markdownConverter) inline(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return markdownSpaces.ReplaceAllString(node.Data, " ")
+	}
+	if node.Type != html.ElementNode {
+		return ""
+	}
+	wrap := func(marker string) string {
+		text := m.children(node)
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			return text
+		}
+		// Keep the surrounding spaces outside the markers
+		lead := text[:len(text)-len(strings.TrimLeft(text, " "))]
+		trail := text[len(strings.TrimRight(text, " ")):]
+		return lead + marker + trimmed + marker + trail
+	}
+	switch node.DataAtom {
+	case atom.Strong, atom.B:
+		return wrap("**")
+	case atom.Em, atom.I, atom.Cite:
+		return wrap("*")
+	case atom.Del, atom.S, atom.Strike:
+		return wrap("~~")
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		code := markdownSpaces.ReplaceAllString(htmlText(node), " ")
+		if code == "" {
+			return ""
+		}
+		fence := "`"
+		for strings.Contains(code, fence) {
+			fence += "`"
+		}
+		return fence + code + fence
+	case atom.A:
+		text := strings.TrimSpace(m.children(node))
+		href := m.resolve(htmlAttr(node, "href"))
+		if href == "" || text == "" {
+			return text
+		}
+		return "[" + text + "](" + markdownDestination(href) + ")"
+	case atom.Img:
+		src := m.resolve(htmlAttr(node, "src"))
+		if src == "" {
+			return ""
+		}
+		alt := strings.NewReplacer("[", "", "]", "").Replace(strings.Join(strings.Fields(htmlAttr(node, "alt")), " "))
+		return "![" + alt + "](" + markdownDestination(src) + ")"
+	case atom.Br:
+		return "\n"
+	case atom.Script, atom.Style, atom.Noscript, atom.Template:
+		return ""
+	default:
+		if htmlBlockElements[node.DataAtom] {
+			// A block inside inline content, such as a div in a link
+			return " " + strings.ReplaceAll(m.blocks(node), "\n\n", " ") + " "
+		}
+		return m.children(node)
+	}
+}
+
+// resolve resolves a link against the post's address, dropping anything
+// but http(s) links
+func (m markdownConverter) resolve(link string) string {
+	if strings.HasPrefix(strings.TrimSpace(link), "#") {
+		return ""
+	}
+	return resolveFeedURL(m.base, link)
+}
+
+// markdownDestination escapes a link for a Markdown link destination
+func markdownDestination(link string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(link)
+}
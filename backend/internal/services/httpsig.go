@@ -0,0 +1,142 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTP Signatures (draft-cavage-http-signatures-12) as used between
+// ActivityPub servers: rsa-sha256 over the request target, host, date and,
+// for requests with a body, its SHA-256 digest.
+
+// ErrInvalidSignature is returned for requests whose signature is missing,
+// malformed, stale or does not verify
+var ErrInvalidSignature = errors.New("invalid HTTP signature")
+
+// signatureMaxSkew is how far the Date of a signed request may be from now
+const signatureMaxSkew = 12 * time.Hour
+
+// signRequest adds Date, Digest (when body is not nil) and Signature
+// headers to req
+func signRequest(req *http.Request, keyID string, key *rsa.PrivateKey, body []byte, now time.Time) error {
+	req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		req.Header.Set("Digest", bodyDigest(body))
+		headers = append(headers, "digest")
+	}
+
+	hash := sha256.Sum256([]byte(signingString(req, headers)))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// verifyRequest checks the signature of req and returns the keyId it was
+// signed with. publicKey looks up the key for a keyId, usually by fetching
+// the remote actor.
+func verifyRequest(req *http.Request, body []byte, now time.Time, publicKey func(keyID string) (*rsa.PublicKey, error)) (string, error) {
+	params := parseSignatureHeader(req.Header.Get("Signature"))
+	keyID, headerList, encoded := params["keyId"], params["headers"], params["signature"]
+	if keyID == "" || encoded == "" {
+		return "", fmt.Errorf("%w: missing keyId or signature", ErrInvalidSignature)
+	}
+	if algorithm := params["algorithm"]; algorithm != "" && algorithm != "rsa-sha256" && algorithm != "hs2019" {
+		return "", fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, algorithm)
+	}
+	if headerList == "" {
+		headerList = "date"
+	}
+	headers := strings.Fields(strings.ToLower(headerList))
+	signed := make(map[string]bool, len(headers))
+	for _, header := range headers {
+		signed[header] = true
+	}
+	if !signed["(request-target)"] || !signed["date"] || (body != nil && !signed["digest"]) {
+		return "", fmt.Errorf("%w: (request-target), date and digest must be signed", ErrInvalidSignature)
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil || date.Sub(now) > signatureMaxSkew || now.Sub(date) > signatureMaxSkew {
+		return "", fmt.Errorf("%w: date is missing or too far from now", ErrInvalidSignature)
+	}
+	if body != nil && req.Header.Get("Digest") != bodyDigest(body) {
+		return "", fmt.Errorf("%w: digest does not match the body", ErrInvalidSignature)
+	}
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("%w: signature is not base64", ErrInvalidSignature)
+	}
+
+	key, err := publicKey(keyID)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	hash := sha256.Sum256([]byte(signingString(req, headers)))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature); err != nil {
+		return "", fmt.Errorf("%w: signature does not verify", ErrInvalidSignature)
+	}
+	return keyID, nil
+}
+
+func signingString(req *http.Request, headers []string) string {
+	lines := make([]string, len(headers))
+	for i, header := range headers {
+		var value string
+		switch header {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = req.Host
+		default:
+			value = strings.TrimSpace(req.Header.Get(header))
+		}
+		lines[i] = header + ": " + value
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parseSignatureHeader(header string) map[string]string {
+	params := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		params[name] = strings.Trim(value, `"`)
+	}
+	return params
+}
+
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// parsePublicKeyPEM reads the RSA public key an actor publishes
+func parsePublicKeyPEM(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	if parsed, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		if key, ok := parsed.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
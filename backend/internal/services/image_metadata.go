@@ -0,0 +1,440 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kinds of image metadata
+const (
+	MetadataGPS          = "gps"
+	MetadataSerialNumber = "serial_number"
+	MetadataOwner        = "owner"
+	MetadataCamera       = "camera"
+	MetadataCaptureTime  = "capture_time"
+	MetadataSoftware     = "software"
+	MetadataMakerNote    = "maker_note"
+	MetadataComment      = "comment"
+	MetadataXMP          = "xmp"
+	MetadataIPTC         = "iptc"
+	MetadataOrientation  = "orientation"
+	MetadataColorProfile = "color_profile"
+)
+
+// metadataStatImages is the stat kind counting every image checked
+const metadataStatImages = "images"
+
+// imageMetadataKinds lists the kinds in the order reports give them
+var imageMetadataKinds = []string{
+	MetadataGPS, MetadataSerialNumber, MetadataOwner, MetadataCamera, MetadataCaptureTime, MetadataSoftware,
+	MetadataMakerNote, MetadataComment, MetadataXMP, MetadataIPTC, MetadataOrientation, MetadataColorProfile,
+}
+
+// sensitiveMetadata are the kinds that can identify the photographer or
+// where they were
+var sensitiveMetadata = map[string]bool{MetadataGPS: true, MetadataSerialNumber: true, MetadataOwner: true}
+
+// ImageMetadata is the metadata found in a JPEG, PNG or GIF image
+type ImageMetadata struct {
+	mimeType    string
+	found       map[string]bool
+	orientation uint16
+	profile     [][]byte // JPEG APP2 segments or the PNG iCCP chunk, whole
+}
+
+// ScanImageMetadata reads the metadata of an image without decoding it.
+// Malformed metadata ends the scan; what was read until then is kept.
+func ScanImageMetadata(content []byte, mimeType string) *ImageMetadata {
+	m := &ImageMetadata{mimeType: mimeType, found: map[string]bool{}}
+	switch mimeType {
+	case "image/jpeg", "image/jpg":
+		m.scanJPEG(content)
+	case "image/png":
+		m.scanPNG(content)
+	case "image/gif":
+		m.scanGIF(content)
+	}
+	return m
+}
+
+// Report lists the metadata found, of which the kinds kept were restored
+func (m *ImageMetadata) Report(kept []string) *models.ImageMetadataReport {
+	report := &models.ImageMetadataReport{Found: []string{}, Sensitive: []string{}, Kept: kept}
+	if report.Kept == nil {
+		report.Kept = []string{}
+	}
+	for _, kind := range imageMetadataKinds {
+		if m.found[kind] {
+			report.Found = append(report.Found, kind)
+			if sensitiveMetadata[kind] {
+				report.Sensitive = append(report.Sensitive, kind)
+			}
+		}
+	}
+	return report
+}
+
+// Orientation returns the EXIF orientation of the image, 1 to 8, or 0
+// when it has none
+func (m *ImageMetadata) Orientation() uint16 {
+	if m.orientation > 8 {
+		return 0
+	}
+	return m.orientation
+}
+
+// Restore puts the color profile back into the re-encoded image, clean,
+// returning the image and the kinds it restored. Only JPEG and PNG images
+// carry one. The orientation is not restored: re-encoded images are turned
+// upright with OrientImage instead, which every viewer shows the same way.
+func (m *ImageMetadata) Restore(clean []byte) ([]byte, []string) {
+	if len(m.profile) == 0 {
+		return clean, nil
+	}
+	var out bytes.Buffer
+	switch {
+	case (m.mimeType == "image/jpeg" || m.mimeType == "image/jpg") && bytes.HasPrefix(clean, []byte{0xFF, 0xD8}):
+		out.Write(clean[:2])
+		for _, segment := range m.profile {
+			out.Write(segment)
+		}
+		out.Write(clean[2:])
+	case m.mimeType == "image/png" && len(clean) >= pngHeaderEnd:
+		// The profile must come before the image data, so it follows IHDR
+		out.Write(clean[:pngHeaderEnd])
+		for _, chunk := range m.profile {
+			out.Write(chunk)
+		}
+		out.Write(clean[pngHeaderEnd:])
+	default:
+		return clean, nil
+	}
+	return out.Bytes(), []string{MetadataColorProfile}
+}
+
+// OrientImage turns an image the way its EXIF orientation says it is
+// shown: mirrored for 2 and 4, rotated for 3, 6 and 8, and both for 5 and 7
+func OrientImage(img image.Image, orientation uint16) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := x, y
+			switch orientation {
+			case 2:
+				sx = w - 1 - x
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sy = h - 1 - y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+func (m *ImageMetadata) scanJPEG(data []byte) {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return
+	}
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return
+		}
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF:
+			pos++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD8):
+			pos += 2
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			// The image data starts; metadata comes before it
+			return
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return
+		}
+		payload := data[pos+4 : pos+2+length]
+		switch marker {
+		case 0xE1:
+			if bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+				m.scanTIFF(payload[6:])
+			} else if bytes.HasPrefix(payload, []byte("http://ns.adobe.com/xap/1.0/")) {
+				m.found[MetadataXMP] = true
+			}
+		case 0xE2:
+			if bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00")) {
+				m.found[MetadataColorProfile] = true
+				m.profile = append(m.profile, append([]byte(nil), data[pos:pos+2+length]...))
+			}
+		case 0xED:
+			m.found[MetadataIPTC] = true
+		case 0xFE:
+			m.found[MetadataComment] = true
+		}
+		pos += 2 + length
+	}
+}
+
+// pngHeaderEnd is where the IHDR chunk of a PNG image ends
+const pngHeaderEnd = 8 + 4 + 4 + 13 + 4
+
+func (m *ImageMetadata) scanPNG(data []byte) {
+	if !bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) {
+		return
+	}
+	for pos := 8; pos+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		if length < 0 || pos+12+length > len(data) {
+			return
+		}
+		chunk := string(data[pos+4 : pos+8])
+		body := data[pos+8 : pos+8+length]
+		switch chunk {
+		case "eXIf":
+			m.scanTIFF(body)
+		case "tEXt", "zTXt", "iTXt":
+			keyword, _, _ := bytes.Cut(body, []byte{0})
+			if chunk == "iTXt" && string(keyword) == "XML:com.adobe.xmp" {
+				m.found[MetadataXMP] = true
+			} else {
+				m.found[MetadataComment] = true
+			}
+		case "iCCP":
+			m.found[MetadataColorProfile] = true
+			m.profile = append(m.profile, append([]byte(nil), data[pos:pos+12+length]...))
+		case "IDAT", "IEND":
+			return
+		}
+		pos += 12 + length
+	}
+}
+
+func (m *ImageMetadata) scanGIF(data []byte) {
+	if len(data) < 13 || !bytes.HasPrefix(data, []byte("GIF8")) {
+		return
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&0x07 + 1)
+	}
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21:
+			if pos+2 > len(data) {
+				return
+			}
+			label := data[pos+1]
+			pos += 2
+			if label == 0xFE {
+				m.found[MetadataComment] = true
+			} else if label == 0xFF && pos+12 <= len(data) && string(data[pos+1:pos+12]) == "XMP DataXMP" {
+				m.found[MetadataXMP] = true
+			}
+			pos = skipGIFSubBlocks(data, pos)
+		case 0x2C:
+			if pos+10 > len(data) {
+				return
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			// The LZW code size precedes the image data
+			pos = skipGIFSubBlocks(data, pos+1)
+		default:
+			return
+		}
+	}
+}
+
+func skipGIFSubBlocks(data []byte, pos int) int {
+	for pos < len(data) {
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos
+		}
+		pos += size
+	}
+	return len(data)
+}
+
+// EXIF tags by the kind of metadata they hold. IFD0 and the Exif IFD share
+// this table; the GPS IFD is told apart by its pointer.
+var exifTagKinds = map[uint16]string{
+	0x010F: MetadataCamera,       // Make
+	0x0110: MetadataCamera,       // Model
+	0x0131: MetadataSoftware,     // Software
+	0x0132: MetadataCaptureTime,  // DateTime
+	0x013B: MetadataOwner,        // Artist
+	0x9C9C: MetadataComment,      // XPComment
+	0x9C9D: MetadataOwner,        // XPAuthor
+	0x9003: MetadataCaptureTime,  // DateTimeOriginal
+	0x9004: MetadataCaptureTime,  // DateTimeDigitized
+	0x927C: MetadataMakerNote,    // MakerNote
+	0x9286: MetadataComment,      // UserComment
+	0xA430: MetadataOwner,        // CameraOwnerName
+	0xA431: MetadataSerialNumber, // BodySerialNumber
+	0xA433: MetadataCamera,       // LensMake
+	0xA434: MetadataCamera,       // LensModel
+	0xA435: MetadataSerialNumber, // LensSerialNumber
+}
+
+const (
+	exifTagOrientation = 0x0112
+	exifTagExifIFD     = 0x8769
+	exifTagGPSIFD      = 0x8825
+)
+
+// scanTIFF reads the tags of the TIFF structure that holds EXIF data
+func (m *ImageMetadata) scanTIFF(data []byte) {
+	if len(data) < 8 {
+		return
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return
+	}
+	if order.Uint16(data[2:]) != 42 {
+		return
+	}
+	seen := map[uint32]bool{}
+	var walk func(offset uint32, gps bool)
+	walk = func(offset uint32, gps bool) {
+		if seen[offset] || int64(offset)+2 > int64(len(data)) {
+			return
+		}
+		seen[offset] = true
+		count := int(order.Uint16(data[offset:]))
+		for i := 0; i < count; i++ {
+			entry := int(offset) + 2 + i*12
+			if entry+12 > len(data) {
+				return
+			}
+			tag := order.Uint16(data[entry:])
+			value := data[entry+8 : entry+12]
+			switch {
+			case gps:
+				// Tag 0 is only the version of the GPS tags
+				if tag != 0 {
+					m.found[MetadataGPS] = true
+				}
+			case tag == exifTagExifIFD:
+				walk(order.Uint32(value), false)
+			case tag == exifTagGPSIFD:
+				walk(order.Uint32(value), true)
+			case tag == exifTagOrientation:
+				m.found[MetadataOrientation] = true
+				m.orientation = order.Uint16(value)
+			default:
+				if kind, ok := exifTagKinds[tag]; ok {
+					m.found[kind] = true
+				}
+			}
+		}
+	}
+	walk(order.Uint32(data[4:]), false)
+}
+
+// ImageMetadataStats counts the metadata found in a site's uploaded images,
+// so admins can see how often photos carried GPS positions and the like
+type ImageMetadataStats struct {
+	db func() *gorm.DB
+}
+
+// ImageMetadataTotals are the counts of a site: how many images were
+// checked, and how many of them carried each kind of metadata
+type ImageMetadataTotals struct {
+	Images int64            `json:"images"`
+	Found  map[string]int64 `json:"found"`
+}
+
+// NewImageMetadataStats creates the image metadata stats
+func NewImageMetadataStats() *ImageMetadataStats {
+	return &ImageMetadataStats{db: func() *gorm.DB { return database.DB }}
+}
+
+// Record counts an uploaded image and the metadata it carried
+func (s *ImageMetadataStats) Record(ctx context.Context, report *models.ImageMetadataReport) error {
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, kind := range append([]string{metadataStatImages}, report.Found...) {
+			stat := models.MediaMetadataStat{Kind: kind, Images: 1}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "site_id"}, {Name: "kind"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"images": gorm.Expr("media_metadata_stats.images + 1")}),
+			}).Create(&stat).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Totals returns the counts of the site in ctx
+func (s *ImageMetadataStats) Totals(ctx context.Context) (*ImageMetadataTotals, error) {
+	var stats []models.MediaMetadataStat
+	if err := s.db().WithContext(ctx).Find(&stats).Error; err != nil {
+		return nil, err
+	}
+	totals := &ImageMetadataTotals{Found: map[string]int64{}}
+	for _, stat := range stats {
+		if stat.Kind == metadataStatImages {
+			totals.Images = stat.Images
+		} else {
+			totals.Found[stat.Kind] = stat.Images
+		}
+	}
+	return totals, nil
+}
+
+var (
+	globalImageMetadataStats     *ImageMetadataStats
+	globalImageMetadataStatsOnce sync.Once
+)
+
+// GetGlobalImageMetadataStats returns the global image metadata stats
+func GetGlobalImageMetadataStats() *ImageMetadataStats {
+	globalImageMetadataStatsOnce.Do(func() {
+		globalImageMetadataStats = NewImageMetadataStats()
+	})
+	return globalImageMetadataStats
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+)
+
+// testEXIF builds little-endian EXIF data with a camera make, an
+// orientation, a body serial number and a GPS position
+func testEXIF() []byte {
+	type entry struct {
+		tag, kind uint16
+		count     uint32
+		value     [4]byte
+	}
+	ifd := func(entries []entry) []byte {
+		out := binary.LittleEndian.AppendUint16(nil, uint16(len(entries)))
+		for _, e := range entries {
+			out = binary.LittleEndian.AppendUint16(out, e.tag)
+			out = binary.LittleEndian.AppendUint16(out, e.kind)
+			out = binary.LittleEndian.AppendUint32(out, e.count)
+			out = append(out, e.value[:]...)
+		}
+		return binary.LittleEndian.AppendUint32(out, 0)
+	}
+	offset := func(n uint32) [4]byte {
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], n)
+		return b
+	}
+	// IFD0 at 8 has 4 entries (54 bytes), the Exif IFD follows at 62 with
+	// one (18 bytes) and the GPS IFD at 80
+	tiff := []byte{'I', 'I', 42, 0, 8, 0, 0, 0}
+	tiff = append(tiff, ifd([]entry{
+		{0x010F, 2, 4, [4]byte{'A', 'c', 'm', 0}},
+		{exifTagOrientation, 3, 1, [4]byte{6, 0, 0, 0}},
+		{exifTagExifIFD, 4, 1, offset(62)},
+		{exifTagGPSIFD, 4, 1, offset(80)},
+	})...)
+	tiff = append(tiff, ifd([]entry{{0xA431, 2, 4, [4]byte{'4', '2', '7', 0}}})...)
+	return append(tiff, ifd([]entry{{0, 1, 4, [4]byte{2, 3, 0, 0}}, {2, 5, 3, offset(0)}})...)
+}
+
+func TestImageMetadata(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	var clean bytes.Buffer
+	if err := jpeg.Encode(&clean, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// A photo with EXIF, a color profile and a comment
+	var photo bytes.Buffer
+	photo.Write(clean.Bytes()[:2])
+	writeJPEGSegment(&photo, 0xE1, append([]byte("Exif\x00\x00"), testEXIF()...))
+	writeJPEGSegment(&photo, 0xE2, []byte("ICC_PROFILE\x00\x01\x01profile"))
+	writeJPEGSegment(&photo, 0xFE, []byte("shot at home"))
+	photo.Write(clean.Bytes()[2:])
+
+	metadata := ScanImageMetadata(photo.Bytes(), "image/jpeg")
+	report := metadata.Report(nil)
+	want := &models.ImageMetadataReport{
+		Found:     []string{MetadataGPS, MetadataSerialNumber, MetadataCamera, MetadataComment, MetadataOrientation, MetadataColorProfile},
+		Sensitive: []string{MetadataGPS, MetadataSerialNumber},
+		Kept:      []string{},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("Report() = %+v, want %+v", report, want)
+	}
+
+	// Only the color profile is put back
+	out, kept := metadata.Restore(clean.Bytes())
+	if !reflect.DeepEqual(kept, []string{MetadataColorProfile}) {
+		t.Errorf("kept = %v", kept)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatalf("restored JPEG does not decode: %v", err)
+	}
+	restored := ScanImageMetadata(out, "image/jpeg")
+	if found := restored.Report(nil).Found; !reflect.DeepEqual(found, []string{MetadataColorProfile}) || restored.Orientation() != 0 {
+		t.Errorf("restored JPEG has %v, orientation %d", found, restored.Orientation())
+	}
+	if out, kept := ScanImageMetadata(clean.Bytes(), "image/jpeg").Restore(clean.Bytes()); !bytes.Equal(out, clean.Bytes()) || kept != nil {
+		t.Errorf("Restore() of an image without a profile changed it")
+	}
+
+	// PNG text chunks are comments, and the color profile is kept whole
+	var pngClean bytes.Buffer
+	if err := png.Encode(&pngClean, img); err != nil {
+		t.Fatal(err)
+	}
+	var pngPhoto bytes.Buffer
+	pngPhoto.Write(pngClean.Bytes()[:pngHeaderEnd])
+	writePNGChunk(&pngPhoto, "iCCP", []byte("sRGB\x00\x00profile"))
+	writePNGChunk(&pngPhoto, "tEXt", []byte("Comment\x00hello"))
+	writePNGChunk(&pngPhoto, "eXIf", testEXIF())
+	pngPhoto.Write(pngClean.Bytes()[pngHeaderEnd:])
+	metadata = ScanImageMetadata(pngPhoto.Bytes(), "image/png")
+	if found := metadata.Report(nil).Found; len(found) != 6 {
+		t.Errorf("PNG metadata = %v", found)
+	}
+	out, _ = metadata.Restore(pngClean.Bytes())
+	if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+		t.Fatalf("restored PNG does not decode: %v", err)
+	}
+	if found := ScanImageMetadata(out, "image/png").Report(nil).Found; !reflect.DeepEqual(found, []string{MetadataColorProfile}) {
+		t.Errorf("restored PNG has %v", found)
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, red)
+	img.Set(1, 0, blue)
+
+	for _, tt := range []struct {
+		orientation   uint16
+		width, height int
+		first         color.RGBA // top left pixel
+	}{
+		{1, 2, 1, red},
+		{2, 2, 1, blue},
+		{3, 2, 1, blue},
+		{4, 2, 1, red},
+		{5, 1, 2, red},
+		{6, 1, 2, red},
+		{7, 1, 2, blue},
+		{8, 1, 2, blue},
+	} {
+		oriented := OrientImage(img, tt.orientation)
+		bounds := oriented.Bounds()
+		if bounds.Dx() != tt.width || bounds.Dy() != tt.height || oriented.At(0, 0) != tt.first {
+			t.Errorf("orientation %d: %dx%d starting with %v, want %dx%d starting with %v",
+				tt.orientation, bounds.Dx(), bounds.Dy(), oriented.At(0, 0), tt.width, tt.height, tt.first)
+		}
+	}
+}
+
+func TestImageMetadataStats(t *testing.T) {
+	newTestDB(t)
+	if err := database.DB.Use(database.SiteScope{}); err != nil {
+		t.Fatal(err)
+	}
+	s := NewImageMetadataStats()
+	ctx := database.WithSite(context.Background(), models.DefaultSiteID)
+	for _, found := range [][]string{{MetadataGPS, MetadataCamera}, {MetadataCamera}, {}} {
+		if err := s.Record(ctx, &models.ImageMetadataReport{Found: found}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	totals, err := s.Totals(ctx)
+	if err != nil || totals.Images != 3 || totals.Found[MetadataGPS] != 1 || totals.Found[MetadataCamera] != 2 {
+		t.Errorf("Totals() = %+v, %v", totals, err)
+	}
+	if other, _ := s.Totals(database.WithSite(context.Background(), 2)); other.Images != 0 {
+		t.Errorf("another site counted %d images", other.Images)
+	}
+}
+
+func writeJPEGSegment(out *bytes.Buffer, marker byte, payload []byte) {
+	out.Write([]byte{0xFF, marker})
+	out.Write(binary.BigEndian.AppendUint16(nil, uint16(len(payload)+2)))
+	out.Write(payload)
+}
+
+func writePNGChunk(out *bytes.Buffer, chunk string, body []byte) {
+	out.Write(binary.BigEndian.AppendUint32(nil, uint32(len(body))))
+	typed := append([]byte(chunk), body...)
+	out.Write(typed)
+	out.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(typed)))
+}
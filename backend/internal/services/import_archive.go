@@ -0,0 +1,290 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Importers that take zip files open them with OpenImportArchive, which
+// checks every entry before anything is read: names must stay inside the
+// archive, only regular files with an allowed extension are kept, and the
+// number of entries, their sizes and how far they inflate are bounded. Zip
+// files inside the archive are checked the same way, up to a depth.
+var ErrUnsafeArchive = errors.New("unsafe archive")
+
+// ArchiveLimits bound what an import archive may hold
+type ArchiveLimits struct {
+	// MaxEntries counts the files of nested archives too
+	MaxEntries int
+	// MaxFileSize and MaxTotalSize are uncompressed sizes
+	MaxFileSize  int64
+	MaxTotalSize int64
+	// MaxRatio is how many times larger than compressed a file over 1 MB
+	// may be
+	MaxRatio uint64
+	// MaxDepth is how many levels of zip files inside the archive are
+	// accepted; 0 rejects them
+	MaxDepth int
+	// Extensions are the lowercase extensions of the files accepted, with
+	// the dot
+	Extensions []string
+}
+
+// DefaultImportArchiveLimits suit exports of other blogs: their posts,
+// data files and media
+var DefaultImportArchiveLimits = ArchiveLimits{
+	MaxEntries:   10000,
+	MaxFileSize:  100 << 20,
+	MaxTotalSize: 1 << 30,
+	MaxRatio:     100,
+	MaxDepth:     1,
+	Extensions: []string{
+		".xml", ".md", ".markdown", ".html", ".htm", ".txt", ".json", ".yaml", ".yml", ".toml", ".csv",
+		".png", ".jpg", ".jpeg", ".gif", ".webp", ".avif", ".svg", ".ico", ".bmp",
+		".mp3", ".mp4", ".webm", ".pdf", ".zip",
+	},
+}
+
+const maxArchivePathLength = 1024
+
+// archiveSignatures start archives and executables, which a file claiming
+// to be something else must not contain
+var archiveSignatures = [][]byte{
+	[]byte("PK\x03\x04"),         // zip
+	[]byte("\x1f\x8b\x08"),       // gzip
+	[]byte("Rar!\x1a\x07"),       // rar
+	[]byte("7z\xbc\xaf\x27\x1c"), // 7z
+	[]byte("\xfd7zXZ\x00"),       // xz
+	[]byte("MZ\x90\x00"),         // Windows executable
+	[]byte("\x7fELF"),            // Linux executable
+	[]byte("\xcf\xfa\xed\xfe"),   // macOS executable
+	[]byte("\xca\xfe\xba\xbe"),   // macOS universal binary
+}
+
+// ImportArchive is a checked zip file
+type ImportArchive struct {
+	// Files are the regular files, in archive order
+	Files []*ImportArchiveFile
+
+	limits   ArchiveLimits
+	entries  int
+	inflated int64
+}
+
+// ImportArchiveFile is a file of an import archive. Name is its cleaned
+// path, with forward slashes.
+type ImportArchiveFile struct {
+	Name string
+	Size int64
+
+	file *zip.File
+}
+
+// OpenImportArchive reads and checks the entries of a zip file
+func OpenImportArchive(r io.ReaderAt, size int64, limits ArchiveLimits) (*ImportArchive, error) {
+	a := &ImportArchive{limits: limits}
+	files, err := a.check(r, size, limits.MaxDepth, "")
+	if err != nil {
+		return nil, err
+	}
+	a.Files = files
+	// The nested archives were read to check them
+	a.inflated = 0
+	return a, nil
+}
+
+// check checks the entries of an archive, nested below prefix, and returns
+// its files
+func (a *ImportArchive) check(r io.ReaderAt, size int64, depth int, prefix string) ([]*ImportArchiveFile, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s%v", ErrUnsafeArchive, prefix, err)
+	}
+	var files []*ImportArchiveFile
+	seen := map[string]bool{}
+	for _, file := range zipReader.File {
+		if ignoredArchiveEntry(file.Name) {
+			continue
+		}
+		name, err := cleanArchiveName(file.Name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s%v", ErrUnsafeArchive, prefix, err)
+		}
+		mode := file.Mode()
+		if mode.IsDir() {
+			continue
+		}
+		if !mode.IsRegular() {
+			return nil, fmt.Errorf("%w: %s%s is not a regular file", ErrUnsafeArchive, prefix, name)
+		}
+		if file.Flags&0x1 != 0 {
+			return nil, fmt.Errorf("%w: %s%s is encrypted", ErrUnsafeArchive, prefix, name)
+		}
+		// Case-insensitive file systems would write both to one file
+		if key := strings.ToLower(name); seen[key] {
+			return nil, fmt.Errorf("%w: %s%s is in the archive twice", ErrUnsafeArchive, prefix, name)
+		} else {
+			seen[key] = true
+		}
+		ext := strings.ToLower(path.Ext(name))
+		if !a.allowed(ext) {
+			return nil, fmt.Errorf("%w: %s%s is not an accepted file type", ErrUnsafeArchive, prefix, name)
+		}
+
+		a.entries++
+		if a.entries > a.limits.MaxEntries {
+			return nil, fmt.Errorf("%w: more than %d files", ErrUnsafeArchive, a.limits.MaxEntries)
+		}
+		if file.UncompressedSize64 > uint64(a.limits.MaxFileSize) {
+			return nil, fmt.Errorf("%w: %s%s is larger than %d MB", ErrUnsafeArchive, prefix, name, a.limits.MaxFileSize>>20)
+		}
+		if file.UncompressedSize64 > 1<<20 && file.UncompressedSize64 > file.CompressedSize64*a.limits.MaxRatio {
+			return nil, fmt.Errorf("%w: %s%s inflates more than %d times", ErrUnsafeArchive, prefix, name, a.limits.MaxRatio)
+		}
+
+		entry := &ImportArchiveFile{Name: name, Size: int64(file.UncompressedSize64), file: file}
+		if ext == ".zip" {
+			if depth == 0 {
+				return nil, fmt.Errorf("%w: %s%s nests archives too deep", ErrUnsafeArchive, prefix, name)
+			}
+			data, err := a.read(entry, true)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := a.check(bytes.NewReader(data), int64(len(data)), depth-1, prefix+name+": "); err != nil {
+				return nil, err
+			}
+		}
+		files = append(files, entry)
+	}
+	return files, nil
+}
+
+func (a *ImportArchive) allowed(ext string) bool {
+	for _, allowed := range a.limits.Extensions {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFile reads a file of the archive. The sizes the archive gives are not
+// trusted: reading stops at the limits whatever they say.
+func (a *ImportArchive) ReadFile(file *ImportArchiveFile) ([]byte, error) {
+	return a.read(file, strings.EqualFold(path.Ext(file.Name), ".zip"))
+}
+
+func (a *ImportArchive) read(file *ImportArchiveFile, archive bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := a.copy(&buf, file, archive); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// copy writes a file of the archive to dst within the limits, and rejects
+// files whose content is an archive or a program they do not claim to be
+func (a *ImportArchive) copy(dst io.Writer, file *ImportArchiveFile, archive bool) error {
+	src, err := file.file.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrUnsafeArchive, file.Name, err)
+	}
+	defer src.Close()
+
+	limit := a.limits.MaxFileSize
+	if left := a.limits.MaxTotalSize - a.inflated; left < limit {
+		limit = left
+	}
+	var head [8]byte
+	n, err := io.ReadFull(src, head[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: %s: %v", ErrUnsafeArchive, file.Name, err)
+	}
+	if !archive {
+		for _, signature := range archiveSignatures {
+			if bytes.HasPrefix(head[:n], signature) {
+				return fmt.Errorf("%w: %s is an archive or a program", ErrUnsafeArchive, file.Name)
+			}
+		}
+	}
+	written, err := io.Copy(dst, io.LimitReader(io.MultiReader(bytes.NewReader(head[:n]), src), limit+1))
+	a.inflated += written
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrUnsafeArchive, file.Name, err)
+	}
+	if written > limit {
+		if limit < a.limits.MaxFileSize {
+			return fmt.Errorf("%w: larger than %d MB uncompressed", ErrUnsafeArchive, a.limits.MaxTotalSize>>20)
+		}
+		return fmt.Errorf("%w: %s is larger than %d MB", ErrUnsafeArchive, file.Name, a.limits.MaxFileSize>>20)
+	}
+	return nil
+}
+
+// Extract writes the files of the archive under dir, which must not hold
+// any of them yet, and returns the paths written. Nothing is left behind
+// when it fails.
+func (a *ImportArchive) Extract(dir string) ([]string, error) {
+	var written []string
+	for _, file := range a.Files {
+		target := filepath.Join(dir, filepath.FromSlash(file.Name))
+		if rel, err := filepath.Rel(dir, target); err != nil || !filepath.IsLocal(rel) {
+			removeFiles(written)
+			return nil, fmt.Errorf("%w: %s is outside the archive", ErrUnsafeArchive, file.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			removeFiles(written)
+			return nil, err
+		}
+		if err := a.extract(file, target); err != nil {
+			removeFiles(written)
+			return nil, err
+		}
+		written = append(written, target)
+	}
+	return written, nil
+}
+
+func (a *ImportArchive) extract(file *ImportArchiveFile, target string) error {
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if err := a.copy(dst, file, strings.EqualFold(path.Ext(file.Name), ".zip")); err != nil {
+		dst.Close()
+		os.Remove(target)
+		return err
+	}
+	return dst.Close()
+}
+
+// cleanArchiveName returns the path of an entry, rejecting those that would
+// be written outside the folder the archive is extracted to
+func cleanArchiveName(name string) (string, error) {
+	switch {
+	case len(name) > maxArchivePathLength:
+		return "", fmt.Errorf("path %.40q... is too long", name)
+	case strings.ContainsAny(name, "\\\x00"), strings.Contains(name, ":"):
+		return "", fmt.Errorf("bad path %q", name)
+	}
+	clean := path.Clean(strings.TrimSuffix(name, "/"))
+	if path.IsAbs(name) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") || !filepath.IsLocal(filepath.FromSlash(clean)) {
+		return "", fmt.Errorf("path %q leaves the archive", name)
+	}
+	return clean, nil
+}
+
+// ignoredArchiveEntry tells the files operating systems add when zipping
+// a folder, which are left out rather than rejected
+func ignoredArchiveEntry(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(name, "__MACOSX/") || base == ".DS_Store" || base == "Thumbs.db" || base == "desktop.ini"
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openImportZip(t *testing.T, files map[string]string, limits ArchiveLimits) (*ImportArchive, error) {
+	r := themeZip(t, files)
+	return OpenImportArchive(r, r.Size(), limits)
+}
+
+func zipString(t *testing.T, files map[string]string) string {
+	r := themeZip(t, files)
+	data := make([]byte, r.Size())
+	r.Read(data)
+	return string(data)
+}
+
+func TestImportArchive(t *testing.T) {
+	archive, err := openImportZip(t, map[string]string{
+		"export/posts/hello.md":   "# Hello",
+		"export/images/a.png":     "png",
+		"export/media.zip":        zipString(t, map[string]string{"b.jpg": "jpg"}),
+		"__MACOSX/export/._a.png": "resource fork",
+		"export/.DS_Store":        "finder",
+	}, DefaultImportArchiveLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive.Files) != 3 {
+		t.Fatalf("files = %+v", archive.Files)
+	}
+
+	dir := t.TempDir()
+	written, err := archive.Extract(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 3 {
+		t.Errorf("written = %v", written)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "export", "posts", "hello.md")); err != nil || string(data) != "# Hello" {
+		t.Errorf("hello.md = %q, %v", data, err)
+	}
+	// Nothing is overwritten, and a failed extraction leaves nothing
+	if _, err := archive.Extract(dir); err == nil {
+		t.Error("extracting over existing files succeeded")
+	}
+	os.Remove(filepath.Join(dir, "export", "media.zip"))
+	if _, err := archive.Extract(dir); err == nil {
+		t.Error("extracting over existing files succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "export", "media.zip")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("media.zip left behind: %v", err)
+	}
+}
+
+func TestImportArchiveRejects(t *testing.T) {
+	many := map[string]string{}
+	for _, name := range []string{"a", "b", "c"} {
+		many[name+".md"] = name
+	}
+	deep := zipString(t, map[string]string{"inner.zip": zipString(t, map[string]string{"a.md": "a"})})
+	bomb := strings.Repeat("a", 3<<20)
+
+	limits := DefaultImportArchiveLimits
+	limits.MaxEntries = 2
+	for name, files := range map[string]map[string]string{
+		"parent path":    {"../evil.md": "x"},
+		"nested parent":  {"posts/../../evil.md": "x"},
+		"absolute path":  {"/etc/evil.md": "x"},
+		"backslash":      {"..\\evil.md": "x"},
+		"drive":          {"C:/evil.md": "x"},
+		"extension":      {"shell.php": "<?php"},
+		"no extension":   {"run": "x"},
+		"case duplicate": {"Post.md": "a", "post.md": "b"},
+		"entries":        many,
+		"depth":          {"outer.zip": deep},
+		"nested slip":    {"media.zip": zipString(t, map[string]string{"../evil.md": "x"})},
+		"ratio":          {"bomb.txt": bomb},
+	} {
+		if _, err := openImportZip(t, files, limits); !errors.Is(err, ErrUnsafeArchive) {
+			t.Errorf("%s: err = %v, want ErrUnsafeArchive", name, err)
+		}
+	}
+
+	// Links are not followed
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	header := &zip.FileHeader{Name: "link.md"}
+	header.SetMode(fs.ModeSymlink | 0777)
+	writer, _ := zipWriter.CreateHeader(header)
+	writer.Write([]byte("/etc/passwd"))
+	zipWriter.Close()
+	if _, err := OpenImportArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()), limits); !errors.Is(err, ErrUnsafeArchive) {
+		t.Errorf("symlink: err = %v, want ErrUnsafeArchive", err)
+	}
+}
+
+func TestImportArchiveReadLimits(t *testing.T) {
+	limits := DefaultImportArchiveLimits
+	limits.MaxTotalSize = 10
+	archive, err := openImportZip(t, map[string]string{
+		"a.md":    "12345678",
+		"b.md":    "12345678",
+		"x.md":    "PK\x03\x04 not markdown",
+		"run.txt": "MZ\x90\x00 not text",
+	}, limits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*ImportArchiveFile{}
+	for _, file := range archive.Files {
+		files[file.Name] = file
+	}
+	for _, name := range []string{"x.md", "run.txt"} {
+		if _, err := archive.ReadFile(files[name]); !errors.Is(err, ErrUnsafeArchive) {
+			t.Errorf("%s: err = %v, want ErrUnsafeArchive", name, err)
+		}
+	}
+	if data, err := archive.ReadFile(files["a.md"]); err != nil || string(data) != "12345678" {
+		t.Fatalf("a.md = %q, %v", data, err)
+	}
+	// Together they inflate past the limit
+	if _, err := archive.ReadFile(files["b.md"]); !errors.Is(err, ErrUnsafeArchive) {
+		t.Errorf("b.md: err = %v, want ErrUnsafeArchive", err)
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// jobProgressInterval is the least time between writes of a job's step
+	// and item errors. A change of percent is written at once.
+	jobProgressInterval = time.Second
+	// maxJobItemErrors is how many failed items a job keeps
+	maxJobItemErrors = 50
+)
+
+// JobProgress reports how far a running job has got to the job's row,
+// where the admin API streams it from. Handlers get it from their context
+// with JobProgressFrom. Outside a job that is nil, and the methods of a
+// nil JobProgress do nothing, so services report progress the same way
+// whether they run as a job or not.
+type JobProgress struct {
+	db       func() *gorm.DB
+	jobID    uint
+	workerID string
+
+	mu        sync.Mutex
+	total     int
+	done      int
+	percent   int
+	step      string
+	errors    []string
+	failed    int
+	dirty     bool
+	writtenAt time.Time
+}
+
+type jobProgressKey struct{}
+
+func newJobProgress(db func() *gorm.DB, jobID uint, workerID string) *JobProgress {
+	return &JobProgress{db: db, jobID: jobID, workerID: workerID}
+}
+
+// JobProgressFrom returns the progress reporter of the job running with
+// ctx, or nil
+func JobProgressFrom(ctx context.Context) *JobProgress {
+	if ctx == nil {
+		return nil
+	}
+	p, _ := ctx.Value(jobProgressKey{}).(*JobProgress)
+	return p
+}
+
+func withJobProgress(ctx context.Context, p *JobProgress) context.Context {
+	return context.WithValue(ctx, jobProgressKey{}, p)
+}
+
+// SetTotal sets how many items the job works through. Percent is the
+// share of them marked done, held below 100 until the job succeeds.
+func (p *JobProgress) SetTotal(total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+	p.update()
+}
+
+// Step describes what the job is doing now
+func (p *JobProgress) Step(format string, args ...interface{}) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.step = truncateRunes(fmt.Sprintf(format, args...), 200)
+	p.dirty = true
+	p.update()
+}
+
+// Advance marks n more items done
+func (p *JobProgress) Advance(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+	p.update()
+}
+
+// ItemFailed records an item that failed without failing the job. The
+// item still counts as done.
+func (p *JobProgress) ItemFailed(item string, err error) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failed++
+	if len(p.errors) < maxJobItemErrors {
+		p.errors = append(p.errors, fmt.Sprintf("%s: %v", item, err))
+	}
+	p.done++
+	p.dirty = true
+	p.update()
+}
+
+// update writes the progress when the percent changed, or when other
+// changes have waited long enough. The caller holds p.mu.
+func (p *JobProgress) update() {
+	percent := p.percent
+	if p.total > 0 {
+		percent = p.done * 100 / p.total
+		if percent > 99 {
+			percent = 99
+		}
+	}
+	if percent != p.percent {
+		p.percent = percent
+		p.dirty = true
+	} else if !p.dirty || time.Since(p.writtenAt) < jobProgressInterval {
+		return
+	}
+	p.write()
+}
+
+// flush writes changes update held back
+func (p *JobProgress) flush() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.dirty {
+		p.write()
+	}
+}
+
+func (p *JobProgress) write() {
+	updates := map[string]interface{}{"progress": p.percent, "step": p.step}
+	if len(p.errors) > 0 {
+		items := p.errors
+		if p.failed > len(items) {
+			items = append(items[:len(items):len(items)], fmt.Sprintf("%d more items failed", p.failed-len(items)))
+		}
+		data, _ := json.Marshal(items)
+		updates["item_errors"] = string(data)
+	}
+	// The lock condition keeps a worker that lost the job to stale
+	// recovery from overwriting the progress of the next attempt
+	err := p.db().Model(&models.Job{}).Where("id = ? AND locked_by = ?", p.jobID, p.workerID).Updates(updates).Error
+	if err != nil {
+		slog.Warn("Failed to record job progress", "job_id", p.jobID, "error", err)
+	}
+	p.dirty = false
+	p.writtenAt = time.Now()
+}
+
+// JobProgressEvent is the state of a job as its progress stream sends it
+type JobProgressEvent struct {
+	ID         uint            `json:"id"`
+	Type       string          `json:"type"`
+	Status     string          `json:"status"`
+	Attempts   int             `json:"attempts"`
+	Progress   int             `json:"progress"`
+	Step       string          `json:"step,omitempty"`
+	ItemErrors []string        `json:"item_errors"`
+	Error      string          `json:"error,omitempty"`
+	Result     json.RawMessage `json:"result,omitempty"`
+}
+
+// NewJobProgressEvent describes the progress of a job
+func NewJobProgressEvent(job *models.Job) JobProgressEvent {
+	event := JobProgressEvent{
+		ID:         job.ID,
+		Type:       job.Type,
+		Status:     job.Status,
+		Attempts:   job.Attempts,
+		Progress:   job.Progress,
+		Step:       job.Step,
+		ItemErrors: []string{},
+		Error:      job.LastError,
+	}
+	if job.ItemErrors != "" {
+		json.Unmarshal([]byte(job.ItemErrors), &event.ItemErrors)
+	}
+	if job.Result != "" {
+		event.Result = json.RawMessage(job.Result)
+	}
+	return event
+}
+
+// JobFinished reports whether a job is in a state it leaves only when an
+// admin retries it
+func JobFinished(job *models.Job) bool {
+	return job.Status == models.JobSucceeded || job.Status == models.JobDead || job.Status == models.JobCancelled
+}
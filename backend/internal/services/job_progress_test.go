@@ -0,0 +1,101 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestJobProgressIsRecordedWhileRunning(t *testing.T) {
+	q := setupJobQueueTest(t)
+	var seen []models.Job
+	q.Register("test.progress", func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		progress := JobProgressFrom(ctx)
+		progress.SetTotal(4)
+		for i := 1; i <= 4; i++ {
+			progress.Step("Item %d", i)
+			if i == 3 {
+				progress.ItemFailed(fmt.Sprintf("item %d", i), errors.New("unreachable"))
+			} else {
+				progress.Advance(1)
+			}
+			var job models.Job
+			database.DB.First(&job, "type = ?", "test.progress")
+			seen = append(seen, job)
+		}
+		return nil, nil
+	})
+
+	queued, _ := q.Enqueue("test.progress", nil)
+	job := runNext(t, q)
+
+	// Every item changes the percent, so each is written at once, and the
+	// last is held below 100 until the job succeeds
+	for i, want := range []int{25, 50, 75, 99} {
+		if seen[i].Progress != want || seen[i].Step != fmt.Sprintf("Item %d", i+1) {
+			t.Errorf("after item %d progress = %d %q, want %d", i+1, seen[i].Progress, seen[i].Step, want)
+		}
+	}
+	if job.Status != models.JobSucceeded || job.Progress != 100 {
+		t.Fatalf("finished job = %+v", job)
+	}
+	event := NewJobProgressEvent(job)
+	if len(event.ItemErrors) != 1 || event.ItemErrors[0] != "item 3: unreachable" {
+		t.Errorf("item errors = %v", event.ItemErrors)
+	}
+
+	// A retry starts from scratch
+	database.DB.Model(&models.Job{}).Where("id = ?", queued.ID).Update("status", models.JobDead)
+	if _, err := q.Retry(queued.ID); err != nil {
+		t.Fatal(err)
+	}
+	claimed, err := q.claim()
+	if err != nil || claimed == nil {
+		t.Fatalf("claim = %v, %v", claimed, err)
+	}
+	job, _ = q.Get(queued.ID)
+	if job.Progress != 0 || job.Step != "" || job.ItemErrors != "" {
+		t.Errorf("progress not cleared for the next attempt: %+v", job)
+	}
+}
+
+func TestJobProgressCapsItemErrors(t *testing.T) {
+	newTestDB(t)
+	job := models.Job{Type: "test.errors", Status: models.JobRunning, LockedBy: "worker"}
+	database.DB.Create(&job)
+
+	progress := newJobProgress(func() *gorm.DB { return database.DB }, job.ID, "worker")
+	progress.SetTotal(maxJobItemErrors + 5)
+	for i := 0; i < maxJobItemErrors+5; i++ {
+		progress.ItemFailed(fmt.Sprintf("item %d", i), errors.New("failed"))
+	}
+	progress.flush()
+
+	database.DB.First(&job, job.ID)
+	items := NewJobProgressEvent(&job).ItemErrors
+	if len(items) != maxJobItemErrors+1 || items[maxJobItemErrors] != "5 more items failed" {
+		t.Errorf("got %d item errors ending in %q", len(items), items[len(items)-1])
+	}
+
+	// Another worker's progress is not written over the job
+	other := newJobProgress(func() *gorm.DB { return database.DB }, job.ID, "other")
+	other.Step("Elsewhere")
+	other.flush()
+	database.DB.First(&job, job.ID)
+	if job.Step == "Elsewhere" {
+		t.Errorf("progress of a worker that lost the job was recorded")
+	}
+
+	// Outside a job there is no reporter, and reporting does nothing
+	none := JobProgressFrom(context.Background())
+	none.SetTotal(1)
+	none.Step("nothing")
+	none.ItemFailed("item", errors.New("failed"))
+	none.Advance(1)
+}
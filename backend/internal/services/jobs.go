@@ -0,0 +1,741 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Built-in job types
+const (
+	JobBackupCreate       = "backup.create"
+	JobBackupScheduled    = "backup.scheduled"
+	JobSEOSiteAudit       = "seo.site_audit"
+	JobSEOKeywordData     = "seo.keyword_data"
+	JobSEOBulkMetadata    = "seo.bulk_metadata"
+	JobContentQuality     = "content.quality"
+	JobWritingSuggestions = "writing.suggestions"
+	JobUpdateProfiles     = "behavior.update_profiles"
+	JobStorageSnapshot    = "storage.snapshot"
+	JobDatabaseOptimize   = "database.optimize"
+	JobSiteMonitor        = "monitor.check"
+	JobNewsletterSend     = "newsletter.send"
+	JobNewsletterDigest   = "newsletter.digest"
+	JobNewsletterConfirm  = "newsletter.confirm"
+	JobNewsletterAIDigest = "newsletter.ai_digest"
+	JobNotifierSend       = "notifiers.send"
+	JobActivityPubDeliver = "activitypub.deliver"
+	JobWebSubPublish      = "websub.publish"
+	JobSearchPush         = "search_push.submit"
+	JobWebmentionSend     = "webmention.send"
+	JobWebmentionVerify   = "webmention.verify"
+	JobCommentsNotify     = "comments.notify"
+	JobCommentsReply      = "comments.reply"
+	JobTranslationRefresh = "translation.refresh"
+	JobDiagramsRender     = "diagrams.render"
+	JobContactForward     = "contact.forward"
+	JobFriendLinksCheck   = "friend_links.check"
+	JobEbookExport        = "ebook.export"
+	JobAPIKeyUsagePurge   = "api_keys.purge_usage"
+	JobRecountViews       = "article_views.recount"
+	JobExpirePins         = "articles.expire_pins"
+	JobNotificationDigest = "notifications.digest"
+	JobTopicCentroids     = "topics.centroids"
+	JobSearchRebuild      = "search.rebuild"
+	JobShareCounts        = "share_counts.collect"
+	JobCommentBridgeSync  = "comment_bridge.sync"
+	JobFeedImport         = "feed_import.fetch"
+)
+
+var (
+	ErrJobNotFound    = errors.New("job not found")
+	ErrUnknownJobType = errors.New("unknown job type")
+	ErrJobState       = errors.New("job cannot be changed in its current state")
+	ErrJobExists      = errors.New("a job with this unique key already exists")
+)
+
+// JobHandler runs one job. The payload is the JSON given to Enqueue and the
+// returned value is stored as the job result. ctx is cancelled on shutdown
+// and carries the job's JobProgress.
+type JobHandler func(ctx context.Context, payload json.RawMessage) (interface{}, error)
+
+// JobQueue is a database-backed queue of background work. Workers claim
+// jobs with a conditional update, so several server instances can share
+// one queue. Failed jobs are retried with exponential backoff and moved to
+// the dead-letter state after MaxAttempts.
+type JobQueue struct {
+	db           func() *gorm.DB
+	workerID     string
+	workers      int
+	maxAttempts  int
+	timeout      time.Duration
+	retention    time.Duration
+	pollInterval time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+	wake     chan struct{}
+	started  bool
+}
+
+// JobFilter narrows a job listing
+type JobFilter struct {
+	Status string
+	Type   string
+	Limit  int
+	Offset int
+}
+
+// JobStats counts jobs by status
+type JobStats struct {
+	Counts  map[string]int64 `json:"counts"`
+	Types   []string         `json:"types"`
+	Workers int              `json:"workers"`
+}
+
+// NewJobQueue creates a job queue configured from JOB_* settings
+func NewJobQueue() *JobQueue {
+	cfg := config.Get().Jobs
+	hostname, _ := os.Hostname()
+	return &JobQueue{
+		db:           func() *gorm.DB { return database.DB },
+		workerID:     fmt.Sprintf("%s-%s", hostname, uuid.NewString()[:8]),
+		workers:      cfg.Workers,
+		maxAttempts:  cfg.MaxAttempts,
+		timeout:      cfg.Timeout.Std(),
+		retention:    time.Duration(cfg.RetentionDays) * 24 * time.Hour,
+		pollInterval: 5 * time.Second,
+		handlers:     make(map[string]JobHandler),
+		wake:         make(chan struct{}, cfg.Workers),
+	}
+}
+
+// Register sets the handler for a job type
+func (q *JobQueue) Register(jobType string, handler JobHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+func (q *JobQueue) handler(jobType string) (JobHandler, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	handler, ok := q.handlers[jobType]
+	return handler, ok
+}
+
+// Types lists the registered job types
+func (q *JobQueue) Types() []string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	types := make([]string, 0, len(q.handlers))
+	for jobType := range q.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Enqueue adds a job to run as soon as a worker is free
+func (q *JobQueue) Enqueue(jobType string, payload interface{}) (*models.Job, error) {
+	return q.EnqueueAt(jobType, payload, time.Now())
+}
+
+// EnqueueAt adds a job that runs no earlier than runAt
+func (q *JobQueue) EnqueueAt(jobType string, payload interface{}, runAt time.Time) (*models.Job, error) {
+	return q.enqueue(jobType, payload, runAt, "")
+}
+
+// EnqueueUnique adds a job unless a job with the same key was ever queued,
+// on this instance or another, in which case it returns ErrJobExists
+func (q *JobQueue) EnqueueUnique(jobType string, payload interface{}, key string) (*models.Job, error) {
+	return q.enqueue(jobType, payload, time.Now(), key)
+}
+
+// EnqueueUniqueAt is EnqueueUnique for a job that runs no earlier than runAt
+func (q *JobQueue) EnqueueUniqueAt(jobType string, payload interface{}, key string, runAt time.Time) (*models.Job, error) {
+	return q.enqueue(jobType, payload, runAt, key)
+}
+
+func (q *JobQueue) enqueue(jobType string, payload interface{}, runAt time.Time, key string) (*models.Job, error) {
+	if _, ok := q.handler(jobType); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJobType, jobType)
+	}
+
+	job := &models.Job{
+		Type:        jobType,
+		Status:      models.JobPending,
+		MaxAttempts: q.maxAttempts,
+		RunAt:       runAt,
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode job payload: %v", err)
+		}
+		job.Payload = string(data)
+	}
+	if key != "" {
+		job.UniqueKey = &key
+		result := q.db().Clauses(clause.OnConflict{DoNothing: true}).Create(job)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, ErrJobExists
+		}
+	} else if err := q.db().Create(job).Error; err != nil {
+		return nil, err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Start launches the workers and the maintenance loop. Handlers should be
+// registered first; a job whose type has no handler goes straight to the
+// dead-letter state.
+func (q *JobQueue) Start() {
+	q.mu.Lock()
+	if q.started {
+		q.mu.Unlock()
+		return
+	}
+	q.started = true
+	q.mu.Unlock()
+
+	for i := 0; i < q.workers; i++ {
+		go q.worker()
+	}
+	go q.maintain()
+	slog.Info("Job queue started", "workers", q.workers, "worker_id", q.workerID)
+}
+
+func (q *JobQueue) worker() {
+	for {
+		if IsShuttingDown() {
+			return
+		}
+		job, err := q.claim()
+		if err != nil {
+			slog.Error("Failed to claim job", "error", err)
+		}
+		if job != nil {
+			q.run(job)
+			continue
+		}
+
+		timer := time.NewTimer(q.pollInterval)
+		select {
+		case <-q.wake:
+		case <-timer.C:
+		case <-ShuttingDown():
+		}
+		timer.Stop()
+	}
+}
+
+// claim takes the next due job, or returns nil when there is none. The
+// status condition in the update makes claiming safe between instances.
+func (q *JobQueue) claim() (*models.Job, error) {
+	db := q.db()
+	for {
+		var job models.Job
+		err := db.Where("status = ? AND run_at <= ?", models.JobPending, time.Now()).
+			Order("run_at ASC, id ASC").First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		result := db.Model(&models.Job{}).
+			Where("id = ? AND status = ?", job.ID, models.JobPending).
+			Updates(map[string]interface{}{
+				"status":      models.JobRunning,
+				"locked_by":   q.workerID,
+				"locked_at":   now,
+				"started_at":  now,
+				"attempts":    gorm.Expr("attempts + 1"),
+				"progress":    0,
+				"step":        "",
+				"item_errors": "",
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = models.JobRunning
+			job.LockedBy = q.workerID
+			job.LockedAt = &now
+			job.StartedAt = &now
+			job.Attempts++
+			job.Progress, job.Step, job.ItemErrors = 0, "", ""
+			return &job, nil
+		}
+		// Another worker won the race; look for the next job
+	}
+}
+
+func (q *JobQueue) run(job *models.Job) {
+	if !beginJob() {
+		// Shutdown began after the claim; hand the job back untouched
+		q.db().Model(job).Updates(map[string]interface{}{
+			"status":    models.JobPending,
+			"attempts":  gorm.Expr("attempts - 1"),
+			"locked_by": "",
+			"locked_at": nil,
+		})
+		return
+	}
+	defer endJob()
+
+	logger := slog.With("job_id", job.ID, "type", job.Type, "attempt", job.Attempts)
+	handler, ok := q.handler(job.Type)
+	if !ok {
+		q.finish(job, nil, fmt.Errorf("%w: %s", ErrUnknownJobType, job.Type), true)
+		logger.Error("No handler registered for job")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-ShuttingDown():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	progress := newJobProgress(q.db, job.ID, q.workerID)
+	ctx = withJobProgress(ctx, progress)
+
+	logger.Info("Running job")
+	result, err := runHandler(ctx, handler, json.RawMessage(job.Payload))
+	progress.flush()
+	if err != nil {
+		dead := job.Attempts >= job.MaxAttempts
+		q.finish(job, nil, err, dead)
+		if dead {
+			logger.Error("Job failed permanently", "error", err)
+		} else {
+			logger.Warn("Job failed, will retry", "error", err, "retry_at", job.RunAt)
+		}
+		return
+	}
+	q.finish(job, result, nil, false)
+	logger.Info("Job succeeded")
+}
+
+// runHandler calls handler, turning a panic into an error
+func runHandler(ctx context.Context, handler JobHandler, payload json.RawMessage) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	if len(payload) == 0 {
+		payload = json.RawMessage("null")
+	}
+	return handler(ctx, payload)
+}
+
+// finish records the outcome of a run. Failures that are not dead are
+// rescheduled with exponential backoff.
+func (q *JobQueue) finish(job *models.Job, result interface{}, runErr error, dead bool) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"locked_by": "",
+		"locked_at": nil,
+	}
+	switch {
+	case runErr == nil:
+		job.Status = models.JobSucceeded
+		job.LastError = ""
+		job.Progress = 100
+		updates["progress"] = job.Progress
+		if result != nil {
+			if data, err := json.Marshal(result); err == nil {
+				updates["result"] = string(data)
+			}
+		}
+		updates["finished_at"] = now
+	case dead:
+		job.Status = models.JobDead
+		job.LastError = runErr.Error()
+		updates["finished_at"] = now
+	default:
+		job.Status = models.JobPending
+		job.LastError = runErr.Error()
+		job.RunAt = now.Add(retryBackoff(job.Attempts))
+		updates["run_at"] = job.RunAt
+	}
+	updates["status"] = job.Status
+	updates["last_error"] = job.LastError
+
+	if err := q.db().Model(&models.Job{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+		slog.Error("Failed to record job result", "job_id", job.ID, "error", err)
+	}
+}
+
+// retryBackoff is the delay before retrying after the given attempt:
+// 30s, 1m, 2m, ... capped at one hour
+func retryBackoff(attempt int) time.Duration {
+	delay := 30 * time.Second
+	for i := 1; i < attempt && delay < time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > time.Hour {
+		delay = time.Hour
+	}
+	return delay
+}
+
+// maintain requeues jobs whose worker disappeared and prunes old history
+func (q *JobQueue) maintain() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		q.recoverStale()
+		q.prune()
+
+		select {
+		case <-ticker.C:
+		case <-ShuttingDown():
+			return
+		}
+	}
+}
+
+// recoverStale returns jobs that have been running longer than the timeout
+// to the queue, or dead-letters them if they are out of attempts
+func (q *JobQueue) recoverStale() {
+	var stale []models.Job
+	cutoff := time.Now().Add(-q.timeout)
+	if err := q.db().Where("status = ? AND locked_at < ?", models.JobRunning, cutoff).Find(&stale).Error; err != nil {
+		slog.Error("Failed to look up stale jobs", "error", err)
+		return
+	}
+	for i := range stale {
+		job := &stale[i]
+		slog.Warn("Recovering stale job", "job_id", job.ID, "type", job.Type, "locked_by", job.LockedBy)
+		q.finish(job, nil, fmt.Errorf("timed out after %s on %s", q.timeout, job.LockedBy), job.Attempts >= job.MaxAttempts)
+	}
+}
+
+// prune deletes finished jobs older than the retention period. Dead jobs
+// are kept until an admin retries or deletes them, and the newest job of
+// each type is kept so schedules can always show their last run.
+func (q *JobQueue) prune() {
+	if q.retention <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-q.retention)
+	// The derived table lets MySQL delete from the table it selects from
+	latest := q.db().Table("(?) AS latest", q.db().Model(&models.Job{}).Select("MAX(id) AS id").Group("type")).Select("id")
+	q.db().Where("status IN ? AND finished_at < ? AND id NOT IN (?)", []string{models.JobSucceeded, models.JobCancelled}, cutoff, latest).
+		Delete(&models.Job{})
+}
+
+// Latest returns the most recent job of a type, or nil if there is none
+func (q *JobQueue) Latest(jobType string) (*models.Job, error) {
+	var job models.Job
+	err := q.db().Where("type = ?", jobType).Order("id DESC").First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Active reports whether a job of the type is pending or running
+func (q *JobQueue) Active(jobType string) (bool, error) {
+	var count int64
+	err := q.db().Model(&models.Job{}).
+		Where("type = ? AND status IN ?", jobType, []string{models.JobPending, models.JobRunning}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// List returns jobs matching filter, newest first, and the total count
+func (q *JobQueue) List(filter JobFilter) ([]models.Job, int64, error) {
+	query := q.db().Model(&models.Job{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if filter.Limit <= 0 || filter.Limit > 200 {
+		filter.Limit = 50
+	}
+
+	var jobs []models.Job
+	err := query.Order("id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&jobs).Error
+	return jobs, total, err
+}
+
+// Get returns a job by ID
+func (q *JobQueue) Get(id uint) (*models.Job, error) {
+	var job models.Job
+	if err := q.db().First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Retry puts a dead or cancelled job back in the queue with fresh attempts
+func (q *JobQueue) Retry(id uint) (*models.Job, error) {
+	return q.transition(id, []string{models.JobDead, models.JobCancelled}, map[string]interface{}{
+		"status":      models.JobPending,
+		"attempts":    0,
+		"run_at":      time.Now(),
+		"finished_at": nil,
+	})
+}
+
+// Cancel stops a pending job from running
+func (q *JobQueue) Cancel(id uint) (*models.Job, error) {
+	return q.transition(id, []string{models.JobPending}, map[string]interface{}{
+		"status":      models.JobCancelled,
+		"finished_at": time.Now(),
+	})
+}
+
+// Delete removes a job that is not running
+func (q *JobQueue) Delete(id uint) error {
+	if _, err := q.Get(id); err != nil {
+		return err
+	}
+	result := q.db().Where("id = ? AND status <> ?", id, models.JobRunning).Delete(&models.Job{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobState
+	}
+	return nil
+}
+
+func (q *JobQueue) transition(id uint, from []string, updates map[string]interface{}) (*models.Job, error) {
+	if _, err := q.Get(id); err != nil {
+		return nil, err
+	}
+	result := q.db().Model(&models.Job{}).Where("id = ? AND status IN ?", id, from).Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrJobState
+	}
+	if updates["status"] == models.JobPending {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+	return q.Get(id)
+}
+
+// Stats counts jobs by status
+func (q *JobQueue) Stats() (*JobStats, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := q.db().Model(&models.Job{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	stats := &JobStats{
+		Counts:  map[string]int64{models.JobPending: 0, models.JobRunning: 0, models.JobSucceeded: 0, models.JobDead: 0, models.JobCancelled: 0},
+		Types:   q.Types(),
+		Workers: q.workers,
+	}
+	for _, row := range rows {
+		stats.Counts[row.Status] = row.Count
+	}
+	return stats, nil
+}
+
+// registerBuiltinJobs registers the job types implemented in this package
+func (q *JobQueue) registerBuiltinJobs() {
+	q.Register(JobBackupCreate, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalBackupService().Create()
+	})
+	q.Register(JobBackupScheduled, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalBackupService().RunScheduledBackup()
+	})
+	q.Register(JobSEOSiteAudit, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		check, err := NewSEOHealthCheckerService(database.DB).RunSiteWideHealthCheck(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"health_check_id": check.ID, "overall_score": check.OverallScore}, nil
+	})
+	q.Register(JobSEOKeywordData, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalKeywordDataService().Enrich(ctx)
+	})
+	q.Register(JobSEOBulkMetadata, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalSEOBulkService().Generate(ctx, payload)
+	})
+	q.Register(JobShareCounts, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalShareCountService().Collect(ctx, payload)
+	})
+	q.Register(JobCommentBridgeSync, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalCommentBridgeService().Sync(ctx)
+	})
+	q.Register(JobContentQuality, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalContentQualityService().AnalyzeAll(ctx)
+	})
+	q.Register(JobWritingSuggestions, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalWritingSuggestionService().Generate(ctx)
+	})
+	q.Register(JobPluginDeliver, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalPluginService().Deliver(ctx, payload)
+	})
+	q.Register(JobUpdateProfiles, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		updated, err := GetGlobalBehaviorTracker().UpdateActiveProfiles(ctx)
+		return map[string]int{"profiles_updated": updated}, err
+	})
+	q.Register(JobStorageSnapshot, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalStorageService().Snapshot()
+	})
+	q.Register(JobSiteMonitor, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalSiteMonitor().Check(ctx)
+	})
+	q.Register(JobNewsletterSend, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		job, err := decodeNewsletterJob(payload)
+		if err != nil {
+			return nil, err
+		}
+		return GetGlobalNewsletterService().Deliver(ctx, job.NewsletterID)
+	})
+	q.Register(JobNewsletterDigest, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		sent, err := GetGlobalNewsletterService().SendDigests(ctx)
+		return map[string]int{"digests_sent": sent}, err
+	})
+	q.Register(JobNewsletterAIDigest, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalNewsletterService().runAIDigestJob(ctx, payload)
+	})
+	q.Register(JobNewsletterConfirm, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		job, err := decodeNewsletterJob(payload)
+		if err != nil {
+			return nil, err
+		}
+		return nil, GetGlobalNewsletterService().SendConfirmation(job.SubscriberID, job.BaseURL)
+	})
+	q.Register(JobNotifierSend, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalNotifierService().Deliver(ctx, payload)
+	})
+	q.Register(JobActivityPubDeliver, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalActivityPubService().Deliver(ctx, payload)
+	})
+	q.Register(JobWebSubPublish, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalWebSubService().Publish(ctx, payload)
+	})
+	q.Register(JobSearchPush, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalSearchPushService().Deliver(ctx, payload)
+	})
+	q.Register(JobWebmentionSend, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalWebmentionService().Send(ctx, payload)
+	})
+	q.Register(JobWebmentionVerify, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalWebmentionService().Verify(ctx, payload)
+	})
+	q.Register(JobCommentsNotify, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalCommentNotifier().Send(ctx, payload)
+	})
+	q.Register(JobCommentsReply, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalCommentNotifier().SendReply(ctx, payload)
+	})
+	q.Register(JobTranslationRefresh, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalTranslationRefresher().Refresh(ctx, payload)
+	})
+	q.Register(JobDiagramsRender, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalDiagramRenderer().Prerender(ctx, payload)
+	})
+	q.Register(JobContactForward, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalContactService().Forward(ctx, payload)
+	})
+	q.Register(JobFriendLinksCheck, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalFriendLinkService().Check(ctx, payload)
+	})
+	q.Register(JobFeedImport, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalFeedImportService().Fetch(ctx, payload)
+	})
+	q.Register(JobEbookExport, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalEbookService().Export(ctx, payload)
+	})
+	q.Register(JobExpirePins, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		unpinned, err := GetGlobalPinService().ExpirePins(ctx)
+		return map[string]int64{"articles_unpinned": unpinned}, err
+	})
+	q.Register(JobRecountViews, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		updated, err := GetGlobalArticleViewService().Recount(ctx)
+		return map[string]int64{"articles_updated": updated}, err
+	})
+	q.Register(JobAPIKeyUsagePurge, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		deleted, err := GetGlobalAPIKeyService().PurgeUsage(ctx)
+		return map[string]int64{"usage_rows_deleted": deleted}, err
+	})
+	q.Register(JobNotificationDigest, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalNotificationService().SendDigest(ctx)
+	})
+	q.Register(JobSearchRebuild, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalSearchIndexService().Rebuild(ctx)
+	})
+	q.Register(JobTopicCentroids, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return GetGlobalTopicService().Rebuild(ctx)
+	})
+	q.Register(JobDatabaseOptimize, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		var request OptimizePayload
+		if err := json.Unmarshal(payload, &request); err != nil {
+			return nil, err
+		}
+		return GetGlobalDatabaseOptimizer().Run(ctx, request.Steps)
+	})
+}
+
+var (
+	globalJobQueue *JobQueue
+	jobQueueOnce   sync.Once
+)
+
+// GetGlobalJobQueue returns the global job queue
+func GetGlobalJobQueue() *JobQueue {
+	jobQueueOnce.Do(func() {
+		globalJobQueue = NewJobQueue()
+		globalJobQueue.registerBuiltinJobs()
+	})
+	return globalJobQueue
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func setupJobQueueTest(t *testing.T) *JobQueue {
+	t.Helper()
+	newTestDB(t)
+	q := NewJobQueue()
+	q.maxAttempts = 2
+	return q
+}
+
+// runNext claims and runs one due job synchronously
+func runNext(t *testing.T, q *JobQueue) *models.Job {
+	t.Helper()
+	job, err := q.claim()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job == nil {
+		return nil
+	}
+	q.run(job)
+	job, err = q.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestJobQueueRunsJobAndStoresResult(t *testing.T) {
+	q := setupJobQueueTest(t)
+	q.Register("test.echo", func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		var in struct{ N int }
+		json.Unmarshal(payload, &in)
+		return map[string]int{"double": in.N * 2}, nil
+	})
+
+	if _, err := q.Enqueue("test.missing", nil); !errors.Is(err, ErrUnknownJobType) {
+		t.Errorf("Enqueue of unregistered type error = %v", err)
+	}
+	queued, err := q.Enqueue("test.echo", map[string]int{"N": 21})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := runNext(t, q)
+	if job == nil || job.ID != queued.ID {
+		t.Fatalf("expected job %d to run", queued.ID)
+	}
+	if job.Status != models.JobSucceeded || job.Result != `{"double":42}` || job.Attempts != 1 || job.FinishedAt == nil {
+		t.Errorf("unexpected job after run: %+v", job)
+	}
+	if next := runNext(t, q); next != nil {
+		t.Errorf("finished job ran again")
+	}
+}
+
+func TestJobQueueRetriesThenDeadLetters(t *testing.T) {
+	q := setupJobQueueTest(t)
+	calls := 0
+	q.Register("test.fail", func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		return nil, errors.New("still failing")
+	})
+
+	queued, _ := q.Enqueue("test.fail", nil)
+	job := runNext(t, q)
+	if job.Status != models.JobPending || job.LastError == "" || !job.RunAt.After(time.Now()) {
+		t.Fatalf("first failure should be rescheduled with backoff: %+v", job)
+	}
+	if next := runNext(t, q); next != nil {
+		t.Fatalf("job ran before its retry time")
+	}
+
+	// Make the retry due now
+	database.DB.Model(&models.Job{}).Where("id = ?", queued.ID).Update("run_at", time.Now().Add(-time.Second))
+	job = runNext(t, q)
+	if job.Status != models.JobDead || job.Attempts != 2 || job.LastError != "still failing" {
+		t.Fatalf("job should be dead-lettered after max attempts: %+v", job)
+	}
+
+	// An admin retry gives it fresh attempts
+	if _, err := q.Cancel(queued.ID); !errors.Is(err, ErrJobState) {
+		t.Errorf("Cancel of a dead job error = %v", err)
+	}
+	retried, err := q.Retry(queued.ID)
+	if err != nil || retried.Status != models.JobPending || retried.Attempts != 0 {
+		t.Fatalf("Retry = %+v, %v", retried, err)
+	}
+}
+
+func TestJobQueueRecoversStaleJobs(t *testing.T) {
+	q := setupJobQueueTest(t)
+	q.Register("test.noop", func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return nil, nil
+	})
+	queued, _ := q.Enqueue("test.noop", nil)
+	if _, err := q.claim(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The worker holding the job disappears past the timeout
+	database.DB.Model(&models.Job{}).Where("id = ?", queued.ID).Update("locked_at", time.Now().Add(-2*q.timeout))
+	q.recoverStale()
+
+	job, _ := q.Get(queued.ID)
+	if job.Status != models.JobPending || job.LockedBy != "" {
+		t.Fatalf("stale job should be requeued: %+v", job)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	cases := map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 4: 4 * time.Minute, 20: time.Hour}
+	for attempt, want := range cases {
+		if got := retryBackoff(attempt); got != want {
+			t.Errorf("retryBackoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestJobQueuePruneKeepsLatestOfEachType(t *testing.T) {
+	q := setupJobQueueTest(t)
+	q.retention = time.Hour
+	q.Register("test.noop", func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return nil, nil
+	})
+	older, _ := q.Enqueue("test.noop", nil)
+	latest, _ := q.Enqueue("test.noop", nil)
+	runNext(t, q)
+	runNext(t, q)
+	database.DB.Model(&models.Job{}).Where("1 = 1").Update("finished_at", time.Now().Add(-2*time.Hour))
+
+	q.prune()
+	if _, err := q.Get(older.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("old job was not pruned: %v", err)
+	}
+	if job, err := q.Latest("test.noop"); err != nil || job == nil || job.ID != latest.ID {
+		t.Errorf("Latest after prune = %+v, %v", job, err)
+	}
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrKeywordDataNotConfigured is returned when no provider is configured
+var ErrKeywordDataNotConfigured = errors.New("no keyword data provider is configured")
+
+// KeywordDataScheduleName is the scheduler entry for KEYWORD_DATA_SCHEDULE
+const KeywordDataScheduleName = "seo-keyword-data"
+
+// keywordDataUsageType is the service type provider costs are recorded
+// under in the AI usage records
+const keywordDataUsageType = "seo_keywords"
+
+// KeywordDataResult describes one enrichment run
+type KeywordDataResult struct {
+	Provider      string  `json:"provider"`
+	Checked       int     `json:"checked"`
+	Updated       int     `json:"updated"`
+	NoData        int     `json:"no_data"`
+	Cost          float64 `json:"cost"`
+	BudgetReached bool    `json:"budget_reached"`
+}
+
+// KeywordDataStatus reports how current the keyword data is and what the
+// provider cost this month
+type KeywordDataStatus struct {
+	Provider      string     `json:"provider"`
+	Schedule      string     `json:"schedule"`
+	MaxAge        string     `json:"max_age"`
+	BatchSize     int        `json:"batch_size"`
+	MonthlyBudget float64    `json:"monthly_budget"`
+	MonthCost     float64    `json:"month_cost"`
+	Keywords      int64      `json:"keywords"`
+	Stale         int64      `json:"stale"`
+	LastUpdatedAt *time.Time `json:"last_updated_at,omitempty"`
+}
+
+// KeywordDataService fills tracked SEO keywords with the search volume and
+// difficulty of a data provider, replacing the rough estimates they are
+// created with. Keywords whose data is missing or older than the maximum
+// age are refreshed in batches, oldest first, within a monthly budget.
+type KeywordDataService struct {
+	db        func() *gorm.DB
+	provider  KeywordDataProvider
+	cfg       config.KeywordDataConfig
+	now       func() time.Time
+	trackCost func(UsageMetrics) error
+}
+
+// NewKeywordDataService creates a keyword data service from the
+// KEYWORD_DATA_* settings
+func NewKeywordDataService() *KeywordDataService {
+	cfg := config.Get().KeywordData
+	provider, err := NewKeywordDataProvider(cfg)
+	if err != nil {
+		slog.Error("Keyword data provider disabled", "error", err)
+	}
+	return &KeywordDataService{
+		db:        func() *gorm.DB { return database.DB },
+		provider:  provider,
+		cfg:       cfg,
+		now:       time.Now,
+		trackCost: NewAIUsageTracker().TrackUsage,
+	}
+}
+
+// Enabled reports whether a provider is configured
+func (s *KeywordDataService) Enabled() bool {
+	return s.provider != nil
+}
+
+// Enrich refreshes the data of the next batch of active keywords that have
+// none or stale data. Once this month's cost reaches the budget it stops
+// and reports BudgetReached.
+func (s *KeywordDataService) Enrich(ctx context.Context) (*KeywordDataResult, error) {
+	if !s.Enabled() {
+		return nil, ErrKeywordDataNotConfigured
+	}
+	result := &KeywordDataResult{Provider: s.provider.Name()}
+	spent, err := s.monthCost()
+	if err != nil {
+		return nil, err
+	}
+	if s.overBudget(spent) {
+		result.BudgetReached = true
+		return result, nil
+	}
+
+	var keywords []models.SEOKeyword
+	if err := s.StaleScope(s.db().WithContext(ctx)).Where("tracking_status = ?", "active").
+		Order("data_updated_at IS NOT NULL, data_updated_at, id").Limit(s.cfg.BatchSize).
+		Find(&keywords).Error; err != nil {
+		return nil, err
+	}
+
+	// One lookup per language, each keyword text once
+	byLanguage := make(map[string]map[string][]models.SEOKeyword)
+	for _, keyword := range keywords {
+		texts := byLanguage[keyword.Language]
+		if texts == nil {
+			texts = make(map[string][]models.SEOKeyword)
+			byLanguage[keyword.Language] = texts
+		}
+		text := normalizeKeyword(keyword.Keyword)
+		texts[text] = append(texts[text], keyword)
+	}
+	languages := make([]string, 0, len(byLanguage))
+	for language := range byLanguage {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(keywords))
+	for _, language := range languages {
+		if s.overBudget(spent) {
+			result.BudgetReached = true
+			break
+		}
+		texts := make([]string, 0, len(byLanguage[language]))
+		for text := range byLanguage[language] {
+			texts = append(texts, text)
+		}
+		sort.Strings(texts)
+
+		progress.Step("Looking up %d %s keywords", len(texts), language)
+		started := s.now()
+		metrics, cost, err := s.provider.Lookup(ctx, language, texts)
+		spent += cost
+		result.Cost += cost
+		s.recordCost(language, texts, cost, s.now().Sub(started), err)
+		if err != nil {
+			return result, fmt.Errorf("%s lookup for %s keywords failed: %w", s.provider.Name(), language, err)
+		}
+
+		updatedAt := s.now()
+		found := make(map[string]KeywordMetrics, len(metrics))
+		for _, m := range metrics {
+			found[normalizeKeyword(m.Keyword)] = m
+		}
+		for _, text := range texts {
+			rows := byLanguage[language][text]
+			result.Checked += len(rows)
+			m, ok := found[text]
+			updates := map[string]interface{}{"data_source": s.provider.Name(), "data_updated_at": updatedAt}
+			if ok {
+				updates["search_volume"] = m.SearchVolume
+				if m.HasDifficulty {
+					updates["difficulty_score"] = m.Difficulty
+					updates["difficulty"] = difficultyLabel(m.Difficulty)
+				}
+				result.Updated += len(rows)
+			} else {
+				// Providers leave out keywords too rare to report; they are
+				// stamped anyway so the budget is not spent on them again
+				// before the maximum age
+				result.NoData += len(rows)
+			}
+			ids := make([]uint, len(rows))
+			for i, row := range rows {
+				ids[i] = row.ID
+			}
+			if err := s.db().WithContext(ctx).Model(&models.SEOKeyword{}).Where("id IN ?", ids).Updates(updates).Error; err != nil {
+				return result, err
+			}
+			progress.Advance(len(rows))
+		}
+	}
+	if result.BudgetReached {
+		slog.Warn("Keyword data budget reached", "provider", result.Provider, "budget", s.cfg.MonthlyBudget)
+	}
+	return result, nil
+}
+
+// Status reports the provider settings, this month's cost and how many
+// tracked keywords have stale data
+func (s *KeywordDataService) Status() (*KeywordDataStatus, error) {
+	status := &KeywordDataStatus{
+		Schedule:      s.cfg.Schedule,
+		MaxAge:        s.cfg.MaxAge.Std().String(),
+		BatchSize:     s.cfg.BatchSize,
+		MonthlyBudget: s.cfg.MonthlyBudget,
+	}
+	if s.Enabled() {
+		status.Provider = s.provider.Name()
+	}
+	var err error
+	if status.MonthCost, err = s.monthCost(); err != nil {
+		return nil, err
+	}
+	if err := s.db().Model(&models.SEOKeyword{}).Count(&status.Keywords).Error; err != nil {
+		return nil, err
+	}
+	if err := s.StaleScope(s.db().Model(&models.SEOKeyword{})).Count(&status.Stale).Error; err != nil {
+		return nil, err
+	}
+	var latest models.SEOKeyword
+	found := s.db().Where("data_updated_at IS NOT NULL").Order("data_updated_at DESC").Limit(1).Find(&latest)
+	if found.Error != nil {
+		return nil, found.Error
+	}
+	if found.RowsAffected > 0 {
+		status.LastUpdatedAt = latest.DataUpdatedAt
+	}
+	return status, nil
+}
+
+// MarkStale sets DataStale on keywords whose data is missing or older than
+// the maximum age
+func (s *KeywordDataService) MarkStale(keywords []models.SEOKeyword) {
+	cutoff := s.cutoff()
+	for i := range keywords {
+		updated := keywords[i].DataUpdatedAt
+		keywords[i].DataStale = updated == nil || updated.Before(cutoff)
+	}
+}
+
+// StaleScope narrows a keyword query to keywords with stale data
+func (s *KeywordDataService) StaleScope(db *gorm.DB) *gorm.DB {
+	return db.Where("(data_updated_at IS NULL OR data_updated_at < ?)", s.cutoff())
+}
+
+func (s *KeywordDataService) cutoff() time.Time {
+	return s.now().Add(-s.cfg.MaxAge.Std())
+}
+
+// monthCost sums the provider costs recorded since the start of the month
+func (s *KeywordDataService) monthCost() (float64, error) {
+	now := s.now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	var cost float64
+	err := s.db().Model(&models.AIUsageRecord{}).
+		Where("service_type = ? AND created_at >= ?", keywordDataUsageType, start).
+		Select("COALESCE(SUM(estimated_cost), 0)").Scan(&cost).Error
+	return cost, err
+}
+
+func (s *KeywordDataService) overBudget(spent float64) bool {
+	return s.cfg.MonthlyBudget > 0 && spent >= s.cfg.MonthlyBudget
+}
+
+func (s *KeywordDataService) recordCost(language string, keywords []string, cost float64, took time.Duration, lookupErr error) {
+	inputLength := 0
+	for _, keyword := range keywords {
+		inputLength += len(keyword)
+	}
+	usage := UsageMetrics{
+		ServiceType:   keywordDataUsageType,
+		Provider:      s.provider.Name(),
+		Operation:     "keyword_metrics",
+		EstimatedCost: cost,
+		Currency:      "USD",
+		Language:      language,
+		InputLength:   inputLength,
+		ResponseTime:  took,
+		Success:       lookupErr == nil,
+	}
+	if lookupErr != nil {
+		usage.ErrorMessage = lookupErr.Error()
+	}
+	if err := s.trackCost(usage); err != nil {
+		slog.Warn("Failed to record keyword data cost", "error", err)
+	}
+}
+
+// difficultyLabel buckets a 0-100 difficulty score into the labels
+// keywords are filtered by
+func difficultyLabel(score int) string {
+	switch {
+	case score < 30:
+		return "easy"
+	case score < 70:
+		return "medium"
+	default:
+		return "hard"
+	}
+}
+
+// Global keyword data service instance
+var (
+	globalKeywordDataService     *KeywordDataService
+	globalKeywordDataServiceOnce sync.Once
+)
+
+// GetGlobalKeywordDataService returns the global keyword data service
+func GetGlobalKeywordDataService() *KeywordDataService {
+	globalKeywordDataServiceOnce.Do(func() {
+		globalKeywordDataService = NewKeywordDataService()
+	})
+	return globalKeywordDataService
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/security"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeywordMetrics is what a keyword data provider knows about a keyword.
+// Difficulty is on a 0-100 scale; HasDifficulty is false when the provider
+// returned a search volume only.
+type KeywordMetrics struct {
+	Keyword       string `json:"keyword"`
+	SearchVolume  int    `json:"search_volume"`
+	Difficulty    int    `json:"difficulty"`
+	HasDifficulty bool   `json:"has_difficulty"`
+}
+
+// KeywordDataProvider looks up the search volume and difficulty of keywords
+// in one language. cost is what the lookup was billed, in USD.
+type KeywordDataProvider interface {
+	Name() string
+	Lookup(ctx context.Context, language string, keywords []string) (metrics []KeywordMetrics, cost float64, err error)
+}
+
+// NewKeywordDataProvider builds the provider described by the keyword data
+// settings, or returns nil when none is configured
+func NewKeywordDataProvider(cfg config.KeywordDataConfig) (KeywordDataProvider, error) {
+	client := &http.Client{Timeout: 60 * time.Second}
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "dataforseo":
+		password, err := security.ResolveSecret(cfg.DataForSEO.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve DataForSEO password: %v", err)
+		}
+		return &dataForSEOProvider{
+			baseURL:  "https://api.dataforseo.com",
+			login:    cfg.DataForSEO.Login,
+			password: password,
+			location: cfg.Location,
+			client:   client,
+		}, nil
+	case "google_ads":
+		ads := cfg.GoogleAds
+		secrets := []*string{&ads.DeveloperToken, &ads.ClientSecret, &ads.RefreshToken}
+		for _, secret := range secrets {
+			resolved, err := security.ResolveSecret(*secret)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve Google Ads credentials: %v", err)
+			}
+			*secret = resolved
+		}
+		return &googleAdsProvider{
+			baseURL:  "https://googleads.googleapis.com/" + ads.APIVersion,
+			tokenURL: "https://oauth2.googleapis.com/token",
+			cfg:      ads,
+			location: cfg.Location,
+			client:   client,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported keyword data provider %q", cfg.Provider)
+	}
+}
+
+// dataForSEOProvider reads Google Ads search volume and the DataForSEO Labs
+// keyword difficulty, both billed per request
+type dataForSEOProvider struct {
+	baseURL  string
+	login    string
+	password string
+	location int
+	client   *http.Client
+}
+
+// dataForSEOStatusOK is the status_code of a successful request or task
+const dataForSEOStatusOK = 20000
+
+type dataForSEOResponse struct {
+	StatusCode    int     `json:"status_code"`
+	StatusMessage string  `json:"status_message"`
+	Cost          float64 `json:"cost"`
+	Tasks         []struct {
+		StatusCode    int             `json:"status_code"`
+		StatusMessage string          `json:"status_message"`
+		Result        json.RawMessage `json:"result"`
+	} `json:"tasks"`
+}
+
+func (p *dataForSEOProvider) Name() string { return "dataforseo" }
+
+func (p *dataForSEOProvider) Lookup(ctx context.Context, language string, keywords []string) ([]KeywordMetrics, float64, error) {
+	task := []map[string]interface{}{{
+		"keywords":      keywords,
+		"location_code": p.location,
+		"language_code": dataForSEOLanguage(language),
+	}}
+
+	var volumes []struct {
+		Keyword      string `json:"keyword"`
+		SearchVolume *int   `json:"search_volume"`
+	}
+	cost, err := p.post(ctx, "/v3/keywords_data/google_ads/search_volume/live", task, &volumes)
+	if err != nil {
+		return nil, cost, err
+	}
+	var difficulties []struct {
+		Items []struct {
+			Keyword    string `json:"keyword"`
+			Difficulty *int   `json:"keyword_difficulty"`
+		} `json:"items"`
+	}
+	difficultyCost, err := p.post(ctx, "/v3/dataforseo_labs/google/bulk_keyword_difficulty/live", task, &difficulties)
+	cost += difficultyCost
+	if err != nil {
+		return nil, cost, err
+	}
+
+	byKeyword := make(map[string]*KeywordMetrics, len(volumes))
+	var metrics []KeywordMetrics
+	for _, volume := range volumes {
+		if volume.SearchVolume == nil {
+			continue
+		}
+		metrics = append(metrics, KeywordMetrics{Keyword: volume.Keyword, SearchVolume: *volume.SearchVolume})
+	}
+	for i := range metrics {
+		byKeyword[normalizeKeyword(metrics[i].Keyword)] = &metrics[i]
+	}
+	for _, result := range difficulties {
+		for _, item := range result.Items {
+			if m, ok := byKeyword[normalizeKeyword(item.Keyword)]; ok && item.Difficulty != nil {
+				m.Difficulty, m.HasDifficulty = *item.Difficulty, true
+			}
+		}
+	}
+	return metrics, cost, nil
+}
+
+// post sends one task and decodes the result of its first task into out
+func (p *dataForSEOProvider) post(ctx context.Context, path string, task interface{}, out interface{}) (float64, error) {
+	body, err := json.Marshal(task)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.SetBasicAuth(p.login, p.password)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("DataForSEO returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var decoded dataForSEOResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return 0, fmt.Errorf("failed to decode DataForSEO response: %v", err)
+	}
+	switch {
+	case decoded.StatusCode != dataForSEOStatusOK:
+		return decoded.Cost, fmt.Errorf("DataForSEO error %d: %s", decoded.StatusCode, decoded.StatusMessage)
+	case len(decoded.Tasks) == 0:
+		return decoded.Cost, fmt.Errorf("DataForSEO returned no task")
+	case decoded.Tasks[0].StatusCode != dataForSEOStatusOK:
+		return decoded.Cost, fmt.Errorf("DataForSEO task error %d: %s", decoded.Tasks[0].StatusCode, decoded.Tasks[0].StatusMessage)
+	}
+	if len(decoded.Tasks[0].Result) == 0 || string(decoded.Tasks[0].Result) == "null" {
+		return decoded.Cost, nil
+	}
+	if err := json.Unmarshal(decoded.Tasks[0].Result, out); err != nil {
+		return decoded.Cost, fmt.Errorf("failed to decode DataForSEO result: %v", err)
+	}
+	return decoded.Cost, nil
+}
+
+// dataForSEOLanguage maps a blog language to a DataForSEO language code,
+// which names Chinese by its script
+func dataForSEOLanguage(language string) string {
+	switch strings.ToLower(language) {
+	case "zh", "zh-cn", "zh-hans", "zh-sg":
+		return "zh-CN"
+	case "zh-tw", "zh-hant", "zh-hk":
+		return "zh-TW"
+	}
+	base, _, _ := strings.Cut(language, "-")
+	return strings.ToLower(base)
+}
+
+// googleAdsProvider reads the historical metrics of the keyword planner.
+// The planner has no SEO difficulty, so the advertiser competition index,
+// also 0-100, stands in for it. The API itself is free to call.
+type googleAdsProvider struct {
+	baseURL  string
+	tokenURL string
+	cfg      config.GoogleAdsConfig
+	location int
+	client   *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// googleAdsLanguages are the language constants of the keyword planner
+var googleAdsLanguages = map[string]int{
+	"en":    1000,
+	"de":    1001,
+	"fr":    1002,
+	"es":    1003,
+	"it":    1004,
+	"ja":    1005,
+	"ko":    1012,
+	"pt":    1014,
+	"zh":    1017,
+	"zh-cn": 1017,
+	"zh-tw": 1018,
+	"zh-hk": 1018,
+	"ru":    1031,
+}
+
+func (p *googleAdsProvider) Name() string { return "google_ads" }
+
+func (p *googleAdsProvider) Lookup(ctx context.Context, language string, keywords []string) ([]KeywordMetrics, float64, error) {
+	constant, ok := googleAdsLanguages[strings.ToLower(language)]
+	if !ok {
+		base, _, _ := strings.Cut(strings.ToLower(language), "-")
+		if constant, ok = googleAdsLanguages[base]; !ok {
+			return nil, 0, fmt.Errorf("Google Ads has no language constant for %q", language)
+		}
+	}
+	token, err := p.token(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"keywords":           keywords,
+		"language":           fmt.Sprintf("languageConstants/%d", constant),
+		"geoTargetConstants": []string{fmt.Sprintf("geoTargetConstants/%d", p.location)},
+		"keywordPlanNetwork": "GOOGLE_SEARCH",
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	customerID := strings.ReplaceAll(p.cfg.CustomerID, "-", "")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		p.baseURL+"/customers/"+customerID+":generateKeywordHistoricalMetrics", bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("developer-token", p.cfg.DeveloperToken)
+	if p.cfg.LoginCustomerID != "" {
+		req.Header.Set("login-customer-id", strings.ReplaceAll(p.cfg.LoginCustomerID, "-", ""))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, 0, fmt.Errorf("Google Ads returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	// int64 fields are encoded as strings
+	var decoded struct {
+		Results []struct {
+			Text    string `json:"text"`
+			Metrics *struct {
+				AvgMonthlySearches string `json:"avgMonthlySearches"`
+				CompetitionIndex   string `json:"competitionIndex"`
+			} `json:"keywordMetrics"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode Google Ads response: %v", err)
+	}
+	var metrics []KeywordMetrics
+	for _, result := range decoded.Results {
+		if result.Metrics == nil {
+			continue
+		}
+		m := KeywordMetrics{Keyword: result.Text}
+		m.SearchVolume, _ = strconv.Atoi(result.Metrics.AvgMonthlySearches)
+		if index, err := strconv.Atoi(result.Metrics.CompetitionIndex); err == nil {
+			m.Difficulty, m.HasDifficulty = index, true
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, 0, nil
+}
+
+// token returns an OAuth access token, exchanging the refresh token when
+// the last one is about to expire
+func (p *googleAdsProvider) token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.accessToken != "" && time.Until(p.expiresAt) > time.Minute {
+		return p.accessToken, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+		"refresh_token": {p.cfg.RefreshToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		Error       string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode Google OAuth response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", fmt.Errorf("Google OAuth token refresh failed: %s %s", resp.Status, token.Error)
+	}
+	p.accessToken = token.AccessToken
+	p.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return p.accessToken, nil
+}
+
+// normalizeKeyword is the form keywords are matched in, as providers may
+// change their case and spacing
+func normalizeKeyword(keyword string) string {
+	return strings.ToLower(strings.Join(strings.Fields(keyword), " "))
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestKeywordDataEnrich(t *testing.T) {
+	newTestDB(t)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if user, password, _ := r.BasicAuth(); user != "login" || password != "secret" {
+			t.Errorf("request without the credentials")
+		}
+		var tasks []struct {
+			Keywords     []string `json:"keywords"`
+			LanguageCode string   `json:"language_code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&tasks); err != nil || len(tasks) != 1 {
+			t.Errorf("bad request body: %v", err)
+			return
+		}
+		if tasks[0].LanguageCode != "zh-CN" && tasks[0].LanguageCode != "en" {
+			t.Errorf("language_code = %q", tasks[0].LanguageCode)
+		}
+		var result interface{}
+		switch r.URL.Path {
+		case "/v3/keywords_data/google_ads/search_volume/live":
+			var volumes []map[string]interface{}
+			for _, keyword := range tasks[0].Keywords {
+				if keyword != "rare keyword" {
+					volumes = append(volumes, map[string]interface{}{"keyword": keyword, "search_volume": 1000 + len(keyword)})
+				}
+			}
+			result = volumes
+		case "/v3/dataforseo_labs/google/bulk_keyword_difficulty/live":
+			result = []map[string]interface{}{{"items": []map[string]interface{}{{"keyword": "golang", "keyword_difficulty": 82}}}}
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status_code": 20000,
+			"cost":        0.05,
+			"tasks":       []interface{}{map[string]interface{}{"status_code": 20000, "result": result}},
+		})
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	fresh := now.Add(-time.Hour)
+	keywords := []models.SEOKeyword{
+		{Keyword: "Golang", Language: "en", TrackingStatus: "active"},
+		{Keyword: "rare keyword", Language: "en", TrackingStatus: "active"},
+		{Keyword: "缓存", Language: "zh", TrackingStatus: "active"},
+		{Keyword: "paused", Language: "en", TrackingStatus: "paused"},
+		{Keyword: "fresh", Language: "en", TrackingStatus: "active", DataUpdatedAt: &fresh},
+	}
+	for i := range keywords {
+		if err := database.DB.Create(&keywords[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tracked []UsageMetrics
+	s := NewKeywordDataService()
+	s.cfg = config.KeywordDataConfig{MaxAge: config.Duration(24 * time.Hour), BatchSize: 10, MonthlyBudget: 0.15}
+	s.provider = &dataForSEOProvider{baseURL: server.URL, login: "login", password: "secret", location: 2840, client: server.Client()}
+	s.now = func() time.Time { return now }
+	s.trackCost = func(usage UsageMetrics) error {
+		tracked = append(tracked, usage)
+		return database.DB.Create(&models.AIUsageRecord{
+			ServiceType: usage.ServiceType, Provider: usage.Provider, Operation: usage.Operation,
+			EstimatedCost: usage.EstimatedCost, CreatedAt: now,
+		}).Error
+	}
+
+	result, err := s.Enrich(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Checked != 3 || result.Updated != 2 || result.NoData != 1 || result.Cost != 0.2 || result.BudgetReached {
+		t.Errorf("Enrich() = %+v", result)
+	}
+	if requests != 4 || len(tracked) != 2 || tracked[0].Language != "en" || tracked[0].ServiceType != keywordDataUsageType {
+		t.Errorf("%d requests, tracked %+v", requests, tracked)
+	}
+
+	var golang models.SEOKeyword
+	database.DB.First(&golang, keywords[0].ID)
+	if golang.SearchVolume != 1006 || golang.DifficultyScore != 82 || golang.Difficulty != "hard" ||
+		golang.DataSource != "dataforseo" || golang.DataUpdatedAt == nil {
+		t.Errorf("enriched keyword = %+v", golang)
+	}
+
+	// Everything active is fresh now, and the budget is spent anyway
+	result, err = s.Enrich(context.Background())
+	if err != nil || !result.BudgetReached || requests != 4 {
+		t.Errorf("second Enrich() = %+v, %v after %d requests", result, err, requests)
+	}
+
+	status, err := s.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Provider != "dataforseo" || status.Keywords != 5 || status.Stale != 1 || status.MonthCost != 0.2 {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	var listed []models.SEOKeyword
+	database.DB.Order("id").Find(&listed)
+	s.MarkStale(listed)
+	for i, keyword := range listed {
+		if want := keyword.TrackingStatus == "paused"; keyword.DataStale != want {
+			t.Errorf("keyword %d stale = %v, want %v", i, keyword.DataStale, want)
+		}
+	}
+}
+
+func TestGoogleAdsLookup(t *testing.T) {
+	var tokens int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokens++
+			if r.FormValue("refresh_token") != "refresh" {
+				t.Errorf("refresh_token = %q", r.FormValue("refresh_token"))
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "access", "expires_in": 3600})
+		case "/v21/customers/1234567890:generateKeywordHistoricalMetrics":
+			if r.Header.Get("Authorization") != "Bearer access" || r.Header.Get("developer-token") != "dev" || r.Header.Get("login-customer-id") != "111" {
+				t.Errorf("headers = %v", r.Header)
+			}
+			var body struct {
+				Language string `json:"language"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Language != "languageConstants/1005" {
+				t.Errorf("language = %q", body.Language)
+			}
+			w.Write([]byte(`{"results": [
+				{"text": "機械学習", "keywordMetrics": {"avgMonthlySearches": "5400", "competitionIndex": "24"}},
+				{"text": "rare"}
+			]}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	p := &googleAdsProvider{
+		baseURL:  server.URL + "/v21",
+		tokenURL: server.URL + "/token",
+		cfg:      config.GoogleAdsConfig{CustomerID: "123-456-7890", LoginCustomerID: "111", DeveloperToken: "dev", RefreshToken: "refresh"},
+		location: 2392,
+		client:   server.Client(),
+	}
+	for range 2 {
+		metrics, cost, err := p.Lookup(context.Background(), "ja-JP", []string{"機械学習", "rare"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cost != 0 || len(metrics) != 1 || metrics[0].SearchVolume != 5400 || metrics[0].Difficulty != 24 || !metrics[0].HasDifficulty {
+			t.Errorf("Lookup() = %+v, %v", metrics, cost)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("the access token was refreshed %d times", tokens)
+	}
+	if _, _, err := p.Lookup(context.Background(), "xx", []string{"a"}); err == nil {
+		t.Error("Lookup() of a language without a constant succeeded")
+	}
+}
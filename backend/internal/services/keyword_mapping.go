@@ -0,0 +1,390 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultKeywordMatchThreshold is the similarity an article needs to be
+	// assigned a keyword. Keywords are short, so they match articles less
+	// closely than search queries do.
+	DefaultKeywordMatchThreshold = 0.5
+	// DefaultCannibalizationMargin is how close to the best match another
+	// article must come to compete with it for a keyword
+	DefaultCannibalizationMargin = 0.05
+
+	keywordEmbeddingTTL = 30 * 24 * time.Hour
+	// maxMappedKeywords bounds the keywords embedded by one mapping
+	maxMappedKeywords = 500
+)
+
+var (
+	ErrInvalidKeywordMapping = errors.New("invalid keyword mapping request")
+	ErrKeywordEmbedding      = errors.New("keywords could not be embedded")
+)
+
+// Statuses of a keyword in a mapping
+const (
+	KeywordAssigned     = "assigned"
+	KeywordCannibalized = "cannibalized"
+	KeywordUnassigned   = "unassigned"
+)
+
+// KeywordMappingOptions narrow and tune a mapping. Zero values take the
+// defaults and an empty Language maps the keywords of every language.
+type KeywordMappingOptions struct {
+	Language  string
+	Threshold float64
+	Margin    float64
+}
+
+// KeywordArticleMatch is an article matched to a keyword
+type KeywordArticleMatch struct {
+	ArticleID  uint    `json:"article_id"`
+	Title      string  `json:"title"`
+	Similarity float64 `json:"similarity"`
+	// Targeted is set when the article lists the keyword in its SEO
+	// keywords or the keyword is tracked for it
+	Targeted bool `json:"targeted"`
+}
+
+// KeywordMapping is where a tracked keyword belongs. Keywords tracked more
+// than once, for different articles, are mapped once.
+type KeywordMapping struct {
+	Keyword      string               `json:"keyword"`
+	Language     string               `json:"language"`
+	KeywordIDs   []uint               `json:"keyword_ids"`
+	SearchVolume int                  `json:"search_volume"`
+	Status       string               `json:"status"`
+	Article      *KeywordArticleMatch `json:"article,omitempty"`
+	// Competing are the other articles that target the keyword or match it
+	// almost as well as Article
+	Competing []KeywordArticleMatch `json:"competing"`
+}
+
+// KeywordMappingReport maps the tracked keywords to articles
+type KeywordMappingReport struct {
+	Provider     string           `json:"provider"`
+	Model        string           `json:"model"`
+	Threshold    float64          `json:"threshold"`
+	Margin       float64          `json:"margin"`
+	Keywords     []KeywordMapping `json:"keywords"`
+	Assigned     int              `json:"assigned"`
+	Cannibalized int              `json:"cannibalized"`
+	Unassigned   int              `json:"unassigned"`
+	// Truncated is set when more keywords are tracked than one mapping
+	// covers; those with the most search volume are mapped
+	Truncated bool `json:"truncated"`
+}
+
+// KeywordMappingService matches the tracked SEO keywords to the published
+// articles whose embeddings are closest to theirs, to find the keywords
+// several articles compete for and those no article covers yet
+type KeywordMappingService struct {
+	db    func() *gorm.DB
+	now   func() time.Time
+	model func() (string, string)
+	embed func(ctx context.Context, text string) ([]float64, error)
+	// vectors caches keyword embeddings, which do not change for a model
+	vectors *cache.Namespace
+}
+
+// NewKeywordMappingService creates a keyword mapping service using the
+// default embedding provider
+func NewKeywordMappingService() *KeywordMappingService {
+	return &KeywordMappingService{
+		db:    func() *gorm.DB { return database.DB },
+		now:   time.Now,
+		model: func() (string, string) { return GetGlobalEmbeddingService().DefaultModel() },
+		embed: func(ctx context.Context, text string) ([]float64, error) {
+			return GetGlobalEmbeddingService().EmbedText(ctx, text, "seo_keyword_mapping")
+		},
+		vectors: cache.New("keyword_embeddings", keywordEmbeddingTTL),
+	}
+}
+
+// mappingArticle is a published article a keyword can be mapped to
+type mappingArticle struct {
+	id     uint
+	title  string
+	titles map[string]string
+	tags   []string
+}
+
+// Map maps the active tracked keywords. Each keyword is embedded once per
+// model and compared with the combined embeddings of the articles in its
+// language made by the same model.
+func (s *KeywordMappingService) Map(ctx context.Context, opts KeywordMappingOptions) (*KeywordMappingReport, error) {
+	if opts.Threshold == 0 {
+		opts.Threshold = DefaultKeywordMatchThreshold
+	}
+	if opts.Margin == 0 {
+		opts.Margin = DefaultCannibalizationMargin
+	}
+	if opts.Threshold < 0 || opts.Threshold > 1 {
+		return nil, fmt.Errorf("%w: threshold must be between 0 and 1", ErrInvalidKeywordMapping)
+	}
+	if opts.Margin < 0 || opts.Margin > 1 {
+		return nil, fmt.Errorf("%w: margin must be between 0 and 1", ErrInvalidKeywordMapping)
+	}
+	provider, model := s.model()
+	report := &KeywordMappingReport{
+		Provider:  provider,
+		Model:     model,
+		Threshold: opts.Threshold,
+		Margin:    opts.Margin,
+		Keywords:  []KeywordMapping{},
+	}
+
+	mappings, err := s.trackedKeywords(ctx, opts.Language)
+	if err != nil {
+		return nil, err
+	}
+	if len(mappings) > maxMappedKeywords {
+		mappings, report.Truncated = mappings[:maxMappedKeywords], true
+	}
+	if len(mappings) == 0 {
+		return report, nil
+	}
+	tracked, err := s.trackedArticles(ctx, mappings)
+	if err != nil {
+		return nil, err
+	}
+	articles, err := s.articles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make(map[string]map[uint][]float64)
+	for i := range mappings {
+		mapping := &mappings[i]
+		if _, ok := vectors[mapping.Language]; !ok {
+			if vectors[mapping.Language], err = s.articleVectors(ctx, mapping.Language, provider, model); err != nil {
+				return nil, err
+			}
+		}
+		vector, err := s.keywordVector(ctx, provider, model, mapping.Keyword)
+		if err != nil {
+			return nil, err
+		}
+		s.assign(mapping, vector, articles, vectors[mapping.Language], tracked[mappingKey(mapping)], opts)
+		switch mapping.Status {
+		case KeywordAssigned:
+			report.Assigned++
+		case KeywordCannibalized:
+			report.Cannibalized++
+		default:
+			report.Unassigned++
+		}
+	}
+	report.Keywords = mappings
+	return report, nil
+}
+
+// assign picks the article closest to the keyword and the articles that
+// compete with it
+func (s *KeywordMappingService) assign(mapping *KeywordMapping, vector []float64, articles map[uint]*mappingArticle, vectors map[uint][]float64, tracked map[uint]bool, opts KeywordMappingOptions) {
+	var matches []KeywordArticleMatch
+	for id, article := range articles {
+		match := KeywordArticleMatch{
+			ArticleID: id,
+			Title:     article.title,
+			Targeted:  tracked[id] || containsString(article.tags, mapping.Keyword),
+		}
+		if title := article.titles[mapping.Language]; title != "" {
+			match.Title = title
+		}
+		if articleVector, ok := vectors[id]; ok && len(articleVector) == len(vector) {
+			match.Similarity = roundTo(cosineSimilarity(vector, articleVector), 4)
+		}
+		if match.Targeted || match.Similarity >= opts.Threshold {
+			matches = append(matches, match)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Similarity != matches[j].Similarity {
+			return matches[i].Similarity > matches[j].Similarity
+		}
+		return matches[i].ArticleID < matches[j].ArticleID
+	})
+
+	mapping.Status = KeywordUnassigned
+	mapping.Competing = []KeywordArticleMatch{}
+	if len(matches) == 0 || matches[0].Similarity < opts.Threshold {
+		// Articles that target the keyword without covering it are listed,
+		// but the keyword still needs content
+		mapping.Competing = append(mapping.Competing, matches...)
+		return
+	}
+	best := matches[0]
+	mapping.Article = &best
+	mapping.Status = KeywordAssigned
+	for _, match := range matches[1:] {
+		if match.Targeted || match.Similarity >= best.Similarity-opts.Margin {
+			mapping.Competing = append(mapping.Competing, match)
+		}
+	}
+	if len(mapping.Competing) > 0 {
+		mapping.Status = KeywordCannibalized
+	}
+}
+
+// trackedKeywords returns the active tracked keywords, one mapping per
+// keyword and language, with the most searched first
+func (s *KeywordMappingService) trackedKeywords(ctx context.Context, language string) ([]KeywordMapping, error) {
+	query := s.db().WithContext(ctx).Model(&models.SEOKeyword{}).
+		Select("id", "keyword", "language", "search_volume").
+		Where("tracking_status = ?", "active")
+	if language != "" {
+		query = query.Where("language = ?", language)
+	}
+	var keywords []models.SEOKeyword
+	if err := query.Order("id").Find(&keywords).Error; err != nil {
+		return nil, err
+	}
+	index := make(map[string]int)
+	var mappings []KeywordMapping
+	for _, keyword := range keywords {
+		text := strings.ToLower(strings.Join(strings.Fields(keyword.Keyword), " "))
+		if text == "" {
+			continue
+		}
+		key := keyword.Language + "|" + text
+		i, ok := index[key]
+		if !ok {
+			i = len(mappings)
+			index[key] = i
+			mappings = append(mappings, KeywordMapping{Keyword: text, Language: keyword.Language})
+		}
+		mappings[i].KeywordIDs = append(mappings[i].KeywordIDs, keyword.ID)
+		mappings[i].SearchVolume = max(mappings[i].SearchVolume, keyword.SearchVolume)
+	}
+	sort.SliceStable(mappings, func(i, j int) bool { return mappings[i].SearchVolume > mappings[j].SearchVolume })
+	return mappings, nil
+}
+
+// trackedArticles returns the articles each mapping's keywords are
+// tracked for, by mappingKey
+func (s *KeywordMappingService) trackedArticles(ctx context.Context, mappings []KeywordMapping) (map[string]map[uint]bool, error) {
+	var ids []uint
+	byID := make(map[uint]string)
+	for _, mapping := range mappings {
+		for _, id := range mapping.KeywordIDs {
+			ids = append(ids, id)
+			byID[id] = mappingKey(&mapping)
+		}
+	}
+	var keywords []models.SEOKeyword
+	if err := s.db().WithContext(ctx).Select("id", "article_id").
+		Where("id IN ? AND article_id IS NOT NULL", ids).Find(&keywords).Error; err != nil {
+		return nil, err
+	}
+	tracked := make(map[string]map[uint]bool)
+	for _, keyword := range keywords {
+		key := byID[keyword.ID]
+		if tracked[key] == nil {
+			tracked[key] = make(map[uint]bool)
+		}
+		tracked[key][*keyword.ArticleID] = true
+	}
+	return tracked, nil
+}
+
+func mappingKey(mapping *KeywordMapping) string {
+	return mapping.Language + "|" + mapping.Keyword
+}
+
+// articles returns the site's published articles with their titles in
+// every language and their tags
+func (s *KeywordMappingService) articles(ctx context.Context) (map[uint]*mappingArticle, error) {
+	var articles []models.Article
+	if err := s.db().WithContext(ctx).Select("id", "title", "seo_keywords").
+		Where("created_at <= ?", s.now()).Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*mappingArticle, len(articles))
+	ids := make([]uint, 0, len(articles))
+	for _, article := range articles {
+		byID[article.ID] = &mappingArticle{
+			id:     article.ID,
+			title:  article.Title,
+			titles: make(map[string]string),
+			tags:   ArticleTags(article.SEOKeywords),
+		}
+		ids = append(ids, article.ID)
+	}
+	if len(ids) == 0 {
+		return byID, nil
+	}
+	var translations []models.ArticleTranslation
+	if err := s.db().WithContext(ctx).Select("article_id", "language", "title").
+		Where("article_id IN ?", ids).Find(&translations).Error; err != nil {
+		return nil, err
+	}
+	for _, translation := range translations {
+		if article, ok := byID[translation.ArticleID]; ok && translation.Title != "" {
+			article.titles[translation.Language] = translation.Title
+		}
+	}
+	return byID, nil
+}
+
+// articleVectors returns the combined embeddings of the articles in
+// language made by the model
+func (s *KeywordMappingService) articleVectors(ctx context.Context, language, provider, model string) (map[uint][]float64, error) {
+	var embeddings []models.ArticleEmbedding
+	if err := s.db().WithContext(ctx).Select("article_id", "embedding").
+		Where("language = ? AND content_type = ? AND provider = ? AND model = ?", language, "combined", provider, model).
+		Find(&embeddings).Error; err != nil {
+		return nil, err
+	}
+	vectors := make(map[uint][]float64, len(embeddings))
+	for _, embedding := range embeddings {
+		var vector []float64
+		if err := json.Unmarshal([]byte(embedding.Embedding), &vector); err == nil && len(vector) > 0 {
+			vectors[embedding.ArticleID] = vector
+		}
+	}
+	return vectors, nil
+}
+
+// keywordVector returns the embedding of a keyword, generated once per
+// model
+func (s *KeywordMappingService) keywordVector(ctx context.Context, provider, model, keyword string) ([]float64, error) {
+	key := provider + "|" + model + "|" + keyword
+	var vector []float64
+	if s.vectors.Get(key, &vector) {
+		return vector, nil
+	}
+	vector, err := s.embed(ctx, keyword)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrKeywordEmbedding, keyword, err)
+	}
+	s.vectors.Set(key, vector)
+	return vector, nil
+}
+
+var (
+	globalKeywordMappingService     *KeywordMappingService
+	globalKeywordMappingServiceOnce sync.Once
+)
+
+// GetGlobalKeywordMappingService returns the global keyword mapping service
+func GetGlobalKeywordMappingService() *KeywordMappingService {
+	globalKeywordMappingServiceOnce.Do(func() {
+		globalKeywordMappingService = NewKeywordMappingService()
+	})
+	return globalKeywordMappingService
+}
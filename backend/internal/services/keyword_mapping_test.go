@@ -0,0 +1,140 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestKeywordMapping(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+
+	category := models.Category{Name: "Tech", DefaultLang: "en"}
+	database.DB.Create(&category)
+	articles := []struct {
+		article models.Article
+		vector  []float64
+	}{
+		{models.Article{Title: "Indexes", SEOKeywords: "sql"}, []float64{1, 0, 0}},
+		{models.Article{Title: "Query plans"}, []float64{0.98, 0.2, 0}},
+		{models.Article{Title: "Colors", SEOKeywords: "Postgres indexing"}, []float64{0, 0, 1}},
+	}
+	for i := range articles {
+		articles[i].article.CategoryID = category.ID
+		articles[i].article.DefaultLang = "en"
+		if err := database.DB.Create(&articles[i].article).Error; err != nil {
+			t.Fatal(err)
+		}
+		encoded, _ := json.Marshal(articles[i].vector)
+		database.DB.Create(&models.ArticleEmbedding{
+			ArticleID: articles[i].article.ID, Language: "en", ContentType: "combined",
+			Provider: "openai", Model: "small", Embedding: string(encoded), Dimensions: 3,
+		})
+	}
+	database.DB.Create(&models.ArticleTranslation{ArticleID: articles[0].article.ID, Language: "zh", Title: "索引"})
+	indexes, colors := articles[0].article.ID, articles[2].article.ID
+
+	keywords := []models.SEOKeyword{
+		{Keyword: "SQL tuning", Language: "en", SearchVolume: 900, ArticleID: &indexes},
+		{Keyword: "sql  Tuning", Language: "en", SearchVolume: 100},
+		{Keyword: "color theory", Language: "en", SearchVolume: 500},
+		{Keyword: "kubernetes", Language: "en", SearchVolume: 300},
+		{Keyword: "postgres indexing", Language: "en", SearchVolume: 200},
+		{Keyword: "sql tuning", Language: "zh", SearchVolume: 50},
+		{Keyword: "paused", Language: "en", TrackingStatus: "paused"},
+	}
+	for i := range keywords {
+		if err := database.DB.Create(&keywords[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vectors := map[string][]float64{
+		"sql tuning":        {1, 0, 0},
+		"color theory":      {0, 0, 1},
+		"kubernetes":        {0, 1, 0},
+		"postgres indexing": {0.7, 0.7, 0},
+	}
+	calls := 0
+	s := &KeywordMappingService{
+		db:    func() *gorm.DB { return database.DB },
+		now:   time.Now,
+		model: func() (string, string) { return "openai", "small" },
+		embed: func(ctx context.Context, text string) ([]float64, error) {
+			calls++
+			if vector, ok := vectors[text]; ok {
+				return vector, nil
+			}
+			return nil, errors.New("provider down")
+		},
+		vectors: cache.New("test_keyword_embeddings", time.Minute),
+	}
+
+	report, err := s.Map(ctx, KeywordMappingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Keywords) != 5 || report.Assigned != 1 || report.Cannibalized != 2 || report.Unassigned != 2 {
+		t.Fatalf("Map() = %+v", report)
+	}
+	byKey := make(map[string]KeywordMapping)
+	for _, mapping := range report.Keywords {
+		byKey[mapping.Language+"|"+mapping.Keyword] = mapping
+	}
+
+	// Tracked twice and matched nearly as well by a second article
+	tuning := byKey["en|sql tuning"]
+	if report.Keywords[0].Keyword != "sql tuning" || len(tuning.KeywordIDs) != 2 || tuning.SearchVolume != 900 {
+		t.Errorf("sql tuning = %+v, want both tracked keywords, first by search volume", tuning)
+	}
+	if tuning.Status != KeywordCannibalized || tuning.Article.Title != "Indexes" || !tuning.Article.Targeted ||
+		len(tuning.Competing) != 1 || tuning.Competing[0].Title != "Query plans" {
+		t.Errorf("sql tuning = %+v", tuning)
+	}
+	// An article with the keyword in its SEO keywords competes however far
+	// its content is
+	postgres := byKey["en|postgres indexing"]
+	if postgres.Status != KeywordCannibalized || postgres.Article.Title != "Query plans" ||
+		len(postgres.Competing) != 1 || postgres.Competing[0].ArticleID != colors || !postgres.Competing[0].Targeted {
+		t.Errorf("postgres indexing = %+v", postgres)
+	}
+	if colorTheory := byKey["en|color theory"]; colorTheory.Status != KeywordAssigned || colorTheory.Article.ArticleID != colors {
+		t.Errorf("color theory = %+v", colorTheory)
+	}
+	if kubernetes := byKey["en|kubernetes"]; kubernetes.Status != KeywordUnassigned || kubernetes.Article != nil {
+		t.Errorf("kubernetes = %+v, want new content needed", kubernetes)
+	}
+	// No article has a Chinese embedding, though one is tracked for it
+	if zh := byKey["zh|sql tuning"]; zh.Status != KeywordUnassigned || len(zh.Competing) != 0 {
+		t.Errorf("zh sql tuning = %+v", zh)
+	}
+
+	// Keyword embeddings are generated once
+	before := calls
+	report, err = s.Map(ctx, KeywordMappingOptions{Language: "en", Threshold: 0.9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != before {
+		t.Errorf("%d more embeddings generated, want the cached ones", calls-before)
+	}
+	if len(report.Keywords) != 4 || report.Unassigned != 2 {
+		t.Errorf("Map(en, 0.9) = %+v", report)
+	}
+
+	if _, err := s.Map(ctx, KeywordMappingOptions{Threshold: 2}); !errors.Is(err, ErrInvalidKeywordMapping) {
+		t.Errorf("threshold 2: %v", err)
+	}
+	database.DB.Create(&models.SEOKeyword{Keyword: "unknown", Language: "en"})
+	if _, err := s.Map(ctx, KeywordMappingOptions{}); !errors.Is(err, ErrKeywordEmbedding) {
+		t.Errorf("failing provider: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Where a negotiated visitor language came from
+const (
+	LanguageSourcePreference     = "preference"
+	LanguageSourceAcceptLanguage = "accept_language"
+	LanguageSourceGeoIP          = "geoip"
+	LanguageSourceDefault        = "default"
+)
+
+// countryLanguages is the language most visitors from a country read, for
+// visitors whose browser asks for no language the site has
+var countryLanguages = map[string]string{
+	"CN": "zh", "TW": "zh", "HK": "zh", "MO": "zh", "SG": "zh",
+	"US": "en", "GB": "en", "AU": "en", "CA": "en", "NZ": "en", "IE": "en",
+	"JP": "ja",
+	"KR": "ko",
+	"ES": "es", "MX": "es", "AR": "es", "CO": "es", "CL": "es", "PE": "es", "VE": "es",
+	"FR": "fr",
+	"DE": "de", "AT": "de",
+	"IT": "it",
+	"PT": "pt", "BR": "pt",
+	"RU": "ru", "BY": "ru", "KZ": "ru",
+	"SA": "ar", "AE": "ar", "EG": "ar", "MA": "ar", "DZ": "ar", "IQ": "ar", "JO": "ar", "QA": "ar", "KW": "ar",
+	"IN": "hi",
+}
+
+// VisitorLanguage is the content language chosen for a visitor
+type VisitorLanguage struct {
+	Language string `json:"language"`
+	Source   string `json:"source"`
+}
+
+// NegotiateLanguage picks the content language for a visitor from the
+// languages the site is enabled for: the stored preference, else the
+// browser's Accept-Language in order of preference, else the language of
+// the visitor's country, else the site default. A regional tag such as
+// zh-TW or pt-BR falls back to its base language. country is only called
+// when the browser asks for no enabled language, as a lookup may be slow.
+func NegotiateLanguage(enabled []string, defaultLanguage, preference, acceptLanguage string, country func() string) VisitorLanguage {
+	if language := matchLanguage(enabled, preference); language != "" {
+		return VisitorLanguage{language, LanguageSourcePreference}
+	}
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if language := matchLanguage(enabled, tag); language != "" {
+			return VisitorLanguage{language, LanguageSourceAcceptLanguage}
+		}
+	}
+	if country != nil {
+		if language := matchLanguage(enabled, countryLanguages[strings.ToUpper(country())]); language != "" {
+			return VisitorLanguage{language, LanguageSourceGeoIP}
+		}
+	}
+	return VisitorLanguage{defaultLanguage, LanguageSourceDefault}
+}
+
+// matchLanguage returns the enabled language for a language tag, trying
+// the tag itself, then its base language
+func matchLanguage(enabled []string, tag string) string {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if tag == "" {
+		return ""
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	for _, candidate := range []string{tag, base} {
+		for _, language := range enabled {
+			if strings.ToLower(language) == candidate {
+				return language
+			}
+		}
+	}
+	return ""
+}
+
+// parseAcceptLanguage returns the language tags of an Accept-Language
+// header, most preferred first, leaving out the wildcard and tags with a
+// zero quality
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			tags = append(tags, weighted{tag, quality})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+	result := make([]string, len(tags))
+	for i, tag := range tags {
+		result[i] = tag.tag
+	}
+	return result
+}
@@ -0,0 +1,37 @@
+package services
+
+import "testing"
+
+func TestNegotiateLanguage(t *testing.T) {
+	enabled := []string{"zh", "en", "ja", "pt"}
+	for _, tc := range []struct {
+		name           string
+		preference     string
+		acceptLanguage string
+		country        string
+		want           VisitorLanguage
+	}{
+		{"preference wins", "ja", "en-US,en;q=0.9", "CN", VisitorLanguage{"ja", LanguageSourcePreference}},
+		{"disabled preference is ignored", "fr", "en", "", VisitorLanguage{"en", LanguageSourceAcceptLanguage}},
+		{"quality order", "", "de;q=0.2, ja;q=0.8, en;q=0.5", "", VisitorLanguage{"ja", LanguageSourceAcceptLanguage}},
+		{"region falls back to base", "", "zh-TW,fr;q=0.9", "", VisitorLanguage{"zh", LanguageSourceAcceptLanguage}},
+		{"underscore tags", "", "pt_BR", "", VisitorLanguage{"pt", LanguageSourceAcceptLanguage}},
+		{"zero quality is refused", "", "en;q=0, *", "JP", VisitorLanguage{"ja", LanguageSourceGeoIP}},
+		{"country", "", "ko-KR", "br", VisitorLanguage{"pt", LanguageSourceGeoIP}},
+		{"default", "", "", "Unknown", VisitorLanguage{"zh", LanguageSourceDefault}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			looked := false
+			got := NegotiateLanguage(enabled, "zh", tc.preference, tc.acceptLanguage, func() string {
+				looked = true
+				return tc.country
+			})
+			if got != tc.want {
+				t.Errorf("NegotiateLanguage = %+v, want %+v", got, tc.want)
+			}
+			if looked != (tc.want.Source == LanguageSourceGeoIP || tc.want.Source == LanguageSourceDefault) {
+				t.Errorf("country looked up = %v for a %s match", looked, tc.want.Source)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// LanguageArticle is one of the most read articles in a language, titled
+// in it
+type LanguageArticle struct {
+	ID        uint   `json:"id"`
+	Title     string `json:"title"`
+	ViewCount uint   `json:"view_count"`
+}
+
+// LanguageStats is how much of a site can be read in one language
+type LanguageStats struct {
+	Language string `json:"language"`
+	// Original counts the articles written in the language and Translated
+	// those translated into it
+	Original   int64 `json:"original"`
+	Translated int64 `json:"translated"`
+	// Coverage is the share of all articles readable in the language, from
+	// 0 to 1
+	Coverage float64 `json:"coverage"`
+	// Views is the views of the articles written in the language
+	Views       int64             `json:"views"`
+	TopArticles []LanguageArticle `json:"top_articles,omitempty"`
+}
+
+// GetLanguageStats counts the articles of a site by the languages they can
+// be read in, most readable language first. With top above 0 each language
+// lists its most read articles, at one more query per language. db must be
+// scoped to the site.
+func GetLanguageStats(db *gorm.DB, top int) ([]LanguageStats, error) {
+	var total int64
+	var originals []struct {
+		Language string
+		Count    int64
+		Views    int64
+	}
+	if err := db.Model(&models.Article{}).
+		Select("default_lang AS language, COUNT(*) AS count, COALESCE(SUM(view_count), 0) AS views").
+		Group("default_lang").Scan(&originals).Error; err != nil {
+		return nil, err
+	}
+	var translated []struct {
+		Language string
+		Count    int64
+	}
+	// Translations into an article's own language add nothing readable
+	if err := db.Model(&models.ArticleTranslation{}).
+		Select("article_translations.language AS language, COUNT(DISTINCT article_translations.article_id) AS count").
+		Joins("JOIN articles ON articles.id = article_translations.article_id AND articles.deleted_at IS NULL").
+		Where("article_translations.language <> articles.default_lang").
+		Group("article_translations.language").Scan(&translated).Error; err != nil {
+		return nil, err
+	}
+
+	byLanguage := make(map[string]*LanguageStats)
+	language := func(code string) *LanguageStats {
+		stats, ok := byLanguage[code]
+		if !ok {
+			stats = &LanguageStats{Language: code}
+			byLanguage[code] = stats
+		}
+		return stats
+	}
+	for _, row := range originals {
+		stats := language(row.Language)
+		stats.Original, stats.Views = row.Count, row.Views
+		total += row.Count
+	}
+	for _, row := range translated {
+		language(row.Language).Translated = row.Count
+	}
+
+	languages := make([]LanguageStats, 0, len(byLanguage))
+	for _, stats := range byLanguage {
+		if total > 0 {
+			stats.Coverage = float64(stats.Original+stats.Translated) / float64(total)
+		}
+		if top > 0 {
+			articles, err := TopArticlesInLanguage(db, stats.Language, top)
+			if err != nil {
+				return nil, err
+			}
+			stats.TopArticles = articles
+		}
+		languages = append(languages, *stats)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		a, b := languages[i], languages[j]
+		if a.Original+a.Translated != b.Original+b.Translated {
+			return a.Original+a.Translated > b.Original+b.Translated
+		}
+		return a.Language < b.Language
+	})
+	return languages, nil
+}
+
+// TopArticlesInLanguage returns the most read articles that can be read in
+// language, written in it or translated into it, titled in it. db must be
+// scoped to the site.
+func TopArticlesInLanguage(db *gorm.DB, language string, limit int) ([]LanguageArticle, error) {
+	articles := []LanguageArticle{}
+	err := db.Model(&models.Article{}).
+		Select("articles.id, COALESCE(NULLIF(article_translations.title, ''), articles.title) AS title, articles.view_count").
+		Joins("LEFT JOIN article_translations ON article_translations.article_id = articles.id AND article_translations.language = ? AND articles.default_lang <> ?", language, language).
+		Where("articles.default_lang = ? OR article_translations.id IS NOT NULL", language).
+		Order("articles.view_count DESC, articles.id DESC").
+		Limit(limit).
+		Scan(&articles).Error
+	return articles, err
+}
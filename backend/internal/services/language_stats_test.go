@@ -0,0 +1,55 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"testing"
+)
+
+func TestLanguageStats(t *testing.T) {
+	newTestDB(t)
+	for _, article := range []models.Article{
+		{Title: "你好", DefaultLang: "zh", ViewCount: 10, Translations: []models.ArticleTranslation{{Language: "en", Title: "Hello"}}},
+		{Title: "世界", DefaultLang: "zh", ViewCount: 30, Translations: []models.ArticleTranslation{{Language: "zh", Title: "世界"}}},
+		{Title: "Only English", DefaultLang: "en", ViewCount: 5},
+		{Title: "Bonjour", DefaultLang: "fr", ViewCount: 1, Translations: []models.ArticleTranslation{{Language: "en", Title: ""}}},
+	} {
+		if err := database.DB.Create(&article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	languages, err := GetLanguageStats(database.DB, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(languages) != 3 {
+		t.Fatalf("languages = %+v", languages)
+	}
+	en, zh, fr := languages[0], languages[1], languages[2]
+	if en.Language != "en" || en.Original != 1 || en.Translated != 2 || en.Coverage != 0.75 || en.Views != 5 {
+		t.Errorf("en = %+v", en)
+	}
+	// A translation into the article's own language adds nothing
+	if zh.Language != "zh" || zh.Original != 2 || zh.Translated != 0 || zh.Coverage != 0.5 || zh.Views != 40 {
+		t.Errorf("zh = %+v", zh)
+	}
+	if fr.Language != "fr" || fr.Original != 1 || fr.Coverage != 0.25 {
+		t.Errorf("fr = %+v", fr)
+	}
+
+	// Titled in the language, falling back to the original title
+	if len(en.TopArticles) != 2 || en.TopArticles[0].Title != "Hello" || en.TopArticles[1].Title != "Only English" {
+		t.Errorf("en top = %+v", en.TopArticles)
+	}
+	if len(zh.TopArticles) != 2 || zh.TopArticles[0].Title != "世界" || zh.TopArticles[1].Title != "你好" {
+		t.Errorf("zh top = %+v", zh.TopArticles)
+	}
+	top, err := TopArticlesInLanguage(database.DB, "en", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 3 || top[2].Title != "Bonjour" {
+		t.Errorf("en top 5 = %+v", top)
+	}
+}
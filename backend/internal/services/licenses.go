@@ -0,0 +1,99 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Licenses an article can be published under
+const (
+	LicenseAllRightsReserved = "all-rights-reserved"
+	LicenseCCBY              = "cc-by-4.0"
+	LicenseCCBYNC            = "cc-by-nc-4.0"
+	LicenseCustom            = "custom"
+)
+
+// maxLicenseTerms is the longest custom license in characters; longer
+// terms belong on a page the license links to
+const maxLicenseTerms = 500
+
+// ErrInvalidLicense is returned for an unknown license or a custom license
+// without terms
+var ErrInvalidLicense = errors.New("invalid license")
+
+var licenses = map[string]models.ContentLicense{
+	LicenseAllRightsReserved: {Code: LicenseAllRightsReserved, Name: "All rights reserved"},
+	LicenseCCBY:              {Code: LicenseCCBY, Name: "CC BY 4.0", URL: "https://creativecommons.org/licenses/by/4.0/"},
+	LicenseCCBYNC:            {Code: LicenseCCBYNC, Name: "CC BY-NC 4.0", URL: "https://creativecommons.org/licenses/by-nc/4.0/"},
+}
+
+// NormalizeLicense checks a license code and the terms of a custom license,
+// returning them trimmed. The terms are kept only for custom licenses. An
+// empty code is accepted when optional, for articles following the site.
+func NormalizeLicense(code, custom string, optional bool) (string, string, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	custom = strings.TrimSpace(custom)
+	switch {
+	case code == "" && optional:
+		return "", "", nil
+	case code == LicenseCustom:
+		if custom == "" || utf8.RuneCountInString(custom) > maxLicenseTerms || strings.ContainsAny(custom, "\r\n") {
+			return "", "", fmt.Errorf("%w: a custom license needs its terms on one line of at most %d characters", ErrInvalidLicense, maxLicenseTerms)
+		}
+		return code, custom, nil
+	case licenses[code].Code != "":
+		return code, "", nil
+	}
+	return "", "", fmt.Errorf("%w: license must be %s, %s, %s or %s", ErrInvalidLicense,
+		LicenseAllRightsReserved, LicenseCCBY, LicenseCCBYNC, LicenseCustom)
+}
+
+// ResolveLicense returns the license the article is under: its own, or
+// the site's default
+func ResolveLicense(article *models.Article, settings *models.SiteSettings) models.ContentLicense {
+	code, custom := article.License, article.LicenseCustom
+	if code == "" {
+		code, custom = settings.DefaultLicense, settings.DefaultLicenseCustom
+	}
+	if code == LicenseCustom && custom != "" {
+		license := models.ContentLicense{Code: LicenseCustom, Name: custom}
+		if validMediaURL(custom) && !strings.HasPrefix(custom, "/") {
+			license.URL = custom
+		}
+		return license
+	}
+	if license, ok := licenses[code]; ok {
+		return license
+	}
+	return licenses[LicenseAllRightsReserved]
+}
+
+// ApplyLicenses fills in the license each of the articles is under, reading
+// the site's default from db
+func ApplyLicenses(db *gorm.DB, articles []models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	var settings models.SiteSettings
+	if err := db.Select("default_license", "default_license_custom").Limit(1).Find(&settings).Error; err != nil {
+		return err
+	}
+	for i := range articles {
+		license := ResolveLicense(&articles[i], &settings)
+		articles[i].LicenseInfo = &license
+	}
+	return nil
+}
+
+// LicenseText describes a license in one line, with the URL of its terms
+func LicenseText(license models.ContentLicense) string {
+	if license.URL != "" && license.URL != license.Name {
+		return fmt.Sprintf("%s (%s)", license.Name, license.URL)
+	}
+	return license.Name
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLicenses(t *testing.T) {
+	for _, test := range []struct {
+		code, custom string
+		optional     bool
+		want         string
+	}{
+		{"", "", true, ""},
+		{" CC-BY-4.0 ", "ignored", false, LicenseCCBY},
+		{"custom", " Quote freely with a link ", false, LicenseCustom},
+	} {
+		code, custom, err := NormalizeLicense(test.code, test.custom, test.optional)
+		if err != nil || code != test.want || (code != LicenseCustom && custom != "") {
+			t.Errorf("NormalizeLicense(%q, %q) = %q, %q, %v", test.code, test.custom, code, custom, err)
+		}
+	}
+	for _, bad := range [][2]string{{"", ""}, {"mit", ""}, {"custom", ""}, {"custom", "two\nlines"}, {"custom", strings.Repeat("x", 501)}} {
+		if _, _, err := NormalizeLicense(bad[0], bad[1], false); !errors.Is(err, ErrInvalidLicense) {
+			t.Errorf("NormalizeLicense(%q, %q) = %v, want ErrInvalidLicense", bad[0], bad[1], err)
+		}
+	}
+
+	settings := &models.SiteSettings{DefaultLicense: LicenseCCBYNC}
+	if license := ResolveLicense(&models.Article{}, settings); license.Code != LicenseCCBYNC || license.URL == "" {
+		t.Errorf("article following the site = %+v", license)
+	}
+	article := &models.Article{License: LicenseCustom, LicenseCustom: "https://example.com/terms"}
+	if license := ResolveLicense(article, settings); license.URL != "https://example.com/terms" || LicenseText(license) != "https://example.com/terms" {
+		t.Errorf("custom license = %+v", license)
+	}
+	if license := ResolveLicense(&models.Article{}, &models.SiteSettings{}); license.Code != LicenseAllRightsReserved {
+		t.Errorf("without a default = %+v", license)
+	}
+	if text := LicenseText(licenses[LicenseCCBY]); text != "CC BY 4.0 (https://creativecommons.org/licenses/by/4.0/)" {
+		t.Errorf("LicenseText() = %q", text)
+	}
+}
@@ -0,0 +1,478 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/security"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	linkPreviewTimeout      = 10 * time.Second
+	linkPreviewMaxBody      = 1 << 20
+	linkPreviewMaxRedirects = 5
+	linkPreviewMaxURL       = 2048
+	// linkPreviewTTL is how long a preview is kept, and linkPreviewRetry how
+	// long a failed one is before the page is fetched again
+	linkPreviewTTL   = 24 * time.Hour
+	linkPreviewRetry = 10 * time.Minute
+)
+
+var (
+	ErrInvalidPreviewURL  = errors.New("URL must be an absolute http(s) address on port 80 or 443")
+	ErrPreviewBlocked     = errors.New("URL points to a private or local address")
+	ErrPreviewUnavailable = errors.New("link preview unavailable")
+)
+
+// LinkPreview is what a page tells about itself through oEmbed and
+// OpenGraph. EmbedURL is the https address of the player or post of a
+// video or rich embed, for an iframe; HTML is the provider's embed markup
+// with scripts and frames removed.
+type LinkPreview struct {
+	URL         string    `json:"url"`
+	Type        string    `json:"type"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	SiteName    string    `json:"site_name,omitempty"`
+	AuthorName  string    `json:"author_name,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	EmbedURL    string    `json:"embed_url,omitempty"`
+	HTML        string    `json:"html,omitempty"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// linkPreviewEntry is a cached preview, or the reason there is none
+type linkPreviewEntry struct {
+	Preview *LinkPreview `json:"preview,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// oembedProviders are the oEmbed endpoints of sites whose pages are too
+// large or too scripted to discover them from
+var oembedProviders = []struct {
+	pattern  *regexp.Regexp
+	endpoint string
+}{
+	{regexp.MustCompile(`^https?://((www|m)\.)?youtube\.com/(watch|shorts/)|^https?://youtu\.be/`), "https://www.youtube.com/oembed"},
+	{regexp.MustCompile(`^https?://(www\.)?vimeo\.com/`), "https://vimeo.com/api/oembed.json"},
+	{regexp.MustCompile(`^https?://((www|mobile)\.)?(twitter|x)\.com/[^/]+/status/`), "https://publish.twitter.com/oembed"},
+	{regexp.MustCompile(`^https?://open\.spotify\.com/`), "https://open.spotify.com/oembed"},
+	{regexp.MustCompile(`^https?://soundcloud\.com/`), "https://soundcloud.com/oembed"},
+	{regexp.MustCompile(`^https?://(www\.)?flickr\.com/photos/`), "https://www.flickr.com/services/oembed/"},
+}
+
+// blockedNetworks are address ranges that are not the public internet but
+// that net.IP has no predicate for
+var blockedNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{"0.0.0.0/8", "100.64.0.0/10", "192.0.0.0/24", "198.18.0.0/15", "240.0.0.0/4", "64:ff9b::/96", "2001:db8::/32"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+// LinkPreviewService fetches previews of external links for the editor and
+// rendered articles, so browsers need not fetch other sites themselves.
+// Requests only reach public addresses: the address a host resolves to is
+// checked when connecting, so neither redirects nor DNS answers can point
+// a fetch at the server's own network.
+type LinkPreviewService struct {
+	client   *http.Client
+	checkURL func(*url.URL) error
+	cache    *cache.Namespace
+}
+
+// NewLinkPreviewService creates a link preview service that only fetches
+// public addresses
+func NewLinkPreviewService() *LinkPreviewService {
+	return &LinkPreviewService{
+		client:   newPublicClient(),
+		checkURL: checkPreviewURL,
+		cache:    cache.New("link_previews", linkPreviewTTL),
+	}
+}
+
+// newPublicClient returns a client for addresses other sites give, which
+// only connects to public addresses and checks every redirect
+func newPublicClient() *http.Client {
+	dialer := &net.Dialer{Timeout: linkPreviewTimeout, Control: func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+			return ErrPreviewBlocked
+		}
+		return nil
+	}}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   linkPreviewTimeout,
+		ResponseHeaderTimeout: linkPreviewTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       time.Minute,
+	}
+	return &http.Client{
+		Timeout:   linkPreviewTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= linkPreviewMaxRedirects {
+				return errors.New("too many redirects")
+			}
+			return checkPreviewURL(req.URL)
+		},
+	}
+}
+
+// ParsePreviewURL parses the address of a link to preview, without its
+// fragment
+func ParsePreviewURL(raw string) (*url.URL, error) {
+	if len(raw) > linkPreviewMaxURL {
+		return nil, ErrInvalidPreviewURL
+	}
+	target, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" || target.User != nil {
+		return nil, ErrInvalidPreviewURL
+	}
+	target.Fragment, target.RawFragment = "", ""
+	return target, nil
+}
+
+// checkPreviewURL allows http(s) addresses on the standard ports whose host,
+// when it is an address rather than a name, is public
+func checkPreviewURL(target *url.URL) error {
+	if (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return ErrInvalidPreviewURL
+	}
+	if port := target.Port(); port != "" && port != "80" && port != "443" {
+		return ErrInvalidPreviewURL
+	}
+	host := strings.ToLower(target.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal") {
+		return ErrPreviewBlocked
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return ErrPreviewBlocked
+	}
+	return nil
+}
+
+// isPublicIP reports whether an address is on the public internet
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsMulticast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Preview returns the preview of a link, from the cache when it was fetched
+// recently. Failures are remembered for a while too, so a broken link is
+// not fetched on every view.
+func (s *LinkPreviewService) Preview(ctx context.Context, target *url.URL) (*LinkPreview, error) {
+	if err := s.checkURL(target); err != nil {
+		return nil, err
+	}
+	key := segmentHash(target.String())
+	var entry linkPreviewEntry
+	if s.cache.Get(key, &entry) {
+		if entry.Preview == nil {
+			return nil, fmt.Errorf("%w: %s", ErrPreviewUnavailable, entry.Error)
+		}
+		return entry.Preview, nil
+	}
+
+	preview, err := s.fetch(ctx, target)
+	if err != nil {
+		if errors.Is(err, ErrPreviewBlocked) || errors.Is(err, ErrInvalidPreviewURL) {
+			return nil, err
+		}
+		s.cache.SetWithTTL(key, linkPreviewEntry{Error: err.Error()}, linkPreviewRetry)
+		return nil, fmt.Errorf("%w: %v", ErrPreviewUnavailable, err)
+	}
+	s.cache.Set(key, linkPreviewEntry{Preview: preview})
+	return preview, nil
+}
+
+// fetch builds a preview from the page's OpenGraph tags and its oEmbed
+// data, which wins where both say something. Pages of known providers are
+// not fetched, only their oEmbed endpoint.
+func (s *LinkPreviewService) fetch(ctx context.Context, target *url.URL) (*LinkPreview, error) {
+	preview := &LinkPreview{URL: target.String(), Type: "link", FetchedAt: time.Now()}
+	endpoint := ""
+	for _, provider := range oembedProviders {
+		if provider.pattern.MatchString(target.String()) {
+			endpoint = provider.endpoint + "?format=json&url=" + url.QueryEscape(target.String())
+			break
+		}
+	}
+
+	var err error
+	if endpoint == "" {
+		endpoint, err = s.fetchPage(ctx, target, preview)
+	}
+	if endpoint != "" {
+		if oembedErr := s.fetchOEmbed(ctx, endpoint, preview); err == nil {
+			err = oembedErr
+		}
+	}
+	if preview.Title == "" && preview.ImageURL == "" && preview.EmbedURL == "" && preview.HTML == "" {
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New("page has no title, image or embed")
+	}
+	return preview, nil
+}
+
+// get fetches an address and returns at most linkPreviewMaxBody of its
+// body, decoded to UTF-8 for HTML, with the address it ended up at
+func (s *LinkPreviewService) get(ctx context.Context, address, accept string) ([]byte, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkURL(req.URL); err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", "kuno-link-preview")
+	req.Header.Set("Accept", accept)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s answered %s", req.URL.Host, resp.Status)
+	}
+	var body io.Reader = io.LimitReader(resp.Body, linkPreviewMaxBody)
+	if contentType := resp.Header.Get("Content-Type"); strings.Contains(contentType, "html") {
+		if body, err = charset.NewReader(body, contentType); err != nil {
+			return nil, nil, err
+		}
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, resp.Request.URL, nil
+}
+
+// fetchPage reads the OpenGraph, Twitter card and plain HTML metadata of a
+// page into preview and returns the oEmbed endpoint the page links to
+func (s *LinkPreviewService) fetchPage(ctx context.Context, target *url.URL, preview *LinkPreview) (string, error) {
+	page, final, err := s.get(ctx, target.String(), "text/html,application/xhtml+xml")
+	if err != nil {
+		return "", err
+	}
+	meta, title, oembed := parsePageHead(page)
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := strings.TrimSpace(meta[key]); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+	preview.Title = first("og:title", "twitter:title")
+	if preview.Title == "" {
+		preview.Title = strings.Join(strings.Fields(title), " ")
+	}
+	preview.Description = first("og:description", "twitter:description", "description")
+	preview.SiteName = first("og:site_name", "application-name")
+	preview.AuthorName = first("author", "article:author")
+	preview.ImageURL = resolvePreviewURL(final, first("og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src"))
+	if kind := first("og:type"); strings.HasPrefix(kind, "video") {
+		preview.Type = "video"
+	}
+	return resolvePreviewURL(final, oembed), nil
+}
+
+// parsePageHead returns the meta tags of a page's head by name or
+// property, its title and its JSON oEmbed link
+func parsePageHead(page []byte) (meta map[string]string, title, oembed string) {
+	meta = make(map[string]string)
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "head" {
+				return
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttrs := z.TagName()
+			attrs := make(map[string]string)
+			for hasAttrs {
+				var key, value []byte
+				key, value, hasAttrs = z.TagAttr()
+				attrs[string(key)] = string(value)
+			}
+			switch string(name) {
+			case "body":
+				return
+			case "meta":
+				key := strings.ToLower(attrs["property"])
+				if key == "" {
+					key = strings.ToLower(attrs["name"])
+				}
+				if _, seen := meta[key]; key != "" && !seen {
+					meta[key] = attrs["content"]
+				}
+			case "link":
+				if oembed == "" && strings.Contains(strings.ToLower(attrs["rel"]), "alternate") &&
+					strings.EqualFold(attrs["type"], "application/json+oembed") {
+					oembed = attrs["href"]
+				}
+			case "title":
+				if title == "" && z.Next() == html.TextToken {
+					title = string(z.Text())
+				}
+			}
+		}
+	}
+}
+
+// oembedResponse is an oEmbed answer; sizes are numbers or strings
+// depending on the provider
+type oembedResponse struct {
+	Type         string          `json:"type"`
+	Title        string          `json:"title"`
+	AuthorName   string          `json:"author_name"`
+	ProviderName string          `json:"provider_name"`
+	URL          string          `json:"url"`
+	ThumbnailURL string          `json:"thumbnail_url"`
+	HTML         string          `json:"html"`
+	Width        json.RawMessage `json:"width"`
+	Height       json.RawMessage `json:"height"`
+}
+
+// fetchOEmbed merges an oEmbed answer into preview. The embed markup is
+// reduced to the address of its iframe and its markup without scripts or
+// frames, as it comes from another site.
+func (s *LinkPreviewService) fetchOEmbed(ctx context.Context, endpoint string, preview *LinkPreview) error {
+	data, final, err := s.get(ctx, endpoint, "application/json")
+	if err != nil {
+		return err
+	}
+	var embed oembedResponse
+	if err := json.Unmarshal(data, &embed); err != nil {
+		return fmt.Errorf("invalid oEmbed response: %w", err)
+	}
+	switch embed.Type {
+	case "photo", "video", "rich", "link":
+		preview.Type = embed.Type
+	}
+	if title := strings.TrimSpace(embed.Title); title != "" {
+		preview.Title = title
+	}
+	if embed.AuthorName != "" {
+		preview.AuthorName = embed.AuthorName
+	}
+	if embed.ProviderName != "" {
+		preview.SiteName = embed.ProviderName
+	}
+	if embed.Type == "photo" && embed.URL != "" {
+		preview.ImageURL = resolvePreviewURL(final, embed.URL)
+	} else if embed.ThumbnailURL != "" && preview.ImageURL == "" {
+		preview.ImageURL = resolvePreviewURL(final, embed.ThumbnailURL)
+	}
+	if embed.HTML != "" {
+		preview.EmbedURL = iframeSource(embed.HTML)
+		preview.HTML = strings.TrimSpace(security.NewUGCPolicy().Sanitize(embed.HTML))
+	}
+	preview.Width, preview.Height = oembedSize(embed.Width), oembedSize(embed.Height)
+	return nil
+}
+
+// iframeSource returns the https address of the first iframe of embed
+// markup
+func iframeSource(markup string) string {
+	z := html.NewTokenizer(strings.NewReader(markup))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttrs := z.TagName()
+			if string(name) != "iframe" {
+				continue
+			}
+			for hasAttrs {
+				var key, value []byte
+				key, value, hasAttrs = z.TagAttr()
+				if string(key) != "src" {
+					continue
+				}
+				source := string(value)
+				if strings.HasPrefix(source, "//") {
+					source = "https:" + source
+				}
+				if parsed, err := url.Parse(source); err == nil && parsed.Scheme == "https" && parsed.Host != "" {
+					return parsed.String()
+				}
+				return ""
+			}
+		}
+	}
+}
+
+func oembedSize(raw json.RawMessage) int {
+	size, err := strconv.Atoi(strings.Trim(string(raw), `"`))
+	if err != nil || size < 0 {
+		return 0
+	}
+	return size
+}
+
+// resolvePreviewURL resolves a link of a page against the page's address,
+// keeping only http(s) results
+func resolvePreviewURL(base *url.URL, link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return ""
+	}
+	resolved, err := base.Parse(link)
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+		return ""
+	}
+	return resolved.String()
+}
+
+var (
+	globalLinkPreviewService *LinkPreviewService
+	linkPreviewServiceOnce   sync.Once
+)
+
+// GetGlobalLinkPreviewService returns the global link preview service
+func GetGlobalLinkPreviewService() *LinkPreviewService {
+	linkPreviewServiceOnce.Do(func() {
+		globalLinkPreviewService = NewLinkPreviewService()
+	})
+	return globalLinkPreviewService
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestLinkPreview(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			fetches++
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			w.Write([]byte(`<html><head><title>Plain title</title>
+<meta property="og:title" content="Caf` + "\xe9" + ` &amp; more">
+<meta name="description" content="A post">
+<meta property="og:image" content="/cover.png">
+<link rel="alternate" type="application/json+oembed" href="/oembed?url=post">
+</head><body><meta property="og:title" content="Ignored"></body></html>`))
+		case "/oembed":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"type": "video", "provider_name": "Example", "width": "640", "height": 360,
+"html": "<blockquote>Watch</blockquote><iframe src=\"//player.example.com/embed/1\"></iframe><script src=\"https://example.com/x.js\"></script>"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s := &LinkPreviewService{
+		client:   server.Client(),
+		checkURL: func(*url.URL) error { return nil },
+		cache:    cache.New("link_previews_test", linkPreviewTTL),
+	}
+	target, _ := ParsePreviewURL(server.URL + "/post#comments")
+	preview, err := s.Preview(context.Background(), target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.URL != server.URL+"/post" || preview.Title != "Café & more" || preview.Description != "A post" ||
+		preview.ImageURL != server.URL+"/cover.png" || preview.SiteName != "Example" {
+		t.Errorf("preview = %+v", preview)
+	}
+	if preview.Type != "video" || preview.EmbedURL != "https://player.example.com/embed/1" || preview.Width != 640 || preview.Height != 360 {
+		t.Errorf("embed = %+v", preview)
+	}
+	if strings.Contains(preview.HTML, "script") || strings.Contains(preview.HTML, "iframe") || !strings.Contains(preview.HTML, "Watch") {
+		t.Errorf("html = %q, want the markup without scripts or frames", preview.HTML)
+	}
+
+	if _, err := s.Preview(context.Background(), target); err != nil || fetches != 1 {
+		t.Errorf("second preview fetched the page again (%d fetches, %v)", fetches, err)
+	}
+	missing, _ := ParsePreviewURL(server.URL + "/missing")
+	if _, err := s.Preview(context.Background(), missing); !errors.Is(err, ErrPreviewUnavailable) {
+		t.Errorf("missing page = %v, want ErrPreviewUnavailable", err)
+	}
+}
+
+func TestLinkPreviewStaysOnPublicAddresses(t *testing.T) {
+	for raw, want := range map[string]error{
+		"https://example.com/post":       nil,
+		"http://example.com:443/":        nil,
+		"ftp://example.com/":             ErrInvalidPreviewURL,
+		"https://example.com:8080/":      ErrInvalidPreviewURL,
+		"http://localhost/":              ErrPreviewBlocked,
+		"http://127.0.0.1/":              ErrPreviewBlocked,
+		"http://10.1.2.3/":               ErrPreviewBlocked,
+		"http://169.254.169.254/latest/": ErrPreviewBlocked,
+		"http://100.64.0.1/":             ErrPreviewBlocked,
+		"http://[::1]/":                  ErrPreviewBlocked,
+		"http://[::ffff:192.168.0.1]/":   ErrPreviewBlocked,
+		"http://[fd00::1]/":              ErrPreviewBlocked,
+	} {
+		target, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := checkPreviewURL(target); !errors.Is(err, want) {
+			t.Errorf("%s: %v, want %v", raw, err, want)
+		}
+	}
+
+	// A name resolving to a private address is refused when connecting,
+	// here a test server on the loopback address
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("private address reached")
+	}))
+	defer server.Close()
+	s := NewLinkPreviewService()
+	s.checkURL = func(*url.URL) error { return nil }
+	target, _ := ParsePreviewURL(strings.Replace(server.URL, "127.0.0.1", "localhost", 1))
+	if _, err := s.Preview(context.Background(), target); !errors.Is(err, ErrPreviewBlocked) {
+		t.Errorf("preview of %s = %v, want ErrPreviewBlocked", target, err)
+	}
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrLLMNotConfigured is returned when the AI settings have no enabled chat
+// provider
+var ErrLLMNotConfigured = errors.New("no AI provider is configured, enable one in the AI settings")
+
+const (
+	llmTimeout   = 2 * time.Minute
+	llmMaxTokens = 2048
+)
+
+// Base URLs used when a provider has no base_url setting, the same as the
+// admin settings suggest
+var llmDefaultBaseURLs = map[string]string{
+	"openai":  "https://api.openai.com/v1",
+	"gemini":  "https://generativelanguage.googleapis.com/v1beta",
+	"volcano": "https://ark.cn-beijing.volces.com/api/v3",
+	"claude":  "https://api.anthropic.com/v1",
+}
+
+// llmPrices are rough USD prices per 1K input and output tokens, used for
+// the estimated cost in AI usage records
+var llmPrices = map[string][2]float64{
+	"openai":  {0.0004, 0.0016},
+	"gemini":  {0.0003, 0.0025},
+	"volcano": {0.0001, 0.0004},
+	"claude":  {0.003, 0.015},
+}
+
+// Names of the languages prompts ask for; other languages are named by
+// their code
+var llmLanguageNames = map[string]string{
+	"en": "English",
+	"zh": "Simplified Chinese",
+	"ja": "Japanese",
+}
+
+func llmLanguageName(language string) string {
+	if name := llmLanguageNames[language]; name != "" {
+		return name
+	}
+	return language
+}
+
+// LLMRequest is a prompt for the chat model. ServiceType and Operation label
+// the AI usage record of the call.
+type LLMRequest struct {
+	System      string
+	Prompt      string
+	ServiceType string
+	Operation   string
+	Language    string
+}
+
+// llmProvider is a chat provider from the AI settings, with its key
+// decrypted
+type llmProvider struct {
+	name    string
+	apiKey  string
+	model   string
+	baseURL string
+}
+
+// llmReply is a completion and the tokens it took
+type llmReply struct {
+	text         string
+	inputTokens  int
+	outputTokens int
+}
+
+// LLMClient sends prompts to the default chat provider of a site's AI
+// settings, the one the admin editor uses for summaries and translations,
+// and records every call in the AI usage records.
+type LLMClient struct {
+	db      func() *gorm.DB
+	client  *http.Client
+	tracker *AIUsageTracker
+}
+
+// NewLLMClient creates a client for the configured AI providers
+func NewLLMClient() *LLMClient {
+	return &LLMClient{
+		db:      func() *gorm.DB { return database.DB },
+		client:  &http.Client{Timeout: llmTimeout},
+		tracker: NewAIUsageTracker(),
+	}
+}
+
+// Configured reports whether the site has an enabled chat provider
+func (l *LLMClient) Configured(siteID uint) bool {
+	_, err := l.provider(siteID)
+	return err == nil
+}
+
+// LLMUsage is what a completion took, for callers that keep to a budget
+type LLMUsage struct {
+	InputTokens   int
+	OutputTokens  int
+	EstimatedCost float64
+}
+
+// Complete returns the model's answer to a prompt
+func (l *LLMClient) Complete(ctx context.Context, siteID uint, request LLMRequest) (string, error) {
+	text, _, err := l.CompleteWithUsage(ctx, siteID, request)
+	return text, err
+}
+
+// CompleteWithUsage returns the model's answer to a prompt and the tokens
+// and estimated cost it took
+func (l *LLMClient) CompleteWithUsage(ctx context.Context, siteID uint, request LLMRequest) (string, LLMUsage, error) {
+	provider, err := l.provider(siteID)
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+
+	started := time.Now()
+	var reply *llmReply
+	switch provider.name {
+	case "claude":
+		reply, err = l.completeClaude(ctx, provider, request)
+	case "gemini":
+		reply, err = l.completeGemini(ctx, provider, request)
+	default:
+		reply, err = l.completeOpenAI(ctx, provider, request)
+	}
+
+	metrics := UsageMetrics{
+		ServiceType:  request.ServiceType,
+		Provider:     provider.name,
+		Model:        provider.model,
+		Operation:    request.Operation,
+		Currency:     "USD",
+		Language:     request.Language,
+		InputLength:  len(request.System) + len(request.Prompt),
+		ResponseTime: time.Since(started),
+		Success:      err == nil,
+	}
+	if err != nil {
+		metrics.ErrorMessage = err.Error()
+	} else {
+		prices := llmPrices[provider.name]
+		metrics.InputTokens = reply.inputTokens
+		metrics.OutputTokens = reply.outputTokens
+		metrics.TotalTokens = reply.inputTokens + reply.outputTokens
+		metrics.EstimatedCost = float64(reply.inputTokens)/1000*prices[0] + float64(reply.outputTokens)/1000*prices[1]
+		metrics.OutputLength = len(reply.text)
+	}
+	if trackErr := l.tracker.TrackUsage(metrics); trackErr != nil {
+		slog.Warn("Failed to track AI usage", "operation", request.Operation, "error", trackErr)
+	}
+	if err != nil {
+		return "", LLMUsage{}, err
+	}
+	return reply.text, LLMUsage{
+		InputTokens:   metrics.InputTokens,
+		OutputTokens:  metrics.OutputTokens,
+		EstimatedCost: metrics.EstimatedCost,
+	}, nil
+}
+
+// provider returns the site's default chat provider, or the first enabled
+// one when the default is off
+func (l *LLMClient) provider(siteID uint) (*llmProvider, error) {
+	var settings models.SiteSettings
+	if err := l.db().Where("site_id = ?", siteID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	if settings.AIConfig == "" {
+		return nil, ErrLLMNotConfigured
+	}
+	var secure security.SecureAIConfig
+	if err := json.Unmarshal([]byte(settings.AIConfig), &secure); err != nil {
+		return nil, fmt.Errorf("failed to parse AI settings: %v", err)
+	}
+	input, err := security.GetGlobalAIConfigService().DecryptAIConfig(&secure)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt AI settings: %v", err)
+	}
+
+	names := []string{input.DefaultProvider}
+	others := make([]string, 0, len(input.Providers))
+	for name := range input.Providers {
+		if name != input.DefaultProvider {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	for _, name := range append(names, others...) {
+		config, ok := input.Providers[name]
+		if !ok || !config.Enabled || config.APIKey == "" || config.Model == "" {
+			continue
+		}
+		kind := config.Provider
+		if kind == "" {
+			kind = name
+		}
+		if _, known := llmDefaultBaseURLs[kind]; !known {
+			continue
+		}
+		baseURL := strings.TrimRight(config.Settings["base_url"], "/")
+		if baseURL == "" {
+			baseURL = llmDefaultBaseURLs[kind]
+		}
+		return &llmProvider{name: kind, apiKey: config.APIKey, model: config.Model, baseURL: baseURL}, nil
+	}
+	return nil, ErrLLMNotConfigured
+}
+
+// completeOpenAI calls an OpenAI-compatible chat completions API, which
+// Volcano Engine also provides
+func (l *LLMClient) completeOpenAI(ctx context.Context, provider *llmProvider, request LLMRequest) (*llmReply, error) {
+	body := map[string]interface{}{
+		"model": provider.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": request.System},
+			{"role": "user", "content": request.Prompt},
+		},
+	}
+	var response struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + provider.apiKey}
+	if err := l.post(ctx, provider.baseURL+"/chat/completions", headers, body, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Choices) == 0 {
+		return nil, errors.New("the AI provider returned no answer")
+	}
+	return &llmReply{
+		text:         response.Choices[0].Message.Content,
+		inputTokens:  response.Usage.PromptTokens,
+		outputTokens: response.Usage.CompletionTokens,
+	}, nil
+}
+
+// completeClaude calls the Anthropic messages API
+func (l *LLMClient) completeClaude(ctx context.Context, provider *llmProvider, request LLMRequest) (*llmReply, error) {
+	body := map[string]interface{}{
+		"model":      provider.model,
+		"max_tokens": llmMaxTokens,
+		"system":     request.System,
+		"messages":   []map[string]string{{"role": "user", "content": request.Prompt}},
+	}
+	var response struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	endpoint := provider.baseURL
+	if !strings.HasSuffix(endpoint, "/messages") {
+		endpoint += "/messages"
+	}
+	headers := map[string]string{"x-api-key": provider.apiKey, "anthropic-version": "2023-06-01"}
+	if err := l.post(ctx, endpoint, headers, body, &response); err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return &llmReply{text: text.String(), inputTokens: response.Usage.InputTokens, outputTokens: response.Usage.OutputTokens}, nil
+}
+
+// completeGemini calls the Gemini generateContent API
+func (l *LLMClient) completeGemini(ctx context.Context, provider *llmProvider, request LLMRequest) (*llmReply, error) {
+	body := map[string]interface{}{
+		"systemInstruction": map[string]interface{}{"parts": []map[string]string{{"text": request.System}}},
+		"contents": []map[string]interface{}{
+			{"role": "user", "parts": []map[string]string{{"text": request.Prompt}}},
+		},
+	}
+	var response struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+		} `json:"candidates"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+		} `json:"usageMetadata"`
+	}
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", provider.baseURL, provider.model)
+	headers := map[string]string{"x-goog-api-key": provider.apiKey}
+	if err := l.post(ctx, endpoint, headers, body, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Candidates) == 0 {
+		return nil, errors.New("the AI provider returned no answer")
+	}
+	var text strings.Builder
+	for _, part := range response.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	return &llmReply{
+		text:         text.String(),
+		inputTokens:  response.UsageMetadata.PromptTokenCount,
+		outputTokens: response.UsageMetadata.CandidatesTokenCount,
+	}, nil
+}
+
+// post sends a JSON request and decodes the JSON response
+func (l *LLMClient) post(ctx context.Context, endpoint string, headers map[string]string, body, response interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("AI provider returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
@@ -0,0 +1,317 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAddressSuppressed   = errors.New("address is on the suppression list")
+	ErrSuppressionNotFound = errors.New("suppression not found")
+	ErrInvalidSuppression  = errors.New("invalid suppression, reason must be bounce, complaint or manual")
+	ErrInvalidMailWebhook  = errors.New("invalid mail webhook")
+	ErrUnknownMailProvider = errors.New("unknown mail provider, use sendgrid, mailgun, postmark or ses")
+)
+
+// MailEvent is a hard bounce or complaint reported by a mail provider
+type MailEvent struct {
+	Email  string
+	Reason string
+	Detail string
+}
+
+// mailWebhookParsers read the bounce and complaint events out of each
+// provider's webhook body; other events are ignored
+var mailWebhookParsers = map[string]func(body []byte) ([]MailEvent, error){
+	"sendgrid": parseSendGridEvents,
+	"mailgun":  parseMailgunEvents,
+	"postmark": parsePostmarkEvents,
+	"ses":      parseSESEvents,
+}
+
+// normalizeEmail returns the bare, lower-case address of a recipient
+func normalizeEmail(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		address = parsed.Address
+	}
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// Suppressed reports whether email must not be sent to an address
+func (m *Mailer) Suppressed(address string) (bool, error) {
+	var count int64
+	err := m.db().Model(&models.MailSuppression{}).Where("email = ?", normalizeEmail(address)).Count(&count).Error
+	return count > 0, err
+}
+
+// ListSuppressions returns a page of suppressed addresses, newest first,
+// optionally only those with the given reason
+func (m *Mailer) ListSuppressions(reason string, page, limit int) ([]models.MailSuppression, int64, error) {
+	query := m.db().Model(&models.MailSuppression{})
+	if reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	suppressions := []models.MailSuppression{}
+	err := query.Order("updated_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&suppressions).Error
+	return suppressions, total, err
+}
+
+// Suppress stops all email to an address. A bounce also marks the
+// address's newsletter subscriptions bounced and a complaint unsubscribes
+// them; a later report replaces the reason of an earlier one.
+func (m *Mailer) Suppress(address, reason, source, detail string) (*models.MailSuppression, error) {
+	email := normalizeEmail(address)
+	if _, err := mail.ParseAddress(email); err != nil {
+		return nil, ErrInvalidEmail
+	}
+	switch reason {
+	case models.SuppressionBounce, models.SuppressionComplaint, models.SuppressionManual:
+	default:
+		return nil, ErrInvalidSuppression
+	}
+
+	suppression := models.MailSuppression{Email: email, Reason: reason, Source: source, Detail: truncateRunes(detail, 500)}
+	err := m.db().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "email"}},
+			DoUpdates: clause.AssignmentColumns([]string{"reason", "source", "detail", "updated_at"}),
+		}).Create(&suppression).Error; err != nil {
+			return err
+		}
+		subscribed := tx.Model(&models.Subscriber{}).
+			Where("email = ? AND status IN ?", email, []string{models.SubscriberPending, models.SubscriberActive})
+		switch reason {
+		case models.SuppressionBounce:
+			return subscribed.Updates(map[string]interface{}{"status": models.SubscriberBounced, "last_bounce_at": time.Now()}).Error
+		case models.SuppressionComplaint:
+			return subscribed.Updates(map[string]interface{}{"status": models.SubscriberUnsubscribed, "unsubscribed_at": time.Now()}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := m.db().Where("email = ?", email).First(&suppression).Error; err != nil {
+		return nil, err
+	}
+	return &suppression, nil
+}
+
+// Unsuppress lets email be sent to an address again. Newsletter
+// subscriptions stay as they are; the reader can subscribe again.
+func (m *Mailer) Unsuppress(id uint) error {
+	result := m.db().Delete(&models.MailSuppression{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSuppressionNotFound
+	}
+	return nil
+}
+
+// HandleWebhook suppresses the addresses in a provider's bounce and
+// complaint webhook and returns how many it suppressed. Amazon SNS
+// subscription requests for the SES webhook are confirmed.
+func (m *Mailer) HandleWebhook(ctx context.Context, provider string, body []byte) (int, error) {
+	parse, ok := mailWebhookParsers[provider]
+	if !ok {
+		return 0, ErrUnknownMailProvider
+	}
+	if provider == "ses" {
+		confirmed, err := m.confirmSNSSubscription(ctx, body)
+		if confirmed || err != nil {
+			return 0, err
+		}
+	}
+	events, err := parse(body)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidMailWebhook, err)
+	}
+	suppressed := 0
+	for _, event := range events {
+		if _, err := m.Suppress(event.Email, event.Reason, provider, event.Detail); err != nil {
+			if errors.Is(err, ErrInvalidEmail) {
+				slog.Warn("Ignoring mail webhook event with an invalid address", "provider", provider, "email", event.Email)
+				continue
+			}
+			return 0, err
+		}
+		slog.Info("Address suppressed", "provider", provider, "email", event.Email, "reason", event.Reason)
+		suppressed++
+	}
+	return suppressed, nil
+}
+
+// confirmSNSSubscription visits the SubscribeURL of an Amazon SNS
+// subscription request, which only AWS hosts may send
+func (m *Mailer) confirmSNSSubscription(ctx context.Context, body []byte) (bool, error) {
+	var envelope struct {
+		Type         string
+		SubscribeURL string
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Type != "SubscriptionConfirmation" {
+		return false, nil
+	}
+	subscribe, err := url.Parse(envelope.SubscribeURL)
+	if err != nil || subscribe.Scheme != "https" || !strings.HasSuffix(subscribe.Hostname(), ".amazonaws.com") {
+		return true, fmt.Errorf("%w: SubscribeURL is not an AWS address", ErrInvalidMailWebhook)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, subscribe.String(), nil)
+	if err != nil {
+		return true, err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 300 {
+		return true, fmt.Errorf("SNS subscription confirmation returned status %d", resp.StatusCode)
+	}
+	slog.Info("Confirmed the SES notification subscription")
+	return true, nil
+}
+
+// parseSendGridEvents reads a SendGrid event webhook batch. Blocked mail is
+// a temporary failure and is not suppressed.
+func parseSendGridEvents(body []byte) ([]MailEvent, error) {
+	var batch []struct {
+		Email  string `json:"email"`
+		Event  string `json:"event"`
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, err
+	}
+	var events []MailEvent
+	for _, event := range batch {
+		switch {
+		case event.Event == "bounce" && event.Type != "blocked":
+			events = append(events, MailEvent{Email: event.Email, Reason: models.SuppressionBounce, Detail: event.Reason})
+		case event.Event == "spamreport":
+			events = append(events, MailEvent{Email: event.Email, Reason: models.SuppressionComplaint})
+		}
+	}
+	return events, nil
+}
+
+// parseMailgunEvents reads a Mailgun webhook. Only permanent failures are
+// bounces; temporary ones are retried by Mailgun.
+func parseMailgunEvents(body []byte) ([]MailEvent, error) {
+	var payload struct {
+		EventData struct {
+			Event          string `json:"event"`
+			Severity       string `json:"severity"`
+			Recipient      string `json:"recipient"`
+			DeliveryStatus struct {
+				Message     string `json:"message"`
+				Description string `json:"description"`
+			} `json:"delivery-status"`
+		} `json:"event-data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	data := payload.EventData
+	switch {
+	case data.Event == "failed" && data.Severity == "permanent":
+		detail := data.DeliveryStatus.Message
+		if detail == "" {
+			detail = data.DeliveryStatus.Description
+		}
+		return []MailEvent{{Email: data.Recipient, Reason: models.SuppressionBounce, Detail: detail}}, nil
+	case data.Event == "complained":
+		return []MailEvent{{Email: data.Recipient, Reason: models.SuppressionComplaint}}, nil
+	}
+	return nil, nil
+}
+
+// parsePostmarkEvents reads a Postmark bounce or spam complaint webhook
+func parsePostmarkEvents(body []byte) ([]MailEvent, error) {
+	var payload struct {
+		RecordType  string
+		Type        string
+		Email       string
+		Description string
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	switch {
+	case payload.RecordType == "Bounce" && payload.Type == "HardBounce":
+		return []MailEvent{{Email: payload.Email, Reason: models.SuppressionBounce, Detail: payload.Description}}, nil
+	case payload.RecordType == "SpamComplaint":
+		return []MailEvent{{Email: payload.Email, Reason: models.SuppressionComplaint}}, nil
+	}
+	return nil, nil
+}
+
+// parseSESEvents reads an Amazon SES notification delivered through SNS,
+// in either the notification or the event publishing format
+func parseSESEvents(body []byte) ([]MailEvent, error) {
+	var envelope struct {
+		Type    string
+		Message string
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Type != "Notification" {
+		return nil, nil
+	}
+	var message struct {
+		NotificationType string `json:"notificationType"`
+		EventType        string `json:"eventType"`
+		Bounce           struct {
+			BounceType        string `json:"bounceType"`
+			BouncedRecipients []struct {
+				EmailAddress   string `json:"emailAddress"`
+				DiagnosticCode string `json:"diagnosticCode"`
+			} `json:"bouncedRecipients"`
+		} `json:"bounce"`
+		Complaint struct {
+			ComplainedRecipients []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"complainedRecipients"`
+		} `json:"complaint"`
+	}
+	if err := json.Unmarshal([]byte(envelope.Message), &message); err != nil {
+		return nil, err
+	}
+	kind := message.NotificationType
+	if kind == "" {
+		kind = message.EventType
+	}
+	var events []MailEvent
+	switch {
+	case kind == "Bounce" && message.Bounce.BounceType == "Permanent":
+		for _, recipient := range message.Bounce.BouncedRecipients {
+			events = append(events, MailEvent{Email: recipient.EmailAddress, Reason: models.SuppressionBounce, Detail: recipient.DiagnosticCode})
+		}
+	case kind == "Complaint":
+		for _, recipient := range message.Complaint.ComplainedRecipients {
+			events = append(events, MailEvent{Email: recipient.EmailAddress, Reason: models.SuppressionComplaint})
+		}
+	}
+	return events, nil
+}
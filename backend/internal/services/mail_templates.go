@@ -0,0 +1,201 @@
+package services
+
+import "time"
+
+// Built-in email templates. Admins can override any of them per language
+// through the mail template API; languages without a built-in text or an
+// override fall back to English.
+const (
+	MailTemplateTest                = "test"
+	MailTemplatePasswordReset       = "password_reset"
+	MailTemplateCommentReply        = "comment_reply"
+	MailTemplateCommentPending      = "comment_pending"
+	MailTemplateSubscriptionConfirm = "subscription_confirm"
+	MailTemplateAnnouncement        = "announcement"
+	MailTemplateDigest              = "digest"
+	MailTemplateNewsletter          = "newsletter"
+	MailTemplateContactMessage      = "contact_message"
+)
+
+type mailText struct {
+	subject string
+	body    string
+}
+
+type mailTemplateDef struct {
+	description string
+	variables   []string
+	// sample returns data to validate and preview the template with
+	sample func() map[string]interface{}
+	texts  map[string]mailText
+}
+
+var mailTemplates = map[string]mailTemplateDef{
+	MailTemplateTest: {
+		description: "Sent from the admin settings to check the SMTP settings",
+		variables:   []string{"Host", "Source"},
+		sample: func() map[string]interface{} {
+			return map[string]interface{}{"Host": "smtp.example.com", "Source": "settings"}
+		},
+		texts: map[string]mailText{
+			"en": {"Test email", "This is a test email. Outgoing email through {{.Host}} works.\n"},
+			"zh": {"测试邮件", "这是一封测试邮件，通过 {{.Host}} 发送邮件正常。\n"},
+			"ja": {"テストメール", "これはテストメールです。{{.Host}} 経由のメール送信は正常に動作しています。\n"},
+		},
+	},
+	MailTemplatePasswordReset: {
+		description: "Link to choose a new password",
+		variables:   []string{"Username", "Link", "ExpiresMinutes"},
+		sample: func() map[string]interface{} {
+			return map[string]interface{}{"Username": "admin", "Link": "https://blog.example.com/admin/reset?token=…", "ExpiresMinutes": 60}
+		},
+		texts: map[string]mailText{
+			"en": {"Reset your password", "Someone asked to reset the password of {{.Username}}.\n\n" +
+				"Choose a new password here within {{.ExpiresMinutes}} minutes:\n{{.Link}}\n\n" +
+				"If you did not ask for this, ignore this message; your password stays the same.\n"},
+			"zh": {"重置密码", "有人请求重置账户 {{.Username}} 的密码。\n\n" +
+				"请在 {{.ExpiresMinutes}} 分钟内通过以下链接设置新密码：\n{{.Link}}\n\n" +
+				"如果这不是您本人的操作，请忽略此邮件，您的密码不会改变。\n"},
+			"ja": {"パスワードの再設定", "{{.Username}} のパスワード再設定がリクエストされました。\n\n" +
+				"{{.ExpiresMinutes}} 分以内に次のリンクから新しいパスワードを設定してください：\n{{.Link}}\n\n" +
+				"心当たりがない場合はこのメールを無視してください。パスワードは変更されません。\n"},
+		},
+	},
+	MailTemplateCommentReply: {
+		description: "Tells a commenter about a reply to their comment",
+		variables:   []string{"Name", "ArticleTitle", "ReplyAuthor", "Reply", "Link", "UnsubscribeLink"},
+		sample: func() map[string]interface{} {
+			return map[string]interface{}{
+				"Name": "Alice", "ArticleTitle": "Hello World", "ReplyAuthor": "Bob", "Reply": "Thanks for sharing!",
+				"Link": "https://blog.example.com/en/article/1#comments", "UnsubscribeLink": "https://blog.example.com/unsubscribe",
+			}
+		},
+		texts: map[string]mailText{
+			"en": {"{{.ReplyAuthor}} replied to your comment on {{.ArticleTitle}}", "Hi {{.Name}},\n\n" +
+				"{{.ReplyAuthor}} replied to your comment on \"{{.ArticleTitle}}\":\n\n{{.Reply}}\n\n" +
+				"Read the conversation: {{.Link}}\n\n--\nStop emails about replies: {{.UnsubscribeLink}}\n"},
+			"zh": {"{{.ReplyAuthor}} 回复了您在《{{.ArticleTitle}}》下的评论", "{{.Name}}，您好：\n\n" +
+				"{{.ReplyAuthor}} 回复了您在《{{.ArticleTitle}}》下的评论：\n\n{{.Reply}}\n\n" +
+				"查看完整对话：{{.Link}}\n\n--\n不再接收回复通知：{{.UnsubscribeLink}}\n"},
+			"ja": {"{{.ReplyAuthor}} さんが「{{.ArticleTitle}}」のコメントに返信しました", "{{.Name}} さん\n\n" +
+				"{{.ReplyAuthor}} さんが「{{.ArticleTitle}}」でのあなたのコメントに返信しました：\n\n{{.Reply}}\n\n" +
+				"会話を読む：{{.Link}}\n\n--\n返信の通知を停止する：{{.UnsubscribeLink}}\n"},
+		},
+	},
+	MailTemplateCommentPending: {
+		description: "Tells admins about comments waiting for moderation",
+		variables:   []string{"Count", "Comments (Author, ArticleTitle, Excerpt)", "Link"},
+		sample: func() map[string]interface{} {
+			return map[string]interface{}{
+				"Count": 1,
+				"Comments": []map[string]interface{}{
+					{"Author": "Alice", "ArticleTitle": "Hello World", "Excerpt": "Great post!"},
+				},
+				"Link": "https://blog.example.com/admin/comments",
+			}
+		},
+		texts: map[string]mailText{
+			"en": {"{{.Count}} comment(s) awaiting moderation", "New comments are waiting for your review:\n" +
+				"{{range .Comments}}\n* {{.Author}} on \"{{.ArticleTitle}}\":\n  {{.Excerpt}}\n{{end}}\nModerate them here: {{.Link}}\n"},
+			"zh": {"{{.Count}} 条评论待审核", "有新的评论等待您审核：\n" +
+				"{{range .Comments}}\n* {{.Author}} 评论了《{{.ArticleTitle}}》：\n  {{.Excerpt}}\n{{end}}\n前往审核：{{.Link}}\n"},
+			"ja": {"{{.Count}} 件のコメントが承認待ちです", "新しいコメントが確認を待っています：\n" +
+				"{{range .Comments}}\n* {{.Author}} さん「{{.ArticleTitle}}」：\n  {{.Excerpt}}\n{{end}}\n確認する：{{.Link}}\n"},
+		},
+	},
+	MailTemplateSubscriptionConfirm: {
+		description: "Double opt-in link sent when someone subscribes to new posts",
+		variables:   []string{"Link"},
+		sample: func() map[string]interface{} {
+			return map[string]interface{}{"Link": "https://blog.example.com/api/newsletter/confirm?token=…"}
+		},
+		texts: map[string]mailText{
+			"en": {"Confirm your subscription", "Please confirm that you want to receive new posts by email:\n\n{{.Link}}\n\n" +
+				"If you did not sign up, ignore this message and you will not hear from us again.\n"},
+			"zh": {"请确认您的订阅", "请点击以下链接，确认通过邮件接收新文章：\n\n{{.Link}}\n\n" +
+				"如果您没有订阅，请忽略此邮件，我们不会再联系您。\n"},
+			"ja": {"購読の確認", "新着記事をメールで受け取るには、次のリンクから購読を確認してください：\n\n{{.Link}}\n\n" +
+				"心当たりがない場合はこのメールを無視してください。今後メールが届くことはありません。\n"},
+		},
+	},
+	MailTemplateAnnouncement: {
+		description: "Newsletter announcing a newly published article",
+		variables:   []string{"Title", "Excerpt", "Link"},
+		sample: func() map[string]interface{} {
+			return map[string]interface{}{"Title": "Hello World", "Excerpt": "A first post.", "Link": "https://blog.example.com/en/article/1"}
+		},
+		texts: map[string]mailText{
+			"en": {"New post: {{.Title}}", "{{.Title}}\n\n{{if .Excerpt}}{{.Excerpt}}\n\n{{end}}Read it here: {{.Link}}\n"},
+			"zh": {"新文章：{{.Title}}", "{{.Title}}\n\n{{if .Excerpt}}{{.Excerpt}}\n\n{{end}}阅读全文：{{.Link}}\n"},
+			"ja": {"新着記事：{{.Title}}", "{{.Title}}\n\n{{if .Excerpt}}{{.Excerpt}}\n\n{{end}}続きを読む：{{.Link}}\n"},
+		},
+	},
+	MailTemplateDigest: {
+		description: "Newsletter listing the articles published since the last digest, with the intro and most read articles of AI-composed digests",
+		variables:   []string{"Since", "Count", "Intro", "Articles (Title, Excerpt, Link)", "Popular (Title, Excerpt, Link)"},
+		sample: func() map[string]interface{} {
+			return map[string]interface{}{
+				"Since": time.Now().AddDate(0, 0, -7),
+				"Count": 1,
+				"Intro": "",
+				"Articles": []map[string]interface{}{
+					{"Title": "Hello World", "Excerpt": "A first post.", "Link": "https://blog.example.com/en/article/1"},
+				},
+				"Popular": []map[string]interface{}{},
+			}
+		},
+		texts: map[string]mailText{
+			"en": {`{{if eq .Count 1}}New post: {{(index .Articles 0).Title}}{{else}}{{.Count}} new posts{{end}}`,
+				"{{if .Intro}}{{.Intro}}\n\n{{end}}" +
+					"New posts since {{.Since.Format \"January 2, 2006\"}}:\n" +
+					"{{range .Articles}}\n* {{.Title}}\n{{if .Excerpt}}  {{.Excerpt}}\n{{end}}  {{.Link}}\n{{end}}" +
+					"{{if .Popular}}\nMost read:\n{{range .Popular}}\n* {{.Title}}\n{{if .Excerpt}}  {{.Excerpt}}\n{{end}}  {{.Link}}\n{{end}}{{end}}"},
+			"zh": {`{{if eq .Count 1}}新文章：{{(index .Articles 0).Title}}{{else}}{{.Count}} 篇新文章{{end}}`,
+				"{{if .Intro}}{{.Intro}}\n\n{{end}}" +
+					"自 {{.Since.Format \"2006年1月2日\"}} 以来的新文章：\n" +
+					"{{range .Articles}}\n* {{.Title}}\n{{if .Excerpt}}  {{.Excerpt}}\n{{end}}  {{.Link}}\n{{end}}" +
+					"{{if .Popular}}\n最受欢迎：\n{{range .Popular}}\n* {{.Title}}\n{{if .Excerpt}}  {{.Excerpt}}\n{{end}}  {{.Link}}\n{{end}}{{end}}"},
+			"ja": {`{{if eq .Count 1}}新着記事：{{(index .Articles 0).Title}}{{else}}新着記事 {{.Count}} 件{{end}}`,
+				"{{if .Intro}}{{.Intro}}\n\n{{end}}" +
+					"{{.Since.Format \"2006年1月2日\"}} 以降の新着記事：\n" +
+					"{{range .Articles}}\n* {{.Title}}\n{{if .Excerpt}}  {{.Excerpt}}\n{{end}}  {{.Link}}\n{{end}}" +
+					"{{if .Popular}}\nよく読まれた記事：\n{{range .Popular}}\n* {{.Title}}\n{{if .Excerpt}}  {{.Excerpt}}\n{{end}}  {{.Link}}\n{{end}}{{end}}"},
+		},
+	},
+	MailTemplateNewsletter: {
+		description: "Wraps every newsletter sent to a subscriber, adding the unsubscribe footer",
+		variables:   []string{"Subject", "Body", "UnsubscribeLink"},
+		sample: func() map[string]interface{} {
+			return map[string]interface{}{
+				"Subject": "New post: Hello World", "Body": "Hello World\n\nRead it here: https://blog.example.com/en/article/1\n",
+				"UnsubscribeLink": "https://blog.example.com/api/newsletter/unsubscribe?token=…",
+			}
+		},
+		texts: map[string]mailText{
+			"en": {"{{.Subject}}", "{{.Body}}\n\n--\nYou receive this email because you subscribed to new posts.\nUnsubscribe: {{.UnsubscribeLink}}\n"},
+			"zh": {"{{.Subject}}", "{{.Body}}\n\n--\n您收到此邮件是因为您订阅了新文章。\n退订：{{.UnsubscribeLink}}\n"},
+			"ja": {"{{.Subject}}", "{{.Body}}\n\n--\n新着記事を購読しているため、このメールをお送りしています。\n購読解除：{{.UnsubscribeLink}}\n"},
+		},
+	},
+	MailTemplateContactMessage: {
+		description: "Forwards a message sent through the contact form to CONTACT_FORWARD_EMAIL; replies go to the sender",
+		variables:   []string{"Name", "Email", "Subject", "Message", "Link"},
+		sample: func() map[string]interface{} {
+			return map[string]interface{}{
+				"Name": "Alice", "Email": "alice@example.com", "Subject": "Hello",
+				"Message": "I enjoyed your latest post.", "Link": "https://blog.example.com/en/admin",
+			}
+		},
+		texts: map[string]mailText{
+			"en": {"Contact form: {{if .Subject}}{{.Subject}}{{else}}message from {{.Name}}{{end}}",
+				"{{.Name}} <{{.Email}}> wrote through the contact form:\n\n{{.Message}}\n\n" +
+					"Reply to this email to answer {{.Name}}. All messages: {{.Link}}\n"},
+			"zh": {"联系表单：{{if .Subject}}{{.Subject}}{{else}}来自 {{.Name}} 的留言{{end}}",
+				"{{.Name}} <{{.Email}}> 通过联系表单留言：\n\n{{.Message}}\n\n" +
+					"直接回复此邮件即可答复 {{.Name}}。查看全部留言：{{.Link}}\n"},
+			"ja": {"お問い合わせ：{{if .Subject}}{{.Subject}}{{else}}{{.Name}} さんからのメッセージ{{end}}",
+				"{{.Name}} <{{.Email}}> さんからお問い合わせフォームでメッセージが届きました：\n\n{{.Message}}\n\n" +
+					"このメールに返信すると {{.Name}} さんに届きます。すべてのメッセージ：{{.Link}}\n"},
+		},
+	},
+}
@@ -0,0 +1,432 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidMailSettings = errors.New("invalid mail settings")
+	ErrInvalidMailTemplate = errors.New("invalid email template")
+	ErrMailTemplateUnknown = errors.New("unknown email template")
+)
+
+// mailSettingKey is the SystemSetting holding SMTP settings saved by an admin
+const mailSettingKey = "mail_settings"
+
+// MailSettings is the SMTP server used for outgoing email. Settings saved
+// by an admin take precedence over the SMTP_* configuration.
+type MailSettings struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+	// Password is encrypted at rest and never returned by the API
+	Password    string `json:"password,omitempty"`
+	PasswordSet bool   `json:"password_set"`
+	From        string `json:"from"`
+	// Source is "settings" for settings saved by an admin and "config" for
+	// the SMTP_* configuration
+	Source string `json:"source,omitempty"`
+}
+
+// MailTemplate is the effective subject and body of a template in one
+// language
+type MailTemplate struct {
+	Name     string `json:"name"`
+	Language string `json:"language"`
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	// Overridden is set when an admin replaced the built-in template
+	Overridden bool `json:"overridden"`
+}
+
+// MailTemplateInfo lists a template with its variables and languages
+type MailTemplateInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Variables   []string `json:"variables"`
+	Languages   []string `json:"languages"` // built in
+	Overrides   []string `json:"overrides"`
+}
+
+// Mailer sends email through SMTP and renders the localized templates
+// every outgoing message is built from
+type Mailer struct {
+	db func() *gorm.DB
+	// sendMail is replaced in tests
+	sendMail func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+	// client confirms the SES webhook subscription
+	client *http.Client
+}
+
+// NewMailer creates a mailer
+func NewMailer() *Mailer {
+	return &Mailer{
+		db:       func() *gorm.DB { return database.DB },
+		sendMail: smtp.SendMail,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Settings returns the SMTP settings in use, without the password
+func (m *Mailer) Settings() (*MailSettings, error) {
+	settings, err := m.settings()
+	if err != nil {
+		return nil, err
+	}
+	settings.PasswordSet = settings.Password != ""
+	settings.Password = ""
+	return settings, nil
+}
+
+// settings returns the SMTP settings in use with the decrypted password
+func (m *Mailer) settings() (*MailSettings, error) {
+	stored, err := m.loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		password, err := security.GetGlobalCryptoService().DecryptAPIKey(stored.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt SMTP password: %v", err)
+		}
+		stored.Password, stored.Source = password, "settings"
+		return stored, nil
+	}
+
+	cfg := config.Get().SMTP
+	password, err := security.ResolveSecret(cfg.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve SMTP password: %v", err)
+	}
+	return &MailSettings{
+		Host: cfg.Host, Port: cfg.Port, Username: cfg.Username, Password: password, From: cfg.From, Source: "config",
+	}, nil
+}
+
+// SaveSettings stores SMTP settings that replace the SMTP_* configuration.
+// An empty password keeps the stored one; an empty host removes the saved
+// settings so the configuration applies again.
+func (m *Mailer) SaveSettings(input MailSettings) (*MailSettings, error) {
+	input.Host, input.From = strings.TrimSpace(input.Host), strings.TrimSpace(input.From)
+	if input.Host == "" {
+		if err := m.db().Where("key = ?", mailSettingKey).Delete(&models.SystemSetting{}).Error; err != nil {
+			return nil, err
+		}
+		return m.Settings()
+	}
+	if input.Port == "" {
+		input.Port = "587"
+	}
+	if port, err := strconv.Atoi(input.Port); err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("%w: %q is not a valid port", ErrInvalidMailSettings, input.Port)
+	}
+	if _, err := mail.ParseAddress(input.From); err != nil {
+		return nil, fmt.Errorf("%w: from must be an email address", ErrInvalidMailSettings)
+	}
+
+	stored, err := m.loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	password := ""
+	if input.Password != "" {
+		if password, err = security.GetGlobalCryptoService().EncryptAPIKey(input.Password); err != nil {
+			return nil, err
+		}
+	} else if stored != nil {
+		password = stored.Password
+	}
+
+	value, err := json.Marshal(MailSettings{
+		Host: input.Host, Port: input.Port, Username: strings.TrimSpace(input.Username), Password: password, From: input.From,
+	})
+	if err != nil {
+		return nil, err
+	}
+	setting := models.SystemSetting{Key: mailSettingKey, Value: string(value)}
+	if err := m.db().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&setting).Error; err != nil {
+		return nil, err
+	}
+	return m.Settings()
+}
+
+func (m *Mailer) loadSettings() (*MailSettings, error) {
+	var setting models.SystemSetting
+	if err := m.db().Limit(1).Find(&setting, models.SystemSetting{Key: mailSettingKey}).Error; err != nil {
+		return nil, err
+	}
+	if setting.Value == "" {
+		return nil, nil
+	}
+	var settings MailSettings
+	if err := json.Unmarshal([]byte(setting.Value), &settings); err != nil {
+		return nil, nil
+	}
+	return &settings, nil
+}
+
+// Configured reports whether an SMTP host and sender are set
+func (m *Mailer) Configured() bool {
+	settings, err := m.settings()
+	return err == nil && settings.Host != "" && settings.From != ""
+}
+
+// Send sends a plain-text email with extra headers. Rejections by the
+// server are returned as *textproto.Error, so callers can tell permanent
+// failures by their code. Suppressed addresses get no email and
+// ErrAddressSuppressed.
+func (m *Mailer) Send(to, subject, body string, headers map[string]string) error {
+	suppressed, err := m.Suppressed(to)
+	if err != nil {
+		return err
+	}
+	if suppressed {
+		return fmt.Errorf("%w: %s", ErrAddressSuppressed, to)
+	}
+	settings, err := m.settings()
+	if err != nil {
+		return err
+	}
+	if settings.Host == "" || settings.From == "" {
+		return ErrMailNotConfigured
+	}
+	from, err := mail.ParseAddress(settings.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %q: %v", settings.From, err)
+	}
+
+	var auth smtp.Auth
+	if settings.Username != "" {
+		auth = smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)
+	}
+
+	var message strings.Builder
+	message.WriteString("From: " + from.String() + "\r\n")
+	message.WriteString("To: " + to + "\r\n")
+	message.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", subject) + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	for name, value := range headers {
+		message.WriteString(name + ": " + value + "\r\n")
+	}
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("\r\n" + body)
+
+	return m.sendMail(settings.Host+":"+settings.Port, auth, from.Address, []string{to}, []byte(message.String()))
+}
+
+// SendTemplate renders a template in the recipient's language and sends it
+func (m *Mailer) SendTemplate(to, name, language string, data map[string]interface{}, headers map[string]string) error {
+	subject, body, err := m.Render(name, language, data)
+	if err != nil {
+		return err
+	}
+	return m.Send(to, subject, body, headers)
+}
+
+// SendTest mails the test template to check the SMTP settings
+func (m *Mailer) SendTest(to, language string) error {
+	if _, err := mail.ParseAddress(to); err != nil {
+		return ErrInvalidEmail
+	}
+	settings, err := m.Settings()
+	if err != nil {
+		return err
+	}
+	return m.SendTemplate(to, MailTemplateTest, language, map[string]interface{}{
+		"Host": settings.Host, "Source": settings.Source,
+	}, nil)
+}
+
+// Render returns the subject and body of a template for the language. An
+// admin override for the language wins over the built-in template; without
+// either the English built-in is used.
+func (m *Mailer) Render(name, language string, data map[string]interface{}) (string, string, error) {
+	tmpl, err := m.Template(name, language)
+	if err != nil {
+		return "", "", err
+	}
+	return renderMailTemplate(tmpl.Subject, tmpl.Body, data)
+}
+
+// Template returns the effective template for a language
+func (m *Mailer) Template(name, language string) (*MailTemplate, error) {
+	def, ok := mailTemplates[name]
+	if !ok {
+		return nil, ErrMailTemplateUnknown
+	}
+	for _, lang := range mailLanguageCandidates(language) {
+		var override models.EmailTemplate
+		found := m.db().Where("name = ? AND language = ?", name, lang).Limit(1).Find(&override)
+		if found.Error != nil {
+			return nil, found.Error
+		}
+		if found.RowsAffected > 0 {
+			return &MailTemplate{Name: name, Language: lang, Subject: override.Subject, Body: override.Body, Overridden: true}, nil
+		}
+		if text, ok := def.texts[lang]; ok {
+			return &MailTemplate{Name: name, Language: lang, Subject: text.subject, Body: text.body}, nil
+		}
+	}
+	text := def.texts["en"]
+	return &MailTemplate{Name: name, Language: "en", Subject: text.subject, Body: text.body}, nil
+}
+
+// Templates lists every template with its built-in languages and overrides
+func (m *Mailer) Templates() ([]MailTemplateInfo, error) {
+	var overrides []models.EmailTemplate
+	if err := m.db().Select("name", "language").Order("language").Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	infos := make([]MailTemplateInfo, 0, len(mailTemplates))
+	for name, def := range mailTemplates {
+		info := MailTemplateInfo{Name: name, Description: def.description, Variables: def.variables, Overrides: []string{}}
+		for lang := range def.texts {
+			info.Languages = append(info.Languages, lang)
+		}
+		sort.Strings(info.Languages)
+		for _, override := range overrides {
+			if override.Name == name {
+				info.Overrides = append(info.Overrides, override.Language)
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// SaveTemplate overrides a template for one language. The template must
+// render with the sample data of the built-in one.
+func (m *Mailer) SaveTemplate(name, language, subject, body string) (*MailTemplate, error) {
+	def, ok := mailTemplates[name]
+	if !ok {
+		return nil, ErrMailTemplateUnknown
+	}
+	language, subject = strings.TrimSpace(language), strings.TrimSpace(subject)
+	if language == "" || len(language) > 10 {
+		return nil, fmt.Errorf("%w: language is required", ErrInvalidMailTemplate)
+	}
+	if subject == "" || len(subject) > 255 || strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("%w: subject (at most 255 characters) and body are required", ErrInvalidMailTemplate)
+	}
+	if _, _, err := renderMailTemplate(subject, body, def.sample()); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMailTemplate, err)
+	}
+
+	override := models.EmailTemplate{Name: name, Language: language, Subject: subject, Body: body}
+	if err := m.db().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}, {Name: "language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"subject", "body", "updated_at"}),
+	}).Create(&override).Error; err != nil {
+		return nil, err
+	}
+	return &MailTemplate{Name: name, Language: language, Subject: subject, Body: body, Overridden: true}, nil
+}
+
+// DeleteTemplate removes an override so the built-in template applies again
+func (m *Mailer) DeleteTemplate(name, language string) error {
+	if _, ok := mailTemplates[name]; !ok {
+		return ErrMailTemplateUnknown
+	}
+	return m.db().Where("name = ? AND language = ?", name, language).Delete(&models.EmailTemplate{}).Error
+}
+
+// PreviewTemplate renders a template with sample data
+func (m *Mailer) PreviewTemplate(name, language string) (string, string, error) {
+	def, ok := mailTemplates[name]
+	if !ok {
+		return "", "", ErrMailTemplateUnknown
+	}
+	return m.Render(name, language, def.sample())
+}
+
+func renderMailTemplate(subject, body string, data map[string]interface{}) (string, string, error) {
+	render := func(name, text string) (string, error) {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+	}
+	renderedSubject, err := render("subject", subject)
+	if err != nil {
+		return "", "", err
+	}
+	renderedBody, err := render("body", body)
+	if err != nil {
+		return "", "", err
+	}
+	// Subjects are a single header line
+	return strings.Join(strings.Fields(renderedSubject), " "), renderedBody, nil
+}
+
+// mailLanguageCandidates returns the languages to look for, most specific
+// first: "zh-CN" tries "zh-CN" and then "zh"
+func mailLanguageCandidates(language string) []string {
+	language = strings.TrimSpace(language)
+	if language == "" {
+		return []string{"en"}
+	}
+	candidates := []string{language}
+	if base, _, ok := strings.Cut(language, "-"); ok {
+		candidates = append(candidates, base)
+	}
+	return candidates
+}
+
+// smtpConfigured reports whether outgoing email is set up
+func smtpConfigured() bool {
+	return GetGlobalMailer().Configured()
+}
+
+// sendPlainEmail sends a plain-text email through the configured SMTP server
+func sendPlainEmail(to, subject, body string) error {
+	return GetGlobalMailer().Send(to, subject, body, nil)
+}
+
+// sendEmail sends a plain-text email with extra headers through the
+// configured SMTP server
+func sendEmail(to, subject, body string, headers map[string]string) error {
+	return GetGlobalMailer().Send(to, subject, body, headers)
+}
+
+var (
+	globalMailer *Mailer
+	mailerOnce   sync.Once
+)
+
+// GetGlobalMailer returns the shared mailer
+func GetGlobalMailer() *Mailer {
+	mailerOnce.Do(func() {
+		globalMailer = NewMailer()
+	})
+	return globalMailer
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newTestMailer(t *testing.T) (*Mailer, *[]string) {
+	newTestDB(t)
+	var sent []string
+	m := &Mailer{
+		db: func() *gorm.DB { return database.DB },
+		sendMail: func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+			sent = append(sent, addr+"|"+from+"|"+strings.Join(to, ",")+"\n"+string(msg))
+			return nil
+		},
+	}
+	return m, &sent
+}
+
+func TestMailerSettings(t *testing.T) {
+	m, sent := newTestMailer(t)
+
+	if err := m.Send("reader@example.com", "Hi", "Hello", nil); !errors.Is(err, ErrMailNotConfigured) {
+		t.Fatalf("expected ErrMailNotConfigured, got %v", err)
+	}
+	if _, err := m.SaveSettings(MailSettings{Host: "smtp.example.com", Port: "nope", From: "blog@example.com"}); !errors.Is(err, ErrInvalidMailSettings) {
+		t.Errorf("expected ErrInvalidMailSettings for a bad port, got %v", err)
+	}
+
+	settings, err := m.SaveSettings(MailSettings{
+		Host: "smtp.example.com", Username: "blog", Password: "hunter2", From: "Blog <blog@example.com>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Port != "587" || !settings.PasswordSet || settings.Password != "" || settings.Source != "settings" {
+		t.Errorf("unexpected settings %+v", settings)
+	}
+	var stored models.SystemSetting
+	database.DB.First(&stored, "key = ?", mailSettingKey)
+	if strings.Contains(stored.Value, "hunter2") {
+		t.Error("SMTP password stored in plain text")
+	}
+
+	// Saving without a password keeps the stored one
+	if _, err := m.SaveSettings(MailSettings{Host: "smtp.example.com", Port: "2525", Username: "blog", From: "blog@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if full, _ := m.settings(); full.Password != "hunter2" || full.Port != "2525" {
+		t.Errorf("password not kept %+v", full)
+	}
+
+	if err := m.SendTest("admin@example.com", "zh"); err != nil {
+		t.Fatal(err)
+	}
+	if len(*sent) != 1 || !strings.HasPrefix((*sent)[0], "smtp.example.com:2525|blog@example.com|admin@example.com\n") ||
+		!strings.Contains((*sent)[0], "Subject: =?UTF-8?q?") {
+		t.Errorf("unexpected message %q", *sent)
+	}
+
+	// An empty host returns to the SMTP_* configuration
+	settings, err = m.SaveSettings(MailSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Source != "config" || m.Configured() {
+		t.Errorf("saved settings not removed %+v", settings)
+	}
+}
+
+func TestMailerTemplates(t *testing.T) {
+	m, _ := newTestMailer(t)
+	data := map[string]interface{}{"Link": "https://blog.example.com/confirm"}
+
+	subject, body, err := m.Render(MailTemplateSubscriptionConfirm, "zh-CN", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "请确认您的订阅" || !strings.Contains(body, "https://blog.example.com/confirm") {
+		t.Errorf("expected the Chinese template, got %q %q", subject, body)
+	}
+	if subject, _, _ := m.Render(MailTemplateSubscriptionConfirm, "ko", data); subject != "Confirm your subscription" {
+		t.Errorf("expected the English fallback, got %q", subject)
+	}
+
+	if _, err := m.SaveTemplate(MailTemplateSubscriptionConfirm, "ko", "구독 확인", "{{.Missing}}"); !errors.Is(err, ErrInvalidMailTemplate) {
+		t.Errorf("expected ErrInvalidMailTemplate for an unknown variable, got %v", err)
+	}
+	if _, err := m.SaveTemplate("nope", "ko", "x", "y"); !errors.Is(err, ErrMailTemplateUnknown) {
+		t.Errorf("expected ErrMailTemplateUnknown, got %v", err)
+	}
+	if _, err := m.SaveTemplate(MailTemplateSubscriptionConfirm, "ko", "구독 확인", "링크: {{.Link}}\n"); err != nil {
+		t.Fatal(err)
+	}
+	subject, body, err = m.Render(MailTemplateSubscriptionConfirm, "ko", data)
+	if err != nil || subject != "구독 확인" || body != "링크: https://blog.example.com/confirm\n" {
+		t.Errorf("override not used: %q %q %v", subject, body, err)
+	}
+
+	infos, err := m.Templates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		if info.Name == MailTemplateSubscriptionConfirm && (len(info.Overrides) != 1 || info.Overrides[0] != "ko") {
+			t.Errorf("unexpected overrides %+v", info)
+		}
+	}
+
+	if err := m.DeleteTemplate(MailTemplateSubscriptionConfirm, "ko"); err != nil {
+		t.Fatal(err)
+	}
+	if subject, _, _ := m.Render(MailTemplateSubscriptionConfirm, "ko", data); subject != "Confirm your subscription" {
+		t.Errorf("override not removed, got %q", subject)
+	}
+}
+
+func TestBuiltinMailTemplates(t *testing.T) {
+	for name, def := range mailTemplates {
+		if _, ok := def.texts["en"]; !ok {
+			t.Errorf("%s has no English text", name)
+		}
+		for lang, text := range def.texts {
+			if _, _, err := renderMailTemplate(text.subject, text.body, def.sample()); err != nil {
+				t.Errorf("%s (%s): %v", name, lang, err)
+			}
+		}
+	}
+}
+
+func TestMailSuppression(t *testing.T) {
+	m, sent := newTestMailer(t)
+	if _, err := m.SaveSettings(MailSettings{Host: "smtp.example.com", Port: "587", From: "blog@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	database.DB.Create(&models.Subscriber{Email: "gone@example.com", Status: models.SubscriberActive, Token: "t1"})
+	database.DB.Create(&models.Subscriber{Email: "angry@example.com", Status: models.SubscriberActive, Token: "t2"})
+
+	webhooks := []struct {
+		provider, body string
+		suppressed     int
+	}{
+		{"sendgrid", `[{"email":"gone@example.com","event":"bounce","type":"bounce","reason":"550 no such user"},
+			{"email":"busy@example.com","event":"bounce","type":"blocked"},{"email":"x@example.com","event":"open"}]`, 1},
+		{"mailgun", `{"event-data":{"event":"failed","severity":"temporary","recipient":"later@example.com"}}`, 0},
+		{"postmark", `{"RecordType":"SpamComplaint","Email":"Angry@Example.com"}`, 1},
+		{"ses", `{"Type":"Notification","Message":"{\"notificationType\":\"Bounce\",\"bounce\":{\"bounceType\":\"Permanent\",` +
+			`\"bouncedRecipients\":[{\"emailAddress\":\"old@example.com\",\"diagnosticCode\":\"smtp; 550\"}]}}"}`, 1},
+	}
+	for _, webhook := range webhooks {
+		suppressed, err := m.HandleWebhook(ctx, webhook.provider, []byte(webhook.body))
+		if err != nil || suppressed != webhook.suppressed {
+			t.Errorf("%s webhook suppressed %d, %v; want %d", webhook.provider, suppressed, err, webhook.suppressed)
+		}
+	}
+	if _, err := m.HandleWebhook(ctx, "ses", []byte(`{"Type":"SubscriptionConfirmation","SubscribeURL":"https://evil.example/confirm"}`)); !errors.Is(err, ErrInvalidMailWebhook) {
+		t.Errorf("expected ErrInvalidMailWebhook for a foreign SubscribeURL, got %v", err)
+	}
+	if _, err := m.HandleWebhook(ctx, "mystery", []byte(`{}`)); !errors.Is(err, ErrUnknownMailProvider) {
+		t.Errorf("expected ErrUnknownMailProvider, got %v", err)
+	}
+
+	var gone, angry models.Subscriber
+	database.DB.First(&gone, "email = ?", "gone@example.com")
+	database.DB.First(&angry, "email = ?", "angry@example.com")
+	if gone.Status != models.SubscriberBounced || angry.Status != models.SubscriberUnsubscribed || angry.UnsubscribedAt == nil {
+		t.Errorf("subscribers not updated: %s, %s", gone.Status, angry.Status)
+	}
+
+	if err := m.Send("Gone <GONE@example.com>", "Hello", "Hi", nil); !errors.Is(err, ErrAddressSuppressed) {
+		t.Errorf("expected ErrAddressSuppressed, got %v", err)
+	}
+	if len(*sent) != 0 {
+		t.Fatalf("mail sent to a suppressed address: %v", *sent)
+	}
+
+	suppressions, total, err := m.ListSuppressions(models.SuppressionBounce, 1, 10)
+	if err != nil || total != 2 || len(suppressions) != 2 {
+		t.Fatalf("unexpected bounce suppressions %+v %d %v", suppressions, total, err)
+	}
+	manual, err := m.Suppress("someone@example.com", models.SuppressionManual, "admin", "asked by phone")
+	if err != nil || manual.Source != "admin" {
+		t.Fatalf("manual suppression failed %+v %v", manual, err)
+	}
+	if _, err := m.Suppress("someone@example.com", "annoyed", "admin", ""); !errors.Is(err, ErrInvalidSuppression) {
+		t.Errorf("expected ErrInvalidSuppression, got %v", err)
+	}
+	if err := m.Unsuppress(manual.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send("someone@example.com", "Hello", "Hi", nil); err != nil || len(*sent) != 1 {
+		t.Errorf("mail not sent after removing the suppression: %v", err)
+	}
+	if err := m.Unsuppress(manual.ID); !errors.Is(err, ErrSuppressionNotFound) {
+		t.Errorf("expected ErrSuppressionNotFound, got %v", err)
+	}
+}
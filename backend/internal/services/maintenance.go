@@ -0,0 +1,150 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maintenanceSettingKey is the system setting holding the maintenance state
+const maintenanceSettingKey = "maintenance"
+
+const (
+	defaultMaintenanceRetryAfter = 300
+	// maintenanceRefresh is how often the state is reread from the database,
+	// so a toggle from the CLI or another instance takes effect without a
+	// restart even when the memory cache driver cannot broadcast it
+	maintenanceRefresh = 5 * time.Second
+)
+
+// MaintenanceState describes read-only maintenance mode. While it is
+// enabled writes are refused with 503 and RetryAfter, and public pages are
+// served from cache.
+type MaintenanceState struct {
+	Enabled    bool       `json:"enabled"`
+	Message    string     `json:"message,omitempty"`
+	RetryAfter int        `json:"retry_after"` // seconds
+	Since      *time.Time `json:"since,omitempty"`
+}
+
+// MaintenanceService stores the maintenance switch in the database so every
+// instance and the admin CLI share it
+type MaintenanceService struct {
+	db func() *gorm.DB
+
+	mu       sync.RWMutex
+	state    MaintenanceState
+	loadedAt time.Time
+}
+
+// NewMaintenanceService creates a maintenance service
+func NewMaintenanceService() *MaintenanceService {
+	s := &MaintenanceService{db: func() *gorm.DB { return database.DB }}
+	cache.Subscribe(cache.TopicMaintenance, s.reset)
+	return s
+}
+
+// State returns the current maintenance state. It is cached briefly; if the
+// database cannot be read, for example during a restore, the last known
+// state is kept.
+func (s *MaintenanceService) State() MaintenanceState {
+	s.mu.RLock()
+	state, fresh := s.state, time.Since(s.loadedAt) < maintenanceRefresh
+	s.mu.RUnlock()
+	if fresh {
+		return state
+	}
+
+	loaded, err := s.load()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Now()
+	if err != nil {
+		slog.Warn("Failed to read maintenance state, keeping last known state", "error", err)
+		return s.state
+	}
+	s.state = loaded
+	return loaded
+}
+
+// Enable turns on maintenance mode. A retryAfter of zero uses the default
+// of five minutes.
+func (s *MaintenanceService) Enable(message string, retryAfter int) (MaintenanceState, error) {
+	if retryAfter <= 0 {
+		retryAfter = defaultMaintenanceRetryAfter
+	}
+	now := time.Now()
+	state := MaintenanceState{Enabled: true, Message: message, RetryAfter: retryAfter, Since: &now}
+	if current := s.State(); current.Enabled && current.Since != nil {
+		state.Since = current.Since
+	}
+	return state, s.save(state)
+}
+
+// Disable turns off maintenance mode
+func (s *MaintenanceService) Disable() error {
+	return s.save(MaintenanceState{RetryAfter: defaultMaintenanceRetryAfter})
+}
+
+func (s *MaintenanceService) load() (MaintenanceState, error) {
+	state := MaintenanceState{RetryAfter: defaultMaintenanceRetryAfter}
+	var setting models.SystemSetting
+	err := s.db().Limit(1).Find(&setting, models.SystemSetting{Key: maintenanceSettingKey}).Error
+	if err != nil || setting.Value == "" {
+		return state, err
+	}
+	err = json.Unmarshal([]byte(setting.Value), &state)
+	return state, err
+}
+
+func (s *MaintenanceService) save(state MaintenanceState) error {
+	value, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	setting := models.SystemSetting{Key: maintenanceSettingKey, Value: string(value)}
+	err = s.db().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&setting).Error
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.state, s.loadedAt = state, time.Now()
+	s.mu.Unlock()
+	cache.Publish(cache.TopicMaintenance)
+	if state.Enabled {
+		slog.Warn("Maintenance mode enabled", "message", state.Message, "retry_after", state.RetryAfter)
+	} else {
+		slog.Info("Maintenance mode disabled")
+	}
+	return nil
+}
+
+func (s *MaintenanceService) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Time{}
+}
+
+var (
+	globalMaintenance *MaintenanceService
+	maintenanceOnce   sync.Once
+)
+
+// GetGlobalMaintenanceService returns the global maintenance service
+func GetGlobalMaintenanceService() *MaintenanceService {
+	maintenanceOnce.Do(func() {
+		globalMaintenance = NewMaintenanceService()
+	})
+	return globalMaintenance
+}
@@ -0,0 +1,40 @@
+package services
+
+import "testing"
+
+func TestMaintenanceStateIsSharedThroughDatabase(t *testing.T) {
+	newTestDB(t)
+	cli := NewMaintenanceService()
+	server := NewMaintenanceService()
+
+	if server.State().Enabled {
+		t.Fatal("maintenance enabled on a new database")
+	}
+	state, err := cli.Enable("Upgrading", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.RetryAfter != defaultMaintenanceRetryAfter || state.Since == nil {
+		t.Errorf("Enable = %+v", state)
+	}
+
+	// The server rereads the switch once its cached copy is stale
+	server.reset()
+	if got := server.State(); !got.Enabled || got.Message != "Upgrading" {
+		t.Fatalf("server state after CLI enable = %+v", got)
+	}
+
+	// Enabling again keeps the original start time
+	again, _ := cli.Enable("Still upgrading", 60)
+	if !again.Since.Equal(*state.Since) || again.RetryAfter != 60 {
+		t.Errorf("second Enable = %+v", again)
+	}
+
+	if err := cli.Disable(); err != nil {
+		t.Fatal(err)
+	}
+	server.reset()
+	if server.State().Enabled {
+		t.Error("server still in maintenance after disable")
+	}
+}
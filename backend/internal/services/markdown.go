@@ -0,0 +1,297 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"html"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// MarkdownRenderer renders article Markdown as HTML on the server, for
+// output that cannot run the site's scripts: code is highlighted with
+// inline styles, $math$ and $$math$$ become MathML and Mermaid diagrams
+// SVG images when a diagram server is configured. Raw HTML in the source is
+// left out, so the result is safe to embed anywhere.
+type MarkdownRenderer struct {
+	highlighter *CodeHighlighter
+	diagrams    *DiagramRenderer
+	markdown    goldmark.Markdown
+}
+
+// NewMarkdownRenderer creates a renderer using the given highlighter and
+// diagram renderer
+func NewMarkdownRenderer(highlighter *CodeHighlighter, diagrams *DiagramRenderer) *MarkdownRenderer {
+	m := &MarkdownRenderer{highlighter: highlighter, diagrams: diagrams}
+	m.markdown = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(
+			parser.WithBlockParsers(util.Prioritized(mathBlockParser{}, 150)),
+			parser.WithInlineParsers(util.Prioritized(mathInlineParser{}, 150)),
+		),
+		goldmark.WithRendererOptions(renderer.WithNodeRenderers(util.Prioritized(markdownNodeRenderer{m}, 100))),
+	)
+	return m
+}
+
+// Render returns source rendered as HTML. Diagrams that cannot be rendered
+// are shown as code.
+func (m *MarkdownRenderer) Render(ctx context.Context, source string) (string, error) {
+	src := []byte(source)
+	doc := m.markdown.Parser().Parse(text.NewReader(src))
+	if m.diagrams.Enabled() {
+		for _, block := range fencedBlocks(doc, src, "mermaid") {
+			svg, err := m.diagrams.Mermaid(ctx, blockText(block, src))
+			if err != nil {
+				slog.Warn("Failed to render diagram", "error", err)
+				continue
+			}
+			block.Parent().ReplaceChild(block.Parent(), block, &diagramBlock{svg: svg})
+		}
+	}
+	var out bytes.Buffer
+	if err := m.markdown.Renderer().Render(&out, src, doc); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// MermaidDiagrams returns the source of every Mermaid diagram in Markdown
+func MermaidDiagrams(source string) []string {
+	src := []byte(source)
+	doc := goldmark.DefaultParser().Parse(text.NewReader(src))
+	var diagrams []string
+	for _, block := range fencedBlocks(doc, src, "mermaid") {
+		diagrams = append(diagrams, blockText(block, src))
+	}
+	return diagrams
+}
+
+// fencedBlocks returns the fenced code blocks of a document in a language
+func fencedBlocks(doc ast.Node, src []byte, language string) []*ast.FencedCodeBlock {
+	var blocks []*ast.FencedCodeBlock
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if block, ok := node.(*ast.FencedCodeBlock); ok && entering && string(block.Language(src)) == language {
+			blocks = append(blocks, block)
+		}
+		return ast.WalkContinue, nil
+	})
+	return blocks
+}
+
+// blockText returns the raw lines of a block
+func blockText(node ast.Node, src []byte) string {
+	var content strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		content.Write(segment.Value(src))
+	}
+	return content.String()
+}
+
+var (
+	kindMathInline = ast.NewNodeKind("MathInline")
+	kindMathBlock  = ast.NewNodeKind("MathBlock")
+	kindDiagram    = ast.NewNodeKind("Diagram")
+)
+
+// mathInline is $math$ within text
+type mathInline struct {
+	ast.BaseInline
+	tex string
+}
+
+func (n *mathInline) Kind() ast.NodeKind { return kindMathInline }
+
+func (n *mathInline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Tex": n.tex}, nil)
+}
+
+// mathBlock is math between lines of $$
+type mathBlock struct {
+	ast.BaseBlock
+}
+
+func (n *mathBlock) Kind() ast.NodeKind { return kindMathBlock }
+
+func (n *mathBlock) IsRaw() bool { return true }
+
+func (n *mathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// diagramBlock is a diagram rendered as SVG
+type diagramBlock struct {
+	ast.BaseBlock
+	svg string
+}
+
+func (n *diagramBlock) Kind() ast.NodeKind { return kindDiagram }
+
+func (n *diagramBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// mathInlineParser reads $math$ like a code span: the math ends at the next
+// run of as many dollar signs on the same line
+type mathInlineParser struct{}
+
+func (mathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	opener := 0
+	for opener < len(line) && line[opener] == '$' {
+		opener++
+	}
+	for i := opener; i < len(line); i++ {
+		if line[i] != '$' {
+			continue
+		}
+		closer := i
+		for i < len(line) && line[i] == '$' {
+			i++
+		}
+		if i-closer == opener {
+			tex := strings.TrimSpace(string(line[opener:closer]))
+			if tex == "" {
+				return nil
+			}
+			block.Advance(i)
+			return &mathInline{tex: tex}
+		}
+	}
+	return nil
+}
+
+// mathBlockParser reads math between a line of $$ and the next one
+type mathBlockParser struct{}
+
+var mathFence = []byte("$$")
+
+func (mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	pos := pc.BlockOffset()
+	// $$math$$ on one line is inline math
+	if pos < 0 || !bytes.HasPrefix(line[pos:], mathFence) || bytes.IndexByte(line[pos+2:], '$') >= 0 {
+		return nil, parser.NoChildren
+	}
+	reader.AdvanceToEOL()
+	return &mathBlock{}, parser.NoChildren
+}
+
+func (mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if w, pos := util.IndentWidth(line, reader.LineOffset()); w < 4 && bytes.HasPrefix(line[pos:], mathFence) && util.IsBlank(line[pos+2:]) {
+		reader.AdvanceToEOL()
+		return parser.Close
+	}
+	node.Lines().Append(segment)
+	reader.AdvanceToEOL()
+	return parser.Continue | parser.NoChildren
+}
+
+func (mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// markdownNodeRenderer renders highlighted code, math and diagrams
+type markdownNodeRenderer struct {
+	markdown *MarkdownRenderer
+}
+
+func (r markdownNodeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCode)
+	reg.Register(kindMathInline, r.renderMathInline)
+	reg.Register(kindMathBlock, r.renderMathBlock)
+	reg.Register(kindDiagram, r.renderDiagram)
+}
+
+func (r markdownNodeRenderer) renderFencedCode(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	block := node.(*ast.FencedCodeBlock)
+	info := ""
+	if block.Info != nil {
+		info = string(block.Info.Segment.Value(source))
+	}
+	highlighted, _, err := r.markdown.highlighter.Highlight(blockText(block, source), ParseFenceInfo(info))
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	_, err = w.WriteString(highlighted)
+	return ast.WalkSkipChildren, err
+}
+
+func (r markdownNodeRenderer) renderMathInline(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	tex := node.(*mathInline).tex
+	mathML, err := RenderMath(tex, false)
+	if err != nil {
+		mathML = "<code>" + html.EscapeString(tex) + "</code>"
+	}
+	_, err = w.WriteString(mathML)
+	return ast.WalkSkipChildren, err
+}
+
+func (r markdownNodeRenderer) renderMathBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	tex := strings.TrimSpace(blockText(node, source))
+	mathML, err := RenderMath(tex, true)
+	if err != nil {
+		mathML = "<pre><code>" + html.EscapeString(tex) + "</code></pre>"
+	}
+	_, err = w.WriteString(mathML + "\n")
+	return ast.WalkSkipChildren, err
+}
+
+// renderDiagram shows the SVG as an image, where any script in it cannot run
+func (r markdownNodeRenderer) renderDiagram(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	svg := base64.StdEncoding.EncodeToString([]byte(node.(*diagramBlock).svg))
+	_, err := w.WriteString(`<figure class="diagram"><img src="data:image/svg+xml;base64,` + svg + `" alt="Diagram"></figure>` + "\n")
+	return ast.WalkSkipChildren, err
+}
+
+var (
+	globalMarkdownRenderer     *MarkdownRenderer
+	globalMarkdownRendererOnce sync.Once
+)
+
+// GetGlobalMarkdownRenderer returns the global Markdown renderer
+func GetGlobalMarkdownRenderer() *MarkdownRenderer {
+	globalMarkdownRendererOnce.Do(func() {
+		globalMarkdownRenderer = NewMarkdownRenderer(GetGlobalCodeHighlighter(), GetGlobalDiagramRenderer())
+	})
+	return globalMarkdownRenderer
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	m := NewMarkdownRenderer(NewCodeHighlighter(), &DiagramRenderer{})
+	out, err := m.Render(context.Background(), "# Title\n\n<script>alert(1)</script>\n\n```js {2}\nconst a = 1\nconst b = 2\n```\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n```mermaid\ngraph TD; A-->B\n```\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "<h1>Title</h1>") || !strings.Contains(out, "<table>") {
+		t.Errorf("markdown not rendered: %s", out)
+	}
+	if strings.Contains(out, "<script>") {
+		t.Errorf("raw HTML kept: %s", out)
+	}
+	if !strings.Contains(out, "<pre") || !strings.Contains(out, `style="`) || strings.Contains(out, "language-js") {
+		t.Errorf("code block not highlighted: %s", out)
+	}
+	if !strings.Contains(out, "A--&gt;B") {
+		t.Errorf("want the diagram as code without a diagram server: %s", out)
+	}
+}
+
+func TestRenderMath(t *testing.T) {
+	m := NewMarkdownRenderer(NewCodeHighlighter(), &DiagramRenderer{})
+	out, err := m.Render(context.Background(), "Energy $E=mc^2$ and `$x$` and $$a<b$$, priced \\$5.\n\n$$\n\\sum_{i=1}^n i\n$$\n\nBroken $\\frac{a$ math\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(out, `display="inline"`) != 2 || strings.Count(out, `display="block"`) != 1 {
+		t.Errorf("want two inline formulas and one block: %s", out)
+	}
+	if !strings.Contains(out, "<code>$x$</code>") || !strings.Contains(out, "priced $5.") {
+		t.Errorf("dollars outside math changed: %s", out)
+	}
+	if !strings.Contains(out, "<mo>&lt;</mo>") {
+		t.Errorf("math not escaped: %s", out)
+	}
+	if !strings.Contains(out, `<code>\frac{a</code>`) {
+		t.Errorf("want invalid math shown as code: %s", out)
+	}
+}
+
+func TestRenderDiagrams(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		source, _ := io.ReadAll(r.Body)
+		if r.URL.Path != "/mermaid/svg" || r.Method != http.MethodPost {
+			t.Errorf("request to %s %s", r.Method, r.URL.Path)
+		}
+		if strings.Contains(string(source), "invalid") {
+			http.Error(w, "Syntax error in graph", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg">` + strings.TrimSpace(string(source)) + `</svg>`))
+	}))
+	defer server.Close()
+
+	diagrams := &DiagramRenderer{krokiURL: server.URL, client: server.Client(), cache: cache.New("diagrams_test", diagramTTL)}
+	m := NewMarkdownRenderer(NewCodeHighlighter(), diagrams)
+	source := "```mermaid\ngraph TD; A-->B\n```\n\n```mermaid\ninvalid\n```\n"
+	if got := MermaidDiagrams(source); !reflect.DeepEqual(got, []string{"graph TD; A-->B\n", "invalid\n"}) {
+		t.Errorf("MermaidDiagrams = %q", got)
+	}
+	for i := 0; i < 2; i++ {
+		out, err := m.Render(context.Background(), source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		svg := base64.StdEncoding.EncodeToString([]byte(`<svg xmlns="http://www.w3.org/2000/svg">graph TD; A-->B</svg>`))
+		if !strings.Contains(out, `<img src="data:image/svg+xml;base64,`+svg+`"`) {
+			t.Errorf("diagram not rendered: %s", out)
+		}
+		if !strings.Contains(out, "<pre") || !strings.Contains(out, "invalid") {
+			t.Errorf("want the invalid diagram as code: %s", out)
+		}
+	}
+	// The valid diagram comes from the cache the second time
+	if requests != 3 {
+		t.Errorf("%d requests to the diagram server, want 3", requests)
+	}
+}
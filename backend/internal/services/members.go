@@ -0,0 +1,583 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"gorm.io/gorm"
+)
+
+const (
+	// memberTokenPrefix marks the tokens issued here, which are looked up
+	// locally; other tokens go to MEMBERS_VERIFY_URL
+	memberTokenPrefix = "kmt_"
+	// memberPasscodeAlphabet leaves out characters that are easily misread
+	memberPasscodeAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+	memberPasscodeLength   = 12
+	memberVerifyTimeout    = 5 * time.Second
+	maxMemberVerifyBody    = 64 << 10
+)
+
+var (
+	ErrMemberNotFound      = errors.New("member not found")
+	ErrInvalidMember       = errors.New("invalid member")
+	ErrMemberExists        = errors.New("a member with this email already exists")
+	ErrMemberLoginFailed   = errors.New("invalid email or passcode")
+	ErrMemberInactive      = errors.New("membership has expired or been disabled")
+	ErrMemberTokenRejected = errors.New("membership token is unknown or expired")
+)
+
+// moreMarker ends the teaser of an article that has one
+var moreMarker = regexp.MustCompile(`(?i)<!--\s*more\s*-->`)
+
+// MemberInput holds the editable fields of a member; nil fields are left
+// unchanged. ExpiresAt is an RFC 3339 time, or empty for a membership that
+// does not expire.
+type MemberInput struct {
+	Email     *string `json:"email"`
+	Name      *string `json:"name"`
+	Note      *string `json:"note"`
+	Disabled  *bool   `json:"disabled"`
+	ExpiresAt *string `json:"expires_at"`
+}
+
+// MemberIdentity is the reader a membership token belongs to. MemberID is
+// 0 for members verified by MEMBERS_VERIFY_URL.
+type MemberIdentity struct {
+	MemberID  uint       `json:"member_id,omitempty"`
+	Email     string     `json:"email,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// MemberSession is a membership token issued when a member signs in
+type MemberSession struct {
+	Token     string         `json:"token"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	Member    *models.Member `json:"member"`
+}
+
+// MemberService keeps the members who may read members-only articles and
+// the tokens they sign in for. Admins create members and hand out their
+// passcodes, which are shown once. Sites with accounts elsewhere can set
+// MEMBERS_VERIFY_URL instead, and have readers send the tokens of that
+// system.
+type MemberService struct {
+	db             func() *gorm.DB
+	now            func() time.Time
+	teaserLength   int
+	tokenTTL       time.Duration
+	verifyURL      string
+	verifyCacheTTL time.Duration
+	verifications  *cache.Namespace
+	client         *http.Client
+}
+
+// NewMemberService creates a member service from the MEMBERS_* settings
+func NewMemberService() *MemberService {
+	cfg := config.Get().Members
+	return &MemberService{
+		db:             func() *gorm.DB { return database.DB },
+		now:            time.Now,
+		teaserLength:   cfg.TeaserLength,
+		tokenTTL:       time.Duration(cfg.TokenTTL),
+		verifyURL:      cfg.VerifyURL,
+		verifyCacheTTL: time.Duration(cfg.VerifyCacheTTL),
+		verifications:  cache.New("member_verifications", time.Duration(cfg.VerifyCacheTTL)),
+		client:         &http.Client{Timeout: memberVerifyTimeout},
+	}
+}
+
+// List returns the site's members, newest first. A non-empty query matches
+// their email or name.
+func (s *MemberService) List(ctx context.Context, query string) ([]models.Member, error) {
+	members := []models.Member{}
+	db := s.db().WithContext(ctx)
+	if query = strings.TrimSpace(query); query != "" {
+		pattern := "%" + strings.ToLower(query) + "%"
+		db = db.Where(database.Like("email LIKE ? OR LOWER(name) LIKE ?"), pattern, pattern)
+	}
+	err := db.Order("created_at DESC, id DESC").Find(&members).Error
+	return members, err
+}
+
+// Get returns one member
+func (s *MemberService) Get(ctx context.Context, id uint) (*models.Member, error) {
+	var member models.Member
+	if err := s.db().WithContext(ctx).First(&member, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// Create adds a member, returning it with its passcode, which is not shown
+// again
+func (s *MemberService) Create(ctx context.Context, input MemberInput) (*models.Member, string, error) {
+	member := &models.Member{}
+	if input.Email == nil {
+		return nil, "", fmt.Errorf("%w: email is required", ErrInvalidMember)
+	}
+	if err := applyMemberInput(member, input); err != nil {
+		return nil, "", err
+	}
+	passcode, err := newMemberPasscode()
+	if err != nil {
+		return nil, "", err
+	}
+	member.PasscodeHash = hashMemberSecret(normalizePasscode(passcode))
+	if err := s.ensureUniqueEmail(ctx, member); err != nil {
+		return nil, "", err
+	}
+	if err := s.db().WithContext(ctx).Create(member).Error; err != nil {
+		return nil, "", err
+	}
+	return member, passcode, nil
+}
+
+// Update changes a member. Disabling a member or ending their membership
+// signs them out.
+func (s *MemberService) Update(ctx context.Context, id uint, input MemberInput) (*models.Member, error) {
+	member, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyMemberInput(member, input); err != nil {
+		return nil, err
+	}
+	if err := s.ensureUniqueEmail(ctx, member); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Save(member).Error; err != nil {
+		return nil, err
+	}
+	if !member.Active(s.now()) {
+		if err := s.signOut(ctx, member.ID); err != nil {
+			return nil, err
+		}
+	}
+	return member, nil
+}
+
+// ResetPasscode gives a member a new passcode, which is returned and not
+// shown again, and signs them out everywhere
+func (s *MemberService) ResetPasscode(ctx context.Context, id uint) (*models.Member, string, error) {
+	member, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	passcode, err := newMemberPasscode()
+	if err != nil {
+		return nil, "", err
+	}
+	member.PasscodeHash = hashMemberSecret(normalizePasscode(passcode))
+	if err := s.db().WithContext(ctx).Model(member).Update("passcode_hash", member.PasscodeHash).Error; err != nil {
+		return nil, "", err
+	}
+	if err := s.signOut(ctx, member.ID); err != nil {
+		return nil, "", err
+	}
+	return member, passcode, nil
+}
+
+// Delete removes a member and their tokens
+func (s *MemberService) Delete(ctx context.Context, id uint) error {
+	member, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.signOut(ctx, member.ID); err != nil {
+		return err
+	}
+	return s.db().WithContext(ctx).Delete(member).Error
+}
+
+// Login checks a member's passcode and issues a membership token
+func (s *MemberService) Login(ctx context.Context, email, passcode string) (*MemberSession, error) {
+	var member models.Member
+	err := s.db().WithContext(ctx).Where("email = ?", strings.ToLower(strings.TrimSpace(email))).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMemberLoginFailed
+	}
+	if err != nil {
+		return nil, err
+	}
+	hash := hashMemberSecret(normalizePasscode(passcode))
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(member.PasscodeHash)) != 1 {
+		return nil, ErrMemberLoginFailed
+	}
+	now := s.now()
+	if !member.Active(now) {
+		return nil, ErrMemberInactive
+	}
+
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	token := memberTokenPrefix + hex.EncodeToString(buf)
+	expiresAt := now.Add(s.tokenTTL)
+	if member.ExpiresAt != nil && member.ExpiresAt.Before(expiresAt) {
+		expiresAt = *member.ExpiresAt
+	}
+	err = s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Tokens the member no longer uses go when they sign in again
+		if err := tx.Where("member_id = ? AND expires_at <= ?", member.ID, now).Delete(&models.MemberToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.MemberToken{MemberID: member.ID, TokenHash: hashMemberSecret(token), ExpiresAt: expiresAt}).Error; err != nil {
+			return err
+		}
+		member.LastLoginAt = &now
+		return tx.Model(&member).Update("last_login_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MemberSession{Token: token, ExpiresAt: expiresAt, Member: &member}, nil
+}
+
+// Logout revokes a membership token issued here
+func (s *MemberService) Logout(ctx context.Context, token string) error {
+	return s.db().WithContext(ctx).Where("token_hash = ?", hashMemberSecret(token)).Delete(&models.MemberToken{}).Error
+}
+
+// Verify returns the member a token belongs to, or ErrMemberTokenRejected
+// when it does not give access to members-only articles of the site in
+// ctx
+func (s *MemberService) Verify(ctx context.Context, token string) (*MemberIdentity, error) {
+	if token == "" {
+		return nil, ErrMemberTokenRejected
+	}
+	if !strings.HasPrefix(token, memberTokenPrefix) {
+		if s.verifyURL == "" {
+			return nil, ErrMemberTokenRejected
+		}
+		return s.verifyExternal(ctx, token)
+	}
+
+	now := s.now()
+	var issued models.MemberToken
+	err := s.db().WithContext(ctx).Where("token_hash = ? AND expires_at > ?", hashMemberSecret(token), now).First(&issued).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMemberTokenRejected
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The member lookup is scoped to the site, so a token of another site
+	// is not found
+	member, err := s.Get(ctx, issued.MemberID)
+	if errors.Is(err, ErrMemberNotFound) {
+		return nil, ErrMemberTokenRejected
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !member.Active(now) {
+		return nil, ErrMemberTokenRejected
+	}
+	return &MemberIdentity{MemberID: member.ID, Email: member.Email, Name: member.Name, ExpiresAt: &issued.ExpiresAt}, nil
+}
+
+// verifyExternal asks MEMBERS_VERIFY_URL about a token issued elsewhere.
+// The URL receives {"token", "site_id"} and answers 200 with the member's
+// {"email", "name", "expires_at"}, all optional, or any 4xx status when the
+// token gives no access. Answers are cached for MEMBERS_VERIFY_CACHE_TTL.
+func (s *MemberService) verifyExternal(ctx context.Context, token string) (*MemberIdentity, error) {
+	siteID, _ := database.SiteFromContext(ctx)
+	key := fmt.Sprintf("%d:%s", siteID, hashMemberSecret(token))
+	var cached struct {
+		Identity *MemberIdentity `json:"identity"`
+	}
+	if s.verifyCacheTTL > 0 && s.verifications.Get(key, &cached) {
+		if cached.Identity == nil {
+			return nil, ErrMemberTokenRejected
+		}
+		return cached.Identity, nil
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"token": token, "site_id": siteID})
+	ctx, cancel := context.WithTimeout(ctx, memberVerifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.verifyURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KUNO-Members/1.0")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("member verification: %w", err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		identity := &MemberIdentity{}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxMemberVerifyBody)).Decode(identity); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("member verification: %w", err)
+		}
+		identity.MemberID = 0
+		if identity.ExpiresAt != nil && !identity.ExpiresAt.After(s.now()) {
+			identity = nil
+		}
+		cached.Identity = identity
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		cached.Identity = nil
+	default:
+		return nil, fmt.Errorf("member verification answered %d", resp.StatusCode)
+	}
+	if s.verifyCacheTTL > 0 {
+		s.verifications.Set(key, cached)
+	}
+	if cached.Identity == nil {
+		return nil, ErrMemberTokenRejected
+	}
+	return cached.Identity, nil
+}
+
+// Lock replaces the content of a members-only article, and of its
+// translations, with a teaser and marks it locked. Other articles are left
+// alone.
+func (s *MemberService) Lock(article *models.Article) {
+	if !article.MembersOnly {
+		return
+	}
+	article.Content = Teaser(article.Content, article.ContentType, s.teaserLength)
+	for i := range article.Translations {
+		article.Translations[i].Content = Teaser(article.Translations[i].Content, article.ContentType, s.teaserLength)
+	}
+	article.Locked = true
+}
+
+func (s *MemberService) signOut(ctx context.Context, memberID uint) error {
+	return s.db().WithContext(ctx).Where("member_id = ?", memberID).Delete(&models.MemberToken{}).Error
+}
+
+func (s *MemberService) ensureUniqueEmail(ctx context.Context, member *models.Member) error {
+	var count int64
+	if err := s.db().WithContext(ctx).Model(&models.Member{}).
+		Where("email = ? AND id <> ?", member.Email, member.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrMemberExists
+	}
+	return nil
+}
+
+func applyMemberInput(member *models.Member, input MemberInput) error {
+	if input.Email != nil {
+		address, err := mail.ParseAddress(strings.TrimSpace(*input.Email))
+		if err != nil || len(address.Address) > 255 {
+			return fmt.Errorf("%w: email is not a valid address", ErrInvalidMember)
+		}
+		member.Email = strings.ToLower(address.Address)
+	}
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if utf8.RuneCountInString(name) > 100 {
+			return fmt.Errorf("%w: name must be at most 100 characters", ErrInvalidMember)
+		}
+		member.Name = name
+	}
+	if input.Note != nil {
+		note := strings.TrimSpace(*input.Note)
+		if utf8.RuneCountInString(note) > 500 {
+			return fmt.Errorf("%w: note must be at most 500 characters", ErrInvalidMember)
+		}
+		member.Note = note
+	}
+	if input.Disabled != nil {
+		member.Disabled = *input.Disabled
+	}
+	if input.ExpiresAt != nil {
+		if *input.ExpiresAt == "" {
+			member.ExpiresAt = nil
+		} else {
+			parsed, err := time.Parse(time.RFC3339, *input.ExpiresAt)
+			if err != nil {
+				return fmt.Errorf("%w: expires_at must be an RFC 3339 time", ErrInvalidMember)
+			}
+			member.ExpiresAt = &parsed
+		}
+	}
+	return nil
+}
+
+// newMemberPasscode returns a random passcode grouped as XXXX-XXXX-XXXX
+func newMemberPasscode() (string, error) {
+	buf := make([]byte, memberPasscodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i, c := range buf {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(memberPasscodeAlphabet[int(c)%len(memberPasscodeAlphabet)])
+	}
+	return b.String(), nil
+}
+
+// normalizePasscode accepts passcodes typed without dashes or in lower case
+func normalizePasscode(passcode string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(passcode))
+}
+
+func hashMemberSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Teaser returns the start of an article's content: the part before a
+// <!--more--> marker, or the leading blocks that fit in length characters.
+// The last block is never part of it, so a short article is not given away
+// whole, and a first block too long to fit is cut. HTML content is cut
+// between top-level elements so its tags stay balanced.
+func Teaser(content, contentType string, length int) string {
+	if loc := moreMarker.FindStringIndex(content); loc != nil {
+		return strings.TrimSpace(content[:loc[0]])
+	}
+	if contentType == "html" {
+		return htmlTeaser(content, length)
+	}
+	return markdownTeaser(content, length)
+}
+
+func markdownTeaser(content string, length int) string {
+	blocks := markdownBlocks(content)
+	if len(blocks) == 0 {
+		return ""
+	}
+	var teaser strings.Builder
+	chars := 0
+	for _, block := range blocks[:len(blocks)-1] {
+		n := utf8.RuneCountInString(block)
+		if chars+n > length {
+			break
+		}
+		teaser.WriteString(block + "\n\n")
+		chars += n
+	}
+	if teaser.Len() == 0 {
+		first := blocks[0]
+		if strings.HasPrefix(first, "```") || strings.HasPrefix(first, "~~~") {
+			// Cutting a code block would leave its fence open
+			return ""
+		}
+		return cutTeaser(first, length)
+	}
+	return strings.TrimSpace(teaser.String())
+}
+
+// markdownBlocks splits Markdown at blank lines outside code fences
+func markdownBlocks(content string) []string {
+	var blocks []string
+	var block strings.Builder
+	fenced := false
+	flush := func() {
+		if text := strings.TrimSpace(block.String()); text != "" {
+			blocks = append(blocks, text)
+		}
+		block.Reset()
+	}
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+		if trimmed == "" && !fenced {
+			flush()
+			continue
+		}
+		block.WriteString(line + "\n")
+	}
+	flush()
+	return blocks
+}
+
+func htmlTeaser(content string, length int) string {
+	body := &html.Node{Type: html.ElementNode, DataAtom: atom.Body, Data: "body"}
+	nodes, err := html.ParseFragment(strings.NewReader(content), body)
+	if err != nil {
+		return ""
+	}
+	// Whitespace between elements is not a block of its own
+	blocks := nodes[:0]
+	for _, node := range nodes {
+		if node.Type != html.TextNode || strings.TrimSpace(node.Data) != "" {
+			blocks = append(blocks, node)
+		}
+	}
+	if len(blocks) == 0 {
+		return ""
+	}
+	var teaser strings.Builder
+	chars := 0
+	for _, node := range blocks[:len(blocks)-1] {
+		n := utf8.RuneCountInString(strings.TrimSpace(htmlText(node)))
+		if chars+n > length {
+			break
+		}
+		if err := html.Render(&teaser, node); err != nil {
+			return ""
+		}
+		chars += n
+	}
+	if teaser.Len() == 0 {
+		text := cutTeaser(strings.Join(strings.Fields(htmlText(blocks[0])), " "), length)
+		if text == "" {
+			return ""
+		}
+		return "<p>" + html.EscapeString(text) + "</p>"
+	}
+	return teaser.String()
+}
+
+// cutTeaser cuts text to length characters, and to at most half of it so
+// that a single block is not given away whole
+func cutTeaser(text string, length int) string {
+	limit := min(length, utf8.RuneCountInString(text)/2)
+	if limit < 2 {
+		return ""
+	}
+	return truncateRunes(text, limit)
+}
+
+var (
+	globalMemberService     *MemberService
+	globalMemberServiceOnce sync.Once
+)
+
+// GetGlobalMemberService returns the global member service
+func GetGlobalMemberService() *MemberService {
+	globalMemberServiceOnce.Do(func() {
+		globalMemberService = NewMemberService()
+	})
+	return globalMemberService
+}
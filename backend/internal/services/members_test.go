@@ -0,0 +1,228 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newTestMemberService(now *time.Time) *MemberService {
+	return &MemberService{
+		db:             func() *gorm.DB { return database.DB },
+		now:            func() time.Time { return *now },
+		teaserLength:   40,
+		tokenTTL:       24 * time.Hour,
+		verifyCacheTTL: time.Minute,
+		verifications:  cache.New("test_member_verifications", time.Minute),
+		client:         http.DefaultClient,
+	}
+}
+
+func TestMemberLoginAndVerify(t *testing.T) {
+	newTestDB(t)
+	if err := database.DB.Use(database.SiteScope{}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestMemberService(&now)
+	ctx := database.WithSite(context.Background(), models.DefaultSiteID)
+
+	for _, bad := range []MemberInput{
+		{Email: strPtr("not an email")},
+		{Email: strPtr("a@example.com"), ExpiresAt: strPtr("next year")},
+	} {
+		if _, _, err := s.Create(ctx, bad); !errors.Is(err, ErrInvalidMember) {
+			t.Errorf("Create(%+v) = %v, want ErrInvalidMember", bad, err)
+		}
+	}
+	member, passcode, err := s.Create(ctx, MemberInput{Email: strPtr("Reader@Example.com"), Name: strPtr("Reader")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member.Email != "reader@example.com" || len(passcode) != memberPasscodeLength+2 {
+		t.Fatalf("member %q with passcode %q", member.Email, passcode)
+	}
+	if _, _, err := s.Create(ctx, MemberInput{Email: strPtr("reader@example.com")}); !errors.Is(err, ErrMemberExists) {
+		t.Errorf("duplicate email: %v", err)
+	}
+
+	if _, err := s.Login(ctx, "reader@example.com", "WRONG-CODE-0000"); !errors.Is(err, ErrMemberLoginFailed) {
+		t.Errorf("wrong passcode: %v", err)
+	}
+	if _, err := s.Login(ctx, "nobody@example.com", passcode); !errors.Is(err, ErrMemberLoginFailed) {
+		t.Errorf("unknown email: %v", err)
+	}
+	// Passcodes may be typed without dashes and in lower case
+	session, err := s.Login(ctx, " READER@example.com", strings.ToLower(strings.ReplaceAll(passcode, "-", "")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(session.Token, memberTokenPrefix) || !session.ExpiresAt.Equal(now.Add(24*time.Hour)) {
+		t.Fatalf("session = %+v", session)
+	}
+	identity, err := s.Verify(ctx, session.Token)
+	if err != nil || identity.MemberID != member.ID || identity.Email != "reader@example.com" {
+		t.Fatalf("Verify = %+v, %v", identity, err)
+	}
+	// Without MEMBERS_VERIFY_URL, foreign tokens are turned away
+	if _, err := s.Verify(ctx, "external-token"); !errors.Is(err, ErrMemberTokenRejected) {
+		t.Errorf("foreign token: %v", err)
+	}
+
+	// Tokens do not work on other sites
+	other := database.WithSite(context.Background(), 2)
+	if _, err := s.Verify(other, session.Token); !errors.Is(err, ErrMemberTokenRejected) {
+		t.Errorf("token on another site: %v", err)
+	}
+
+	// An expired membership cannot sign in, and its tokens stop working
+	past := "2026-04-30T00:00:00Z"
+	if _, err := s.Update(ctx, member.ID, MemberInput{ExpiresAt: &past}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(ctx, session.Token); !errors.Is(err, ErrMemberTokenRejected) {
+		t.Errorf("token after expiry: %v", err)
+	}
+	if _, err := s.Login(ctx, "reader@example.com", passcode); !errors.Is(err, ErrMemberInactive) {
+		t.Errorf("login after expiry: %v", err)
+	}
+
+	// A membership ending soon caps the token
+	soon := now.Add(2 * time.Hour).Format(time.RFC3339)
+	if _, err := s.Update(ctx, member.ID, MemberInput{ExpiresAt: &soon}); err != nil {
+		t.Fatal(err)
+	}
+	session, err = s.Login(ctx, "reader@example.com", passcode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !session.ExpiresAt.Equal(now.Add(2 * time.Hour)) {
+		t.Errorf("token expires %v, want the membership's end", session.ExpiresAt)
+	}
+
+	// A new passcode signs the member out, and so does logging out
+	_, reset, err := s.ResetPasscode(ctx, member.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(ctx, session.Token); !errors.Is(err, ErrMemberTokenRejected) {
+		t.Errorf("token after passcode reset: %v", err)
+	}
+	if _, err := s.Login(ctx, "reader@example.com", passcode); !errors.Is(err, ErrMemberLoginFailed) {
+		t.Errorf("old passcode after reset: %v", err)
+	}
+	session, err = s.Login(ctx, "reader@example.com", reset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Logout(ctx, session.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(ctx, session.Token); !errors.Is(err, ErrMemberTokenRejected) {
+		t.Errorf("token after logout: %v", err)
+	}
+
+	if err := s.Delete(ctx, member.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, member.ID); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("Get after delete: %v", err)
+	}
+}
+
+func TestMemberVerifyExternal(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var body struct {
+			Token  string `json:"token"`
+			SiteID uint   `json:"site_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body.Token {
+		case "good":
+			w.Write([]byte(`{"email":"paid@example.com","name":"Paid"}`))
+		case "broken":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestMemberService(&now)
+	s.verifyURL = server.URL
+	t.Cleanup(s.verifications.Clear)
+	ctx := context.Background()
+
+	for range 2 {
+		identity, err := s.Verify(ctx, "good")
+		if err != nil || identity.Email != "paid@example.com" || identity.MemberID != 0 {
+			t.Fatalf("Verify(good) = %+v, %v", identity, err)
+		}
+		if _, err := s.Verify(ctx, "bad"); !errors.Is(err, ErrMemberTokenRejected) {
+			t.Errorf("Verify(bad) = %v", err)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("%d verification requests, want 2 with the answers cached", n)
+	}
+	// Failures of the verifier are errors, not rejections, and are not cached
+	if _, err := s.Verify(ctx, "broken"); err == nil || errors.Is(err, ErrMemberTokenRejected) {
+		t.Errorf("Verify(broken) = %v", err)
+	}
+}
+
+func TestLockMembersOnly(t *testing.T) {
+	now := time.Now()
+	s := newTestMemberService(&now)
+
+	public := &models.Article{Content: "All of it"}
+	s.Lock(public)
+	if public.Locked || public.Content != "All of it" {
+		t.Errorf("public article locked: %+v", public)
+	}
+
+	article := &models.Article{
+		MembersOnly: true,
+		Content:     "Intro paragraph.\n\nSecond paragraph.\n\nThe rest that only members read.",
+		Translations: []models.ArticleTranslation{
+			{Language: "zh", Content: "引言。\n\n<!-- more -->\n\n正文。"},
+		},
+	}
+	s.Lock(article)
+	if !article.Locked || article.Content != "Intro paragraph.\n\nSecond paragraph." {
+		t.Errorf("locked content = %q", article.Content)
+	}
+	if article.Translations[0].Content != "引言。" {
+		t.Errorf("locked translation = %q", article.Translations[0].Content)
+	}
+
+	tests := []struct {
+		name, content, contentType, want string
+	}{
+		{"single paragraph is cut to half", "abcdefghij", "markdown", "abcd…"},
+		{"long first paragraph", strings.Repeat("word ", 40) + "\n\nEnd.", "markdown", strings.Repeat("word ", 7) + "word…"},
+		{"long code block first", "```go\n" + strings.Repeat("fmt.Println()\n", 5) + "```\n\nAfter.", "markdown", ""},
+		{"html keeps its tags", "<p>One.</p>\n<p>Two.</p>\n<p>Members only.</p>", "html", "<p>One.</p><p>Two.</p>"},
+		{"html single element", "<p>Only one element here</p>", "html", "<p>Only one…</p>"},
+		{"empty", "", "markdown", ""},
+	}
+	for _, tt := range tests {
+		if got := Teaser(tt.content, tt.contentType, 40); got != tt.want {
+			t.Errorf("%s: Teaser = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxMomentLength is the longest moment in characters; anything longer
+	// belongs in an article
+	maxMomentLength = 2000
+	// maxMomentImages is the most images a moment may show
+	maxMomentImages = 9
+	// momentTitleLength is the length of the title feeds show for a moment
+	momentTitleLength = 60
+)
+
+var (
+	ErrInvalidMoment  = errors.New("invalid moment")
+	ErrMomentNotFound = errors.New("moment not found")
+)
+
+// MomentInput holds the editable fields of a moment; nil fields are left
+// unchanged
+type MomentInput struct {
+	Content    *string  `json:"content"`
+	Images     []string `json:"images"`
+	Visibility *string  `json:"visibility"`
+}
+
+// MomentService manages moments, the short status updates of a site. They
+// have no titles, translations or SEO data of their own; public ones are
+// listed on the site and in the moments feed, and in the main feed when
+// MOMENTS_IN_FEED is set.
+type MomentService struct {
+	db       func() *gorm.DB
+	inFeed   bool
+	markdown *MarkdownRenderer
+}
+
+// NewMomentService creates a moment service from the configuration
+func NewMomentService() *MomentService {
+	return &MomentService{
+		db:       func() *gorm.DB { return database.DB },
+		inFeed:   config.Get().Moments.InFeed,
+		markdown: GetGlobalMarkdownRenderer(),
+	}
+}
+
+// InFeed reports whether public moments are mixed into the main feed
+func (s *MomentService) InFeed() bool {
+	return s.inFeed
+}
+
+// List returns a page of the site's moments with one of the given
+// visibilities, newest first; without visibilities every moment is listed
+func (s *MomentService) List(ctx context.Context, visibilities []string, page, limit int) ([]models.Moment, int64, error) {
+	query := s.db().WithContext(ctx).Model(&models.Moment{})
+	if len(visibilities) > 0 {
+		query = query.Where("visibility IN ?", visibilities)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	moments := []models.Moment{}
+	err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&moments).Error
+	return moments, total, err
+}
+
+// Get returns one of the site's moments with one of the given
+// visibilities, or any visibility when none are given
+func (s *MomentService) Get(ctx context.Context, id uint, visibilities ...string) (*models.Moment, error) {
+	query := s.db().WithContext(ctx)
+	if len(visibilities) > 0 {
+		query = query.Where("visibility IN ?", visibilities)
+	}
+	var moment models.Moment
+	if err := query.First(&moment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMomentNotFound
+		}
+		return nil, err
+	}
+	return &moment, nil
+}
+
+// Create posts a moment, public unless the input says otherwise
+func (s *MomentService) Create(ctx context.Context, input MomentInput) (*models.Moment, error) {
+	moment := &models.Moment{Visibility: models.MomentPublic}
+	moment.SetImages([]string{})
+	if err := applyMomentInput(moment, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Create(moment).Error; err != nil {
+		return nil, err
+	}
+	cache.Publish(cache.TopicMoments)
+	return moment, nil
+}
+
+// Update edits a moment
+func (s *MomentService) Update(ctx context.Context, id uint, input MomentInput) (*models.Moment, error) {
+	moment, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyMomentInput(moment, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Save(moment).Error; err != nil {
+		return nil, err
+	}
+	cache.Publish(cache.TopicMoments)
+	return moment, nil
+}
+
+// Delete removes a moment
+func (s *MomentService) Delete(ctx context.Context, id uint) error {
+	result := s.db().WithContext(ctx).Delete(&models.Moment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMomentNotFound
+	}
+	cache.Publish(cache.TopicMoments)
+	return nil
+}
+
+// Title returns the start of a moment's text, for feeds that need a title
+func (s *MomentService) Title(moment *models.Moment) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(moment.Content), "\n")
+	title := strings.Join(strings.Fields(line), " ")
+	if utf8.RuneCountInString(title) > momentTitleLength {
+		title = truncateRunes(title, momentTitleLength-1) + "…"
+	}
+	return title
+}
+
+// HTML renders a moment's text and images for feeds. Images uploaded to
+// the site are linked under baseURL.
+func (s *MomentService) HTML(ctx context.Context, moment *models.Moment, baseURL string) string {
+	var out strings.Builder
+	if rendered, err := s.markdown.Render(ctx, moment.Content); err != nil {
+		slog.Warn("Failed to render moment", "moment_id", moment.ID, "error", err)
+		out.WriteString("<p>" + html.EscapeString(moment.Content) + "</p>\n")
+	} else {
+		out.WriteString(rendered)
+	}
+	for _, image := range moment.ImageList {
+		if strings.HasPrefix(image, "/") {
+			image = strings.TrimRight(baseURL, "/") + image
+		}
+		out.WriteString(`<p><img src="` + html.EscapeString(image) + `" alt=""></p>` + "\n")
+	}
+	return out.String()
+}
+
+func applyMomentInput(moment *models.Moment, input MomentInput) error {
+	if input.Content != nil {
+		moment.Content = strings.TrimSpace(*input.Content)
+	}
+	if input.Images != nil {
+		images := make([]string, 0, len(input.Images))
+		for _, image := range input.Images {
+			if image = strings.TrimSpace(image); image != "" {
+				images = append(images, image)
+			}
+		}
+		moment.SetImages(images)
+	}
+	if input.Visibility != nil {
+		moment.Visibility = *input.Visibility
+	}
+
+	if moment.Content == "" && len(moment.ImageList) == 0 {
+		return fmt.Errorf("%w: a moment needs text or images", ErrInvalidMoment)
+	}
+	if utf8.RuneCountInString(moment.Content) > maxMomentLength {
+		return fmt.Errorf("%w: content is at most %d characters", ErrInvalidMoment, maxMomentLength)
+	}
+	if len(moment.ImageList) > maxMomentImages {
+		return fmt.Errorf("%w: at most %d images", ErrInvalidMoment, maxMomentImages)
+	}
+	for _, image := range moment.ImageList {
+		if !validMediaURL(image) {
+			return fmt.Errorf("%w: %q is not an http(s) URL or a path on the site", ErrInvalidMoment, image)
+		}
+	}
+	switch moment.Visibility {
+	case models.MomentPublic, models.MomentUnlisted, models.MomentPrivate:
+	default:
+		return fmt.Errorf("%w: visibility must be public, unlisted or private", ErrInvalidMoment)
+	}
+	return nil
+}
+
+// validMediaURL accepts absolute http(s) URLs and paths on the site,
+// such as those of uploaded media, for images and links shown on the site
+func validMediaURL(image string) bool {
+	if len(image) > 500 || strings.ContainsAny(image, "\n\r") {
+		return false
+	}
+	if strings.HasPrefix(image, "/") {
+		return !strings.HasPrefix(image, "//")
+	}
+	u, err := url.Parse(image)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+var (
+	globalMomentService     *MomentService
+	globalMomentServiceOnce sync.Once
+)
+
+// GetGlobalMomentService returns the global moment service
+func GetGlobalMomentService() *MomentService {
+	globalMomentServiceOnce.Do(func() {
+		globalMomentService = NewMomentService()
+	})
+	return globalMomentService
+}
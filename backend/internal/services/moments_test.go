@@ -0,0 +1,100 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newTestMomentService(t *testing.T) *MomentService {
+	newTestDB(t)
+	return &MomentService{
+		db:       func() *gorm.DB { return database.DB },
+		markdown: NewMarkdownRenderer(NewCodeHighlighter(), &DiagramRenderer{}),
+	}
+}
+
+func TestMoments(t *testing.T) {
+	s := newTestMomentService(t)
+	ctx := context.Background()
+
+	for _, bad := range []MomentInput{
+		{Content: strPtr("   ")},
+		{Content: strPtr(strings.Repeat("a", maxMomentLength+1))},
+		{Content: strPtr("Hi"), Images: []string{"javascript:alert(1)"}},
+		{Content: strPtr("Hi"), Images: []string{"//evil.example/a.png"}},
+		{Images: make([]string, maxMomentImages+1)},
+		{Content: strPtr("Hi"), Visibility: strPtr("friends")},
+	} {
+		if _, err := s.Create(ctx, bad); !errors.Is(err, ErrInvalidMoment) {
+			t.Errorf("Create(%+v) = %v, want ErrInvalidMoment", bad, err)
+		}
+	}
+
+	images := []string{"/uploads/images/cat.png", "https://cdn.example.com/dog.jpg"}
+	public, err := s.Create(ctx, MomentInput{Content: strPtr("Out for a walk with **the dog**"), Images: images})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if public.Visibility != models.MomentPublic {
+		t.Errorf("new moment is %s, want public", public.Visibility)
+	}
+	unlisted, err := s.Create(ctx, MomentInput{Images: images[:1], Visibility: strPtr(models.MomentUnlisted)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, MomentInput{Content: strPtr("Note to self"), Visibility: strPtr(models.MomentPrivate)}); err != nil {
+		t.Fatal(err)
+	}
+
+	listed, total, err := s.List(ctx, []string{models.MomentPublic}, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(listed) != 1 || listed[0].ID != public.ID {
+		t.Fatalf("public moments = %+v", listed)
+	}
+	if !reflect.DeepEqual(listed[0].ImageList, images) {
+		t.Errorf("images = %q, want %q", listed[0].ImageList, images)
+	}
+	if _, total, _ := s.List(ctx, nil, 1, 10); total != 3 {
+		t.Errorf("%d moments in all, want 3", total)
+	}
+	if _, err := s.Get(ctx, unlisted.ID, models.MomentPublic, models.MomentUnlisted); err != nil {
+		t.Errorf("unlisted moment not found by its link: %v", err)
+	}
+
+	html := s.HTML(ctx, &listed[0], "https://blog.example.com")
+	if !strings.Contains(html, "<strong>the dog</strong>") ||
+		!strings.Contains(html, `src="https://blog.example.com/uploads/images/cat.png"`) ||
+		!strings.Contains(html, `src="https://cdn.example.com/dog.jpg"`) {
+		t.Errorf("HTML = %s", html)
+	}
+	if title := s.Title(&models.Moment{Content: strings.Repeat("word ", 20) + "\nsecond line"}); len([]rune(title)) != momentTitleLength || !strings.HasSuffix(title, "…") {
+		t.Errorf("Title = %q", title)
+	}
+
+	// Making the moment private hides it from the public
+	updated, err := s.Update(ctx, public.ID, MomentInput{Visibility: strPtr(models.MomentPrivate), Images: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.ImageList) != 0 || updated.Content == "" {
+		t.Errorf("after update %+v", updated)
+	}
+	if _, err := s.Get(ctx, public.ID, models.MomentPublic, models.MomentUnlisted); !errors.Is(err, ErrMomentNotFound) {
+		t.Errorf("private moment = %v, want ErrMomentNotFound", err)
+	}
+	if err := s.Delete(ctx, public.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, public.ID); !errors.Is(err, ErrMomentNotFound) {
+		t.Errorf("second delete = %v", err)
+	}
+}
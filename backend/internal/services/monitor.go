@@ -0,0 +1,389 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// MonitorScheduleName is the scheduler entry for MONITOR_SCHEDULE
+	MonitorScheduleName = "site-monitor"
+	// monitorSettingKey is the system setting holding the latest check, so
+	// alerts are raised once however many instances run the monitor
+	monitorSettingKey = "site_monitor"
+)
+
+// Site monitor alert events, sent as the webhook's event field
+const (
+	MonitorEventDown         = "site.down"
+	MonitorEventRecovered    = "site.recovered"
+	MonitorEventCertExpiring = "cert.expiring"
+)
+
+// ErrMonitorDisabled is returned when there is no URL to check
+var ErrMonitorDisabled = errors.New("site monitor has no URL, set MONITOR_URL or PUBLIC_URL")
+
+// MonitorCheck is the result of checking the site's public URL, with the
+// state carried from one check to the next to decide when to alert
+type MonitorCheck struct {
+	URL        string    `json:"url"`
+	Up         bool      `json:"up"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	ResponseMS int64     `json:"response_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+
+	CertExpiresAt *time.Time `json:"cert_expires_at,omitempty"`
+	CertDaysLeft  *int       `json:"cert_days_left,omitempty"`
+	CertIssuer    string     `json:"cert_issuer,omitempty"`
+
+	// Failures counts failed checks in a row; DownSince is set once they
+	// reach the alert threshold
+	Failures  int        `json:"failures"`
+	DownSince *time.Time `json:"down_since,omitempty"`
+	// CertAlerted is the expiry of the certificate last warned about, so
+	// each certificate is reported once
+	CertAlerted *time.Time `json:"cert_alerted,omitempty"`
+}
+
+// MonitorStatus reports the monitor's settings and the latest check
+type MonitorStatus struct {
+	Enabled   bool          `json:"enabled"`
+	URL       string        `json:"url,omitempty"`
+	Schedule  string        `json:"schedule,omitempty"`
+	NextRun   *time.Time    `json:"next_run,omitempty"`
+	LastCheck *MonitorCheck `json:"last_check,omitempty"`
+}
+
+// MonitorAlert is the payload of a site monitor notification
+type MonitorAlert struct {
+	Event   string       `json:"event"`
+	Message string       `json:"message"`
+	Check   MonitorCheck `json:"check"`
+}
+
+// SiteMonitor checks that the site answers at its public URL and that its
+// TLS certificate is not about to expire, and alerts when either changes
+type SiteMonitor struct {
+	db           func() *gorm.DB
+	url          string
+	failures     int
+	certWarnDays int
+	webhookURL   string
+	email        string
+	httpClient   *http.Client
+	now          func() time.Time
+	// notify delivers an alert; replaced in tests
+	notify func(MonitorAlert)
+}
+
+// NewSiteMonitor creates a monitor from the MONITOR_* settings
+func NewSiteMonitor() *SiteMonitor {
+	cfg := config.Get()
+	m := &SiteMonitor{
+		db:           func() *gorm.DB { return database.DB },
+		url:          cfg.Monitor.URL,
+		failures:     cfg.Monitor.Failures,
+		certWarnDays: cfg.Monitor.CertWarnDays,
+		webhookURL:   cfg.Monitor.WebhookURL,
+		email:        cfg.Monitor.Email,
+		httpClient:   &http.Client{Timeout: cfg.Monitor.Timeout.Std()},
+		now:          time.Now,
+	}
+	if m.url == "" {
+		m.url = cfg.Server.PublicURL
+	}
+	if m.webhookURL == "" && m.email == "" {
+		m.webhookURL, m.email = cfg.Alerts.WebhookURL, cfg.Alerts.Email
+	}
+	m.notify = m.send
+	return m
+}
+
+// Enabled reports whether there is a URL to check
+func (m *SiteMonitor) Enabled() bool {
+	return m.url != ""
+}
+
+// Status returns the monitor's settings and the latest check
+func (m *SiteMonitor) Status() (*MonitorStatus, error) {
+	status := &MonitorStatus{Enabled: m.Enabled(), URL: m.url}
+	if status.Enabled {
+		status.Schedule = config.Get().Monitor.Schedule
+		status.NextRun = GetGlobalScheduler().NextRun(MonitorScheduleName)
+	}
+	last, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	status.LastCheck = last
+	return status, nil
+}
+
+// Check requests the public URL, reads its certificate, raises the alerts
+// that are due and records the result
+func (m *SiteMonitor) Check(ctx context.Context) (*MonitorCheck, error) {
+	if !m.Enabled() {
+		return nil, ErrMonitorDisabled
+	}
+	previous, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	check := m.probe(ctx)
+	alerts := m.evaluate(previous, check)
+	if err := m.save(check); err != nil {
+		return nil, err
+	}
+	for _, alert := range alerts {
+		slog.Warn("Site monitor alert", "event", alert.Event, "url", check.URL, "message", alert.Message)
+		m.notify(alert)
+	}
+	return check, nil
+}
+
+// probe requests the URL once. A certificate that fails verification is
+// still read, so an expired certificate is reported with its expiry.
+func (m *SiteMonitor) probe(ctx context.Context) *MonitorCheck {
+	check := &MonitorCheck{URL: m.url, CheckedAt: m.now()}
+	started := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.url, nil)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	req.Header.Set("User-Agent", "kuno-monitor")
+
+	resp, err := m.httpClient.Do(req)
+	check.ResponseMS = time.Since(started).Milliseconds()
+	var cert *x509.Certificate
+	if err != nil {
+		check.Error = err.Error()
+		if req.URL.Scheme == "https" {
+			cert = m.peerCertificate(ctx, req.URL)
+		}
+	} else {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		check.StatusCode = resp.StatusCode
+		check.Up = resp.StatusCode < 400
+		if !check.Up {
+			check.Error = resp.Status
+		}
+		if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+			cert = resp.TLS.PeerCertificates[0]
+		}
+	}
+
+	if cert != nil {
+		expires := cert.NotAfter
+		days := int(expires.Sub(check.CheckedAt).Hours() / 24)
+		check.CertExpiresAt, check.CertDaysLeft = &expires, &days
+		check.CertIssuer = cert.Issuer.CommonName
+	}
+	return check
+}
+
+// peerCertificate reads the certificate a host presents without verifying it
+func (m *SiteMonitor) peerCertificate(ctx context.Context, u *url.URL) *x509.Certificate {
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: m.httpClient.Timeout},
+		Config:    &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+	return certs[0]
+}
+
+// evaluate carries the alert state over from the previous check and returns
+// the alerts the new result calls for
+func (m *SiteMonitor) evaluate(previous, check *MonitorCheck) []MonitorAlert {
+	var alerts []MonitorAlert
+	if previous != nil {
+		check.DownSince, check.CertAlerted = previous.DownSince, previous.CertAlerted
+	}
+
+	if check.Up {
+		if check.DownSince != nil {
+			alerts = append(alerts, MonitorAlert{
+				Event: MonitorEventRecovered,
+				Message: fmt.Sprintf("%s is reachable again after %s", check.URL,
+					check.CheckedAt.Sub(*check.DownSince).Round(time.Second)),
+			})
+			check.DownSince = nil
+		}
+	} else {
+		if previous != nil {
+			check.Failures = previous.Failures
+		}
+		check.Failures++
+		if check.DownSince == nil && check.Failures >= m.failures {
+			since := check.CheckedAt
+			check.DownSince = &since
+			alerts = append(alerts, MonitorAlert{
+				Event:   MonitorEventDown,
+				Message: fmt.Sprintf("%s is unreachable: %s", check.URL, check.Error),
+			})
+		}
+	}
+
+	if check.CertExpiresAt != nil && *check.CertDaysLeft <= m.certWarnDays &&
+		(check.CertAlerted == nil || !check.CertAlerted.Equal(*check.CertExpiresAt)) {
+		message := fmt.Sprintf("The TLS certificate of %s expires in %d days, on %s", check.URL,
+			*check.CertDaysLeft, check.CertExpiresAt.Format(time.RFC1123))
+		if *check.CertDaysLeft < 0 {
+			message = fmt.Sprintf("The TLS certificate of %s expired on %s", check.URL, check.CertExpiresAt.Format(time.RFC1123))
+		}
+		expires := *check.CertExpiresAt
+		check.CertAlerted = &expires
+		alerts = append(alerts, MonitorAlert{Event: MonitorEventCertExpiring, Message: message})
+	}
+
+	for i := range alerts {
+		alerts[i].Check = *check
+	}
+	return alerts
+}
+
+// monitorSeverity is the notification severity of each alert event
+var monitorSeverity = map[string]string{
+	MonitorEventDown:         models.SeverityCritical,
+	MonitorEventRecovered:    models.SeverityInfo,
+	MonitorEventCertExpiring: models.SeverityWarning,
+}
+
+// send records an alert in the notification center and delivers it to the
+// configured webhook and email address
+func (m *SiteMonitor) send(alert MonitorAlert) {
+	notifyAdmin(NotificationInput{
+		Source:   models.NotificationSourceMonitor,
+		Type:     alert.Event,
+		Severity: monitorSeverity[alert.Event],
+		Title:    alert.Message,
+		Message:  formatMonitorCheck(alert.Check),
+	})
+	if m.webhookURL != "" {
+		if err := m.sendWebhook(alert); err != nil {
+			slog.Error("Failed to send site monitor webhook", "event", alert.Event, "error", err)
+		}
+	}
+	if m.email != "" && smtpConfigured() {
+		subject := "[kuno] " + alert.Message
+		if len(subject) > 120 {
+			subject = subject[:120]
+		}
+		if err := sendPlainEmail(m.email, subject, formatMonitorAlertEmail(alert)); err != nil {
+			slog.Error("Failed to send site monitor email", "event", alert.Event, "error", err)
+		}
+	}
+}
+
+func (m *SiteMonitor) sendWebhook(alert MonitorAlert) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"event": alert.Event,
+		"data":  alert,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := m.httpClient.Post(m.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func formatMonitorAlertEmail(alert MonitorAlert) string {
+	return alert.Message + "\n\n" + formatMonitorCheck(alert.Check)
+}
+
+// formatMonitorCheck lists the details of a check, one per line
+func formatMonitorCheck(check MonitorCheck) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "URL:       %s\n", check.URL)
+	fmt.Fprintf(&b, "Checked:   %s\n", check.CheckedAt.Format(time.RFC1123))
+	if check.StatusCode != 0 {
+		fmt.Fprintf(&b, "Status:    %d\n", check.StatusCode)
+	}
+	if check.Error != "" {
+		fmt.Fprintf(&b, "Error:     %s\n", check.Error)
+	}
+	if check.CertExpiresAt != nil {
+		fmt.Fprintf(&b, "Cert:      expires %s (%s)\n", check.CertExpiresAt.Format(time.RFC1123), check.CertIssuer)
+	}
+	return b.String()
+}
+
+func (m *SiteMonitor) load() (*MonitorCheck, error) {
+	var setting models.SystemSetting
+	if err := m.db().Limit(1).Find(&setting, models.SystemSetting{Key: monitorSettingKey}).Error; err != nil {
+		return nil, err
+	}
+	if setting.Value == "" {
+		return nil, nil
+	}
+	var check MonitorCheck
+	if err := json.Unmarshal([]byte(setting.Value), &check); err != nil {
+		return nil, nil
+	}
+	return &check, nil
+}
+
+func (m *SiteMonitor) save(check *MonitorCheck) error {
+	value, err := json.Marshal(check)
+	if err != nil {
+		return err
+	}
+	setting := models.SystemSetting{Key: monitorSettingKey, Value: string(value)}
+	return m.db().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&setting).Error
+}
+
+var (
+	globalSiteMonitor *SiteMonitor
+	siteMonitorOnce   sync.Once
+)
+
+// GetGlobalSiteMonitor returns the shared site monitor
+func GetGlobalSiteMonitor() *SiteMonitor {
+	siteMonitorOnce.Do(func() {
+		globalSiteMonitor = NewSiteMonitor()
+	})
+	return globalSiteMonitor
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestSiteMonitorAlerts(t *testing.T) {
+	newTestDB(t)
+	healthy := true
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	var events []string
+	m := &SiteMonitor{
+		db:           func() *gorm.DB { return database.DB },
+		url:          server.URL,
+		failures:     2,
+		certWarnDays: 14,
+		httpClient:   server.Client(),
+		now:          func() time.Time { return now },
+		notify:       func(alert MonitorAlert) { events = append(events, alert.Event) },
+	}
+	check := func() *MonitorCheck {
+		t.Helper()
+		result, err := m.Check(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	first := check()
+	if !first.Up || first.StatusCode != http.StatusOK || first.CertExpiresAt == nil {
+		t.Fatalf("unexpected first check %+v", first)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no alerts for a healthy site, got %v", events)
+	}
+
+	// One failure stays below the threshold, the second raises an alert
+	healthy = false
+	if result := check(); result.Up || result.Failures != 1 || len(events) != 0 {
+		t.Fatalf("unexpected first failure %+v, alerts %v", result, events)
+	}
+	if result := check(); result.DownSince == nil || len(events) != 1 || events[0] != MonitorEventDown {
+		t.Fatalf("expected a down alert, got %+v, alerts %v", result, events)
+	}
+	check()
+	if len(events) != 1 {
+		t.Fatalf("down alert repeated: %v", events)
+	}
+
+	healthy = true
+	if result := check(); result.DownSince != nil || result.Failures != 0 || events[len(events)-1] != MonitorEventRecovered {
+		t.Fatalf("expected recovery, got %+v, alerts %v", result, events)
+	}
+
+	// Close to expiry the certificate is reported once
+	now = first.CertExpiresAt.Add(-10 * 24 * time.Hour)
+	events = nil
+	check()
+	check()
+	if len(events) != 1 || events[0] != MonitorEventCertExpiring {
+		t.Fatalf("expected one certificate alert, got %v", events)
+	}
+
+	status, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.LastCheck == nil || *status.LastCheck.CertDaysLeft != 10 {
+		t.Errorf("unexpected status %+v", status.LastCheck)
+	}
+}
+
+func TestSiteMonitorDisabled(t *testing.T) {
+	m := &SiteMonitor{}
+	if _, err := m.Check(context.Background()); err != ErrMonitorDisabled {
+		t.Errorf("expected ErrMonitorDisabled, got %v", err)
+	}
+}
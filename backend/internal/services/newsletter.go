@@ -0,0 +1,809 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidEmail        = errors.New("invalid email address")
+	ErrInvalidNewsletter   = errors.New("invalid newsletter")
+	ErrNewsletterNotFound  = errors.New("newsletter not found")
+	ErrNewsletterNotDraft  = errors.New("newsletter has already been sent")
+	ErrSubscriberNotFound  = errors.New("subscriber not found")
+	ErrSubscriptionToken   = errors.New("invalid or expired subscription link")
+	ErrMailNotConfigured   = errors.New("email is not configured, set SMTP_HOST and SMTP_FROM or save the mail settings")
+	ErrNoArticlesForDigest = errors.New("no new articles since the last digest")
+)
+
+const (
+	// NewsletterDigestScheduleName is the scheduler entry for NEWSLETTER_DIGEST_SCHEDULE
+	NewsletterDigestScheduleName = "newsletter-digest"
+	// confirmationResendInterval keeps repeated sign-ups from mailing the
+	// same address over and over
+	confirmationResendInterval = 10 * time.Minute
+	// defaultDigestWindow is how far back the first digest of a site looks
+	defaultDigestWindow = 7 * 24 * time.Hour
+	// summaryLength is the longest article excerpt in a composed newsletter
+	summaryLength = 300
+)
+
+// NewsletterInput holds the editable fields of a newsletter; nil fields are
+// left unchanged
+type NewsletterInput struct {
+	Subject    *string `json:"subject"`
+	Body       *string `json:"body"`
+	Language   *string `json:"language"`
+	ArticleIDs []uint  `json:"article_ids"`
+}
+
+// SubscriberStats counts a site's subscribers by status
+type SubscriberStats map[string]int64
+
+// newsletterJob is the payload of newsletter send and confirmation jobs
+type newsletterJob struct {
+	NewsletterID uint   `json:"newsletter_id,omitempty"`
+	SubscriberID uint   `json:"subscriber_id,omitempty"`
+	BaseURL      string `json:"base_url,omitempty"`
+}
+
+// NewsletterService manages email subscribers and sends newsletters to
+// them. Sign-ups are confirmed by email before anything else is sent, and
+// every message carries a one-click unsubscribe link.
+type NewsletterService struct {
+	db         func() *gorm.DB
+	batchSize  int
+	batchDelay time.Duration
+	maxBounces int
+	// sendMail and mailConfigured are replaced in tests
+	sendMail       func(to, subject, body string, headers map[string]string) error
+	mailConfigured func() bool
+	// llm writes AI-composed digests
+	llm *LLMClient
+}
+
+// NewNewsletterService creates a newsletter service from NEWSLETTER_* settings
+func NewNewsletterService() *NewsletterService {
+	cfg := config.Get().Newsletter
+	return &NewsletterService{
+		db:             func() *gorm.DB { return database.DB },
+		batchSize:      cfg.BatchSize,
+		batchDelay:     cfg.BatchDelay.Std(),
+		maxBounces:     cfg.MaxBounces,
+		sendMail:       sendEmail,
+		mailConfigured: smtpConfigured,
+		llm:            NewLLMClient(),
+	}
+}
+
+// Subscribe signs an address up for the site in ctx and mails it a
+// confirmation link. Addresses that are already subscribed get no mail and
+// no error, so the endpoint does not reveal who is subscribed.
+func (s *NewsletterService) Subscribe(ctx context.Context, email, language, baseURL string) error {
+	if !s.mailConfigured() {
+		return ErrMailNotConfigured
+	}
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || address.Address != strings.TrimSpace(email) || len(address.Address) > 254 {
+		return ErrInvalidEmail
+	}
+	email = strings.ToLower(address.Address)
+
+	db := s.db().WithContext(ctx)
+	var subscriber models.Subscriber
+	found := db.Where("email = ?", email).Limit(1).Find(&subscriber)
+	if found.Error != nil {
+		return found.Error
+	}
+	switch {
+	case found.RowsAffected == 0:
+		token, err := newSubscriptionToken()
+		if err != nil {
+			return err
+		}
+		subscriber = models.Subscriber{Email: email, Language: language, Status: models.SubscriberPending, Token: token}
+		if err := db.Create(&subscriber).Error; err != nil {
+			return err
+		}
+	case subscriber.Status == models.SubscriberActive:
+		return nil
+	case subscriber.Status == models.SubscriberPending && time.Since(subscriber.UpdatedAt) < confirmationResendInterval:
+		return nil
+	default:
+		// Signing up again after unsubscribing or bouncing starts over
+		token, err := newSubscriptionToken()
+		if err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"status": models.SubscriberPending, "token": token, "language": language,
+			"bounce_count": 0, "unsubscribed_at": nil, "updated_at": time.Now(),
+		}
+		if err := db.Model(&subscriber).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	_, err = GetGlobalJobQueue().Enqueue(JobNewsletterConfirm, newsletterJob{SubscriberID: subscriber.ID, BaseURL: baseURL})
+	return err
+}
+
+// SendConfirmation mails the confirmation link to a pending subscriber
+func (s *NewsletterService) SendConfirmation(subscriberID uint, baseURL string) error {
+	var subscriber models.Subscriber
+	if err := s.db().First(&subscriber, subscriberID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if subscriber.Status != models.SubscriberPending {
+		return nil
+	}
+	subject, body, err := GetGlobalMailer().Render(MailTemplateSubscriptionConfirm, subscriber.Language, map[string]interface{}{
+		"Link": subscriptionLink(baseURL, "confirm", subscriber.Token),
+	})
+	if err != nil {
+		return err
+	}
+	err = s.sendMail(subscriber.Email, subject, body, nil)
+	if errors.Is(err, ErrAddressSuppressed) {
+		slog.Info("Skipping confirmation to a suppressed address", "subscriber_id", subscriber.ID)
+		return nil
+	}
+	return err
+}
+
+// Confirm activates the subscription the token belongs to
+func (s *NewsletterService) Confirm(token string) (*models.Subscriber, error) {
+	subscriber, err := s.byToken(token)
+	if err != nil {
+		return nil, err
+	}
+	switch subscriber.Status {
+	case models.SubscriberActive:
+		return subscriber, nil
+	case models.SubscriberPending:
+	default:
+		return nil, ErrSubscriptionToken
+	}
+	now := time.Now()
+	if err := s.db().Model(subscriber).Updates(map[string]interface{}{
+		"status": models.SubscriberActive, "confirmed_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return subscriber, nil
+}
+
+// Unsubscribe ends the subscription the token belongs to
+func (s *NewsletterService) Unsubscribe(token string) (*models.Subscriber, error) {
+	subscriber, err := s.byToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if subscriber.Status == models.SubscriberUnsubscribed {
+		return subscriber, nil
+	}
+	now := time.Now()
+	if err := s.db().Model(subscriber).Updates(map[string]interface{}{
+		"status": models.SubscriberUnsubscribed, "unsubscribed_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return subscriber, nil
+}
+
+func (s *NewsletterService) byToken(token string) (*models.Subscriber, error) {
+	if len(token) != 64 {
+		return nil, ErrSubscriptionToken
+	}
+	var subscriber models.Subscriber
+	if err := s.db().Where("token = ?", token).First(&subscriber).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubscriptionToken
+		}
+		return nil, err
+	}
+	return &subscriber, nil
+}
+
+// ListSubscribers returns a page of the site's subscribers, newest first,
+// optionally only those with the given status
+func (s *NewsletterService) ListSubscribers(ctx context.Context, status string, page, limit int) ([]models.Subscriber, int64, error) {
+	query := s.db().WithContext(ctx).Model(&models.Subscriber{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	subscribers := []models.Subscriber{}
+	err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&subscribers).Error
+	return subscribers, total, err
+}
+
+// SubscriberStats counts the site's subscribers by status
+func (s *NewsletterService) SubscriberStats(ctx context.Context) (SubscriberStats, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := s.db().WithContext(ctx).Model(&models.Subscriber{}).
+		Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	stats := SubscriberStats{
+		models.SubscriberPending: 0, models.SubscriberActive: 0,
+		models.SubscriberUnsubscribed: 0, models.SubscriberBounced: 0,
+	}
+	for _, row := range rows {
+		stats[row.Status] = row.Count
+	}
+	return stats, nil
+}
+
+// DeleteSubscriber removes a subscriber and its delivery history
+func (s *NewsletterService) DeleteSubscriber(ctx context.Context, id uint) error {
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Subscriber{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSubscriberNotFound
+		}
+		return tx.Where("subscriber_id = ?", id).Delete(&models.NewsletterDelivery{}).Error
+	})
+}
+
+// RecordBounces counts a bounce for each address, as reported by the mail
+// provider, and stops mailing addresses that reached the bounce limit. It
+// returns how many known addresses were updated.
+func (s *NewsletterService) RecordBounces(ctx context.Context, emails []string) (int, error) {
+	updated := 0
+	for _, email := range emails {
+		var subscriber models.Subscriber
+		found := s.db().WithContext(ctx).Where("email = ?", strings.ToLower(strings.TrimSpace(email))).Limit(1).Find(&subscriber)
+		if found.Error != nil {
+			return updated, found.Error
+		}
+		if found.RowsAffected == 0 {
+			continue
+		}
+		if err := s.recordBounce(&subscriber); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+func (s *NewsletterService) recordBounce(subscriber *models.Subscriber) error {
+	now := time.Now()
+	updates := map[string]interface{}{"bounce_count": gorm.Expr("bounce_count + 1"), "last_bounce_at": now}
+	if subscriber.BounceCount+1 >= s.maxBounces && subscriber.Status == models.SubscriberActive {
+		updates["status"] = models.SubscriberBounced
+	}
+	return s.db().Model(&models.Subscriber{}).Where("id = ?", subscriber.ID).Updates(updates).Error
+}
+
+// ListNewsletters returns the site's newsletters, newest first
+func (s *NewsletterService) ListNewsletters(ctx context.Context) ([]models.Newsletter, error) {
+	newsletters := []models.Newsletter{}
+	err := s.db().WithContext(ctx).Order("created_at DESC").Find(&newsletters).Error
+	return newsletters, err
+}
+
+// GetNewsletter returns one of the site's newsletters
+func (s *NewsletterService) GetNewsletter(ctx context.Context, id uint) (*models.Newsletter, error) {
+	var newsletter models.Newsletter
+	if err := s.db().WithContext(ctx).First(&newsletter, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNewsletterNotFound
+		}
+		return nil, err
+	}
+	return &newsletter, nil
+}
+
+// CreateNewsletter saves a draft newsletter written by hand. Links in it
+// point at baseURL.
+func (s *NewsletterService) CreateNewsletter(ctx context.Context, input NewsletterInput, baseURL string) (*models.Newsletter, error) {
+	newsletter := &models.Newsletter{Kind: models.NewsletterCustom, Status: models.NewsletterDraft, BaseURL: baseURL}
+	if err := applyNewsletterInput(newsletter, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Create(newsletter).Error; err != nil {
+		return nil, err
+	}
+	return newsletter, nil
+}
+
+// UpdateNewsletter edits a draft
+func (s *NewsletterService) UpdateNewsletter(ctx context.Context, id uint, input NewsletterInput) (*models.Newsletter, error) {
+	newsletter, err := s.GetNewsletter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if newsletter.Status != models.NewsletterDraft {
+		return nil, ErrNewsletterNotDraft
+	}
+	if err := applyNewsletterInput(newsletter, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Save(newsletter).Error; err != nil {
+		return nil, err
+	}
+	return newsletter, nil
+}
+
+// DeleteNewsletter removes a newsletter that is not being sent, with its
+// delivery history
+func (s *NewsletterService) DeleteNewsletter(ctx context.Context, id uint) error {
+	newsletter, err := s.GetNewsletter(ctx, id)
+	if err != nil {
+		return err
+	}
+	if newsletter.Status == models.NewsletterSending {
+		return ErrNewsletterNotDraft
+	}
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("newsletter_id = ?", id).Delete(&models.NewsletterDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(newsletter).Error
+	})
+}
+
+func applyNewsletterInput(newsletter *models.Newsletter, input NewsletterInput) error {
+	if input.Subject != nil {
+		newsletter.Subject = strings.TrimSpace(*input.Subject)
+	}
+	if input.Body != nil {
+		newsletter.Body = *input.Body
+	}
+	if input.Language != nil {
+		newsletter.Language = strings.TrimSpace(*input.Language)
+	}
+	if input.ArticleIDs != nil {
+		newsletter.ArticleIDs = joinIDs(input.ArticleIDs)
+	}
+	if newsletter.Subject == "" || len(newsletter.Subject) > 255 {
+		return fmt.Errorf("%w: subject is required and at most 255 characters", ErrInvalidNewsletter)
+	}
+	if strings.TrimSpace(newsletter.Body) == "" {
+		return fmt.Errorf("%w: body is required", ErrInvalidNewsletter)
+	}
+	return nil
+}
+
+// ComposeAnnouncement drafts a newsletter announcing a new article
+func (s *NewsletterService) ComposeAnnouncement(ctx context.Context, article *models.Article, baseURL string) (*models.Newsletter, error) {
+	subject, body, err := GetGlobalMailer().Render(MailTemplateAnnouncement, article.DefaultLang, map[string]interface{}{
+		"Title": article.Title, "Excerpt": articleExcerpt(article), "Link": articleLink(baseURL, article),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	newsletter := &models.Newsletter{
+		SiteID:     article.SiteID,
+		Kind:       models.NewsletterAnnouncement,
+		Subject:    truncateRunes(subject, 255),
+		Body:       body,
+		ArticleIDs: joinIDs([]uint{article.ID}),
+		BaseURL:    baseURL,
+		Status:     models.NewsletterDraft,
+	}
+	if err := s.db().WithContext(ctx).Create(newsletter).Error; err != nil {
+		return nil, err
+	}
+	return newsletter, nil
+}
+
+// ComposeDigest drafts a newsletter listing the site's articles published
+// since the last digest was sent, or in the last week for the first one
+func (s *NewsletterService) ComposeDigest(ctx context.Context, siteID uint, baseURL string) (*models.Newsletter, error) {
+	db := s.db().WithContext(ctx)
+	since, err := s.lastDigest(db, siteID)
+	if err != nil {
+		return nil, err
+	}
+
+	var articles []models.Article
+	if err := db.Where("site_id = ? AND created_at > ? AND created_at <= ?", siteID, since, time.Now()).
+		Order("created_at").Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	if len(articles) == 0 {
+		return nil, ErrNoArticlesForDigest
+	}
+
+	ids := make([]uint, 0, len(articles))
+	items := make([]map[string]interface{}, 0, len(articles))
+	for i := range articles {
+		ids = append(ids, articles[i].ID)
+		items = append(items, map[string]interface{}{
+			"Title": articles[i].Title, "Excerpt": articleExcerpt(&articles[i]), "Link": articleLink(baseURL, &articles[i]),
+		})
+	}
+	subject, body, err := GetGlobalMailer().Render(MailTemplateDigest, siteLanguage(db, siteID), map[string]interface{}{
+		"Since": since, "Count": len(articles), "Intro": "", "Articles": items, "Popular": nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	newsletter := &models.Newsletter{
+		SiteID:     siteID,
+		Kind:       models.NewsletterDigest,
+		Subject:    truncateRunes(subject, 255),
+		Body:       body,
+		ArticleIDs: joinIDs(ids),
+		BaseURL:    baseURL,
+		Status:     models.NewsletterDraft,
+	}
+	if err := db.Create(newsletter).Error; err != nil {
+		return nil, err
+	}
+	return newsletter, nil
+}
+
+// lastDigest returns when the last digest of a site was sent, or the start
+// of the default window before the first one
+func (s *NewsletterService) lastDigest(db *gorm.DB, siteID uint) (time.Time, error) {
+	var last models.Newsletter
+	found := db.Where("site_id = ? AND kind = ? AND status = ?", siteID, models.NewsletterDigest, models.NewsletterSent).
+		Order("sent_at DESC").Limit(1).Find(&last)
+	if found.Error != nil {
+		return time.Time{}, found.Error
+	}
+	if found.RowsAffected > 0 && last.SentAt != nil {
+		return *last.SentAt, nil
+	}
+	return time.Now().Add(-defaultDigestWindow), nil
+}
+
+// Send queues a draft to be mailed to every active subscriber of its site
+func (s *NewsletterService) Send(ctx context.Context, id uint) (*models.Job, error) {
+	if !s.mailConfigured() {
+		return nil, ErrMailNotConfigured
+	}
+	newsletter, err := s.GetNewsletter(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result := s.db().Model(&models.Newsletter{}).
+		Where("id = ? AND status = ?", newsletter.ID, models.NewsletterDraft).
+		Update("status", models.NewsletterSending)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNewsletterNotDraft
+	}
+	return GetGlobalJobQueue().Enqueue(JobNewsletterSend, newsletterJob{NewsletterID: newsletter.ID})
+}
+
+// Deliver mails a newsletter that is being sent, in batches. Deliveries are
+// recorded one by one, so a run interrupted by a restart picks up where it
+// stopped. Addresses the mail server rejects permanently count as bounces.
+func (s *NewsletterService) Deliver(ctx context.Context, id uint) (*models.Newsletter, error) {
+	db := s.db()
+	var newsletter models.Newsletter
+	if err := db.First(&newsletter, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNewsletterNotFound
+		}
+		return nil, err
+	}
+	if newsletter.Status != models.NewsletterSending {
+		return &newsletter, nil
+	}
+
+	// Queue a delivery for every active subscriber that has none yet and
+	// is not suppressed
+	var recipients []models.Subscriber
+	query := db.Where("site_id = ? AND status = ?", newsletter.SiteID, models.SubscriberActive).
+		Where("id NOT IN (?)", db.Model(&models.NewsletterDelivery{}).Select("subscriber_id").Where("newsletter_id = ?", newsletter.ID)).
+		Where("email NOT IN (?)", db.Model(&models.MailSuppression{}).Select("email"))
+	if newsletter.Language != "" {
+		query = query.Where("language = ?", newsletter.Language)
+	}
+	if err := query.Find(&recipients).Error; err != nil {
+		return nil, err
+	}
+	if len(recipients) > 0 {
+		deliveries := make([]models.NewsletterDelivery, len(recipients))
+		for i, subscriber := range recipients {
+			deliveries[i] = models.NewsletterDelivery{
+				NewsletterID: newsletter.ID, SubscriberID: subscriber.ID, Email: subscriber.Email, Status: models.DeliveryPending,
+			}
+		}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(deliveries, 500).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		var batch []models.NewsletterDelivery
+		if err := db.Where("newsletter_id = ? AND status = ?", newsletter.ID, models.DeliveryPending).
+			Order("id").Limit(s.batchSize).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for i := range batch {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			s.deliver(&newsletter, &batch[i])
+		}
+		if len(batch) < s.batchSize {
+			break
+		}
+		select {
+		case <-time.After(s.batchDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	now := time.Now()
+	newsletter.Status, newsletter.SentAt = models.NewsletterSent, &now
+	if err := s.countDeliveries(&newsletter); err != nil {
+		return nil, err
+	}
+	if err := db.Save(&newsletter).Error; err != nil {
+		return nil, err
+	}
+	slog.Info("Newsletter sent", "newsletter_id", newsletter.ID, "recipients", newsletter.Recipients,
+		"sent", newsletter.Sent, "failed", newsletter.Failed, "bounced", newsletter.Bounced)
+	return &newsletter, nil
+}
+
+// deliver mails the newsletter to one subscriber and records the outcome
+func (s *NewsletterService) deliver(newsletter *models.Newsletter, delivery *models.NewsletterDelivery) {
+	var subscriber models.Subscriber
+	err := s.db().First(&subscriber, delivery.SubscriberID).Error
+	switch {
+	case err == nil && subscriber.Status != models.SubscriberActive:
+		err = fmt.Errorf("subscriber is %s", subscriber.Status)
+	case err == nil:
+		unsubscribe := subscriptionLink(newsletter.BaseURL, "unsubscribe", subscriber.Token)
+		var subject, body string
+		subject, body, err = GetGlobalMailer().Render(MailTemplateNewsletter, subscriber.Language, map[string]interface{}{
+			"Subject": newsletter.Subject, "Body": strings.TrimRight(newsletter.Body, "\n"), "UnsubscribeLink": unsubscribe,
+		})
+		if err == nil {
+			err = s.sendMail(subscriber.Email, subject, body, map[string]string{
+				"List-Unsubscribe":      "<" + unsubscribe + ">",
+				"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+			})
+		}
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": models.DeliverySent, "sent_at": now, "error": ""}
+	if err != nil {
+		updates["status"], updates["error"] = models.DeliveryFailed, err.Error()
+		if isPermanentMailError(err) {
+			updates["status"] = models.DeliveryBounced
+			if bounceErr := s.recordBounce(&subscriber); bounceErr != nil {
+				slog.Error("Failed to record bounce", "subscriber_id", subscriber.ID, "error", bounceErr)
+			}
+		}
+		slog.Warn("Newsletter delivery failed", "newsletter_id", newsletter.ID, "subscriber_id", delivery.SubscriberID, "error", err)
+	}
+	if err := s.db().Model(delivery).Updates(updates).Error; err != nil {
+		slog.Error("Failed to record newsletter delivery", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// countDeliveries refreshes the newsletter's delivery counters
+func (s *NewsletterService) countDeliveries(newsletter *models.Newsletter) error {
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	if err := s.db().Model(&models.NewsletterDelivery{}).Select("status, COUNT(*) AS count").
+		Where("newsletter_id = ?", newsletter.ID).Group("status").Scan(&rows).Error; err != nil {
+		return err
+	}
+	newsletter.Recipients, newsletter.Sent, newsletter.Failed, newsletter.Bounced = 0, 0, 0, 0
+	for _, row := range rows {
+		newsletter.Recipients += row.Count
+		switch row.Status {
+		case models.DeliverySent:
+			newsletter.Sent = row.Count
+		case models.DeliveryFailed:
+			newsletter.Failed = row.Count
+		case models.DeliveryBounced:
+			newsletter.Bounced = row.Count
+		}
+	}
+	return nil
+}
+
+// SendDigests composes and sends a digest for every site with active
+// subscribers and new articles
+func (s *NewsletterService) SendDigests(ctx context.Context) (int, error) {
+	var siteIDs []uint
+	if err := s.db().Model(&models.Subscriber{}).Distinct().Where("status = ?", models.SubscriberActive).
+		Pluck("site_id", &siteIDs).Error; err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, siteID := range siteIDs {
+		baseURL := siteBaseURL(siteID)
+		if baseURL == "" {
+			slog.Warn("Skipping newsletter digest, the site has no public URL", "site_id", siteID)
+			continue
+		}
+		newsletter, err := s.ComposeDigest(ctx, siteID, baseURL)
+		if errors.Is(err, ErrNoArticlesForDigest) {
+			continue
+		}
+		if err != nil {
+			return sent, err
+		}
+		if _, err := s.Send(ctx, newsletter.ID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// announce is the ArticlePublished hook that mails new articles to
+// subscribers when NEWSLETTER_ANNOUNCE_POSTS is on
+func (s *NewsletterService) announce(ctx context.Context, event hooks.Event, payload interface{}) error {
+	article, ok := payload.(*models.Article)
+	if !ok || !config.Get().Newsletter.AnnouncePosts || !s.mailConfigured() {
+		return nil
+	}
+	var active int64
+	if err := s.db().Model(&models.Subscriber{}).
+		Where("site_id = ? AND status = ?", article.SiteID, models.SubscriberActive).Count(&active).Error; err != nil {
+		return err
+	}
+	if active == 0 {
+		return nil
+	}
+	baseURL := siteBaseURL(article.SiteID)
+	if baseURL == "" {
+		return fmt.Errorf("cannot announce article %d, set PUBLIC_URL for links to it", article.ID)
+	}
+	newsletter, err := s.ComposeAnnouncement(ctx, article, baseURL)
+	if err != nil {
+		return err
+	}
+	_, err = s.Send(ctx, newsletter.ID)
+	return err
+}
+
+// siteBaseURL returns the public address of a site for links in mail sent
+// outside a request: PUBLIC_URL, or the first host of the site in
+// multi-site mode
+func siteBaseURL(siteID uint) string {
+	cfg := config.Get().Server
+	if !cfg.MultiSite {
+		return strings.TrimRight(cfg.PublicURL, "/")
+	}
+	site, err := GetGlobalSiteService().Get(siteID)
+	if err != nil {
+		return ""
+	}
+	if hosts := site.HostList(); len(hosts) > 0 {
+		return "https://" + hosts[0]
+	}
+	return ""
+}
+
+// siteLanguage returns the default language of a site for mail sent to
+// all of its subscribers
+func siteLanguage(db *gorm.DB, siteID uint) string {
+	var settings models.SiteSettings
+	if err := db.Where("site_id = ?", siteID).Limit(1).Find(&settings).Error; err != nil || settings.DefaultLanguage == "" {
+		return "en"
+	}
+	return settings.DefaultLanguage
+}
+
+func subscriptionLink(baseURL, action, token string) string {
+	return strings.TrimRight(baseURL, "/") + "/api/newsletter/" + action + "?token=" + url.QueryEscape(token)
+}
+
+func articleLink(baseURL string, article *models.Article) string {
+	return localizedArticleLink(baseURL, article.DefaultLang, article.ID)
+}
+
+func localizedArticleLink(baseURL, language string, articleID uint) string {
+	return fmt.Sprintf("%s/%s/article/%d", strings.TrimRight(baseURL, "/"), language, articleID)
+}
+
+// articleExcerpt returns the article's summary, shortened for an email
+func articleExcerpt(article *models.Article) string {
+	return truncateRunes(strings.TrimSpace(article.Summary), summaryLength)
+}
+
+func truncateRunes(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+func joinIDs(ids []uint) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// isPermanentMailError reports whether the mail server refused the message
+// for good, such as for an unknown mailbox
+func isPermanentMailError(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 500 && protoErr.Code < 600
+}
+
+func newSubscriptionToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// registerHooks subscribes post announcements to the publish hook
+func (s *NewsletterService) registerHooks() {
+	hooks.Register(hooks.ArticlePublished, "newsletter", s.announce)
+}
+
+// decodeNewsletterJob reads the payload of a newsletter job
+func decodeNewsletterJob(payload json.RawMessage) (newsletterJob, error) {
+	var job newsletterJob
+	err := json.Unmarshal(payload, &job)
+	return job, err
+}
+
+var (
+	globalNewsletterService *NewsletterService
+	newsletterServiceOnce   sync.Once
+)
+
+// GetGlobalNewsletterService returns the global newsletter service,
+// registering its publish hook on first use
+func GetGlobalNewsletterService() *NewsletterService {
+	newsletterServiceOnce.Do(func() {
+		globalNewsletterService = NewNewsletterService()
+		globalNewsletterService.registerHooks()
+	})
+	return globalNewsletterService
+}
@@ -0,0 +1,327 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// NewsletterAIDigestScheduleName is the scheduler entry for NEWSLETTER_AI_DIGEST_SCHEDULE
+	NewsletterAIDigestScheduleName = "newsletter-ai-digest"
+	// aiDigestLimit is how many new articles a digest covers by default
+	aiDigestLimit    = 5
+	aiDigestMaxLimit = 20
+	aiDigestMaxDays  = 90
+	// aiDigestPopular is how many of the period's most read older articles
+	// are added to the new ones
+	aiDigestPopular = 3
+	// aiDigestContentLength is how much of each article the model reads
+	aiDigestContentLength = 1500
+)
+
+// AIDigestOptions selects what an AI-composed digest covers. A zero Days
+// covers the articles since the last digest, and an empty Language writes
+// in the site language for every subscriber.
+type AIDigestOptions struct {
+	Days     int    `json:"days,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// aiDigestJob is the payload of JobNewsletterAIDigest for one site; a
+// scheduled run has no payload and drafts a digest for every site
+type aiDigestJob struct {
+	SiteID  uint   `json:"site_id"`
+	BaseURL string `json:"base_url"`
+	AIDigestOptions
+}
+
+// aiDigestText is the model's answer, blurbs keyed by article ID
+type aiDigestText struct {
+	Intro  string            `json:"intro"`
+	Blurbs map[string]string `json:"blurbs"`
+}
+
+// QueueAIDigest checks that a digest can be written and queues the job that
+// asks the AI provider for it, which takes too long for a request. The
+// draft shows up in the newsletter list when the job is done.
+func (s *NewsletterService) QueueAIDigest(ctx context.Context, siteID uint, baseURL string, options AIDigestOptions) (*models.Job, error) {
+	if options.Days < 0 || options.Days > aiDigestMaxDays || options.Limit < 0 || options.Limit > aiDigestMaxLimit ||
+		len(options.Language) > 10 {
+		return nil, fmt.Errorf("%w: days must be 0 to %d and limit 0 to %d", ErrInvalidNewsletter, aiDigestMaxDays, aiDigestMaxLimit)
+	}
+	if !s.llm.Configured(siteID) {
+		return nil, ErrLLMNotConfigured
+	}
+	since, err := s.aiDigestSince(ctx, siteID, options)
+	if err != nil {
+		return nil, err
+	}
+	var count int64
+	if err := s.db().WithContext(ctx).Model(&models.Article{}).
+		Where("site_id = ? AND created_at > ?", siteID, since).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrNoArticlesForDigest
+	}
+	return GetGlobalJobQueue().Enqueue(JobNewsletterAIDigest, aiDigestJob{SiteID: siteID, BaseURL: baseURL, AIDigestOptions: options})
+}
+
+// ComposeAIDigest drafts a digest of the period's new articles and its most
+// read older ones, with an intro and a blurb per article written by the
+// site's AI provider. The draft is kept for review and is not sent.
+func (s *NewsletterService) ComposeAIDigest(ctx context.Context, siteID uint, baseURL string, options AIDigestOptions) (*models.Newsletter, error) {
+	db := s.db().WithContext(ctx)
+	since, err := s.aiDigestSince(ctx, siteID, options)
+	if err != nil {
+		return nil, err
+	}
+	limit := options.Limit
+	if limit == 0 {
+		limit = aiDigestLimit
+	}
+
+	var articles []models.Article
+	if err := db.Preload("Translations").Where("site_id = ? AND created_at > ? AND created_at <= ?", siteID, since, time.Now()).
+		Order("view_count DESC, created_at").Limit(limit).Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	if len(articles) == 0 {
+		return nil, ErrNoArticlesForDigest
+	}
+	ids := make([]uint, len(articles))
+	for i := range articles {
+		ids[i] = articles[i].ID
+	}
+	popular, err := s.popularArticles(ctx, siteID, since, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	language := options.Language
+	if language == "" {
+		language = siteLanguage(db, siteID)
+	}
+	for i := range articles {
+		ApplyTranslation(&articles[i], language)
+	}
+	for i := range popular {
+		ApplyTranslation(&popular[i], language)
+	}
+
+	text, err := s.writeAIDigest(ctx, siteID, language, articles, popular)
+	if err != nil {
+		return nil, err
+	}
+	items := func(list []models.Article) []map[string]interface{} {
+		result := make([]map[string]interface{}, len(list))
+		for i := range list {
+			blurb := strings.TrimSpace(text.Blurbs[strconv.FormatUint(uint64(list[i].ID), 10)])
+			if blurb == "" {
+				blurb = articleExcerpt(&list[i])
+			}
+			result[i] = map[string]interface{}{
+				"Title": list[i].Title, "Excerpt": truncateRunes(blurb, summaryLength),
+				"Link": localizedArticleLink(baseURL, language, list[i].ID),
+			}
+		}
+		return result
+	}
+	subject, body, err := GetGlobalMailer().Render(MailTemplateDigest, language, map[string]interface{}{
+		"Since": since, "Count": len(articles), "Intro": strings.TrimSpace(text.Intro),
+		"Articles": items(articles), "Popular": items(popular),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range popular {
+		ids = append(ids, popular[i].ID)
+	}
+	newsletter := &models.Newsletter{
+		SiteID:     siteID,
+		Kind:       models.NewsletterDigest,
+		Subject:    truncateRunes(subject, 255),
+		Body:       body,
+		ArticleIDs: joinIDs(ids),
+		Language:   options.Language,
+		BaseURL:    baseURL,
+		Status:     models.NewsletterDraft,
+	}
+	if err := db.Create(newsletter).Error; err != nil {
+		return nil, err
+	}
+	return newsletter, nil
+}
+
+// DraftAIDigests composes an AI digest draft for every site with active
+// subscribers and new articles, for NEWSLETTER_AI_DIGEST_SCHEDULE
+func (s *NewsletterService) DraftAIDigests(ctx context.Context) (int, error) {
+	var siteIDs []uint
+	if err := s.db().Model(&models.Subscriber{}).Distinct().Where("status = ?", models.SubscriberActive).
+		Pluck("site_id", &siteIDs).Error; err != nil {
+		return 0, err
+	}
+	drafted := 0
+	for _, siteID := range siteIDs {
+		baseURL := siteBaseURL(siteID)
+		if baseURL == "" {
+			slog.Warn("Skipping AI newsletter digest, the site has no public URL", "site_id", siteID)
+			continue
+		}
+		if !s.llm.Configured(siteID) {
+			slog.Warn("Skipping AI newsletter digest, no AI provider is configured", "site_id", siteID)
+			continue
+		}
+		_, err := s.ComposeAIDigest(ctx, siteID, baseURL, AIDigestOptions{})
+		if errors.Is(err, ErrNoArticlesForDigest) {
+			continue
+		}
+		if err != nil {
+			return drafted, err
+		}
+		drafted++
+	}
+	return drafted, nil
+}
+
+// runAIDigestJob is the JobNewsletterAIDigest handler
+func (s *NewsletterService) runAIDigestJob(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var job *aiDigestJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, err
+	}
+	if job == nil {
+		drafted, err := s.DraftAIDigests(ctx)
+		return map[string]int{"digests_drafted": drafted}, err
+	}
+	newsletter, err := s.ComposeAIDigest(ctx, job.SiteID, job.BaseURL, job.AIDigestOptions)
+	if errors.Is(err, ErrNoArticlesForDigest) {
+		return map[string]string{"skipped": err.Error()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]uint{"newsletter_id": newsletter.ID}, nil
+}
+
+// aiDigestSince returns the start of the period a digest covers
+func (s *NewsletterService) aiDigestSince(ctx context.Context, siteID uint, options AIDigestOptions) (time.Time, error) {
+	if options.Days > 0 {
+		return time.Now().AddDate(0, 0, -options.Days), nil
+	}
+	return s.lastDigest(s.db().WithContext(ctx), siteID)
+}
+
+// popularArticles returns the articles of a site read most since a time,
+// leaving out the ones already in the digest
+func (s *NewsletterService) popularArticles(ctx context.Context, siteID uint, since time.Time, exclude []uint) ([]models.Article, error) {
+	db := s.db().WithContext(ctx)
+	var rows []struct {
+		ArticleID uint
+		Views     int64
+	}
+	if err := db.Model(&models.ArticleView{}).
+		Select("article_views.article_id, COUNT(*) AS views").
+		Joins("JOIN articles ON articles.id = article_views.article_id").
+		Where("articles.site_id = ? AND articles.deleted_at IS NULL AND article_views.created_at > ?", siteID, since).
+		Where("article_views.article_id NOT IN ?", exclude).
+		Group("article_views.article_id").Order("views DESC").Limit(aiDigestPopular).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ArticleID
+	}
+	var found []models.Article
+	if err := db.Preload("Translations").Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Article, len(found))
+	for _, article := range found {
+		byID[article.ID] = article
+	}
+	articles := make([]models.Article, 0, len(found))
+	for _, id := range ids {
+		if article, ok := byID[id]; ok {
+			articles = append(articles, article)
+		}
+	}
+	return articles, nil
+}
+
+// writeAIDigest asks the AI provider for the intro and blurbs of a digest
+func (s *NewsletterService) writeAIDigest(ctx context.Context, siteID uint, language string, articles, popular []models.Article) (*aiDigestText, error) {
+	type promptArticle struct {
+		ID      uint   `json:"id"`
+		Title   string `json:"title"`
+		Summary string `json:"summary,omitempty"`
+		Content string `json:"content"`
+		New     bool   `json:"new"`
+	}
+	list := make([]promptArticle, 0, len(articles)+len(popular))
+	add := func(article *models.Article, isNew bool) {
+		content := strings.Join(strings.Fields(htmlTag.ReplaceAllString(article.Content, " ")), " ")
+		list = append(list, promptArticle{
+			ID: article.ID, Title: article.Title, Summary: article.Summary,
+			Content: truncateRunes(content, aiDigestContentLength), New: isNew,
+		})
+	}
+	for i := range articles {
+		add(&articles[i], true)
+	}
+	for i := range popular {
+		add(&popular[i], false)
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	languageName := llmLanguageName(language)
+	answer, err := s.llm.Complete(ctx, siteID, LLMRequest{
+		System: "You write the email newsletter of a blog. Stay faithful to the articles: do not invent facts, " +
+			"quotes or numbers. Answer with a single JSON object and nothing else.",
+		Prompt: fmt.Sprintf("Write this period's digest in %s.\n"+
+			"- intro: two or three friendly sentences introducing the period's articles.\n"+
+			"- blurbs: for every article, one or two sentences that make readers want to open it, keyed by the article id. "+
+			"Articles with \"new\": false were published earlier and were read the most this period.\n"+
+			"Answer as {\"intro\": \"...\", \"blurbs\": {\"<id>\": \"...\"}}.\n\nArticles:\n%s", languageName, data),
+		ServiceType: "newsletter",
+		Operation:   "compose_digest",
+		Language:    language,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseAIDigest(answer)
+}
+
+// parseAIDigest reads the JSON object in the model's answer, which may be
+// wrapped in a code fence or prose
+func parseAIDigest(answer string) (*aiDigestText, error) {
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("the AI answer has no JSON object")
+	}
+	var text aiDigestText
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &text); err != nil {
+		return nil, fmt.Errorf("failed to parse the AI answer: %v", err)
+	}
+	if strings.TrimSpace(text.Intro) == "" && len(text.Blurbs) == 0 {
+		return nil, errors.New("the AI answer has no intro or blurbs")
+	}
+	return &text, nil
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type sentMail struct {
+	to, subject, body string
+	headers           map[string]string
+}
+
+func newTestNewsletterService(t *testing.T) (*NewsletterService, *[]sentMail) {
+	newTestDB(t)
+	var outbox []sentMail
+	s := &NewsletterService{
+		db:             func() *gorm.DB { return database.DB },
+		batchSize:      2,
+		maxBounces:     1,
+		mailConfigured: func() bool { return true },
+		sendMail: func(to, subject, body string, headers map[string]string) error {
+			// gone@ accepted the confirmation, but its mailbox was removed since
+			if strings.HasPrefix(to, "gone@") && headers != nil {
+				return &textproto.Error{Code: 550, Msg: "mailbox unavailable"}
+			}
+			outbox = append(outbox, sentMail{to, subject, body, headers})
+			return nil
+		},
+	}
+	return s, &outbox
+}
+
+// subscribeAndConfirm runs the double opt-in for an address
+func subscribeAndConfirm(t *testing.T, s *NewsletterService, email string) *models.Subscriber {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.Subscribe(ctx, email, "en", "https://blog.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	var subscriber models.Subscriber
+	if err := database.DB.Where("email = ?", strings.ToLower(email)).First(&subscriber).Error; err != nil {
+		t.Fatal(err)
+	}
+	if subscriber.Status != models.SubscriberPending {
+		t.Fatalf("new subscriber is %s", subscriber.Status)
+	}
+	if err := s.SendConfirmation(subscriber.ID, "https://blog.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	confirmed, err := s.Confirm(subscriber.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return confirmed
+}
+
+func TestNewsletterSubscription(t *testing.T) {
+	s, outbox := newTestNewsletterService(t)
+	ctx := context.Background()
+
+	if err := s.Subscribe(ctx, "not an address", "", ""); err != ErrInvalidEmail {
+		t.Errorf("expected ErrInvalidEmail, got %v", err)
+	}
+
+	subscriber := subscribeAndConfirm(t, s, "Reader@Example.com")
+	if len(*outbox) != 1 || !strings.Contains((*outbox)[0].body, "/api/newsletter/confirm?token="+subscriber.Token) {
+		t.Fatalf("unexpected confirmation mail %+v", *outbox)
+	}
+	var stored models.Subscriber
+	database.DB.First(&stored, subscriber.ID)
+	if stored.Status != models.SubscriberActive || stored.ConfirmedAt == nil || stored.Email != "reader@example.com" {
+		t.Errorf("unexpected subscriber after confirming %+v", stored)
+	}
+
+	// Signing up again does not reset an active subscription
+	if err := s.Subscribe(ctx, "reader@example.com", "en", ""); err != nil {
+		t.Fatal(err)
+	}
+	database.DB.First(&stored, subscriber.ID)
+	if stored.Status != models.SubscriberActive {
+		t.Errorf("subscribing again changed the status to %s", stored.Status)
+	}
+
+	if _, err := s.Unsubscribe(stored.Token); err != nil {
+		t.Fatal(err)
+	}
+	database.DB.First(&stored, subscriber.ID)
+	if stored.Status != models.SubscriberUnsubscribed || stored.UnsubscribedAt == nil {
+		t.Errorf("unexpected subscriber after unsubscribing %+v", stored)
+	}
+	if _, err := s.Confirm(stored.Token); err != ErrSubscriptionToken {
+		t.Errorf("confirming after unsubscribing should fail, got %v", err)
+	}
+	if _, err := s.Unsubscribe(strings.Repeat("0", 64)); err != ErrSubscriptionToken {
+		t.Errorf("expected ErrSubscriptionToken for an unknown token, got %v", err)
+	}
+}
+
+func TestNewsletterDelivery(t *testing.T) {
+	s, outbox := newTestNewsletterService(t)
+	ctx := context.Background()
+	for _, email := range []string{"a@example.com", "b@example.com", "gone@example.com"} {
+		subscribeAndConfirm(t, s, email)
+	}
+	pending := models.Subscriber{Email: "pending@example.com", Status: models.SubscriberPending, Token: strings.Repeat("p", 64)}
+	database.DB.Create(&pending)
+
+	article := models.Article{Title: "Hello", Summary: "A first post", DefaultLang: "en", CreatedAt: time.Now().Add(-time.Hour)}
+	database.DB.Create(&article)
+	newsletter, err := s.ComposeDigest(ctx, models.DefaultSiteID, "https://blog.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newsletter.Subject != "New post: Hello" || !strings.Contains(newsletter.Body, "https://blog.example.com/en/article/") {
+		t.Fatalf("unexpected digest %+v", newsletter)
+	}
+
+	*outbox = nil
+	if _, err := s.Send(ctx, newsletter.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Send(ctx, newsletter.ID); err != ErrNewsletterNotDraft {
+		t.Errorf("sending twice should fail, got %v", err)
+	}
+	sent, err := s.Deliver(ctx, newsletter.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.Status != models.NewsletterSent || sent.Recipients != 3 || sent.Sent != 2 || sent.Bounced != 1 {
+		t.Errorf("unexpected delivery stats %+v", sent)
+	}
+	if len(*outbox) != 2 || !strings.HasPrefix((*outbox)[0].headers["List-Unsubscribe"], "<https://blog.example.com/api/newsletter/unsubscribe?token=") {
+		t.Errorf("unexpected newsletter mail %+v", *outbox)
+	}
+
+	var gone models.Subscriber
+	database.DB.Where("email = ?", "gone@example.com").First(&gone)
+	if gone.Status != models.SubscriberBounced || gone.BounceCount != 1 {
+		t.Errorf("bounced address not recorded %+v", gone)
+	}
+
+	// The next digest only covers articles published after this one
+	if _, err := s.ComposeDigest(ctx, models.DefaultSiteID, "https://blog.example.com"); err != ErrNoArticlesForDigest {
+		t.Errorf("expected ErrNoArticlesForDigest, got %v", err)
+	}
+}
+
+func TestComposeAIDigest(t *testing.T) {
+	s, _ := newTestNewsletterService(t)
+	var prompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer sk-test-digest-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var request struct {
+			Messages []struct{ Content string } `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		prompt = request.Messages[1].Content
+		answer := "```json\n{\"intro\": \"A busy week on the blog.\", \"blurbs\": {\"1\": \"Why Go generics matter.\"}}\n```"
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": answer}}},
+			"usage":   map[string]int{"prompt_tokens": 300, "completion_tokens": 40},
+		})
+	}))
+	defer server.Close()
+	s.llm = &LLMClient{db: s.db, client: server.Client(), tracker: NewAIUsageTracker()}
+	ctx := context.Background()
+
+	if _, err := s.QueueAIDigest(ctx, models.DefaultSiteID, "https://blog.example.com", AIDigestOptions{}); !errors.Is(err, ErrLLMNotConfigured) {
+		t.Fatalf("expected ErrLLMNotConfigured, got %v", err)
+	}
+	secure, err := security.GetGlobalAIConfigService().EncryptAIConfig(&security.InputAIConfig{
+		DefaultProvider: "openai",
+		Providers: map[string]security.InputProviderConfig{
+			"openai": {Provider: "openai", APIKey: "sk-test-digest-key", Model: "gpt-test", Enabled: true,
+				Settings: map[string]string{"base_url": server.URL}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aiConfig, _ := json.Marshal(secure)
+	database.DB.Where(models.SiteSettings{SiteID: models.DefaultSiteID}).
+		Assign(models.SiteSettings{DefaultLanguage: "en", AIConfig: string(aiConfig)}).
+		FirstOrCreate(&models.SiteSettings{})
+
+	generics := models.Article{Title: "Go generics", Summary: "Type parameters explained", Content: "<p>Generics in Go</p>", DefaultLang: "en"}
+	database.DB.Create(&generics)
+	older := models.Article{Title: "Older favourite", Summary: "Still read a lot", DefaultLang: "en", CreatedAt: time.Now().AddDate(0, -2, 0)}
+	database.DB.Create(&older)
+	database.DB.Create(&models.ArticleView{ArticleID: older.ID, IPAddress: "192.0.2.1"})
+
+	newsletter, err := s.ComposeAIDigest(ctx, models.DefaultSiteID, "https://blog.example.com", AIDigestOptions{Days: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "English") || !strings.Contains(prompt, "Generics in Go") || !strings.Contains(prompt, `"new":false`) {
+		t.Errorf("unexpected prompt %q", prompt)
+	}
+	for _, want := range []string{"A busy week on the blog.", "Why Go generics matter.", "Most read:", "Still read a lot", "https://blog.example.com/en/article/2"} {
+		if !strings.Contains(newsletter.Body, want) {
+			t.Errorf("digest body misses %q:\n%s", want, newsletter.Body)
+		}
+	}
+	if newsletter.Status != models.NewsletterDraft || newsletter.Kind != models.NewsletterDigest || newsletter.ArticleIDs != "1,2" {
+		t.Errorf("unexpected newsletter %+v", newsletter)
+	}
+
+	var usage models.AIUsageRecord
+	if err := database.DB.Where("service_type = ?", "newsletter").First(&usage).Error; err != nil {
+		t.Fatal(err)
+	}
+	if usage.Provider != "openai" || usage.TotalTokens != 340 || usage.EstimatedCost <= 0 || !usage.Success {
+		t.Errorf("unexpected usage record %+v", usage)
+	}
+}
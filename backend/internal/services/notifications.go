@@ -0,0 +1,381 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NotificationDigestScheduleName is the scheduler entry for
+// NOTIFICATION_DIGEST_SCHEDULE
+const NotificationDigestScheduleName = "notification-digest"
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrInvalidNotification  = errors.New("invalid notification")
+)
+
+// severityRank orders the notification severities
+var severityRank = map[string]int{
+	models.SeverityInfo:     0,
+	models.SeverityWarning:  1,
+	models.SeverityError:    2,
+	models.SeverityCritical: 3,
+}
+
+// NotificationInput is an event to show in the notification center. With
+// a Key, nothing is recorded when a notification with the same key exists.
+type NotificationInput struct {
+	Source    string
+	Type      string
+	Severity  string
+	Title     string
+	Message   string
+	ActionURL string
+	ArticleID *uint
+	KeywordID *uint
+	Key       string
+}
+
+// NotificationFilter narrows a notification listing; empty fields match
+// every notification
+type NotificationFilter struct {
+	Source   string
+	Type     string
+	Severity string
+	IsRead   *bool
+	Page     int
+	Limit    int
+}
+
+// NotificationSummary counts the unread notifications, as shown by the
+// admin header badge
+type NotificationSummary struct {
+	Unread     int64                `json:"unread"`
+	BySeverity map[string]int64     `json:"by_severity"`
+	BySource   map[string]int64     `json:"by_source"`
+	Latest     *models.Notification `json:"latest,omitempty"`
+}
+
+// NotificationService keeps the admin notification center: SEO findings,
+// AI cost limits, backup runs, broken friend links, site monitor and
+// security alerts in one list with read state. Changes are published on
+// the cache's notifications topic so open streams on every instance
+// update, and unread notifications are emailed in a digest on
+// NOTIFICATION_DIGEST_SCHEDULE.
+type NotificationService struct {
+	db             func() *gorm.DB
+	digestEmail    string
+	digestSeverity string
+	// sendMail and mailConfigured are replaced in tests
+	sendMail       func(to, subject, body string) error
+	mailConfigured func() bool
+
+	mu       sync.Mutex
+	watchers map[chan struct{}]struct{}
+}
+
+// NewNotificationService creates a notification service from the
+// NOTIFICATION_* settings
+func NewNotificationService() *NotificationService {
+	cfg := config.Get()
+	s := &NotificationService{
+		db:             func() *gorm.DB { return database.DB },
+		digestEmail:    cfg.Notifications.DigestEmail,
+		digestSeverity: cfg.Notifications.DigestSeverity,
+		sendMail:       sendPlainEmail,
+		mailConfigured: smtpConfigured,
+		watchers:       make(map[chan struct{}]struct{}),
+	}
+	if s.digestEmail == "" {
+		s.digestEmail = cfg.Alerts.Email
+	}
+	return s
+}
+
+// Notify records a notification. It returns nil without an error when the
+// input's key was already reported.
+func (s *NotificationService) Notify(input NotificationInput) (*models.Notification, error) {
+	if input.Severity == "" {
+		input.Severity = models.SeverityInfo
+	}
+	if _, ok := severityRank[input.Severity]; !ok {
+		return nil, fmt.Errorf("%w: unknown severity %q", ErrInvalidNotification, input.Severity)
+	}
+	if input.Source == "" || input.Type == "" || input.Title == "" {
+		return nil, fmt.Errorf("%w: source, type and title are required", ErrInvalidNotification)
+	}
+	if input.Key != "" {
+		var existing int64
+		if err := s.db().Model(&models.Notification{}).Where("dedupe_key = ?", input.Key).Count(&existing).Error; err != nil {
+			return nil, err
+		}
+		if existing > 0 {
+			return nil, nil
+		}
+	}
+
+	notification := models.Notification{
+		Source:    input.Source,
+		Type:      input.Type,
+		Severity:  input.Severity,
+		Title:     truncateRunes(input.Title, 255),
+		Message:   input.Message,
+		ActionURL: input.ActionURL,
+		ArticleID: input.ArticleID,
+		KeywordID: input.KeywordID,
+		DedupeKey: input.Key,
+	}
+	if err := s.db().Create(&notification).Error; err != nil {
+		return nil, err
+	}
+	cache.Publish(cache.TopicNotifications)
+	return &notification, nil
+}
+
+// List returns a page of notifications, newest first
+func (s *NotificationService) List(ctx context.Context, filter NotificationFilter) ([]models.Notification, int64, error) {
+	query := s.db().WithContext(ctx).Model(&models.Notification{})
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Severity != "" {
+		query = query.Where("severity = ?", filter.Severity)
+	}
+	if filter.IsRead != nil {
+		query = query.Where("is_read = ?", *filter.IsRead)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 50
+	}
+	notifications := []models.Notification{}
+	err := query.Order("created_at DESC, id DESC").Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit).Find(&notifications).Error
+	return notifications, total, err
+}
+
+// Summary counts the unread notifications by severity and source
+func (s *NotificationService) Summary(ctx context.Context) (*NotificationSummary, error) {
+	summary := &NotificationSummary{
+		BySeverity: map[string]int64{},
+		BySource:   map[string]int64{},
+	}
+	for severity := range severityRank {
+		summary.BySeverity[severity] = 0
+	}
+	var rows []struct {
+		Source   string
+		Severity string
+		Count    int64
+	}
+	if err := s.db().WithContext(ctx).Model(&models.Notification{}).Where("is_read = ?", false).
+		Select("source, severity, COUNT(*) AS count").Group("source, severity").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		summary.Unread += row.Count
+		summary.BySeverity[row.Severity] += row.Count
+		summary.BySource[row.Source] += row.Count
+	}
+	if summary.Unread > 0 {
+		var latest models.Notification
+		if err := s.db().WithContext(ctx).Where("is_read = ?", false).Order("created_at DESC, id DESC").First(&latest).Error; err != nil {
+			return nil, err
+		}
+		summary.Latest = &latest
+	}
+	return summary, nil
+}
+
+// Get returns one notification
+func (s *NotificationService) Get(ctx context.Context, id uint) (*models.Notification, error) {
+	var notification models.Notification
+	if err := s.db().WithContext(ctx).First(&notification, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotificationNotFound
+		}
+		return nil, err
+	}
+	return &notification, nil
+}
+
+// SetRead marks a notification read or unread
+func (s *NotificationService) SetRead(ctx context.Context, id uint, read bool) (*models.Notification, error) {
+	notification, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if notification.IsRead != read {
+		updates := map[string]interface{}{"is_read": read, "read_at": nil}
+		if read {
+			updates["read_at"] = time.Now()
+		}
+		if err := s.db().WithContext(ctx).Model(notification).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		cache.Publish(cache.TopicNotifications)
+	}
+	return s.Get(ctx, id)
+}
+
+// MarkAllRead marks the unread notifications of a source, or of every
+// source when it is empty, read and returns how many there were
+func (s *NotificationService) MarkAllRead(ctx context.Context, source string) (int64, error) {
+	query := s.db().WithContext(ctx).Model(&models.Notification{}).Where("is_read = ?", false)
+	if source != "" {
+		query = query.Where("source = ?", source)
+	}
+	result := query.Updates(map[string]interface{}{"is_read": true, "read_at": time.Now()})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		cache.Publish(cache.TopicNotifications)
+	}
+	return result.RowsAffected, nil
+}
+
+// Delete removes a notification
+func (s *NotificationService) Delete(ctx context.Context, id uint) error {
+	result := s.db().WithContext(ctx).Delete(&models.Notification{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+	cache.Publish(cache.TopicNotifications)
+	return nil
+}
+
+// Watch returns a channel that receives a value whenever notifications
+// change, and a function that stops watching. Changes that arrive while a
+// value is pending are merged into it.
+func (s *NotificationService) Watch() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.watchers[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.watchers, ch)
+		s.mu.Unlock()
+	}
+}
+
+func (s *NotificationService) changed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.watchers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// SendDigest emails the unread notifications of at least the digest
+// severity that no earlier digest included. Without an address or SMTP
+// there is nothing to do.
+func (s *NotificationService) SendDigest(ctx context.Context) (interface{}, error) {
+	if s.digestEmail == "" || !s.mailConfigured() {
+		return map[string]int{"notifications_sent": 0}, nil
+	}
+	var pending []models.Notification
+	if err := s.db().WithContext(ctx).
+		Where("is_read = ? AND digested_at IS NULL AND severity IN ?", false, severitiesAtLeast(s.digestSeverity)).
+		Order("created_at, id").Find(&pending).Error; err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return map[string]int{"notifications_sent": 0}, nil
+	}
+
+	subject := "[kuno] 1 unread notification"
+	if len(pending) > 1 {
+		subject = fmt.Sprintf("[kuno] %d unread notifications", len(pending))
+	}
+	if err := s.sendMail(s.digestEmail, subject, formatNotificationDigest(pending)); err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(pending))
+	for i, notification := range pending {
+		ids[i] = notification.ID
+	}
+	if err := s.db().WithContext(ctx).Model(&models.Notification{}).Where("id IN ?", ids).
+		Update("digested_at", time.Now()).Error; err != nil {
+		return nil, err
+	}
+	return map[string]int{"notifications_sent": len(pending)}, nil
+}
+
+// severitiesAtLeast returns the severities as urgent as min or more
+func severitiesAtLeast(min string) []string {
+	var severities []string
+	for severity, rank := range severityRank {
+		if rank >= severityRank[min] {
+			severities = append(severities, severity)
+		}
+	}
+	return severities
+}
+
+func formatNotificationDigest(notifications []models.Notification) string {
+	var b strings.Builder
+	b.WriteString("These notifications are waiting in the admin notification center.\n")
+	for _, notification := range notifications {
+		fmt.Fprintf(&b, "\n[%s] %s\n", strings.ToUpper(notification.Severity), notification.Title)
+		fmt.Fprintf(&b, "%s, %s\n", notification.Source, notification.CreatedAt.Format(time.RFC1123))
+		if notification.Message != "" {
+			fmt.Fprintf(&b, "%s\n", notification.Message)
+		}
+	}
+	b.WriteString("\nNotifications marked read before the next digest are left out of it.\n")
+	return b.String()
+}
+
+// notifyAdmin records a notification for a background task, which has
+// nobody to return an error to
+func notifyAdmin(input NotificationInput) {
+	service := GetGlobalNotificationService()
+	if service.db() == nil {
+		// No database yet, as in commands that run without one
+		return
+	}
+	if _, err := service.Notify(input); err != nil {
+		slog.Error("Failed to record notification", "source", input.Source, "type", input.Type, "error", err)
+	}
+}
+
+var (
+	globalNotificationService     *NotificationService
+	globalNotificationServiceOnce sync.Once
+)
+
+// GetGlobalNotificationService returns the global notification service
+func GetGlobalNotificationService() *NotificationService {
+	globalNotificationServiceOnce.Do(func() {
+		globalNotificationService = NewNotificationService()
+		cache.Subscribe(cache.TopicNotifications, globalNotificationService.changed)
+	})
+	return globalNotificationService
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func setupNotificationTest(t *testing.T) *NotificationService {
+	t.Helper()
+	newTestDB(t)
+	return &NotificationService{
+		db:             func() *gorm.DB { return database.DB },
+		digestSeverity: models.SeverityWarning,
+		mailConfigured: func() bool { return true },
+		watchers:       make(map[chan struct{}]struct{}),
+	}
+}
+
+func TestNotificationCenter(t *testing.T) {
+	s := setupNotificationTest(t)
+	ctx := context.Background()
+
+	budget := NotificationInput{Source: models.NotificationSourceAI, Type: "cost_limit", Severity: models.SeverityWarning,
+		Title: "AI daily cost limit reached", Key: "ai.cost_limit.daily:2026-10-15"}
+	first, err := s.Notify(budget)
+	if err != nil || first == nil {
+		t.Fatalf("Notify = %v, %v", first, err)
+	}
+	// The same condition is reported once
+	if again, err := s.Notify(budget); err != nil || again != nil {
+		t.Errorf("repeated key recorded again: %v, %v", again, err)
+	}
+	if _, err := s.Notify(NotificationInput{Source: models.NotificationSourceBackup, Type: "backup_failed",
+		Severity: "fatal", Title: "Backup failed"}); !errors.Is(err, ErrInvalidNotification) {
+		t.Errorf("unknown severity error = %v", err)
+	}
+	s.Notify(NotificationInput{Source: models.NotificationSourceBackup, Type: "backup_failed",
+		Severity: models.SeverityError, Title: "Scheduled backup failed", Message: "disk full"})
+	s.Notify(NotificationInput{Source: models.NotificationSourceSEO, Type: "ranking_change", Title: "Ranking improved"})
+
+	unread := false
+	list, total, err := s.List(ctx, NotificationFilter{Source: models.NotificationSourceBackup, IsRead: &unread})
+	if err != nil || total != 1 || list[0].Title != "Scheduled backup failed" {
+		t.Fatalf("backup notifications = %+v, %d, %v", list, total, err)
+	}
+	summary, _ := s.Summary(ctx)
+	if summary.Unread != 3 || summary.BySeverity[models.SeverityWarning] != 1 || summary.BySource[models.NotificationSourceSEO] != 1 ||
+		summary.Latest == nil || summary.Latest.Title != "Ranking improved" {
+		t.Errorf("summary = %+v", summary)
+	}
+
+	// Read state
+	read, err := s.SetRead(ctx, first.ID, true)
+	if err != nil || !read.IsRead || read.ReadAt == nil {
+		t.Fatalf("SetRead = %+v, %v", read, err)
+	}
+	if marked, _ := s.MarkAllRead(ctx, models.NotificationSourceSEO); marked != 1 {
+		t.Errorf("marked %d SEO notifications read", marked)
+	}
+	if summary, _ = s.Summary(ctx); summary.Unread != 1 || summary.BySource[models.NotificationSourceBackup] != 1 {
+		t.Errorf("summary after reading = %+v", summary)
+	}
+	if _, err := s.SetRead(ctx, 9999, true); !errors.Is(err, ErrNotificationNotFound) {
+		t.Errorf("missing notification error = %v", err)
+	}
+}
+
+func TestNotificationDigest(t *testing.T) {
+	s := setupNotificationTest(t)
+	ctx := context.Background()
+	var sent []string
+	s.sendMail = func(to, subject, body string) error {
+		sent = append(sent, to+"|"+subject+"|"+body)
+		return nil
+	}
+
+	s.Notify(NotificationInput{Source: models.NotificationSourceMonitor, Type: MonitorEventDown, Severity: models.SeverityCritical, Title: "Site is down"})
+	s.Notify(NotificationInput{Source: models.NotificationSourceSEO, Type: "ranking_change", Title: "Ranking improved"})
+	read, _ := s.Notify(NotificationInput{Source: models.NotificationSourceBackup, Type: "backup_failed", Severity: models.SeverityError, Title: "Backup failed"})
+	s.SetRead(ctx, read.ID, true)
+
+	// Without an address there is nothing to send
+	if result, err := s.SendDigest(ctx); err != nil || len(sent) != 0 {
+		t.Fatalf("digest without address = %v, %v", result, err)
+	}
+
+	s.digestEmail = "admin@example.com"
+	if _, err := s.SendDigest(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Only the unread notification of at least warning severity is sent
+	if len(sent) != 1 || !strings.Contains(sent[0], "|[kuno] 1 unread notification|") ||
+		!strings.Contains(sent[0], "[CRITICAL] Site is down") || strings.Contains(sent[0], "Ranking") || strings.Contains(sent[0], "Backup") {
+		t.Fatalf("digest = %q", sent)
+	}
+	// and not again
+	s.SendDigest(ctx)
+	if len(sent) != 1 {
+		t.Errorf("notification sent in a second digest")
+	}
+}
+
+func TestNotificationWatch(t *testing.T) {
+	s := setupNotificationTest(t)
+	changed, stop := s.Watch()
+	s.changed()
+	s.changed()
+	select {
+	case <-changed:
+	default:
+		t.Fatal("watcher not told about a change")
+	}
+	// Changes while one was pending are merged
+	select {
+	case <-changed:
+		t.Fatal("second change not merged into the first")
+	default:
+	}
+	stop()
+	s.changed()
+	select {
+	case <-changed:
+		t.Error("stopped watcher told about a change")
+	default:
+	}
+}
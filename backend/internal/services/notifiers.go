@@ -0,0 +1,472 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotifierNotFound = errors.New("notifier not found")
+	ErrInvalidNotifier  = errors.New("invalid notifier")
+)
+
+const (
+	defaultTelegramAPI = "https://api.telegram.org"
+	notifierTimeout    = 10 * time.Second
+	// notifierTestEvent marks the card sent by Test
+	notifierTestEvent = "notifier.test"
+)
+
+// notifierLabels introduces the card in the notifier's language
+var notifierLabels = map[string]map[string]string{
+	"en": {string(hooks.ArticlePublished): "New post", string(hooks.ArticleUpdated): "Updated", notifierTestEvent: "Test notification"},
+	"zh": {string(hooks.ArticlePublished): "新文章", string(hooks.ArticleUpdated): "文章更新", notifierTestEvent: "测试通知"},
+	"ja": {string(hooks.ArticlePublished): "新着記事", string(hooks.ArticleUpdated): "記事の更新", notifierTestEvent: "テスト通知"},
+}
+
+// NotifierInput is the editable part of a notifier. Nil fields are left
+// unchanged on update.
+type NotifierInput struct {
+	Name       *string `json:"name"`
+	Kind       *string `json:"kind"`
+	Credential *string `json:"credential"`
+	ChatID     *string `json:"chat_id"`
+	Language   *string `json:"language"`
+	OnPublish  *bool   `json:"on_publish"`
+	OnUpdate   *bool   `json:"on_update"`
+	Enabled    *bool   `json:"enabled"`
+}
+
+// notifierCard is what a notifier posts about an article
+type notifierCard struct {
+	Label    string
+	Title    string
+	Summary  string
+	URL      string
+	ImageURL string
+}
+
+// notifierDelivery is the job payload for JobNotifierSend
+type notifierDelivery struct {
+	NotifierID uint   `json:"notifier_id"`
+	ArticleID  uint   `json:"article_id"`
+	Event      string `json:"event"`
+}
+
+// NotifierService posts published and updated articles to Telegram chats
+// and Discord or Slack channels. Posts are sent by the job queue so failed
+// ones are retried with backoff.
+type NotifierService struct {
+	db          func() *gorm.DB
+	client      *http.Client
+	telegramAPI string
+	baseURL     func(siteID uint) string
+}
+
+// NewNotifierService creates a notifier service
+func NewNotifierService() *NotifierService {
+	return &NotifierService{
+		db:          func() *gorm.DB { return database.DB },
+		client:      &http.Client{Timeout: notifierTimeout},
+		telegramAPI: defaultTelegramAPI,
+		baseURL:     siteBaseURL,
+	}
+}
+
+// List returns the notifiers of the site in ctx
+func (s *NotifierService) List(ctx context.Context) ([]models.Notifier, error) {
+	notifiers := []models.Notifier{}
+	if err := s.db().WithContext(ctx).Order("name ASC").Find(&notifiers).Error; err != nil {
+		return nil, err
+	}
+	for i := range notifiers {
+		notifiers[i].CredentialSet = notifiers[i].Credential != ""
+	}
+	return notifiers, nil
+}
+
+// Get returns one of the notifiers of the site in ctx
+func (s *NotifierService) Get(ctx context.Context, id uint) (*models.Notifier, error) {
+	var notifier models.Notifier
+	if err := s.db().WithContext(ctx).First(&notifier, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotifierNotFound
+		}
+		return nil, err
+	}
+	notifier.CredentialSet = notifier.Credential != ""
+	return &notifier, nil
+}
+
+// Create adds a notifier. New notifiers post published articles unless
+// told otherwise.
+func (s *NotifierService) Create(ctx context.Context, input NotifierInput) (*models.Notifier, error) {
+	notifier := &models.Notifier{OnPublish: true, Enabled: true}
+	if err := applyNotifierInput(notifier, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Create(notifier).Error; err != nil {
+		return nil, err
+	}
+	return notifier, nil
+}
+
+// Update changes the fields set in input
+func (s *NotifierService) Update(ctx context.Context, id uint, input NotifierInput) (*models.Notifier, error) {
+	notifier, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyNotifierInput(notifier, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Save(notifier).Error; err != nil {
+		return nil, err
+	}
+	return notifier, nil
+}
+
+// Delete removes a notifier
+func (s *NotifierService) Delete(ctx context.Context, id uint) error {
+	result := s.db().WithContext(ctx).Delete(&models.Notifier{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotifierNotFound
+	}
+	return nil
+}
+
+// Test posts the latest published article of the site, or a sample card
+// when there is none, and returns the error from the chat service
+func (s *NotifierService) Test(ctx context.Context, id uint) error {
+	notifier, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	var article models.Article
+	found := s.db().WithContext(ctx).Preload("Translations").
+		Where("created_at <= ?", time.Now()).Order("created_at DESC").Limit(1).Find(&article)
+	if found.Error != nil {
+		return found.Error
+	}
+
+	var card notifierCard
+	if found.RowsAffected > 0 {
+		card = s.card(notifier, &article, notifierTestEvent)
+	} else {
+		card = notifierCard{
+			Label: notifierLabel(notifier.Language, notifierTestEvent),
+			Title: notifier.Name,
+			URL:   s.baseURL(notifier.SiteID),
+		}
+	}
+	err = s.post(ctx, notifier, card)
+	s.recordCall(notifier.ID, err)
+	return err
+}
+
+func applyNotifierInput(notifier *models.Notifier, input NotifierInput) error {
+	if input.Name != nil {
+		notifier.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Kind != nil {
+		notifier.Kind = strings.TrimSpace(*input.Kind)
+	}
+	if input.ChatID != nil {
+		notifier.ChatID = strings.TrimSpace(*input.ChatID)
+	}
+	if input.Language != nil {
+		notifier.Language = strings.TrimSpace(*input.Language)
+	}
+	if input.OnPublish != nil {
+		notifier.OnPublish = *input.OnPublish
+	}
+	if input.OnUpdate != nil {
+		notifier.OnUpdate = *input.OnUpdate
+	}
+	if input.Enabled != nil {
+		notifier.Enabled = *input.Enabled
+	}
+
+	if notifier.Name == "" || len(notifier.Name) > 100 {
+		return fmt.Errorf("%w: name is required and at most 100 characters", ErrInvalidNotifier)
+	}
+	switch notifier.Kind {
+	case models.NotifierTelegram:
+		if notifier.ChatID == "" {
+			return fmt.Errorf("%w: chat_id is required for telegram", ErrInvalidNotifier)
+		}
+	case models.NotifierDiscord, models.NotifierSlack:
+	default:
+		return fmt.Errorf("%w: kind must be telegram, discord or slack", ErrInvalidNotifier)
+	}
+
+	if input.Credential != nil {
+		credential := strings.TrimSpace(*input.Credential)
+		if notifier.Kind != models.NotifierTelegram {
+			u, err := url.Parse(credential)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				return fmt.Errorf("%w: credential must be the https webhook URL", ErrInvalidNotifier)
+			}
+		}
+		encrypted, err := security.GetGlobalCryptoService().EncryptAPIKey(credential)
+		if err != nil {
+			return err
+		}
+		notifier.Credential = encrypted
+	}
+	if notifier.Credential == "" {
+		return fmt.Errorf("%w: credential (bot token or webhook URL) is required", ErrInvalidNotifier)
+	}
+	notifier.CredentialSet = true
+	return nil
+}
+
+// registerHooks queues posts for published and updated articles
+func (s *NotifierService) registerHooks() {
+	hooks.Register(hooks.ArticlePublished, "notifiers", s.dispatch)
+	hooks.Register(hooks.ArticleUpdated, "notifiers", s.dispatch)
+}
+
+// dispatch queues a post to every enabled notifier of the article's site
+// that follows the event
+func (s *NotifierService) dispatch(ctx context.Context, event hooks.Event, payload interface{}) error {
+	article, ok := payload.(*models.Article)
+	if !ok {
+		return nil
+	}
+	column := "on_publish"
+	if event == hooks.ArticleUpdated {
+		column = "on_update"
+	}
+	var notifiers []models.Notifier
+	if err := s.db().Where("site_id = ? AND enabled = ? AND "+column+" = ?", article.SiteID, true, true).
+		Find(&notifiers).Error; err != nil {
+		return err
+	}
+	for _, notifier := range notifiers {
+		delivery := notifierDelivery{NotifierID: notifier.ID, ArticleID: article.ID, Event: string(event)}
+		if _, err := GetGlobalJobQueue().Enqueue(JobNotifierSend, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deliver posts a queued article to one notifier
+func (s *NotifierService) Deliver(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var delivery notifierDelivery
+	if err := json.Unmarshal(payload, &delivery); err != nil {
+		return nil, err
+	}
+	var notifier models.Notifier
+	if err := s.db().First(&notifier, delivery.NotifierID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return map[string]string{"skipped": "notifier deleted"}, nil
+		}
+		return nil, err
+	}
+	if !notifier.Enabled {
+		return map[string]string{"skipped": "notifier disabled"}, nil
+	}
+	var article models.Article
+	if err := s.db().Preload("Translations").First(&article, delivery.ArticleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return map[string]string{"skipped": "article deleted"}, nil
+		}
+		return nil, err
+	}
+
+	err := s.post(ctx, &notifier, s.card(&notifier, &article, delivery.Event))
+	s.recordCall(notifier.ID, err)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"notifier": notifier.Name, "article_id": article.ID}, nil
+}
+
+// card builds the post for an article in the notifier's language
+func (s *NotifierService) card(notifier *models.Notifier, article *models.Article, event string) notifierCard {
+	language, title, summary := article.DefaultLang, article.Title, article.Summary
+	for _, translation := range article.Translations {
+		if notifier.Language != "" && translation.Language == notifier.Language && translation.Title != "" {
+			language, title, summary = translation.Language, translation.Title, translation.Summary
+			break
+		}
+	}
+	baseURL := strings.TrimRight(s.baseURL(article.SiteID), "/")
+	card := notifierCard{
+		Label:   notifierLabel(language, event),
+		Title:   title,
+		Summary: truncateRunes(strings.TrimSpace(summary), summaryLength),
+		URL:     localizedArticleLink(baseURL, language, article.ID),
+	}
+	if article.CoverImageURL != nil && *article.CoverImageURL != "" {
+		card.ImageURL = *article.CoverImageURL
+		if strings.HasPrefix(card.ImageURL, "/") {
+			card.ImageURL = baseURL + card.ImageURL
+		}
+	}
+	return card
+}
+
+func notifierLabel(language, event string) string {
+	for _, lang := range mailLanguageCandidates(language) {
+		if labels, ok := notifierLabels[lang]; ok {
+			return labels[event]
+		}
+	}
+	return notifierLabels["en"][event]
+}
+
+// post sends a card in the format of the notifier's chat service
+func (s *NotifierService) post(ctx context.Context, notifier *models.Notifier, card notifierCard) error {
+	credential, err := security.GetGlobalCryptoService().DecryptAPIKey(notifier.Credential)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt notifier credential: %v", err)
+	}
+	// Links and images need absolute URLs, which chat services fetch themselves
+	if !strings.HasPrefix(card.URL, "http") {
+		card.URL = ""
+	}
+	if !strings.HasPrefix(card.ImageURL, "http") {
+		card.ImageURL = ""
+	}
+
+	switch notifier.Kind {
+	case models.NotifierTelegram:
+		return s.postTelegram(ctx, credential, notifier.ChatID, card)
+	case models.NotifierDiscord:
+		return s.postJSON(ctx, credential, discordMessage(card))
+	case models.NotifierSlack:
+		return s.postJSON(ctx, credential, slackMessage(card))
+	}
+	return fmt.Errorf("unknown notifier kind %q", notifier.Kind)
+}
+
+// postTelegram sends the card through the Bot API, as a photo with a
+// caption when the article has a cover image
+func (s *NotifierService) postTelegram(ctx context.Context, token, chatID string, card notifierCard) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s\n<b>%s</b>", html.EscapeString(card.Label), html.EscapeString(card.Title))
+	if card.Summary != "" {
+		fmt.Fprintf(&text, "\n\n%s", html.EscapeString(card.Summary))
+	}
+	if card.URL != "" {
+		fmt.Fprintf(&text, "\n\n<a href=\"%s\">%s</a>", html.EscapeString(card.URL), html.EscapeString(card.URL))
+	}
+
+	method, message := "sendMessage", map[string]interface{}{"chat_id": chatID, "text": text.String(), "parse_mode": "HTML"}
+	// Captions are limited to 1024 characters
+	if card.ImageURL != "" && len([]rune(text.String())) <= 1024 {
+		method, message = "sendPhoto", map[string]interface{}{
+			"chat_id": chatID, "photo": card.ImageURL, "caption": text.String(), "parse_mode": "HTML",
+		}
+	}
+	return s.postJSON(ctx, s.telegramAPI+"/bot"+token+"/"+method, message)
+}
+
+func discordMessage(card notifierCard) map[string]interface{} {
+	embed := map[string]interface{}{
+		"title":       truncateRunes(card.Title, 256),
+		"description": card.Summary,
+	}
+	if card.URL != "" {
+		embed["url"] = card.URL
+	}
+	if card.ImageURL != "" {
+		embed["image"] = map[string]string{"url": card.ImageURL}
+	}
+	return map[string]interface{}{"content": card.Label, "embeds": []interface{}{embed}}
+}
+
+func slackMessage(card notifierCard) map[string]interface{} {
+	escape := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+	heading := "*" + escape(card.Title) + "*"
+	if card.URL != "" {
+		heading = "*<" + card.URL + "|" + escape(card.Title) + ">*"
+	}
+	text := escape(card.Label) + "\n" + heading
+	if card.Summary != "" {
+		text += "\n" + escape(card.Summary)
+	}
+	blocks := []interface{}{
+		map[string]interface{}{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": text}},
+	}
+	if card.ImageURL != "" {
+		blocks = append(blocks, map[string]interface{}{"type": "image", "image_url": card.ImageURL, "alt_text": card.Title})
+	}
+	return map[string]interface{}{"text": card.Label + ": " + card.Title + " " + card.URL, "blocks": blocks}
+}
+
+// postJSON POSTs a JSON message and turns error responses into errors that
+// include the service's explanation
+func (s *NotifierService) postJSON(ctx context.Context, endpoint string, message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The URL holds the bot token or webhook secret; keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("chat service returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+func (s *NotifierService) recordCall(id uint, callErr error) {
+	updates := map[string]interface{}{"last_error": ""}
+	if callErr != nil {
+		updates["last_error"] = callErr.Error()
+	} else {
+		updates["last_sent_at"] = time.Now()
+	}
+	s.db().Model(&models.Notifier{}).Where("id = ?", id).Updates(updates)
+}
+
+var (
+	globalNotifierService *NotifierService
+	notifierServiceOnce   sync.Once
+)
+
+// GetGlobalNotifierService returns the global notifier service, registering
+// its publish and update hooks on first use
+func GetGlobalNotifierService() *NotifierService {
+	notifierServiceOnce.Do(func() {
+		globalNotifierService = NewNotifierService()
+		globalNotifierService.registerHooks()
+	})
+	return globalNotifierService
+}
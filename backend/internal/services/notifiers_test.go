@@ -0,0 +1,106 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestNotifierDelivery(t *testing.T) {
+	newTestDB(t)
+	requests := map[string]string{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests[r.URL.Path] = string(body)
+	}))
+	defer server.Close()
+
+	s := &NotifierService{
+		db:          func() *gorm.DB { return database.DB },
+		client:      server.Client(),
+		telegramAPI: server.URL,
+		baseURL:     func(uint) string { return "https://blog.example.com" },
+	}
+	ctx := context.Background()
+	str := func(v string) *string { return &v }
+
+	if _, err := s.Create(ctx, NotifierInput{Name: str("tg"), Kind: str("telegram"), Credential: str("123:abc")}); !errors.Is(err, ErrInvalidNotifier) {
+		t.Errorf("expected ErrInvalidNotifier without chat_id, got %v", err)
+	}
+	if _, err := s.Create(ctx, NotifierInput{Name: str("slack"), Kind: str("slack"), Credential: str("http://hooks.example.com")}); !errors.Is(err, ErrInvalidNotifier) {
+		t.Errorf("expected ErrInvalidNotifier for a plain http webhook, got %v", err)
+	}
+
+	telegram, err := s.Create(ctx, NotifierInput{Name: str("tg"), Kind: str("telegram"), Credential: str("123:abc"), ChatID: str("@blog"), Language: str("en")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(telegram.Credential, "abc") || !telegram.CredentialSet || !telegram.OnPublish || telegram.OnUpdate {
+		t.Errorf("unexpected notifier %+v", telegram)
+	}
+	discord, err := s.Create(ctx, NotifierInput{Name: str("discord"), Kind: str("discord"), Credential: str(server.URL + "/discord")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	onUpdate := true
+	slack, err := s.Create(ctx, NotifierInput{Name: str("slack"), Kind: str("slack"), Credential: str(server.URL + "/slack"), OnUpdate: &onUpdate})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cover := "/uploads/cover.png"
+	article := models.Article{Title: "你好", Summary: "第一篇", DefaultLang: "zh", CoverImageURL: &cover}
+	database.DB.Create(&article)
+	database.DB.Create(&models.ArticleTranslation{ArticleID: article.ID, Language: "en", Title: "Hello <world>", Summary: "A first post"})
+
+	// Only the Slack notifier follows updates
+	if err := s.dispatch(ctx, hooks.ArticleUpdated, &article); err != nil {
+		t.Fatal(err)
+	}
+	var jobs []models.Job
+	database.DB.Where("type = ?", JobNotifierSend).Find(&jobs)
+	if len(jobs) != 1 || !strings.Contains(jobs[0].Payload, `"notifier_id":`+jsonNumber(slack.ID)) {
+		t.Fatalf("unexpected jobs for an update %+v", jobs)
+	}
+
+	for _, notifier := range []*models.Notifier{telegram, discord, slack} {
+		payload, _ := json.Marshal(notifierDelivery{NotifierID: notifier.ID, ArticleID: article.ID, Event: string(hooks.ArticlePublished)})
+		if _, err := s.Deliver(ctx, payload); err != nil {
+			t.Fatalf("%s: %v", notifier.Name, err)
+		}
+	}
+
+	photo := requests["/bot123:abc/sendPhoto"]
+	if !strings.Contains(photo, `"chat_id":"@blog"`) || !strings.Contains(photo, "Hello \\u0026lt;world\\u0026gt;") ||
+		!strings.Contains(photo, "https://blog.example.com/uploads/cover.png") || !strings.Contains(photo, "https://blog.example.com/en/article/") {
+		t.Errorf("unexpected telegram message %s", photo)
+	}
+	if body := requests["/discord"]; !strings.Contains(body, `"content":"新文章"`) || !strings.Contains(body, `"title":"你好"`) ||
+		!strings.Contains(body, "https://blog.example.com/zh/article/") {
+		t.Errorf("unexpected discord message %s", body)
+	}
+	if body := requests["/slack"]; !strings.Contains(body, `"type":"image"`) || !strings.Contains(body, "第一篇") {
+		t.Errorf("unexpected slack message %s", body)
+	}
+
+	var stored models.Notifier
+	database.DB.First(&stored, discord.ID)
+	if stored.LastSentAt == nil || stored.LastError != "" {
+		t.Errorf("delivery not recorded %+v", stored)
+	}
+}
+
+func jsonNumber(id uint) string {
+	out, _ := json.Marshal(id)
+	return string(out)
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRouteLanguage is the language the frontend serves without a
+	// language prefix in the path
+	DefaultRouteLanguage = "zh"
+	// headArticleBodyLength is how much of an article its schema.org data
+	// quotes
+	headArticleBodyLength = 500
+)
+
+// Kinds of page heads
+const (
+	PageHeadHome    = "home"
+	PageHeadArticle = "article"
+	PageHeadPage    = "page"
+)
+
+// HeadMeta is a meta tag, named either by name or by property as Open
+// Graph tags are
+type HeadMeta struct {
+	Name     string `json:"name,omitempty"`
+	Property string `json:"property,omitempty"`
+	Content  string `json:"content"`
+}
+
+// HeadLink is a link tag
+type HeadLink struct {
+	Rel      string `json:"rel"`
+	Href     string `json:"href"`
+	HrefLang string `json:"hreflang,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+}
+
+// PageHead is the prepared <head> of a page in one language: its title,
+// description and robots directive, Open Graph and Twitter tags, canonical
+// and hreflang links, and schema.org data. Frontends render it as it is
+// instead of building the metadata themselves.
+type PageHead struct {
+	Path        string                   `json:"path"`
+	Language    string                   `json:"language"`
+	Kind        string                   `json:"kind"`
+	Title       string                   `json:"title"`
+	Description string                   `json:"description"`
+	Canonical   string                   `json:"canonical"`
+	Robots      string                   `json:"robots"`
+	Meta        []HeadMeta               `json:"meta"`
+	Links       []HeadLink               `json:"links"`
+	JSONLD      []map[string]interface{} `json:"json_ld"`
+}
+
+// HeadSite is what a page head tells about the site, in the page's
+// language
+type HeadSite struct {
+	BaseURL     string
+	Title       string
+	Description string
+	FaviconURL  string
+	// Languages are the languages the site has content in, its default
+	// language first
+	Languages []string
+	// NoIndex keeps search engines away from every page, as the site's
+	// block_search_engines setting asks
+	NoIndex      bool
+	Verification []VerificationMeta
+	// Webmention is the address of the site's Webmention endpoint, when
+	// articles accept mentions
+	Webmention string
+}
+
+// HeadArticle is an article as its head describes it: translated to the
+// page's language with its author and license filled in, the languages
+// it can be read in, its own first, and the path of its category
+type HeadArticle struct {
+	Article   *models.Article
+	Languages []string
+	Crumbs    []models.CategoryCrumb
+}
+
+// LocalizedPath prefixes a path with its language, except for the
+// language the frontend serves without a prefix
+func LocalizedPath(path, language string) string {
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
+	if language == DefaultRouteLanguage {
+		return path
+	}
+	if path == "/" {
+		return "/" + language
+	}
+	return "/" + language + path
+}
+
+// SitePageHead returns the head of the home page at "/", or of another
+// page of the site with the site's title and description
+func SitePageHead(site HeadSite, path, language string) *PageHead {
+	kind := PageHeadPage
+	if path == "/" {
+		kind = PageHeadHome
+	}
+	head := newPageHead(site, kind, language, site.Languages, func(string) string { return path })
+	head.Title, head.Description = site.Title, site.Description
+	head.openGraph(site, "website")
+
+	if kind == PageHeadHome {
+		head.JSONLD = append(head.JSONLD, map[string]interface{}{
+			"@context":    "https://schema.org",
+			"@type":       "WebSite",
+			"name":        site.Title,
+			"description": site.Description,
+			"url":         head.Canonical,
+			"inLanguage":  head.Language,
+			"potentialAction": map[string]interface{}{
+				"@type": "SearchAction",
+				"target": map[string]string{
+					"@type":       "EntryPoint",
+					"urlTemplate": strings.TrimRight(site.BaseURL, "/") + "/search?q={search_term_string}",
+				},
+				"query-input": "required name=search_term_string",
+			},
+		})
+	}
+	return head
+}
+
+// ArticlePageHead returns the head of an article page. The SEO title and
+// description are only used in the article's own language, as they are
+// not translated.
+func ArticlePageHead(site HeadSite, item HeadArticle, language string) *PageHead {
+	article := item.Article
+	head := newPageHead(site, PageHeadArticle, language, item.Languages, func(language string) string {
+		return "/article/" + url.PathEscape(ArticleSlug(article, language))
+	})
+
+	title, description := article.Title, article.Summary
+	if head.Language == article.DefaultLang {
+		if article.SEOTitle != "" {
+			title = article.SEOTitle
+		}
+		if article.SEODescription != "" {
+			description = article.SEODescription
+		}
+	}
+	head.Title = title
+	if site.Title != "" {
+		head.Title = title + " - " + site.Title
+	}
+	head.Description = strings.Join(strings.Fields(description), " ")
+	if article.CanonicalURL != "" {
+		head.Canonical = article.CanonicalURL
+	}
+	if site.Webmention != "" {
+		head.Links = append(head.Links, HeadLink{Rel: "webmention", Href: site.Webmention})
+	}
+
+	head.openGraph(site, "article")
+	published, modified := article.CreatedAt.UTC().Format(time.RFC3339), article.UpdatedAt.UTC().Format(time.RFC3339)
+	head.Meta = append(head.Meta,
+		HeadMeta{Property: "article:published_time", Content: published},
+		HeadMeta{Property: "article:modified_time", Content: modified},
+	)
+	if article.CoverImageURL != nil && *article.CoverImageURL != "" {
+		image := headImageURL(site.BaseURL, *article.CoverImageURL)
+		alt := article.CoverImageAlt
+		if alt == "" {
+			alt = article.Title
+		}
+		head.Meta = append(head.Meta,
+			HeadMeta{Property: "og:image", Content: image},
+			HeadMeta{Property: "og:image:alt", Content: alt},
+			HeadMeta{Name: "twitter:image", Content: image},
+		)
+	}
+
+	// The page's own address, which a canonical address elsewhere replaces
+	// only as the canonical link
+	link := strings.TrimRight(site.BaseURL, "/") + LocalizedPath("/article/"+url.PathEscape(ArticleSlug(article, head.Language)), head.Language)
+	head.JSONLD = append(head.JSONLD, articleSchema(site, article, head.Language, head.Description, link, published, modified))
+
+	home := strings.TrimRight(site.BaseURL, "/") + strings.TrimSuffix(LocalizedPath("/", head.Language), "/")
+	if home == "" {
+		home = "/"
+	}
+	crumbs := []interface{}{breadcrumb(1, site.Title, home)}
+	for _, crumb := range item.Crumbs {
+		crumbs = append(crumbs, breadcrumb(len(crumbs)+1, crumb.Name, fmt.Sprintf("%s?category=%d", home, crumb.ID)))
+	}
+	crumbs = append(crumbs, breadcrumb(len(crumbs)+1, article.Title, link))
+	head.JSONLD = append(head.JSONLD, map[string]interface{}{
+		"@context":        "https://schema.org",
+		"@type":           "BreadcrumbList",
+		"itemListElement": crumbs,
+	})
+	return head
+}
+
+// newPageHead fills in what every page has: the canonical address and the
+// alternates in the other languages, the robots directive, the feed, icon
+// and verification tags. A language the page is not available in is
+// served with the canonical address of its first language and kept out
+// of search results.
+func newPageHead(site HeadSite, kind, language string, languages []string, pathFor func(language string) string) *PageHead {
+	base := strings.TrimRight(site.BaseURL, "/")
+	available := false
+	for _, candidate := range languages {
+		available = available || candidate == language
+	}
+	canonicalLanguage := language
+	if !available && len(languages) > 0 {
+		canonicalLanguage = languages[0]
+	}
+	address := func(language string) string {
+		return base + LocalizedPath(pathFor(language), language)
+	}
+
+	head := &PageHead{
+		Path:      pathFor(canonicalLanguage),
+		Language:  canonicalLanguage,
+		Kind:      kind,
+		Canonical: address(canonicalLanguage),
+		JSONLD:    []map[string]interface{}{},
+	}
+	switch {
+	case site.NoIndex:
+		head.Robots = "noindex, nofollow"
+	case !available:
+		head.Robots = "noindex, follow"
+	default:
+		head.Robots = "index, follow, max-image-preview:large, max-snippet:-1"
+	}
+
+	head.Links = append(head.Links, HeadLink{Rel: "canonical", Href: head.Canonical})
+	defaultLanguage := canonicalLanguage
+	for _, alternate := range languages {
+		head.Links = append(head.Links, HeadLink{Rel: "alternate", HrefLang: alternate, Href: address(alternate)})
+		if alternate == DefaultRouteLanguage {
+			defaultLanguage = alternate
+		}
+	}
+	if len(languages) > 0 {
+		head.Links = append(head.Links, HeadLink{Rel: "alternate", HrefLang: "x-default", Href: address(defaultLanguage)})
+	}
+	head.Links = append(head.Links, HeadLink{
+		Rel: "alternate", Type: "application/rss+xml", Title: site.Title + " RSS Feed",
+		Href: base + "/feed-" + canonicalLanguage + ".xml",
+	})
+	if site.FaviconURL != "" {
+		head.Links = append(head.Links, HeadLink{Rel: "icon", Href: headImageURL(base, site.FaviconURL)})
+	}
+
+	head.Meta = append(head.Meta, HeadMeta{Name: "robots", Content: head.Robots})
+	for _, verification := range site.Verification {
+		head.Meta = append(head.Meta, HeadMeta{Name: verification.Name, Content: verification.Content})
+	}
+	return head
+}
+
+// openGraph adds the description, Open Graph and Twitter tags of the
+// head's title and description
+func (h *PageHead) openGraph(site HeadSite, kind string) {
+	h.Meta = append(h.Meta,
+		HeadMeta{Name: "description", Content: h.Description},
+		HeadMeta{Property: "og:title", Content: h.Title},
+		HeadMeta{Property: "og:description", Content: h.Description},
+		HeadMeta{Property: "og:url", Content: h.Canonical},
+		HeadMeta{Property: "og:site_name", Content: site.Title},
+		HeadMeta{Property: "og:locale", Content: h.Language},
+		HeadMeta{Property: "og:type", Content: kind},
+		HeadMeta{Name: "twitter:card", Content: "summary_large_image"},
+		HeadMeta{Name: "twitter:title", Content: h.Title},
+		HeadMeta{Name: "twitter:description", Content: h.Description},
+	)
+}
+
+// HTML renders the head as tags to place in a page's <head>
+func (h *PageHead) HTML() string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "<title>%s</title>\n", html.EscapeString(h.Title))
+	for _, meta := range h.Meta {
+		if meta.Property != "" {
+			fmt.Fprintf(&out, "<meta property=\"%s\" content=\"%s\">\n", html.EscapeString(meta.Property), html.EscapeString(meta.Content))
+		} else {
+			fmt.Fprintf(&out, "<meta name=\"%s\" content=\"%s\">\n", html.EscapeString(meta.Name), html.EscapeString(meta.Content))
+		}
+	}
+	for _, link := range h.Links {
+		fmt.Fprintf(&out, "<link rel=\"%s\"", html.EscapeString(link.Rel))
+		for _, attribute := range [][2]string{{"hreflang", link.HrefLang}, {"type", link.Type}, {"title", link.Title}, {"href", link.Href}} {
+			if attribute[1] != "" {
+				fmt.Fprintf(&out, " %s=\"%s\"", attribute[0], html.EscapeString(attribute[1]))
+			}
+		}
+		out.WriteString(">\n")
+	}
+	for _, data := range h.JSONLD {
+		// json.Marshal escapes <, > and &, so the data cannot close the
+		// script element
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&out, "<script type=\"application/ld+json\">%s</script>\n", encoded)
+	}
+	return out.String()
+}
+
+// articleSchema is the schema.org BlogPosting of an article
+func articleSchema(site HeadSite, article *models.Article, language, description, link, published, modified string) map[string]interface{} {
+	words := countWords(article.Content)
+	author := map[string]interface{}{"@type": "Person", "name": site.Title}
+	if article.Author != nil {
+		author["name"] = article.Author.Name
+		if article.Author.AvatarURL != "" {
+			author["image"] = headImageURL(site.BaseURL, article.Author.AvatarURL)
+		}
+		var sameAs []string
+		for _, profile := range article.Author.SocialLinks {
+			sameAs = append(sameAs, profile.URL)
+		}
+		if len(sameAs) > 0 {
+			author["sameAs"] = sameAs
+		}
+	}
+	schema := map[string]interface{}{
+		"@context":      "https://schema.org",
+		"@type":         "BlogPosting",
+		"headline":      article.Title,
+		"description":   description,
+		"url":           link,
+		"datePublished": published,
+		"dateModified":  modified,
+		"author":        author,
+		"publisher":     map[string]interface{}{"@type": "Organization", "name": site.Title},
+		"inLanguage":    language,
+		"wordCount":     words,
+		"timeRequired":  fmt.Sprintf("PT%dM", (words+readingWordsPerMinute-1)/readingWordsPerMinute),
+		"articleBody":   truncateRunes(article.Content, headArticleBodyLength),
+	}
+	// Members-only articles are not quoted to crawlers
+	if article.MembersOnly {
+		delete(schema, "articleBody")
+		schema["isAccessibleForFree"] = false
+	}
+	if article.CoverImageURL != nil && *article.CoverImageURL != "" {
+		schema["image"] = headImageURL(site.BaseURL, *article.CoverImageURL)
+	}
+	if license := article.LicenseInfo; license != nil {
+		if license.URL != "" {
+			schema["license"] = license.URL
+		} else if license.Name != "" {
+			schema["license"] = license.Name
+		}
+	}
+	return schema
+}
+
+func breadcrumb(position int, name, item string) map[string]interface{} {
+	return map[string]interface{}{"@type": "ListItem", "position": position, "name": name, "item": item}
+}
+
+// headImageURL returns the absolute address of an image, with uploads
+// under the API they are served from
+func headImageURL(baseURL, image string) string {
+	if strings.HasPrefix(image, "/uploads/") {
+		image = "/api" + image
+	}
+	return absoluteURL(baseURL, image)
+}
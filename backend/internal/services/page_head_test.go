@@ -0,0 +1,89 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArticlePageHead(t *testing.T) {
+	site := HeadSite{
+		BaseURL:      "https://blog.example.com",
+		Title:        "My Blog",
+		Description:  "Notes",
+		FaviconURL:   "/uploads/favicon.png",
+		Languages:    []string{"zh", "en"},
+		Verification: []VerificationMeta{{Name: "baidu-site-verification", Content: "code"}},
+		Webmention:   "https://blog.example.com/api/webmention",
+	}
+	cover := "/uploads/cover.png"
+	article := &models.Article{
+		ID: 7, Title: "Hello <world>", Summary: "A  first\npost", Content: "one two three", DefaultLang: "en",
+		SEOTitle: "Hello SEO", SEOSlug: "hello", CoverImageURL: &cover,
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), UpdatedAt: time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC),
+		Translations: []models.ArticleTranslation{{Language: "zh", Title: "你好", SEOSlug: "ni-hao"}},
+		Author:       &models.ArticleAuthor{Name: "Ada", SocialLinks: []models.AuthorLink{{URL: "https://github.com/ada"}}},
+		LicenseInfo:  &models.ContentLicense{Code: "cc-by-4.0", Name: "CC BY 4.0", URL: "https://creativecommons.org/licenses/by/4.0/"},
+	}
+	item := HeadArticle{Article: article, Languages: []string{"en", "zh"}, Crumbs: []models.CategoryCrumb{{ID: 3, Name: "Go"}}}
+
+	head := ArticlePageHead(site, item, "en")
+	if head.Title != "Hello SEO - My Blog" || head.Description != "A first post" || head.Canonical != "https://blog.example.com/en/article/hello" {
+		t.Errorf("unexpected head %+v", head)
+	}
+	if !strings.HasPrefix(head.Robots, "index, follow") {
+		t.Errorf("robots = %q", head.Robots)
+	}
+	rendered := head.HTML()
+	for _, want := range []string{
+		"<title>Hello SEO - My Blog</title>",
+		`<meta property="og:type" content="article">`,
+		`<meta property="og:image" content="https://blog.example.com/api/uploads/cover.png">`,
+		`<meta property="article:published_time" content="2026-01-02T03:04:05Z">`,
+		`<meta name="baidu-site-verification" content="code">`,
+		`<link rel="webmention" href="https://blog.example.com/api/webmention">`,
+		// The default route language has no prefix
+		`<link rel="alternate" hreflang="zh" href="https://blog.example.com/article/ni-hao">`,
+		`<link rel="alternate" hreflang="x-default" href="https://blog.example.com/article/ni-hao">`,
+		`<link rel="alternate" type="application/rss+xml" title="My Blog RSS Feed" href="https://blog.example.com/feed-en.xml">`,
+		`"@type":"BlogPosting"`, `"license":"https://creativecommons.org/licenses/by/4.0/"`, `"sameAs":["https://github.com/ada"]`,
+		`"item":"https://blog.example.com/en?category=3"`,
+		// Titles cannot break out of the JSON-LD script
+		`"headline":"Hello \u003cworld\u003e"`,
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("head is missing %s:\n%s", want, rendered)
+		}
+	}
+
+	// A language the article is not in points to its own and is not indexed
+	head = ArticlePageHead(site, item, "fr")
+	if head.Language != "en" || head.Canonical != "https://blog.example.com/en/article/hello" || !strings.HasPrefix(head.Robots, "noindex") {
+		t.Errorf("unexpected head for a missing language %+v", head)
+	}
+
+	// Articles first published elsewhere name that page as canonical, and
+	// members-only articles are not quoted
+	article.CanonicalURL = "https://old.example.com/hello"
+	article.MembersOnly = true
+	head = ArticlePageHead(site, item, "en")
+	if head.Canonical != "https://old.example.com/hello" || head.JSONLD[0]["articleBody"] != nil || head.JSONLD[0]["isAccessibleForFree"] != false {
+		t.Errorf("unexpected head %+v", head)
+	}
+}
+
+func TestSitePageHead(t *testing.T) {
+	site := HeadSite{BaseURL: "https://blog.example.com", Title: "My Blog", Description: "Notes", Languages: []string{"zh", "en"}, NoIndex: true}
+	head := SitePageHead(site, "/", "en")
+	if head.Kind != PageHeadHome || head.Canonical != "https://blog.example.com/en" || head.Robots != "noindex, nofollow" {
+		t.Errorf("unexpected home head %+v", head)
+	}
+	if len(head.JSONLD) != 1 || head.JSONLD[0]["@type"] != "WebSite" {
+		t.Errorf("unexpected home schema %+v", head.JSONLD)
+	}
+	head = SitePageHead(site, "/rss", "zh")
+	if head.Kind != PageHeadPage || head.Canonical != "https://blog.example.com/rss" || len(head.JSONLD) != 0 {
+		t.Errorf("unexpected page head %+v", head)
+	}
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// pageSlug is the form of page slugs: lowercase words joined by hyphens
+var pageSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+var (
+	ErrInvalidPage  = errors.New("invalid page")
+	ErrPageNotFound = errors.New("page not found")
+)
+
+// PageInput holds the editable fields of a page; nil fields are left
+// unchanged. Translations, when given, replace every translation of the
+// page.
+type PageInput struct {
+	Slug         *string                `json:"slug"`
+	Title        *string                `json:"title"`
+	Summary      *string                `json:"summary"`
+	Content      *string                `json:"content"`
+	NavPlacement *string                `json:"nav_placement"`
+	IsPublished  *bool                  `json:"is_published"`
+	Translations []PageTranslationInput `json:"translations"`
+}
+
+// PageTranslationInput is a page in another language; empty fields fall
+// back to the page's own
+type PageTranslationInput struct {
+	Language string `json:"language"`
+	Title    string `json:"title"`
+	Summary  string `json:"summary"`
+	Content  string `json:"content"`
+}
+
+// PageOrder moves a page to a position in the navigation
+type PageOrder struct {
+	ID    uint `json:"id"`
+	Order int  `json:"order"`
+}
+
+// PageService manages the standalone pages of each site. Visitors see
+// published pages by slug in the language they ask for; drafts are only
+// listed for admins.
+type PageService struct {
+	db func() *gorm.DB
+}
+
+// NewPageService creates a page service
+func NewPageService() *PageService {
+	return &PageService{db: func() *gorm.DB { return database.DB }}
+}
+
+// List returns the site's pages in display order, optionally only the
+// published ones or those placed in a navigation
+func (s *PageService) List(ctx context.Context, publishedOnly bool, nav *string) ([]models.Page, error) {
+	query := s.db().WithContext(ctx).Preload("Translations")
+	if publishedOnly {
+		query = query.Where("is_published = ?", true)
+	}
+	if nav != nil {
+		query = query.Where("nav_placement = ?", *nav)
+	}
+	pages := []models.Page{}
+	err := query.Order("display_order ASC, id ASC").Find(&pages).Error
+	return pages, err
+}
+
+// Get returns one of the site's pages
+func (s *PageService) Get(ctx context.Context, id uint) (*models.Page, error) {
+	return s.find(s.db().WithContext(ctx).Where("id = ?", id))
+}
+
+// GetBySlug returns the site's published page with a slug
+func (s *PageService) GetBySlug(ctx context.Context, slug string) (*models.Page, error) {
+	return s.find(s.db().WithContext(ctx).Where("slug = ? AND is_published = ?", slug, true))
+}
+
+func (s *PageService) find(query *gorm.DB) (*models.Page, error) {
+	var page models.Page
+	if err := query.Preload("Translations").First(&page).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPageNotFound
+		}
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Create adds a page, a draft unless the input publishes it, at the end of
+// the navigation
+func (s *PageService) Create(ctx context.Context, input PageInput) (*models.Page, error) {
+	page := &models.Page{}
+	if err := applyPageInput(page, input); err != nil {
+		return nil, err
+	}
+	db := s.db().WithContext(ctx)
+	if err := checkPageSlug(db, page); err != nil {
+		return nil, err
+	}
+	var maxOrder int
+	if err := db.Model(&models.Page{}).Select("COALESCE(MAX(display_order), 0)").Scan(&maxOrder).Error; err != nil {
+		return nil, err
+	}
+	page.DisplayOrder = maxOrder + 1
+	if err := db.Create(page).Error; err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Update edits a page and, when given, replaces its translations
+func (s *PageService) Update(ctx context.Context, id uint, input PageInput) (*models.Page, error) {
+	page, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPageInput(page, input); err != nil {
+		return nil, err
+	}
+	err = s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkPageSlug(tx, page); err != nil {
+			return err
+		}
+		if input.Translations != nil {
+			if err := tx.Where("page_id = ?", page.ID).Delete(&models.PageTranslation{}).Error; err != nil {
+				return err
+			}
+			for i := range page.Translations {
+				page.Translations[i].ID = 0
+				page.Translations[i].PageID = page.ID
+			}
+		}
+		return tx.Save(page).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// Delete removes a page and its translations
+func (s *PageService) Delete(ctx context.Context, id uint) error {
+	page, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("page_id = ?", page.ID).Delete(&models.PageTranslation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(page).Error
+	})
+}
+
+// Reorder sets the display order of the given pages
+func (s *PageService) Reorder(ctx context.Context, orders []PageOrder) error {
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, order := range orders {
+			if err := tx.Model(&models.Page{}).Where("id = ?", order.ID).
+				Update("display_order", order.Order).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ApplyPageTranslation shows a page in lang where it has been translated;
+// untranslated fields keep the site's default language
+func ApplyPageTranslation(page *models.Page, lang string) {
+	for _, translation := range page.Translations {
+		if translation.Language == lang {
+			if translation.Title != "" {
+				page.Title = translation.Title
+			}
+			if translation.Summary != "" {
+				page.Summary = translation.Summary
+			}
+			if translation.Content != "" {
+				page.Content = translation.Content
+			}
+			break
+		}
+	}
+}
+
+// checkPageSlug rejects a slug another page of the site already has
+func checkPageSlug(db *gorm.DB, page *models.Page) error {
+	var count int64
+	if err := db.Model(&models.Page{}).Where("slug = ? AND id != ?", page.Slug, page.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: slug %q is already in use", ErrInvalidPage, page.Slug)
+	}
+	return nil
+}
+
+func applyPageInput(page *models.Page, input PageInput) error {
+	if input.Slug != nil {
+		page.Slug = strings.ToLower(strings.TrimSpace(*input.Slug))
+	}
+	if input.Title != nil {
+		page.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Summary != nil {
+		page.Summary = strings.TrimSpace(*input.Summary)
+	}
+	if input.Content != nil {
+		page.Content = *input.Content
+	}
+	if input.NavPlacement != nil {
+		page.NavPlacement = *input.NavPlacement
+	}
+	if input.IsPublished != nil {
+		page.IsPublished = *input.IsPublished
+	}
+	if input.Translations != nil {
+		page.Translations = make([]models.PageTranslation, 0, len(input.Translations))
+		seen := map[string]bool{}
+		for _, t := range input.Translations {
+			language := strings.TrimSpace(t.Language)
+			if language == "" || len(language) > 10 || seen[language] {
+				return fmt.Errorf("%w: each translation needs a distinct language code", ErrInvalidPage)
+			}
+			seen[language] = true
+			translation := models.PageTranslation{
+				PageID:   page.ID,
+				Language: language,
+				Title:    strings.TrimSpace(t.Title),
+				Summary:  strings.TrimSpace(t.Summary),
+				Content:  t.Content,
+			}
+			if utf8.RuneCountInString(translation.Title) > 200 || utf8.RuneCountInString(translation.Summary) > 500 {
+				return fmt.Errorf("%w: the %s title is at most 200 characters and the summary 500", ErrInvalidPage, language)
+			}
+			page.Translations = append(page.Translations, translation)
+		}
+	}
+
+	// "all" would be shadowed by the admin listing at /api/pages/all
+	if len(page.Slug) > 100 || !pageSlug.MatchString(page.Slug) || page.Slug == "all" {
+		return fmt.Errorf("%w: slug must be lowercase letters, digits and hyphens, at most 100 characters", ErrInvalidPage)
+	}
+	if page.Title == "" || utf8.RuneCountInString(page.Title) > 200 {
+		return fmt.Errorf("%w: title is required and at most 200 characters", ErrInvalidPage)
+	}
+	if utf8.RuneCountInString(page.Summary) > 500 {
+		return fmt.Errorf("%w: summary is at most 500 characters", ErrInvalidPage)
+	}
+	switch page.NavPlacement {
+	case models.PageNavNone, models.PageNavHeader, models.PageNavFooter:
+	default:
+		return fmt.Errorf("%w: nav_placement must be header, footer or empty", ErrInvalidPage)
+	}
+	return nil
+}
+
+var (
+	globalPageService     *PageService
+	globalPageServiceOnce sync.Once
+)
+
+// GetGlobalPageService returns the global page service
+func GetGlobalPageService() *PageService {
+	globalPageServiceOnce.Do(func() {
+		globalPageService = NewPageService()
+	})
+	return globalPageService
+}
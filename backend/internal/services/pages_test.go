@@ -0,0 +1,95 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestPages(t *testing.T) {
+	newTestDB(t)
+	s := &PageService{db: func() *gorm.DB { return database.DB }}
+	ctx := context.Background()
+	published, header := true, models.PageNavHeader
+
+	for _, bad := range []PageInput{
+		{Title: strPtr("About")},
+		{Slug: strPtr("about me"), Title: strPtr("About")},
+		{Slug: strPtr("all"), Title: strPtr("All")},
+		{Slug: strPtr("about")},
+		{Slug: strPtr("about"), Title: strPtr("About"), NavPlacement: strPtr("sidebar")},
+		{Slug: strPtr("about"), Title: strPtr("About"), Translations: []PageTranslationInput{{Language: "zh"}, {Language: "zh"}}},
+	} {
+		if _, err := s.Create(ctx, bad); !errors.Is(err, ErrInvalidPage) {
+			t.Errorf("Create(%+v) = %v, want ErrInvalidPage", bad, err)
+		}
+	}
+
+	about, err := s.Create(ctx, PageInput{
+		Slug: strPtr("About"), Title: strPtr("About"), Content: strPtr("# Hi\n\nI write here."),
+		NavPlacement: &header, IsPublished: &published,
+		Translations: []PageTranslationInput{{Language: "zh", Title: "关于"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if about.Slug != "about" {
+		t.Errorf("slug = %q, want it lowercased", about.Slug)
+	}
+	now, err := s.Create(ctx, PageInput{Slug: strPtr("now"), Title: strPtr("Now"), IsPublished: &published})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, PageInput{Slug: strPtr("uses"), Title: strPtr("Uses")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, PageInput{Slug: strPtr("now"), Title: strPtr("Again")}); !errors.Is(err, ErrInvalidPage) {
+		t.Errorf("duplicate slug = %v, want ErrInvalidPage", err)
+	}
+
+	// The draft is only listed for admins, and the header has only About
+	if pages, _ := s.List(ctx, true, nil); len(pages) != 2 {
+		t.Errorf("%d published pages, want 2", len(pages))
+	}
+	if pages, _ := s.List(ctx, false, nil); len(pages) != 3 {
+		t.Errorf("%d pages in all, want 3", len(pages))
+	}
+	if pages, _ := s.List(ctx, true, &header); len(pages) != 1 || pages[0].ID != about.ID {
+		t.Errorf("header pages = %+v", pages)
+	}
+	if _, err := s.GetBySlug(ctx, "uses"); !errors.Is(err, ErrPageNotFound) {
+		t.Errorf("draft by slug = %v, want ErrPageNotFound", err)
+	}
+
+	page, err := s.GetBySlug(ctx, "about")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyPageTranslation(page, "zh")
+	if page.Title != "关于" || page.Content != "# Hi\n\nI write here." {
+		t.Errorf("translated page = %+v", page)
+	}
+
+	// Taking another page's slug is refused; replacing translations works
+	if _, err := s.Update(ctx, now.ID, PageInput{Slug: strPtr("about")}); !errors.Is(err, ErrInvalidPage) {
+		t.Errorf("update to a used slug = %v", err)
+	}
+	updated, err := s.Update(ctx, about.ID, PageInput{Translations: []PageTranslationInput{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, _ := s.Get(ctx, updated.ID); len(reloaded.Translations) != 0 {
+		t.Errorf("translations left after replacing them: %+v", reloaded.Translations)
+	}
+
+	if err := s.Delete(ctx, about.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, about.ID); !errors.Is(err, ErrPageNotFound) {
+		t.Errorf("second delete = %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Bounds of the site's pin limit
+const (
+	DefaultMaxPinnedArticles = 2
+	maxPinnedArticlesLimit   = 20
+)
+
+var (
+	ErrInvalidPin      = errors.New("invalid pin")
+	ErrPinLimitReached = errors.New("pin limit reached")
+)
+
+// PinOrder places a pinned article among the pinned articles
+type PinOrder struct {
+	ID    uint `json:"id"`
+	Order int  `json:"order"`
+}
+
+// PinService keeps the site's pinned articles within its limit, in the
+// order admins arrange them, and unpins articles whose pin has ended
+type PinService struct {
+	db  func() *gorm.DB
+	now func() time.Time
+}
+
+// NewPinService creates a pin service
+func NewPinService() *PinService {
+	return &PinService{
+		db:  func() *gorm.DB { return database.DB },
+		now: time.Now,
+	}
+}
+
+// CheckLimit returns ErrPinLimitReached when pinning the article would
+// pin more articles than the site allows. Pins that have ended but are not
+// cleared yet do not count.
+func (s *PinService) CheckLimit(ctx context.Context, articleID uint) error {
+	db := s.db().WithContext(ctx)
+	var settings models.SiteSettings
+	if err := db.Select("max_pinned_articles").Limit(1).Find(&settings).Error; err != nil {
+		return err
+	}
+	limit := settings.MaxPinnedArticles
+	if limit <= 0 {
+		limit = DefaultMaxPinnedArticles
+	}
+	var pinned int64
+	err := db.Model(&models.Article{}).
+		Where("is_pinned = ? AND id <> ? AND (unpin_at IS NULL OR unpin_at > ?)", true, articleID, s.now()).
+		Count(&pinned).Error
+	if err != nil {
+		return err
+	}
+	if pinned >= int64(limit) {
+		return fmt.Errorf("%w: at most %d articles can be pinned", ErrPinLimitReached, limit)
+	}
+	return nil
+}
+
+// ValidateUnpinAt checks the end of a pin, which must be in the future
+func (s *PinService) ValidateUnpinAt(unpinAt *time.Time) error {
+	if unpinAt != nil && !unpinAt.After(s.now()) {
+		return fmt.Errorf("%w: unpin_at must be in the future", ErrInvalidPin)
+	}
+	return nil
+}
+
+// Reorder sets the order of the pinned articles. Every article given must
+// be pinned.
+func (s *PinService) Reorder(ctx context.Context, orders []PinOrder) error {
+	err := s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, order := range orders {
+			result := tx.Model(&models.Article{}).Where("id = ? AND is_pinned = ?", order.ID, true).
+				UpdateColumn("pin_order", order.Order)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("%w: article %d is not pinned", ErrInvalidPin, order.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	cache.Publish(cache.TopicArticles)
+	return nil
+}
+
+// ExpirePins unpins the articles of every site whose pin has ended,
+// returning how many it unpinned
+func (s *PinService) ExpirePins(ctx context.Context) (int64, error) {
+	result := s.db().WithContext(ctx).Model(&models.Article{}).
+		Where("is_pinned = ? AND unpin_at <= ?", true, s.now()).
+		UpdateColumns(map[string]interface{}{"is_pinned": false, "pin_order": 0, "pinned_at": nil, "unpin_at": nil})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		cache.Publish(cache.TopicArticles)
+	}
+	return result.RowsAffected, nil
+}
+
+// ValidateMaxPinned checks a site's pin limit
+func ValidateMaxPinned(limit int) error {
+	if limit < 1 || limit > maxPinnedArticlesLimit {
+		return fmt.Errorf("%w: max_pinned_articles must be between 1 and %d", ErrInvalidPin, maxPinnedArticlesLimit)
+	}
+	return nil
+}
+
+var (
+	globalPinService     *PinService
+	globalPinServiceOnce sync.Once
+)
+
+// GetGlobalPinService returns the global pin service
+func GetGlobalPinService() *PinService {
+	globalPinServiceOnce.Do(func() {
+		globalPinService = NewPinService()
+	})
+	return globalPinService
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPins(t *testing.T) {
+	newTestDB(t)
+	if err := database.DB.Use(database.SiteScope{}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	s := NewPinService()
+	s.now = func() time.Time { return now }
+	ctx := database.WithSite(context.Background(), models.DefaultSiteID)
+	db := database.DB.WithContext(ctx)
+
+	soon, later := now.Add(time.Hour), now.Add(48*time.Hour)
+	articles := []models.Article{
+		{Title: "Welcome", IsPinned: true, PinOrder: 1, PinnedAt: &now},
+		{Title: "Sale", IsPinned: true, PinOrder: 2, PinnedAt: &now, UnpinAt: &soon},
+		{Title: "Roadmap"},
+		{Title: "Changelog", IsPinned: true, PinOrder: 3, PinnedAt: &now, UnpinAt: &later},
+	}
+	for i := range articles {
+		if err := db.Create(&articles[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&models.SiteSettings{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	roadmap := articles[2].ID
+
+	// Without a limit of its own the site pins two articles
+	if err := s.CheckLimit(ctx, roadmap); !errors.Is(err, ErrPinLimitReached) {
+		t.Errorf("CheckLimit() = %v, want ErrPinLimitReached", err)
+	}
+	db.Model(&models.SiteSettings{}).Where("1 = 1").Update("max_pinned_articles", 4)
+	if err := s.CheckLimit(ctx, roadmap); err != nil {
+		t.Errorf("CheckLimit() with a limit of 4 = %v", err)
+	}
+	db.Model(&models.SiteSettings{}).Where("1 = 1").Update("max_pinned_articles", 3)
+	if err := s.CheckLimit(ctx, articles[0].ID); err != nil {
+		t.Errorf("CheckLimit() of a pinned article = %v, want it not to count itself", err)
+	}
+
+	if err := s.ValidateUnpinAt(&now); !errors.Is(err, ErrInvalidPin) {
+		t.Errorf("ValidateUnpinAt(now) = %v, want ErrInvalidPin", err)
+	}
+	if err := s.Reorder(ctx, []PinOrder{{ID: articles[3].ID, Order: 1}, {ID: roadmap, Order: 2}}); !errors.Is(err, ErrInvalidPin) {
+		t.Errorf("reordering an unpinned article = %v, want ErrInvalidPin", err)
+	}
+	var changelog models.Article
+	db.First(&changelog, articles[3].ID)
+	if changelog.PinOrder != 3 {
+		t.Errorf("failed reorder left pin_order = %d, want 3", changelog.PinOrder)
+	}
+	if err := s.Reorder(ctx, []PinOrder{{ID: articles[3].ID, Order: 1}, {ID: articles[0].ID, Order: 2}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ended pins no longer count and are cleared by ExpirePins
+	now = now.Add(2 * time.Hour)
+	db.Model(&models.SiteSettings{}).Where("1 = 1").Update("max_pinned_articles", 2)
+	if err := s.CheckLimit(ctx, roadmap); !errors.Is(err, ErrPinLimitReached) {
+		t.Errorf("CheckLimit() = %v, want ErrPinLimitReached", err)
+	}
+	if unpinned, err := s.ExpirePins(ctx); err != nil || unpinned != 1 {
+		t.Fatalf("ExpirePins() = %d, %v, want 1", unpinned, err)
+	}
+	var pinned []models.Article
+	db.Where("is_pinned = ?", true).Order("pin_order").Find(&pinned)
+	if len(pinned) != 2 || pinned[0].Title != "Changelog" || pinned[1].Title != "Welcome" {
+		t.Errorf("pinned after expiry = %+v", pinned)
+	}
+	var sale models.Article
+	db.First(&sale, articles[1].ID)
+	if sale.IsPinned || sale.PinnedAt != nil || sale.UnpinAt != nil || sale.PinOrder != 0 {
+		t.Errorf("expired pin = %+v", sale)
+	}
+
+	if ValidateMaxPinned(0) == nil || ValidateMaxPinned(21) == nil || ValidateMaxPinned(5) != nil {
+		t.Error("ValidateMaxPinned() accepts limits outside 1..20")
+	}
+}
@@ -0,0 +1,460 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// JobPluginDeliver delivers an action event to one webhook plugin
+const JobPluginDeliver = "plugins.deliver"
+
+// pluginPingEvent is sent by Test to check a plugin is reachable
+const pluginPingEvent = "plugin.ping"
+
+const (
+	defaultPluginTimeout = 5
+	maxPluginTimeout     = 30
+	maxPluginResponse    = 10 << 20
+)
+
+var (
+	ErrPluginNotFound = errors.New("plugin not found")
+	ErrInvalidPlugin  = errors.New("invalid plugin")
+)
+
+// PluginInput is the editable part of a plugin. Nil fields are left
+// unchanged on update.
+type PluginInput struct {
+	Name           *string  `json:"name"`
+	URL            *string  `json:"url"`
+	Secret         *string  `json:"secret"`
+	Events         []string `json:"events"`
+	Enabled        *bool    `json:"enabled"`
+	TimeoutSeconds *int     `json:"timeout_seconds"`
+}
+
+// PluginCallResult describes one synchronous call to a plugin
+type PluginCallResult struct {
+	StatusCode int    `json:"status_code"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// pluginDelivery is the job payload for JobPluginDeliver
+type pluginDelivery struct {
+	PluginID uint            `json:"plugin_id"`
+	Event    string          `json:"event"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// PluginService calls webhook plugins from hooks. Every request is a JSON
+// POST of {"event", "data", "timestamp"} signed with the plugin secret in
+// X-Kuno-Signature (sha256=<hex HMAC of the body>).
+//
+// For filter events the plugin may answer 2xx with {"data": ...} to replace
+// the payload fields, or 422 with {"error": "reason"} to reject the change.
+// A plugin that times out or errors on a filter event is skipped so an
+// unreachable integration cannot block editing or reading.
+type PluginService struct {
+	db     func() *gorm.DB
+	client *http.Client
+
+	mu      sync.RWMutex
+	plugins []models.Plugin
+	loaded  bool
+}
+
+// NewPluginService creates a plugin service
+func NewPluginService() *PluginService {
+	return &PluginService{
+		db:     func() *gorm.DB { return database.DB },
+		client: &http.Client{},
+	}
+}
+
+// List returns all plugins
+func (s *PluginService) List() ([]models.Plugin, error) {
+	var plugins []models.Plugin
+	err := s.db().Order("name ASC").Find(&plugins).Error
+	return plugins, err
+}
+
+// Get returns a plugin by ID
+func (s *PluginService) Get(id uint) (*models.Plugin, error) {
+	var plugin models.Plugin
+	if err := s.db().First(&plugin, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPluginNotFound
+		}
+		return nil, err
+	}
+	return &plugin, nil
+}
+
+// Create adds a plugin. A signing secret is generated when none is given;
+// the secret is returned here and never shown again.
+func (s *PluginService) Create(input PluginInput) (*models.Plugin, string, error) {
+	plugin := models.Plugin{Enabled: true, TimeoutSeconds: defaultPluginTimeout}
+	if err := applyPluginInput(&plugin, input); err != nil {
+		return nil, "", err
+	}
+	if plugin.Name == "" || plugin.URL == "" {
+		return nil, "", fmt.Errorf("%w: name and url are required", ErrInvalidPlugin)
+	}
+	if plugin.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, "", err
+		}
+		plugin.Secret = hex.EncodeToString(secret)
+	}
+
+	if err := s.db().Create(&plugin).Error; err != nil {
+		return nil, "", err
+	}
+	s.invalidate()
+	return &plugin, plugin.Secret, nil
+}
+
+// Update changes the fields set in input
+func (s *PluginService) Update(id uint, input PluginInput) (*models.Plugin, error) {
+	plugin, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPluginInput(plugin, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().Save(plugin).Error; err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return plugin, nil
+}
+
+// Delete removes a plugin
+func (s *PluginService) Delete(id uint) error {
+	result := s.db().Delete(&models.Plugin{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPluginNotFound
+	}
+	s.invalidate()
+	return nil
+}
+
+// Test sends a ping event to a plugin and reports the response
+func (s *PluginService) Test(id uint) (*PluginCallResult, error) {
+	plugin, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	body, err := pluginRequestBody(pluginPingEvent, map[string]string{"plugin": plugin.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	status, _, callErr := s.call(context.Background(), plugin, pluginPingEvent, body)
+	result := &PluginCallResult{StatusCode: status, DurationMs: time.Since(start).Milliseconds()}
+	if callErr == nil && status >= 300 {
+		callErr = fmt.Errorf("plugin returned status %d", status)
+	}
+	if callErr != nil {
+		result.Error = callErr.Error()
+	}
+	s.recordCall(plugin.ID, callErr)
+	return result, nil
+}
+
+func applyPluginInput(plugin *models.Plugin, input PluginInput) error {
+	if input.Name != nil {
+		plugin.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.URL != nil {
+		u, err := url.Parse(strings.TrimSpace(*input.URL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidPlugin)
+		}
+		plugin.URL = u.String()
+	}
+	if input.Secret != nil {
+		plugin.Secret = *input.Secret
+	}
+	if input.Events != nil {
+		events := make([]string, 0, len(input.Events))
+		for _, event := range input.Events {
+			event = strings.TrimSpace(event)
+			if _, ok := hooks.Events[hooks.Event(event)]; !ok {
+				return fmt.Errorf("%w: unknown event %q", ErrInvalidPlugin, event)
+			}
+			events = append(events, event)
+		}
+		plugin.Events = strings.Join(events, ",")
+	}
+	if input.Enabled != nil {
+		plugin.Enabled = *input.Enabled
+	}
+	if input.TimeoutSeconds != nil {
+		if *input.TimeoutSeconds < 1 || *input.TimeoutSeconds > maxPluginTimeout {
+			return fmt.Errorf("%w: timeout_seconds must be between 1 and %d", ErrInvalidPlugin, maxPluginTimeout)
+		}
+		plugin.TimeoutSeconds = *input.TimeoutSeconds
+	}
+	return nil
+}
+
+// registerHooks subscribes the plugin dispatcher to every hook event
+func (s *PluginService) registerHooks() {
+	for event := range hooks.Events {
+		hooks.Register(event, "plugins", s.dispatch)
+	}
+	cache.Subscribe(cache.TopicPlugins, s.reset)
+}
+
+// invalidate drops the enabled plugin list here and on other instances
+func (s *PluginService) invalidate() {
+	s.reset()
+	cache.Publish(cache.TopicPlugins)
+}
+
+func (s *PluginService) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loaded = false
+	s.plugins = nil
+}
+
+// subscribers returns the enabled plugins subscribed to event
+func (s *PluginService) subscribers(event hooks.Event) []models.Plugin {
+	s.mu.RLock()
+	loaded, plugins := s.loaded, s.plugins
+	s.mu.RUnlock()
+
+	if !loaded {
+		if err := s.db().Where("enabled = ?", true).Find(&plugins).Error; err != nil {
+			slog.Error("Failed to load plugins", "error", err)
+			return nil
+		}
+		s.mu.Lock()
+		s.plugins, s.loaded = plugins, true
+		s.mu.Unlock()
+	}
+
+	var matched []models.Plugin
+	for _, plugin := range plugins {
+		for _, name := range strings.Split(plugin.Events, ",") {
+			if name == string(event) {
+				matched = append(matched, plugin)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// dispatch is the hook that forwards events to subscribed plugins
+func (s *PluginService) dispatch(ctx context.Context, event hooks.Event, payload interface{}) error {
+	plugins := s.subscribers(event)
+	if len(plugins) == 0 {
+		return nil
+	}
+
+	if !hooks.IsFilter(event) {
+		body, err := pluginRequestBody(string(event), payload)
+		if err != nil {
+			return err
+		}
+		for _, plugin := range plugins {
+			delivery := pluginDelivery{PluginID: plugin.ID, Event: string(event), Body: body}
+			if _, err := GetGlobalJobQueue().Enqueue(JobPluginDeliver, delivery); err != nil {
+				slog.Error("Failed to queue plugin delivery", "plugin", plugin.Name, "event", event, "error", err)
+			}
+		}
+		return nil
+	}
+
+	for i := range plugins {
+		if err := s.filter(ctx, &plugins[i], event, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filter calls one plugin for a filter event and applies its answer
+func (s *PluginService) filter(ctx context.Context, plugin *models.Plugin, event hooks.Event, payload interface{}) error {
+	body, err := pluginRequestBody(string(event), payload)
+	if err != nil {
+		return err
+	}
+
+	status, response, callErr := s.call(ctx, plugin, string(event), body)
+	switch {
+	case callErr != nil:
+	case status == http.StatusUnprocessableEntity:
+		var rejection struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(response, &rejection)
+		if rejection.Error == "" {
+			rejection.Error = "rejected"
+		}
+		return &hooks.RejectError{Hook: plugin.Name, Reason: rejection.Error}
+	case status >= 300:
+		callErr = fmt.Errorf("plugin returned status %d", status)
+	default:
+		callErr = applyPluginResponse(response, payload)
+	}
+
+	// Successful filter calls are not recorded; article.render runs on
+	// every read
+	if callErr != nil {
+		slog.Warn("Plugin call failed, skipping", "plugin", plugin.Name, "event", event, "error", callErr)
+		s.recordCall(plugin.ID, callErr)
+	}
+	return nil
+}
+
+// applyPluginResponse merges the "data" object of a filter response into
+// payload. The primary key cannot be changed.
+func applyPluginResponse(response []byte, payload interface{}) error {
+	if len(bytes.TrimSpace(response)) == 0 {
+		return nil
+	}
+	var answer struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(response, &answer); err != nil {
+		return fmt.Errorf("invalid response: %v", err)
+	}
+	if len(answer.Data) == 0 || string(answer.Data) == "null" {
+		return nil
+	}
+
+	if article, ok := payload.(*models.Article); ok {
+		id := article.ID
+		defer func() { article.ID = id }()
+	}
+	if err := json.Unmarshal(answer.Data, payload); err != nil {
+		return fmt.Errorf("invalid response data: %v", err)
+	}
+	return nil
+}
+
+// Deliver sends a queued action event. It runs as a job so failed
+// deliveries are retried with backoff.
+func (s *PluginService) Deliver(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var delivery pluginDelivery
+	if err := json.Unmarshal(payload, &delivery); err != nil {
+		return nil, err
+	}
+	plugin, err := s.Get(delivery.PluginID)
+	if errors.Is(err, ErrPluginNotFound) {
+		// Deleted since the event was queued; nothing to retry
+		return map[string]string{"skipped": "plugin deleted"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !plugin.Enabled {
+		return map[string]string{"skipped": "plugin disabled"}, nil
+	}
+
+	status, _, err := s.call(ctx, plugin, delivery.Event, delivery.Body)
+	if err == nil && status >= 300 {
+		err = fmt.Errorf("plugin returned status %d", status)
+	}
+	s.recordCall(plugin.ID, err)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"plugin": plugin.Name, "status_code": status}, nil
+}
+
+// call POSTs a signed body to the plugin and returns the status and body
+func (s *PluginService) call(ctx context.Context, plugin *models.Plugin, event string, body []byte) (int, []byte, error) {
+	timeout := time.Duration(plugin.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultPluginTimeout * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, plugin.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	mac := hmac.New(sha256.New, []byte(plugin.Secret))
+	mac.Write(body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "KUNO-Plugins/1.0")
+	req.Header.Set("X-Kuno-Event", event)
+	req.Header.Set("X-Kuno-Delivery", uuid.NewString())
+	req.Header.Set("X-Kuno-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(io.LimitReader(resp.Body, maxPluginResponse))
+	return resp.StatusCode, response, err
+}
+
+func (s *PluginService) recordCall(id uint, callErr error) {
+	lastError := ""
+	if callErr != nil {
+		lastError = callErr.Error()
+	}
+	s.db().Model(&models.Plugin{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_called_at": time.Now(),
+		"last_error":     lastError,
+	})
+}
+
+func pluginRequestBody(event string, payload interface{}) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"event":     event,
+		"data":      payload,
+		"timestamp": time.Now().Unix(),
+	})
+}
+
+var (
+	globalPluginService *PluginService
+	pluginServiceOnce   sync.Once
+)
+
+// GetGlobalPluginService returns the global plugin service, registering
+// its dispatcher with the hook registry on first use
+func GetGlobalPluginService() *PluginService {
+	pluginServiceOnce.Do(func() {
+		globalPluginService = NewPluginService()
+		globalPluginService.registerHooks()
+	})
+	return globalPluginService
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPluginFilterModifiesAndRejects(t *testing.T) {
+	newTestDB(t)
+	var secret string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		if r.Header.Get("X-Kuno-Signature") != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Data models.Article `json:"data"`
+		}
+		json.Unmarshal(body, &req)
+		if req.Data.Title == "spam" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error":"looks like spam"}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"id":99,"title":"` + req.Data.Title + ` (edited)"}}`))
+	}))
+	defer server.Close()
+
+	s := NewPluginService()
+	name, url := "moderator", server.URL
+	plugin, generated, err := s.Create(PluginInput{Name: &name, URL: &url, Events: []string{string(hooks.BeforeArticleSave)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret = generated
+
+	article := &models.Article{ID: 7, Title: "Hello", Content: "body"}
+	if err := s.dispatch(context.Background(), hooks.BeforeArticleSave, article); err != nil {
+		t.Fatal(err)
+	}
+	if article.Title != "Hello (edited)" || article.ID != 7 || article.Content != "body" {
+		t.Errorf("article after filter = %+v", article)
+	}
+
+	var reject *hooks.RejectError
+	err = s.dispatch(context.Background(), hooks.BeforeArticleSave, &models.Article{Title: "spam"})
+	if !errors.As(err, &reject) || reject.Reason != "looks like spam" || reject.Hook != "moderator" {
+		t.Errorf("dispatch of rejected article error = %v", err)
+	}
+
+	// Plugins only receive the events they subscribe to
+	if err := s.dispatch(context.Background(), hooks.ArticleRender, &models.Article{Title: "spam"}); err != nil {
+		t.Errorf("unsubscribed event reached the plugin: %v", err)
+	}
+
+	// A wrong secret makes the plugin fail, which is skipped rather than blocking the save
+	wrong := "wrong"
+	if _, err := s.Update(plugin.ID, PluginInput{Secret: &wrong}); err != nil {
+		t.Fatal(err)
+	}
+	article = &models.Article{Title: "Hello"}
+	if err := s.dispatch(context.Background(), hooks.BeforeArticleSave, article); err != nil || article.Title != "Hello" {
+		t.Errorf("failing plugin should be skipped: %v, %+v", err, article)
+	}
+	if stored, _ := s.Get(plugin.ID); stored.LastError == "" {
+		t.Error("plugin failure was not recorded")
+	}
+}
+
+func TestPluginActionEventIsQueuedAndDelivered(t *testing.T) {
+	newTestDB(t)
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Event string         `json:"event"`
+			Data  models.Article `json:"data"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		received <- req.Event + ":" + req.Data.Title
+	}))
+	defer server.Close()
+
+	s := NewPluginService()
+	name, url := "notifier", server.URL
+	if _, _, err := s.Create(PluginInput{Name: &name, URL: &url, Events: []string{string(hooks.ArticlePublished)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.dispatch(context.Background(), hooks.ArticlePublished, &models.Article{Title: "Launch"}); err != nil {
+		t.Fatal(err)
+	}
+
+	job := runNext(t, GetGlobalJobQueue())
+	if job == nil || job.Type != JobPluginDeliver || job.Status != models.JobSucceeded {
+		t.Fatalf("delivery job = %+v", job)
+	}
+	if got := <-received; got != "article.published:Launch" {
+		t.Errorf("plugin received %q", got)
+	}
+}
+
+func TestPluginInputValidation(t *testing.T) {
+	newTestDB(t)
+	s := NewPluginService()
+	name, url, badURL := "x", "https://example.com/hook", "ftp://example.com"
+	if _, _, err := s.Create(PluginInput{Name: &name, URL: &badURL}); !errors.Is(err, ErrInvalidPlugin) {
+		t.Errorf("non-http URL error = %v", err)
+	}
+	if _, _, err := s.Create(PluginInput{Name: &name, URL: &url, Events: []string{"article.deleted"}}); !errors.Is(err, ErrInvalidPlugin) {
+		t.Errorf("unknown event error = %v", err)
+	}
+}
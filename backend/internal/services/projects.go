@@ -0,0 +1,293 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxProjectLinks is the most links a project may show
+	maxProjectLinks = 10
+	// maxProjectTech is the most technologies a project may list
+	maxProjectTech = 30
+)
+
+var (
+	ErrInvalidProject  = errors.New("invalid project")
+	ErrProjectNotFound = errors.New("project not found")
+)
+
+// ProjectInput holds the editable fields of a project; nil fields are left
+// unchanged. Translations, when given, replace every translation of the
+// project.
+type ProjectInput struct {
+	Name         *string                   `json:"name"`
+	Description  *string                   `json:"description"`
+	CoverImage   *string                   `json:"cover_image"`
+	Links        []models.ProjectLink      `json:"links"`
+	TechStack    []string                  `json:"tech_stack"`
+	Status       *string                   `json:"status"`
+	Featured     *bool                     `json:"featured"`
+	IsActive     *bool                     `json:"is_active"`
+	Translations []ProjectTranslationInput `json:"translations"`
+}
+
+// ProjectTranslationInput is a project's name and description in another
+// language; an empty name falls back to the project's own
+type ProjectTranslationInput struct {
+	Language    string `json:"language"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ProjectOrder moves a project to a position on the portfolio page
+type ProjectOrder struct {
+	ID    uint `json:"id"`
+	Order int  `json:"order"`
+}
+
+// ProjectService manages the portfolio of each site. Visitors see the
+// active projects, featured ones first, in the language they ask for.
+type ProjectService struct {
+	db func() *gorm.DB
+}
+
+// NewProjectService creates a project service
+func NewProjectService() *ProjectService {
+	return &ProjectService{db: func() *gorm.DB { return database.DB }}
+}
+
+// List returns the site's projects in display order, optionally only the
+// active ones or those with a status
+func (s *ProjectService) List(ctx context.Context, activeOnly bool, status string) ([]models.Project, error) {
+	query := s.db().WithContext(ctx).Preload("Translations")
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	projects := []models.Project{}
+	err := query.Order("featured DESC, display_order ASC, id ASC").Find(&projects).Error
+	return projects, err
+}
+
+// Get returns one of the site's projects, optionally only an active one
+func (s *ProjectService) Get(ctx context.Context, id uint, activeOnly bool) (*models.Project, error) {
+	query := s.db().WithContext(ctx).Preload("Translations")
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+	var project models.Project
+	if err := query.First(&project, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	return &project, nil
+}
+
+// Create adds an active project at the end of the portfolio
+func (s *ProjectService) Create(ctx context.Context, input ProjectInput) (*models.Project, error) {
+	project := &models.Project{Status: models.ProjectActive, IsActive: true}
+	project.SetLinks([]models.ProjectLink{})
+	project.SetTechStack([]string{})
+	if err := applyProjectInput(project, input); err != nil {
+		return nil, err
+	}
+	db := s.db().WithContext(ctx)
+	var maxOrder int
+	if err := db.Model(&models.Project{}).Select("COALESCE(MAX(display_order), 0)").Scan(&maxOrder).Error; err != nil {
+		return nil, err
+	}
+	project.DisplayOrder = maxOrder + 1
+	if err := db.Create(project).Error; err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// Update edits a project and, when given, replaces its translations
+func (s *ProjectService) Update(ctx context.Context, id uint, input ProjectInput) (*models.Project, error) {
+	project, err := s.Get(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyProjectInput(project, input); err != nil {
+		return nil, err
+	}
+	err = s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if input.Translations != nil {
+			if err := tx.Where("project_id = ?", project.ID).Delete(&models.ProjectTranslation{}).Error; err != nil {
+				return err
+			}
+			for i := range project.Translations {
+				project.Translations[i].ID = 0
+				project.Translations[i].ProjectID = project.ID
+			}
+		}
+		return tx.Save(project).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// Delete removes a project and its translations
+func (s *ProjectService) Delete(ctx context.Context, id uint) error {
+	project, err := s.Get(ctx, id, false)
+	if err != nil {
+		return err
+	}
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_id = ?", project.ID).Delete(&models.ProjectTranslation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(project).Error
+	})
+}
+
+// Reorder sets the display order of the given projects
+func (s *ProjectService) Reorder(ctx context.Context, orders []ProjectOrder) error {
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, order := range orders {
+			if err := tx.Model(&models.Project{}).Where("id = ?", order.ID).
+				Update("display_order", order.Order).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ApplyProjectTranslation shows a project in lang where it has been
+// translated; untranslated fields keep the site's default language
+func ApplyProjectTranslation(project *models.Project, lang string) {
+	for _, translation := range project.Translations {
+		if translation.Language == lang {
+			if translation.Name != "" {
+				project.Name = translation.Name
+			}
+			if translation.Description != "" {
+				project.Description = translation.Description
+			}
+			break
+		}
+	}
+}
+
+func applyProjectInput(project *models.Project, input ProjectInput) error {
+	if input.Name != nil {
+		project.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		project.Description = strings.TrimSpace(*input.Description)
+	}
+	if input.CoverImage != nil {
+		project.CoverImage = strings.TrimSpace(*input.CoverImage)
+	}
+	if input.Links != nil {
+		links := make([]models.ProjectLink, 0, len(input.Links))
+		for _, link := range input.Links {
+			link.Label, link.URL = strings.TrimSpace(link.Label), strings.TrimSpace(link.URL)
+			if link.URL != "" {
+				links = append(links, link)
+			}
+		}
+		project.SetLinks(links)
+	}
+	if input.TechStack != nil {
+		tech := make([]string, 0, len(input.TechStack))
+		for _, name := range input.TechStack {
+			if name = strings.TrimSpace(name); name != "" {
+				tech = append(tech, name)
+			}
+		}
+		project.SetTechStack(tech)
+	}
+	if input.Status != nil {
+		project.Status = *input.Status
+	}
+	if input.Featured != nil {
+		project.Featured = *input.Featured
+	}
+	if input.IsActive != nil {
+		project.IsActive = *input.IsActive
+	}
+	if input.Translations != nil {
+		project.Translations = make([]models.ProjectTranslation, 0, len(input.Translations))
+		seen := map[string]bool{}
+		for _, t := range input.Translations {
+			language := strings.TrimSpace(t.Language)
+			if language == "" || len(language) > 10 || seen[language] {
+				return fmt.Errorf("%w: each translation needs a distinct language code", ErrInvalidProject)
+			}
+			seen[language] = true
+			translation := models.ProjectTranslation{
+				ProjectID:   project.ID,
+				Language:    language,
+				Name:        strings.TrimSpace(t.Name),
+				Description: strings.TrimSpace(t.Description),
+			}
+			if utf8.RuneCountInString(translation.Name) > 100 {
+				return fmt.Errorf("%w: the %s name is at most 100 characters", ErrInvalidProject, language)
+			}
+			project.Translations = append(project.Translations, translation)
+		}
+	}
+
+	if project.Name == "" || utf8.RuneCountInString(project.Name) > 100 {
+		return fmt.Errorf("%w: name is required and at most 100 characters", ErrInvalidProject)
+	}
+	if project.CoverImage != "" && !validMediaURL(project.CoverImage) {
+		return fmt.Errorf("%w: cover_image must be an http(s) URL or a path on the site", ErrInvalidProject)
+	}
+	if len(project.LinkList) > maxProjectLinks {
+		return fmt.Errorf("%w: at most %d links", ErrInvalidProject, maxProjectLinks)
+	}
+	for _, link := range project.LinkList {
+		if utf8.RuneCountInString(link.Label) > 50 {
+			return fmt.Errorf("%w: link labels are at most 50 characters", ErrInvalidProject)
+		}
+		if !validMediaURL(link.URL) {
+			return fmt.Errorf("%w: %q is not an http(s) URL or a path on the site", ErrInvalidProject, link.URL)
+		}
+	}
+	if len(project.TechList) > maxProjectTech {
+		return fmt.Errorf("%w: at most %d technologies", ErrInvalidProject, maxProjectTech)
+	}
+	for _, name := range project.TechList {
+		if strings.Contains(name, ",") || utf8.RuneCountInString(name) > 50 {
+			return fmt.Errorf("%w: %q must be at most 50 characters without commas", ErrInvalidProject, name)
+		}
+	}
+	switch project.Status {
+	case models.ProjectActive, models.ProjectMaintained, models.ProjectCompleted, models.ProjectArchived, models.ProjectDiscontinued:
+	default:
+		return fmt.Errorf("%w: status must be active, maintained, completed, archived or discontinued", ErrInvalidProject)
+	}
+	return nil
+}
+
+var (
+	globalProjectService     *ProjectService
+	globalProjectServiceOnce sync.Once
+)
+
+// GetGlobalProjectService returns the global project service
+func GetGlobalProjectService() *ProjectService {
+	globalProjectServiceOnce.Do(func() {
+		globalProjectService = NewProjectService()
+	})
+	return globalProjectService
+}
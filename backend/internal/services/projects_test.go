@@ -0,0 +1,106 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestProjects(t *testing.T) {
+	newTestDB(t)
+	s := &ProjectService{db: func() *gorm.DB { return database.DB }}
+	ctx := context.Background()
+
+	for _, bad := range []ProjectInput{
+		{Description: strPtr("No name")},
+		{Name: strPtr("Kuno"), Status: strPtr("abandoned")},
+		{Name: strPtr("Kuno"), CoverImage: strPtr("javascript:alert(1)")},
+		{Name: strPtr("Kuno"), Links: []models.ProjectLink{{Label: "Demo", URL: "//evil.example"}}},
+		{Name: strPtr("Kuno"), TechStack: []string{"Go, Gin"}},
+		{Name: strPtr("Kuno"), Translations: []ProjectTranslationInput{{Language: "en"}, {Language: "en"}}},
+	} {
+		if _, err := s.Create(ctx, bad); !errors.Is(err, ErrInvalidProject) {
+			t.Errorf("Create(%+v) = %v, want ErrInvalidProject", bad, err)
+		}
+	}
+
+	links := []models.ProjectLink{{Label: "Source", URL: "https://github.com/xuemian168/kuno"}, {Label: "Demo", URL: "/demo"}}
+	kuno, err := s.Create(ctx, ProjectInput{
+		Name:         strPtr("酷诺"),
+		Description:  strPtr("博客系统"),
+		Links:        links,
+		TechStack:    []string{"Go", " Next.js ", ""},
+		Translations: []ProjectTranslationInput{{Language: "en", Name: "Kuno", Description: "A blog engine"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hidden := false
+	if _, err := s.Create(ctx, ProjectInput{Name: strPtr("Draft"), IsActive: &hidden}); err != nil {
+		t.Fatal(err)
+	}
+	featured := true
+	tool, err := s.Create(ctx, ProjectInput{Name: strPtr("Tool"), Status: strPtr(models.ProjectArchived), Featured: &featured})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projects, err := s.List(ctx, true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 2 || projects[0].ID != tool.ID || projects[1].ID != kuno.ID {
+		t.Fatalf("active projects = %+v, want the featured one first and no draft", projects)
+	}
+	if !reflect.DeepEqual(projects[1].LinkList, links) || !reflect.DeepEqual(projects[1].TechList, []string{"Go", "Next.js"}) {
+		t.Errorf("links = %+v, tech = %q", projects[1].LinkList, projects[1].TechList)
+	}
+	if archived, _ := s.List(ctx, true, models.ProjectArchived); len(archived) != 1 {
+		t.Errorf("%d archived projects, want 1", len(archived))
+	}
+	if all, _ := s.List(ctx, false, ""); len(all) != 3 {
+		t.Errorf("%d projects in all, want 3", len(all))
+	}
+
+	ApplyProjectTranslation(&projects[1], "en")
+	if projects[1].Name != "Kuno" || projects[1].Description != "A blog engine" {
+		t.Errorf("in English %q: %q", projects[1].Name, projects[1].Description)
+	}
+
+	// Replacing the translations keeps only the new ones
+	updated, err := s.Update(ctx, kuno.ID, ProjectInput{Translations: []ProjectTranslationInput{{Language: "ja", Name: "クノ"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "酷诺" || len(updated.LinkList) != 2 {
+		t.Errorf("after update %+v", updated)
+	}
+	got, err := s.Get(ctx, kuno.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Translations) != 1 || got.Translations[0].Language != "ja" {
+		t.Errorf("translations = %+v, want only ja", got.Translations)
+	}
+	ApplyProjectTranslation(got, "ja")
+	if got.Name != "クノ" || got.Description != "博客系统" {
+		t.Errorf("in Japanese %q: %q", got.Name, got.Description)
+	}
+
+	if err := s.Delete(ctx, kuno.ID); err != nil {
+		t.Fatal(err)
+	}
+	var left int64
+	database.DB.Model(&models.ProjectTranslation{}).Count(&left)
+	if left != 0 {
+		t.Errorf("%d translations left after delete", left)
+	}
+	if _, err := s.Get(ctx, kuno.ID, false); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("deleted project = %v", err)
+	}
+}
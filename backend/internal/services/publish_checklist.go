@@ -0,0 +1,111 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/models"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Items of the publish checklist
+const (
+	ChecklistCoverImage    = "cover_image"
+	ChecklistSummary       = "summary"
+	ChecklistSEOScore      = "seo_score"
+	ChecklistInternalLinks = "internal_links"
+)
+
+// checklistSuggestions caps the SEO suggestions a failed score lists
+const checklistSuggestions = 3
+
+// checklistLinkPattern finds the targets of Markdown links, leaving out
+// images, and of HTML anchors
+var checklistLinkPattern = regexp.MustCompile(`(?:^|[^!])\[[^\]]*\]\(\s*<?([^)\s>]+)|<a\s[^>]*href\s*=\s*["']([^"']+)["']`)
+
+// ChecklistFailure is an item of the publish checklist an article does not
+// meet, with what to do about it
+type ChecklistFailure struct {
+	Check   string   `json:"check"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
+}
+
+// PublishChecklist checks an article against the publish checklist of the
+// configuration before it goes live
+type PublishChecklist struct {
+	config   config.PublishConfig
+	analyzer *SEOAnalyzerService
+}
+
+// NewPublishChecklist creates the checklist of cfg
+func NewPublishChecklist(cfg config.PublishConfig) *PublishChecklist {
+	return &PublishChecklist{config: cfg, analyzer: NewSEOAnalyzerService()}
+}
+
+// Enabled reports whether the checklist has any item
+func (p *PublishChecklist) Enabled() bool {
+	return p.config.RequireCoverImage || p.config.RequireSummary || p.config.MinSEOScore > 0 || p.config.MinInternalLinks > 0
+}
+
+// Check returns the items the article fails, none when it may be
+// published. baseURL is the site's address, so absolute links to it count
+// as internal.
+func (p *PublishChecklist) Check(article *models.Article, baseURL string) []ChecklistFailure {
+	var failures []ChecklistFailure
+	if p.config.RequireCoverImage && article.CoverImageID == nil && (article.CoverImageURL == nil || strings.TrimSpace(*article.CoverImageURL) == "") {
+		failures = append(failures, ChecklistFailure{Check: ChecklistCoverImage, Message: "Set a cover image"})
+	}
+	if p.config.RequireSummary && strings.TrimSpace(article.Summary) == "" {
+		failures = append(failures, ChecklistFailure{Check: ChecklistSummary, Message: "Write a summary"})
+	}
+	if p.config.MinSEOScore > 0 {
+		analysis, err := p.analyzer.AnalyzeContent(article, article.SEOKeywords, article.DefaultLang)
+		if err == nil && analysis.OverallScore < p.config.MinSEOScore {
+			suggestions := analysis.Suggestions
+			if len(suggestions) > checklistSuggestions {
+				suggestions = suggestions[:checklistSuggestions]
+			}
+			failures = append(failures, ChecklistFailure{
+				Check:   ChecklistSEOScore,
+				Message: fmt.Sprintf("Raise the SEO score from %d to at least %d", analysis.OverallScore, p.config.MinSEOScore),
+				Details: suggestions,
+			})
+		}
+	}
+	if p.config.MinInternalLinks > 0 {
+		if links := countInternalLinks(article.Content, baseURL); links < p.config.MinInternalLinks {
+			failures = append(failures, ChecklistFailure{
+				Check:   ChecklistInternalLinks,
+				Message: fmt.Sprintf("Link to at least %d other pages of the site, found %d", p.config.MinInternalLinks, links),
+			})
+		}
+	}
+	return failures
+}
+
+// countInternalLinks counts the links of content to other pages of the
+// site: relative links and absolute ones to baseURL. Anchors within the
+// page and uploaded files do not count.
+func countInternalLinks(content, baseURL string) int {
+	baseURL = strings.TrimRight(baseURL, "/")
+	count := 0
+	for _, match := range checklistLinkPattern.FindAllStringSubmatch(content, -1) {
+		target := match[1]
+		if target == "" {
+			target = match[2]
+		}
+		if baseURL != "" && (target == baseURL || strings.HasPrefix(target, baseURL+"/")) {
+			target = "/" + strings.TrimPrefix(strings.TrimPrefix(target, baseURL), "/")
+		}
+		switch {
+		case target == "" || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "//"):
+		case strings.HasPrefix(target, "/uploads/"):
+		case strings.Contains(target, ":"):
+			// Another site, or mailto: and other schemes
+		default:
+			count++
+		}
+	}
+	return count
+}
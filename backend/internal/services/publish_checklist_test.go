@@ -0,0 +1,46 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/models"
+	"testing"
+)
+
+func TestPublishChecklist(t *testing.T) {
+	checklist := &PublishChecklist{
+		config:   config.PublishConfig{RequireCoverImage: true, RequireSummary: true, MinSEOScore: 100, MinInternalLinks: 2},
+		analyzer: &SEOAnalyzerService{rules: func(string) SEORules { return builtinSEORules["en"] }},
+	}
+	if !checklist.Enabled() || (&PublishChecklist{}).Enabled() {
+		t.Error("Enabled does not follow the configuration")
+	}
+
+	article := &models.Article{Title: "Trip", Content: "See [the plan](/en/article/2).", DefaultLang: "en"}
+	failures := checklist.Check(article, "https://blog.example")
+	checks := map[string]ChecklistFailure{}
+	for _, failure := range failures {
+		checks[failure.Check] = failure
+	}
+	if len(failures) != 4 || checks[ChecklistSEOScore].Message == "" || len(checks[ChecklistSEOScore].Details) == 0 {
+		t.Fatalf("failures = %+v", failures)
+	}
+	if checks[ChecklistInternalLinks].Message != "Link to at least 2 other pages of the site, found 1" {
+		t.Errorf("internal links = %q", checks[ChecklistInternalLinks].Message)
+	}
+
+	cover := "/uploads/cover.png"
+	article.CoverImageURL, article.Summary = &cover, "Where we went"
+	article.Content += ` And <a href="https://blog.example/en/article/3">the map</a>.`
+	checklist.config.MinSEOScore = 0
+	if failures := checklist.Check(article, "https://blog.example/"); len(failures) != 0 {
+		t.Errorf("failures = %+v, want none", failures)
+	}
+}
+
+func TestCountInternalLinks(t *testing.T) {
+	content := `[a](/en/article/1) [b](https://blog.example/zh/article/2) [c](https://other.example/x)
+![img](/uploads/a.png) [d](#top) [e](mailto:me@example.com) [f](//cdn.example/x) <a href='tags/go'>g</a>`
+	if got := countInternalLinks(content, "https://blog.example"); got != 3 {
+		t.Errorf("countInternalLinks = %d, want 3", got)
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// quickLinkClickWindow is how long repeated clicks from one address on one
+// link count once
+const quickLinkClickWindow = time.Hour
+
+var (
+	ErrInvalidQuickLink  = errors.New("invalid quick link")
+	ErrQuickLinkNotFound = errors.New("quick link not found")
+)
+
+// quickLinkIconName matches the names of theme icons, as opposed to image
+// URLs
+var quickLinkIconName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// QuickLinkInput holds the editable fields of a quick link; nil fields are
+// left unchanged
+type QuickLinkInput struct {
+	Title    *string `json:"title"`
+	URL      *string `json:"url"`
+	Icon     *string `json:"icon"`
+	IsActive *bool   `json:"is_active"`
+}
+
+// QuickLinkOrder moves a link to a position on the page
+type QuickLinkOrder struct {
+	ID    uint `json:"id"`
+	Order int  `json:"order"`
+}
+
+// PublicQuickLink is a quick link as shown to readers, without its click
+// count
+type PublicQuickLink struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Icon  string `json:"icon,omitempty"`
+}
+
+// QuickLinkService manages the links of each site's quick links page.
+// Clicks are counted per link, once per address and hour.
+type QuickLinkService struct {
+	db     func() *gorm.DB
+	clicks *cache.Namespace
+}
+
+// NewQuickLinkService creates a quick link service
+func NewQuickLinkService() *QuickLinkService {
+	return &QuickLinkService{
+		db:     func() *gorm.DB { return database.DB },
+		clicks: cache.New("quick_link_clicks", quickLinkClickWindow),
+	}
+}
+
+// List returns the site's quick links in display order, including inactive
+// ones, with their click counts
+func (s *QuickLinkService) List(ctx context.Context) ([]models.QuickLink, error) {
+	links := []models.QuickLink{}
+	err := s.db().WithContext(ctx).Order("display_order ASC, id ASC").Find(&links).Error
+	return links, err
+}
+
+// Public returns the site's active quick links for readers
+func (s *QuickLinkService) Public(ctx context.Context) ([]PublicQuickLink, error) {
+	var active []models.QuickLink
+	if err := s.db().WithContext(ctx).Where("is_active = ?", true).
+		Order("display_order ASC, id ASC").Find(&active).Error; err != nil {
+		return nil, err
+	}
+	links := make([]PublicQuickLink, len(active))
+	for i, link := range active {
+		links[i] = PublicQuickLink{ID: link.ID, Title: link.Title, URL: link.URL, Icon: link.Icon}
+	}
+	return links, nil
+}
+
+// Get returns one of the site's quick links
+func (s *QuickLinkService) Get(ctx context.Context, id uint) (*models.QuickLink, error) {
+	var link models.QuickLink
+	if err := s.db().WithContext(ctx).First(&link, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuickLinkNotFound
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Create adds an active quick link at the end of the page
+func (s *QuickLinkService) Create(ctx context.Context, input QuickLinkInput) (*models.QuickLink, error) {
+	link := &models.QuickLink{IsActive: true}
+	if err := applyQuickLinkInput(link, input); err != nil {
+		return nil, err
+	}
+	db := s.db().WithContext(ctx)
+	var maxOrder int
+	if err := db.Model(&models.QuickLink{}).Select("COALESCE(MAX(display_order), 0)").Scan(&maxOrder).Error; err != nil {
+		return nil, err
+	}
+	link.DisplayOrder = maxOrder + 1
+	if err := db.Create(link).Error; err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// Update edits a quick link
+func (s *QuickLinkService) Update(ctx context.Context, id uint, input QuickLinkInput) (*models.QuickLink, error) {
+	link, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyQuickLinkInput(link, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Save(link).Error; err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// Delete removes a quick link
+func (s *QuickLinkService) Delete(ctx context.Context, id uint) error {
+	result := s.db().WithContext(ctx).Delete(&models.QuickLink{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrQuickLinkNotFound
+	}
+	return nil
+}
+
+// Reorder sets the display order of the given links
+func (s *QuickLinkService) Reorder(ctx context.Context, orders []QuickLinkOrder) error {
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, order := range orders {
+			if err := tx.Model(&models.QuickLink{}).Where("id = ?", order.ID).
+				Update("display_order", order.Order).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Click counts a reader opening an active quick link. Repeated clicks from
+// the same address within the hour are not counted again.
+func (s *QuickLinkService) Click(ctx context.Context, id uint, ip string) error {
+	link, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !link.IsActive {
+		return ErrQuickLinkNotFound
+	}
+	key := fmt.Sprintf("%d:%s", link.ID, ip)
+	var seen bool
+	if s.clicks.Get(key, &seen) {
+		return nil
+	}
+	s.clicks.Set(key, true)
+	return s.db().WithContext(ctx).Model(link).UpdateColumn("clicks", gorm.Expr("clicks + 1")).Error
+}
+
+func applyQuickLinkInput(link *models.QuickLink, input QuickLinkInput) error {
+	if input.Title != nil {
+		link.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.URL != nil {
+		link.URL = strings.TrimSpace(*input.URL)
+	}
+	if input.Icon != nil {
+		link.Icon = strings.TrimSpace(*input.Icon)
+	}
+	if input.IsActive != nil {
+		link.IsActive = *input.IsActive
+	}
+
+	if link.Title == "" || utf8.RuneCountInString(link.Title) > 100 {
+		return fmt.Errorf("%w: title is required and at most 100 characters", ErrInvalidQuickLink)
+	}
+	if !validMediaURL(link.URL) && !validMailtoURL(link.URL) {
+		return fmt.Errorf("%w: url must be an http(s) URL, a path on the site or a mailto: address", ErrInvalidQuickLink)
+	}
+	if link.Icon != "" && !quickLinkIconName.MatchString(link.Icon) && !validMediaURL(link.Icon) {
+		return fmt.Errorf("%w: icon must be the name of a theme icon or an image URL", ErrInvalidQuickLink)
+	}
+	return nil
+}
+
+// validMailtoURL accepts mailto: links to a single address
+func validMailtoURL(link string) bool {
+	address, ok := strings.CutPrefix(link, "mailto:")
+	if !ok || len(link) > 500 {
+		return false
+	}
+	address, _, _ = strings.Cut(address, "?")
+	at := strings.IndexByte(address, '@')
+	return at > 0 && at < len(address)-1 && !strings.ContainsAny(address, " ,\n\r<>")
+}
+
+var (
+	globalQuickLinkService     *QuickLinkService
+	globalQuickLinkServiceOnce sync.Once
+)
+
+// GetGlobalQuickLinkService returns the global quick link service
+func GetGlobalQuickLinkService() *QuickLinkService {
+	globalQuickLinkServiceOnce.Do(func() {
+		globalQuickLinkService = NewQuickLinkService()
+	})
+	return globalQuickLinkService
+}
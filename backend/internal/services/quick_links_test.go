@@ -0,0 +1,94 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestQuickLinks(t *testing.T) {
+	newTestDB(t)
+	s := &QuickLinkService{
+		db:     func() *gorm.DB { return database.DB },
+		clicks: cache.New("test_quick_link_clicks", time.Hour),
+	}
+	s.clicks.Clear()
+	ctx := context.Background()
+
+	for _, bad := range []QuickLinkInput{
+		{URL: strPtr("https://example.com")},
+		{Title: strPtr("Script"), URL: strPtr("javascript:alert(1)")},
+		{Title: strPtr("Protocol relative"), URL: strPtr("//evil.example/")},
+		{Title: strPtr("Two addresses"), URL: strPtr("mailto:a@example.com,b@example.com")},
+		{Title: strPtr("Bad icon"), URL: strPtr("/about"), Icon: strPtr("<svg onload=alert(1)>")},
+	} {
+		if _, err := s.Create(ctx, bad); !errors.Is(err, ErrInvalidQuickLink) {
+			t.Errorf("Create(%+v) = %v, want ErrInvalidQuickLink", bad, err)
+		}
+	}
+
+	inactive := false
+	var ids []uint
+	for _, input := range []QuickLinkInput{
+		{Title: strPtr(" Latest article "), URL: strPtr("/article/42"), Icon: strPtr("book-open")},
+		{Title: strPtr("GitHub"), URL: strPtr("https://github.com/alice"), Icon: strPtr("github")},
+		{Title: strPtr("Email me"), URL: strPtr("mailto:alice@example.com?subject=Hi"), Icon: strPtr("/uploads/images/mail.png")},
+		{Title: strPtr("Old shop"), URL: strPtr("https://shop.example.com"), IsActive: &inactive},
+	} {
+		link, err := s.Create(ctx, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, link.ID)
+	}
+	// GitHub moves to the front
+	if err := s.Reorder(ctx, []QuickLinkOrder{{ID: ids[1], Order: 0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := s.Public(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 3 || links[0].Title != "GitHub" || links[1].Title != "Latest article" || links[2].Icon != "/uploads/images/mail.png" {
+		t.Fatalf("public links = %+v", links)
+	}
+	all, err := s.List(ctx)
+	if err != nil || len(all) != 4 {
+		t.Fatalf("List = %d, %v, want 4", len(all), err)
+	}
+
+	// Fields left out of an update keep their value
+	updated, err := s.Update(ctx, ids[0], QuickLinkInput{Title: strPtr("Newest article")})
+	if err != nil || updated.URL != "/article/42" || updated.Icon != "book-open" {
+		t.Errorf("Update = %+v, %v", updated, err)
+	}
+	if _, err := s.Update(ctx, ids[0], QuickLinkInput{Title: strPtr("")}); !errors.Is(err, ErrInvalidQuickLink) {
+		t.Errorf("Update with an empty title = %v", err)
+	}
+
+	// Repeated clicks from one address count once
+	for _, ip := range []string{"192.0.2.1", "192.0.2.1", "192.0.2.2"} {
+		if err := s.Click(ctx, ids[1], ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if link, _ := s.Get(ctx, ids[1]); link.Clicks != 2 {
+		t.Errorf("clicks = %d, want 2", link.Clicks)
+	}
+	if err := s.Click(ctx, ids[3], "192.0.2.1"); !errors.Is(err, ErrQuickLinkNotFound) {
+		t.Errorf("click on an inactive link = %v", err)
+	}
+
+	if err := s.Delete(ctx, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, ids[0]); !errors.Is(err, ErrQuickLinkNotFound) {
+		t.Errorf("second delete = %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// readingFinishedProgress is the progress from which an article counts as
+// read to the end and drops out of the list of articles to resume
+const readingFinishedProgress = 0.95
+
+var (
+	ErrInvalidReadingPosition  = errors.New("invalid reading position")
+	ErrReadingPositionNotFound = errors.New("reading position not found")
+)
+
+// ReadingPositionInput is where a reader is in an article
+type ReadingPositionInput struct {
+	Progress float64 `json:"progress"`
+	Anchor   string  `json:"anchor"`
+	Language string  `json:"language"`
+}
+
+// ReadingPositionService remembers where readers are in articles so they
+// can resume reading in a later session. Readers are known by the user ID
+// the behavior tracker records, and their furthest progress also corrects
+// the scroll depth of the behavior tracked for the article.
+type ReadingPositionService struct {
+	db  func() *gorm.DB
+	now func() time.Time
+}
+
+// NewReadingPositionService creates a reading position service
+func NewReadingPositionService() *ReadingPositionService {
+	return &ReadingPositionService{
+		db:  func() *gorm.DB { return database.DB },
+		now: time.Now,
+	}
+}
+
+// Save stores the reader's position in a published article
+func (s *ReadingPositionService) Save(ctx context.Context, userID string, articleID uint, input ReadingPositionInput) (*models.ReadingPosition, error) {
+	if err := validReaderID(userID); err != nil {
+		return nil, err
+	}
+	if math.IsNaN(input.Progress) || input.Progress < 0 || input.Progress > 1 {
+		return nil, fmt.Errorf("%w: progress must be between 0 and 1", ErrInvalidReadingPosition)
+	}
+	input.Anchor = strings.TrimSpace(input.Anchor)
+	if len(input.Anchor) > 255 {
+		return nil, fmt.Errorf("%w: anchor is at most 255 bytes", ErrInvalidReadingPosition)
+	}
+	if input.Language != "" && (len(input.Language) > 10 || !languageCode.MatchString(input.Language)) {
+		return nil, fmt.Errorf("%w: unknown language %q", ErrInvalidReadingPosition, input.Language)
+	}
+
+	var position models.ReadingPosition
+	err := s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var published int64
+		if err := tx.Model(&models.Article{}).Where("id = ? AND created_at <= ?", articleID, s.now()).Count(&published).Error; err != nil {
+			return err
+		}
+		if published == 0 {
+			return ErrArticleNotFound
+		}
+
+		err := tx.Where("user_id = ? AND article_id = ?", userID, articleID).First(&position).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		position.UserID = userID
+		position.ArticleID = articleID
+		position.Progress = input.Progress
+		position.MaxProgress = math.Max(position.MaxProgress, input.Progress)
+		position.Anchor = input.Anchor
+		if input.Language != "" {
+			position.Language = input.Language
+		}
+		if err := tx.Save(&position).Error; err != nil {
+			return err
+		}
+
+		// Behavior is tracked when the article is opened, before the reader
+		// has scrolled far; the furthest position is the better figure
+		return tx.Model(&models.UserReadingBehavior{}).
+			Where("user_id = ? AND article_id = ? AND scroll_depth < ?", userID, articleID, position.MaxProgress).
+			Update("scroll_depth", position.MaxProgress).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+// Get returns the reader's position in an article
+func (s *ReadingPositionService) Get(ctx context.Context, userID string, articleID uint) (*models.ReadingPosition, error) {
+	if err := validReaderID(userID); err != nil {
+		return nil, err
+	}
+	var position models.ReadingPosition
+	err := s.db().WithContext(ctx).Where("user_id = ? AND article_id = ?", userID, articleID).
+		Where("article_id IN (?)", s.published(ctx)).First(&position).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReadingPositionNotFound
+		}
+		return nil, err
+	}
+	return &position, nil
+}
+
+// InProgress returns the published articles the reader started but has not
+// finished, most recently read first
+func (s *ReadingPositionService) InProgress(ctx context.Context, userID string, limit int) ([]models.ReadingPosition, error) {
+	if err := validReaderID(userID); err != nil {
+		return nil, err
+	}
+	positions := []models.ReadingPosition{}
+	err := s.db().WithContext(ctx).Where("user_id = ? AND progress > 0 AND progress < ?", userID, readingFinishedProgress).
+		Where("article_id IN (?)", s.published(ctx)).
+		Order("updated_at DESC, id DESC").Limit(limit).Find(&positions).Error
+	return positions, err
+}
+
+// Delete forgets the reader's position in an article
+func (s *ReadingPositionService) Delete(ctx context.Context, userID string, articleID uint) error {
+	if err := validReaderID(userID); err != nil {
+		return err
+	}
+	result := s.db().WithContext(ctx).Where("user_id = ? AND article_id = ?", userID, articleID).Delete(&models.ReadingPosition{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrReadingPositionNotFound
+	}
+	return nil
+}
+
+// published selects the IDs of the site's published articles
+func (s *ReadingPositionService) published(ctx context.Context) *gorm.DB {
+	return s.db().WithContext(ctx).Model(&models.Article{}).Select("id").Where("created_at <= ?", s.now())
+}
+
+func validReaderID(userID string) error {
+	if userID == "" || len(userID) > 255 {
+		return fmt.Errorf("%w: a user ID of at most 255 bytes is required", ErrInvalidReadingPosition)
+	}
+	return nil
+}
+
+var (
+	globalReadingPositionService     *ReadingPositionService
+	globalReadingPositionServiceOnce sync.Once
+)
+
+// GetGlobalReadingPositionService returns the global reading position service
+func GetGlobalReadingPositionService() *ReadingPositionService {
+	globalReadingPositionServiceOnce.Do(func() {
+		globalReadingPositionService = NewReadingPositionService()
+	})
+	return globalReadingPositionService
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestReadingPositions(t *testing.T) {
+	newTestDB(t)
+	now := time.Now()
+	s := &ReadingPositionService{
+		db:  func() *gorm.DB { return database.DB },
+		now: func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	first := models.Article{Title: "First", CategoryID: 1, CreatedAt: now.Add(-2 * time.Hour)}
+	second := models.Article{Title: "Second", CategoryID: 1, CreatedAt: now.Add(-time.Hour)}
+	scheduled := models.Article{Title: "Scheduled", CategoryID: 1, CreatedAt: now.Add(time.Hour)}
+	for _, article := range []*models.Article{&first, &second, &scheduled} {
+		if err := database.DB.Create(article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.DB.Create(&models.UserReadingBehavior{UserID: "reader", ArticleID: first.ID, InteractionType: "view", ScrollDepth: 0.1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range []ReadingPositionInput{{Progress: -0.1}, {Progress: 1.5}, {Progress: 0.5, Language: "en us"}} {
+		if _, err := s.Save(ctx, "reader", first.ID, bad); !errors.Is(err, ErrInvalidReadingPosition) {
+			t.Errorf("Save(%+v) = %v, want ErrInvalidReadingPosition", bad, err)
+		}
+	}
+	if _, err := s.Save(ctx, "", first.ID, ReadingPositionInput{Progress: 0.5}); !errors.Is(err, ErrInvalidReadingPosition) {
+		t.Errorf("Save without a reader = %v", err)
+	}
+	if _, err := s.Save(ctx, "reader", scheduled.ID, ReadingPositionInput{Progress: 0.5}); !errors.Is(err, ErrArticleNotFound) {
+		t.Errorf("Save in a scheduled article = %v, want ErrArticleNotFound", err)
+	}
+
+	// Scrolling back keeps the furthest progress, which also corrects the
+	// tracked scroll depth
+	if _, err := s.Save(ctx, "reader", first.ID, ReadingPositionInput{Progress: 0.6, Anchor: "setup", Language: "en"}); err != nil {
+		t.Fatal(err)
+	}
+	position, err := s.Save(ctx, "reader", first.ID, ReadingPositionInput{Progress: 0.4, Anchor: "intro"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position.Progress != 0.4 || position.MaxProgress != 0.6 || position.Anchor != "intro" || position.Language != "en" {
+		t.Errorf("position = %+v", position)
+	}
+	var behavior models.UserReadingBehavior
+	database.DB.Where("user_id = ?", "reader").First(&behavior)
+	if behavior.ScrollDepth != 0.6 {
+		t.Errorf("tracked scroll depth = %v, want 0.6", behavior.ScrollDepth)
+	}
+
+	if _, err := s.Save(ctx, "reader", second.ID, ReadingPositionInput{Progress: 0.98}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Save(ctx, "someone else", second.ID, ReadingPositionInput{Progress: 0.3}); err != nil {
+		t.Fatal(err)
+	}
+	inProgress, err := s.InProgress(ctx, "reader", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inProgress) != 1 || inProgress[0].ArticleID != first.ID {
+		t.Errorf("in progress = %+v, want only the unfinished first article", inProgress)
+	}
+
+	if got, err := s.Get(ctx, "someone else", second.ID); err != nil || got.Progress != 0.3 {
+		t.Errorf("Get = %+v, %v", got, err)
+	}
+	if err := s.Delete(ctx, "reader", first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "reader", first.ID); !errors.Is(err, ErrReadingPositionNotFound) {
+		t.Errorf("Get after delete = %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"math"
+	"sort"
+)
+
+// Device types reported by the behavior tracker
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+)
+
+// deviceReadingMinutes are the reading lengths that suit each device when
+// the reader's history does not tell: short reads on phones, long-form on
+// desktops
+var deviceReadingMinutes = map[string]float64{
+	DeviceMobile:  5,
+	DeviceTablet:  8,
+	DeviceDesktop: 12,
+}
+
+const (
+	// readingWordsPerMinute converts the words counted by countWords to
+	// minutes of reading
+	readingWordsPerMinute = 250
+	// finishedScrollDepth is how far a reader scrolls through an article
+	// that counts as finished
+	finishedScrollDepth = 0.8
+	// minFinishedReads is how many finished reads on a device are needed
+	// before their length replaces the device's default
+	minFinishedReads = 3
+)
+
+// ValidDeviceType reports whether device is one the behavior tracker reports
+func ValidDeviceType(device string) bool {
+	_, ok := deviceReadingMinutes[device]
+	return ok
+}
+
+// readingMinutes estimates how long an article takes to read
+func readingMinutes(article *models.Article) float64 {
+	return math.Max(float64(countWords(article.Content))/readingWordsPerMinute, 1)
+}
+
+// preferredReadingMinutes returns the reading length that suits a reader on
+// a device: the median length of the articles they finished on it, once
+// there are enough of them, or else the device's default
+func (re *RecommendationEngine) preferredReadingMinutes(userID, device string) float64 {
+	fallback := deviceReadingMinutes[device]
+	if userID == "" {
+		return fallback
+	}
+	var articles []models.Article
+	err := database.DB.Model(&models.Article{}).Select("articles.id", "articles.content").
+		Joins("JOIN user_reading_behaviors ON user_reading_behaviors.article_id = articles.id").
+		Where("user_reading_behaviors.user_id = ? AND user_reading_behaviors.device_type = ?", userID, device).
+		Where("user_reading_behaviors.scroll_depth >= ?", finishedScrollDepth).
+		Distinct().Limit(50).Find(&articles).Error
+	if err != nil || len(articles) < minFinishedReads {
+		return fallback
+	}
+	minutes := make([]float64, len(articles))
+	for i := range articles {
+		minutes[i] = readingMinutes(&articles[i])
+	}
+	sort.Float64s(minutes)
+	if n := len(minutes); n%2 == 0 {
+		return (minutes[n/2-1] + minutes[n/2]) / 2
+	}
+	return minutes[len(minutes)/2]
+}
+
+// applyDeviceBias raises the confidence of recommendations close to the
+// reading length that suits the reader's device and lowers that of the
+// others, by at most a fifth either way. Four times longer or shorter than
+// preferred gets the full penalty.
+func (re *RecommendationEngine) applyDeviceBias(recommendations []RecommendationResult, options RecommendationOptions) {
+	preferred := re.preferredReadingMinutes(options.UserID, options.DeviceType)
+	for i := range recommendations {
+		distance := math.Abs(math.Log(readingMinutes(&recommendations[i].Article) / preferred))
+		factor := 1.2 - 0.4*math.Min(distance/math.Log(4), 1)
+		recommendations[i].Confidence = math.Min(recommendations[i].Confidence*factor, 1)
+	}
+}
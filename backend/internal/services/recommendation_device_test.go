@@ -0,0 +1,51 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestApplyDeviceBias(t *testing.T) {
+	newTestDB(t)
+	re := &RecommendationEngine{}
+	words := func(n int) string { return strings.TrimSpace(strings.Repeat("word ", n)) }
+
+	short := models.Article{ID: 1, Content: words(250 * 4)} // 4 minutes
+	long := models.Article{ID: 2, Content: words(250 * 20)} // 20 minutes
+	recommendations := func() []RecommendationResult {
+		return []RecommendationResult{{Article: short, Confidence: 0.5}, {Article: long, Confidence: 0.5}}
+	}
+
+	mobile := recommendations()
+	re.applyDeviceBias(mobile, RecommendationOptions{UserID: "reader", DeviceType: DeviceMobile})
+	if mobile[0].Confidence <= 0.5 || mobile[1].Confidence >= 0.5 {
+		t.Errorf("mobile confidences = %v, %v, want the short read first", mobile[0].Confidence, mobile[1].Confidence)
+	}
+	desktop := recommendations()
+	re.applyDeviceBias(desktop, RecommendationOptions{UserID: "reader", DeviceType: DeviceDesktop})
+	if desktop[1].Confidence <= desktop[0].Confidence {
+		t.Errorf("desktop confidences = %v, %v, want the long read first", desktop[0].Confidence, desktop[1].Confidence)
+	}
+
+	// A reader who finishes long articles on their phone gets long reads there
+	for i := 0; i < minFinishedReads; i++ {
+		article := models.Article{Title: "Long read", Content: words(250 * 25), CategoryID: 1}
+		if err := database.DB.Create(&article).Error; err != nil {
+			t.Fatal(err)
+		}
+		database.DB.Create(&models.UserReadingBehavior{UserID: "reader", ArticleID: article.ID, DeviceType: DeviceMobile, ScrollDepth: 0.9})
+	}
+	if minutes := re.preferredReadingMinutes("reader", DeviceMobile); minutes != 25 {
+		t.Errorf("preferred minutes = %v, want 25", minutes)
+	}
+	if minutes := re.preferredReadingMinutes("reader", DeviceTablet); minutes != deviceReadingMinutes[DeviceTablet] {
+		t.Errorf("tablet minutes = %v, want the default", minutes)
+	}
+	mobile = recommendations()
+	re.applyDeviceBias(mobile, RecommendationOptions{UserID: "reader", DeviceType: DeviceMobile})
+	if mobile[1].Confidence <= mobile[0].Confidence {
+		t.Errorf("confidences = %v, %v, want the long read first", mobile[0].Confidence, mobile[1].Confidence)
+	}
+}
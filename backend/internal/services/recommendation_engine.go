@@ -1,8 +1,10 @@
 package services
 
 import (
+	"blog-backend/internal/config"
 	"blog-backend/internal/database"
 	"blog-backend/internal/models"
+	"context"
 	"fmt"
 	"log"
 	"math"
@@ -54,19 +56,20 @@ type RecommendationOptions struct {
 	Categories    []string `json:"categories"`     // Filter by categories
 	MaxAge        int      `json:"max_age"`        // Maximum article age in days
 	Diversify     bool     `json:"diversify"`      // Ensure topic diversity
+	DeviceType    string   `json:"device_type"`    // Reader's device: desktop, mobile or tablet; empty when unknown
 }
 
 // NewRecommendationEngine creates a new recommendation engine
-func NewRecommendationEngine() *RecommendationEngine {
+func NewRecommendationEngine(embeddingService *EmbeddingService, behaviorTracker *BehaviorTracker, cache *SmartCache) *RecommendationEngine {
 	return &RecommendationEngine{
-		embeddingService: GetGlobalEmbeddingService(),
-		behaviorTracker:  GetGlobalBehaviorTracker(),
-		cache:            GetGlobalCache(),
+		embeddingService: embeddingService,
+		behaviorTracker:  behaviorTracker,
+		cache:            cache,
 	}
 }
 
 // GetPersonalizedRecommendations generates personalized recommendations for a user
-func (re *RecommendationEngine) GetPersonalizedRecommendations(options RecommendationOptions) ([]RecommendationResult, error) {
+func (re *RecommendationEngine) GetPersonalizedRecommendations(ctx context.Context, options RecommendationOptions) ([]RecommendationResult, error) {
 	if options.Limit <= 0 {
 		options.Limit = 10
 	}
@@ -80,18 +83,23 @@ func (re *RecommendationEngine) GetPersonalizedRecommendations(options Recommend
 	// Generate language-specific cache key
 	cacheKey := fmt.Sprintf("recommendations_%s_%s_%d_%t", options.UserID, options.Language, options.Limit, options.Diversify)
 
+	// Recommendations lean towards the reading length that suits the device
+	deviceAware := config.Get().Recommendations.DeviceAware && ValidDeviceType(options.DeviceType)
+	if deviceAware {
+		cacheKey += "_" + options.DeviceType
+	}
+
 	// Check cache first with extended TTL for recommendations
-	if cached, exists := re.cache.Get(cacheKey); exists {
-		if recommendations, ok := cached.([]RecommendationResult); ok {
-			log.Printf("🔄 Using cached recommendations for user %s (language: %s) - avoiding AI API calls", options.UserID, options.Language)
-			return recommendations, nil
-		}
+	var cached []RecommendationResult
+	if re.cache.Get(cacheKey, &cached) {
+		log.Printf("🔄 Using cached recommendations for user %s (language: %s) - avoiding AI API calls", options.UserID, options.Language)
+		return cached, nil
 	}
 
 	var allRecommendations []RecommendationResult
 
 	// 1. Content-based recommendations (based on reading history)
-	contentBased, err := re.getContentBasedRecommendations(options)
+	contentBased, err := re.getContentBasedRecommendations(ctx, options)
 	if err != nil {
 		log.Printf("Content-based recommendations failed: %v", err)
 	} else {
@@ -137,6 +145,10 @@ func (re *RecommendationEngine) GetPersonalizedRecommendations(options Recommend
 		}
 	}
 
+	if deviceAware {
+		re.applyDeviceBias(allRecommendations, options)
+	}
+
 	// Deduplicate and rank recommendations
 	recommendations := re.rankAndDeduplicateRecommendations(allRecommendations, options)
 
@@ -166,7 +178,7 @@ func (re *RecommendationEngine) GetPersonalizedRecommendations(options Recommend
 }
 
 // getContentBasedRecommendations generates recommendations based on user's reading history
-func (re *RecommendationEngine) getContentBasedRecommendations(options RecommendationOptions) ([]RecommendationResult, error) {
+func (re *RecommendationEngine) getContentBasedRecommendations(ctx context.Context, options RecommendationOptions) ([]RecommendationResult, error) {
 	// Get user's reading behavior - try user's language first, then fall back to any language
 	var behaviors []models.UserReadingBehavior
 
@@ -212,6 +224,22 @@ func (re *RecommendationEngine) getContentBasedRecommendations(options Recommend
 		}
 	}
 
+	// Interests are kept by the category's own name, and similar articles
+	// come with it translated
+	categoryNames := make(map[uint]string)
+	for _, behavior := range behaviors {
+		categoryNames[behavior.Article.CategoryID] = behavior.Article.Category.Name
+	}
+	categoryName := func(id uint) string {
+		if name, ok := categoryNames[id]; ok {
+			return name
+		}
+		var category models.Category
+		database.DB.Select("id", "name").Limit(1).Find(&category, id)
+		categoryNames[id] = category.Name
+		return category.Name
+	}
+
 	// Find similar articles using embeddings
 	for _, behavior := range behaviors {
 		if behavior.Article.ID == 0 {
@@ -229,6 +257,7 @@ func (re *RecommendationEngine) getContentBasedRecommendations(options Recommend
 			// Fallback to text-based search if embedding not found
 			log.Printf("⚠️ Falling back to text search for article %d: %v", behavior.Article.ID, err)
 			similar, err = re.embeddingService.SearchSimilarArticles(
+				ctx,
 				behavior.Article.Title+" "+behavior.Article.Summary,
 				options.Language,
 				5,
@@ -250,7 +279,7 @@ func (re *RecommendationEngine) getContentBasedRecommendations(options Recommend
 			confidence := result.Similarity
 
 			// Boost confidence if article is in user's preferred categories
-			if categoryScore, exists := userInterests.Categories[result.CategoryName]; exists {
+			if categoryScore, exists := userInterests.Categories[categoryName(result.CategoryID)]; exists {
 				confidence += categoryScore * 0.3
 			}
 
@@ -262,10 +291,11 @@ func (re *RecommendationEngine) getContentBasedRecommendations(options Recommend
 			if confidence >= options.MinConfidence {
 				recommendations = append(recommendations, RecommendationResult{
 					Article: models.Article{
-						ID:       result.ArticleID,
-						Title:    result.Title,
-						Summary:  result.Summary,
-						Category: models.Category{Name: result.CategoryName},
+						ID:         result.ArticleID,
+						Title:      result.Title,
+						Summary:    result.Summary,
+						CategoryID: result.CategoryID,
+						Category:   models.Category{ID: result.CategoryID, Name: result.CategoryName},
 					},
 					Confidence:         confidence,
 					ReasonType:         "similar_content",
@@ -591,11 +621,15 @@ func (re *RecommendationEngine) getSerendipityRecommendations(options Recommenda
 			}
 		}
 
+		// The reason names the category in the reader's language
+		category := article.Category
+		ApplyCategoryTranslation(&category, options.Language)
+
 		recommendations = append(recommendations, RecommendationResult{
 			Article:            article,
 			Confidence:         confidence,
 			ReasonType:         "discovery",
-			ReasonDetails:      re.generateDiscoveryReason(article.Category.Name, options.Language) + reasonSuffix,
+			ReasonDetails:      re.generateDiscoveryReason(category.Name, options.Language) + reasonSuffix,
 			RecommendationType: "serendipity",
 			Category:           "discovery",
 			IsLearningPath:     false,
@@ -769,11 +803,11 @@ func (re *RecommendationEngine) diversifyRecommendations(recommendations []Recom
 	}
 
 	var diversified []RecommendationResult
-	categoryCount := make(map[string]int)
+	categoryCount := make(map[uint]int)
 	typeCount := make(map[string]int)
 
 	for _, rec := range recommendations {
-		category := rec.Article.Category.Name
+		category := rec.Article.CategoryID
 		recType := rec.RecommendationType
 
 		// Limit per category and type to ensure diversity
@@ -901,11 +935,11 @@ func (re *RecommendationEngine) GenerateReadingPath(userID string, topic string,
 	// Get articles related to the topic in user's language or with translations
 	var articles []models.Article
 	query := database.DB.Preload("Category").Preload("Translations").
-		Where("title LIKE ? OR summary LIKE ?", "%"+topic+"%", "%"+topic+"%")
+		Where(database.Like("title LIKE ? OR summary LIKE ?"), "%"+topic+"%", "%"+topic+"%")
 
 	if language != "" {
 		// Prioritize articles in user's language or with any translation (relaxed conditions)
-		query = query.Where("(default_lang = ?) OR (EXISTS (SELECT 1 FROM article_translations WHERE article_translations.article_id = articles.id AND article_translations.language = ? AND (article_translations.title LIKE ? OR article_translations.summary LIKE ?)))",
+		query = query.Where(database.Like("(default_lang = ?) OR (EXISTS (SELECT 1 FROM article_translations WHERE article_translations.article_id = articles.id AND article_translations.language = ? AND (article_translations.title LIKE ? OR article_translations.summary LIKE ?)))"),
 			language, language, "%"+topic+"%", "%"+topic+"%")
 	}
 
@@ -955,6 +989,8 @@ func (re *RecommendationEngine) GenerateReadingPath(userID string, topic string,
 		difficulty = "advanced"
 	}
 
+	pathArticles = re.applyTranslationsToRecommendations(pathArticles, language)
+
 	path := &ReadingPath{
 		PathID:      fmt.Sprintf("path_%s_%s_%d", strings.ReplaceAll(topic, " ", "_"), language, time.Now().Unix()),
 		Title:       fmt.Sprintf("Learning Path: %s", strings.Title(topic)),
@@ -1056,15 +1092,11 @@ type RecommendationAnalytics struct {
 	AvgConfidence        float64        `json:"avg_confidence"`
 }
 
-// Global recommendation engine instance
-var globalRecommendationEngine *RecommendationEngine
-
-// GetGlobalRecommendationEngine returns the global recommendation engine instance
+// GetGlobalRecommendationEngine returns the recommendation engine of the
+// global container. It holds no state of its own; cached recommendations
+// live in the shared SmartCache.
 func GetGlobalRecommendationEngine() *RecommendationEngine {
-	if globalRecommendationEngine == nil {
-		globalRecommendationEngine = NewRecommendationEngine()
-	}
-	return globalRecommendationEngine
+	return GetGlobalContainer().RecommendationEngine
 }
 
 // Multilingual reason generators
@@ -1151,48 +1183,23 @@ func (re *RecommendationEngine) applyTranslationToArticle(article models.Article
 	return article
 }
 
-// applyTranslationToCategory applies translation to category if available for the target language
-func (re *RecommendationEngine) applyTranslationToCategory(category models.Category, targetLanguage string) models.Category {
-	// If category is already in target language, return as is
-	if category.DefaultLang == targetLanguage {
-		return category
-	}
-
-	// Look for translation in target language
-	for _, translation := range category.Translations {
-		if translation.Language == targetLanguage {
-			// Create a copy of the category with translated content
-			translatedCategory := category
-			translatedCategory.Name = translation.Name
-			translatedCategory.Description = translation.Description
-			return translatedCategory
-		}
-	}
-
-	// No translation found, return original category
-	return category
-}
-
 // applyTranslationsToRecommendations applies translations to all recommended articles and categories
 func (re *RecommendationEngine) applyTranslationsToRecommendations(recommendations []RecommendationResult, targetLanguage string) []RecommendationResult {
 	for i := range recommendations {
 		recommendations[i].Article = re.applyTranslationToArticle(recommendations[i].Article, targetLanguage)
-		recommendations[i].Article.Category = re.applyTranslationToCategory(recommendations[i].Article.Category, targetLanguage)
+		ApplyCategoryTranslation(&recommendations[i].Article.Category, targetLanguage)
 	}
 	return recommendations
 }
 
 // setRecommendationCache stores recommendations with extended TTL to reduce AI API costs
 func (re *RecommendationEngine) setRecommendationCache(key string, recommendations []RecommendationResult) {
-	// Use SQLite cache directly with 2-hour TTL for recommendations to minimize AI API calls
+	// Keep recommendations in the SQLite tier for 2 hours to minimize AI API calls
 	extendedTTL := time.Hour * 2
-	if err := re.cache.sqliteCache.Set(key, recommendations, &extendedTTL); err != nil {
+	if err := re.cache.SetWithTTL(key, recommendations, extendedTTL); err != nil {
 		log.Printf("Failed to cache recommendations with extended TTL: %v", err)
 	}
 	
-	// Also set in memory cache for faster access
-	re.cache.Set(key, recommendations)
-	
 	log.Printf("💾 Cached recommendations for key %s with 2-hour TTL to reduce AI API costs", key)
 }
 
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for page cursors this server did not issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor makes an opaque page cursor of the sort key of the last
+// item of a page
+func encodeCursor(key ...string) string {
+	data, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the sort key of a cursor made by encodeCursor with
+// n values
+func decodeCursor(cursor string, n int) ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var key []string
+	if err := json.Unmarshal(data, &key); err != nil || len(key) != n {
+		return nil, ErrInvalidCursor
+	}
+	return key, nil
+}
+
+// ArticleSummary is the part of an article that lists of recommendations
+// show, without its content and translations. WordCount stands in for the
+// content, for reading time estimates.
+type ArticleSummary struct {
+	ID            uint                  `json:"id"`
+	Title         string                `json:"title"`
+	Summary       string                `json:"summary"`
+	SEOSlug       string                `json:"seo_slug,omitempty"`
+	CoverImageURL *string               `json:"cover_image_url,omitempty"`
+	Category      *models.CategoryCrumb `json:"category,omitempty"`
+	ViewCount     uint                  `json:"view_count"`
+	WordCount     int                   `json:"word_count"`
+	CreatedAt     time.Time             `json:"created_at"`
+}
+
+// SummarizeArticle returns the summary of an article, in the language its
+// translation was applied in
+func SummarizeArticle(article models.Article) ArticleSummary {
+	summary := ArticleSummary{
+		ID:            article.ID,
+		Title:         article.Title,
+		Summary:       article.Summary,
+		SEOSlug:       article.SEOSlug,
+		CoverImageURL: article.CoverImageURL,
+		ViewCount:     article.ViewCount,
+		WordCount:     len(strings.Fields(article.Content)),
+		CreatedAt:     article.CreatedAt,
+	}
+	if article.Category.ID != 0 {
+		summary.Category = &models.CategoryCrumb{ID: article.Category.ID, Name: article.Category.Name}
+	}
+	return summary
+}
+
+// RecommendationSummary is a RecommendationResult with the summary of its
+// article
+type RecommendationSummary struct {
+	RecommendationResult
+	Article ArticleSummary `json:"article"`
+}
+
+// SummarizeRecommendations replaces the articles of recommendations with
+// their summaries
+func SummarizeRecommendations(recommendations []RecommendationResult) []RecommendationSummary {
+	summaries := make([]RecommendationSummary, len(recommendations))
+	for i, recommendation := range recommendations {
+		summaries[i] = RecommendationSummary{RecommendationResult: recommendation, Article: SummarizeArticle(recommendation.Article)}
+	}
+	return summaries
+}
+
+// BehaviorSummary is a tracked reading behavior with the title of its
+// article instead of the whole article
+type BehaviorSummary struct {
+	ID              uint      `json:"id"`
+	ArticleID       uint      `json:"article_id"`
+	ArticleTitle    string    `json:"article_title"`
+	SessionID       string    `json:"session_id"`
+	InteractionType string    `json:"interaction_type"`
+	ReadingTime     int       `json:"reading_time"`
+	ScrollDepth     float64   `json:"scroll_depth"`
+	DeviceType      string    `json:"device_type"`
+	Language        string    `json:"language"`
+	ReferrerType    string    `json:"referrer_type"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ListBehaviors returns a page of a user's tracked behaviors, newest first,
+// and the cursor of the next page, empty after the last one
+func (bt *BehaviorTracker) ListBehaviors(userID, cursor string, limit int) ([]BehaviorSummary, string, error) {
+	query := database.DB.Table("user_reading_behaviors AS b").
+		Select("b.id, b.article_id, a.title AS article_title, b.session_id, b.interaction_type, b.reading_time, "+
+			"b.scroll_depth, b.device_type, b.language, b.referrer_type, b.created_at").
+		Joins("LEFT JOIN articles AS a ON a.id = b.article_id").
+		Where("b.user_id = ?", userID).
+		Order("b.id DESC").
+		Limit(limit + 1)
+	if cursor != "" {
+		key, err := decodeCursor(cursor, 1)
+		if err != nil {
+			return nil, "", err
+		}
+		var lastID uint
+		if _, err := fmt.Sscan(key[0], &lastID); err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		query = query.Where("b.id < ?", lastID)
+	}
+
+	behaviors := []BehaviorSummary{}
+	if err := query.Scan(&behaviors).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to list behaviors: %w", err)
+	}
+	if len(behaviors) <= limit {
+		return behaviors, "", nil
+	}
+	behaviors = behaviors[:limit]
+	return behaviors, encodeCursor(fmt.Sprint(behaviors[limit-1].ID)), nil
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecommendationPages(t *testing.T) {
+	newTestDB(t)
+	bt := NewBehaviorTracker(NewSmartCache(DefaultCacheConfig()))
+	t.Cleanup(bt.Stop)
+
+	article := models.Article{Title: "First", Content: "one two three", CategoryID: 1}
+	if err := database.DB.Create(&article).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Readers b and c were last active at the same time, and b read in two
+	// languages
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, behavior := range []models.UserReadingBehavior{
+		{UserID: "a", Language: "en", CreatedAt: now},
+		{UserID: "c", Language: "en", CreatedAt: now.Add(-time.Hour)},
+		{UserID: "b", Language: "zh", CreatedAt: now.Add(-time.Hour)},
+		{UserID: "b", Language: "en", CreatedAt: now.Add(-time.Hour)},
+		{UserID: "a", Language: "en", CreatedAt: now.Add(-2 * time.Hour)},
+		{UserID: "d", Language: "en", CreatedAt: now.Add(-3 * time.Hour)},
+	} {
+		behavior.ArticleID = article.ID
+		behavior.InteractionType = "view"
+		if err := database.DB.Create(&behavior).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var seen []string
+	cursor := ""
+	for page := 0; ; page++ {
+		users, next, err := bt.GetRecentUsers(2, 0, 7, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, user := range users {
+			seen = append(seen, user.UserID+"/"+user.Language)
+		}
+		if next == "" {
+			break
+		}
+		if page > 3 {
+			t.Fatal("the pages do not end")
+		}
+		cursor = next
+	}
+	want := []string{"a/en", "b/en", "b/zh", "c/en", "d/en"}
+	if len(seen) != len(want) {
+		t.Fatalf("pages = %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("pages = %v, want %v", seen, want)
+		}
+	}
+
+	behaviors, next, err := bt.ListBehaviors("a", "", 1)
+	if err != nil || len(behaviors) != 1 || behaviors[0].ArticleTitle != "First" || next == "" {
+		t.Fatalf("ListBehaviors() = %+v, %q, %v", behaviors, next, err)
+	}
+	older, next, err := bt.ListBehaviors("a", next, 1)
+	if err != nil || len(older) != 1 || older[0].ID >= behaviors[0].ID || next != "" {
+		t.Errorf("ListBehaviors() after the cursor = %+v, %q, %v", older, next, err)
+	}
+	for _, bad := range []string{"!", encodeCursor("1", "2")} {
+		if _, _, err := bt.ListBehaviors("a", bad, 1); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("ListBehaviors(%q) = %v, want ErrInvalidCursor", bad, err)
+		}
+	}
+
+	summary := SummarizeArticle(article)
+	if summary.WordCount != 3 || summary.Category != nil {
+		t.Errorf("SummarizeArticle() = %+v", summary)
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Region rules of an article, see models.Article.RegionRule
+const (
+	RegionRuleBlock = "block"
+	RegionRuleAllow = "allow"
+)
+
+// Reasons an article is withheld from a visitor
+const (
+	WithheldRegionBlocked    = "region_blocked"
+	WithheldRegionNotAllowed = "region_not_allowed"
+)
+
+var ErrInvalidRegionRule = errors.New("invalid region rule")
+
+// NormalizeRegionRule checks a region rule and its comma-separated country
+// codes and returns them as stored: the codes uppercased, without
+// duplicates. An empty rule clears the regions.
+func NormalizeRegionRule(rule, regions string) (string, string, error) {
+	rule = strings.ToLower(strings.TrimSpace(rule))
+	switch rule {
+	case "":
+		return "", "", nil
+	case RegionRuleBlock, RegionRuleAllow:
+	default:
+		return "", "", fmt.Errorf("%w: unknown rule %q, use block or allow", ErrInvalidRegionRule, rule)
+	}
+	var codes []string
+	seen := map[string]bool{}
+	for _, code := range strings.Split(regions, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		if !isCountryCode(code) {
+			return "", "", fmt.Errorf("%w: %q is not a two-letter country code", ErrInvalidRegionRule, code)
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return "", "", fmt.Errorf("%w: a %s rule needs at least one country", ErrInvalidRegionRule, rule)
+	}
+	return rule, strings.Join(codes, ","), nil
+}
+
+// RegionWithheld tells why an article is not served to a visitor from
+// country, an ISO 3166-1 alpha-2 code or empty when not known, and returns
+// "" when it is served. Visitors whose country is not known are served
+// articles blocked elsewhere but not those allowed only in some regions.
+func RegionWithheld(article *models.Article, country string) string {
+	listed := false
+	for _, region := range article.RegionList() {
+		if region == country {
+			listed = true
+			break
+		}
+	}
+	switch article.RegionRule {
+	case RegionRuleBlock:
+		if listed {
+			return WithheldRegionBlocked
+		}
+	case RegionRuleAllow:
+		if !listed {
+			return WithheldRegionNotAllowed
+		}
+	}
+	return ""
+}
+
+// CountryCode returns the ISO 3166-1 alpha-2 code a GeoIP lookup or a CDN
+// header gives, or "" for anything else, such as Unknown or Local
+func CountryCode(country string) string {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	// XX and T1 are what CDNs send for unknown addresses and Tor
+	if !isCountryCode(country) || country == "XX" || country == "T1" {
+		return ""
+	}
+	return country
+}
+
+func isCountryCode(code string) bool {
+	return len(code) == 2 && code[0] >= 'A' && code[0] <= 'Z' && code[1] >= 'A' && code[1] <= 'Z'
+}
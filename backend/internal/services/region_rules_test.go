@@ -0,0 +1,53 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"errors"
+	"testing"
+)
+
+func TestNormalizeRegionRule(t *testing.T) {
+	rule, regions, err := NormalizeRegionRule(" Block ", "cn, us,CN,,de")
+	if err != nil || rule != RegionRuleBlock || regions != "CN,US,DE" {
+		t.Errorf("NormalizeRegionRule = %q, %q, %v", rule, regions, err)
+	}
+	if rule, regions, err := NormalizeRegionRule("", "CN"); err != nil || rule != "" || regions != "" {
+		t.Errorf("an empty rule = %q, %q, %v", rule, regions, err)
+	}
+	for _, bad := range [][2]string{{"hide", "CN"}, {"allow", ""}, {"allow", "China"}, {"block", "C1"}} {
+		if _, _, err := NormalizeRegionRule(bad[0], bad[1]); !errors.Is(err, ErrInvalidRegionRule) {
+			t.Errorf("%v: err = %v, want ErrInvalidRegionRule", bad, err)
+		}
+	}
+}
+
+func TestRegionWithheld(t *testing.T) {
+	blocked := &models.Article{RegionRule: RegionRuleBlock, Regions: "CN,RU"}
+	allowed := &models.Article{RegionRule: RegionRuleAllow, Regions: "DE,AT"}
+	open := &models.Article{}
+	for _, tc := range []struct {
+		article *models.Article
+		country string
+		want    string
+	}{
+		{blocked, "CN", WithheldRegionBlocked},
+		{blocked, "US", ""},
+		{blocked, "", ""},
+		{allowed, "DE", ""},
+		{allowed, "US", WithheldRegionNotAllowed},
+		{allowed, "", WithheldRegionNotAllowed},
+		{open, "CN", ""},
+	} {
+		if got := RegionWithheld(tc.article, tc.country); got != tc.want {
+			t.Errorf("%s %s from %q = %q, want %q", tc.article.RegionRule, tc.article.Regions, tc.country, got, tc.want)
+		}
+	}
+}
+
+func TestCountryCode(t *testing.T) {
+	for country, want := range map[string]string{"de": "DE", " US ": "US", "Unknown": "", "Local": "", "XX": "", "T1": "", "": ""} {
+		if got := CountryCode(country); got != want {
+			t.Errorf("CountryCode(%q) = %q, want %q", country, got, want)
+		}
+	}
+}
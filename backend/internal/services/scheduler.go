@@ -0,0 +1,429 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/cron"
+	"blog-backend/internal/models"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrScheduleBusy     = errors.New("schedule already has a queued or running job")
+)
+
+// Schedule is a periodic task. Each time the cron expression matches, the
+// scheduler queues a job of JobType, so runs get the job queue's retries
+// and their results are kept in the job history.
+type Schedule struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Cron        string      `json:"cron"`
+	JobType     string      `json:"job_type"`
+	Payload     interface{} `json:"-"`
+}
+
+// ScheduleStatus is a schedule with its next run time and most recent job
+type ScheduleStatus struct {
+	Schedule
+	NextRun *time.Time  `json:"next_run,omitempty"`
+	LastRun *models.Job `json:"last_run,omitempty"`
+}
+
+// scheduledRun is a schedule that is due, with the time it was due at
+type scheduledRun struct {
+	Schedule
+	at time.Time
+}
+
+type scheduleEntry struct {
+	Schedule
+	cron *cron.Schedule
+	next time.Time
+}
+
+// Scheduler queues jobs for registered schedules. A run is skipped while
+// the previous job of the same schedule is still pending or running. Every
+// instance runs a scheduler; each run is queued with a unique key derived
+// from its schedule and time, so it is queued once however many replicas
+// fire it.
+type Scheduler struct {
+	queue *JobQueue
+
+	mu      sync.Mutex
+	entries map[string]*scheduleEntry
+	wake    chan struct{}
+	started bool
+}
+
+// NewScheduler creates a scheduler that queues jobs on queue
+func NewScheduler(queue *JobQueue) *Scheduler {
+	return &Scheduler{
+		queue:   queue,
+		entries: make(map[string]*scheduleEntry),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add registers a schedule, replacing any schedule with the same name
+func (s *Scheduler) Add(schedule Schedule) error {
+	parsed, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return fmt.Errorf("schedule %s: %w", schedule.Name, err)
+	}
+
+	s.mu.Lock()
+	s.entries[schedule.Name] = &scheduleEntry{
+		Schedule: schedule,
+		cron:     parsed,
+		next:     parsed.Next(time.Now()),
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Start runs the scheduling loop until shutdown
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return
+	}
+	s.started = true
+	count := len(s.entries)
+	s.mu.Unlock()
+
+	go s.loop()
+	slog.Info("Scheduler started", "schedules", count)
+}
+
+func (s *Scheduler) loop() {
+	for {
+		due, next := s.due(time.Now())
+		for _, run := range due {
+			s.fire(run)
+		}
+
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		case <-ShuttingDown():
+			timer.Stop()
+			return
+		}
+		timer.Stop()
+	}
+}
+
+// due advances the schedules whose run time has passed and returns them,
+// along with the earliest upcoming run
+func (s *Scheduler) due(now time.Time) ([]scheduledRun, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []scheduledRun
+	var next time.Time
+	for _, entry := range s.entries {
+		if entry.next.IsZero() {
+			continue
+		}
+		if !entry.next.After(now) {
+			due = append(due, scheduledRun{Schedule: entry.Schedule, at: entry.next})
+			entry.next = entry.cron.Next(now)
+			if entry.next.IsZero() {
+				continue
+			}
+		}
+		if next.IsZero() || entry.next.Before(next) {
+			next = entry.next
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Name < due[j].Name })
+	return due, next
+}
+
+// fire queues a run of schedule unless its previous run has not finished
+// or another instance already queued it
+func (s *Scheduler) fire(run scheduledRun) {
+	key := fmt.Sprintf("schedule:%s:%d", run.Name, run.at.Unix())
+	if _, err := s.enqueue(run.Schedule, key); err != nil {
+		switch {
+		case errors.Is(err, ErrJobExists):
+			slog.Debug("Scheduled run already queued by another instance", "schedule", run.Name)
+		case errors.Is(err, ErrScheduleBusy):
+			slog.Warn("Skipping scheduled run, previous run has not finished", "schedule", run.Name)
+		default:
+			slog.Error("Failed to queue scheduled run", "schedule", run.Name, "error", err)
+		}
+	}
+}
+
+func (s *Scheduler) enqueue(schedule Schedule, key string) (*models.Job, error) {
+	active, err := s.queue.Active(schedule.JobType)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, ErrScheduleBusy
+	}
+	if key != "" {
+		return s.queue.EnqueueUnique(schedule.JobType, schedule.Payload, key)
+	}
+	return s.queue.Enqueue(schedule.JobType, schedule.Payload)
+}
+
+// Trigger queues a run of the named schedule now
+func (s *Scheduler) Trigger(name string) (*models.Job, error) {
+	s.mu.Lock()
+	entry, ok := s.entries[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+	return s.enqueue(entry.Schedule, "")
+}
+
+// NextRun returns when the named schedule runs next, or nil if it is not
+// registered or never matches
+func (s *Scheduler) NextRun(name string) *time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[name]
+	if !ok || entry.next.IsZero() {
+		return nil
+	}
+	next := entry.next
+	return &next
+}
+
+// List returns every schedule with its next and last run, sorted by name
+func (s *Scheduler) List() ([]ScheduleStatus, error) {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+	sort.Strings(names)
+
+	statuses := make([]ScheduleStatus, 0, len(names))
+	for _, name := range names {
+		status, err := s.Get(name)
+		if errors.Is(err, ErrScheduleNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// Get returns the named schedule with its next and last run
+func (s *Scheduler) Get(name string) (*ScheduleStatus, error) {
+	s.mu.Lock()
+	entry, ok := s.entries[name]
+	var status ScheduleStatus
+	if ok {
+		status.Schedule = entry.Schedule
+		if !entry.next.IsZero() {
+			next := entry.next
+			status.NextRun = &next
+		}
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+
+	last, err := s.queue.Latest(status.JobType)
+	if err != nil {
+		return nil, err
+	}
+	status.LastRun = last
+	return &status, nil
+}
+
+// registerBuiltinSchedules adds the periodic tasks implemented in this package
+func (s *Scheduler) registerBuiltinSchedules() {
+	schedules := []Schedule{
+		{
+			Name:        "user-profiles",
+			Description: "Recompute reading profiles of users active in the last day",
+			Cron:        "15 * * * *",
+			JobType:     JobUpdateProfiles,
+		},
+		{
+			Name:        "seo-site-audit",
+			Description: "Run the site-wide SEO health check",
+			Cron:        "0 2 * * *",
+			JobType:     JobSEOSiteAudit,
+		},
+		{
+			Name:        ContentQualityScheduleName,
+			Description: "Score the content quality and originality of every article",
+			Cron:        "30 2 * * *",
+			JobType:     JobContentQuality,
+		},
+		{
+			Name:        WritingSuggestionsScheduleName,
+			Description: "Suggest topics from unanswered searches, uncovered keywords and thin categories",
+			Cron:        "45 2 * * *",
+			JobType:     JobWritingSuggestions,
+		},
+		{
+			Name:        "storage-snapshot",
+			Description: "Record database and upload sizes for the disk usage trend",
+			Cron:        "30 0 * * *",
+			JobType:     JobStorageSnapshot,
+		},
+		{
+			Name:        "pins-expire",
+			Description: "Unpin articles whose pin has ended",
+			Cron:        "* * * * *",
+			JobType:     JobExpirePins,
+		},
+		{
+			Name:        "article-views-recount",
+			Description: "Reconcile article view counts with the recorded views",
+			Cron:        "20 * * * *",
+			JobType:     JobRecountViews,
+		},
+		{
+			Name:        "topic-centroids",
+			Description: "Recompute the embedding centroids of categories and tags for topic pages",
+			Cron:        "10 3 * * *",
+			JobType:     JobTopicCentroids,
+		},
+		{
+			Name:        "api-key-usage-purge",
+			Description: "Delete the hourly usage of API keys past its retention",
+			Cron:        "45 3 * * *",
+			JobType:     JobAPIKeyUsagePurge,
+		},
+	}
+	if schedule := config.Get().Database.OptimizeSchedule; schedule != "" {
+		schedules = append(schedules, Schedule{
+			Name:        OptimizeScheduleName,
+			Description: "Check database integrity, refresh planner statistics and reclaim free space",
+			Cron:        schedule,
+			JobType:     JobDatabaseOptimize,
+		})
+	}
+	if schedule := config.Get().Monitor.Schedule; schedule != "" && GetGlobalSiteMonitor().Enabled() {
+		schedules = append(schedules, Schedule{
+			Name:        MonitorScheduleName,
+			Description: "Check that the public site is reachable and its TLS certificate is not expiring",
+			Cron:        schedule,
+			JobType:     JobSiteMonitor,
+		})
+	}
+	if schedule := config.Get().Newsletter.DigestSchedule; schedule != "" {
+		schedules = append(schedules, Schedule{
+			Name:        NewsletterDigestScheduleName,
+			Description: "Email subscribers a digest of the articles published since the last one",
+			Cron:        schedule,
+			JobType:     JobNewsletterDigest,
+		})
+	}
+	if schedule := config.Get().Newsletter.AIDigestSchedule; schedule != "" {
+		schedules = append(schedules, Schedule{
+			Name:        NewsletterAIDigestScheduleName,
+			Description: "Draft an AI-written digest of the period's articles for review",
+			Cron:        schedule,
+			JobType:     JobNewsletterAIDigest,
+		})
+	}
+	if schedule := config.Get().FriendLinks.CheckSchedule; schedule != "" {
+		schedules = append(schedules, Schedule{
+			Name:        FriendLinksScheduleName,
+			Description: "Check that friends' sites still link back to the blog",
+			Cron:        schedule,
+			JobType:     JobFriendLinksCheck,
+		})
+	}
+	if schedule := config.Get().FeedImport.Schedule; schedule != "" {
+		schedules = append(schedules, Schedule{
+			Name:        FeedImportScheduleName,
+			Description: "Stage the new posts of followed RSS and Atom feeds as drafts",
+			Cron:        schedule,
+			JobType:     JobFeedImport,
+		})
+	}
+	if cfg := config.Get().KeywordData; cfg.Schedule != "" && GetGlobalKeywordDataService().Enabled() {
+		schedules = append(schedules, Schedule{
+			Name:        KeywordDataScheduleName,
+			Description: "Refresh the search volume and difficulty of SEO keywords with stale data",
+			Cron:        cfg.Schedule,
+			JobType:     JobSEOKeywordData,
+		})
+	}
+	if schedule := config.Get().ShareCounts.Schedule; schedule != "" && GetGlobalShareCountService().Enabled() {
+		schedules = append(schedules, Schedule{
+			Name:        ShareCountsScheduleName,
+			Description: "Collect how often recent articles were shared on social platforms",
+			Cron:        schedule,
+			JobType:     JobShareCounts,
+		})
+	}
+	if schedule := config.Get().CommentBridge.Schedule; schedule != "" && GetGlobalCommentBridgeService().Enabled() {
+		schedules = append(schedules, Schedule{
+			Name:        CommentBridgeScheduleName,
+			Description: "Sync the comment counts of articles from the external comment system",
+			Cron:        schedule,
+			JobType:     JobCommentBridgeSync,
+		})
+	}
+	if schedule := config.Get().Notifications.DigestSchedule; schedule != "" {
+		schedules = append(schedules, Schedule{
+			Name:        NotificationDigestScheduleName,
+			Description: "Email a digest of unread admin notifications",
+			Cron:        schedule,
+			JobType:     JobNotificationDigest,
+		})
+	}
+	if cfg := config.Get().Backup; cfg.Schedule != "" {
+		schedules = append(schedules, Schedule{
+			Name:        BackupScheduleName,
+			Description: "Create a backup, copy it off-site and apply retention",
+			Cron:        cfg.Schedule,
+			JobType:     JobBackupScheduled,
+		})
+	}
+
+	for _, schedule := range schedules {
+		if err := s.Add(schedule); err != nil {
+			slog.Error("Invalid schedule, task disabled", "schedule", schedule.Name, "error", err)
+		}
+	}
+}
+
+var (
+	globalScheduler *Scheduler
+	schedulerOnce   sync.Once
+)
+
+// GetGlobalScheduler returns the global scheduler
+func GetGlobalScheduler() *Scheduler {
+	schedulerOnce.Do(func() {
+		globalScheduler = NewScheduler(GetGlobalJobQueue())
+		globalScheduler.registerBuiltinSchedules()
+	})
+	return globalScheduler
+}
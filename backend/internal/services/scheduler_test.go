@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedulerDueAdvancesSchedules(t *testing.T) {
+	s := NewScheduler(nil)
+	if err := s.Add(Schedule{Name: "bad", Cron: "61 * * * *"}); err == nil {
+		t.Error("Add accepted an invalid cron expression")
+	}
+	s.Add(Schedule{Name: "hourly", Cron: "0 * * * *", JobType: "test.hourly"})
+	s.Add(Schedule{Name: "daily", Cron: "0 3 * * *", JobType: "test.daily"})
+
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, time.Local)
+	s.entries["hourly"].next = now.Add(-time.Minute)
+	s.entries["daily"].next = time.Date(2024, 5, 2, 3, 0, 0, 0, time.Local)
+
+	due, next := s.due(now)
+	if len(due) != 1 || due[0].Name != "hourly" {
+		t.Fatalf("due = %+v", due)
+	}
+	if want := time.Date(2024, 5, 1, 11, 0, 0, 0, time.Local); !next.Equal(want) {
+		t.Errorf("next = %s, want %s", next, want)
+	}
+	if due, _ := s.due(now); len(due) != 0 {
+		t.Errorf("schedule fired twice for the same time: %+v", due)
+	}
+}
+
+func TestSchedulerTriggerSkipsBusySchedule(t *testing.T) {
+	q := setupJobQueueTest(t)
+	q.Register("test.task", func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return "done", nil
+	})
+	s := NewScheduler(q)
+	s.Add(Schedule{Name: "task", Cron: "0 * * * *", JobType: "test.task"})
+
+	if _, err := s.Trigger("missing"); !errors.Is(err, ErrScheduleNotFound) {
+		t.Errorf("Trigger of unknown schedule error = %v", err)
+	}
+	status, err := s.Get("task")
+	if err != nil || status.LastRun != nil || status.NextRun == nil {
+		t.Fatalf("Get before any run = %+v, %v", status, err)
+	}
+
+	job, err := s.Trigger("task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Trigger("task"); !errors.Is(err, ErrScheduleBusy) {
+		t.Errorf("second Trigger while pending error = %v", err)
+	}
+
+	runNext(t, q)
+	status, _ = s.Get("task")
+	if status.LastRun == nil || status.LastRun.ID != job.ID || status.LastRun.Result != `"done"` {
+		t.Fatalf("last run = %+v", status.LastRun)
+	}
+	if _, err := s.Trigger("task"); err != nil {
+		t.Errorf("Trigger after the run finished error = %v", err)
+	}
+}
+
+func TestSchedulerQueuesEachRunOnceAcrossInstances(t *testing.T) {
+	q := setupJobQueueTest(t)
+	q.Register("test.task", func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		return nil, nil
+	})
+	first, second := NewScheduler(q), NewScheduler(q)
+	run := scheduledRun{
+		Schedule: Schedule{Name: "task", Cron: "0 * * * *", JobType: "test.task"},
+		at:       time.Date(2024, 5, 1, 11, 0, 0, 0, time.Local),
+	}
+
+	first.fire(run)
+	runNext(t, q) // finished before the second instance fires
+	second.fire(run)
+
+	_, total, err := q.List(JobFilter{Type: "test.task"})
+	if err != nil || total != 1 {
+		t.Fatalf("queued %d jobs for one scheduled run (err %v), want 1", total, err)
+	}
+
+	run.at = run.at.Add(time.Hour)
+	second.fire(run)
+	if _, total, _ := q.List(JobFilter{Type: "test.task"}); total != 2 {
+		t.Errorf("next run was not queued, %d jobs", total)
+	}
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of search index tracked in models.SearchIndex. Keyword search
+// queries the article tables directly; its entry counts the documents it
+// searches.
+const (
+	SearchIndexKeyword   = "keyword"
+	SearchIndexEmbedding = "embedding"
+	// searchIndexAll is the language of the totals over every language
+	searchIndexAll = "all"
+	// searchIndexShadow is the table a rebuild writes the new vector index
+	// to while searches keep using the current one
+	searchIndexShadow = "article_embeddings_rebuild"
+)
+
+// searchIndexColumns are the columns of an article embedding copied from
+// the shadow table, leaving the live table to number the rows
+const searchIndexColumns = "article_id, content_type, language, provider, model, embedding, dimensions, content_hash, token_count, created_at, updated_at"
+
+var ErrSearchRebuildRunning = errors.New("a search index rebuild is already running")
+
+// SearchIndexRebuild reports the outcome of a rebuild
+type SearchIndexRebuild struct {
+	Articles int `json:"articles"`
+	// Documents is the number of embeddings in the new index: Embedded of
+	// them were generated, Reused copied because their text and model did
+	// not change and Kept left from the old index as their text could not
+	// be embedded
+	Documents int `json:"documents"`
+	Embedded  int `json:"embedded"`
+	Reused    int `json:"reused"`
+	Kept      int `json:"kept"`
+	Failed    int `json:"failed"`
+	// Newer counts the embeddings saved while the rebuild ran, which are
+	// kept over the rebuilt embeddings of the same text
+	Newer int `json:"newer"`
+	// Dropped counts the embeddings of the old index that matched no
+	// current text
+	Dropped  int    `json:"dropped"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+// searchDocument is one text of an article that the vector index embeds
+type searchDocument struct {
+	contentType string
+	language    string
+	text        string
+	hash        string
+}
+
+// SearchIndexService rebuilds the search indexes without taking search
+// down. The vector index is written to a shadow table, reusing the
+// embeddings whose text and model did not change, and replaces the live
+// index in one transaction once complete, so searches see either the old
+// index or the new one. Embeddings saved to the live index while the
+// rebuild runs, for articles edited meanwhile, are merged in rather than
+// lost. Keyword search has no index of its own to rebuild, as it queries
+// the article tables; index statistics are counted from the tables rather
+// than kept up to date by hand.
+type SearchIndexService struct {
+	db    func() *gorm.DB
+	now   func() time.Time
+	model func() (string, string)
+	embed func(ctx context.Context, text string) ([]float64, error)
+}
+
+// NewSearchIndexService creates a search index service using the default
+// embedding provider
+func NewSearchIndexService() *SearchIndexService {
+	return &SearchIndexService{
+		db:    func() *gorm.DB { return database.DB },
+		now:   time.Now,
+		model: func() (string, string) { return GetGlobalEmbeddingService().DefaultModel() },
+		embed: func(ctx context.Context, text string) ([]float64, error) {
+			return GetGlobalEmbeddingService().EmbedText(ctx, text, "search_index_rebuild")
+		},
+	}
+}
+
+// Start queues a rebuild unless one is already queued or running
+func (s *SearchIndexService) Start() (*models.Job, error) {
+	queue := GetGlobalJobQueue()
+	active, err := queue.Active(JobSearchRebuild)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, ErrSearchRebuildRunning
+	}
+	return queue.Enqueue(JobSearchRebuild, nil)
+}
+
+// Status returns the index statistics and the latest rebuild job, if any
+func (s *SearchIndexService) Status() ([]models.SearchIndex, *models.Job, error) {
+	var indexes []models.SearchIndex
+	if err := s.db().Order("index_type, language").Find(&indexes).Error; err != nil {
+		return nil, nil, err
+	}
+	job, err := GetGlobalJobQueue().Latest(JobSearchRebuild)
+	return indexes, job, err
+}
+
+// Rebuild rebuilds the vector index of every article and language in the
+// shadow table and swaps it in. When texts needed embedding and none could
+// be, the live index is left as it was.
+func (s *SearchIndexService) Rebuild(ctx context.Context) (*SearchIndexRebuild, error) {
+	provider, model := s.model()
+	report := &SearchIndexRebuild{Provider: provider, Model: model}
+	db := s.db()
+
+	if err := db.Exec("DROP TABLE IF EXISTS " + searchIndexShadow).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Exec("CREATE TABLE " + searchIndexShadow + " AS SELECT * FROM article_embeddings WHERE 1 = 0").Error; err != nil {
+		return nil, fmt.Errorf("failed to create the shadow index: %w", err)
+	}
+	defer db.Exec("DROP TABLE IF EXISTS " + searchIndexShadow)
+
+	// Embeddings numbered after the last one are saved during the rebuild
+	var lastID uint
+	if err := db.Model(&models.ArticleEmbedding{}).Select("COALESCE(MAX(id), 0)").Scan(&lastID).Error; err != nil {
+		return nil, err
+	}
+
+	var articleIDs []uint
+	if err := db.Model(&models.Article{}).Order("id").Pluck("id", &articleIDs).Error; err != nil {
+		return nil, err
+	}
+	report.Articles = len(articleIDs)
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(articleIDs))
+
+	for _, articleID := range articleIDs {
+		if IsShuttingDown() {
+			return nil, errors.New("search index rebuild interrupted by shutdown")
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var article models.Article
+		if err := db.Preload("Translations").Limit(1).Find(&article, articleID).Error; err != nil {
+			return nil, err
+		}
+		if article.ID == 0 {
+			progress.Advance(1)
+			continue
+		}
+		progress.Step("Indexing article %d: %s", article.ID, article.Title)
+		if err := s.rebuildArticle(ctx, &article, provider, model, report); err != nil {
+			progress.ItemFailed(fmt.Sprintf("article %d", article.ID), err)
+			continue
+		}
+		progress.Advance(1)
+	}
+	if report.Failed > 0 && report.Embedded == 0 && report.Reused == 0 {
+		return nil, fmt.Errorf("no text could be embedded with %s, the index was left as it was", provider)
+	}
+
+	progress.Step("Swapping in the new index")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		removed := tx.Exec("DELETE FROM article_embeddings WHERE id <= ?", lastID)
+		if removed.Error != nil {
+			return removed.Error
+		}
+		var newer int64
+		if err := tx.Model(&models.ArticleEmbedding{}).Count(&newer).Error; err != nil {
+			return err
+		}
+		// Articles deleted while the index was rebuilt are left out, and
+		// texts embedded again meanwhile keep their newer embedding
+		copied := tx.Exec("INSERT INTO article_embeddings (" + searchIndexColumns + ") SELECT " + searchIndexColumns +
+			" FROM " + searchIndexShadow + " AS rebuilt WHERE article_id IN (SELECT id FROM articles)" +
+			" AND NOT EXISTS (SELECT 1 FROM article_embeddings AS live WHERE live.article_id = rebuilt.article_id" +
+			" AND live.language = rebuilt.language AND live.content_type = rebuilt.content_type)")
+		if copied.Error != nil {
+			return copied.Error
+		}
+		report.Newer = int(newer)
+		report.Documents = int(copied.RowsAffected) + report.Newer
+		report.Dropped = max(int(removed.RowsAffected)-report.Reused-report.Kept, 0)
+		return s.recordStats(tx, true)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to swap in the new index: %w", err)
+	}
+	return report, nil
+}
+
+// rebuildArticle writes the embeddings of an article's texts to the shadow
+// table. A text that fails to embed keeps its old embedding, if it had one.
+func (s *SearchIndexService) rebuildArticle(ctx context.Context, article *models.Article, provider, model string, report *SearchIndexRebuild) error {
+	var existing []models.ArticleEmbedding
+	if err := s.db().Where("article_id = ?", article.ID).Order("id DESC").Find(&existing).Error; err != nil {
+		return err
+	}
+
+	var failed error
+	for _, document := range searchDocuments(article) {
+		var reuse, previous *models.ArticleEmbedding
+		for i := range existing {
+			candidate := &existing[i]
+			if candidate.ContentType != document.contentType || candidate.Language != document.language {
+				continue
+			}
+			if previous == nil {
+				previous = candidate
+			}
+			if candidate.ContentHash == document.hash && candidate.Provider == provider && candidate.Model == model {
+				reuse = candidate
+				break
+			}
+		}
+
+		row := reuse
+		if row != nil {
+			report.Reused++
+		} else if vector, err := s.embed(ctx, document.text); err != nil {
+			report.Failed++
+			failed = err
+			if previous == nil {
+				continue
+			}
+			row = previous
+			report.Kept++
+		} else {
+			data, err := json.Marshal(vector)
+			if err != nil {
+				return err
+			}
+			now := s.now()
+			row = &models.ArticleEmbedding{
+				ArticleID: article.ID, ContentType: document.contentType, Language: document.language,
+				Provider: provider, Model: model, Embedding: string(data), Dimensions: len(vector),
+				ContentHash: document.hash, CreatedAt: now, UpdatedAt: now,
+			}
+			report.Embedded++
+		}
+		if err := s.db().Table(searchIndexShadow).Create(map[string]interface{}{
+			"article_id": row.ArticleID, "content_type": row.ContentType, "language": row.Language,
+			"provider": row.Provider, "model": row.Model, "embedding": row.Embedding, "dimensions": row.Dimensions,
+			"content_hash": row.ContentHash, "token_count": row.TokenCount,
+			"created_at": row.CreatedAt, "updated_at": row.UpdatedAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return failed
+}
+
+// searchDocuments returns the texts of an article and its translations
+// that the vector index embeds, hashed as EmbeddingService hashes them so
+// embeddings it made are reused
+func searchDocuments(article *models.Article) []searchDocument {
+	var documents []searchDocument
+	add := func(language, title, summary, content string) {
+		for _, text := range []struct{ kind, text string }{
+			{"title", title},
+			{"content", content},
+			{"summary", summary},
+			{"combined", fmt.Sprintf("%s\n\n%s\n\n%s", title, summary, content)},
+		} {
+			if strings.TrimSpace(text.text) == "" {
+				continue
+			}
+			documents = append(documents, searchDocument{
+				contentType: text.kind, language: language, text: text.text,
+				hash: fmt.Sprintf("%x", sha256.Sum256([]byte(text.text))),
+			})
+		}
+	}
+	add(article.DefaultLang, article.Title, article.Summary, article.Content)
+	for _, translation := range article.Translations {
+		add(translation.Language, translation.Title, translation.Summary, translation.Content)
+	}
+	return documents
+}
+
+// RecordStats counts the documents of each index and language into
+// models.SearchIndex
+func (s *SearchIndexService) RecordStats() error {
+	return s.recordStats(s.db(), false)
+}
+
+func (s *SearchIndexService) recordStats(db *gorm.DB, rebuilt bool) error {
+	type languageCount struct {
+		Language string
+		Count    int
+	}
+	counts := map[string]map[string]int{
+		SearchIndexKeyword:   {searchIndexAll: 0},
+		SearchIndexEmbedding: {searchIndexAll: 0},
+	}
+
+	var rows []languageCount
+	if err := db.Model(&models.Article{}).Select("default_lang AS language, COUNT(*) AS count").
+		Group("default_lang").Scan(&rows).Error; err != nil {
+		return err
+	}
+	var translated []languageCount
+	if err := db.Model(&models.ArticleTranslation{}).Select("language, COUNT(*) AS count").
+		Where("article_id IN (SELECT id FROM articles)").Group("language").Scan(&translated).Error; err != nil {
+		return err
+	}
+	for _, row := range append(rows, translated...) {
+		counts[SearchIndexKeyword][row.Language] += row.Count
+		counts[SearchIndexKeyword][searchIndexAll] += row.Count
+	}
+	rows = nil
+	if err := db.Model(&models.ArticleEmbedding{}).Select("language, COUNT(DISTINCT article_id) AS count").
+		Group("language").Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		counts[SearchIndexEmbedding][row.Language] = row.Count
+		counts[SearchIndexEmbedding][searchIndexAll] += row.Count
+	}
+
+	now := s.now()
+	for indexType, languages := range counts {
+		var stale []models.SearchIndex
+		if err := db.Where("index_type = ?", indexType).Find(&stale).Error; err != nil {
+			return err
+		}
+		for _, index := range stale {
+			if _, ok := languages[index.Language]; !ok {
+				if err := db.Delete(&index).Error; err != nil {
+					return err
+				}
+			}
+		}
+		for language, total := range languages {
+			var index models.SearchIndex
+			if err := db.Where("index_type = ? AND language = ?", indexType, language).Limit(1).Find(&index).Error; err != nil {
+				return err
+			}
+			index.IndexType, index.Language, index.TotalDocuments, index.LastUpdated = indexType, language, total, now
+			if rebuilt || index.LastRebuild.IsZero() {
+				index.LastRebuild = now
+			}
+			if err := db.Save(&index).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	globalSearchIndexService *SearchIndexService
+	searchIndexServiceOnce   sync.Once
+)
+
+// GetGlobalSearchIndexService returns the global search index service
+func GetGlobalSearchIndexService() *SearchIndexService {
+	searchIndexServiceOnce.Do(func() {
+		globalSearchIndexService = NewSearchIndexService()
+	})
+	return globalSearchIndexService
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestSearchIndexRebuild(t *testing.T) {
+	newTestDB(t)
+	var embedded []string
+	failing := map[string]bool{}
+	s := &SearchIndexService{
+		db:    func() *gorm.DB { return database.DB },
+		now:   func() time.Time { return time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC) },
+		model: func() (string, string) { return "openai", "small" },
+		embed: func(ctx context.Context, text string) ([]float64, error) {
+			if failing[text] {
+				return nil, errors.New("provider down")
+			}
+			embedded = append(embedded, text)
+			return []float64{1, 0}, nil
+		},
+	}
+	hash := func(text string) string { return fmt.Sprintf("%x", sha256.Sum256([]byte(text))) }
+
+	first := models.Article{Title: "Hello", DefaultLang: "en"}
+	database.DB.Create(&first)
+	database.DB.Create(&models.ArticleTranslation{ArticleID: first.ID, Language: "zh", Title: "你好"})
+	second := models.Article{Title: "Second", Content: "Body", DefaultLang: "en"}
+	database.DB.Create(&second)
+	database.DB.Create([]models.ArticleEmbedding{
+		// Unchanged text made by the current model is reused
+		{ArticleID: first.ID, ContentType: "title", Language: "en", Provider: "openai", Model: "small", Embedding: "[0,1]", Dimensions: 2, ContentHash: hash("Hello")},
+		// Text that changed since is embedded again
+		{ArticleID: second.ID, ContentType: "title", Language: "en", Provider: "openai", Model: "small", Embedding: "[0,1]", Dimensions: 2, ContentHash: hash("Old")},
+		// The text was removed
+		{ArticleID: second.ID, ContentType: "summary", Language: "en", Provider: "openai", Model: "small", Embedding: "[0,1]", Dimensions: 2, ContentHash: hash("Gone")},
+		// Made by another model, and failing to embed again
+		{ArticleID: first.ID, ContentType: "title", Language: "zh", Provider: "gemini", Model: "old", Embedding: "[0,1]", Dimensions: 2, ContentHash: hash("你好")},
+	})
+	failing["你好"] = true
+
+	report, err := s.Rebuild(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Hello: title reused, combined embedded; 你好: title kept, combined
+	// embedded; Second: title, content and combined embedded
+	if report.Articles != 2 || report.Reused != 1 || report.Kept != 1 || report.Failed != 1 ||
+		report.Embedded != 5 || report.Documents != 7 || report.Dropped != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if len(embedded) != 5 {
+		t.Errorf("expected five texts embedded, got %q", embedded)
+	}
+	var rows []models.ArticleEmbedding
+	database.DB.Order("article_id, language, content_type").Find(&rows)
+	if len(rows) != 7 {
+		t.Fatalf("expected the new index to be swapped in, got %d rows", len(rows))
+	}
+	for _, row := range rows {
+		if row.ArticleID == second.ID && row.ContentType == "summary" {
+			t.Errorf("embedding of removed text left in the index: %+v", row)
+		}
+		if row.ArticleID == first.ID && row.Language == "zh" && row.ContentType == "title" && row.Model != "old" {
+			t.Errorf("expected the failed text to keep its embedding, got %+v", row)
+		}
+	}
+	if database.DB.Migrator().HasTable(searchIndexShadow) {
+		t.Error("shadow table left behind")
+	}
+
+	var indexes []models.SearchIndex
+	database.DB.Order("index_type, language").Find(&indexes)
+	counts := map[string]int{}
+	for _, index := range indexes {
+		counts[index.IndexType+"/"+index.Language] = index.TotalDocuments
+	}
+	want := map[string]int{"embedding/all": 3, "embedding/en": 2, "embedding/zh": 1, "keyword/all": 3, "keyword/en": 2, "keyword/zh": 1}
+	if fmt.Sprint(counts) != fmt.Sprint(want) {
+		t.Errorf("index statistics = %v, want %v", counts, want)
+	}
+
+	// When nothing can be embedded the live index is left alone
+	for _, row := range rows {
+		database.DB.Model(&row).Update("model", "older")
+	}
+	s.embed = func(context.Context, string) ([]float64, error) { return nil, errors.New("not configured") }
+	if _, err := s.Rebuild(context.Background()); err == nil {
+		t.Error("expected a rebuild without a working provider to fail")
+	}
+	var count int64
+	database.DB.Model(&models.ArticleEmbedding{}).Count(&count)
+	if count != 7 {
+		t.Errorf("expected the live index to be untouched, got %d rows", count)
+	}
+}
+
+func TestSearchIndexRebuildKeepsEmbeddingsSavedMeanwhile(t *testing.T) {
+	newTestDB(t)
+	article := models.Article{Title: "Hello", Content: "Body", DefaultLang: "en"}
+	database.DB.Create(&article)
+	database.DB.Create(&models.ArticleEmbedding{
+		ArticleID: article.ID, ContentType: "content", Language: "en", Provider: "openai", Model: "old", Embedding: "[0,1]", Dimensions: 2,
+	})
+
+	s := &SearchIndexService{
+		db:    func() *gorm.DB { return database.DB },
+		now:   time.Now,
+		model: func() (string, string) { return "openai", "small" },
+		embed: func(ctx context.Context, text string) ([]float64, error) {
+			if text == "Body" {
+				// The article is edited and embedded again while the
+				// rebuild runs
+				database.DB.Create(&models.ArticleEmbedding{
+					ArticleID: article.ID, ContentType: "content", Language: "en", Provider: "openai", Model: "edited", Embedding: "[1,1]", Dimensions: 2,
+				})
+			}
+			return []float64{1, 0}, nil
+		},
+	}
+
+	report, err := s.Rebuild(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Title, content and combined were rebuilt; the edited content wins
+	if report.Embedded != 3 || report.Newer != 1 || report.Documents != 3 || report.Dropped != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	var rows []models.ArticleEmbedding
+	database.DB.Order("content_type").Find(&rows)
+	if len(rows) != 3 {
+		t.Fatalf("expected three embeddings, got %+v", rows)
+	}
+	for _, row := range rows {
+		want := "small"
+		if row.ContentType == "content" {
+			want = "edited"
+		}
+		if row.Model != want {
+			t.Errorf("%s embedding made by %q, want %q", row.ContentType, row.Model, want)
+		}
+	}
+}
@@ -0,0 +1,546 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSearchPushEngineNotFound = errors.New("search push engine not found")
+	ErrInvalidSearchPushEngine  = errors.New("invalid search push engine")
+	// ErrSearchPushNotAllowed is returned for articles search engines are
+	// not told about
+	ErrSearchPushNotAllowed = errors.New("article cannot be pushed to search engines")
+)
+
+const (
+	// defaultBaiduPushEndpoint is Baidu's normal URL submission API
+	defaultBaiduPushEndpoint = "http://data.zz.baidu.com/urls"
+	searchPushTimeout        = 15 * time.Second
+	// searchPushLogRetention is how long submissions are logged
+	searchPushLogRetention = 90 * 24 * time.Hour
+)
+
+// searchPushDay is the time zone the engines' daily quotas reset in
+var searchPushDay = time.FixedZone("CST", 8*60*60)
+
+// SearchPushEngineInput is the editable part of a search push engine. Nil
+// fields are left unchanged on update.
+type SearchPushEngineInput struct {
+	Engine     *string `json:"engine"`
+	Endpoint   *string `json:"endpoint"`
+	Token      *string `json:"token"`
+	DailyQuota *int    `json:"daily_quota" binding:"omitempty,min=0"`
+	OnUpdate   *bool   `json:"on_update"`
+	Enabled    *bool   `json:"enabled"`
+}
+
+// searchPushDelivery is the job payload for JobSearchPush
+type searchPushDelivery struct {
+	EngineID  uint `json:"engine_id"`
+	ArticleID uint `json:"article_id"`
+}
+
+// searchPushResponse is the answer of a push API. Baidu reports how many
+// links it took and how much of the day's quota is left, or an error code
+// with a message; Sogou and 360 answer in the same form or not at all.
+type searchPushResponse struct {
+	Success     *int     `json:"success"`
+	Remain      *int     `json:"remain"`
+	NotSameSite []string `json:"not_same_site"`
+	NotValid    []string `json:"not_valid"`
+	Error       int      `json:"error"`
+	Message     string   `json:"message"`
+}
+
+// SearchPushService pushes the links of published articles, and of updated
+// ones where the engine asks for it, to the URL submission APIs of Baidu,
+// Sogou and 360, which Chinese search engines crawl much sooner than
+// sitemaps. Pushes are sent by the job queue, keep within the engine's
+// daily quota and are logged.
+type SearchPushService struct {
+	db      func() *gorm.DB
+	client  *http.Client
+	baseURL func(siteID uint) string
+	now     func() time.Time
+}
+
+// NewSearchPushService creates a search push service
+func NewSearchPushService() *SearchPushService {
+	return &SearchPushService{
+		db:      func() *gorm.DB { return database.DB },
+		client:  &http.Client{Timeout: searchPushTimeout},
+		baseURL: siteBaseURL,
+		now:     time.Now,
+	}
+}
+
+// List returns the engines of the site in ctx with what they submitted
+// today
+func (s *SearchPushService) List(ctx context.Context) ([]models.SearchPushEngine, error) {
+	engines := []models.SearchPushEngine{}
+	if err := s.db().WithContext(ctx).Order("engine ASC").Find(&engines).Error; err != nil {
+		return nil, err
+	}
+	for i := range engines {
+		if err := s.describe(&engines[i]); err != nil {
+			return nil, err
+		}
+	}
+	return engines, nil
+}
+
+// Get returns one of the engines of the site in ctx
+func (s *SearchPushService) Get(ctx context.Context, id uint) (*models.SearchPushEngine, error) {
+	var engine models.SearchPushEngine
+	if err := s.db().WithContext(ctx).First(&engine, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSearchPushEngineNotFound
+		}
+		return nil, err
+	}
+	if err := s.describe(&engine); err != nil {
+		return nil, err
+	}
+	return &engine, nil
+}
+
+// Create adds an engine. New engines push published articles only.
+func (s *SearchPushService) Create(ctx context.Context, input SearchPushEngineInput) (*models.SearchPushEngine, error) {
+	engine := &models.SearchPushEngine{Enabled: true}
+	if err := applySearchPushInput(engine, input); err != nil {
+		return nil, err
+	}
+	var existing int64
+	if err := s.db().WithContext(ctx).Model(&models.SearchPushEngine{}).
+		Where("engine = ?", engine.Engine).Count(&existing).Error; err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("%w: %s is already configured", ErrInvalidSearchPushEngine, engine.Engine)
+	}
+	if err := s.db().WithContext(ctx).Create(engine).Error; err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// Update changes the fields set in input; the engine itself stays
+func (s *SearchPushService) Update(ctx context.Context, id uint, input SearchPushEngineInput) (*models.SearchPushEngine, error) {
+	engine, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if input.Engine != nil && strings.TrimSpace(*input.Engine) != engine.Engine {
+		return nil, fmt.Errorf("%w: the engine cannot be changed", ErrInvalidSearchPushEngine)
+	}
+	if err := applySearchPushInput(engine, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().WithContext(ctx).Save(engine).Error; err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// Delete removes an engine and its log
+func (s *SearchPushService) Delete(ctx context.Context, id uint) error {
+	result := s.db().WithContext(ctx).Delete(&models.SearchPushEngine{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSearchPushEngineNotFound
+	}
+	return s.db().WithContext(ctx).Where("engine_id = ?", id).Delete(&models.SearchPushLog{}).Error
+}
+
+// Logs returns the submissions of the site in ctx, newest first, optionally
+// of one engine or article
+func (s *SearchPushService) Logs(ctx context.Context, engineID, articleID uint, page, limit int) ([]models.SearchPushLog, int64, error) {
+	query := s.db().WithContext(ctx).Model(&models.SearchPushLog{})
+	if engineID != 0 {
+		query = query.Where("engine_id = ?", engineID)
+	}
+	if articleID != 0 {
+		query = query.Where("article_id = ?", articleID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	logs := []models.SearchPushLog{}
+	err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&logs).Error
+	return logs, total, err
+}
+
+// Submit pushes one article of the site in ctx to an engine right away,
+// whether or not the engine is enabled, and returns the logged submission
+func (s *SearchPushService) Submit(ctx context.Context, engineID, articleID uint) (*models.SearchPushLog, error) {
+	engine, err := s.Get(ctx, engineID)
+	if err != nil {
+		return nil, err
+	}
+	var article models.Article
+	if err := s.db().WithContext(ctx).Preload("Translations").First(&article, articleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrArticleNotFound
+		}
+		return nil, err
+	}
+	if reason := s.unpushable(&article); reason != "" {
+		return nil, fmt.Errorf("%w: %s", ErrSearchPushNotAllowed, reason)
+	}
+	return s.push(ctx, engine, &article)
+}
+
+func applySearchPushInput(engine *models.SearchPushEngine, input SearchPushEngineInput) error {
+	if input.Engine != nil {
+		engine.Engine = strings.TrimSpace(*input.Engine)
+	}
+	if input.Endpoint != nil {
+		engine.Endpoint = strings.TrimSpace(*input.Endpoint)
+	}
+	if input.DailyQuota != nil {
+		if *input.DailyQuota < 0 {
+			return fmt.Errorf("%w: daily_quota cannot be negative", ErrInvalidSearchPushEngine)
+		}
+		engine.DailyQuota = *input.DailyQuota
+	}
+	if input.OnUpdate != nil {
+		engine.OnUpdate = *input.OnUpdate
+	}
+	if input.Enabled != nil {
+		engine.Enabled = *input.Enabled
+	}
+
+	switch engine.Engine {
+	case models.SearchPushBaidu:
+	case models.SearchPushSogou, models.SearchPush360:
+		// Neither publishes a fixed API; their webmaster tools give the
+		// address along with the token
+		if engine.Endpoint == "" {
+			return fmt.Errorf("%w: endpoint is required for %s", ErrInvalidSearchPushEngine, engine.Engine)
+		}
+	default:
+		return fmt.Errorf("%w: engine must be baidu, sogou or 360", ErrInvalidSearchPushEngine)
+	}
+	if engine.Endpoint != "" {
+		u, err := url.Parse(engine.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: endpoint must be an http(s) URL", ErrInvalidSearchPushEngine)
+		}
+	}
+
+	if input.Token != nil {
+		token := strings.TrimSpace(*input.Token)
+		if token == "" {
+			return fmt.Errorf("%w: token is required", ErrInvalidSearchPushEngine)
+		}
+		encrypted, err := security.GetGlobalCryptoService().EncryptAPIKey(token)
+		if err != nil {
+			return err
+		}
+		engine.Token = encrypted
+	}
+	if engine.Token == "" {
+		return fmt.Errorf("%w: token is required", ErrInvalidSearchPushEngine)
+	}
+	engine.TokenSet = true
+	return nil
+}
+
+// describe fills in the fields of an engine that are not stored
+func (s *SearchPushService) describe(engine *models.SearchPushEngine) error {
+	engine.TokenSet = engine.Token != ""
+	submitted, err := s.submittedToday(engine.ID)
+	engine.SubmittedToday = submitted
+	return err
+}
+
+// submittedToday counts the links an engine took since midnight in Beijing
+func (s *SearchPushService) submittedToday(engineID uint) (int, error) {
+	now := s.now().In(searchPushDay)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, searchPushDay)
+	var submitted int64
+	err := s.db().Model(&models.SearchPushLog{}).
+		Where("engine_id = ? AND status = ? AND created_at >= ?", engineID, models.SearchPushSucceeded, midnight).
+		Select("COALESCE(SUM(accepted), 0)").Scan(&submitted).Error
+	return int(submitted), err
+}
+
+// remainingQuota returns how many links an engine may still submit today,
+// or -1 when neither the site nor the engine set a limit
+func (s *SearchPushService) remainingQuota(engine *models.SearchPushEngine) (int, error) {
+	remaining := -1
+	if engine.DailyQuota > 0 {
+		submitted, err := s.submittedToday(engine.ID)
+		if err != nil {
+			return 0, err
+		}
+		remaining = max(engine.DailyQuota-submitted, 0)
+	}
+	today := s.now().In(searchPushDay).Format("2006-01-02")
+	if engine.QuotaRemaining != nil && engine.QuotaDate == today && (remaining < 0 || *engine.QuotaRemaining < remaining) {
+		remaining = *engine.QuotaRemaining
+	}
+	return remaining, nil
+}
+
+// registerHooks queues pushes for published and updated articles
+func (s *SearchPushService) registerHooks() {
+	hooks.Register(hooks.ArticlePublished, "search_push", s.dispatch)
+	hooks.Register(hooks.ArticleUpdated, "search_push", s.dispatch)
+}
+
+// dispatch queues a push to every enabled engine of the article's site
+// that follows the event
+func (s *SearchPushService) dispatch(ctx context.Context, event hooks.Event, payload interface{}) error {
+	article, ok := payload.(*models.Article)
+	if !ok || s.unpushable(article) != "" {
+		return nil
+	}
+	query := s.db().Where("site_id = ? AND enabled = ?", article.SiteID, true)
+	if event == hooks.ArticleUpdated {
+		query = query.Where("on_update = ?", true)
+	}
+	var engines []models.SearchPushEngine
+	if err := query.Find(&engines).Error; err != nil {
+		return err
+	}
+	for _, engine := range engines {
+		delivery := searchPushDelivery{EngineID: engine.ID, ArticleID: article.ID}
+		if _, err := GetGlobalJobQueue().Enqueue(JobSearchPush, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unpushable returns why search engines should not be told about an
+// article, or "" when they should
+func (s *SearchPushService) unpushable(article *models.Article) string {
+	switch {
+	case article.MembersOnly:
+		return "members-only articles cannot be crawled"
+	case article.CanonicalURL != "":
+		return "the article's canonical page is on another site"
+	case s.baseURL(article.SiteID) == "":
+		return "the site has no public URL"
+	}
+	var blocked bool
+	s.db().Model(&models.SiteSettings{}).Where("site_id = ?", article.SiteID).
+		Select("block_search_engines").Limit(1).Scan(&blocked)
+	if blocked {
+		return "the site asks search engines not to index it"
+	}
+	return ""
+}
+
+// Deliver pushes a queued article to one engine. Errors from the engine
+// other than it being unreachable are logged rather than retried, since a
+// bad token or a used up quota does not fix itself.
+func (s *SearchPushService) Deliver(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var delivery searchPushDelivery
+	if err := json.Unmarshal(payload, &delivery); err != nil {
+		return nil, err
+	}
+	var engine models.SearchPushEngine
+	if err := s.db().First(&engine, delivery.EngineID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return map[string]string{"skipped": "engine deleted"}, nil
+		}
+		return nil, err
+	}
+	if !engine.Enabled {
+		return map[string]string{"skipped": "engine disabled"}, nil
+	}
+	var article models.Article
+	if err := s.db().Preload("Translations").First(&article, delivery.ArticleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return map[string]string{"skipped": "article deleted"}, nil
+		}
+		return nil, err
+	}
+
+	entry, err := s.push(ctx, &engine, &article)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"engine": engine.Engine, "article_id": article.ID, "status": entry.Status, "accepted": entry.Accepted}, nil
+}
+
+// articleURLs returns the links of an article in every language it can be
+// read in, its own language first
+func articleURLs(baseURL string, article *models.Article) []string {
+	urls := []string{localizedArticleLink(baseURL, article.DefaultLang, article.ID)}
+	for _, translation := range article.Translations {
+		if translation.Language != article.DefaultLang && translation.Title != "" {
+			urls = append(urls, localizedArticleLink(baseURL, translation.Language, article.ID))
+		}
+	}
+	return urls
+}
+
+// push submits the article's links within the remaining quota and logs the
+// outcome. It returns an error only when the engine could not be reached,
+// which is worth retrying.
+func (s *SearchPushService) push(ctx context.Context, engine *models.SearchPushEngine, article *models.Article) (*models.SearchPushLog, error) {
+	base := strings.TrimRight(s.baseURL(article.SiteID), "/")
+	urls := articleURLs(base, article)
+	entry := &models.SearchPushLog{
+		SiteID: engine.SiteID, EngineID: engine.ID, Engine: engine.Engine, ArticleID: article.ID, CreatedAt: s.now(),
+	}
+
+	remaining, err := s.remainingQuota(engine)
+	if err != nil {
+		return nil, err
+	}
+	if remaining == 0 {
+		entry.URLs, entry.Status, entry.Error = strings.Join(urls, "\n"), models.SearchPushSkipped, "daily quota used up"
+		return entry, s.record(engine, entry, nil)
+	}
+	if remaining > 0 && remaining < len(urls) {
+		urls = urls[:remaining]
+	}
+	entry.URLs, entry.Submitted = strings.Join(urls, "\n"), len(urls)
+
+	response, err := s.send(ctx, engine, base, urls)
+	var unreachable *searchPushUnreachable
+	switch {
+	case errors.As(err, &unreachable):
+		entry.Status, entry.Error = models.SearchPushFailed, err.Error()
+		if recordErr := s.record(engine, entry, nil); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, err
+	case err != nil:
+		entry.Status, entry.Error = models.SearchPushFailed, err.Error()
+	default:
+		entry.Status, entry.Accepted = models.SearchPushSucceeded, len(urls)
+		if response.Success != nil {
+			entry.Accepted = *response.Success
+		}
+		if rejected := append(response.NotSameSite, response.NotValid...); len(rejected) > 0 {
+			entry.Error = "rejected: " + strings.Join(rejected, ", ")
+		}
+	}
+	return entry, s.record(engine, entry, response)
+}
+
+// searchPushUnreachable is an error of the network or the engine's server
+type searchPushUnreachable struct {
+	err error
+}
+
+func (e *searchPushUnreachable) Error() string { return e.err.Error() }
+func (e *searchPushUnreachable) Unwrap() error { return e.err }
+
+// send posts the links to the engine's push API, one per line, with the
+// site and token in the query as Baidu expects
+func (s *SearchPushService) send(ctx context.Context, engine *models.SearchPushEngine, site string, urls []string) (*searchPushResponse, error) {
+	token, err := security.GetGlobalCryptoService().DecryptAPIKey(engine.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt push token: %v", err)
+	}
+	endpoint := engine.Endpoint
+	if endpoint == "" {
+		endpoint = defaultBaiduPushEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set("site", site)
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), strings.NewReader(strings.Join(urls, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The URL holds the token; keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, &searchPushUnreachable{err}
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var response searchPushResponse
+	if len(strings.TrimSpace(string(body))) > 0 {
+		// Engines that answer with something other than JSON are judged by
+		// the status alone
+		_ = json.Unmarshal(body, &response)
+	}
+	if resp.StatusCode >= 500 {
+		return nil, &searchPushUnreachable{fmt.Errorf("%s returned status %d", engine.Engine, resp.StatusCode)}
+	}
+	if resp.StatusCode >= 300 || response.Error != 0 {
+		message := response.Message
+		if message == "" {
+			message = strings.TrimSpace(string(body))
+		}
+		return &response, fmt.Errorf("%s returned status %d: %s", engine.Engine, resp.StatusCode, message)
+	}
+	return &response, nil
+}
+
+// record stores a submission with the quota the engine reported and
+// forgets submissions past their retention
+func (s *SearchPushService) record(engine *models.SearchPushEngine, entry *models.SearchPushLog, response *searchPushResponse) error {
+	if err := s.db().Create(entry).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"last_error": entry.Error}
+	if entry.Status == models.SearchPushSucceeded {
+		updates["last_pushed_at"] = s.now()
+	}
+	today := s.now().In(searchPushDay).Format("2006-01-02")
+	switch {
+	case response != nil && response.Remain != nil:
+		updates["quota_remaining"], updates["quota_date"] = *response.Remain, today
+	case response != nil && strings.Contains(strings.ToLower(response.Message), "over quota"):
+		updates["quota_remaining"], updates["quota_date"] = 0, today
+	}
+	if err := s.db().Model(&models.SearchPushEngine{}).Where("id = ?", engine.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	return s.db().Where("created_at < ?", s.now().Add(-searchPushLogRetention)).Delete(&models.SearchPushLog{}).Error
+}
+
+var (
+	globalSearchPushService *SearchPushService
+	searchPushServiceOnce   sync.Once
+)
+
+// GetGlobalSearchPushService returns the global search push service,
+// registering its publish and update hooks on first use
+func GetGlobalSearchPushService() *SearchPushService {
+	searchPushServiceOnce.Do(func() {
+		globalSearchPushService = NewSearchPushService()
+		globalSearchPushService.registerHooks()
+	})
+	return globalSearchPushService
+}
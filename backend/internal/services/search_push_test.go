@@ -0,0 +1,150 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestSearchPush(t *testing.T) {
+	newTestDB(t)
+	var bodies []string
+	var query string
+	remain := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies, query = append(bodies, string(body)), r.URL.RawQuery
+		if r.URL.Query().Get("token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":401,"message":"token is not valid"}`)
+			return
+		}
+		accepted := strings.Count(string(body), "\n") + 1
+		remain -= accepted
+		fmt.Fprintf(w, `{"remain":%d,"success":%d,"not_same_site":[],"not_valid":[]}`, remain, accepted)
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	s := &SearchPushService{
+		db:      func() *gorm.DB { return database.DB },
+		client:  server.Client(),
+		baseURL: func(uint) string { return "https://blog.example.com" },
+		now:     func() time.Time { return now },
+	}
+	ctx := context.Background()
+	str := func(v string) *string { return &v }
+
+	if _, err := s.Create(ctx, SearchPushEngineInput{Engine: str("sogou"), Token: str("secret")}); !errors.Is(err, ErrInvalidSearchPushEngine) {
+		t.Errorf("expected ErrInvalidSearchPushEngine for sogou without an endpoint, got %v", err)
+	}
+	if _, err := s.Create(ctx, SearchPushEngineInput{Engine: str("google"), Token: str("secret")}); !errors.Is(err, ErrInvalidSearchPushEngine) {
+		t.Errorf("expected ErrInvalidSearchPushEngine for an unknown engine, got %v", err)
+	}
+	baidu, err := s.Create(ctx, SearchPushEngineInput{Engine: str("baidu"), Endpoint: str(server.URL + "/urls"), Token: str("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(baidu.Token, "secret") || !baidu.TokenSet || baidu.OnUpdate || !baidu.Enabled {
+		t.Errorf("unexpected engine %+v", baidu)
+	}
+	if _, err := s.Create(ctx, SearchPushEngineInput{Engine: str("baidu"), Token: str("other")}); !errors.Is(err, ErrInvalidSearchPushEngine) {
+		t.Errorf("expected a second baidu engine to be refused, got %v", err)
+	}
+	sogou, err := s.Create(ctx, SearchPushEngineInput{Engine: str("sogou"), Endpoint: str(server.URL + "/sogou"), Token: str("wrong")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	article := models.Article{Title: "你好", DefaultLang: "zh"}
+	database.DB.Create(&article)
+	database.DB.Create(&models.ArticleTranslation{ArticleID: article.ID, Language: "en", Title: "Hello"})
+	database.DB.Preload("Translations").First(&article, article.ID)
+
+	// Neither engine follows updates
+	if err := s.dispatch(ctx, hooks.ArticleUpdated, &article); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.dispatch(ctx, hooks.ArticlePublished, &article); err != nil {
+		t.Fatal(err)
+	}
+	var jobs []models.Job
+	database.DB.Where("type = ?", JobSearchPush).Find(&jobs)
+	if len(jobs) != 2 {
+		t.Fatalf("expected a push per engine for a published article, got %+v", jobs)
+	}
+
+	payload, _ := json.Marshal(searchPushDelivery{EngineID: baidu.ID, ArticleID: article.ID})
+	if _, err := s.Deliver(ctx, payload); err != nil {
+		t.Fatal(err)
+	}
+	link := fmt.Sprintf("https://blog.example.com/zh/article/%d\nhttps://blog.example.com/en/article/%d", article.ID, article.ID)
+	if len(bodies) != 1 || bodies[0] != link || !strings.Contains(query, "site=https%3A%2F%2Fblog.example.com") {
+		t.Errorf("unexpected push %q with %s", bodies, query)
+	}
+	stored, err := s.Get(ctx, baidu.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.QuotaRemaining == nil || *stored.QuotaRemaining != 0 || stored.QuotaDate != "2026-03-01" ||
+		stored.SubmittedToday != 2 || stored.LastPushedAt == nil {
+		t.Errorf("quota not recorded %+v", stored)
+	}
+
+	// The quota Baidu reported is used up until the next day in Beijing
+	entry, err := s.Submit(ctx, baidu.ID, article.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Status != models.SearchPushSkipped || len(bodies) != 1 {
+		t.Errorf("expected the push to be skipped, got %+v", entry)
+	}
+	now = now.Add(15 * time.Hour)
+	remain = 10
+	if entry, err = s.Submit(ctx, baidu.ID, article.ID); err != nil || entry.Status != models.SearchPushSucceeded {
+		t.Errorf("expected a push the next day, got %+v, %v", entry, err)
+	}
+
+	// A site quota cuts the links submitted
+	quota := 3
+	if _, err := s.Update(ctx, baidu.ID, SearchPushEngineInput{DailyQuota: &quota}); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err = s.Submit(ctx, baidu.ID, article.ID); err != nil || entry.Submitted != 1 {
+		t.Errorf("expected one link within the quota, got %+v, %v", entry, err)
+	}
+
+	// A rejected token is logged, not retried
+	payload, _ = json.Marshal(searchPushDelivery{EngineID: sogou.ID, ArticleID: article.ID})
+	if _, err := s.Deliver(ctx, payload); err != nil {
+		t.Fatalf("expected a rejected push not to be retried, got %v", err)
+	}
+	logs, total, err := s.Logs(ctx, sogou.ID, 0, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || logs[0].Status != models.SearchPushFailed || !strings.Contains(logs[0].Error, "token is not valid") {
+		t.Errorf("unexpected sogou log %+v", logs)
+	}
+	if _, total, _ := s.Logs(ctx, 0, article.ID, 1, 10); total != 5 {
+		t.Errorf("expected five logged submissions, got %d", total)
+	}
+
+	members := models.Article{Title: "Members", DefaultLang: "en", MembersOnly: true}
+	database.DB.Create(&members)
+	if _, err := s.Submit(ctx, baidu.ID, members.ID); !errors.Is(err, ErrSearchPushNotAllowed) {
+		t.Errorf("expected members-only articles not to be pushed, got %v", err)
+	}
+}
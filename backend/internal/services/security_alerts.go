@@ -0,0 +1,165 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginAlert describes a login from an unrecognized device or IP
+type LoginAlert struct {
+	Username    string    `json:"username"`
+	IPAddress   string    `json:"ip_address"`
+	Location    string    `json:"location"`
+	Browser     string    `json:"browser"`
+	OS          string    `json:"os"`
+	UserAgent   string    `json:"user_agent"`
+	LoginMethod string    `json:"login_method"`
+	NewDevice   bool      `json:"new_device"`
+	NewIP       bool      `json:"new_ip"`
+	Time        time.Time `json:"time"`
+	RevokeURL   string    `json:"revoke_url"`
+}
+
+// SecurityAlertService delivers security notifications by webhook and email
+type SecurityAlertService struct {
+	webhookURL string
+	alertEmail string
+	httpClient *http.Client
+}
+
+// NewSecurityAlertService creates a service from SECURITY_ALERT_* settings
+func NewSecurityAlertService() *SecurityAlertService {
+	return &SecurityAlertService{
+		webhookURL: os.Getenv("SECURITY_ALERT_WEBHOOK_URL"),
+		alertEmail: os.Getenv("SECURITY_ALERT_EMAIL"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// IsEnabled reports whether any alert channel is configured
+func (s *SecurityAlertService) IsEnabled() bool {
+	return s.webhookURL != "" || (s.alertEmail != "" && smtpConfigured())
+}
+
+// SendLoginAlert records a new-device login in the notification center
+// and notifies all configured channels about it
+func (s *SecurityAlertService) SendLoginAlert(alert LoginAlert) {
+	notifyAdmin(NotificationInput{
+		Source:   models.NotificationSourceSecurity,
+		Type:     "login_new_device",
+		Severity: models.SeverityWarning,
+		Title:    fmt.Sprintf("New sign-in to %s from %s", alert.Username, alert.IPAddress),
+		Message:  formatLoginAlertDetails(alert),
+	})
+
+	if s.webhookURL != "" {
+		if err := s.sendWebhook("login.new_device", alert); err != nil {
+			slog.Error("Failed to send login alert webhook", "error", err)
+		}
+	}
+
+	if s.alertEmail != "" && smtpConfigured() {
+		subject := fmt.Sprintf("New sign-in to %s from %s", alert.Username, alert.IPAddress)
+		if err := sendPlainEmail(s.alertEmail, subject, formatLoginAlertEmail(alert)); err != nil {
+			slog.Error("Failed to send login alert email", "error", err)
+		}
+	}
+}
+
+// SendAlert records an operational problem in the notification center and
+// notifies all configured channels about it. The webhook receives event
+// and payload; the email has subject and body.
+func (s *SecurityAlertService) SendAlert(event, subject, body string, payload interface{}) {
+	notifyAdmin(NotificationInput{
+		Source:   models.NotificationSourceSystem,
+		Type:     event,
+		Severity: models.SeverityWarning,
+		Title:    subject,
+		Message:  body,
+	})
+
+	if s.webhookURL != "" {
+		if err := s.sendWebhook(event, payload); err != nil {
+			slog.Error("Failed to send alert webhook", "event", event, "error", err)
+		}
+	}
+	if s.alertEmail != "" && smtpConfigured() {
+		if err := sendPlainEmail(s.alertEmail, "[kuno] "+subject, body); err != nil {
+			slog.Error("Failed to send alert email", "event", event, "error", err)
+		}
+	}
+}
+
+func (s *SecurityAlertService) sendWebhook(event string, payload interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"event": event,
+		"data":  payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.httpClient.Post(s.webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func formatLoginAlertEmail(alert LoginAlert) string {
+	var b strings.Builder
+	b.WriteString(formatLoginAlertDetails(alert))
+	b.WriteString("\nIf this was you, no action is needed.\n")
+	fmt.Fprintf(&b, "If not, revoke this session immediately and change your password:\n%s\n", alert.RevokeURL)
+	return b.String()
+}
+
+// formatLoginAlertDetails describes the login without the revoke link,
+// which only the alert email carries
+func formatLoginAlertDetails(alert LoginAlert) string {
+	var reasons []string
+	if alert.NewDevice {
+		reasons = append(reasons, "a device")
+	}
+	if alert.NewIP {
+		reasons = append(reasons, "an IP address")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Your account %s just signed in from %s that has not been seen before.\n\n", alert.Username, strings.Join(reasons, " and "))
+	fmt.Fprintf(&b, "Time:      %s\n", alert.Time.Format(time.RFC1123))
+	fmt.Fprintf(&b, "IP:        %s\n", alert.IPAddress)
+	if alert.Location != "" {
+		fmt.Fprintf(&b, "Location:  %s\n", alert.Location)
+	}
+	fmt.Fprintf(&b, "Browser:   %s on %s\n", alert.Browser, alert.OS)
+	fmt.Fprintf(&b, "Method:    %s\n", alert.LoginMethod)
+	return b.String()
+}
+
+// Global security alert service instance
+var (
+	globalSecurityAlertService     *SecurityAlertService
+	globalSecurityAlertServiceOnce sync.Once
+)
+
+// GetGlobalSecurityAlertService returns the global security alert service
+func GetGlobalSecurityAlertService() *SecurityAlertService {
+	globalSecurityAlertServiceOnce.Do(func() {
+		globalSecurityAlertService = NewSecurityAlertService()
+	})
+	return globalSecurityAlertService
+}
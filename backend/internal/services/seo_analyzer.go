@@ -1,9 +1,9 @@
 package services
 
 import (
+	"blog-backend/internal/keywords"
 	"blog-backend/internal/models"
 	"encoding/json"
-	"fmt"
 	"gorm.io/gorm"
 	"math"
 	"regexp"
@@ -14,15 +14,17 @@ import (
 
 // SEOAnalyzerService provides comprehensive SEO analysis functionality
 type SEOAnalyzerService struct {
-	// Add dependencies here if needed
+	// rules returns the thresholds of a language
+	rules func(language string) SEORules
 }
 
 // NewSEOAnalyzerService creates a new SEO analyzer service
 func NewSEOAnalyzerService() *SEOAnalyzerService {
-	return &SEOAnalyzerService{}
+	return &SEOAnalyzerService{rules: GetGlobalSEORuleService().Rules}
 }
 
-// AnalyzeContent performs comprehensive SEO analysis of content
+// AnalyzeContent performs comprehensive SEO analysis of content against the
+// rules of language, with the issues and suggestions in that language
 func (s *SEOAnalyzerService) AnalyzeContent(article *models.Article, focusKeyword string, language string) (*models.SEOAnalysisResult, error) {
 	// Extract content components
 	title := article.SEOTitle
@@ -33,11 +35,19 @@ func (s *SEOAnalyzerService) AnalyzeContent(article *models.Article, focusKeywor
 	description := article.SEODescription
 	content := article.Content
 
+	// Direction marks in right-to-left text would count as characters and
+	// split keywords
+	title = models.StripBidiControls(title)
+	description = models.StripBidiControls(description)
+	content = models.StripBidiControls(content)
+	focusKeyword = models.StripBidiControls(focusKeyword)
+
 	// Perform individual analyses
-	titleAnalysis := s.analyzeTitleSEO(title, focusKeyword, language)
-	descriptionAnalysis := s.analyzeDescriptionSEO(description, focusKeyword, language)
-	contentAnalysis := s.analyzeContentSEO(content, focusKeyword, language)
-	keywordAnalysis := s.analyzeKeywordUsage(title, description, content, focusKeyword, language)
+	rules := s.rules(language)
+	titleAnalysis := s.analyzeTitleSEO(title, focusKeyword, language, rules)
+	descriptionAnalysis := s.analyzeDescriptionSEO(description, focusKeyword, language, rules)
+	contentAnalysis := s.analyzeContentSEO(content, focusKeyword, language, rules)
+	keywordAnalysis := s.analyzeKeywordUsage(title, description, content, focusKeyword, language, rules)
 	readabilityAnalysis := s.analyzeReadability(content, language)
 	technicalAnalysis := s.analyzeTechnicalSEO(article)
 
@@ -45,7 +55,7 @@ func (s *SEOAnalyzerService) AnalyzeContent(article *models.Article, focusKeywor
 	overallScore := s.calculateOverallScore(titleAnalysis, descriptionAnalysis, contentAnalysis, keywordAnalysis, readabilityAnalysis, technicalAnalysis)
 
 	// Generate comprehensive suggestions
-	suggestions := s.generateSuggestions(titleAnalysis, descriptionAnalysis, contentAnalysis, keywordAnalysis, readabilityAnalysis, technicalAnalysis)
+	suggestions := s.generateSuggestions(language, titleAnalysis, descriptionAnalysis, contentAnalysis, keywordAnalysis, readabilityAnalysis, technicalAnalysis)
 
 	return &models.SEOAnalysisResult{
 		OverallScore:        overallScore,
@@ -56,15 +66,16 @@ func (s *SEOAnalyzerService) AnalyzeContent(article *models.Article, focusKeywor
 		ReadabilityAnalysis: readabilityAnalysis,
 		TechnicalAnalysis:   technicalAnalysis,
 		Suggestions:         suggestions,
+		Language:            language,
 		CreatedAt:           time.Now(),
 	}, nil
 }
 
 // analyzeTitleSEO analyzes SEO title quality
-func (s *SEOAnalyzerService) analyzeTitleSEO(title, focusKeyword, language string) models.TitleAnalysis {
+func (s *SEOAnalyzerService) analyzeTitleSEO(title, focusKeyword, language string, rules SEORules) models.TitleAnalysis {
 	analysis := models.TitleAnalysis{
 		Length:          utf8.RuneCountInString(title),
-		OptimalLength:   models.Range{Min: 30, Max: 60},
+		OptimalLength:   rules.TitleLength,
 		HasFocusKeyword: s.containsKeyword(title, focusKeyword),
 		BrandIncluded:   false, // This would need brand detection logic
 		Uniqueness:      0.9,   // This would need database comparison
@@ -74,22 +85,22 @@ func (s *SEOAnalyzerService) analyzeTitleSEO(title, focusKeyword, language strin
 
 	// Length scoring
 	lengthScore := 100
-	if analysis.Length < 30 {
+	if analysis.Length < rules.TitleLength.Min {
 		lengthScore = 60
-		analysis.Issues = append(analysis.Issues, "标题太短，建议至少30个字符")
-		analysis.Suggestions = append(analysis.Suggestions, "增加关键词或描述性词语来丰富标题")
-	} else if analysis.Length > 60 {
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "title_too_short", rules.TitleLength.Min))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "title_too_short_fix"))
+	} else if analysis.Length > rules.TitleLength.Max {
 		lengthScore = 70
-		analysis.Issues = append(analysis.Issues, "标题太长，可能在搜索结果中被截断")
-		analysis.Suggestions = append(analysis.Suggestions, "精简标题，保持在60个字符以内")
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "title_too_long"))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "title_too_long_fix", rules.TitleLength.Max))
 	}
 
 	// Keyword scoring
 	keywordScore := 100
 	if !analysis.HasFocusKeyword && focusKeyword != "" {
 		keywordScore = 40
-		analysis.Issues = append(analysis.Issues, "标题中缺少焦点关键词")
-		analysis.Suggestions = append(analysis.Suggestions, fmt.Sprintf("在标题中包含关键词：%s", focusKeyword))
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "title_missing_keyword"))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "title_missing_keyword_fix", focusKeyword))
 	}
 
 	// Calculate final score
@@ -99,10 +110,10 @@ func (s *SEOAnalyzerService) analyzeTitleSEO(title, focusKeyword, language strin
 }
 
 // analyzeDescriptionSEO analyzes meta description quality
-func (s *SEOAnalyzerService) analyzeDescriptionSEO(description, focusKeyword, language string) models.DescriptionAnalysis {
+func (s *SEOAnalyzerService) analyzeDescriptionSEO(description, focusKeyword, language string, rules SEORules) models.DescriptionAnalysis {
 	analysis := models.DescriptionAnalysis{
 		Length:          utf8.RuneCountInString(description),
-		OptimalLength:   models.Range{Min: 120, Max: 160},
+		OptimalLength:   rules.DescriptionLength,
 		HasFocusKeyword: s.containsKeyword(description, focusKeyword),
 		HasCallToAction: s.hasCallToAction(description, language),
 		Uniqueness:      0.9,
@@ -114,31 +125,31 @@ func (s *SEOAnalyzerService) analyzeDescriptionSEO(description, focusKeyword, la
 	lengthScore := 100
 	if analysis.Length == 0 {
 		lengthScore = 0
-		analysis.Issues = append(analysis.Issues, "缺少元描述")
-		analysis.Suggestions = append(analysis.Suggestions, "添加120-160字符的元描述")
-	} else if analysis.Length < 120 {
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "description_missing"))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "description_missing_fix", rules.DescriptionLength.Min, rules.DescriptionLength.Max))
+	} else if analysis.Length < rules.DescriptionLength.Min {
 		lengthScore = 70
-		analysis.Issues = append(analysis.Issues, "元描述太短")
-		analysis.Suggestions = append(analysis.Suggestions, "增加描述内容，突出文章价值")
-	} else if analysis.Length > 160 {
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "description_too_short"))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "description_too_short_fix"))
+	} else if analysis.Length > rules.DescriptionLength.Max {
 		lengthScore = 70
-		analysis.Issues = append(analysis.Issues, "元描述太长，可能被截断")
-		analysis.Suggestions = append(analysis.Suggestions, "精简描述，保持在160字符以内")
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "description_too_long"))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "description_too_long_fix", rules.DescriptionLength.Max))
 	}
 
 	// Keyword scoring
 	keywordScore := 100
 	if !analysis.HasFocusKeyword && focusKeyword != "" {
 		keywordScore = 60
-		analysis.Issues = append(analysis.Issues, "元描述中缺少焦点关键词")
-		analysis.Suggestions = append(analysis.Suggestions, "在描述中自然地包含关键词")
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "description_missing_keyword"))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "description_missing_keyword_fix"))
 	}
 
 	// Call to action scoring
 	ctaScore := 100
 	if !analysis.HasCallToAction {
 		ctaScore = 80
-		analysis.Suggestions = append(analysis.Suggestions, "考虑添加行动号召词语，如：了解更多、立即查看等")
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "description_call_to_action"))
 	}
 
 	// Calculate final score
@@ -148,24 +159,24 @@ func (s *SEOAnalyzerService) analyzeDescriptionSEO(description, focusKeyword, la
 }
 
 // analyzeContentSEO analyzes content quality and structure
-func (s *SEOAnalyzerService) analyzeContentSEO(content, focusKeyword, language string) models.ContentAnalysis {
-	// Clean content from markdown
+func (s *SEOAnalyzerService) analyzeContentSEO(content, focusKeyword, language string, rules SEORules) models.ContentAnalysis {
+	// Clean content from markdown. Words are counted with the language's
+	// tokenizer, since Chinese and Japanese are not split by spaces.
 	cleanContent := s.stripMarkdown(content)
-	words := strings.Fields(cleanContent)
-	wordCount := len(words)
+	wordCount := len(keywords.Tokenize(cleanContent, language))
 
 	// Analyze heading structure
-	headingStructure := s.analyzeHeadingStructure(content, focusKeyword)
+	headingStructure := s.analyzeHeadingStructure(content, focusKeyword, language)
 
 	// Calculate keyword density
-	keywordDensity := s.calculateKeywordDensity(cleanContent, focusKeyword)
+	keywordDensity := s.calculateKeywordDensity(cleanContent, focusKeyword, language)
 
 	// Count links
 	internalLinks := s.countInternalLinks(content)
 	externalLinks := s.countExternalLinks(content)
 
 	// Analyze images
-	imageOptimization := s.analyzeImageOptimization(content)
+	imageOptimization := s.analyzeImageOptimization(content, language)
 
 	analysis := models.ContentAnalysis{
 		WordCount:         wordCount,
@@ -181,23 +192,23 @@ func (s *SEOAnalyzerService) analyzeContentSEO(content, focusKeyword, language s
 
 	// Word count scoring
 	contentScore := 100
-	if wordCount < 300 {
+	if wordCount < rules.ContentWords.Min {
 		contentScore = 60
-		analysis.Issues = append(analysis.Issues, "内容太短，建议至少300字")
-		analysis.Suggestions = append(analysis.Suggestions, "扩展内容，提供更详细的信息")
-	} else if wordCount > 3000 {
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "content_too_short", rules.ContentWords.Min))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "content_too_short_fix"))
+	} else if wordCount > rules.ContentWords.Max {
 		contentScore = 90
-		analysis.Suggestions = append(analysis.Suggestions, "考虑将长文章分割成系列文章")
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "content_too_long_fix"))
 	}
 
 	// Links scoring
 	if internalLinks == 0 {
-		analysis.Issues = append(analysis.Issues, "缺少内部链接")
-		analysis.Suggestions = append(analysis.Suggestions, "添加2-3个相关文章的内部链接")
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "content_no_internal_links"))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "content_no_internal_links_fix"))
 	}
 
 	if externalLinks == 0 {
-		analysis.Suggestions = append(analysis.Suggestions, "考虑添加权威来源的外部链接")
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "content_no_external_links_fix"))
 	}
 
 	analysis.Score = contentScore
@@ -206,22 +217,25 @@ func (s *SEOAnalyzerService) analyzeContentSEO(content, focusKeyword, language s
 }
 
 // analyzeKeywordUsage analyzes keyword usage across all content
-func (s *SEOAnalyzerService) analyzeKeywordUsage(title, description, content, focusKeyword, language string) models.KeywordAnalysis {
+func (s *SEOAnalyzerService) analyzeKeywordUsage(title, description, content, focusKeyword, language string, rules SEORules) models.KeywordAnalysis {
 	if focusKeyword == "" {
 		return models.KeywordAnalysis{
 			Score:       50,
-			Issues:      []string{"未设置焦点关键词"},
-			Suggestions: []string{"设置一个主要关键词来优化内容"},
+			Issues:      []string{seoMessage(language, "keyword_missing")},
+			Suggestions: []string{seoMessage(language, "keyword_missing_fix")},
 		}
 	}
 
 	// Count keyword usage
 	cleanContent := s.stripMarkdown(content)
 	allText := title + " " + description + " " + cleanContent
-	totalWords := len(strings.Fields(allText))
+	totalWords := len(keywords.Tokenize(allText, language))
 	keywordCount := s.countKeywordOccurrences(allText, focusKeyword)
 
-	density := float64(keywordCount) / float64(totalWords) * 100
+	density := 0.0
+	if totalWords > 0 {
+		density = float64(keywordCount) / float64(totalWords) * 100
+	}
 
 	// Analyze distribution
 	distribution := []models.KeywordDistribution{
@@ -238,33 +252,33 @@ func (s *SEOAnalyzerService) analyzeKeywordUsage(title, description, content, fo
 		FocusKeywordUsage:    keywordCount,
 		KeywordDistribution:  distribution,
 		KeywordDensity:       density,
-		OptimalDensity:       models.Range{Min: 50, Max: 250}, // 0.5% to 2.5%
-		RelatedKeywordsFound: 0,                               // Would need semantic analysis
+		OptimalDensity:       densityRange(rules),
+		RelatedKeywordsFound: 0, // Would need semantic analysis
 		Issues:               []string{},
 		Suggestions:          []string{},
 	}
 
 	// Density scoring
 	score := 100
-	if density < 0.5 {
+	if density < rules.KeywordDensityMin {
 		score = 60
-		analysis.Issues = append(analysis.Issues, "关键词密度过低")
-		analysis.Suggestions = append(analysis.Suggestions, "适当增加关键词使用频率")
-	} else if density > 2.5 {
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "keyword_density_low", rules.KeywordDensityMin))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "keyword_density_low_fix"))
+	} else if density > rules.KeywordDensityMax {
 		score = 50
-		analysis.Issues = append(analysis.Issues, "关键词密度过高，可能被视为堆砌")
-		analysis.Suggestions = append(analysis.Suggestions, "减少关键词使用，使内容更自然")
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "keyword_density_high", rules.KeywordDensityMax))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "keyword_density_high_fix"))
 	}
 
 	// Distribution scoring
 	if distribution[0].Title == 0 {
 		score -= 20
-		analysis.Issues = append(analysis.Issues, "标题中缺少关键词")
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "keyword_not_in_title"))
 	}
 
 	if distribution[0].Headings == 0 {
 		score -= 10
-		analysis.Suggestions = append(analysis.Suggestions, "在副标题中包含关键词")
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "keyword_not_in_headings_fix"))
 	}
 
 	analysis.Score = score
@@ -289,6 +303,7 @@ func (s *SEOAnalyzerService) analyzeReadability(content, language string) models
 		AvgParagraphLength:        avgParagraphLength,
 		PassiveVoicePercentage:    s.calculatePassiveVoice(sentences),
 		TransitionWordsPercentage: s.calculateTransitionWords(cleanContent, language),
+		Direction:                 models.LanguageDirection(language),
 		Issues:                    []string{},
 		Suggestions:               []string{},
 	}
@@ -298,20 +313,20 @@ func (s *SEOAnalyzerService) analyzeReadability(content, language string) models
 
 	if avgSentenceLength > 20 {
 		score -= 15
-		analysis.Issues = append(analysis.Issues, "句子平均长度过长")
-		analysis.Suggestions = append(analysis.Suggestions, "使用更短的句子提高可读性")
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "readability_long_sentences"))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "readability_long_sentences_fix"))
 	}
 
 	if avgParagraphLength > 100 {
 		score -= 10
-		analysis.Issues = append(analysis.Issues, "段落过长")
-		analysis.Suggestions = append(analysis.Suggestions, "将长段落分成更短的段落")
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "readability_long_paragraphs"))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "readability_long_paragraphs_fix"))
 	}
 
 	if analysis.PassiveVoicePercentage > 25 {
 		score -= 10
-		analysis.Issues = append(analysis.Issues, "被动语态使用过多")
-		analysis.Suggestions = append(analysis.Suggestions, "使用更多主动语态")
+		analysis.Issues = append(analysis.Issues, seoMessage(language, "readability_passive"))
+		analysis.Suggestions = append(analysis.Suggestions, seoMessage(language, "readability_passive_fix"))
 	}
 
 	analysis.Score = score
@@ -353,6 +368,8 @@ func (s *SEOAnalyzerService) hasCallToAction(text, language string) bool {
 	ctaWords := []string{"了解", "查看", "阅读", "点击", "获取", "下载", "立即", "马上"}
 	if language == "en" {
 		ctaWords = []string{"learn", "discover", "read", "click", "get", "download", "now", "today"}
+	} else if language == "ar" {
+		ctaWords = []string{"اقرأ", "اكتشف", "تعرف", "اضغط", "احصل", "حمل", "الآن", "اليوم"}
 	}
 
 	textLower := strings.ToLower(text)
@@ -370,7 +387,7 @@ func (s *SEOAnalyzerService) stripMarkdown(content string) string {
 	return re.ReplaceAllString(content, "")
 }
 
-func (s *SEOAnalyzerService) analyzeHeadingStructure(content, focusKeyword string) models.HeadingStructure {
+func (s *SEOAnalyzerService) analyzeHeadingStructure(content, focusKeyword, language string) models.HeadingStructure {
 	h1Count := strings.Count(content, "# ")
 	h2Count := strings.Count(content, "## ")
 	h3Count := strings.Count(content, "### ")
@@ -384,15 +401,15 @@ func (s *SEOAnalyzerService) analyzeHeadingStructure(content, focusKeyword strin
 
 	if h1Count == 0 {
 		score -= 30
-		issues = append(issues, "缺少H1标题")
+		issues = append(issues, seoMessage(language, "heading_no_h1"))
 	} else if h1Count > 1 {
 		score -= 20
-		issues = append(issues, "H1标题过多")
+		issues = append(issues, seoMessage(language, "heading_many_h1"))
 	}
 
 	if h2Count == 0 {
 		score -= 20
-		issues = append(issues, "缺少H2副标题")
+		issues = append(issues, seoMessage(language, "heading_no_h2"))
 	}
 
 	return models.HeadingStructure{
@@ -405,14 +422,17 @@ func (s *SEOAnalyzerService) analyzeHeadingStructure(content, focusKeyword strin
 	}
 }
 
-func (s *SEOAnalyzerService) calculateKeywordDensity(content, keyword string) []models.KeywordDensity {
+func (s *SEOAnalyzerService) calculateKeywordDensity(content, keyword, language string) []models.KeywordDensity {
 	if keyword == "" {
 		return []models.KeywordDensity{}
 	}
 
-	words := strings.Fields(content)
+	words := keywords.Tokenize(content, language)
 	count := s.countKeywordOccurrences(content, keyword)
-	density := float64(count) / float64(len(words)) * 100
+	density := 0.0
+	if len(words) > 0 {
+		density = float64(count) / float64(len(words)) * 100
+	}
 
 	return []models.KeywordDensity{
 		{
@@ -469,7 +489,7 @@ func (s *SEOAnalyzerService) countExternalLinks(content string) int {
 	return len(linkPattern.FindAllString(content, -1))
 }
 
-func (s *SEOAnalyzerService) analyzeImageOptimization(content string) models.ImageOptimization {
+func (s *SEOAnalyzerService) analyzeImageOptimization(content, language string) models.ImageOptimization {
 	// Count markdown images
 	imagePattern := regexp.MustCompile(`!\[([^\]]*)\]\(([^)]+)\)`)
 	matches := imagePattern.FindAllStringSubmatch(content, -1)
@@ -492,7 +512,7 @@ func (s *SEOAnalyzerService) analyzeImageOptimization(content string) models.Ima
 
 	issues := []string{}
 	if totalImages > 0 && imagesWithAlt < totalImages {
-		issues = append(issues, "部分图片缺少alt属性")
+		issues = append(issues, seoMessage(language, "images_missing_alt"))
 	}
 
 	return models.ImageOptimization{
@@ -506,8 +526,9 @@ func (s *SEOAnalyzerService) analyzeImageOptimization(content string) models.Ima
 }
 
 func (s *SEOAnalyzerService) splitIntoSentences(text string) []string {
-	// Simplified sentence splitting
-	sentences := regexp.MustCompile(`[.!?]+`).Split(text, -1)
+	// Simplified sentence splitting, with the Arabic question mark and the
+	// Urdu full stop
+	sentences := regexp.MustCompile(`[.!?؟۔]+`).Split(text, -1)
 	result := []string{}
 	for _, sentence := range sentences {
 		if strings.TrimSpace(sentence) != "" {
@@ -543,6 +564,8 @@ func (s *SEOAnalyzerService) calculateTransitionWords(content, language string)
 	transitionWords := []string{"因此", "所以", "然而", "但是", "而且", "另外", "首先", "其次", "最后", "总之"}
 	if language == "en" {
 		transitionWords = []string{"therefore", "however", "moreover", "furthermore", "first", "second", "finally", "in conclusion"}
+	} else if language == "ar" {
+		transitionWords = []string{"لذلك", "لكن", "ولكن", "كذلك", "أيضا", "أولا", "ثانيا", "أخيرا", "وبالتالي"}
 	}
 
 	words := strings.Fields(content)
@@ -626,6 +649,15 @@ func (s *SEOAnalyzerService) analyzeSchema() models.Schema {
 	}
 }
 
+// densityRange is the keyword density range of rules in hundredths of a
+// percent
+func densityRange(rules SEORules) models.Range {
+	return models.Range{
+		Min: int(math.Round(rules.KeywordDensityMin * 100)),
+		Max: int(math.Round(rules.KeywordDensityMax * 100)),
+	}
+}
+
 func (s *SEOAnalyzerService) calculateOverallScore(title models.TitleAnalysis, description models.DescriptionAnalysis, content models.ContentAnalysis, keyword models.KeywordAnalysis, readability models.ReadabilityAnalysis, technical models.TechnicalAnalysis) int {
 	// Weighted average
 	weights := map[string]float64{
@@ -647,29 +679,29 @@ func (s *SEOAnalyzerService) calculateOverallScore(title models.TitleAnalysis, d
 	return int(math.Round(totalScore))
 }
 
-func (s *SEOAnalyzerService) generateSuggestions(title models.TitleAnalysis, description models.DescriptionAnalysis, content models.ContentAnalysis, keyword models.KeywordAnalysis, readability models.ReadabilityAnalysis, technical models.TechnicalAnalysis) []string {
+func (s *SEOAnalyzerService) generateSuggestions(language string, title models.TitleAnalysis, description models.DescriptionAnalysis, content models.ContentAnalysis, keyword models.KeywordAnalysis, readability models.ReadabilityAnalysis, technical models.TechnicalAnalysis) []string {
 	suggestions := []string{}
 
 	// Collect high-priority suggestions
 	if title.Score < 70 {
-		suggestions = append(suggestions, "优化标题："+strings.Join(title.Suggestions, "，"))
+		suggestions = append(suggestions, seoMessage(language, "summary_title", seoList(language, title.Suggestions)))
 	}
 
 	if description.Score < 70 {
-		suggestions = append(suggestions, "改进元描述："+strings.Join(description.Suggestions, "，"))
+		suggestions = append(suggestions, seoMessage(language, "summary_description", seoList(language, description.Suggestions)))
 	}
 
 	if keyword.Score < 70 {
-		suggestions = append(suggestions, "优化关键词使用："+strings.Join(keyword.Suggestions, "，"))
+		suggestions = append(suggestions, seoMessage(language, "summary_keyword", seoList(language, keyword.Suggestions)))
 	}
 
 	if content.Score < 70 {
-		suggestions = append(suggestions, "改进内容结构："+strings.Join(content.Suggestions, "，"))
+		suggestions = append(suggestions, seoMessage(language, "summary_content", seoList(language, content.Suggestions)))
 	}
 
 	// Add general best practices
-	suggestions = append(suggestions, "定期更新内容以保持新鲜度")
-	suggestions = append(suggestions, "确保内容对用户有价值")
+	suggestions = append(suggestions, seoMessage(language, "summary_fresh"))
+	suggestions = append(suggestions, seoMessage(language, "summary_valuable"))
 
 	return suggestions
 }
@@ -693,8 +725,8 @@ func (s *SEOAnalyzerService) SaveAnalysisResult(db *gorm.DB, articleID uint, ana
 		IssuesFound:      len(analysis.TitleAnalysis.Issues) + len(analysis.DescriptionAnalysis.Issues) + len(analysis.ContentAnalysis.Issues),
 		CheckResults:     string(checkResultsJSON),
 		Suggestions:      string(suggestionsJSON),
-		Language:         "zh", // Would detect from content
-		CheckDuration:    100,  // Would measure actual time
+		Language:         analysis.Language,
+		CheckDuration:    100, // Would measure actual time
 	}
 
 	return db.Create(healthCheck).Error
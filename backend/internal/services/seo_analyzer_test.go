@@ -0,0 +1,39 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"testing"
+)
+
+func TestAnalyzeRightToLeftContent(t *testing.T) {
+	article := &models.Article{
+		Title:       "مرحبا بالعالم",
+		Content:     "هذه الجملة الأولى. هل هذه الجملة الثانية؟ ‏نعم‏ هي كذلك.",
+		DefaultLang: "zh",
+		Translations: []models.ArticleTranslation{
+			{Language: "ar", Title: "عنوان", Content: "محتوى عربي؟ نعم."},
+		},
+	}
+	analysis, err := NewSEOAnalyzerService().AnalyzeContent(article, "‎نعم", "ar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.ReadabilityAnalysis.Direction != models.DirectionRTL {
+		t.Errorf("direction = %q, want rtl", analysis.ReadabilityAnalysis.Direction)
+	}
+	// Ten words over three sentences, split at the Arabic question mark
+	if got := analysis.ReadabilityAnalysis.AvgSentenceLength; got < 3.3 || got > 3.4 {
+		t.Errorf("average sentence length = %v, want 10/3", got)
+	}
+	if analysis.KeywordAnalysis.FocusKeywordUsage != 1 {
+		t.Errorf("keyword count = %d, want the marked keyword found once", analysis.KeywordAnalysis.FocusKeywordUsage)
+	}
+
+	ApplyTranslation(article, "ar")
+	if article.Dir != models.DirectionRTL || article.Title != "عنوان" {
+		t.Errorf("translated article = %q in %q, want the Arabic title right to left", article.Title, article.Dir)
+	}
+	if models.LanguageDirection("fa-IR") != models.DirectionRTL || models.LanguageDirection("en") != models.DirectionLTR {
+		t.Error("LanguageDirection does not tell right-to-left languages apart")
+	}
+}
@@ -0,0 +1,523 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidSEOBulk       = errors.New("invalid bulk SEO metadata request")
+	ErrNoArticlesMissingSEO = errors.New("no article is missing the selected SEO metadata")
+	ErrSEOProposalNotFound  = errors.New("SEO metadata proposal not found")
+)
+
+// SEO metadata fields a bulk generation job can fill in
+const (
+	SEOFieldTitle       = "title"
+	SEOFieldDescription = "description"
+	SEOFieldSlug        = "slug"
+)
+
+// SEO metadata proposal statuses
+const (
+	SEOProposalPending   = "pending"
+	SEOProposalApplied   = "applied"
+	SEOProposalDismissed = "dismissed"
+)
+
+// Bulk generation limits. Each prompt covers a batch of articles, each
+// shown to the model by its title, summary and the start of its content.
+const (
+	seoBulkDefaultLimit  = 100
+	seoBulkMaxLimit      = 1000
+	seoBulkDefaultBatch  = 10
+	seoBulkMaxBatch      = 25
+	seoBulkContentLength = 800
+	seoBulkMaxSlug       = 80
+	seoProposalsPage     = 100
+)
+
+// seoSlugInvalid matches what an SEO slug may not contain
+var seoSlugInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// SEOBulkOptions selects what a bulk generation job fills in. Fields
+// default to all three. The job stops before the batch that would start
+// over MaxCost (USD, estimated) or MaxTokens; zero means no cap. Unless
+// Apply is set, proposals wait for review instead of being written to the
+// articles.
+type SEOBulkOptions struct {
+	Fields    []string `json:"fields,omitempty"`
+	Limit     int      `json:"limit,omitempty"`
+	BatchSize int      `json:"batch_size,omitempty"`
+	MaxCost   float64  `json:"max_cost,omitempty"`
+	MaxTokens int      `json:"max_tokens,omitempty"`
+	Apply     bool     `json:"apply,omitempty"`
+}
+
+// seoBulkJob is the payload of JobSEOBulkMetadata
+type seoBulkJob struct {
+	SiteID uint `json:"site_id"`
+	SEOBulkOptions
+}
+
+// SEOBulkResult describes one bulk generation job
+type SEOBulkResult struct {
+	Articles      int     `json:"articles"`
+	Proposed      int     `json:"proposed"`
+	Applied       int     `json:"applied"`
+	Failed        int     `json:"failed"`
+	InputTokens   int     `json:"input_tokens"`
+	OutputTokens  int     `json:"output_tokens"`
+	EstimatedCost float64 `json:"estimated_cost"`
+	BudgetReached bool    `json:"budget_reached"`
+}
+
+// seoBulkAnswer is the model's metadata for one article
+type seoBulkAnswer struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Slug        string `json:"slug"`
+}
+
+// SEOBulkService fills in the SEO titles, descriptions and slugs legacy
+// articles lack with the AI provider from the AI settings, in batches of
+// articles per prompt. Articles with a pending proposal are left alone
+// until it is decided, so running the job again continues where the last
+// run stopped.
+type SEOBulkService struct {
+	db    func() *gorm.DB
+	now   func() time.Time
+	llm   *LLMClient
+	rules func(language string) SEORules
+}
+
+// NewSEOBulkService creates a bulk SEO metadata service
+func NewSEOBulkService() *SEOBulkService {
+	return &SEOBulkService{
+		db:    func() *gorm.DB { return database.DB },
+		now:   time.Now,
+		llm:   NewLLMClient(),
+		rules: GetGlobalSEORuleService().Rules,
+	}
+}
+
+// Queue checks a bulk generation request and queues the job that runs it
+func (s *SEOBulkService) Queue(ctx context.Context, siteID uint, options SEOBulkOptions) (*models.Job, error) {
+	if err := validateSEOBulk(&options); err != nil {
+		return nil, err
+	}
+	if !s.llm.Configured(siteID) {
+		return nil, ErrLLMNotConfigured
+	}
+	var count int64
+	if err := s.missing(s.db().WithContext(ctx), siteID, options.Fields).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrNoArticlesMissingSEO
+	}
+	return GetGlobalJobQueue().Enqueue(JobSEOBulkMetadata, seoBulkJob{SiteID: siteID, SEOBulkOptions: options})
+}
+
+// Generate is the JobSEOBulkMetadata handler. It proposes metadata for up
+// to Limit articles missing it, oldest first, and applies the proposals
+// right away when asked to.
+func (s *SEOBulkService) Generate(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var job seoBulkJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, err
+	}
+	if err := validateSEOBulk(&job.SEOBulkOptions); err != nil {
+		return nil, err
+	}
+	db := s.db().WithContext(ctx)
+	var articles []models.Article
+	if err := s.missing(db, job.SiteID, job.Fields).Order("id").Limit(job.Limit).Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	result := &SEOBulkResult{Articles: len(articles)}
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(articles))
+	var jobID uint
+	if progress != nil {
+		jobID = progress.jobID
+	}
+
+	// Slugs proposed in this run, which the database does not know yet
+	slugs := make(map[string]bool)
+	var lastErr error
+	for start := 0; start < len(articles); start += job.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if (job.MaxCost > 0 && result.EstimatedCost >= job.MaxCost) ||
+			(job.MaxTokens > 0 && result.InputTokens+result.OutputTokens >= job.MaxTokens) {
+			result.BudgetReached = true
+			slog.Warn("Bulk SEO metadata budget reached", "site_id", job.SiteID, "cost", result.EstimatedCost,
+				"tokens", result.InputTokens+result.OutputTokens)
+			break
+		}
+		batch := articles[start:min(start+job.BatchSize, len(articles))]
+		progress.Step("Writing SEO metadata for articles %d-%d of %d", start+1, start+len(batch), len(articles))
+
+		answers, usage, err := s.write(ctx, job.SiteID, job.Fields, batch)
+		result.InputTokens += usage.InputTokens
+		result.OutputTokens += usage.OutputTokens
+		result.EstimatedCost += usage.EstimatedCost
+		if errors.Is(err, ErrLLMNotConfigured) {
+			return result, err
+		}
+		for i := range batch {
+			article := &batch[i]
+			itemErr := err
+			var proposal *models.SEOMetadataProposal
+			if answer, ok := answers[article.ID]; itemErr == nil && !ok {
+				itemErr = errors.New("the AI answer has no metadata for the article")
+			} else if itemErr == nil {
+				proposal = s.proposal(db, article, answer, job.Fields, slugs)
+				proposal.JobID = jobID
+				if proposal.SEOTitle == "" && proposal.SEODescription == "" && proposal.SEOSlug == "" {
+					itemErr = errors.New("the AI answer has no usable metadata")
+				}
+			}
+			if itemErr != nil {
+				progress.ItemFailed(fmt.Sprintf("article %d", article.ID), itemErr)
+				result.Failed++
+				lastErr = itemErr
+				continue
+			}
+			if err := db.Create(proposal).Error; err != nil {
+				return result, err
+			}
+			result.Proposed++
+			if job.Apply {
+				applied, err := s.apply(db, proposal)
+				if err != nil {
+					return result, err
+				}
+				if applied {
+					result.Applied++
+				}
+			}
+		}
+		progress.Advance(len(batch))
+	}
+	if result.Applied > 0 {
+		cache.Publish(cache.TopicArticles)
+	}
+	if result.Proposed == 0 && lastErr != nil {
+		return result, fmt.Errorf("no SEO metadata was generated: %w", lastErr)
+	}
+	return result, nil
+}
+
+// Proposals lists the proposals of a site with a status, newest first
+func (s *SEOBulkService) Proposals(ctx context.Context, siteID uint, status string, limit int) ([]models.SEOMetadataProposal, error) {
+	if status != SEOProposalPending && status != SEOProposalApplied && status != SEOProposalDismissed {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidSEOBulk, status)
+	}
+	if limit <= 0 || limit > seoProposalsPage*5 {
+		limit = seoProposalsPage
+	}
+	proposals := []models.SEOMetadataProposal{}
+	err := s.db().WithContext(ctx).
+		Preload("Article", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "title", "default_lang", "seo_title", "seo_description", "seo_slug")
+		}).
+		Where("site_id = ? AND status = ?", siteID, status).
+		Order("id DESC").Limit(limit).Find(&proposals).Error
+	return proposals, err
+}
+
+// Decide applies or dismisses pending proposals. Applying writes only the
+// fields the article still lacks, and skips a slug another article has
+// taken since. It returns the proposals decided.
+func (s *SEOBulkService) Decide(ctx context.Context, siteID uint, ids []uint, status string) ([]models.SEOMetadataProposal, error) {
+	if status != SEOProposalApplied && status != SEOProposalDismissed {
+		return nil, fmt.Errorf("%w: a proposal can only be applied or dismissed", ErrInvalidSEOBulk)
+	}
+	if len(ids) == 0 || len(ids) > seoBulkMaxLimit {
+		return nil, fmt.Errorf("%w: give 1 to %d proposal ids", ErrInvalidSEOBulk, seoBulkMaxLimit)
+	}
+	db := s.db().WithContext(ctx)
+	var proposals []models.SEOMetadataProposal
+	if err := db.Where("site_id = ? AND id IN ? AND status = ?", siteID, ids, SEOProposalPending).
+		Order("id").Find(&proposals).Error; err != nil {
+		return nil, err
+	}
+	if len(proposals) == 0 {
+		return nil, ErrSEOProposalNotFound
+	}
+	applied := false
+	for i := range proposals {
+		if status == SEOProposalDismissed {
+			now := s.now()
+			proposals[i].Status, proposals[i].DecidedAt = SEOProposalDismissed, &now
+			if err := db.Model(&proposals[i]).Select("status", "decided_at").Updates(&proposals[i]).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		changed, err := s.apply(db, &proposals[i])
+		if err != nil {
+			return nil, err
+		}
+		applied = applied || changed
+	}
+	if applied {
+		cache.Publish(cache.TopicArticles)
+	}
+	return proposals, nil
+}
+
+// missing selects the articles of a site that lack one of the fields and
+// have no pending proposal
+func (s *SEOBulkService) missing(db *gorm.DB, siteID uint, fields []string) *gorm.DB {
+	columns := map[string]string{SEOFieldTitle: "seo_title", SEOFieldDescription: "seo_description", SEOFieldSlug: "seo_slug"}
+	conditions := make([]string, 0, len(fields))
+	for _, field := range fields {
+		column := columns[field]
+		conditions = append(conditions, fmt.Sprintf("(%s IS NULL OR %s = '')", column, column))
+	}
+	return db.Model(&models.Article{}).
+		Where("site_id = ?", siteID).
+		Where(strings.Join(conditions, " OR ")).
+		Where("id NOT IN (?)", db.Model(&models.SEOMetadataProposal{}).Select("article_id").
+			Where("site_id = ? AND status = ?", siteID, SEOProposalPending))
+}
+
+// write asks the AI provider for the missing metadata of a batch of
+// articles, each in its own language
+func (s *SEOBulkService) write(ctx context.Context, siteID uint, fields []string, batch []models.Article) (map[uint]seoBulkAnswer, LLMUsage, error) {
+	type promptArticle struct {
+		ID                uint     `json:"id"`
+		Language          string   `json:"language"`
+		Title             string   `json:"title"`
+		Summary           string   `json:"summary,omitempty"`
+		Content           string   `json:"content"`
+		Missing           []string `json:"missing"`
+		TitleLength       string   `json:"title_length,omitempty"`
+		DescriptionLength string   `json:"description_length,omitempty"`
+	}
+	list := make([]promptArticle, len(batch))
+	for i := range batch {
+		article := &batch[i]
+		content := strings.Join(strings.Fields(htmlTag.ReplaceAllString(article.Content, " ")), " ")
+		rules := s.rules(article.DefaultLang)
+		item := promptArticle{
+			ID: article.ID, Language: llmLanguageName(article.DefaultLang), Title: article.Title,
+			Summary: article.Summary, Content: truncateRunes(content, seoBulkContentLength),
+			Missing: missingSEOFields(article, fields),
+		}
+		for _, field := range item.Missing {
+			switch field {
+			case SEOFieldTitle:
+				item.TitleLength = fmt.Sprintf("%d-%d characters", rules.TitleLength.Min, rules.TitleLength.Max)
+			case SEOFieldDescription:
+				item.DescriptionLength = fmt.Sprintf("%d-%d characters", rules.DescriptionLength.Min, rules.DescriptionLength.Max)
+			}
+		}
+		list[i] = item
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return nil, LLMUsage{}, err
+	}
+
+	answer, usage, err := s.llm.CompleteWithUsage(ctx, siteID, LLMRequest{
+		System: "You write the SEO metadata of blog articles. Stay faithful to each article: do not promise " +
+			"what it does not cover. Answer with a single JSON object and nothing else.",
+		Prompt: "For every article, write only the fields listed in \"missing\", in the article's language:\n" +
+			"- title: a search result title of title_length that makes the topic clear.\n" +
+			"- description: a meta description of description_length summing up what the reader learns.\n" +
+			"- slug: a short URL slug of lowercase English words joined by hyphens, also for articles in other languages.\n" +
+			"Answer as {\"<id>\": {\"title\": \"...\", \"description\": \"...\", \"slug\": \"...\"}}.\n\nArticles:\n" + string(data),
+		ServiceType: "seo",
+		Operation:   "bulk_metadata",
+	})
+	if err != nil {
+		return nil, usage, err
+	}
+	answers, err := parseSEOBulkAnswer(answer)
+	return answers, usage, err
+}
+
+// proposal turns the model's answer for an article into a proposal of the
+// fields it lacks. A slug that is taken gets the article ID appended.
+func (s *SEOBulkService) proposal(db *gorm.DB, article *models.Article, answer seoBulkAnswer, fields []string, slugs map[string]bool) *models.SEOMetadataProposal {
+	proposal := &models.SEOMetadataProposal{
+		SiteID:    article.SiteID,
+		ArticleID: article.ID,
+		Language:  article.DefaultLang,
+		Status:    SEOProposalPending,
+	}
+	for _, field := range missingSEOFields(article, fields) {
+		switch field {
+		case SEOFieldTitle:
+			proposal.SEOTitle = truncateRunes(strings.Join(strings.Fields(answer.Title), " "), 255)
+		case SEOFieldDescription:
+			proposal.SEODescription = truncateRunes(strings.Join(strings.Fields(answer.Description), " "), 500)
+		case SEOFieldSlug:
+			slug := normalizeSEOSlug(answer.Slug)
+			if slug == "" {
+				continue
+			}
+			if slugs[slug] || CheckArticleSlug(db, article.ID, "", slug) != nil {
+				slug = normalizeSEOSlug(slug + "-" + strconv.FormatUint(uint64(article.ID), 10))
+			}
+			if slugs[slug] || CheckArticleSlug(db, article.ID, "", slug) != nil {
+				continue
+			}
+			slugs[slug] = true
+			proposal.SEOSlug = slug
+		}
+	}
+	return proposal
+}
+
+// apply writes a proposal's fields the article still lacks and marks the
+// proposal applied. It reports whether the article changed.
+func (s *SEOBulkService) apply(db *gorm.DB, proposal *models.SEOMetadataProposal) (bool, error) {
+	var article models.Article
+	if err := db.Select("id", "seo_title", "seo_description", "seo_slug").
+		Where("site_id = ?", proposal.SiteID).Limit(1).Find(&article, proposal.ArticleID).Error; err != nil {
+		return false, err
+	}
+	updates := map[string]interface{}{}
+	if article.ID != 0 {
+		if proposal.SEOTitle != "" && article.SEOTitle == "" {
+			updates["seo_title"] = proposal.SEOTitle
+		}
+		if proposal.SEODescription != "" && article.SEODescription == "" {
+			updates["seo_description"] = proposal.SEODescription
+		}
+		if proposal.SEOSlug != "" && article.SEOSlug == "" {
+			err := CheckArticleSlug(db, article.ID, "", proposal.SEOSlug)
+			if err != nil && !errors.Is(err, ErrSlugInUse) {
+				return false, err
+			}
+			if err == nil {
+				updates["seo_slug"] = proposal.SEOSlug
+			}
+		}
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&article).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		now := s.now()
+		proposal.Status, proposal.DecidedAt = SEOProposalApplied, &now
+		return tx.Model(proposal).Select("status", "decided_at").Updates(proposal).Error
+	})
+	return err == nil && len(updates) > 0, err
+}
+
+// missingSEOFields returns the fields an article lacks of those asked for
+func missingSEOFields(article *models.Article, fields []string) []string {
+	missing := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if (field == SEOFieldTitle && article.SEOTitle == "") ||
+			(field == SEOFieldDescription && article.SEODescription == "") ||
+			(field == SEOFieldSlug && article.SEOSlug == "") {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// normalizeSEOSlug makes a slug of lowercase ASCII letters, digits and
+// single hyphens
+func normalizeSEOSlug(slug string) string {
+	slug = strings.Trim(seoSlugInvalid.ReplaceAllString(strings.ToLower(slug), "-"), "-")
+	if len(slug) > seoBulkMaxSlug {
+		slug = strings.TrimRight(slug[:seoBulkMaxSlug], "-")
+	}
+	// A slug of digits would be taken for an article ID
+	if _, err := strconv.ParseUint(slug, 10, 64); err == nil {
+		return ""
+	}
+	return slug
+}
+
+// parseSEOBulkAnswer reads the metadata by article ID from the JSON object
+// in the model's answer, which may be wrapped in a code fence or prose
+func parseSEOBulkAnswer(answer string) (map[uint]seoBulkAnswer, error) {
+	start, end := strings.Index(answer, "{"), strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("the AI answer has no JSON object")
+	}
+	var raw map[string]seoBulkAnswer
+	if err := json.Unmarshal([]byte(answer[start:end+1]), &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse the AI answer: %v", err)
+	}
+	answers := make(map[uint]seoBulkAnswer, len(raw))
+	for key, value := range raw {
+		if id, err := strconv.ParseUint(strings.TrimSpace(key), 10, 32); err == nil {
+			answers[uint(id)] = value
+		}
+	}
+	return answers, nil
+}
+
+// validateSEOBulk checks the options and fills in their defaults
+func validateSEOBulk(options *SEOBulkOptions) error {
+	if len(options.Fields) == 0 {
+		options.Fields = []string{SEOFieldTitle, SEOFieldDescription, SEOFieldSlug}
+	}
+	seen := make(map[string]bool, len(options.Fields))
+	fields := options.Fields[:0:0]
+	for _, field := range options.Fields {
+		if field != SEOFieldTitle && field != SEOFieldDescription && field != SEOFieldSlug {
+			return fmt.Errorf("%w: unknown field %q, use title, description or slug", ErrInvalidSEOBulk, field)
+		}
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	options.Fields = fields
+	if options.Limit < 0 || options.Limit > seoBulkMaxLimit {
+		return fmt.Errorf("%w: limit must be 0 to %d", ErrInvalidSEOBulk, seoBulkMaxLimit)
+	}
+	if options.BatchSize < 0 || options.BatchSize > seoBulkMaxBatch {
+		return fmt.Errorf("%w: batch_size must be 0 to %d", ErrInvalidSEOBulk, seoBulkMaxBatch)
+	}
+	if options.MaxCost < 0 || options.MaxTokens < 0 {
+		return fmt.Errorf("%w: max_cost and max_tokens must not be negative", ErrInvalidSEOBulk)
+	}
+	if options.Limit == 0 {
+		options.Limit = seoBulkDefaultLimit
+	}
+	if options.BatchSize == 0 {
+		options.BatchSize = seoBulkDefaultBatch
+	}
+	return nil
+}
+
+var (
+	globalSEOBulkService     *SEOBulkService
+	globalSEOBulkServiceOnce sync.Once
+)
+
+// GetGlobalSEOBulkService returns the global bulk SEO metadata service
+func GetGlobalSEOBulkService() *SEOBulkService {
+	globalSEOBulkServiceOnce.Do(func() {
+		globalSEOBulkService = NewSEOBulkService()
+	})
+	return globalSEOBulkService
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestSEOBulkMetadata(t *testing.T) {
+	newTestDB(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var request struct {
+			Messages []struct{ Content string } `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		prompt := request.Messages[1].Content
+		var articles []struct {
+			ID uint `json:"id"`
+		}
+		json.Unmarshal([]byte(prompt[strings.Index(prompt, "Articles:\n")+len("Articles:\n"):]), &articles)
+		answer := map[string]seoBulkAnswer{}
+		for _, article := range articles {
+			answer[fmt.Sprint(article.ID)] = seoBulkAnswer{
+				Title:       fmt.Sprintf("SEO title %d", article.ID),
+				Description: fmt.Sprintf("  Description\nof %d ", article.ID),
+				Slug:        "Taken Slug!",
+			}
+		}
+		content, _ := json.Marshal(answer)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "```json\n" + string(content) + "\n```"}}},
+			"usage":   map[string]int{"prompt_tokens": 100, "completion_tokens": 50},
+		})
+	}))
+	defer server.Close()
+
+	secure, err := security.GetGlobalAIConfigService().EncryptAIConfig(&security.InputAIConfig{
+		DefaultProvider: "openai",
+		Providers: map[string]security.InputProviderConfig{
+			"openai": {Provider: "openai", APIKey: "sk-test", Model: "gpt-test", Enabled: true,
+				Settings: map[string]string{"base_url": server.URL}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aiConfig, _ := json.Marshal(secure)
+	database.DB.Create(&models.SiteSettings{SiteID: models.DefaultSiteID, DefaultLanguage: "en", AIConfig: string(aiConfig)})
+
+	db := func() *gorm.DB { return database.DB }
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := &SEOBulkService{
+		db:    db,
+		now:   func() time.Time { return now },
+		llm:   &LLMClient{db: db, client: server.Client(), tracker: NewAIUsageTracker()},
+		rules: func(string) SEORules { return builtinSEORules["en"] },
+	}
+
+	taken := models.Article{Title: "Taken", Content: "x", DefaultLang: "en", SEOTitle: "t", SEODescription: "d", SEOSlug: "taken-slug"}
+	bare := models.Article{Title: "Bare", Content: "<p>Some <b>content</b></p>", DefaultLang: "en"}
+	titled := models.Article{Title: "Titled", Content: "y", DefaultLang: "en", SEOTitle: "Kept title"}
+	for _, article := range []*models.Article{&taken, &bare, &titled} {
+		if err := database.DB.Create(article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	payload, _ := json.Marshal(seoBulkJob{SiteID: models.DefaultSiteID, SEOBulkOptions: SEOBulkOptions{BatchSize: 1, MaxTokens: 150}})
+	out, err := s.Generate(context.Background(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := out.(*SEOBulkResult)
+	if result.Articles != 2 || result.Proposed != 1 || !result.BudgetReached || calls != 1 || result.InputTokens != 100 {
+		t.Fatalf("result = %+v after %d calls, want 1 of 2 articles proposed before the token budget", result, calls)
+	}
+
+	payload, _ = json.Marshal(seoBulkJob{SiteID: models.DefaultSiteID})
+	if out, err = s.Generate(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+	if result = out.(*SEOBulkResult); result.Articles != 1 || result.Proposed != 1 {
+		t.Fatalf("result = %+v, want only the article without a pending proposal", result)
+	}
+
+	proposals, err := s.Proposals(context.Background(), models.DefaultSiteID, SEOProposalPending, 0)
+	if err != nil || len(proposals) != 2 {
+		t.Fatalf("Proposals = %d, %v, want 2", len(proposals), err)
+	}
+	byArticle := map[uint]models.SEOMetadataProposal{}
+	for _, proposal := range proposals {
+		byArticle[proposal.ArticleID] = proposal
+	}
+	first, second := byArticle[bare.ID], byArticle[titled.ID]
+	if first.SEOTitle != fmt.Sprintf("SEO title %d", bare.ID) || first.SEODescription != fmt.Sprintf("Description of %d", bare.ID) ||
+		first.SEOSlug != fmt.Sprintf("taken-slug-%d", bare.ID) {
+		t.Errorf("proposal = %+v, want the answer tidied up and a free slug", first)
+	}
+	if second.SEOTitle != "" || second.SEOSlug != fmt.Sprintf("taken-slug-%d", titled.ID) || second.Article == nil {
+		t.Errorf("proposal = %+v, want no title for an article that has one", second)
+	}
+
+	// Nothing changes until proposals are applied
+	var article models.Article
+	database.DB.First(&article, bare.ID)
+	if article.SEOTitle != "" {
+		t.Fatalf("article has SEO title %q before review", article.SEOTitle)
+	}
+	if _, err := s.Queue(context.Background(), models.DefaultSiteID, SEOBulkOptions{}); !errors.Is(err, ErrNoArticlesMissingSEO) {
+		t.Fatalf("Queue = %v, want ErrNoArticlesMissingSEO while every gap has a pending proposal", err)
+	}
+
+	if _, err := s.Decide(context.Background(), models.DefaultSiteID, []uint{first.ID}, SEOProposalApplied); err != nil {
+		t.Fatal(err)
+	}
+	database.DB.First(&article, bare.ID)
+	if article.SEOTitle != first.SEOTitle || article.SEODescription != first.SEODescription || article.SEOSlug != first.SEOSlug {
+		t.Errorf("article = %q %q %q, want the proposal applied", article.SEOTitle, article.SEODescription, article.SEOSlug)
+	}
+
+	// An admin filled in the description meanwhile; it is kept
+	database.DB.Model(&models.Article{}).Where("id = ?", titled.ID).Update("seo_description", "Manual")
+	decided, err := s.Decide(context.Background(), models.DefaultSiteID, []uint{second.ID}, SEOProposalApplied)
+	if err != nil || len(decided) != 1 || decided[0].Status != SEOProposalApplied || decided[0].DecidedAt == nil {
+		t.Fatalf("Decide = %+v, %v", decided, err)
+	}
+	article = models.Article{}
+	database.DB.First(&article, titled.ID)
+	if article.SEOTitle != "Kept title" || article.SEODescription != "Manual" || article.SEOSlug != second.SEOSlug {
+		t.Errorf("article = %q %q %q, want only the slug applied", article.SEOTitle, article.SEODescription, article.SEOSlug)
+	}
+	if _, err := s.Decide(context.Background(), models.DefaultSiteID, []uint{second.ID}, SEOProposalDismissed); !errors.Is(err, ErrSEOProposalNotFound) {
+		t.Errorf("Decide on a decided proposal = %v, want ErrSEOProposalNotFound", err)
+	}
+}
+
+func TestValidateSEOBulk(t *testing.T) {
+	options := SEOBulkOptions{Fields: []string{"slug", "title", "slug"}}
+	if err := validateSEOBulk(&options); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(options.Fields, ",") != "slug,title" || options.Limit != seoBulkDefaultLimit || options.BatchSize != seoBulkDefaultBatch {
+		t.Errorf("options = %+v", options)
+	}
+	for _, bad := range []SEOBulkOptions{
+		{Fields: []string{"keywords"}},
+		{Limit: seoBulkMaxLimit + 1},
+		{BatchSize: -1},
+		{MaxCost: -1},
+	} {
+		if err := validateSEOBulk(&bad); !errors.Is(err, ErrInvalidSEOBulk) {
+			t.Errorf("validateSEOBulk(%+v) = %v, want ErrInvalidSEOBulk", bad, err)
+		}
+	}
+	for slug, want := range map[string]string{"Hello, World!": "hello-world", "--a--b--": "a-b", "2024": "", "你好": ""} {
+		if got := normalizeSEOSlug(slug); got != want {
+			t.Errorf("normalizeSEOSlug(%q) = %q, want %q", slug, got, want)
+		}
+	}
+}
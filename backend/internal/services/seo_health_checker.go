@@ -2,6 +2,7 @@ package services
 
 import (
 	"blog-backend/internal/models"
+	"context"
 	"encoding/json"
 	"fmt"
 	"gorm.io/gorm"
@@ -23,7 +24,7 @@ func NewSEOHealthCheckerService(db *gorm.DB) *SEOHealthCheckerService {
 }
 
 // RunSiteWideHealthCheck performs a comprehensive health check on all articles
-func (s *SEOHealthCheckerService) RunSiteWideHealthCheck() (*models.SEOHealthCheck, error) {
+func (s *SEOHealthCheckerService) RunSiteWideHealthCheck(ctx context.Context) (*models.SEOHealthCheck, error) {
 	startTime := time.Now()
 
 	// Get all published articles
@@ -37,13 +38,22 @@ func (s *SEOHealthCheckerService) RunSiteWideHealthCheck() (*models.SEOHealthChe
 	categoryScores := make(map[string]int)
 	suggestions := []string{}
 
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(articles))
+
 	// Analyze each article
 	for _, article := range articles {
-		analysis, err := s.analyzer.AnalyzeContent(&article, "", "zh")
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Step("Analyzing article %d: %s", article.ID, article.Title)
+		analysis, err := s.analyzer.AnalyzeContent(&article, "", article.DefaultLang)
 		if err != nil {
 			fmt.Printf("Failed to analyze article %d: %v\n", article.ID, err)
+			progress.ItemFailed(fmt.Sprintf("article %d", article.ID), err)
 			continue
 		}
+		progress.Advance(1)
 
 		totalScore += analysis.OverallScore
 		totalIssues += len(analysis.TitleAnalysis.Issues) +
@@ -141,7 +151,7 @@ func (s *SEOHealthCheckerService) RunArticleHealthCheck(articleID uint) (*models
 	}
 
 	// Analyze article
-	analysis, err := s.analyzer.AnalyzeContent(&article, article.SEOKeywords, "zh")
+	analysis, err := s.analyzer.AnalyzeContent(&article, article.SEOKeywords, article.DefaultLang)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze article: %w", err)
 	}
@@ -180,7 +190,7 @@ func (s *SEOHealthCheckerService) RunArticleHealthCheck(articleID uint) (*models
 		IssuesFound:      totalIssues,
 		CheckResults:     string(resultsJSON),
 		Suggestions:      string(suggestionsJSON),
-		Language:         "zh",
+		Language:         analysis.Language,
 		CheckDuration:    int(time.Since(startTime).Milliseconds()),
 	}
 
@@ -294,30 +304,25 @@ func (s *SEOHealthCheckerService) GetAutomationRules() ([]models.SEOAutomationRu
 	return rules, nil
 }
 
-// GetSEONotifications retrieves SEO notifications
-func (s *SEOHealthCheckerService) GetSEONotifications(filters map[string]interface{}) ([]models.SEONotification, error) {
-	var notifications []models.SEONotification
-	query := s.db.Preload("Article").Preload("Keyword")
+// GetSEONotifications retrieves the SEO notifications of the notification center
+func (s *SEOHealthCheckerService) GetSEONotifications(filters map[string]interface{}) ([]models.Notification, error) {
+	filter := NotificationFilter{Source: models.NotificationSourceSEO, Limit: 50}
 
 	// Apply filters
-	if isRead, ok := filters["is_read"]; ok {
-		query = query.Where("is_read = ?", isRead)
+	if isRead, ok := filters["is_read"].(bool); ok {
+		filter.IsRead = &isRead
 	}
 
-	if severity, ok := filters["severity"]; ok && severity != "" {
-		query = query.Where("severity = ?", severity)
+	if severity, ok := filters["severity"].(string); ok {
+		filter.Severity = severity
 	}
 
-	if limit, ok := filters["limit"]; ok && limit != nil {
-		query = query.Limit(limit.(int))
-	} else {
-		query = query.Limit(50)
+	if limit, ok := filters["limit"].(int); ok && limit > 0 {
+		filter.Limit = limit
 	}
 
-	// Order by creation date
-	query = query.Order("created_at DESC")
-
-	if err := query.Find(&notifications).Error; err != nil {
+	notifications, _, err := GetGlobalNotificationService().List(context.Background(), filter)
+	if err != nil {
 		return nil, fmt.Errorf("failed to fetch notifications: %w", err)
 	}
 
@@ -326,7 +331,7 @@ func (s *SEOHealthCheckerService) GetSEONotifications(filters map[string]interfa
 
 // MarkNotificationAsRead marks a notification as read
 func (s *SEOHealthCheckerService) MarkNotificationAsRead(notificationID uint) error {
-	if err := s.db.Model(&models.SEONotification{}).Where("id = ?", notificationID).Update("is_read", true).Error; err != nil {
+	if _, err := GetGlobalNotificationService().SetRead(context.Background(), notificationID, true); err != nil {
 		return fmt.Errorf("failed to mark notification as read: %w", err)
 	}
 
@@ -379,7 +384,7 @@ func (s *SEOHealthCheckerService) calculatePerformanceDistribution(articles []mo
 	}
 
 	for _, article := range articles {
-		analysis, err := s.analyzer.AnalyzeContent(&article, "", "zh")
+		analysis, err := s.analyzer.AnalyzeContent(&article, "", article.DefaultLang)
 		if err != nil {
 			continue
 		}
@@ -403,7 +408,7 @@ func (s *SEOHealthCheckerService) identifyTopIssues(articles []models.Article) [
 	issueCount := make(map[string]int)
 
 	for _, article := range articles {
-		analysis, err := s.analyzer.AnalyzeContent(&article, "", "zh")
+		analysis, err := s.analyzer.AnalyzeContent(&article, "", article.DefaultLang)
 		if err != nil {
 			continue
 		}
@@ -492,25 +497,29 @@ func (s *SEOHealthCheckerService) getIssueSeverity(issue string) string {
 }
 
 func (s *SEOHealthCheckerService) createHealthNotifications(healthCheck *models.SEOHealthCheck) {
-	// Create notifications for poor overall scores
+	// Create notifications for poor overall scores, once a day however
+	// often the check runs
+	day := healthCheck.CreatedAt.Format("2006-01-02")
 	if healthCheck.OverallScore < 50 {
-		notification := models.SEONotification{
+		notifyAdmin(NotificationInput{
+			Source:   models.NotificationSourceSEO,
 			Type:     "health_alert",
-			Severity: "critical",
+			Severity: models.SeverityCritical,
 			Title:    "SEO健康状况告警",
 			Message:  fmt.Sprintf("网站SEO整体得分较低 (%d/100)，需要立即优化", healthCheck.OverallScore),
-		}
-		s.db.Create(&notification)
+			Key:      "seo.low_score:" + day,
+		})
 	}
 
 	// Create notifications for high issue counts
 	if healthCheck.IssuesFound > 50 {
-		notification := models.SEONotification{
+		notifyAdmin(NotificationInput{
+			Source:   models.NotificationSourceSEO,
 			Type:     "health_alert",
-			Severity: "warning",
+			Severity: models.SeverityWarning,
 			Title:    "发现大量SEO问题",
 			Message:  fmt.Sprintf("检测到 %d 个SEO问题，建议制定优化计划", healthCheck.IssuesFound),
-		}
-		s.db.Create(&notification)
+			Key:      "seo.many_issues:" + day,
+		})
 	}
 }
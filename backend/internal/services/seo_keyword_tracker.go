@@ -1,6 +1,7 @@
 package services
 
 import (
+	"blog-backend/internal/database"
 	"blog-backend/internal/models"
 	"fmt"
 	"gorm.io/gorm"
@@ -82,9 +83,14 @@ func (s *SEOKeywordTrackerService) GetKeywords(filters map[string]interface{}) (
 		query = query.Where("difficulty = ?", difficulty)
 	}
 
+	keywordData := GetGlobalKeywordDataService()
+	if stale, ok := filters["stale"]; ok && stale == true {
+		query = keywordData.StaleScope(query)
+	}
+
 	// Add search filter
 	if search, ok := filters["search"]; ok && search != "" {
-		query = query.Where("keyword LIKE ? OR notes LIKE ?", "%"+search.(string)+"%", "%"+search.(string)+"%")
+		query = query.Where(database.Like("keyword LIKE ? OR notes LIKE ?"), "%"+search.(string)+"%", "%"+search.(string)+"%")
 	}
 
 	// Order by creation date
@@ -93,6 +99,7 @@ func (s *SEOKeywordTrackerService) GetKeywords(filters map[string]interface{}) (
 	if err := query.Find(&keywords).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch keywords: %w", err)
 	}
+	keywordData.MarkStale(keywords)
 
 	return keywords, nil
 }
@@ -485,16 +492,15 @@ func (s *SEOKeywordTrackerService) createRankingNotification(keyword models.SEOK
 		severity = "warning"
 	}
 
-	notification := models.SEONotification{
+	notifyAdmin(NotificationInput{
+		Source:    models.NotificationSourceSEO,
 		Type:      "ranking_change",
 		Severity:  severity,
 		Title:     title,
 		Message:   message,
 		KeywordID: &keyword.ID,
 		ArticleID: keyword.ArticleID,
-	}
-
-	s.db.Create(&notification)
+	})
 }
 
 func (s *SEOKeywordTrackerService) generateRandomColor() string {
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"blog-backend/internal/keywords"
+	"fmt"
+	"strings"
+)
+
+// seoMessageLanguage is the language of SEO analysis messages for languages
+// without translations
+const seoMessageLanguage = "en"
+
+// seoMessages holds the issues and suggestions of the SEO analysis by key
+// and base language. Placeholders are filled in from the rules applied, so
+// the messages quote the thresholds of the analyzed language.
+var seoMessages = map[string]map[string]string{
+	"title_too_short": {
+		"en": "The title is too short; use at least %d characters",
+		"zh": "标题太短，建议至少%d个字符",
+	},
+	"title_too_short_fix": {
+		"en": "Add keywords or descriptive words to the title",
+		"zh": "增加关键词或描述性词语来丰富标题",
+	},
+	"title_too_long": {
+		"en": "The title is too long and may be cut off in search results",
+		"zh": "标题太长，可能在搜索结果中被截断",
+	},
+	"title_too_long_fix": {
+		"en": "Shorten the title to at most %d characters",
+		"zh": "精简标题，保持在%d个字符以内",
+	},
+	"title_missing_keyword": {
+		"en": "The title does not contain the focus keyword",
+		"zh": "标题中缺少焦点关键词",
+	},
+	"title_missing_keyword_fix": {
+		"en": "Include the keyword in the title: %s",
+		"zh": "在标题中包含关键词：%s",
+	},
+	"description_missing": {
+		"en": "The meta description is missing",
+		"zh": "缺少元描述",
+	},
+	"description_missing_fix": {
+		"en": "Add a meta description of %d-%d characters",
+		"zh": "添加%d-%d字符的元描述",
+	},
+	"description_too_short": {
+		"en": "The meta description is too short",
+		"zh": "元描述太短",
+	},
+	"description_too_short_fix": {
+		"en": "Expand the description to show what the article offers",
+		"zh": "增加描述内容，突出文章价值",
+	},
+	"description_too_long": {
+		"en": "The meta description is too long and may be cut off",
+		"zh": "元描述太长，可能被截断",
+	},
+	"description_too_long_fix": {
+		"en": "Shorten the description to at most %d characters",
+		"zh": "精简描述，保持在%d字符以内",
+	},
+	"description_missing_keyword": {
+		"en": "The meta description does not contain the focus keyword",
+		"zh": "元描述中缺少焦点关键词",
+	},
+	"description_missing_keyword_fix": {
+		"en": "Work the keyword naturally into the description",
+		"zh": "在描述中自然地包含关键词",
+	},
+	"description_call_to_action": {
+		"en": "Consider adding a call to action such as \"learn more\" or \"read now\"",
+		"zh": "考虑添加行动号召词语，如：了解更多、立即查看等",
+	},
+	"content_too_short": {
+		"en": "The content is too short; aim for at least %d words",
+		"zh": "内容太短，建议至少%d字",
+	},
+	"content_too_short_fix": {
+		"en": "Expand the content with more detail",
+		"zh": "扩展内容，提供更详细的信息",
+	},
+	"content_too_long_fix": {
+		"en": "Consider splitting the article into a series",
+		"zh": "考虑将长文章分割成系列文章",
+	},
+	"content_no_internal_links": {
+		"en": "There are no internal links",
+		"zh": "缺少内部链接",
+	},
+	"content_no_internal_links_fix": {
+		"en": "Link to 2-3 related articles",
+		"zh": "添加2-3个相关文章的内部链接",
+	},
+	"content_no_external_links_fix": {
+		"en": "Consider linking to authoritative external sources",
+		"zh": "考虑添加权威来源的外部链接",
+	},
+	"keyword_missing": {
+		"en": "No focus keyword is set",
+		"zh": "未设置焦点关键词",
+	},
+	"keyword_missing_fix": {
+		"en": "Set a main keyword to optimize the content for",
+		"zh": "设置一个主要关键词来优化内容",
+	},
+	"keyword_density_low": {
+		"en": "The keyword density is below %.1f%%",
+		"zh": "关键词密度过低，建议不低于%.1f%%",
+	},
+	"keyword_density_low_fix": {
+		"en": "Use the keyword a little more often",
+		"zh": "适当增加关键词使用频率",
+	},
+	"keyword_density_high": {
+		"en": "The keyword density is above %.1f%% and may look like keyword stuffing",
+		"zh": "关键词密度超过%.1f%%，可能被视为堆砌",
+	},
+	"keyword_density_high_fix": {
+		"en": "Use the keyword less so the text reads naturally",
+		"zh": "减少关键词使用，使内容更自然",
+	},
+	"keyword_not_in_title": {
+		"en": "The keyword is missing from the title",
+		"zh": "标题中缺少关键词",
+	},
+	"keyword_not_in_headings_fix": {
+		"en": "Include the keyword in a subheading",
+		"zh": "在副标题中包含关键词",
+	},
+	"readability_long_sentences": {
+		"en": "Sentences are too long on average",
+		"zh": "句子平均长度过长",
+	},
+	"readability_long_sentences_fix": {
+		"en": "Use shorter sentences to make the text easier to read",
+		"zh": "使用更短的句子提高可读性",
+	},
+	"readability_long_paragraphs": {
+		"en": "Paragraphs are too long",
+		"zh": "段落过长",
+	},
+	"readability_long_paragraphs_fix": {
+		"en": "Split long paragraphs into shorter ones",
+		"zh": "将长段落分成更短的段落",
+	},
+	"readability_passive": {
+		"en": "The passive voice is used too often",
+		"zh": "被动语态使用过多",
+	},
+	"readability_passive_fix": {
+		"en": "Use the active voice more",
+		"zh": "使用更多主动语态",
+	},
+	"heading_no_h1": {
+		"en": "There is no H1 heading",
+		"zh": "缺少H1标题",
+	},
+	"heading_many_h1": {
+		"en": "There are several H1 headings",
+		"zh": "H1标题过多",
+	},
+	"heading_no_h2": {
+		"en": "There are no H2 subheadings",
+		"zh": "缺少H2副标题",
+	},
+	"images_missing_alt": {
+		"en": "Some images have no alt text",
+		"zh": "部分图片缺少alt属性",
+	},
+	"summary_title": {
+		"en": "Improve the title: %s",
+		"zh": "优化标题：%s",
+	},
+	"summary_description": {
+		"en": "Improve the meta description: %s",
+		"zh": "改进元描述：%s",
+	},
+	"summary_keyword": {
+		"en": "Improve keyword usage: %s",
+		"zh": "优化关键词使用：%s",
+	},
+	"summary_content": {
+		"en": "Improve the content structure: %s",
+		"zh": "改进内容结构：%s",
+	},
+	"summary_fresh": {
+		"en": "Update the content regularly to keep it fresh",
+		"zh": "定期更新内容以保持新鲜度",
+	},
+	"summary_valuable": {
+		"en": "Make sure the content is valuable to readers",
+		"zh": "确保内容对用户有价值",
+	},
+}
+
+// seoListSeparators joins suggestions within a summary line
+var seoListSeparators = map[string]string{
+	"en": "; ",
+	"zh": "，",
+}
+
+// seoMessage returns an SEO analysis message in a language, falling back to
+// English
+func seoMessage(language, key string, args ...interface{}) string {
+	texts := seoMessages[key]
+	text, ok := texts[keywords.BaseLanguage(language)]
+	if !ok {
+		text = texts[seoMessageLanguage]
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// seoList joins messages the way a language separates list items
+func seoList(language string, items []string) string {
+	separator, ok := seoListSeparators[keywords.BaseLanguage(language)]
+	if !ok {
+		separator = seoListSeparators[seoMessageLanguage]
+	}
+	return strings.Join(items, separator)
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/keywords"
+	"blog-backend/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidSEORules is returned for SEO rules that cannot be saved
+var ErrInvalidSEORules = errors.New("invalid SEO rules")
+
+const (
+	// seoRulesSettingKey is the system setting holding the rules saved by
+	// an admin, by base language
+	seoRulesSettingKey = "seo_rules"
+	// seoRulesDefault is the key of the rules for languages without their own
+	seoRulesDefault = "default"
+)
+
+// SEORules are the thresholds the SEO analysis measures an article against.
+// Lengths are in characters, content in words as the language's tokenizer
+// splits them and keyword density in percent of those words.
+type SEORules struct {
+	Language          string       `json:"language"`
+	TitleLength       models.Range `json:"title_length"`
+	DescriptionLength models.Range `json:"description_length"`
+	ContentWords      models.Range `json:"content_words"`
+	KeywordDensityMin float64      `json:"keyword_density_min"`
+	KeywordDensityMax float64      `json:"keyword_density_max"`
+	Customized        bool         `json:"customized"`
+}
+
+// builtinSEORules are the rules used unless an admin saves others. Search
+// results cut titles and descriptions by display width, so languages
+// written in wide characters get about half the Latin lengths.
+var builtinSEORules = map[string]SEORules{
+	seoRulesDefault: {
+		TitleLength:       models.Range{Min: 30, Max: 60},
+		DescriptionLength: models.Range{Min: 120, Max: 160},
+		ContentWords:      models.Range{Min: 300, Max: 3000},
+		KeywordDensityMin: 0.5,
+		KeywordDensityMax: 2.5,
+	},
+	"zh": {
+		TitleLength:       models.Range{Min: 15, Max: 30},
+		DescriptionLength: models.Range{Min: 50, Max: 80},
+		ContentWords:      models.Range{Min: 300, Max: 3000},
+		KeywordDensityMin: 1,
+		KeywordDensityMax: 3,
+	},
+	"ja": {
+		TitleLength:       models.Range{Min: 15, Max: 32},
+		DescriptionLength: models.Range{Min: 60, Max: 120},
+		ContentWords:      models.Range{Min: 300, Max: 3000},
+		KeywordDensityMin: 1,
+		KeywordDensityMax: 3,
+	},
+	"ko": {
+		TitleLength:       models.Range{Min: 15, Max: 40},
+		DescriptionLength: models.Range{Min: 50, Max: 100},
+		ContentWords:      models.Range{Min: 200, Max: 2500},
+		KeywordDensityMin: 0.5,
+		KeywordDensityMax: 2.5,
+	},
+}
+
+// SEORuleService keeps the SEO analysis thresholds of each language: the
+// built-in ones, unless an admin saved rules of their own for the language.
+// Languages with neither use the default rules, which can be saved as well.
+type SEORuleService struct {
+	db func() *gorm.DB
+
+	mu    sync.RWMutex
+	saved map[string]SEORules // nil until loaded
+}
+
+// NewSEORuleService creates an SEO rule service
+func NewSEORuleService() *SEORuleService {
+	return &SEORuleService{db: func() *gorm.DB { return database.DB }}
+}
+
+// Rules returns the rules for a language tag. If the saved rules cannot be
+// read the built-in ones apply and the next call retries.
+func (s *SEORuleService) Rules(language string) SEORules {
+	saved, err := s.current()
+	if err != nil {
+		slog.Warn("Failed to load SEO rules", "error", err)
+	}
+	return resolveSEORules(saved, keywords.BaseLanguage(language))
+}
+
+// List returns the rules of every language with built-in or saved rules,
+// the default rules first
+func (s *SEORuleService) List() ([]SEORules, error) {
+	saved, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	languages := []string{}
+	for language := range builtinSEORules {
+		languages = append(languages, language)
+	}
+	for language := range saved {
+		if _, ok := builtinSEORules[language]; !ok {
+			languages = append(languages, language)
+		}
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if (languages[i] == seoRulesDefault) != (languages[j] == seoRulesDefault) {
+			return languages[i] == seoRulesDefault
+		}
+		return languages[i] < languages[j]
+	})
+	rules := make([]SEORules, 0, len(languages))
+	for _, language := range languages {
+		rules = append(rules, resolveSEORules(saved, language))
+	}
+	return rules, nil
+}
+
+// Save replaces the rules of a language, or the default rules for
+// "default"
+func (s *SEORuleService) Save(language string, rules SEORules) (*SEORules, error) {
+	key, err := seoRulesLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSEORules(rules); err != nil {
+		return nil, err
+	}
+	saved, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	rules.Language, rules.Customized = key, true
+	saved[key] = rules
+	if err := s.store(saved); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// Reset removes the saved rules of a language so the built-in or default
+// rules apply again
+func (s *SEORuleService) Reset(language string) (*SEORules, error) {
+	key, err := seoRulesLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	delete(saved, key)
+	if err := s.store(saved); err != nil {
+		return nil, err
+	}
+	rules := resolveSEORules(saved, key)
+	return &rules, nil
+}
+
+// current returns the saved rules, loading them on first use
+func (s *SEORuleService) current() (map[string]SEORules, error) {
+	s.mu.RLock()
+	saved := s.saved
+	s.mu.RUnlock()
+	if saved != nil {
+		return saved, nil
+	}
+	if s.db() == nil {
+		return nil, nil
+	}
+
+	saved, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.saved = saved
+	s.mu.Unlock()
+	return saved, nil
+}
+
+func (s *SEORuleService) load() (map[string]SEORules, error) {
+	saved := make(map[string]SEORules)
+	var setting models.SystemSetting
+	err := s.db().Limit(1).Find(&setting, models.SystemSetting{Key: seoRulesSettingKey}).Error
+	if err != nil || setting.Value == "" {
+		return saved, err
+	}
+	err = json.Unmarshal([]byte(setting.Value), &saved)
+	return saved, err
+}
+
+func (s *SEORuleService) store(saved map[string]SEORules) error {
+	value, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	setting := models.SystemSetting{Key: seoRulesSettingKey, Value: string(value)}
+	if err := s.db().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&setting).Error; err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.saved = saved
+	s.mu.Unlock()
+	cache.Publish(cache.TopicSEORules)
+	return nil
+}
+
+func (s *SEORuleService) reset() {
+	s.mu.Lock()
+	s.saved = nil
+	s.mu.Unlock()
+}
+
+// resolveSEORules picks the saved rules of a language, then its built-in
+// rules, then the saved and built-in default rules
+func resolveSEORules(saved map[string]SEORules, language string) SEORules {
+	rules, ok := saved[language]
+	if !ok {
+		rules, ok = builtinSEORules[language]
+	}
+	if !ok {
+		if rules, ok = saved[seoRulesDefault]; !ok {
+			rules = builtinSEORules[seoRulesDefault]
+		}
+	}
+	rules.Language = language
+	return rules
+}
+
+func seoRulesLanguage(language string) (string, error) {
+	language = strings.TrimSpace(language)
+	if language == seoRulesDefault {
+		return language, nil
+	}
+	if !languageCode.MatchString(language) {
+		return "", fmt.Errorf("%w: %q is not a language code", ErrInvalidSEORules, language)
+	}
+	return keywords.BaseLanguage(language), nil
+}
+
+func validateSEORules(rules SEORules) error {
+	for name, r := range map[string]models.Range{
+		"title_length":       rules.TitleLength,
+		"description_length": rules.DescriptionLength,
+		"content_words":      rules.ContentWords,
+	} {
+		if r.Min < 1 || r.Max < r.Min {
+			return fmt.Errorf("%w: %s needs 0 < min <= max", ErrInvalidSEORules, name)
+		}
+	}
+	if rules.KeywordDensityMin < 0 || rules.KeywordDensityMax <= rules.KeywordDensityMin || rules.KeywordDensityMax > 100 {
+		return fmt.Errorf("%w: keyword density needs 0 <= min < max <= 100", ErrInvalidSEORules)
+	}
+	return nil
+}
+
+// Global SEO rule service instance
+var (
+	globalSEORuleService     *SEORuleService
+	globalSEORuleServiceOnce sync.Once
+)
+
+// GetGlobalSEORuleService returns the global SEO rule service
+func GetGlobalSEORuleService() *SEORuleService {
+	globalSEORuleServiceOnce.Do(func() {
+		globalSEORuleService = NewSEORuleService()
+		cache.Subscribe(cache.TopicSEORules, globalSEORuleService.reset)
+	})
+	return globalSEORuleService
+}
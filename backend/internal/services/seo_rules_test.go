@@ -0,0 +1,105 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSEORulesPerLanguage(t *testing.T) {
+	newTestDB(t)
+	s := NewSEORuleService()
+
+	if rules := s.Rules("zh-CN"); rules.Language != "zh" || rules.TitleLength.Max != 30 || rules.Customized {
+		t.Errorf("Rules(zh-CN) = %+v", rules)
+	}
+	if rules := s.Rules("fr"); rules.TitleLength != (models.Range{Min: 30, Max: 60}) {
+		t.Errorf("Rules(fr) = %+v, want the default rules", rules)
+	}
+
+	custom := SEORules{
+		TitleLength:       models.Range{Min: 20, Max: 70},
+		DescriptionLength: models.Range{Min: 100, Max: 150},
+		ContentWords:      models.Range{Min: 500, Max: 4000},
+		KeywordDensityMin: 1,
+		KeywordDensityMax: 2,
+	}
+	if _, err := s.Save("default", custom); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Save("ZH-tw", custom); err != nil {
+		t.Fatal(err)
+	}
+	if rules := s.Rules("fr"); rules.TitleLength.Max != 70 || !rules.Customized {
+		t.Errorf("Rules(fr) after saving the default = %+v", rules)
+	}
+
+	// Other instances read the saved rules
+	other := NewSEORuleService()
+	if rules := other.Rules("zh"); rules.ContentWords.Min != 500 {
+		t.Errorf("saved zh rules not shared: %+v", rules)
+	}
+	list, err := other.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var languages []string
+	for _, rules := range list {
+		languages = append(languages, rules.Language)
+	}
+	if strings.Join(languages, ",") != "default,ja,ko,zh" {
+		t.Errorf("List() languages = %v", languages)
+	}
+
+	reset, err := s.Reset("zh")
+	if err != nil || reset.TitleLength.Max != 30 || reset.Customized {
+		t.Errorf("Reset() = %+v, %v", reset, err)
+	}
+
+	invalid := custom
+	invalid.KeywordDensityMax = 0.5
+	if _, err := s.Save("en", invalid); !errors.Is(err, ErrInvalidSEORules) {
+		t.Errorf("Save() with min density above max = %v", err)
+	}
+	if _, err := s.Save("not a language", custom); !errors.Is(err, ErrInvalidSEORules) {
+		t.Errorf("Save() for a bad language = %v", err)
+	}
+}
+
+func TestSEOAnalysisLocalized(t *testing.T) {
+	rules := map[string]SEORules{
+		"en": resolveSEORules(nil, "en"),
+		"zh": resolveSEORules(nil, "zh"),
+	}
+	s := &SEOAnalyzerService{rules: func(language string) SEORules { return rules[language] }}
+
+	// Twenty characters is a short title in English and a good one in Chinese
+	article := &models.Article{Title: "数据库索引的设计原则与实践：从入门到精通", Content: "正文"}
+	zh, _ := s.AnalyzeContent(article, "", "zh")
+	if len(zh.TitleAnalysis.Issues) != 0 || zh.TitleAnalysis.OptimalLength.Max != 30 {
+		t.Errorf("zh title analysis = %+v", zh.TitleAnalysis)
+	}
+	if zh.KeywordAnalysis.Issues[0] != "未设置焦点关键词" || zh.Language != "zh" {
+		t.Errorf("zh keyword issues = %v", zh.KeywordAnalysis.Issues)
+	}
+
+	article = &models.Article{Title: "Database indexes", Content: "Short."}
+	en, _ := s.AnalyzeContent(article, "index", "en")
+	if got := en.TitleAnalysis.Issues; len(got) != 1 || got[0] != "The title is too short; use at least 30 characters" {
+		t.Errorf("en title issues = %q", got)
+	}
+	for _, suggestion := range en.Suggestions {
+		if strings.ContainsAny(suggestion, "，：") {
+			t.Errorf("English suggestion with Chinese punctuation: %q", suggestion)
+		}
+	}
+	if en.KeywordAnalysis.OptimalDensity != (models.Range{Min: 50, Max: 250}) {
+		t.Errorf("optimal density = %+v", en.KeywordAnalysis.OptimalDensity)
+	}
+
+	// Languages without translations fall back to English
+	if got := seoMessage("fr", "heading_no_h1"); got != "There is no H1 heading" {
+		t.Errorf("French message = %q", got)
+	}
+}
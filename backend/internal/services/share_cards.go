@@ -0,0 +1,281 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/models"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	wechatAPI = "https://api.weixin.qq.com/cgi-bin"
+	// shareDescriptionLength is the longest description on a share card;
+	// the apps cut longer ones to two or three lines
+	shareDescriptionLength = 120
+	// wechatTicketMargin is how long before WeChat expires a JS-SDK ticket
+	// a new one is fetched
+	wechatTicketMargin = 5 * time.Minute
+)
+
+// wechatJSAPIs are the JS-SDK calls share cards are signed for: the share
+// menus of chats and Moments, and the older versions of both
+var wechatJSAPIs = []string{"updateAppMessageShareData", "updateTimelineShareData", "onMenuShareAppMessage", "onMenuShareTimeline"}
+
+var ErrShareNotConfigured = errors.New("WeChat sharing is not configured")
+
+// ShareContent is what a share card shows: absolute addresses of the page
+// and its image, a title and a short description
+type ShareContent struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image,omitempty"`
+}
+
+// ArticleShareContent returns the share content of an article with the
+// translation to language applied, linked at link. The SEO description
+// or summary describe it, and its cover image, or else fallbackImage,
+// shows on the card.
+func ArticleShareContent(article *models.Article, language, link, baseURL, fallbackImage string) ShareContent {
+	description := article.Summary
+	if language == article.DefaultLang && article.SEODescription != "" {
+		description = article.SEODescription
+	}
+	image := fallbackImage
+	if article.CoverImageURL != nil && *article.CoverImageURL != "" {
+		image = *article.CoverImageURL
+	}
+	if image != "" {
+		image = absoluteURL(baseURL, image)
+	}
+	return ShareContent{
+		URL:         link,
+		Title:       article.Title,
+		Description: truncateRunes(strings.Join(strings.Fields(description), " "), shareDescriptionLength),
+		Image:       image,
+	}
+}
+
+// ShareCard is a page's share metadata in the forms Chinese apps read.
+// WeChat is the wx.config payload for the JS-SDK, set when a WeChat
+// official account is configured, after which the page passes the share
+// content to the share calls; Weibo, QQ and QZone are share links; Meta
+// are the schema.org tags QQ and WeChat read for link cards when the page
+// is shared without the JS-SDK.
+type ShareCard struct {
+	ShareContent
+	WeChat *WeChatShareConfig `json:"wechat,omitempty"`
+	Weibo  string             `json:"weibo"`
+	QQ     string             `json:"qq"`
+	QZone  string             `json:"qzone"`
+	Meta   []ShareMeta        `json:"meta"`
+}
+
+// WeChatShareConfig is the wx.config payload signed for one page address,
+// in the JS-SDK's field names
+type WeChatShareConfig struct {
+	AppID     string   `json:"appId"`
+	Timestamp int64    `json:"timestamp"`
+	NonceStr  string   `json:"nonceStr"`
+	Signature string   `json:"signature"`
+	JSAPIList []string `json:"jsApiList"`
+}
+
+// ShareMeta is a meta tag for a page head
+type ShareMeta struct {
+	ItemProp string `json:"itemprop"`
+	Content  string `json:"content"`
+}
+
+// ShareCardService builds share cards. JS-SDK tickets are cached for the
+// instance, or across instances with a shared cache, as WeChat limits how
+// often they may be fetched.
+type ShareCardService struct {
+	appID       string
+	appSecret   string
+	weiboAppKey string
+	api         string
+	client      *http.Client
+	tickets     *cache.Namespace
+	now         func() time.Time
+	nonce       func() string
+	mu          sync.Mutex
+}
+
+// NewShareCardService creates a share card service from the configuration
+func NewShareCardService() *ShareCardService {
+	cfg := config.Get().Share
+	return &ShareCardService{
+		appID:       cfg.WeChatAppID,
+		appSecret:   cfg.WeChatAppSecret,
+		weiboAppKey: cfg.WeiboAppKey,
+		api:         wechatAPI,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		tickets:     cache.New("wechat_tickets", 2*time.Hour),
+		now:         time.Now,
+		nonce:       shareNonce,
+	}
+}
+
+// Card returns the share card of content. pageURL is the address of the
+// page the JS-SDK runs on, which the WeChat signature is bound to; it
+// defaults to the content's URL. When WeChat fails the card is returned
+// without the signature along with the error.
+func (s *ShareCardService) Card(ctx context.Context, content ShareContent, pageURL string) (*ShareCard, error) {
+	card := &ShareCard{
+		ShareContent: content,
+		Weibo:        shareLink("https://service.weibo.com/share/share.php", "url", content.URL, "title", content.Title, "pic", content.Image, "appkey", s.weiboAppKey),
+		QQ:           shareLink("https://connect.qq.com/widget/shareqq/index.html", "url", content.URL, "title", content.Title, "desc", content.Description, "summary", content.Description, "pics", content.Image),
+		QZone:        shareLink("https://sns.qzone.qq.com/cgi-bin/qzshare/cgi_qzshare_onekey", "url", content.URL, "title", content.Title, "desc", content.Description, "summary", content.Description, "pics", content.Image),
+		Meta: []ShareMeta{
+			{ItemProp: "name", Content: content.Title},
+			{ItemProp: "description", Content: content.Description},
+		},
+	}
+	if content.Image != "" {
+		card.Meta = append(card.Meta, ShareMeta{ItemProp: "image", Content: content.Image})
+	}
+	if s.appID == "" {
+		return card, nil
+	}
+	if pageURL == "" {
+		pageURL = content.URL
+	}
+	signed, err := s.Sign(ctx, pageURL)
+	if err != nil {
+		return card, err
+	}
+	card.WeChat = signed
+	return card, nil
+}
+
+// Sign returns the wx.config payload for the page at pageURL. The
+// signature covers the address without its fragment, as WeChat computes
+// it in the page.
+func (s *ShareCardService) Sign(ctx context.Context, pageURL string) (*WeChatShareConfig, error) {
+	if s.appID == "" {
+		return nil, ErrShareNotConfigured
+	}
+	ticket, err := s.ticket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pageURL, _, _ = strings.Cut(pageURL, "#")
+	signed := &WeChatShareConfig{
+		AppID:     s.appID,
+		Timestamp: s.now().Unix(),
+		NonceStr:  s.nonce(),
+		JSAPIList: wechatJSAPIs,
+	}
+	// The fields in alphabetical order, joined as a query string but not
+	// escaped
+	sum := sha1.Sum([]byte(fmt.Sprintf("jsapi_ticket=%s&noncestr=%s&timestamp=%d&url=%s", ticket, signed.NonceStr, signed.Timestamp, pageURL)))
+	signed.Signature = hex.EncodeToString(sum[:])
+	return signed, nil
+}
+
+// ticket returns a cached JS-SDK ticket, fetching a new one with a new
+// access token when it has expired
+func (s *ShareCardService) ticket(ctx context.Context) (string, error) {
+	var ticket string
+	if s.tickets.Get(s.appID, &ticket) {
+		return ticket, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tickets.Get(s.appID, &ticket) {
+		return ticket, nil
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	query := url.Values{"grant_type": {"client_credential"}, "appid": {s.appID}, "secret": {s.appSecret}}
+	if _, err := s.call(ctx, "/token?"+query.Encode(), &token); err != nil {
+		return "", err
+	}
+	var result struct {
+		Ticket string `json:"ticket"`
+	}
+	query = url.Values{"access_token": {token.AccessToken}, "type": {"jsapi"}}
+	expiresIn, err := s.call(ctx, "/ticket/getticket?"+query.Encode(), &result)
+	if err != nil {
+		return "", err
+	}
+	if ttl := time.Duration(expiresIn)*time.Second - wechatTicketMargin; ttl > 0 {
+		s.tickets.SetWithTTL(s.appID, result.Ticket, ttl)
+	}
+	return result.Ticket, nil
+}
+
+// call requests a WeChat API and decodes its answer into dest, returning
+// the expires_in it carries
+func (s *ShareCardService) call(ctx context.Context, path string, dest interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.api+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("wechat: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return 0, fmt.Errorf("wechat: %w", err)
+	}
+	var status struct {
+		ErrCode   int    `json:"errcode"`
+		ErrMsg    string `json:"errmsg"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return 0, fmt.Errorf("wechat: HTTP %d: %v", resp.StatusCode, err)
+	}
+	if status.ErrCode != 0 {
+		return 0, fmt.Errorf("wechat: error %d: %s", status.ErrCode, status.ErrMsg)
+	}
+	return status.ExpiresIn, json.Unmarshal(body, dest)
+}
+
+// shareLink adds the non-empty pairs of params to a share address
+func shareLink(base string, params ...string) string {
+	query := url.Values{}
+	for i := 0; i+1 < len(params); i += 2 {
+		if params[i+1] != "" {
+			query.Set(params[i], params[i+1])
+		}
+	}
+	return base + "?" + query.Encode()
+}
+
+func shareNonce() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+var (
+	globalShareCardService     *ShareCardService
+	globalShareCardServiceOnce sync.Once
+)
+
+// GetGlobalShareCardService returns the global share card service
+func GetGlobalShareCardService() *ShareCardService {
+	globalShareCardServiceOnce.Do(func() {
+		globalShareCardService = NewShareCardService()
+	})
+	return globalShareCardService
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareCard(t *testing.T) {
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.URL.Path]++
+		switch r.URL.Path {
+		case "/token":
+			if r.URL.Query().Get("secret") != "s3cret" {
+				w.Write([]byte(`{"errcode":40125,"errmsg":"invalid appsecret"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"TOKEN","expires_in":7200}`))
+		case "/ticket/getticket":
+			if r.URL.Query().Get("access_token") != "TOKEN" {
+				t.Errorf("ticket requested with %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok","ticket":"sM4AOVdWfPE4DxkXGEs8VMCPGGVi4C3VM0P37wVUCFvkVAy_90u5h9nbSlYy3-Sl-HhTdfl2fzFy1AOcHKP7qg","expires_in":7200}`))
+		}
+	}))
+	defer server.Close()
+
+	s := &ShareCardService{
+		appID:       "wx123",
+		appSecret:   "s3cret",
+		weiboAppKey: "9876",
+		api:         server.URL,
+		client:      server.Client(),
+		tickets:     cache.New("test_wechat_tickets", time.Hour),
+		now:         func() time.Time { return time.Unix(1414587457, 0) },
+		nonce:       func() string { return "Wm3WZYTPz0wzccnW" },
+	}
+	t.Cleanup(s.tickets.Clear)
+	ctx := context.Background()
+
+	cover := "/uploads/images/cover.png"
+	article := &models.Article{
+		ID: 1, Title: "Hello", DefaultLang: "zh", Summary: strings.Repeat("长", 200),
+		SEODescription: "  A  short\ndescription ", CoverImageURL: &cover,
+	}
+	content := ArticleShareContent(article, "zh", "https://blog.example.com/zh/article/hello", "https://blog.example.com", "/uploads/branding/logo.png")
+	if content.Description != "A short description" || content.Image != "https://blog.example.com/uploads/images/cover.png" {
+		t.Errorf("content = %+v", content)
+	}
+	if en := ArticleShareContent(article, "en", "", "https://blog.example.com", ""); len([]rune(en.Description)) != shareDescriptionLength {
+		t.Errorf("translated description has %d characters", len([]rune(en.Description)))
+	}
+
+	// The example of the WeChat JS-SDK documentation
+	card, err := s.Card(ctx, content, "http://mp.weixin.qq.com?params=value#section")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.WeChat == nil || card.WeChat.Signature != "0f9de62fce790f9a083d5c99e95740ceb90c27ed" || card.WeChat.AppID != "wx123" {
+		t.Errorf("wechat = %+v", card.WeChat)
+	}
+	weibo, _ := url.Parse(card.Weibo)
+	if weibo.Host != "service.weibo.com" || weibo.Query().Get("appkey") != "9876" || weibo.Query().Get("pic") != content.Image {
+		t.Errorf("weibo = %s", card.Weibo)
+	}
+	if qq, _ := url.Parse(card.QQ); qq.Query().Get("desc") != content.Description || qq.Query().Get("url") != content.URL {
+		t.Errorf("qq = %s", card.QQ)
+	}
+	if len(card.Meta) != 3 || card.Meta[2].ItemProp != "image" {
+		t.Errorf("meta = %+v", card.Meta)
+	}
+
+	// The ticket is fetched once
+	if _, err := s.Card(ctx, content, ""); err != nil {
+		t.Fatal(err)
+	}
+	if calls["/token"] != 1 || calls["/ticket/getticket"] != 1 {
+		t.Errorf("calls = %v", calls)
+	}
+
+	// Errors from WeChat leave the signature out
+	s.tickets.Clear()
+	s.appSecret = "wrong"
+	card, err = s.Card(ctx, content, "")
+	if err == nil || !strings.Contains(err.Error(), "40125") || card == nil || card.WeChat != nil || card.QQ == "" {
+		t.Errorf("card = %+v, %v", card, err)
+	}
+
+	// Without an account there is nothing to sign
+	s.appID = ""
+	if card, err := s.Card(ctx, content, ""); err != nil || card.WeChat != nil {
+		t.Errorf("unconfigured card = %+v, %v", card, err)
+	}
+}
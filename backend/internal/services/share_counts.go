@@ -0,0 +1,458 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrShareCountsNotConfigured is returned when no share count platform is
+// configured
+var ErrShareCountsNotConfigured = errors.New("no share count platform is configured")
+
+// ShareCountsScheduleName is the scheduler entry for SHARE_COUNTS_SCHEDULE
+const ShareCountsScheduleName = "share-counts"
+
+// ShareTally is what a platform reports about one page
+type ShareTally struct {
+	Count    int64 `json:"count"`
+	Comments int64 `json:"comments"`
+}
+
+// shareCountPlatform reads how often a page was shared on one platform
+type shareCountPlatform interface {
+	Name() string
+	Count(ctx context.Context, pageURL string) (ShareTally, error)
+}
+
+// shareCountJob is the payload of JobShareCounts; without an article every
+// article published within the maximum age is checked
+type shareCountJob struct {
+	ArticleID uint `json:"article_id,omitempty"`
+}
+
+// ShareCountResult describes one collection run
+type ShareCountResult struct {
+	Articles int `json:"articles"`
+	Checked  int `json:"checked"`
+	Changed  int `json:"changed"`
+	Failed   int `json:"failed"`
+}
+
+// PlatformShares is an article's latest count on one platform with the
+// samples that led to it, oldest first
+type PlatformShares struct {
+	Platform  string                     `json:"platform"`
+	Count     int64                      `json:"count"`
+	Comments  int64                      `json:"comments"`
+	FetchedAt time.Time                  `json:"fetched_at"`
+	History   []models.ArticleShareCount `json:"history"`
+}
+
+// ArticleShares sums up how often an article was shared
+type ArticleShares struct {
+	Total     int64            `json:"total"`
+	Platforms []PlatformShares `json:"platforms"`
+}
+
+// ShareCountService collects how often articles are shared on the social
+// platforms that publish counts. Each article is looked up under the link
+// of every language it is served in, and a sample is kept whenever a
+// platform's numbers change.
+type ShareCountService struct {
+	db        func() *gorm.DB
+	now       func() time.Time
+	platforms []shareCountPlatform
+	maxAge    time.Duration
+	baseURL   func(siteID uint) string
+}
+
+// NewShareCountService creates a share count service from the
+// SHARE_COUNTS_* settings
+func NewShareCountService() *ShareCountService {
+	cfg := config.Get().ShareCounts
+	s := &ShareCountService{
+		db:      func() *gorm.DB { return database.DB },
+		now:     time.Now,
+		maxAge:  cfg.MaxAge.Std(),
+		baseURL: siteBaseURL,
+	}
+	client := &http.Client{Timeout: 20 * time.Second}
+	for _, name := range cfg.Platforms {
+		switch name {
+		case "hackernews":
+			s.platforms = append(s.platforms, &hackerNewsShares{baseURL: "https://hn.algolia.com", client: client})
+		case "v2ex":
+			s.platforms = append(s.platforms, &v2exShares{baseURL: "https://www.sov2ex.com", client: client})
+		case "facebook":
+			token, err := security.ResolveSecret(cfg.FacebookToken)
+			if err != nil {
+				slog.Error("Facebook share counts disabled", "error", err)
+				continue
+			}
+			s.platforms = append(s.platforms, &facebookShares{baseURL: "https://graph.facebook.com/v19.0", token: token, client: client})
+		case "x":
+			token, err := security.ResolveSecret(cfg.XBearerToken)
+			if err != nil {
+				slog.Error("X share counts disabled", "error", err)
+				continue
+			}
+			s.platforms = append(s.platforms, &xShares{baseURL: "https://api.x.com", token: token, client: client})
+		}
+	}
+	return s
+}
+
+// Enabled reports whether any platform is configured
+func (s *ShareCountService) Enabled() bool {
+	return len(s.platforms) > 0
+}
+
+// Queue queues a collection of one article's share counts
+func (s *ShareCountService) Queue(ctx context.Context, siteID, articleID uint) (*models.Job, error) {
+	if !s.Enabled() {
+		return nil, ErrShareCountsNotConfigured
+	}
+	var count int64
+	if err := s.db().WithContext(ctx).Model(&models.Article{}).Where("id = ? AND site_id = ?", articleID, siteID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrArticleNotFound
+	}
+	return GetGlobalJobQueue().Enqueue(JobShareCounts, shareCountJob{ArticleID: articleID})
+}
+
+// Collect fetches the share counts of one article, or of every article
+// published within the maximum age when the payload names none. A
+// platform failing for an article is reported and the run goes on.
+func (s *ShareCountService) Collect(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	if !s.Enabled() {
+		return nil, ErrShareCountsNotConfigured
+	}
+	var job shareCountJob
+	if len(payload) > 0 && string(payload) != "null" {
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return nil, err
+		}
+	}
+	now := s.now()
+	query := s.db().WithContext(ctx).Preload("Translations", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "article_id", "language")
+	})
+	if job.ArticleID != 0 {
+		query = query.Where("id = ?", job.ArticleID)
+	} else {
+		query = query.Where("created_at > ? AND created_at <= ?", now.Add(-s.maxAge), now)
+	}
+	var articles []models.Article
+	if err := query.Select("id", "site_id", "title", "default_lang").Order("id").Find(&articles).Error; err != nil {
+		return nil, err
+	}
+
+	result := &ShareCountResult{Articles: len(articles)}
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(articles) * len(s.platforms))
+	for i := range articles {
+		article := &articles[i]
+		links := s.links(article)
+		for _, platform := range s.platforms {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			item := fmt.Sprintf("%s on %s", article.Title, platform.Name())
+			if len(links) == 0 {
+				result.Failed++
+				progress.ItemFailed(item, errors.New("the site has no public URL to look up, set PUBLIC_URL"))
+				continue
+			}
+			progress.Step("Counting shares of %s", item)
+			var tally ShareTally
+			var err error
+			for _, link := range links {
+				var one ShareTally
+				if one, err = platform.Count(ctx, link); err != nil {
+					break
+				}
+				tally.Count += one.Count
+				tally.Comments += one.Comments
+			}
+			if err != nil {
+				result.Failed++
+				progress.ItemFailed(item, err)
+				continue
+			}
+			result.Checked++
+			changed, err := s.record(ctx, article, platform.Name(), tally, now)
+			if err != nil {
+				return result, err
+			}
+			if changed {
+				result.Changed++
+			}
+			progress.Advance(1)
+		}
+	}
+	return result, nil
+}
+
+// Shares returns an article's latest counts and their history
+func (s *ShareCountService) Shares(ctx context.Context, articleID uint) (*ArticleShares, error) {
+	var samples []models.ArticleShareCount
+	if err := s.db().WithContext(ctx).Where("article_id = ?", articleID).
+		Order("platform, fetched_at, id").Find(&samples).Error; err != nil {
+		return nil, err
+	}
+	shares := &ArticleShares{Platforms: []PlatformShares{}}
+	for _, sample := range samples {
+		n := len(shares.Platforms)
+		if n == 0 || shares.Platforms[n-1].Platform != sample.Platform {
+			shares.Platforms = append(shares.Platforms, PlatformShares{Platform: sample.Platform})
+			n++
+		}
+		platform := &shares.Platforms[n-1]
+		platform.Count, platform.Comments, platform.FetchedAt = sample.Count, sample.Comments, sample.FetchedAt
+		platform.History = append(platform.History, sample)
+	}
+	for _, platform := range shares.Platforms {
+		shares.Total += platform.Count
+	}
+	return shares, nil
+}
+
+// links returns the addresses an article is served under, one per language
+func (s *ShareCountService) links(article *models.Article) []string {
+	base := s.baseURL(article.SiteID)
+	if base == "" {
+		return nil
+	}
+	seen := map[string]bool{}
+	var links []string
+	languages := []string{article.DefaultLang}
+	for _, translation := range article.Translations {
+		languages = append(languages, translation.Language)
+	}
+	for _, language := range languages {
+		if language != "" && !seen[language] {
+			seen[language] = true
+			links = append(links, localizedArticleLink(base, language, article.ID))
+		}
+	}
+	return links
+}
+
+// record stores the tally unless it equals the platform's latest sample
+func (s *ShareCountService) record(ctx context.Context, article *models.Article, platform string, tally ShareTally, at time.Time) (bool, error) {
+	var latest models.ArticleShareCount
+	found := s.db().WithContext(ctx).Where("article_id = ? AND platform = ?", article.ID, platform).
+		Order("fetched_at DESC, id DESC").Limit(1).Find(&latest)
+	if found.Error != nil {
+		return false, found.Error
+	}
+	if found.RowsAffected > 0 && latest.Count == tally.Count && latest.Comments == tally.Comments {
+		return false, nil
+	}
+	sample := models.ArticleShareCount{
+		SiteID:    article.SiteID,
+		ArticleID: article.ID,
+		Platform:  platform,
+		Count:     tally.Count,
+		Comments:  tally.Comments,
+		FetchedAt: at,
+	}
+	return true, s.db().WithContext(ctx).Create(&sample).Error
+}
+
+// hackerNewsShares counts the points and comments of Hacker News stories
+// submitted with the page's address, found through the Algolia search API
+type hackerNewsShares struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (p *hackerNewsShares) Name() string { return "hackernews" }
+
+func (p *hackerNewsShares) Count(ctx context.Context, pageURL string) (ShareTally, error) {
+	query := url.Values{
+		"query":                        {pageURL},
+		"restrictSearchableAttributes": {"url"},
+		"tags":                         {"story"},
+		"hitsPerPage":                  {"50"},
+	}
+	var response struct {
+		Hits []struct {
+			URL         string `json:"url"`
+			Points      int64  `json:"points"`
+			NumComments int64  `json:"num_comments"`
+		} `json:"hits"`
+	}
+	if err := getShareJSON(ctx, p.client, p.baseURL+"/api/v1/search?"+query.Encode(), "", &response); err != nil {
+		return ShareTally{}, err
+	}
+	// The search matches words of the address; only stories of the page
+	// itself count
+	var tally ShareTally
+	for _, hit := range response.Hits {
+		if sameSharedPage(hit.URL, pageURL) {
+			tally.Count += hit.Points
+			tally.Comments += hit.NumComments
+		}
+	}
+	return tally, nil
+}
+
+// v2exShares counts the V2EX topics that link to the page and their
+// replies. V2EX has no search API of its own, so topics are found through
+// the SOV2EX search engine.
+type v2exShares struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (p *v2exShares) Name() string { return "v2ex" }
+
+func (p *v2exShares) Count(ctx context.Context, pageURL string) (ShareTally, error) {
+	query := url.Values{"q": {pageURL}, "size": {"50"}}
+	var response struct {
+		Hits []struct {
+			Source struct {
+				Title   string `json:"title"`
+				Content string `json:"content"`
+				Replies int64  `json:"replies"`
+			} `json:"_source"`
+		} `json:"hits"`
+	}
+	if err := getShareJSON(ctx, p.client, p.baseURL+"/api/search?"+query.Encode(), "", &response); err != nil {
+		return ShareTally{}, err
+	}
+	var tally ShareTally
+	for _, hit := range response.Hits {
+		if strings.Contains(hit.Source.Content, pageURL) || strings.Contains(hit.Source.Title, pageURL) {
+			tally.Count++
+			tally.Comments += hit.Source.Replies
+		}
+	}
+	return tally, nil
+}
+
+// facebookShares reads the engagement the Graph API reports for the page:
+// shares and reactions, and comments
+type facebookShares struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func (p *facebookShares) Name() string { return "facebook" }
+
+func (p *facebookShares) Count(ctx context.Context, pageURL string) (ShareTally, error) {
+	query := url.Values{"id": {pageURL}, "fields": {"engagement"}, "access_token": {p.token}}
+	var response struct {
+		Engagement struct {
+			ReactionCount      int64 `json:"reaction_count"`
+			CommentCount       int64 `json:"comment_count"`
+			ShareCount         int64 `json:"share_count"`
+			CommentPluginCount int64 `json:"comment_plugin_count"`
+		} `json:"engagement"`
+	}
+	if err := getShareJSON(ctx, p.client, p.baseURL+"/?"+query.Encode(), "", &response); err != nil {
+		return ShareTally{}, err
+	}
+	e := response.Engagement
+	return ShareTally{Count: e.ShareCount + e.ReactionCount, Comments: e.CommentCount + e.CommentPluginCount}, nil
+}
+
+// xShares counts the posts that linked to the page in the last seven days,
+// the window of the X recent counts API, so its count rises and falls
+type xShares struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func (p *xShares) Name() string { return "x" }
+
+func (p *xShares) Count(ctx context.Context, pageURL string) (ShareTally, error) {
+	query := url.Values{"query": {fmt.Sprintf("url:%q", pageURL)}, "granularity": {"day"}}
+	var response struct {
+		Meta struct {
+			TotalTweetCount int64 `json:"total_tweet_count"`
+		} `json:"meta"`
+	}
+	if err := getShareJSON(ctx, p.client, p.baseURL+"/2/tweets/counts/recent?"+query.Encode(), "Bearer "+p.token, &response); err != nil {
+		return ShareTally{}, err
+	}
+	return ShareTally{Count: response.Meta.TotalTweetCount}, nil
+}
+
+// getShareJSON fetches a platform API and decodes its JSON answer
+func getShareJSON(ctx context.Context, client *http.Client, address, authorization string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %d: %s", req.URL.Host, resp.StatusCode, truncateRunes(strings.TrimSpace(string(body)), 200))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// sameSharedPage reports whether a submitted address is the page,
+// ignoring the scheme, a leading www., a trailing slash and the fragment
+func sameSharedPage(submitted, page string) bool {
+	normalize := func(address string) string {
+		u, err := url.Parse(strings.TrimSpace(address))
+		if err != nil || u.Host == "" {
+			return ""
+		}
+		host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+		path := strings.TrimRight(u.EscapedPath(), "/")
+		if u.RawQuery != "" {
+			path += "?" + u.RawQuery
+		}
+		return host + path
+	}
+	want := normalize(page)
+	return want != "" && normalize(submitted) == want
+}
+
+var (
+	globalShareCountService     *ShareCountService
+	globalShareCountServiceOnce sync.Once
+)
+
+// GetGlobalShareCountService returns the global share count service
+func GetGlobalShareCountService() *ShareCountService {
+	globalShareCountServiceOnce.Do(func() {
+		globalShareCountService = NewShareCountService()
+	})
+	return globalShareCountService
+}
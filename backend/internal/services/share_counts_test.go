@@ -0,0 +1,129 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestShareCountCollect(t *testing.T) {
+	newTestDB(t)
+
+	points := int64(10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("query")
+		switch r.URL.Path {
+		case "/api/v1/search":
+			json.NewEncoder(w).Encode(map[string]interface{}{"hits": []map[string]interface{}{
+				{"url": strings.Replace(page, "https://", "http://www.", 1) + "/", "points": points, "num_comments": 4},
+				{"url": page + "-other", "points": 500, "num_comments": 90},
+			}})
+		case "/api/search":
+			page = r.URL.Query().Get("q")
+			json.NewEncoder(w).Encode(map[string]interface{}{"hits": []map[string]interface{}{
+				{"_source": map[string]interface{}{"title": "Worth a read", "content": "See " + page, "replies": 7}},
+				{"_source": map[string]interface{}{"title": "Unrelated", "content": "nothing", "replies": 30}},
+			}})
+		case "/2/tweets/counts/recent":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				t.Errorf("X request without the bearer token")
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"title":"Too Many Requests"}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := &ShareCountService{
+		db:     func() *gorm.DB { return database.DB },
+		now:    func() time.Time { return now },
+		maxAge: 30 * 24 * time.Hour,
+		platforms: []shareCountPlatform{
+			&hackerNewsShares{baseURL: server.URL, client: server.Client()},
+			&v2exShares{baseURL: server.URL, client: server.Client()},
+			&xShares{baseURL: server.URL, token: "token", client: server.Client()},
+		},
+		baseURL: func(uint) string { return "https://blog.example.com" },
+	}
+
+	recent := models.Article{Title: "Recent", Content: "x", DefaultLang: "en", CreatedAt: now.Add(-24 * time.Hour)}
+	old := models.Article{Title: "Old", Content: "y", DefaultLang: "en", CreatedAt: now.Add(-60 * 24 * time.Hour)}
+	scheduled := models.Article{Title: "Scheduled", Content: "z", DefaultLang: "en", CreatedAt: now.Add(time.Hour)}
+	for _, article := range []*models.Article{&recent, &old, &scheduled} {
+		if err := database.DB.Create(article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.DB.Create(&models.ArticleTranslation{ArticleID: recent.ID, Language: "zh", Title: "最近", Content: "x"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := s.Collect(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := out.(*ShareCountResult)
+	if result.Articles != 1 || result.Checked != 2 || result.Changed != 2 || result.Failed != 1 {
+		t.Fatalf("result = %+v, want the recent article counted on two platforms and X failing", result)
+	}
+
+	shares, err := s.Shares(context.Background(), recent.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares.Platforms) != 2 || shares.Total != 22 {
+		t.Fatalf("shares = %+v, want both languages summed on two platforms", shares)
+	}
+	hn := shares.Platforms[0]
+	if hn.Platform != "hackernews" || hn.Count != 20 || hn.Comments != 8 || !hn.FetchedAt.Equal(now) {
+		t.Errorf("hackernews = %+v", hn)
+	}
+	if v2ex := shares.Platforms[1]; v2ex.Platform != "v2ex" || v2ex.Count != 2 || v2ex.Comments != 14 {
+		t.Errorf("v2ex = %+v", v2ex)
+	}
+
+	// Unchanged counts add no samples; changed ones do
+	now = now.Add(24 * time.Hour)
+	points = 15
+	payload, _ := json.Marshal(shareCountJob{ArticleID: recent.ID})
+	if out, err = s.Collect(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+	if result = out.(*ShareCountResult); result.Checked != 2 || result.Changed != 1 {
+		t.Fatalf("result = %+v, want only the Hacker News count changed", result)
+	}
+	if shares, _ = s.Shares(context.Background(), recent.ID); len(shares.Platforms[0].History) != 2 ||
+		shares.Platforms[0].Count != 30 || len(shares.Platforms[1].History) != 1 || shares.Total != 32 {
+		t.Errorf("shares = %+v, want a second Hacker News sample", shares)
+	}
+
+	if _, err := (&ShareCountService{}).Collect(context.Background(), nil); err != ErrShareCountsNotConfigured {
+		t.Errorf("Collect without platforms = %v, want ErrShareCountsNotConfigured", err)
+	}
+}
+
+func TestSameSharedPage(t *testing.T) {
+	page := "https://blog.example.com/en/article/1"
+	for submitted, want := range map[string]bool{
+		"http://www.blog.example.com/en/article/1/":   true,
+		"https://Blog.Example.com/en/article/1#intro": true,
+		"https://blog.example.com/en/article/12":      false,
+		"https://blog.example.com/en/article/1?ref=x": false,
+		"": false,
+	} {
+		if got := sameSharedPage(submitted, page); got != want {
+			t.Errorf("sameSharedPage(%q) = %v, want %v", submitted, got, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Background work coordination used during graceful shutdown
+var (
+	shutdownChan   = make(chan struct{})
+	shutdownOnce   sync.Once
+	backgroundMu   sync.Mutex
+	backgroundJobs sync.WaitGroup
+)
+
+// ShuttingDown returns a channel that is closed once shutdown has begun
+func ShuttingDown() <-chan struct{} {
+	return shutdownChan
+}
+
+// IsShuttingDown reports whether shutdown has begun
+func IsShuttingDown() bool {
+	select {
+	case <-shutdownChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// beginJob registers a background job, returning false once shutdown has begun
+func beginJob() bool {
+	backgroundMu.Lock()
+	defer backgroundMu.Unlock()
+	if IsShuttingDown() {
+		return false
+	}
+	backgroundJobs.Add(1)
+	return true
+}
+
+// endJob marks a background job registered with beginJob as finished
+func endJob() {
+	backgroundJobs.Done()
+}
+
+// Shutdown stops background schedulers, drains the behavior and article view
+// queues, waits for in-flight embedding jobs and persists cache state. It
+// returns ctx.Err() if the deadline passes before all jobs finish.
+func Shutdown(ctx context.Context) error {
+	backgroundMu.Lock()
+	shutdownOnce.Do(func() { close(shutdownChan) })
+	backgroundMu.Unlock()
+
+	if c := globalContainer.Load(); c != nil {
+		slog.Info("Flushing behavior queue")
+		c.Close()
+	}
+	if globalArticleViewService != nil {
+		slog.Info("Flushing article view queue")
+		globalArticleViewService.Stop()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		backgroundJobs.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		slog.Warn("Timed out waiting for background jobs", "error", err)
+	}
+
+	return err
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"crypto/rand"
+	"encoding/base64"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// loadSigningKey returns the HMAC key kept in the system setting named
+// settingKey, creating and storing it encrypted on first use so every
+// instance signs alike
+func loadSigningKey(db *gorm.DB, settingKey string) ([]byte, error) {
+	var setting models.SystemSetting
+	if err := db.Limit(1).Find(&setting, models.SystemSetting{Key: settingKey}).Error; err != nil {
+		return nil, err
+	}
+	if setting.Value == "" {
+		generated := make([]byte, 32)
+		if _, err := rand.Read(generated); err != nil {
+			return nil, err
+		}
+		encrypted, err := security.GetGlobalCryptoService().EncryptAPIKey(base64.StdEncoding.EncodeToString(generated))
+		if err != nil {
+			return nil, err
+		}
+		// Another instance may have stored a key first; use whichever won
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.SystemSetting{Key: settingKey, Value: encrypted}).Error; err != nil {
+			return nil, err
+		}
+		if err := db.Limit(1).Find(&setting, models.SystemSetting{Key: settingKey}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	decrypted, err := security.GetGlobalCryptoService().DecryptAPIKey(setting.Value)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(decrypted)
+}
@@ -0,0 +1,616 @@
+package services
+
+import (
+	"archive/zip"
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Site archives (.kuno files) are zip files holding one JSON file per model
+// under data/, the site's uploads under media/ and a manifest. They carry
+// content between instances and database backends: ids are reassigned on
+// import, so an archive can be loaded into a site that already has content.
+const (
+	SiteArchiveFormat    = "kuno"
+	SiteArchiveVersion   = 1
+	SiteArchiveExtension = ".kuno"
+
+	siteArchiveManifest = "manifest.json"
+	siteArchiveMedia    = "media/"
+)
+
+// ErrInvalidSiteArchive is returned for files that are not site archives
+var ErrInvalidSiteArchive = errors.New("invalid site archive")
+
+// ErrUnsupportedSiteArchive is returned for archives written by a newer
+// format version
+var ErrUnsupportedSiteArchive = errors.New("site archive version not supported")
+
+// SiteArchiveManifest describes a site archive. SiteDir is the upload
+// directory of the exported site, used to rewrite media URLs when it is
+// imported into a site with another directory.
+type SiteArchiveManifest struct {
+	Format    string         `json:"format"`
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Driver    string         `json:"driver"`
+	SiteTitle string         `json:"site_title,omitempty"`
+	SiteDir   string         `json:"site_dir,omitempty"`
+	Tables    map[string]int `json:"tables"`
+	Media     int            `json:"media"`
+}
+
+// SiteImportResult counts what an import created and what it skipped
+// because the site already had it
+type SiteImportResult struct {
+	Manifest     *SiteArchiveManifest `json:"manifest"`
+	Created      map[string]int       `json:"created"`
+	Skipped      map[string]int       `json:"skipped"`
+	MediaWritten int                  `json:"media_written"`
+	MediaSkipped int                  `json:"media_skipped"`
+}
+
+// siteArchiveData is the content of a site archive
+type siteArchiveData struct {
+	Categories           []models.Category
+	CategoryTranslations []models.CategoryTranslation
+	Articles             []models.Article
+	ArticleTranslations  []models.ArticleTranslation
+	Media                []models.MediaLibrary
+	SocialMedia          []models.SocialMedia
+	Settings             []models.SiteSettings
+	SettingsTranslations []models.SiteSettingsTranslation
+}
+
+// files maps the data files of an archive to the slices holding them
+func (d *siteArchiveData) files() []struct {
+	name  string
+	value interface{}
+} {
+	return []struct {
+		name  string
+		value interface{}
+	}{
+		{"data/categories.json", &d.Categories},
+		{"data/category_translations.json", &d.CategoryTranslations},
+		{"data/articles.json", &d.Articles},
+		{"data/article_translations.json", &d.ArticleTranslations},
+		{"data/media_libraries.json", &d.Media},
+		{"data/social_media.json", &d.SocialMedia},
+		{"data/site_settings.json", &d.Settings},
+		{"data/site_settings_translations.json", &d.SettingsTranslations},
+	}
+}
+
+// SiteArchive exports and imports the content of one site
+type SiteArchive struct {
+	db        *gorm.DB
+	uploadDir string
+	siteDir   string
+}
+
+// NewSiteArchive creates a site archive for the site db is scoped to, whose
+// uploads live in siteDir under uploadDir ("" for the default site)
+func NewSiteArchive(db *gorm.DB, uploadDir, siteDir string) *SiteArchive {
+	return &SiteArchive{db: db, uploadDir: uploadDir, siteDir: siteDir}
+}
+
+// Export writes the site's categories, articles, media, social links and
+// settings to w. AI provider settings are left out since they hold API keys.
+func (a *SiteArchive) Export(w io.Writer) (*SiteArchiveManifest, error) {
+	data, err := a.load()
+	if err != nil {
+		return nil, err
+	}
+	manifest := &SiteArchiveManifest{
+		Format:    SiteArchiveFormat,
+		Version:   SiteArchiveVersion,
+		CreatedAt: time.Now().UTC(),
+		Driver:    database.Dialect(),
+		SiteDir:   a.siteDir,
+		Tables:    map[string]int{},
+	}
+	if len(data.Settings) > 0 {
+		manifest.SiteTitle = data.Settings[0].SiteTitle
+	}
+
+	zipWriter := zip.NewWriter(w)
+	for _, file := range data.files() {
+		entry, err := zipWriter.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.NewEncoder(entry).Encode(file.value); err != nil {
+			return nil, err
+		}
+		table := strings.TrimSuffix(path.Base(file.name), ".json")
+		manifest.Tables[table] = reflect.ValueOf(file.value).Elem().Len()
+	}
+
+	if manifest.Media, err = a.writeMedia(zipWriter); err != nil {
+		return nil, err
+	}
+
+	entry, err := zipWriter.Create(siteArchiveManifest)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(entry).Encode(manifest); err != nil {
+		return nil, err
+	}
+	return manifest, zipWriter.Close()
+}
+
+func (a *SiteArchive) load() (*siteArchiveData, error) {
+	data := &siteArchiveData{}
+	if err := a.db.Preload("Translations").Order("id").Find(&data.Categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to read categories: %v", err)
+	}
+	for i := range data.Categories {
+		data.CategoryTranslations = append(data.CategoryTranslations, data.Categories[i].Translations...)
+		data.Categories[i].Translations = nil
+	}
+	if err := a.db.Preload("Translations").Order("id").Find(&data.Articles).Error; err != nil {
+		return nil, fmt.Errorf("failed to read articles: %v", err)
+	}
+	for i := range data.Articles {
+		data.ArticleTranslations = append(data.ArticleTranslations, data.Articles[i].Translations...)
+		data.Articles[i].Translations = nil
+	}
+	if err := a.db.Order("id").Find(&data.Media).Error; err != nil {
+		return nil, fmt.Errorf("failed to read media: %v", err)
+	}
+	if err := a.db.Order("id").Find(&data.SocialMedia).Error; err != nil {
+		return nil, fmt.Errorf("failed to read social media: %v", err)
+	}
+	if err := a.db.Preload("Translations").Order("id").Limit(1).Find(&data.Settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to read settings: %v", err)
+	}
+	for i := range data.Settings {
+		data.SettingsTranslations = append(data.SettingsTranslations, data.Settings[i].Translations...)
+		data.Settings[i].Translations = nil
+		data.Settings[i].AIConfig = ""
+	}
+	return data, nil
+}
+
+// writeMedia adds the site's upload directory to the archive. The default
+// site skips sites/, which holds the uploads of the other sites.
+func (a *SiteArchive) writeMedia(zipWriter *zip.Writer) (int, error) {
+	root := filepath.Join(a.uploadDir, a.siteDir)
+	count := 0
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && file == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if a.siteDir == "" && rel == "sites" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		// Media is mostly compressed already
+		header.Name = siteArchiveMedia + filepath.ToSlash(rel)
+		header.Method = zip.Store
+		writer, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		if _, err := io.Copy(writer, src); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// ReadSiteArchiveManifest validates the archive's format and version and
+// returns its manifest
+func ReadSiteArchiveManifest(r io.ReaderAt, size int64) (*SiteArchiveManifest, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSiteArchive, err)
+	}
+	return readSiteManifest(zipReader)
+}
+
+func readSiteManifest(zipReader *zip.Reader) (*SiteArchiveManifest, error) {
+	var manifest SiteArchiveManifest
+	if err := readZipJSON(zipReader, siteArchiveManifest, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Format != SiteArchiveFormat || manifest.Version < 1 {
+		return nil, fmt.Errorf("%w: missing or unknown manifest", ErrInvalidSiteArchive)
+	}
+	if manifest.Version > SiteArchiveVersion {
+		return nil, fmt.Errorf("%w: version %d, this release reads up to %d",
+			ErrUnsupportedSiteArchive, manifest.Version, SiteArchiveVersion)
+	}
+	return &manifest, nil
+}
+
+// Import adds the archive's content to the site. Categories and articles
+// the site already has, matched by name and title, are kept and the
+// imported rows pointing at them are linked to the existing ones. With
+// settings the site settings are replaced too. Nothing is imported if any
+// part fails.
+func (a *SiteArchive) Import(r io.ReaderAt, size int64, settings bool) (*SiteImportResult, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSiteArchive, err)
+	}
+	manifest, err := readSiteManifest(zipReader)
+	if err != nil {
+		return nil, err
+	}
+	data := &siteArchiveData{}
+	for _, file := range data.files() {
+		if err := readZipJSON(zipReader, file.name, file.value); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	var media []*zip.File
+	for _, file := range zipReader.File {
+		rel, ok := strings.CutPrefix(file.Name, siteArchiveMedia)
+		if !ok || strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return nil, fmt.Errorf("%w: unsafe media path %q", ErrInvalidSiteArchive, file.Name)
+		}
+		media = append(media, file)
+	}
+
+	result := &SiteImportResult{Manifest: manifest, Created: map[string]int{}, Skipped: map[string]int{}}
+	written, err := a.extractMedia(media, result)
+	if err != nil {
+		removeFiles(written)
+		return nil, err
+	}
+
+	rewrite := a.urlRewriter(manifest.SiteDir, media)
+	err = a.db.Transaction(func(tx *gorm.DB) error {
+		return importSiteData(tx, data, a.uploadDir, rewrite, settings, result)
+	})
+	if err != nil {
+		removeFiles(written)
+		return nil, err
+	}
+	cache.Publish(cache.TopicArticles, cache.TopicCategories, cache.TopicSettings)
+	return result, nil
+}
+
+// extractMedia writes the archive's media files that do not exist yet and
+// returns the paths written
+func (a *SiteArchive) extractMedia(files []*zip.File, result *SiteImportResult) ([]string, error) {
+	var written []string
+	root := filepath.Join(a.uploadDir, a.siteDir)
+	for _, file := range files {
+		target := filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(file.Name, siteArchiveMedia)))
+		if _, err := os.Stat(target); err == nil {
+			result.MediaSkipped++
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return written, err
+		}
+		if err := extractZipFile(file, target); err != nil {
+			return written, fmt.Errorf("failed to extract %s: %v", file.Name, err)
+		}
+		written = append(written, target)
+		result.MediaWritten++
+	}
+	return written, nil
+}
+
+// urlRewriter maps media URLs of the exported site to this site's upload
+// directory, or returns nil when both use the same directory
+func (a *SiteArchive) urlRewriter(fromDir string, files []*zip.File) *strings.Replacer {
+	if fromDir == a.siteDir || len(files) == 0 {
+		return nil
+	}
+	var pairs []string
+	for _, file := range files {
+		rel := strings.TrimPrefix(file.Name, siteArchiveMedia)
+		pairs = append(pairs, "/uploads/"+path.Join(fromDir, rel), "/uploads/"+path.Join(a.siteDir, rel))
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func importSiteData(tx *gorm.DB, data *siteArchiveData, uploadDir string, rewrite *strings.Replacer, settings bool, result *SiteImportResult) error {
+	rewriteURL := func(value string) string {
+		if rewrite == nil {
+			return value
+		}
+		return rewrite.Replace(value)
+	}
+	// Ids are assigned by this database
+	create := func(value interface{}) error {
+		return tx.Omit(clause.Associations).Create(value).Error
+	}
+	siteID := uint(models.DefaultSiteID)
+	if id, ok := database.SiteFromContext(tx.Statement.Context); ok {
+		siteID = id
+	}
+
+	if settings && len(data.Settings) > 0 {
+		if err := importSiteSettings(tx, data, siteID, rewriteURL); err != nil {
+			return err
+		}
+		result.Created["site_settings"] = 1
+	}
+
+	categoryIDs := map[uint]uint{}
+	newCategories := map[uint]bool{}
+	for _, category := range data.Categories {
+		var existing models.Category
+		if err := tx.Where("name = ?", category.Name).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			categoryIDs[category.ID] = existing.ID
+			result.Skipped["categories"]++
+			continue
+		}
+		oldID := category.ID
+		category.ID, category.SiteID, category.ParentID = 0, siteID, nil
+		if err := create(&category); err != nil {
+			return fmt.Errorf("failed to import category %q: %v", category.Name, err)
+		}
+		categoryIDs[oldID] = category.ID
+		newCategories[oldID] = true
+		result.Created["categories"]++
+	}
+	// Parents are linked once every category has its new ID
+	for _, category := range data.Categories {
+		if !newCategories[category.ID] || category.ParentID == nil {
+			continue
+		}
+		if parentID, ok := categoryIDs[*category.ParentID]; ok {
+			err := tx.Model(&models.Category{}).Where("id = ?", categoryIDs[category.ID]).Update("parent_id", parentID).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, translation := range data.CategoryTranslations {
+		if !newCategories[translation.CategoryID] {
+			continue
+		}
+		translation.ID, translation.CategoryID = 0, categoryIDs[translation.CategoryID]
+		if err := create(&translation); err != nil {
+			return err
+		}
+		result.Created["category_translations"]++
+	}
+
+	mediaIDs := map[uint]uint{}
+	for _, media := range data.Media {
+		media.URL = rewriteURL(media.URL)
+		var existing models.MediaLibrary
+		if err := tx.Where("url = ?", media.URL).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			mediaIDs[media.ID] = existing.ID
+			result.Skipped["media_libraries"]++
+			continue
+		}
+		oldID := media.ID
+		media.ID, media.SiteID = 0, siteID
+		if rel, ok := strings.CutPrefix(media.URL, "/uploads/"); ok {
+			media.FilePath = filepath.Join(uploadDir, filepath.FromSlash(rel))
+		}
+		if err := create(&media); err != nil {
+			return fmt.Errorf("failed to import media %q: %v", media.OriginalName, err)
+		}
+		mediaIDs[oldID] = media.ID
+		result.Created["media_libraries"]++
+	}
+
+	sanitize := security.ShouldSanitize(true)
+	policy := security.GetGlobalHTMLPolicy()
+	articleIDs := map[uint]uint{}
+	for _, article := range data.Articles {
+		var existing models.Article
+		if err := tx.Where("title = ?", article.Title).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			result.Skipped["articles"]++
+			continue
+		}
+		oldID := article.ID
+		article.ID, article.SiteID = 0, siteID
+		article.CategoryID = categoryIDs[article.CategoryID]
+		if article.CoverImageID != nil {
+			if id, ok := mediaIDs[*article.CoverImageID]; ok {
+				article.CoverImageID = &id
+			} else {
+				article.CoverImageID = nil
+			}
+		}
+		if article.CoverImageURL != nil {
+			url := rewriteURL(*article.CoverImageURL)
+			article.CoverImageURL = &url
+		}
+		article.Content = rewriteURL(article.Content)
+		if sanitize {
+			article.Content = policy.SanitizeContent(article.Content, article.ContentType)
+		}
+		if err := create(&article); err != nil {
+			return fmt.Errorf("failed to import article %q: %v", article.Title, err)
+		}
+		articleIDs[oldID] = article.ID
+		result.Created["articles"]++
+	}
+	contentTypes := map[uint]string{}
+	for _, article := range data.Articles {
+		contentTypes[article.ID] = article.ContentType
+	}
+	for _, translation := range data.ArticleTranslations {
+		articleID, ok := articleIDs[translation.ArticleID]
+		if !ok {
+			continue
+		}
+		translation.Content = rewriteURL(translation.Content)
+		if sanitize {
+			translation.Content = policy.SanitizeContent(translation.Content, contentTypes[translation.ArticleID])
+		}
+		translation.ID, translation.ArticleID = 0, articleID
+		if err := create(&translation); err != nil {
+			return err
+		}
+		result.Created["article_translations"]++
+	}
+
+	for _, social := range data.SocialMedia {
+		var existing models.SocialMedia
+		if err := tx.Where("platform = ? AND url = ?", social.Platform, social.URL).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID != 0 {
+			result.Skipped["social_media"]++
+			continue
+		}
+		social.ID, social.SiteID = 0, siteID
+		active := social.IsActive
+		if err := create(&social); err != nil {
+			return err
+		}
+		// Create stores the column default for false
+		if !active {
+			if err := tx.Model(&social).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+		result.Created["social_media"]++
+	}
+	return nil
+}
+
+// importSiteSettings replaces the site's settings and their translations
+// with the archive's, keeping the AI configuration and setup state
+func importSiteSettings(tx *gorm.DB, data *siteArchiveData, siteID uint, rewriteURL func(string) string) error {
+	imported := data.Settings[0]
+	var current models.SiteSettings
+	if err := tx.Order("id").Limit(1).Find(&current).Error; err != nil {
+		return err
+	}
+	oldID := imported.ID
+	imported.ID, imported.SiteID = current.ID, siteID
+	imported.AIConfig, imported.SetupCompleted = current.AIConfig, current.SetupCompleted || imported.SetupCompleted
+	imported.CreatedAt = current.CreatedAt
+	imported.LogoURL = rewriteURL(imported.LogoURL)
+	imported.LogoDarkURL = rewriteURL(imported.LogoDarkURL)
+	imported.FaviconURL = rewriteURL(imported.FaviconURL)
+	imported.FaviconSetURL = rewriteURL(imported.FaviconSetURL)
+	imported.BackgroundImageURL = rewriteURL(imported.BackgroundImageURL)
+	imported.Translations = nil
+	if current.ID == 0 {
+		// Create stores column defaults for false and zero values, so the
+		// row is created first and then saved with every column
+		current.SiteID = siteID
+		if err := tx.Omit(clause.Associations).Create(&current).Error; err != nil {
+			return err
+		}
+		imported.ID, imported.CreatedAt = current.ID, current.CreatedAt
+	}
+	if err := tx.Select("*").Omit(clause.Associations).Save(&imported).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("settings_id = ?", imported.ID).Delete(&models.SiteSettingsTranslation{}).Error; err != nil {
+		return err
+	}
+	for _, translation := range data.SettingsTranslations {
+		if translation.SettingsID != oldID {
+			continue
+		}
+		translation.ID, translation.SettingsID = 0, imported.ID
+		if err := tx.Create(&translation).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readZipJSON(zipReader *zip.Reader, name string, value interface{}) error {
+	file, err := zipReader.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %s is missing: %w", ErrInvalidSiteArchive, name, fs.ErrNotExist)
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidSiteArchive, err)
+	}
+	defer file.Close()
+	if err := json.NewDecoder(file).Decode(value); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidSiteArchive, name, err)
+	}
+	return nil
+}
+
+func extractZipFile(file *zip.File, target string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(target)
+		return err
+	}
+	return dst.Close()
+}
+
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
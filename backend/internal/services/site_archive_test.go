@@ -0,0 +1,130 @@
+package services
+
+import (
+	"archive/zip"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openArchiveTestDB(t *testing.T) (*gorm.DB, string) {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(dir, "blog.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db, filepath.Join(dir, "uploads")
+}
+
+func TestSiteArchiveRoundTrip(t *testing.T) {
+	src, srcUploads := openArchiveTestDB(t)
+	os.MkdirAll(filepath.Join(srcUploads, "images"), 0755)
+	os.WriteFile(filepath.Join(srcUploads, "images", "cover.png"), []byte("png"), 0644)
+
+	sourceSettings := models.SiteSettings{SiteTitle: "Source", AIConfig: `{"api_key":"secret"}`}
+	src.Create(&sourceSettings)
+	src.Model(&sourceSettings).Update("show_view_count", false)
+	travel := models.Category{Name: "Travel"}
+	notes := models.Category{Name: "Notes"}
+	src.Create(&travel)
+	src.Create(&notes)
+	src.Create(&models.CategoryTranslation{CategoryID: notes.ID, Language: "en", Name: "Notes"})
+	media := models.MediaLibrary{FileName: "cover.png", OriginalName: "cover.png", FilePath: filepath.Join(srcUploads, "images", "cover.png"),
+		FileSize: 3, MimeType: "image/png", MediaType: models.MediaTypeImage, URL: "/uploads/images/cover.png"}
+	src.Create(&media)
+	article := models.Article{Title: "Trip", Content: "![cover](/uploads/images/cover.png)", CategoryID: notes.ID, CoverImageID: &media.ID}
+	src.Create(&article)
+	src.Create(&models.ArticleTranslation{ArticleID: article.ID, Language: "en", Title: "Trip"})
+
+	var buf bytes.Buffer
+	manifest, err := NewSiteArchive(src, srcUploads, "").Export(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Tables["articles"] != 1 || manifest.Media != 1 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("secret")) {
+		t.Error("archive contains the AI configuration")
+	}
+
+	// The destination already has Notes, under another id, and stores this
+	// site's uploads in its own directory
+	dst := newTestDB(t)
+	dstUploads := t.TempDir()
+	dst.Create(&models.Category{Name: "Other"})
+	dst.Create(&models.Category{Name: "Notes"})
+	result, err := NewSiteArchive(dst, dstUploads, "sites/blog").Import(bytes.NewReader(buf.Bytes()), int64(buf.Len()), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created["articles"] != 1 || result.Created["categories"] != 1 || result.Skipped["categories"] != 1 || result.MediaWritten != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	var imported models.Article
+	dst.Preload("Category").Preload("Translations").First(&imported)
+	if imported.Category.Name != "Notes" || len(imported.Translations) != 1 {
+		t.Errorf("article not linked to the existing category: %+v", imported)
+	}
+	if imported.Content != "![cover](/uploads/sites/blog/images/cover.png)" {
+		t.Errorf("media URL not rewritten: %s", imported.Content)
+	}
+	var importedMedia models.MediaLibrary
+	dst.First(&importedMedia, *imported.CoverImageID)
+	if importedMedia.URL != "/uploads/sites/blog/images/cover.png" {
+		t.Errorf("cover not linked to the imported media: %+v", importedMedia)
+	}
+	if _, err := os.Stat(filepath.Join(dstUploads, "sites", "blog", "images", "cover.png")); err != nil {
+		t.Error(err)
+	}
+	var settings models.SiteSettings
+	dst.First(&settings)
+	if settings.SiteTitle != "Source" || settings.ShowViewCount {
+		t.Errorf("settings not imported: %+v", settings)
+	}
+
+	// Importing again adds nothing
+	result, err = NewSiteArchive(dst, dstUploads, "sites/blog").Import(bytes.NewReader(buf.Bytes()), int64(buf.Len()), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Created) != 0 || result.MediaSkipped != 1 {
+		t.Errorf("expected nothing new on the second import, got %+v", result)
+	}
+}
+
+func TestSiteArchiveRejectsNewerVersions(t *testing.T) {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	entry, _ := zipWriter.Create(siteArchiveManifest)
+	json.NewEncoder(entry).Encode(SiteArchiveManifest{Format: SiteArchiveFormat, Version: SiteArchiveVersion + 1})
+	zipWriter.Close()
+
+	_, err := ReadSiteArchiveManifest(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !errors.Is(err, ErrUnsupportedSiteArchive) {
+		t.Errorf("expected ErrUnsupportedSiteArchive, got %v", err)
+	}
+	_, err = ReadSiteArchiveManifest(bytes.NewReader([]byte("not a zip")), 9)
+	if !errors.Is(err, ErrInvalidSiteArchive) {
+		t.Errorf("expected ErrInvalidSiteArchive, got %v", err)
+	}
+}
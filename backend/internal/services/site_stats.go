@@ -0,0 +1,172 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// siteStatsTTL is how long computed statistics are served before view
+// counts are read again
+const siteStatsTTL = 10 * time.Minute
+
+// ErrSiteStatsDisabled is returned when a site exposes no statistics
+var ErrSiteStatsDisabled = errors.New("site statistics are not public")
+
+// SiteStats are the public figures of a site. Only the figures the site's
+// settings expose are set.
+type SiteStats struct {
+	Posts       *int64     `json:"posts,omitempty"`
+	Words       *int64     `json:"words,omitempty"`
+	DaysRunning *int64     `json:"days_running,omitempty"`
+	Views       *int64     `json:"views,omitempty"`
+	LastUpdated *time.Time `json:"last_updated,omitempty"`
+}
+
+// SiteStatsService computes the statistics shown in footers and stats
+// widgets from a site's visible articles. Counting words reads every
+// article, so results are cached per site until articles or settings change.
+type SiteStatsService struct {
+	db    func() *gorm.DB
+	cache *cache.Namespace
+	now   func() time.Time
+}
+
+// NewSiteStatsService creates a site statistics service
+func NewSiteStatsService() *SiteStatsService {
+	s := &SiteStatsService{
+		db:    func() *gorm.DB { return database.DB },
+		cache: cache.New("site_stats", siteStatsTTL),
+		now:   time.Now,
+	}
+	for _, topic := range []string{cache.TopicArticles, cache.TopicSettings} {
+		cache.Subscribe(topic, s.cache.Clear)
+	}
+	return s
+}
+
+// Get returns the statistics the site in ctx has made public, or
+// ErrSiteStatsDisabled when it exposes none
+func (s *SiteStatsService) Get(ctx context.Context) (*SiteStats, error) {
+	siteID, _ := database.SiteFromContext(ctx)
+	key := fmt.Sprint(siteID)
+	var stats SiteStats
+	if s.cache.Get(key, &stats) {
+		return &stats, nil
+	}
+
+	db := s.db().WithContext(ctx)
+	var settings models.SiteSettings
+	if err := db.Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	if !settings.StatsShowPosts && !settings.StatsShowWords && !settings.StatsShowDaysRunning &&
+		!settings.StatsShowViews && !settings.StatsShowLastUpdated {
+		return nil, ErrSiteStatsDisabled
+	}
+
+	now := s.now()
+	visible := func() *gorm.DB {
+		return db.Model(&models.Article{}).Where("created_at <= ?", now)
+	}
+	if settings.StatsShowPosts {
+		var posts int64
+		if err := visible().Count(&posts).Error; err != nil {
+			return nil, err
+		}
+		stats.Posts = &posts
+	}
+	if settings.StatsShowWords {
+		var words int64
+		var batch []models.Article
+		err := visible().Select("id", "content").FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+			for _, article := range batch {
+				words += int64(countWords(article.Content))
+			}
+			return nil
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+		stats.Words = &words
+	}
+	if settings.StatsShowViews {
+		var views int64
+		if err := visible().Select("COALESCE(SUM(view_count), 0)").Scan(&views).Error; err != nil {
+			return nil, err
+		}
+		stats.Views = &views
+	}
+	if settings.StatsShowDaysRunning {
+		// Imported articles can be older than the installation
+		start := settings.CreatedAt
+		var first models.Article
+		if err := visible().Select("created_at").Order("created_at ASC").Limit(1).Find(&first).Error; err != nil {
+			return nil, err
+		}
+		if !first.CreatedAt.IsZero() && (start.IsZero() || first.CreatedAt.Before(start)) {
+			start = first.CreatedAt
+		}
+		days := int64(0)
+		if !start.IsZero() && now.After(start) {
+			days = int64(now.Sub(start).Hours() / 24)
+		}
+		stats.DaysRunning = &days
+	}
+	if settings.StatsShowLastUpdated {
+		var last models.Article
+		if err := visible().Select("updated_at").Order("updated_at DESC").Limit(1).Find(&last).Error; err != nil {
+			return nil, err
+		}
+		if !last.UpdatedAt.IsZero() {
+			stats.LastUpdated = &last.UpdatedAt
+		}
+	}
+
+	s.cache.Set(key, stats)
+	return &stats, nil
+}
+
+// countWords counts the words of an article the way readers would: each
+// Chinese or Japanese character is a word, as is each run of other letters
+// and digits. HTML tags and Markdown punctuation are not counted.
+func countWords(text string) int {
+	count := 0
+	inWord := false
+	for _, r := range htmlTag.ReplaceAllString(text, " ") {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+			count++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				count++
+				inWord = true
+			}
+		default:
+			inWord = false
+		}
+	}
+	return count
+}
+
+var (
+	globalSiteStatsService     *SiteStatsService
+	globalSiteStatsServiceOnce sync.Once
+)
+
+// GetGlobalSiteStatsService returns the global site statistics service
+func GetGlobalSiteStatsService() *SiteStatsService {
+	globalSiteStatsServiceOnce.Do(func() {
+		globalSiteStatsService = NewSiteStatsService()
+	})
+	return globalSiteStatsService
+}
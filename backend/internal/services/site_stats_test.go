@@ -0,0 +1,80 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCountWords(t *testing.T) {
+	for text, want := range map[string]int{
+		"":                                0,
+		"Hello, **world**! It's 2026.":    5,
+		"你好，世界":                           4,
+		"Go 语言很好用":                        6,
+		`<p class="lead">Two words</p>`:   2,
+		"```go\nfmt.Println(\"hi\")\n```": 4,
+		"日本語のテキストです":                      10,
+		"안녕하세요 세계":                        2,
+	} {
+		if got := countWords(text); got != want {
+			t.Errorf("countWords(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestSiteStats(t *testing.T) {
+	newTestDB(t)
+	s := NewSiteStatsService()
+	now := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	settings := models.SiteSettings{CreatedAt: now.AddDate(0, 0, -30)}
+	database.DB.Create(&settings)
+	if _, err := s.Get(ctx); !errors.Is(err, ErrSiteStatsDisabled) {
+		t.Fatalf("stats of a site exposing none = %v, want ErrSiteStatsDisabled", err)
+	}
+
+	for _, article := range []models.Article{
+		{Title: "Old", Content: "Imported from another blog", ViewCount: 10, CreatedAt: now.AddDate(0, 0, -100), UpdatedAt: now.AddDate(0, 0, -2)},
+		{Title: "New", Content: "你好世界", ViewCount: 5, CreatedAt: now.AddDate(0, 0, -1), UpdatedAt: now.AddDate(0, 0, -1)},
+		{Title: "Scheduled", Content: "Not yet", ViewCount: 1, CreatedAt: now.Add(time.Hour), UpdatedAt: now},
+	} {
+		if err := database.DB.Create(&article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	database.DB.Model(&settings).Updates(map[string]interface{}{"stats_show_posts": true, "stats_show_words": true, "stats_show_views": true})
+	cache.Publish(cache.TopicSettings)
+
+	stats, err := s.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Posts == nil || *stats.Posts != 2 || stats.Words == nil || *stats.Words != 8 || stats.Views == nil || *stats.Views != 15 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.DaysRunning != nil || stats.LastUpdated != nil {
+		t.Errorf("figures the site did not expose: %+v", stats)
+	}
+
+	database.DB.Model(&settings).Updates(map[string]interface{}{
+		"stats_show_posts": false, "stats_show_days_running": true, "stats_show_last_updated": true,
+	})
+	cache.Publish(cache.TopicSettings)
+	stats, err = s.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Posts != nil || stats.DaysRunning == nil || *stats.DaysRunning != 100 {
+		t.Errorf("days running = %+v, want counted from the oldest article", stats)
+	}
+	if stats.LastUpdated == nil || !stats.LastUpdated.Equal(now.AddDate(0, 0, -1)) {
+		t.Errorf("last updated = %v, want the newest visible article", stats.LastUpdated)
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// verificationCode is the form of the codes search engines hand out
+	verificationCode = regexp.MustCompile(`^[A-Za-z0-9_-]{1,200}$`)
+	// googleVerificationFile is the name of Google's HTML verification file,
+	// which Google names differently from its meta tag code
+	googleVerificationFile = regexp.MustCompile(`^google[0-9a-f]+\.html$`)
+	// metaContent finds the code in a pasted meta tag
+	metaContent = regexp.MustCompile(`content\s*=\s*["']([^"']*)["']`)
+)
+
+// ErrInvalidVerificationCode is returned for a code that is not in the form
+// search engines use
+var ErrInvalidVerificationCode = errors.New("invalid site verification code")
+
+// VerificationMeta is a meta tag for the head of the site's pages
+type VerificationMeta struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// VerificationFile is a verification file served at the site's root
+type VerificationFile struct {
+	ContentType string
+	Body        string
+}
+
+// siteVerificationCodes are the codes a site entered, cached per site
+type siteVerificationCodes struct {
+	Google string
+	Bing   string
+	Baidu  string
+	Yandex string
+}
+
+// SiteVerificationService serves the codes with which Google, Bing, Baidu
+// and Yandex check that a site is its owner's, both as meta tags for the
+// frontend to render and as the verification files the engines fetch, so
+// verifying a site needs no redeploy. Each code serves both methods of its
+// engine, except for Google, whose file name is entered instead of the meta
+// tag code to use its HTML file method.
+type SiteVerificationService struct {
+	db    func() *gorm.DB
+	cache *cache.Namespace
+}
+
+// NewSiteVerificationService creates a site verification service
+func NewSiteVerificationService() *SiteVerificationService {
+	s := &SiteVerificationService{
+		db:    func() *gorm.DB { return database.DB },
+		cache: cache.New("site_verification", time.Hour),
+	}
+	cache.Subscribe(cache.TopicSettings, s.cache.Clear)
+	return s
+}
+
+// Meta returns the verification meta tags of the site in ctx
+func (s *SiteVerificationService) Meta(ctx context.Context) ([]VerificationMeta, error) {
+	codes, err := s.codes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	meta := []VerificationMeta{}
+	if codes.Google != "" && !googleVerificationFile.MatchString(codes.Google) {
+		meta = append(meta, VerificationMeta{Name: "google-site-verification", Content: codes.Google})
+	}
+	if codes.Bing != "" {
+		meta = append(meta, VerificationMeta{Name: "msvalidate.01", Content: codes.Bing})
+	}
+	if codes.Baidu != "" {
+		meta = append(meta, VerificationMeta{Name: "baidu-site-verification", Content: codes.Baidu})
+	}
+	if codes.Yandex != "" {
+		meta = append(meta, VerificationMeta{Name: "yandex-verification", Content: codes.Yandex})
+	}
+	return meta, nil
+}
+
+// File returns the verification file of the site in ctx with a name, if
+// one of its codes calls for it
+func (s *SiteVerificationService) File(ctx context.Context, name string) (*VerificationFile, error) {
+	codes, err := s.codes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case codes.Google != "" && name == codes.Google && googleVerificationFile.MatchString(name):
+		return &VerificationFile{"text/html; charset=utf-8", "google-site-verification: " + name}, nil
+	case codes.Bing != "" && name == "BingSiteAuth.xml":
+		body := "<?xml version=\"1.0\"?>\n<users>\n\t<user>" + codes.Bing + "</user>\n</users>\n"
+		return &VerificationFile{"application/xml; charset=utf-8", body}, nil
+	case codes.Baidu != "" && name == "baidu_verify_"+codes.Baidu+".html":
+		return &VerificationFile{"text/html; charset=utf-8", codes.Baidu}, nil
+	case codes.Yandex != "" && name == "yandex_"+codes.Yandex+".html":
+		body := "<html>\n<head>\n<meta http-equiv=\"Content-Type\" content=\"text/html; charset=UTF-8\">\n</head>\n" +
+			"<body>Verification: " + codes.Yandex + "</body>\n</html>\n"
+		return &VerificationFile{"text/html; charset=utf-8", body}, nil
+	}
+	return nil, nil
+}
+
+func (s *SiteVerificationService) codes(ctx context.Context) (*siteVerificationCodes, error) {
+	siteID, _ := database.SiteFromContext(ctx)
+	key := fmt.Sprint(siteID)
+	var codes siteVerificationCodes
+	if s.cache.Get(key, &codes) {
+		return &codes, nil
+	}
+	var settings models.SiteSettings
+	err := s.db().WithContext(ctx).Select("google_site_verification", "bing_site_verification", "baidu_site_verification", "yandex_site_verification").
+		Limit(1).Find(&settings).Error
+	if err != nil {
+		return nil, err
+	}
+	codes = siteVerificationCodes{
+		Google: settings.GoogleSiteVerification,
+		Bing:   settings.BingSiteVerification,
+		Baidu:  settings.BaiduSiteVerification,
+		Yandex: settings.YandexSiteVerification,
+	}
+	s.cache.Set(key, codes)
+	return &codes, nil
+}
+
+// NormalizeVerificationCode checks a verification code entered by an
+// admin. A whole pasted meta tag is reduced to its code, and for Google
+// the name of its HTML verification file is accepted too. An empty code
+// turns the engine's verification off.
+func NormalizeVerificationCode(engine, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if match := metaContent.FindStringSubmatch(value); match != nil {
+		value = strings.TrimSpace(html.UnescapeString(match[1]))
+	}
+	if value == "" || verificationCode.MatchString(value) || (engine == "google" && googleVerificationFile.MatchString(value)) {
+		return value, nil
+	}
+	return "", fmt.Errorf("%w for %s: %q", ErrInvalidVerificationCode, engine, value)
+}
+
+var (
+	globalSiteVerificationService     *SiteVerificationService
+	globalSiteVerificationServiceOnce sync.Once
+)
+
+// GetGlobalSiteVerificationService returns the global site verification
+// service
+func GetGlobalSiteVerificationService() *SiteVerificationService {
+	globalSiteVerificationServiceOnce.Do(func() {
+		globalSiteVerificationService = NewSiteVerificationService()
+	})
+	return globalSiteVerificationService
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestNormalizeVerificationCode(t *testing.T) {
+	for _, tc := range []struct{ engine, value, want string }{
+		{"google", "  abcDEF-123_x ", "abcDEF-123_x"},
+		{"google", `<meta name="google-site-verification" content="abcDEF-123_x" />`, "abcDEF-123_x"},
+		{"google", "google1234abcd.html", "google1234abcd.html"},
+		{"bing", "", ""},
+		{"baidu", "code-Xy12AbC", "code-Xy12AbC"},
+	} {
+		got, err := NormalizeVerificationCode(tc.engine, tc.value)
+		if err != nil || got != tc.want {
+			t.Errorf("NormalizeVerificationCode(%s, %q) = %q, %v, want %q", tc.engine, tc.value, got, err, tc.want)
+		}
+	}
+	for _, bad := range []string{"abc def", `"><script>`, "bing1234.html"} {
+		if _, err := NormalizeVerificationCode("bing", bad); !errors.Is(err, ErrInvalidVerificationCode) {
+			t.Errorf("NormalizeVerificationCode(bing, %q) = %v, want ErrInvalidVerificationCode", bad, err)
+		}
+	}
+}
+
+func TestSiteVerification(t *testing.T) {
+	newTestDB(t)
+	s := &SiteVerificationService{
+		db:    func() *gorm.DB { return database.DB },
+		cache: cache.New("test_site_verification", time.Hour),
+	}
+	s.cache.Clear()
+	ctx := context.Background()
+
+	settings := models.SiteSettings{
+		GoogleSiteVerification: "google1234abcd.html",
+		BingSiteVerification:   "0123456789ABCDEF",
+		YandexSiteVerification: "a1b2c3d4",
+	}
+	if err := database.DB.Create(&settings).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Google's file name is no meta tag code
+	meta, err := s.Meta(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta) != 2 || meta[0].Name != "msvalidate.01" || meta[1].Name != "yandex-verification" || meta[1].Content != "a1b2c3d4" {
+		t.Errorf("meta = %+v", meta)
+	}
+
+	for name, want := range map[string]string{
+		"google1234abcd.html":  "google-site-verification: google1234abcd.html",
+		"BingSiteAuth.xml":     "<user>0123456789ABCDEF</user>",
+		"yandex_a1b2c3d4.html": "Verification: a1b2c3d4",
+	} {
+		file, err := s.File(ctx, name)
+		if err != nil || file == nil || !strings.Contains(file.Body, want) {
+			t.Errorf("File(%s) = %+v, %v", name, file, err)
+		}
+	}
+	for _, name := range []string{"google0000.html", "baidu_verify_.html", "yandex_other.html", "robots.txt"} {
+		if file, err := s.File(ctx, name); err != nil || file != nil {
+			t.Errorf("File(%s) = %+v, %v, want none", name, file, err)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSiteNotFound = errors.New("site not found")
+	ErrInvalidSite  = errors.New("invalid site")
+	ErrSiteInUse    = errors.New("site still has content")
+)
+
+var siteSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// SiteInput is the editable part of a site. Nil fields are left unchanged
+// on update.
+type SiteInput struct {
+	Name  *string  `json:"name"`
+	Slug  *string  `json:"slug"`
+	Hosts []string `json:"hosts"`
+}
+
+// SiteAdmin is the first administrator created together with a new site
+type SiteAdmin struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// SiteService manages the sites of a multi-site instance and maps request
+// hosts to them. The host table is cached in memory and reloaded when any
+// instance changes a site.
+type SiteService struct {
+	db func() *gorm.DB
+
+	mu     sync.RWMutex
+	hosts  map[string]models.Site
+	byID   map[uint]models.Site
+	loaded bool
+}
+
+// NewSiteService creates a site service
+func NewSiteService() *SiteService {
+	s := &SiteService{db: func() *gorm.DB { return database.DB }}
+	cache.Subscribe(cache.TopicSites, s.reset)
+	return s
+}
+
+// List returns all sites
+func (s *SiteService) List() ([]models.Site, error) {
+	var sites []models.Site
+	err := s.db().Order("id ASC").Find(&sites).Error
+	return sites, err
+}
+
+// Get returns a site by ID
+func (s *SiteService) Get(id uint) (*models.Site, error) {
+	var site models.Site
+	if err := s.db().First(&site, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSiteNotFound
+		}
+		return nil, err
+	}
+	return &site, nil
+}
+
+// Create adds a site together with its settings and first administrator,
+// so the new blog is usable as soon as its host points at this instance
+func (s *SiteService) Create(input SiteInput, admin SiteAdmin) (*models.Site, error) {
+	var site models.Site
+	if err := s.applyInput(&site, input); err != nil {
+		return nil, err
+	}
+	if site.Name == "" || site.Slug == "" {
+		return nil, fmt.Errorf("%w: name and slug are required", ErrInvalidSite)
+	}
+	admin.Username = strings.TrimSpace(admin.Username)
+	if len(admin.Username) < 3 || len(admin.Password) < 6 {
+		return nil, fmt.Errorf("%w: admin username must be at least 3 and password at least 6 characters", ErrInvalidSite)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(admin.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&site).Error; err != nil {
+			return err
+		}
+		settings := models.SiteSettings{
+			SiteID:             site.ID,
+			SiteTitle:          site.Name,
+			FooterText:         "© " + site.Name,
+			ShowViewCount:      true,
+			ShowSiteTitle:      true,
+			EnableSoundEffects: true,
+			SetupCompleted:     true,
+		}
+		if err := tx.Create(&settings).Error; err != nil {
+			return err
+		}
+		user := models.User{SiteID: site.ID, Username: admin.Username, Password: string(hash), IsAdmin: true}
+		if err := tx.Create(&user).Error; err != nil {
+			return fmt.Errorf("%w: username %q is already taken", ErrInvalidSite, admin.Username)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return &site, nil
+}
+
+// Update changes the fields set in input
+func (s *SiteService) Update(id uint, input SiteInput) (*models.Site, error) {
+	site, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyInput(site, input); err != nil {
+		return nil, err
+	}
+	if err := s.db().Save(site).Error; err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return site, nil
+}
+
+// Delete removes a site that no longer owns any articles, categories,
+// media or social links. Its settings, users, newsletter subscribers,
+// notifiers and fediverse followers are removed with it. The default site cannot be deleted.
+func (s *SiteService) Delete(id uint) error {
+	if id == models.DefaultSiteID {
+		return fmt.Errorf("%w: the default site cannot be deleted", ErrInvalidSite)
+	}
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+
+	err := s.db().Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.Article{}, &models.Category{}, &models.MediaLibrary{}, &models.SocialMedia{}} {
+			var count int64
+			if err := tx.Model(model).Where("site_id = ?", id).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrSiteInUse
+			}
+		}
+		if err := tx.Where("site_id = ?", id).Delete(&models.SiteSettings{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("site_id = ?", id).Delete(&models.User{}).Error; err != nil {
+			return err
+		}
+		newsletters := tx.Model(&models.Newsletter{}).Select("id").Where("site_id = ?", id)
+		if err := tx.Where("newsletter_id IN (?)", newsletters).Delete(&models.NewsletterDelivery{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.Newsletter{}, &models.Subscriber{}, &models.Notifier{},
+			&models.Follower{}, &models.FederatedReply{}, &models.ArticleSlugRedirect{}, &models.TranslationMemory{},
+			&models.MediaMetadataStat{}} {
+			if err := tx.Where("site_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.Site{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func (s *SiteService) applyInput(site *models.Site, input SiteInput) error {
+	if input.Name != nil {
+		site.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Slug != nil {
+		slug := strings.TrimSpace(*input.Slug)
+		if !siteSlugPattern.MatchString(slug) {
+			return fmt.Errorf("%w: slug may only contain lowercase letters, digits and dashes", ErrInvalidSite)
+		}
+		if site.ID == models.DefaultSiteID && slug != site.Slug {
+			return fmt.Errorf("%w: the default site's slug cannot be changed", ErrInvalidSite)
+		}
+		site.Slug = slug
+	}
+	if input.Hosts != nil {
+		hosts := make([]string, 0, len(input.Hosts))
+		for _, host := range input.Hosts {
+			if host = models.NormalizeHost(host); host == "" {
+				continue
+			}
+			if owner, ok := s.lookup(host); ok && owner.ID != site.ID {
+				return fmt.Errorf("%w: host %s already belongs to site %s", ErrInvalidSite, host, owner.Slug)
+			}
+			hosts = append(hosts, host)
+		}
+		site.Hosts = strings.Join(hosts, ",")
+	}
+	return nil
+}
+
+// Resolve returns the site serving host, falling back to the default site
+// for hosts that belong to no site
+func (s *SiteService) Resolve(host string) (*models.Site, error) {
+	if site, ok := s.lookup(models.NormalizeHost(host)); ok {
+		return &site, nil
+	}
+	s.mu.RLock()
+	site, ok := s.byID[models.DefaultSiteID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrSiteNotFound
+	}
+	return &site, nil
+}
+
+// lookup finds the site that lists host, loading the host table if needed
+func (s *SiteService) lookup(host string) (models.Site, bool) {
+	s.mu.RLock()
+	loaded := s.loaded
+	s.mu.RUnlock()
+	if !loaded {
+		if err := s.load(); err != nil {
+			slog.Error("Failed to load sites", "error", err)
+			return models.Site{}, false
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	site, ok := s.hosts[host]
+	return site, ok
+}
+
+func (s *SiteService) load() error {
+	sites, err := s.List()
+	if err != nil {
+		return err
+	}
+	hosts := make(map[string]models.Site)
+	byID := make(map[uint]models.Site, len(sites))
+	for _, site := range sites {
+		byID[site.ID] = site
+		for _, host := range site.HostList() {
+			hosts[host] = site
+		}
+	}
+
+	s.mu.Lock()
+	s.hosts, s.byID, s.loaded = hosts, byID, true
+	s.mu.Unlock()
+	return nil
+}
+
+// invalidate drops the host table here and on other instances
+func (s *SiteService) invalidate() {
+	s.reset()
+	cache.Publish(cache.TopicSites)
+}
+
+func (s *SiteService) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loaded = false
+	s.hosts, s.byID = nil, nil
+}
+
+var (
+	globalSiteService *SiteService
+	siteServiceOnce   sync.Once
+)
+
+// GetGlobalSiteService returns the global site service
+func GetGlobalSiteService() *SiteService {
+	siteServiceOnce.Do(func() {
+		globalSiteService = NewSiteService()
+	})
+	return globalSiteService
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"errors"
+	"testing"
+)
+
+func TestSiteServiceResolvesHosts(t *testing.T) {
+	newTestDB(t)
+	s := NewSiteService()
+
+	name, slug := "Agency Client", "client"
+	site, err := s.Create(SiteInput{Name: &name, Slug: &slug, Hosts: []string{"Client.example.com:443", " www.client.example "}},
+		SiteAdmin{Username: "client-admin", Password: "secret123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resolved, err := s.Resolve("client.example.com"); err != nil || resolved.ID != site.ID {
+		t.Errorf("Resolve(client host) = %+v, %v", resolved, err)
+	}
+	if resolved, err := s.Resolve("WWW.CLIENT.EXAMPLE:8080"); err != nil || resolved.ID != site.ID {
+		t.Errorf("Resolve(alias with port) = %+v, %v", resolved, err)
+	}
+	if resolved, err := s.Resolve("unknown.example"); err != nil || resolved.ID != models.DefaultSiteID {
+		t.Errorf("Resolve(unknown host) = %+v, %v, want the default site", resolved, err)
+	}
+
+	var user models.User
+	if err := database.DB.Where("username = ?", "client-admin").First(&user).Error; err != nil || user.SiteID != site.ID || !user.IsAdmin {
+		t.Errorf("site admin = %+v, %v", user, err)
+	}
+
+	other, otherSlug := "Other", "other"
+	if _, err := s.Create(SiteInput{Name: &other, Slug: &otherSlug, Hosts: []string{"client.example.com"}},
+		SiteAdmin{Username: "other-admin", Password: "secret123"}); !errors.Is(err, ErrInvalidSite) {
+		t.Errorf("Create with a taken host error = %v", err)
+	}
+}
+
+func TestSiteServiceDeleteRequiresEmptySite(t *testing.T) {
+	newTestDB(t)
+	s := NewSiteService()
+
+	name, slug := "Temporary", "temp"
+	site, err := s.Create(SiteInput{Name: &name, Slug: &slug}, SiteAdmin{Username: "temp-admin", Password: "secret123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.DB.Create(&models.Category{SiteID: site.ID, Name: "Only"})
+
+	if err := s.Delete(site.ID); !errors.Is(err, ErrSiteInUse) {
+		t.Errorf("Delete of a site with content error = %v", err)
+	}
+	if err := s.Delete(models.DefaultSiteID); !errors.Is(err, ErrInvalidSite) {
+		t.Errorf("Delete of the default site error = %v", err)
+	}
+
+	database.DB.Unscoped().Where("site_id = ?", site.ID).Delete(&models.Category{})
+	if err := s.Delete(site.ID); err != nil {
+		t.Fatal(err)
+	}
+	var users int64
+	database.DB.Model(&models.User{}).Where("site_id = ?", site.ID).Count(&users)
+	if users != 0 {
+		t.Errorf("%d users of the deleted site remain", users)
+	}
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/keywords"
+	"blog-backend/internal/models"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidStopWords is returned for a stop word list that cannot be saved
+var ErrInvalidStopWords = errors.New("invalid stop words")
+
+// Limits of a saved stop word list
+const (
+	maxStopWords      = 5000
+	maxStopWordLength = 50
+)
+
+// StopWordList is the stop words of a language. Customized lists were saved
+// by an admin and replace the built-in words.
+type StopWordList struct {
+	Language   string   `json:"language"`
+	Words      []string `json:"words"`
+	Customized bool     `json:"customized"`
+}
+
+// StopWordService keeps the stop words left out of extracted keywords:
+// the built-in lists of the keywords package, unless an admin saved a list
+// of their own for the language. Interests, SEO keyword suggestions and
+// smart tags all extract keywords through it.
+type StopWordService struct {
+	db func() *gorm.DB
+
+	mu   sync.RWMutex
+	sets map[string]map[string]bool // by base language
+}
+
+// NewStopWordService creates a stop word service
+func NewStopWordService() *StopWordService {
+	return &StopWordService{
+		db:   func() *gorm.DB { return database.DB },
+		sets: make(map[string]map[string]bool),
+	}
+}
+
+// Extract returns the keywords of text in a language
+func (s *StopWordService) Extract(text, language string) []string {
+	return keywords.Extract(text, language, s.set(language))
+}
+
+// Lists returns the list of every language with built-in or saved stop words
+func (s *StopWordService) Lists() ([]StopWordList, error) {
+	var saved []models.StopWords
+	if err := s.db().Order("language").Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	lists := make(map[string]StopWordList)
+	for _, language := range keywords.Languages() {
+		lists[language] = StopWordList{Language: language, Words: keywords.DefaultStopWords(language)}
+	}
+	for _, row := range saved {
+		lists[row.Language] = savedStopWordList(row)
+	}
+	out := make([]StopWordList, 0, len(lists))
+	for _, list := range lists {
+		out = append(out, list)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Language < out[j].Language })
+	return out, nil
+}
+
+// List returns the stop words used for a language tag
+func (s *StopWordService) List(language string) (*StopWordList, error) {
+	base, err := stopWordLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	var row models.StopWords
+	found := s.db().Where("language = ?", base).Limit(1).Find(&row)
+	if found.Error != nil {
+		return nil, found.Error
+	}
+	if found.RowsAffected > 0 {
+		list := savedStopWordList(row)
+		return &list, nil
+	}
+	return &StopWordList{Language: base, Words: keywords.DefaultStopWords(base)}, nil
+}
+
+// Save replaces the stop words of a language. Words are lowercased like
+// the words they are matched against, and duplicates are dropped.
+func (s *StopWordService) Save(language string, words []string) (*StopWordList, error) {
+	base, err := stopWordLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(words))
+	var cleaned []string
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" || seen[word] {
+			continue
+		}
+		if utf8.RuneCountInString(word) > maxStopWordLength || strings.ContainsAny(word, "\r\n") {
+			return nil, fmt.Errorf("%w: %q is not a word of at most %d characters", ErrInvalidStopWords, word, maxStopWordLength)
+		}
+		seen[word] = true
+		cleaned = append(cleaned, word)
+	}
+	if len(cleaned) > maxStopWords {
+		return nil, fmt.Errorf("%w: at most %d words", ErrInvalidStopWords, maxStopWords)
+	}
+	sort.Strings(cleaned)
+
+	row := models.StopWords{Language: base, Words: strings.Join(cleaned, "\n")}
+	if err := s.db().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"words", "updated_at"}),
+	}).Create(&row).Error; err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return &StopWordList{Language: base, Words: cleaned, Customized: true}, nil
+}
+
+// Reset removes the saved list of a language so the built-in words apply
+// again
+func (s *StopWordService) Reset(language string) error {
+	base, err := stopWordLanguage(language)
+	if err != nil {
+		return err
+	}
+	if err := s.db().Where("language = ?", base).Delete(&models.StopWords{}).Error; err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// set returns the stop words of a language tag, loading them on first use
+func (s *StopWordService) set(language string) map[string]bool {
+	base := keywords.BaseLanguage(language)
+	s.mu.RLock()
+	set, ok := s.sets[base]
+	s.mu.RUnlock()
+	if ok {
+		return set
+	}
+
+	words := keywords.DefaultStopWords(base)
+	var row models.StopWords
+	found := s.db().Where("language = ?", base).Limit(1).Find(&row)
+	switch {
+	case found.Error != nil:
+		// Extraction goes on with the built-in words; the next call retries
+		slog.Warn("Failed to load stop words", "language", base, "error", found.Error)
+		return toSet(words)
+	case found.RowsAffected > 0:
+		words = savedStopWordList(row).Words
+	}
+	set = toSet(words)
+	s.mu.Lock()
+	s.sets[base] = set
+	s.mu.Unlock()
+	return set
+}
+
+// invalidate drops the loaded lists here and on other instances
+func (s *StopWordService) invalidate() {
+	s.reset()
+	cache.Publish(cache.TopicStopWords)
+}
+
+func (s *StopWordService) reset() {
+	s.mu.Lock()
+	s.sets = make(map[string]map[string]bool)
+	s.mu.Unlock()
+}
+
+func stopWordLanguage(language string) (string, error) {
+	language = strings.TrimSpace(language)
+	if !languageCode.MatchString(language) {
+		return "", fmt.Errorf("%w: %q is not a language code", ErrInvalidStopWords, language)
+	}
+	return keywords.BaseLanguage(language), nil
+}
+
+func savedStopWordList(row models.StopWords) StopWordList {
+	words := []string{}
+	if row.Words != "" {
+		words = strings.Split(row.Words, "\n")
+	}
+	return StopWordList{Language: row.Language, Words: words, Customized: true}
+}
+
+func toSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		set[word] = true
+	}
+	return set
+}
+
+// Global stop word service instance
+var (
+	globalStopWordService     *StopWordService
+	globalStopWordServiceOnce sync.Once
+)
+
+// GetGlobalStopWordService returns the global stop word service
+func GetGlobalStopWordService() *StopWordService {
+	globalStopWordServiceOnce.Do(func() {
+		globalStopWordService = NewStopWordService()
+		cache.Subscribe(cache.TopicStopWords, globalStopWordService.reset)
+	})
+	return globalStopWordService
+}
+
+// ExtractKeywords returns the keywords of text in a language, leaving out
+// its stop words
+func ExtractKeywords(text, language string) []string {
+	return GetGlobalStopWordService().Extract(text, language)
+}
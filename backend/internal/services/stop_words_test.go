@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStopWordService(t *testing.T) {
+	newTestDB(t)
+	s := NewStopWordService()
+
+	text := "我们可以使用缓存提升性能"
+	if got, want := s.Extract(text, "zh-CN"), []string{"缓存", "提升", "性能"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() with the built-in words = %q, want %q", got, want)
+	}
+
+	list, err := s.Save("zh-CN", []string{" 提升 ", "性能", "提升", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Language != "zh" || !reflect.DeepEqual(list.Words, []string{"性能", "提升"}) || !list.Customized {
+		t.Errorf("Save() = %+v", list)
+	}
+	// The saved list replaces the built-in one
+	if got, want := s.Extract(text, "zh"), []string{"我们", "可以", "使用", "缓存"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() with the saved words = %q, want %q", got, want)
+	}
+	lists, err := s.Lists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var languages []string
+	for _, list := range lists {
+		languages = append(languages, list.Language)
+		if list.Customized != (list.Language == "zh") {
+			t.Errorf("the %s list is customized: %v", list.Language, list.Customized)
+		}
+	}
+	if !reflect.DeepEqual(languages, []string{"en", "ja", "zh"}) {
+		t.Errorf("Lists() has the languages %q", languages)
+	}
+
+	if err := s.Reset("zh"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.Extract(text, "zh"), []string{"缓存", "提升", "性能"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() after the reset = %q, want %q", got, want)
+	}
+
+	for _, bad := range []struct {
+		language string
+		words    []string
+	}{
+		{"", []string{"a"}},
+		{"not a language", []string{"a"}},
+		{"en", []string{"two\nlines"}},
+	} {
+		if _, err := s.Save(bad.language, bad.words); !errors.Is(err, ErrInvalidStopWords) {
+			t.Errorf("Save(%q, %q) = %v, want ErrInvalidStopWords", bad.language, bad.words, err)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/events"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"fmt"
+	"io/fs"
+	"math"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// storageTrendWindow is how far back growth is measured
+	storageTrendWindow = 30 * 24 * time.Hour
+	// storageSnapshotRetention is how long daily snapshots are kept
+	storageSnapshotRetention = 365 * 24 * time.Hour
+	// storageFullSoonDays warns when the disk fills within this many days
+	storageFullSoonDays = 14
+)
+
+// Storage warning kinds
+const (
+	StorageWarningVolumeFull = "volume_full"
+	StorageWarningFullSoon   = "full_soon"
+)
+
+// Walking a large uploads directory is slow, so reports are reused for a
+// few minutes unless a refresh is asked for
+var storageUsageCache = cache.New("storage", 10*time.Minute)
+
+func init() {
+	// Uploaded and deleted media change the upload sizes
+	events.Subscribe(func() { storageUsageCache.Delete("usage") }, hooks.MediaUploaded, hooks.MediaUpdated)
+}
+
+// FileUsage is the number and total size of a group of files
+type FileUsage struct {
+	Files int64 `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// DatabaseUsage is the size of the database and the rows in each table
+type DatabaseUsage struct {
+	Driver string               `json:"driver"`
+	Bytes  int64                `json:"bytes"`
+	Rows   int64                `json:"rows"`
+	Tables []database.TableRows `json:"tables"`
+}
+
+// UploadUsage is the size of the uploads directory. ByType groups files by
+// the major MIME type of their extension: image, video, audio, application,
+// text or other.
+type UploadUsage struct {
+	Path string `json:"path"`
+	FileUsage
+	ByType map[string]FileUsage `json:"by_type"`
+}
+
+// VolumeUsage is the capacity of a filesystem holding kuno's data. Paths
+// lists the data directories stored on it.
+type VolumeUsage struct {
+	Paths       []string `json:"paths"`
+	Total       int64    `json:"total"`
+	Free        int64    `json:"free"`
+	Used        int64    `json:"used"`
+	UsedPercent float64  `json:"used_percent"`
+}
+
+// StorageTrend is the growth of the database and uploads, measured against
+// the oldest daily snapshot in the trend window
+type StorageTrend struct {
+	Since               time.Time                `json:"since"`
+	DatabaseBytesPerDay int64                    `json:"database_bytes_per_day"`
+	UploadBytesPerDay   int64                    `json:"upload_bytes_per_day"`
+	DaysUntilFull       *int                     `json:"days_until_full,omitempty"`
+	Snapshots           []models.StorageSnapshot `json:"snapshots"`
+}
+
+// StorageWarning flags a volume that is, or soon will be, full
+type StorageWarning struct {
+	Kind          string   `json:"kind"`
+	Paths         []string `json:"paths"`
+	UsedPercent   float64  `json:"used_percent,omitempty"`
+	DaysUntilFull int      `json:"days_until_full,omitempty"`
+	Message       string   `json:"message"`
+}
+
+// StorageUsage reports where kuno's disk space goes
+type StorageUsage struct {
+	Database    DatabaseUsage    `json:"database"`
+	Uploads     UploadUsage      `json:"uploads"`
+	BackupBytes int64            `json:"backup_bytes"`
+	Volumes     []VolumeUsage    `json:"volumes"`
+	Trend       *StorageTrend    `json:"trend,omitempty"`
+	Warnings    []StorageWarning `json:"warnings"`
+	MeasuredAt  time.Time        `json:"measured_at"`
+}
+
+// StorageService measures the database, uploads and the volumes they live
+// on, and records daily snapshots to follow their growth
+type StorageService struct {
+	db          func() *gorm.DB
+	uploadDir   string
+	backupDir   func() string
+	warnPercent int
+}
+
+// NewStorageService creates a storage service from the storage settings
+func NewStorageService() *StorageService {
+	cfg := config.Get().Storage
+	return &StorageService{
+		db:          func() *gorm.DB { return database.DB },
+		uploadDir:   cfg.UploadDir,
+		backupDir:   func() string { return GetGlobalBackupService().dir },
+		warnPercent: cfg.DiskWarnPercent,
+	}
+}
+
+// Usage returns the current storage report. Unless refresh is set a report
+// measured in the last few minutes is returned.
+func (s *StorageService) Usage(refresh bool) (*StorageUsage, error) {
+	var usage StorageUsage
+	if !refresh && storageUsageCache.Get("usage", &usage) {
+		return &usage, nil
+	}
+
+	measured, err := s.measure()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.addTrend(measured); err != nil {
+		return nil, err
+	}
+	measured.Warnings = s.warnings(measured)
+	storageUsageCache.Set("usage", measured)
+	return measured, nil
+}
+
+// Snapshot records the current database and upload sizes for the trend and
+// drops snapshots past the retention period
+func (s *StorageService) Snapshot() (*models.StorageSnapshot, error) {
+	usage, err := s.measure()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &models.StorageSnapshot{
+		DatabaseBytes: usage.Database.Bytes,
+		UploadBytes:   usage.Uploads.Bytes,
+		UploadFiles:   usage.Uploads.Files,
+		Rows:          usage.Database.Rows,
+		VolumeFree:    minFree(usage.Volumes),
+	}
+	if err := s.db().Create(snapshot).Error; err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-storageSnapshotRetention)
+	if err := s.db().Where("created_at < ?", cutoff).Delete(&models.StorageSnapshot{}).Error; err != nil {
+		return nil, err
+	}
+	storageUsageCache.Delete("usage")
+	return snapshot, nil
+}
+
+func (s *StorageService) measure() (*StorageUsage, error) {
+	db := s.db()
+	usage := &StorageUsage{MeasuredAt: time.Now(), Warnings: []StorageWarning{}}
+
+	var err error
+	usage.Database.Driver = database.Dialect()
+	if usage.Database.Bytes, err = database.Size(db); err != nil {
+		return nil, fmt.Errorf("failed to read database size: %v", err)
+	}
+	if usage.Database.Tables, err = database.CountRows(db); err != nil {
+		return nil, fmt.Errorf("failed to count rows: %v", err)
+	}
+	for _, table := range usage.Database.Tables {
+		usage.Database.Rows += table.Rows
+	}
+
+	if usage.Uploads, err = measureUploads(s.uploadDir); err != nil {
+		return nil, fmt.Errorf("failed to measure uploads: %v", err)
+	}
+	backupDir := s.backupDir()
+	if backups, err := dirSize(backupDir); err == nil {
+		usage.BackupBytes = backups.Bytes
+	}
+
+	usage.Volumes = volumesOf(database.DataDir(), s.uploadDir, backupDir)
+	return usage, nil
+}
+
+func (s *StorageService) addTrend(usage *StorageUsage) error {
+	var snapshots []models.StorageSnapshot
+	since := usage.MeasuredAt.Add(-storageTrendWindow)
+	if err := s.db().Where("created_at >= ?", since).Order("created_at").Find(&snapshots).Error; err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	oldest := snapshots[0]
+	trend := &StorageTrend{Since: oldest.CreatedAt, Snapshots: snapshots}
+	days := usage.MeasuredAt.Sub(oldest.CreatedAt).Hours() / 24
+	if days >= 1 {
+		trend.DatabaseBytesPerDay = int64(float64(usage.Database.Bytes-oldest.DatabaseBytes) / days)
+		trend.UploadBytesPerDay = int64(float64(usage.Uploads.Bytes-oldest.UploadBytes) / days)
+		growth := trend.DatabaseBytesPerDay + trend.UploadBytesPerDay
+		if free := minFree(usage.Volumes); growth > 0 && len(usage.Volumes) > 0 {
+			remaining := int(math.Min(float64(free/growth), math.MaxInt32))
+			trend.DaysUntilFull = &remaining
+		}
+	}
+	usage.Trend = trend
+	return nil
+}
+
+func (s *StorageService) warnings(usage *StorageUsage) []StorageWarning {
+	warnings := []StorageWarning{}
+	for _, volume := range usage.Volumes {
+		if s.warnPercent > 0 && volume.UsedPercent >= float64(s.warnPercent) {
+			warnings = append(warnings, StorageWarning{
+				Kind:        StorageWarningVolumeFull,
+				Paths:       volume.Paths,
+				UsedPercent: volume.UsedPercent,
+				Message: fmt.Sprintf("The volume holding %s is %.0f%% full",
+					strings.Join(volume.Paths, ", "), volume.UsedPercent),
+			})
+		}
+	}
+	if trend := usage.Trend; trend != nil && trend.DaysUntilFull != nil && *trend.DaysUntilFull <= storageFullSoonDays {
+		var paths []string
+		for _, volume := range usage.Volumes {
+			paths = append(paths, volume.Paths...)
+		}
+		warnings = append(warnings, StorageWarning{
+			Kind:          StorageWarningFullSoon,
+			Paths:         paths,
+			DaysUntilFull: *trend.DaysUntilFull,
+			Message:       fmt.Sprintf("At the current growth rate the disk is full in about %d days", *trend.DaysUntilFull),
+		})
+	}
+	return warnings
+}
+
+func measureUploads(dir string) (UploadUsage, error) {
+	usage := UploadUsage{Path: dir, ByType: map[string]FileUsage{}}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		kind := uploadKind(path)
+		group := usage.ByType[kind]
+		group.Files++
+		group.Bytes += info.Size()
+		usage.ByType[kind] = group
+		usage.Files++
+		usage.Bytes += info.Size()
+		return nil
+	})
+	return usage, err
+}
+
+// uploadKind returns the major MIME type for the file's extension
+func uploadKind(path string) string {
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if kind, _, ok := strings.Cut(mimeType, "/"); ok {
+		switch kind {
+		case "image", "video", "audio", "application", "text":
+			return kind
+		}
+	}
+	return "other"
+}
+
+func dirSize(dir string) (FileUsage, error) {
+	var usage FileUsage
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			usage.Files++
+			usage.Bytes += info.Size()
+		}
+		return nil
+	})
+	return usage, err
+}
+
+// volumesOf returns the filesystems holding the given directories, each
+// listed once. Empty or unreadable paths are skipped.
+func volumesOf(paths ...string) []VolumeUsage {
+	volumes := []VolumeUsage{}
+	seen := map[uint64]int{}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		stat, err := statVolume(path)
+		if err != nil {
+			continue
+		}
+		if i, ok := seen[stat.device]; ok {
+			volumes[i].Paths = append(volumes[i].Paths, path)
+			continue
+		}
+		volume := VolumeUsage{Paths: []string{path}, Total: stat.total, Free: stat.free, Used: stat.total - stat.free}
+		if stat.total > 0 {
+			volume.UsedPercent = math.Round(float64(volume.Used)/float64(stat.total)*1000) / 10
+		}
+		seen[stat.device] = len(volumes)
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
+
+func minFree(volumes []VolumeUsage) int64 {
+	var free int64
+	for i, volume := range volumes {
+		if i == 0 || volume.Free < free {
+			free = volume.Free
+		}
+	}
+	return free
+}
+
+// volumeStat is the capacity of the filesystem holding a path. Free is the
+// space available to unprivileged users.
+type volumeStat struct {
+	device uint64
+	total  int64
+	free   int64
+}
+
+var (
+	globalStorageService *StorageService
+	storageServiceOnce   sync.Once
+)
+
+// GetGlobalStorageService returns the shared storage service
+func GetGlobalStorageService() *StorageService {
+	storageServiceOnce.Do(func() {
+		globalStorageService = NewStorageService()
+	})
+	return globalStorageService
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestStorageUsage(t *testing.T) {
+	backups := setupBackupTest(t)
+	files := map[string]string{
+		"images/a.png": "png image",
+		"images/b.JPG": "jpg",
+		"videos/c.mp4": "video data",
+		"misc/README":  "?",
+	}
+	for name, content := range files {
+		path := filepath.Join(backups.uploadDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	database.DB.Create(&models.Category{Name: "Notes"})
+	database.DB.Create(&models.StorageSnapshot{CreatedAt: time.Now().Add(-10 * 24 * time.Hour)})
+
+	s := &StorageService{
+		db:        func() *gorm.DB { return database.DB },
+		uploadDir: backups.uploadDir,
+		backupDir: func() string { return backups.dir },
+	}
+	usage, err := s.Usage(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if usage.Uploads.Files != 4 || usage.Uploads.ByType["image"].Files != 2 ||
+		usage.Uploads.ByType["video"].Bytes != int64(len("video data")) || usage.Uploads.ByType["other"].Files != 1 {
+		t.Fatalf("unexpected uploads: %+v", usage.Uploads)
+	}
+	if usage.Database.Bytes == 0 {
+		t.Error("database size not measured")
+	}
+	rows := map[string]int64{}
+	for _, table := range usage.Database.Tables {
+		rows[table.Table] = table.Rows
+	}
+	if rows["categories"] != 1 || rows["storage_snapshots"] != 1 {
+		t.Errorf("unexpected row counts: %v", rows)
+	}
+	if usage.Trend == nil || usage.Trend.UploadBytesPerDay <= 0 || usage.Trend.DatabaseBytesPerDay <= 0 {
+		t.Errorf("expected growth since the snapshot, got %+v", usage.Trend)
+	}
+
+	if _, err := s.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	database.DB.Model(&models.StorageSnapshot{}).Count(&count)
+	if count != 2 {
+		t.Errorf("expected 2 snapshots, got %d", count)
+	}
+}
+
+func TestStorageWarnings(t *testing.T) {
+	s := &StorageService{warnPercent: 90}
+	days := 3
+	usage := &StorageUsage{
+		Volumes: []VolumeUsage{
+			{Paths: []string{"/data"}, UsedPercent: 95},
+			{Paths: []string{"/uploads"}, UsedPercent: 40},
+		},
+		Trend: &StorageTrend{DaysUntilFull: &days},
+	}
+
+	warnings := s.warnings(usage)
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %+v", warnings)
+	}
+	if warnings[0].Kind != StorageWarningVolumeFull || warnings[0].Paths[0] != "/data" {
+		t.Errorf("unexpected volume warning: %+v", warnings[0])
+	}
+	if warnings[1].Kind != StorageWarningFullSoon || warnings[1].DaysUntilFull != 3 {
+		t.Errorf("unexpected growth warning: %+v", warnings[1])
+	}
+
+	s.warnPercent = 0
+	days = 60
+	if warnings := s.warnings(usage); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %+v", warnings)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package services
+
+import "errors"
+
+// Volume capacity is only reported on Unix systems
+func statVolume(path string) (volumeStat, error) {
+	return volumeStat{}, errors.New("volume statistics are not supported on this platform")
+}
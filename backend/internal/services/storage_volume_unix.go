@@ -0,0 +1,22 @@
+//go:build unix
+
+package services
+
+import "syscall"
+
+func statVolume(path string) (volumeStat, error) {
+	var fsStat syscall.Statfs_t
+	if err := syscall.Statfs(path, &fsStat); err != nil {
+		return volumeStat{}, err
+	}
+	var fileStat syscall.Stat_t
+	if err := syscall.Stat(path, &fileStat); err != nil {
+		return volumeStat{}, err
+	}
+	blockSize := int64(fsStat.Bsize)
+	return volumeStat{
+		device: uint64(fileStat.Dev),
+		total:  int64(fsStat.Blocks) * blockSize,
+		free:   int64(fsStat.Bavail) * blockSize,
+	}, nil
+}
@@ -0,0 +1,528 @@
+package services
+
+import (
+	"archive/zip"
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Theme packages are zip files holding a theme.json manifest, an optional
+// theme.css stylesheet and the images and fonts it uses under assets/. The
+// stylesheet refers to them as url(assets/...). A package may also have all
+// of this inside one top-level folder.
+const (
+	ThemePackageFormat  = "kuno-theme"
+	ThemePackageVersion = 1
+	// MaxThemePackageSize is the largest theme package accepted
+	MaxThemePackageSize = 20 << 20
+
+	themeManifest   = "theme.json"
+	themeStylesheet = "theme.css"
+	themeAssetDir   = "assets/"
+
+	maxThemeAssets    = 200
+	maxThemeAssetSize = 5 << 20
+	maxThemeCSSSize   = 1 << 20
+)
+
+// themeAssetTypes are the files a theme may ship, by extension. SVG images
+// are served with a sandboxing content security policy.
+var themeAssetTypes = map[string]string{
+	".png":   "image/png",
+	".jpg":   "image/jpeg",
+	".jpeg":  "image/jpeg",
+	".gif":   "image/gif",
+	".webp":  "image/webp",
+	".avif":  "image/avif",
+	".svg":   "image/svg+xml",
+	".ico":   "image/x-icon",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+}
+
+var (
+	// themeName is the form of theme names: lowercase words joined by
+	// hyphens
+	themeName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	// themeAssetRef finds the references to assets in a package stylesheet
+	themeAssetRef = regexp.MustCompile(`url\(\s*(['"]?)(?:\./)?assets/`)
+)
+
+var (
+	ErrInvalidTheme     = errors.New("invalid theme package")
+	ErrUnsupportedTheme = errors.New("theme package version not supported")
+	ErrThemeNotFound    = errors.New("theme not found")
+)
+
+// ThemeManifest is the theme.json of a theme package. Version is the
+// theme's own; FormatVersion is that of the package layout.
+type ThemeManifest struct {
+	Format        string          `json:"format"`
+	FormatVersion int             `json:"format_version"`
+	Name          string          `json:"name"`
+	Version       string          `json:"version,omitempty"`
+	Title         string          `json:"title"`
+	Description   string          `json:"description,omitempty"`
+	Author        string          `json:"author,omitempty"`
+	Homepage      string          `json:"homepage,omitempty"`
+	License       string          `json:"license,omitempty"`
+	Preview       string          `json:"preview,omitempty"`
+	Config        json.RawMessage `json:"config,omitempty"`
+}
+
+// ThemePackage is the content of a theme package
+type ThemePackage struct {
+	Manifest ThemeManifest       `json:"manifest"`
+	CSS      string              `json:"-"`
+	Assets   []models.ThemeAsset `json:"assets"`
+}
+
+// ReadThemePackage reads and validates a theme package
+func ReadThemePackage(r io.ReaderAt, size int64) (*ThemePackage, error) {
+	if size > MaxThemePackageSize {
+		return nil, fmt.Errorf("%w: larger than %d MB", ErrInvalidTheme, MaxThemePackageSize>>20)
+	}
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTheme, err)
+	}
+
+	// Packages zipped from their folder keep everything under it
+	prefix := ""
+	for _, file := range zipReader.File {
+		if dir, name := path.Split(file.Name); name == themeManifest && strings.Count(dir, "/") <= 1 {
+			if dir == "" {
+				prefix = ""
+				break
+			}
+			prefix = dir
+		}
+	}
+
+	pkg := &ThemePackage{Assets: []models.ThemeAsset{}}
+	var manifest []byte
+	for _, file := range zipReader.File {
+		if file.FileInfo().IsDir() || !strings.HasPrefix(file.Name, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(file.Name, prefix)
+		if strings.Contains(name, "\\") || path.Clean("/"+name) != "/"+name {
+			return nil, fmt.Errorf("%w: bad file name %q", ErrInvalidTheme, file.Name)
+		}
+		switch {
+		case name == themeManifest:
+			if manifest, err = readThemeFile(file, 64<<10); err != nil {
+				return nil, err
+			}
+		case name == themeStylesheet:
+			css, err := readThemeFile(file, maxThemeCSSSize)
+			if err != nil {
+				return nil, err
+			}
+			if !utf8.Valid(css) {
+				return nil, fmt.Errorf("%w: %s is not UTF-8", ErrInvalidTheme, themeStylesheet)
+			}
+			pkg.CSS = string(css)
+		case strings.HasPrefix(name, themeAssetDir):
+			assetPath := strings.TrimPrefix(name, themeAssetDir)
+			contentType, ok := themeAssetTypes[strings.ToLower(path.Ext(assetPath))]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s is not an image or font", ErrInvalidTheme, name)
+			}
+			if len(assetPath) > 255 {
+				return nil, fmt.Errorf("%w: asset path %q is too long", ErrInvalidTheme, assetPath)
+			}
+			if len(pkg.Assets) == maxThemeAssets {
+				return nil, fmt.Errorf("%w: more than %d assets", ErrInvalidTheme, maxThemeAssets)
+			}
+			data, err := readThemeFile(file, maxThemeAssetSize)
+			if err != nil {
+				return nil, err
+			}
+			pkg.Assets = append(pkg.Assets, models.ThemeAsset{
+				Path:        assetPath,
+				ContentType: contentType,
+				Size:        int64(len(data)),
+				Data:        data,
+			})
+		}
+		// Anything else, such as a README or LICENSE, is left out
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidTheme, themeManifest)
+	}
+	if err := json.Unmarshal(manifest, &pkg.Manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTheme, themeManifest, err)
+	}
+	if err := pkg.validate(); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+func readThemeFile(file *zip.File, limit int64) ([]byte, error) {
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%w: %s is larger than %d KB", ErrInvalidTheme, file.Name, limit>>10)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTheme, err)
+	}
+	defer src.Close()
+	// The size in the header is not to be trusted
+	data, err := io.ReadAll(io.LimitReader(src, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTheme, file.Name, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: %s is larger than %d KB", ErrInvalidTheme, file.Name, limit>>10)
+	}
+	return data, nil
+}
+
+func (p *ThemePackage) validate() error {
+	m := &p.Manifest
+	if m.Format != ThemePackageFormat || m.FormatVersion < 1 {
+		return fmt.Errorf("%w: %s must have format %q and a format_version", ErrInvalidTheme, themeManifest, ThemePackageFormat)
+	}
+	if m.FormatVersion > ThemePackageVersion {
+		return fmt.Errorf("%w: format version %d, this release reads up to %d", ErrUnsupportedTheme, m.FormatVersion, ThemePackageVersion)
+	}
+	m.Name = strings.TrimSpace(m.Name)
+	m.Title = strings.TrimSpace(m.Title)
+	if len(m.Name) > 64 || !themeName.MatchString(m.Name) {
+		return fmt.Errorf("%w: name must be lowercase letters, digits and hyphens, at most 64 characters", ErrInvalidTheme)
+	}
+	if m.Title == "" {
+		m.Title = m.Name
+	}
+	if utf8.RuneCountInString(m.Title) > 100 || len(m.Version) > 32 || utf8.RuneCountInString(m.Author) > 100 || len(m.License) > 50 {
+		return fmt.Errorf("%w: title or author longer than 100 characters, version than 32 or license than 50", ErrInvalidTheme)
+	}
+	if m.Homepage != "" {
+		u, err := url.Parse(m.Homepage)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(m.Homepage) > 500 {
+			return fmt.Errorf("%w: homepage must be an http(s) URL", ErrInvalidTheme)
+		}
+	}
+	if len(m.Config) == 0 {
+		m.Config = json.RawMessage("{}")
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(m.Config, &config); err != nil || config == nil {
+		return fmt.Errorf("%w: config must be a JSON object", ErrInvalidTheme)
+	}
+	if m.Preview != "" {
+		preview := strings.TrimPrefix(m.Preview, themeAssetDir)
+		if p.asset(preview) == nil {
+			return fmt.Errorf("%w: preview %s is not among the assets", ErrInvalidTheme, m.Preview)
+		}
+		if !strings.HasPrefix(themeAssetTypes[strings.ToLower(path.Ext(preview))], "image/") {
+			return fmt.Errorf("%w: preview %s is not an image", ErrInvalidTheme, m.Preview)
+		}
+		m.Preview = themeAssetDir + preview
+	}
+	// Activating the theme makes its stylesheet the site's custom CSS
+	for _, finding := range GetGlobalCustomCodeService().Scan(models.DefaultSiteID, CustomCodeCSS, p.CSS) {
+		if finding.Severity == CustomCodeError {
+			return fmt.Errorf("%w: %s line %d %s", ErrInvalidTheme, themeStylesheet, finding.Line, finding.Message)
+		}
+	}
+	return nil
+}
+
+func (p *ThemePackage) asset(assetPath string) *models.ThemeAsset {
+	for i := range p.Assets {
+		if p.Assets[i].Path == assetPath {
+			return &p.Assets[i]
+		}
+	}
+	return nil
+}
+
+// Write writes the package as a zip file
+func (p *ThemePackage) Write(w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+	manifest, err := json.MarshalIndent(p.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		name string
+		data []byte
+	}{{themeManifest, manifest}}
+	if p.CSS != "" {
+		files = append(files, struct {
+			name string
+			data []byte
+		}{themeStylesheet, []byte(p.CSS)})
+	}
+	for _, asset := range p.Assets {
+		files = append(files, struct {
+			name string
+			data []byte
+		}{themeAssetDir + asset.Path, asset.Data})
+	}
+	for _, file := range files {
+		writer, err := zipWriter.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(file.data); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// ThemeService installs theme packages on a site, activates them and
+// packages the site's theme for sharing. Activation copies the theme's
+// configuration and stylesheet into the site settings, with asset
+// references pointing at the installed assets; exporting the current theme
+// reverses that, so adjustments made in the settings travel with it.
+type ThemeService struct {
+	db func() *gorm.DB
+}
+
+// NewThemeService creates a theme service
+func NewThemeService() *ThemeService {
+	return &ThemeService{db: func() *gorm.DB { return database.DB }}
+}
+
+// List returns the themes installed on the site, without asset data, and
+// the name of the active one
+func (s *ThemeService) List(ctx context.Context) ([]models.Theme, string, error) {
+	db := s.db().WithContext(ctx)
+	themes := []models.Theme{}
+	if err := db.Preload("Assets", withoutAssetData).Order("title ASC, id ASC").Find(&themes).Error; err != nil {
+		return nil, "", err
+	}
+	var settings models.SiteSettings
+	if err := db.Select("active_theme").Limit(1).Find(&settings).Error; err != nil {
+		return nil, "", err
+	}
+	return themes, settings.ActiveTheme, nil
+}
+
+// Get returns an installed theme without asset data
+func (s *ThemeService) Get(ctx context.Context, name string) (*models.Theme, error) {
+	var theme models.Theme
+	if err := s.db().WithContext(ctx).Preload("Assets", withoutAssetData).Where("name = ?", name).First(&theme).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrThemeNotFound
+		}
+		return nil, err
+	}
+	return &theme, nil
+}
+
+// Asset returns an asset of an installed theme
+func (s *ThemeService) Asset(ctx context.Context, name, assetPath string) (*models.ThemeAsset, error) {
+	db := s.db().WithContext(ctx)
+	var asset models.ThemeAsset
+	err := db.Where("theme_id IN (?) AND path = ?", db.Model(&models.Theme{}).Select("id").Where("name = ?", name), assetPath).
+		First(&asset).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrThemeNotFound
+		}
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// Install adds a theme package to the site, replacing an installed theme
+// of the same name. An active theme stays as it is in the site settings
+// until it is activated again.
+func (s *ThemeService) Install(ctx context.Context, pkg *ThemePackage) (*models.Theme, error) {
+	var theme models.Theme
+	err := s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("name = ?", pkg.Manifest.Name).First(&theme).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if theme.ID != 0 {
+			if err := tx.Where("theme_id = ?", theme.ID).Delete(&models.ThemeAsset{}).Error; err != nil {
+				return err
+			}
+		}
+		m := pkg.Manifest
+		theme.Name = m.Name
+		theme.Version = m.Version
+		theme.Title = m.Title
+		theme.Description = m.Description
+		theme.Author = m.Author
+		theme.Homepage = m.Homepage
+		theme.License = m.License
+		theme.Preview = strings.TrimPrefix(m.Preview, themeAssetDir)
+		theme.Config = string(m.Config)
+		theme.CSS = pkg.CSS
+		theme.Assets = nil
+		if err := tx.Save(&theme).Error; err != nil {
+			return err
+		}
+		for _, asset := range pkg.Assets {
+			asset.ID = 0
+			asset.ThemeID = theme.ID
+			if err := tx.Create(&asset).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, theme.Name)
+}
+
+// Activate makes an installed theme the site's theme, replacing the theme
+// configuration and custom CSS in the site settings with the theme's
+func (s *ThemeService) Activate(ctx context.Context, name string) error {
+	theme, err := s.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	css := themeAssetRef.ReplaceAllString(theme.CSS, "url(${1}"+theme.AssetURL(""))
+	result := s.db().WithContext(ctx).Model(&models.SiteSettings{}).Where("1 = 1").Updates(map[string]interface{}{
+		"active_theme": theme.Name,
+		"theme_config": theme.Config,
+		"custom_css":   css,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: the site has no settings yet", ErrInvalidTheme)
+	}
+	cache.Publish(cache.TopicSettings)
+	return nil
+}
+
+// Delete removes an installed theme. The active theme cannot be removed,
+// as the site's stylesheet uses its assets.
+func (s *ThemeService) Delete(ctx context.Context, name string) error {
+	theme, err := s.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	_, active, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+	if active == theme.Name {
+		return fmt.Errorf("%w: %s is the active theme", ErrInvalidTheme, theme.Name)
+	}
+	return s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("theme_id = ?", theme.ID).Delete(&models.ThemeAsset{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(theme).Error
+	})
+}
+
+// Package returns an installed theme as a package
+func (s *ThemeService) Package(ctx context.Context, name string) (*ThemePackage, error) {
+	theme, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.pack(ctx, theme, theme.Config, theme.CSS)
+}
+
+// PackageCurrent returns the site's current theme as a package: the active
+// theme's metadata and assets with the theme configuration and custom CSS
+// of the site settings. Sites without an installed active theme are
+// packaged as a theme named "custom" without assets.
+func (s *ThemeService) PackageCurrent(ctx context.Context) (*ThemePackage, error) {
+	var settings models.SiteSettings
+	if err := s.db().WithContext(ctx).Select("active_theme", "theme_config", "custom_css").Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	theme, err := s.Get(ctx, settings.ActiveTheme)
+	if errors.Is(err, ErrThemeNotFound) {
+		theme, err = &models.Theme{Name: "custom", Title: "Custom theme"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	config := strings.TrimSpace(settings.ThemeConfig)
+	if config == "" {
+		config = "{}"
+	}
+	css := strings.ReplaceAll(settings.CustomCSS, theme.AssetURL(""), themeAssetDir)
+	return s.pack(ctx, theme, config, css)
+}
+
+func (s *ThemeService) pack(ctx context.Context, theme *models.Theme, config, css string) (*ThemePackage, error) {
+	pkg := &ThemePackage{
+		Manifest: ThemeManifest{
+			Format:        ThemePackageFormat,
+			FormatVersion: ThemePackageVersion,
+			Name:          theme.Name,
+			Version:       theme.Version,
+			Title:         theme.Title,
+			Description:   theme.Description,
+			Author:        theme.Author,
+			Homepage:      theme.Homepage,
+			License:       theme.License,
+			Config:        json.RawMessage(config),
+		},
+		CSS:    css,
+		Assets: []models.ThemeAsset{},
+	}
+	if theme.Preview != "" {
+		pkg.Manifest.Preview = themeAssetDir + theme.Preview
+	}
+	if theme.ID != 0 {
+		if err := s.db().WithContext(ctx).Where("theme_id = ?", theme.ID).Find(&pkg.Assets).Error; err != nil {
+			return nil, err
+		}
+		sort.Slice(pkg.Assets, func(i, j int) bool { return pkg.Assets[i].Path < pkg.Assets[j].Path })
+	}
+	// Check what goes out the way packages coming in are checked
+	var buf bytes.Buffer
+	if err := pkg.Write(&buf); err != nil {
+		return nil, err
+	}
+	if _, err := ReadThemePackage(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// withoutAssetData preloads theme assets without their content
+func withoutAssetData(db *gorm.DB) *gorm.DB {
+	return db.Omit("data").Order("path ASC")
+}
+
+var (
+	globalThemeService     *ThemeService
+	globalThemeServiceOnce sync.Once
+)
+
+// GetGlobalThemeService returns the global theme service
+func GetGlobalThemeService() *ThemeService {
+	globalThemeServiceOnce.Do(func() {
+		globalThemeService = NewThemeService()
+	})
+	return globalThemeService
+}
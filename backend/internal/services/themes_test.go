@@ -0,0 +1,152 @@
+package services
+
+import (
+	"archive/zip"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func themeZip(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for name, content := range files {
+		writer, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer.Write([]byte(content))
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestReadThemePackage(t *testing.T) {
+	manifest := `{"format":"kuno-theme","format_version":1,"name":"paper","title":"Paper","preview":"preview.png","config":{"accent":"#333"}}`
+	pkg, err := readThemeZip(t, map[string]string{
+		"paper/theme.json":         manifest,
+		"paper/theme.css":          "body { background: url(assets/paper.png); }",
+		"paper/assets/preview.png": "png",
+		"paper/assets/paper.png":   "png",
+		"paper/README.md":          "About the theme",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Manifest.Name != "paper" || pkg.Manifest.Preview != "assets/preview.png" || len(pkg.Assets) != 2 || pkg.CSS == "" {
+		t.Errorf("package = %+v", pkg)
+	}
+
+	for name, files := range map[string]map[string]string{
+		"no manifest":   {"theme.css": "body {}"},
+		"wrong format":  {"theme.json": `{"format":"kuno","format_version":1,"name":"paper"}`},
+		"bad name":      {"theme.json": `{"format":"kuno-theme","format_version":1,"name":"Paper Theme"}`},
+		"config list":   {"theme.json": `{"format":"kuno-theme","format_version":1,"name":"paper","config":[1]}`},
+		"no preview":    {"theme.json": manifest},
+		"script asset":  {"theme.json": `{"format":"kuno-theme","format_version":1,"name":"paper"}`, "assets/x.js": "alert(1)"},
+		"escaping path": {"theme.json": `{"format":"kuno-theme","format_version":1,"name":"paper"}`, "assets/../../x.png": "png"},
+	} {
+		if _, err := readThemeZip(t, files); !errors.Is(err, ErrInvalidTheme) {
+			t.Errorf("%s: err = %v, want ErrInvalidTheme", name, err)
+		}
+	}
+	if _, err := readThemeZip(t, map[string]string{"theme.json": `{"format":"kuno-theme","format_version":2,"name":"paper"}`}); !errors.Is(err, ErrUnsupportedTheme) {
+		t.Errorf("newer format = %v, want ErrUnsupportedTheme", err)
+	}
+}
+
+func readThemeZip(t *testing.T, files map[string]string) (*ThemePackage, error) {
+	r := themeZip(t, files)
+	return ReadThemePackage(r, r.Size())
+}
+
+func TestThemeInstallAndExport(t *testing.T) {
+	newTestDB(t)
+	s := &ThemeService{db: func() *gorm.DB { return database.DB }}
+	ctx := context.Background()
+	if err := database.DB.Create(&models.SiteSettings{SiteTitle: "Blog"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, err := readThemeZip(t, map[string]string{
+		"theme.json":         `{"format":"kuno-theme","format_version":1,"name":"paper","version":"1.0.0","title":"Paper","preview":"assets/preview.png"}`,
+		"theme.css":          "body { background: url('assets/paper.png'); }",
+		"assets/preview.png": "preview",
+		"assets/paper.png":   "paper",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Install(ctx, pkg); err != nil {
+		t.Fatal(err)
+	}
+	// Installing again replaces the theme
+	theme, err := s.Install(ctx, pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme.PreviewURL != "/api/themes/paper/assets/preview.png" || theme.Config != "{}" || len(theme.Assets) != 2 || theme.Assets[0].Data != nil {
+		t.Errorf("installed theme = %+v", theme)
+	}
+	if asset, err := s.Asset(ctx, "paper", "paper.png"); err != nil || string(asset.Data) != "paper" || asset.ContentType != "image/png" {
+		t.Errorf("asset = %+v, %v", asset, err)
+	}
+
+	if err := s.Activate(ctx, "paper"); err != nil {
+		t.Fatal(err)
+	}
+	var settings models.SiteSettings
+	database.DB.First(&settings)
+	if settings.ActiveTheme != "paper" || settings.CustomCSS != "body { background: url('/api/themes/paper/assets/paper.png'); }" {
+		t.Errorf("settings after activation: theme %q, css %q", settings.ActiveTheme, settings.CustomCSS)
+	}
+	if err := s.Delete(ctx, "paper"); !errors.Is(err, ErrInvalidTheme) {
+		t.Errorf("deleting the active theme = %v", err)
+	}
+
+	// The current theme carries the adjusted settings with the theme's assets
+	database.DB.Model(&settings).Updates(map[string]interface{}{
+		"theme_config": `{"accent":"#c00"}`,
+		"custom_css":   settings.CustomCSS + "\nh1 { color: red; }",
+	})
+	current, err := s.PackageCurrent(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Manifest.Name != "paper" || string(current.Manifest.Config) != `{"accent":"#c00"}` || len(current.Assets) != 2 ||
+		!strings.Contains(current.CSS, "url('assets/paper.png')") || !strings.Contains(current.CSS, "color: red") {
+		t.Errorf("current theme = %+v, css %q", current.Manifest, current.CSS)
+	}
+	var buf bytes.Buffer
+	if err := current.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadThemePackage(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Errorf("exported package does not read back: %v", err)
+	}
+
+	database.DB.Model(&settings).Update("active_theme", "")
+	if current, err := s.PackageCurrent(ctx); err != nil || current.Manifest.Name != "custom" || len(current.Assets) != 0 {
+		t.Errorf("without an installed theme = %+v, %v", current, err)
+	}
+	if err := s.Delete(ctx, "paper"); err != nil {
+		t.Fatal(err)
+	}
+	if themes, _, err := s.List(ctx); err != nil || len(themes) != 0 {
+		t.Errorf("themes after delete = %+v, %v", themes, err)
+	}
+	var assets int64
+	database.DB.Model(&models.ThemeAsset{}).Count(&assets)
+	if assets != 0 {
+		t.Errorf("%d assets left after delete", assets)
+	}
+}
@@ -0,0 +1,504 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of topic with a centroid
+const (
+	TopicCategory = "category"
+	TopicTag      = "tag"
+)
+
+// minTagArticles is the fewest articles a tag needs to be a topic. A tag
+// of one article says nothing its article does not.
+const minTagArticles = 2
+
+var (
+	ErrTopicNotFound = errors.New("topic has no centroid in this language")
+	ErrInvalidTopic  = errors.New("invalid topic")
+)
+
+// TopicSimilarity is a topic close to another one
+type TopicSimilarity struct {
+	Kind         string  `json:"kind"`
+	Key          string  `json:"key"`
+	Name         string  `json:"name"`
+	ArticleCount int     `json:"article_count"`
+	Similarity   float64 `json:"similarity"`
+}
+
+// TopicRebuildResult sums up a rebuild of the centroids
+type TopicRebuildResult struct {
+	Categories int `json:"categories"`
+	Tags       int `json:"tags"`
+	// Articles is the number of published articles with an embedding
+	Articles int `json:"articles"`
+}
+
+// TopicService keeps the centroids of categories and tags, the mean of the
+// embeddings of their articles, for "similar topics" and "most
+// representative articles" on topic pages. Centroids are precomputed by a
+// job, so the pages need no call to an AI provider.
+type TopicService struct {
+	db  func() *gorm.DB
+	now func() time.Time
+}
+
+// NewTopicService creates a topic service
+func NewTopicService() *TopicService {
+	return &TopicService{
+		db:  func() *gorm.DB { return database.DB },
+		now: time.Now,
+	}
+}
+
+// topicArticle is a published article with what puts it in topics
+type topicArticle struct {
+	ID          uint
+	SiteID      uint
+	CategoryID  uint
+	SEOKeywords string
+}
+
+// topicVector is a stored article embedding
+type topicVector struct {
+	articleID uint
+	provider  string
+	model     string
+	vector    []float64
+}
+
+// Rebuild recomputes the centroids of every site from the stored article
+// embeddings and replaces the old ones
+func (s *TopicService) Rebuild(ctx context.Context) (*TopicRebuildResult, error) {
+	var articles []topicArticle
+	if err := s.db().WithContext(ctx).Model(&models.Article{}).Select("id", "site_id", "category_id", "seo_keywords").
+		Where("created_at <= ?", s.now()).Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	var categories []models.Category
+	if err := s.db().WithContext(ctx).Select("id", "site_id", "parent_id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	parents := make(map[uint]uint, len(categories))
+	for _, category := range categories {
+		if category.ParentID != nil {
+			parents[category.ID] = *category.ParentID
+		}
+	}
+
+	vectors, err := s.loadVectors(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TopicRebuildResult{}
+	bySite := make(map[uint][]topicArticle)
+	for _, article := range articles {
+		bySite[article.SiteID] = append(bySite[article.SiteID], article)
+	}
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(bySite))
+	for siteID, siteArticles := range bySite {
+		centroids, embedded := buildCentroids(siteID, siteArticles, parents, vectors, s.now())
+		result.Articles += embedded
+		for _, centroid := range centroids {
+			if centroid.Kind == TopicCategory {
+				result.Categories++
+			} else {
+				result.Tags++
+			}
+		}
+		err := s.db().WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("site_id = ?", siteID).Delete(&models.TopicCentroid{}).Error; err != nil {
+				return err
+			}
+			if len(centroids) == 0 {
+				return nil
+			}
+			return tx.CreateInBatches(centroids, 100).Error
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to save the topics of site %d: %w", siteID, err)
+		}
+		progress.Advance(1)
+	}
+	// Sites without published articles keep no topics
+	sites := make([]uint, 0, len(bySite))
+	for siteID := range bySite {
+		sites = append(sites, siteID)
+	}
+	stale := s.db().WithContext(ctx)
+	if len(sites) > 0 {
+		stale = stale.Where("site_id NOT IN ?", sites)
+	} else {
+		stale = stale.Where("1 = 1")
+	}
+	return result, stale.Delete(&models.TopicCentroid{}).Error
+}
+
+// loadVectors returns the combined embeddings of articles by language
+func (s *TopicService) loadVectors(ctx context.Context) (map[string][]topicVector, error) {
+	var embeddings []models.ArticleEmbedding
+	if err := s.db().WithContext(ctx).Select("article_id", "language", "provider", "model", "embedding").
+		Where("content_type = ?", "combined").Find(&embeddings).Error; err != nil {
+		return nil, err
+	}
+	vectors := make(map[string][]topicVector)
+	for _, embedding := range embeddings {
+		var vector []float64
+		if err := json.Unmarshal([]byte(embedding.Embedding), &vector); err != nil || len(vector) == 0 {
+			continue
+		}
+		vectors[embedding.Language] = append(vectors[embedding.Language], topicVector{
+			articleID: embedding.ArticleID,
+			provider:  embedding.Provider,
+			model:     embedding.Model,
+			vector:    vector,
+		})
+	}
+	return vectors, nil
+}
+
+// buildCentroids computes the centroids of one site's topics in every
+// language its articles have embeddings in. It also returns how many of
+// the articles have an embedding.
+func buildCentroids(siteID uint, articles []topicArticle, parents map[uint]uint, vectors map[string][]topicVector, now time.Time) ([]models.TopicCentroid, int) {
+	// The topics of each article: its category, the categories above it
+	// and its tags
+	topics := make(map[uint][]topicRef, len(articles))
+	tagArticles := make(map[string]int)
+	for _, article := range articles {
+		var refs []topicRef
+		seen := map[uint]bool{}
+		for id := article.CategoryID; id != 0 && !seen[id]; id = parents[id] {
+			seen[id] = true
+			refs = append(refs, topicRef{TopicCategory, strconv.FormatUint(uint64(id), 10)})
+		}
+		for _, tag := range ArticleTags(article.SEOKeywords) {
+			refs = append(refs, topicRef{TopicTag, tag})
+			tagArticles[tag]++
+		}
+		topics[article.ID] = refs
+	}
+
+	var centroids []models.TopicCentroid
+	embedded := make(map[uint]bool)
+	languages := make([]string, 0, len(vectors))
+	for language := range vectors {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	for _, language := range languages {
+		// Vectors of different models cannot be compared, so only those of
+		// the model most of the site's articles use count
+		counts := make(map[[2]string]int)
+		for _, v := range vectors[language] {
+			if _, ok := topics[v.articleID]; ok {
+				counts[[2]string{v.provider, v.model}]++
+			}
+		}
+		var model [2]string
+		for candidate, count := range counts {
+			if count > counts[model] || count == counts[model] && (candidate[0]+candidate[1]) < (model[0]+model[1]) {
+				model = candidate
+			}
+		}
+		if counts[model] == 0 {
+			continue
+		}
+
+		sums := make(map[topicRef][]float64)
+		members := make(map[topicRef]int)
+		var order []topicRef
+		for _, v := range vectors[language] {
+			refs, ok := topics[v.articleID]
+			if !ok || v.provider != model[0] || v.model != model[1] {
+				continue
+			}
+			embedded[v.articleID] = true
+			unit := normalizeVector(v.vector)
+			for _, ref := range refs {
+				if ref.kind == TopicTag && tagArticles[ref.key] < minTagArticles {
+					continue
+				}
+				sum, ok := sums[ref]
+				if !ok {
+					sum = make([]float64, len(unit))
+					order = append(order, ref)
+				}
+				if len(sum) != len(unit) {
+					continue
+				}
+				for i, x := range unit {
+					sum[i] += x
+				}
+				sums[ref] = sum
+				members[ref]++
+			}
+		}
+		for _, ref := range order {
+			centroid := normalizeVector(sums[ref])
+			data, _ := json.Marshal(centroid)
+			centroids = append(centroids, models.TopicCentroid{
+				SiteID:       siteID,
+				Kind:         ref.kind,
+				Key:          ref.key,
+				Language:     language,
+				Provider:     model[0],
+				Model:        model[1],
+				Embedding:    string(data),
+				Dimensions:   len(centroid),
+				ArticleCount: members[ref],
+				UpdatedAt:    now,
+			})
+		}
+	}
+	return centroids, len(embedded)
+}
+
+// topicRef names a topic
+type topicRef struct {
+	kind string
+	key  string
+}
+
+// ArticleTags returns the tags of an article, its comma-separated SEO
+// keywords, lowercased and without repeats
+func ArticleTags(keywords string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.FieldsFunc(keywords, func(r rune) bool { return r == ',' || r == '，' || r == '、' }) {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || len(tag) > 191 || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// Similar returns the topics of either kind whose centroids are closest to
+// that of the topic, most similar first
+func (s *TopicService) Similar(ctx context.Context, kind, key, language string, limit int) ([]TopicSimilarity, error) {
+	source, vector, err := s.centroid(ctx, kind, key, language)
+	if err != nil {
+		return nil, err
+	}
+	var others []models.TopicCentroid
+	if err := s.db().WithContext(ctx).
+		Where("language = ? AND provider = ? AND model = ? AND id <> ?", language, source.Provider, source.Model, source.ID).
+		Find(&others).Error; err != nil {
+		return nil, err
+	}
+	similar := make([]TopicSimilarity, 0, len(others))
+	for _, other := range others {
+		var otherVector []float64
+		if err := json.Unmarshal([]byte(other.Embedding), &otherVector); err != nil {
+			continue
+		}
+		similar = append(similar, TopicSimilarity{
+			Kind:         other.Kind,
+			Key:          other.Key,
+			Name:         other.Key,
+			ArticleCount: other.ArticleCount,
+			Similarity:   roundTo(cosineSimilarity(vector, otherVector), 4),
+		})
+	}
+	sort.SliceStable(similar, func(i, j int) bool { return similar[i].Similarity > similar[j].Similarity })
+	if limit > 0 && len(similar) > limit {
+		similar = similar[:limit]
+	}
+	if err := s.nameCategories(ctx, similar, language); err != nil {
+		return nil, err
+	}
+	return similar, nil
+}
+
+// Representative returns the published articles of the topic closest to
+// its centroid, most representative first
+func (s *TopicService) Representative(ctx context.Context, kind, key, language string, limit int) ([]models.EmbeddingSearchResult, error) {
+	source, vector, err := s.centroid(ctx, kind, key, language)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db().WithContext(ctx).Preload("Category").Preload("Translations", "language = ?", language).
+		Where("created_at <= ?", s.now())
+	if kind == TopicCategory {
+		id, _ := strconv.ParseUint(key, 10, 32)
+		ids, err := GetGlobalCategoryService().Descendants(ctx, uint(id))
+		if err != nil {
+			if errors.Is(err, ErrCategoryNotFound) {
+				return nil, ErrTopicNotFound
+			}
+			return nil, err
+		}
+		query = query.Where("category_id IN ?", ids)
+	} else {
+		// LIKE narrows the articles down; ArticleTags decides
+		key = source.Key
+		query = query.Where(database.Like("LOWER(seo_keywords) LIKE ?"), "%"+key+"%")
+	}
+	var articles []models.Article
+	if err := query.Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Article, len(articles))
+	ids := make([]uint, 0, len(articles))
+	for i := range articles {
+		if kind == TopicTag && !containsString(ArticleTags(articles[i].SEOKeywords), key) {
+			continue
+		}
+		byID[articles[i].ID] = &articles[i]
+		ids = append(ids, articles[i].ID)
+	}
+	if len(ids) == 0 {
+		return []models.EmbeddingSearchResult{}, nil
+	}
+
+	var embeddings []models.ArticleEmbedding
+	if err := s.db().WithContext(ctx).Select("article_id", "embedding").
+		Where("article_id IN ? AND language = ? AND content_type = ? AND provider = ? AND model = ?",
+			ids, language, "combined", source.Provider, source.Model).
+		Find(&embeddings).Error; err != nil {
+		return nil, err
+	}
+	results := make([]models.EmbeddingSearchResult, 0, len(embeddings))
+	for _, embedding := range embeddings {
+		var articleVector []float64
+		if err := json.Unmarshal([]byte(embedding.Embedding), &articleVector); err != nil {
+			continue
+		}
+		article := byID[embedding.ArticleID]
+		ApplyTranslation(article, language)
+		ApplyCategoryTranslation(&article.Category, language)
+		results = append(results, models.EmbeddingSearchResult{
+			ArticleID:    article.ID,
+			Title:        article.Title,
+			Summary:      article.Summary,
+			CategoryID:   article.CategoryID,
+			CategoryName: article.Category.Name,
+			Language:     language,
+			Similarity:   roundTo(cosineSimilarity(vector, articleVector), 4),
+			ViewCount:    article.ViewCount,
+			CreatedAt:    article.CreatedAt,
+		})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Similarity > results[j].Similarity })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// centroid returns the stored centroid of a topic and its vector
+func (s *TopicService) centroid(ctx context.Context, kind, key, language string) (*models.TopicCentroid, []float64, error) {
+	switch kind {
+	case TopicCategory:
+		if id, err := strconv.ParseUint(key, 10, 32); err != nil || id == 0 {
+			return nil, nil, fmt.Errorf("%w: category must be an ID", ErrInvalidTopic)
+		}
+	case TopicTag:
+		key = strings.ToLower(strings.Join(strings.Fields(key), " "))
+	default:
+		return nil, nil, fmt.Errorf("%w: kind must be %s or %s", ErrInvalidTopic, TopicCategory, TopicTag)
+	}
+	var centroid models.TopicCentroid
+	err := s.db().WithContext(ctx).Where("kind = ? AND topic_key = ? AND language = ?", kind, key, language).First(&centroid).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrTopicNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var vector []float64
+	if err := json.Unmarshal([]byte(centroid.Embedding), &vector); err != nil {
+		return nil, nil, fmt.Errorf("corrupt centroid of %s %q: %w", kind, key, err)
+	}
+	return &centroid, vector, nil
+}
+
+// nameCategories replaces the IDs of category topics with their names in
+// the language
+func (s *TopicService) nameCategories(ctx context.Context, topics []TopicSimilarity, language string) error {
+	var ids []uint
+	for _, topic := range topics {
+		if topic.Kind == TopicCategory {
+			id, _ := strconv.ParseUint(topic.Key, 10, 32)
+			ids = append(ids, uint(id))
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var categories []models.Category
+	if err := s.db().WithContext(ctx).Preload("Translations").Where("id IN ?", ids).Find(&categories).Error; err != nil {
+		return err
+	}
+	names := make(map[string]string, len(categories))
+	for i := range categories {
+		ApplyCategoryTranslation(&categories[i], language)
+		names[strconv.FormatUint(uint64(categories[i].ID), 10)] = categories[i].Name
+	}
+	for i := range topics {
+		if name, ok := names[topics[i].Key]; ok && topics[i].Kind == TopicCategory {
+			topics[i].Name = name
+		}
+	}
+	return nil
+}
+
+// normalizeVector returns the vector scaled to unit length
+func normalizeVector(vector []float64) []float64 {
+	var norm float64
+	for _, x := range vector {
+		norm += x * x
+	}
+	unit := make([]float64, len(vector))
+	if norm == 0 {
+		return unit
+	}
+	norm = math.Sqrt(norm)
+	for i, x := range vector {
+		unit[i] = x / norm
+	}
+	return unit
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	globalTopicService     *TopicService
+	globalTopicServiceOnce sync.Once
+)
+
+// GetGlobalTopicService returns the global topic service
+func GetGlobalTopicService() *TopicService {
+	globalTopicServiceOnce.Do(func() {
+		globalTopicService = NewTopicService()
+	})
+	return globalTopicService
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTopicCentroids(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+
+	backend := models.Category{Name: "Backend", DefaultLang: "en"}
+	database.DB.Create(&backend)
+	databases := models.Category{Name: "Databases", DefaultLang: "en", ParentID: &backend.ID}
+	database.DB.Create(&databases)
+	design := models.Category{Name: "Design", DefaultLang: "en"}
+	database.DB.Create(&design)
+	database.DB.Create(&models.CategoryTranslation{CategoryID: databases.ID, Language: "zh", Name: "数据库"})
+
+	articles := []struct {
+		article models.Article
+		vector  []float64
+	}{
+		{models.Article{Title: "Indexes", CategoryID: databases.ID, SEOKeywords: "SQL, performance"}, []float64{1, 0, 0}},
+		{models.Article{Title: "Query plans", CategoryID: databases.ID, SEOKeywords: "sql"}, []float64{0.8, 0.2, 0}},
+		{models.Article{Title: "HTTP servers", CategoryID: backend.ID, SEOKeywords: "performance"}, []float64{0.5, 0.5, 0}},
+		{models.Article{Title: "Colors", CategoryID: design.ID, SEOKeywords: "palette"}, []float64{0, 0, 1}},
+		{models.Article{Title: "Scheduled", CategoryID: design.ID, CreatedAt: time.Now().Add(time.Hour)}, []float64{0, 1, 0}},
+	}
+	for i := range articles {
+		articles[i].article.DefaultLang = "en"
+		if err := database.DB.Create(&articles[i].article).Error; err != nil {
+			t.Fatal(err)
+		}
+		encoded, _ := json.Marshal(articles[i].vector)
+		for _, language := range []string{"en", "zh"} {
+			database.DB.Create(&models.ArticleEmbedding{
+				ArticleID: articles[i].article.ID, Language: language, ContentType: "combined",
+				Provider: "openai", Model: "small", Embedding: string(encoded), Dimensions: 3,
+			})
+		}
+	}
+	// An embedding of another model is left out rather than mixed in
+	database.DB.Create(&models.ArticleEmbedding{
+		ArticleID: articles[3].article.ID, Language: "en", ContentType: "combined",
+		Provider: "gemini", Model: "other", Embedding: "[0,1,0,0]", Dimensions: 4,
+	})
+	// Centroids of a category that no longer has articles go
+	database.DB.Create(&models.TopicCentroid{SiteID: 1, Kind: TopicCategory, Key: "999", Language: "en", Embedding: "[1,0,0]"})
+
+	s := NewTopicService()
+	result, err := s.Rebuild(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Per language: three categories, and sql and performance but not the
+	// palette tag of one article
+	if result.Categories != 6 || result.Tags != 4 || result.Articles != 4 {
+		t.Errorf("Rebuild() = %+v", result)
+	}
+	var stale int64
+	database.DB.Model(&models.TopicCentroid{}).Where("topic_key = ?", "999").Count(&stale)
+	if stale != 0 {
+		t.Error("the centroid of a category without articles was kept")
+	}
+
+	var parent models.TopicCentroid
+	database.DB.Where("kind = ? AND topic_key = ? AND language = ?", TopicCategory, strconv.Itoa(int(backend.ID)), "en").First(&parent)
+	if parent.ArticleCount != 3 || parent.Provider != "openai" {
+		t.Errorf("parent category centroid = %+v, want its subcategory's articles included", parent)
+	}
+
+	databasesKey := strconv.Itoa(int(databases.ID))
+	similar, err := s.Similar(ctx, TopicCategory, databasesKey, "zh", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(similar) != 4 || similar[0].Kind != TopicTag || similar[0].Key != "sql" || similar[0].Similarity < 0.999 {
+		t.Fatalf("Similar() = %+v", similar)
+	}
+	if last := similar[len(similar)-1]; last.Name != "Design" || last.Similarity != 0 {
+		t.Errorf("least similar = %+v", last)
+	}
+
+	representative, err := s.Representative(ctx, TopicTag, " Performance ", "en", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(representative) != 2 || representative[0].Similarity != representative[1].Similarity {
+		t.Errorf("Representative(performance) = %+v", representative)
+	}
+	representative, err = s.Representative(ctx, TopicCategory, strconv.Itoa(int(backend.ID)), "en", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(representative) != 1 || representative[0].Title != "Query plans" {
+		t.Errorf("Representative(backend) = %+v", representative)
+	}
+
+	if _, err := s.Similar(ctx, TopicTag, "palette", "en", 10); !errors.Is(err, ErrTopicNotFound) {
+		t.Errorf("tag of one article: %v", err)
+	}
+	if _, err := s.Similar(ctx, "author", "1", "en", 10); !errors.Is(err, ErrInvalidTopic) {
+		t.Errorf("unknown kind: %v", err)
+	}
+	if _, err := s.Similar(ctx, TopicCategory, "databases", "en", 10); !errors.Is(err, ErrInvalidTopic) {
+		t.Errorf("category by name: %v", err)
+	}
+}
+
+func TestArticleTags(t *testing.T) {
+	got := ArticleTags(" Go,  go , Web  Servers，数据库、, ")
+	want := []string{"go", "web servers", "数据库"}
+	if len(got) != len(want) {
+		t.Fatalf("ArticleTags() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ArticleTags()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
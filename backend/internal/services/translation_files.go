@@ -0,0 +1,614 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Translation file formats for translators working outside the admin:
+// XLIFF 1.2 and gettext PO. Each translatable string is a unit identified
+// as kind/id/field, such as article/12/title or settings/1/site_title.
+const (
+	TranslationFormatXLIFF = "xliff"
+	TranslationFormatPO    = "po"
+)
+
+// States of an exported unit, as XLIFF names them. In PO files units to
+// review are marked fuzzy and new ones have an empty msgstr.
+const (
+	TranslationUnitNew         = "new"
+	TranslationUnitTranslated  = "translated"
+	TranslationUnitNeedsReview = "needs-review-translation"
+)
+
+// ErrInvalidTranslationFile is returned for a file that is not an XLIFF or
+// PO file of translations into one language
+var ErrInvalidTranslationFile = errors.New("invalid translation file")
+
+// TranslationUnit is one translatable string: its source text and the
+// translation, if any. Note names what the string belongs to.
+type TranslationUnit struct {
+	ID             string
+	SourceLanguage string
+	Source         string
+	Target         string
+	State          string
+	Note           string
+}
+
+// TranslationImportSkip is a unit of an imported file that was not applied
+type TranslationImportSkip struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// TranslationImportResult counts what an import of a translation file
+// changed. Units left new or to review in the file are not imported and
+// counted as unreviewed.
+type TranslationImportResult struct {
+	Language   string                  `json:"language"`
+	Imported   int                     `json:"imported"`
+	Unchanged  int                     `json:"unchanged"`
+	Unreviewed int                     `json:"unreviewed"`
+	Created    int                     `json:"created"`
+	Updated    int                     `json:"updated"`
+	Skipped    []TranslationImportSkip `json:"skipped"`
+}
+
+// translationField is a translatable field of an article, category or the
+// site settings and its translation
+type translationField struct {
+	name   string
+	source string
+	target *string
+	// changed reports whether the field changed since the translation was
+	// made, for articles that know their sections
+	changed bool
+}
+
+// TranslationUnits lists the strings of the site to translate into
+// language: the title, summary and content of each article, the name and
+// description of each category and the site title and subtitle, each with
+// its current translation. Content already written in language is left
+// out, and with missing only strings without an up to date, reviewed
+// translation are listed. db must be scoped to the site.
+func TranslationUnits(db *gorm.DB, language string, missing bool) ([]TranslationUnit, error) {
+	var settings models.SiteSettings
+	if err := db.Preload("Translations").Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	defaultLanguage := settings.DefaultLanguage
+	if defaultLanguage == "" {
+		defaultLanguage = "zh"
+	}
+	var articles []models.Article
+	if err := db.Preload("Translations").Order("id").Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	var categories []models.Category
+	if err := db.Preload("Translations").Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	units := []TranslationUnit{}
+	add := func(kind string, id uint, title, sourceLanguage string, exists, outdated, machine bool, fields []translationField) {
+		if sourceLanguage == "" {
+			sourceLanguage = defaultLanguage
+		}
+		if sourceLanguage == language {
+			return
+		}
+		for _, field := range fields {
+			if strings.TrimSpace(field.source) == "" {
+				continue
+			}
+			unit := TranslationUnit{
+				ID:             fmt.Sprintf("%s/%d/%s", kind, id, field.name),
+				SourceLanguage: sourceLanguage,
+				Source:         field.source,
+				State:          TranslationUnitNew,
+				Note:           fmt.Sprintf("%s %q", kind, title),
+			}
+			if exists && *field.target != "" {
+				unit.Target = *field.target
+				unit.State = TranslationUnitTranslated
+				if machine || (outdated && field.changed) {
+					unit.State = TranslationUnitNeedsReview
+				}
+			}
+			if !missing || unit.State != TranslationUnitTranslated {
+				units = append(units, unit)
+			}
+		}
+	}
+
+	for i := range articles {
+		article := &articles[i]
+		var translation models.ArticleTranslation
+		exists := false
+		for _, t := range article.Translations {
+			if t.Language == language {
+				translation, exists = t, true
+			}
+		}
+		source := article.Source()
+		previous, sectioned := translation.TranslatedSource()
+		outdated := exists && translationStale(&translation, article.SourceHash())
+		fields := []translationField{
+			{"title", article.Title, &translation.Title, !sectioned || previous.Title != source.Title},
+			{"summary", article.Summary, &translation.Summary, !sectioned || previous.Summary != source.Summary},
+			{"content", article.Content, &translation.Content, !sectioned || strings.Join(previous.Content, " ") != strings.Join(source.Content, " ")},
+		}
+		add(models.TranslationArticle, article.ID, article.Title, article.DefaultLang, exists, outdated, translation.MachineTranslated, fields)
+	}
+	for i := range categories {
+		category := &categories[i]
+		var translation models.CategoryTranslation
+		exists := false
+		for _, t := range category.Translations {
+			if t.Language == language {
+				translation, exists = t, true
+			}
+		}
+		outdated := exists && translation.SourceHash != "" && translation.SourceHash != category.SourceHash()
+		fields := []translationField{
+			{"name", category.Name, &translation.Name, true},
+			{"description", category.Description, &translation.Description, true},
+		}
+		add(models.TranslationCategory, category.ID, category.Name, category.DefaultLang, exists, outdated, translation.MachineTranslated, fields)
+	}
+	if settings.ID != 0 {
+		var translation models.SiteSettingsTranslation
+		exists := false
+		for _, t := range settings.Translations {
+			if t.Language == language {
+				translation, exists = t, true
+			}
+		}
+		outdated := exists && translation.SourceHash != "" && translation.SourceHash != settings.SourceHash()
+		fields := []translationField{
+			{"site_title", settings.SiteTitle, &translation.SiteTitle, true},
+			{"site_subtitle", settings.SiteSubtitle, &translation.SiteSubtitle, true},
+		}
+		add(models.TranslationSettings, settings.ID, settings.SiteTitle, defaultLanguage, exists, outdated, translation.MachineTranslated, fields)
+	}
+	return units, nil
+}
+
+// ImportTranslationUnits writes the translated units of a file into
+// language, creating translations that do not exist yet. A unit is only
+// applied when its source is still the current text, so a file made before
+// the content changed cannot overwrite a newer translation with an older
+// one. Imported translations count as reviewed. db must be scoped to the
+// site.
+func ImportTranslationUnits(db *gorm.DB, language string, units []TranslationUnit) (*TranslationImportResult, error) {
+	result := &TranslationImportResult{Language: language, Skipped: []TranslationImportSkip{}}
+	skip := func(id, reason string) {
+		result.Skipped = append(result.Skipped, TranslationImportSkip{ID: id, Reason: reason})
+	}
+
+	// Units are applied item by item, in the order the file lists them
+	type itemUnit struct {
+		TranslationUnit
+		field string
+	}
+	type item struct {
+		kind  string
+		id    uint
+		units []itemUnit
+	}
+	var items []*item
+	byKey := map[string]*item{}
+	for _, unit := range units {
+		if strings.TrimSpace(unit.Target) == "" || unit.State == TranslationUnitNew || strings.HasPrefix(unit.State, "needs-") {
+			result.Unreviewed++
+			continue
+		}
+		kind, rest, _ := strings.Cut(unit.ID, "/")
+		idText, field, _ := strings.Cut(rest, "/")
+		id, err := strconv.ParseUint(idText, 10, 32)
+		if err != nil || field == "" {
+			skip(unit.ID, "unknown string")
+			continue
+		}
+		key := kind + "/" + idText
+		if byKey[key] == nil {
+			byKey[key] = &item{kind: kind, id: uint(id)}
+			items = append(items, byKey[key])
+		}
+		byKey[key].units = append(byKey[key].units, itemUnit{unit, field})
+	}
+
+	var settings models.SiteSettings
+	if err := db.Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	defaultLanguage := settings.DefaultLanguage
+	if defaultLanguage == "" {
+		defaultLanguage = "zh"
+	}
+
+	topics := map[string]bool{}
+	for _, it := range items {
+		var fields []translationField
+		var sourceLanguage string
+		var save func() error
+		var found, exists bool
+
+		switch it.kind {
+		case models.TranslationArticle:
+			var article models.Article
+			if err := db.Limit(1).Find(&article, it.id).Error; err != nil {
+				return nil, err
+			}
+			var translation models.ArticleTranslation
+			if err := db.Where("article_id = ? AND language = ?", it.id, language).Limit(1).Find(&translation).Error; err != nil {
+				return nil, err
+			}
+			found, exists, sourceLanguage = article.ID != 0, translation.ID != 0, article.DefaultLang
+			fields = []translationField{
+				{name: "title", source: article.Title, target: &translation.Title},
+				{name: "summary", source: article.Summary, target: &translation.Summary},
+				{name: "content", source: article.Content, target: &translation.Content},
+			}
+			save = func() error {
+				if security.ShouldSanitize(true) {
+					translation.Content = security.GetGlobalHTMLPolicy().SanitizeContent(translation.Content, article.ContentType)
+				}
+				translation.ArticleID, translation.Language, translation.MachineTranslated = article.ID, language, false
+				translation.StampSource(&article)
+				topics[cache.TopicArticles] = true
+				return db.Save(&translation).Error
+			}
+		case models.TranslationCategory:
+			var category models.Category
+			if err := db.Limit(1).Find(&category, it.id).Error; err != nil {
+				return nil, err
+			}
+			var translation models.CategoryTranslation
+			if err := db.Where("category_id = ? AND language = ?", it.id, language).Limit(1).Find(&translation).Error; err != nil {
+				return nil, err
+			}
+			found, exists, sourceLanguage = category.ID != 0, translation.ID != 0, category.DefaultLang
+			fields = []translationField{
+				{name: "name", source: category.Name, target: &translation.Name},
+				{name: "description", source: category.Description, target: &translation.Description},
+			}
+			save = func() error {
+				translation.CategoryID, translation.Language, translation.MachineTranslated = category.ID, language, false
+				translation.SourceHash = category.SourceHash()
+				topics[cache.TopicCategories] = true
+				return db.Save(&translation).Error
+			}
+		case models.TranslationSettings:
+			var translation models.SiteSettingsTranslation
+			if err := db.Where("settings_id = ? AND language = ?", it.id, language).Limit(1).Find(&translation).Error; err != nil {
+				return nil, err
+			}
+			found, exists, sourceLanguage = settings.ID != 0 && settings.ID == it.id, translation.ID != 0, defaultLanguage
+			fields = []translationField{
+				{name: "site_title", source: settings.SiteTitle, target: &translation.SiteTitle},
+				{name: "site_subtitle", source: settings.SiteSubtitle, target: &translation.SiteSubtitle},
+			}
+			save = func() error {
+				translation.SettingsID, translation.Language, translation.MachineTranslated = settings.ID, language, false
+				translation.SourceHash = settings.SourceHash()
+				topics[cache.TopicSettings] = true
+				return db.Save(&translation).Error
+			}
+		}
+		if !found {
+			for _, unit := range it.units {
+				skip(unit.ID, "unknown string")
+			}
+			continue
+		}
+		if sourceLanguage == "" {
+			sourceLanguage = defaultLanguage
+		}
+
+		changed := false
+		for _, unit := range it.units {
+			var target *translationField
+			for i := range fields {
+				if fields[i].name == unit.field {
+					target = &fields[i]
+				}
+			}
+			switch {
+			case target == nil:
+				skip(unit.ID, "unknown string")
+			case sourceLanguage == language:
+				skip(unit.ID, "written in this language")
+			case normalizeUnitText(unit.Source) != normalizeUnitText(target.source):
+				skip(unit.ID, "source changed since the file was exported")
+			case *target.target == unit.Target:
+				result.Unchanged++
+			default:
+				*target.target = unit.Target
+				result.Imported++
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := save(); err != nil {
+			return nil, err
+		}
+		if exists {
+			result.Updated++
+		} else {
+			result.Created++
+		}
+	}
+
+	for topic := range topics {
+		cache.Publish(topic)
+	}
+	return result, nil
+}
+
+// normalizeUnitText evens out the line endings and surrounding space that
+// translation tools tend to change
+func normalizeUnitText(text string) string {
+	return strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+}
+
+// XLIFF 1.2 documents, with one file per source language
+type xliffDocument struct {
+	XMLName xml.Name    `xml:"xliff"`
+	Xmlns   string      `xml:"xmlns,attr,omitempty"`
+	Version string      `xml:"version,attr"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	Original       string      `xml:"original,attr"`
+	SourceLanguage string      `xml:"source-language,attr"`
+	TargetLanguage string      `xml:"target-language,attr"`
+	Datatype       string      `xml:"datatype,attr"`
+	Units          []xliffUnit `xml:"body>trans-unit"`
+}
+
+type xliffUnit struct {
+	ID     string       `xml:"id,attr"`
+	Space  string       `xml:"http://www.w3.org/XML/1998/namespace space,attr,omitempty"`
+	Source string       `xml:"source"`
+	Target *xliffTarget `xml:"target"`
+	Note   string       `xml:"note,omitempty"`
+}
+
+type xliffTarget struct {
+	State string `xml:"state,attr,omitempty"`
+	Text  string `xml:",chardata"`
+}
+
+// WriteTranslationFile writes units for translation into language in
+// format
+func WriteTranslationFile(w io.Writer, format, language string, units []TranslationUnit) error {
+	switch format {
+	case TranslationFormatXLIFF:
+		return writeXLIFF(w, language, units)
+	case TranslationFormatPO:
+		return writePO(w, language, units)
+	}
+	return fmt.Errorf("%w: unknown format %q", ErrInvalidTranslationFile, format)
+}
+
+func writeXLIFF(w io.Writer, language string, units []TranslationUnit) error {
+	document := xliffDocument{Xmlns: "urn:oasis:names:tc:xliff:document:1.2", Version: "1.2"}
+	files := map[string]int{}
+	for _, unit := range units {
+		index, ok := files[unit.SourceLanguage]
+		if !ok {
+			index = len(document.Files)
+			files[unit.SourceLanguage] = index
+			document.Files = append(document.Files, xliffFile{
+				Original: "kuno", SourceLanguage: unit.SourceLanguage, TargetLanguage: language, Datatype: "plaintext",
+			})
+		}
+		xu := xliffUnit{ID: unit.ID, Space: "preserve", Source: unit.Source, Note: unit.Note}
+		if unit.Target != "" {
+			xu.Target = &xliffTarget{State: unit.State, Text: unit.Target}
+		}
+		document.Files[index].Units = append(document.Files[index].Units, xu)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func writePO(w io.Writer, language string, units []TranslationUnit) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "msgid \"\"\nmsgstr \"\"\n\"Content-Type: text/plain; charset=UTF-8\\n\"\n\"Language: %s\\n\"\n\"X-Generator: Kuno\\n\"\n", poEscape(language))
+	for _, unit := range units {
+		fmt.Fprintf(out, "\n#. %s\n#. source language: %s\n", strings.ReplaceAll(unit.Note, "\n", " "), unit.SourceLanguage)
+		if unit.State == TranslationUnitNeedsReview {
+			out.WriteString("#, fuzzy\n")
+		}
+		writePOString(out, "msgctxt", unit.ID)
+		writePOString(out, "msgid", unit.Source)
+		writePOString(out, "msgstr", unit.Target)
+	}
+	return out.Flush()
+}
+
+// writePOString writes a PO keyword and its string, one line per line of
+// the text
+func writePOString(out *bufio.Writer, keyword, text string) {
+	if !strings.Contains(text, "\n") {
+		fmt.Fprintf(out, "%s \"%s\"\n", keyword, poEscape(text))
+		return
+	}
+	fmt.Fprintf(out, "%s \"\"\n", keyword)
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line != "" {
+			fmt.Fprintf(out, "\"%s\"\n", poEscape(line))
+		}
+	}
+}
+
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+func poEscape(text string) string {
+	return poEscaper.Replace(text)
+}
+
+// ParseTranslationFile reads the units of an XLIFF or PO file and the
+// language they are translated into. format may be empty to tell the two
+// apart by their content.
+func ParseTranslationFile(data []byte, format string) (string, []TranslationUnit, error) {
+	if format == "" {
+		format = TranslationFormatPO
+		if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("<")) {
+			format = TranslationFormatXLIFF
+		}
+	}
+	switch format {
+	case TranslationFormatXLIFF:
+		return parseXLIFF(data)
+	case TranslationFormatPO:
+		return parsePO(data)
+	}
+	return "", nil, fmt.Errorf("%w: unknown format %q", ErrInvalidTranslationFile, format)
+}
+
+func parseXLIFF(data []byte) (string, []TranslationUnit, error) {
+	var document xliffDocument
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidTranslationFile, err)
+	}
+	if document.Version != "" && !strings.HasPrefix(document.Version, "1.") {
+		return "", nil, fmt.Errorf("%w: only XLIFF 1.x is supported", ErrInvalidTranslationFile)
+	}
+	language := ""
+	units := []TranslationUnit{}
+	for _, file := range document.Files {
+		if language != "" && file.TargetLanguage != language {
+			return "", nil, fmt.Errorf("%w: files translate into more than one language", ErrInvalidTranslationFile)
+		}
+		language = file.TargetLanguage
+		for _, xu := range file.Units {
+			unit := TranslationUnit{ID: xu.ID, SourceLanguage: file.SourceLanguage, Source: xu.Source, Note: xu.Note}
+			if xu.Target != nil {
+				unit.Target, unit.State = xu.Target.Text, xu.Target.State
+			}
+			units = append(units, unit)
+		}
+	}
+	return language, units, nil
+}
+
+func parsePO(data []byte) (string, []TranslationUnit, error) {
+	var language string
+	units := []TranslationUnit{}
+	var entry struct {
+		context, id, str string
+		fuzzy, seen      bool
+	}
+	var current *string
+	flush := func() {
+		switch {
+		case !entry.seen:
+		case entry.id == "" && entry.context == "":
+			// The header entry carries the language
+			for _, line := range strings.Split(entry.str, "\n") {
+				if value, ok := strings.CutPrefix(line, "Language:"); ok {
+					language = strings.TrimSpace(value)
+				}
+			}
+		default:
+			unit := TranslationUnit{ID: entry.context, Source: entry.id, Target: entry.str}
+			if entry.fuzzy {
+				unit.State = TranslationUnitNeedsReview
+			}
+			units = append(units, unit)
+		}
+		entry.context, entry.id, entry.str, entry.fuzzy, entry.seen = "", "", "", false, false
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		var keyword, rest string
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#"):
+			// A comment starts the next entry; obsolete entries (#~) are
+			// dropped with the other comments
+			if entry.seen {
+				flush()
+			}
+			if flags, ok := strings.CutPrefix(line, "#,"); ok && strings.Contains(flags, "fuzzy") {
+				entry.fuzzy = true
+			}
+			continue
+		case strings.HasPrefix(line, `"`):
+			if current == nil {
+				return "", nil, fmt.Errorf("%w: line %d: text outside an entry", ErrInvalidTranslationFile, number)
+			}
+			text, err := strconv.Unquote(line)
+			if err != nil {
+				return "", nil, fmt.Errorf("%w: line %d: %v", ErrInvalidTranslationFile, number, err)
+			}
+			*current += text
+			continue
+		default:
+			keyword, rest, _ = strings.Cut(line, " ")
+		}
+
+		text, err := strconv.Unquote(strings.TrimSpace(rest))
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: line %d: %v", ErrInvalidTranslationFile, number, err)
+		}
+		switch keyword {
+		case "msgctxt":
+			if entry.seen {
+				flush()
+			}
+			entry.context, current = text, &entry.context
+		case "msgid":
+			if entry.seen {
+				flush()
+			}
+			entry.id, current = text, &entry.id
+		case "msgstr", "msgstr[0]":
+			entry.str, current = text, &entry.str
+			entry.seen = true
+		case "msgid_plural":
+			current = new(string)
+		default:
+			if !strings.HasPrefix(keyword, "msgstr[") {
+				return "", nil, fmt.Errorf("%w: line %d: unknown keyword %q", ErrInvalidTranslationFile, number, keyword)
+			}
+			current = new(string)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidTranslationFile, err)
+	}
+	flush()
+	return language, units, nil
+}
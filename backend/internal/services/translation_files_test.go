@@ -0,0 +1,126 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTranslationFileRoundTrip(t *testing.T) {
+	newTestDB(t)
+	database.DB.Create(&models.SiteSettings{SiteID: models.DefaultSiteID, DefaultLanguage: "zh", SiteTitle: "我的博客", SiteSubtitle: "笔记"})
+	category := models.Category{Name: "技术", DefaultLang: "zh"}
+	database.DB.Create(&category)
+	article := models.Article{Title: "标题", Summary: "摘要", Content: "第一段\n\n\"第二段\"\t", DefaultLang: "zh", CategoryID: category.ID}
+	database.DB.Create(&article)
+	translation := models.ArticleTranslation{ArticleID: article.ID, Language: "en", Title: "Title", MachineTranslated: true}
+	translation.StampSource(&article)
+	database.DB.Create(&translation)
+	// Written in English, so not exported for English
+	database.DB.Create(&models.Article{Title: "Hello", Content: "World", DefaultLang: "en", CategoryID: category.ID})
+
+	units, err := TranslationUnits(database.DB, "en", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(units) != 6 {
+		t.Fatalf("units = %+v, want 6", units)
+	}
+	if units[0].Target != "Title" || units[0].State != TranslationUnitNeedsReview || units[1].State != TranslationUnitNew {
+		t.Errorf("article units = %+v, want the machine title to review and the summary new", units[:3])
+	}
+
+	for _, format := range []string{TranslationFormatXLIFF, TranslationFormatPO} {
+		t.Run(format, func(t *testing.T) {
+			var file bytes.Buffer
+			if err := WriteTranslationFile(&file, format, "en", units); err != nil {
+				t.Fatal(err)
+			}
+			language, parsed, err := ParseTranslationFile(file.Bytes(), "")
+			if err != nil {
+				t.Fatalf("parse: %v\n%s", err, file.String())
+			}
+			if language != "en" || len(parsed) != len(units) {
+				t.Fatalf("parsed %q with %d units, want en with %d", language, len(parsed), len(units))
+			}
+			for i := range units {
+				if parsed[i].ID != units[i].ID || parsed[i].Source != units[i].Source || parsed[i].Target != units[i].Target ||
+					(parsed[i].State == TranslationUnitNeedsReview) != (units[i].State == TranslationUnitNeedsReview) {
+					t.Errorf("unit %d = %+v, want %+v", i, parsed[i], units[i])
+				}
+			}
+		})
+	}
+
+	for i := range units {
+		units[i].Target, units[i].State = "EN "+units[i].Source, TranslationUnitTranslated
+	}
+	units[1].State = TranslationUnitNeedsReview
+	units[4].Source = "Old title"
+	result, err := ImportTranslationUnits(database.DB, "en", append(units, TranslationUnit{ID: "article/999/title", Target: "Lost"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Imported != 4 || result.Unreviewed != 1 || result.Created != 2 || result.Updated != 1 || len(result.Skipped) != 2 {
+		t.Errorf("result = %+v, want 4 imported into 2 new and 1 updated translation, 1 unreviewed and 2 skipped", result)
+	}
+
+	database.DB.First(&translation, translation.ID)
+	if translation.Title != "EN 标题" || translation.Summary != "" || !strings.Contains(translation.Content, "EN 第一段") ||
+		translation.MachineTranslated || translation.SourceHash != article.SourceHash() {
+		t.Errorf("article translation = %+v", translation)
+	}
+	var categoryTranslation models.CategoryTranslation
+	database.DB.Where("category_id = ? AND language = ?", category.ID, "en").First(&categoryTranslation)
+	if categoryTranslation.Name != "EN 技术" || categoryTranslation.SourceHash != category.SourceHash() {
+		t.Errorf("category translation = %+v", categoryTranslation)
+	}
+	var settingsTranslation models.SiteSettingsTranslation
+	database.DB.Where("language = ?", "en").First(&settingsTranslation)
+	if settingsTranslation.SiteTitle != "" || settingsTranslation.SiteSubtitle != "EN 笔记" {
+		t.Errorf("settings translation = %+v, want only the subtitle whose source is current", settingsTranslation)
+	}
+
+	if missing, _ := TranslationUnits(database.DB, "en", true); len(missing) != 2 {
+		t.Errorf("missing units = %+v, want the summary and site title", missing)
+	}
+}
+
+func TestParsePO(t *testing.T) {
+	po := `# translator comment
+msgid ""
+msgstr ""
+"Language: ja\n"
+
+#, fuzzy
+msgctxt "article/1/title"
+msgid "Title"
+msgstr "タイトル"
+
+msgctxt "article/1/content"
+msgid ""
+"line one\n"
+"line \"two\""
+msgstr "一行目\n二行目"
+
+#~ msgctxt "article/2/title"
+#~ msgid "Gone"
+#~ msgstr "消えた"
+`
+	language, units, err := ParseTranslationFile([]byte(po), TranslationFormatPO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if language != "ja" || len(units) != 2 {
+		t.Fatalf("parsed %q, %+v, want ja with 2 units", language, units)
+	}
+	if units[0].State != TranslationUnitNeedsReview || units[1].State != "" ||
+		units[1].Source != "line one\nline \"two\"" || units[1].Target != "一行目\n二行目" {
+		t.Errorf("units = %+v", units)
+	}
+	if _, _, err := ParseTranslationFile([]byte("msgid \"a\"\nbogus \"b\""), TranslationFormatPO); err == nil {
+		t.Error("unknown keyword parsed without an error")
+	}
+}
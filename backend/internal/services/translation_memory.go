@@ -0,0 +1,325 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// translationMemoryBatch is how many segments one lookup or save takes
+	translationMemoryBatch = 500
+	// translationMemoryMaxSegment is the longest segment kept, in characters
+	translationMemoryMaxSegment = 10000
+	// translationMemoryMaxFuzzy is the longest segment compared for similar
+	// sources, in characters; longer ones only match exactly
+	translationMemoryMaxFuzzy = 2000
+	// translationMemoryCandidates is how many sources of a similar length
+	// are compared with a segment
+	translationMemoryCandidates = 50
+)
+
+var (
+	// ErrInvalidTranslationMemory is returned for a lookup or save the
+	// memory cannot take
+	ErrInvalidTranslationMemory  = errors.New("invalid translation memory request")
+	ErrTranslationMemoryNotFound = errors.New("translation memory entry not found")
+)
+
+// numberPattern finds the numbers of a segment, which must all be the same
+// for a similar source to be reused
+var numberPattern = regexp.MustCompile(`\d+`)
+
+// TranslationPair is a segment and its translation
+type TranslationPair struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// TranslationMemoryMatch is a remembered translation for the segment at
+// Index of a lookup. Score is the similarity of the sources in percent,
+// 100 for the same text.
+type TranslationMemoryMatch struct {
+	Index       int    `json:"index"`
+	ID          uint   `json:"id"`
+	Translation string `json:"translation"`
+	Score       int    `json:"score"`
+}
+
+// TranslationMemoryService remembers translated segments per site and
+// language pair, and finds them again for the same or a nearly identical
+// source so they need not be sent to a translation service again. Similar
+// sources are only reused when they share every number, as the number is
+// what most often differs between otherwise identical boilerplate.
+type TranslationMemoryService struct {
+	db    func() *gorm.DB
+	match int
+}
+
+// NewTranslationMemoryService creates a translation memory from the
+// configuration
+func NewTranslationMemoryService() *TranslationMemoryService {
+	return &TranslationMemoryService{
+		db:    func() *gorm.DB { return database.DB },
+		match: config.Get().AI.TranslationMemoryMatch,
+	}
+}
+
+// Enabled reports whether the translation memory is on
+func (m *TranslationMemoryService) Enabled() bool {
+	return m.match > 0
+}
+
+// Lookup returns the remembered translations for segments from one
+// language into another: exact matches, else the most similar source at or
+// above the configured similarity
+func (m *TranslationMemoryService) Lookup(ctx context.Context, siteID uint, from, to string, segments []string) ([]TranslationMemoryMatch, error) {
+	if err := checkMemoryRequest(from, to, len(segments)); err != nil {
+		return nil, err
+	}
+	matches := []TranslationMemoryMatch{}
+	if !m.Enabled() {
+		return matches, nil
+	}
+	db := m.db().WithContext(ctx).Where("site_id = ? AND source_language = ? AND target_language = ?", siteID, from, to)
+
+	hashes := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if source := normalizeSegment(segment); source != "" {
+			hashes = append(hashes, segmentHash(source))
+		}
+	}
+	var exact []models.TranslationMemory
+	if len(hashes) > 0 {
+		if err := db.Session(&gorm.Session{}).Where("source_hash IN ?", hashes).Find(&exact).Error; err != nil {
+			return nil, err
+		}
+	}
+	byHash := make(map[string]*models.TranslationMemory, len(exact))
+	for i := range exact {
+		byHash[exact[i].SourceHash] = &exact[i]
+	}
+
+	var used []uint
+	for i, segment := range segments {
+		source := normalizeSegment(segment)
+		if source == "" {
+			continue
+		}
+		if entry, ok := byHash[segmentHash(source)]; ok {
+			matches = append(matches, TranslationMemoryMatch{Index: i, ID: entry.ID, Translation: entry.Target, Score: 100})
+			used = append(used, entry.ID)
+			continue
+		}
+		if m.match == 100 {
+			continue
+		}
+		entry, score, err := m.similar(db.Session(&gorm.Session{}), source)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			matches = append(matches, TranslationMemoryMatch{Index: i, ID: entry.ID, Translation: entry.Target, Score: score})
+			used = append(used, entry.ID)
+		}
+	}
+	if len(used) > 0 {
+		if err := m.db().WithContext(ctx).Model(&models.TranslationMemory{}).Where("id IN ?", used).
+			UpdateColumn("uses", gorm.Expr("uses + 1")).Error; err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
+
+// similar returns the remembered source most like source, if any is
+// similar enough, with its similarity
+func (m *TranslationMemoryService) similar(db *gorm.DB, source string) (*models.TranslationMemory, int, error) {
+	length := utf8.RuneCountInString(source)
+	if length > translationMemoryMaxFuzzy {
+		return nil, 0, nil
+	}
+	var candidates []models.TranslationMemory
+	if err := db.Where("source_length BETWEEN ? AND ?", length*m.match/100, length*100/m.match).
+		Order("uses DESC").Limit(translationMemoryCandidates).Find(&candidates).Error; err != nil {
+		return nil, 0, err
+	}
+	numbers := strings.Join(numberPattern.FindAllString(source, -1), " ")
+	var best *models.TranslationMemory
+	bestScore := 0
+	for i := range candidates {
+		candidate := &candidates[i]
+		if strings.Join(numberPattern.FindAllString(candidate.Source, -1), " ") != numbers {
+			continue
+		}
+		if score := similarity(source, candidate.Source); score >= m.match && score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best, bestScore, nil
+}
+
+// Remember keeps translated segments for later lookups, replacing what was
+// remembered for the same source. Segments left untranslated and segments
+// too long to be reused are skipped. It returns how many were kept.
+func (m *TranslationMemoryService) Remember(ctx context.Context, siteID uint, from, to string, pairs []TranslationPair) (int, error) {
+	if err := checkMemoryRequest(from, to, len(pairs)); err != nil {
+		return 0, err
+	}
+	if !m.Enabled() {
+		return 0, nil
+	}
+	entries := make(map[string]*models.TranslationMemory, len(pairs))
+	for _, pair := range pairs {
+		source, target := normalizeSegment(pair.Source), strings.TrimSpace(pair.Target)
+		length := utf8.RuneCountInString(source)
+		if source == "" || target == "" || source == normalizeSegment(target) || length > translationMemoryMaxSegment {
+			continue
+		}
+		hash := segmentHash(source)
+		entries[hash] = &models.TranslationMemory{
+			SiteID: siteID, SourceLanguage: from, TargetLanguage: to,
+			SourceHash: hash, SourceLength: length, Source: source, Target: target,
+		}
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	rows := make([]*models.TranslationMemory, 0, len(entries))
+	for _, entry := range entries {
+		rows = append(rows, entry)
+	}
+	err := m.db().WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "site_id"}, {Name: "source_language"}, {Name: "target_language"}, {Name: "source_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"target", "updated_at"}),
+	}).Create(&rows).Error
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// List returns a page of the remembered segments, most used first,
+// optionally for one language pair or with sources containing query
+func (m *TranslationMemoryService) List(ctx context.Context, siteID uint, from, to, query string, page, limit int) ([]models.TranslationMemory, int64, error) {
+	db := m.db().WithContext(ctx).Model(&models.TranslationMemory{}).Where("site_id = ?", siteID)
+	if from != "" {
+		db = db.Where("source_language = ?", from)
+	}
+	if to != "" {
+		db = db.Where("target_language = ?", to)
+	}
+	if query != "" {
+		db = db.Where("source LIKE ?", "%"+query+"%")
+	}
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	entries := []models.TranslationMemory{}
+	err := db.Order("uses DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&entries).Error
+	return entries, total, err
+}
+
+// Delete forgets one remembered segment, or every one of the site when id
+// is zero
+func (m *TranslationMemoryService) Delete(ctx context.Context, siteID, id uint) error {
+	db := m.db().WithContext(ctx).Where("site_id = ?", siteID)
+	if id != 0 {
+		db = db.Where("id = ?", id)
+	}
+	result := db.Delete(&models.TranslationMemory{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if id != 0 && result.RowsAffected == 0 {
+		return ErrTranslationMemoryNotFound
+	}
+	return nil
+}
+
+func checkMemoryRequest(from, to string, count int) error {
+	if from == "" || to == "" || from == to || len(from) > 10 || len(to) > 10 {
+		return fmt.Errorf("%w: source_language and target_language must be two different languages", ErrInvalidTranslationMemory)
+	}
+	if count > translationMemoryBatch {
+		return fmt.Errorf("%w: at most %d segments at a time", ErrInvalidTranslationMemory, translationMemoryBatch)
+	}
+	return nil
+}
+
+// normalizeSegment trims a segment and collapses its spacing inside lines,
+// keeping line breaks, which matter to Markdown
+func normalizeSegment(segment string) string {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(segment, "\r\n", "\n")), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func segmentHash(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// similarity returns how alike two texts are in percent: 100 less the
+// character edits between them over the length of the longer
+func similarity(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 100
+	}
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return (longest - previous[len(rb)]) * 100 / longest
+}
+
+var (
+	globalTranslationMemory *TranslationMemoryService
+	translationMemoryOnce   sync.Once
+)
+
+// GetGlobalTranslationMemory returns the global translation memory
+func GetGlobalTranslationMemory() *TranslationMemoryService {
+	translationMemoryOnce.Do(func() {
+		globalTranslationMemory = NewTranslationMemoryService()
+	})
+	return globalTranslationMemory
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestTranslationMemory(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+	m := &TranslationMemoryService{db: func() *gorm.DB { return database.DB }, match: 90}
+
+	stored, err := m.Remember(ctx, models.DefaultSiteID, "zh", "en", []TranslationPair{
+		{"感谢阅读，欢迎在评论区留言。", "Thanks for reading, leave a comment below."},
+		{"本文写于 2024 年。", "Written in 2024."},
+		{"`code`", "`code`"},
+		{"  ", "ignored"},
+	})
+	if err != nil || stored != 2 {
+		t.Fatalf("Remember = %d, %v, want 2 pairs kept", stored, err)
+	}
+	// Saving a source again replaces its translation
+	m.Remember(ctx, models.DefaultSiteID, "zh", "en", []TranslationPair{{"本文写于  2024 年。", "This post was written in 2024."}})
+
+	matches, err := m.Lookup(ctx, models.DefaultSiteID, "zh", "en", []string{
+		"新段落",
+		" 本文写于 2024 年。 ",
+		"感谢阅读，欢迎在评论区留言！",
+		"本文写于 2025 年。",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("matches = %+v, want an exact and a similar one", matches)
+	}
+	if matches[0].Index != 1 || matches[0].Score != 100 || matches[0].Translation != "This post was written in 2024." {
+		t.Errorf("exact match = %+v", matches[0])
+	}
+	// A different year is not reused however similar the rest is
+	if matches[1].Index != 2 || matches[1].Score < 90 || matches[1].Score == 100 {
+		t.Errorf("similar match = %+v", matches[1])
+	}
+
+	if other, _ := m.Lookup(ctx, models.DefaultSiteID, "zh", "ja", []string{"本文写于 2024 年。"}); len(other) != 0 {
+		t.Errorf("matches for another language = %+v, want none", other)
+	}
+	if _, err := m.Lookup(ctx, models.DefaultSiteID, "en", "en", nil); !errors.Is(err, ErrInvalidTranslationMemory) {
+		t.Errorf("lookup within one language = %v, want ErrInvalidTranslationMemory", err)
+	}
+
+	entries, total, err := m.List(ctx, models.DefaultSiteID, "zh", "en", "", 1, 10)
+	if err != nil || total != 2 || entries[0].Uses != 1 {
+		t.Fatalf("List = %+v, %d, %v, want two entries, the most used first", entries, total, err)
+	}
+	if err := m.Delete(ctx, models.DefaultSiteID, entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Delete(ctx, models.DefaultSiteID, entries[0].ID); !errors.Is(err, ErrTranslationMemoryNotFound) {
+		t.Errorf("deleting twice = %v, want ErrTranslationMemoryNotFound", err)
+	}
+
+	m.match = 0
+	if matches, _ := m.Lookup(ctx, models.DefaultSiteID, "zh", "en", []string{"感谢阅读，欢迎在评论区留言。"}); len(matches) != 0 {
+		t.Errorf("disabled memory matched %+v", matches)
+	}
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrArticleNotFound is returned for an article that is not on the site
+var ErrArticleNotFound = errors.New("article not found")
+
+// translationRefreshJob is the payload of JobTranslationRefresh: one
+// translation of an article
+type translationRefreshJob struct {
+	SiteID    uint   `json:"site_id"`
+	ArticleID uint   `json:"article_id"`
+	Language  string `json:"language"`
+}
+
+// StaleTranslation is an article translation made from an earlier version
+// of the article. Sections are the title, the summary and the content
+// blocks; a translation written before sections were kept has them all
+// changed.
+type StaleTranslation struct {
+	ArticleID         uint      `json:"article_id"`
+	Title             string    `json:"title"`
+	Language          string    `json:"language"`
+	ChangedSections   int       `json:"changed_sections"`
+	TotalSections     int       `json:"total_sections"`
+	MachineTranslated bool      `json:"machine_translated"`
+	TranslatedAt      time.Time `json:"translated_at"`
+}
+
+// TranslationRefresher brings stale article translations up to date with
+// the AI provider from the AI settings. Only the sections that changed
+// since a translation was written are sent; the rest of the translation is
+// kept as it is.
+type TranslationRefresher struct {
+	db     func() *gorm.DB
+	llm    *LLMClient
+	memory *TranslationMemoryService
+	auto   bool
+}
+
+// NewTranslationRefresher creates a translation refresher from the
+// configuration
+func NewTranslationRefresher() *TranslationRefresher {
+	return &TranslationRefresher{
+		db:     func() *gorm.DB { return database.DB },
+		llm:    NewLLMClient(),
+		memory: GetGlobalTranslationMemory(),
+		auto:   config.Get().AI.AutoRetranslate,
+	}
+}
+
+// registerHooks re-translates updated articles when AI_AUTO_RETRANSLATE is
+// set
+func (r *TranslationRefresher) registerHooks() {
+	hooks.Register(hooks.ArticleUpdated, "translation_refresh", r.articleUpdated)
+}
+
+// articleUpdated queues the refresh of every translation the update made
+// stale. The job key holds the new source, so saving the article again
+// without changing its text queues nothing.
+func (r *TranslationRefresher) articleUpdated(ctx context.Context, event hooks.Event, payload interface{}) error {
+	article, ok := payload.(*models.Article)
+	if !ok || !r.auto {
+		return nil
+	}
+	_, err := r.queue(ctx, article.SiteID, article.ID, "", true)
+	if errors.Is(err, ErrLLMNotConfigured) {
+		return nil
+	}
+	return err
+}
+
+// QueueRefresh queues the refresh of an article's stale translations, or
+// only of the one in language when it is set, and returns the queued jobs
+func (r *TranslationRefresher) QueueRefresh(ctx context.Context, siteID, articleID uint, language string) ([]*models.Job, error) {
+	return r.queue(ctx, siteID, articleID, language, false)
+}
+
+func (r *TranslationRefresher) queue(ctx context.Context, siteID, articleID uint, language string, unique bool) ([]*models.Job, error) {
+	var article models.Article
+	if err := r.db().WithContext(ctx).Preload("Translations").Where("site_id = ?", siteID).Limit(1).Find(&article, articleID).Error; err != nil {
+		return nil, err
+	}
+	if article.ID == 0 {
+		return nil, ErrArticleNotFound
+	}
+	if !r.llm.Configured(siteID) {
+		return nil, ErrLLMNotConfigured
+	}
+	sourceHash := article.SourceHash()
+	jobs := []*models.Job{}
+	for _, translation := range article.Translations {
+		if (language != "" && translation.Language != language) || !translationStale(&translation, sourceHash) {
+			continue
+		}
+		payload := translationRefreshJob{SiteID: siteID, ArticleID: article.ID, Language: translation.Language}
+		var job *models.Job
+		var err error
+		if unique {
+			key := fmt.Sprintf("translation.refresh:%d:%s:%s", article.ID, translation.Language, sourceHash)
+			job, err = GetGlobalJobQueue().EnqueueUnique(JobTranslationRefresh, payload, key)
+			if errors.Is(err, ErrJobExists) {
+				continue
+			}
+		} else {
+			job, err = GetGlobalJobQueue().Enqueue(JobTranslationRefresh, payload)
+		}
+		if err != nil {
+			return jobs, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// Refresh re-translates the changed sections of one translation, keeping
+// the translated blocks whose source is unchanged and reusing the
+// translation memory before asking the AI provider. The result is marked
+// as a machine translation for review.
+func (r *TranslationRefresher) Refresh(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var job translationRefreshJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, err
+	}
+	var article models.Article
+	if err := r.db().Where("site_id = ?", job.SiteID).Limit(1).Find(&article, job.ArticleID).Error; err != nil {
+		return nil, err
+	}
+	var translation models.ArticleTranslation
+	if err := r.db().Where("article_id = ? AND language = ?", job.ArticleID, job.Language).Limit(1).Find(&translation).Error; err != nil {
+		return nil, err
+	}
+	if article.ID == 0 || translation.ID == 0 {
+		return map[string]string{"skipped": "translation deleted"}, nil
+	}
+	if !translationStale(&translation, article.SourceHash()) {
+		return map[string]string{"skipped": "translation up to date"}, nil
+	}
+
+	sourceLanguage := article.DefaultLang
+	if sourceLanguage == "" {
+		sourceLanguage = siteLanguage(r.db(), job.SiteID)
+	}
+	translated, remembered := 0, 0
+	translate := func(text string) (string, error) {
+		if strings.TrimSpace(text) == "" {
+			return "", nil
+		}
+		if r.memory != nil {
+			matches, err := r.memory.Lookup(ctx, job.SiteID, sourceLanguage, job.Language, []string{text})
+			if err != nil {
+				return "", err
+			}
+			if len(matches) > 0 {
+				remembered++
+				return matches[0].Translation, nil
+			}
+		}
+		translated++
+		answer, err := r.llm.Complete(ctx, job.SiteID, LLMRequest{
+			System: fmt.Sprintf("You translate blog articles from %s to %s. Keep Markdown and HTML markup, code, "+
+				"URLs and image paths unchanged, and translate only the text. Answer with the translation and nothing else.",
+				llmLanguageName(sourceLanguage), llmLanguageName(job.Language)),
+			Prompt:      text,
+			ServiceType: "translation",
+			Operation:   "refresh_translation",
+			Language:    job.Language,
+		})
+		if err != nil {
+			return "", err
+		}
+		answer = strings.TrimSpace(answer)
+		if r.memory != nil {
+			if _, err := r.memory.Remember(ctx, job.SiteID, sourceLanguage, job.Language, []TranslationPair{{text, answer}}); err != nil {
+				slog.Warn("Failed to remember a translation", "article_id", job.ArticleID, "error", err)
+			}
+		}
+		return answer, nil
+	}
+
+	source := article.Source()
+	previous, sectioned := translation.TranslatedSource()
+	blocks := models.ContentBlocks(article.Content)
+	progress := JobProgressFrom(ctx)
+	progress.SetTotal(len(blocks) + 2)
+	var err error
+	if !sectioned || previous.Title != source.Title {
+		progress.Step("Translating the title")
+		if translation.Title, err = translate(article.Title); err != nil {
+			return nil, err
+		}
+	}
+	progress.Advance(1)
+	if !sectioned || previous.Summary != source.Summary {
+		progress.Step("Translating the summary")
+		if translation.Summary, err = translate(article.Summary); err != nil {
+			return nil, err
+		}
+	}
+	progress.Advance(1)
+
+	// Translated blocks are matched to their source by position, which only
+	// holds while the translation has as many blocks as its source had
+	kept := map[string]string{}
+	if translatedBlocks := models.ContentBlocks(translation.Content); sectioned && len(translatedBlocks) == len(previous.Content) {
+		for i, hash := range previous.Content {
+			if _, ok := kept[hash]; !ok {
+				kept[hash] = translatedBlocks[i]
+			}
+		}
+	}
+	for i, block := range blocks {
+		if keep, ok := kept[source.Content[i]]; ok {
+			blocks[i] = keep
+			progress.Advance(1)
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(block), "```") || strings.HasPrefix(strings.TrimSpace(block), "~~~") {
+			progress.Advance(1)
+			continue
+		}
+		progress.Step("Translating section %d of %d", i+1, len(blocks))
+		if blocks[i], err = translate(block); err != nil {
+			return nil, err
+		}
+		progress.Advance(1)
+	}
+	translation.Content = strings.Join(blocks, "\n\n")
+	if security.ShouldSanitize(false) {
+		translation.Content = security.GetGlobalHTMLPolicy().SanitizeContent(translation.Content, article.ContentType)
+	}
+
+	translation.StampSource(&article)
+	translation.MachineTranslated = true
+	if err := r.db().Save(&translation).Error; err != nil {
+		return nil, err
+	}
+	cache.Publish(cache.TopicArticles)
+	return map[string]int{"translated_sections": translated, "remembered_sections": remembered, "total_sections": len(blocks) + 2}, nil
+}
+
+// StaleTranslations lists the article translations made from an earlier
+// version of their article, for one article or for all when articleID is
+// zero. db must be scoped to the site.
+func StaleTranslations(db *gorm.DB, articleID uint) ([]StaleTranslation, error) {
+	query := db.Preload("Translations").Order("id")
+	if articleID != 0 {
+		query = query.Where("id = ?", articleID)
+	}
+	var articles []models.Article
+	if err := query.Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	stale := []StaleTranslation{}
+	for i := range articles {
+		article := &articles[i]
+		sourceHash := article.SourceHash()
+		source := article.Source()
+		for j := range article.Translations {
+			translation := &article.Translations[j]
+			if !translationStale(translation, sourceHash) {
+				continue
+			}
+			changed, total := changedSections(source, translation)
+			stale = append(stale, StaleTranslation{
+				ArticleID:         article.ID,
+				Title:             article.Title,
+				Language:          translation.Language,
+				ChangedSections:   changed,
+				TotalSections:     total,
+				MachineTranslated: translation.MachineTranslated,
+				TranslatedAt:      translation.UpdatedAt,
+			})
+		}
+	}
+	return stale, nil
+}
+
+// translationStale reports whether a translation was made from another
+// version of the source; unstamped translations are taken as up to date,
+// as in the translation status
+func translationStale(translation *models.ArticleTranslation, sourceHash string) bool {
+	return translation.SourceHash != "" && translation.SourceHash != sourceHash
+}
+
+// changedSections counts the sections of the source a translation was not
+// made from
+func changedSections(source models.ArticleSource, translation *models.ArticleTranslation) (changed, total int) {
+	total = len(source.Content) + 2
+	previous, ok := translation.TranslatedSource()
+	if !ok {
+		return total, total
+	}
+	if previous.Title != source.Title {
+		changed++
+	}
+	if previous.Summary != source.Summary {
+		changed++
+	}
+	translated := make(map[string]bool, len(previous.Content))
+	for _, hash := range previous.Content {
+		translated[hash] = true
+	}
+	for _, hash := range source.Content {
+		if !translated[hash] {
+			changed++
+		}
+	}
+	return changed, total
+}
+
+var (
+	globalTranslationRefresher *TranslationRefresher
+	translationRefresherOnce   sync.Once
+)
+
+// GetGlobalTranslationRefresher returns the global translation refresher,
+// registering its hook on first use
+func GetGlobalTranslationRefresher() *TranslationRefresher {
+	translationRefresherOnce.Do(func() {
+		globalTranslationRefresher = NewTranslationRefresher()
+		globalTranslationRefresher.registerHooks()
+	})
+	return globalTranslationRefresher
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"blog-backend/internal/security"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestContentBlocks(t *testing.T) {
+	content := "# Title\r\n\r\nFirst paragraph\nstill first\n\n\n```go\nfunc main() {\n\n}\n```\n\nLast"
+	want := []string{"# Title", "First paragraph\nstill first", "```go\nfunc main() {\n\n}\n```", "Last"}
+	if blocks := models.ContentBlocks(content); !reflect.DeepEqual(blocks, want) {
+		t.Errorf("ContentBlocks = %q, want %q", blocks, want)
+	}
+}
+
+func TestTranslationRefresh(t *testing.T) {
+	newTestDB(t)
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Messages []struct{ Content string } `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		prompt := request.Messages[1].Content
+		prompts = append(prompts, prompt)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": "EN " + prompt}}},
+			"usage":   map[string]int{"prompt_tokens": 10, "completion_tokens": 10},
+		})
+	}))
+	defer server.Close()
+	db := func() *gorm.DB { return database.DB }
+	r := &TranslationRefresher{db: db, llm: &LLMClient{db: db, client: server.Client(), tracker: NewAIUsageTracker()}}
+
+	secure, err := security.GetGlobalAIConfigService().EncryptAIConfig(&security.InputAIConfig{
+		DefaultProvider: "openai",
+		Providers: map[string]security.InputProviderConfig{
+			"openai": {Provider: "openai", APIKey: "sk-test", Model: "gpt-test", Enabled: true,
+				Settings: map[string]string{"base_url": server.URL}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aiConfig, _ := json.Marshal(secure)
+	database.DB.Create(&models.SiteSettings{SiteID: models.DefaultSiteID, DefaultLanguage: "zh", AIConfig: string(aiConfig)})
+
+	article := models.Article{Title: "标题", Summary: "摘要", Content: "第一段\n\n第二段\n\n```\ncode\n```", DefaultLang: "zh"}
+	database.DB.Create(&article)
+	translation := models.ArticleTranslation{ArticleID: article.ID, Language: "en", Title: "Title", Summary: "Summary",
+		Content: "First\n\nSecond\n\n```\ncode\n```"}
+	translation.StampSource(&article)
+	database.DB.Create(&translation)
+	// A translation from before sections were kept
+	legacy := models.ArticleTranslation{ArticleID: article.ID, Language: "ja", Title: "タイトル", SourceHash: "old"}
+	database.DB.Create(&legacy)
+
+	database.DB.Model(&article).Update("content", "第一段\n\n新的第二段\n\n```\ncode\n```")
+	database.DB.First(&article, article.ID)
+
+	stale, err := StaleTranslations(database.DB, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 2 || stale[0].Language != "en" || stale[0].ChangedSections != 1 || stale[0].TotalSections != 5 ||
+		stale[1].ChangedSections != 5 {
+		t.Fatalf("stale = %+v, want en with 1 of 5 sections changed and ja with all", stale)
+	}
+
+	payload, _ := json.Marshal(translationRefreshJob{SiteID: models.DefaultSiteID, ArticleID: article.ID, Language: "en"})
+	if _, err := r.Refresh(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts) != 1 || prompts[0] != "新的第二段" {
+		t.Errorf("prompts = %q, want only the changed paragraph", prompts)
+	}
+	database.DB.First(&translation, translation.ID)
+	if want := "First\n\nEN 新的第二段\n\n```\ncode\n```"; translation.Content != want || translation.Title != "Title" {
+		t.Errorf("translation = %q, %q, want %q", translation.Title, translation.Content, want)
+	}
+	if !translation.MachineTranslated || translation.SourceHash != article.SourceHash() {
+		t.Errorf("translation not stamped as an up to date machine translation: %+v", translation)
+	}
+	if stale, _ := StaleTranslations(database.DB, article.ID); len(stale) != 1 || stale[0].Language != "ja" {
+		t.Errorf("stale after refresh = %+v, want only ja", stale)
+	}
+
+	// Without sections everything but the code block is translated again
+	prompts = nil
+	payload, _ = json.Marshal(translationRefreshJob{SiteID: models.DefaultSiteID, ArticleID: article.ID, Language: "ja"})
+	if _, err := r.Refresh(context.Background(), payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts) != 4 || !strings.Contains(prompts[0], "标题") {
+		t.Errorf("prompts = %q, want title, summary and two paragraphs", prompts)
+	}
+	if _, err := r.Refresh(context.Background(), payload); err != nil || len(prompts) != 4 {
+		t.Errorf("refreshing an up to date translation = %v, %d prompts, want it skipped", err, len(prompts))
+	}
+}
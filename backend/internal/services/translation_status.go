@@ -0,0 +1,244 @@
+package services
+
+import (
+	"blog-backend/internal/models"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrTranslationNotFound    = errors.New("translation not found")
+	ErrInvalidTranslationKind = errors.New("invalid translation kind, use article, category or settings")
+)
+
+// TranslationItem is a translation that needs attention
+type TranslationItem struct {
+	Kind      string    `json:"kind"`
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TranslationCoverage counts the content of one kind that could be
+// translated into a language: everything not written in it, how much of it
+// is translated, and how many translations are out of date or machine
+// translations waiting for review
+type TranslationCoverage struct {
+	Total      int `json:"total"`
+	Translated int `json:"translated"`
+	Outdated   int `json:"outdated"`
+	Machine    int `json:"machine"`
+}
+
+// LanguageTranslationStatus is the translation coverage of one language
+type LanguageTranslationStatus struct {
+	Language   string              `json:"language"`
+	Articles   TranslationCoverage `json:"articles"`
+	Categories TranslationCoverage `json:"categories"`
+	Settings   TranslationCoverage `json:"settings"`
+	Outdated   []TranslationItem   `json:"outdated"`
+	Machine    []TranslationItem   `json:"machine"`
+}
+
+// TranslationStatus is the translation coverage of a site in every language
+// it has translations in
+type TranslationStatus struct {
+	DefaultLanguage string                      `json:"default_language"`
+	Languages       []LanguageTranslationStatus `json:"languages"`
+}
+
+// translationState is what the report needs to know of one translation
+type translationState struct {
+	language   string
+	sourceHash string
+	machine    bool
+	updatedAt  time.Time
+}
+
+// statusReport builds a TranslationStatus language by language
+type statusReport map[string]*LanguageTranslationStatus
+
+func (r statusReport) language(language string) *LanguageTranslationStatus {
+	status, ok := r[language]
+	if !ok {
+		status = &LanguageTranslationStatus{Language: language, Outdated: []TranslationItem{}, Machine: []TranslationItem{}}
+		r[language] = status
+	}
+	return status
+}
+
+// add counts one source in every language but its own. A translation is
+// outdated when the source changed after it was written, which it tells by
+// the source hash it was stamped with.
+func (r statusReport) add(languages []string, kind string, id uint, title, sourceLanguage, sourceHash string,
+	translations []translationState, coverage func(*LanguageTranslationStatus) *TranslationCoverage) {
+	byLanguage := make(map[string]translationState, len(translations))
+	for _, translation := range translations {
+		byLanguage[translation.language] = translation
+	}
+	for _, language := range languages {
+		if language == sourceLanguage {
+			continue
+		}
+		status := r.language(language)
+		counts := coverage(status)
+		counts.Total++
+		translation, ok := byLanguage[language]
+		if !ok {
+			continue
+		}
+		counts.Translated++
+		item := TranslationItem{Kind: kind, ID: id, Title: title, UpdatedAt: translation.updatedAt}
+		if translation.sourceHash != "" && translation.sourceHash != sourceHash {
+			counts.Outdated++
+			status.Outdated = append(status.Outdated, item)
+		}
+		if translation.machine {
+			counts.Machine++
+			status.Machine = append(status.Machine, item)
+		}
+	}
+}
+
+// GetTranslationStatus reports the site's translation coverage for every
+// language any of its content is translated into, or only for language when
+// it is set. Content is not counted in the language it is written in. db
+// must be scoped to the site.
+func GetTranslationStatus(db *gorm.DB, language string) (*TranslationStatus, error) {
+	var settings models.SiteSettings
+	if err := db.Preload("Translations").Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	defaultLanguage := settings.DefaultLanguage
+	if defaultLanguage == "" {
+		defaultLanguage = "zh"
+	}
+
+	var articles []models.Article
+	if err := db.Preload("Translations").Order("id").Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	var categories []models.Category
+	if err := db.Preload("Translations").Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	languages := []string{language}
+	if language == "" {
+		seen := map[string]bool{}
+		languages = nil
+		note := func(language string) {
+			if !seen[language] {
+				seen[language] = true
+				languages = append(languages, language)
+			}
+		}
+		for _, article := range articles {
+			for _, translation := range article.Translations {
+				note(translation.Language)
+			}
+		}
+		for _, category := range categories {
+			for _, translation := range category.Translations {
+				note(translation.Language)
+			}
+		}
+		for _, translation := range settings.Translations {
+			note(translation.Language)
+		}
+		sort.Strings(languages)
+	}
+
+	report := statusReport{}
+	for _, language := range languages {
+		report.language(language)
+	}
+	for i := range articles {
+		article := &articles[i]
+		states := make([]translationState, len(article.Translations))
+		for j, translation := range article.Translations {
+			states[j] = translationState{translation.Language, translation.SourceHash, translation.MachineTranslated, translation.UpdatedAt}
+		}
+		report.add(languages, models.TranslationArticle, article.ID, article.Title, article.DefaultLang, article.SourceHash(), states,
+			func(status *LanguageTranslationStatus) *TranslationCoverage { return &status.Articles })
+	}
+	for i := range categories {
+		category := &categories[i]
+		states := make([]translationState, len(category.Translations))
+		for j, translation := range category.Translations {
+			states[j] = translationState{translation.Language, translation.SourceHash, translation.MachineTranslated, translation.UpdatedAt}
+		}
+		report.add(languages, models.TranslationCategory, category.ID, category.Name, category.DefaultLang, category.SourceHash(), states,
+			func(status *LanguageTranslationStatus) *TranslationCoverage { return &status.Categories })
+	}
+	if settings.ID != 0 {
+		states := make([]translationState, len(settings.Translations))
+		for j, translation := range settings.Translations {
+			states[j] = translationState{translation.Language, translation.SourceHash, translation.MachineTranslated, translation.UpdatedAt}
+		}
+		report.add(languages, models.TranslationSettings, settings.ID, settings.SiteTitle, defaultLanguage, settings.SourceHash(), states,
+			func(status *LanguageTranslationStatus) *TranslationCoverage { return &status.Settings })
+	}
+
+	status := &TranslationStatus{DefaultLanguage: defaultLanguage, Languages: []LanguageTranslationStatus{}}
+	for _, language := range languages {
+		status.Languages = append(status.Languages, *report[language])
+	}
+	return status, nil
+}
+
+// ReviewTranslation marks a machine translation as reviewed. id is the
+// article, category or settings the translation belongs to; db must be
+// scoped to the site.
+func ReviewTranslation(db *gorm.DB, kind string, id uint, language string) error {
+	var source, translation interface{}
+	var column string
+	switch kind {
+	case models.TranslationArticle:
+		source, translation, column = &models.Article{}, &models.ArticleTranslation{}, "article_id"
+	case models.TranslationCategory:
+		source, translation, column = &models.Category{}, &models.CategoryTranslation{}, "category_id"
+	case models.TranslationSettings:
+		source, translation, column = &models.SiteSettings{}, &models.SiteSettingsTranslation{}, "settings_id"
+	default:
+		return ErrInvalidTranslationKind
+	}
+
+	// Translations have no site, so the source is looked up in the site first
+	var count int64
+	if err := db.Model(source).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrTranslationNotFound
+	}
+	where := column + " = ? AND language = ?"
+	if err := db.Model(translation).Where(where, id, language).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrTranslationNotFound
+	}
+	return db.Model(translation).Where(where, id, language).UpdateColumn("machine_translated", false).Error
+}
+
+// MissingTranslations returns the required languages, other than the
+// article's own, it has no translation with both a title and content in
+func MissingTranslations(required []string, defaultLang string, translations []models.ArticleTranslation) []string {
+	complete := make(map[string]bool, len(translations))
+	for _, translation := range translations {
+		complete[translation.Language] = strings.TrimSpace(translation.Title) != "" && strings.TrimSpace(translation.Content) != ""
+	}
+	missing := []string{}
+	for _, language := range required {
+		if language != defaultLang && !complete[language] && !slices.Contains(missing, language) {
+			missing = append(missing, language)
+		}
+	}
+	return missing
+}
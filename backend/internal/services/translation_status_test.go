@@ -0,0 +1,94 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTranslationStatus(t *testing.T) {
+	newTestDB(t)
+	db := database.DB
+
+	settings := models.SiteSettings{SiteTitle: "Blog", DefaultLanguage: "zh"}
+	if err := db.Create(&settings).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&models.SiteSettingsTranslation{SettingsID: settings.ID, Language: "en", SiteTitle: "Blog", SourceHash: settings.SourceHash()})
+
+	article := models.Article{Title: "你好", Content: "内容", DefaultLang: "zh"}
+	if err := db.Create(&article).Error; err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&models.ArticleTranslation{ArticleID: article.ID, Language: "en", Title: "Hello", SourceHash: article.SourceHash(), MachineTranslated: true})
+	db.Create(&models.ArticleTranslation{ArticleID: article.ID, Language: "ja", Title: "こんにちは", SourceHash: article.SourceHash()})
+	untranslated := models.Article{Title: "Second", DefaultLang: "en"}
+	db.Create(&untranslated)
+
+	// The source changes after the translations were written
+	db.Model(&article).Update("content", "新内容")
+
+	category := models.Category{Name: "分类", DefaultLang: "zh"}
+	db.Create(&category)
+	db.Create(&models.CategoryTranslation{CategoryID: category.ID, Language: "en", Name: "Category", SourceHash: category.SourceHash()})
+
+	status, err := GetTranslationStatus(db, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.DefaultLanguage != "zh" || len(status.Languages) != 2 {
+		t.Fatalf("status = %+v, want zh with en and ja", status)
+	}
+	en, ja := status.Languages[0], status.Languages[1]
+	if en.Language != "en" || ja.Language != "ja" {
+		t.Fatalf("languages = %s, %s, want en, ja", en.Language, ja.Language)
+	}
+	// The second article is written in English, so it only counts for Japanese
+	if want := (TranslationCoverage{Total: 1, Translated: 1, Outdated: 1, Machine: 1}); en.Articles != want {
+		t.Errorf("en articles = %+v, want %+v", en.Articles, want)
+	}
+	if want := (TranslationCoverage{Total: 2, Translated: 1, Outdated: 1}); ja.Articles != want {
+		t.Errorf("ja articles = %+v, want %+v", ja.Articles, want)
+	}
+	if want := (TranslationCoverage{Total: 1, Translated: 1}); en.Categories != want || en.Settings != want {
+		t.Errorf("en categories = %+v, settings = %+v, want %+v", en.Categories, en.Settings, want)
+	}
+	if ja.Settings.Translated != 0 || ja.Settings.Total != 1 {
+		t.Errorf("ja settings = %+v, want one untranslated", ja.Settings)
+	}
+	if len(en.Outdated) != 1 || en.Outdated[0].ID != article.ID || en.Outdated[0].Kind != models.TranslationArticle {
+		t.Errorf("en outdated = %+v, want the first article", en.Outdated)
+	}
+	if len(en.Machine) != 1 || len(ja.Machine) != 0 {
+		t.Errorf("machine = %+v, %+v, want only the English article", en.Machine, ja.Machine)
+	}
+
+	if err := ReviewTranslation(db, models.TranslationArticle, article.ID, "en"); err != nil {
+		t.Fatal(err)
+	}
+	status, _ = GetTranslationStatus(db, "en")
+	if len(status.Languages) != 1 || status.Languages[0].Articles.Machine != 0 {
+		t.Errorf("after review = %+v, want no machine translations", status.Languages)
+	}
+	if err := ReviewTranslation(db, models.TranslationArticle, article.ID, "fr"); !errors.Is(err, ErrTranslationNotFound) {
+		t.Errorf("review of a missing translation = %v, want ErrTranslationNotFound", err)
+	}
+	if err := ReviewTranslation(db, "page", article.ID, "en"); !errors.Is(err, ErrInvalidTranslationKind) {
+		t.Errorf("review of an unknown kind = %v, want ErrInvalidTranslationKind", err)
+	}
+}
+
+func TestMissingTranslations(t *testing.T) {
+	translations := []models.ArticleTranslation{
+		{Language: "en", Title: "Title", Content: "Content"},
+		{Language: "ja", Title: "タイトル", Content: "  "},
+		{Language: "fr", Title: "Titre"},
+		{Language: "fr", Title: "Titre", Content: "Contenu"},
+	}
+	missing := MissingTranslations([]string{"zh", "en", "ja", "fr", "de", "de"}, "zh", translations)
+	if strings.Join(missing, ",") != "ja,de" {
+		t.Errorf("missing = %v, want ja and de", missing)
+	}
+}
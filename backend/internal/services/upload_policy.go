@@ -0,0 +1,244 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// MaxUploadSize is the highest size limit an upload type can have, as
+// uploads are read into memory to be checked
+const MaxUploadSize = 100 << 20
+
+// SVGMimeType is the type of SVG images, which are sanitized instead of
+// re-encoded and need UploadPolicy.AllowSVG
+const SVGMimeType = "image/svg+xml"
+
+var (
+	ErrInvalidUploadPolicy = errors.New("invalid upload policy")
+	ErrUploadNotAllowed    = errors.New("upload not allowed")
+)
+
+// uploadFormat is a file type the upload checks know how to validate
+type uploadFormat struct {
+	mediaType  models.MediaType
+	extensions []string
+	enabled    bool // without a policy of the site's own
+}
+
+// uploadFormats are the types that can be allowed, by MIME type
+var uploadFormats = map[string]uploadFormat{
+	"image/jpeg": {models.MediaTypeImage, []string{".jpg", ".jpeg"}, true},
+	"image/png":  {models.MediaTypeImage, []string{".png"}, true},
+	"image/gif":  {models.MediaTypeImage, []string{".gif"}, true},
+	"image/webp": {models.MediaTypeImage, []string{".webp"}, false},
+	SVGMimeType:  {models.MediaTypeImage, []string{".svg"}, false},
+	"video/mp4":  {models.MediaTypeVideo, []string{".mp4"}, true},
+	"video/avi":  {models.MediaTypeVideo, []string{".avi"}, true},
+	"video/mov":  {models.MediaTypeVideo, []string{".mov"}, true},
+	"video/webm": {models.MediaTypeVideo, []string{".webm"}, false},
+	"video/ogg":  {models.MediaTypeVideo, []string{".ogg", ".ogv"}, false},
+}
+
+// UploadPolicy is what a site accepts as media uploads. Types holds a rule
+// for every type that can be allowed, keyed by MIME type; SVG images are
+// only accepted when AllowSVG opts in to them as well.
+type UploadPolicy struct {
+	AllowSVG bool                        `json:"allow_svg"`
+	Types    map[string]UploadTypePolicy `json:"types"`
+}
+
+// UploadTypePolicy allows or refuses one upload type. Extensions are the
+// file name endings accepted for it, and MaxSize is in bytes.
+type UploadTypePolicy struct {
+	Enabled    bool     `json:"enabled"`
+	Extensions []string `json:"extensions"`
+	MaxSize    int64    `json:"max_size"`
+}
+
+// DefaultUploadPolicy is the policy of sites that have not configured one:
+// JPEG, PNG, GIF, MP4, AVI and MOV files of up to MaxUploadSize
+func DefaultUploadPolicy() UploadPolicy {
+	policy := UploadPolicy{Types: make(map[string]UploadTypePolicy, len(uploadFormats))}
+	for mimeType, format := range uploadFormats {
+		policy.Types[mimeType] = UploadTypePolicy{
+			Enabled:    format.enabled,
+			Extensions: append([]string(nil), format.extensions...),
+			MaxSize:    MaxUploadSize,
+		}
+	}
+	return policy
+}
+
+// Check returns the media type of an upload, or ErrUploadNotAllowed when
+// its type, file name or size is not accepted
+func (p *UploadPolicy) Check(contentType, fileName string, size int64) (models.MediaType, error) {
+	mimeType := normalizeUploadType(contentType)
+	rule, known := p.Types[mimeType]
+	if !known || !rule.Enabled || (mimeType == SVGMimeType && !p.AllowSVG) {
+		return "", fmt.Errorf("%w: %s files are not accepted", ErrUploadNotAllowed, contentType)
+	}
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if !contains(rule.Extensions, ext) {
+		return "", fmt.Errorf("%w: file extension %q is not accepted for %s files, use %s", ErrUploadNotAllowed, ext, mimeType, strings.Join(rule.Extensions, ", "))
+	}
+	if size > rule.MaxSize {
+		return "", fmt.Errorf("%w: %s files are limited to %s", ErrUploadNotAllowed, mimeType, formatUploadSize(rule.MaxSize))
+	}
+	return uploadFormats[mimeType].mediaType, nil
+}
+
+// normalizeUploadType drops the parameters of a content type and maps the
+// nonstandard image/jpg to image/jpeg
+func normalizeUploadType(contentType string) string {
+	mimeType, _, _ := strings.Cut(contentType, ";")
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if mimeType == "image/jpg" {
+		return "image/jpeg"
+	}
+	return mimeType
+}
+
+func formatUploadSize(size int64) string {
+	if size >= 1<<20 && size%(1<<20) == 0 {
+		return fmt.Sprintf("%dMB", size>>20)
+	}
+	if size >= 1<<10 && size%(1<<10) == 0 {
+		return fmt.Sprintf("%dKB", size>>10)
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
+
+// UploadPolicyService stores each site's upload policy in its settings
+type UploadPolicyService struct {
+	db func() *gorm.DB
+}
+
+// NewUploadPolicyService creates an upload policy service
+func NewUploadPolicyService() *UploadPolicyService {
+	return &UploadPolicyService{db: func() *gorm.DB { return database.DB }}
+}
+
+// Get returns the site's upload policy, or the default policy
+func (s *UploadPolicyService) Get(ctx context.Context) (*UploadPolicy, error) {
+	var settings models.SiteSettings
+	if err := s.db().WithContext(ctx).Select("upload_policy").Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	policy := DefaultUploadPolicy()
+	if settings.UploadPolicy == "" {
+		return &policy, nil
+	}
+	if err := json.Unmarshal([]byte(settings.UploadPolicy), &policy); err != nil {
+		return nil, fmt.Errorf("stored upload policy: %w", err)
+	}
+	policy.complete()
+	return &policy, nil
+}
+
+// Update validates an upload policy given as JSON and saves it. Types left
+// out keep their default rule; in a rule, no extensions stands for all of
+// the type's extensions and a max_size of 0 for MaxUploadSize.
+func (s *UploadPolicyService) Update(ctx context.Context, body io.Reader) (*UploadPolicy, error) {
+	policy := DefaultUploadPolicy()
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUploadPolicy, err)
+	}
+	policy.complete()
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(policy); err != nil {
+		return nil, err
+	}
+	result := s.db().WithContext(ctx).Model(&models.SiteSettings{}).Where("1 = 1").Update("upload_policy", strings.TrimSpace(buf.String()))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: the site has no settings yet", ErrInvalidUploadPolicy)
+	}
+	cache.Publish(cache.TopicSettings)
+	return &policy, nil
+}
+
+// complete fills in the extensions and size limit of rules without them
+func (p *UploadPolicy) complete() {
+	if p.Types == nil {
+		p.Types = DefaultUploadPolicy().Types
+	}
+	for mimeType, rule := range p.Types {
+		format, known := uploadFormats[mimeType]
+		if !known {
+			continue
+		}
+		if len(rule.Extensions) == 0 {
+			rule.Extensions = append([]string(nil), format.extensions...)
+		}
+		for i, ext := range rule.Extensions {
+			rule.Extensions[i] = strings.ToLower(ext)
+		}
+		if rule.MaxSize == 0 {
+			rule.MaxSize = MaxUploadSize
+		}
+		p.Types[mimeType] = rule
+	}
+}
+
+func (p *UploadPolicy) validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidUploadPolicy}, args...)...)
+	}
+	mimeTypes := make([]string, 0, len(uploadFormats))
+	for mimeType := range uploadFormats {
+		mimeTypes = append(mimeTypes, mimeType)
+	}
+	sort.Strings(mimeTypes)
+	for mimeType, rule := range p.Types {
+		format, known := uploadFormats[mimeType]
+		if !known {
+			return invalid("types: unknown type %q, use %s", mimeType, strings.Join(mimeTypes, ", "))
+		}
+		for _, ext := range rule.Extensions {
+			if !contains(format.extensions, ext) {
+				return invalid("types.%s.extensions: %q is not an extension of the type, use %s", mimeType, ext, strings.Join(format.extensions, ", "))
+			}
+		}
+		if rule.MaxSize < 0 || rule.MaxSize > MaxUploadSize {
+			return invalid("types.%s.max_size: must be between 1 and %d bytes", mimeType, MaxUploadSize)
+		}
+	}
+	if p.Types[SVGMimeType].Enabled && !p.AllowSVG {
+		return invalid("types.%s: set allow_svg to accept sanitized SVG images", SVGMimeType)
+	}
+	return nil
+}
+
+var (
+	globalUploadPolicyService     *UploadPolicyService
+	globalUploadPolicyServiceOnce sync.Once
+)
+
+// GetGlobalUploadPolicyService returns the global upload policy service
+func GetGlobalUploadPolicyService() *UploadPolicyService {
+	globalUploadPolicyServiceOnce.Do(func() {
+		globalUploadPolicyService = NewUploadPolicyService()
+	})
+	return globalUploadPolicyService
+}
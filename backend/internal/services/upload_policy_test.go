@@ -0,0 +1,95 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUploadPolicy(t *testing.T) {
+	newTestDB(t)
+	s := NewUploadPolicyService()
+	ctx := context.Background()
+
+	policy, err := s.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*policy, DefaultUploadPolicy()) {
+		t.Errorf("Get() without settings = %+v, want the default policy", policy)
+	}
+
+	if _, err := s.Update(ctx, strings.NewReader(`{}`)); !errors.Is(err, ErrInvalidUploadPolicy) {
+		t.Errorf("Update() without settings = %v, want ErrInvalidUploadPolicy", err)
+	}
+	if err := database.DB.Create(&models.SiteSettings{SiteTitle: "Blog"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bad := range []string{
+		`{"allow_svgs": true}`,
+		`{"types": {"application/pdf": {"enabled": true}}}`,
+		`{"types": {"image/png": {"enabled": true, "extensions": [".jpg"]}}}`,
+		`{"types": {"image/png": {"enabled": true, "max_size": -1}}}`,
+		`{"types": {"video/mp4": {"enabled": true, "max_size": 104857601}}}`,
+		`{"types": {"image/svg+xml": {"enabled": true}}}`,
+	} {
+		if _, err := s.Update(ctx, strings.NewReader(bad)); !errors.Is(err, ErrInvalidUploadPolicy) {
+			t.Errorf("Update(%s) = %v, want ErrInvalidUploadPolicy", bad, err)
+		}
+	}
+
+	// Types left out keep their default rule
+	saved, err := s.Update(ctx, strings.NewReader(`{
+		"allow_svg": true,
+		"types": {
+			"image/svg+xml": {"enabled": true, "max_size": 1048576},
+			"image/jpeg": {"enabled": true, "extensions": [".JPG"], "max_size": 5242880},
+			"video/avi": {"enabled": false}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Get(ctx); err != nil || !reflect.DeepEqual(got, saved) {
+		t.Errorf("Get() = %+v, %v, want the saved policy %+v", got, err, saved)
+	}
+	if rule := saved.Types["image/jpeg"]; !reflect.DeepEqual(rule.Extensions, []string{".jpg"}) {
+		t.Errorf("jpeg extensions = %v, want them lower case", rule.Extensions)
+	}
+
+	for _, upload := range []struct {
+		contentType, fileName string
+		size                  int64
+		allowed               bool
+	}{
+		{"image/svg+xml", "logo.svg", 1024, true},
+		{"image/svg+xml", "logo.svg", 2 << 20, false},
+		{"image/jpeg", "photo.jpg", 5 << 20, true},
+		{"image/jpeg", "photo.jpeg", 1024, false},
+		{"image/jpeg", "photo.jpg", 6 << 20, false},
+		{"image/png", "image.png", 50 << 20, true},
+		{"video/avi", "clip.avi", 1024, false},
+		{"image/webp", "photo.webp", 1024, false},
+	} {
+		_, err := saved.Check(upload.contentType, upload.fileName, upload.size)
+		if upload.allowed && err != nil {
+			t.Errorf("Check(%s, %s, %d) = %v", upload.contentType, upload.fileName, upload.size, err)
+		} else if !upload.allowed && !errors.Is(err, ErrUploadNotAllowed) {
+			t.Errorf("Check(%s, %s, %d) = %v, want ErrUploadNotAllowed", upload.contentType, upload.fileName, upload.size, err)
+		}
+	}
+	if _, err := saved.Check("image/jpeg", "photo.jpg", 6<<20); err == nil || !strings.Contains(err.Error(), "5MB") {
+		t.Errorf("Check() of a large photo = %v, want it to name the 5MB limit", err)
+	}
+
+	// Without allow_svg, an enabled SVG rule still refuses SVG images
+	saved.AllowSVG = false
+	if _, err := saved.Check("image/svg+xml", "logo.svg", 1024); !errors.Is(err, ErrUploadNotAllowed) {
+		t.Errorf("Check() of an SVG without allow_svg = %v", err)
+	}
+}
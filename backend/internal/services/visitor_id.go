@@ -0,0 +1,114 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/security"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// VisitorService derives the IDs that article views and reading behavior
+// are attributed to, so unique visitors are counted the same way wherever
+// they are tracked. IDs are keyed hashes of what a request reveals about
+// its reader; the key is the configured salt or, with daily rotation, a
+// key derived from it for the current UTC day.
+type VisitorService struct {
+	now    func() time.Time
+	salt   []byte
+	daily  bool
+	strict bool
+}
+
+// NewVisitorService creates a visitor service from the tracking settings
+func NewVisitorService() *VisitorService {
+	cfg := config.Get().Tracking
+	s := &VisitorService{
+		now:    time.Now,
+		daily:  cfg.VisitorRotation == "daily" || cfg.StrictPrivacy,
+		strict: cfg.StrictPrivacy,
+	}
+	if cfg.VisitorSalt != "" {
+		salt, err := security.ResolveSecret(cfg.VisitorSalt)
+		if err != nil {
+			slog.Error("Failed to resolve visitor ID salt", "error", err)
+		} else {
+			s.salt = []byte(salt)
+		}
+	}
+	if s.salt == nil && s.daily {
+		// Rotating keys must not be derivable from public data
+		s.salt = make([]byte, 32)
+		if _, err := rand.Read(s.salt); err != nil {
+			panic(fmt.Sprintf("generate visitor ID salt: %v", err))
+		}
+		slog.Warn("Using a generated visitor ID salt; visitor IDs change on restart and differ between replicas. Set VISITOR_ID_SALT to keep them.")
+	}
+	return s
+}
+
+// StrictPrivacy reports whether tracking keeps only what counting needs
+func (s *VisitorService) StrictPrivacy() bool {
+	return s.strict
+}
+
+// ViewID identifies the visitor behind an article view by address and
+// user agent
+func (s *VisitorService) ViewID(ip, userAgent string) string {
+	sum := s.digest(fmt.Sprintf("%s|%s", ip, userAgent))
+	return hex.EncodeToString(sum)
+}
+
+// BehaviorID identifies the reader behind tracked behavior. Readers are
+// told apart by the session ID their browser keeps, except in strict
+// privacy mode, where they get the ID of their views instead, so they can
+// be followed no longer than the key lasts.
+func (s *VisitorService) BehaviorID(sessionID, deviceType, userAgent, ip string) string {
+	var sum []byte
+	if s.strict {
+		sum = s.digest(fmt.Sprintf("%s|%s", ip, userAgent))
+	} else {
+		sum = s.digest(fmt.Sprintf("%s:%s:%s", sessionID, deviceType, userAgent))
+	}
+	return fmt.Sprintf("user_%x", sum[:8])
+}
+
+// digest hashes data with the current key. Without a salt it is the plain
+// SHA-256 used before IDs were salted, which keeps existing IDs valid.
+func (s *VisitorService) digest(data string) []byte {
+	if s.salt == nil {
+		sum := sha256.Sum256([]byte(data))
+		return sum[:]
+	}
+	mac := hmac.New(sha256.New, s.key())
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// key is the salt, or the day's key derived from it
+func (s *VisitorService) key() []byte {
+	if !s.daily {
+		return s.salt
+	}
+	mac := hmac.New(sha256.New, s.salt)
+	mac.Write([]byte(s.now().UTC().Format("2006-01-02")))
+	return mac.Sum(nil)
+}
+
+var (
+	globalVisitorService     *VisitorService
+	globalVisitorServiceOnce sync.Once
+)
+
+// GetGlobalVisitorService returns the global visitor service
+func GetGlobalVisitorService() *VisitorService {
+	globalVisitorServiceOnce.Do(func() {
+		globalVisitorService = NewVisitorService()
+	})
+	return globalVisitorService
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVisitorServiceIDs(t *testing.T) {
+	now := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	t.Run("unsalted IDs are the plain hashes", func(t *testing.T) {
+		s := &VisitorService{now: clock}
+		want := fmt.Sprintf("%x", sha256.Sum256([]byte("203.0.113.7|Firefox")))
+		if got := s.ViewID("203.0.113.7", "Firefox"); got != want {
+			t.Fatalf("ViewID = %s, want %s", got, want)
+		}
+		behavior := sha256.Sum256([]byte("session_1:mobile:Firefox"))
+		if got := s.BehaviorID("session_1", "mobile", "Firefox", "203.0.113.7"); got != fmt.Sprintf("user_%x", behavior[:8]) {
+			t.Fatalf("BehaviorID = %s", got)
+		}
+	})
+
+	t.Run("salted IDs depend on the salt", func(t *testing.T) {
+		a := &VisitorService{now: clock, salt: []byte("one")}
+		b := &VisitorService{now: clock, salt: []byte("two")}
+		plain := &VisitorService{now: clock}
+		id := a.ViewID("203.0.113.7", "Firefox")
+		if len(id) != 64 {
+			t.Fatalf("ViewID has %d characters, want 64", len(id))
+		}
+		if id != a.ViewID("203.0.113.7", "Firefox") {
+			t.Fatal("ViewID is not stable")
+		}
+		if id == b.ViewID("203.0.113.7", "Firefox") || id == plain.ViewID("203.0.113.7", "Firefox") {
+			t.Fatal("ViewID does not depend on the salt")
+		}
+		now = now.Add(48 * time.Hour)
+		if id != a.ViewID("203.0.113.7", "Firefox") {
+			t.Fatal("ViewID changed without rotation")
+		}
+	})
+
+	t.Run("daily rotation changes IDs at UTC midnight", func(t *testing.T) {
+		now = time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+		s := &VisitorService{now: clock, salt: []byte("salt"), daily: true}
+		first := s.ViewID("203.0.113.7", "Firefox")
+		now = now.Add(59 * time.Minute)
+		if s.ViewID("203.0.113.7", "Firefox") != first {
+			t.Fatal("ViewID changed within the day")
+		}
+		now = now.Add(2 * time.Minute)
+		if s.ViewID("203.0.113.7", "Firefox") == first {
+			t.Fatal("ViewID did not change on the next day")
+		}
+	})
+
+	t.Run("strict privacy ignores the session", func(t *testing.T) {
+		s := &VisitorService{now: clock, salt: []byte("salt"), daily: true, strict: true}
+		one := s.BehaviorID("session_1", "mobile", "Firefox", "203.0.113.7")
+		if two := s.BehaviorID("session_2", "desktop", "Firefox", "203.0.113.7"); two != one {
+			t.Fatalf("BehaviorID = %s and %s for the same visitor", one, two)
+		}
+		if !strings.HasPrefix(s.ViewID("203.0.113.7", "Firefox"), strings.TrimPrefix(one, "user_")) {
+			t.Fatal("BehaviorID does not match the visitor's ViewID")
+		}
+		if s.BehaviorID("", "", "Firefox", "198.51.100.1") == one {
+			t.Fatal("BehaviorID is the same for different visitors")
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthCheckWarmup is the readiness check that holds traffic back until
+// the critical caches are warm
+const HealthCheckWarmup = "warmup"
+
+// warmupLoadPoll is how often a task waiting for the server to be less busy
+// checks again
+const warmupLoadPoll = 100 * time.Millisecond
+
+// Warm-up task states
+const (
+	WarmupPending = "pending"
+	WarmupRunning = "running"
+	WarmupDone    = "done"
+	WarmupFailed  = "failed"
+)
+
+// WarmupTask fills a cache at startup. Tasks with a lower Priority start
+// first; Critical ones hold /readyz back until they are done.
+type WarmupTask struct {
+	Name     string
+	Priority int
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// WarmupResult is the state of a warm-up task
+type WarmupResult struct {
+	Name       string `json:"name"`
+	Critical   bool   `json:"critical"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Warmup runs the warm-up tasks once, in the background after startup. It
+// yields to visitors: no task starts while more than MaxInFlight requests
+// are being served, and at most Concurrency tasks run at a time.
+type Warmup struct {
+	cfg config.WarmupConfig
+	now func() time.Time
+
+	mu        sync.Mutex
+	tasks     []WarmupTask
+	results   map[string]*WarmupResult
+	load      func() int
+	started   bool
+	startedAt time.Time
+	// critical counts the critical tasks not finished yet
+	critical int
+}
+
+// NewWarmup creates the warm-up from the WARMUP_* settings
+func NewWarmup() *Warmup {
+	return &Warmup{
+		cfg:     config.Get().Warmup,
+		now:     time.Now,
+		results: make(map[string]*WarmupResult),
+		load:    func() int { return 0 },
+	}
+}
+
+// Register adds a task. Tasks registered after Start are ignored.
+func (w *Warmup) Register(task WarmupTask) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started {
+		return
+	}
+	w.tasks = append(w.tasks, task)
+	w.results[task.Name] = &WarmupResult{Name: task.Name, Critical: task.Critical, Status: WarmupPending}
+}
+
+// SetLoad sets how the number of requests being served is read
+func (w *Warmup) SetLoad(load func() int) {
+	w.mu.Lock()
+	w.load = load
+	w.mu.Unlock()
+}
+
+// Start runs the tasks in the background. It does nothing when warm-up is
+// off or has started already.
+func (w *Warmup) Start() {
+	w.mu.Lock()
+	if w.started || !w.cfg.Enabled {
+		w.mu.Unlock()
+		return
+	}
+	w.started = true
+	w.startedAt = w.now()
+	tasks := append([]WarmupTask(nil), w.tasks...)
+	for _, task := range tasks {
+		if task.Critical {
+			w.critical++
+		}
+	}
+	w.mu.Unlock()
+
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].Priority < tasks[j].Priority })
+	go w.run(tasks)
+}
+
+func (w *Warmup) run(tasks []WarmupTask) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(w.cfg.Timeout))
+	defer cancel()
+	go func() {
+		select {
+		case <-ShuttingDown():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	started := w.now()
+	slots := make(chan struct{}, max(w.cfg.Concurrency, 1))
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		// Tasks start in order of priority, each once a slot is free and
+		// visitors leave room for it
+		acquired := false
+		select {
+		case slots <- struct{}{}:
+			acquired = true
+			w.waitForCapacity(ctx)
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			if acquired {
+				<-slots
+			}
+			w.finish(task, 0, fmt.Errorf("not started: %v", ctx.Err()))
+			continue
+		}
+		wg.Add(1)
+		go func(task WarmupTask) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			w.setStatus(task.Name, WarmupRunning)
+			taskStarted := w.now()
+			err := task.Run(ctx)
+			w.finish(task, w.now().Sub(taskStarted), err)
+		}(task)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, result := range w.Status() {
+		if result.Status == WarmupFailed {
+			failed++
+		}
+	}
+	slog.Info("Warm-up finished", "tasks", len(tasks), "failed", failed, "duration", w.now().Sub(started).Round(time.Millisecond).String())
+}
+
+// waitForCapacity returns once no more than MaxInFlight requests are being
+// served, or ctx is done
+func (w *Warmup) waitForCapacity(ctx context.Context) {
+	w.mu.Lock()
+	load := w.load
+	w.mu.Unlock()
+	for load() > w.cfg.MaxInFlight {
+		select {
+		case <-time.After(warmupLoadPoll):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *Warmup) setStatus(name, status string) {
+	w.mu.Lock()
+	w.results[name].Status = status
+	w.mu.Unlock()
+}
+
+func (w *Warmup) finish(task WarmupTask, took time.Duration, err error) {
+	w.mu.Lock()
+	result := w.results[task.Name]
+	result.Status = WarmupDone
+	result.DurationMS = took.Milliseconds()
+	if err != nil {
+		result.Status = WarmupFailed
+		result.Error = err.Error()
+	}
+	if task.Critical {
+		w.critical--
+	}
+	w.mu.Unlock()
+	if err != nil {
+		slog.Warn("Warm-up task failed", "task", task.Name, "error", err)
+	}
+}
+
+// Pending returns an error while critical tasks are still warming and the
+// timeout has not passed, for /readyz
+func (w *Warmup) Pending() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.started || w.critical == 0 || w.now().Sub(w.startedAt) >= time.Duration(w.cfg.Timeout) {
+		return nil
+	}
+	return fmt.Errorf("%d critical warm-up tasks pending", w.critical)
+}
+
+// Status returns the state of each task, in the order they were registered
+func (w *Warmup) Status() []WarmupResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	results := make([]WarmupResult, 0, len(w.tasks))
+	for _, task := range w.tasks {
+		results = append(results, *w.results[task.Name])
+	}
+	return results
+}
+
+var (
+	globalWarmup     *Warmup
+	globalWarmupOnce sync.Once
+)
+
+// GetGlobalWarmup returns the global warm-up
+func GetGlobalWarmup() *Warmup {
+	globalWarmupOnce.Do(func() {
+		globalWarmup = NewWarmup()
+	})
+	return globalWarmup
+}
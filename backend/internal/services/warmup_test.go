@@ -0,0 +1,129 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestWarmup(timeout time.Duration) *Warmup {
+	return &Warmup{
+		cfg: config.WarmupConfig{
+			Enabled:     true,
+			Concurrency: 1,
+			MaxInFlight: 2,
+			Timeout:     config.Duration(timeout),
+		},
+		now:     time.Now,
+		results: make(map[string]*WarmupResult),
+		load:    func() int { return 0 },
+	}
+}
+
+func waitForWarmup(t *testing.T, w *Warmup) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		finished := true
+		for _, result := range w.Status() {
+			if result.Status == WarmupPending || result.Status == WarmupRunning {
+				finished = false
+			}
+		}
+		if finished {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("warm-up did not finish: %+v", w.Status())
+}
+
+func TestWarmupRunsByPriorityBehindReadiness(t *testing.T) {
+	w := newTestWarmup(time.Minute)
+	var mu sync.Mutex
+	var order []string
+	release := make(chan struct{})
+	task := func(name string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			if name == "settings" {
+				<-release
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+	w.Register(WarmupTask{Name: "embeddings", Priority: 2, Run: task("embeddings")})
+	w.Register(WarmupTask{Name: "llms.txt", Priority: 1, Critical: true, Run: task("llms.txt")})
+	w.Register(WarmupTask{Name: "settings", Priority: 0, Critical: true, Run: task("settings")})
+
+	if err := w.Pending(); err != nil {
+		t.Errorf("Pending before start = %v", err)
+	}
+	w.Start()
+	if err := w.Pending(); err == nil {
+		t.Error("Pending = nil while critical tasks run")
+	}
+	close(release)
+	waitForWarmup(t, w)
+
+	if err := w.Pending(); err != nil {
+		t.Errorf("Pending after warm-up = %v", err)
+	}
+	if len(order) != 3 || order[0] != "settings" || order[1] != "llms.txt" || order[2] != "embeddings" {
+		t.Errorf("order = %v", order)
+	}
+	for _, result := range w.Status() {
+		if result.Status != WarmupDone {
+			t.Errorf("%s: %s", result.Name, result.Status)
+		}
+	}
+}
+
+func TestWarmupWaitsForLoad(t *testing.T) {
+	w := newTestWarmup(time.Minute)
+	var load atomic.Int64
+	load.Store(5)
+	w.SetLoad(func() int { return int(load.Load()) })
+	var ran atomic.Bool
+	w.Register(WarmupTask{Name: "settings", Critical: true, Run: func(context.Context) error {
+		ran.Store(true)
+		return nil
+	}})
+
+	w.Start()
+	time.Sleep(3 * warmupLoadPoll)
+	if ran.Load() {
+		t.Fatal("task started while the server was busy")
+	}
+	load.Store(1)
+	waitForWarmup(t, w)
+	if !ran.Load() {
+		t.Error("task did not run once the server was less busy")
+	}
+}
+
+func TestWarmupTimeout(t *testing.T) {
+	w := newTestWarmup(50 * time.Millisecond)
+	w.Register(WarmupTask{Name: "slow", Critical: true, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	w.Register(WarmupTask{Name: "never", Priority: 1, Critical: true, Run: func(context.Context) error { return nil }})
+
+	w.Start()
+	waitForWarmup(t, w)
+	// Readiness no longer waits once the timeout has passed
+	if err := w.Pending(); err != nil {
+		t.Errorf("Pending after the timeout = %v", err)
+	}
+	for _, result := range w.Status() {
+		if result.Status != WarmupFailed {
+			t.Errorf("%s: %s, want failed", result.Name, result.Status)
+		}
+	}
+}
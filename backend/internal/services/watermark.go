@@ -0,0 +1,315 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/imaging"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	watermarkCacheTTL   = 24 * time.Hour
+	maxWatermarkTextLen = 100
+)
+
+var ErrInvalidWatermarkPolicy = errors.New("invalid watermark policy")
+
+// WatermarkPolicy is how a site marks the media library images it serves.
+// The mark is Text, or the image of the media library with LogoMediaID
+// when set, placed at one of imaging.Positions and Size percent of the
+// image's width wide. Images smaller than MinWidth or MinHeight are left
+// alone.
+type WatermarkPolicy struct {
+	Enabled     bool    `json:"enabled"`
+	Text        string  `json:"text"`
+	LogoMediaID uint    `json:"logo_media_id"`
+	Position    string  `json:"position"`
+	Opacity     float64 `json:"opacity"`
+	Size        int     `json:"size"`
+	MinWidth    int     `json:"min_width"`
+	MinHeight   int     `json:"min_height"`
+}
+
+// DefaultWatermarkPolicy is the policy of sites that have not configured
+// one, which marks nothing until enabled
+func DefaultWatermarkPolicy() WatermarkPolicy {
+	return WatermarkPolicy{
+		Position:  "bottom-right",
+		Opacity:   0.5,
+		Size:      20,
+		MinWidth:  400,
+		MinHeight: 300,
+	}
+}
+
+// WatermarkedImage is an image as served, with its watermark
+type WatermarkedImage struct {
+	Data        []byte `json:"data"`
+	ContentType string `json:"content_type"`
+	// Tag changes with the watermark, for the ETag
+	Tag string `json:"tag"`
+}
+
+// siteWatermark is a site's policy with its logo loaded
+type siteWatermark struct {
+	policy WatermarkPolicy
+	logo   image.Image
+	tag    string
+}
+
+// WatermarkService stores each site's watermark policy in its settings and
+// marks images as they are served, so the files on disk stay the originals
+type WatermarkService struct {
+	db     func() *gorm.DB
+	marked *cache.Namespace
+
+	mu    sync.RWMutex
+	sites map[uint]*siteWatermark
+}
+
+// NewWatermarkService creates a watermark service
+func NewWatermarkService() *WatermarkService {
+	return &WatermarkService{
+		db:     func() *gorm.DB { return database.DB },
+		marked: cache.New("image_watermarks", watermarkCacheTTL),
+		sites:  make(map[uint]*siteWatermark),
+	}
+}
+
+// Get returns the site's watermark policy, or the default policy
+func (s *WatermarkService) Get(ctx context.Context) (*WatermarkPolicy, error) {
+	var settings models.SiteSettings
+	if err := s.db().WithContext(ctx).Select("watermark_policy").Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	policy := DefaultWatermarkPolicy()
+	if settings.WatermarkPolicy == "" {
+		return &policy, nil
+	}
+	if err := json.Unmarshal([]byte(settings.WatermarkPolicy), &policy); err != nil {
+		return nil, fmt.Errorf("stored watermark policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// Update validates a watermark policy given as JSON and saves it. Fields
+// left out keep their default.
+func (s *WatermarkService) Update(ctx context.Context, body io.Reader) (*WatermarkPolicy, error) {
+	policy := DefaultWatermarkPolicy()
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWatermarkPolicy, err)
+	}
+	policy.Text = strings.TrimSpace(policy.Text)
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+	if policy.LogoMediaID != 0 {
+		if _, err := s.loadLogo(ctx, policy.LogoMediaID); err != nil {
+			return nil, err
+		}
+	}
+
+	encoded, err := json.Marshal(policy)
+	if err != nil {
+		return nil, err
+	}
+	result := s.db().WithContext(ctx).Model(&models.SiteSettings{}).Where("1 = 1").Update("watermark_policy", string(encoded))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: the site has no settings yet", ErrInvalidWatermarkPolicy)
+	}
+	s.reset()
+	cache.Publish(cache.TopicSettings)
+	return &policy, nil
+}
+
+func (p *WatermarkPolicy) validate() error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidWatermarkPolicy}, args...)...)
+	}
+	if !contains(imaging.Positions, p.Position) {
+		return invalid("position: must be one of %s", strings.Join(imaging.Positions, ", "))
+	}
+	if p.Opacity < 0.05 || p.Opacity > 1 {
+		return invalid("opacity: must be between 0.05 and 1")
+	}
+	if p.Size < 1 || p.Size > 100 {
+		return invalid("size: must be between 1 and 100 percent of the image width")
+	}
+	if p.MinWidth < 0 || p.MinHeight < 0 {
+		return invalid("min_width, min_height: must not be negative")
+	}
+	if utf8.RuneCountInString(p.Text) > maxWatermarkTextLen {
+		return invalid("text: must be at most %d characters", maxWatermarkTextLen)
+	}
+	if p.Enabled && p.Text == "" && p.LogoMediaID == 0 {
+		return invalid("text or logo_media_id is required to enable watermarks")
+	}
+	return nil
+}
+
+// loadLogo reads an image of the site's media library to mark images with
+func (s *WatermarkService) loadLogo(ctx context.Context, id uint) (image.Image, error) {
+	var media models.MediaLibrary
+	if err := s.db().WithContext(ctx).First(&media, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: logo_media_id: no media with ID %d", ErrInvalidWatermarkPolicy, id)
+		}
+		return nil, err
+	}
+	if media.MediaType != models.MediaTypeImage || strings.Contains(media.FilePath, "://") {
+		return nil, fmt.Errorf("%w: logo_media_id: must be a locally stored PNG, JPEG or GIF image", ErrInvalidWatermarkPolicy)
+	}
+	file, err := os.Open(media.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: logo_media_id: %v", ErrInvalidWatermarkPolicy, err)
+	}
+	defer file.Close()
+	logo, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: logo_media_id: must be a locally stored PNG, JPEG or GIF image", ErrInvalidWatermarkPolicy)
+	}
+	return logo, nil
+}
+
+// current returns the site's policy and logo, loaded once until settings
+// change
+func (s *WatermarkService) current(ctx context.Context) (*siteWatermark, error) {
+	siteID, _ := database.SiteFromContext(ctx)
+	s.mu.RLock()
+	site, ok := s.sites[siteID]
+	s.mu.RUnlock()
+	if ok {
+		return site, nil
+	}
+
+	policy, err := s.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	site = &siteWatermark{policy: *policy}
+	if policy.Enabled && policy.LogoMediaID != 0 {
+		// A logo deleted since falls back to the text, or no watermark
+		if site.logo, err = s.loadLogo(ctx, policy.LogoMediaID); err != nil {
+			slog.Warn("Failed to load watermark logo", "media_id", policy.LogoMediaID, "error", err)
+			site.policy.Enabled = site.policy.Text != ""
+		}
+	}
+	encoded, _ := json.Marshal(site.policy)
+	sum := sha256.Sum256(fmt.Appendf(encoded, "|%t", site.logo != nil))
+	site.tag = hex.EncodeToString(sum[:6])
+
+	s.mu.Lock()
+	s.sites[siteID] = site
+	s.mu.Unlock()
+	return site, nil
+}
+
+func (s *WatermarkService) reset() {
+	s.mu.Lock()
+	s.sites = make(map[uint]*siteWatermark)
+	s.mu.Unlock()
+}
+
+// Apply returns the image in content, the file at name in the version its
+// ETag names, with the site's watermark. It returns nil when the image is
+// served as it is: watermarks are off, the image is below the size
+// threshold, or it is not a JPEG or PNG image. GIFs are left alone so
+// animations keep playing. content is read from the start.
+func (s *WatermarkService) Apply(ctx context.Context, name, version string, content io.ReadSeeker) (*WatermarkedImage, error) {
+	site, err := s.current(ctx)
+	if err != nil || !site.policy.Enabled {
+		return nil, err
+	}
+	siteID, _ := database.SiteFromContext(ctx)
+	key := fmt.Sprintf("%d|%s|%s|%s", siteID, name, version, site.tag)
+	var marked WatermarkedImage
+	if s.marked.Get(key, &marked) {
+		return &marked, nil
+	}
+
+	config, format, err := image.DecodeConfig(content)
+	if _, seekErr := content.Seek(0, io.SeekStart); seekErr != nil {
+		return nil, seekErr
+	}
+	if err != nil || (format != "jpeg" && format != "png") ||
+		config.Width < site.policy.MinWidth || config.Height < site.policy.MinHeight {
+		return nil, nil
+	}
+	img, _, err := image.Decode(content)
+	if _, seekErr := content.Seek(0, io.SeekStart); seekErr != nil {
+		return nil, seekErr
+	}
+	if err != nil {
+		// A file that does not decode is served as it is, as it would be
+		// without watermarks
+		return nil, nil
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+	width := max(1, bounds.Dx()*site.policy.Size/100)
+	var mark image.Image
+	if site.logo != nil {
+		logo := site.logo.Bounds()
+		mark = imaging.Resize(site.logo, width, max(1, logo.Dy()*width/logo.Dx()))
+	} else {
+		mark = imaging.Text(site.policy.Text, width)
+	}
+	imaging.Stamp(dst, mark, site.policy.Position, site.policy.Opacity)
+
+	var out bytes.Buffer
+	if format == "jpeg" {
+		marked.ContentType = "image/jpeg"
+		err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: 90})
+	} else {
+		marked.ContentType = "image/png"
+		err = png.Encode(&out, dst)
+	}
+	if err != nil {
+		return nil, err
+	}
+	marked.Data = out.Bytes()
+	marked.Tag = "w" + site.tag
+	s.marked.Set(key, marked)
+	return &marked, nil
+}
+
+var (
+	globalWatermarkService     *WatermarkService
+	globalWatermarkServiceOnce sync.Once
+)
+
+// GetGlobalWatermarkService returns the global watermark service
+func GetGlobalWatermarkService() *WatermarkService {
+	globalWatermarkServiceOnce.Do(func() {
+		globalWatermarkService = NewWatermarkService()
+		cache.Subscribe(cache.TopicSettings, globalWatermarkService.reset)
+	})
+	return globalWatermarkService
+}
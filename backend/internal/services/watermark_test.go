@@ -0,0 +1,158 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// solidImage is an image of one color, encoded with encode
+func solidImage(t *testing.T, width, height int, c color.Color, encode func(*bytes.Buffer, image.Image) error) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) }
+
+// changedIn counts the pixels of marked that differ from color c in rect
+func changedIn(marked image.Image, c color.Color, rect image.Rectangle) int {
+	r0, g0, b0, _ := c.RGBA()
+	changed := 0
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			r, g, b, _ := marked.At(x, y).RGBA()
+			if r != r0 || g != g0 || b != b0 {
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+func TestWatermarkPolicy(t *testing.T) {
+	newTestDB(t)
+	ctx := context.Background()
+	if err := database.DB.Create(&models.SiteSettings{SiteTitle: "Blog"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := &WatermarkService{db: func() *gorm.DB { return database.DB }, marked: cache.New("test_watermarks", time.Minute), sites: map[uint]*siteWatermark{}}
+
+	policy, err := s.Get(ctx)
+	if err != nil || policy.Enabled || policy.Position != "bottom-right" {
+		t.Fatalf("default policy = %+v, %v", policy, err)
+	}
+
+	for _, body := range []string{
+		`{"enabled": true}`,
+		`{"text": "x", "position": "middle"}`,
+		`{"text": "x", "opacity": 0}`,
+		`{"text": "x", "size": 150}`,
+		`{"text": "x", "min_width": -1}`,
+		`{"text": "x", "logo_media_id": 99}`,
+		`{"text": "` + strings.Repeat("x", 101) + `"}`,
+		`{"text": "x", "color": "red"}`,
+	} {
+		if _, err := s.Update(ctx, strings.NewReader(body)); !errors.Is(err, ErrInvalidWatermarkPolicy) {
+			t.Errorf("Update(%s) error = %v", body, err)
+		}
+	}
+
+	blue := color.NRGBA{R: 30, G: 90, B: 200, A: 255}
+	photo := solidImage(t, 800, 600, blue, encodePNG)
+	if marked, err := s.Apply(ctx, "/images/photo.png", "v1", bytes.NewReader(photo)); err != nil || marked != nil {
+		t.Fatalf("Apply() while disabled = %v, %v", marked, err)
+	}
+
+	policy, err = s.Update(ctx, strings.NewReader(`{"enabled": true, "text": " kuno.example ", "opacity": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Text != "kuno.example" || policy.MinWidth != 400 {
+		t.Errorf("saved policy = %+v, want trimmed text and default threshold", policy)
+	}
+
+	content := bytes.NewReader(photo)
+	marked, err := s.Apply(ctx, "/images/photo.png", "v1", content)
+	if err != nil || marked == nil {
+		t.Fatalf("Apply() = %v, %v", marked, err)
+	}
+	if marked.ContentType != "image/png" || marked.Tag == "" {
+		t.Errorf("watermarked image = %s, tag %q", marked.ContentType, marked.Tag)
+	}
+	if pos, _ := content.Seek(0, io.SeekCurrent); pos != 0 {
+		t.Errorf("content left at %d, want rewound", pos)
+	}
+	img, err := png.Decode(bytes.NewReader(marked.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := changedIn(img, blue, image.Rect(400, 450, 800, 600)); n == 0 {
+		t.Error("no watermark in the bottom right corner")
+	}
+	if n := changedIn(img, blue, image.Rect(0, 0, 400, 300)); n != 0 {
+		t.Errorf("%d pixels changed away from the watermark", n)
+	}
+
+	// Small images, GIFs and files that are not images are served as they are
+	small := solidImage(t, 300, 200, blue, encodePNG)
+	animated := solidImage(t, 800, 600, blue, func(buf *bytes.Buffer, img image.Image) error { return gif.Encode(buf, img, nil) })
+	for name, data := range map[string][]byte{"small.png": small, "anim.gif": animated, "notes.txt": []byte("hello")} {
+		if marked, err := s.Apply(ctx, "/images/"+name, "v1", bytes.NewReader(data)); err != nil || marked != nil {
+			t.Errorf("Apply(%s) = %v, %v, want no watermark", name, marked, err)
+		}
+	}
+
+	// A logo from the media library replaces the text
+	dir := t.TempDir()
+	logoPath := filepath.Join(dir, "logo.png")
+	if err := os.WriteFile(logoPath, solidImage(t, 40, 20, color.NRGBA{R: 255, A: 255}, encodePNG), 0644); err != nil {
+		t.Fatal(err)
+	}
+	logo := models.MediaLibrary{FileName: "logo.png", OriginalName: "logo.png", FilePath: logoPath, MimeType: "image/png", MediaType: models.MediaTypeImage, URL: "/uploads/images/logo.png"}
+	database.DB.Create(&logo)
+	body := `{"enabled": true, "logo_media_id": ` + strconv.FormatUint(uint64(logo.ID), 10) + `, "position": "top-left", "opacity": 1, "size": 25}`
+	if _, err := s.Update(ctx, strings.NewReader(body)); err != nil {
+		t.Fatal(err)
+	}
+	withLogo, err := s.Apply(ctx, "/images/photo.png", "v1", bytes.NewReader(photo))
+	if err != nil || withLogo == nil {
+		t.Fatalf("Apply() with logo = %v, %v", withLogo, err)
+	}
+	if withLogo.Tag == marked.Tag {
+		t.Error("the tag did not change with the policy")
+	}
+	img, _ = png.Decode(bytes.NewReader(withLogo.Data))
+	// 25% of 800 pixels wide, so 200 by 100, after a margin of 15
+	if r, g, b, _ := img.At(100, 60).RGBA(); r>>8 != 255 || g != 0 || b != 0 {
+		t.Errorf("pixel inside the logo = %d,%d,%d, want red", r>>8, g>>8, b>>8)
+	}
+	if n := changedIn(img, blue, image.Rect(0, 200, 800, 600)); n != 0 {
+		t.Errorf("%d pixels changed below the logo", n)
+	}
+}
@@ -0,0 +1,648 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// webmentionMaxLinks bounds the pages one article sends mentions to
+	webmentionMaxLinks = 50
+	// webmentionExcerpt is the length in runes a mentioning page is quoted at
+	webmentionExcerpt   = 500
+	webmentionUserAgent = "kuno-webmention"
+)
+
+var ErrInvalidWebmention = errors.New("invalid webmention")
+
+var (
+	// linkHeaderPattern splits a Link header into its addresses and their
+	// parameters
+	linkHeaderPattern = regexp.MustCompile(`<([^>]*)>([^<]*)`)
+	linkRelPattern    = regexp.MustCompile(`(?i)\brel\s*=\s*(?:"([^"]*)"|([^\s;,]+))`)
+	// linkMarkdown finds the links of an article, bare addresses included
+	linkMarkdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+)
+
+// webmentionDelivery is the job payload for JobWebmentionSend
+type webmentionDelivery struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// webmentionReceipt is the job payload for JobWebmentionVerify
+type webmentionReceipt struct {
+	SiteID    uint   `json:"site_id"`
+	ArticleID uint   `json:"article_id"`
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+}
+
+// webmentionPage is a page fetched from another site, its body decoded to
+// UTF-8 when it is HTML
+type webmentionPage struct {
+	Status int
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+	HTML   bool
+}
+
+// webmentionSource is what a mentioning page tells about itself, from its
+// h-entry microformats or else its meta tags
+type webmentionSource struct {
+	AuthorName string
+	AuthorURL  string
+	Content    string // plain text
+	URL        string
+	Published  time.Time
+}
+
+// WebmentionService sends and receives Webmentions
+// (https://www.w3.org/TR/webmention/). Published and updated articles
+// notify the pages they link to that have an endpoint. Mentions of the
+// site's articles are verified in the background, by fetching the page
+// sending them and finding the link, and are then stored as pending
+// replies, moderated and shown with the other comments. A page that stops
+// linking or is gone takes its mention with it.
+type WebmentionService struct {
+	db       func() *gorm.DB
+	client   *http.Client
+	checkURL func(*url.URL) error
+	enabled  func() bool
+	baseURL  func(siteID uint) string
+	now      func() time.Time
+}
+
+// NewWebmentionService creates a Webmention service that only fetches
+// public addresses
+func NewWebmentionService() *WebmentionService {
+	return &WebmentionService{
+		db:       func() *gorm.DB { return database.DB },
+		client:   newPublicClient(),
+		checkURL: checkPreviewURL,
+		enabled:  func() bool { return GetGlobalFeatureService().Enabled(FeatureWebmention) },
+		baseURL:  siteBaseURL,
+		now:      time.Now,
+	}
+}
+
+// Receive accepts a mention of target, one of the articles of the site in
+// ctx served at base, by the page at source. The source is verified by a
+// job; the same mention sent again within the hour is verified once.
+func (s *WebmentionService) Receive(ctx context.Context, siteID uint, base, source, target string) error {
+	sourceURL, err := ParsePreviewURL(source)
+	if err != nil {
+		return fmt.Errorf("%w: source must be an http(s) URL", ErrInvalidWebmention)
+	}
+	if err := s.checkURL(sourceURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebmention, err)
+	}
+	targetURL, err := url.Parse(strings.TrimSpace(target))
+	if err != nil || targetURL.Host == "" {
+		return fmt.Errorf("%w: target must be an http(s) URL", ErrInvalidWebmention)
+	}
+	targetURL.Fragment, targetURL.RawFragment = "", ""
+	if sourceURL.String() == targetURL.String() {
+		return fmt.Errorf("%w: source and target are the same page", ErrInvalidWebmention)
+	}
+	articleID, err := s.targetArticle(ctx, base, targetURL)
+	if err != nil {
+		return err
+	}
+
+	receipt := webmentionReceipt{SiteID: siteID, ArticleID: articleID, Source: sourceURL.String(), Target: targetURL.String()}
+	key := fmt.Sprintf("webmention:%s:%d", segmentHash(receipt.Source+" "+receipt.Target), s.now().Unix()/3600)
+	_, err = GetGlobalJobQueue().EnqueueUnique(JobWebmentionVerify, receipt, key)
+	if errors.Is(err, ErrJobExists) {
+		return nil
+	}
+	return err
+}
+
+// targetArticle returns the published article of the site in ctx that a
+// page address names, by ID or slug, with or without a language prefix
+func (s *WebmentionService) targetArticle(ctx context.Context, base string, target *url.URL) (uint, error) {
+	notArticle := fmt.Errorf("%w: target is not an article of this site", ErrInvalidWebmention)
+	root, err := url.Parse(base)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || !strings.EqualFold(target.Host, root.Host) {
+		return 0, notArticle
+	}
+	prefix := strings.TrimRight(root.Path, "/") + "/"
+	if !strings.HasPrefix(target.Path, prefix) {
+		return 0, notArticle
+	}
+	segments := strings.FieldsFunc(strings.TrimPrefix(target.Path, prefix), func(r rune) bool { return r == '/' })
+	language := ""
+	if len(segments) == 3 {
+		language, segments = segments[0], segments[1:]
+	}
+	if len(segments) != 2 || segments[0] != "article" {
+		return 0, notArticle
+	}
+
+	articleID, err := strconv.ParseUint(segments[1], 10, 32)
+	if err != nil {
+		resolved, _, err := ResolveArticleSlug(s.db().WithContext(ctx), segments[1], language)
+		if err != nil {
+			return 0, err
+		}
+		articleID = uint64(resolved)
+	}
+	var article models.Article
+	if err := s.db().WithContext(ctx).Select("id", "created_at").Limit(1).Find(&article, articleID).Error; err != nil {
+		return 0, err
+	}
+	if article.ID == 0 || article.CreatedAt.After(s.now()) {
+		return 0, notArticle
+	}
+	return article.ID, nil
+}
+
+// Verify fetches the source of a received mention and stores, updates or
+// removes the mention depending on whether the page still links to the
+// article. Unreachable sources and server errors are retried.
+func (s *WebmentionService) Verify(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var receipt webmentionReceipt
+	if err := json.Unmarshal(payload, &receipt); err != nil {
+		return nil, err
+	}
+	objectID := fmt.Sprintf("webmention:%d:%s", receipt.ArticleID, receipt.Source)
+	remove := func(reason string) (interface{}, error) {
+		result := s.db().Where("site_id = ? AND object_id = ?", receipt.SiteID, objectID).Delete(&models.FederatedReply{})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		return map[string]interface{}{"skipped": reason, "removed": result.RowsAffected}, nil
+	}
+
+	page, err := s.fetch(ctx, receipt.Source)
+	switch {
+	case errors.Is(err, ErrPreviewBlocked), errors.Is(err, ErrInvalidPreviewURL):
+		return map[string]string{"skipped": err.Error()}, nil
+	case err != nil:
+		return nil, err
+	case page.Status == http.StatusGone:
+		return remove("source is gone")
+	case page.Status >= 500:
+		return nil, fmt.Errorf("source answered %d", page.Status)
+	case page.Status != http.StatusOK:
+		return map[string]string{"skipped": fmt.Sprintf("source answered %d", page.Status)}, nil
+	}
+	mention, linked := parseWebmentionSource(page, receipt.Target)
+	if !linked {
+		return remove("source does not link to the target")
+	}
+
+	reply := models.FederatedReply{
+		SiteID:      receipt.SiteID,
+		ArticleID:   receipt.ArticleID,
+		Source:      models.ReplySourceWebmention,
+		ObjectID:    objectID,
+		ActorID:     mention.AuthorURL,
+		AuthorName:  mention.AuthorName,
+		AuthorURL:   mention.AuthorURL,
+		URL:         mention.URL,
+		Status:      models.ReplyPending,
+		PublishedAt: mention.Published,
+	}
+	if reply.ActorID == "" {
+		reply.ActorID = page.URL.Scheme + "://" + page.URL.Host
+	}
+	if reply.AuthorName == "" {
+		reply.AuthorName = page.URL.Hostname()
+	}
+	if reply.URL == "" {
+		reply.URL = receipt.Source
+	}
+	if reply.PublishedAt.IsZero() {
+		reply.PublishedAt = s.now()
+	}
+	if mention.Content != "" {
+		reply.Content = "<p>" + html.EscapeString(truncateRunes(mention.Content, webmentionExcerpt)) + "</p>"
+	}
+
+	// A mention sent again is an update of the page, which keeps its
+	// moderation state
+	updated := s.db().Model(&models.FederatedReply{}).Where("site_id = ? AND object_id = ?", receipt.SiteID, objectID).
+		Updates(map[string]interface{}{
+			"actor_id": reply.ActorID, "author_name": reply.AuthorName, "author_url": reply.AuthorURL,
+			"content": reply.Content, "url": reply.URL,
+		})
+	if updated.Error != nil {
+		return nil, updated.Error
+	}
+	if updated.RowsAffected > 0 {
+		return map[string]string{"updated": objectID}, nil
+	}
+	created := s.db().Clauses(clause.OnConflict{DoNothing: true}).Create(&reply)
+	if created.Error != nil {
+		return nil, created.Error
+	}
+	if created.RowsAffected > 0 {
+		hooks.Notify(ctx, hooks.CommentPending, &reply)
+	}
+	return map[string]interface{}{"reply_id": reply.ID}, nil
+}
+
+// Mentions returns the approved mentions of an article of the site in ctx,
+// newest first
+func (s *WebmentionService) Mentions(ctx context.Context, articleID uint) ([]models.FederatedReply, error) {
+	var replies []models.FederatedReply
+	err := s.db().WithContext(ctx).Where("article_id = ? AND source = ? AND status = ?",
+		articleID, models.ReplySourceWebmention, models.ReplyApproved).Order("published_at DESC").Find(&replies).Error
+	return replies, err
+}
+
+// registerHooks sends mentions for published and updated articles
+func (s *WebmentionService) registerHooks() {
+	hooks.Register(hooks.ArticlePublished, "webmention", s.dispatch)
+	hooks.Register(hooks.ArticleUpdated, "webmention", s.dispatch)
+}
+
+// dispatch queues a mention of every page an article links to. Members-only
+// articles are not announced, as the pages they link to could not read
+// them to verify the mention.
+func (s *WebmentionService) dispatch(ctx context.Context, event hooks.Event, payload interface{}) error {
+	article, ok := payload.(*models.Article)
+	if !ok || !s.enabled() || article.MembersOnly {
+		return nil
+	}
+	base := s.baseURL(article.SiteID)
+	if base == "" {
+		return nil
+	}
+	source := localizedArticleLink(base, article.DefaultLang, article.ID)
+	for _, target := range articleLinks(article, base) {
+		if _, err := GetGlobalJobQueue().Enqueue(JobWebmentionSend, webmentionDelivery{Source: source, Target: target}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// articleLinks returns the addresses on other sites that an article and
+// its translations link to, at most webmentionMaxLinks of them
+func articleLinks(article *models.Article, base string) []string {
+	own, _ := url.Parse(base)
+	sources := []string{article.Content}
+	for _, translation := range article.Translations {
+		sources = append(sources, translation.Content)
+	}
+	seen := make(map[string]bool)
+	var links []string
+	for _, source := range sources {
+		src := []byte(source)
+		doc := linkMarkdown.Parser().Parse(text.NewReader(src))
+		ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering || len(links) >= webmentionMaxLinks {
+				return ast.WalkContinue, nil
+			}
+			var destination string
+			switch link := node.(type) {
+			case *ast.Link:
+				destination = string(link.Destination)
+			case *ast.AutoLink:
+				if link.AutoLinkType == ast.AutoLinkURL {
+					destination = string(link.URL(src))
+				}
+			}
+			target, err := ParsePreviewURL(destination)
+			if err != nil || (own != nil && strings.EqualFold(target.Host, own.Host)) || seen[target.String()] {
+				return ast.WalkContinue, nil
+			}
+			seen[target.String()] = true
+			links = append(links, target.String())
+			return ast.WalkContinue, nil
+		})
+	}
+	return links
+}
+
+// Send discovers the Webmention endpoint of a linked page and notifies it.
+// Pages without an endpoint are skipped; unreachable pages and endpoints
+// and server errors are retried.
+func (s *WebmentionService) Send(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var delivery webmentionDelivery
+	if err := json.Unmarshal(payload, &delivery); err != nil {
+		return nil, err
+	}
+	endpoint, err := s.discover(ctx, delivery.Target)
+	switch {
+	case errors.Is(err, ErrPreviewBlocked), errors.Is(err, ErrInvalidPreviewURL):
+		return map[string]string{"skipped": err.Error()}, nil
+	case err != nil:
+		return nil, err
+	case endpoint == nil:
+		return map[string]string{"skipped": "target has no webmention endpoint"}, nil
+	}
+	if err := s.checkURL(endpoint); err != nil {
+		return map[string]string{"skipped": err.Error()}, nil
+	}
+
+	form := url.Values{"source": {delivery.Source}, "target": {delivery.Target}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", webmentionUserAgent)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	switch {
+	case resp.StatusCode >= 500:
+		return nil, fmt.Errorf("endpoint answered %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	case resp.StatusCode >= 300:
+		return map[string]interface{}{"endpoint": endpoint.String(), "rejected": resp.StatusCode, "error": strings.TrimSpace(string(detail))}, nil
+	}
+	return map[string]interface{}{"endpoint": endpoint.String(), "status": resp.StatusCode}, nil
+}
+
+// discover returns the Webmention endpoint a page names in its Link
+// headers or, for HTML, the first <link> or <a> with rel="webmention"
+func (s *WebmentionService) discover(ctx context.Context, target string) (*url.URL, error) {
+	page, err := s.fetch(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	if page.Status >= 500 {
+		return nil, fmt.Errorf("target answered %d", page.Status)
+	}
+	if page.Status != http.StatusOK {
+		return nil, nil
+	}
+	href, found := "", false
+	for _, value := range page.Header.Values("Link") {
+		for _, link := range linkHeaderPattern.FindAllStringSubmatch(value, -1) {
+			if rel := linkRelPattern.FindStringSubmatch(link[2]); rel != nil && isWebmentionRel(rel[1]+rel[2]) {
+				href, found = link[1], true
+				break
+			}
+		}
+		if found {
+			break
+		}
+	}
+	if !found && page.HTML {
+		doc, err := html.Parse(bytes.NewReader(page.Body))
+		if err != nil {
+			return nil, err
+		}
+		node := findNode(doc, func(n *html.Node) bool {
+			_, ok := nodeAttr(n, "href")
+			return (n.Data == "link" || n.Data == "a") && ok && isWebmentionRel(attrValue(n, "rel"))
+		})
+		if node != nil {
+			href, found = attrValue(node, "href"), true
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	// An empty href is the page itself
+	return page.URL.Parse(strings.TrimSpace(href))
+}
+
+// fetch gets a page another site names, with at most linkPreviewMaxBody of
+// its body
+func (s *WebmentionService) fetch(ctx context.Context, address string) (*webmentionPage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", webmentionUserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.5")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	contentType := resp.Header.Get("Content-Type")
+	page := &webmentionPage{Status: resp.StatusCode, URL: resp.Request.URL, Header: resp.Header, HTML: strings.Contains(contentType, "html")}
+	var body io.Reader = io.LimitReader(resp.Body, linkPreviewMaxBody)
+	if page.HTML {
+		if body, err = charset.NewReader(body, contentType); err != nil {
+			return nil, err
+		}
+	}
+	if page.Body, err = io.ReadAll(body); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// parseWebmentionSource reports whether a page links to target and reads
+// who wrote it and what it says. Pages other than HTML only need to
+// contain the address.
+func parseWebmentionSource(page *webmentionPage, target string) (webmentionSource, bool) {
+	var source webmentionSource
+	if !page.HTML {
+		return source, bytes.Contains(page.Body, []byte(target))
+	}
+	doc, err := html.Parse(bytes.NewReader(page.Body))
+	if err != nil {
+		return source, false
+	}
+	linked := findNode(doc, func(n *html.Node) bool {
+		for _, name := range []string{"href", "src"} {
+			if value, ok := nodeAttr(n, name); ok {
+				if resolved, err := page.URL.Parse(strings.TrimSpace(value)); err == nil {
+					resolved.Fragment, resolved.RawFragment = "", ""
+					if resolved.String() == target {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}) != nil
+	if !linked {
+		return source, false
+	}
+
+	absolute := func(value string) string {
+		resolved, err := page.URL.Parse(strings.TrimSpace(value))
+		if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") {
+			return ""
+		}
+		return resolved.String()
+	}
+	if entry := findNode(doc, func(n *html.Node) bool { return hasClass(n, "h-entry") }); entry != nil {
+		for _, class := range []string{"e-content", "p-summary", "p-name"} {
+			if property := findProperty(entry, class); property != nil {
+				source.Content = nodeText(property)
+				break
+			}
+		}
+		if author := findProperty(entry, "p-author"); author != nil {
+			source.AuthorName = nodeText(author)
+			if name := findProperty(author, "p-name"); name != nil {
+				source.AuthorName = nodeText(name)
+			}
+			if link := findProperty(author, "u-url"); link != nil {
+				source.AuthorURL = absolute(attrValue(link, "href"))
+			} else if author.Data == "a" {
+				source.AuthorURL = absolute(attrValue(author, "href"))
+			}
+		}
+		if link := findProperty(entry, "u-url"); link != nil {
+			source.URL = absolute(attrValue(link, "href"))
+		}
+		if published := findProperty(entry, "dt-published"); published != nil {
+			value := attrValue(published, "datetime")
+			if value == "" {
+				value = nodeText(published)
+			}
+			source.Published, _ = time.Parse(time.RFC3339, value)
+		}
+	}
+
+	meta, title, _ := parsePageHead(page.Body)
+	if source.Content == "" {
+		if source.Content = strings.Join(strings.Fields(meta["og:description"]), " "); source.Content == "" {
+			if source.Content = strings.Join(strings.Fields(meta["description"]), " "); source.Content == "" {
+				source.Content = strings.Join(strings.Fields(title), " ")
+			}
+		}
+	}
+	if source.AuthorName == "" {
+		source.AuthorName = strings.TrimSpace(meta["author"])
+	}
+	return source, true
+}
+
+func isWebmentionRel(rel string) bool {
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		if value == "webmention" {
+			return true
+		}
+	}
+	return false
+}
+
+// findNode returns the first element under n, in document order, that
+// match accepts
+func findNode(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findNode(child, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findProperty returns the first element under root with a microformats
+// property class, without looking into nested microformats, which hold
+// their own properties
+func findProperty(root *html.Node, class string) *html.Node {
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if hasClass(child, class) {
+			return child
+		}
+		nested := false
+		for _, name := range strings.Fields(attrValue(child, "class")) {
+			nested = nested || strings.HasPrefix(name, "h-")
+		}
+		if !nested {
+			if found := findProperty(child, class); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, name := range strings.Fields(attrValue(n, "class")) {
+		if name == class {
+			return true
+		}
+	}
+	return false
+}
+
+func nodeAttr(n *html.Node, name string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Namespace == "" && attr.Key == name {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+func attrValue(n *html.Node, name string) string {
+	value, _ := nodeAttr(n, name)
+	return value
+}
+
+// nodeText returns the text of an element with its whitespace collapsed,
+// leaving out scripts and styles
+func nodeText(n *html.Node) string {
+	var text strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			text.WriteString(n.Data)
+			text.WriteByte(' ')
+		case n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style"):
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+var (
+	globalWebmentionService *WebmentionService
+	webmentionServiceOnce   sync.Once
+)
+
+// GetGlobalWebmentionService returns the global Webmention service,
+// registering its publish and update hooks on first use
+func GetGlobalWebmentionService() *WebmentionService {
+	webmentionServiceOnce.Do(func() {
+		globalWebmentionService = NewWebmentionService()
+		globalWebmentionService.registerHooks()
+	})
+	return globalWebmentionService
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestWebmentionReceive(t *testing.T) {
+	newTestDB(t)
+	source := `<html><head><title>Reply</title></head><body>
+<article class="h-entry">
+  <a class="p-author h-card" href="/about"><span class="p-name">Grace</span><img class="u-photo" src="/me.png"></a>
+  <a class="u-url" href="/notes/1">#</a>
+  <time class="dt-published" datetime="2026-02-01T10:00:00Z">Feb 1</time>
+  <div class="e-content">Great <b>post</b>, see <a href="%s">this article</a>.<script>alert(1)</script></div>
+</article></body></html>`
+	var page string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		fmt.Fprint(w, page)
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	s := &WebmentionService{
+		db:       func() *gorm.DB { return database.DB },
+		client:   server.Client(),
+		checkURL: func(*url.URL) error { return nil },
+		enabled:  func() bool { return true },
+		baseURL:  func(uint) string { return "https://blog.example.com" },
+		now:      func() time.Time { return now },
+	}
+	ctx := context.Background()
+	article := models.Article{Title: "Hello", DefaultLang: "en", SEOSlug: "hello", CreatedAt: now.Add(-time.Hour)}
+	database.DB.Create(&article)
+	scheduled := models.Article{Title: "Later", DefaultLang: "en", CreatedAt: now.Add(time.Hour)}
+	database.DB.Create(&scheduled)
+
+	target := "https://blog.example.com/en/article/hello"
+	for _, mention := range [][2]string{
+		{"ftp://example.com/post", target},
+		{server.URL + "/post", "https://other.example.com/en/article/hello"},
+		{server.URL + "/post", "https://blog.example.com/en/about"},
+		{server.URL + "/post", fmt.Sprintf("https://blog.example.com/article/%d", scheduled.ID)},
+		{target, target},
+	} {
+		if err := s.Receive(ctx, 1, "https://blog.example.com", mention[0], mention[1]); !errors.Is(err, ErrInvalidWebmention) {
+			t.Errorf("expected %s -> %s to be refused, got %v", mention[0], mention[1], err)
+		}
+	}
+	// The same mention sent twice is verified once
+	for i := 0; i < 2; i++ {
+		if err := s.Receive(ctx, 1, "https://blog.example.com", server.URL+"/post#reply", target+"#comments"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var jobs []models.Job
+	database.DB.Where("type = ?", JobWebmentionVerify).Find(&jobs)
+	if len(jobs) != 1 {
+		t.Fatalf("expected one verification, got %+v", jobs)
+	}
+	var receipt webmentionReceipt
+	json.Unmarshal([]byte(jobs[0].Payload), &receipt)
+	if receipt.ArticleID != article.ID || receipt.Source != server.URL+"/post" || receipt.Target != target {
+		t.Errorf("unexpected receipt %+v", receipt)
+	}
+
+	payload := json.RawMessage(jobs[0].Payload)
+	page = fmt.Sprintf(source, target)
+	if _, err := s.Verify(ctx, payload); err != nil {
+		t.Fatal(err)
+	}
+	var reply models.FederatedReply
+	database.DB.Where("source = ?", models.ReplySourceWebmention).First(&reply)
+	if reply.ArticleID != article.ID || reply.Status != models.ReplyPending || reply.AuthorName != "Grace" ||
+		reply.AuthorURL != server.URL+"/about" || reply.URL != server.URL+"/notes/1" ||
+		reply.Content != "<p>Great post , see this article .</p>" || !reply.PublishedAt.Equal(time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected mention %+v", reply)
+	}
+
+	// Approved mentions are listed with the article, and stay approved when
+	// the page changes
+	database.DB.Model(&reply).Update("status", models.ReplyApproved)
+	page = strings.Replace(fmt.Sprintf(source, target), "Great", "Fine", 1)
+	if _, err := s.Verify(ctx, payload); err != nil {
+		t.Fatal(err)
+	}
+	mentions, err := s.Mentions(ctx, article.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mentions) != 1 || mentions[0].Status != models.ReplyApproved || !strings.HasPrefix(mentions[0].Content, "<p>Fine") {
+		t.Errorf("unexpected mentions %+v", mentions)
+	}
+
+	// Server errors are retried and other failures are not
+	status = http.StatusBadGateway
+	if _, err := s.Verify(ctx, payload); err == nil {
+		t.Error("expected a failing source to be retried")
+	}
+	status = http.StatusNotFound
+	if _, err := s.Verify(ctx, payload); err != nil {
+		t.Errorf("expected a missing source not to be retried, got %v", err)
+	}
+
+	// A page that no longer links drops its mention
+	status = http.StatusOK
+	page = fmt.Sprintf(source, "https://elsewhere.example.com/")
+	if _, err := s.Verify(ctx, payload); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	database.DB.Model(&models.FederatedReply{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected the mention to be removed, %d left", count)
+	}
+}
+
+func TestWebmentionSend(t *testing.T) {
+	newTestDB(t)
+	var received []url.Values
+	mux := http.NewServeMux()
+	mux.HandleFunc("/header", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Link", `<https://example.com/other>; rel="me", </endpoint?via=header>; rel="webmention"`)
+		fmt.Fprint(w, "<html></html>")
+	})
+	mux.HandleFunc("/html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><link rel="stylesheet" href="/style.css"></head><body><a rel="nofollow webmention" href="endpoint?via=html">mention</a></body></html>`)
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><body>No endpoint</body></html>`)
+	})
+	mux.HandleFunc("/endpoint", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form := r.PostForm
+		form.Set("via", r.URL.Query().Get("via"))
+		received = append(received, form)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	enabled := true
+	s := &WebmentionService{
+		db:       func() *gorm.DB { return database.DB },
+		client:   server.Client(),
+		checkURL: func(*url.URL) error { return nil },
+		enabled:  func() bool { return enabled },
+		baseURL:  func(uint) string { return "https://blog.example.com" },
+		now:      time.Now,
+	}
+	ctx := context.Background()
+	article := models.Article{
+		DefaultLang: "en",
+		Content: fmt.Sprintf("See [one](%s/header), <%s/html> and [again](%s/header#top).\n\n[Home](https://blog.example.com/about) and [mail](mailto:a@example.com)",
+			server.URL, server.URL, server.URL),
+		Translations: []models.ArticleTranslation{{Language: "zh", Content: fmt.Sprintf("[无](%s/plain)", server.URL)}},
+	}
+	database.DB.Create(&article)
+
+	if err := s.dispatch(ctx, hooks.ArticlePublished, &article); err != nil {
+		t.Fatal(err)
+	}
+	var jobs []models.Job
+	database.DB.Where("type = ?", JobWebmentionSend).Order("id").Find(&jobs)
+	if len(jobs) != 3 {
+		t.Fatalf("expected a mention per linked page on other sites, got %+v", jobs)
+	}
+	for _, job := range jobs {
+		if _, err := s.Send(ctx, json.RawMessage(job.Payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	source := fmt.Sprintf("https://blog.example.com/en/article/%d", article.ID)
+	if len(received) != 2 || received[0].Get("via") != "header" || received[1].Get("via") != "html" ||
+		received[0].Get("source") != source || received[1].Get("target") != server.URL+"/html" {
+		t.Errorf("unexpected mentions %+v", received)
+	}
+
+	// Members-only articles and a switched off feature send nothing
+	article.MembersOnly = true
+	s.dispatch(ctx, hooks.ArticleUpdated, &article)
+	article.MembersOnly, enabled = false, false
+	s.dispatch(ctx, hooks.ArticleUpdated, &article)
+	var count int64
+	database.DB.Model(&models.Job{}).Where("type = ?", JobWebmentionSend).Count(&count)
+	if count != 3 {
+		t.Errorf("expected no further mentions, got %d jobs", count)
+	}
+}
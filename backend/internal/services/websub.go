@@ -0,0 +1,171 @@
+package services
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultFeedLanguage is the language of feeds requested without ?lang=
+	DefaultFeedLanguage = "zh"
+	websubTimeout       = 10 * time.Second
+)
+
+// websubPublish is the job payload for JobWebSubPublish
+type websubPublish struct {
+	Hub    string   `json:"hub"`
+	Topics []string `json:"topics"`
+}
+
+// WebSubService tells WebSub hubs that the RSS feeds changed when an article
+// is published or updated, so subscribers get it without polling. Pings are
+// sent by the job queue and retried when a hub is down.
+type WebSubService struct {
+	db      func() *gorm.DB
+	client  *http.Client
+	hubs    []string
+	baseURL func(siteID uint) string
+}
+
+// NewWebSubService creates a WebSub service for the configured hubs
+func NewWebSubService() *WebSubService {
+	return &WebSubService{
+		db:      func() *gorm.DB { return database.DB },
+		client:  &http.Client{Timeout: websubTimeout},
+		hubs:    config.Get().WebSub.Hubs,
+		baseURL: siteBaseURL,
+	}
+}
+
+// Hubs returns the hubs feeds advertise
+func (s *WebSubService) Hubs() []string {
+	return s.hubs
+}
+
+// FeedURL returns the canonical URL of an RSS feed, which is the WebSub
+// topic readers subscribe to. A zero categoryID is the feed of all articles.
+func FeedURL(baseURL string, categoryID uint, lang string) string {
+	path := "/api/rss"
+	if categoryID != 0 {
+		path = fmt.Sprintf("/api/rss/category/%d", categoryID)
+	}
+	return strings.TrimRight(baseURL, "/") + path + "?lang=" + url.QueryEscape(lang)
+}
+
+// registerHooks pings the hubs for published and updated articles
+func (s *WebSubService) registerHooks() {
+	hooks.Register(hooks.ArticlePublished, "websub", s.dispatch)
+	hooks.Register(hooks.ArticleUpdated, "websub", s.dispatch)
+}
+
+// dispatch queues a ping to every hub for the feeds the article appears in
+func (s *WebSubService) dispatch(ctx context.Context, event hooks.Event, payload interface{}) error {
+	article, ok := payload.(*models.Article)
+	if !ok || len(s.hubs) == 0 {
+		return nil
+	}
+	base := s.baseURL(article.SiteID)
+	if base == "" {
+		return nil
+	}
+	topics, err := s.topics(article, base)
+	if err != nil {
+		return err
+	}
+	for _, hub := range s.hubs {
+		if _, err := GetGlobalJobQueue().Enqueue(JobWebSubPublish, websubPublish{Hub: hub, Topics: topics}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// topics returns the feeds that change with an article: the feed of all
+// articles and of its category, in every language the site's feeds are read
+// in
+func (s *WebSubService) topics(article *models.Article, base string) ([]string, error) {
+	languages := map[string]bool{
+		DefaultFeedLanguage:                  true,
+		article.DefaultLang:                  true,
+		siteLanguage(s.db(), article.SiteID): true,
+	}
+	var translated []string
+	if err := s.db().Model(&models.ArticleTranslation{}).
+		Joins("JOIN articles ON articles.id = article_translations.article_id").
+		Where("articles.site_id = ?", article.SiteID).
+		Distinct().Pluck("article_translations.language", &translated).Error; err != nil {
+		return nil, err
+	}
+	for _, language := range translated {
+		languages[language] = true
+	}
+
+	var topics []string
+	for language := range languages {
+		if language == "" {
+			continue
+		}
+		topics = append(topics, FeedURL(base, 0, language))
+		if article.CategoryID != 0 {
+			topics = append(topics, FeedURL(base, article.CategoryID, language))
+		}
+	}
+	sort.Strings(topics)
+	return topics, nil
+}
+
+// Publish sends a queued ping to a hub, one request per topic as hubs
+// expect
+func (s *WebSubService) Publish(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var ping websubPublish
+	if err := json.Unmarshal(payload, &ping); err != nil {
+		return nil, err
+	}
+	for _, topic := range ping.Topics {
+		form := url.Values{"hub.mode": {"publish"}, "hub.url": {topic}}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, ping.Hub, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("hub returned status %d for %s: %s", resp.StatusCode, topic, strings.TrimSpace(string(detail)))
+		}
+	}
+	return map[string]interface{}{"hub": ping.Hub, "topics": len(ping.Topics)}, nil
+}
+
+var (
+	globalWebSubService *WebSubService
+	websubServiceOnce   sync.Once
+)
+
+// GetGlobalWebSubService returns the global WebSub service, registering its
+// publish and update hooks on first use
+func GetGlobalWebSubService() *WebSubService {
+	websubServiceOnce.Do(func() {
+		globalWebSubService = NewWebSubService()
+		globalWebSubService.registerHooks()
+	})
+	return globalWebSubService
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/hooks"
+	"blog-backend/internal/models"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestWebSubPublish(t *testing.T) {
+	newTestDB(t)
+	var pinged []string
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("hub.mode") != "publish" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		pinged = append(pinged, r.Form.Get("hub.url"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hub.Close()
+
+	s := &WebSubService{
+		db:      func() *gorm.DB { return database.DB },
+		client:  hub.Client(),
+		hubs:    []string{hub.URL},
+		baseURL: func(uint) string { return "https://blog.example.com" },
+	}
+	ctx := context.Background()
+
+	article := models.Article{Title: "Hello", DefaultLang: "en", CategoryID: 3}
+	database.DB.Create(&article)
+	database.DB.Create(&models.ArticleTranslation{ArticleID: article.ID, Language: "ja", Title: "こんにちは"})
+
+	if err := s.dispatch(ctx, hooks.ArticlePublished, &article); err != nil {
+		t.Fatal(err)
+	}
+	var jobs []models.Job
+	database.DB.Where("type = ?", JobWebSubPublish).Find(&jobs)
+	if len(jobs) != 1 {
+		t.Fatalf("expected one ping job, got %d", len(jobs))
+	}
+	var ping websubPublish
+	json.Unmarshal([]byte(jobs[0].Payload), &ping)
+	want := map[string]bool{
+		"https://blog.example.com/api/rss?lang=en":            true,
+		"https://blog.example.com/api/rss/category/3?lang=ja": true,
+		"https://blog.example.com/api/rss?lang=zh":            true,
+	}
+	found := 0
+	for _, topic := range ping.Topics {
+		if want[topic] {
+			found++
+		}
+	}
+	if ping.Hub != hub.URL || len(ping.Topics) != 6 || found != len(want) {
+		t.Fatalf("unexpected ping %+v", ping)
+	}
+
+	if _, err := s.Publish(ctx, json.RawMessage(jobs[0].Payload)); err != nil {
+		t.Fatal(err)
+	}
+	if len(pinged) != len(ping.Topics) {
+		t.Errorf("expected %d pings, got %v", len(ping.Topics), pinged)
+	}
+}
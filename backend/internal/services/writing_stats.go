@@ -0,0 +1,455 @@
+package services
+
+import (
+	"blog-backend/internal/cache"
+	"blog-backend/internal/database"
+	"blog-backend/internal/keywords"
+	"blog-backend/internal/models"
+	"blog-backend/internal/search"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// writingStatsTTL is how long computed writing statistics are served
+	// before the articles are read again
+	writingStatsTTL = time.Hour
+	// richnessWindow is the number of words vocabulary richness is measured
+	// over, so that long articles are not penalized for repeating words
+	richnessWindow = 100
+	// writingTopTerms is the number of most-used terms listed per language
+	writingTopTerms = 20
+)
+
+// ErrInvalidWritingStats is returned for writing statistics that cannot be
+// computed as asked, such as of a year out of range
+var ErrInvalidWritingStats = errors.New("invalid writing statistics request")
+
+// WritingStats describes how a site or one of its authors writes: over
+// every year, or month by month when Year is set
+type WritingStats struct {
+	AuthorID  uint       `json:"author_id,omitempty"`
+	Year      int        `json:"year,omitempty"`
+	Articles  int        `json:"articles"`
+	Words     int        `json:"words"`
+	FirstPost *time.Time `json:"first_post,omitempty"`
+	LastPost  *time.Time `json:"last_post,omitempty"`
+	WritingStyle
+	Cadence   WritingCadence         `json:"cadence"`
+	Periods   []WritingPeriod        `json:"periods"`
+	Languages []WritingLanguageStats `json:"languages"`
+}
+
+// WritingStyle measures the length and vocabulary of articles.
+// VocabularyRichness is the share of distinct words in each run of 100
+// words, averaged over the text, from 0 to 1; shorter articles are measured
+// whole.
+type WritingStyle struct {
+	AverageWords       int     `json:"average_words"`
+	VocabularyRichness float64 `json:"vocabulary_richness"`
+}
+
+// WritingPeriod is what was published in a year ("2025") or a month
+// ("2025-03")
+type WritingPeriod struct {
+	Period   string `json:"period"`
+	Articles int    `json:"articles"`
+	Words    int    `json:"words"`
+	WritingStyle
+}
+
+// WritingCadence is how regularly articles were published. Gaps are in days
+// between consecutive articles and streaks in consecutive weeks with at
+// least one; weekdays are counted from Sunday.
+type WritingCadence struct {
+	PostsPerMonth      float64 `json:"posts_per_month"`
+	AverageGapDays     float64 `json:"average_gap_days"`
+	LongestGapDays     int     `json:"longest_gap_days"`
+	LongestStreakWeeks int     `json:"longest_streak_weeks"`
+	BusiestWeekday     string  `json:"busiest_weekday,omitempty"`
+	Weekdays           [7]int  `json:"weekdays"`
+}
+
+// WritingLanguageStats is the writing in one language: the articles written
+// in it and the translations into it
+type WritingLanguageStats struct {
+	Language     string `json:"language"`
+	Articles     int    `json:"articles"`
+	Translations int    `json:"translations"`
+	Words        int    `json:"words"`
+	WritingStyle
+	TopTerms []TermCount `json:"top_terms"`
+}
+
+// TermCount is how often a term was used
+type TermCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+// WritingStatsService computes writing statistics from published articles
+// for "year in review" reports and the public stats page. Reading every
+// article is slow, so results are cached until articles or stop words
+// change.
+type WritingStatsService struct {
+	db      func() *gorm.DB
+	cache   *cache.Namespace
+	now     func() time.Time
+	authors *AuthorService
+	terms   func(text, language string) []string
+}
+
+// NewWritingStatsService creates a writing statistics service
+func NewWritingStatsService() *WritingStatsService {
+	s := &WritingStatsService{
+		db:      func() *gorm.DB { return database.DB },
+		cache:   cache.New("writing_stats", writingStatsTTL),
+		now:     time.Now,
+		authors: GetGlobalAuthorService(),
+		terms:   ExtractKeywords,
+	}
+	for _, topic := range []string{cache.TopicArticles, cache.TopicStopWords} {
+		cache.Subscribe(topic, s.cache.Clear)
+	}
+	return s
+}
+
+// Site returns the writing statistics of every article of the site in ctx,
+// of one year when year is not 0
+func (s *WritingStatsService) Site(ctx context.Context, year int) (*WritingStats, error) {
+	return s.compute(ctx, 0, year)
+}
+
+// Author returns the writing statistics of the articles an author has
+// published, of one year when year is not 0
+func (s *WritingStatsService) Author(ctx context.Context, userID uint, year int) (*WritingStats, error) {
+	profile, err := s.authors.profile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile.ID == 0 {
+		return nil, ErrAuthorNotFound
+	}
+	return s.compute(ctx, userID, year)
+}
+
+// writingTally adds up the texts of a period or language
+type writingTally struct {
+	texts, words      int
+	windows, richness float64
+}
+
+func (t *writingTally) add(words int, windows, richness float64) {
+	t.texts++
+	t.words += words
+	t.windows += windows
+	t.richness += richness
+}
+
+func (t *writingTally) style() WritingStyle {
+	var style WritingStyle
+	if t.texts > 0 {
+		style.AverageWords = t.words / t.texts
+	}
+	if t.windows > 0 {
+		style.VocabularyRichness = roundRatio(t.richness / t.windows)
+	}
+	return style
+}
+
+func (s *WritingStatsService) compute(ctx context.Context, authorID uint, year int) (*WritingStats, error) {
+	now := s.now().UTC()
+	if year != 0 && (year < 1970 || year > now.Year()) {
+		return nil, fmt.Errorf("%w: year must be between 1970 and %d", ErrInvalidWritingStats, now.Year())
+	}
+	siteID, _ := database.SiteFromContext(ctx)
+	key := fmt.Sprintf("%d:%d:%d", siteID, authorID, year)
+	var stats WritingStats
+	if s.cache.Get(key, &stats) {
+		return &stats, nil
+	}
+
+	query := s.db().WithContext(ctx).Model(&models.Article{}).
+		Select("id", "title", "content", "content_type", "default_lang", "members_only", "created_at").
+		Preload("Translations", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "article_id", "language", "title", "content")
+		}).
+		Where("created_at <= ?", now)
+	if authorID != 0 {
+		query = query.Where("author_id = ?", authorID)
+	}
+	if year != 0 {
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		query = query.Where("created_at >= ? AND created_at < ?", start, start.AddDate(1, 0, 0))
+	}
+
+	var total writingTally
+	var posted []time.Time
+	periods := make(map[string]*writingTally)
+	languages := make(map[string]*WritingLanguageStats)
+	tallies := make(map[string]*writingTally)
+	terms := make(map[string]map[string]int)
+	language := func(code string) (*WritingLanguageStats, *writingTally) {
+		if _, ok := languages[code]; !ok {
+			languages[code] = &WritingLanguageStats{Language: code}
+			tallies[code] = &writingTally{}
+			terms[code] = make(map[string]int)
+		}
+		return languages[code], tallies[code]
+	}
+	text := func(code, title, content, contentType string, membersOnly bool) (int, float64, float64) {
+		plain := search.PlainText(content, contentType)
+		words := countWords(plain)
+		windows, richness := vocabularyRichness(keywords.Tokenize(plain, code))
+		// Members-only articles count, but their words are not shown
+		if !membersOnly {
+			for _, term := range s.terms(title+"\n"+plain, code) {
+				terms[code][term]++
+			}
+		}
+		return words, windows, richness
+	}
+
+	var batch []models.Article
+	err := query.FindInBatches(&batch, 100, func(tx *gorm.DB, _ int) error {
+		for _, article := range batch {
+			created := article.CreatedAt.UTC()
+			posted = append(posted, created)
+
+			lang, tally := language(article.DefaultLang)
+			words, windows, richness := text(article.DefaultLang, article.Title, article.Content, article.ContentType, article.MembersOnly)
+			lang.Articles++
+			tally.add(words, windows, richness)
+			total.add(words, windows, richness)
+			period := created.Format("2006")
+			if year != 0 {
+				period = created.Format("2006-01")
+			}
+			if periods[period] == nil {
+				periods[period] = &writingTally{}
+			}
+			periods[period].add(words, windows, richness)
+
+			for _, translation := range article.Translations {
+				if translation.Language == article.DefaultLang {
+					continue
+				}
+				lang, tally := language(translation.Language)
+				words, windows, richness := text(translation.Language, translation.Title, translation.Content, article.ContentType, article.MembersOnly)
+				lang.Translations++
+				tally.add(words, windows, richness)
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	// Batches are read in ID order, which imports can make differ from
+	// the order of publication
+	sort.Slice(posted, func(i, j int) bool { return posted[i].Before(posted[j]) })
+
+	stats = WritingStats{
+		AuthorID:     authorID,
+		Year:         year,
+		Articles:     total.texts,
+		Words:        total.words,
+		WritingStyle: total.style(),
+		Periods:      []WritingPeriod{},
+		Languages:    []WritingLanguageStats{},
+	}
+	if len(posted) > 0 {
+		stats.FirstPost, stats.LastPost = &posted[0], &posted[len(posted)-1]
+	}
+	stats.Cadence = writingCadence(posted, year, now)
+
+	// Every month of a year is listed, and every year since the first post
+	var names []string
+	switch {
+	case year != 0:
+		for month := time.January; month <= time.December; month++ {
+			names = append(names, fmt.Sprintf("%d-%02d", year, month))
+		}
+	case len(posted) > 0:
+		for y := posted[0].Year(); y <= posted[len(posted)-1].Year(); y++ {
+			names = append(names, fmt.Sprint(y))
+		}
+	}
+	for _, name := range names {
+		period := WritingPeriod{Period: name}
+		if tally := periods[name]; tally != nil {
+			period.Articles, period.Words, period.WritingStyle = tally.texts, tally.words, tally.style()
+		}
+		stats.Periods = append(stats.Periods, period)
+	}
+
+	for code, lang := range languages {
+		lang.Words, lang.WritingStyle = tallies[code].words, tallies[code].style()
+		lang.TopTerms = topTerms(terms[code], writingTopTerms)
+		stats.Languages = append(stats.Languages, *lang)
+	}
+	sort.Slice(stats.Languages, func(i, j int) bool {
+		a, b := stats.Languages[i], stats.Languages[j]
+		if a.Articles+a.Translations != b.Articles+b.Translations {
+			return a.Articles+a.Translations > b.Articles+b.Translations
+		}
+		return a.Language < b.Language
+	})
+
+	s.cache.Set(key, stats)
+	return &stats, nil
+}
+
+// vocabularyRichness returns the number of windows of richnessWindow words
+// in a text and the sum of their shares of distinct words, so that texts
+// can be averaged by their length. Numbers and punctuation are not words.
+func vocabularyRichness(tokens []string) (float64, float64) {
+	words := tokens[:0:0]
+	for _, token := range tokens {
+		for _, r := range token {
+			if unicode.IsLetter(r) {
+				words = append(words, token)
+				break
+			}
+		}
+	}
+	if len(words) == 0 {
+		return 0, 0
+	}
+	if len(words) <= richnessWindow {
+		return 1, distinctShare(words)
+	}
+	// A moving window, counted incrementally
+	counts := make(map[string]int)
+	for _, word := range words[:richnessWindow] {
+		counts[word]++
+	}
+	windows, sum := 1.0, float64(len(counts))/richnessWindow
+	for i := richnessWindow; i < len(words); i++ {
+		out := words[i-richnessWindow]
+		if counts[out]--; counts[out] == 0 {
+			delete(counts, out)
+		}
+		counts[words[i]]++
+		windows++
+		sum += float64(len(counts)) / richnessWindow
+	}
+	return windows, sum
+}
+
+func distinctShare(words []string) float64 {
+	seen := make(map[string]bool, len(words))
+	for _, word := range words {
+		seen[word] = true
+	}
+	return float64(len(seen)) / float64(len(words))
+}
+
+// writingCadence measures how regularly the articles, oldest first, were
+// published. Posts per month count the months of the year, up to now, or
+// the months since the first post.
+func writingCadence(posted []time.Time, year int, now time.Time) WritingCadence {
+	var cadence WritingCadence
+	if len(posted) == 0 {
+		return cadence
+	}
+	for _, at := range posted {
+		cadence.Weekdays[at.Weekday()]++
+	}
+	busiest := 0
+	for day, count := range cadence.Weekdays {
+		if count > cadence.Weekdays[busiest] {
+			busiest = day
+		}
+	}
+	cadence.BusiestWeekday = time.Weekday(busiest).String()
+
+	start, end := posted[0], now
+	if year != 0 {
+		start = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		if year < now.Year() {
+			end = time.Date(year, time.December, 1, 0, 0, 0, 0, time.UTC)
+		}
+	}
+	months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month()) + 1
+	cadence.PostsPerMonth = roundRatio(float64(len(posted)) / float64(months))
+
+	if len(posted) > 1 {
+		span := posted[len(posted)-1].Sub(posted[0]).Hours() / 24
+		cadence.AverageGapDays = math.Round(span/float64(len(posted)-1)*10) / 10
+	}
+	streak := 0
+	var last time.Time
+	for i, at := range posted {
+		if i > 0 {
+			if gap := int(at.Sub(posted[i-1]).Hours() / 24); gap > cadence.LongestGapDays {
+				cadence.LongestGapDays = gap
+			}
+		}
+		// Weeks start on Monday, as ISO weeks do
+		week := startOfWeek(at)
+		switch {
+		case streak > 0 && week.Equal(last):
+			continue
+		case streak > 0 && week.Equal(last.AddDate(0, 0, 7)):
+			streak++
+		default:
+			streak = 1
+		}
+		last = week
+		if streak > cadence.LongestStreakWeeks {
+			cadence.LongestStreakWeeks = streak
+		}
+	}
+	return cadence
+}
+
+func startOfWeek(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// topTerms returns the most used terms, ties in alphabetical order
+func topTerms(counts map[string]int, limit int) []TermCount {
+	top := make([]TermCount, 0, len(counts))
+	for term, count := range counts {
+		top = append(top, TermCount{Term: term, Count: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Term < top[j].Term
+	})
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
+
+// roundRatio rounds a ratio to three decimals
+func roundRatio(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+var (
+	globalWritingStatsService     *WritingStatsService
+	globalWritingStatsServiceOnce sync.Once
+)
+
+// GetGlobalWritingStatsService returns the global writing statistics
+// service
+func GetGlobalWritingStatsService() *WritingStatsService {
+	globalWritingStatsServiceOnce.Do(func() {
+		globalWritingStatsService = NewWritingStatsService()
+	})
+	return globalWritingStatsService
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestVocabularyRichness(t *testing.T) {
+	distinct := make([]string, 150)
+	for i := range distinct {
+		distinct[i] = fmt.Sprintf("word%c%c", 'a'+i/26, 'a'+i%26)
+	}
+	for name, test := range map[string]struct {
+		tokens  []string
+		windows float64
+		average float64
+	}{
+		"empty":           {nil, 0, 0},
+		"numbers only":    {[]string{"2026", "42"}, 0, 0},
+		"short text":      {[]string{"go", "is", "fun", "go", "2026"}, 1, 0.75},
+		"long distinct":   {distinct, 51, 1},
+		"long repetitive": {strings.Fields(strings.Repeat("the cat ", 100)), 101, 0.02},
+	} {
+		windows, sum := vocabularyRichness(test.tokens)
+		average := 0.0
+		if windows > 0 {
+			average = roundRatio(sum / windows)
+		}
+		if windows != test.windows || average != test.average {
+			t.Errorf("%s: got %v windows averaging %v, want %v averaging %v", name, windows, average, test.windows, test.average)
+		}
+	}
+}
+
+func TestWritingStats(t *testing.T) {
+	newTestDB(t)
+	now := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	s := NewWritingStatsService()
+	s.db = func() *gorm.DB { return database.DB }
+	s.now = func() time.Time { return now }
+	s.authors = &AuthorService{db: s.db, now: s.now}
+	ctx := context.Background()
+
+	writer := models.User{Username: "writer", Password: "x"}
+	other := models.User{Username: "other", Password: "x"}
+	database.DB.Create(&writer)
+	database.DB.Create(&other)
+	database.DB.Create(&models.AuthorProfile{UserID: writer.ID, DisplayName: "Writer"})
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 9, 0, 0, 0, time.UTC) }
+	for _, article := range []models.Article{
+		// Mondays, two weeks in a row and then seven weeks later
+		{AuthorID: &writer.ID, Title: "Tips", DefaultLang: "en", CreatedAt: day(2025, time.January, 6),
+			Content:      "**Channels** make concurrency simple. Use channels, and more channels.",
+			Translations: []models.ArticleTranslation{{Language: "zh", Title: "技巧", Content: "通道让并发变得简单"}}},
+		{AuthorID: &writer.ID, Title: "Secret", DefaultLang: "en", MembersOnly: true, CreatedAt: day(2025, time.January, 13),
+			Content: "Secret secret recipes for members"},
+		{AuthorID: &writer.ID, Title: "你好", DefaultLang: "zh", CreatedAt: day(2025, time.March, 3), Content: "你好世界"},
+		{AuthorID: &writer.ID, Title: "New year", DefaultLang: "en", CreatedAt: day(2026, time.January, 5), Content: "Fresh start"},
+		{AuthorID: &other.ID, Title: "Guest", DefaultLang: "en", CreatedAt: day(2025, time.February, 1), Content: "Guest post"},
+		{AuthorID: &writer.ID, Title: "Scheduled", DefaultLang: "en", CreatedAt: now.Add(time.Hour), Content: "Not yet"},
+	} {
+		if err := database.DB.Create(&article).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := s.Author(ctx, writer.ID, 2025)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Articles != 3 || stats.Words != 18 || len(stats.Periods) != 12 ||
+		stats.Periods[0].Period != "2025-01" || stats.Periods[0].Articles != 2 || stats.Periods[1].Articles != 0 || stats.Periods[2].Articles != 1 {
+		t.Errorf("unexpected statistics %+v", stats)
+	}
+	cadence := stats.Cadence
+	if cadence.BusiestWeekday != "Monday" || cadence.Weekdays[time.Monday] != 3 || cadence.LongestStreakWeeks != 2 ||
+		cadence.LongestGapDays != 49 || cadence.AverageGapDays != 28 || cadence.PostsPerMonth != 0.25 {
+		t.Errorf("unexpected cadence %+v", cadence)
+	}
+	if len(stats.Languages) != 2 || stats.Languages[0].Language != "en" || stats.Languages[0].Articles != 2 ||
+		stats.Languages[1].Language != "zh" || stats.Languages[1].Articles != 1 || stats.Languages[1].Translations != 1 {
+		t.Fatalf("unexpected languages %+v", stats.Languages)
+	}
+	// Members-only words are counted but never listed
+	terms := fmt.Sprint(stats.Languages[0].TopTerms)
+	if !strings.HasPrefix(terms, "[{channels 3}") || strings.Contains(terms, "secret") {
+		t.Errorf("unexpected English terms %s", terms)
+	}
+
+	site, err := s.Site(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if site.Articles != 5 || len(site.Periods) != 2 || site.Periods[0].Articles != 4 || site.Periods[1].Period != "2026" ||
+		!site.FirstPost.Equal(day(2025, time.January, 6)) || !site.LastPost.Equal(day(2026, time.January, 5)) {
+		t.Errorf("unexpected site statistics %+v", site)
+	}
+
+	if _, err := s.Author(ctx, other.ID, 0); !errors.Is(err, ErrAuthorNotFound) {
+		t.Errorf("expected an author without a profile not to be found, got %v", err)
+	}
+	if _, err := s.Site(ctx, 2027); !errors.Is(err, ErrInvalidWritingStats) {
+		t.Errorf("expected a future year to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,414 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrWritingSuggestionNotFound = errors.New("writing suggestion not found")
+	ErrInvalidWritingSuggestion  = errors.New("invalid writing suggestion request")
+)
+
+// WritingSuggestionsScheduleName is the scheduler entry of the nightly
+// topic gap analysis
+const WritingSuggestionsScheduleName = "writing-suggestions"
+
+// Kinds of topic gap suggestions
+const (
+	SuggestionSearchGap   = "search_gap"
+	SuggestionKeywordGap  = "keyword_gap"
+	SuggestionCategoryGap = "category_gap"
+)
+
+// Writing suggestion statuses
+const (
+	SuggestionPending   = "pending"
+	SuggestionAccepted  = "accepted"
+	SuggestionDismissed = "dismissed"
+)
+
+// Topic gap analysis. Searches count for unansweredSearchWindow and need
+// minUnansweredSearches to be more than noise; a category is sparse below
+// sparseCategoryShare of the average articles per category. Each kind of
+// gap is ranked 0-1 within its kind and weighed so readers' searches come
+// before tracked keywords, and those before thin categories.
+const (
+	unansweredSearchWindow = 90 * 24 * time.Hour
+	minUnansweredSearches  = 2
+	maxUnansweredQuery     = 200
+	sparseCategoryShare    = 0.5
+	searchGapWeight        = 1.0
+	keywordGapWeight       = 0.8
+	categoryGapWeight      = 0.6
+	writingSuggestionsPage = 50
+)
+
+// WritingSuggestionFilter selects the suggestions of a list
+type WritingSuggestionFilter struct {
+	Language string
+	Status   string
+	Type     string
+	Limit    int
+}
+
+// WritingSuggestionRunResult describes one topic gap analysis
+type WritingSuggestionRunResult struct {
+	Created        int   `json:"created"`
+	Updated        int   `json:"updated"`
+	Closed         int   `json:"closed"`
+	SearchesPurged int64 `json:"searches_purged"`
+}
+
+// WritingSuggestionService ranks what to write next from the gaps in the
+// blog's coverage: searches readers made without finding an article,
+// tracked SEO keywords no article covers and categories with few articles
+// in a language. Suggestions stay pending until an admin accepts or
+// dismisses them; pending ones whose gap has closed are removed.
+type WritingSuggestionService struct {
+	db  func() *gorm.DB
+	now func() time.Time
+}
+
+// NewWritingSuggestionService creates a writing suggestion service
+func NewWritingSuggestionService() *WritingSuggestionService {
+	return &WritingSuggestionService{
+		db:  func() *gorm.DB { return database.DB },
+		now: time.Now,
+	}
+}
+
+// RecordUnansweredSearch counts a search that found no article
+func (s *WritingSuggestionService) RecordUnansweredSearch(query, language string) error {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	if query == "" || language == "" {
+		return nil
+	}
+	if utf8.RuneCountInString(query) > maxUnansweredQuery {
+		query = string([]rune(query)[:maxUnansweredQuery])
+	}
+	now := s.now()
+	return s.db().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "query"}, {Name: "language"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":            gorm.Expr("count + 1"),
+			"last_searched_at": now,
+		}),
+	}).Create(&models.UnansweredSearch{Query: query, Language: language, Count: 1, LastSearchedAt: now}).Error
+}
+
+// Generate finds the current topic gaps and updates the suggestions
+func (s *WritingSuggestionService) Generate(ctx context.Context) (*WritingSuggestionRunResult, error) {
+	db := s.db().WithContext(ctx)
+	result := &WritingSuggestionRunResult{}
+	purged := db.Where("last_searched_at < ?", s.now().Add(-unansweredSearchWindow)).Delete(&models.UnansweredSearch{})
+	if purged.Error != nil {
+		return nil, purged.Error
+	}
+	result.SearchesPurged = purged.RowsAffected
+
+	var candidates []models.WritingSuggestion
+	for _, find := range []func(*gorm.DB) ([]models.WritingSuggestion, error){s.searchGaps, s.keywordGaps, s.categoryGaps} {
+		found, err := find(db)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, found...)
+	}
+
+	var existing []models.WritingSuggestion
+	if err := db.Where("suggestion_type IN ?", []string{SuggestionSearchGap, SuggestionKeywordGap, SuggestionCategoryGap}).
+		Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]models.WritingSuggestion, len(existing))
+	for _, suggestion := range existing {
+		byKey[suggestionKey(suggestion)] = suggestion
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, candidate := range candidates {
+			key := suggestionKey(candidate)
+			current, ok := byKey[key]
+			delete(byKey, key)
+			switch {
+			case !ok:
+				if err := tx.Create(&candidate).Error; err != nil {
+					return err
+				}
+				result.Created++
+			case current.Status == SuggestionPending:
+				if err := tx.Model(&current).Updates(map[string]interface{}{
+					"content":         candidate.Content,
+					"relevance_score": candidate.RelevanceScore,
+					"signal":          candidate.Signal,
+					"category_id":     candidate.CategoryID,
+				}).Error; err != nil {
+					return err
+				}
+				result.Updated++
+			}
+			// Accepted and dismissed suggestions keep the admin's decision
+		}
+		for _, closed := range byKey {
+			if closed.Status != SuggestionPending {
+				continue
+			}
+			if err := tx.Delete(&closed).Error; err != nil {
+				return err
+			}
+			result.Closed++
+		}
+		return nil
+	})
+	return result, err
+}
+
+// List returns suggestions, most relevant first
+func (s *WritingSuggestionService) List(filter WritingSuggestionFilter) ([]models.WritingSuggestion, error) {
+	query := s.db().Preload("Category")
+	if filter.Language != "" {
+		query = query.Where("language = ?", filter.Language)
+	}
+	if filter.Status != "" {
+		if !oneOfStatus(filter.Status) {
+			return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidWritingSuggestion, filter.Status)
+		}
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Type != "" {
+		query = query.Where("suggestion_type = ?", filter.Type)
+	}
+	limit := filter.Limit
+	if limit <= 0 || limit > writingSuggestionsPage*4 {
+		limit = writingSuggestionsPage
+	}
+	suggestions := []models.WritingSuggestion{}
+	err := query.Order("relevance_score DESC, id").Limit(limit).Find(&suggestions).Error
+	return suggestions, err
+}
+
+// Decide accepts or dismisses a suggestion. Accepted suggestions are marked
+// used; dismissed ones are not suggested again while their gap lasts.
+func (s *WritingSuggestionService) Decide(id uint, status string) (*models.WritingSuggestion, error) {
+	if status != SuggestionAccepted && status != SuggestionDismissed {
+		return nil, fmt.Errorf("%w: a suggestion can only be accepted or dismissed", ErrInvalidWritingSuggestion)
+	}
+	var suggestion models.WritingSuggestion
+	if err := s.db().First(&suggestion, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWritingSuggestionNotFound
+		}
+		return nil, err
+	}
+	now := s.now()
+	suggestion.Status, suggestion.IsUsed, suggestion.DecidedAt = status, status == SuggestionAccepted, &now
+	if err := s.db().Model(&suggestion).Select("status", "is_used", "decided_at").Updates(&suggestion).Error; err != nil {
+		return nil, err
+	}
+	return &suggestion, nil
+}
+
+// searchGaps suggests the queries searched repeatedly without results that
+// still match no article
+func (s *WritingSuggestionService) searchGaps(db *gorm.DB) ([]models.WritingSuggestion, error) {
+	var searches []models.UnansweredSearch
+	if err := db.Where("count >= ?", minUnansweredSearches).Order("count DESC, id").Find(&searches).Error; err != nil {
+		return nil, err
+	}
+	var suggestions []models.WritingSuggestion
+	for _, search := range searches {
+		covered, err := s.covered(db, search.Query, search.Language)
+		if err != nil {
+			return nil, err
+		}
+		if covered {
+			continue
+		}
+		suggestions = append(suggestions, models.WritingSuggestion{
+			SuggestionType: SuggestionSearchGap,
+			Topic:          search.Query,
+			Content:        fmt.Sprintf("Readers searched for %q %d times without finding an article", search.Query, search.Count),
+			Signal:         search.Count,
+			Language:       search.Language,
+			Status:         SuggestionPending,
+		})
+	}
+	rankBySignal(suggestions, searchGapWeight)
+	return suggestions, nil
+}
+
+// keywordGaps suggests the active site-wide keywords no article mentions,
+// by search volume
+func (s *WritingSuggestionService) keywordGaps(db *gorm.DB) ([]models.WritingSuggestion, error) {
+	var keywords []models.SEOKeyword
+	if err := db.Where("article_id IS NULL AND tracking_status = ?", "active").
+		Order("search_volume DESC, id").Find(&keywords).Error; err != nil {
+		return nil, err
+	}
+	var suggestions []models.WritingSuggestion
+	for _, keyword := range keywords {
+		covered, err := s.covered(db, keyword.Keyword, keyword.Language)
+		if err != nil {
+			return nil, err
+		}
+		if covered {
+			continue
+		}
+		suggestions = append(suggestions, models.WritingSuggestion{
+			SuggestionType: SuggestionKeywordGap,
+			Topic:          strings.TrimSpace(keyword.Keyword),
+			Content:        fmt.Sprintf("The tracked keyword %q, searched about %d times a month, has no article yet", keyword.Keyword, keyword.SearchVolume),
+			Signal:         keyword.SearchVolume,
+			Language:       keyword.Language,
+			Status:         SuggestionPending,
+		})
+	}
+	rankBySignal(suggestions, keywordGapWeight)
+	return suggestions, nil
+}
+
+// categoryGaps suggests the categories with far fewer articles in a
+// language than the average category
+func (s *WritingSuggestionService) categoryGaps(db *gorm.DB) ([]models.WritingSuggestion, error) {
+	var categories []models.Category
+	if err := db.Preload("Translations").Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	if len(categories) < 2 {
+		return nil, nil
+	}
+
+	// Articles available in each language by category: in their default
+	// language and their translations
+	type count struct {
+		Language   string
+		CategoryID uint
+		Articles   int
+	}
+	var counts []count
+	err := db.Raw(`SELECT language, category_id, COUNT(*) AS articles FROM (
+			SELECT default_lang AS language, category_id, id FROM articles WHERE deleted_at IS NULL
+			UNION
+			SELECT t.language, a.category_id, a.id FROM article_translations t
+			JOIN articles a ON a.id = t.article_id WHERE a.deleted_at IS NULL
+		) available GROUP BY language, category_id`).Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	byLanguage := make(map[string]map[uint]int)
+	for _, c := range counts {
+		if byLanguage[c.Language] == nil {
+			byLanguage[c.Language] = make(map[uint]int)
+		}
+		byLanguage[c.Language][c.CategoryID] = c.Articles
+	}
+
+	languages := make([]string, 0, len(byLanguage))
+	for language := range byLanguage {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	var suggestions []models.WritingSuggestion
+	for _, language := range languages {
+		perCategory := byLanguage[language]
+		total := 0
+		for _, category := range categories {
+			total += perCategory[category.ID]
+		}
+		average := float64(total) / float64(len(categories))
+		for _, category := range categories {
+			articles := perCategory[category.ID]
+			if float64(articles) >= average*sparseCategoryShare {
+				continue
+			}
+			name := category.Name
+			for _, translation := range category.Translations {
+				if translation.Language == language && translation.Name != "" {
+					name = translation.Name
+				}
+			}
+			id := category.ID
+			suggestions = append(suggestions, models.WritingSuggestion{
+				SuggestionType: SuggestionCategoryGap,
+				Topic:          name,
+				Content:        fmt.Sprintf("The category %q has %d articles in this language against %.1f on average", name, articles, average),
+				RelevanceScore: roundTo(categoryGapWeight*(1-float64(articles)/average), 3),
+				Signal:         articles,
+				Language:       language,
+				CategoryID:     &id,
+				Status:         SuggestionPending,
+			})
+		}
+	}
+	return suggestions, nil
+}
+
+// covered reports whether an article in the language mentions the topic
+// in its title or content
+func (s *WritingSuggestionService) covered(db *gorm.DB, topic, language string) (bool, error) {
+	pattern := "%" + strings.TrimSpace(topic) + "%"
+	var n int64
+	err := db.Model(&models.Article{}).Where(
+		db.Where("default_lang = ?", language).Where(database.Like("(title LIKE ? OR content LIKE ?)"), pattern, pattern).
+			Or("id IN (?)", db.Model(&models.ArticleTranslation{}).Select("article_id").
+				Where("language = ?", language).Where(database.Like("(title LIKE ? OR content LIKE ?)"), pattern, pattern)),
+	).Count(&n).Error
+	return n > 0, err
+}
+
+// rankBySignal sets the relevance of gaps of one kind from their signal
+// relative to the strongest, or to half the weight when none has a signal
+func rankBySignal(suggestions []models.WritingSuggestion, weight float64) {
+	top := 0
+	for _, suggestion := range suggestions {
+		top = max(top, suggestion.Signal)
+	}
+	for i := range suggestions {
+		relevance := 0.5
+		if top > 0 {
+			relevance = float64(suggestions[i].Signal) / float64(top)
+		}
+		suggestions[i].RelevanceScore = roundTo(weight*relevance, 3)
+	}
+}
+
+// suggestionKey identifies the gap a suggestion is about across runs
+func suggestionKey(suggestion models.WritingSuggestion) string {
+	topic := strings.ToLower(suggestion.Topic)
+	if suggestion.CategoryID != nil {
+		topic = fmt.Sprint(*suggestion.CategoryID)
+	}
+	return suggestion.SuggestionType + "\x00" + suggestion.Language + "\x00" + topic
+}
+
+func oneOfStatus(status string) bool {
+	return status == SuggestionPending || status == SuggestionAccepted || status == SuggestionDismissed
+}
+
+// Global writing suggestion service instance
+var (
+	globalWritingSuggestionService     *WritingSuggestionService
+	globalWritingSuggestionServiceOnce sync.Once
+)
+
+// GetGlobalWritingSuggestionService returns the global writing suggestion
+// service
+func GetGlobalWritingSuggestionService() *WritingSuggestionService {
+	globalWritingSuggestionServiceOnce.Do(func() {
+		globalWritingSuggestionService = NewWritingSuggestionService()
+	})
+	return globalWritingSuggestionService
+}
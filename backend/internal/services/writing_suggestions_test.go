@@ -0,0 +1,105 @@
+package services
+
+import (
+	"blog-backend/internal/database"
+	"blog-backend/internal/models"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWritingSuggestionsGenerate(t *testing.T) {
+	newTestDB(t)
+	s := NewWritingSuggestionService()
+
+	categories := []models.Category{{Name: "Go"}, {Name: "Rust"}, {Name: "Databases"}}
+	for i := range categories {
+		database.DB.Create(&categories[i])
+	}
+	database.DB.Create(&models.CategoryTranslation{CategoryID: categories[1].ID, Language: "zh", Name: "Rust 语言"})
+	for _, article := range []models.Article{
+		{Title: "Go generics", Content: "Type parameters", CategoryID: categories[0].ID, DefaultLang: "en"},
+		{Title: "Go modules", Content: "Versioning", CategoryID: categories[0].ID, DefaultLang: "en"},
+		{Title: "Go 并发", Content: "goroutine", CategoryID: categories[0].ID, DefaultLang: "zh"},
+		{Title: "Indexes", Content: "B-trees", CategoryID: categories[2].ID, DefaultLang: "en"},
+	} {
+		database.DB.Create(&article)
+	}
+
+	for _, search := range []struct{ query, language string }{
+		{"Kubernetes  Operators", "en"}, {"kubernetes operators", "en"}, {"kubernetes operators", "en"},
+		{"wasm", "en"}, {"wasm", "en"},
+		{"once", "en"},                         // a single search is noise
+		{"generics", "en"}, {"generics", "en"}, // answered since
+	} {
+		if err := s.RecordUnansweredSearch(search.query, search.language); err != nil {
+			t.Fatal(err)
+		}
+	}
+	database.DB.Create(&models.SEOKeyword{Keyword: "grpc streaming", Language: "en", SearchVolume: 900, TrackingStatus: "active"})
+	database.DB.Create(&models.SEOKeyword{Keyword: "modules", Language: "en", SearchVolume: 5000, TrackingStatus: "active"})
+
+	result, err := s.Generate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	suggestions, err := s.List(WritingSuggestionFilter{Language: "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, suggestion := range suggestions {
+		got = append(got, suggestion.SuggestionType+":"+suggestion.Topic)
+	}
+	want := []string{"search_gap:kubernetes operators", "keyword_gap:grpc streaming", "search_gap:wasm", "category_gap:Rust"}
+	if len(got) != len(want) {
+		t.Fatalf("suggestions = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("suggestions = %q, want %q", got, want)
+			break
+		}
+	}
+	if suggestions[0].Signal != 3 || suggestions[0].RelevanceScore != 1 {
+		t.Errorf("top suggestion = %+v", suggestions[0])
+	}
+
+	// Only the Go category has Chinese articles
+	zh, _ := s.List(WritingSuggestionFilter{Language: "zh"})
+	if len(zh) != 2 || zh[0].Topic+", "+zh[1].Topic != "Rust 语言, Databases" {
+		t.Errorf("zh suggestions = %+v", zh)
+	}
+
+	// A dismissed gap stays dismissed, a covered one is closed
+	if _, err := s.Decide(suggestions[2].ID, SuggestionDismissed); err != nil {
+		t.Fatal(err)
+	}
+	accepted, err := s.Decide(suggestions[1].ID, SuggestionAccepted)
+	if err != nil || !accepted.IsUsed || accepted.DecidedAt == nil {
+		t.Fatalf("Decide() = %+v, %v", accepted, err)
+	}
+	database.DB.Create(&models.Article{Title: "Kubernetes operators in Go", CategoryID: categories[0].ID, DefaultLang: "en"})
+	second, err := s.Generate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 6 || second.Created != 0 || second.Closed != 1 {
+		t.Errorf("first run %+v, second run %+v", result, second)
+	}
+	pending, _ := s.List(WritingSuggestionFilter{Language: "en", Status: SuggestionPending})
+	if len(pending) != 1 || pending[0].Topic != "Rust" {
+		t.Errorf("pending after the second run = %+v", pending)
+	}
+	dismissed, _ := s.List(WritingSuggestionFilter{Language: "en", Status: SuggestionDismissed})
+	if len(dismissed) != 1 || dismissed[0].Topic != "wasm" {
+		t.Errorf("dismissed = %+v", dismissed)
+	}
+
+	if _, err := s.Decide(9999, SuggestionAccepted); !errors.Is(err, ErrWritingSuggestionNotFound) {
+		t.Errorf("Decide() of a missing suggestion = %v", err)
+	}
+	if _, err := s.Decide(suggestions[0].ID, SuggestionPending); !errors.Is(err, ErrInvalidWritingSuggestion) {
+		t.Errorf("Decide() back to pending = %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/security"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureAPIVersion is the Blob service version requests are made with
+const azureAPIVersion = "2021-08-06"
+
+// Azure stores objects as block blobs in an Azure Blob Storage container,
+// authenticating with the account's shared key
+type Azure struct {
+	account   string
+	key       []byte
+	container string
+	endpoint  *url.URL
+	publicURL string
+	prefix    string
+
+	httpClient *http.Client
+}
+
+// NewAzure creates an Azure Blob Storage driver
+func NewAzure(cfg config.AzureConfig) (*Azure, error) {
+	secret, err := security.ResolveSecret(cfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve Azure storage key: %v", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("Azure storage key is not valid base64: %v", err)
+	}
+	if cfg.Account == "" || cfg.Container == "" {
+		return nil, fmt.Errorf("Azure storage needs an account and a container")
+	}
+
+	rawEndpoint := cfg.Endpoint
+	if rawEndpoint == "" {
+		rawEndpoint = fmt.Sprintf("https://%s.blob.core.windows.net", cfg.Account)
+	}
+	endpoint, err := url.Parse(strings.TrimRight(rawEndpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Azure storage endpoint: %v", err)
+	}
+	return &Azure{
+		account:    cfg.Account,
+		key:        key,
+		container:  cfg.Container,
+		endpoint:   endpoint,
+		publicURL:  strings.TrimRight(cfg.PublicURL, "/"),
+		prefix:     strings.Trim(cfg.Prefix, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Minute},
+	}, nil
+}
+
+// Name implements Driver
+func (a *Azure) Name() string { return DriverAzure }
+
+// Put uploads the object as a block blob in a single request, which the
+// service accepts up to 5000 MiB
+func (a *Azure) Put(key string, r io.Reader, size int64, contentType string) error {
+	headers := map[string]string{"x-ms-blob-type": "BlockBlob"}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	resp, err := a.send(http.MethodPut, a.blobURL(key), key, r, size, headers)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get implements Driver
+func (a *Azure) Get(key string) (io.ReadCloser, *ObjectInfo, error) {
+	resp, err := a.send(http.MethodGet, a.blobURL(key), key, nil, 0, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, objectInfoFromResponse(key, resp), nil
+}
+
+// Stat implements Driver
+func (a *Azure) Stat(key string) (*ObjectInfo, error) {
+	resp, err := a.send(http.MethodHead, a.blobURL(key), key, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return objectInfoFromResponse(key, resp), nil
+}
+
+// List implements Driver
+func (a *Azure) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	marker := ""
+	for {
+		u := a.containerURL()
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefixedKey(a.prefix, prefix)}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		u.RawQuery = query.Encode()
+
+		resp, err := a.send(http.MethodGet, u, prefix, nil, 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Blobs []struct {
+				Name       string `xml:"Name"`
+				Properties struct {
+					LastModified  string `xml:"Last-Modified"`
+					ETag          string `xml:"Etag"`
+					ContentLength int64  `xml:"Content-Length"`
+					ContentType   string `xml:"Content-Type"`
+				} `xml:"Properties"`
+			} `xml:"Blobs>Blob"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Azure listing: %v", err)
+		}
+
+		for _, blob := range result.Blobs {
+			key := blob.Name
+			if a.prefix != "" {
+				key = strings.TrimPrefix(key, a.prefix+"/")
+			}
+			modified, _ := http.ParseTime(blob.Properties.LastModified)
+			objects = append(objects, ObjectInfo{
+				Key:          key,
+				Size:         blob.Properties.ContentLength,
+				ContentType:  blob.Properties.ContentType,
+				ETag:         strings.Trim(blob.Properties.ETag, `"`),
+				LastModified: modified,
+			})
+		}
+		if result.NextMarker == "" {
+			return objects, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+// Delete implements Driver
+func (a *Azure) Delete(key string) error {
+	resp, err := a.send(http.MethodDelete, a.blobURL(key), key, nil, 0, nil)
+	if err == ErrObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// URL implements Driver
+func (a *Azure) URL(key string) string {
+	fullKey := prefixedKey(a.prefix, key)
+	if a.publicURL != "" {
+		return a.publicURL + "/" + escapeKey(fullKey)
+	}
+	return a.blobURL(key).String()
+}
+
+func (a *Azure) containerURL() *url.URL {
+	u := *a.endpoint
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + a.container
+	return &u
+}
+
+func (a *Azure) blobURL(key string) *url.URL {
+	u := a.containerURL()
+	fullKey := prefixedKey(a.prefix, key)
+	u.RawPath = u.EscapedPath() + "/" + escapeKey(fullKey)
+	u.Path += "/" + fullKey
+	return u
+}
+
+// send signs and executes a request with the account's shared key
+func (a *Azure) send(method string, u *url.URL, key string, body io.Reader, length int64, headers map[string]string) (*http.Response, error) {
+	if body == nil {
+		body = http.NoBody
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	if length > 0 {
+		req.ContentLength = length
+	}
+	req.Header.Set("Authorization", "SharedKey "+a.account+":"+a.sign(req))
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Azure request failed: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrObjectNotFound
+	}
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("Azure %s %s returned %d: %s", method, key, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+// sign computes the Shared Key signature of a request
+func (a *Azure) sign(req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+	var msHeaders []string
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			msHeaders = append(msHeaders, lower)
+		}
+	}
+	sort.Strings(msHeaders)
+	var canonicalHeaders strings.Builder
+	for _, name := range msHeaders {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+
+	var resource strings.Builder
+	resource.WriteString("/" + a.account + req.URL.EscapedPath())
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := query[name]
+		sort.Strings(values)
+		resource.WriteString("\n" + strings.ToLower(name) + ":" + strings.Join(values, ","))
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, sent as x-ms-date instead
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+	}, "\n") + "\n" + canonicalHeaders.String() + resource.String()
+
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
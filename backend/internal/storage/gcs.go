@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"blog-backend/internal/config"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	gcsDefaultEndpoint = "https://storage.googleapis.com"
+	gcsScope           = "https://www.googleapis.com/auth/devstorage.read_write"
+	gcsMetadataToken   = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// GCS stores objects in a Google Cloud Storage bucket through the JSON
+// API. It signs in with a service account key file, or with the instance's
+// service account when running on Google Cloud.
+type GCS struct {
+	bucket    string
+	endpoint  string
+	publicURL string
+	prefix    string
+	account   *gcsServiceAccount
+	// anonymous skips authentication, for emulators given as the endpoint
+	anonymous bool
+
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// gcsServiceAccount is the part of a service account key file used to
+// request access tokens
+type gcsServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewGCS creates a Google Cloud Storage driver. GCS_CREDENTIALS_FILE falls
+// back to GOOGLE_APPLICATION_CREDENTIALS.
+func NewGCS(cfg config.GCSConfig) (*GCS, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("GCS storage needs a bucket")
+	}
+	g := &GCS{
+		bucket:     cfg.Bucket,
+		endpoint:   strings.TrimRight(cfg.Endpoint, "/"),
+		publicURL:  strings.TrimRight(cfg.PublicURL, "/"),
+		prefix:     strings.Trim(cfg.Prefix, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Minute},
+	}
+	if g.endpoint == "" {
+		g.endpoint = gcsDefaultEndpoint
+	}
+
+	credentials := cfg.CredentialsFile
+	if credentials == "" {
+		credentials = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	switch {
+	case credentials != "":
+		data, err := os.ReadFile(credentials)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read GCS credentials: %v", err)
+		}
+		var account gcsServiceAccount
+		if err := json.Unmarshal(data, &account); err != nil || account.ClientEmail == "" || account.PrivateKey == "" {
+			return nil, fmt.Errorf("GCS credentials file is not a service account key")
+		}
+		if account.TokenURI == "" {
+			account.TokenURI = "https://oauth2.googleapis.com/token"
+		}
+		g.account = &account
+	case g.endpoint != gcsDefaultEndpoint:
+		g.anonymous = true
+	}
+	return g, nil
+}
+
+// Name implements Driver
+func (g *GCS) Name() string { return DriverGCS }
+
+// Put uploads the object in a single media upload
+func (g *GCS) Put(key string, r io.Reader, size int64, contentType string) error {
+	query := url.Values{"uploadType": {"media"}, "name": {prefixedKey(g.prefix, key)}}
+	u := g.endpoint + "/upload/storage/v1/b/" + url.PathEscape(g.bucket) + "/o?" + query.Encode()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	resp, err := g.send(http.MethodPost, u, key, r, size, contentType)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get implements Driver
+func (g *GCS) Get(key string) (io.ReadCloser, *ObjectInfo, error) {
+	resp, err := g.send(http.MethodGet, g.objectURL(key)+"?alt=media", key, nil, 0, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, objectInfoFromResponse(key, resp), nil
+}
+
+// Stat implements Driver
+func (g *GCS) Stat(key string) (*ObjectInfo, error) {
+	resp, err := g.send(http.MethodGet, g.objectURL(key), key, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var object gcsObject
+	if err := json.NewDecoder(resp.Body).Decode(&object); err != nil {
+		return nil, fmt.Errorf("failed to parse GCS object metadata: %v", err)
+	}
+	info := object.info()
+	info.Key = key
+	return &info, nil
+}
+
+// List implements Driver
+func (g *GCS) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	pageToken := ""
+	for {
+		query := url.Values{"prefix": {prefixedKey(g.prefix, prefix)}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		u := g.endpoint + "/storage/v1/b/" + url.PathEscape(g.bucket) + "/o?" + query.Encode()
+		resp, err := g.send(http.MethodGet, u, prefix, nil, 0, "")
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Items         []gcsObject `json:"items"`
+			NextPageToken string      `json:"nextPageToken"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse GCS listing: %v", err)
+		}
+
+		for _, item := range result.Items {
+			info := item.info()
+			if g.prefix != "" {
+				info.Key = strings.TrimPrefix(info.Key, g.prefix+"/")
+			}
+			objects = append(objects, info)
+		}
+		if result.NextPageToken == "" {
+			return objects, nil
+		}
+		pageToken = result.NextPageToken
+	}
+}
+
+// Delete implements Driver
+func (g *GCS) Delete(key string) error {
+	resp, err := g.send(http.MethodDelete, g.objectURL(key), key, nil, 0, "")
+	if err == ErrObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// URL implements Driver
+func (g *GCS) URL(key string) string {
+	fullKey := escapeKey(prefixedKey(g.prefix, key))
+	if g.publicURL != "" {
+		return g.publicURL + "/" + fullKey
+	}
+	return gcsDefaultEndpoint + "/" + g.bucket + "/" + fullKey
+}
+
+func (g *GCS) objectURL(key string) string {
+	return g.endpoint + "/storage/v1/b/" + url.PathEscape(g.bucket) + "/o/" + url.PathEscape(prefixedKey(g.prefix, key))
+}
+
+func (g *GCS) send(method, u, key string, body io.Reader, length int64, contentType string) (*http.Response, error) {
+	if body == nil {
+		body = http.NoBody
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if length > 0 {
+		req.ContentLength = length
+	}
+	if !g.anonymous {
+		token, err := g.accessToken()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GCS request failed: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrObjectNotFound
+	}
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("GCS %s %s returned %d: %s", method, key, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+// accessToken returns a cached OAuth access token, requesting a new one a
+// minute before it expires
+func (g *GCS) accessToken() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Now().Before(g.tokenExpiry.Add(-time.Minute)) {
+		return g.token, nil
+	}
+
+	var req *http.Request
+	if g.account != nil {
+		key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(g.account.PrivateKey))
+		if err != nil {
+			return "", fmt.Errorf("invalid GCS service account key: %v", err)
+		}
+		now := time.Now()
+		assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":   g.account.ClientEmail,
+			"scope": gcsScope,
+			"aud":   g.account.TokenURI,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+		}).SignedString(key)
+		if err != nil {
+			return "", err
+		}
+		form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+		req, _ = http.NewRequest(http.MethodPost, g.account.TokenURI, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req, _ = http.NewRequest(http.MethodGet, gcsMetadataToken, nil)
+		req.Header.Set("Metadata-Flavor", "Google")
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get GCS access token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("GCS token request returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("invalid GCS token response")
+	}
+	g.token = token.AccessToken
+	g.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return g.token, nil
+}
+
+// gcsObject is the object resource of the JSON API
+type gcsObject struct {
+	Name        string    `json:"name"`
+	Size        string    `json:"size"`
+	ContentType string    `json:"contentType"`
+	ETag        string    `json:"etag"`
+	Updated     time.Time `json:"updated"`
+}
+
+func (o gcsObject) info() ObjectInfo {
+	size, _ := strconv.ParseInt(o.Size, 10, 64)
+	return ObjectInfo{Key: o.Name, Size: size, ContentType: o.ContentType, ETag: o.ETag, LastModified: o.Updated}
+}
@@ -0,0 +1,502 @@
+package storage
+
+import (
+	"blog-backend/internal/security"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3Config holds connection settings for an S3-compatible bucket
+// (AWS S3, MinIO, Cloudflare R2, Backblaze B2, ...)
+type S3Config struct {
+	Bucket         string
+	Region         string
+	Endpoint       string // e.g. https://s3.us-east-1.amazonaws.com or a MinIO URL
+	AccessKey      string
+	SecretKey      string
+	SessionToken   string
+	PublicURL      string // base URL objects are served from (CDN or bucket URL)
+	Prefix         string // key prefix inside the bucket
+	ForcePathStyle bool
+}
+
+// S3Client talks to an S3-compatible API using Signature Version 4
+type S3Client struct {
+	config     S3Config
+	httpClient *http.Client
+}
+
+// LoadS3ConfigFromEnv reads S3_* variables. The backend is enabled when S3_BUCKET is set.
+func LoadS3ConfigFromEnv() S3Config {
+	cfg := S3Config{
+		Bucket:         os.Getenv("S3_BUCKET"),
+		Region:         getEnvOrDefault("S3_REGION", "us-east-1"),
+		Endpoint:       strings.TrimRight(os.Getenv("S3_ENDPOINT"), "/"),
+		AccessKey:      getEnvOrDefault("S3_ACCESS_KEY_ID", os.Getenv("AWS_ACCESS_KEY_ID")),
+		SecretKey:      getEnvOrDefault("S3_SECRET_ACCESS_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY")),
+		SessionToken:   os.Getenv("AWS_SESSION_TOKEN"),
+		PublicURL:      strings.TrimRight(os.Getenv("S3_PUBLIC_URL"), "/"),
+		Prefix:         strings.Trim(os.Getenv("S3_PREFIX"), "/"),
+		ForcePathStyle: os.Getenv("S3_FORCE_PATH_STYLE") == "true",
+	}
+
+	// Credentials may be secret manager references
+	if resolved, err := security.ResolveSecret(cfg.SecretKey); err == nil {
+		cfg.SecretKey = resolved
+	} else {
+		slog.Error("Failed to resolve S3 secret key", "error", err)
+	}
+	if resolved, err := security.ResolveSecret(cfg.AccessKey); err == nil {
+		cfg.AccessKey = resolved
+	}
+
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	return cfg
+}
+
+// NewS3Client creates a client for the given configuration
+func NewS3Client(config S3Config) *S3Client {
+	return &S3Client{
+		config:     config,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// IsConfigured reports whether bucket and credentials are present
+func (s *S3Client) IsConfigured() bool {
+	return s.config.Bucket != "" && s.config.AccessKey != "" && s.config.SecretKey != ""
+}
+
+// Bucket returns the configured bucket name
+func (s *S3Client) Bucket() string {
+	return s.config.Bucket
+}
+
+// FullKey applies the configured prefix to a key
+func (s *S3Client) FullKey(key string) string {
+	key = strings.TrimLeft(key, "/")
+	if s.config.Prefix == "" {
+		return key
+	}
+	return s.config.Prefix + "/" + key
+}
+
+// objectURL returns the API URL for a full object key
+func (s *S3Client) objectURL(fullKey string) *url.URL {
+	endpoint, _ := url.Parse(s.config.Endpoint)
+	u := *endpoint
+	escapedKey := escapeKey(fullKey)
+	if s.config.ForcePathStyle {
+		u.Path = "/" + s.config.Bucket + "/" + fullKey
+		u.RawPath = "/" + s.config.Bucket + "/" + escapedKey
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = "/" + fullKey
+		u.RawPath = "/" + escapedKey
+	}
+	return &u
+}
+
+// bucketURL returns the API URL of the bucket itself
+func (s *S3Client) bucketURL() *url.URL {
+	endpoint, _ := url.Parse(s.config.Endpoint)
+	u := *endpoint
+	if s.config.ForcePathStyle {
+		u.Path = "/" + s.config.Bucket + "/"
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path = "/"
+	}
+	return &u
+}
+
+// PublicURL returns the URL visitors use to fetch an object
+func (s *S3Client) PublicURL(key string) string {
+	fullKey := s.FullKey(key)
+	if s.config.PublicURL != "" {
+		return s.config.PublicURL + "/" + escapeKey(fullKey)
+	}
+	return s.objectURL(fullKey).String()
+}
+
+// PresignPutObject returns a URL that accepts a single PUT of exactly
+// contentLength bytes with the given content type until it expires
+func (s *S3Client) PresignPutObject(key, contentType string, contentLength int64, expires time.Duration) (string, map[string]string, error) {
+	if !s.IsConfigured() {
+		return "", nil, fmt.Errorf("S3 storage is not configured")
+	}
+
+	u := s.objectURL(s.FullKey(key))
+	headers := map[string]string{
+		"content-length": strconv.FormatInt(contentLength, 10),
+		"content-type":   contentType,
+		"host":           u.Host,
+	}
+
+	now := time.Now().UTC()
+	scope := s.credentialScope(now)
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.config.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", signedHeaderNames(headers))
+	if s.config.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	signature := s.sign(http.MethodPut, u, headers, "UNSIGNED-PAYLOAD", now)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+
+	// Headers the uploader must send unchanged
+	return u.String(), map[string]string{
+		"Content-Type":   contentType,
+		"Content-Length": headers["content-length"],
+	}, nil
+}
+
+// HeadObject returns metadata for an object
+func (s *S3Client) HeadObject(key string) (*ObjectInfo, error) {
+	resp, err := s.do(http.MethodHead, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return objectInfoFromResponse(key, resp), nil
+}
+
+// GetObject streams an object; the caller must close the reader
+func (s *S3Client) GetObject(key string) (io.ReadCloser, *ObjectInfo, error) {
+	resp, err := s.do(http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, objectInfoFromResponse(key, resp), nil
+}
+
+// PutObject uploads an object from memory
+func (s *S3Client) PutObject(key string, content []byte, contentType string) error {
+	resp, err := s.do(http.MethodPut, key, content, contentType)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// PutObjectStream uploads an object of known length without buffering it
+// in memory. The payload is sent unsigned, so use an HTTPS endpoint.
+func (s *S3Client) PutObjectStream(key string, body io.Reader, length int64, contentType string) error {
+	if !s.IsConfigured() {
+		return fmt.Errorf("S3 storage is not configured")
+	}
+	resp, err := s.send(http.MethodPut, s.objectURL(s.FullKey(key)), key, body, length, "UNSIGNED-PAYLOAD", contentType)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ListObjects returns all objects whose key starts with prefix. Keys are
+// returned relative to the configured prefix.
+func (s *S3Client) ListObjects(prefix string) ([]ObjectInfo, error) {
+	if !s.IsConfigured() {
+		return nil, fmt.Errorf("S3 storage is not configured")
+	}
+
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.FullKey(prefix)}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u := s.bucketURL()
+		u.RawQuery = canonicalQuery(query)
+
+		resp, err := s.send(http.MethodGet, u, prefix, nil, 0, sha256Hex(nil), "")
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Contents []struct {
+				Key          string
+				Size         int64
+				ETag         string
+				LastModified time.Time
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse S3 listing: %v", err)
+		}
+
+		for _, item := range result.Contents {
+			key := item.Key
+			if s.config.Prefix != "" {
+				key = strings.TrimPrefix(key, s.config.Prefix+"/")
+			}
+			objects = append(objects, ObjectInfo{
+				Key:          key,
+				Size:         item.Size,
+				ETag:         strings.Trim(item.ETag, `"`),
+				LastModified: item.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// DeleteObject removes an object; deleting a missing object is not an error
+func (s *S3Client) DeleteObject(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, "")
+	if err != nil && err != ErrObjectNotFound {
+		return err
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+// Name implements Driver
+func (s *S3Client) Name() string { return DriverS3 }
+
+// Put implements Driver
+func (s *S3Client) Put(key string, r io.Reader, size int64, contentType string) error {
+	return s.PutObjectStream(key, r, size, contentType)
+}
+
+// Get implements Driver
+func (s *S3Client) Get(key string) (io.ReadCloser, *ObjectInfo, error) { return s.GetObject(key) }
+
+// Stat implements Driver
+func (s *S3Client) Stat(key string) (*ObjectInfo, error) { return s.HeadObject(key) }
+
+// List implements Driver
+func (s *S3Client) List(prefix string) ([]ObjectInfo, error) { return s.ListObjects(prefix) }
+
+// Delete implements Driver
+func (s *S3Client) Delete(key string) error { return s.DeleteObject(key) }
+
+// URL implements Driver
+func (s *S3Client) URL(key string) string { return s.PublicURL(key) }
+
+func (s *S3Client) do(method, key string, body []byte, contentType string) (*http.Response, error) {
+	if !s.IsConfigured() {
+		return nil, fmt.Errorf("S3 storage is not configured")
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	return s.send(method, s.objectURL(s.FullKey(key)), key, reader, int64(len(body)), sha256Hex(body), contentType)
+}
+
+// send signs and executes a request. Streaming bodies pass
+// "UNSIGNED-PAYLOAD" as the payload hash.
+func (s *S3Client) send(method string, u *url.URL, key string, body io.Reader, length int64, payloadHash, contentType string) (*http.Response, error) {
+	if body == nil {
+		body = http.NoBody
+	}
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	headers := map[string]string{
+		"host":                 u.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           now.Format("20060102T150405Z"),
+	}
+	if contentType != "" {
+		headers["content-type"] = contentType
+	}
+	if s.config.SessionToken != "" {
+		headers["x-amz-security-token"] = s.config.SessionToken
+	}
+
+	signature := s.sign(method, u, headers, payloadHash, now)
+	for k, v := range headers {
+		if k != "host" {
+			req.Header.Set(k, v)
+		}
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKey, s.credentialScope(now), signedHeaderNames(headers), signature))
+	if length > 0 {
+		req.ContentLength = length
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request failed: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrObjectNotFound
+	}
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("S3 %s %s returned %d: %s", method, key, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+func (s *S3Client) credentialScope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+// sign computes the SigV4 signature for a request with the given signed headers
+func (s *S3Client) sign(method string, u *url.URL, headers map[string]string, payloadHash string, now time.Time) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(names, ";"),
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		s.credentialScope(now),
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.config.SecretKey), now.Format("20060102"))
+	signingKey = hmacSHA256(signingKey, s.config.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+}
+
+func objectInfoFromResponse(key string, resp *http.Response) *ObjectInfo {
+	info := &ObjectInfo{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.LastModified = modified
+	}
+	return info
+}
+
+func signedHeaderNames(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ";")
+}
+
+// canonicalQuery encodes query parameters the way SigV4 expects (sorted, %20 for spaces)
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range values[k] {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapeKey URI-encodes each segment of an object key
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func uriEncode(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Global S3 client instance
+var (
+	globalS3Client     *S3Client
+	globalS3ClientOnce sync.Once
+)
+
+// GetGlobalS3Client returns the S3 client built from the environment
+func GetGlobalS3Client() *S3Client {
+	globalS3ClientOnce.Do(func() {
+		globalS3Client = NewS3Client(LoadS3ConfigFromEnv())
+		if globalS3Client.IsConfigured() {
+			slog.Info("S3 storage enabled", "bucket", globalS3Client.Bucket())
+		}
+	})
+	return globalS3Client
+}
+
+// IsS3Enabled reports whether the S3 backend is configured
+func IsS3Enabled() bool {
+	return GetGlobalS3Client().IsConfigured()
+}
@@ -0,0 +1,140 @@
+// Package storage stores media, off-site backups and exports in the local
+// filesystem or a remote object store. Every driver streams object bodies
+// and addresses objects by slash-separated keys.
+package storage
+
+import (
+	"blog-backend/internal/config"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Storage drivers
+const (
+	DriverLocal  = "local"
+	DriverS3     = "s3"
+	DriverAzure  = "azure"
+	DriverGCS    = "gcs"
+	DriverWebDAV = "webdav"
+)
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// ErrObjectNotFound is returned when an object does not exist
+var ErrObjectNotFound = fmt.Errorf("object not found")
+
+// Driver stores objects under keys such as "uploads/images/a.png"
+type Driver interface {
+	// Name is the driver name, also used as the scheme of stored file paths
+	Name() string
+	// Put stores size bytes read from r, replacing any existing object
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Get streams an object; the caller must close the reader
+	Get(key string) (io.ReadCloser, *ObjectInfo, error)
+	Stat(key string) (*ObjectInfo, error)
+	// List returns the objects whose key starts with prefix
+	List(prefix string) ([]ObjectInfo, error)
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(key string) error
+	// URL returns the address visitors fetch an object from
+	URL(key string) string
+}
+
+// Presigner is implemented by drivers that can hand out URLs for uploading
+// straight to the store, bypassing the backend
+type Presigner interface {
+	PresignPutObject(key, contentType string, contentLength int64, expires time.Duration) (string, map[string]string, error)
+}
+
+// Open builds the driver selected by the storage settings
+func Open(cfg config.StorageConfig) (Driver, error) {
+	switch cfg.Driver {
+	case "", DriverLocal:
+		return NewLocal(cfg.UploadDir, "/uploads"), nil
+	case DriverS3:
+		client := NewS3Client(LoadS3ConfigFromEnv())
+		if !client.IsConfigured() {
+			return nil, fmt.Errorf("S3 storage has no bucket or credentials")
+		}
+		return client, nil
+	case DriverAzure:
+		return NewAzure(cfg.Azure)
+	case DriverGCS:
+		return NewGCS(cfg.GCS)
+	case DriverWebDAV:
+		return NewWebDAV(cfg.WebDAV)
+	}
+	return nil, fmt.Errorf("unsupported storage driver %q", cfg.Driver)
+}
+
+// CleanKey normalizes a key and rejects keys that would escape the store
+func CleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(key, "\\", "/")
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("invalid object key %q", key)
+		}
+	}
+	cleaned := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if cleaned == "" {
+		return "", fmt.Errorf("empty object key")
+	}
+	return cleaned, nil
+}
+
+var (
+	globalDriver     Driver
+	globalDriverErr  error
+	globalDriverOnce sync.Once
+)
+
+// GetGlobalDriver returns the driver configured with STORAGE_DRIVER. A
+// remote driver that cannot be set up falls back to local storage, and the
+// error is kept for IsRemote's callers to report.
+func GetGlobalDriver() Driver {
+	globalDriverOnce.Do(func() {
+		cfg := config.Get().Storage
+		globalDriver, globalDriverErr = Open(cfg)
+		if globalDriverErr != nil {
+			slog.Error("Failed to set up storage driver, using local storage", "driver", cfg.Driver, "error", globalDriverErr)
+			globalDriver = NewLocal(cfg.UploadDir, "/uploads")
+			return
+		}
+		if globalDriver.Name() != DriverLocal {
+			slog.Info("Remote storage enabled", "driver", globalDriver.Name())
+		}
+	})
+	return globalDriver
+}
+
+// IsRemote reports whether media, backups and exports go to a remote store
+func IsRemote() bool {
+	return GetGlobalDriver().Name() != DriverLocal
+}
+
+// DriverError returns why the configured driver could not be set up
+func DriverError() error {
+	GetGlobalDriver()
+	return globalDriverErr
+}
+
+// prefixedKey applies a configured key prefix
+func prefixedKey(prefix, key string) string {
+	key = strings.TrimLeft(key, "/")
+	if prefix == "" {
+		return key
+	}
+	return prefix + "/" + key
+}
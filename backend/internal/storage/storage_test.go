@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"blog-backend/internal/config"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCleanKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"uploads/a.png":     "uploads/a.png",
+		"/uploads//a.png":   "uploads/a.png",
+		"uploads\\b\\c.txt": "uploads/b/c.txt",
+	} {
+		if got, err := CleanKey(key); err != nil || got != want {
+			t.Errorf("CleanKey(%q) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	for _, key := range []string{"", "/", "../etc/passwd", "uploads/../../x", "a\\..\\b"} {
+		if _, err := CleanKey(key); err == nil {
+			t.Errorf("CleanKey(%q) should fail", key)
+		}
+	}
+}
+
+func TestLocalDriver(t *testing.T) {
+	driver := NewLocal(t.TempDir(), "/uploads")
+	testDriver(t, driver)
+	if url := driver.URL("images/a.png"); url != "/uploads/images/a.png" {
+		t.Errorf("unexpected URL %q", url)
+	}
+}
+
+func TestGCSDriver(t *testing.T) {
+	server := httptest.NewServer(newFakeGCS("media"))
+	defer server.Close()
+
+	driver, err := NewGCS(config.GCSConfig{Bucket: "media", Endpoint: server.URL, Prefix: "site", PublicURL: "https://cdn.example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDriver(t, driver)
+	if url := driver.URL("images/a b.png"); url != "https://cdn.example.com/site/images/a%20b.png" {
+		t.Errorf("unexpected URL %q", url)
+	}
+}
+
+// testDriver runs the same round trip against any driver
+func testDriver(t *testing.T, driver Driver) {
+	t.Helper()
+	content := "hello storage"
+	if err := driver.Put("docs/a.txt", strings.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Put("other/b.txt", strings.NewReader("b"), 1, "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+
+	body, info, err := driver.Get("docs/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != content || info.Size != int64(len(content)) {
+		t.Errorf("unexpected object %q (%+v)", data, info)
+	}
+	if info, err := driver.Stat("docs/a.txt"); err != nil || info.Size != int64(len(content)) {
+		t.Errorf("unexpected stat %+v, %v", info, err)
+	}
+
+	objects, err := driver.List("docs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Key != "docs/a.txt" {
+		t.Errorf("unexpected listing %+v", objects)
+	}
+
+	if err := driver.Delete("docs/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := driver.Stat("docs/a.txt"); err != ErrObjectNotFound {
+		t.Errorf("expected ErrObjectNotFound after delete, got %v", err)
+	}
+	if err := driver.Delete("docs/a.txt"); err != nil {
+		t.Errorf("deleting a missing object should succeed, got %v", err)
+	}
+}
+
+// fakeGCS serves the subset of the GCS JSON API the driver uses
+type fakeGCS struct {
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeGCS(bucket string) *fakeGCS {
+	return &fakeGCS{bucket: bucket, objects: map[string][]byte{}, types: map[string]string{}}
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	objectPrefix := "/storage/v1/b/" + f.bucket + "/o"
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload"+objectPrefix:
+		name := r.URL.Query().Get("name")
+		data, _ := io.ReadAll(r.Body)
+		f.objects[name], f.types[name] = data, r.Header.Get("Content-Type")
+		json.NewEncoder(w).Encode(f.resource(name))
+	case r.Method == http.MethodGet && r.URL.Path == objectPrefix:
+		var items []map[string]string
+		for name := range f.objects {
+			if strings.HasPrefix(name, r.URL.Query().Get("prefix")) {
+				items = append(items, f.resource(name))
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"items": items})
+	case strings.HasPrefix(r.URL.Path, objectPrefix+"/"):
+		name := strings.TrimPrefix(r.URL.Path, objectPrefix+"/")
+		data, ok := f.objects[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			delete(f.objects, name)
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Query().Get("alt") == "media":
+			w.Header().Set("Content-Type", f.types[name])
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.Write(data)
+		default:
+			json.NewEncoder(w).Encode(f.resource(name))
+		}
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func (f *fakeGCS) resource(name string) map[string]string {
+	return map[string]string{"name": name, "size": strconv.Itoa(len(f.objects[name])), "contentType": f.types[name]}
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"blog-backend/internal/config"
+	"blog-backend/internal/security"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// WebDAV stores objects as files in a WebDAV collection, creating
+// sub-collections as needed
+type WebDAV struct {
+	baseURL   *url.URL
+	username  string
+	password  string
+	publicURL string
+
+	httpClient *http.Client
+}
+
+// NewWebDAV creates a WebDAV driver
+func NewWebDAV(cfg config.WebDAVConfig) (*WebDAV, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("WebDAV URL must use http or https")
+	}
+	password, err := security.ResolveSecret(cfg.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve WebDAV password: %v", err)
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/"
+	u.RawPath = ""
+	return &WebDAV{
+		baseURL:    u,
+		username:   cfg.Username,
+		password:   password,
+		publicURL:  strings.TrimRight(cfg.PublicURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Minute},
+	}, nil
+}
+
+// Name implements Driver
+func (w *WebDAV) Name() string { return DriverWebDAV }
+
+// Put implements Driver
+func (w *WebDAV) Put(key string, r io.Reader, size int64, contentType string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	// Create the collection and its parents on first use; 405 means one
+	// already exists
+	collection := ""
+	for _, segment := range strings.Split(path.Dir("/"+key), "/") {
+		if segment == "" {
+			continue
+		}
+		collection += segment + "/"
+		if resp, err := w.do("MKCOL", collection, nil, 0, nil); err == nil {
+			resp.Body.Close()
+		}
+	}
+	if collection == "" {
+		if resp, err := w.do("MKCOL", "", nil, 0, nil); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	headers := map[string]string{}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	resp, err := w.do(http.MethodPut, key, r, size, headers)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get implements Driver
+func (w *WebDAV) Get(key string) (io.ReadCloser, *ObjectInfo, error) {
+	resp, err := w.do(http.MethodGet, key, nil, 0, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, objectInfoFromResponse(key, resp), nil
+}
+
+// Stat implements Driver
+func (w *WebDAV) Stat(key string) (*ObjectInfo, error) {
+	resp, err := w.do(http.MethodHead, key, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return objectInfoFromResponse(key, resp), nil
+}
+
+// List walks the collections below the prefix's directory one level at a
+// time, since many servers refuse infinite-depth PROPFIND
+func (w *WebDAV) List(prefix string) ([]ObjectInfo, error) {
+	start := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		start = prefix[:i+1]
+	}
+	var objects []ObjectInfo
+	pending := []string{start}
+	for len(pending) > 0 {
+		collection := pending[0]
+		pending = pending[1:]
+		entries, err := w.propfind(collection)
+		if err == ErrObjectNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			switch {
+			case entry.collection && entry.Key != collection && strings.HasPrefix(entry.Key, start):
+				if strings.HasPrefix(entry.Key, prefix) || strings.HasPrefix(prefix, entry.Key) {
+					pending = append(pending, entry.Key)
+				}
+			case !entry.collection && strings.HasPrefix(entry.Key, prefix):
+				objects = append(objects, entry.ObjectInfo)
+			}
+		}
+	}
+	return objects, nil
+}
+
+// Delete implements Driver
+func (w *WebDAV) Delete(key string) error {
+	resp, err := w.do(http.MethodDelete, key, nil, 0, nil)
+	if err == ErrObjectNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// URL implements Driver
+func (w *WebDAV) URL(key string) string {
+	if w.publicURL != "" {
+		return w.publicURL + "/" + escapeKey(strings.TrimLeft(key, "/"))
+	}
+	return w.resolve(key).String()
+}
+
+type webdavEntry struct {
+	ObjectInfo
+	collection bool
+}
+
+func (w *WebDAV) propfind(collection string) ([]webdavEntry, error) {
+	body := strings.NewReader(`<?xml version="1.0"?><d:propfind xmlns:d="DAV:"><d:prop><d:resourcetype/><d:getcontentlength/><d:getlastmodified/><d:getcontenttype/><d:getetag/></d:prop></d:propfind>`)
+	resp, err := w.do("PROPFIND", collection, body, int64(body.Len()), map[string]string{"Depth": "1", "Content-Type": "application/xml"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Responses []struct {
+			Href       string    `xml:"href"`
+			Collection *struct{} `xml:"propstat>prop>resourcetype>collection"`
+			Length     int64     `xml:"propstat>prop>getcontentlength"`
+			Modified   string    `xml:"propstat>prop>getlastmodified"`
+			Type       string    `xml:"propstat>prop>getcontenttype"`
+			ETag       string    `xml:"propstat>prop>getetag"`
+		} `xml:"response"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse WebDAV listing: %v", err)
+	}
+
+	basePath := w.baseURL.Path
+	var entries []webdavEntry
+	for _, item := range result.Responses {
+		href, err := url.Parse(item.Href)
+		if err != nil {
+			continue
+		}
+		key, ok := strings.CutPrefix(href.Path, basePath)
+		if !ok {
+			continue
+		}
+		modified, _ := http.ParseTime(item.Modified)
+		entries = append(entries, webdavEntry{
+			ObjectInfo: ObjectInfo{
+				Key:          key,
+				Size:         item.Length,
+				ContentType:  item.Type,
+				ETag:         strings.Trim(item.ETag, `"`),
+				LastModified: modified,
+			},
+			collection: item.Collection != nil,
+		})
+	}
+	return entries, nil
+}
+
+func (w *WebDAV) resolve(key string) *url.URL {
+	u := *w.baseURL
+	u.Path += strings.TrimLeft(key, "/")
+	return &u
+}
+
+func (w *WebDAV) do(method, key string, body io.Reader, size int64, headers map[string]string) (*http.Response, error) {
+	if body == nil {
+		body = http.NoBody
+	}
+	req, err := http.NewRequest(method, w.resolve(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if size > 0 {
+		req.ContentLength = size
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if w.username != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("WebDAV request failed: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrObjectNotFound
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("WebDAV %s %s returned %d", method, key, resp.StatusCode)
+	}
+	return resp, nil
+}
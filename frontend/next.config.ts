@@ -12,6 +12,13 @@ const nextConfig: NextConfig = {
   images: {
     unoptimized: true,
   },
+  // Per-language sitemaps and feeds, linked from the sitemap index at /sitemap.xml
+  async rewrites() {
+    return [
+      { source: '/sitemap-:locale([a-z]{2}).xml', destination: '/sitemaps/:locale' },
+      { source: '/feed-:locale([a-z]{2}).xml', destination: '/feeds/:locale' },
+    ];
+  },
   webpack: (config, { isServer }) => {
     // Monaco Editor webpack plugin configuration
     if (!isServer) {
@@ -12,6 +12,7 @@ import { Tabs, TabsContent, TabsList, TabsTrigger } from "@/components/ui/tabs"
 import { apiClient, Article, AnalyticsData } from "@/lib/api"
 import { GeographicChart } from "@/components/analytics/geographic-chart"
 import { BrowserChart } from "@/components/analytics/browser-chart"
+import { LanguageBalance } from "@/components/analytics/language-balance"
 import { SEODashboard } from "@/components/admin/seo-dashboard"
 import { KeywordManager } from "@/components/admin/keyword-manager"
 import { SEOAutoChecker } from "@/components/admin/seo-auto-checker"
@@ -283,6 +284,9 @@ export default function AnalyticsPage({ params }: AnalyticsPageProps) {
             </Card>
           )}
 
+          {/* Articles by language */}
+          <LanguageBalance stats={analytics?.language_stats || []} locale={locale} />
+
           {/* Enhanced Analytics Section */}
           <div className="grid grid-cols-1 lg:grid-cols-2 gap-8">
             {/* Geographic Analytics */}
@@ -0,0 +1,49 @@
+"use client"
+
+import { useEffect, useState } from "react"
+import { ArrowLeft, BookOpen } from "lucide-react"
+import { Link } from '@/i18n/routing'
+import { Button } from "@/components/ui/button"
+import { ApiDocs } from "@/components/admin/api-docs"
+
+interface ApiDocsPageProps {
+  params: Promise<{ locale: string }>
+}
+
+export default function ApiDocsPage({ params }: ApiDocsPageProps) {
+  const [locale, setLocale] = useState<string>('zh')
+
+  useEffect(() => {
+    params.then(({ locale: paramLocale }) => {
+      setLocale(paramLocale)
+    })
+  }, [params])
+
+  return (
+    <div className="max-w-7xl mx-auto">
+      <div className="flex items-center gap-4 mb-8">
+        <Link href="/admin">
+          <Button variant="outline" size="sm">
+            <ArrowLeft className="h-4 w-4 mr-2" />
+            {locale === 'zh' ? '返回' : 'Back'}
+          </Button>
+        </Link>
+        <div>
+          <div className="flex items-center gap-2 mb-2">
+            <BookOpen className="h-8 w-8 text-primary" />
+            <h1 className="text-3xl font-bold">
+              {locale === 'zh' ? 'API 文档' : 'API Documentation'}
+            </h1>
+          </div>
+          <p className="text-muted-foreground">
+            {locale === 'zh'
+              ? '由路由生成的 OpenAPI 描述，可直接试用各个接口'
+              : 'The OpenAPI description generated from the routes, with every endpoint ready to try'}
+          </p>
+        </div>
+      </div>
+
+      <ApiDocs locale={locale} />
+    </div>
+  )
+}
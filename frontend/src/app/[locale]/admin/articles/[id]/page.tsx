@@ -2,6 +2,7 @@
 
 import { useEffect, useState } from "react"
 import { ArticleDiffEditor } from "@/components/admin/article-diff-editor"
+import { ArticlePresenceBanner } from "@/components/admin/article-presence"
 import { apiClient, Article } from "@/lib/api"
 
 interface EditArticlePageProps {
@@ -52,5 +53,10 @@ export default function EditArticlePage({ params }: EditArticlePageProps) {
     )
   }
 
-  return <ArticleDiffEditor article={article} isEditing locale={locale} />
+  return (
+    <>
+      <ArticlePresenceBanner articleId={article.id} mode="editing" locale={locale} />
+      <ArticleDiffEditor article={article} isEditing locale={locale} />
+    </>
+  )
 }
\ No newline at end of file
@@ -2,7 +2,7 @@
 
 import { useEffect, useState } from "react"
 import { motion } from "framer-motion"
-import { Plus, Edit, Trash2, Settings, Eye, BarChart3, Download, Check, X, Pin, PinOff } from "lucide-react"
+import { Plus, Edit, Trash2, Settings, Eye, BarChart3, Download, Check, X, Pin, PinOff, MessageSquare } from "lucide-react"
 import { useTranslations } from 'next-intl'
 import { Link } from '@/i18n/routing'
 import { Button } from "@/components/ui/button"
@@ -16,6 +16,8 @@ import { useThrottle } from "@/hooks/use-debounce"
 import { Clock, Calendar } from "lucide-react"
 import { AIToolsDropdown } from "@/components/admin/ai-tools-dropdown"
 import { ContentActionsDropdown } from "@/components/admin/content-actions-dropdown"
+import { BackupStatusCard } from "@/components/admin/backup-status-card"
+import { DiskUsageCard } from "@/components/admin/disk-usage-card"
 
 interface AdminPageProps {
   params: Promise<{ locale: string }>
@@ -89,15 +91,7 @@ export default function AdminPage({ params }: AdminPageProps) {
     try {
       const newPinnedState = !article.is_pinned
       
-      // If trying to pin, check current pinned count
-      if (newPinnedState) {
-        const currentPinnedCount = articles.filter(a => a.is_pinned && a.id !== article.id).length
-        if (currentPinnedCount >= 2) {
-          alert(locale === 'zh' ? '最多只能置顶2篇文章' : 'Maximum 2 articles can be pinned')
-          return
-        }
-      }
-      
+      // The server enforces the site's limit on pinned articles
       const updatedData = {
         ...article,
         is_pinned: newPinnedState,
@@ -133,8 +127,8 @@ export default function AdminPage({ params }: AdminPageProps) {
       
       // Handle specific error messages
       let errorMessage = locale === 'zh' ? '置顶设置失败' : 'Failed to toggle pin'
-      if (error.response?.data?.error === 'Maximum 2 articles can be pinned') {
-        errorMessage = locale === 'zh' ? '最多只能置顶2篇文章' : 'Maximum 2 articles can be pinned'
+      if (error instanceof Error && error.message.includes(' 400 ')) {
+        errorMessage = locale === 'zh' ? '置顶文章数量已达上限' : 'The maximum number of pinned articles is reached'
       }
       
       alert(errorMessage)
@@ -431,6 +425,12 @@ export default function AdminPage({ params }: AdminPageProps) {
             </Card>
           </div>
 
+          {/* Backup Status */}
+          <BackupStatusCard locale={locale} />
+
+          {/* Disk Usage Warnings */}
+          <DiskUsageCard locale={locale} />
+
           {/* Articles Management */}
           <div className="mb-12">
             <div className="flex flex-col gap-4 mb-6 lg:flex-row lg:justify-between lg:items-center">
@@ -583,6 +583,12 @@ export default function AdminPage({ params }: AdminPageProps) {
                                 {article.view_count}
                               </div>
                             )}
+                            {article.discussion && (
+                              <div className="flex items-center gap-1 text-sm text-muted-foreground" title={article.discussion.provider}>
+                                <MessageSquare className="h-3 w-3" />
+                                {article.discussion.comments}
+                              </div>
+                            )}
                           </div>
                           <div className="flex gap-2">
                             <Button 
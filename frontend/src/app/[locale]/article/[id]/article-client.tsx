@@ -19,6 +19,7 @@ import { useAuth } from '@/contexts/auth-context'
 import { Link, routing } from '@/i18n/routing'
 import { useDynamicTheme } from '@/contexts/dynamic-theme-context'
 import { RelatedArticles } from '@/components/related-articles'
+import { getArticleSlug, getLanguageDirection } from '@/lib/seo-locale-utils'
 
 interface ArticlePageClientProps {
   id: string
@@ -191,9 +192,11 @@ export default function ArticlePageClient({ id, locale, initialArticle, initialS
 
   const siteUrl = getSiteUrl()
   const defaultLocale = routing.defaultLocale
+  const articleSlug = encodeURIComponent(getArticleSlug(article, locale))
+  const contentDir = article.dir || getLanguageDirection(locale)
   const articleUrl = locale === defaultLocale
-    ? `${siteUrl}/article/${article.seo_slug || id}`
-    : `${siteUrl}/${locale}/article/${article.seo_slug || id}`
+    ? `${siteUrl}/article/${articleSlug}`
+    : `${siteUrl}/${locale}/article/${articleSlug}`
 
   return (
     <div className="container mx-auto px-4 py-8 max-w-4xl">
@@ -254,12 +257,12 @@ export default function ArticlePageClient({ id, locale, initialArticle, initialS
                   <Badge variant="secondary">{article.category.name}</Badge>
                 </div>
                 
-                <h1 className="text-4xl md:text-5xl font-bold leading-tight">
+                <h1 className="text-4xl md:text-5xl font-bold leading-tight" dir={contentDir}>
                   {article.title}
                 </h1>
                 
                 {article.summary && (
-                  <p className="text-xl text-muted-foreground leading-relaxed">
+                  <p className="text-xl text-muted-foreground leading-relaxed" dir={contentDir}>
                     {article.summary}
                   </p>
                 )}
@@ -279,7 +282,7 @@ export default function ArticlePageClient({ id, locale, initialArticle, initialS
           {/* Article Content */}
           <div className={`enhanced-article-container ${isDynamicThemeActive && analysisResult ? 'dynamic-theme-active' : ''}`}>
             <div className="enhanced-article-content">
-              <article className="prose prose-lg dark:prose-invert max-w-none">
+              <article className="prose prose-lg dark:prose-invert max-w-none" dir={contentDir}>
                 <MarkdownRenderer 
                   content={article.content} 
                   includeStructuredData={true}
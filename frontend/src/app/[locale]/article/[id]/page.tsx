@@ -1,30 +1,33 @@
 import type { Metadata } from "next";
 import { getTranslations } from 'next-intl/server'
-import { notFound } from 'next/navigation'
+import { notFound, permanentRedirect } from 'next/navigation'
 import ArticlePageClient from './article-client'
 import { generateArticleMetadata } from '@/lib/metadata-utils'
-import { fetchArticle, fetchSettings } from '@/lib/server-api'
+import { fetchArticle, fetchBootstrapConfig, fetchSettings } from '@/lib/server-api'
 import { ArticleStructuredData, BreadcrumbStructuredData } from '@/components/seo/structured-data'
-import { getSiteUrl } from '@/lib/config'
+import { getMediaUrl, getSiteUrl } from '@/lib/config'
 import { routing } from '@/i18n/routing'
-import { getArticleAvailableLocales } from '@/lib/seo-locale-utils'
+import { getArticleAvailableLocales, getArticleLocalizedPaths, getArticleSlug } from '@/lib/seo-locale-utils'
 
 interface ArticlePageProps {
   params: Promise<{ id: string; locale: string }>
+  searchParams: Promise<{ preview?: string }>
 }
 
-export async function generateMetadata({ params }: ArticlePageProps): Promise<Metadata> {
+export async function generateMetadata({ params, searchParams }: ArticlePageProps): Promise<Metadata> {
   const { id, locale } = await params
+  const { preview } = await searchParams
   const t = await getTranslations({ locale })
 
   try {
-    const article = await fetchArticle(id, locale)
+    const article = await fetchArticle(id, locale, preview)
     const availableLocales = getArticleAvailableLocales(article)
 
     return generateArticleMetadata({
       locale,
-      canonical: `/article/${article.seo_slug || id}`,
+      canonical: `/article/${encodeURIComponent(getArticleSlug(article, locale))}`,
       availableLocales,
+      localizedPaths: getArticleLocalizedPaths(article, availableLocales),
       article: {
         title: article.title,
         summary: article.summary,
@@ -35,10 +38,12 @@ export async function generateMetadata({ params }: ArticlePageProps): Promise<Me
         created_at: article.created_at,
         updated_at: article.updated_at,
         default_lang: article.default_lang,
+        canonical_url: article.canonical_url,
         translations: article.translations,
       },
       robots: {
-        index: true,
+        // Preview links show scheduled articles, which are not indexed yet
+        index: !preview,
         follow: true,
       }
     })
@@ -57,15 +62,17 @@ export async function generateMetadata({ params }: ArticlePageProps): Promise<Me
   }
 }
 
-export default async function ArticlePage({ params }: ArticlePageProps) {
+export default async function ArticlePage({ params, searchParams }: ArticlePageProps) {
   const { id, locale } = await params
+  const { preview } = await searchParams
 
   // 服务端获取文章数据和设置（SEO 关键：确保初始 HTML 包含完整内容）
-  let article, settings
+  let article, settings, bootstrap
   try {
-    ;[article, settings] = await Promise.all([
-      fetchArticle(id, locale),
+    ;[article, settings, bootstrap] = await Promise.all([
+      fetchArticle(id, locale, preview),
       fetchSettings(locale),
+      fetchBootstrapConfig().catch(() => null),
     ])
   } catch (error) {
     // 仅 404 显示 notFound 页面，其他错误向上抛出让 Next.js error boundary 处理
@@ -75,31 +82,53 @@ export default async function ArticlePage({ params }: ArticlePageProps) {
     throw error
   }
 
+  // Slugs are translated, and old slugs or another language's slug move to the current one.
+  // Numeric IDs keep working as they are.
+  let requestedSlug = id
+  try {
+    requestedSlug = decodeURIComponent(id)
+  } catch {
+    // Already decoded
+  }
+  const localeSlug = getArticleSlug(article, locale)
+  if (!/^\d+$/.test(requestedSlug) && requestedSlug !== localeSlug) {
+    permanentRedirect(`/${locale}/article/${encodeURIComponent(localeSlug)}`)
+  }
+
   // 服务端渲染结构化数据
   const siteUrl = getSiteUrl()
   const defaultLocale = routing.defaultLocale
   const articleAvailableLocales = getArticleAvailableLocales(article)
   const contentLocale = articleAvailableLocales.includes(locale) ? locale : (article.default_lang || defaultLocale)
+  const articleSlug = encodeURIComponent(getArticleSlug(article, contentLocale))
   const articleUrl = contentLocale === defaultLocale
-    ? `${siteUrl}/article/${article.seo_slug || id}`
-    : `${siteUrl}/${contentLocale}/article/${article.seo_slug || id}`
+    ? `${siteUrl}/article/${articleSlug}`
+    : `${siteUrl}/${contentLocale}/article/${articleSlug}`
   const homeUrl = contentLocale === defaultLocale ? siteUrl : `${siteUrl}/${contentLocale}`
   const t = await getTranslations({ locale })
 
   const breadcrumbItems = [
     { name: t('nav.home'), url: homeUrl },
+    ...(article.category_path || []).map(crumb => ({ name: crumb.name, url: `${homeUrl}?category=${crumb.id}` })),
     { name: article.title, url: articleUrl }
   ]
 
   return (
     <>
+      {bootstrap?.features.includes('webmention') && (
+        <link rel="webmention" href={`${bootstrap.api_base}/webmention`} />
+      )}
       <ArticleStructuredData
         title={article.title}
         description={article.summary || ''}
         url={articleUrl}
         datePublished={article.created_at}
         dateModified={article.updated_at}
-        author={settings?.site_title || 'Blog'}
+        author={article.author?.name || settings?.site_title || 'Blog'}
+        authorImage={article.author?.avatar_url && getMediaUrl(article.author.avatar_url)}
+        authorLinks={article.author?.social_links?.map(link => link.url)}
+        publisher={settings?.site_title || 'Blog'}
+        license={article.license_info?.url || article.license_info?.name}
         locale={contentLocale}
         content={article.content}
       />
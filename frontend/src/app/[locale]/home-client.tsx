@@ -51,7 +51,7 @@ export default function HomePageClient({ locale, initialArticles, initialCategor
     const fetchFiltered = async () => {
       try {
         setLoading(true)
-        const articlesData = await apiClient.getArticles({ categoryId: selectedCategory, lang: locale })
+        const articlesData = await apiClient.getArticles({ categoryId: selectedCategory, includeSubcategories: true, lang: locale })
         setArticles(articlesData)
       } catch (error) {
         console.error('Failed to fetch filtered articles:', error)
@@ -67,6 +67,14 @@ export default function HomePageClient({ locale, initialArticles, initialCategor
     checkRAGAvailability()
   }, [])
 
+  // Category breadcrumbs link here with ?category=<id>
+  useEffect(() => {
+    const categoryId = Number(new URLSearchParams(window.location.search).get('category'))
+    if (categoryId > 0) {
+      setSelectedCategory(categoryId)
+    }
+  }, [])
+
   const handleCategoryFilter = (categoryId: number | null) => {
     setSelectedCategory(categoryId)
   }
@@ -14,9 +14,10 @@ import { CustomCSSInjector } from '@/components/custom-css-injector'
 import { CustomJSInjector } from '@/components/custom-js-injector'
 import { LayoutBackground } from '@/components/layout-background'
 import '../globals.css'
-import { getSiteUrl, getApiUrl, getPublicApiUrl } from '@/lib/config'
+import { getSiteUrl, getApiUrl } from '@/lib/config'
 import { generateFaviconUrl } from '@/lib/favicon-utils'
 import { buildLocalizedPath, getSiteAvailableLocales } from '@/lib/seo-locale-utils'
+import { feedPath } from '@/lib/sitemap'
 
 const geistSans = Geist({
   variable: "--font-geist-sans",
@@ -86,7 +87,7 @@ export async function generateMetadata({ params }: { params: Promise<{ locale: s
       types: {
         'application/rss+xml': [
           {
-            url: `${getPublicApiUrl()}/rss?lang=${locale}`,
+            url: `${siteUrl}${feedPath(locale)}`,
             title: `${siteTitle} RSS Feed`,
           },
         ],
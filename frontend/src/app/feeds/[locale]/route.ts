@@ -0,0 +1,48 @@
+import { getApiUrl, getSiteUrl } from '@/lib/config'
+import { fetchSitemapSettings } from '@/lib/sitemap'
+import { getSiteAvailableLocales } from '@/lib/seo-locale-utils'
+
+export const dynamic = 'force-dynamic'
+
+// Serves /feed-<locale>.xml from the backend RSS feed of that language
+export async function GET(
+  request: Request,
+  { params }: { params: Promise<{ locale: string }> }
+) {
+  const { locale } = await params
+  const { settings } = await fetchSitemapSettings()
+
+  if (!getSiteAvailableLocales(settings).includes(locale)) {
+    return new Response('Not Found', { status: 404 })
+  }
+
+  // The backend builds the article links from the public host it is asked for
+  const siteUrl = new URL(getSiteUrl())
+  const query = new URLSearchParams({ lang: locale })
+  const limit = new URL(request.url).searchParams.get('limit')
+  if (limit) {
+    query.set('limit', limit)
+  }
+
+  try {
+    const response = await fetch(`${getApiUrl()}/rss?${query}`, {
+      headers: {
+        'Accept': 'application/rss+xml',
+        'X-Forwarded-Host': siteUrl.host,
+        'X-Forwarded-Proto': siteUrl.protocol.replace(':', ''),
+      },
+      cache: 'no-store',
+    })
+
+    const headers: Record<string, string> = {
+      'Content-Type': response.headers.get('Content-Type') || 'application/rss+xml; charset=utf-8',
+    }
+    if (response.ok) {
+      headers['Cache-Control'] = 'public, max-age=3600, s-maxage=3600'
+    }
+    return new Response(await response.text(), { status: response.status, headers })
+  } catch (error) {
+    console.error('Failed to fetch RSS feed:', error)
+    return new Response('Feed unavailable', { status: 502 })
+  }
+}
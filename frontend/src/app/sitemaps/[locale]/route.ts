@@ -0,0 +1,34 @@
+import { getSiteAvailableLocales } from '@/lib/seo-locale-utils'
+import {
+  buildSitemapUrls,
+  EMPTY_URLSET,
+  fetchSitemapSettings,
+  renderUrlset,
+  XML_HEADERS,
+} from '@/lib/sitemap'
+
+export const dynamic = 'force-dynamic'
+
+// Serves /sitemap-<locale>.xml: the pages of one language, each linked to its translations
+export async function GET(
+  _request: Request,
+  { params }: { params: Promise<{ locale: string }> }
+) {
+  const { locale } = await params
+  const { settings, blocked } = await fetchSitemapSettings()
+
+  if (blocked) {
+    return new Response(EMPTY_URLSET, {
+      headers: {
+        'Content-Type': 'application/xml; charset=utf-8',
+      },
+    })
+  }
+
+  if (!getSiteAvailableLocales(settings).includes(locale)) {
+    return new Response('Not Found', { status: 404 })
+  }
+
+  const urls = await buildSitemapUrls(settings)
+  return new Response(renderUrlset(urls.filter((url) => url.locale === locale)), { headers: XML_HEADERS })
+}
@@ -0,0 +1,82 @@
+'use client'
+
+import { useEffect, useRef, useState } from 'react'
+import { apiClient } from '@/lib/api'
+
+// Swagger UI is loaded from the CDN when the page opens rather than bundled
+const SWAGGER_UI_VERSION = '5.17.14'
+const SWAGGER_UI_BASE = `https://cdn.jsdelivr.net/npm/swagger-ui-dist@${SWAGGER_UI_VERSION}`
+
+type SwaggerUIBundle = (options: Record<string, unknown>) => unknown
+
+declare global {
+  interface Window {
+    SwaggerUIBundle?: SwaggerUIBundle
+  }
+}
+
+function loadSwaggerUI(): Promise<SwaggerUIBundle> {
+  if (window.SwaggerUIBundle) {
+    return Promise.resolve(window.SwaggerUIBundle)
+  }
+  if (!document.querySelector(`link[href="${SWAGGER_UI_BASE}/swagger-ui.css"]`)) {
+    const style = document.createElement('link')
+    style.rel = 'stylesheet'
+    style.href = `${SWAGGER_UI_BASE}/swagger-ui.css`
+    document.head.appendChild(style)
+  }
+  return new Promise((resolve, reject) => {
+    const script = document.createElement('script')
+    script.src = `${SWAGGER_UI_BASE}/swagger-ui-bundle.js`
+    script.crossOrigin = 'anonymous'
+    script.onload = () => (window.SwaggerUIBundle ? resolve(window.SwaggerUIBundle) : reject(new Error('Swagger UI did not load')))
+    script.onerror = () => reject(new Error('Swagger UI could not be loaded'))
+    document.body.appendChild(script)
+  })
+}
+
+interface ApiDocsProps {
+  locale: string
+}
+
+// Swagger UI for the OpenAPI description of the API. "Try it out" requests
+// are sent with the admin's login token.
+export function ApiDocs({ locale }: ApiDocsProps) {
+  const container = useRef<HTMLDivElement>(null)
+  const [error, setError] = useState<string | null>(null)
+
+  useEffect(() => {
+    let cancelled = false
+    Promise.all([apiClient.getOpenAPISpec(), loadSwaggerUI()])
+      .then(([spec, SwaggerUI]) => {
+        if (cancelled || !container.current) return
+        SwaggerUI({
+          spec,
+          domNode: container.current,
+          deepLinking: true,
+          requestInterceptor: (request: { headers: Record<string, string> }) => {
+            const token = apiClient.getToken()
+            if (token && !request.headers['Authorization']) {
+              request.headers['Authorization'] = `Bearer ${token}`
+            }
+            return request
+          },
+        })
+      })
+      .catch((err: Error) => {
+        if (!cancelled) setError(err.message)
+      })
+    return () => {
+      cancelled = true
+    }
+  }, [])
+
+  if (error) {
+    return (
+      <div className="rounded-md border border-destructive/50 p-4 text-destructive">
+        {locale === 'zh' ? `无法加载 API 文档：${error}` : `Failed to load the API documentation: ${error}`}
+      </div>
+    )
+  }
+  return <div ref={container} className="rounded-md bg-white" />
+}
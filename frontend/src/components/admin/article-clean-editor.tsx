@@ -9,7 +9,7 @@ import { Input } from "@/components/ui/input"
 import { Textarea } from "@/components/ui/textarea"
 import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from "@/components/ui/select"
 import { Label } from "@/components/ui/label"
-import { apiClient, Article, Category, ArticleTranslation } from "@/lib/api"
+import { apiClient, Article, ArticleSaveOptions, Category, ArticleTranslation, MissingTranslationsError } from "@/lib/api"
 import { 
   ArrowLeft,
   Save,
@@ -171,10 +171,17 @@ export function ArticleCleanEditor({ article, isEditing = false, locale = 'zh' }
         translations: translations.filter(t => t.title.trim() || t.content.trim() || t.summary.trim())
       }
 
-      if (isEditing && article) {
-        await apiClient.updateArticle(article.id, articleData)
-      } else {
-        await apiClient.createArticle(articleData)
+      const save = (options: ArticleSaveOptions = {}) => isEditing && article
+        ? apiClient.updateArticle(article.id, { ...articleData, ...options })
+        : apiClient.createArticle({ ...articleData, ...options })
+
+      try {
+        await save()
+      } catch (error) {
+        // Publishing can require translations; the author may publish anyway
+        if (!(error instanceof MissingTranslationsError)) throw error
+        if (!confirm(`This article is not translated into: ${error.missingLanguages.join(', ')}. Publish anyway?`)) return
+        await save({ allow_missing_translations: true })
       }
       router.push('/admin')
     } catch (error) {
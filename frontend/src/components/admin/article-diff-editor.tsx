@@ -26,7 +26,7 @@ import {
   DialogHeader,
   DialogTitle,
 } from "@/components/ui/dialog"
-import { apiClient, Article, Category, ArticleTranslation, SiteSettings } from "@/lib/api"
+import { apiClient, Article, ArticleSaveOptions, Category, ArticleTranslation, MissingTranslationsError, SiteSettings } from "@/lib/api"
 import { getMediaUrl } from "@/lib/config"
 import { 
   ArrowLeft,
@@ -263,6 +263,8 @@ export function ArticleDiffEditor({ article, isEditing = false, locale = 'zh' }:
         title: t.title || '',
         content: t.content || '',
         summary: t.summary || '',
+        seo_slug: t.seo_slug || '',
+        machine_translated: Boolean(t.machine_translated),
         created_at: t.created_at || new Date().toISOString(),
         updated_at: t.updated_at || new Date().toISOString()
       }))
@@ -691,6 +693,7 @@ export function ArticleDiffEditor({ article, isEditing = false, locale = 'zh' }:
               updateTranslation(targetLang, 'title', result.title)
               updateTranslation(targetLang, 'content', restoredContent)
               updateTranslation(targetLang, 'summary', result.summary)
+              markMachineTranslated(targetLang)
             }
 
             successCount++
@@ -794,6 +797,7 @@ export function ArticleDiffEditor({ article, isEditing = false, locale = 'zh' }:
           setFormData(prev => ({ ...prev, [field]: translatedText }))
         } else {
           updateTranslation(targetLanguage, field, translatedText)
+          markMachineTranslated(targetLanguage)
         }
       }
     } catch (error) {
@@ -1027,8 +1031,28 @@ export function ArticleDiffEditor({ article, isEditing = false, locale = 'zh' }:
 
       console.log('📤 Final articleData being sent to API:', articleData)
 
+      // Publishing can require translations; the author may publish anyway.
+      // Returns null when they choose to keep translating.
+      const save = async <T,>(request: (options: ArticleSaveOptions) => Promise<T>): Promise<T | null> => {
+        try {
+          return await request({})
+        } catch (error) {
+          if (!(error instanceof MissingTranslationsError)) throw error
+          const languages = error.missingLanguages.join(', ')
+          const message = normalizedLocale === 'zh'
+            ? `文章尚未翻译为：${languages}。仍要发布吗？`
+            : `This article is not translated into: ${languages}. Publish anyway?`
+          if (!confirm(message)) return null
+          return request({ allow_missing_translations: true })
+        }
+      }
+
       if (isEditing && article) {
-        const updatedArticle = await apiClient.updateArticle(article.id, articleData)
+        const updatedArticle = await save(options => apiClient.updateArticle(article.id, { ...articleData, ...options }))
+        if (!updatedArticle) {
+          setSaveStatus('idle')
+          return
+        }
         console.log('✅ Article updated successfully. Response:', updatedArticle)
         setSaveStatus('saved')
         playSuccessSound() // Play success sound
@@ -1054,7 +1078,11 @@ export function ArticleDiffEditor({ article, isEditing = false, locale = 'zh' }:
           setTimeout(() => setSaveStatus('idle'), 3000)
         }
       } else {
-        const newArticle = await apiClient.createArticle(articleData)
+        const newArticle = await save(options => apiClient.createArticle({ ...articleData, ...options }))
+        if (!newArticle) {
+          setSaveStatus('idle')
+          return
+        }
         setSaveStatus('saved')
         playSuccessSound() // Play success sound
         
@@ -1495,7 +1523,7 @@ export function ArticleDiffEditor({ article, isEditing = false, locale = 'zh' }:
     setTargetLanguage(tempLang)
   }, [sourceLanguage, targetLanguage])
 
-  const updateTranslation = (language: string | null, field: keyof Pick<ArticleTranslation, 'title' | 'content' | 'summary'>, value: string) => {
+  const updateTranslation = (language: string | null, field: keyof Pick<ArticleTranslation, 'title' | 'content' | 'summary' | 'seo_slug'>, value: string) => {
     // If language is null, don't update anything
     if (!language) return
     
@@ -1528,6 +1556,7 @@ export function ArticleDiffEditor({ article, isEditing = false, locale = 'zh' }:
           title: field === 'title' ? value : '',
           content: field === 'content' ? value : '',
           summary: field === 'summary' ? value : '',
+          seo_slug: field === 'seo_slug' ? value : '',
           created_at: new Date().toISOString(),
           updated_at: new Date().toISOString()
         }
@@ -1538,6 +1567,15 @@ export function ArticleDiffEditor({ article, isEditing = false, locale = 'zh' }:
     })
   }
 
+  // Flags a translation written by the translation service for review
+  const markMachineTranslated = (language: string | null) => {
+    if (!language || language === getDefaultLanguage()) return
+
+    setTranslations(prev => prev.map(t =>
+      t.language === language ? { ...t, machine_translated: true } : t
+    ))
+  }
+
   interface OnlineVideo {
     id: string
     url: string
@@ -1917,6 +1955,19 @@ export function ArticleDiffEditor({ article, isEditing = false, locale = 'zh' }:
             />
           </div>
 
+          {/* Translated SEO slug, the default language's is in the SEO settings */}
+          {!isDefaultSingleLanguage && (
+          <div className="space-y-2">
+            <Label htmlFor="translation-slug">{normalizedLocale === 'zh' ? 'URL 别名（此语言）' : 'URL Slug (this language)'}</Label>
+            <Input
+              id="translation-slug"
+              value={currentTranslation.seo_slug || ''}
+              onChange={(e) => updateTranslation(activeSingleLanguage, 'seo_slug', e.target.value)}
+              placeholder={formData.seo_slug || (normalizedLocale === 'zh' ? '留空则使用默认别名' : 'Leave empty to use the default slug')}
+            />
+          </div>
+          )}
+
           {/* Publication Time */}
           {isDefaultSingleLanguage && (
           <div className="space-y-2">
@@ -2148,6 +2199,17 @@ export function ArticleDiffEditor({ article, isEditing = false, locale = 'zh' }:
                 className="min-h-[100px] resize-none"
               />
             </div>
+            {language !== getDefaultLanguage() && (
+              <div className="space-y-2">
+                <Label htmlFor={`slug-${side}`}>{normalizedLocale === 'zh' ? 'URL 别名（此语言）' : 'URL Slug (this language)'}</Label>
+                <Input
+                  id={`slug-${side}`}
+                  value={translation.seo_slug || ''}
+                  onChange={(e) => updateTranslation(language, 'seo_slug', e.target.value)}
+                  placeholder={formData.seo_slug || (normalizedLocale === 'zh' ? '留空则使用默认别名' : 'Leave empty to use the default slug')}
+                />
+              </div>
+            )}
             {/* Cover Image Section - Only show on source side and only if it's default language */}
             {isSource && language === getDefaultLanguage() && (
               <div className="space-y-2">
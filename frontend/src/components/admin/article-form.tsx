@@ -15,7 +15,7 @@ import { Badge } from "@/components/ui/badge"
 import { Tabs, TabsContent, TabsList, TabsTrigger } from "@/components/ui/tabs"
 import { Separator } from "@/components/ui/separator"
 import { MarkdownEditor } from "@/components/markdown/markdown-editor"
-import { apiClient, Article, Category, ArticleTranslation, MediaLibrary } from "@/lib/api"
+import { apiClient, Article, ArticleSaveOptions, Category, ArticleTranslation, MissingTranslationsError, MediaLibrary } from "@/lib/api"
 import { Languages, Plus, Trash2, Pin, ImageIcon, X } from "lucide-react"
 import MediaSelector from "@/components/admin/media-selector"
 import { getMediaUrl } from "@/lib/config"
@@ -194,10 +194,17 @@ export function ArticleForm({ article, isEditing = false, locale = 'zh' }: Artic
         translations: translations.filter(t => t.title.trim() || t.content.trim() || t.summary.trim())
       }
 
-      if (isEditing && article) {
-        await apiClient.updateArticle(article.id, articleData)
-      } else {
-        await apiClient.createArticle(articleData)
+      const save = (options: ArticleSaveOptions = {}) => isEditing && article
+        ? apiClient.updateArticle(article.id, { ...articleData, ...options })
+        : apiClient.createArticle({ ...articleData, ...options })
+
+      try {
+        await save()
+      } catch (error) {
+        // Publishing can require translations; the author may publish anyway
+        if (!(error instanceof MissingTranslationsError)) throw error
+        if (!confirm(`This article is not translated into: ${error.missingLanguages.join(', ')}. Publish anyway?`)) return
+        await save({ allow_missing_translations: true })
       }
       router.push('/admin')
     } catch (error) {
@@ -0,0 +1,78 @@
+'use client'
+
+import { useEffect, useState } from 'react'
+import { apiClient, ArticlePresence, ArticlePresenceMode } from '@/lib/api'
+import { AlertTriangle, Eye } from 'lucide-react'
+
+interface ArticlePresenceBannerProps {
+  articleId: number
+  mode: ArticlePresenceMode
+  locale: string
+}
+
+// Reports this tab as having the article open and shows who else does,
+// warning while someone else is editing it
+export function ArticlePresenceBanner({ articleId, mode, locale }: ArticlePresenceBannerProps) {
+  const [others, setOthers] = useState<ArticlePresence[]>([])
+  const [editingByOthers, setEditingByOthers] = useState(false)
+
+  useEffect(() => {
+    const sessionId = crypto.randomUUID()
+    let timer: ReturnType<typeof setTimeout> | undefined
+    let stopped = false
+
+    const heartbeat = async () => {
+      let delay = 15
+      try {
+        const state = await apiClient.updateArticlePresence(articleId, sessionId, mode)
+        setOthers(state.others)
+        setEditingByOthers(state.editing_by_others)
+        delay = state.heartbeat_seconds
+      } catch (error) {
+        console.error('Failed to update article presence:', error)
+      }
+      if (!stopped) {
+        timer = setTimeout(heartbeat, delay * 1000)
+      }
+    }
+    heartbeat()
+
+    const leave = () => {
+      apiClient.leaveArticlePresence(articleId, sessionId).catch(() => {})
+    }
+    window.addEventListener('pagehide', leave)
+    return () => {
+      stopped = true
+      clearTimeout(timer)
+      window.removeEventListener('pagehide', leave)
+      leave()
+    }
+  }, [articleId, mode])
+
+  if (others.length === 0) {
+    return null
+  }
+
+  const names = Array.from(new Set(others.map(other => other.username))).join(', ')
+  const editors = Array.from(new Set(others.filter(other => other.mode === 'editing').map(other => other.username))).join(', ')
+
+  if (editingByOthers) {
+    return (
+      <div className="mb-4 flex items-start gap-2 rounded-md border border-amber-300 bg-amber-50 p-3 text-sm text-amber-900 dark:border-amber-700 dark:bg-amber-950 dark:text-amber-200">
+        <AlertTriangle className="h-4 w-4 mt-0.5 shrink-0" />
+        <span>
+          {locale === 'zh'
+            ? `${editors} 正在编辑这篇文章。同时保存会覆盖对方的修改。`
+            : `${editors} is also editing this article. Saving at the same time overwrites each other's changes.`}
+        </span>
+      </div>
+    )
+  }
+
+  return (
+    <div className="mb-4 flex items-center gap-2 text-sm text-muted-foreground">
+      <Eye className="h-4 w-4 shrink-0" />
+      <span>{locale === 'zh' ? `${names} 正在查看这篇文章` : `Also viewing: ${names}`}</span>
+    </div>
+  )
+}
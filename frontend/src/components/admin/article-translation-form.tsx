@@ -43,6 +43,7 @@ interface ArticleTranslationFormProps {
 }
 
 import { languageManager } from "@/services/translation/language-manager"
+import { getLanguageDirection } from "@/lib/seo-locale-utils"
 
 // Admin interface languages (hardcoded)
 const adminInterfaceLanguages = [
@@ -623,6 +624,7 @@ export function ArticleTranslationForm({ article, isEditing = false, locale = 'z
                         }}
                         placeholder={t('article.enterTitle')}
                         disabled={showPreview}
+                        dir={getLanguageDirection(targetLanguage)}
                       />
                     </div>
 
@@ -678,6 +680,7 @@ export function ArticleTranslationForm({ article, isEditing = false, locale = 'z
                         placeholder={t('article.enterSummary')}
                         rows={3}
                         disabled={showPreview}
+                        dir={getLanguageDirection(targetLanguage)}
                       />
                     </div>
 
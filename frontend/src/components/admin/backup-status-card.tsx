@@ -0,0 +1,65 @@
+'use client'
+
+import { useEffect, useState } from 'react'
+import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
+import { Badge } from '@/components/ui/badge'
+import { apiClient, BackupStatus } from '@/lib/api'
+import { Archive, AlertTriangle } from 'lucide-react'
+
+interface BackupStatusCardProps {
+  locale: string
+}
+
+export function BackupStatusCard({ locale }: BackupStatusCardProps) {
+  const [status, setStatus] = useState<BackupStatus | null>(null)
+
+  useEffect(() => {
+    apiClient.getBackupStatus()
+      .then(setStatus)
+      .catch(error => console.error('Failed to fetch backup status:', error))
+  }, [])
+
+  // Nothing to report until scheduled backups are configured or have run
+  if (!status || (!status.schedule && !status.last_success)) {
+    return null
+  }
+
+  const formatDate = (value?: string) =>
+    value ? new Date(value).toLocaleString(locale === 'zh' ? 'zh-CN' : 'en-US') : '-'
+
+  const lastSuccess = status.last_success
+
+  return (
+    <Card className="mb-8">
+      <CardHeader className="flex flex-row items-center justify-between space-y-0 pb-2">
+        <CardTitle className="text-sm font-medium">{locale === 'zh' ? '自动备份' : 'Scheduled Backups'}</CardTitle>
+        <Archive className="h-4 w-4 text-muted-foreground" />
+      </CardHeader>
+      <CardContent className="space-y-2 text-sm">
+        <div className="flex flex-wrap items-center gap-2">
+          <span className="text-muted-foreground">{locale === 'zh' ? '上次成功备份' : 'Last successful backup'}:</span>
+          <span className="font-medium">{formatDate(lastSuccess?.finished_at)}</span>
+          {lastSuccess?.uploaded && (
+            <Badge variant="secondary">{lastSuccess.target}</Badge>
+          )}
+          {status.running && (
+            <Badge>{locale === 'zh' ? '进行中' : 'Running'}</Badge>
+          )}
+        </div>
+        {status.next_run && (
+          <div className="text-muted-foreground">
+            {locale === 'zh' ? '下次备份' : 'Next backup'}: {formatDate(status.next_run)}
+          </div>
+        )}
+        {status.last_error && (
+          <div className="flex items-start gap-2 text-red-600 dark:text-red-400">
+            <AlertTriangle className="h-4 w-4 mt-0.5 shrink-0" />
+            <span>
+              {locale === 'zh' ? '上次备份失败' : 'Last run failed'} ({formatDate(status.last_run_at)}): {status.last_error}
+            </span>
+          </div>
+        )}
+      </CardContent>
+    </Card>
+  )
+}
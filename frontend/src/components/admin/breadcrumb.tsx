@@ -124,6 +124,13 @@ export function AdminBreadcrumb() {
           })
           break
 
+        case 'api-docs':
+          items.push({
+            label: 'API',
+            isActive: true
+          })
+          break
+
         case 'import':
           items.push({
             label: t('import.contentImport'),
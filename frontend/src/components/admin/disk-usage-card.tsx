@@ -0,0 +1,84 @@
+'use client'
+
+import { useEffect, useState } from 'react'
+import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
+import { Badge } from '@/components/ui/badge'
+import { apiClient, DiskUsage, StorageWarning } from '@/lib/api'
+import { HardDrive, AlertTriangle } from 'lucide-react'
+
+interface DiskUsageCardProps {
+  locale: string
+}
+
+const formatBytes = (bytes: number) => {
+  const units = ['B', 'KB', 'MB', 'GB', 'TB']
+  let value = bytes
+  let unit = 0
+  while (Math.abs(value) >= 1024 && unit < units.length - 1) {
+    value /= 1024
+    unit++
+  }
+  return `${value.toFixed(unit === 0 ? 0 : 1)} ${units[unit]}`
+}
+
+export function DiskUsageCard({ locale }: DiskUsageCardProps) {
+  const [usage, setUsage] = useState<DiskUsage | null>(null)
+
+  useEffect(() => {
+    apiClient.getDiskUsage()
+      .then(setUsage)
+      .catch(error => console.error('Failed to fetch disk usage:', error))
+  }, [])
+
+  // Only shown when a volume is close to full
+  if (!usage || usage.warnings.length === 0) {
+    return null
+  }
+
+  const describe = (warning: StorageWarning) => {
+    const paths = warning.paths.join(', ')
+    if (warning.kind === 'full_soon') {
+      return locale === 'zh'
+        ? `按当前增长速度，磁盘约 ${warning.days_until_full} 天后将被占满`
+        : `At the current growth rate the disk is full in about ${warning.days_until_full} days`
+    }
+    return locale === 'zh'
+      ? `${paths} 所在磁盘已使用 ${warning.used_percent?.toFixed(0)}%`
+      : `The volume holding ${paths} is ${warning.used_percent?.toFixed(0)}% full`
+  }
+
+  return (
+    <Card className="mb-8 border-red-200 dark:border-red-900">
+      <CardHeader className="flex flex-row items-center justify-between space-y-0 pb-2">
+        <CardTitle className="text-sm font-medium">{locale === 'zh' ? '磁盘空间' : 'Disk Space'}</CardTitle>
+        <HardDrive className="h-4 w-4 text-muted-foreground" />
+      </CardHeader>
+      <CardContent className="space-y-2 text-sm">
+        {usage.warnings.map((warning, index) => (
+          <div key={index} className="flex items-start gap-2 text-red-600 dark:text-red-400">
+            <AlertTriangle className="h-4 w-4 mt-0.5 shrink-0" />
+            <span>{describe(warning)}</span>
+          </div>
+        ))}
+        <div className="flex flex-wrap items-center gap-2 text-muted-foreground">
+          <Badge variant="secondary">
+            {locale === 'zh' ? '数据库' : 'Database'}: {formatBytes(usage.database.bytes)}
+          </Badge>
+          <Badge variant="secondary">
+            {locale === 'zh' ? '上传文件' : 'Uploads'}: {formatBytes(usage.uploads.bytes)}
+          </Badge>
+          {usage.backup_bytes > 0 && (
+            <Badge variant="secondary">
+              {locale === 'zh' ? '备份' : 'Backups'}: {formatBytes(usage.backup_bytes)}
+            </Badge>
+          )}
+          {usage.volumes.map(volume => (
+            <span key={volume.paths.join(',')}>
+              {locale === 'zh' ? '剩余' : 'Free'}: {formatBytes(volume.free)} / {formatBytes(volume.total)}
+            </span>
+          ))}
+        </div>
+      </CardContent>
+    </Card>
+  )
+}
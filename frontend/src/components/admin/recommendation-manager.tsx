@@ -8,7 +8,7 @@ import { Badge } from '@/components/ui/badge'
 import { Tabs, TabsContent, TabsList, TabsTrigger } from '@/components/ui/tabs'
 import { Alert, AlertDescription } from '@/components/ui/alert'
 import { Loader2, Users, TrendingUp, Eye, Clock, Target, Brain, BarChart3 } from 'lucide-react'
-import { apiClient, RecommendationResult, UserProfile, ReadingPatterns, RecommendationAnalytics } from '@/lib/api'
+import { apiClient, RecommendationSummary, UserProfile, ReadingPatterns, RecommendationAnalytics } from '@/lib/api'
 import UserList from './user-list'
 
 interface RecommendationManagerProps {
@@ -19,7 +19,7 @@ const RecommendationManager: React.FC<RecommendationManagerProps> = ({ language
   const [activeTab, setActiveTab] = useState('overview')
   const [loading, setLoading] = useState(false)
   const [error, setError] = useState<string | null>(null)
-  const [popularContent, setPopularContent] = useState<RecommendationResult[]>([])
+  const [popularContent, setPopularContent] = useState<RecommendationSummary[]>([])
   const [selectedUserId, setSelectedUserId] = useState('')
   const [userProfile, setUserProfile] = useState<UserProfile | null>(null)
   const [readingPatterns, setReadingPatterns] = useState<ReadingPatterns | null>(null)
@@ -246,7 +246,7 @@ const RecommendationManager: React.FC<RecommendationManagerProps> = ({ language
     }
   }
 
-  const getRecommendationIcon = (recommendation: RecommendationResult): string => {
+  const getRecommendationIcon = (recommendation: RecommendationSummary): string => {
     if (recommendation.is_learning_path || recommendation.category === 'learning') {
       return '📚'
     }
@@ -259,7 +259,7 @@ const RecommendationManager: React.FC<RecommendationManagerProps> = ({ language
     }
   }
 
-  const getRecommendationCategoryLabel = (recommendation: RecommendationResult, language: string): string => {
+  const getRecommendationCategoryLabel = (recommendation: RecommendationSummary, language: string): string => {
     if (recommendation.is_learning_path || recommendation.category === 'learning') {
       if (language === 'zh') return '学习路径'
       if (language === 'ja') return '学習パス'
@@ -470,7 +470,7 @@ const RecommendationManager: React.FC<RecommendationManagerProps> = ({ language
                               {recommendation.article.view_count || 0} 次浏览
                             </span>
                             <span>
-                              分类: {recommendation.article.category.name}
+                              分类: {recommendation.article.category?.name}
                             </span>
                           </div>
                         </div>
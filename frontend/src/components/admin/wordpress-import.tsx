@@ -69,7 +69,9 @@ export function WordPressImport({ onImportComplete }: WordPressImportProps) {
   const handleFileSelect = (event: React.ChangeEvent<HTMLInputElement>) => {
     const file = event.target.files?.[0]
     if (file) {
-      if (!file.name.toLowerCase().endsWith('.xml')) {
+      const name = file.name.toLowerCase()
+      // Zipped exports are checked by the server before they are read
+      if (!name.endsWith('.xml') && !name.endsWith('.zip')) {
         setError(t('import.selectValidXmlFile'))
         setSelectedFile(null)
         return
@@ -255,7 +257,7 @@ export function WordPressImport({ onImportComplete }: WordPressImportProps) {
         <Input
           id="wordpress-file"
           type="file"
-          accept=".xml"
+          accept=".xml,.zip"
           onChange={handleFileSelect}
           disabled={parsing}
           className="cursor-pointer"
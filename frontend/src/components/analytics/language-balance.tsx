@@ -0,0 +1,69 @@
+"use client"
+
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
+import { Badge } from '@/components/ui/badge'
+import { LanguageStats } from '@/lib/api'
+import { Eye, Languages } from 'lucide-react'
+
+interface LanguageBalanceProps {
+  stats: LanguageStats[]
+  locale: string
+}
+
+// Shows how many articles can be read in each language and the most read
+// of them, so gaps in the translations stand out
+export function LanguageBalance({ stats, locale }: LanguageBalanceProps) {
+  if (stats.length === 0) {
+    return null
+  }
+
+  return (
+    <Card>
+      <CardHeader>
+        <CardTitle className="flex items-center gap-2">
+          <Languages className="h-5 w-5" />
+          {locale === 'zh' ? '语言分布' : 'Languages'}
+        </CardTitle>
+        <CardDescription>
+          {locale === 'zh' ? '各语言可阅读的文章数量、翻译覆盖率和热门文章' : 'Articles readable in each language, translation coverage and top posts'}
+        </CardDescription>
+      </CardHeader>
+      <CardContent>
+        <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+          {stats.map((language) => {
+            const coverage = Math.round(language.coverage * 100)
+            return (
+              <div key={language.language} className="p-4 border rounded-lg space-y-3">
+                <div className="flex items-center justify-between">
+                  <Badge variant="outline" className="uppercase">{language.language}</Badge>
+                  <span className="text-sm font-semibold">{coverage}%</span>
+                </div>
+                <div className="h-2 rounded-full bg-muted overflow-hidden">
+                  <div className="h-full bg-blue-500" style={{ width: `${coverage}%` }} />
+                </div>
+                <p className="text-sm text-muted-foreground">
+                  {locale === 'zh'
+                    ? `${language.original + language.translated} 篇（原创 ${language.original}，翻译 ${language.translated}）`
+                    : `${language.original + language.translated} articles (${language.original} written, ${language.translated} translated)`}
+                </p>
+                {(language.top_articles || []).length > 0 && (
+                  <ul className="space-y-1 text-sm">
+                    {(language.top_articles || []).map((article) => (
+                      <li key={article.id} className="flex items-center justify-between gap-2">
+                        <span className="line-clamp-1">{article.title}</span>
+                        <span className="flex items-center gap-1 text-muted-foreground shrink-0">
+                          <Eye className="h-3 w-3" />
+                          {article.view_count}
+                        </span>
+                      </li>
+                    ))}
+                  </ul>
+                )}
+              </div>
+            )
+          })}
+        </div>
+      </CardContent>
+    </Card>
+  )
+}
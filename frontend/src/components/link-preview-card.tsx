@@ -0,0 +1,93 @@
+'use client'
+
+import { useEffect, useState } from 'react'
+import { ExternalLink } from 'lucide-react'
+import { apiClient, LinkPreview } from '@/lib/api'
+
+interface LinkPreviewCardProps {
+  url: string
+  className?: string
+}
+
+/**
+ * Rich preview of an external link, written in Markdown as
+ * <LinkPreview url="https://..." />. Videos and posts with an embeddable
+ * player are shown in a sandboxed frame, other pages as a card; the link
+ * itself is shown while loading or when the page has no preview.
+ */
+export default function LinkPreviewCard({ url, className = '' }: LinkPreviewCardProps) {
+  const [preview, setPreview] = useState<LinkPreview | null>(null)
+  const [failed, setFailed] = useState(false)
+
+  useEffect(() => {
+    let cancelled = false
+    setPreview(null)
+    setFailed(false)
+    apiClient.getLinkPreview(url)
+      .then((result) => {
+        if (!cancelled) setPreview(result)
+      })
+      .catch(() => {
+        if (!cancelled) setFailed(true)
+      })
+    return () => {
+      cancelled = true
+    }
+  }, [url])
+
+  if (!preview || failed) {
+    return (
+      <a href={url} target="_blank" rel="noopener noreferrer" className="text-primary underline hover:no-underline break-all">
+        {url}
+      </a>
+    )
+  }
+
+  if (preview.embed_url && (preview.type === 'video' || preview.type === 'rich')) {
+    const ratio = preview.width && preview.height ? `${preview.width} / ${preview.height}` : '16 / 9'
+    return (
+      <div className={`my-6 ${className}`}>
+        <iframe
+          src={preview.embed_url}
+          title={preview.title || preview.site_name || url}
+          className="w-full rounded-lg border-0"
+          style={{ aspectRatio: ratio }}
+          loading="lazy"
+          sandbox="allow-scripts allow-same-origin allow-popups allow-presentation"
+          allow="encrypted-media; fullscreen; picture-in-picture"
+          allowFullScreen
+        />
+      </div>
+    )
+  }
+
+  return (
+    <a
+      href={url}
+      target="_blank"
+      rel="noopener noreferrer"
+      className={`not-prose my-6 flex overflow-hidden rounded-lg border bg-card no-underline transition-colors hover:bg-muted/50 ${className}`}
+    >
+      <div className="flex min-w-0 flex-1 flex-col gap-1 p-4">
+        <span className="line-clamp-2 font-medium text-foreground">{preview.title || url}</span>
+        {preview.description && (
+          <span className="line-clamp-2 text-sm text-muted-foreground">{preview.description}</span>
+        )}
+        <span className="mt-auto flex items-center gap-1 pt-1 text-xs text-muted-foreground">
+          <ExternalLink className="h-3 w-3 shrink-0" />
+          <span className="truncate">{preview.site_name || new URL(url).hostname}</span>
+        </span>
+      </div>
+      {preview.image_url && (
+        <img
+          src={preview.image_url}
+          alt=""
+          loading="lazy"
+          decoding="async"
+          referrerPolicy="no-referrer"
+          className="hidden w-40 shrink-0 object-cover sm:block"
+        />
+      )}
+    </a>
+  )
+}
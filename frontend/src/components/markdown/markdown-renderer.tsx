@@ -10,6 +10,7 @@ import rehypeKatex from 'rehype-katex'
 import 'katex/dist/katex.min.css'
 import YouTubeEmbed from '@/components/youtube-embed'
 import BiliBiliEmbed from '@/components/bilibili-embed'
+import LinkPreviewCard from '@/components/link-preview-card'
 import { CodeBlock } from '@/components/code-block'
 import { MermaidChart } from '@/components/mermaid-chart'
 import { MermaidErrorBoundary } from '@/components/mermaid-error-boundary'
@@ -173,6 +174,12 @@ export function MarkdownRenderer({ content, className = "", includeStructuredDat
     }
   )
 
+  // Link previews are fetched by the backend, which only accepts http(s) links
+  processedContent = processedContent.replace(
+    /<LinkPreview\s+url="(https?:\/\/[^"]+)"\s*\/>/g,
+    (_match, url) => `<div class="link-preview" data-url="${url}"></div>`
+  )
+
   return (
     <div className={`prose prose-neutral dark:prose-invert max-w-none ${className}`}>
       <ReactMarkdown
@@ -386,6 +393,13 @@ export function MarkdownRenderer({ content, className = "", includeStructuredDat
               }
             }
             
+            if (className === 'link-preview') {
+              const url = (props as any)['data-url']
+              if (url) {
+                return <LinkPreviewCard url={url} />
+              }
+            }
+
             return <div className={className} {...props} />
           },
         }}
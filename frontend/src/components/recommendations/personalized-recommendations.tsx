@@ -5,7 +5,7 @@ import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import { Button } from '@/components/ui/button'
 import { Badge } from '@/components/ui/badge'
 import { Loader2, Eye, Clock, Sparkles, TrendingUp, Users } from 'lucide-react'
-import { apiClient, RecommendationResult } from '@/lib/api'
+import { apiClient, RecommendationSummary } from '@/lib/api'
 import { getDeviceInfo } from '@/lib/device-utils'
 import { useClientLocale } from '@/hooks/useClientLocale'
 
@@ -30,7 +30,7 @@ const PersonalizedRecommendations: React.FC<PersonalizedRecommendationsProps> =
 }) => {
   const { currentLocale } = useClientLocale()
   const effectiveLanguage = language || currentLocale || 'en'
-  const [recommendations, setRecommendations] = useState<RecommendationResult[]>([])
+  const [recommendations, setRecommendations] = useState<RecommendationSummary[]>([])
   const [loading, setLoading] = useState(false)
   const [error, setError] = useState<string | null>(null)
   const [isMounted, setIsMounted] = useState(false)
@@ -79,7 +79,8 @@ const PersonalizedRecommendations: React.FC<PersonalizedRecommendationsProps> =
         exclude_read: true,
         include_reason: showReason,
         diversify: true,
-        min_confidence: 0.1
+        min_confidence: 0.1,
+        device_type: getDeviceInfo().deviceType
       })
       
       // Filter out the current article if excludeArticleId is provided
@@ -167,9 +168,8 @@ const PersonalizedRecommendations: React.FC<PersonalizedRecommendationsProps> =
     return langLabels[type as keyof typeof langLabels] || langLabels.default
   }
 
-  const formatReadingTime = (content: string): string => {
+  const formatReadingTime = (wordCount: number): string => {
     const wordsPerMinute = effectiveLanguage === 'zh' ? 300 : effectiveLanguage === 'ja' ? 400 : 200 // Adjust for language
-    const wordCount = content.split(/\s+/).length
     const minutes = Math.ceil(wordCount / wordsPerMinute)
     
     if (effectiveLanguage === 'zh') {
@@ -369,7 +369,7 @@ const PersonalizedRecommendations: React.FC<PersonalizedRecommendationsProps> =
                 </span>
                 <span className="flex items-center gap-1">
                   <Clock className="h-3 w-3" />
-                  {recommendation?.article?.content ? formatReadingTime(recommendation.article.content) : '0 min'}
+                  {recommendation?.article?.word_count ? formatReadingTime(recommendation.article.word_count) : '0 min'}
                 </span>
                 <span>{recommendation?.article?.category?.name || ''}</span>
               </div>
@@ -53,6 +53,10 @@ export function ArticleStructuredData({
   datePublished,
   dateModified,
   author,
+  authorImage,
+  authorLinks,
+  publisher,
+  license,
   locale,
   content
 }: {
@@ -62,6 +66,12 @@ export function ArticleStructuredData({
   datePublished: string
   dateModified?: string
   author: string
+  authorImage?: string
+  // Profiles of the author on other sites, for schema.org sameAs
+  authorLinks?: string[]
+  publisher?: string
+  // URL of the license's terms, or the terms of a custom license
+  license?: string
   locale: string
   content: string
 }) {
@@ -79,12 +89,15 @@ export function ArticleStructuredData({
     "dateModified": dateModified || datePublished,
     "author": {
       "@type": "Person",
-      "name": author
+      "name": author,
+      ...(authorImage && { "image": authorImage }),
+      ...(authorLinks?.length && { "sameAs": authorLinks })
     },
     "publisher": {
       "@type": "Organization",
-      "name": author
+      "name": publisher || author
     },
+    ...(license && { "license": license }),
     "inLanguage": locale,
     "wordCount": wordCount,
     "timeRequired": `PT${readingTimeMinutes}M`,
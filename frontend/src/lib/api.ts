@@ -12,6 +12,10 @@ export interface ArticleTranslation {
   title: string
   content: string
   summary: string
+  seo_slug?: string
+  source_hash?: string
+  machine_translated?: boolean
+  dir?: 'ltr' | 'rtl'
   created_at: string
   updated_at: string
 }
@@ -24,9 +28,31 @@ export interface Article {
   summary: string
   category_id: number
   category: Category
+  // The article's category and the categories above it, top first
+  category_path?: CategoryCrumb[]
   default_lang: string
+  // Text direction of the language the article is served in
+  dir?: 'ltr' | 'rtl'
   translations: ArticleTranslation[]
   view_count?: number
+  // The user who wrote the article, credited once they have an author profile
+  author_id?: number
+  author?: ArticleAuthor
+  // License code, empty to follow the site's default; license_info is the one in effect
+  license?: string
+  license_custom?: string
+  license_info?: ContentLicense
+  // Members-only articles are served locked, with a teaser as content, to readers who are not members
+  members_only?: boolean
+  locked?: boolean
+  // Where an imported article first appeared; canonical_url, when set, is the page search engines are pointed to
+  source_name?: string
+  source_url?: string
+  canonical_url?: string
+  // Comment count on the external comment system (Giscus or Disqus) the site keeps
+  discussion?: ArticleDiscussion
+  // Set on search results: escaped HTML with the matched terms in <mark>
+  highlight?: SearchHighlight
   // Cover Image Fields
   cover_image_url?: string
   cover_image_id?: number
@@ -35,6 +61,7 @@ export interface Article {
   is_pinned?: boolean
   pin_order?: number
   pinned_at?: string
+  unpin_at?: string
   // SEO Fields
   seo_title?: string
   seo_description?: string
@@ -46,14 +73,38 @@ export interface Article {
   updated_at: string
 }
 
+export interface AuthorLink {
+  label: string
+  url: string
+}
+
+export interface ContentLicense {
+  code: string
+  name: string
+  url?: string
+}
+
+export interface ArticleAuthor {
+  id: number
+  name: string
+  avatar_url?: string
+  social_links?: AuthorLink[]
+}
+
 export interface Category {
   id: number
   name: string
   description: string
+  parent_id?: number | null
   created_at: string
   updated_at: string
 }
 
+export interface CategoryCrumb {
+  id: number
+  name: string
+}
+
 export interface SiteSettingsTranslation {
   id?: number
   settings_id?: number
@@ -91,6 +142,11 @@ export interface SiteSettings {
   // Privacy and Indexing Control
   block_search_engines?: boolean
   block_ai_training?: boolean
+  // License of articles that do not choose one
+  default_license?: string
+  default_license_custom?: string
+  // Articles that can be pinned at once
+  max_pinned_articles?: number
   translations?: SiteSettingsTranslation[]
   created_at: string
   updated_at: string
@@ -108,6 +164,7 @@ export interface EmbeddingSearchResult {
   article_id: number
   title: string
   summary: string
+  category_id: number
   category_name: string
   language: string
   similarity: number
@@ -115,6 +172,17 @@ export interface EmbeddingSearchResult {
   created_at: string
 }
 
+export type TopicKind = 'category' | 'tag'
+
+export interface TopicSimilarity {
+  kind: TopicKind
+  // Category ID or lowercased tag
+  key: string
+  name: string
+  article_count: number
+  similarity: number
+}
+
 export interface SemanticSearchRequest {
   query: string
   language?: string
@@ -266,6 +334,14 @@ export interface LanguageConfig {
   default_language: string
   enabled_languages: string[]
   supported_languages: Record<string, string>
+  directions?: Record<string, 'ltr' | 'rtl'>
+}
+
+export interface VisitorLanguage {
+  language: string
+  source: 'preference' | 'accept_language' | 'geoip' | 'default'
+  default_language: string
+  enabled_languages: string[]
 }
 
 export interface SocialMedia {
@@ -279,6 +355,22 @@ export interface SocialMedia {
   updated_at: string
 }
 
+export interface QuickLink {
+  id: number
+  title: string
+  url: string
+  icon?: string
+  display_order: number
+  is_active: boolean
+  clicks: number
+  created_at: string
+  updated_at: string
+}
+
+export type PublicQuickLink = Pick<QuickLink, 'id' | 'title' | 'url' | 'icon'>
+
+export type QuickLinkInput = Partial<Pick<QuickLink, 'title' | 'url' | 'icon' | 'is_active'>>
+
 export interface MediaLibrary {
   id: number
   file_name: string
@@ -291,6 +383,53 @@ export interface MediaLibrary {
   alt: string
   created_at: string
   updated_at: string
+  // Set on upload, for images
+  metadata_report?: ImageMetadataReport
+}
+
+export type ImageMetadataKind =
+  | 'gps' | 'serial_number' | 'owner' | 'camera' | 'capture_time' | 'software'
+  | 'maker_note' | 'comment' | 'xmp' | 'iptc' | 'orientation' | 'color_profile'
+
+export interface ImageMetadataReport {
+  found: ImageMetadataKind[]
+  sensitive: ImageMetadataKind[]
+  kept: ImageMetadataKind[]
+}
+
+export interface ImageMetadataStats {
+  images: number
+  found: Partial<Record<ImageMetadataKind, number>>
+}
+
+export interface UploadTypePolicy {
+  enabled: boolean
+  extensions: string[]
+  max_size: number // bytes
+}
+
+// Types are keyed by MIME type; SVG also needs allow_svg
+export interface UploadPolicy {
+  allow_svg: boolean
+  types: Record<string, UploadTypePolicy>
+}
+
+export type WatermarkPosition =
+  | 'top-left' | 'top' | 'top-right'
+  | 'left' | 'center' | 'right'
+  | 'bottom-left' | 'bottom' | 'bottom-right'
+  | 'tile'
+
+// Applied to media library JPEG and PNG images as they are served
+export interface WatermarkPolicy {
+  enabled: boolean
+  text: string
+  logo_media_id: number // replaces the text when set
+  position: WatermarkPosition
+  opacity: number // 0.05 to 1
+  size: number // percent of the image width
+  min_width: number
+  min_height: number
 }
 
 export interface MediaBatchUploadFailure {
@@ -369,6 +508,25 @@ export interface AnalyticsData {
   geographic_stats: GeographicStats[]
   browser_stats: BrowserStats[]
   platform_stats: PlatformStats[]
+  language_stats: LanguageStats[]
+  // Set when comments are synced from an external comment system
+  discussions?: {
+    provider: 'giscus' | 'disqus'
+    comments: number
+    discussions: number
+    last_comment_at?: string
+    most_comments: Array<{ id: number; title: string; comments: number; url?: string }>
+  }
+}
+
+// How much of the site can be read in one language
+export interface LanguageStats {
+  language: string
+  original: number
+  translated: number
+  coverage: number
+  views: number
+  top_articles?: { id: number; title: string; view_count: number }[]
 }
 
 export interface ArticleAnalytics {
@@ -381,6 +539,73 @@ export interface ArticleAnalytics {
     user_agent: string
     created_at: string
   }>
+  share_counts: ArticleShareCounts
+  discussion: {
+    provider: 'giscus' | 'disqus'
+    thread_id: string
+    url: string
+    comments: number
+    last_comment_at?: string
+    synced_at: string
+  } | null
+}
+
+export interface CacheUsage {
+  entries: number
+  bytes: number
+}
+
+export interface CacheOverview {
+  driver: CacheUsage & { driver: 'memory' | 'redis'; hits: number; misses: number; hit_rate: number }
+  namespaces: Array<CacheUsage & { name: string; ttl_seconds: number; hits: number; misses: number; hit_rate: number }>
+  smart: Array<{ name: string; shared: CacheUsage; persistent: CacheUsage; hits: number; misses: number; hit_rate: number }>
+}
+
+export interface ArticleDiscussion {
+  provider: 'giscus' | 'disqus'
+  comments: number
+  url?: string
+}
+
+export interface CustomCodeFinding {
+  field: 'css' | 'js'
+  rule: string
+  severity: 'error' | 'warning'
+  line: number
+  message: string
+}
+
+export interface CustomCodeAudit {
+  id: number
+  user_id: number
+  ip_address: string
+  user_agent: string
+  field: 'css' | 'js'
+  outcome: 'saved' | 'rejected'
+  previous_hash: string
+  content_hash: string
+  content: string
+  findings: string // JSON array of CustomCodeFinding
+  created_at: string
+}
+
+export interface ArticleShareSample {
+  id: number
+  platform: string
+  count: number
+  comments: number
+  fetched_at: string
+}
+
+export interface ArticleShareCounts {
+  total: number
+  platforms: Array<{
+    platform: string
+    count: number
+    comments: number
+    fetched_at: string
+    history: ArticleShareSample[]
+  }>
 }
 
 export interface MediaListResponse {
@@ -401,6 +626,10 @@ export interface SEOKeyword {
   best_rank: number
   search_volume: number
   difficulty: 'easy' | 'medium' | 'hard'
+  difficulty_score?: number
+  data_source?: string
+  data_updated_at?: string | null
+  data_stale?: boolean
   tracking_status: 'active' | 'paused'
   notes: string
   tags: string
@@ -409,6 +638,115 @@ export interface SEOKeyword {
   article?: Article
 }
 
+export interface KeywordDataStatus {
+  provider: '' | 'dataforseo' | 'google_ads'
+  schedule: string
+  max_age: string
+  batch_size: number
+  monthly_budget: number
+  month_cost: number
+  keywords: number
+  stale: number
+  last_updated_at?: string
+}
+
+export interface KeywordArticleMatch {
+  article_id: number
+  title: string
+  similarity: number
+  targeted: boolean
+}
+
+export interface KeywordMapping {
+  keyword: string
+  language: string
+  keyword_ids: number[]
+  search_volume: number
+  status: 'assigned' | 'cannibalized' | 'unassigned'
+  article?: KeywordArticleMatch
+  competing: KeywordArticleMatch[]
+}
+
+export interface KeywordMappingReport {
+  provider: string
+  model: string
+  threshold: number
+  margin: number
+  keywords: KeywordMapping[]
+  assigned: number
+  cannibalized: number
+  unassigned: number
+  truncated: boolean
+}
+
+export interface SEOBulkMetadataOptions {
+  fields?: ('title' | 'description' | 'slug')[]
+  limit?: number
+  batch_size?: number
+  max_cost?: number
+  max_tokens?: number
+  apply?: boolean
+}
+
+export interface SEOMetadataProposal {
+  id: number
+  site_id: number
+  article_id: number
+  job_id: number
+  language: string
+  seo_title: string
+  seo_description: string
+  seo_slug: string
+  status: 'pending' | 'applied' | 'dismissed'
+  decided_at?: string
+  created_at: string
+  article?: Pick<Article, 'id' | 'title' | 'default_lang' | 'seo_title' | 'seo_description' | 'seo_slug'>
+}
+
+export interface FeedImportSource {
+  id: number
+  site_id: number
+  url: string
+  title: string
+  site_url: string
+  category_id: number
+  language: string
+  // Followed feeds are fetched again on FEED_IMPORT_SCHEDULE
+  follow: boolean
+  last_fetched_at?: string
+  last_error?: string
+  // Posts waiting for review
+  drafts: number
+  created_at: string
+  updated_at: string
+}
+
+export interface FeedImportPost {
+  id: number
+  site_id: number
+  source_id: number
+  guid: string
+  link: string
+  title: string
+  summary: string
+  // The post converted to Markdown
+  content: string
+  author?: string
+  published_at?: string
+  status: 'draft' | 'published' | 'dismissed'
+  article_id?: number
+  created_at: string
+  updated_at: string
+  source?: Omit<FeedImportSource, 'drafts'>
+}
+
+export interface FeedImportInput {
+  url?: string
+  category_id?: number
+  language?: string
+  follow?: boolean
+}
+
 export interface SEOKeywordGroup {
   id: number
   name: string
@@ -546,6 +884,7 @@ export interface SEOAnalysisResult {
     suggestions: string[]
   }
   suggestions: string[]
+  language: string
   created_at: string
 }
 
@@ -567,22 +906,36 @@ export interface SEOAutomationRule {
   updated_at: string
 }
 
-export interface SEONotification {
+export type NotificationSeverity = 'info' | 'warning' | 'error' | 'critical'
+
+// Named apart from the browser's Notification
+export interface AdminNotification {
   id: number
-  type: 'health_alert' | 'ranking_change' | 'keyword_opportunity'
-  severity: 'info' | 'warning' | 'error' | 'critical'
+  source: 'seo' | 'ai' | 'backup' | 'links' | 'security' | 'monitor' | 'system'
+  type: string
+  severity: NotificationSeverity
   title: string
   message: string
+  action_url?: string
   article_id?: number
   keyword_id?: number
-  action_url: string
   is_read: boolean
-  is_archived: boolean
-  expires_at?: string
+  read_at?: string
   created_at: string
   updated_at: string
-  article?: Article
-  keyword?: SEOKeyword
+}
+
+export interface NotificationSummary {
+  unread: number
+  by_severity: Record<NotificationSeverity, number>
+  by_source: Record<string, number>
+  latest?: AdminNotification
+}
+
+// SEO notifications are the "seo" source of the notification center
+export interface SEONotification extends AdminNotification {
+  source: 'seo'
+  type: 'health_alert' | 'ranking_change' | 'keyword_opportunity'
 }
 
 // Content Assistant Interfaces
@@ -677,6 +1030,24 @@ export interface RecommendationResult {
   is_learning_path?: boolean
 }
 
+// Recommendation lists carry these summaries unless include=article asks
+// for whole articles
+export interface ArticleSummary {
+  id: number
+  title: string
+  summary: string
+  seo_slug?: string
+  cover_image_url?: string
+  category?: { id: number; name: string }
+  view_count: number
+  word_count: number
+  created_at: string
+}
+
+export interface RecommendationSummary extends Omit<RecommendationResult, 'article'> {
+  article: ArticleSummary
+}
+
 export interface ReadingPath {
   path_id: string
   title: string
@@ -716,6 +1087,20 @@ export interface RecentUser {
   avg_scroll_depth: number
 }
 
+export interface UserBehavior {
+  id: number
+  article_id: number
+  article_title: string
+  session_id: string
+  interaction_type: string
+  reading_time: number
+  scroll_depth: number
+  device_type: string
+  language: string
+  referrer_type: string
+  created_at: string
+}
+
 export interface ReadingPatterns {
   total_reading_time: number
   average_reading_time: number
@@ -764,6 +1149,7 @@ export interface PersonalizedRecommendationsRequest {
   categories?: string[]
   max_age?: number
   diversify?: boolean
+  device_type?: 'desktop' | 'mobile' | 'tablet'
 }
 
 export interface ReadingPathRequest {
@@ -772,153 +1158,799 @@ export interface ReadingPathRequest {
   language?: string
 }
 
-class ApiClient {
-  private token: string | null = null
+export interface BackupRunResult {
+  name: string
+  size: number
+  sha256: string
+  target?: string
+  uploaded: boolean
+  pruned?: string[]
+  started_at: string
+  finished_at: string
+}
 
-  constructor() {
-    this.loadToken()
-  }
+export interface BackupStatus {
+  schedule: string
+  target: string
+  retention_count: number
+  retention_days: number
+  next_run?: string
+  running: boolean
+  last_run_at?: string
+  last_error?: string
+  last_success?: BackupRunResult
+}
 
-  private getBaseUrl(): string {
-    return getApiBaseUrl()
-  }
+export interface StorageVolume {
+  paths: string[]
+  total: number
+  free: number
+  used: number
+  used_percent: number
+}
 
-  private loadToken() {
-    if (typeof window !== 'undefined') {
-      this.token = localStorage.getItem('auth_token')
-    }
-  }
+export interface StorageWarning {
+  kind: 'volume_full' | 'full_soon'
+  paths: string[]
+  used_percent?: number
+  days_until_full?: number
+  message: string
+}
 
-  private saveToken(token: string) {
-    if (typeof window !== 'undefined') {
-      localStorage.setItem('auth_token', token)
-      this.token = token
-    }
-  }
+export interface BootstrapConfig {
+  site_url: string
+  base_path: string
+  api_base: string
+  upload_base: string
+  features: string[]
+  default_language: string
+  multi_site: boolean
+}
 
-  private clearToken() {
-    if (typeof window !== 'undefined') {
-      localStorage.removeItem('auth_token')
-      this.token = null
-    }
-  }
+export interface PageHeadMeta {
+  name?: string
+  property?: string
+  content: string
+}
 
-  async request<T>(endpoint: string, options: RequestInit = {}): Promise<T> {
-    const url = `${this.getBaseUrl()}${endpoint}`
-    const headers: Record<string, string> = {
-      'Content-Type': 'application/json',
-      ...(options.headers as Record<string, string>),
-    }
+export interface PageHeadLink {
+  rel: string
+  href: string
+  hreflang?: string
+  type?: string
+  title?: string
+}
 
-    if (this.token) {
-      headers['Authorization'] = `Bearer ${this.token}`
-    }
+// The prepared <head> of a frontend route; html is the same tags rendered
+export interface PageHead {
+  path: string
+  language: string
+  kind: 'home' | 'article' | 'page'
+  title: string
+  description: string
+  canonical: string
+  robots: string
+  meta: PageHeadMeta[]
+  links: PageHeadLink[]
+  json_ld: Record<string, unknown>[]
+  html: string
+}
 
-    const response = await fetch(url, {
-      headers,
-      ...options,
-    })
+export interface DiskUsage {
+  database: {
+    driver: string
+    bytes: number
+    rows: number
+    tables: { table: string; rows: number }[]
+  }
+  uploads: {
+    path: string
+    files: number
+    bytes: number
+    by_type: Record<string, { files: number; bytes: number }>
+  }
+  backup_bytes: number
+  volumes: StorageVolume[]
+  trend?: {
+    since: string
+    database_bytes_per_day: number
+    upload_bytes_per_day: number
+    days_until_full?: number
+  }
+  warnings: StorageWarning[]
+  measured_at: string
+}
 
-    if (!response.ok) {
-      if (response.status === 401) {
-        this.clearToken()
-        if (typeof window !== 'undefined') {
-          window.location.href = '/admin/login'
-        }
-      }
-      throw new Error(`API request failed: ${response.status} ${response.statusText}`)
-    }
+export interface SiteMonitorCheck {
+  url: string
+  up: boolean
+  status_code?: number
+  error?: string
+  response_ms: number
+  checked_at: string
+  cert_expires_at?: string
+  cert_days_left?: number
+  cert_issuer?: string
+  failures: number
+  down_since?: string
+}
 
-    return response.json()
-  }
+export interface SiteMonitorStatus {
+  enabled: boolean
+  url?: string
+  schedule?: string
+  next_run?: string
+  last_check?: SiteMonitorCheck
+}
 
-  // Articles
-  async getArticles(options?: { categoryId?: number; lang?: string }): Promise<Article[]> {
-    const params = new URLSearchParams()
-    if (options?.categoryId) params.set('category_id', options.categoryId.toString())
-    if (options?.lang) params.set('lang', options.lang)
-    const queryString = params.toString() ? `?${params.toString()}` : ''
-    return this.request<Article[]>(`/articles${queryString}`)
-  }
+export interface SearchHighlight {
+  title: string
+  summary: string
+  snippet: string
+}
 
-  async getArticle(id: string | number, lang?: string): Promise<Article> {
-    const params = lang ? `?lang=${lang}` : ''
-    return this.request<Article>(`/articles/${id}${params}`)
-  }
+export interface Member {
+  id: number
+  email: string
+  name: string
+  note: string
+  disabled: boolean
+  expires_at?: string
+  last_login_at?: string
+  created_at: string
+  updated_at: string
+}
 
-  async createArticle(article: Omit<Article, 'id' | 'created_at' | 'updated_at' | 'category'>): Promise<Article> {
-    return this.request<Article>('/articles', {
-      method: 'POST',
-      body: JSON.stringify(article),
-    })
+export interface MemberSession {
+  token: string
+  expires_at: string
+  member: Member
+}
+
+export interface Subscriber {
+  id: number
+  email: string
+  language: string
+  status: 'pending' | 'active' | 'unsubscribed' | 'bounced'
+  bounce_count: number
+  last_bounce_at?: string
+  confirmed_at?: string
+  unsubscribed_at?: string
+  created_at: string
+}
+
+export interface Newsletter {
+  id: number
+  kind: 'announcement' | 'digest' | 'custom'
+  status: 'draft' | 'sending' | 'sent'
+  subject: string
+  body: string
+  language: string
+  recipients: number
+  sent: number
+  failed: number
+  bounced: number
+  sent_at?: string
+  created_at: string
+  updated_at: string
+}
+
+export interface MailSettings {
+  host: string
+  port: string
+  username: string
+  password?: string
+  password_set: boolean
+  from: string
+  source?: 'settings' | 'config'
+}
+
+export interface MailTemplateInfo {
+  name: string
+  description: string
+  variables: string[]
+  languages: string[]
+  overrides: string[]
+}
+
+export interface MailTemplate {
+  name: string
+  language: string
+  subject: string
+  body: string
+  overridden: boolean
+}
+
+export interface StopWordList {
+  language: string
+  words: string[]
+  customized: boolean
+}
+
+export interface KeywordPreview {
+  language: string
+  words: string[]
+  keywords: string[]
+}
+
+export interface SEORules {
+  language: string // base language, or 'default'
+  title_length: { min: number; max: number }
+  description_length: { min: number; max: number }
+  content_words: { min: number; max: number }
+  keyword_density_min: number // percent
+  keyword_density_max: number
+  customized: boolean
+}
+
+export interface ContentQualityAnalysis {
+  id: number
+  article_id: number
+  language: string
+  word_count: number
+  topic_clusters: string // JSON array of the main keywords
+  content_score: number
+  keyword_density: string // JSON array of { keyword, count, density }
+  readability_score: number
+  originality_score: number
+  similar_article_id?: number
+  similarity?: number
+  seo_score: number
+  created_at: string
+  updated_at: string
+  article?: Pick<Article, 'id' | 'title' | 'default_lang' | 'seo_slug'>
+}
+
+export interface WritingSuggestion {
+  id: number
+  suggestion_type: 'search_gap' | 'keyword_gap' | 'category_gap' | 'inspiration'
+  topic: string
+  content: string
+  relevance_score: number
+  signal: number // searches, monthly search volume or articles in the category
+  language: string
+  category_id?: number
+  is_used: boolean
+  status: 'pending' | 'accepted' | 'dismissed'
+  decided_at?: string
+  created_at: string
+  updated_at: string
+  category?: Category
+}
+
+export interface JobProgress {
+  id: number
+  type: string
+  status: 'pending' | 'running' | 'succeeded' | 'dead' | 'cancelled'
+  attempts: number
+  progress: number // percent
+  step?: string
+  item_errors: string[]
+  error?: string
+  result?: unknown
+}
+
+export interface MailSuppression {
+  id: number
+  email: string
+  reason: 'bounce' | 'complaint' | 'manual'
+  source: string
+  detail?: string
+  created_at: string
+  updated_at: string
+}
+
+export interface Notifier {
+  id: number
+  name: string
+  kind: 'telegram' | 'discord' | 'slack'
+  credential_set: boolean
+  chat_id?: string
+  language?: string
+  on_publish: boolean
+  on_update: boolean
+  enabled: boolean
+  last_sent_at?: string
+  last_error?: string
+  created_at: string
+  updated_at: string
+}
+
+export interface NotifierInput {
+  name?: string
+  kind?: 'telegram' | 'discord' | 'slack'
+  credential?: string
+  chat_id?: string
+  language?: string
+  on_publish?: boolean
+  on_update?: boolean
+  enabled?: boolean
+}
+
+export type SearchPushEngineKind = 'baidu' | 'sogou' | '360'
+
+export interface SearchPushEngine {
+  id: number
+  engine: SearchPushEngineKind
+  endpoint?: string
+  token_set: boolean
+  daily_quota: number
+  on_update: boolean
+  enabled: boolean
+  quota_remaining?: number
+  quota_date?: string
+  submitted_today: number
+  last_pushed_at?: string
+  last_error?: string
+  created_at: string
+  updated_at: string
+}
+
+export interface SearchPushEngineInput {
+  engine?: SearchPushEngineKind
+  endpoint?: string
+  token?: string
+  daily_quota?: number
+  on_update?: boolean
+  enabled?: boolean
+}
+
+export interface SearchPushLog {
+  id: number
+  engine_id: number
+  engine: SearchPushEngineKind
+  article_id: number
+  urls: string
+  submitted: number
+  accepted: number
+  status: 'succeeded' | 'failed' | 'skipped'
+  error?: string
+  created_at: string
+}
+
+export interface FediverseFollower {
+  id: number
+  site_id: number
+  actor_id: string
+  username: string
+  inbox: string
+  shared_inbox?: string
+  created_at: string
+}
+
+export interface FediverseReply {
+  id: number
+  site_id: number
+  article_id: number
+  // Reply this one answers
+  parent_id?: number
+  source: 'fediverse' | 'disqus' | 'artalk' | 'waline' | 'webmention'
+  object_id: string
+  actor_id: string
+  author_name: string
+  author_url: string
+  content: string
+  url: string
+  status: 'pending' | 'approved' | 'rejected'
+  published_at: string
+  created_at: string
+  updated_at: string
+}
+
+export interface CommentImportResult {
+  format: string
+  imported: number
+  duplicates: number
+  skipped: number
+  threaded: number
+  unmatched_pages: string[]
+}
+
+export type TranslationKind = 'article' | 'category' | 'settings'
+
+export interface TranslationCoverage {
+  total: number
+  translated: number
+  outdated: number
+  machine: number
+}
+
+export interface TranslationItem {
+  kind: TranslationKind
+  id: number
+  title: string
+  updated_at: string
+}
+
+export interface LanguageTranslationStatus {
+  language: string
+  articles: TranslationCoverage
+  categories: TranslationCoverage
+  settings: TranslationCoverage
+  outdated: TranslationItem[]
+  machine: TranslationItem[]
+}
+
+export interface TranslationStatus {
+  default_language: string
+  languages: LanguageTranslationStatus[]
+}
+
+export interface TranslationPair {
+  source: string
+  target: string
+}
+
+export interface TranslationMemoryMatch {
+  index: number
+  id: number
+  translation: string
+  score: number
+}
+
+export interface TranslationMemoryEntry {
+  id: number
+  source_language: string
+  target_language: string
+  source: string
+  target: string
+  uses: number
+  created_at: string
+  updated_at: string
+}
+
+export interface TranslationImportResult {
+  language: string
+  imported: number
+  unchanged: number
+  unreviewed: number
+  created: number
+  updated: number
+  skipped: { id: string; reason: string }[]
+}
+
+export interface LinkPreview {
+  url: string
+  type: 'link' | 'photo' | 'video' | 'rich'
+  title?: string
+  description?: string
+  site_name?: string
+  author_name?: string
+  image_url?: string
+  embed_url?: string
+  html?: string
+  width?: number
+  height?: number
+  fetched_at: string
+}
+
+export interface StaleTranslation {
+  article_id: number
+  title: string
+  language: string
+  changed_sections: number
+  total_sections: number
+  machine_translated: boolean
+  translated_at: string
+}
+
+/**
+ * Thrown when an article cannot be published before it is translated into
+ * every language the server requires. Saving again with
+ * allow_missing_translations publishes it anyway.
+ */
+// Body of a failed API request; see GET /api/errors for the codes
+export interface ApiErrorResponse {
+  error: string
+  code: string
+  message: string
+  localized_message: string
+  request_id?: string
+  details?: unknown
+  [key: string]: unknown
+}
+
+export type ArticlePresenceMode = 'viewing' | 'editing'
+
+export interface ArticlePresence {
+  session_id: string
+  user_id: number
+  username: string
+  mode: ArticlePresenceMode
+  since: string
+  seen_at: string
+}
+
+export interface ArticlePresenceState {
+  others: ArticlePresence[]
+  editing_by_others: boolean
+  heartbeat_seconds: number
+}
+
+export interface ApiFieldError {
+  field?: string
+  rule: string
+  param?: string
+  message: string
+}
+
+export interface ApiErrorCatalogEntry {
+  code: string
+  status: number
+  messages: Record<string, string>
+}
+
+export class ApiRequestError extends Error {
+  constructor(message: string, public status: number, public body?: ApiErrorResponse) {
+    super(message)
+    this.name = 'ApiRequestError'
   }
 
-  async updateArticle(id: number, article: Partial<Article>): Promise<Article> {
-    return this.request<Article>(`/articles/${id}`, {
-      method: 'PUT',
-      body: JSON.stringify(article),
-    })
+  get code(): string | undefined {
+    return this.body?.code
   }
 
-  async deleteArticle(id: number): Promise<void> {
-    await this.request(`/articles/${id}`, {
-      method: 'DELETE',
-    })
+  get requestId(): string | undefined {
+    return this.body?.request_id
   }
 
-  async importMarkdown(data: { title: string, content: string, category_id?: number }): Promise<{ message: string, article: Article }> {
-    return this.request('/articles/import', {
-      method: 'POST',
-      body: JSON.stringify(data),
-    })
+  // The refused fields of a validation_failed error
+  get fieldErrors(): ApiFieldError[] {
+    if (this.code !== 'validation_failed') return []
+    const details = this.body?.details as { fields?: ApiFieldError[] } | undefined
+    return details?.fields ?? []
   }
+}
 
-  async importGhost(file: File): Promise<{ message: string, result: { imported_articles: number, imported_pages: number, imported_tags: number, errors: string[] } }> {
-    const formData = new FormData()
-    formData.append('file', file)
+export class MissingTranslationsError extends Error {
+  constructor(message: string, public missingLanguages: string[]) {
+    super(message)
+    this.name = 'MissingTranslationsError'
+  }
+}
 
-    const response = await fetch(`${this.getBaseUrl()}/articles/import-ghost`, {
-      method: 'POST',
-      headers: {
-        'Authorization': this.token ? `Bearer ${this.token}` : '',
-      },
-      body: formData,
+export type ArticleSaveOptions = { allow_missing_translations?: boolean }
+
+class ApiClient {
+  private token: string | null = null
+
+  constructor() {
+    this.loadToken()
+  }
+
+  private getBaseUrl(): string {
+    return getApiBaseUrl()
+  }
+
+  private loadToken() {
+    if (typeof window !== 'undefined') {
+      this.token = localStorage.getItem('auth_token')
+    }
+  }
+
+  private saveToken(token: string) {
+    if (typeof window !== 'undefined') {
+      localStorage.setItem('auth_token', token)
+      this.token = token
+    }
+  }
+
+  private clearToken() {
+    if (typeof window !== 'undefined') {
+      localStorage.removeItem('auth_token')
+      this.token = null
+    }
+  }
+
+  async request<T>(endpoint: string, options: RequestInit = {}): Promise<T> {
+    const url = `${this.getBaseUrl()}${endpoint}`
+    const headers: Record<string, string> = {
+      'Content-Type': 'application/json',
+      ...(options.headers as Record<string, string>),
+    }
+
+    if (this.token) {
+      headers['Authorization'] = `Bearer ${this.token}`
+    }
+    const memberToken = typeof window !== 'undefined' ? localStorage.getItem('member_token') : null
+    if (memberToken) {
+      headers['X-Member-Token'] = memberToken
+    }
+
+    const response = await fetch(url, {
+      headers,
+      ...options,
     })
 
     if (!response.ok) {
-      if (response.status === 401) {
+      // A rejected membership token says nothing about the admin session
+      if (response.status === 401 && !endpoint.startsWith('/members/')) {
         this.clearToken()
         if (typeof window !== 'undefined') {
           window.location.href = '/admin/login'
         }
       }
-      throw new Error(`Import failed: ${response.status} ${response.statusText}`)
+      const body: ApiErrorResponse | null = await response.json().catch(() => null)
+      if (body?.code === 'missing_translations' && Array.isArray(body.missing_languages)) {
+        throw new MissingTranslationsError(body.error, body.missing_languages)
+      }
+      throw new ApiRequestError(
+        body?.message || `API request failed: ${response.status} ${response.statusText}`,
+        response.status,
+        body ?? undefined,
+      )
     }
 
     return response.json()
   }
 
-  // Categories
-  async getCategories(options?: { lang?: string }): Promise<Category[]> {
-    const params = options?.lang ? `?lang=${options.lang}` : ''
-    return this.request<Category[]>(`/categories${params}`)
+  // Articles
+  async getArticles(options?: { categoryId?: number; includeSubcategories?: boolean; lang?: string }): Promise<Article[]> {
+    const params = new URLSearchParams()
+    if (options?.categoryId) params.set('category_id', options.categoryId.toString())
+    if (options?.includeSubcategories) params.set('include_subcategories', 'true')
+    if (options?.lang) params.set('lang', options.lang)
+    const queryString = params.toString() ? `?${params.toString()}` : ''
+    return this.request<Article[]>(`/articles${queryString}`)
   }
 
-  async getCategory(id: number): Promise<Category> {
-    return this.request<Category>(`/categories/${id}`)
+  async getArticle(id: string | number, lang?: string): Promise<Article> {
+    const params = lang ? `?lang=${lang}` : ''
+    return this.request<Article>(`/articles/${id}${params}`)
   }
 
-  async createCategory(category: Omit<Category, 'id' | 'created_at' | 'updated_at'>): Promise<Category> {
-    return this.request<Category>('/categories', {
+  async createArticle(article: Omit<Article, 'id' | 'created_at' | 'updated_at' | 'category'> & ArticleSaveOptions): Promise<Article> {
+    return this.request<Article>('/articles', {
       method: 'POST',
-      body: JSON.stringify(category),
+      body: JSON.stringify(article),
     })
   }
 
-  async updateCategory(id: number, category: Partial<Category>): Promise<Category> {
-    return this.request<Category>(`/categories/${id}`, {
+  async updateArticle(id: number, article: Partial<Article> & ArticleSaveOptions): Promise<Article> {
+    return this.request<Article>(`/articles/${id}`, {
       method: 'PUT',
-      body: JSON.stringify(category),
+      body: JSON.stringify(article),
+    })
+  }
+
+  async updatePinnedOrder(order: { id: number; order: number }[]): Promise<{ message: string }> {
+    return this.request('/articles/pinned/order', {
+      method: 'PUT',
+      body: JSON.stringify(order),
+    })
+  }
+
+  async deleteArticle(id: number): Promise<void> {
+    await this.request(`/articles/${id}`, {
+      method: 'DELETE',
+    })
+  }
+
+  // Who else has an article open in the admin. Open tabs send a heartbeat
+  // every heartbeat_seconds and leave when closed.
+  async getArticlePresence(id: number, sessionId?: string): Promise<ArticlePresenceState> {
+    const params = sessionId ? `?session_id=${encodeURIComponent(sessionId)}` : ''
+    return this.request<ArticlePresenceState>(`/articles/${id}/presence${params}`)
+  }
+
+  async updateArticlePresence(id: number, sessionId: string, mode: ArticlePresenceMode): Promise<ArticlePresenceState> {
+    return this.request<ArticlePresenceState>(`/articles/${id}/presence`, {
+      method: 'POST',
+      body: JSON.stringify({ session_id: sessionId, mode }),
+    })
+  }
+
+  async leaveArticlePresence(id: number, sessionId: string): Promise<void> {
+    await this.request(`/articles/${id}/presence?session_id=${encodeURIComponent(sessionId)}`, {
+      method: 'DELETE',
+      keepalive: true,
+    })
+  }
+
+  async importMarkdown(data: { title: string, content: string, category_id?: number }): Promise<{ message: string, article: Article }> {
+    return this.request('/articles/import', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async importGhost(file: File): Promise<{ message: string, result: { imported_articles: number, imported_pages: number, imported_tags: number, errors: string[] } }> {
+    const formData = new FormData()
+    formData.append('file', file)
+
+    const response = await fetch(`${this.getBaseUrl()}/articles/import-ghost`, {
+      method: 'POST',
+      headers: {
+        'Authorization': this.token ? `Bearer ${this.token}` : '',
+      },
+      body: formData,
+    })
+
+    if (!response.ok) {
+      if (response.status === 401) {
+        this.clearToken()
+        if (typeof window !== 'undefined') {
+          window.location.href = '/admin/login'
+        }
+      }
+      throw new Error(`Import failed: ${response.status} ${response.statusText}`)
+    }
+
+    return response.json()
+  }
+
+  // Feed imports: posts pulled from RSS and Atom feeds wait as drafts until published
+  async getFeedImports(): Promise<{ feeds: FeedImportSource[] }> {
+    return this.request('/feed-imports')
+  }
+
+  async addFeedImport(input: FeedImportInput & { url: string }): Promise<{ feed: FeedImportSource; job: { id: number; status: string } }> {
+    return this.request('/feed-imports', {
+      method: 'POST',
+      body: JSON.stringify(input),
+    })
+  }
+
+  async updateFeedImport(id: number, input: FeedImportInput): Promise<FeedImportSource> {
+    return this.request(`/feed-imports/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(input),
+    })
+  }
+
+  async deleteFeedImport(id: number): Promise<void> {
+    await this.request(`/feed-imports/${id}`, {
+      method: 'DELETE',
+    })
+  }
+
+  async fetchFeedImport(id: number): Promise<{ id: number; status: string }> {
+    return this.request(`/feed-imports/${id}/fetch`, {
+      method: 'POST',
+    })
+  }
+
+  async getFeedImportPosts(params?: {
+    status?: FeedImportPost['status']
+    feed_id?: number
+    limit?: number
+  }): Promise<{ posts: FeedImportPost[] }> {
+    const searchParams = new URLSearchParams()
+    if (params?.status) searchParams.append('status', params.status)
+    if (params?.feed_id) searchParams.append('feed_id', params.feed_id.toString())
+    if (params?.limit) searchParams.append('limit', params.limit.toString())
+    const query = searchParams.toString()
+    return this.request(`/feed-imports/posts${query ? `?${query}` : ''}`)
+  }
+
+  async decideFeedImportPosts(ids: number[], decision: 'publish' | 'dismiss'): Promise<{ posts: FeedImportPost[] }> {
+    return this.request(`/feed-imports/posts/${decision}`, {
+      method: 'POST',
+      body: JSON.stringify({ ids }),
+    })
+  }
+
+  // Categories
+  async getCategories(options?: { lang?: string }): Promise<Category[]> {
+    const params = options?.lang ? `?lang=${options.lang}` : ''
+    return this.request<Category[]>(`/categories${params}`)
+  }
+
+  async getCategory(id: number): Promise<Category> {
+    return this.request<Category>(`/categories/${id}`)
+  }
+
+  async createCategory(category: Omit<Category, 'id' | 'created_at' | 'updated_at'>): Promise<Category> {
+    return this.request<Category>('/categories', {
+      method: 'POST',
+      body: JSON.stringify(category),
+    })
+  }
+
+  async updateCategory(id: number, category: Partial<Category>): Promise<Category> {
+    return this.request<Category>(`/categories/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(category),
     })
   }
 
@@ -928,34 +1960,923 @@ class ApiClient {
     })
   }
 
-  // Settings
-  async getSettings(params?: { lang?: string }): Promise<SiteSettings> {
-    const queryParams = new URLSearchParams()
-    if (params?.lang) {
-      queryParams.append('lang', params.lang)
-    }
-    const url = queryParams.toString() ? `/settings?${queryParams}` : '/settings'
-    return this.request<SiteSettings>(url)
+  // Settings
+  async getSettings(params?: { lang?: string }): Promise<SiteSettings> {
+    const queryParams = new URLSearchParams()
+    if (params?.lang) {
+      queryParams.append('lang', params.lang)
+    }
+    const url = queryParams.toString() ? `/settings?${queryParams}` : '/settings'
+    return this.request<SiteSettings>(url)
+  }
+
+  // Language configuration
+  async getLanguageConfig(): Promise<LanguageConfig> {
+    return this.request<LanguageConfig>('/languages')
+  }
+
+  // Best content language for this visitor, from the locale cookie,
+  // Accept-Language and GeoIP
+  async negotiateLanguage(): Promise<VisitorLanguage> {
+    return this.request<VisitorLanguage>('/languages/negotiate')
+  }
+
+  // Error codes of the API with their messages by language
+  async getErrorCatalog(): Promise<{ errors: ApiErrorCatalogEntry[] }> {
+    return this.request<{ errors: ApiErrorCatalogEntry[] }>('/errors')
+  }
+
+  // OpenAPI 3 description of the API, generated from its routes (admin)
+  async getOpenAPISpec(): Promise<Record<string, unknown>> {
+    return this.request<Record<string, unknown>>('/openapi.json')
+  }
+
+  // Admin login token, for tools that call the API themselves
+  getToken(): string | null {
+    return this.token
+  }
+
+  async updateSettings(settings: Partial<SiteSettings>): Promise<SiteSettings> {
+    return this.request<SiteSettings>('/settings', {
+      method: 'PUT',
+      body: JSON.stringify(settings),
+    })
+  }
+
+  async getCustomCodeAudits(limit?: number): Promise<{ audits: CustomCodeAudit[] }> {
+    return this.request(limit ? `/settings/custom-code/audits?limit=${limit}` : '/settings/custom-code/audits')
+  }
+
+  // Upload logo file
+  async uploadLogo(file: File): Promise<{ url: string; message: string }> {
+    const formData = new FormData()
+    formData.append('file', file)
+
+    const response = await fetch(`${this.getBaseUrl()}/settings/upload-logo`, {
+      method: 'POST',
+      headers: {
+        'Authorization': this.token ? `Bearer ${this.token}` : '',
+      },
+      body: formData,
+    })
+
+    if (!response.ok) {
+      if (response.status === 401) {
+        this.clearToken()
+        if (typeof window !== 'undefined') {
+          window.location.href = '/admin/login'
+        }
+      }
+      throw new Error(`Upload failed: ${response.status} ${response.statusText}`)
+    }
+
+    return response.json()
+  }
+
+  // Upload favicon file
+  async uploadFavicon(file: File): Promise<{ url: string; message: string }> {
+    const formData = new FormData()
+    formData.append('file', file)
+
+    const response = await fetch(`${this.getBaseUrl()}/settings/upload-favicon`, {
+      method: 'POST',
+      headers: {
+        'Authorization': this.token ? `Bearer ${this.token}` : '',
+      },
+      body: formData,
+    })
+
+    if (!response.ok) {
+      if (response.status === 401) {
+        this.clearToken()
+        if (typeof window !== 'undefined') {
+          window.location.href = '/admin/login'
+        }
+      }
+      throw new Error(`Upload failed: ${response.status} ${response.statusText}`)
+    }
+
+    return response.json()
+  }
+
+  // Upload background image file
+  async uploadBackgroundImage(file: File): Promise<{ url: string; message: string }> {
+    const formData = new FormData()
+    formData.append('file', file)
+
+    const response = await fetch(`${this.getBaseUrl()}/settings/upload-background`, {
+      method: 'POST',
+      headers: {
+        'Authorization': this.token ? `Bearer ${this.token}` : '',
+      },
+      body: formData,
+    })
+
+    if (!response.ok) {
+      if (response.status === 401) {
+        this.clearToken()
+        if (typeof window !== 'undefined') {
+          window.location.href = '/admin/login'
+        }
+      }
+      throw new Error(`Upload failed: ${response.status} ${response.statusText}`)
+    }
+
+    return response.json()
+  }
+
+  // Remove background image
+  async removeBackgroundImage(): Promise<{ message: string }> {
+    const response = await fetch(`${this.getBaseUrl()}/settings/background`, {
+      method: 'DELETE',
+      headers: {
+        'Authorization': this.token ? `Bearer ${this.token}` : '',
+        'Content-Type': 'application/json',
+      },
+    })
+
+    if (!response.ok) {
+      if (response.status === 401) {
+        this.clearToken()
+        if (typeof window !== 'undefined') {
+          window.location.href = '/admin/login'
+        }
+      }
+      throw new Error(`Remove failed: ${response.status} ${response.statusText}`)
+    }
+
+    return response.json()
+  }
+
+  // Authentication
+  async login(credentials: LoginRequest): Promise<LoginResponse> {
+    const response = await this.request<LoginResponse>('/login', {
+      method: 'POST',
+      body: JSON.stringify(credentials),
+    })
+    
+    this.saveToken(response.token)
+    return response
+  }
+
+  logout() {
+    this.clearToken()
+    if (typeof window !== 'undefined') {
+      window.location.href = '/'
+    }
+  }
+
+  isAuthenticated(): boolean {
+    return !!this.token
+  }
+
+  async getCurrentUser(): Promise<User> {
+    return this.request<User>('/me')
+  }
+
+  async changePassword(oldPassword: string, newPassword: string): Promise<{ message: string }> {
+    return this.request('/change-password', {
+      method: 'PUT',
+      body: JSON.stringify({ old_password: oldPassword, new_password: newPassword }),
+    })
+  }
+
+  async getRecoveryStatus(): Promise<RecoveryStatusResponse> {
+    return this.request('/recovery-status', {
+      method: 'GET',
+    })
+  }
+
+  // Setup
+  async getSetupStatus(): Promise<SetupStatusResponse> {
+    return this.request('/setup/status', {
+      method: 'GET',
+    })
+  }
+
+  async initializeSetup(data: SetupRequest): Promise<SetupResponse> {
+    return this.request('/setup/initialize', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    })
+  }
+
+  // Social Media
+  async getSocialMediaList(): Promise<SocialMedia[]> {
+    return this.request('/social-media')
+  }
+
+  async getAllSocialMedia(): Promise<SocialMedia[]> {
+    return this.request('/social-media/all', {
+      method: 'GET',
+    })
+  }
+
+  async getSocialMedia(id: number): Promise<SocialMedia> {
+    return this.request(`/social-media/${id}`)
+  }
+
+  async createSocialMedia(data: Omit<SocialMedia, 'id' | 'created_at' | 'updated_at'>): Promise<SocialMedia> {
+    return this.request('/social-media', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async updateSocialMedia(id: number, data: Partial<SocialMedia>): Promise<SocialMedia> {
+    return this.request(`/social-media/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async deleteSocialMedia(id: number): Promise<{ message: string }> {
+    return this.request(`/social-media/${id}`, {
+      method: 'DELETE',
+    })
+  }
+
+  async updateSocialMediaOrder(order: { id: number; order: number }[]): Promise<{ message: string }> {
+    return this.request('/social-media/order', {
+      method: 'PUT',
+      body: JSON.stringify(order),
+    })
+  }
+
+  // Quick links ("link in bio") page
+  async getQuickLinks(): Promise<{ links: PublicQuickLink[] }> {
+    return this.request('/quick-links')
+  }
+
+  async trackQuickLinkClick(id: number): Promise<void> {
+    return this.request(`/quick-links/${id}/click`, {
+      method: 'POST',
+    })
+  }
+
+  async getAllQuickLinks(): Promise<{ links: QuickLink[] }> {
+    return this.request('/quick-links/all')
+  }
+
+  async createQuickLink(input: QuickLinkInput & { title: string; url: string }): Promise<QuickLink> {
+    return this.request('/quick-links', {
+      method: 'POST',
+      body: JSON.stringify(input),
+    })
+  }
+
+  async updateQuickLink(id: number, input: QuickLinkInput): Promise<QuickLink> {
+    return this.request(`/quick-links/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(input),
+    })
+  }
+
+  async deleteQuickLink(id: number): Promise<{ message: string }> {
+    return this.request(`/quick-links/${id}`, {
+      method: 'DELETE',
+    })
+  }
+
+  async updateQuickLinkOrder(order: { id: number; order: number }[]): Promise<{ message: string }> {
+    return this.request('/quick-links/order', {
+      method: 'PUT',
+      body: JSON.stringify(order),
+    })
+  }
+
+  // Media
+  async uploadMedia(file: File, alt?: string): Promise<MediaLibrary> {
+    const formData = new FormData()
+    formData.append('file', file)
+    if (alt) {
+      formData.append('alt', alt)
+    }
+
+    const response = await fetch(`${this.getBaseUrl()}/media/upload`, {
+      method: 'POST',
+      headers: {
+        'Authorization': this.token ? `Bearer ${this.token}` : '',
+      },
+      body: formData,
+    })
+
+    if (!response.ok) {
+      if (response.status === 401) {
+        this.clearToken()
+        if (typeof window !== 'undefined') {
+          window.location.href = '/admin/login'
+        }
+      }
+      throw new Error(`Upload failed: ${response.status} ${response.statusText}`)
+    }
+
+    return response.json()
+  }
+
+  async getMediaMetadataStats(): Promise<ImageMetadataStats> {
+    return this.request('/media/metadata-stats')
+  }
+
+  async getUploadPolicy(): Promise<UploadPolicy> {
+    return this.request('/media/upload-policy')
+  }
+
+  async updateUploadPolicy(policy: Partial<UploadPolicy>): Promise<UploadPolicy> {
+    return this.request('/media/upload-policy', {
+      method: 'PUT',
+      body: JSON.stringify(policy),
+    })
+  }
+
+  async getWatermarkPolicy(): Promise<WatermarkPolicy> {
+    return this.request('/media/watermark')
+  }
+
+  async updateWatermarkPolicy(policy: Partial<WatermarkPolicy>): Promise<WatermarkPolicy> {
+    return this.request('/media/watermark', {
+      method: 'PUT',
+      body: JSON.stringify(policy),
+    })
+  }
+
+  // The file as uploaded, without the watermark
+  async downloadMediaOriginal(id: number): Promise<void> {
+    const response = await fetch(`${this.getBaseUrl()}/media/${id}/original`, {
+      headers: {
+        'Authorization': this.token ? `Bearer ${this.token}` : '',
+      },
+    })
+    if (!response.ok) {
+      const errorText = await response.text()
+      throw new Error(`Download failed: ${response.status} ${response.statusText} - ${errorText}`)
+    }
+
+    const blob = await response.blob()
+    const downloadUrl = window.URL.createObjectURL(blob)
+    const link = document.createElement('a')
+    link.href = downloadUrl
+    const contentDisposition = response.headers.get('Content-Disposition')
+    link.download = contentDisposition?.match(/filename="(.+)"/)?.[1] || `media-${id}`
+    document.body.appendChild(link)
+    link.click()
+    document.body.removeChild(link)
+    window.URL.revokeObjectURL(downloadUrl)
+  }
+
+  async uploadMediaBatch(files: File[], alts?: string[]): Promise<MediaBatchUploadResponse> {
+    const formData = new FormData()
+    files.forEach((file) => formData.append('files', file))
+
+    if (alts && alts.length > 0) {
+      formData.append('alts', JSON.stringify(alts))
+    }
+
+    const response = await fetch(`${this.getBaseUrl()}/media/upload/batch`, {
+      method: 'POST',
+      headers: {
+        'Authorization': this.token ? `Bearer ${this.token}` : '',
+      },
+      body: formData,
+    })
+
+    if (!response.ok) {
+      if (response.status === 401) {
+        this.clearToken()
+        if (typeof window !== 'undefined') {
+          window.location.href = '/admin/login'
+        }
+      }
+      throw new Error(`Upload failed: ${response.status} ${response.statusText}`)
+    }
+
+    return response.json()
+  }
+
+  async getMediaList(type?: 'image' | 'video', page = 1, limit = 20, search?: string): Promise<MediaListResponse> {
+    const params = new URLSearchParams({
+      page: page.toString(),
+      limit: limit.toString(),
+    })
+    
+    if (type) {
+      params.append('type', type)
+    }
+    
+    if (search) {
+      params.append('search', search)
+    }
+
+    return this.request<MediaListResponse>(`/media?${params}`)
+  }
+
+  async getMedia(id: number): Promise<MediaLibrary> {
+    return this.request<MediaLibrary>(`/media/${id}`)
+  }
+
+  // Link other sites may embed while hotlink protection is on; ttl such as '72h'
+  async signMediaURL(id: number, ttl?: string): Promise<{ url: string; expires_at: string }> {
+    const params = ttl ? `?ttl=${encodeURIComponent(ttl)}` : ''
+    return this.request(`/media/${id}/signed-url${params}`, { method: 'POST' })
+  }
+
+  async updateMedia(id: number, alt: string): Promise<MediaLibrary> {
+    return this.request<MediaLibrary>(`/media/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify({ alt }),
+    })
+  }
+
+  async deleteMedia(id: number): Promise<{ message: string }> {
+    return this.request(`/media/${id}`, {
+      method: 'DELETE',
+    })
+  }
+
+  async bulkDeleteMedia(ids: number[]): Promise<{
+    success_count: number
+    total_count: number
+    failed: Array<{ id: number; filename: string; error: string }>
+    message: string
+  }> {
+    return this.request('/media/bulk', {
+      method: 'DELETE',
+      body: JSON.stringify({ ids }),
+    })
+  }
+
+  // Analytics endpoints
+  async getAnalytics(params?: { lang?: string }): Promise<AnalyticsData> {
+    const queryParams = new URLSearchParams()
+    if (params?.lang) {
+      queryParams.append('lang', params.lang)
+    }
+    const url = queryParams.toString() ? `/analytics?${queryParams}` : '/analytics'
+    return this.request<AnalyticsData>(url)
+  }
+
+  async getArticleAnalytics(id: number, params?: { lang?: string }): Promise<ArticleAnalytics> {
+    const queryParams = new URLSearchParams()
+    if (params?.lang) {
+      queryParams.append('lang', params.lang)
+    }
+    const url = queryParams.toString() ? `/analytics/articles/${id}?${queryParams}` : `/analytics/articles/${id}`
+    return this.request<ArticleAnalytics>(url)
+  }
+
+  async refreshArticleShareCounts(id: number): Promise<{ id: number; status: string }> {
+    return this.request(`/analytics/articles/${id}/share-counts`, { method: 'POST' })
+  }
+
+  async getGeographicAnalytics(): Promise<{ geographic_stats: GeographicStats[] }> {
+    return this.request('/analytics/geographic')
+  }
+
+  async getGeographicStats(): Promise<GeographicStats[]> {
+    const result = await this.request<{ geographic_stats: GeographicStats[] }>('/analytics/geographic')
+    return result.geographic_stats
+  }
+
+  async getBrowserAnalytics(): Promise<{ browser_stats: BrowserStats[], platform_stats: PlatformStats[] }> {
+    return this.request('/analytics/browsers')
+  }
+
+  async getTrendAnalytics(days?: number): Promise<{ trends: TrendStats[] }> {
+    const queryParams = new URLSearchParams()
+    if (days) {
+      queryParams.append('days', days.toString())
+    }
+    const url = queryParams.toString() ? `/analytics/trends?${queryParams}` : '/analytics/trends'
+    return this.request(url)
+  }
+
+  // Export endpoints
+  async exportArticle(id: number, params?: { lang?: string }): Promise<void> {
+    const queryParams = new URLSearchParams()
+    if (params?.lang) {
+      queryParams.append('lang', params.lang)
+    }
+    const url = queryParams.toString() ? `/export/article/${id}?${queryParams}` : `/export/article/${id}`
+    
+    // Direct download via window.open
+    const fullUrl = `${this.getBaseUrl()}${url}`
+    const token = localStorage.getItem('auth_token')
+    if (token) {
+      // Use fetch to handle authentication
+      const response = await fetch(fullUrl, {
+        headers: {
+          'Authorization': `Bearer ${token}`
+        }
+      })
+      
+      if (response.ok) {
+        const blob = await response.blob()
+        const downloadUrl = window.URL.createObjectURL(blob)
+        const link = document.createElement('a')
+        link.href = downloadUrl
+        
+        // Extract filename from Content-Disposition header
+        const contentDisposition = response.headers.get('Content-Disposition')
+        const filename = contentDisposition?.match(/filename="(.+)"/)?.[1] || `article-${id}.md`
+        link.download = filename
+        
+        document.body.appendChild(link)
+        link.click()
+        document.body.removeChild(link)
+        window.URL.revokeObjectURL(downloadUrl)
+      } else {
+        const errorText = await response.text()
+        throw new Error(`Export failed: ${response.status} ${response.statusText} - ${errorText}`)
+      }
+    }
+  }
+
+  async exportArticles(params?: { 
+    lang?: string
+    categoryId?: number
+    articleIds?: number[]
+  }): Promise<void> {
+    const queryParams = new URLSearchParams()
+    if (params?.lang) {
+      queryParams.append('lang', params.lang)
+    }
+    if (params?.categoryId) {
+      queryParams.append('category_id', params.categoryId.toString())
+    }
+    if (params?.articleIds && params.articleIds.length > 0) {
+      queryParams.append('article_ids', params.articleIds.join(','))
+    }
+    
+    const url = queryParams.toString() ? `/export/articles?${queryParams}` : '/export/articles'
+    const fullUrl = `${this.getBaseUrl()}${url}`
+    const token = localStorage.getItem('auth_token')
+    
+    if (token) {
+      const response = await fetch(fullUrl, {
+        headers: {
+          'Authorization': `Bearer ${token}`
+        }
+      })
+      
+      if (response.ok) {
+        const blob = await response.blob()
+        const downloadUrl = window.URL.createObjectURL(blob)
+        const link = document.createElement('a')
+        link.href = downloadUrl
+        
+        const contentDisposition = response.headers.get('Content-Disposition')
+        const filename = contentDisposition?.match(/filename="(.+)"/)?.[1] || 'articles-export.zip'
+        link.download = filename
+        
+        document.body.appendChild(link)
+        link.click()
+        document.body.removeChild(link)
+        window.URL.revokeObjectURL(downloadUrl)
+      } else {
+        const errorText = await response.text()
+        throw new Error(`Export failed: ${response.status} ${response.statusText} - ${errorText}`)
+      }
+    }
+  }
+
+  async exportAllArticles(params?: { lang?: string }): Promise<void> {
+    const queryParams = new URLSearchParams()
+    if (params?.lang) {
+      queryParams.append('lang', params.lang)
+    }
+    
+    const url = queryParams.toString() ? `/export/all?${queryParams}` : '/export/all'
+    const fullUrl = `${this.getBaseUrl()}${url}`
+    const token = localStorage.getItem('auth_token')
+    
+    if (token) {
+      const response = await fetch(fullUrl, {
+        headers: {
+          'Authorization': `Bearer ${token}`
+        }
+      })
+      
+      if (response.ok) {
+        const blob = await response.blob()
+        const downloadUrl = window.URL.createObjectURL(blob)
+        const link = document.createElement('a')
+        link.href = downloadUrl
+        
+        const contentDisposition = response.headers.get('Content-Disposition')
+        const filename = contentDisposition?.match(/filename="(.+)"/)?.[1] || 'blog-export.zip'
+        link.download = filename
+        
+        document.body.appendChild(link)
+        link.click()
+        document.body.removeChild(link)
+        window.URL.revokeObjectURL(downloadUrl)
+      } else {
+        const errorText = await response.text()
+        throw new Error(`Export failed: ${response.status} ${response.statusText} - ${errorText}`)
+      }
+    }
+  }
+
+  // System endpoints
+  async getBootstrapConfig(): Promise<BootstrapConfig> {
+    return this.request('/config')
+  }
+
+  async getPageHead(path: string, lang?: string): Promise<PageHead> {
+    const params = new URLSearchParams({ path })
+    if (lang) params.append('lang', lang)
+    return this.request(`/head?${params}`)
+  }
+
+  async getSystemInfo(): Promise<{ system_info: any }> {
+    return this.request('/system/info')
+  }
+
+  async checkUpdates(): Promise<any> {
+    return this.request('/system/check-updates')
+  }
+
+  async clearCache(): Promise<{ message: string }> {
+    return this.request('/system/clear-cache', {
+      method: 'POST'
+    })
+  }
+
+  // Cache statistics and purges
+  async getCacheOverview(): Promise<CacheOverview> {
+    return this.request('/cache')
+  }
+
+  async purgeCacheNamespace(name: string): Promise<{ message: string }> {
+    return this.request(`/cache/${encodeURIComponent(name)}`, { method: 'DELETE' })
+  }
+
+  async purgeSmartCacheGroup(group: string): Promise<{ message: string }> {
+    return this.request(`/cache/smart/${encodeURIComponent(group)}`, { method: 'DELETE' })
+  }
+
+  async purgeAllCaches(): Promise<{ message: string }> {
+    return this.request('/cache', { method: 'DELETE' })
+  }
+
+  // Backup endpoints
+  async getBackupStatus(): Promise<BackupStatus> {
+    return this.request('/backups/status')
+  }
+
+  async getDiskUsage(refresh = false): Promise<DiskUsage> {
+    return this.request(`/system/disk${refresh ? '?refresh=true' : ''}`)
+  }
+
+  async getSiteMonitor(): Promise<SiteMonitorStatus> {
+    return this.request('/system/monitor')
+  }
+
+  async runSiteMonitor(): Promise<SiteMonitorCheck> {
+    return this.request('/system/monitor/check', {
+      method: 'POST'
+    })
+  }
+
+  // Newsletter endpoints
+  async subscribeNewsletter(email: string, language?: string): Promise<{ message: string }> {
+    return this.request('/newsletter/subscribe', {
+      method: 'POST',
+      body: JSON.stringify({ email, language })
+    })
+  }
+
+  async getNewsletters(): Promise<{ newsletters: Newsletter[] }> {
+    return this.request('/newsletters')
+  }
+
+  async createNewsletter(data: {
+    kind?: 'custom' | 'digest' | 'announcement'
+    article_id?: number
+    subject?: string
+    body?: string
+    language?: string
+  }): Promise<Newsletter> {
+    return this.request('/newsletters', {
+      method: 'POST',
+      body: JSON.stringify(data)
+    })
+  }
+
+  async composeAIDigest(data: { days?: number; limit?: number; language?: string } = {}): Promise<any> {
+    return this.request('/newsletters', {
+      method: 'POST',
+      body: JSON.stringify({ kind: 'ai_digest', ...data })
+    })
+  }
+
+  async updateNewsletter(id: number, data: { subject: string; body: string; language?: string }): Promise<Newsletter> {
+    return this.request(`/newsletters/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data)
+    })
+  }
+
+  async deleteNewsletter(id: number): Promise<{ message: string }> {
+    return this.request(`/newsletters/${id}`, {
+      method: 'DELETE'
+    })
+  }
+
+  async sendNewsletter(id: number): Promise<any> {
+    return this.request(`/newsletters/${id}/send`, {
+      method: 'POST'
+    })
+  }
+
+  async getSubscribers(params?: { status?: string; page?: number; limit?: number }): Promise<{
+    subscribers: Subscriber[]
+    stats: Record<string, number>
+    pagination: { page: number; limit: number; total: number }
+  }> {
+    const query = new URLSearchParams()
+    if (params?.status) query.set('status', params.status)
+    if (params?.page) query.set('page', String(params.page))
+    if (params?.limit) query.set('limit', String(params.limit))
+    const qs = query.toString()
+    return this.request(`/newsletters/subscribers${qs ? `?${qs}` : ''}`)
+  }
+
+  async deleteSubscriber(id: number): Promise<{ message: string }> {
+    return this.request(`/newsletters/subscribers/${id}`, {
+      method: 'DELETE'
+    })
+  }
+
+  // Member endpoints. Readers sign in for a token that unlocks
+  // members-only articles; admins manage the members.
+  async memberLogin(email: string, passcode: string): Promise<MemberSession> {
+    const session = await this.request<MemberSession>('/members/login', {
+      method: 'POST',
+      body: JSON.stringify({ email, passcode })
+    })
+    if (typeof window !== 'undefined') {
+      localStorage.setItem('member_token', session.token)
+    }
+    return session
+  }
+
+  async memberLogout(): Promise<void> {
+    try {
+      await this.request('/members/logout', { method: 'POST' })
+    } finally {
+      if (typeof window !== 'undefined') {
+        localStorage.removeItem('member_token')
+      }
+    }
+  }
+
+  async getCurrentMember(): Promise<{ member_id?: number; email?: string; name?: string; expires_at?: string }> {
+    return this.request('/members/me')
+  }
+
+  async getMembers(q?: string): Promise<{ members: Member[] }> {
+    return this.request(`/members${q ? `?q=${encodeURIComponent(q)}` : ''}`)
+  }
+
+  async createMember(data: { email: string; name?: string; note?: string; expires_at?: string }): Promise<{ member: Member; passcode: string }> {
+    return this.request('/members', {
+      method: 'POST',
+      body: JSON.stringify(data)
+    })
+  }
+
+  async updateMember(id: number, data: { email?: string; name?: string; note?: string; disabled?: boolean; expires_at?: string }): Promise<Member> {
+    return this.request(`/members/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data)
+    })
+  }
+
+  async resetMemberPasscode(id: number): Promise<{ member: Member; passcode: string }> {
+    return this.request(`/members/${id}/passcode`, {
+      method: 'POST'
+    })
+  }
+
+  async deleteMember(id: number): Promise<{ message: string }> {
+    return this.request(`/members/${id}`, {
+      method: 'DELETE'
+    })
+  }
+
+  // Chat notifier endpoints
+  async getNotifiers(): Promise<{ notifiers: Notifier[] }> {
+    return this.request('/notifiers')
+  }
+
+  async createNotifier(data: NotifierInput): Promise<Notifier> {
+    return this.request('/notifiers', {
+      method: 'POST',
+      body: JSON.stringify(data)
+    })
+  }
+
+  async updateNotifier(id: number, data: NotifierInput): Promise<Notifier> {
+    return this.request(`/notifiers/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data)
+    })
+  }
+
+  async deleteNotifier(id: number): Promise<{ message: string }> {
+    return this.request(`/notifiers/${id}`, {
+      method: 'DELETE'
+    })
+  }
+
+  async testNotifier(id: number): Promise<{ message: string }> {
+    return this.request(`/notifiers/${id}/test`, {
+      method: 'POST'
+    })
+  }
+
+  // Baidu, Sogou and 360 URL push endpoints
+  async getSearchPushEngines(): Promise<{ engines: SearchPushEngine[] }> {
+    return this.request('/search-push/engines')
+  }
+
+  async createSearchPushEngine(data: SearchPushEngineInput): Promise<SearchPushEngine> {
+    return this.request('/search-push/engines', {
+      method: 'POST',
+      body: JSON.stringify(data)
+    })
+  }
+
+  async updateSearchPushEngine(id: number, data: SearchPushEngineInput): Promise<SearchPushEngine> {
+    return this.request(`/search-push/engines/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data)
+    })
+  }
+
+  async deleteSearchPushEngine(id: number): Promise<{ message: string }> {
+    return this.request(`/search-push/engines/${id}`, {
+      method: 'DELETE'
+    })
+  }
+
+  async submitSearchPush(id: number, articleId: number): Promise<SearchPushLog> {
+    return this.request(`/search-push/engines/${id}/submit`, {
+      method: 'POST',
+      body: JSON.stringify({ article_id: articleId })
+    })
+  }
+
+  async getSearchPushLogs(params: { engine_id?: number; article_id?: number; page?: number; limit?: number } = {}): Promise<{
+    logs: SearchPushLog[]
+    pagination: { page: number; limit: number; total: number }
+  }> {
+    const query = new URLSearchParams()
+    Object.entries(params).forEach(([key, value]) => {
+      if (value !== undefined) query.append(key, String(value))
+    })
+    const suffix = query.toString() ? `?${query}` : ''
+    return this.request(`/search-push/logs${suffix}`)
+  }
+
+  // Fediverse (ActivityPub) endpoints
+  async getFediverseFollowers(): Promise<{ followers: FediverseFollower[] }> {
+    return this.request('/fediverse/followers')
+  }
+
+  async removeFediverseFollower(id: number): Promise<{ message: string }> {
+    return this.request(`/fediverse/followers/${id}`, {
+      method: 'DELETE'
+    })
+  }
+
+  async getFediverseReplies(params?: { status?: string; article_id?: number }): Promise<{ replies: FediverseReply[] }> {
+    const query = new URLSearchParams()
+    if (params?.status) query.set('status', params.status)
+    if (params?.article_id) query.set('article_id', String(params.article_id))
+    const suffix = query.toString() ? `?${query}` : ''
+    return this.request(`/fediverse/replies${suffix}`)
   }
 
-  // Language configuration
-  async getLanguageConfig(): Promise<LanguageConfig> {
-    return this.request<LanguageConfig>('/languages')
+  async updateFediverseReply(id: number, status: FediverseReply['status']): Promise<FediverseReply> {
+    return this.request(`/fediverse/replies/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify({ status })
+    })
   }
 
-  async updateSettings(settings: Partial<SiteSettings>): Promise<SiteSettings> {
-    return this.request<SiteSettings>('/settings', {
-      method: 'PUT',
-      body: JSON.stringify(settings),
+  async deleteFediverseReply(id: number): Promise<{ message: string }> {
+    return this.request(`/fediverse/replies/${id}`, {
+      method: 'DELETE'
     })
   }
 
-  // Upload logo file
-  async uploadLogo(file: File): Promise<{ url: string; message: string }> {
+  async importComments(file: File, format: 'disqus' | 'artalk' | 'waline'): Promise<CommentImportResult> {
     const formData = new FormData()
     formData.append('file', file)
 
-    const response = await fetch(`${this.getBaseUrl()}/settings/upload-logo`, {
+    const response = await fetch(`${this.getBaseUrl()}/fediverse/replies/import?format=${format}`, {
       method: 'POST',
       headers: {
         'Authorization': this.token ? `Bearer ${this.token}` : '',
@@ -970,467 +2891,412 @@ class ApiClient {
           window.location.href = '/admin/login'
         }
       }
-      throw new Error(`Upload failed: ${response.status} ${response.statusText}`)
+      const error = await response.json().catch(() => null)
+      throw new Error(error?.error || `Import failed: ${response.status} ${response.statusText}`)
     }
 
     return response.json()
   }
 
-  // Upload favicon file
-  async uploadFavicon(file: File): Promise<{ url: string; message: string }> {
-    const formData = new FormData()
-    formData.append('file', file)
-
-    const response = await fetch(`${this.getBaseUrl()}/settings/upload-favicon`, {
-      method: 'POST',
-      headers: {
-        'Authorization': this.token ? `Bearer ${this.token}` : '',
-      },
-      body: formData,
-    })
+  async getArticleFediverseReplies(articleId: number): Promise<{ replies: FediverseReply[] }> {
+    return this.request(`/activitypub/replies?article_id=${articleId}`)
+  }
 
-    if (!response.ok) {
-      if (response.status === 401) {
-        this.clearToken()
-        if (typeof window !== 'undefined') {
-          window.location.href = '/admin/login'
-        }
-      }
-      throw new Error(`Upload failed: ${response.status} ${response.statusText}`)
-    }
+  async getArticleWebmentions(articleId: number): Promise<{ mentions: FediverseReply[] }> {
+    return this.request(`/webmention/mentions?article_id=${articleId}`)
+  }
 
-    return response.json()
+  // Mail endpoints
+  async getMailSettings(): Promise<MailSettings> {
+    return this.request('/mail/settings')
   }
 
-  // Upload background image file
-  async uploadBackgroundImage(file: File): Promise<{ url: string; message: string }> {
-    const formData = new FormData()
-    formData.append('file', file)
+  async updateMailSettings(settings: Partial<MailSettings>): Promise<MailSettings> {
+    return this.request('/mail/settings', {
+      method: 'PUT',
+      body: JSON.stringify(settings)
+    })
+  }
 
-    const response = await fetch(`${this.getBaseUrl()}/settings/upload-background`, {
+  async sendTestMail(to: string, language?: string): Promise<{ message: string }> {
+    return this.request('/mail/test', {
       method: 'POST',
-      headers: {
-        'Authorization': this.token ? `Bearer ${this.token}` : '',
-      },
-      body: formData,
+      body: JSON.stringify({ to, language })
     })
+  }
 
-    if (!response.ok) {
-      if (response.status === 401) {
-        this.clearToken()
-        if (typeof window !== 'undefined') {
-          window.location.href = '/admin/login'
-        }
-      }
-      throw new Error(`Upload failed: ${response.status} ${response.statusText}`)
-    }
+  async getMailTemplates(): Promise<{ templates: MailTemplateInfo[] }> {
+    return this.request('/mail/templates')
+  }
 
-    return response.json()
+  async getMailTemplate(name: string, lang: string): Promise<MailTemplate> {
+    return this.request(`/mail/templates/${name}/${lang}`)
   }
 
-  // Remove background image
-  async removeBackgroundImage(): Promise<{ message: string }> {
-    const response = await fetch(`${this.getBaseUrl()}/settings/background`, {
-      method: 'DELETE',
-      headers: {
-        'Authorization': this.token ? `Bearer ${this.token}` : '',
-        'Content-Type': 'application/json',
-      },
+  async updateMailTemplate(name: string, lang: string, data: { subject: string; body: string }): Promise<MailTemplate> {
+    return this.request(`/mail/templates/${name}/${lang}`, {
+      method: 'PUT',
+      body: JSON.stringify(data)
     })
-
-    if (!response.ok) {
-      if (response.status === 401) {
-        this.clearToken()
-        if (typeof window !== 'undefined') {
-          window.location.href = '/admin/login'
-        }
-      }
-      throw new Error(`Remove failed: ${response.status} ${response.statusText}`)
-    }
-
-    return response.json()
   }
 
-  // Authentication
-  async login(credentials: LoginRequest): Promise<LoginResponse> {
-    const response = await this.request<LoginResponse>('/login', {
-      method: 'POST',
-      body: JSON.stringify(credentials),
+  async resetMailTemplate(name: string, lang: string): Promise<{ message: string }> {
+    return this.request(`/mail/templates/${name}/${lang}`, {
+      method: 'DELETE'
     })
-    
-    this.saveToken(response.token)
-    return response
   }
 
-  logout() {
-    this.clearToken()
-    if (typeof window !== 'undefined') {
-      window.location.href = '/'
-    }
+  async previewMailTemplate(name: string, lang: string): Promise<{ subject: string; body: string }> {
+    return this.request(`/mail/templates/${name}/${lang}/preview`)
   }
 
-  isAuthenticated(): boolean {
-    return !!this.token
+  async getStopWordLists(): Promise<{ lists: StopWordList[] }> {
+    return this.request('/stop-words')
   }
 
-  async getCurrentUser(): Promise<User> {
-    return this.request<User>('/me')
+  async getStopWords(lang: string): Promise<StopWordList> {
+    return this.request(`/stop-words/${lang}`)
   }
 
-  async changePassword(oldPassword: string, newPassword: string): Promise<{ message: string }> {
-    return this.request('/change-password', {
+  async updateStopWords(lang: string, words: string[]): Promise<StopWordList> {
+    return this.request(`/stop-words/${lang}`, {
       method: 'PUT',
-      body: JSON.stringify({ old_password: oldPassword, new_password: newPassword }),
+      body: JSON.stringify({ words })
     })
   }
 
-  async getRecoveryStatus(): Promise<RecoveryStatusResponse> {
-    return this.request('/recovery-status', {
-      method: 'GET',
+  async resetStopWords(lang: string): Promise<{ message: string }> {
+    return this.request(`/stop-words/${lang}`, {
+      method: 'DELETE'
     })
   }
 
-  // Setup
-  async getSetupStatus(): Promise<SetupStatusResponse> {
-    return this.request('/setup/status', {
-      method: 'GET',
+  async previewKeywords(lang: string, text: string): Promise<KeywordPreview> {
+    return this.request(`/stop-words/${lang}/preview`, {
+      method: 'POST',
+      body: JSON.stringify({ text })
     })
   }
 
-  async initializeSetup(data: SetupRequest): Promise<SetupResponse> {
-    return this.request('/setup/initialize', {
-      method: 'POST',
-      body: JSON.stringify(data),
-    })
+  async getSEORules(): Promise<{ rules: SEORules[] }> {
+    return this.request('/seo/rules')
   }
 
-  // Social Media
-  async getSocialMediaList(): Promise<SocialMedia[]> {
-    return this.request('/social-media')
+  async updateSEORules(lang: string, rules: Omit<SEORules, 'language' | 'customized'>): Promise<SEORules> {
+    return this.request(`/seo/rules/${lang}`, {
+      method: 'PUT',
+      body: JSON.stringify(rules)
+    })
   }
 
-  async getAllSocialMedia(): Promise<SocialMedia[]> {
-    return this.request('/social-media/all', {
-      method: 'GET',
+  async resetSEORules(lang: string): Promise<SEORules> {
+    return this.request(`/seo/rules/${lang}`, {
+      method: 'DELETE'
     })
   }
 
-  async getSocialMedia(id: number): Promise<SocialMedia> {
-    return this.request(`/social-media/${id}`)
+  async getContentQualityReport(params: { language?: string; max_score?: number; limit?: number; offset?: number } = {}): Promise<{
+    analyses: ContentQualityAnalysis[]
+    total: number
+  }> {
+    const query = new URLSearchParams()
+    if (params.language) query.append('language', params.language)
+    if (params.max_score !== undefined) query.append('max_score', params.max_score.toString())
+    if (params.limit) query.append('limit', params.limit.toString())
+    if (params.offset) query.append('offset', params.offset.toString())
+    const queryString = query.toString()
+    return this.request(`/content-quality${queryString ? `?${queryString}` : ''}`)
   }
 
-  async createSocialMedia(data: Omit<SocialMedia, 'id' | 'created_at' | 'updated_at'>): Promise<SocialMedia> {
-    return this.request('/social-media', {
-      method: 'POST',
-      body: JSON.stringify(data),
-    })
+  async getArticleContentQuality(articleId: number): Promise<ContentQualityAnalysis> {
+    return this.request(`/content-quality/articles/${articleId}`)
   }
 
-  async updateSocialMedia(id: number, data: Partial<SocialMedia>): Promise<SocialMedia> {
-    return this.request(`/social-media/${id}`, {
-      method: 'PUT',
-      body: JSON.stringify(data),
+  async runContentQuality(): Promise<{ id: number; status: string }> {
+    return this.request('/content-quality/run', {
+      method: 'POST'
     })
   }
 
-  async deleteSocialMedia(id: number): Promise<{ message: string }> {
-    return this.request(`/social-media/${id}`, {
-      method: 'DELETE',
+  async getWritingSuggestions(params: { language?: string; status?: string; type?: string; limit?: number } = {}): Promise<{
+    suggestions: WritingSuggestion[]
+  }> {
+    const query = new URLSearchParams()
+    if (params.language) query.append('language', params.language)
+    if (params.status) query.append('status', params.status)
+    if (params.type) query.append('type', params.type)
+    if (params.limit) query.append('limit', params.limit.toString())
+    const queryString = query.toString()
+    return this.request(`/writing-suggestions${queryString ? `?${queryString}` : ''}`)
+  }
+
+  async generateWritingSuggestions(): Promise<{ id: number; status: string }> {
+    return this.request('/writing-suggestions/generate', {
+      method: 'POST'
     })
   }
 
-  async updateSocialMediaOrder(order: { id: number; order: number }[]): Promise<{ message: string }> {
-    return this.request('/social-media/order', {
-      method: 'PUT',
-      body: JSON.stringify(order),
+  async acceptWritingSuggestion(id: number): Promise<WritingSuggestion> {
+    return this.request(`/writing-suggestions/${id}/accept`, {
+      method: 'POST'
     })
   }
 
-  // Media
-  async uploadMedia(file: File, alt?: string): Promise<MediaLibrary> {
-    const formData = new FormData()
-    formData.append('file', file)
-    if (alt) {
-      formData.append('alt', alt)
-    }
+  async dismissWritingSuggestion(id: number): Promise<WritingSuggestion> {
+    return this.request(`/writing-suggestions/${id}/dismiss`, {
+      method: 'POST'
+    })
+  }
 
-    const response = await fetch(`${this.getBaseUrl()}/media/upload`, {
-      method: 'POST',
-      headers: {
-        'Authorization': this.token ? `Bearer ${this.token}` : '',
-      },
-      body: formData,
+  // Streams a job's progress until it finishes. EventSource cannot send
+  // the Authorization header, so the event stream is read with fetch.
+  async streamJobProgress(id: number, onProgress: (progress: JobProgress) => void, signal?: AbortSignal): Promise<JobProgress> {
+    const response = await fetch(`${this.getBaseUrl()}/jobs/${id}/progress`, {
+      headers: this.token ? { 'Authorization': `Bearer ${this.token}` } : {},
+      signal,
     })
+    if (!response.ok || !response.body) {
+      throw new Error(`API request failed: ${response.status} ${response.statusText}`)
+    }
 
-    if (!response.ok) {
-      if (response.status === 401) {
-        this.clearToken()
-        if (typeof window !== 'undefined') {
-          window.location.href = '/admin/login'
+    const reader = response.body.getReader()
+    const decoder = new TextDecoder()
+    let buffer = ''
+    for (;;) {
+      const { done, value } = await reader.read()
+      if (done) {
+        throw new Error('Job progress stream closed before the job finished')
+      }
+      buffer += decoder.decode(value, { stream: true })
+      let end
+      while ((end = buffer.indexOf('\n\n')) >= 0) {
+        const message = buffer.slice(0, end)
+        buffer = buffer.slice(end + 2)
+        let event = 'message'
+        let data = ''
+        for (const line of message.split('\n')) {
+          if (line.startsWith('event:')) event = line.slice(6).trim()
+          else if (line.startsWith('data:')) data += line.slice(5)
+        }
+        if (event === 'error') {
+          throw new Error(JSON.parse(data).error)
+        }
+        if (event !== 'progress' && event !== 'done') continue
+        const progress = JSON.parse(data) as JobProgress
+        onProgress(progress)
+        if (event === 'done') {
+          reader.cancel()
+          return progress
         }
       }
-      throw new Error(`Upload failed: ${response.status} ${response.statusText}`)
     }
+  }
 
-    return response.json()
+  async getNotifications(params: { source?: string; type?: string; severity?: string; is_read?: boolean; page?: number; limit?: number } = {}): Promise<{
+    notifications: AdminNotification[]
+    summary: NotificationSummary
+    pagination: { page: number; limit: number; total: number }
+  }> {
+    const query = new URLSearchParams()
+    if (params.source) query.set('source', params.source)
+    if (params.type) query.set('type', params.type)
+    if (params.severity) query.set('severity', params.severity)
+    if (params.is_read !== undefined) query.set('is_read', String(params.is_read))
+    if (params.page) query.set('page', String(params.page))
+    if (params.limit) query.set('limit', String(params.limit))
+    const suffix = query.toString()
+    return this.request(`/notifications${suffix ? `?${suffix}` : ''}`)
   }
 
-  async uploadMediaBatch(files: File[], alts?: string[]): Promise<MediaBatchUploadResponse> {
-    const formData = new FormData()
-    files.forEach((file) => formData.append('files', file))
+  async getNotificationSummary(): Promise<NotificationSummary> {
+    return this.request('/notifications/summary')
+  }
 
-    if (alts && alts.length > 0) {
-      formData.append('alts', JSON.stringify(alts))
-    }
+  async setNotificationRead(id: number, isRead: boolean): Promise<AdminNotification> {
+    return this.request(`/notifications/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify({ is_read: isRead }),
+    })
+  }
 
-    const response = await fetch(`${this.getBaseUrl()}/media/upload/batch`, {
+  async markAllNotificationsRead(source?: string): Promise<{ marked: number }> {
+    return this.request(`/notifications/read-all${source ? `?source=${encodeURIComponent(source)}` : ''}`, {
       method: 'POST',
-      headers: {
-        'Authorization': this.token ? `Bearer ${this.token}` : '',
-      },
-      body: formData,
     })
+  }
 
-    if (!response.ok) {
-      if (response.status === 401) {
-        this.clearToken()
-        if (typeof window !== 'undefined') {
-          window.location.href = '/admin/login'
-        }
-      }
-      throw new Error(`Upload failed: ${response.status} ${response.statusText}`)
-    }
-
-    return response.json()
+  async deleteNotification(id: number): Promise<{ message: string }> {
+    return this.request(`/notifications/${id}`, { method: 'DELETE' })
   }
 
-  async getMediaList(type?: 'image' | 'video', page = 1, limit = 20, search?: string): Promise<MediaListResponse> {
-    const params = new URLSearchParams({
-      page: page.toString(),
-      limit: limit.toString(),
+  // Opens the websocket behind the admin header badge. onSummary gets the
+  // unread counts when it opens and whenever notifications change; close the
+  // returned socket to stop.
+  async openNotificationStream(onSummary: (summary: NotificationSummary) => void): Promise<WebSocket> {
+    const { ticket } = await this.request<{ ticket: string; expires_in: number }>('/notifications/stream/ticket', {
+      method: 'POST',
     })
-    
-    if (type) {
-      params.append('type', type)
-    }
-    
-    if (search) {
-      params.append('search', search)
+    const url = new URL(`${this.getBaseUrl()}/notifications/stream`, window.location.href)
+    url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:'
+    url.searchParams.set('ticket', ticket)
+    const socket = new WebSocket(url.toString())
+    socket.onmessage = (event) => {
+      const message = JSON.parse(event.data) as { type: string } & NotificationSummary
+      if (message.type === 'summary') onSummary(message)
     }
-
-    return this.request<MediaListResponse>(`/media?${params}`)
+    return socket
   }
 
-  async getMedia(id: number): Promise<MediaLibrary> {
-    return this.request<MediaLibrary>(`/media/${id}`)
+  async getMailSuppressions(params: { reason?: string; page?: number; limit?: number } = {}): Promise<{
+    suppressions: MailSuppression[]
+    pagination: { page: number; limit: number; total: number }
+  }> {
+    const query = new URLSearchParams()
+    if (params.reason) query.set('reason', params.reason)
+    if (params.page) query.set('page', String(params.page))
+    if (params.limit) query.set('limit', String(params.limit))
+    const suffix = query.toString()
+    return this.request(`/mail/suppressions${suffix ? `?${suffix}` : ''}`)
   }
 
-  async updateMedia(id: number, alt: string): Promise<MediaLibrary> {
-    return this.request<MediaLibrary>(`/media/${id}`, {
-      method: 'PUT',
-      body: JSON.stringify({ alt }),
+  async addMailSuppression(email: string, detail?: string): Promise<MailSuppression> {
+    return this.request('/mail/suppressions', {
+      method: 'POST',
+      body: JSON.stringify({ email, detail })
     })
   }
 
-  async deleteMedia(id: number): Promise<{ message: string }> {
-    return this.request(`/media/${id}`, {
-      method: 'DELETE',
+  async removeMailSuppression(id: number): Promise<{ message: string }> {
+    return this.request(`/mail/suppressions/${id}`, {
+      method: 'DELETE'
     })
-  }
-
-  async bulkDeleteMedia(ids: number[]): Promise<{
-    success_count: number
-    total_count: number
-    failed: Array<{ id: number; filename: string; error: string }>
-    message: string
-  }> {
-    return this.request('/media/bulk', {
-      method: 'DELETE',
-      body: JSON.stringify({ ids }),
+  }
+
+  async getTranslationStatus(language?: string): Promise<TranslationStatus> {
+    return this.request(`/translations/status${language ? `?language=${encodeURIComponent(language)}` : ''}`)
+  }
+
+  async reviewTranslation(kind: TranslationKind, id: number, language: string): Promise<{ message: string }> {
+    return this.request('/translations/review', {
+      method: 'POST',
+      body: JSON.stringify({ kind, id, language })
     })
   }
 
-  // Analytics endpoints
-  async getAnalytics(params?: { lang?: string }): Promise<AnalyticsData> {
-    const queryParams = new URLSearchParams()
-    if (params?.lang) {
-      queryParams.append('lang', params.lang)
-    }
-    const url = queryParams.toString() ? `/analytics?${queryParams}` : '/analytics'
-    return this.request<AnalyticsData>(url)
+  async getStaleTranslations(articleId?: number): Promise<{ translations: StaleTranslation[] }> {
+    return this.request(`/translations/stale${articleId ? `?article_id=${articleId}` : ''}`)
   }
 
-  async getArticleAnalytics(id: number, params?: { lang?: string }): Promise<ArticleAnalytics> {
-    const queryParams = new URLSearchParams()
-    if (params?.lang) {
-      queryParams.append('lang', params.lang)
-    }
-    const url = queryParams.toString() ? `/analytics/articles/${id}?${queryParams}` : `/analytics/articles/${id}`
-    return this.request<ArticleAnalytics>(url)
+  async lookupTranslationMemory(from: string, to: string, segments: string[]): Promise<{ matches: TranslationMemoryMatch[] }> {
+    return this.request('/translations/memory/lookup', {
+      method: 'POST',
+      body: JSON.stringify({ source_language: from, target_language: to, segments })
+    })
   }
 
-  async getGeographicAnalytics(): Promise<{ geographic_stats: GeographicStats[] }> {
-    return this.request('/analytics/geographic')
+  async rememberTranslations(from: string, to: string, pairs: TranslationPair[]): Promise<{ stored: number }> {
+    return this.request('/translations/memory', {
+      method: 'POST',
+      body: JSON.stringify({ source_language: from, target_language: to, pairs })
+    })
   }
 
-  async getGeographicStats(): Promise<GeographicStats[]> {
-    const result = await this.request<{ geographic_stats: GeographicStats[] }>('/analytics/geographic')
-    return result.geographic_stats
+  async getTranslationMemory(params?: {
+    source_language?: string
+    target_language?: string
+    q?: string
+    page?: number
+    limit?: number
+  }): Promise<{ entries: TranslationMemoryEntry[]; enabled: boolean; pagination: { page: number; limit: number; total: number } }> {
+    const query = new URLSearchParams()
+    Object.entries(params || {}).forEach(([key, value]) => {
+      if (value !== undefined && value !== '') {
+        query.append(key, String(value))
+      }
+    })
+    return this.request(`/translations/memory${query.toString() ? `?${query}` : ''}`)
   }
 
-  async getBrowserAnalytics(): Promise<{ browser_stats: BrowserStats[], platform_stats: PlatformStats[] }> {
-    return this.request('/analytics/browsers')
+  async deleteTranslationMemoryEntry(id: number): Promise<{ message: string }> {
+    return this.request(`/translations/memory/${id}`, { method: 'DELETE' })
   }
 
-  async getTrendAnalytics(days?: number): Promise<{ trends: TrendStats[] }> {
-    const queryParams = new URLSearchParams()
-    if (days) {
-      queryParams.append('days', days.toString())
-    }
-    const url = queryParams.toString() ? `/analytics/trends?${queryParams}` : '/analytics/trends'
-    return this.request(url)
+  async clearTranslationMemory(): Promise<{ message: string }> {
+    return this.request('/translations/memory', { method: 'DELETE' })
   }
 
-  // Export endpoints
-  async exportArticle(id: number, params?: { lang?: string }): Promise<void> {
-    const queryParams = new URLSearchParams()
-    if (params?.lang) {
-      queryParams.append('lang', params.lang)
-    }
-    const url = queryParams.toString() ? `/export/article/${id}?${queryParams}` : `/export/article/${id}`
-    
-    // Direct download via window.open
-    const fullUrl = `${this.getBaseUrl()}${url}`
-    const token = localStorage.getItem('auth_token')
-    if (token) {
-      // Use fetch to handle authentication
-      const response = await fetch(fullUrl, {
-        headers: {
-          'Authorization': `Bearer ${token}`
-        }
-      })
-      
-      if (response.ok) {
-        const blob = await response.blob()
-        const downloadUrl = window.URL.createObjectURL(blob)
-        const link = document.createElement('a')
-        link.href = downloadUrl
-        
-        // Extract filename from Content-Disposition header
-        const contentDisposition = response.headers.get('Content-Disposition')
-        const filename = contentDisposition?.match(/filename="(.+)"/)?.[1] || `article-${id}.md`
-        link.download = filename
-        
-        document.body.appendChild(link)
-        link.click()
-        document.body.removeChild(link)
-        window.URL.revokeObjectURL(downloadUrl)
-      } else {
-        const errorText = await response.text()
-        throw new Error(`Export failed: ${response.status} ${response.statusText} - ${errorText}`)
-      }
-    }
+  async refreshTranslations(articleId: number, language?: string): Promise<{ message: string; jobs: { id: number }[] }> {
+    return this.request('/translations/refresh', {
+      method: 'POST',
+      body: JSON.stringify({ article_id: articleId, language })
+    })
   }
 
-  async exportArticles(params?: { 
-    lang?: string
-    categoryId?: number
-    articleIds?: number[]
-  }): Promise<void> {
-    const queryParams = new URLSearchParams()
-    if (params?.lang) {
-      queryParams.append('lang', params.lang)
-    }
-    if (params?.categoryId) {
-      queryParams.append('category_id', params.categoryId.toString())
-    }
-    if (params?.articleIds && params.articleIds.length > 0) {
-      queryParams.append('article_ids', params.articleIds.join(','))
+  async exportTranslations(language: string, format: 'xliff' | 'po' = 'xliff', missing = false): Promise<void> {
+    const queryParams = new URLSearchParams({ language, format })
+    if (missing) {
+      queryParams.append('missing', 'true')
     }
-    
-    const url = queryParams.toString() ? `/export/articles?${queryParams}` : '/export/articles'
-    const fullUrl = `${this.getBaseUrl()}${url}`
-    const token = localStorage.getItem('auth_token')
-    
-    if (token) {
-      const response = await fetch(fullUrl, {
-        headers: {
-          'Authorization': `Bearer ${token}`
-        }
-      })
-      
-      if (response.ok) {
-        const blob = await response.blob()
-        const downloadUrl = window.URL.createObjectURL(blob)
-        const link = document.createElement('a')
-        link.href = downloadUrl
-        
-        const contentDisposition = response.headers.get('Content-Disposition')
-        const filename = contentDisposition?.match(/filename="(.+)"/)?.[1] || 'articles-export.zip'
-        link.download = filename
-        
-        document.body.appendChild(link)
-        link.click()
-        document.body.removeChild(link)
-        window.URL.revokeObjectURL(downloadUrl)
-      } else {
-        const errorText = await response.text()
-        throw new Error(`Export failed: ${response.status} ${response.statusText} - ${errorText}`)
-      }
+
+    const response = await fetch(`${this.getBaseUrl()}/translations/export?${queryParams}`, {
+      headers: {
+        'Authorization': this.token ? `Bearer ${this.token}` : '',
+      },
+    })
+    if (!response.ok) {
+      const errorText = await response.text()
+      throw new Error(`Export failed: ${response.status} ${response.statusText} - ${errorText}`)
     }
+
+    const blob = await response.blob()
+    const downloadUrl = window.URL.createObjectURL(blob)
+    const link = document.createElement('a')
+    link.href = downloadUrl
+    const contentDisposition = response.headers.get('Content-Disposition')
+    link.download = contentDisposition?.match(/filename="(.+)"/)?.[1] || `kuno-${language}.${format === 'po' ? 'po' : 'xlf'}`
+    document.body.appendChild(link)
+    link.click()
+    document.body.removeChild(link)
+    window.URL.revokeObjectURL(downloadUrl)
   }
 
-  async exportAllArticles(params?: { lang?: string }): Promise<void> {
-    const queryParams = new URLSearchParams()
-    if (params?.lang) {
-      queryParams.append('lang', params.lang)
-    }
-    
-    const url = queryParams.toString() ? `/export/all?${queryParams}` : '/export/all'
-    const fullUrl = `${this.getBaseUrl()}${url}`
-    const token = localStorage.getItem('auth_token')
-    
-    if (token) {
-      const response = await fetch(fullUrl, {
-        headers: {
-          'Authorization': `Bearer ${token}`
+  async importTranslations(file: File, language?: string): Promise<{ message: string; result: TranslationImportResult }> {
+    const formData = new FormData()
+    formData.append('file', file)
+    const query = language ? `?language=${encodeURIComponent(language)}` : ''
+
+    const response = await fetch(`${this.getBaseUrl()}/translations/import${query}`, {
+      method: 'POST',
+      headers: {
+        'Authorization': this.token ? `Bearer ${this.token}` : '',
+      },
+      body: formData,
+    })
+
+    if (!response.ok) {
+      if (response.status === 401) {
+        this.clearToken()
+        if (typeof window !== 'undefined') {
+          window.location.href = '/admin/login'
         }
-      })
-      
-      if (response.ok) {
-        const blob = await response.blob()
-        const downloadUrl = window.URL.createObjectURL(blob)
-        const link = document.createElement('a')
-        link.href = downloadUrl
-        
-        const contentDisposition = response.headers.get('Content-Disposition')
-        const filename = contentDisposition?.match(/filename="(.+)"/)?.[1] || 'blog-export.zip'
-        link.download = filename
-        
-        document.body.appendChild(link)
-        link.click()
-        document.body.removeChild(link)
-        window.URL.revokeObjectURL(downloadUrl)
-      } else {
-        const errorText = await response.text()
-        throw new Error(`Export failed: ${response.status} ${response.statusText} - ${errorText}`)
       }
+      const error = await response.json().catch(() => null)
+      throw new Error(error?.error || `Import failed: ${response.status} ${response.statusText}`)
     }
-  }
-
-  // System endpoints
-  async getSystemInfo(): Promise<{ system_info: any }> {
-    return this.request('/system/info')
-  }
 
-  async checkUpdates(): Promise<any> {
-    return this.request('/system/check-updates')
+    return response.json()
   }
 
-  async clearCache(): Promise<{ message: string }> {
-    return this.request('/system/clear-cache', {
-      method: 'POST'
-    })
+  // Link previews: signed-in editors can preview any link, readers only
+  // links of published articles
+  async getLinkPreview(url: string): Promise<LinkPreview> {
+    if (this.token) {
+      return this.request<LinkPreview>('/link-preview', {
+        method: 'POST',
+        body: JSON.stringify({ url }),
+      })
+    }
+    return this.request<LinkPreview>(`/link-preview?url=${encodeURIComponent(url)}`)
   }
 
   // Search endpoints
@@ -1438,6 +3304,10 @@ class ApiClient {
     page?: number
     limit?: number
     lang?: string
+    // Characters of the content snippet, 40 to 500
+    snippetLength?: number
+    // JSON keys each article is trimmed to, e.g. ['id', 'title', 'highlight']
+    fields?: string[]
   }): Promise<{
     articles: Article[]
     pagination: {
@@ -1452,12 +3322,72 @@ class ApiClient {
       q: query,
       ...(options?.page && { page: options.page.toString() }),
       ...(options?.limit && { limit: options.limit.toString() }),
-      ...(options?.lang && { lang: options.lang })
+      ...(options?.lang && { lang: options.lang }),
+      ...(options?.snippetLength && { snippet_length: options.snippetLength.toString() }),
+      ...(options?.fields?.length && { fields: options.fields.join(',') })
     })
 
     return this.request(`/articles/search?${params.toString()}`)
   }
 
+  // Topic endpoints
+  async getSimilarTopics(kind: TopicKind, key: string | number, options?: { lang?: string; limit?: number }): Promise<{
+    topics: TopicSimilarity[]
+    language: string
+  }> {
+    const params = new URLSearchParams({
+      ...(options?.lang && { lang: options.lang }),
+      ...(options?.limit && { limit: options.limit.toString() })
+    })
+    return this.request(`/topics/${kind}/${encodeURIComponent(String(key))}/similar?${params.toString()}`)
+  }
+
+  async getTopicArticles(kind: TopicKind, key: string | number, options?: { lang?: string; limit?: number }): Promise<{
+    articles: EmbeddingSearchResult[]
+    language: string
+  }> {
+    const params = new URLSearchParams({
+      ...(options?.lang && { lang: options.lang }),
+      ...(options?.limit && { limit: options.limit.toString() })
+    })
+    return this.request(`/topics/${kind}/${encodeURIComponent(String(key))}/articles?${params.toString()}`)
+  }
+
+  async rebuildTopics(): Promise<{ message: string; job: { id: number } }> {
+    return this.request('/topics/rebuild', { method: 'POST' })
+  }
+
+  async getSearchIndexStatus(): Promise<{
+    indexes: Array<{
+      index_type: 'keyword' | 'embedding'
+      language: string
+      total_documents: number
+      last_updated: string
+      last_rebuild: string
+    }>
+    last_rebuild: { id: number; status: string; error?: string; updated_at: string } | null
+  }> {
+    return this.request('/admin/search/index')
+  }
+
+  async rebuildSearchIndex(): Promise<{ message: string; job: { id: number } }> {
+    return this.request('/admin/search/rebuild', { method: 'POST' })
+  }
+
+  async getCommentBridgeStatus(): Promise<{
+    provider: 'giscus' | 'disqus'
+    sync_content: boolean
+    discussions: number
+    comments: number
+    last_sync: { id: number; status: string; error?: string; updated_at: string } | null
+  }> {
+    return this.request('/admin/comment-bridge')
+  }
+
+  async syncCommentBridge(): Promise<{ message: string; job: { id: number } }> {
+    return this.request('/admin/comment-bridge/sync', { method: 'POST' })
+  }
+
   // Semantic search endpoints
   async semanticSearch(request: SemanticSearchRequest): Promise<SemanticSearchResponse> {
     return this.request('/search/semantic', {
@@ -1802,6 +3732,7 @@ class ApiClient {
     language?: string
     tracking_status?: string
     difficulty?: string
+    stale?: boolean
     search?: string
   }): Promise<{
     keywords: SEOKeyword[]
@@ -1812,6 +3743,7 @@ class ApiClient {
     if (filters?.language) params.append('language', filters.language)
     if (filters?.tracking_status) params.append('tracking_status', filters.tracking_status)
     if (filters?.difficulty) params.append('difficulty', filters.difficulty)
+    if (filters?.stale) params.append('stale', 'true')
     if (filters?.search) params.append('search', filters.search)
     
     const queryString = params.toString()
@@ -1882,6 +3814,54 @@ class ApiClient {
     })
   }
 
+  async getKeywordDataStatus(): Promise<KeywordDataStatus> {
+    return this.request('/seo/keywords/data')
+  }
+
+  async refreshKeywordData(): Promise<{ id: number; status: string }> {
+    return this.request('/seo/keywords/data/refresh', {
+      method: 'POST'
+    })
+  }
+
+  async queueBulkSEOMetadata(options: SEOBulkMetadataOptions = {}): Promise<{ id: number; status: string }> {
+    return this.request('/seo/metadata/bulk', {
+      method: 'POST',
+      body: JSON.stringify(options)
+    })
+  }
+
+  async getSEOMetadataProposals(params?: {
+    status?: SEOMetadataProposal['status']
+    limit?: number
+  }): Promise<{ proposals: SEOMetadataProposal[] }> {
+    const searchParams = new URLSearchParams()
+    if (params?.status) searchParams.append('status', params.status)
+    if (params?.limit) searchParams.append('limit', params.limit.toString())
+    const query = searchParams.toString()
+    return this.request(`/seo/metadata/proposals${query ? `?${query}` : ''}`)
+  }
+
+  async decideSEOMetadataProposals(ids: number[], decision: 'apply' | 'dismiss'): Promise<{ proposals: SEOMetadataProposal[] }> {
+    return this.request(`/seo/metadata/proposals/${decision}`, {
+      method: 'POST',
+      body: JSON.stringify({ ids })
+    })
+  }
+
+  async getKeywordMapping(params?: {
+    language?: string
+    threshold?: number
+    margin?: number
+  }): Promise<KeywordMappingReport> {
+    const searchParams = new URLSearchParams()
+    if (params?.language) searchParams.append('language', params.language)
+    if (params?.threshold) searchParams.append('threshold', params.threshold.toString())
+    if (params?.margin) searchParams.append('margin', params.margin.toString())
+    const query = searchParams.toString()
+    return this.request(`/seo/keywords/mapping${query ? `?${query}` : ''}`)
+  }
+
   async getSEOKeywordsByGroup(): Promise<{
     grouped_keywords: Record<string, SEOKeyword[]>
   }> {
@@ -2064,7 +4044,7 @@ class ApiClient {
   }
 
   async getPersonalizedRecommendations(params: PersonalizedRecommendationsRequest = {}): Promise<{
-    recommendations: RecommendationResult[]
+    recommendations: RecommendationSummary[]
     count: number
     user_id: string
     message: string
@@ -2079,6 +4059,7 @@ class ApiClient {
     if (params.diversify !== undefined) searchParams.append('diversify', params.diversify.toString())
     if (params.categories) searchParams.append('categories', params.categories.join(','))
     if (params.max_age) searchParams.append('max_age', params.max_age.toString())
+    if (params.device_type) searchParams.append('device_type', params.device_type)
     
     const queryString = searchParams.toString()
     return this.request(`/recommendations/personalized${queryString ? `?${queryString}` : ''}`)
@@ -2099,7 +4080,7 @@ class ApiClient {
     limit?: number
     days?: number
   } = {}): Promise<{
-    popular_content: RecommendationResult[]
+    popular_content: RecommendationSummary[]
     count: number
     days: number
     message: string
@@ -2154,21 +4135,36 @@ class ApiClient {
     })
   }
 
-  async getRecentUsers(options?: { limit?: number; offset?: number; days?: number }): Promise<{
+  async getRecentUsers(options?: { limit?: number; offset?: number; days?: number; cursor?: string }): Promise<{
     users: RecentUser[]
     count: number
     limit: number
     offset: number
     days: number
+    next_cursor: string
     message: string
   }> {
     const params = new URLSearchParams()
     if (options?.limit) params.set('limit', options.limit.toString())
     if (options?.offset) params.set('offset', options.offset.toString())
     if (options?.days) params.set('days', options.days.toString())
+    if (options?.cursor) params.set('cursor', options.cursor)
     const queryString = params.toString() ? `?${params.toString()}` : ''
     return this.request(`/recommendations/users/recent${queryString}`)
   }
+
+  async getUserBehaviors(userId: string, options?: { limit?: number; cursor?: string }): Promise<{
+    behaviors: UserBehavior[]
+    count: number
+    limit: number
+    next_cursor: string
+  }> {
+    const params = new URLSearchParams()
+    if (options?.limit) params.set('limit', options.limit.toString())
+    if (options?.cursor) params.set('cursor', options.cursor)
+    const queryString = params.toString() ? `?${params.toString()}` : ''
+    return this.request(`/recommendations/users/${userId}/behaviors${queryString}`)
+  }
 }
 
 export const apiClient = new ApiClient()
\ No newline at end of file
@@ -1,9 +1,10 @@
 import type { Metadata } from "next";
 import { getTranslations } from 'next-intl/server'
-import { getSiteUrl, getApiUrl, getPublicApiUrl } from '@/lib/config'
+import { getSiteUrl, getApiUrl } from '@/lib/config'
 import { generateIconsMetadata } from '@/lib/favicon-utils'
 import { routing } from '@/i18n/routing'
 import { buildLocalizedPath, getSiteAvailableLocales, normalizeSeoLocales } from '@/lib/seo-locale-utils'
+import { feedPath } from '@/lib/sitemap'
 
 export interface SiteSettings {
   site_title?: string
@@ -27,6 +28,7 @@ export interface PageMetadataOptions {
   customSettings?: SiteSettings
   includeRSS?: boolean
   availableLocales?: string[]
+  localizedPaths?: Record<string, string>
   robots?: {
     index?: boolean
     follow?: boolean
@@ -65,6 +67,7 @@ export async function generatePageMetadata(options: PageMetadataOptions): Promis
     customSettings,
     includeRSS = true,
     availableLocales,
+    localizedPaths,
     robots = { index: true, follow: true }
   } = options
 
@@ -95,15 +98,17 @@ export async function generatePageMetadata(options: PageMetadataOptions): Promis
   // Generate alternate language links including self-referential
   const languages: Record<string, string> = {}
   const canonicalPath = canonical || '/'
+  // Pages such as articles may have a different path in each language
+  const pathFor = (loc: string) => localizedPaths?.[loc] || canonicalPath
   seoLocales.forEach(loc => {
-    languages[loc] = `${siteUrl}${buildLocalizedPath(canonicalPath, loc, defaultLocale)}`
+    languages[loc] = `${siteUrl}${buildLocalizedPath(pathFor(loc), loc, defaultLocale)}`
   })
   
   // x-default 始终指向默认语言版本
-  languages['x-default'] = `${siteUrl}${buildLocalizedPath(canonicalPath, defaultLocale, defaultLocale)}`
+  languages['x-default'] = `${siteUrl}${buildLocalizedPath(pathFor(defaultLocale), defaultLocale, defaultLocale)}`
   
   // Build canonical URL - full absolute URL is preferred for SEO
-  const fullCanonicalPath = buildLocalizedPath(canonicalPath, canonicalLocale, defaultLocale)
+  const fullCanonicalPath = buildLocalizedPath(pathFor(canonicalLocale), canonicalLocale, defaultLocale)
   const fullCanonicalUrl = `${siteUrl}${fullCanonicalPath}`
 
   // Build metadata object
@@ -147,7 +152,7 @@ export async function generatePageMetadata(options: PageMetadataOptions): Promis
       types: {
         'application/rss+xml': [
           {
-            url: `${getPublicApiUrl()}/rss?lang=${canonicalLocale}`,
+            url: `${siteUrl}${feedPath(canonicalLocale)}`,
             title: `${siteTitle} RSS Feed`,
           },
         ],
@@ -202,6 +207,8 @@ export async function generateArticleMetadata(options: PageMetadataOptions & {
     created_at: string
     updated_at: string
     default_lang?: string
+    // Where the article first appeared, such as the post on an old blog it was imported from
+    canonical_url?: string
     translations?: Array<{
       language: string
       title?: string
@@ -225,6 +232,10 @@ export async function generateArticleMetadata(options: PageMetadataOptions & {
     description: finalDescription,
   })
 
+  if (article.canonical_url) {
+    metadata.alternates = { ...metadata.alternates, canonical: article.canonical_url }
+  }
+
   // Add article-specific OpenGraph data
   if (metadata.openGraph) {
     metadata.openGraph = {
@@ -16,15 +16,21 @@ type ArticleTranslationLike = {
   title?: string
   summary?: string
   content?: string
+  seo_slug?: string
 }
 
 type ArticleLike = {
+  id?: number | string
+  seo_slug?: string
   default_lang?: string
   translations?: ArticleTranslationLike[]
 }
 
 const supportedLocaleSet = new Set<string>(routing.locales as readonly string[])
 
+// Languages written from right to left, as the backend lists them
+const rtlLanguages = new Set(['ar', 'arc', 'ckb', 'dv', 'fa', 'he', 'iw', 'ks', 'ps', 'sd', 'ug', 'ur', 'yi'])
+
 function hasMeaningfulText(value?: string | null): boolean {
   return Boolean(value?.trim())
 }
@@ -105,3 +111,22 @@ export function getArticleAvailableLocales(
 
   return filteredLocales.length > 0 ? filteredLocales : [defaultLocale]
 }
+
+export function getArticleSlug(article: ArticleLike, locale: string): string {
+  const translatedSlug = article.translations?.find(
+    (translation) => translation.language === locale && translation.seo_slug
+  )?.seo_slug
+
+  return translatedSlug || article.seo_slug || String(article.id ?? '')
+}
+
+export function getArticleLocalizedPaths(article: ArticleLike, locales: string[]): Record<string, string> {
+  return Object.fromEntries(
+    locales.map((locale) => [locale, `/article/${encodeURIComponent(getArticleSlug(article, locale))}`])
+  )
+}
+
+export function getLanguageDirection(language?: string | null): 'ltr' | 'rtl' {
+  const base = (language || '').trim().toLowerCase().replace('_', '-').split('-')[0]
+  return rtlLanguages.has(base) ? 'rtl' : 'ltr'
+}
@@ -4,7 +4,7 @@
  */
 
 import { getApiUrl } from './config'
-import type { Article, Category, SiteSettings } from './api'
+import type { Article, BootstrapConfig, Category, SiteSettings } from './api'
 
 function getServerApiUrl(): string {
   return getApiUrl()
@@ -31,8 +31,11 @@ export async function fetchArticles(locale: string, categoryId?: number): Promis
   return serverFetch<Article[]>(`/articles?${params.toString()}`)
 }
 
-export async function fetchArticle(id: string | number, locale: string): Promise<Article> {
-  return serverFetch<Article>(`/articles/${id}?lang=${locale}`)
+export async function fetchArticle(id: string | number, locale: string, preview?: string): Promise<Article> {
+  const params = new URLSearchParams({ lang: locale })
+  if (preview) params.set('preview', preview)
+  // Previews of scheduled articles are never cached
+  return serverFetch<Article>(`/articles/${id}?${params.toString()}`, preview ? 0 : 60)
 }
 
 export async function fetchCategories(locale: string): Promise<Category[]> {
@@ -42,3 +45,7 @@ export async function fetchCategories(locale: string): Promise<Category[]> {
 export async function fetchSettings(locale: string): Promise<SiteSettings> {
   return serverFetch<SiteSettings>(`/settings?lang=${locale}`, 300)
 }
+
+export async function fetchBootstrapConfig(): Promise<BootstrapConfig> {
+  return serverFetch<BootstrapConfig>('/config')
+}
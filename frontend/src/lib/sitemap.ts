@@ -0,0 +1,168 @@
+import { getApiUrl, getSiteUrl } from '@/lib/config'
+import {
+  buildLocalizedPath,
+  getArticleAvailableLocales,
+  getArticleLocalizedPaths,
+  getSiteAvailableLocales,
+} from '@/lib/seo-locale-utils'
+
+type SiteSettingsResponse = {
+  block_search_engines?: boolean
+  default_language?: string
+  translations?: Array<{
+    language: string
+    site_title?: string
+    site_subtitle?: string
+  }>
+}
+
+type SitemapArticle = {
+  id: number | string
+  seo_slug?: string
+  region_rule?: string
+  default_lang?: string
+  translations?: Array<{
+    language: string
+    title?: string
+    summary?: string
+    content?: string
+    seo_slug?: string
+  }>
+  created_at: string
+  updated_at?: string
+}
+
+export type SitemapUrl = {
+  locale: string
+  xml: string
+  lastmod: string
+}
+
+export const EMPTY_URLSET = '<?xml version="1.0" encoding="UTF-8"?>\n<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"></urlset>'
+
+export const XML_HEADERS = {
+  'Content-Type': 'application/xml; charset=utf-8',
+  'Cache-Control': 'public, max-age=3600, s-maxage=3600',
+}
+
+/**
+ * Sitemap and feed file names of a language, e.g. /sitemap-en.xml and /feed-en.xml.
+ * They are rewritten to the sitemaps and feeds routes in next.config.ts.
+ */
+export function sitemapPath(locale: string): string {
+  return `/sitemap-${locale}.xml`
+}
+
+export function feedPath(locale: string): string {
+  return `/feed-${locale}.xml`
+}
+
+function generateAlternateLinks(siteUrl: string, locales: string[], path: string | Record<string, string>): string {
+  return locales
+    .map((locale) => {
+      const localePath = typeof path === 'string' ? path : path[locale]
+      const href = `${siteUrl}${buildLocalizedPath(localePath, locale)}`
+      return `<xhtml:link rel="alternate" hreflang="${locale}" href="${href}" />`
+    })
+    .join('\n')
+}
+
+/**
+ * Fetches the site settings for the sitemaps. Returns null when they cannot
+ * be read, and blocked when search engines are kept out of the site.
+ */
+export async function fetchSitemapSettings(): Promise<{ settings: SiteSettingsResponse | null, blocked: boolean }> {
+  try {
+    const response = await fetch(`${getApiUrl()}/settings`, {
+      headers: {
+        'Accept': 'application/json',
+      },
+      cache: 'no-store',
+    })
+
+    if (response.ok) {
+      const settings = await response.json() as SiteSettingsResponse | null
+      return { settings, blocked: Boolean(settings?.block_search_engines) }
+    }
+  } catch (error) {
+    console.error('Failed to fetch settings for sitemap:', error)
+  }
+  return { settings: null, blocked: false }
+}
+
+/**
+ * Builds the sitemap entries of every language of the site: the home page
+ * and each published article in the languages it is translated into, all
+ * linked to their other languages.
+ */
+export async function buildSitemapUrls(settings: SiteSettingsResponse | null): Promise<SitemapUrl[]> {
+  const apiUrl = getApiUrl()
+  const siteUrl = getSiteUrl()
+  const siteLocales = getSiteAvailableLocales(settings)
+  const urls: SitemapUrl[] = []
+  const now = new Date()
+
+  siteLocales.forEach((locale) => {
+    const path = buildLocalizedPath('/', locale)
+    urls.push({
+      locale,
+      lastmod: now.toISOString(),
+      xml: `<url>
+<loc>${siteUrl}${path}</loc>
+${generateAlternateLinks(siteUrl, siteLocales, '/')}
+<lastmod>${now.toISOString()}</lastmod>
+<changefreq>daily</changefreq>
+<priority>1</priority>
+</url>`,
+    })
+  })
+
+  try {
+    const response = await fetch(`${apiUrl}/articles`, {
+      headers: {
+        'Accept': 'application/json',
+      },
+      cache: 'no-store',
+    })
+
+    if (response.ok) {
+      const articles: SitemapArticle[] = await response.json()
+      // Articles limited to some regions are left out, as sitemaps are
+      // cached and read by crawlers everywhere
+      const publishedArticles = articles.filter((article) => new Date(article.created_at) <= now && !article.region_rule)
+
+      publishedArticles.forEach((article) => {
+        const articleLocales = getArticleAvailableLocales(article)
+        // Each language is listed at its own translated slug
+        const articlePaths = getArticleLocalizedPaths(article, articleLocales)
+        const alternates = generateAlternateLinks(siteUrl, articleLocales, articlePaths)
+        const lastmod = new Date(article.updated_at || article.created_at).toISOString()
+
+        articleLocales.forEach((locale) => {
+          urls.push({
+            locale,
+            lastmod,
+            xml: `<url>
+<loc>${siteUrl}${buildLocalizedPath(articlePaths[locale], locale)}</loc>
+${alternates}
+<lastmod>${lastmod}</lastmod>
+<changefreq>weekly</changefreq>
+<priority>0.8</priority>
+</url>`,
+          })
+        })
+      })
+    }
+  } catch (error) {
+    console.error('Failed to fetch articles for sitemap:', error)
+  }
+
+  return urls
+}
+
+export function renderUrlset(urls: SitemapUrl[]): string {
+  return `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:xhtml="http://www.w3.org/1999/xhtml">
+${urls.map((url) => url.xml).join('\n')}
+</urlset>`
+}
@@ -17,6 +17,7 @@ export default async function middleware(request: NextRequest) {
     pathname.includes('.') ||
     pathname === '/favicon.ico' ||
     pathname === '/sitemap.xml' ||
+    /^\/(sitemap|feed)-[a-z]{2}\.xml$/.test(pathname) ||
     pathname === '/robots.txt' ||
     pathname === '/llms.txt'
   ) {
@@ -56,11 +57,44 @@ export default async function middleware(request: NextRequest) {
     console.error('Failed to check setup status:', error)
   }
 
+  // Paths without a locale go to the language the backend picks for the visitor
+  const firstSegment = pathname.split('/')[1]
+  if (!(routing.locales as readonly string[]).includes(firstSegment)) {
+    const language = await negotiateLanguage(request)
+    if (language) {
+      const url = request.nextUrl.clone()
+      url.pathname = `/${language}${pathname === '/' ? '' : pathname}`
+      return NextResponse.redirect(url)
+    }
+  }
+
   return i18nMiddleware(request)
 }
 
+// Ask the backend for the visitor's language, forwarding what it decides on
+async function negotiateLanguage(request: NextRequest): Promise<string | null> {
+  const headers: Record<string, string> = { 'Cache-Control': 'no-cache' }
+  for (const name of ['accept-language', 'cookie', 'x-forwarded-for', 'x-real-ip', 'cf-ipcountry', 'cloudfront-viewer-country']) {
+    const value = request.headers.get(name)
+    if (value) {
+      headers[name] = value
+    }
+  }
+  try {
+    const response = await fetch(`${getApiUrl()}/languages/negotiate`, { headers })
+    if (!response.ok) {
+      return null
+    }
+    const data = await response.json()
+    return (routing.locales as readonly string[]).includes(data.language) ? data.language : null
+  } catch (error) {
+    console.error('Failed to negotiate the visitor language:', error)
+    return null
+  }
+}
+
 export const config = {
   matcher: [
-    '/((?!api|ai-proxy|_next/static|_next/image|favicon.ico|embed|sitemap.xml|robots.txt|llms.txt|.*\\.png|.*\\.jpg|.*\\.jpeg|.*\\.gif|.*\\.svg|.*\\.ico|.*\\.webp).*)'
+    '/((?!api|ai-proxy|_next/static|_next/image|favicon.ico|embed|sitemap.xml|sitemap-[a-z]{2}\\.xml|feed-[a-z]{2}\\.xml|robots.txt|llms.txt|.*\\.png|.*\\.jpg|.*\\.jpeg|.*\\.gif|.*\\.svg|.*\\.ico|.*\\.webp).*)'
   ]
 }
@@ -78,6 +78,10 @@ export function getPlaceholders(text: string): string[] {
   return Array.from(new Set(text.match(PLACEHOLDER_PATTERN) || []))
 }
 
+export function isProtectedOnly(text: string): boolean {
+  return text.replace(PLACEHOLDER_PATTERN, '').trim() === ''
+}
+
 export function validateTranslatedContent(
   sourceText: string,
   translatedText: string,
@@ -9,7 +9,9 @@ import { LibreTranslateProvider } from './providers/libre-translate'
 import { MyMemoryProvider } from './providers/mymemory'
 import { GoogleFreeProvider } from './providers/google-free'
 import { aiUsageTracker } from '../ai-usage-tracker'
+import { apiClient, TranslationPair } from '@/lib/api'
 import {
+  isProtectedOnly,
   protectTranslatableContent,
   restoreProtectedContent,
   splitMarkdownIntoSemanticChunks,
@@ -33,6 +35,9 @@ export interface TranslationUsageStats {
   }
 }
 
+// Segments per translation memory request, the most the backend takes
+const TRANSLATION_MEMORY_BATCH = 500
+
 interface SelectiveCommentPlaceholder {
   placeholder: string
   commentText: string
@@ -181,20 +186,75 @@ export class TranslationService {
     let mergedTranslation = ''
     let mergedUsage: TranslationResult['usage'] | undefined
 
-    for (const chunk of chunks) {
-      const result = await this.callActiveProvider(chunk.text, from, to)
-      const issues = validateTranslatedContent(chunk.text, result.translatedText, chunk.id)
+    // Paragraphs translated before, in this article or another, come from
+    // the translation memory; only the rest is sent to the provider
+    const restore = (value: string) => restoreProtectedContent(value, protectedContent.items)
+    const chunkParagraphs = chunks.map((chunk) => chunk.text.split(/\n{2,}/))
+    const remembered = await this.lookupTranslationMemory(chunkParagraphs.flat().map(restore), from, to)
+    const newPairs: TranslationPair[] = []
+    let offset = 0
+
+    const translateChunkText = async (sourceText: string, chunkId: string): Promise<string> => {
+      const result = await this.callActiveProvider(sourceText, from, to)
+      const issues = validateTranslatedContent(sourceText, result.translatedText, chunkId)
 
       if (issues.length > 0) {
         throw new Error(`Translation QA failed: ${issues.map((issue) => issue.message).join('; ')}`)
       }
 
-      mergedTranslation = mergedTranslation
-        ? `${mergedTranslation}\n\n${result.translatedText}`
-        : result.translatedText
       mergedUsage = this.mergeUsage(mergedUsage, result.usage)
+      return result.translatedText
+    }
+
+    for (const [chunkIndex, chunk] of chunks.entries()) {
+      const paragraphs = chunkParagraphs[chunkIndex]
+      const start = offset
+      offset += paragraphs.length
+      const translated = new Map<number, string>()
+      paragraphs.forEach((paragraph, index) => {
+        const known = remembered.get(start + index)
+        if (known !== undefined) {
+          translated.set(index, known)
+        } else if (isProtectedOnly(paragraph)) {
+          translated.set(index, paragraph)
+        }
+      })
+
+      let translatedChunk: string
+      const missing = paragraphs.map((_, index) => index).filter((index) => !translated.has(index))
+      if (translated.size === 0) {
+        translatedChunk = await translateChunkText(chunk.text, chunk.id)
+        const translatedParagraphs = translatedChunk.split(/\n{2,}/)
+        if (translatedParagraphs.length === paragraphs.length) {
+          paragraphs.forEach((paragraph, index) => {
+            newPairs.push({ source: restore(paragraph), target: restore(translatedParagraphs[index]) })
+          })
+        }
+      } else if (missing.length === 0) {
+        translatedChunk = paragraphs.map((_, index) => translated.get(index)).join('\n\n')
+      } else {
+        const sourceText = missing.map((index) => paragraphs[index]).join('\n\n')
+        const translatedParagraphs = (await translateChunkText(sourceText, chunk.id)).split(/\n{2,}/)
+        if (translatedParagraphs.length === missing.length) {
+          missing.forEach((index, position) => {
+            translated.set(index, translatedParagraphs[position])
+            newPairs.push({ source: restore(paragraphs[index]), target: restore(translatedParagraphs[position]) })
+          })
+          translatedChunk = paragraphs.map((_, index) => translated.get(index)).join('\n\n')
+        } else {
+          // The provider merged or split paragraphs, so they cannot be put
+          // back in place; translate the chunk as a whole instead
+          translatedChunk = await translateChunkText(chunk.text, chunk.id)
+        }
+      }
+
+      mergedTranslation = mergedTranslation
+        ? `${mergedTranslation}\n\n${translatedChunk}`
+        : translatedChunk
     }
 
+    await this.rememberTranslations(newPairs, from, to)
+
     const restoredText = restoreProtectedContent(mergedTranslation, protectedContent.items)
     const restoreIssues = validateRestoredContent(restoredText)
 
@@ -221,6 +281,39 @@ export class TranslationService {
     }
   }
 
+  // The translation memory saves paid calls but is never required: when it
+  // cannot be reached every paragraph is translated
+  private async lookupTranslationMemory(segments: string[], from: string, to: string): Promise<Map<number, string>> {
+    const remembered = new Map<number, string>()
+    if (!apiClient.isAuthenticated() || segments.length === 0) {
+      return remembered
+    }
+
+    try {
+      for (let start = 0; start < segments.length; start += TRANSLATION_MEMORY_BATCH) {
+        const { matches } = await apiClient.lookupTranslationMemory(from, to, segments.slice(start, start + TRANSLATION_MEMORY_BATCH))
+        matches.forEach((match) => remembered.set(start + match.index, match.translation))
+      }
+    } catch (error) {
+      console.warn('Translation memory lookup failed:', error)
+    }
+    return remembered
+  }
+
+  private async rememberTranslations(pairs: TranslationPair[], from: string, to: string): Promise<void> {
+    if (!apiClient.isAuthenticated() || pairs.length === 0) {
+      return
+    }
+
+    try {
+      for (let start = 0; start < pairs.length; start += TRANSLATION_MEMORY_BATCH) {
+        await apiClient.rememberTranslations(from, to, pairs.slice(start, start + TRANSLATION_MEMORY_BATCH))
+      }
+    } catch (error) {
+      console.warn('Failed to save translations to the translation memory:', error)
+    }
+  }
+
   private getFencedCodeLineNumbers(text: string): Set<number> {
     const codeLineNumbers = new Set<number>()
     let inCodeBlock = false